@@ -0,0 +1,268 @@
+package arangodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+// Config contains the configuration fields shared by the arangodb components
+// for connecting to a database.
+type Config struct {
+	URL      string      `json:"url" yaml:"url"`
+	Database string      `json:"database" yaml:"database"`
+	Username string      `json:"username" yaml:"username"`
+	Password string      `json:"password" yaml:"password"`
+	Timeout  string      `json:"timeout" yaml:"timeout"`
+	TLS      btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		URL:      "",
+		Database: "_system",
+		Username: "",
+		Password: "",
+		Timeout:  "15s",
+		TLS:      btls.NewConfig(),
+	}
+}
+
+// FieldSpecs returns the documentation of the fields of a Config.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("url", "The URL of an ArangoDB server or coordinator.", "http://localhost:8529"),
+		docs.FieldCommon("database", "The database to connect to."),
+		docs.FieldCommon("username", "The username to authenticate with."),
+		docs.FieldCommon("password", "The password to authenticate with."),
+		docs.FieldAdvanced("timeout", "The maximum period of time to wait for a request to complete."),
+		btls.FieldSpec(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Error is an error returned by the API.
+type Error struct {
+	Code    int    `json:"code"`
+	Num     int    `json:"errorNum"`
+	Message string `json:"errorMessage"`
+}
+
+// Error returns a description of the error.
+func (e *Error) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("%v (%v)", e.Message, e.Num)
+	}
+	return fmt.Sprintf("request failed with status %v (%v): %v", e.Code, e.Num, e.Message)
+}
+
+// Client performs requests against the API of a database.
+type Client struct {
+	conf    Config
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client from a config.
+func NewClient(conf Config) (*Client, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	if conf.Database == "" {
+		return nil, errors.New("a database must be specified")
+	}
+	c := &Client{
+		conf:    conf,
+		baseURL: strings.TrimSuffix(conf.URL, "/") + "/_db/" + url.PathEscape(conf.Database),
+		http:    &http.Client{},
+	}
+	if conf.Timeout != "" {
+		var err error
+		if c.http.Timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		c.http.Transport = &http.Transport{TLSClientConfig: tlsConf}
+	}
+	return c, nil
+}
+
+// Do performs a request with a path relative to the database, encoding the
+// body as JSON when it is not nil and decoding the response into result when
+// it is not nil.
+func (c *Client) Do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.conf.Username != "" {
+		req.SetBasicAuth(c.conf.Username, c.conf.Password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := &Error{Code: res.StatusCode}
+		if jErr := json.Unmarshal(resBody, apiErr); jErr != nil || apiErr.Message == "" {
+			apiErr.Message = string(bytes.TrimSpace(resBody))
+		}
+		return apiErr
+	}
+	if result != nil {
+		if err := json.Unmarshal(resBody, result); err != nil {
+			return fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+	return nil
+}
+
+// Ping checks that the database is reachable with the credentials of the
+// client.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Do(ctx, http.MethodGet, "/_api/database/current", nil, nil)
+}
+
+//------------------------------------------------------------------------------
+
+type cursorResponse struct {
+	ID      string            `json:"id"`
+	Result  []json.RawMessage `json:"result"`
+	HasMore bool              `json:"hasMore"`
+}
+
+// Cursor iterates the results of an AQL query in batches.
+type Cursor struct {
+	c    *Client
+	id   string
+	next []json.RawMessage
+	more bool
+}
+
+// Query executes an AQL query, returning a cursor of its results.
+func (c *Client) Query(ctx context.Context, query string, bindVars map[string]interface{}, batchSize int) (*Cursor, error) {
+	body := map[string]interface{}{"query": query}
+	if len(bindVars) > 0 {
+		body["bindVars"] = bindVars
+	}
+	if batchSize > 0 {
+		body["batchSize"] = batchSize
+	}
+	var res cursorResponse
+	if err := c.Do(ctx, http.MethodPost, "/_api/cursor", body, &res); err != nil {
+		return nil, err
+	}
+	return &Cursor{c: c, id: res.ID, next: res.Result, more: res.HasMore}, nil
+}
+
+// NextBatch returns the next batch of results, or io.EOF once all results
+// have been read.
+func (r *Cursor) NextBatch(ctx context.Context) ([]json.RawMessage, error) {
+	if r.next != nil {
+		batch := r.next
+		r.next = nil
+		return batch, nil
+	}
+	if !r.more {
+		return nil, io.EOF
+	}
+	var res cursorResponse
+	if err := r.c.Do(ctx, http.MethodPut, "/_api/cursor/"+url.PathEscape(r.id), nil, &res); err != nil {
+		return nil, err
+	}
+	r.more = res.HasMore
+	if !r.more {
+		r.id = ""
+	}
+	if len(res.Result) == 0 && !r.more {
+		return nil, io.EOF
+	}
+	return res.Result, nil
+}
+
+// Close deletes the cursor from the server if it has not been exhausted.
+func (r *Cursor) Close(ctx context.Context) error {
+	if !r.more || r.id == "" {
+		return nil
+	}
+	r.more = false
+	return r.c.Do(ctx, http.MethodDelete, "/_api/cursor/"+url.PathEscape(r.id), nil, nil)
+}
+
+//------------------------------------------------------------------------------
+
+// WriteResult is the result of writing a single document, where Err is set
+// when the document was rejected.
+type WriteResult struct {
+	Key string
+	Err error
+}
+
+// InsertDocuments writes documents to a collection in a single request, where
+// overwriteMode is one of replace, update, ignore or conflict. A result is
+// returned for each document in order.
+func (c *Client) InsertDocuments(ctx context.Context, collection, overwriteMode string, documents []json.RawMessage) ([]WriteResult, error) {
+	path := "/_api/document/" + url.PathEscape(collection)
+	if overwriteMode != "" {
+		path += "?overwriteMode=" + url.QueryEscape(overwriteMode)
+	}
+
+	var res []struct {
+		Key          string `json:"_key"`
+		Error        bool   `json:"error"`
+		ErrorNum     int    `json:"errorNum"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if err := c.Do(ctx, http.MethodPost, path, documents, &res); err != nil {
+		return nil, err
+	}
+	if len(res) != len(documents) {
+		return nil, fmt.Errorf("expected %v results, received %v", len(documents), len(res))
+	}
+
+	results := make([]WriteResult, len(res))
+	for i, r := range res {
+		results[i].Key = r.Key
+		if r.Error {
+			results[i].Err = &Error{Num: r.ErrorNum, Message: r.ErrorMessage}
+		}
+	}
+	return results, nil
+}
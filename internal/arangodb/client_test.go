@@ -0,0 +1,106 @@
+package arangodb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientQuery(t *testing.T) {
+	var deleted []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		require.Equal(t, "root", user)
+		require.Equal(t, "pass", pass)
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/_db/test/_api/cursor":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "FOR d IN docs FILTER d.n > @n RETURN d", body["query"])
+			assert.Equal(t, map[string]interface{}{"n": float64(1)}, body["bindVars"])
+			assert.Equal(t, float64(2), body["batchSize"])
+			w.Write([]byte(`{"id":"123","result":[{"n":2},{"n":3}],"hasMore":true}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/_db/test/_api/cursor/123":
+			w.Write([]byte(`{"id":"123","result":[{"n":4}],"hasMore":false}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":true,"code":404,"errorNum":1203,"errorMessage":"collection or view not found"}`))
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL
+	conf.Database = "test"
+	conf.Username = "root"
+	conf.Password = "pass"
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	cursor, err := c.Query(ctx, "FOR d IN docs FILTER d.n > @n RETURN d", map[string]interface{}{"n": 1}, 2)
+	require.NoError(t, err)
+
+	var results []string
+	for {
+		batch, err := cursor.NextBatch(ctx)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, r := range batch {
+			results = append(results, string(r))
+		}
+	}
+	assert.Equal(t, []string{`{"n":2}`, `{"n":3}`, `{"n":4}`}, results)
+	require.NoError(t, cursor.Close(ctx))
+	assert.Empty(t, deleted)
+
+	cursor, err = c.Query(ctx, "FOR d IN docs FILTER d.n > @n RETURN d", map[string]interface{}{"n": 1}, 2)
+	require.NoError(t, err)
+	require.NoError(t, cursor.Close(ctx))
+	assert.Equal(t, []string{"/_db/test/_api/cursor/123"}, deleted)
+
+	err = c.Do(ctx, http.MethodGet, "/_api/nope", nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, 1203, err.(*Error).Num)
+	assert.Contains(t, err.Error(), "collection or view not found")
+}
+
+func TestClientInsertDocuments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_db/_system/_api/document/users", r.URL.Path)
+		assert.Equal(t, "update", r.URL.Query().Get("overwriteMode"))
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, `[{"_key":"a"},{"_key":"b"}]`, string(body))
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`[{"_key":"a","_id":"users/a"},{"error":true,"errorNum":1210,"errorMessage":"unique constraint violated"}]`))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+
+	results, err := c.InsertDocuments(context.Background(), "users", "update", []json.RawMessage{
+		json.RawMessage(`{"_key":"a"}`),
+		json.RawMessage(`{"_key":"b"}`),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "a", results[0].Key)
+	assert.NoError(t, results[0].Err)
+	require.Error(t, results[1].Err)
+	assert.Equal(t, "unique constraint violated (1210)", results[1].Err.Error())
+}
@@ -0,0 +1,4 @@
+// Package arangodb implements a minimal client of the HTTP API of ArangoDB,
+// supporting AQL cursors and document writes, as used by the arangodb
+// components.
+package arangodb
@@ -467,3 +467,39 @@ func TestLegacyExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestExpressionsLockedMessage(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`{"type":"header"}`),
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"bar"}`),
+	})
+
+	indexFn, err := query.InitFunction("batch_index")
+	require.NoError(t, err)
+	sizeFn, err := query.InitFunction("batch_size")
+	require.NoError(t, err)
+	idFn, err := query.InitFunction("json", "id")
+	require.NoError(t, err)
+	headerFn, err := query.InitFunction("json", "type")
+	require.NoError(t, err)
+	headerFn, err = query.InitMethod("from", headerFn, int64(0))
+	require.NoError(t, err)
+
+	e := NewExpression(
+		NewQueryResolver(indexFn),
+		StaticResolver("/"),
+		NewQueryResolver(sizeFn),
+		StaticResolver(" "),
+		NewQueryResolver(headerFn),
+		StaticResolver(" "),
+		NewQueryResolver(idFn),
+	)
+
+	// Messages locked to a single part of a batch still resolve against the
+	// entire batch.
+	assert.Equal(t, "1/3 header foo", e.String(0, message.Lock(msg, 1)))
+	assert.Equal(t, "2/3 header bar", e.String(-1, message.Lock(msg, 2)))
+	assert.Equal(t, "2/3 header bar", string(e.Bytes(0, message.Lock(message.Lock(msg, 2), 0))))
+	assert.Equal(t, "2/3 header bar", e.String(2, msg))
+}
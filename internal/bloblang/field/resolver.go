@@ -51,6 +51,7 @@ func (q QueryResolver) ResolveString(index int, msg Message, escaped, legacy boo
 	if msg == nil {
 		msg = message.New(nil)
 	}
+	msg, index = query.UnlockBatch(msg, index)
 	return query.ExecToString(q.fn, query.FunctionContext{
 		Index:    index,
 		MsgBatch: msg,
@@ -69,6 +70,7 @@ func (q QueryResolver) ResolveBytes(index int, msg Message, escaped, legacy bool
 	if msg == nil {
 		msg = message.New(nil)
 	}
+	msg, index = query.UnlockBatch(msg, index)
 	bs := query.ExecToBytes(q.fn, query.FunctionContext{
 		Index:    index,
 		MsgBatch: msg,
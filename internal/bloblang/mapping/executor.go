@@ -124,6 +124,8 @@ func (e *Executor) MapOnto(part types.Part, index int, msg Message) (types.Part,
 }
 
 func (e *Executor) mapPart(appendTo types.Part, index int, reference Message) (types.Part, error) {
+	reference, index = query.UnlockBatch(reference, index)
+
 	var valuePtr *interface{}
 	var parseErr error
 
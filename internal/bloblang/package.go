@@ -43,3 +43,20 @@ func NewMapping(path, expr string) (*mapping.Executor, error) {
 	}
 	return e, nil
 }
+
+// NewEnvironmentMapping attempts to parse and create a Bloblang mapping that is
+// executed without a message, such as when a config is loaded, and therefore
+// functions that access message information are disabled.
+//
+// When a parsing error occurs the returned error may be a *parser.Error type,
+// which allows you to gain positional and structured error messages.
+func NewEnvironmentMapping(expr string) (*mapping.Executor, error) {
+	e, err := parser.ParseMapping("", expr, parser.Context{
+		Functions: query.AllFunctions.NoMessage(),
+		Methods:   query.AllMethods,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
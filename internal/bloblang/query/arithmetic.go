@@ -94,6 +94,32 @@ var ErrDivideByZero = errors.New("attempted to divide by zero")
 
 type intArithmeticFunc func(left, right int64) (int64, error)
 type floatArithmeticFunc func(left, right float64) (float64, error)
+type decimalArithmeticFunc func(left, right Decimal) (Decimal, error)
+
+// Takes a decimal arithmetic func and a generic arithmetic func and returns an
+// arithmetic func where, if either value is a decimal, both values are
+// converted into decimals and the decimal func is called, otherwise the
+// generic func is called.
+func decimalDegradationFunc(op ArithmeticOperator, dFn decimalArithmeticFunc, fn arithmeticOpFunc) arithmeticOpFunc {
+	return func(lhs, rhs Function, left, right interface{}) (interface{}, error) {
+		if !isDecimal(left) && !isDecimal(right) {
+			return fn(lhs, rhs, left, right)
+		}
+		leftDec, err := iGetDecimal(left)
+		if err != nil {
+			return nil, NewTypeMismatch(op.String(), lhs, rhs, left, right)
+		}
+		rightDec, err := iGetDecimal(right)
+		if err != nil {
+			return nil, NewTypeMismatch(op.String(), lhs, rhs, left, right)
+		}
+		res, err := dFn(leftDec, rightDec)
+		if err != nil {
+			return nil, ErrFrom(err, rhs)
+		}
+		return res, nil
+	}
+}
 
 // Takes two arithmetic funcs, one for integer values and one for float values
 // and returns a generic arithmetic func. If both values can be represented as
@@ -134,17 +160,19 @@ func numberDegradationFunc(op ArithmeticOperator, iFn intArithmeticFunc, fFn flo
 func prodOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 	switch op {
 	case ArithmeticMul:
-		return numberDegradationFunc(op,
+		return decimalDegradationFunc(op, func(lhs, rhs Decimal) (Decimal, error) {
+			return lhs.Mul(rhs), nil
+		}, numberDegradationFunc(op,
 			func(lhs, rhs int64) (int64, error) {
 				return lhs * rhs, nil
 			},
 			func(lhs, rhs float64) (float64, error) {
 				return lhs * rhs, nil
 			},
-		), true
+		)), true
 	case ArithmeticDiv:
-		// Only executes on float values.
-		return func(lFn, rFn Function, left, right interface{}) (interface{}, error) {
+		// Only executes on float or decimal values.
+		return decimalDegradationFunc(op, Decimal.Div, func(lFn, rFn Function, left, right interface{}) (interface{}, error) {
 			lhs, err := IGetNumber(left)
 			if err != nil {
 				return nil, NewTypeMismatch(op.String(), lFn, rFn, left, right)
@@ -157,10 +185,10 @@ func prodOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 				return nil, ErrFrom(ErrDivideByZero, rFn)
 			}
 			return lhs / rhs, nil
-		}, true
+		}), true
 	case ArithmeticMod:
-		// Only executes on integer values.
-		return func(lFn, rFn Function, left, right interface{}) (interface{}, error) {
+		// Only executes on integer or decimal values.
+		return decimalDegradationFunc(op, Decimal.Mod, func(lFn, rFn Function, left, right interface{}) (interface{}, error) {
 			lhs, err := IGetInt(left)
 			if err != nil {
 				return nil, NewTypeMismatch(op.String(), lFn, rFn, left, right)
@@ -173,7 +201,7 @@ func prodOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 				return nil, ErrFrom(ErrDivideByZero, rFn)
 			}
 			return lhs % rhs, nil
-		}, true
+		}), true
 	}
 	return nil, false
 }
@@ -181,17 +209,19 @@ func prodOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 func sumOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 	switch op {
 	case ArithmeticAdd:
-		numberAdd := numberDegradationFunc(op,
+		numberAdd := decimalDegradationFunc(op, func(lhs, rhs Decimal) (Decimal, error) {
+			return lhs.Add(rhs), nil
+		}, numberDegradationFunc(op,
 			func(left, right int64) (int64, error) {
 				return left + right, nil
 			},
 			func(left, right float64) (float64, error) {
 				return left + right, nil
 			},
-		)
+		))
 		return func(lFn, rFn Function, left, right interface{}) (interface{}, error) {
 			switch left.(type) {
-			case float64, int, int64, uint64, json.Number:
+			case float64, int, int64, uint64, json.Number, Decimal:
 				return numberAdd(lFn, rFn, left, right)
 			case string, []byte:
 				lhs, err := IGetString(left)
@@ -207,14 +237,16 @@ func sumOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 			return nil, NewTypeMismatch(op.String(), lFn, rFn, left, right)
 		}, true
 	case ArithmeticSub:
-		return numberDegradationFunc(op,
+		return decimalDegradationFunc(op, func(lhs, rhs Decimal) (Decimal, error) {
+			return lhs.Sub(rhs), nil
+		}, numberDegradationFunc(op,
 			func(lhs, rhs int64) (int64, error) {
 				return lhs - rhs, nil
 			},
 			func(lhs, rhs float64) (float64, error) {
 				return lhs - rhs, nil
 			},
-		), true
+		)), true
 	}
 	return nil, false
 }
@@ -339,6 +371,23 @@ func compareOp(op ArithmeticOperator) (arithmeticOpFunc, bool) {
 		boolOpFn := compareBoolFn(op)
 		genericOpFn := compareGenericFn(op)
 		return func(lFn, rFn Function, left, right interface{}) (interface{}, error) {
+			if isDecimal(left) || isDecimal(right) {
+				// Decimals are compared exactly with other numbers.
+				if numOpFn == nil {
+					return nil, NewTypeMismatch(op.String(), lFn, rFn, left, right)
+				}
+				lhs, err := iGetDecimal(left)
+				if err == nil {
+					var rhs Decimal
+					if rhs, err = iGetDecimal(right); err == nil {
+						return numOpFn(float64(lhs.Cmp(rhs)), 0), nil
+					}
+				}
+				if op == ArithmeticNeq {
+					return true, nil
+				}
+				return nil, NewTypeMismatch(op.String(), lFn, rFn, left, right)
+			}
 			switch lhs := restrictForComparison(left).(type) {
 			case string:
 				if strOpFn == nil {
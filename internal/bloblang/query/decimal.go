@@ -0,0 +1,294 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decimalDivisionScale is the minimum number of decimal places retained by the
+// result of a division.
+const decimalDivisionScale = 16
+
+var bigTen = big.NewInt(10)
+
+// Decimal is an arbitrary-precision decimal number, which is represented as an
+// integer coefficient and a scale, i.e. the number of digits after the
+// decimal point. Decimals are immutable.
+type Decimal struct {
+	coef  *big.Int
+	scale int32
+}
+
+// NewDecimal creates a decimal from a coefficient and a scale, such that its
+// value is coef * 10^-scale.
+func NewDecimal(coef int64, scale int32) Decimal {
+	return Decimal{coef: big.NewInt(coef), scale: scale}
+}
+
+// ParseDecimal parses a decimal from a string, which can optionally contain
+// an exponent.
+func ParseDecimal(s string) (Decimal, error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return Decimal{}, errors.New("empty string is not a valid decimal")
+	}
+
+	var exp int64
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.ParseInt(str[i+1:], 10, 32); err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal: %v", s)
+		}
+		str = str[:i]
+	}
+
+	digits, scale := str, int64(0)
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		digits = str[:i] + str[i+1:]
+		scale = int64(len(str) - i - 1)
+	}
+	if digits == "" || digits == "-" || digits == "+" || strings.ContainsAny(digits[1:], "+-") {
+		return Decimal{}, fmt.Errorf("invalid decimal: %v", s)
+	}
+
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal: %v", s)
+	}
+	d := Decimal{coef: coef}
+	if scale -= exp; scale < 0 {
+		d.coef.Mul(d.coef, new(big.Int).Exp(bigTen, big.NewInt(-scale), nil))
+		scale = 0
+	}
+	d.scale = int32(scale)
+	return d, nil
+}
+
+// IToDecimal takes a boxed value and attempts to convert it into a decimal.
+// Floating point values are converted from their shortest representation, and
+// strings are parsed.
+func IToDecimal(v interface{}) (Decimal, error) {
+	switch t := v.(type) {
+	case Decimal:
+		return t, nil
+	case int:
+		return NewDecimal(int64(t), 0), nil
+	case int64:
+		return NewDecimal(t, 0), nil
+	case uint64:
+		return Decimal{coef: new(big.Int).SetUint64(t)}, nil
+	case float64:
+		return ParseDecimal(strconv.FormatFloat(t, 'f', -1, 64))
+	case json.Number:
+		return ParseDecimal(t.String())
+	case []byte:
+		return ParseDecimal(string(t))
+	case string:
+		return ParseDecimal(t)
+	}
+	return Decimal{}, NewTypeError(v, ValueNumber, ValueString)
+}
+
+// iGetDecimal converts a numerical value into a decimal.
+func iGetDecimal(v interface{}) (Decimal, error) {
+	switch v.(type) {
+	case Decimal, int, int64, uint64, float64, json.Number:
+		return IToDecimal(v)
+	}
+	return Decimal{}, NewTypeError(v, ValueNumber)
+}
+
+func isDecimal(v interface{}) bool {
+	_, ok := v.(Decimal)
+	return ok
+}
+
+func (d Decimal) coefficient() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// rescale returns the coefficient of the decimal at a larger scale.
+func (d Decimal) rescale(scale int32) *big.Int {
+	c := d.coefficient()
+	if scale <= d.scale {
+		return new(big.Int).Set(c)
+	}
+	return new(big.Int).Mul(c, new(big.Int).Exp(bigTen, big.NewInt(int64(scale-d.scale)), nil))
+}
+
+func maxScale(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Add returns the sum of two decimals.
+func (d Decimal) Add(o Decimal) Decimal {
+	scale := maxScale(d.scale, o.scale)
+	return Decimal{coef: new(big.Int).Add(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+// Sub returns the difference of two decimals.
+func (d Decimal) Sub(o Decimal) Decimal {
+	scale := maxScale(d.scale, o.scale)
+	return Decimal{coef: new(big.Int).Sub(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+// Mul returns the product of two decimals.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.coefficient(), o.coefficient()), scale: d.scale + o.scale}
+}
+
+// Div returns the quotient of two decimals, which retains at least 16 decimal
+// places rounded half to even, and trailing zeros beyond the scale of either
+// operand are removed.
+func (d Decimal) Div(o Decimal) (Decimal, error) {
+	if o.coefficient().Sign() == 0 {
+		return Decimal{}, ErrDivideByZero
+	}
+	minScale := maxScale(d.scale, o.scale)
+	scale := maxScale(minScale, decimalDivisionScale)
+
+	// Compute with an extra digit of precision for rounding.
+	num := d.rescale(scale + 1 + o.scale)
+	if shift := scale + 1 + o.scale - d.scale; shift < 0 {
+		num = new(big.Int).Quo(num, new(big.Int).Exp(bigTen, big.NewInt(int64(-shift)), nil))
+	}
+	q := Decimal{coef: new(big.Int).Quo(num, o.coefficient()), scale: scale + 1}
+	return q.Round(scale, RoundHalfEven).trim(minScale), nil
+}
+
+// Mod returns the remainder of dividing two decimals, which has the sign of
+// the dividend.
+func (d Decimal) Mod(o Decimal) (Decimal, error) {
+	if o.coefficient().Sign() == 0 {
+		return Decimal{}, ErrDivideByZero
+	}
+	scale := maxScale(d.scale, o.scale)
+	return Decimal{coef: new(big.Int).Rem(d.rescale(scale), o.rescale(scale)), scale: scale}, nil
+}
+
+// Cmp compares two decimals and returns -1, 0 or +1 when the decimal is less
+// than, equal to or greater than the other.
+func (d Decimal) Cmp(o Decimal) int {
+	scale := maxScale(d.scale, o.scale)
+	return d.rescale(scale).Cmp(o.rescale(scale))
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the decimal.
+func (d Decimal) Sign() int {
+	return d.coefficient().Sign()
+}
+
+// trim removes trailing zeros beyond a minimum scale.
+func (d Decimal) trim(minScale int32) Decimal {
+	c := new(big.Int).Set(d.coefficient())
+	scale := d.scale
+	r := new(big.Int)
+	for scale > minScale {
+		q, m := new(big.Int).QuoRem(c, bigTen, r)
+		if m.Sign() != 0 {
+			break
+		}
+		c = q
+		scale--
+	}
+	return Decimal{coef: c, scale: scale}
+}
+
+// Rounding modes supported by Decimal.Round.
+const (
+	RoundHalfEven = "half_even"
+	RoundHalfUp   = "half_up"
+	RoundHalfDown = "half_down"
+	RoundUp       = "up"
+	RoundDown     = "down"
+	RoundCeiling  = "ceiling"
+	RoundFloor    = "floor"
+)
+
+// Round a decimal to a number of decimal places with a rounding mode, where
+// half_up and half_down round half away from and towards zero respectively,
+// and up and down round away from and towards zero respectively. Decimals
+// with fewer decimal places are padded with zeros.
+func (d Decimal) Round(places int32, mode string) Decimal {
+	if places >= d.scale {
+		return Decimal{coef: d.rescale(places), scale: places}
+	}
+
+	divisor := new(big.Int).Exp(bigTen, big.NewInt(int64(d.scale-places)), nil)
+	q, r := new(big.Int).QuoRem(d.coefficient(), divisor, new(big.Int))
+	if r.Sign() == 0 {
+		return Decimal{coef: q, scale: places}
+	}
+
+	negative := d.coefficient().Sign() < 0
+	half := new(big.Int).Abs(r)
+	half.Mul(half, big.NewInt(2))
+	halfCmp := half.Cmp(divisor)
+
+	awayFromZero := false
+	switch mode {
+	case RoundHalfUp:
+		awayFromZero = halfCmp >= 0
+	case RoundHalfDown:
+		awayFromZero = halfCmp > 0
+	case RoundUp:
+		awayFromZero = true
+	case RoundDown:
+	case RoundCeiling:
+		awayFromZero = !negative
+	case RoundFloor:
+		awayFromZero = negative
+	default:
+		awayFromZero = halfCmp > 0 || (halfCmp == 0 && q.Bit(0) == 1)
+	}
+	if awayFromZero {
+		if negative {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{coef: q, scale: places}
+}
+
+// Float64 returns the nearest float64 value of the decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Int64 returns the decimal truncated to an integer.
+func (d Decimal) Int64() int64 {
+	return d.Round(0, RoundDown).coefficient().Int64()
+}
+
+// String returns the decimal in plain notation, retaining its scale.
+func (d Decimal) String() string {
+	c := d.coefficient()
+	digits := new(big.Int).Abs(c).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if c.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON returns the decimal as a JSON number with its exact digits.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
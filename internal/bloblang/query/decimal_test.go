@@ -0,0 +1,168 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimalParse(t *testing.T) {
+	tests := map[string]struct {
+		input  interface{}
+		output string
+		err    string
+	}{
+		"integer string":      {input: "123", output: "123"},
+		"negative string":     {input: "-0.05", output: "-0.05"},
+		"positive string":     {input: "+1.50", output: "1.50"},
+		"leading point":       {input: ".5", output: "0.5"},
+		"exponent":            {input: "1.5e3", output: "1500"},
+		"negative exponent":   {input: "15e-4", output: "0.0015"},
+		"large":               {input: "123456789012345678901234567890.123456789", output: "123456789012345678901234567890.123456789"},
+		"json number":         {input: json.Number("19.990"), output: "19.990"},
+		"float":               {input: 0.1, output: "0.1"},
+		"int":                 {input: int64(-42), output: "-42"},
+		"uint":                {input: uint64(18446744073709551615), output: "18446744073709551615"},
+		"bytes":               {input: []byte("7.25"), output: "7.25"},
+		"not a number":        {input: "nope", err: "invalid decimal: nope"},
+		"empty":               {input: "", err: "empty string is not a valid decimal"},
+		"bad exponent":        {input: "1e", err: "invalid decimal: 1e"},
+		"misplaced sign":      {input: "1-2", err: "invalid decimal: 1-2"},
+		"unsupported type":    {input: true, err: "expected number or string value, got bool (true)"},
+		"multiple points":     {input: "1.2.3", err: "invalid decimal: 1.2.3"},
+		"sign without digits": {input: "-", err: "invalid decimal: -"},
+	}
+
+	for name, test := range tests {
+		d, err := IToDecimal(test.input)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		assert.Equal(t, test.output, d.String(), name)
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	dec := func(s string) Function {
+		d, err := ParseDecimal(s)
+		require.NoError(t, err)
+		return NewLiteralFunction("", d)
+	}
+	lit := func(v interface{}) Function {
+		return NewLiteralFunction("", v)
+	}
+
+	tests := map[string]struct {
+		left   Function
+		op     ArithmeticOperator
+		right  Function
+		output interface{}
+		err    string
+	}{
+		"add":                 {left: dec("0.1"), op: ArithmeticAdd, right: dec("0.2"), output: "0.3"},
+		"add float":           {left: lit(0.1), op: ArithmeticAdd, right: dec("0.2"), output: "0.3"},
+		"add json number":     {left: dec("19.99"), op: ArithmeticAdd, right: lit(json.Number("0.01")), output: "20.00"},
+		"sub":                 {left: dec("1.00"), op: ArithmeticSub, right: lit(int64(3)), output: "-2.00"},
+		"mul":                 {left: dec("19.99"), op: ArithmeticMul, right: dec("0.2"), output: "3.998"},
+		"div":                 {left: dec("10"), op: ArithmeticDiv, right: dec("4"), output: "2.5"},
+		"div scale":           {left: dec("10.00"), op: ArithmeticDiv, right: dec("4"), output: "2.50"},
+		"div recurring":       {left: dec("1"), op: ArithmeticDiv, right: dec("3"), output: "0.3333333333333333"},
+		"div rounds even":     {left: dec("2"), op: ArithmeticDiv, right: dec("3"), output: "0.6666666666666667"},
+		"div small":           {left: dec("1"), op: ArithmeticDiv, right: dec("0.0000000000000000001"), output: "10000000000000000000.0000000000000000000"},
+		"div by zero":         {left: dec("1"), op: ArithmeticDiv, right: lit(int64(0)), err: "number literal: attempted to divide by zero"},
+		"mod":                 {left: dec("-7.5"), op: ArithmeticMod, right: dec("2"), output: "-1.5"},
+		"add string":          {left: dec("1"), op: ArithmeticAdd, right: lit("2"), err: "cannot add types number (from number literal) and string (from string literal)"},
+		"compare equal":       {left: dec("1.50"), op: ArithmeticEq, right: lit(1.5), output: true},
+		"compare not equal":   {left: dec("0.3"), op: ArithmeticNeq, right: dec("0.30"), output: false},
+		"compare greater":     {left: lit(int64(2)), op: ArithmeticGt, right: dec("1.99"), output: true},
+		"compare less equal":  {left: dec("100000000000000000001"), op: ArithmeticLte, right: dec("100000000000000000000"), output: false},
+		"compare string":      {left: dec("1"), op: ArithmeticNeq, right: lit("1"), output: true},
+		"compare string eq":   {left: dec("1"), op: ArithmeticEq, right: lit("1"), err: "cannot compare types number (from number literal) and string (from string literal)"},
+		"float precision mul": {left: lit(1.1), op: ArithmeticMul, right: dec("3"), output: "3.3"},
+	}
+
+	for name, test := range tests {
+		fn, err := NewArithmeticExpression([]Function{test.left, test.right}, []ArithmeticOperator{test.op})
+		if err == nil {
+			var res interface{}
+			if res, err = fn.Exec(FunctionContext{}); err == nil {
+				if d, ok := res.(Decimal); ok {
+					res = d.String()
+				}
+				assert.Equal(t, test.output, res, name)
+			}
+		}
+		if test.err != "" {
+			require.EqualError(t, err, test.err, name)
+		} else {
+			require.NoError(t, err, name)
+		}
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		input  string
+		places int32
+		mode   string
+		output string
+	}{
+		{input: "2.345", places: 2, mode: RoundHalfEven, output: "2.34"},
+		{input: "2.355", places: 2, mode: RoundHalfEven, output: "2.36"},
+		{input: "2.3451", places: 2, mode: RoundHalfEven, output: "2.35"},
+		{input: "-2.5", places: 0, mode: RoundHalfEven, output: "-2"},
+		{input: "2.5", places: 0, mode: RoundHalfUp, output: "3"},
+		{input: "-2.5", places: 0, mode: RoundHalfUp, output: "-3"},
+		{input: "2.5", places: 0, mode: RoundHalfDown, output: "2"},
+		{input: "2.51", places: 0, mode: RoundHalfDown, output: "3"},
+		{input: "2.01", places: 0, mode: RoundUp, output: "3"},
+		{input: "-2.01", places: 0, mode: RoundUp, output: "-3"},
+		{input: "-2.99", places: 0, mode: RoundDown, output: "-2"},
+		{input: "-2.01", places: 0, mode: RoundCeiling, output: "-2"},
+		{input: "2.01", places: 0, mode: RoundCeiling, output: "3"},
+		{input: "-2.01", places: 0, mode: RoundFloor, output: "-3"},
+		{input: "0.005", places: 2, mode: RoundHalfUp, output: "0.01"},
+		{input: "1.5", places: 3, mode: RoundHalfEven, output: "1.500"},
+		{input: "7", places: 2, mode: RoundHalfEven, output: "7.00"},
+	}
+
+	for _, test := range tests {
+		d, err := ParseDecimal(test.input)
+		require.NoError(t, err)
+		assert.Equal(t, test.output, d.Round(test.places, test.mode).String(), "%v %v %v", test.input, test.places, test.mode)
+	}
+}
+
+func TestDecimalMethods(t *testing.T) {
+	dec := func(s string) Decimal {
+		d, err := ParseDecimal(s)
+		require.NoError(t, err)
+		return d
+	}
+
+	assert.Equal(t, ValueNumber, ITypeOf(dec("1.5")))
+	assert.Equal(t, "1.50", IToString(dec("1.50")))
+
+	f, err := IGetNumber(dec("1.25"))
+	require.NoError(t, err)
+	assert.Equal(t, 1.25, f)
+
+	i, err := IGetInt(dec("-7.9"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-7), i)
+
+	b, err := IGetBool(dec("0.00"))
+	require.NoError(t, err)
+	assert.False(t, b)
+
+	jBytes, err := json.Marshal(map[string]interface{}{
+		"price": dec("19.990"),
+		"total": dec("123456789012345678901234567890.01"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"price":19.990,"total":123456789012345678901234567890.01}`, string(jBytes))
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -151,7 +152,7 @@ func NewLiteralFunction(annotation string, v interface{}) *Literal {
 var _ = registerSimpleFunction(
 	NewFunctionSpec(
 		FunctionCategoryMessage, "batch_index",
-		"Returns the index of the mapped message within a batch. This is useful for applying maps only on certain messages of a batch.",
+		"Returns the index of the mapped message within a batch. This is useful for applying maps only on certain messages of a batch. Components that handle the messages of a batch individually, such as the `filter_parts` processor or the `http` processor with `parallel` enabled, still provide the index within the entire batch.",
 		NewExampleSpec("",
 			`root = if batch_index() > 0 { deleted() }`,
 		),
@@ -166,7 +167,7 @@ var _ = registerSimpleFunction(
 var _ = registerSimpleFunction(
 	NewFunctionSpec(
 		FunctionCategoryMessage, "batch_size",
-		"Returns the size of the message batch.",
+		"Returns the size of the message batch, which is the size of the entire batch even within components that handle the messages of a batch individually.",
 		NewExampleSpec("",
 			`root.foo = batch_size()`,
 		),
@@ -306,6 +307,49 @@ var _ = registerSimpleFunction(
 	},
 )
 
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_source",
+		"If an error has occurred during the processing of a message this function returns the label of the processor that flagged it, or its path within the config when it has no label. For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root.doc.failed_at = error_source()`,
+		),
+	),
+	func(ctx FunctionContext) (interface{}, error) {
+		return ctx.MsgBatch.Get(ctx.Index).Metadata().Get(types.FailSourceKey), nil
+	},
+)
+
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_info",
+		"If an error has occurred during the processing of a message this function returns an object describing it, otherwise `null` is returned. The object contains the fields `message`, `source` (the label or path of the processor that flagged the error), `class` (the type of error, which is empty for generic errors), `kind` (one of `timeout`, `http_4xx`, `http_5xx`, `validation` or `parse`, which is empty when the cause of the error is unknown), `attempts` (the number of times the processor was attempted) and `payload_hash` (a hash of the message payload before it was processed, which can be used to group duplicate failures). For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root = this
+root.meta.error = error_info()`,
+		),
+	),
+	func(ctx FunctionContext) (interface{}, error) {
+		meta := ctx.MsgBatch.Get(ctx.Index).Metadata()
+		fail := meta.Get(types.FailFlagKey)
+		if fail == "" {
+			return nil, nil
+		}
+		var attempts int64
+		if attemptsStr := meta.Get(types.FailAttemptsKey); attemptsStr != "" {
+			attempts, _ = strconv.ParseInt(attemptsStr, 10, 64)
+		}
+		return map[string]interface{}{
+			"message":      fail,
+			"source":       meta.Get(types.FailSourceKey),
+			"class":        meta.Get(types.FailClassKey),
+			"kind":         meta.Get(types.FailKindKey),
+			"attempts":     attempts,
+			"payload_hash": meta.Get(types.FailPayloadHashKey),
+		}, nil
+	},
+)
+
 //------------------------------------------------------------------------------
 
 var _ = RegisterFunction(
@@ -74,6 +74,43 @@ func TestFunctions(t *testing.T) {
 				},
 			},
 		},
+		"check error_info function": {
+			input: mustFunc("error_info"),
+			output: map[string]interface{}{
+				"message":      "nope",
+				"source":       "foo",
+				"class":        "",
+				"kind":         "timeout",
+				"attempts":     int64(2),
+				"payload_hash": "abc",
+			},
+			messages: []easyMsg{
+				{content: "bar", meta: map[string]string{
+					"benthos_processing_failed":              "nope",
+					"benthos_processing_failed_source":       "foo",
+					"benthos_processing_failed_kind":         "timeout",
+					"benthos_processing_failed_attempts":     "2",
+					"benthos_processing_failed_payload_hash": "abc",
+				}},
+			},
+		},
+		"check error_info function no error": {
+			input:  mustFunc("error_info"),
+			output: nil,
+			messages: []easyMsg{
+				{content: "bar"},
+			},
+		},
+		"check error_source function": {
+			input:  mustFunc("error_source"),
+			output: "foo",
+			messages: []easyMsg{
+				{content: "bar", meta: map[string]string{
+					"benthos_processing_failed":        "nope",
+					"benthos_processing_failed_source": "foo",
+				}},
+			},
+		},
 		"check var function error": {
 			input: mustFunc("var", "foo"),
 			vars:  map[string]interface{}{},
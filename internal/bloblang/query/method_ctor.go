@@ -131,6 +131,9 @@ func numberMethod(fn func(f *float64, i *int64, ui *uint64) (interface{}, error)
 			} else {
 				return nil, fmt.Errorf("failed to parse number: %v", err)
 			}
+		case Decimal:
+			df := t.Float64()
+			f = &df
 		default:
 			return nil, NewTypeError(v, ValueNumber)
 		}
@@ -263,3 +263,84 @@ var _ = registerSimpleMethod(
 	false,
 	ExpectNArgs(0),
 )
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"as_decimal",
+		"Converts a number or a string into an arbitrary-precision decimal. Arithmetic and comparisons involving a decimal and another number are performed with exact decimal precision, and the result of arithmetic is also a decimal. This avoids the rounding errors of floating point numbers, which makes decimals suitable for monetary values.",
+	).InCategory(
+		MethodCategoryNumbers,
+		"Decimals retain the number of decimal places of their inputs and are serialized as JSON numbers with their exact digits. Divisions retain at least 16 decimal places, rounded half to even.",
+		NewExampleSpec("",
+			`root.total = this.prices.fold(0.as_decimal(), item -> item.tally + item.value.as_decimal())`,
+			`{"prices":[0.1,0.2,0.3]}`,
+			`{"total":0.6}`,
+		),
+		NewExampleSpec("",
+			`root.tax = (this.amount.as_decimal() * 0.2).round_decimal(2)`,
+			`{"amount":"19.99"}`,
+			`{"tax":4.00}`,
+		),
+	).Beta(),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			return IToDecimal(v)
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"round_decimal",
+		"Rounds a number to a number of decimal places and returns it as a decimal. The rounding mode defaults to `half_even`, also known as banker's rounding, which rounds halves to the nearest even digit. Other modes are `half_up` and `half_down`, which round halves away from and towards zero respectively, `up` and `down`, which round away from and towards zero respectively, and `ceiling` and `floor`.",
+	).InCategory(
+		MethodCategoryNumbers,
+		"",
+		NewExampleSpec("",
+			`root.new_value = this.value.round_decimal(2)`,
+			`{"value":"2.345"}`,
+			`{"new_value":2.34}`,
+			`{"value":"2.355"}`,
+			`{"new_value":2.36}`,
+		),
+		NewExampleSpec("",
+			`root.new_value = this.value.round_decimal(0, "half_up")`,
+			`{"value":2.5}`,
+			`{"new_value":3}`,
+			`{"value":-2.5}`,
+			`{"new_value":-3}`,
+		),
+	).Beta(),
+	func(args ...interface{}) (simpleMethod, error) {
+		var places int32
+		if len(args) > 0 {
+			if places = int32(args[0].(int64)); places < 0 {
+				return nil, errors.New("the number of decimal places must not be negative")
+			}
+		}
+		mode := RoundHalfEven
+		if len(args) > 1 {
+			mode = args[1].(string)
+		}
+		switch mode {
+		case RoundHalfEven, RoundHalfUp, RoundHalfDown, RoundUp, RoundDown, RoundCeiling, RoundFloor:
+		default:
+			return nil, fmt.Errorf("unrecognised rounding mode: %v", mode)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			d, err := IToDecimal(v)
+			if err != nil {
+				return nil, err
+			}
+			return d.Round(places, mode), nil
+		}, nil
+	},
+	true,
+	ExpectBetweenNAndMArgs(0, 2),
+	ExpectIntArg(0),
+	ExpectStringArg(1),
+)
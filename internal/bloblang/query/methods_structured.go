@@ -1029,7 +1029,7 @@ var _ = registerMethod(
 func sortMethod(target Function, args ...interface{}) (Function, error) {
 	compareFn := func(ctx FunctionContext, values []interface{}, i, j int) (bool, error) {
 		switch values[i].(type) {
-		case float64, int, int64, uint64, json.Number:
+		case float64, int, int64, uint64, json.Number, Decimal:
 			lhs, err := IGetNumber(values[i])
 			if err != nil {
 				return false, fmt.Errorf("sort element %v: %w", i, err)
@@ -1140,7 +1140,7 @@ func sortByMethod(target Function, args ...interface{}) (Function, error) {
 		}
 
 		switch leftValue.(type) {
-		case float64, int, int64, uint64, json.Number:
+		case float64, int, int64, uint64, json.Number, Decimal:
 			lhs, err := IGetNumber(leftValue)
 			if err != nil {
 				return false, fmt.Errorf("sort_by element %v: %w", i, ErrFrom(err, mapFn))
@@ -1321,7 +1321,7 @@ func sumMethod(target Function, _ ...interface{}) (Function, error) {
 			return nil, err
 		}
 		switch t := ISanitize(v).(type) {
-		case float64, int64, uint64, json.Number:
+		case float64, int64, uint64, json.Number, Decimal:
 			return v, nil
 		case []interface{}:
 			var total float64
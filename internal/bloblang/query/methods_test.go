@@ -1,9 +1,12 @@
 package query
 
 import (
+	"bytes"
 	"encoding/json"
+	"math"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/gabs/v2"
@@ -116,6 +119,120 @@ func TestMethods(t *testing.T) {
 			),
 			err: "string literal: record on line 2: wrong number of fields",
 		},
+		"check stem string": {
+			input: methods(
+				literalFn("Running"),
+				method("stem"),
+			),
+			output: "run",
+		},
+		"check stem array error": {
+			input: methods(
+				jsonFn(`["running",5]`),
+				method("stem"),
+			),
+			err: "array literal: index 1: expected string value, got number (5)",
+		},
+		"check remove stopwords mixed case": {
+			input: methods(
+				jsonFn(`["Der","Hund","und","die","Katze"]`),
+				method("remove_stopwords", "de"),
+			),
+			output: []interface{}{"Hund", "Katze"},
+		},
+		"check remove stopwords not array": {
+			input: methods(
+				literalFn("the cat"),
+				method("remove_stopwords"),
+			),
+			err: "expected array value, got string from string literal (\"the cat\")",
+		},
+		"check tokenize ideographs": {
+			input: methods(
+				literalFn("東京 tower"),
+				method("tokenize"),
+			),
+			output: []interface{}{"東", "京", "tower"},
+		},
+		"check parse user agent http client": {
+			input: methods(
+				literalFn("curl/7.68.0"),
+				method("parse_user_agent"),
+			),
+			output: map[string]interface{}{
+				"browser": map[string]interface{}{
+					"family":  "curl",
+					"version": "7.68.0",
+					"major":   "7",
+					"minor":   "68",
+					"patch":   "0",
+				},
+				"os":     map[string]interface{}{"family": "Other"},
+				"device": map[string]interface{}{"family": "Other", "type": "other"},
+			},
+		},
+		"check parse user agent not string": {
+			input: methods(
+				jsonFn(`{"ua":"curl/7.68.0"}`),
+				method("parse_user_agent"),
+			),
+			err: "expected string value, got object from object literal",
+		},
+		"check parse url ipv6": {
+			input: methods(
+				literalFn("http://[::1]:4195/ready"),
+				method("parse_url"),
+			),
+			output: map[string]interface{}{
+				"scheme": "http",
+				"host":   "::1",
+				"port":   int64(4195),
+				"path":   "/ready",
+				"query":  map[string]interface{}{},
+			},
+		},
+		"check parse url invalid": {
+			input: methods(
+				literalFn("http://foo:bar:baz/"),
+				method("parse_url"),
+			),
+			err: `string literal: parse "http://foo:bar:baz/": invalid port ":baz" after host`,
+		},
+		"check format url ipv6": {
+			input: methods(
+				jsonFn(`{"scheme":"http","host":"::1","path":"/ready","username":"foo"}`),
+				method("format_url"),
+			),
+			output: "http://foo@[::1]/ready",
+		},
+		"check format url string port": {
+			input: methods(
+				jsonFn(`{"scheme":"http","host":"localhost","port":"4195","query":{"a":[true,5],"b":null}}`),
+				method("format_url"),
+			),
+			output: "http://localhost:4195?a=true&a=5&b=null",
+		},
+		"check format url bad host": {
+			input: methods(
+				jsonFn(`{"host":5}`),
+				method("format_url"),
+			),
+			err: "object literal: field host: expected string value, got number (5)",
+		},
+		"check normalize url": {
+			input: methods(
+				literalFn("HTTP://Example.COM:8080/Foo?b=2&utm_source=x&a=1&a=0#Bar"),
+				method("normalize_url", "utm_*"),
+			),
+			output: "http://example.com:8080/Foo?a=1&a=0&b=2#Bar",
+		},
+		"check normalize url remove all params": {
+			input: methods(
+				literalFn("https://example.com/?fbclid=abc"),
+				method("normalize_url", "fbclid"),
+			),
+			output: "https://example.com/",
+		},
 		"check explode 1": {
 			input: methods(
 				jsonFn(`{"foo":[1,2,3],"id":"bar"}`),
@@ -741,6 +858,13 @@ func TestMethods(t *testing.T) {
 			),
 			output: `5eb63bbbe01eeed093cb22bb8f5acdc3`,
 		},
+		"check fingerprint md5": {
+			input: methods(
+				jsonFn(`{"b":{"d":true,"c":null},"a":[1.0,15e-1,"x\u00e9\n"]}`),
+				method("fingerprint", "md5"),
+			),
+			output: `6738c6e249fb3d13e1e54049b9de2b1b`,
+		},
 		"check hex encode": {
 			input: methods(
 				literalFn("hello world"),
@@ -2043,6 +2167,33 @@ func TestMethods(t *testing.T) {
 	}
 }
 
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		input  interface{}
+		output string
+	}{
+		{input: map[string]interface{}{"b": 1, "a": []interface{}{"x", nil}}, output: `{"a":["x",null],"b":1}`},
+		{input: json.Number("10.0"), output: `10`},
+		{input: json.Number("18446744073709551615"), output: `18446744073709551615`},
+		{input: float64(-9007199254740993), output: `-9007199254740992`},
+		{input: 0.000001, output: `0.000001`},
+		{input: 1e-7, output: `1e-07`},
+		{input: 1e21, output: `1e+21`},
+		{input: math.Copysign(0, -1), output: `0`},
+		{input: "<a href=\"\">\u2028\x01", output: `"<a href=\"\">` + "\u2028" + `\u0001"`},
+		{input: []byte("foo"), output: `"foo"`},
+		{input: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), output: `"2021-01-02T03:04:05Z"`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		require.NoError(t, writeCanonicalJSON(&buf, test.input))
+		assert.Equal(t, test.output, buf.String())
+	}
+
+	var buf bytes.Buffer
+	assert.EqualError(t, writeCanonicalJSON(&buf, map[string]interface{}{"a": math.NaN()}), "cannot serialize number NaN as JSON")
+}
+
 func TestMethodTargets(t *testing.T) {
 	function := func(name string, args ...interface{}) Function {
 		t.Helper()
@@ -49,6 +49,30 @@ type MessageBatch interface {
 	Len() int
 }
 
+// LockedBatch is implemented by message batches that restrict access to a
+// single message of a larger batch, such as those given to components that
+// process the messages of a batch individually.
+type LockedBatch interface {
+	// LockedBatch returns the larger batch and the index of the message
+	// within it.
+	LockedBatch() (types.Message, int)
+}
+
+// UnlockBatch returns the larger batch and index of a message when the batch
+// is a LockedBatch, or otherwise returns the batch and index unchanged. This
+// allows functions that access other messages of a batch, such as
+// batch_index and json_from_part, to behave the same regardless of whether a
+// component processes the messages of a batch individually.
+func UnlockBatch(msg MessageBatch, index int) (MessageBatch, int) {
+	for {
+		locked, ok := msg.(LockedBatch)
+		if !ok || (index != 0 && index != -1) {
+			return msg, index
+		}
+		msg, index = locked.LockedBatch()
+	}
+}
+
 // FunctionContext provides access to a range of query targets for functions to
 // reference.
 type FunctionContext struct {
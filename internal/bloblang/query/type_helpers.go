@@ -50,7 +50,7 @@ func ITypeOf(i interface{}) ValueType {
 		return ValueString
 	case []byte:
 		return ValueBytes
-	case int, int64, uint64, float64, json.Number:
+	case int, int64, uint64, float64, json.Number, Decimal:
 		return ValueNumber
 	case bool:
 		return ValueBool
@@ -92,6 +92,8 @@ func IGetNumber(v interface{}) (float64, error) {
 		return t, nil
 	case json.Number:
 		return t.Float64()
+	case Decimal:
+		return t.Float64(), nil
 	}
 	return 0, NewTypeError(v, ValueNumber)
 }
@@ -117,6 +119,8 @@ func IGetInt(v interface{}) (int64, error) {
 			return int64(f), nil
 		}
 		return 0, err
+	case Decimal:
+		return t.Int64(), nil
 	}
 	return 0, NewTypeError(v, ValueNumber)
 }
@@ -136,6 +140,8 @@ func IGetBool(v interface{}) (bool, error) {
 		return t != 0, nil
 	case json.Number:
 		return t.String() != "0", nil
+	case Decimal:
+		return t.Sign() != 0, nil
 	}
 	return false, NewTypeError(v, ValueBool)
 }
@@ -210,10 +216,10 @@ func IIsNull(i interface{}) bool {
 
 // ISanitize takes a boxed value of any type and attempts to convert it into one
 // of the following types: string, []byte, int64, uint64, float64, bool,
-// []interface{}, map[string]interface{}, Delete, Nothing.
+// []interface{}, map[string]interface{}, Delete, Nothing, Decimal.
 func ISanitize(i interface{}) interface{} {
 	switch t := i.(type) {
-	case string, []byte, int64, uint64, float64, bool, []interface{}, map[string]interface{}, Delete, Nothing:
+	case string, []byte, int64, uint64, float64, bool, []interface{}, map[string]interface{}, Delete, Nothing, Decimal:
 		return i
 	case json.RawMessage:
 		return []byte(t)
@@ -252,6 +258,8 @@ func IToBytes(i interface{}) []byte {
 		return t
 	case json.Number:
 		return []byte(t.String())
+	case Decimal:
+		return []byte(t.String())
 	case int64, uint64, float64:
 		return []byte(fmt.Sprintf("%v", t)) // TODO
 	case bool:
@@ -278,6 +286,8 @@ func IToString(i interface{}) string {
 		return fmt.Sprintf("%v", t) // TODO
 	case json.Number:
 		return t.String()
+	case Decimal:
+		return t.String()
 	case bool:
 		if t {
 			return "true"
@@ -304,6 +314,8 @@ func IToNumber(v interface{}) (float64, error) {
 		return t, nil
 	case json.Number:
 		return t.Float64()
+	case Decimal:
+		return t.Float64(), nil
 	case []byte:
 		return strconv.ParseFloat(string(t), 64)
 	case string:
@@ -332,6 +344,8 @@ func IToInt(v interface{}) (int64, error) {
 		return int64(t), nil
 	case json.Number:
 		return t.Int64()
+	case Decimal:
+		return t.Int64(), nil
 	case []byte:
 		return strconv.ParseInt(string(t), 10, 64)
 	case string:
@@ -356,6 +370,8 @@ func IToBool(v interface{}) (bool, error) {
 		return t != 0, nil
 	case json.Number:
 		return t.String() != "0", nil
+	case Decimal:
+		return t.Sign() != 0, nil
 	case []byte:
 		if v, err := strconv.ParseBool(string(t)); err == nil {
 			return v, nil
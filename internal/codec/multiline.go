@@ -0,0 +1,263 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// MultilineDocs is a static field documentation for joining the messages of
+// input codecs that span multiple lines.
+var MultilineDocs = docs.FieldAdvanced(
+	"multiline", "Join messages consumed by the codec that span multiple lines, such as stack traces, into single messages. A message begins with each line that matches the `start_pattern`, and all following lines that don't match it are joined to it with line breaks.",
+).WithChildren(
+	docs.FieldCommon("start_pattern", "A regular expression that matches the first line of each message, when empty lines are not joined.", `^\d{4}-\d{2}-\d{2}`, `^\S`),
+	docs.FieldAdvanced("max_lines", "The maximum number of lines to join into a single message, after which a new message is started."),
+	docs.FieldAdvanced("timeout", "The maximum period of time to wait for a further line before a message is emitted with the lines joined so far."),
+)
+
+// MultilineConfig contains configuration fields for joining messages that span
+// multiple lines.
+type MultilineConfig struct {
+	StartPattern string `json:"start_pattern" yaml:"start_pattern"`
+	MaxLines     int    `json:"max_lines" yaml:"max_lines"`
+	Timeout      string `json:"timeout" yaml:"timeout"`
+}
+
+// NewMultilineConfig creates a multiline configuration with default values.
+func NewMultilineConfig() MultilineConfig {
+	return MultilineConfig{
+		StartPattern: "",
+		MaxLines:     500,
+		Timeout:      "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// multilineAck calls the ack func of a child reader once all messages that
+// contain its parts have been acknowledged.
+type multilineAck struct {
+	mut       sync.Mutex
+	fn        ReaderAckFn
+	remaining int
+	err       error
+}
+
+func (m *multilineAck) ack(ctx context.Context, err error) error {
+	m.mut.Lock()
+	m.remaining--
+	if err != nil && m.err == nil {
+		m.err = err
+	}
+	done, ackErr := m.remaining == 0, m.err
+	m.mut.Unlock()
+
+	if done {
+		return m.fn(ctx, ackErr)
+	}
+	return nil
+}
+
+type multilineResult struct {
+	parts []types.Part
+	ack   ReaderAckFn
+	err   error
+}
+
+type multilineReader struct {
+	child    Reader
+	start    *regexp.Regexp
+	maxLines int
+	timeout  time.Duration
+
+	ctx      context.Context
+	cancel   func()
+	results  chan multilineResult
+	loopDone chan struct{}
+
+	// The child may only be closed once the loop has stopped reading from it,
+	// when a close gives up waiting on the loop it is instead left to the loop
+	// to close the child once it exits.
+	closeMut   sync.Mutex
+	loopExited bool
+	abandoned  bool
+
+	pending     []types.Part
+	pendingAcks []*multilineAck
+	deadline    time.Time
+	ready       []multilineResult
+	err         error
+}
+
+type multilineParams struct {
+	start    *regexp.Regexp
+	maxLines int
+	timeout  time.Duration
+}
+
+func parseMultilineConfig(conf MultilineConfig) (multilineParams, error) {
+	var p multilineParams
+	var err error
+	if p.start, err = regexp.Compile(conf.StartPattern); err != nil {
+		return p, fmt.Errorf("failed to compile multiline start pattern: %w", err)
+	}
+	if p.maxLines = conf.MaxLines; p.maxLines <= 0 {
+		return p, fmt.Errorf("multiline max lines must be greater than zero, got: %v", conf.MaxLines)
+	}
+	if p.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+		return p, fmt.Errorf("failed to parse multiline timeout: %w", err)
+	}
+	return p, nil
+}
+
+func newMultilineReader(params multilineParams, r Reader) Reader {
+	m := &multilineReader{
+		child:    r,
+		start:    params.start,
+		maxLines: params.maxLines,
+		timeout:  params.timeout,
+		results:  make(chan multilineResult),
+		loopDone: make(chan struct{}),
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	go m.loop()
+	return m
+}
+
+// loop reads from the child in the background so that lines can be flushed
+// when no further lines arrive within the timeout.
+func (m *multilineReader) loop() {
+	defer func() {
+		m.closeMut.Lock()
+		m.loopExited = true
+		if m.abandoned {
+			_ = m.child.Close(context.Background())
+		}
+		m.closeMut.Unlock()
+		close(m.loopDone)
+	}()
+	for {
+		parts, ack, err := m.child.Next(m.ctx)
+		select {
+		case m.results <- multilineResult{parts: parts, ack: ack, err: err}:
+		case <-m.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (m *multilineReader) flush() {
+	var buf bytes.Buffer
+	for i, p := range m.pending {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(p.Get())
+	}
+	joined := m.pending[0].Copy()
+	joined.Set(buf.Bytes())
+
+	acks := m.pendingAcks
+	m.pending, m.pendingAcks = nil, nil
+
+	m.ready = append(m.ready, multilineResult{
+		parts: []types.Part{joined},
+		ack: func(ctx context.Context, err error) error {
+			for _, a := range acks {
+				_ = a.ack(ctx, err)
+			}
+			return nil
+		},
+	})
+}
+
+func (m *multilineReader) add(ctx context.Context, res multilineResult) {
+	if len(res.parts) == 0 {
+		_ = res.ack(ctx, nil)
+		return
+	}
+	// The timeout applies to the wait for each further line, and so the
+	// deadline is extended whenever lines are appended.
+	m.deadline = time.Now().Add(m.timeout)
+	shared := &multilineAck{fn: res.ack}
+	for _, p := range res.parts {
+		if len(m.pending) > 0 && (len(m.pending) >= m.maxLines || m.start.Match(p.Get())) {
+			m.flush()
+		}
+		m.pending = append(m.pending, p)
+		if n := len(m.pendingAcks); n == 0 || m.pendingAcks[n-1] != shared {
+			shared.remaining++
+			m.pendingAcks = append(m.pendingAcks, shared)
+		}
+	}
+}
+
+func (m *multilineReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	for {
+		if len(m.ready) > 0 {
+			res := m.ready[0]
+			m.ready = m.ready[1:]
+			return res.parts, res.ack, nil
+		}
+		if m.err != nil {
+			if len(m.pending) > 0 {
+				m.flush()
+				continue
+			}
+			return nil, nil, m.err
+		}
+
+		var timer *time.Timer
+		var timeoutC <-chan time.Time
+		if len(m.pending) > 0 {
+			timer = time.NewTimer(time.Until(m.deadline))
+			timeoutC = timer.C
+		}
+
+		select {
+		case res := <-m.results:
+			if res.err != nil {
+				m.err = res.err
+			} else {
+				m.add(ctx, res)
+			}
+		case <-timeoutC:
+			m.flush()
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, nil, ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (m *multilineReader) Close(ctx context.Context) error {
+	m.cancel()
+	select {
+	case <-m.loopDone:
+		return m.child.Close(ctx)
+	case <-ctx.Done():
+	}
+
+	m.closeMut.Lock()
+	defer m.closeMut.Unlock()
+	if m.loopExited {
+		return m.child.Close(ctx)
+	}
+	m.abandoned = true
+	return ctx.Err()
+}
@@ -0,0 +1,248 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultilineReader(t *testing.T) {
+	data := []byte(`2021-01-01 INFO started
+2021-01-01 ERROR failed
+java.lang.NullPointerException
+	at foo.Bar(Bar.java:12)
+	at foo.Baz(Baz.java:34)
+2021-01-02 INFO done
+  trailing`)
+
+	conf := NewReaderConfig()
+	conf.Multiline.StartPattern = `^\d{4}-`
+	conf.Multiline.MaxLines = 3
+	ctor, err := GetReader("lines", conf)
+	require.NoError(t, err)
+
+	ack := errors.New("default err")
+	r, err := ctor("", noopCloser{bytes.NewReader(data), false}, func(ctx context.Context, err error) error {
+		ack = err
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	var acks []ReaderAckFn
+	for _, exp := range []string{
+		"2021-01-01 INFO started",
+		"2021-01-01 ERROR failed\njava.lang.NullPointerException\n\tat foo.Bar(Bar.java:12)",
+		"\tat foo.Baz(Baz.java:34)",
+		"2021-01-02 INFO done\n  trailing",
+	} {
+		p, ackFn, err := r.Next(ctx)
+		require.NoError(t, err)
+		require.Len(t, p, 1)
+		assert.Equal(t, exp, string(p[0].Get()))
+		acks = append(acks, ackFn)
+	}
+
+	_, _, err = r.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	for _, fn := range acks[1:] {
+		require.NoError(t, fn(ctx, nil))
+	}
+	assert.EqualError(t, ack, "default err")
+
+	require.NoError(t, acks[0](ctx, nil))
+	assert.NoError(t, ack)
+	assert.NoError(t, r.Close(ctx))
+}
+
+func TestMultilineReaderTimeout(t *testing.T) {
+	conf := NewReaderConfig()
+	conf.Multiline.StartPattern = `^\S`
+	conf.Multiline.Timeout = "200ms"
+	ctor, err := GetReader("lines", conf)
+	require.NoError(t, err)
+
+	pr, pw := io.Pipe()
+	r, err := ctor("", pr, func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	go func() {
+		pw.Write([]byte("panic: oh no\n\tgoroutine 1\n"))
+	}()
+
+	p, _, err := r.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, p, 1)
+	assert.Equal(t, "panic: oh no\n\tgoroutine 1", string(p[0].Get()))
+
+	// Reads that time out keep the lines joined so far.
+	go func() {
+		pw.Write([]byte("first\n"))
+	}()
+	shortCtx, shortDone := context.WithTimeout(ctx, time.Millisecond*10)
+	_, _, err = r.Next(shortCtx)
+	shortDone()
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	go func() {
+		pw.Write([]byte("  second\n"))
+	}()
+	p, _, err = r.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, p, 1)
+	assert.Equal(t, "first\n  second", string(p[0].Get()))
+
+	pw.Close()
+	_, _, err = r.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, r.Close(ctx))
+}
+
+func TestMultilineReaderTimeoutExtended(t *testing.T) {
+	conf := NewReaderConfig()
+	conf.Multiline.StartPattern = `^\S`
+	conf.Multiline.Timeout = "200ms"
+	ctor, err := GetReader("lines", conf)
+	require.NoError(t, err)
+
+	pr, pw := io.Pipe()
+	r, err := ctor("", pr, func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	// Lines arrive within the timeout of the line before them, but the entry
+	// as a whole takes longer than the timeout.
+	go func() {
+		pw.Write([]byte("panic: oh no\n"))
+		for i := 0; i < 3; i++ {
+			<-time.After(time.Millisecond * 100)
+			pw.Write([]byte("\tgoroutine 1\n"))
+		}
+	}()
+
+	p, _, err := r.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, p, 1)
+	assert.Equal(t, "panic: oh no\n\tgoroutine 1\n\tgoroutine 1\n\tgoroutine 1", string(p[0].Get()))
+
+	pw.Close()
+	_, _, err = r.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, r.Close(ctx))
+}
+
+type blockingReader struct {
+	t       *testing.T
+	release chan struct{}
+
+	mut      sync.Mutex
+	inFlight bool
+	closed   bool
+}
+
+func (b *blockingReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	b.mut.Lock()
+	b.inFlight = true
+	b.mut.Unlock()
+
+	err := ctx.Err()
+	if b.release != nil {
+		<-b.release
+	} else {
+		<-ctx.Done()
+		err = ctx.Err()
+	}
+
+	// Simulate a read that takes a while to return after being cancelled.
+	<-time.After(time.Millisecond * 10)
+
+	b.mut.Lock()
+	b.inFlight = false
+	b.mut.Unlock()
+	return nil, nil, err
+}
+
+func (b *blockingReader) Close(ctx context.Context) error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if b.inFlight {
+		b.t.Error("child closed during a read")
+	}
+	b.closed = true
+	return nil
+}
+
+func (b *blockingReader) isClosed() bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.closed
+}
+
+func TestMultilineReaderCloseDuringRead(t *testing.T) {
+	params, err := parseMultilineConfig(NewMultilineConfig())
+	require.NoError(t, err)
+
+	child := &blockingReader{t: t}
+	r := newMultilineReader(params, child)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	// Give the loop a chance to begin reading from the child.
+	<-time.After(time.Millisecond * 10)
+
+	require.NoError(t, r.Close(ctx))
+	assert.True(t, child.isClosed())
+}
+
+func TestMultilineReaderCloseAbandoned(t *testing.T) {
+	params, err := parseMultilineConfig(NewMultilineConfig())
+	require.NoError(t, err)
+
+	child := &blockingReader{t: t, release: make(chan struct{})}
+	r := newMultilineReader(params, child)
+
+	<-time.After(time.Millisecond * 10)
+
+	// The child ignores cancellation and so the close gives up waiting.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer done()
+	assert.Equal(t, context.DeadlineExceeded, r.Close(ctx))
+	assert.False(t, child.isClosed())
+
+	// Once the read returns the child is closed by the loop.
+	close(child.release)
+	assert.Eventually(t, child.isClosed, time.Second, time.Millisecond*10)
+}
+
+func TestMultilineReaderBadConfig(t *testing.T) {
+	conf := NewReaderConfig()
+	conf.Multiline.StartPattern = `^(`
+	_, err := GetReader("lines", conf)
+	assert.Contains(t, err.Error(), "failed to compile multiline start pattern")
+
+	conf.Multiline.StartPattern = `^\S`
+	conf.Multiline.MaxLines = 0
+	_, err = GetReader("lines", conf)
+	assert.EqualError(t, err, "multiline max lines must be greater than zero, got: 0")
+}
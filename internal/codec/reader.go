@@ -17,6 +17,7 @@ import (
 	"sync"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/parquet"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
@@ -25,7 +26,7 @@ import (
 var ReaderDocs = docs.FieldCommon(
 	"codec", "The way in which the bytes of a data source should be converted into discrete messages, codecs are useful for specifying how large files or contiunous streams of data might be processed in small chunks rather than loading it all in memory. It's possible to consume lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter. Codecs can be chained with `/`, for example a gzip compressed CSV file can be consumed with the codec `gzip/csv`.", "lines", "delim:\t", "delim:foobar", "gzip/csv",
 ).HasAnnotatedOptions(
-	"auto", "EXPERIMENTAL: Attempts to derive a codec for each file based on information such as the extension. For example, a .tar.gz file would be consumed with the `gzip/tar` codec. Defaults to all-bytes.",
+	"auto", "EXPERIMENTAL: Attempts to derive a codec for each file based on information such as the extension. For example, a .tar.gz file would be consumed with the `gzip/tar` codec, and a .jsonl.gz file with the `gzip/lines` codec. Files with the extensions .csv, .tar, .tgz, .parquet, .jsonl, .ndjson, .log and .txt are recognised, optionally followed by .gz or .gzip. Defaults to all-bytes.",
 	"all-bytes", "Consume the entire file as a single binary message.",
 	"chunker:x", "Consume the file in chunks of a given number of bytes.",
 	"csv", "Consume structured rows as comma separated values, the first row must be a header row.",
@@ -33,6 +34,7 @@ var ReaderDocs = docs.FieldCommon(
 	"gzip", "Decompress a gzip file, this codec should precede another codec, e.g. `gzip/all-bytes`, `gzip/tar`, `gzip/csv`, etc.",
 	"lines", "Consume the file in segments divided by linebreaks.",
 	"multipart", "Consumes the output of another codec and batches messages together. A batch ends when an empty message is consumed. For example, the codec `lines/multipart` could be used to consume multipart messages where an empty line indicates the end of each batch.",
+	"parquet", "EXPERIMENTAL: Parse the file as a Parquet file, and consume each row as a JSON document. The entire file is read into memory before rows are consumed.",
 	"tar", "Parse the file as a tar archive, and consume each file of the archive as a message.",
 )
 
@@ -41,12 +43,14 @@ var ReaderDocs = docs.FieldCommon(
 // ReaderConfig is a general configuration struct that covers all reader codecs.
 type ReaderConfig struct {
 	MaxScanTokenSize int
+	Multiline        MultilineConfig
 }
 
 // NewReaderConfig creates a reader configuration with default values.
 func NewReaderConfig() ReaderConfig {
 	return ReaderConfig{
 		MaxScanTokenSize: bufio.MaxScanTokenSize,
+		Multiline:        NewMultilineConfig(),
 	}
 }
 
@@ -217,6 +221,10 @@ func partReader(codec string, conf ReaderConfig) (ReaderConstructor, bool, error
 		}, true, nil
 	case "tar":
 		return newTarReader, true, nil
+	case "parquet":
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newParquetReader(r, fn)
+		}, true, nil
 	}
 	if strings.HasPrefix(codec, "delim:") {
 		by := strings.TrimPrefix(codec, "delim:")
@@ -252,32 +260,55 @@ func convertDeprecatedCodec(codec string) string {
 // GetReader returns a constructor that creates reader codecs.
 func GetReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
 	codec = convertDeprecatedCodec(codec)
+	if conf.Multiline.StartPattern != "" {
+		params, err := parseMultilineConfig(conf.Multiline)
+		if err != nil {
+			return nil, err
+		}
+		conf.Multiline.StartPattern = ""
+		ctor, err := GetReader(codec, conf)
+		if err != nil {
+			return nil, err
+		}
+		return chainPartIntoReaderCtor(ctor, func(_ string, r Reader) (Reader, error) {
+			return newMultilineReader(params, r), nil
+		}), nil
+	}
 	if codec == "auto" {
 		return autoCodec(conf), nil
 	}
 	return chainedReader(codec, conf)
 }
 
-func autoCodec(conf ReaderConfig) ReaderConstructor {
-	return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
-		codec := "all-bytes"
-		switch filepath.Ext(path) {
-		case ".csv":
-			codec = "csv"
-		case ".csv.gz", ".csv.gzip":
-			codec = "gzip/csv"
-		case ".tar":
-			codec = "tar"
-		case ".tgz":
-			codec = "gzip/tar"
-		}
-		if strings.HasSuffix(path, ".tar.gzip") {
-			codec = "gzip/tar"
-		} else if strings.HasSuffix(path, ".tar.gz") {
-			codec = "gzip/tar"
+// autoCodecName derives the name of a codec from the extension of a path.
+func autoCodecName(path string) string {
+	var prefix string
+	for _, ext := range []string{".gz", ".gzip"} {
+		if strings.HasSuffix(path, ext) {
+			prefix, path = "gzip/", strings.TrimSuffix(path, ext)
+			break
 		}
+	}
 
-		ctor, err := GetReader(codec, conf)
+	codec := "all-bytes"
+	switch filepath.Ext(path) {
+	case ".csv":
+		codec = "csv"
+	case ".tar":
+		codec = "tar"
+	case ".tgz":
+		prefix, codec = "gzip/", "tar"
+	case ".jsonl", ".ndjson", ".log", ".txt":
+		codec = "lines"
+	case ".parquet":
+		codec = "parquet"
+	}
+	return prefix + codec
+}
+
+func autoCodec(conf ReaderConfig) ReaderConstructor {
+	return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+		ctor, err := GetReader(autoCodecName(path), conf)
 		if err != nil {
 			return nil, fmt.Errorf("failed to infer codec: %v", err)
 		}
@@ -480,6 +511,87 @@ func (a *csvReader) Close(ctx context.Context) error {
 
 //------------------------------------------------------------------------------
 
+type parquetReader struct {
+	rows      *parquet.Reader
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newParquetReader(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := parquet.NewReader(data)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetReader{
+		rows:      rows,
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *parquetReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *parquetReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	row, err := a.rows.Next()
+	if err != nil {
+		if err == io.EOF {
+			a.finished = true
+		} else {
+			_ = a.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	a.pending++
+
+	part := message.NewPart(nil)
+	if err := part.SetJSON(row); err != nil {
+		a.pending--
+		return nil, nil, err
+	}
+	return []types.Part{part}, a.ack, nil
+}
+
+func (a *parquetReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		_ = a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		_ = a.sourceAck(ctx, nil)
+	}
+	a.rows.Close()
+	return a.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
 type customDelimReader struct {
 	buf       *bufio.Scanner
 	r         io.ReadCloser
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/Jeffail/benthos/v3/internal/parquet"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -302,6 +303,34 @@ func TestAutoReader(t *testing.T) {
 	testReaderSuite(t, "auto", "foo.csv", data)
 }
 
+func TestAutoCodecName(t *testing.T) {
+	for path, exp := range map[string]string{
+		"foo.csv":           "csv",
+		"foo.csv.gz":        "gzip/csv",
+		"foo.tar.gzip":      "gzip/tar",
+		"foo.tgz":           "gzip/tar",
+		"foo.jsonl":         "lines",
+		"foo.ndjson.gz":     "gzip/lines",
+		"dir.d/foo.log":     "lines",
+		"foo.parquet":       "parquet",
+		"foo.json":          "all-bytes",
+		"foo.json.gz":       "gzip/all-bytes",
+		"foo":               "all-bytes",
+		"nested/foo.txt.gz": "gzip/lines",
+	} {
+		assert.Equal(t, exp, autoCodecName(path), path)
+	}
+}
+
+func TestAutoGzipLinesReader(t *testing.T) {
+	var gzipBuf bytes.Buffer
+	zw := gzip.NewWriter(&gzipBuf)
+	zw.Write([]byte("foo\nbar\nbaz"))
+	zw.Close()
+
+	testReaderSuite(t, "auto", "foo.jsonl.gz", gzipBuf.Bytes(), "foo", "bar", "baz")
+}
+
 func TestCSVGzipReader(t *testing.T) {
 	var gzipBuf bytes.Buffer
 	zw := gzip.NewWriter(&gzipBuf)
@@ -330,6 +359,34 @@ func TestCSVGzipReaderOld(t *testing.T) {
 	)
 }
 
+func TestParquetReader(t *testing.T) {
+	enc, err := parquet.NewEncoder([]parquet.Column{
+		{Name: "col1", Type: "UTF8"},
+		{Name: "col2", Type: "INT64", Optional: true},
+	}, "snappy", 1024)
+	require.NoError(t, err)
+
+	data, err := enc.Encode([][]byte{
+		[]byte(`{"col1":"foo1","col2":1}`),
+		[]byte(`{"col1":"foo2"}`),
+		[]byte(`{"col1":"foo3","col2":3}`),
+	})
+	require.NoError(t, err)
+
+	testReaderSuite(
+		t, "parquet", "", data,
+		`{"col1":"foo1","col2":1}`,
+		`{"col1":"foo2","col2":null}`,
+		`{"col1":"foo3","col2":3}`,
+	)
+	testReaderSuite(
+		t, "auto", "foo.parquet", data,
+		`{"col1":"foo1","col2":1}`,
+		`{"col1":"foo2","col2":null}`,
+		`{"col1":"foo3","col2":3}`,
+	)
+}
+
 func TestAllBytesReader(t *testing.T) {
 	data := []byte("foo\nbar\nbaz")
 	testReaderSuite(t, "all-bytes", "", data, "foo\nbar\nbaz")
@@ -0,0 +1,349 @@
+package couchbase
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document flags set by the Couchbase SDKs in order to indicate the format of
+// a document.
+const (
+	flagsJSON   = 0x02000000
+	flagsBinary = 0x03000000
+)
+
+// bucketConfig is the part of the configuration of a bucket that is required
+// in order to route operations to the nodes that host them.
+type bucketConfig struct {
+	VBucketServerMap struct {
+		HashAlgorithm string   `json:"hashAlgorithm"`
+		ServerList    []string `json:"serverList"`
+		VBucketMap    [][]int  `json:"vBucketMap"`
+	} `json:"vBucketServerMap"`
+	NodesExt []struct {
+		Hostname string         `json:"hostname"`
+		Services map[string]int `json:"services"`
+		ThisNode bool           `json:"thisNode"`
+	} `json:"nodesExt"`
+}
+
+// Client performs operations on the documents of a bucket.
+type Client struct {
+	conf     Config
+	spec     connSpec
+	tlsConf  *tls.Config
+	timeout  time.Duration
+	http     *http.Client
+	mgmtURLs []string
+
+	mut     sync.Mutex
+	servers []string
+	vbMap   [][]int
+	conns   map[string]*memdConn
+}
+
+// NewClient creates a client from a config, no connection is established
+// until Connect is called.
+func NewClient(conf Config) (*Client, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("a bucket must be specified")
+	}
+	spec, err := parseConnSpec(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conf:     conf,
+		spec:     spec,
+		mgmtURLs: spec.mgmtURLs(),
+		conns:    map[string]*memdConn{},
+	}
+	if c.timeout, err = conf.timeout(); err != nil {
+		return nil, err
+	}
+	if spec.mgmtTLS || conf.TLS.Enabled {
+		if c.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	c.http = &http.Client{Timeout: c.timeout}
+	if c.tlsConf != nil {
+		c.http.Transport = &http.Transport{TLSClientConfig: c.tlsConf}
+	}
+	return c, nil
+}
+
+// Connect fetches the configuration of the bucket, connections to nodes are
+// established lazily.
+func (c *Client) Connect(ctx context.Context) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.refreshConfig(ctx)
+}
+
+// Close closes all connections of the client.
+func (c *Client) Close() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for addr, conn := range c.conns {
+		conn.close()
+		delete(c.conns, addr)
+	}
+	return nil
+}
+
+func (c *Client) fetchConfig(ctx context.Context, mgmtURL string) (*bucketConfig, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mgmtURL+"/pools/default/b/"+url.PathEscape(c.conf.Bucket), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.conf.Username, c.conf.Password)
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch bucket config with status %v: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var conf bucketConfig
+	if err := json.Unmarshal(body, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse bucket config: %v", err)
+	}
+	if conf.VBucketServerMap.HashAlgorithm != "" && conf.VBucketServerMap.HashAlgorithm != "CRC" {
+		return nil, fmt.Errorf("hash algorithm not supported: %v", conf.VBucketServerMap.HashAlgorithm)
+	}
+	if len(conf.VBucketServerMap.VBucketMap) == 0 {
+		return nil, errors.New("bucket config does not contain a vbucket map, only couchbase buckets are supported")
+	}
+	return &conf, nil
+}
+
+// kvAddresses returns the addresses of the key value service of the servers
+// of a config, in the order of the server list.
+func (c *Client) kvAddresses(mgmtURL string, conf *bucketConfig) ([]string, error) {
+	u, err := url.Parse(mgmtURL)
+	if err != nil {
+		return nil, err
+	}
+	bootstrapHost := u.Hostname()
+
+	addrs := make([]string, len(conf.VBucketServerMap.ServerList))
+	for i, server := range conf.VBucketServerMap.ServerList {
+		host, port, err := net.SplitHostPort(strings.Replace(server, "$HOST", bootstrapHost, -1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid server address '%v': %v", server, err)
+		}
+		if c.tlsConf != nil {
+			port = ""
+			for _, node := range conf.NodesExt {
+				nodeHost := node.Hostname
+				if nodeHost == "" || nodeHost == "$HOST" {
+					nodeHost = bootstrapHost
+				}
+				if nodeHost == host && node.Services["kvSSL"] > 0 {
+					port = strconv.Itoa(node.Services["kvSSL"])
+				}
+			}
+			if port == "" {
+				port = "11207"
+			}
+		}
+		addrs[i] = net.JoinHostPort(host, port)
+	}
+	return addrs, nil
+}
+
+// refreshConfig fetches the bucket config from the first host that responds,
+// the mutex must be held.
+func (c *Client) refreshConfig(ctx context.Context) error {
+	var err error
+	for _, mgmtURL := range c.mgmtURLs {
+		var conf *bucketConfig
+		if conf, err = c.fetchConfig(ctx, mgmtURL); err != nil {
+			continue
+		}
+		var servers []string
+		if servers, err = c.kvAddresses(mgmtURL, conf); err != nil {
+			continue
+		}
+		c.servers = servers
+		c.vbMap = conf.VBucketServerMap.VBucketMap
+		return nil
+	}
+	return err
+}
+
+// vbucketForKey returns the vbucket of a key.
+func vbucketForKey(key []byte, numVBuckets int) uint16 {
+	crc := crc32.ChecksumIEEE(key)
+	return uint16(((crc >> 16) & 0x7fff) % uint32(numVBuckets))
+}
+
+// serverConn returns a connection to the server with an index of the server
+// list, the mutex must be held.
+func (c *Client) serverConn(ctx context.Context, index int) (*memdConn, error) {
+	if index < 0 || index >= len(c.servers) {
+		return nil, errors.New("vbucket has no active server")
+	}
+	addr := c.servers[index]
+	if conn, exists := c.conns[addr]; exists {
+		return conn, nil
+	}
+	conn, err := connectMemd(ctx, addr, c.tlsConf, c.timeout, c.conf.Username, c.conf.Password, c.conf.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[addr] = conn
+	return conn, nil
+}
+
+func (c *Client) dropConn(conn *memdConn) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for addr, v := range c.conns {
+		if v == conn {
+			conn.close()
+			delete(c.conns, addr)
+		}
+	}
+}
+
+// do routes a request for a key to the node that hosts its vbucket, fetching
+// a new config and retrying when the vbucket has moved.
+func (c *Client) do(ctx context.Context, req *packet) (*packet, error) {
+	for attempt := 0; ; attempt++ {
+		c.mut.Lock()
+		if len(c.vbMap) == 0 {
+			if err := c.refreshConfig(ctx); err != nil {
+				c.mut.Unlock()
+				return nil, err
+			}
+		}
+		req.vbucket = vbucketForKey(req.key, len(c.vbMap))
+		var conn *memdConn
+		var err error
+		if replicas := c.vbMap[req.vbucket]; len(replicas) > 0 {
+			conn, err = c.serverConn(ctx, replicas[0])
+		} else {
+			err = errors.New("vbucket has no active server")
+		}
+		c.mut.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := conn.roundTrip(req)
+		if err != nil {
+			c.dropConn(conn)
+			return nil, err
+		}
+		if res.status != statusNotMyVBucket || attempt >= 3 {
+			return res, nil
+		}
+
+		c.mut.Lock()
+		err = c.refreshConfig(ctx)
+		c.mut.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Get returns the content and CAS of a document.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	res, err := c.do(ctx, &packet{opcode: opGet, key: []byte(key)})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := statusErr(res); err != nil {
+		return nil, 0, err
+	}
+	return res.value, res.cas, nil
+}
+
+func (c *Client) store(ctx context.Context, opcode uint8, key string, value []byte, expiry time.Duration) (uint64, error) {
+	extras := make([]byte, 8)
+	flags := uint32(flagsBinary)
+	if json.Valid(value) {
+		flags = flagsJSON
+	}
+	binary.BigEndian.PutUint32(extras, flags)
+	binary.BigEndian.PutUint32(extras[4:], expirySeconds(expiry))
+
+	res, err := c.do(ctx, &packet{opcode: opcode, key: []byte(key), extras: extras, value: value})
+	if err != nil {
+		return 0, err
+	}
+	if err := statusErr(res); err != nil {
+		return 0, err
+	}
+	return res.cas, nil
+}
+
+// Upsert creates or replaces a document, returning its new CAS. A zero expiry
+// means the document does not expire.
+func (c *Client) Upsert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	return c.store(ctx, opSet, key, value, expiry)
+}
+
+// Insert creates a document, returning ErrKeyExists if it already exists.
+func (c *Client) Insert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	return c.store(ctx, opAdd, key, value, expiry)
+}
+
+// Replace replaces a document, returning ErrKeyNotFound if it does not exist.
+func (c *Client) Replace(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	return c.store(ctx, opReplace, key, value, expiry)
+}
+
+// Remove deletes a document, returning ErrKeyNotFound if it does not exist.
+func (c *Client) Remove(ctx context.Context, key string) (uint64, error) {
+	res, err := c.do(ctx, &packet{opcode: opDelete, key: []byte(key)})
+	if err != nil {
+		return 0, err
+	}
+	if err := statusErr(res); err != nil {
+		return 0, err
+	}
+	return res.cas, nil
+}
+
+// expirySeconds converts an expiry into the protocol representation, where
+// periods longer than 30 days must be given as a unix timestamp.
+func expirySeconds(expiry time.Duration) uint32 {
+	if expiry <= 0 {
+		return 0
+	}
+	secs := int64(expiry / time.Second)
+	if secs == 0 {
+		secs = 1
+	}
+	if secs > 30*24*60*60 {
+		return uint32(time.Now().Unix() + secs)
+	}
+	return uint32(secs)
+}
@@ -0,0 +1,108 @@
+package couchbase
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+// Config contains the configuration fields shared by the couchbase components
+// for connecting to a bucket.
+type Config struct {
+	URL      string      `json:"url" yaml:"url"`
+	Bucket   string      `json:"bucket" yaml:"bucket"`
+	Username string      `json:"username" yaml:"username"`
+	Password string      `json:"password" yaml:"password"`
+	Timeout  string      `json:"timeout" yaml:"timeout"`
+	TLS      btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		URL:      "",
+		Bucket:   "",
+		Username: "",
+		Password: "",
+		Timeout:  "15s",
+		TLS:      btls.NewConfig(),
+	}
+}
+
+// FieldSpecs returns the documentation of the fields of a Config.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("url", "A connection string of the cluster, where the `couchbases` scheme connects with TLS.", "couchbase://localhost", "couchbase://node1,node2", "couchbases://cb.example.com"),
+		docs.FieldCommon("bucket", "The bucket to connect to."),
+		docs.FieldCommon("username", "The username to authenticate with."),
+		docs.FieldCommon("password", "The password to authenticate with."),
+		docs.FieldAdvanced("timeout", "The maximum period of time to wait for an operation to complete."),
+		btls.FieldSpec(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// connSpec is a parsed connection string.
+type connSpec struct {
+	hosts   []string
+	mgmtTLS bool
+}
+
+// mgmtURLs returns the base URLs of the cluster management API of each host.
+func (c connSpec) mgmtURLs() []string {
+	scheme, port := "http", "8091"
+	if c.mgmtTLS {
+		scheme, port = "https", "18091"
+	}
+	urls := make([]string, len(c.hosts))
+	for i, h := range c.hosts {
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, port)
+		}
+		urls[i] = scheme + "://" + h
+	}
+	return urls
+}
+
+func parseConnSpec(connStr string) (connSpec, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return connSpec{}, fmt.Errorf("failed to parse url: %v", err)
+	}
+
+	var spec connSpec
+	switch u.Scheme {
+	case "couchbase", "http":
+	case "couchbases", "https":
+		spec.mgmtTLS = true
+	default:
+		return connSpec{}, fmt.Errorf("url scheme not supported: %v", u.Scheme)
+	}
+	for _, h := range strings.Split(u.Host, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			spec.hosts = append(spec.hosts, h)
+		}
+	}
+	if len(spec.hosts) == 0 {
+		return connSpec{}, errors.New("url must contain at least one host")
+	}
+	return spec, nil
+}
+
+func (c Config) timeout() (time.Duration, error) {
+	if c.Timeout == "" {
+		return 0, nil
+	}
+	t, err := time.ParseDuration(c.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timeout string: %v", err)
+	}
+	return t, nil
+}
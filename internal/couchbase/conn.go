@@ -0,0 +1,193 @@
+package couchbase
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memdConn is an authenticated connection to the key value service of a node,
+// where requests are sent one at a time.
+type memdConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	mut    sync.Mutex
+	opaque uint32
+}
+
+func dialMemd(ctx context.Context, addr string, tlsConf *tls.Config, timeout time.Duration) (*memdConn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf != nil {
+		tConf := tlsConf.Clone()
+		if tConf.ServerName == "" {
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				tConf.ServerName = host
+			}
+		}
+		conn = tls.Client(conn, tConf)
+	}
+	return &memdConn{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// connectMemd dials a node, authenticates and selects a bucket.
+func connectMemd(ctx context.Context, addr string, tlsConf *tls.Config, timeout time.Duration, username, password, bucket string) (*memdConn, error) {
+	c, err := dialMemd(ctx, addr, tlsConf, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.auth(username, password, tlsConf != nil); err == nil {
+		err = c.selectBucket(bucket)
+	}
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("failed to connect to %v: %w", addr, err)
+	}
+	return c, nil
+}
+
+func (c *memdConn) close() error {
+	return c.conn.Close()
+}
+
+func (c *memdConn) write(p *packet) error {
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	_, err := c.conn.Write(p.encode())
+	return err
+}
+
+// roundTrip sends a request and waits for its response, where the opaque of
+// the request is set by the connection.
+func (c *memdConn) roundTrip(req *packet) (*packet, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.opaque++
+	req.magic = magicRequest
+	req.opaque = c.opaque
+	if err := c.write(req); err != nil {
+		return nil, err
+	}
+
+	if c.timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.conn.SetReadDeadline(time.Time{})
+	}
+	for {
+		res, err := readPacket(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if res.magic == magicResponse && res.opaque == req.opaque {
+			return res, nil
+		}
+	}
+}
+
+func (c *memdConn) auth(username, password string, secure bool) error {
+	res, err := c.roundTrip(&packet{opcode: opSASLListMech})
+	if err != nil {
+		return err
+	}
+	if err := statusErr(res); err != nil {
+		return err
+	}
+	offered := map[string]bool{}
+	for _, m := range strings.Fields(string(res.value)) {
+		offered[m] = true
+	}
+
+	for _, m := range scramMechs {
+		if offered[m.name] {
+			return c.authSCRAM(m.name, m.hash, username, password)
+		}
+	}
+	if offered["PLAIN"] {
+		if !secure {
+			return errors.New("the server only offers PLAIN authentication, which requires TLS")
+		}
+		return c.authPlain(username, password)
+	}
+	return fmt.Errorf("no supported authentication mechanisms offered: %s", res.value)
+}
+
+func (c *memdConn) authPlain(username, password string) error {
+	res, err := c.roundTrip(&packet{
+		opcode: opSASLAuth,
+		key:    []byte("PLAIN"),
+		value:  []byte("\x00" + username + "\x00" + password),
+	})
+	if err != nil {
+		return err
+	}
+	return statusErr(res)
+}
+
+func (c *memdConn) authSCRAM(mech string, hashFn scramHashFn, username, password string) error {
+	client, err := newSCRAMClient(hashFn, username, password)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.roundTrip(&packet{
+		opcode: opSASLAuth,
+		key:    []byte(mech),
+		value:  []byte(client.first()),
+	})
+	if err != nil {
+		return err
+	}
+	if res.status != statusAuthContinue {
+		if err := statusErr(res); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+		return errors.New("authentication failed: unexpected response")
+	}
+
+	final, err := client.final(string(res.value))
+	if err != nil {
+		return err
+	}
+	if res, err = c.roundTrip(&packet{
+		opcode: opSASLStep,
+		key:    []byte(mech),
+		value:  []byte(final),
+	}); err != nil {
+		return err
+	}
+	if err := statusErr(res); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	return client.verify(string(res.value))
+}
+
+func (c *memdConn) selectBucket(bucket string) error {
+	res, err := c.roundTrip(&packet{
+		opcode: opSelectBucket,
+		key:    []byte(bucket),
+	})
+	if err != nil {
+		return err
+	}
+	if err := statusErr(res); err != nil {
+		return fmt.Errorf("failed to select bucket '%v': %w", bucket, err)
+	}
+	return nil
+}
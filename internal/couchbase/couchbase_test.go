@@ -0,0 +1,535 @@
+package couchbase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	p := &packet{
+		magic:   magicRequest,
+		opcode:  opSet,
+		vbucket: 12,
+		opaque:  34,
+		cas:     56,
+		extras:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		key:     []byte("foo"),
+		value:   []byte(`{"bar":"baz"}`),
+	}
+	res, err := readPacket(bytes.NewReader(p.encode()))
+	require.NoError(t, err)
+	assert.Equal(t, p, res)
+
+	_, err = readPacket(bytes.NewReader([]byte{0x42}))
+	assert.Error(t, err)
+}
+
+func TestSCRAMVector(t *testing.T) {
+	// Example exchange from RFC 5802 section 5.
+	c := &scramClient{
+		hash:     sha1.New,
+		username: "user",
+		password: "pencil",
+		nonce:    "fyko+d2lbbFgONRv9qkxdawL",
+	}
+	assert.Equal(t, "n,,n=user,r=fyko+d2lbbFgONRv9qkxdawL", c.first())
+
+	final, err := c.final("r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096")
+	require.NoError(t, err)
+	assert.Equal(t, "c=biws,r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,p=v0X8v3Bz2T0CJGbJQyF0X+HI4Ts=", final)
+
+	assert.NoError(t, c.verify("v=rmF9pqV8S7suAoZWja4dJRkFsKQ="))
+	assert.Error(t, c.verify("v=AAAAAAAAAAAAAAAAAAAAAAAAAAA="))
+	assert.Error(t, c.verify("e=invalid-proof"))
+
+	_, err = c.final("r=someothernonce,s=QSXCR+Q6sek8bf92,i=4096")
+	assert.Error(t, err)
+}
+
+func TestVBucketForKey(t *testing.T) {
+	// CRC32 of the key, bits 16-30, modulo the number of vbuckets.
+	assert.Equal(t, uint16(0x73), vbucketForKey([]byte("foo"), 1024))
+	for _, k := range []string{"a", "b", "some-longer-key"} {
+		assert.Less(t, int(vbucketForKey([]byte(k), 64)), 64)
+	}
+}
+
+func TestParseConnSpec(t *testing.T) {
+	spec, err := parseConnSpec("couchbase://node1,node2:9000")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://node1:8091", "http://node2:9000"}, spec.mgmtURLs())
+
+	spec, err = parseConnSpec("couchbases://node1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://node1:18091"}, spec.mgmtURLs())
+
+	_, err = parseConnSpec("redis://node1")
+	assert.Error(t, err)
+
+	_, err = parseConnSpec("couchbase://")
+	assert.Error(t, err)
+}
+
+func TestExpirySeconds(t *testing.T) {
+	assert.Equal(t, uint32(0), expirySeconds(0))
+	assert.Equal(t, uint32(1), expirySeconds(time.Millisecond))
+	assert.Equal(t, uint32(60), expirySeconds(time.Minute))
+	assert.Greater(t, expirySeconds(31*24*time.Hour), uint32(time.Now().Unix()))
+}
+
+//------------------------------------------------------------------------------
+
+const testNumVBuckets = 8
+
+type fakeDoc struct {
+	value []byte
+	cas   uint64
+	seqno uint64
+}
+
+// fakeCluster is a single node cluster implementing enough of the management
+// API and key value protocol to exercise the client.
+type fakeCluster struct {
+	t        *testing.T
+	listener net.Listener
+	mgmt     *httptest.Server
+
+	mut   sync.Mutex
+	docs  map[string]*fakeDoc
+	cas   uint64
+	seqno uint64
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeCluster{
+		t:        t,
+		listener: l,
+		docs:     map[string]*fakeDoc{},
+	}
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	f.mgmt = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "foo" || pass != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/pools/default/b/testbucket" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		vbMap := make([][]int, testNumVBuckets)
+		for i := range vbMap {
+			vbMap[i] = []int{0}
+		}
+		var conf bucketConfig
+		conf.VBucketServerMap.HashAlgorithm = "CRC"
+		conf.VBucketServerMap.ServerList = []string{"$HOST:" + port}
+		conf.VBucketServerMap.VBucketMap = vbMap
+		json.NewEncoder(w).Encode(conf)
+	}))
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	t.Cleanup(func() {
+		l.Close()
+		f.mgmt.Close()
+	})
+	return f
+}
+
+func (f *fakeCluster) config() Config {
+	conf := NewConfig()
+	conf.URL = strings.Replace(f.mgmt.URL, "http://", "couchbase://", 1)
+	conf.Bucket = "testbucket"
+	conf.Username = "foo"
+	conf.Password = "bar"
+	conf.Timeout = "5s"
+	return conf
+}
+
+func (f *fakeCluster) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	var wMut sync.Mutex
+	respond := func(req *packet, status uint16, cas uint64, extras, value []byte) {
+		wMut.Lock()
+		defer wMut.Unlock()
+		conn.Write((&packet{
+			magic:  magicResponse,
+			opcode: req.opcode,
+			status: status,
+			opaque: req.opaque,
+			cas:    cas,
+			extras: extras,
+			value:  value,
+		}).encode())
+	}
+
+	var scram *scramClient
+	for {
+		req, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch req.opcode {
+		case opSASLListMech:
+			respond(req, statusSuccess, 0, nil, []byte("PLAIN SCRAM-SHA1"))
+		case opSASLAuth:
+			clientFirst := string(req.value)
+			nonce := clientFirst[strings.Index(clientFirst, ",r=")+3:]
+			scram = &scramClient{hash: sha1.New, username: "foo", password: "bar", nonce: nonce}
+			scram.first()
+			respond(req, statusAuthContinue, 0, nil, []byte("r="+nonce+"srv,s="+base64.StdEncoding.EncodeToString([]byte("salt"))+",i=16"))
+		case opSASLStep:
+			expected, _ := scram.final("r=" + scram.nonce + "srv,s=" + base64.StdEncoding.EncodeToString([]byte("salt")) + ",i=16")
+			if expected != string(req.value) {
+				respond(req, 0x20, 0, nil, []byte("auth failed"))
+				continue
+			}
+			respond(req, statusSuccess, 0, nil, []byte("v="+base64.StdEncoding.EncodeToString(scram.serverSignature)))
+		case opSelectBucket:
+			respond(req, statusSuccess, 0, nil, nil)
+		case opDCPOpen:
+			respond(req, statusSuccess, 0, nil, nil)
+		case opDCPStreamReq:
+			f.streamVBucket(req, respond, func(p *packet) {
+				wMut.Lock()
+				conn.Write(p.encode())
+				wMut.Unlock()
+			})
+		default:
+			status, cas, extras, value := f.handle(req)
+			respond(req, status, cas, extras, value)
+		}
+	}
+}
+
+func (f *fakeCluster) handle(req *packet) (uint16, uint64, []byte, []byte) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if vbucketForKey(req.key, testNumVBuckets) != req.vbucket {
+		return statusNotMyVBucket, 0, nil, nil
+	}
+
+	key := string(req.key)
+	doc, exists := f.docs[key]
+	store := func(value []byte) uint64 {
+		f.cas++
+		f.seqno++
+		f.docs[key] = &fakeDoc{value: value, cas: f.cas, seqno: f.seqno}
+		return f.cas
+	}
+
+	switch req.opcode {
+	case opGet:
+		if !exists {
+			return statusKeyNotFound, 0, nil, nil
+		}
+		return statusSuccess, doc.cas, []byte{2, 0, 0, 0}, doc.value
+	case opSet:
+		return statusSuccess, store(req.value), nil, nil
+	case opAdd:
+		if exists {
+			return statusKeyExists, 0, nil, nil
+		}
+		return statusSuccess, store(req.value), nil, nil
+	case opReplace:
+		if !exists {
+			return statusKeyNotFound, 0, nil, nil
+		}
+		return statusSuccess, store(req.value), nil, nil
+	case opDelete:
+		if !exists {
+			return statusKeyNotFound, 0, nil, nil
+		}
+		delete(f.docs, key)
+		return statusSuccess, doc.cas + 1, nil, nil
+	case opSubdocMultiLookup:
+		if !exists {
+			return statusKeyNotFound, 0, nil, nil
+		}
+		var obj map[string]json.RawMessage
+		json.Unmarshal(doc.value, &obj)
+		var body []byte
+		status := uint16(statusSuccess)
+		for spec := req.value; len(spec) >= 4; {
+			pathLen := int(binary.BigEndian.Uint16(spec[2:]))
+			path := string(spec[4 : 4+pathLen])
+			spec = spec[4+pathLen:]
+
+			result := make([]byte, 6)
+			v, ok := obj[path]
+			if !ok {
+				status = statusSubdocMultiPath
+				binary.BigEndian.PutUint16(result, statusSubdocPathNotFound)
+			} else {
+				binary.BigEndian.PutUint32(result[2:], uint32(len(v)))
+				result = append(result, v...)
+			}
+			body = append(body, result...)
+		}
+		return status, doc.cas, nil, body
+	case opSubdocMultiMutation:
+		obj := map[string]json.RawMessage{}
+		if exists {
+			json.Unmarshal(doc.value, &obj)
+		} else if len(req.extras) == 0 || req.extras[len(req.extras)-1] != subdocDocMkdoc {
+			return statusKeyNotFound, 0, nil, nil
+		}
+		index := 0
+		for spec := req.value; len(spec) >= 8; index++ {
+			op := spec[0]
+			pathLen := int(binary.BigEndian.Uint16(spec[2:]))
+			valueLen := int(binary.BigEndian.Uint32(spec[4:]))
+			path := string(spec[8 : 8+pathLen])
+			value := spec[8+pathLen : 8+pathLen+valueLen]
+			spec = spec[8+pathLen+valueLen:]
+
+			_, pathExists := obj[path]
+			switch op {
+			case subdocOpDictUpsert:
+				obj[path] = value
+			case subdocOpDelete:
+				if !pathExists {
+					failure := []byte{uint8(index), 0, 0}
+					binary.BigEndian.PutUint16(failure[1:], statusSubdocPathNotFound)
+					return statusSubdocMultiPath, 0, nil, failure
+				}
+				delete(obj, path)
+			}
+		}
+		value, _ := json.Marshal(obj)
+		return statusSuccess, store(value), nil, nil
+	}
+	return 0x81, 0, nil, nil
+}
+
+func (f *fakeCluster) streamVBucket(req *packet, respond func(*packet, uint16, uint64, []byte, []byte), send func(*packet)) {
+	vb := req.vbucket
+	start := binary.BigEndian.Uint64(req.extras[8:])
+	vbuuid := binary.BigEndian.Uint64(req.extras[24:])
+	if vbuuid != 0 && vbuuid != 0xabcd {
+		rollback := make([]byte, 8)
+		respond(req, statusRollback, 0, nil, rollback)
+		return
+	}
+
+	failoverLog := make([]byte, 16)
+	binary.BigEndian.PutUint64(failoverLog, 0xabcd)
+	respond(req, statusSuccess, 0, nil, failoverLog)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	for key, doc := range f.docs {
+		if vbucketForKey([]byte(key), testNumVBuckets) != vb || doc.seqno <= start {
+			continue
+		}
+		extras := make([]byte, 31)
+		binary.BigEndian.PutUint64(extras, doc.seqno)
+		send(&packet{
+			magic:   magicRequest,
+			opcode:  opDCPMutation,
+			vbucket: vb,
+			opaque:  req.opaque,
+			cas:     doc.cas,
+			extras:  extras,
+			key:     []byte(key),
+			value:   doc.value,
+		})
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestClientKV(t *testing.T) {
+	f := newFakeCluster(t)
+
+	c, err := NewClient(f.config())
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx := context.Background()
+	require.NoError(t, c.Connect(ctx))
+
+	_, _, err = c.Get(ctx, "doc1")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	cas, err := c.Insert(ctx, "doc1", []byte(`{"a":1}`), 0)
+	require.NoError(t, err)
+	assert.NotZero(t, cas)
+
+	_, err = c.Insert(ctx, "doc1", []byte(`{"a":2}`), 0)
+	assert.Equal(t, ErrKeyExists, err)
+
+	_, err = c.Replace(ctx, "doc2", []byte(`{"a":2}`), 0)
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	_, err = c.Upsert(ctx, "doc1", []byte(`{"a":3}`), time.Hour)
+	require.NoError(t, err)
+
+	value, getCAS, err := c.Get(ctx, "doc1")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":3}`, string(value))
+	assert.Greater(t, getCAS, cas)
+
+	_, err = c.Remove(ctx, "doc1")
+	require.NoError(t, err)
+
+	_, err = c.Remove(ctx, "doc1")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestClientBadAuth(t *testing.T) {
+	f := newFakeCluster(t)
+
+	conf := f.config()
+	conf.Password = "nope"
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.Error(t, c.Connect(context.Background()))
+}
+
+func TestClientSubdoc(t *testing.T) {
+	f := newFakeCluster(t)
+
+	c, err := NewClient(f.config())
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx := context.Background()
+	require.NoError(t, c.Connect(ctx))
+
+	_, err = c.MutateIn(ctx, "doc1", []MutateSpec{
+		{Op: MutateUpsert, Path: "a", Value: []byte(`"foo"`)},
+	}, false, 0)
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	_, err = c.MutateIn(ctx, "doc1", []MutateSpec{
+		{Op: MutateUpsert, Path: "a", Value: []byte(`"foo"`)},
+		{Op: MutateUpsert, Path: "b", Value: []byte(`10`)},
+	}, true, 0)
+	require.NoError(t, err)
+
+	_, err = c.MutateIn(ctx, "doc1", []MutateSpec{
+		{Op: MutateUpsert, Path: "c", Value: []byte(`true`)},
+		{Op: MutateRemove, Path: "nope"},
+	}, false, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'nope'")
+	assert.True(t, strings.Contains(err.Error(), ErrPathNotFound.Error()))
+
+	results, cas, err := c.LookupIn(ctx, "doc1", []LookupSpec{
+		{Op: LookupGet, Path: "a"},
+		{Op: LookupGet, Path: "c"},
+		{Op: LookupGet, Path: "b"},
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, cas)
+	require.Len(t, results, 3)
+	assert.Equal(t, `"foo"`, string(results[0].Value))
+	assert.Equal(t, ErrPathNotFound, results[1].Err)
+	assert.Equal(t, `10`, string(results[2].Value))
+
+	_, _, err = c.LookupIn(ctx, "doc1", []LookupSpec{{Op: "nope", Path: "a"}})
+	assert.Error(t, err)
+}
+
+func TestClientDCP(t *testing.T) {
+	f := newFakeCluster(t)
+
+	c, err := NewClient(f.config())
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, c.Connect(ctx))
+
+	for i := 0; i < 5; i++ {
+		_, err := c.Upsert(ctx, fmt.Sprintf("doc%v", i), []byte(fmt.Sprintf(`{"i":%v}`, i)), 0)
+		require.NoError(t, err)
+	}
+
+	readAll := func(opts DCPOptions, n int) map[string]*DCPEvent {
+		t.Helper()
+		s, err := c.OpenDCP(ctx, opts)
+		require.NoError(t, err)
+		defer s.Close()
+
+		events := map[string]*DCPEvent{}
+		for len(events) < n {
+			e, err := s.Next(ctx)
+			require.NoError(t, err)
+			events[string(e.Key)] = e
+		}
+		return events
+	}
+
+	events := readAll(DCPOptions{Name: "test"}, 5)
+	for i := 0; i < 5; i++ {
+		e := events[fmt.Sprintf("doc%v", i)]
+		require.NotNil(t, e)
+		assert.Equal(t, EventMutation, e.Type)
+		assert.Equal(t, fmt.Sprintf(`{"i":%v}`, i), string(e.Value))
+		assert.Equal(t, uint64(0xabcd), e.VBUUID)
+		assert.Equal(t, vbucketForKey(e.Key, testNumVBuckets), e.VBucket)
+	}
+
+	// Resuming a vbucket from the position of its last event skips it, and
+	// an unknown vbuuid rolls back to the beginning.
+	doc0, doc1 := events["doc0"], events["doc1"]
+	positions := map[uint16]StreamPosition{
+		doc0.VBucket: {VBUUID: doc0.VBUUID, Seqno: doc0.Seqno},
+	}
+	if doc1.VBucket != doc0.VBucket {
+		positions[doc1.VBucket] = StreamPosition{VBUUID: 0x1234, Seqno: doc1.Seqno}
+	}
+
+	expected := 0
+	for k, e := range events {
+		if e.VBucket != doc0.VBucket || k == "doc0" {
+			if e.VBucket == doc0.VBucket && e.Seqno <= doc0.Seqno {
+				continue
+			}
+			expected++
+		} else if e.Seqno > doc0.Seqno {
+			expected++
+		}
+	}
+	resumed := readAll(DCPOptions{Name: "test", Positions: positions}, expected)
+	assert.NotContains(t, resumed, "doc0")
+	assert.Contains(t, resumed, "doc1")
+}
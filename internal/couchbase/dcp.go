@@ -0,0 +1,265 @@
+package couchbase
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// DCP event types.
+const (
+	EventMutation   = "mutation"
+	EventDeletion   = "deletion"
+	EventExpiration = "expiration"
+)
+
+const (
+	dcpOpenProducer   = 0x01
+	dcpStreamFromNow  = 0x40
+	dcpStreamReqExtra = 48
+)
+
+// StreamPosition is the position of a vbucket stream that can be resumed from.
+type StreamPosition struct {
+	VBUUID uint64 `json:"uuid"`
+	Seqno  uint64 `json:"seqno"`
+}
+
+// DCPEvent is a change to a document received from a DCP stream.
+type DCPEvent struct {
+	Type    string
+	Key     []byte
+	Value   []byte
+	VBucket uint16
+	VBUUID  uint64
+	Seqno   uint64
+	CAS     uint64
+	Flags   uint32
+	Expiry  uint32
+}
+
+// DCPOptions configures a DCP stream.
+type DCPOptions struct {
+	// Name identifies the stream to the cluster.
+	Name string
+
+	// FromNow begins streaming vbuckets without a position from the current
+	// state of the bucket rather than from the beginning.
+	FromNow bool
+
+	// Positions are the positions to resume vbuckets from.
+	Positions map[uint16]StreamPosition
+}
+
+// DCPStream is a stream of changes to all documents of a bucket.
+type DCPStream struct {
+	events chan *DCPEvent
+	errs   chan error
+
+	conns []*memdConn
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// OpenDCP opens a stream of changes to all documents of the bucket, with a
+// connection to each node for the vbuckets it hosts.
+func (c *Client) OpenDCP(ctx context.Context, opts DCPOptions) (*DCPStream, error) {
+	if opts.Name == "" {
+		return nil, errors.New("a stream name must be specified")
+	}
+
+	c.mut.Lock()
+	err := c.refreshConfig(ctx)
+	servers, vbMap := c.servers, c.vbMap
+	c.mut.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([][]uint16, len(servers))
+	for vb, replicas := range vbMap {
+		if len(replicas) == 0 || replicas[0] < 0 || replicas[0] >= len(servers) {
+			return nil, fmt.Errorf("vbucket %v has no active server", vb)
+		}
+		owned[replicas[0]] = append(owned[replicas[0]], uint16(vb))
+	}
+
+	s := &DCPStream{
+		events: make(chan *DCPEvent),
+		errs:   make(chan error, len(servers)),
+		closed: make(chan struct{}),
+	}
+	for i, addr := range servers {
+		if len(owned[i]) == 0 {
+			continue
+		}
+		conn, err := connectMemd(ctx, addr, c.tlsConf, c.timeout, c.conf.Username, c.conf.Password, c.conf.Bucket)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.conns = append(s.conns, conn)
+
+		extras := make([]byte, 8)
+		binary.BigEndian.PutUint32(extras[4:], dcpOpenProducer)
+		res, err := conn.roundTrip(&packet{opcode: opDCPOpen, key: []byte(opts.Name), extras: extras})
+		if err == nil {
+			err = statusErr(res)
+		}
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to open dcp connection to %v: %w", addr, err)
+		}
+
+		// Stream responses arrive interleaved with events, so from here on
+		// the connection is read exclusively by its loop.
+		for _, vb := range owned[i] {
+			pos, exists := opts.Positions[vb]
+			if err := streamRequest(conn, vb, pos, opts.FromNow && !exists); err != nil {
+				s.Close()
+				return nil, err
+			}
+		}
+		s.wg.Add(1)
+		go s.loop(conn)
+	}
+	return s, nil
+}
+
+func streamRequest(conn *memdConn, vb uint16, pos StreamPosition, fromNow bool) error {
+	extras := make([]byte, dcpStreamReqExtra)
+	if fromNow {
+		binary.BigEndian.PutUint32(extras, dcpStreamFromNow)
+	}
+	binary.BigEndian.PutUint64(extras[8:], pos.Seqno)
+	binary.BigEndian.PutUint64(extras[16:], math.MaxUint64)
+	binary.BigEndian.PutUint64(extras[24:], pos.VBUUID)
+	binary.BigEndian.PutUint64(extras[32:], pos.Seqno)
+	binary.BigEndian.PutUint64(extras[40:], pos.Seqno)
+
+	conn.mut.Lock()
+	defer conn.mut.Unlock()
+	return conn.write(&packet{
+		magic:   magicRequest,
+		opcode:  opDCPStreamReq,
+		vbucket: vb,
+		opaque:  uint32(vb),
+		extras:  extras,
+	})
+}
+
+func (s *DCPStream) loop(conn *memdConn) {
+	defer s.wg.Done()
+
+	vbuuids := map[uint16]uint64{}
+	for {
+		p, err := readPacket(conn.r)
+		if err != nil {
+			select {
+			case <-s.closed:
+			default:
+				s.errs <- err
+			}
+			return
+		}
+
+		var event *DCPEvent
+		switch p.opcode {
+		case opDCPStreamReq:
+			vb := uint16(p.opaque)
+			switch p.status {
+			case statusSuccess:
+				if len(p.value) >= 16 {
+					vbuuids[vb] = binary.BigEndian.Uint64(p.value)
+				}
+			case statusRollback:
+				if len(p.value) < 8 {
+					err = errors.New("malformed rollback response")
+					break
+				}
+				// Resume from the point the server has rolled back to, the
+				// vbuuid is refreshed from the failover log of the response.
+				err = streamRequest(conn, vb, StreamPosition{Seqno: binary.BigEndian.Uint64(p.value)}, false)
+			default:
+				err = fmt.Errorf("stream request for vbucket %v failed: %w", vb, statusErr(p))
+			}
+		case opDCPStreamEnd:
+			err = fmt.Errorf("stream for vbucket %v ended", p.vbucket)
+		case opDCPNoop:
+			conn.mut.Lock()
+			err = conn.write(&packet{magic: magicResponse, opcode: opDCPNoop, opaque: p.opaque})
+			conn.mut.Unlock()
+		case opDCPMutation:
+			if len(p.extras) < 24 {
+				err = errors.New("malformed mutation")
+				break
+			}
+			event = &DCPEvent{
+				Type:   EventMutation,
+				Value:  p.value,
+				Flags:  binary.BigEndian.Uint32(p.extras[16:]),
+				Expiry: binary.BigEndian.Uint32(p.extras[20:]),
+			}
+		case opDCPDeletion, opDCPExpiration:
+			if len(p.extras) < 8 {
+				err = errors.New("malformed deletion")
+				break
+			}
+			event = &DCPEvent{Type: EventDeletion}
+			if p.opcode == opDCPExpiration {
+				event.Type = EventExpiration
+			}
+		}
+		if err == nil && event != nil {
+			event.Key = p.key
+			event.VBucket = p.vbucket
+			event.VBUUID = vbuuids[p.vbucket]
+			event.Seqno = binary.BigEndian.Uint64(p.extras)
+			event.CAS = p.cas
+			select {
+			case s.events <- event:
+			case <-s.closed:
+				return
+			}
+		}
+		if err != nil {
+			select {
+			case s.errs <- err:
+			case <-s.closed:
+			}
+			return
+		}
+	}
+}
+
+// Next blocks until the next event of the stream is received. Once an error
+// is returned the stream must be closed.
+func (s *DCPStream) Next(ctx context.Context) (*DCPEvent, error) {
+	select {
+	case e := <-s.events:
+		return e, nil
+	case err := <-s.errs:
+		return nil, err
+	case <-s.closed:
+		return nil, errors.New("stream closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the stream and its connections.
+func (s *DCPStream) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		for _, c := range s.conns {
+			c.close()
+		}
+	})
+	s.wg.Wait()
+	return nil
+}
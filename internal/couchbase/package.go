@@ -0,0 +1,4 @@
+// Package couchbase implements a client for the key value service of Couchbase
+// Server over the memcached binary protocol, including sub-document operations
+// and streaming of changes with the Database Change Protocol (DCP).
+package couchbase
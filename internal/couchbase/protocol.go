@@ -0,0 +1,179 @@
+package couchbase
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	magicRequest  = 0x80
+	magicResponse = 0x81
+
+	headerLen = 24
+
+	// maxBodyLen guards against allocating huge buffers when reading a corrupt
+	// packet, documents are limited to 20MB by the server.
+	maxBodyLen = 64 * 1024 * 1024
+)
+
+// Opcodes of the memcached binary protocol that are used by this package.
+const (
+	opGet          = 0x00
+	opSet          = 0x01
+	opAdd          = 0x02
+	opReplace      = 0x03
+	opDelete       = 0x04
+	opNoop         = 0x0a
+	opHello        = 0x1f
+	opSASLListMech = 0x20
+	opSASLAuth     = 0x21
+	opSASLStep     = 0x22
+	opSelectBucket = 0x89
+
+	opDCPOpen           = 0x50
+	opDCPStreamReq      = 0x53
+	opDCPStreamEnd      = 0x55
+	opDCPSnapshotMarker = 0x56
+	opDCPMutation       = 0x57
+	opDCPDeletion       = 0x58
+	opDCPExpiration     = 0x59
+	opDCPNoop           = 0x5c
+	opDCPBufferAck      = 0x5d
+	opDCPControl        = 0x5e
+
+	opSubdocMultiLookup   = 0xd0
+	opSubdocMultiMutation = 0xd1
+)
+
+// Status codes of responses that are handled by this package.
+const (
+	statusSuccess         = 0x00
+	statusKeyNotFound     = 0x01
+	statusKeyExists       = 0x02
+	statusNotStored       = 0x05
+	statusNotMyVBucket    = 0x07
+	statusAuthContinue    = 0x21
+	statusRollback        = 0x23
+	statusSubdocMultiPath = 0xcc
+)
+
+var (
+	// ErrKeyNotFound is returned when a document does not exist.
+	ErrKeyNotFound = errors.New("document not found")
+
+	// ErrKeyExists is returned when inserting a document that already exists.
+	ErrKeyExists = errors.New("document already exists")
+)
+
+// StatusError is returned for responses with an unsuccessful status that does
+// not have a dedicated error.
+type StatusError struct {
+	Opcode uint8
+	Status uint16
+	Body   string
+}
+
+// Error returns a description of the status.
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("operation 0x%02x failed with status 0x%02x: %v", e.Opcode, e.Status, e.Body)
+	}
+	return fmt.Sprintf("operation 0x%02x failed with status 0x%02x", e.Opcode, e.Status)
+}
+
+func statusErr(p *packet) error {
+	switch p.status {
+	case statusSuccess:
+		return nil
+	case statusKeyNotFound:
+		return ErrKeyNotFound
+	case statusKeyExists:
+		return ErrKeyExists
+	case statusNotStored:
+		if p.opcode == opAdd {
+			return ErrKeyExists
+		}
+		return ErrKeyNotFound
+	}
+	return &StatusError{Opcode: p.opcode, Status: p.status, Body: string(p.value)}
+}
+
+//------------------------------------------------------------------------------
+
+// packet is a request or response of the memcached binary protocol. For
+// requests the vbucket field holds the vbucket, and for responses the status.
+type packet struct {
+	magic    uint8
+	opcode   uint8
+	datatype uint8
+	vbucket  uint16
+	status   uint16
+	opaque   uint32
+	cas      uint64
+	extras   []byte
+	key      []byte
+	value    []byte
+}
+
+func (p *packet) encode() []byte {
+	bodyLen := len(p.extras) + len(p.key) + len(p.value)
+	b := make([]byte, headerLen+bodyLen)
+	b[0] = p.magic
+	b[1] = p.opcode
+	binary.BigEndian.PutUint16(b[2:], uint16(len(p.key)))
+	b[4] = uint8(len(p.extras))
+	b[5] = p.datatype
+	if p.magic == magicResponse {
+		binary.BigEndian.PutUint16(b[6:], p.status)
+	} else {
+		binary.BigEndian.PutUint16(b[6:], p.vbucket)
+	}
+	binary.BigEndian.PutUint32(b[8:], uint32(bodyLen))
+	binary.BigEndian.PutUint32(b[12:], p.opaque)
+	binary.BigEndian.PutUint64(b[16:], p.cas)
+	n := headerLen
+	n += copy(b[n:], p.extras)
+	n += copy(b[n:], p.key)
+	copy(b[n:], p.value)
+	return b
+}
+
+func readPacket(r io.Reader) (*packet, error) {
+	var header [headerLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	p := &packet{
+		magic:    header[0],
+		opcode:   header[1],
+		datatype: header[5],
+		opaque:   binary.BigEndian.Uint32(header[12:]),
+		cas:      binary.BigEndian.Uint64(header[16:]),
+	}
+	if p.magic != magicRequest && p.magic != magicResponse {
+		return nil, fmt.Errorf("unexpected packet magic: 0x%02x", p.magic)
+	}
+	if p.magic == magicResponse {
+		p.status = binary.BigEndian.Uint16(header[6:])
+	} else {
+		p.vbucket = binary.BigEndian.Uint16(header[6:])
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(header[2:]))
+	extLen := int(header[4])
+	bodyLen := int(binary.BigEndian.Uint32(header[8:]))
+	if bodyLen > maxBodyLen || keyLen+extLen > bodyLen {
+		return nil, fmt.Errorf("invalid packet lengths: body %v, key %v, extras %v", bodyLen, keyLen, extLen)
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	p.extras = body[:extLen]
+	p.key = body[extLen : extLen+keyLen]
+	p.value = body[extLen+keyLen:]
+	return p, nil
+}
@@ -0,0 +1,142 @@
+package couchbase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+type scramHashFn func() hash.Hash
+
+// scramMechs are the SCRAM mechanisms supported by this package in order of
+// preference.
+var scramMechs = []struct {
+	name string
+	hash scramHashFn
+}{
+	{"SCRAM-SHA512", sha512.New},
+	{"SCRAM-SHA256", sha256.New},
+	{"SCRAM-SHA1", sha1.New},
+}
+
+// scramClient implements the client side of the SCRAM authentication exchange
+// described in RFC 5802, without channel binding.
+type scramClient struct {
+	hash     scramHashFn
+	username string
+	password string
+	nonce    string
+
+	clientFirstBare string
+	serverSignature []byte
+}
+
+func newSCRAMClient(hashFn scramHashFn, username, password string) (*scramClient, error) {
+	nonceBytes := make([]byte, 18)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, err
+	}
+	return &scramClient{
+		hash:     hashFn,
+		username: username,
+		password: password,
+		nonce:    base64.StdEncoding.EncodeToString(nonceBytes),
+	}, nil
+}
+
+func scramEscape(s string) string {
+	s = strings.Replace(s, "=", "=3D", -1)
+	return strings.Replace(s, ",", "=2C", -1)
+}
+
+// first returns the client-first-message.
+func (c *scramClient) first() string {
+	c.clientFirstBare = "n=" + scramEscape(c.username) + ",r=" + c.nonce
+	return "n,," + c.clientFirstBare
+}
+
+func (c *scramClient) hmac(key []byte, data string) []byte {
+	h := hmac.New(c.hash, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// final returns the client-final-message for a server-first-message.
+func (c *scramClient) final(serverFirst string) (string, error) {
+	var nonce, salt string
+	var iterations int
+	for _, attr := range strings.Split(serverFirst, ",") {
+		if len(attr) < 2 || attr[1] != '=' {
+			continue
+		}
+		switch attr[0] {
+		case 'r':
+			nonce = attr[2:]
+		case 's':
+			salt = attr[2:]
+		case 'i':
+			var err error
+			if iterations, err = strconv.Atoi(attr[2:]); err != nil {
+				return "", fmt.Errorf("invalid iteration count: %v", err)
+			}
+		}
+	}
+	if !strings.HasPrefix(nonce, c.nonce) {
+		return "", errors.New("server nonce does not extend the client nonce")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid salt: %v", err)
+	}
+	if iterations <= 0 {
+		return "", errors.New("invalid iteration count")
+	}
+
+	saltedPassword := pbkdf2.Key([]byte(c.password), saltBytes, iterations, c.hash().Size(), c.hash)
+	clientKey := c.hmac(saltedPassword, "Client Key")
+	h := c.hash()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+
+	clientFinalBare := "c=biws,r=" + nonce
+	authMessage := c.clientFirstBare + "," + serverFirst + "," + clientFinalBare
+
+	clientSignature := c.hmac(storedKey, authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	serverKey := c.hmac(saltedPassword, "Server Key")
+	c.serverSignature = c.hmac(serverKey, authMessage)
+
+	return clientFinalBare + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verify checks the server-final-message.
+func (c *scramClient) verify(serverFinal string) error {
+	if strings.HasPrefix(serverFinal, "e=") {
+		return fmt.Errorf("authentication failed: %v", serverFinal[2:])
+	}
+	if !strings.HasPrefix(serverFinal, "v=") {
+		return errors.New("invalid server final message")
+	}
+	sig, err := base64.StdEncoding.DecodeString(serverFinal[2:])
+	if err != nil {
+		return fmt.Errorf("invalid server signature: %v", err)
+	}
+	if !hmac.Equal(sig, c.serverSignature) {
+		return errors.New("server signature does not match")
+	}
+	return nil
+}
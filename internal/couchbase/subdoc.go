@@ -0,0 +1,236 @@
+package couchbase
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Opcodes of subdocument operations within multi lookup and multi mutation
+// requests.
+const (
+	subdocOpGet            = 0xc5
+	subdocOpExists         = 0xc6
+	subdocOpDictAdd        = 0xc7
+	subdocOpDictUpsert     = 0xc8
+	subdocOpDelete         = 0xc9
+	subdocOpReplace        = 0xca
+	subdocOpArrayPushLast  = 0xcb
+	subdocOpArrayPushFirst = 0xcc
+	subdocOpArrayAddUnique = 0xce
+	subdocOpCounter        = 0xcf
+	subdocOpGetCount       = 0xd2
+
+	subdocFlagMkdirP = 0x01
+	subdocDocMkdoc   = 0x01
+
+	statusSubdocPathNotFound = 0xc0
+	statusSubdocPathExists   = 0xc9
+)
+
+// ErrPathNotFound is returned for subdocument paths that do not exist.
+var ErrPathNotFound = errors.New("path not found")
+
+// LookupOp is the type of a subdocument lookup.
+type LookupOp string
+
+// Subdocument lookups.
+const (
+	LookupGet    LookupOp = "get"
+	LookupExists LookupOp = "exists"
+	LookupCount  LookupOp = "count"
+)
+
+// LookupSpec describes a subdocument lookup.
+type LookupSpec struct {
+	Op   LookupOp
+	Path string
+}
+
+// LookupResult is the result of a subdocument lookup, where Err is
+// ErrPathNotFound when the path does not exist.
+type LookupResult struct {
+	Value []byte
+	Err   error
+}
+
+// MutateOp is the type of a subdocument mutation.
+type MutateOp string
+
+// Subdocument mutations.
+const (
+	MutateInsert         MutateOp = "insert"
+	MutateUpsert         MutateOp = "upsert"
+	MutateReplace        MutateOp = "replace"
+	MutateRemove         MutateOp = "remove"
+	MutateArrayAppend    MutateOp = "array_append"
+	MutateArrayPrepend   MutateOp = "array_prepend"
+	MutateArrayAddUnique MutateOp = "array_add_unique"
+	MutateCounter        MutateOp = "counter"
+)
+
+// MutateSpec describes a subdocument mutation, where Value is a JSON encoded
+// value and is ignored by remove operations.
+type MutateSpec struct {
+	Op    MutateOp
+	Path  string
+	Value []byte
+}
+
+func lookupOpcode(op LookupOp) (uint8, error) {
+	switch op {
+	case LookupGet:
+		return subdocOpGet, nil
+	case LookupExists:
+		return subdocOpExists, nil
+	case LookupCount:
+		return subdocOpGetCount, nil
+	}
+	return 0, fmt.Errorf("lookup operation not recognised: %v", op)
+}
+
+func mutateOpcode(op MutateOp) (uint8, error) {
+	switch op {
+	case MutateInsert:
+		return subdocOpDictAdd, nil
+	case MutateUpsert:
+		return subdocOpDictUpsert, nil
+	case MutateReplace:
+		return subdocOpReplace, nil
+	case MutateRemove:
+		return subdocOpDelete, nil
+	case MutateArrayAppend:
+		return subdocOpArrayPushLast, nil
+	case MutateArrayPrepend:
+		return subdocOpArrayPushFirst, nil
+	case MutateArrayAddUnique:
+		return subdocOpArrayAddUnique, nil
+	case MutateCounter:
+		return subdocOpCounter, nil
+	}
+	return 0, fmt.Errorf("mutate operation not recognised: %v", op)
+}
+
+// ValidateLookupOp returns an error if a lookup operation is not supported.
+func ValidateLookupOp(op string) error {
+	_, err := lookupOpcode(LookupOp(op))
+	return err
+}
+
+// ValidateMutateOp returns an error if a mutate operation is not supported.
+func ValidateMutateOp(op string) error {
+	_, err := mutateOpcode(MutateOp(op))
+	return err
+}
+
+func subdocStatusErr(status uint16) error {
+	switch status {
+	case statusSuccess:
+		return nil
+	case statusSubdocPathNotFound:
+		return ErrPathNotFound
+	case statusSubdocPathExists:
+		return errors.New("path already exists")
+	}
+	return fmt.Errorf("subdocument operation failed with status 0x%02x", status)
+}
+
+// LookupIn performs subdocument lookups on a document, returning a result for
+// each spec in order along with the CAS of the document.
+func (c *Client) LookupIn(ctx context.Context, key string, specs []LookupSpec) ([]LookupResult, uint64, error) {
+	var value []byte
+	for _, s := range specs {
+		opcode, err := lookupOpcode(s.Op)
+		if err != nil {
+			return nil, 0, err
+		}
+		spec := make([]byte, 4+len(s.Path))
+		spec[0] = opcode
+		binary.BigEndian.PutUint16(spec[2:], uint16(len(s.Path)))
+		copy(spec[4:], s.Path)
+		value = append(value, spec...)
+	}
+
+	res, err := c.do(ctx, &packet{opcode: opSubdocMultiLookup, key: []byte(key), value: value})
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.status != statusSuccess && res.status != statusSubdocMultiPath {
+		return nil, 0, statusErr(res)
+	}
+
+	results := make([]LookupResult, 0, len(specs))
+	body := res.value
+	for range specs {
+		if len(body) < 6 {
+			return nil, 0, errors.New("malformed lookup response")
+		}
+		status := binary.BigEndian.Uint16(body)
+		valueLen := int(binary.BigEndian.Uint32(body[2:]))
+		if len(body) < 6+valueLen {
+			return nil, 0, errors.New("malformed lookup response")
+		}
+		results = append(results, LookupResult{
+			Value: body[6 : 6+valueLen],
+			Err:   subdocStatusErr(status),
+		})
+		body = body[6+valueLen:]
+	}
+	return results, res.cas, nil
+}
+
+// MutateIn performs subdocument mutations on a document atomically, returning
+// the new CAS of the document. Intermediate paths are created as needed, and
+// when upsertDoc is true the document is created if it does not exist.
+func (c *Client) MutateIn(ctx context.Context, key string, specs []MutateSpec, upsertDoc bool, expiry time.Duration) (uint64, error) {
+	var value []byte
+	for _, s := range specs {
+		opcode, err := mutateOpcode(s.Op)
+		if err != nil {
+			return 0, err
+		}
+		v := s.Value
+		if s.Op == MutateRemove {
+			v = nil
+		}
+		spec := make([]byte, 8+len(s.Path)+len(v))
+		spec[0] = opcode
+		if s.Op != MutateRemove {
+			spec[1] = subdocFlagMkdirP
+		}
+		binary.BigEndian.PutUint16(spec[2:], uint16(len(s.Path)))
+		binary.BigEndian.PutUint32(spec[4:], uint32(len(v)))
+		copy(spec[8:], s.Path)
+		copy(spec[8+len(s.Path):], v)
+		value = append(value, spec...)
+	}
+
+	var extras []byte
+	if expiry > 0 {
+		extras = make([]byte, 4)
+		binary.BigEndian.PutUint32(extras, expirySeconds(expiry))
+	}
+	if upsertDoc {
+		extras = append(extras, subdocDocMkdoc)
+	}
+
+	res, err := c.do(ctx, &packet{opcode: opSubdocMultiMutation, key: []byte(key), extras: extras, value: value})
+	if err != nil {
+		return 0, err
+	}
+	if res.status == statusSubdocMultiPath && len(res.value) >= 3 {
+		index := int(res.value[0])
+		status := binary.BigEndian.Uint16(res.value[1:])
+		path := ""
+		if index < len(specs) {
+			path = specs[index].Path
+		}
+		return 0, fmt.Errorf("mutation of path '%v' failed: %w", path, subdocStatusErr(status))
+	}
+	if err := statusErr(res); err != nil {
+		return 0, err
+	}
+	return res.cas, nil
+}
@@ -0,0 +1,242 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHost is the address of the Docker daemon on most Linux hosts.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Client makes requests to the Docker Engine API.
+type Client struct {
+	base   string
+	client *http.Client
+}
+
+// NewClient creates a client for a daemon at a host address of the form
+// unix:///path/to/socket, tcp://host:port or http(s)://host:port. When a TLS
+// config is provided TCP connections are made over TLS.
+func NewClient(host string, tlsConf *tls.Config) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host: %w", err)
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConf}
+	base := ""
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		// The host is ignored when dialling a socket but must still be valid.
+		base = "http://docker"
+	case "tcp":
+		base = "http://" + u.Host
+		if tlsConf != nil {
+			base = "https://" + u.Host
+		}
+	case "http", "https":
+		base = u.Scheme + "://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported host scheme: %v", u.Scheme)
+	}
+	return &Client{
+		base:   base,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+// Error is returned when the daemon responds with an error status.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("docker daemon responded with status %v: %v", e.StatusCode, e.Message)
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		var errBody struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &errBody) != nil || errBody.Message == "" {
+			errBody.Message = strings.TrimSpace(string(body))
+		}
+		return nil, &Error{StatusCode: res.StatusCode, Message: errBody.Message}
+	}
+	return res, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	res, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func labelFilters(labels []string, extra map[string][]string) url.Values {
+	filters := map[string][]string{}
+	for k, v := range extra {
+		filters[k] = v
+	}
+	if len(labels) > 0 {
+		filters["label"] = labels
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(filters)
+	return url.Values{"filters": []string{string(b)}}
+}
+
+// Ping checks that the daemon is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	res, err := c.get(ctx, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// Container is a summary of a running container.
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ListContainers returns the running containers that have all of the given
+// labels, where each label is either a key or of the form key=value.
+func (c *Client) ListContainers(ctx context.Context, labels []string) ([]Container, error) {
+	var containers []Container
+	if err := c.getJSON(ctx, "/containers/json", labelFilters(labels, nil), &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// ContainerInfo contains the details of a container.
+type ContainerInfo struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+		Tty    bool              `json:"Tty"`
+	} `json:"Config"`
+}
+
+// InspectContainer returns the details of a container.
+func (c *Client) InspectContainer(ctx context.Context, id string) (*ContainerInfo, error) {
+	var info ContainerInfo
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/json", nil, &info); err != nil {
+		return nil, err
+	}
+	info.Name = strings.TrimPrefix(info.Name, "/")
+	return &info, nil
+}
+
+// Event is a container event emitted by the daemon.
+type Event struct {
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// EventStream is a stream of container events.
+type EventStream struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// Next blocks until the next event is received.
+func (e *EventStream) Next() (Event, error) {
+	var ev Event
+	err := e.dec.Decode(&ev)
+	return ev, err
+}
+
+// Close ends the stream.
+func (e *EventStream) Close() error {
+	return e.body.Close()
+}
+
+// ContainerEvents opens a stream of the given actions of containers that have
+// all of the given labels, such as start or die.
+func (c *Client) ContainerEvents(ctx context.Context, labels []string, actions ...string) (*EventStream, error) {
+	extra := map[string][]string{"type": {"container"}}
+	if len(actions) > 0 {
+		extra["event"] = actions
+	}
+	res, err := c.get(ctx, "/events", labelFilters(labels, extra))
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{body: res.Body, dec: json.NewDecoder(res.Body)}, nil
+}
+
+// LogsOptions describes which logs of a container to read.
+type LogsOptions struct {
+	Stdout bool
+	Stderr bool
+	Follow bool
+
+	// Since only includes logs written at or after a time when non-zero.
+	Since time.Time
+}
+
+// ContainerLogs opens the log stream of a container, where each line is
+// prefixed with its timestamp. Unless the container has a TTY the stream is
+// multiplexed and must be read with ReadLogs.
+func (c *Client) ContainerLogs(ctx context.Context, id string, opts LogsOptions) (io.ReadCloser, error) {
+	query := url.Values{
+		"stdout":     []string{strconv.FormatBool(opts.Stdout)},
+		"stderr":     []string{strconv.FormatBool(opts.Stderr)},
+		"follow":     []string{strconv.FormatBool(opts.Follow)},
+		"timestamps": []string{"true"},
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", fmt.Sprintf("%d.%09d", opts.Since.Unix(), opts.Since.Nanosecond()))
+	}
+	res, err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/logs", query)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func frame(stream byte, data string) []byte {
+	b := make([]byte, 8, 8+len(data))
+	b[0] = stream
+	binary.BigEndian.PutUint32(b[4:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func readAllLogs(t *testing.T, b []byte, multiplexed bool) []Line {
+	t.Helper()
+	var lines []Line
+	require.NoError(t, ReadLogs(bytes.NewReader(b), multiplexed, func(l Line) error {
+		lines = append(lines, l)
+		return nil
+	}))
+	return lines
+}
+
+func TestReadLogsMultiplexed(t *testing.T) {
+	var stream []byte
+	stream = append(stream, frame(1, "2021-06-01T10:00:00.000000001Z hello\n2021-06-01T10:00:01Z wor")...)
+	stream = append(stream, frame(2, "2021-06-01T10:00:02Z oops\n")...)
+	stream = append(stream, frame(1, "ld\n")...)
+	stream = append(stream, frame(0, "ignored")...)
+	stream = append(stream, frame(1, "no timestamp")...)
+
+	ts := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339Nano, s)
+		require.NoError(t, err)
+		return v
+	}
+	assert.Equal(t, []Line{
+		{Stream: StreamStdout, Timestamp: ts("2021-06-01T10:00:00.000000001Z"), Text: "hello"},
+		{Stream: StreamStderr, Timestamp: ts("2021-06-01T10:00:02Z"), Text: "oops"},
+		{Stream: StreamStdout, Timestamp: ts("2021-06-01T10:00:01Z"), Text: "world"},
+		{Stream: StreamStdout, Text: "no timestamp"},
+	}, readAllLogs(t, stream, true))
+
+	err := ReadLogs(bytes.NewReader(frame(1, "foo")[:9]), true, func(Line) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestReadLogsRaw(t *testing.T) {
+	lines := readAllLogs(t, []byte("2021-06-01T10:00:00Z foo bar\r\n2021-06-01T10:00:01Z baz\n"), false)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "foo bar", lines[0].Text)
+	assert.Equal(t, StreamStdout, lines[0].Stream)
+	assert.Equal(t, "baz", lines[1].Text)
+}
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{"label":["app=web"]}`, r.URL.Query().Get("filters"))
+		w.Write([]byte(`[{"Id":"abc","Names":["/web"],"Image":"nginx","Labels":{"app":"web"}}]`))
+	})
+	mux.HandleFunc("/containers/abc/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"abc","Name":"/web","Config":{"Image":"nginx","Labels":{"app":"web"},"Tty":true}}`))
+	})
+	mux.HandleFunc("/containers/nope/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: nope"}`))
+	})
+	mux.HandleFunc("/containers/abc/logs", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.URL.Query().Get("follow"))
+		assert.Equal(t, "true", r.URL.Query().Get("timestamps"))
+		assert.Equal(t, "1622541600.000000005", r.URL.Query().Get("since"))
+		w.Write([]byte("2021-06-01T10:00:00Z foo\n"))
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{"event":["start"],"label":["app=web"],"type":["container"]}`, r.URL.Query().Get("filters"))
+		w.Write([]byte(`{"Type":"container","Action":"start","Actor":{"ID":"abc","Attributes":{"name":"web"}}}`))
+	})
+
+	// The client is exercised over a unix socket as that is how daemons are
+	// most commonly reached.
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	c, err := NewClient("unix://"+socket, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, c.Ping(ctx))
+
+	containers, err := c.ListContainers(ctx, []string{"app=web"})
+	require.NoError(t, err)
+	assert.Equal(t, []Container{{ID: "abc", Names: []string{"/web"}, Image: "nginx", Labels: map[string]string{"app": "web"}}}, containers)
+
+	info, err := c.InspectContainer(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "web", info.Name)
+	assert.True(t, info.Config.Tty)
+
+	_, err = c.InspectContainer(ctx, "nope")
+	assert.EqualError(t, err, "docker daemon responded with status 404: No such container: nope")
+
+	body, err := c.ContainerLogs(ctx, "abc", LogsOptions{Stdout: true, Follow: true, Since: time.Unix(1622541600, 5)})
+	require.NoError(t, err)
+	var lines []Line
+	require.NoError(t, ReadLogs(body, false, func(l Line) error {
+		lines = append(lines, l)
+		return nil
+	}))
+	body.Close()
+	require.Len(t, lines, 1)
+	assert.Equal(t, "foo", lines[0].Text)
+
+	events, err := c.ContainerEvents(ctx, []string{"app=web"}, "start")
+	require.NoError(t, err)
+	ev, err := events.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "start", ev.Action)
+	assert.Equal(t, "abc", ev.Actor.ID)
+	require.NoError(t, events.Close())
+
+	_, err = NewClient("ftp://foo", nil)
+	assert.EqualError(t, err, "unsupported host scheme: ftp")
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// Log streams.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// maxLineSize is the size at which an unterminated line is emitted regardless.
+const maxLineSize = 1024 * 1024
+
+// Line is a single line of a container log.
+type Line struct {
+	Stream    string
+	Timestamp time.Time
+	Text      string
+}
+
+func parseLine(stream string, b []byte) Line {
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	line := Line{Stream: stream, Text: string(b)}
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		if ts, err := time.Parse(time.RFC3339Nano, string(b[:i])); err == nil {
+			line.Timestamp, line.Text = ts, string(b[i+1:])
+		}
+	}
+	return line
+}
+
+// ReadLogs reads a log stream returned by ContainerLogs until it ends, calling
+// fn with each line. Streams of containers without a TTY are multiplexed,
+// containing frames of both stdout and stderr. Streams of containers with a
+// TTY are raw, and all lines are attributed to stdout.
+func ReadLogs(r io.Reader, multiplexed bool, fn func(Line) error) error {
+	if !multiplexed {
+		return readRawLogs(r, fn)
+	}
+
+	buffers := map[byte]*bytes.Buffer{1: {}, 2: {}}
+	streams := map[byte]string{1: StreamStdout, 2: StreamStderr}
+
+	flush := func(streamType byte, all bool) error {
+		buf := buffers[streamType]
+		for {
+			i := bytes.IndexByte(buf.Bytes(), '\n')
+			if i < 0 {
+				if buf.Len() == 0 || (!all && buf.Len() < maxLineSize) {
+					return nil
+				}
+				i = buf.Len()
+			}
+			line := parseLine(streams[streamType], buf.Next(i))
+			buf.Next(1)
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+	}
+
+	br := bufio.NewReader(r)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF {
+				for _, streamType := range []byte{1, 2} {
+					if err := flush(streamType, true); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			return err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		buf, exists := buffers[header[0]]
+		if !exists {
+			// Frames of stdin are never expected, but are skipped if present.
+			if _, err := io.CopyN(ioutil.Discard, br, size); err != nil {
+				return fmt.Errorf("failed to read frame: %w", err)
+			}
+			continue
+		}
+		if _, err := io.CopyN(buf, br, size); err != nil {
+			return fmt.Errorf("failed to read frame: %w", err)
+		}
+		if err := flush(header[0], false); err != nil {
+			return err
+		}
+	}
+}
+
+func readRawLogs(r io.Reader, fn func(Line) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	for scanner.Scan() {
+		if err := fn(parseLine(StreamStdout, scanner.Bytes())); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,4 @@
+// Package docker implements a minimal client of the Docker Engine API covering
+// the endpoints needed for tailing the logs of containers, and decodes the log
+// streams it returns.
+package docker
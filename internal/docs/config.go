@@ -6,6 +6,8 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
 	"github.com/Jeffail/benthos/v3/internal/interop/plugins"
 	"github.com/Jeffail/gabs/v2"
 	"gopkg.in/yaml.v3"
@@ -62,6 +64,45 @@ var labelField = FieldCommon(
 	return nil
 })
 
+var whenField = FieldAdvanced(
+	"when", "An optional [Bloblang query](/docs/guides/bloblang/about) that is executed when the config is loaded and determines whether the component is included. When the query returns `false` the component is removed from the list it belongs to, which makes it possible for a single config to include different components for each environment. Queries are executed without a message and can therefore only use functions that access the environment, such as `env` and `hostname`. Conditions are only supported by components within lists, such as processors and the children of brokers.",
+	`env("ENVIRONMENT") == "prod"`,
+	`hostname().has_prefix("dev-")`,
+).Linter(lintWhenCondition).AtVersion("3.47.0")
+
+func lintWhenCondition(ctx LintContext, line, col int, v interface{}) []Lint {
+	if _, ok := v.(bool); ok {
+		return nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return []Lint{NewLintError(line, fmt.Sprintf("expected a boolean or a Bloblang query, got %T", v))}
+	}
+	_, err := bloblang.NewEnvironmentMapping(str)
+	if err == nil {
+		return nil
+	}
+	if mErr, ok := err.(*parser.Error); ok {
+		bline, bcol := parser.LineAndColOf([]rune(str), mErr.Input)
+		lint := NewLintError(line+bline, mErr.ErrorAtPositionStructured("", []rune(str)))
+		lint.Column = col + bcol
+		return []Lint{lint}
+	}
+	return []Lint{NewLintError(line, err.Error())}
+}
+
+var metricsMappingField = FieldCommon(
+	"mapping", "An optional [Bloblang mapping](/docs/guides/bloblang/about) executed against the name of each metric before it reaches the metrics target, allowing metrics to be renamed, dropped with `deleted()`, and labelled by setting meta fields. Existing labels of a metric are available as meta fields and can be modified or removed, and metrics mapped to the same name and labels are aggregated.",
+	`root = this.replace("input", "source").replace("output", "sink")`,
+	`root = if this.has_suffix(".batch.received") { deleted() }`,
+	`meta topic = deleted()`,
+).Linter(LintBloblangMapping).OmitWhen(func(field, _ interface{}) (string, bool) {
+	if s, ok := field.(string); ok && s == "" {
+		return "field mapping is empty and can be removed", true
+	}
+	return "", false
+}).AtVersion("3.47.0")
+
 func reservedFieldsByType(t Type) map[string]FieldSpec {
 	m := map[string]FieldSpec{
 		"type":   FieldCommon("type", "").HasType(FieldString),
@@ -76,6 +117,7 @@ func reservedFieldsByType(t Type) map[string]FieldSpec {
 		})
 	}
 	if _, isLabelType := map[Type]struct{}{
+		TypeBuffer:    {},
 		TypeInput:     {},
 		TypeProcessor: {},
 		TypeOutput:    {},
@@ -83,6 +125,10 @@ func reservedFieldsByType(t Type) map[string]FieldSpec {
 		TypeRateLimit: {},
 	}[t]; isLabelType {
 		m["label"] = labelField
+		m["when"] = whenField
+	}
+	if t == TypeMetrics {
+		m["mapping"] = metricsMappingField
 	}
 	return m
 }
@@ -195,13 +241,14 @@ func getInferenceCandidateFromList(t Type, defaultType string, l []string) (stri
 //
 // type: foo
 // plugin:
-//   bar: baz
+//
+//	bar: baz
 //
 // And the new style:
 //
 // foo:
-//   bar: baz
 //
+//	bar: baz
 func GetPluginConfigNode(name string, value *yaml.Node) (yaml.Node, error) {
 	for i := 0; i < len(value.Content)-1; i += 2 {
 		if value.Content[i].Value == name {
@@ -338,6 +385,16 @@ func SanitiseNode(cType Type, node *yaml.Node, conf SanitiseConfig) error {
 			break
 		}
 	}
+	if cType == TypeMetrics {
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			if node.Content[i].Value == "mapping" {
+				if _, omit := metricsMappingField.shouldOmitNode(node.Content[i+1], node); !omit {
+					newNodes = append(newNodes, node.Content[i], node.Content[i+1])
+				}
+				break
+			}
+		}
+	}
 	for i := 0; i < len(node.Content)-1; i += 2 {
 		if node.Content[i].Value == "type" {
 			name = node.Content[i+1].Value
@@ -454,6 +454,40 @@ func TestSanitation(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "metrics with mapping",
+			inputType: docs.TypeMetrics,
+			inputConf: map[string]interface{}{
+				"mapping": "root = this.uppercase()",
+				"testsanitfoometrics": map[string]interface{}{
+					"foo1": "simple field",
+				},
+				"someothermetrics": map[string]interface{}{
+					"ignore": "me please",
+				},
+			},
+			res: map[string]interface{}{
+				"mapping": "root = this.uppercase()",
+				"testsanitfoometrics": map[string]interface{}{
+					"foo1": "simple field",
+				},
+			},
+		},
+		{
+			name:      "metrics with empty mapping",
+			inputType: docs.TypeMetrics,
+			inputConf: map[string]interface{}{
+				"mapping": "",
+				"testsanitfoometrics": map[string]interface{}{
+					"foo1": "simple field",
+				},
+			},
+			res: map[string]interface{}{
+				"testsanitfoometrics": map[string]interface{}{
+					"foo1": "simple field",
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -751,3 +785,85 @@ testlintfooinput:
 		})
 	}
 }
+
+func TestArrayShorthand(t *testing.T) {
+	for _, t := range docs.Types() {
+		docs.RegisterDocs(docs.ComponentSpec{
+			Name: fmt.Sprintf("testshorthandfoo%v", string(t)),
+			Type: t,
+			Config: docs.FieldComponent().WithChildren(
+				docs.FieldCommon("foo1", "").Array().HasType(docs.FieldProcessor),
+				docs.FieldAdvanced("foo2", ""),
+			).ArrayShorthand("foo1"),
+		})
+	}
+
+	lintTests := []struct {
+		name      string
+		inputConf string
+		res       []docs.Lint
+	}{
+		{
+			name: "object form",
+			inputConf: `
+testshorthandfooinput:
+  foo1:
+    - testshorthandfooprocessor:
+        foo2: hello world
+  foo2: hello world`,
+		},
+		{
+			name: "array form",
+			inputConf: `
+testshorthandfooinput:
+  - testshorthandfooprocessor:
+      foo2: hello world`,
+		},
+		{
+			name: "array form bad child",
+			inputConf: `
+testshorthandfooinput:
+  - testshorthandfooprocessor:
+      nope: hello world`,
+			res: []docs.Lint{
+				docs.NewLintError(4, "field nope not recognised"),
+			},
+		},
+	}
+
+	for _, test := range lintTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(test.inputConf), &node))
+			lints := docs.LintNode(docs.NewLintContext(), docs.TypeInput, node.Content[0])
+			assert.Equal(t, test.res, lints)
+		})
+	}
+
+	t.Run("sanitise array form", func(t *testing.T) {
+		var node yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte(`
+type: testshorthandfooinput
+testshorthandfooinput:
+  - type: testshorthandfooprocessor
+    testshorthandfooprocessor:
+      foo2: hello world
+`), &node))
+		require.NoError(t, docs.SanitiseNode(docs.TypeInput, node.Content[0], docs.SanitiseConfig{
+			RemoveTypeField: true,
+		}))
+
+		var res interface{}
+		require.NoError(t, node.Decode(&res))
+		assert.Equal(t, map[string]interface{}{
+			"testshorthandfooinput": []interface{}{
+				map[string]interface{}{
+					"testshorthandfooprocessor": map[string]interface{}{
+						"foo2": "hello world",
+					},
+				},
+			},
+		}, res)
+	})
+}
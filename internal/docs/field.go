@@ -78,9 +78,10 @@ type FieldSpec struct {
 	// ExamplesMarshalled is a list of examples marshalled into YAML format.
 	ExamplesMarshalled []string
 
-	omitWhenFn   func(field, parent interface{}) (string, bool)
-	customLintFn LintFunc
-	skipLint     bool
+	omitWhenFn     func(field, parent interface{}) (string, bool)
+	customLintFn   LintFunc
+	skipLint       bool
+	arrayShorthand string
 }
 
 // IsInterpolated indicates that the field supports interpolation functions.
@@ -204,6 +205,31 @@ func (f FieldSpec) LintOptions() FieldSpec {
 	return f
 }
 
+// ArrayShorthand allows an object field to be alternatively configured as an
+// array, in which case the array is treated as the value of the named child
+// field and all other children take their default values. This is useful for
+// adding fields to configs that were previously a plain array.
+func (f FieldSpec) ArrayShorthand(child string) FieldSpec {
+	f.arrayShorthand = child
+	return f
+}
+
+// expandShorthand returns an object node equivalent to a node that uses the
+// array shorthand of the field, or the node itself if the shorthand isn't used.
+func (f FieldSpec) expandShorthand(node *yaml.Node) *yaml.Node {
+	if f.arrayShorthand == "" || node.Kind != yaml.SequenceNode {
+		return node
+	}
+	return &yaml.Node{
+		Kind: yaml.MappingNode,
+		Line: node.Line,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.arrayShorthand, Line: node.Line},
+			node,
+		},
+	}
+}
+
 // Unlinted returns a field spec that will not be lint checked during a config
 // parse.
 func (f FieldSpec) Unlinted() FieldSpec {
@@ -319,6 +345,9 @@ func FieldDeprecated(name string, description ...string) FieldSpec {
 }
 
 func (f FieldSpec) sanitise(s interface{}, filter FieldFilter) {
+	if arr, ok := s.([]interface{}); ok && f.arrayShorthand != "" {
+		s = map[string]interface{}{f.arrayShorthand: arr}
+	}
 	if coreType, isCore := f.Type.IsCoreComponent(); isCore {
 		if f.IsArray {
 			if arr, ok := s.([]interface{}); ok {
@@ -358,6 +387,9 @@ func (f FieldSpec) sanitise(s interface{}, filter FieldFilter) {
 // minimal representation without changing the behaviour of the config. The
 // fields of the result will also be sorted according to the field spec.
 func (f FieldSpec) SanitiseNode(node *yaml.Node, conf SanitiseConfig) error {
+	// The array within a shorthand node is sanitised in place, so the
+	// shorthand is preserved.
+	node = f.expandShorthand(node)
 	if coreType, isCore := f.Type.IsCoreComponent(); isCore {
 		if f.IsArray {
 			for i := 0; i < len(node.Content); i++ {
@@ -425,6 +457,7 @@ func (f FieldSpec) lintNode(ctx LintContext, node *yaml.Node) []Lint {
 	if f.skipLint {
 		return nil
 	}
+	node = f.expandShorthand(node)
 	var lints []Lint
 	if f.IsArray {
 		if node.Kind != yaml.SequenceNode {
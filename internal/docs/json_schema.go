@@ -0,0 +1,189 @@
+package docs
+
+import (
+	"sort"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// Non-string fields may be populated with environment variables, which are
+// strings in the raw config.
+var envVarSchema = map[string]interface{}{
+	"type":    "string",
+	"pattern": `^\$\{[^}]+\}$`,
+}
+
+// JSONSchema serializes a field spec into a JSON schema structure.
+func (f FieldSpec) JSONSchema() map[string]interface{} {
+	return f.jsonSchema(nil)
+}
+
+// jsonSchema serializes a field spec into a JSON schema structure, where the
+// type and default value of the field is inferred from an example value when
+// it is not specified.
+func (f FieldSpec) jsonSchema(example interface{}) map[string]interface{} {
+	_, isCore := f.Type.IsCoreComponent()
+
+	var defaultValue *interface{}
+	if f.Default != nil {
+		defaultValue = f.Default
+	} else if example != nil && len(f.Children) == 0 && !isCore {
+		defaultValue = &example
+	}
+
+	fType, isArray, isMap := f.Type, f.IsArray, f.IsMap
+	if (fType == "" || fType == FieldUnknown) && !isMap && len(f.Children) == 0 && defaultValue != nil && *defaultValue != nil {
+		inferred, inferredArray := getFieldTypeFromInterface(*defaultValue)
+		if inferred != FieldUnknown {
+			fType = inferred
+		}
+		isArray = isArray || inferredArray
+	}
+
+	spec := map[string]interface{}{}
+	if len(f.Children) > 0 {
+		spec["type"] = "object"
+		spec["properties"] = f.Children.jsonSchema(example)
+		spec["additionalProperties"] = false
+	} else {
+		switch fType {
+		case FieldString:
+			spec["type"] = "string"
+		case FieldInt, FieldFloat:
+			spec["anyOf"] = []interface{}{
+				map[string]interface{}{"type": "number"},
+				envVarSchema,
+			}
+		case FieldBool:
+			spec["anyOf"] = []interface{}{
+				map[string]interface{}{"type": "boolean"},
+				envVarSchema,
+			}
+		case FieldObject:
+			// Maps are commonly given the object type in place of their
+			// values.
+			if !isMap {
+				spec["type"] = "object"
+			}
+		default:
+			if coreType, isCore := fType.IsCoreComponent(); isCore {
+				spec["$ref"] = "#/definitions/" + string(coreType)
+			}
+		}
+	}
+
+	if isArray {
+		spec = map[string]interface{}{
+			"type":  "array",
+			"items": spec,
+		}
+	} else if isMap {
+		spec = map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": spec,
+		}
+	}
+
+	if f.arrayShorthand != "" {
+		for _, child := range f.Children {
+			if child.Name == f.arrayShorthand {
+				spec = map[string]interface{}{
+					"anyOf": []interface{}{spec, child.jsonSchema(nil)},
+				}
+				break
+			}
+		}
+	}
+
+	if f.Description != "" {
+		spec["description"] = f.Description
+	}
+	if defaultValue != nil {
+		spec["default"] = *defaultValue
+	}
+	return spec
+}
+
+// JSONSchema serializes a list of field specs into a map of JSON schema
+// properties.
+func (f FieldSpecs) JSONSchema() map[string]interface{} {
+	return f.jsonSchema(nil)
+}
+
+func (f FieldSpecs) jsonSchema(example interface{}) map[string]interface{} {
+	gExample := gabs.Wrap(example)
+	properties := map[string]interface{}{}
+	for _, field := range f {
+		properties[field.Name] = field.jsonSchema(gExample.S(field.Name).Data())
+	}
+	return properties
+}
+
+// JSONSchema serializes a component spec into a JSON schema structure, where
+// the types and default values of fields that aren't specified are inferred
+// from a full config example, which is the same as that provided to
+// AsMarkdown.
+func (c *ComponentSpec) JSONSchema(fullConfigExample interface{}) map[string]interface{} {
+	spec := c.Config.jsonSchema(gabs.Wrap(fullConfigExample).S(c.Name).Data())
+	if c.Summary != "" {
+		spec["description"] = c.Summary
+	}
+	return spec
+}
+
+// ComponentsJSONSchema returns a JSON schema definition for a component type
+// from a map of component names to their schemas, where each component is a
+// property of the object along with the reserved fields of the type.
+func ComponentsJSONSchema(t Type, components map[string]interface{}) map[string]interface{} {
+	names := make([]string, 0, len(components))
+	properties := map[string]interface{}{}
+	for name, schema := range components {
+		names = append(names, name)
+		properties[name] = schema
+	}
+	sort.Strings(names)
+
+	for name, field := range reservedFieldsByType(t) {
+		properties[name] = field.JSONSchema()
+	}
+	properties["type"] = map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "enum": names},
+			envVarSchema,
+		},
+	}
+
+	// Components can be replaced with JSON references to other config files.
+	properties["$ref"] = map[string]interface{}{
+		"type": "string",
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// JSONSchema returns a JSON schema document describing a config with root
+// fields, where the types and default values of fields that aren't specified
+// are inferred from an example config, and each core component type is
+// defined by a map of component names to their schemas.
+func JSONSchema(root FieldSpecs, rootExample interface{}, components map[Type]map[string]interface{}) map[string]interface{} {
+	definitions := map[string]interface{}{
+		// TODO: V4 Remove this
+		"condition": map[string]interface{}{
+			"type": "object",
+		},
+	}
+	for _, t := range Types() {
+		definitions[string(t)] = ComponentsJSONSchema(t, components[t])
+	}
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"type":                 "object",
+		"properties":           root.jsonSchema(rootExample),
+		"additionalProperties": false,
+		"definitions":          definitions,
+	}
+}
@@ -0,0 +1,77 @@
+package docs_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldsJSONSchema(t *testing.T) {
+	spec := docs.FieldComponent().WithChildren(
+		docs.FieldCommon("foo", "A string.").HasType(docs.FieldString).HasDefault("meow"),
+		docs.FieldCommon("bar", "A number.").HasType(docs.FieldInt),
+		docs.FieldCommon("baz", "Some strings.").HasType(docs.FieldString).Array(),
+		docs.FieldCommon("buz", "A processor.").HasType(docs.FieldProcessor),
+		docs.FieldCommon("nested", "An object.").WithChildren(
+			docs.FieldCommon("qux", "A bool.").HasType(docs.FieldBool),
+		),
+	)
+
+	resBytes, err := json.Marshal(spec.JSONSchema())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+	"type": "object",
+	"additionalProperties": false,
+	"properties": {
+		"foo": {"type": "string", "description": "A string.", "default": "meow"},
+		"bar": {
+			"anyOf": [{"type": "number"}, {"type": "string", "pattern": "^\\$\\{[^}]+\\}$"}],
+			"description": "A number."
+		},
+		"baz": {"type": "array", "items": {"type": "string"}, "description": "Some strings."},
+		"buz": {"$ref": "#/definitions/processor", "description": "A processor."},
+		"nested": {
+			"type": "object",
+			"additionalProperties": false,
+			"description": "An object.",
+			"properties": {
+				"qux": {
+					"anyOf": [{"type": "boolean"}, {"type": "string", "pattern": "^\\$\\{[^}]+\\}$"}],
+					"description": "A bool."
+				}
+			}
+		}
+	}
+}`, string(resBytes))
+}
+
+func TestFieldsJSONSchemaInferred(t *testing.T) {
+	spec := docs.FieldComponent().WithChildren(
+		docs.FieldCommon("foo", ""),
+		docs.FieldCommon("bar", ""),
+	)
+
+	resBytes, err := json.Marshal(docs.FieldSpecs(spec.Children).JSONSchema())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"foo": {}, "bar": {}}`, string(resBytes))
+
+	schema := docs.JSONSchema(spec.Children, map[string]interface{}{
+		"foo": "hello",
+		"bar": []int64{10},
+	}, nil)
+
+	resBytes, err = json.Marshal(schema["properties"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	"foo": {"type": "string", "default": "hello"},
+	"bar": {
+		"type": "array",
+		"items": {"anyOf": [{"type": "number"}, {"type": "string", "pattern": "^\\$\\{[^}]+\\}$"}]},
+		"default": [10]
+	}
+}`, string(resBytes))
+}
@@ -0,0 +1,85 @@
+package edi
+
+import (
+	"errors"
+	"strings"
+)
+
+// edifactDefaultDelimiters are used when a document does not begin with a UNA
+// service string advice.
+var edifactDefaultDelimiters = Delimiters{
+	Component:  ':',
+	Element:    '+',
+	Release:    '?',
+	Repetition: '*',
+	Segment:    '\'',
+}
+
+// detectEDIFACT returns the delimiters of an EDIFACT document and the
+// document without its UNA service string advice.
+func detectEDIFACT(doc string) (Delimiters, string, error) {
+	if !strings.HasPrefix(doc, "UNA") {
+		return edifactDefaultDelimiters, doc, nil
+	}
+	if len(doc) < 9 {
+		return Delimiters{}, "", errors.New("UNA service string advice is incomplete")
+	}
+	d := Delimiters{
+		Component: doc[3],
+		Element:   doc[4],
+		Release:   doc[6],
+		Segment:   doc[8],
+	}
+	// The reserved character is used as a repetition separator from syntax
+	// version 4, and is a space otherwise.
+	if rep := doc[7]; rep != ' ' {
+		d.Repetition = rep
+	}
+	if d.Release == ' ' {
+		d.Release = 0
+	}
+	return d, strings.TrimLeft(doc[9:], "\r\n"), nil
+}
+
+func parseEDIFACT(doc string, validate bool) (map[string]interface{}, error) {
+	d, doc, err := detectEDIFACT(doc)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := splitSegments(doc, d)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &envelopeBuilder{
+		validate:     validate,
+		interchange:  "UNB",
+		group:        "UNG",
+		message:      "UNH",
+		messagesKey:  "messages",
+		messageKind:  "message",
+		interchangeT: "UNZ",
+		groupT:       "UNE",
+		messageT:     "UNT",
+		checks: envelopeChecks{
+			messageCount:       1,
+			messageControl:     [2]int{1, 2},
+			groupCount:         1,
+			groupControl:       [2]int{5, 2},
+			interchangeCount:   1,
+			interchangeControl: [2]int{5, 2},
+		},
+	}
+	for _, s := range segments {
+		if err := b.add(s); err != nil {
+			return nil, err
+		}
+	}
+	root, err := b.finish()
+	if err != nil {
+		return nil, err
+	}
+	root["standard"] = "edifact"
+	root["delimiters"] = d.ToMap()
+	return root, nil
+}
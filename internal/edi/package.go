@@ -0,0 +1,3 @@
+// Package edi implements parsing of EDI documents of the X12 and EDIFACT
+// standards into generic structures that can be serialized to JSON.
+package edi
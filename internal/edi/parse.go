@@ -0,0 +1,48 @@
+package edi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Standards supported by Parse.
+const (
+	StandardAuto    = "auto"
+	StandardX12     = "x12"
+	StandardEDIFACT = "edifact"
+)
+
+// DetectStandard returns the standard of a document from its first segment, or
+// an empty string if it is not recognised.
+func DetectStandard(doc []byte) string {
+	trimmed := strings.TrimLeft(strings.TrimPrefix(string(doc), "\ufeff"), " \t\r\n")
+	switch {
+	case strings.HasPrefix(trimmed, "ISA"):
+		return StandardX12
+	case strings.HasPrefix(trimmed, "UNA"), strings.HasPrefix(trimmed, "UNB"):
+		return StandardEDIFACT
+	}
+	return ""
+}
+
+// Parse an EDI document of a given standard into a generic structure of its
+// interchange envelope, with the delimiters of X12 documents detected from the
+// ISA segment and the delimiters of EDIFACT documents detected from the UNA
+// service string advice. When validate is true the counts and control numbers
+// of envelope trailers are checked against their contents.
+func Parse(doc []byte, standard string, validate bool) (map[string]interface{}, error) {
+	if standard == StandardAuto {
+		if standard = DetectStandard(doc); standard == "" {
+			return nil, errors.New("unable to detect the standard of the document")
+		}
+	}
+	str := strings.TrimLeft(strings.TrimPrefix(string(doc), "\ufeff"), " \t\r\n")
+	switch standard {
+	case StandardX12:
+		return parseX12(str, validate)
+	case StandardEDIFACT:
+		return parseEDIFACT(str, validate)
+	}
+	return nil, fmt.Errorf("standard not recognised: %v", standard)
+}
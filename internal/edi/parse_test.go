@@ -0,0 +1,149 @@
+package edi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testX12 = `ISA*00*          *00*          *ZZ*SENDERID       *ZZ*RECEIVERID     *210101*1253*^*00501*000000001*0*P*:~
+GS*PO*SENDERID*RECEIVERID*20210101*1253*1*X*005010~
+ST*850*0001~
+BEG*00*SA*PO123**20210101~
+PO1*1*10*EA*9.99**VP*SKU1^SKU2~
+SV1*HC:99213*50~
+SE*5*0001~
+GE*1*1~
+IEA*1*000000001~
+`
+
+const testEDIFACT = `UNA:+.? '
+UNB+UNOC:3+SENDER+RECEIVER+210101:1253+REF1'
+UNH+1+ORDERS:D:96A:UN'
+BGM+220+PO?+123+9'
+DTM+137:20210101:102'
+UNT+4+1'
+UNZ+1+REF1'`
+
+func jsonOf(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestParseX12(t *testing.T) {
+	root, err := Parse([]byte(testX12), StandardAuto, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "x12", root["standard"])
+	assert.Equal(t, map[string]interface{}{
+		"segment": "~", "element": "*", "component": ":", "repetition": "^",
+	}, root["delimiters"])
+
+	ic := root["interchange"].(map[string]interface{})
+	isa := ic["header"].(map[string]interface{})
+	assert.Equal(t, "SENDERID", isa["ISA06"])
+	assert.Equal(t, ":", isa["ISA16"])
+
+	groups := ic["groups"].([]interface{})
+	require.Len(t, groups, 1)
+	txs := groups[0].(map[string]interface{})["transactions"].([]interface{})
+	require.Len(t, txs, 1)
+
+	tx := txs[0].(map[string]interface{})
+	assert.Equal(t, `{"ST01":"850","ST02":"0001","segment":"ST"}`, jsonOf(t, tx["header"]))
+	assert.Equal(t, `[`+
+		`{"BEG01":"00","BEG02":"SA","BEG03":"PO123","BEG05":"20210101","segment":"BEG"},`+
+		`{"PO101":"1","PO102":"10","PO103":"EA","PO104":"9.99","PO106":"VP","PO107":[["SKU1"],["SKU2"]],"segment":"PO1"},`+
+		`{"SV101":["HC","99213"],"SV102":"50","segment":"SV1"}`+
+		`]`, jsonOf(t, tx["segments"]))
+	assert.Equal(t, `{"SE01":"5","SE02":"0001","segment":"SE"}`, jsonOf(t, tx["trailer"]))
+}
+
+func TestParseEDIFACT(t *testing.T) {
+	root, err := Parse([]byte(testEDIFACT), StandardAuto, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "edifact", root["standard"])
+	assert.Equal(t, map[string]interface{}{
+		"segment": "'", "element": "+", "component": ":", "release": "?",
+	}, root["delimiters"])
+
+	ic := root["interchange"].(map[string]interface{})
+	assert.Equal(t, `{"UNB01":["UNOC","3"],"UNB02":"SENDER","UNB03":"RECEIVER","UNB04":["210101","1253"],"UNB05":"REF1","segment":"UNB"}`, jsonOf(t, ic["header"]))
+
+	msgs := ic["messages"].([]interface{})
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `[`+
+		`{"BGM01":"220","BGM02":"PO+123","BGM03":"9","segment":"BGM"},`+
+		`{"DTM01":["137","20210101","102"],"segment":"DTM"}`+
+		`]`, jsonOf(t, msgs[0].(map[string]interface{})["segments"]))
+}
+
+func TestParseEDIFACTDefaultDelimiters(t *testing.T) {
+	root, err := Parse([]byte(`UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380'UNT+3+1'UNZ+1+9'`), StandardEDIFACT, true)
+	require.NoError(t, err)
+
+	msgs := root["interchange"].(map[string]interface{})["messages"].([]interface{})
+	require.Len(t, msgs, 1)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		doc      string
+		standard string
+		validate bool
+		err      string
+	}{
+		"unknown standard": {
+			doc: "FOO*BAR~", standard: StandardAuto,
+			err: "unable to detect the standard of the document",
+		},
+		"truncated isa": {
+			doc: "ISA*00*~", standard: StandardX12,
+			err: "document does not begin with a complete ISA segment",
+		},
+		"bad segment count": {
+			doc:      `UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380'UNT+4+1'UNZ+1+9'`,
+			standard: StandardEDIFACT, validate: true,
+			err: "message UNT count 4 does not match the actual count 3",
+		},
+		"bad segment count not validated": {
+			doc:      `UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380'UNT+4+1'UNZ+1+9'`,
+			standard: StandardEDIFACT,
+		},
+		"bad control number": {
+			doc:      `UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380'UNT+3+1'UNZ+1+8'`,
+			standard: StandardEDIFACT, validate: true,
+			err: "interchange control number 8 of UNZ does not match 9 of UNB",
+		},
+		"missing trailer": {
+			doc:      `UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380'`,
+			standard: StandardEDIFACT,
+			err:      "missing UNT segment",
+		},
+		"outside of message": {
+			doc:      `UNB+UNOA:1+A+B+210101:1253+9'BGM+380'`,
+			standard: StandardEDIFACT,
+			err:      "segment BGM is outside of a message",
+		},
+		"transaction outside of group": {
+			doc:      testX12[:107] + "ST*850*0001~SE*2*0001~IEA*0*000000001~",
+			standard: StandardX12,
+			err:      "unexpected ST segment",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := Parse([]byte(test.doc), test.standard, test.validate)
+		if test.err == "" {
+			assert.NoError(t, err, name)
+			continue
+		}
+		require.Error(t, err, name)
+		assert.Equal(t, test.err, err.Error(), name)
+	}
+}
@@ -0,0 +1,171 @@
+package edi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Delimiters describes the characters that separate the parts of a document.
+// A zero value indicates that the delimiter is not used.
+type Delimiters struct {
+	Segment    byte
+	Element    byte
+	Component  byte
+	Repetition byte
+	Release    byte
+}
+
+// ToMap returns the delimiters as a generic structure.
+func (d Delimiters) ToMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range map[string]byte{
+		"segment":    d.Segment,
+		"element":    d.Element,
+		"component":  d.Component,
+		"repetition": d.Repetition,
+		"release":    d.Release,
+	} {
+		if v != 0 {
+			m[k] = string(v)
+		}
+	}
+	return m
+}
+
+// Segment is a parsed segment of a document. Each element contains one or
+// more repetitions, and each repetition contains one or more components.
+type Segment struct {
+	ID       string
+	Elements [][][]string
+}
+
+// Element returns the first component of the first repetition of an element
+// by its position, starting at 1, or an empty string if it does not exist.
+func (s Segment) Element(position int) string {
+	if position < 1 || position > len(s.Elements) {
+		return ""
+	}
+	return s.Elements[position-1][0][0]
+}
+
+// ToMap converts the segment into a generic structure where elements are
+// addressed by the segment ID followed by their two digit position, e.g. the
+// third element of a BEG segment has the key BEG03.
+//
+// Simple elements are strings, composite elements are arrays of strings, and
+// repeated elements are arrays of arrays of strings. Empty elements are
+// omitted.
+func (s Segment) ToMap() map[string]interface{} {
+	m := map[string]interface{}{"segment": s.ID}
+	for i, reps := range s.Elements {
+		if v := elementValue(reps); v != nil {
+			m[fmt.Sprintf("%v%02d", s.ID, i+1)] = v
+		}
+	}
+	return m
+}
+
+func trimComponents(comps []string) []string {
+	for len(comps) > 0 && comps[len(comps)-1] == "" {
+		comps = comps[:len(comps)-1]
+	}
+	return comps
+}
+
+func elementValue(reps [][]string) interface{} {
+	if len(reps) > 1 {
+		values := make([]interface{}, 0, len(reps))
+		for _, comps := range reps {
+			comps = trimComponents(comps)
+			values = append(values, toInterfaces(comps))
+		}
+		return values
+	}
+	comps := trimComponents(reps[0])
+	switch len(comps) {
+	case 0:
+		return nil
+	case 1:
+		return comps[0]
+	}
+	return toInterfaces(comps)
+}
+
+func toInterfaces(strs []string) []interface{} {
+	values := make([]interface{}, len(strs))
+	for i, s := range strs {
+		values[i] = s
+	}
+	return values
+}
+
+// splitSegments tokenizes a document into segments according to a set of
+// delimiters, removing release characters and whitespace between segments.
+func splitSegments(doc string, d Delimiters) ([]Segment, error) {
+	var segments []Segment
+	var elements [][][]string
+	var reps [][]string
+	var comps []string
+	var current strings.Builder
+
+	endComponent := func() {
+		comps = append(comps, current.String())
+		current.Reset()
+	}
+	endRepetition := func() {
+		endComponent()
+		reps = append(reps, comps)
+		comps = nil
+	}
+	endElement := func() {
+		endRepetition()
+		elements = append(elements, reps)
+		reps = nil
+	}
+	endSegment := func() error {
+		endElement()
+		id := strings.TrimSpace(elements[0][0][0])
+		if id == "" {
+			if len(elements) == 1 {
+				elements = nil
+				return nil
+			}
+			return fmt.Errorf("segment %v is missing an identifier", len(segments)+1)
+		}
+		segments = append(segments, Segment{ID: id, Elements: elements[1:]})
+		elements = nil
+		return nil
+	}
+
+	for i := 0; i < len(doc); i++ {
+		c := doc[i]
+		switch {
+		case d.Release != 0 && c == d.Release:
+			if i++; i < len(doc) {
+				current.WriteByte(doc[i])
+			}
+		case c == d.Segment:
+			if err := endSegment(); err != nil {
+				return nil, err
+			}
+			// Skip line breaks commonly added after segment terminators.
+			for i+1 < len(doc) && (doc[i+1] == '\r' || doc[i+1] == '\n') && d.Segment != '\r' && d.Segment != '\n' {
+				i++
+			}
+		case c == d.Element:
+			endElement()
+		case d.Repetition != 0 && c == d.Repetition:
+			endRepetition()
+		case d.Component != 0 && c == d.Component:
+			endComponent()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" || len(elements) > 0 || len(reps) > 0 || len(comps) > 0 {
+		if err := endSegment(); err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
@@ -0,0 +1,246 @@
+package edi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// x12ISALength is the fixed length of an X12 ISA segment, including its
+// terminator.
+const x12ISALength = 106
+
+// detectX12 returns the delimiters of an X12 document from its ISA segment.
+func detectX12(doc string) (Delimiters, error) {
+	if len(doc) < x12ISALength || !strings.HasPrefix(doc, "ISA") {
+		return Delimiters{}, errors.New("document does not begin with a complete ISA segment")
+	}
+	d := Delimiters{
+		Element:   doc[3],
+		Component: doc[104],
+		Segment:   doc[105],
+	}
+	// The repetition separator replaced the standards identifier of ISA11 in
+	// version 00402.
+	if rep := doc[82]; rep != 'U' && rep != d.Element {
+		d.Repetition = rep
+	}
+	return d, nil
+}
+
+func parseX12(doc string, validate bool) (map[string]interface{}, error) {
+	d, err := detectX12(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ISA segment contains the delimiters themselves and therefore is
+	// only split by elements.
+	isa, err := splitSegments(doc[:x12ISALength], Delimiters{Element: d.Element, Segment: d.Segment})
+	if err != nil {
+		return nil, err
+	}
+	if len(isa) != 1 || len(isa[0].Elements) != 16 {
+		return nil, errors.New("ISA segment must contain 16 elements")
+	}
+	for _, reps := range isa[0].Elements {
+		reps[0][0] = strings.TrimSpace(reps[0][0])
+	}
+
+	segments, err := splitSegments(doc[x12ISALength:], d)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &envelopeBuilder{
+		validate:     validate,
+		interchange:  "ISA",
+		group:        "GS",
+		message:      "ST",
+		messagesKey:  "transactions",
+		messageKind:  "transaction",
+		groupsOnly:   true,
+		interchangeT: "IEA",
+		groupT:       "GE",
+		messageT:     "SE",
+		checks: envelopeChecks{
+			messageCount:       1,
+			messageControl:     [2]int{2, 2},
+			groupCount:         1,
+			groupControl:       [2]int{6, 2},
+			interchangeCount:   1,
+			interchangeControl: [2]int{13, 2},
+		},
+	}
+	if err := b.add(isa[0]); err != nil {
+		return nil, err
+	}
+	for _, s := range segments {
+		if err := b.add(s); err != nil {
+			return nil, err
+		}
+	}
+	root, err := b.finish()
+	if err != nil {
+		return nil, err
+	}
+	root["standard"] = "x12"
+	root["delimiters"] = d.ToMap()
+	return root, nil
+}
+
+//------------------------------------------------------------------------------
+
+// envelopeChecks describes the positions of elements used to verify the
+// integrity of envelopes. Counts are the positions of elements in trailers
+// that count the contents of an envelope, and controls are pairs of the
+// positions of control numbers within the header and the trailer.
+type envelopeChecks struct {
+	messageCount       int
+	messageControl     [2]int
+	groupCount         int
+	groupControl       [2]int
+	interchangeCount   int
+	interchangeControl [2]int
+}
+
+type envelope struct {
+	header   Segment
+	contents []interface{}
+	count    int
+	obj      map[string]interface{}
+}
+
+// envelopeBuilder assembles segments into a hierarchy of interchange, group
+// and message envelopes.
+type envelopeBuilder struct {
+	validate bool
+
+	interchange, group, message    string
+	interchangeT, groupT, messageT string
+	messagesKey                    string
+	messageKind                    string
+	groupsOnly                     bool
+	checks                         envelopeChecks
+
+	root map[string]interface{}
+	ic   *envelope
+	grp  *envelope
+	msg  *envelope
+}
+
+func (b *envelopeBuilder) checkTrailer(kind string, env *envelope, trailer Segment, countPos int, control [2]int, count int) error {
+	if !b.validate {
+		return nil
+	}
+	if n, err := strconv.Atoi(trailer.Element(countPos)); err != nil || n != count {
+		return fmt.Errorf("%v %v count %v does not match the actual count %v", kind, trailer.ID, trailer.Element(countPos), count)
+	}
+	if h, t := env.header.Element(control[0]), trailer.Element(control[1]); h != t {
+		return fmt.Errorf("%v control number %v of %v does not match %v of %v", kind, t, trailer.ID, h, env.header.ID)
+	}
+	return nil
+}
+
+func (b *envelopeBuilder) open(header Segment) *envelope {
+	return &envelope{header: header, obj: map[string]interface{}{"header": header.ToMap()}}
+}
+
+func (b *envelopeBuilder) add(s Segment) error {
+	switch s.ID {
+	case b.interchange:
+		if b.ic != nil || b.root != nil {
+			return errors.New("documents with multiple interchanges are not supported")
+		}
+		b.ic = b.open(s)
+		return nil
+	case b.group:
+		if b.ic == nil || b.grp != nil || b.msg != nil {
+			return fmt.Errorf("unexpected %v segment", s.ID)
+		}
+		b.grp = b.open(s)
+		return nil
+	case b.message:
+		if b.ic == nil || b.msg != nil || (b.groupsOnly && b.grp == nil) {
+			return fmt.Errorf("unexpected %v segment", s.ID)
+		}
+		b.msg = b.open(s)
+		b.msg.count = 1
+		return nil
+	case b.messageT:
+		if b.msg == nil {
+			return fmt.Errorf("unexpected %v segment", s.ID)
+		}
+		b.msg.count++
+		if err := b.checkTrailer(b.messageKind, b.msg, s, b.checks.messageCount, b.checks.messageControl, b.msg.count); err != nil {
+			return err
+		}
+		b.msg.obj["segments"] = b.msg.contents
+		b.msg.obj["trailer"] = s.ToMap()
+		parent := b.ic
+		if b.grp != nil {
+			parent = b.grp
+		}
+		parent.contents = append(parent.contents, b.msg.obj)
+		b.msg = nil
+		return nil
+	case b.groupT:
+		if b.grp == nil || b.msg != nil {
+			return fmt.Errorf("unexpected %v segment", s.ID)
+		}
+		if err := b.checkTrailer("group", b.grp, s, b.checks.groupCount, b.checks.groupControl, len(b.grp.contents)); err != nil {
+			return err
+		}
+		b.grp.obj[b.messagesKey] = b.grp.contents
+		b.grp.obj["trailer"] = s.ToMap()
+		b.ic.count++
+		b.ic.obj["groups"] = append(groupsOf(b.ic.obj), b.grp.obj)
+		b.grp = nil
+		return nil
+	case b.interchangeT:
+		if b.ic == nil || b.grp != nil || b.msg != nil {
+			return fmt.Errorf("unexpected %v segment", s.ID)
+		}
+		count := b.ic.count
+		if count == 0 {
+			count = len(b.ic.contents)
+		}
+		if err := b.checkTrailer("interchange", b.ic, s, b.checks.interchangeCount, b.checks.interchangeControl, count); err != nil {
+			return err
+		}
+		if len(b.ic.contents) > 0 {
+			b.ic.obj[b.messagesKey] = b.ic.contents
+		}
+		b.ic.obj["trailer"] = s.ToMap()
+		b.root = map[string]interface{}{"interchange": b.ic.obj}
+		b.ic = nil
+		return nil
+	}
+
+	if b.msg == nil {
+		return fmt.Errorf("segment %v is outside of a %v", s.ID, b.messageKind)
+	}
+	b.msg.count++
+	b.msg.contents = append(b.msg.contents, s.ToMap())
+	return nil
+}
+
+func groupsOf(obj map[string]interface{}) []interface{} {
+	groups, _ := obj["groups"].([]interface{})
+	return groups
+}
+
+func (b *envelopeBuilder) finish() (map[string]interface{}, error) {
+	switch {
+	case b.msg != nil:
+		return nil, fmt.Errorf("missing %v segment", b.messageT)
+	case b.grp != nil:
+		return nil, fmt.Errorf("missing %v segment", b.groupT)
+	case b.ic != nil:
+		return nil, fmt.Errorf("missing %v segment", b.interchangeT)
+	case b.root == nil:
+		return nil, fmt.Errorf("missing %v segment", b.interchange)
+	}
+	return b.root, nil
+}
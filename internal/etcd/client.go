@@ -0,0 +1,202 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Int is an int64 encoded as a JSON string, as the gateway does.
+type Int int64
+
+// MarshalJSON encodes the int as a JSON string.
+func (i Int) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+// UnmarshalJSON decodes the int from either a JSON string or number, where an
+// empty value is zero.
+func (i *Int) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	*i = Int(v)
+	return err
+}
+
+// Encode returns a key or value encoded as base64, as the gateway expects.
+func Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// Decode returns a key or value decoded from the base64 of the gateway.
+func Decode(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	return string(b), err
+}
+
+// KeyValue is a key and value stored within etcd.
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Lease Int    `json:"lease"`
+}
+
+// Client makes requests to the gateway of any of a list of endpoints of an etcd
+// cluster.
+type Client struct {
+	endpoints []string
+	client    *http.Client
+}
+
+// NewClient creates a client of an etcd cluster, reached via any of a list of
+// endpoints.
+func NewClient(endpoints []string) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one etcd endpoint must be specified")
+	}
+	trimmed := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		trimmed[i] = strings.TrimSuffix(e, "/")
+	}
+	return &Client{
+		endpoints: trimmed,
+		client:    &http.Client{},
+	}, nil
+}
+
+// Call makes a request to each endpoint in turn until one responds, decoding
+// the response into res.
+func (c *Client) Call(ctx context.Context, path string, req, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, e := range c.endpoints {
+		var hreq *http.Request
+		if hreq, err = http.NewRequestWithContext(ctx, http.MethodPost, e+path, bytes.NewReader(body)); err != nil {
+			return err
+		}
+		hreq.Header.Set("Content-Type", "application/json")
+
+		var hres *http.Response
+		if hres, lastErr = c.client.Do(hreq); lastErr != nil {
+			if ctx.Err() != nil {
+				return lastErr
+			}
+			continue
+		}
+		resBytes, err := ioutil.ReadAll(io.LimitReader(hres.Body, 1024*1024))
+		hres.Body.Close()
+		if err != nil {
+			return err
+		}
+		if hres.StatusCode < 200 || hres.StatusCode > 299 {
+			var sErr struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(resBytes, &sErr) != nil || sErr.Message == "" {
+				sErr.Message = strings.TrimSpace(string(resBytes))
+			}
+			return fmt.Errorf("etcd responded with status %v: %v", hres.StatusCode, sErr.Message)
+		}
+		return json.Unmarshal(resBytes, res)
+	}
+	return lastErr
+}
+
+// Grant creates a lease that expires after a ttl, rounded up to the second.
+func (c *Client) Grant(ctx context.Context, ttl time.Duration) (int64, error) {
+	var res struct {
+		ID Int `json:"ID"`
+	}
+	err := c.Call(ctx, "/v3/lease/grant", map[string]interface{}{
+		"TTL": Int(math.Ceil(ttl.Seconds())),
+	}, &res)
+	return int64(res.ID), err
+}
+
+// KeepAlive renews a lease, returning false if it has already expired.
+func (c *Client) KeepAlive(ctx context.Context, id int64) (bool, error) {
+	var res struct {
+		Result struct {
+			TTL Int `json:"TTL"`
+		} `json:"result"`
+	}
+	err := c.Call(ctx, "/v3/lease/keepalive", map[string]interface{}{
+		"ID": Int(id),
+	}, &res)
+	return res.Result.TTL > 0, err
+}
+
+// Revoke revokes a lease, deleting all keys attached to it.
+func (c *Client) Revoke(ctx context.Context, id int64) error {
+	var res struct{}
+	return c.Call(ctx, "/v3/lease/revoke", map[string]interface{}{
+		"ID": Int(id),
+	}, &res)
+}
+
+// Put writes a value to a key, attached to a lease unless the lease is zero.
+func (c *Client) Put(ctx context.Context, key, value string, lease int64) error {
+	req := map[string]interface{}{
+		"key":   Encode(key),
+		"value": Encode(value),
+	}
+	if lease != 0 {
+		req["lease"] = Int(lease)
+	}
+	var res struct{}
+	return c.Call(ctx, "/v3/kv/put", req, &res)
+}
+
+// prefixEnd returns the end of the range of keys that share a prefix.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
+
+// RangePrefix returns all keys that begin with a prefix, with their keys and
+// values decoded.
+func (c *Client) RangePrefix(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var res struct {
+		Kvs []KeyValue `json:"kvs"`
+	}
+	if err := c.Call(ctx, "/v3/kv/range", map[string]interface{}{
+		"key":       Encode(prefix),
+		"range_end": Encode(prefixEnd(prefix)),
+	}, &res); err != nil {
+		return nil, err
+	}
+	for i, kv := range res.Kvs {
+		var err error
+		if res.Kvs[i].Key, err = Decode(kv.Key); err != nil {
+			return nil, err
+		}
+		if res.Kvs[i].Value, err = Decode(kv.Value); err != nil {
+			return nil, err
+		}
+	}
+	return res.Kvs, nil
+}
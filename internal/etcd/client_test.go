@@ -0,0 +1,324 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKV struct {
+	value string
+	lease int64
+}
+
+// fakeGateway is a minimal fake of the JSON gateway of etcd, where keys
+// attached to a lease are deleted when the lease expires or is revoked.
+type fakeGateway struct {
+	mut    sync.Mutex
+	nextID int64
+	leases map[int64]int64
+	kvs    map[string]fakeKV
+
+	errStatus int
+	errBody   string
+	reqs      []string
+}
+
+func newFakeGateway() *fakeGateway {
+	return &fakeGateway{
+		leases: map[int64]int64{},
+		kvs:    map[string]fakeKV{},
+	}
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.reqs = append(f.reqs, r.URL.Path)
+	if f.errStatus != 0 {
+		w.WriteHeader(f.errStatus)
+		w.Write([]byte(f.errBody))
+		return
+	}
+
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parseInt := func(v interface{}) int64 {
+		s, _ := v.(string)
+		i, _ := strconv.ParseInt(s, 10, 64)
+		return i
+	}
+	decode := func(v interface{}) string {
+		s, _ := v.(string)
+		d, _ := Decode(s)
+		return d
+	}
+
+	var res interface{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextID++
+		f.leases[f.nextID] = parseInt(req["TTL"])
+		res = map[string]interface{}{"ID": strconv.FormatInt(f.nextID, 10), "TTL": req["TTL"]}
+	case "/v3/lease/keepalive":
+		// Expired leases are reported without a TTL.
+		result := map[string]interface{}{"ID": req["ID"]}
+		if ttl, exists := f.leases[parseInt(req["ID"])]; exists {
+			result["TTL"] = strconv.FormatInt(ttl, 10)
+		}
+		res = map[string]interface{}{"result": result}
+	case "/v3/lease/revoke":
+		id := parseInt(req["ID"])
+		if _, exists := f.leases[id]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "etcdserver: requested lease not found",
+				"code":    5,
+				"message": "etcdserver: requested lease not found",
+			})
+			return
+		}
+		f.expireLocked(id)
+		res = map[string]interface{}{}
+	case "/v3/kv/put":
+		lease := parseInt(req["lease"])
+		if _, exists := f.leases[lease]; lease != 0 && !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "etcdserver: requested lease not found",
+			})
+			return
+		}
+		f.kvs[decode(req["key"])] = fakeKV{value: decode(req["value"]), lease: lease}
+		res = map[string]interface{}{}
+	case "/v3/kv/range":
+		start, end := decode(req["key"]), decode(req["range_end"])
+		var keys []string
+		for k := range f.kvs {
+			if k >= start && k < end {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		kvs := []interface{}{}
+		for _, k := range keys {
+			kvs = append(kvs, map[string]interface{}{
+				"key":   Encode(k),
+				"value": Encode(f.kvs[k].value),
+				"lease": strconv.FormatInt(f.kvs[k].lease, 10),
+			})
+		}
+		// The gateway omits empty fields, including the kvs of an empty range.
+		if len(kvs) == 0 {
+			res = map[string]interface{}{"count": "0"}
+		} else {
+			res = map[string]interface{}{"kvs": kvs, "count": strconv.Itoa(len(kvs))}
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeGateway) expireLocked(id int64) {
+	delete(f.leases, id)
+	for k, kv := range f.kvs {
+		if kv.lease == id {
+			delete(f.kvs, k)
+		}
+	}
+}
+
+// expire simulates the expiry of a lease.
+func (f *fakeGateway) expire(id int64) {
+	f.mut.Lock()
+	f.expireLocked(id)
+	f.mut.Unlock()
+}
+
+func (f *fakeGateway) setErr(status int, body string) {
+	f.mut.Lock()
+	f.errStatus, f.errBody = status, body
+	f.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+func TestClientLeases(t *testing.T) {
+	fake := newFakeGateway()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c, err := NewClient([]string{srv.URL + "/"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	id, err := c.Grant(ctx, time.Millisecond*1500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, int64(2), fake.leases[id], "ttl is rounded up to the second")
+
+	require.NoError(t, c.Put(ctx, "foo/a", "a", id))
+	require.NoError(t, c.Put(ctx, "foo/b", "b", 0))
+
+	alive, err := c.KeepAlive(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, alive)
+
+	// Keys attached to an expired lease are deleted.
+	fake.expire(id)
+	alive, err = c.KeepAlive(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, alive)
+
+	kvs, err := c.RangePrefix(ctx, "foo/")
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{{Key: "foo/b", Value: "b"}}, kvs)
+
+	err = c.Put(ctx, "foo/c", "c", id)
+	require.EqualError(t, err, "etcd responded with status 404: etcdserver: requested lease not found")
+
+	// Keys attached to a revoked lease are deleted.
+	id, err = c.Grant(ctx, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, c.Put(ctx, "foo/c", "c", id))
+
+	kvs, err = c.RangePrefix(ctx, "foo/")
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{
+		{Key: "foo/b", Value: "b"},
+		{Key: "foo/c", Value: "c", Lease: Int(id)},
+	}, kvs)
+
+	require.NoError(t, c.Revoke(ctx, id))
+	kvs, err = c.RangePrefix(ctx, "foo/")
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{{Key: "foo/b", Value: "b"}}, kvs)
+
+	err = c.Revoke(ctx, id)
+	require.EqualError(t, err, "etcd responded with status 404: etcdserver: requested lease not found")
+}
+
+func TestClientRangePrefix(t *testing.T) {
+	fake := newFakeGateway()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c, err := NewClient([]string{srv.URL})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	kvs, err := c.RangePrefix(ctx, "foo/")
+	require.NoError(t, err)
+	assert.Empty(t, kvs)
+
+	for _, k := range []string{"foo", "foo/a", "foo/b/c", "foo0", "fop/a"} {
+		require.NoError(t, c.Put(ctx, k, "value of "+k, 0))
+	}
+
+	kvs, err = c.RangePrefix(ctx, "foo/")
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValue{
+		{Key: "foo/a", Value: "value of foo/a"},
+		{Key: "foo/b/c", Value: "value of foo/b/c"},
+	}, kvs)
+}
+
+func TestClientErrors(t *testing.T) {
+	fake := newFakeGateway()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	_, err := NewClient(nil)
+	require.EqualError(t, err, "at least one etcd endpoint must be specified")
+
+	c, err := NewClient([]string{srv.URL})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	fake.setErr(http.StatusServiceUnavailable, `{"error":"etcdserver: no leader","code":14,"message":"etcdserver: no leader"}`)
+	_, err = c.Grant(ctx, time.Second)
+	require.EqualError(t, err, "etcd responded with status 503: etcdserver: no leader")
+
+	fake.setErr(http.StatusBadGateway, "bad gateway\n")
+	_, err = c.RangePrefix(ctx, "foo/")
+	require.EqualError(t, err, "etcd responded with status 502: bad gateway")
+
+	fake.setErr(http.StatusOK, "not json")
+	_, err = c.KeepAlive(ctx, 1)
+	require.Error(t, err)
+
+	// Endpoints that are unreachable are skipped.
+	fake.setErr(0, "")
+	c, err = NewClient([]string{"http://127.0.0.1:1", srv.URL})
+	require.NoError(t, err)
+	_, err = c.Grant(ctx, time.Second)
+	require.NoError(t, err)
+
+	// Endpoints that respond with an error are not skipped.
+	fake.setErr(http.StatusInternalServerError, "nope")
+	c, err = NewClient([]string{srv.URL, srv.URL})
+	require.NoError(t, err)
+	fake.reqs = nil
+	_, err = c.Grant(ctx, time.Second)
+	require.EqualError(t, err, "etcd responded with status 500: nope")
+	assert.Len(t, fake.reqs, 1)
+
+	c, err = NewClient([]string{"http://127.0.0.1:1", "http://127.0.0.1:2"})
+	require.NoError(t, err)
+	_, err = c.Grant(ctx, time.Second)
+	require.Error(t, err)
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	c, err = NewClient([]string{srv.URL})
+	require.NoError(t, err)
+	_, err = c.Grant(cancelledCtx, time.Second)
+	require.Error(t, err)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for prefix, exp := range map[string]string{
+		"foo/":       "foo0",
+		"a":          "b",
+		"a\xff":      "b",
+		"\xff\xff":   "\x00",
+		"foo\xffbar": "foo\xffbas",
+	} {
+		assert.Equal(t, exp, prefixEnd(prefix), prefix)
+	}
+}
+
+func TestInt(t *testing.T) {
+	b, err := json.Marshal(Int(12345678901234))
+	require.NoError(t, err)
+	assert.Equal(t, `"12345678901234"`, string(b))
+
+	var v struct {
+		A Int `json:"a"`
+		B Int `json:"b"`
+		C Int `json:"c"`
+		D Int `json:"d"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a":"5","b":6,"c":""}`), &v))
+	assert.Equal(t, Int(5), v.A)
+	assert.Equal(t, Int(6), v.B)
+	assert.Equal(t, Int(0), v.C)
+	assert.Equal(t, Int(0), v.D)
+
+	require.Error(t, json.Unmarshal([]byte(`{"a":"nope"}`), &v))
+}
@@ -0,0 +1,3 @@
+// Package etcd implements a minimal client of the v3 API of etcd, made via the
+// JSON gateway that etcd v3.4 and later serves over HTTP.
+package etcd
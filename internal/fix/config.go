@@ -0,0 +1,123 @@
+package fix
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+// Config contains the configuration fields shared by the fix input and output
+// for establishing a session.
+type Config struct {
+	Mode              string            `json:"mode" yaml:"mode"`
+	Address           string            `json:"address" yaml:"address"`
+	BeginString       string            `json:"begin_string" yaml:"begin_string"`
+	SenderCompID      string            `json:"sender_comp_id" yaml:"sender_comp_id"`
+	TargetCompID      string            `json:"target_comp_id" yaml:"target_comp_id"`
+	HeartbeatInterval string            `json:"heartbeat_interval" yaml:"heartbeat_interval"`
+	LogonTimeout      string            `json:"logon_timeout" yaml:"logon_timeout"`
+	LogonFields       map[string]string `json:"logon_fields" yaml:"logon_fields"`
+	ResetOnLogon      bool              `json:"reset_on_logon" yaml:"reset_on_logon"`
+	StorePath         string            `json:"store_path" yaml:"store_path"`
+	TLS               btls.Config       `json:"tls" yaml:"tls"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Mode:              "initiator",
+		Address:           "",
+		BeginString:       "FIX.4.4",
+		SenderCompID:      "",
+		TargetCompID:      "",
+		HeartbeatInterval: "30s",
+		LogonTimeout:      "10s",
+		LogonFields:       map[string]string{},
+		ResetOnLogon:      false,
+		StorePath:         "",
+		TLS:               btls.NewConfig(),
+	}
+}
+
+// FieldSpecs returns the documentation of the fields of a Config.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("mode", "Whether to initiate the session by connecting to the counterparty, or to accept the session by listening for a connection from the counterparty.").HasOptions("initiator", "acceptor"),
+		docs.FieldCommon("address", "The address to connect to as an initiator, or to listen on as an acceptor.", "localhost:9876", "0.0.0.0:9876"),
+		docs.FieldCommon("begin_string", "The version of the protocol used by the session.", "FIX.4.2", "FIX.4.4", "FIXT.1.1"),
+		docs.FieldCommon("sender_comp_id", "The identifier of this side of the session."),
+		docs.FieldCommon("target_comp_id", "The identifier of the counterparty of the session."),
+		docs.FieldAdvanced("heartbeat_interval", "The heartbeat interval requested by an initiator. An acceptor uses the interval requested by the counterparty."),
+		docs.FieldAdvanced("logon_timeout", "The maximum period of time to wait for the counterparty to log on."),
+		docs.FieldAdvanced("logon_fields", "A map of additional fields to add to Logon messages, keyed by tag name or number.", map[string]string{
+			"Username": "${USERNAME}",
+			"Password": "${PASSWORD}",
+		}).Map(),
+		docs.FieldAdvanced("reset_on_logon", "Whether to reset sequence numbers at logon and request that the counterparty does the same."),
+		docs.FieldAdvanced("store_path", "A directory to persist the sequence numbers of the session within, so that they survive restarts. If left empty sequence numbers are kept in memory."),
+		btls.FieldSpec(),
+	}
+}
+
+// Endpoint returns an endpoint for establishing sessions from a Config.
+func (c Config) Endpoint(ignoreApplication bool) (*Endpoint, error) {
+	if c.Address == "" {
+		return nil, errors.New("an address must be specified")
+	}
+	if c.SenderCompID == "" || c.TargetCompID == "" {
+		return nil, errors.New("both a sender_comp_id and target_comp_id must be specified")
+	}
+
+	heartBtInt, err := time.ParseDuration(c.HeartbeatInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse heartbeat interval: %w", err)
+	}
+	if heartBtInt < time.Second && heartBtInt != 0 {
+		return nil, errors.New("heartbeat interval must be at least one second")
+	}
+	logonTimeout, err := time.ParseDuration(c.LogonTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse logon timeout: %w", err)
+	}
+
+	sessConf := SessionConfig{
+		BeginString:       c.BeginString,
+		SenderCompID:      c.SenderCompID,
+		TargetCompID:      c.TargetCompID,
+		HeartBtInt:        heartBtInt,
+		ResetOnLogon:      c.ResetOnLogon,
+		IgnoreApplication: ignoreApplication,
+	}
+	for name, value := range c.LogonFields {
+		tag, ok := TagByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unrecognised logon field: %v", name)
+		}
+		sessConf.LogonFields = append(sessConf.LogonFields, Field{Tag: tag, Value: value})
+	}
+
+	if c.StorePath != "" {
+		if sessConf.Store, err = NewFileStore(c.StorePath, c.BeginString, c.SenderCompID, c.TargetCompID); err != nil {
+			return nil, err
+		}
+	}
+
+	var tlsConf *tls.Config
+	if c.TLS.Enabled {
+		if tlsConf, err = c.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.Mode {
+	case "initiator":
+		return NewInitiator(c.Address, tlsConf, logonTimeout, sessConf), nil
+	case "acceptor":
+		return NewAcceptor(c.Address, tlsConf, logonTimeout, sessConf), nil
+	}
+	return nil, fmt.Errorf("mode not recognised: %v", c.Mode)
+}
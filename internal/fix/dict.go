@@ -0,0 +1,141 @@
+package fix
+
+import "strconv"
+
+// tagNames contains the names of commonly used tags, which are used as the
+// keys of messages converted into JSON.
+var tagNames = map[int]string{
+	1:    "Account",
+	6:    "AvgPx",
+	7:    "BeginSeqNo",
+	8:    "BeginString",
+	9:    "BodyLength",
+	10:   "CheckSum",
+	11:   "ClOrdID",
+	14:   "CumQty",
+	15:   "Currency",
+	16:   "EndSeqNo",
+	17:   "ExecID",
+	18:   "ExecInst",
+	20:   "ExecTransType",
+	21:   "HandlInst",
+	22:   "SecurityIDSource",
+	31:   "LastPx",
+	32:   "LastQty",
+	34:   "MsgSeqNum",
+	35:   "MsgType",
+	36:   "NewSeqNo",
+	37:   "OrderID",
+	38:   "OrderQty",
+	39:   "OrdStatus",
+	40:   "OrdType",
+	41:   "OrigClOrdID",
+	43:   "PossDupFlag",
+	44:   "Price",
+	45:   "RefSeqNum",
+	48:   "SecurityID",
+	49:   "SenderCompID",
+	50:   "SenderSubID",
+	52:   "SendingTime",
+	54:   "Side",
+	55:   "Symbol",
+	56:   "TargetCompID",
+	57:   "TargetSubID",
+	58:   "Text",
+	59:   "TimeInForce",
+	60:   "TransactTime",
+	64:   "SettlDate",
+	65:   "SymbolSfx",
+	73:   "NoOrders",
+	78:   "NoAllocs",
+	79:   "AllocAccount",
+	97:   "PossResend",
+	98:   "EncryptMethod",
+	99:   "StopPx",
+	100:  "ExDestination",
+	108:  "HeartBtInt",
+	112:  "TestReqID",
+	122:  "OrigSendingTime",
+	123:  "GapFillFlag",
+	126:  "ExpireTime",
+	141:  "ResetSeqNumFlag",
+	146:  "NoRelatedSym",
+	150:  "ExecType",
+	151:  "LeavesQty",
+	167:  "SecurityType",
+	207:  "SecurityExchange",
+	262:  "MDReqID",
+	263:  "SubscriptionRequestType",
+	264:  "MarketDepth",
+	265:  "MDUpdateType",
+	267:  "NoMDEntryTypes",
+	268:  "NoMDEntries",
+	269:  "MDEntryType",
+	270:  "MDEntryPx",
+	271:  "MDEntrySize",
+	272:  "MDEntryDate",
+	273:  "MDEntryTime",
+	278:  "MDEntryID",
+	279:  "MDUpdateAction",
+	311:  "UnderlyingSymbol",
+	371:  "RefTagID",
+	372:  "RefMsgType",
+	373:  "SessionRejectReason",
+	447:  "PartyIDSource",
+	448:  "PartyID",
+	452:  "PartyRole",
+	453:  "NoPartyIDs",
+	454:  "NoSecurityAltID",
+	455:  "SecurityAltID",
+	553:  "Username",
+	554:  "Password",
+	555:  "NoLegs",
+	600:  "LegSymbol",
+	711:  "NoUnderlyings",
+	789:  "NextExpectedMsgSeqNum",
+	1128: "ApplVerID",
+	1137: "DefaultApplVerID",
+}
+
+// groupDelimiters maps the count tags of common repeating groups to the tag
+// that must appear first within each of their entries.
+var groupDelimiters = map[int]int{
+	73:  11,
+	78:  79,
+	146: 55,
+	267: 269,
+	268: 269,
+	453: 448,
+	454: 455,
+	555: 600,
+	711: 311,
+}
+
+var tagsByName = func() map[string]int {
+	m := make(map[string]int, len(tagNames))
+	for tag, name := range tagNames {
+		m[name] = tag
+	}
+	return m
+}()
+
+// TagName returns the name of a tag, or the tag number as a string if the
+// name is not known.
+func TagName(tag int) string {
+	if name, exists := tagNames[tag]; exists {
+		return name
+	}
+	return strconv.Itoa(tag)
+}
+
+// TagByName returns the tag of a name, which can also be a tag number, and
+// whether it was recognised.
+func TagByName(name string) (int, bool) {
+	if tag, exists := tagsByName[name]; exists {
+		return tag, true
+	}
+	if tag, err := strconv.Atoi(name); err == nil && tag > 0 {
+		return tag, true
+	}
+	return 0, false
+}
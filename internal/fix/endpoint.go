@@ -0,0 +1,223 @@
+package fix
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrNoSession is returned by an initiator endpoint when it does not have an
+// active session.
+var ErrNoSession = errors.New("no active session")
+
+// Endpoint establishes sessions with a counterparty, either by dialling an
+// address as the initiator or by listening on an address as the acceptor.
+// Only one session is active at a time.
+type Endpoint struct {
+	address      string
+	acceptor     bool
+	tlsConf      *tls.Config
+	conf         SessionConfig
+	logonTimeout time.Duration
+
+	// OnAcceptError is called with the errors of connections to an acceptor
+	// that did not result in a session.
+	OnAcceptError func(err error)
+
+	mut      sync.Mutex
+	session  *Session
+	ready    chan struct{}
+	listener net.Listener
+	closed   chan struct{}
+}
+
+// NewInitiator returns an endpoint that establishes sessions by dialling an
+// address, with TLS when a TLS config is provided.
+func NewInitiator(address string, tlsConf *tls.Config, logonTimeout time.Duration, conf SessionConfig) *Endpoint {
+	return newEndpoint(address, false, tlsConf, logonTimeout, conf)
+}
+
+// NewAcceptor returns an endpoint that establishes sessions by listening on an
+// address, with TLS when a TLS config is provided.
+func NewAcceptor(address string, tlsConf *tls.Config, logonTimeout time.Duration, conf SessionConfig) *Endpoint {
+	return newEndpoint(address, true, tlsConf, logonTimeout, conf)
+}
+
+func newEndpoint(address string, acceptor bool, tlsConf *tls.Config, logonTimeout time.Duration, conf SessionConfig) *Endpoint {
+	if conf.Store == nil {
+		conf.Store = NewMemoryStore()
+	}
+	return &Endpoint{
+		address:       address,
+		acceptor:      acceptor,
+		tlsConf:       tlsConf,
+		conf:          conf,
+		logonTimeout:  logonTimeout,
+		OnAcceptError: func(error) {},
+		ready:         make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+}
+
+func (e *Endpoint) activeSession() *Session {
+	if e.session != nil && e.session.Err() == nil {
+		return e.session
+	}
+	return nil
+}
+
+// Connect establishes a session when the endpoint is an initiator without an
+// active session, or begins listening for connections when the endpoint is an
+// acceptor.
+func (e *Endpoint) Connect(ctx context.Context) error {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	if e.acceptor {
+		if e.listener != nil {
+			return nil
+		}
+		listener, err := net.Listen("tcp", e.address)
+		if err != nil {
+			return err
+		}
+		if e.tlsConf != nil {
+			listener = tls.NewListener(listener, e.tlsConf)
+		}
+		e.listener = listener
+		go e.acceptLoop(listener)
+		return nil
+	}
+
+	if e.activeSession() != nil {
+		return nil
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.address)
+	if err != nil {
+		return err
+	}
+	if e.tlsConf != nil {
+		conn = tls.Client(conn, e.tlsConf)
+	}
+
+	logonCtx, done := context.WithTimeout(ctx, e.logonTimeout)
+	defer done()
+	session, err := Initiate(logonCtx, conn, e.conf)
+	if err != nil {
+		return err
+	}
+	e.setSession(session)
+	return nil
+}
+
+func (e *Endpoint) setSession(s *Session) {
+	e.session = s
+	close(e.ready)
+	e.ready = make(chan struct{})
+}
+
+func (e *Endpoint) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-e.closed:
+				return
+			default:
+			}
+			e.OnAcceptError(err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		e.mut.Lock()
+		active := e.activeSession() != nil
+		e.mut.Unlock()
+		if active {
+			conn.Close()
+			e.OnAcceptError(errors.New("rejected connection as a session is already active"))
+			continue
+		}
+
+		ctx, done := context.WithTimeout(context.Background(), e.logonTimeout)
+		session, err := Accept(ctx, conn, e.conf)
+		done()
+		if err != nil {
+			e.OnAcceptError(err)
+			continue
+		}
+
+		e.mut.Lock()
+		select {
+		case <-e.closed:
+			session.Close(time.Second)
+		default:
+			e.setSession(session)
+		}
+		e.mut.Unlock()
+	}
+}
+
+// Session returns the active session of the endpoint. An initiator returns
+// ErrNoSession when it does not have an active session, and an acceptor
+// blocks until a session is established or the context is cancelled.
+func (e *Endpoint) Session(ctx context.Context) (*Session, error) {
+	for {
+		e.mut.Lock()
+		session, ready := e.activeSession(), e.ready
+		e.mut.Unlock()
+
+		if session != nil {
+			return session, nil
+		}
+		if !e.acceptor {
+			return nil, ErrNoSession
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-e.closed:
+			return nil, ErrSessionClosed
+		}
+	}
+}
+
+// Close the endpoint by logging out of any active session and closing the
+// listener of an acceptor.
+func (e *Endpoint) Close(timeout time.Duration) {
+	e.mut.Lock()
+	select {
+	case <-e.closed:
+		e.mut.Unlock()
+		return
+	default:
+	}
+	close(e.closed)
+	if e.listener != nil {
+		e.listener.Close()
+	}
+	session := e.session
+	e.mut.Unlock()
+
+	if session != nil {
+		session.Close(timeout)
+	}
+}
+
+// Addr returns the address that an acceptor is listening on, or nil if it is
+// not listening.
+func (e *Endpoint) Addr() net.Addr {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	if e.listener == nil {
+		return nil
+	}
+	return e.listener.Addr()
+}
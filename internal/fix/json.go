@@ -0,0 +1,238 @@
+package fix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ToMap converts a message into a map of tag names to values. The BodyLength
+// and CheckSum fields are omitted, tags without a known name are keyed by
+// their number, and tags that appear multiple times, such as the fields of
+// repeating groups, are converted into arrays of values in the order that
+// they appear.
+func (m *Message) ToMap() map[string]interface{} {
+	obj := map[string]interface{}{}
+	for _, f := range m.Fields {
+		if f.Tag == TagBodyLength || f.Tag == TagCheckSum {
+			continue
+		}
+		key := TagName(f.Tag)
+		switch existing := obj[key].(type) {
+		case nil:
+			obj[key] = f.Value
+		case string:
+			obj[key] = []interface{}{existing, f.Value}
+		case []interface{}:
+			obj[key] = append(existing, f.Value)
+		}
+	}
+	return obj
+}
+
+//------------------------------------------------------------------------------
+
+type jsonField struct {
+	tag     int
+	value   string
+	values  []string
+	entries [][]jsonField
+}
+
+func (f jsonField) isArray() bool {
+	return f.values != nil
+}
+
+// FromJSON converts a JSON object of tag names or numbers to values into a
+// message. Fields are added in the order that they appear within the
+// document.
+//
+// Arrays of values are converted into repeated tags, where the values of
+// consecutive arrays are interleaved so that each entry of a repeating group
+// is written in turn, and the delimiter of common repeating groups is written
+// first within each entry. Arrays of objects are converted into repeating
+// groups where the key is the count tag of the group and each object is an
+// entry.
+func FromJSON(b []byte) (*Message, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return nil, errors.New("expected a JSON object")
+	}
+	fields, err := decodeJSONObject(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Message{}
+	writeJSONFields(m, fields)
+	return m, nil
+}
+
+func decodeJSONObject(dec *json.Decoder) ([]jsonField, error) {
+	var fields []jsonField
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := t.(string)
+		tag, ok := TagByName(key)
+		if !ok {
+			return nil, fmt.Errorf("unrecognised tag name: %v", key)
+		}
+		f, skip, err := decodeJSONValue(dec)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", key, err)
+		}
+		if skip {
+			continue
+		}
+		f.tag = tag
+		fields = append(fields, f)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func jsonScalar(t json.Token) (string, bool, error) {
+	switch v := t.(type) {
+	case string:
+		return v, false, nil
+	case json.Number:
+		return v.String(), false, nil
+	case bool:
+		if v {
+			return "Y", false, nil
+		}
+		return "N", false, nil
+	case nil:
+		return "", true, nil
+	}
+	return "", false, fmt.Errorf("unexpected token: %v", t)
+}
+
+func decodeJSONValue(dec *json.Decoder) (f jsonField, skip bool, err error) {
+	t, err := dec.Token()
+	if err != nil {
+		return f, false, err
+	}
+	d, isDelim := t.(json.Delim)
+	if !isDelim {
+		f.value, skip, err = jsonScalar(t)
+		return f, skip, err
+	}
+	if d != '[' {
+		return f, false, errors.New("objects are only supported as the entries of repeating groups")
+	}
+
+	f.values = []string{}
+	for dec.More() {
+		if t, err = dec.Token(); err != nil {
+			return f, false, err
+		}
+		if d, isDelim := t.(json.Delim); isDelim && d == '{' {
+			entry, err := decodeJSONObject(dec)
+			if err != nil {
+				return f, false, err
+			}
+			f.entries = append(f.entries, entry)
+			continue
+		}
+		v, skipValue, err := jsonScalar(t)
+		if err != nil {
+			return f, false, err
+		}
+		if !skipValue {
+			f.values = append(f.values, v)
+		}
+	}
+	if len(f.entries) > 0 && len(f.values) > 0 {
+		return f, false, errors.New("arrays must not mix values and objects")
+	}
+	if _, err = dec.Token(); err != nil {
+		return f, false, err
+	}
+	return f, false, nil
+}
+
+func writeJSONFields(m *Message, fields []jsonField) {
+	for i := 0; i < len(fields); {
+		f := fields[i]
+		if f.entries != nil {
+			m.Add(f.tag, strconv.Itoa(len(f.entries)))
+			delim := groupDelimiters[f.tag]
+			for _, entry := range f.entries {
+				writeJSONFields(m, delimiterFirst(entry, delim))
+			}
+			i++
+			continue
+		}
+		if !f.isArray() {
+			m.Add(f.tag, f.value)
+			i++
+			continue
+		}
+
+		// Interleave a run of consecutive arrays of values.
+		j := i
+		for j < len(fields) && fields[j].isArray() && fields[j].entries == nil {
+			j++
+		}
+		run := fields[i:j]
+		for _, rf := range run {
+			if delimiterTags[rf.tag] {
+				run = delimiterFirst(run, rf.tag)
+				break
+			}
+		}
+		for n := 0; ; n++ {
+			written := false
+			for _, rf := range run {
+				if n < len(rf.values) {
+					m.Add(rf.tag, rf.values[n])
+					written = true
+				}
+			}
+			if !written {
+				break
+			}
+		}
+		i = j
+	}
+}
+
+var delimiterTags = func() map[int]bool {
+	m := make(map[int]bool, len(groupDelimiters))
+	for _, delim := range groupDelimiters {
+		m[delim] = true
+	}
+	return m
+}()
+
+func delimiterFirst(fields []jsonField, delim int) []jsonField {
+	if delim == 0 {
+		return fields
+	}
+	for i, f := range fields {
+		if f.tag == delim {
+			if i == 0 {
+				return fields
+			}
+			ordered := make([]jsonField, 0, len(fields))
+			ordered = append(ordered, f)
+			ordered = append(ordered, fields[:i]...)
+			return append(ordered, fields[i+1:]...)
+		}
+	}
+	return fields
+}
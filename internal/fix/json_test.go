@@ -0,0 +1,70 @@
+package fix
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMap(t *testing.T) {
+	fields, err := ParseFields([]byte("8=FIX.4.4|35=W|49=A|56=B|34=3|55=EUR/USD|268=2|269=0|270=1.1|269=1|270=1.2|9999=x"), '|')
+	require.NoError(t, err)
+
+	msg, err := Parse(fields.Bytes())
+	require.NoError(t, err)
+
+	b, err := json.Marshal(msg.ToMap())
+	require.NoError(t, err)
+	assert.Equal(t, `{"9999":"x","BeginString":"FIX.4.4","MDEntryPx":["1.1","1.2"],"MDEntryType":["0","1"],"MsgSeqNum":"3","MsgType":"W","NoMDEntries":"2","SenderCompID":"A","Symbol":"EUR/USD","TargetCompID":"B"}`, string(b))
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		output string
+		err    string
+	}{
+		"scalars": {
+			input:  `{"MsgType":"D","ClOrdID":"O1","Side":1,"OrderQty":100.5,"38":null,"PossDupFlag":true,"Symbol":"AAPL"}`,
+			output: "35=D|11=O1|54=1|38=100.5|43=Y|55=AAPL",
+		},
+		"interleaved arrays": {
+			input:  `{"MsgType":"W","NoMDEntries":2,"MDEntryPx":["1.1","1.2"],"MDEntryType":["0","1"],"MDEntrySize":[5]}`,
+			output: "35=W|268=2|269=0|270=1.1|271=5|269=1|270=1.2",
+		},
+		"groups": {
+			input:  `{"MsgType":"V","NoMDEntryTypes":[{"MDEntryType":"0"},{"MDEntryType":"1"}],"NoRelatedSym":[{"SecurityID":"X","Symbol":"EUR/USD"}]}`,
+			output: "35=V|267=2|269=0|269=1|146=1|55=EUR/USD|48=X",
+		},
+		"unknown name": {
+			input: `{"MsgType":"D","Nope":"x"}`,
+			err:   "unrecognised tag name: Nope",
+		},
+		"nested object": {
+			input: `{"MsgType":"D","Symbol":{"a":"b"}}`,
+			err:   "field Symbol: objects are only supported as the entries of repeating groups",
+		},
+		"mixed array": {
+			input: `{"MsgType":"D","NoPartyIDs":[{"PartyID":"a"},"b"]}`,
+			err:   "field NoPartyIDs: arrays must not mix values and objects",
+		},
+		"not an object": {
+			input: `["35","D"]`,
+			err:   "expected a JSON object",
+		},
+	}
+	for name, test := range tests {
+		msg, err := FromJSON([]byte(test.input))
+		if test.err != "" {
+			require.Error(t, err, name)
+			assert.Equal(t, test.err, err.Error(), name)
+			continue
+		}
+		require.NoError(t, err, name)
+		exp, err := ParseFields([]byte(test.output), '|')
+		require.NoError(t, err, name)
+		assert.Equal(t, exp.Fields, msg.Fields, name)
+	}
+}
@@ -0,0 +1,253 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SOH is the delimiter of the fields of a message.
+const SOH = '\x01'
+
+// Tags of the standard header and trailer, and of session level messages.
+const (
+	TagBeginSeqNo      = 7
+	TagBeginString     = 8
+	TagBodyLength      = 9
+	TagCheckSum        = 10
+	TagEndSeqNo        = 16
+	TagMsgSeqNum       = 34
+	TagMsgType         = 35
+	TagNewSeqNo        = 36
+	TagPossDupFlag     = 43
+	TagRefSeqNum       = 45
+	TagSenderCompID    = 49
+	TagSendingTime     = 52
+	TagTargetCompID    = 56
+	TagText            = 58
+	TagPossResend      = 97
+	TagEncryptMethod   = 98
+	TagHeartBtInt      = 108
+	TagTestReqID       = 112
+	TagOrigSendingTime = 122
+	TagGapFillFlag     = 123
+	TagResetSeqNumFlag = 141
+)
+
+// Session level message types.
+const (
+	MsgTypeHeartbeat     = "0"
+	MsgTypeTestRequest   = "1"
+	MsgTypeResendRequest = "2"
+	MsgTypeReject        = "3"
+	MsgTypeSequenceReset = "4"
+	MsgTypeLogout        = "5"
+	MsgTypeLogon         = "A"
+)
+
+// IsAdmin returns whether a message type is a session level message type.
+func IsAdmin(msgType string) bool {
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeTestRequest, MsgTypeResendRequest,
+		MsgTypeReject, MsgTypeSequenceReset, MsgTypeLogout, MsgTypeLogon:
+		return true
+	}
+	return false
+}
+
+// Field is a single tag=value pair of a message.
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is a FIX message consisting of an ordered list of fields. Tags can
+// appear multiple times within a message when they belong to repeating groups.
+type Message struct {
+	Fields []Field
+}
+
+// NewMessage returns a message of a given type.
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{Tag: TagMsgType, Value: msgType}}}
+}
+
+// Get returns the value of the first occurrence of a tag and whether it
+// exists.
+func (m *Message) Get(tag int) (string, bool) {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetInt returns the value of the first occurrence of a tag as an integer, or
+// zero if it does not exist or is not an integer.
+func (m *Message) GetInt(tag int) int {
+	v, _ := m.Get(tag)
+	i, _ := strconv.Atoi(v)
+	return i
+}
+
+// GetBool returns whether the first occurrence of a tag is set to Y.
+func (m *Message) GetBool(tag int) bool {
+	v, _ := m.Get(tag)
+	return v == "Y"
+}
+
+// Set the value of the first occurrence of a tag, or add the tag to the end of
+// the message when it does not exist.
+func (m *Message) Set(tag int, value string) *Message {
+	for i, f := range m.Fields {
+		if f.Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	return m.Add(tag, value)
+}
+
+// Add a field to the end of the message.
+func (m *Message) Add(tag int, value string) *Message {
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+// MsgType returns the type of the message.
+func (m *Message) MsgType() string {
+	v, _ := m.Get(TagMsgType)
+	return v
+}
+
+// SeqNum returns the sequence number of the message.
+func (m *Message) SeqNum() int {
+	return m.GetInt(TagMsgSeqNum)
+}
+
+// Bytes encodes the message, where the BeginString, BodyLength and MsgType
+// fields are written first and the BodyLength and CheckSum fields are
+// calculated.
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	writeField := func(buf *bytes.Buffer, tag int, value string) {
+		buf.WriteString(strconv.Itoa(tag))
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte(SOH)
+	}
+
+	if msgType, exists := m.Get(TagMsgType); exists {
+		writeField(&body, TagMsgType, msgType)
+	}
+	for _, f := range m.Fields {
+		switch f.Tag {
+		case TagBeginString, TagBodyLength, TagCheckSum, TagMsgType:
+			continue
+		}
+		writeField(&body, f.Tag, f.Value)
+	}
+
+	var buf bytes.Buffer
+	beginString, _ := m.Get(TagBeginString)
+	writeField(&buf, TagBeginString, beginString)
+	writeField(&buf, TagBodyLength, strconv.Itoa(body.Len()))
+	buf.Write(body.Bytes())
+	writeField(&buf, TagCheckSum, fmt.Sprintf("%03d", checkSum(buf.Bytes())))
+	return buf.Bytes()
+}
+
+// String returns the encoded message with fields delimited by a pipe
+// character, which is useful for logging.
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Bytes(), []byte{SOH}, []byte{'|'}))
+}
+
+func checkSum(b []byte) int {
+	var sum int
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+//------------------------------------------------------------------------------
+
+// ParseFields parses a sequence of tag=value pairs separated by a delimiter
+// without validating the header or trailer of the message.
+func ParseFields(b []byte, delim byte) (*Message, error) {
+	m := &Message{}
+	for _, raw := range bytes.Split(bytes.TrimSpace(b), []byte{delim}) {
+		if len(raw) == 0 {
+			continue
+		}
+		i := bytes.IndexByte(raw, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("field '%s' is not of the form tag=value", raw)
+		}
+		tag, err := strconv.Atoi(string(raw[:i]))
+		if err != nil || tag <= 0 {
+			return nil, fmt.Errorf("field '%s' has an invalid tag", raw)
+		}
+		m.Fields = append(m.Fields, Field{Tag: tag, Value: string(raw[i+1:])})
+	}
+	return m, nil
+}
+
+// Parse a message and validate its BeginString, BodyLength and CheckSum
+// fields.
+func Parse(b []byte) (*Message, error) {
+	m, err := ParseFields(b, SOH)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Fields) < 4 {
+		return nil, errors.New("message is too short")
+	}
+	if m.Fields[0].Tag != TagBeginString {
+		return nil, errors.New("message does not begin with a BeginString field")
+	}
+	if m.Fields[1].Tag != TagBodyLength {
+		return nil, errors.New("BodyLength field is not the second field of the message")
+	}
+	if m.Fields[2].Tag != TagMsgType {
+		return nil, errors.New("MsgType field is not the third field of the message")
+	}
+	last := m.Fields[len(m.Fields)-1]
+	if last.Tag != TagCheckSum {
+		return nil, errors.New("message does not end with a CheckSum field")
+	}
+
+	bodyStart := bytes.Index(b, []byte{SOH, '3', '5', '='}) + 1
+	trailerStart := bytes.LastIndex(b, []byte{SOH, '1', '0', '='}) + 1
+	if bodyLength, err := strconv.Atoi(m.Fields[1].Value); err != nil || bodyLength != trailerStart-bodyStart {
+		return nil, fmt.Errorf("BodyLength %v does not match the length of the body %v", m.Fields[1].Value, trailerStart-bodyStart)
+	}
+	if sum, err := strconv.Atoi(last.Value); err != nil || sum != checkSum(b[:trailerStart]) {
+		return nil, fmt.Errorf("CheckSum %v does not match the calculated checksum %03d", last.Value, checkSum(b[:trailerStart]))
+	}
+	return m, nil
+}
+
+// ReadMessage reads the bytes of the next message from a reader, which ends
+// with its CheckSum field.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	var buf []byte
+	for {
+		field, err := r.ReadBytes(SOH)
+		if err != nil {
+			return nil, err
+		}
+		if len(buf) == 0 && !bytes.HasPrefix(field, []byte("8=")) {
+			// Skip anything preceding the start of a message.
+			continue
+		}
+		buf = append(buf, field...)
+		if bytes.HasPrefix(field, []byte("10=")) {
+			return buf, nil
+		}
+	}
+}
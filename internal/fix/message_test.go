@@ -0,0 +1,99 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pipes(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "|", string(SOH)))
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := NewMessage("D").
+		Add(TagBeginString, "FIX.4.2").
+		Add(TagSenderCompID, "CLIENT").
+		Add(TagTargetCompID, "BROKER").
+		Add(TagMsgSeqNum, "1").
+		Add(11, "ORDER1").
+		Add(55, "AAPL")
+
+	assert.Equal(t, "8=FIX.4.2|9=48|35=D|49=CLIENT|56=BROKER|34=1|11=ORDER1|55=AAPL|10=173|", msg.String())
+
+	parsed, err := Parse(msg.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "D", parsed.MsgType())
+	assert.Equal(t, 1, parsed.SeqNum())
+	v, ok := parsed.Get(55)
+	assert.True(t, ok)
+	assert.Equal(t, "AAPL", v)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		err   string
+	}{
+		"bad checksum": {
+			input: "8=FIX.4.2|9=48|35=D|49=CLIENT|56=BROKER|34=1|11=ORDER1|55=AAPL|10=174|",
+			err:   "CheckSum 174 does not match the calculated checksum 173",
+		},
+		"bad body length": {
+			input: "8=FIX.4.2|9=47|35=D|49=CLIENT|56=BROKER|34=1|11=ORDER1|55=AAPL|10=172|",
+			err:   "BodyLength 47 does not match the length of the body 48",
+		},
+		"no begin string": {
+			input: "9=45|8=FIX.4.2|35=D|10=000|",
+			err:   "message does not begin with a BeginString field",
+		},
+		"no checksum": {
+			input: "8=FIX.4.2|9=5|35=D|55=A|",
+			err:   "message does not end with a CheckSum field",
+		},
+		"bad field": {
+			input: "8=FIX.4.2|9=5|35=D|nope|10=000|",
+			err:   "field 'nope' is not of the form tag=value",
+		},
+	}
+	for name, test := range tests {
+		_, err := Parse(pipes(test.input))
+		require.Error(t, err, name)
+		assert.Equal(t, test.err, err.Error(), name)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	first := NewMessage("0").Add(TagBeginString, "FIX.4.4").Add(TagMsgSeqNum, "1").Bytes()
+	second := NewMessage("0").Add(TagBeginString, "FIX.4.4").Add(TagMsgSeqNum, "2").Bytes()
+
+	var buf bytes.Buffer
+	buf.WriteString("garbage" + string(SOH))
+	buf.Write(first)
+	buf.Write(second)
+
+	r := bufio.NewReader(&buf)
+	raw, err := ReadMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, first, raw)
+
+	raw, err = ReadMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, second, raw)
+
+	_, err = ReadMessage(r)
+	assert.Error(t, err)
+}
+
+func TestParseFields(t *testing.T) {
+	msg, err := ParseFields([]byte("35=D|55=AAPL|54=1\n"), '|')
+	require.NoError(t, err)
+	assert.Equal(t, []Field{{35, "D"}, {55, "AAPL"}, {54, "1"}}, msg.Fields)
+
+	_, err = ParseFields([]byte("35=D|foo=bar"), '|')
+	require.EqualError(t, err, "field 'foo=bar' has an invalid tag")
+}
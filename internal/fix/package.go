@@ -0,0 +1,4 @@
+// Package fix implements the tag=value encoding of the Financial Information
+// eXchange (FIX) protocol along with its session layer, which allows sessions
+// to be established either as an initiator or an acceptor.
+package fix
@@ -0,0 +1,587 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+)
+
+// ErrSessionClosed is returned when a session has been closed.
+var ErrSessionClosed = errors.New("session closed")
+
+// SessionConfig contains the settings of a session.
+type SessionConfig struct {
+	BeginString  string
+	SenderCompID string
+	TargetCompID string
+
+	// HeartBtInt is the heartbeat interval requested when initiating a
+	// session. Acceptors use the interval requested by the initiator.
+	HeartBtInt time.Duration
+
+	// ResetOnLogon resets both sequence numbers to 1 at logon, and requests
+	// that the counterparty does the same.
+	ResetOnLogon bool
+
+	// LogonFields are added to Logon messages sent by the session, which can
+	// be used for fields such as Username and Password.
+	LogonFields []Field
+
+	// IgnoreApplication causes application messages received by the session
+	// to be discarded rather than returned by Receive.
+	IgnoreApplication bool
+
+	// Store persists the sequence numbers of the session, if nil they are
+	// kept in memory.
+	Store Store
+}
+
+type received struct {
+	msg    *Message
+	commit func()
+}
+
+// Session is an established FIX session over a connection. Session level
+// messages are handled by the session, including heartbeats, test requests,
+// sequence resets and logouts, and application messages are returned by
+// Receive.
+//
+// Messages are not retained by the session and so resend requests from the
+// counterparty are answered with a gap fill.
+type Session struct {
+	conf       SessionConfig
+	conn       net.Conn
+	rdr        *bufio.Reader
+	heartBtInt time.Duration
+
+	writeMut   sync.Mutex
+	nextSender int
+
+	// Only accessed by the read loop once the session is established.
+	nextTarget    int
+	resendPending bool
+
+	cpMut sync.Mutex
+	cp    *checkpoint.Type
+
+	lastSent    int64
+	lastRecv    int64
+	testReqSent int64
+	loggingOut  int32
+
+	appMsgs   chan received
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+func newSession(conn net.Conn, conf SessionConfig) (*Session, error) {
+	if conf.Store == nil {
+		conf.Store = NewMemoryStore()
+	}
+	if conf.ResetOnLogon {
+		if err := conf.Store.Reset(); err != nil {
+			return nil, err
+		}
+	}
+	sender, target, err := conf.Store.SeqNums()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixNano()
+	return &Session{
+		conf:       conf,
+		conn:       conn,
+		rdr:        bufio.NewReader(conn),
+		heartBtInt: conf.HeartBtInt,
+		nextSender: sender,
+		nextTarget: target,
+		cp:         checkpoint.New(),
+		lastSent:   now,
+		lastRecv:   now,
+		appMsgs:    make(chan received),
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+// Initiate establishes a session over a connection by sending a Logon message
+// and waiting for the Logon response of the counterparty. The connection is
+// closed if the session cannot be established.
+func Initiate(ctx context.Context, conn net.Conn, conf SessionConfig) (*Session, error) {
+	s, err := newSession(conn, conf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	logon := s.logonMessage(conf.ResetOnLogon)
+	if err = s.send(logon); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := s.readLogon(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetBool(TagResetSeqNumFlag) && !conf.ResetOnLogon {
+		s.nextTarget = 1
+	}
+	if err = s.start(resp); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Accept establishes a session over a connection by waiting for a Logon
+// message from the counterparty and responding with a Logon message. The
+// connection is closed if the session cannot be established.
+func Accept(ctx context.Context, conn net.Conn, conf SessionConfig) (*Session, error) {
+	s, err := newSession(conn, conf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	req, err := s.readLogon(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reset := req.GetBool(TagResetSeqNumFlag)
+	if reset && !conf.ResetOnLogon {
+		if err = s.conf.Store.Reset(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		s.nextSender, s.nextTarget = 1, 1
+	}
+	if hb := req.GetInt(TagHeartBtInt); hb > 0 {
+		s.heartBtInt = time.Duration(hb) * time.Second
+	}
+
+	if err = s.send(s.logonMessage(reset || conf.ResetOnLogon)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = s.start(req); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Session) logonMessage(reset bool) *Message {
+	msg := NewMessage(MsgTypeLogon).
+		Add(TagEncryptMethod, "0").
+		Add(TagHeartBtInt, strconv.Itoa(int(s.heartBtInt/time.Second)))
+	if reset {
+		msg.Add(TagResetSeqNumFlag, "Y")
+	}
+	msg.Fields = append(msg.Fields, s.conf.LogonFields...)
+	return msg
+}
+
+func (s *Session) readLogon(ctx context.Context) (*Message, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetReadDeadline(deadline)
+	}
+	defer func() {
+		_ = s.conn.SetReadDeadline(time.Time{})
+	}()
+
+	raw, err := ReadMessage(s.rdr)
+	if err != nil {
+		s.conn.Close()
+		return nil, fmt.Errorf("failed to read logon: %w", err)
+	}
+	msg, err := Parse(raw)
+	if err == nil {
+		err = s.checkHeader(msg)
+	}
+	if err == nil && msg.MsgType() != MsgTypeLogon {
+		err = fmt.Errorf("expected a Logon message, received message type %v", msg.MsgType())
+	}
+	if err != nil {
+		_ = s.send(NewMessage(MsgTypeLogout).Add(TagText, err.Error()))
+		s.conn.Close()
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *Session) start(logon *Message) error {
+	switch seq := logon.SeqNum(); {
+	case seq < s.nextTarget:
+		err := fmt.Errorf("MsgSeqNum too low, expecting %v but received %v", s.nextTarget, seq)
+		_ = s.send(NewMessage(MsgTypeLogout).Add(TagText, err.Error()))
+		s.conn.Close()
+		return err
+	case seq > s.nextTarget:
+		if err := s.requestResend(); err != nil {
+			s.conn.Close()
+			return err
+		}
+	default:
+		s.nextTarget++
+		s.track(s.nextTarget)()
+	}
+
+	go s.readLoop()
+	if s.heartBtInt > 0 {
+		go s.heartbeatLoop()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *Session) checkHeader(msg *Message) error {
+	if v, _ := msg.Get(TagBeginString); v != s.conf.BeginString {
+		return fmt.Errorf("unexpected BeginString %v", v)
+	}
+	if v, _ := msg.Get(TagSenderCompID); v != s.conf.TargetCompID {
+		return fmt.Errorf("unexpected SenderCompID %v", v)
+	}
+	if v, _ := msg.Get(TagTargetCompID); v != s.conf.SenderCompID {
+		return fmt.Errorf("unexpected TargetCompID %v", v)
+	}
+	if msg.SeqNum() <= 0 {
+		return errors.New("missing MsgSeqNum")
+	}
+	return nil
+}
+
+// track an incoming sequence number, the returned func marks it as processed
+// and persists the latest sequence number for which all prior messages have
+// been processed.
+func (s *Session) track(next int) func() {
+	s.cpMut.Lock()
+	resolve := s.cp.Track(next, 1)
+	s.cpMut.Unlock()
+	return func() {
+		s.cpMut.Lock()
+		v := resolve()
+		s.cpMut.Unlock()
+		if n, ok := v.(int); ok {
+			if err := s.conf.Store.SetTargetSeqNum(n); err != nil {
+				s.fail(fmt.Errorf("failed to persist sequence number: %w", err))
+			}
+		}
+	}
+}
+
+func (s *Session) readLoop() {
+	for {
+		raw, err := ReadMessage(s.rdr)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		msg, err := Parse(raw)
+		if err != nil {
+			// Garbled messages are ignored, a resend of them will be
+			// requested once the sequence gap is detected.
+			continue
+		}
+		atomic.StoreInt64(&s.lastRecv, time.Now().UnixNano())
+		atomic.StoreInt64(&s.testReqSent, 0)
+
+		if err = s.checkHeader(msg); err == nil {
+			err = s.handle(msg)
+		}
+		if err != nil {
+			if !errors.Is(err, ErrSessionClosed) {
+				s.logout(err.Error())
+			}
+			s.fail(err)
+			return
+		}
+	}
+}
+
+func (s *Session) handle(msg *Message) error {
+	seq, msgType := msg.SeqNum(), msg.MsgType()
+
+	if msgType == MsgTypeSequenceReset && !msg.GetBool(TagGapFillFlag) {
+		if newSeq := msg.GetInt(TagNewSeqNo); newSeq > s.nextTarget {
+			s.nextTarget = newSeq
+			s.resendPending = false
+			s.track(s.nextTarget)()
+		}
+		return nil
+	}
+
+	if seq > s.nextTarget {
+		switch msgType {
+		case MsgTypeLogout:
+			return s.handleLogout(msg)
+		case MsgTypeResendRequest:
+			if err := s.gapFill(msg.GetInt(TagBeginSeqNo)); err != nil {
+				return err
+			}
+		}
+		if !s.resendPending {
+			return s.requestResend()
+		}
+		return nil
+	}
+	if seq < s.nextTarget {
+		if msg.GetBool(TagPossDupFlag) {
+			return nil
+		}
+		return fmt.Errorf("MsgSeqNum too low, expecting %v but received %v", s.nextTarget, seq)
+	}
+
+	s.nextTarget++
+	if !msg.GetBool(TagPossDupFlag) {
+		s.resendPending = false
+	}
+
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeReject, MsgTypeLogon:
+	case MsgTypeTestRequest:
+		reqID, _ := msg.Get(TagTestReqID)
+		if err := s.send(NewMessage(MsgTypeHeartbeat).Add(TagTestReqID, reqID)); err != nil {
+			return err
+		}
+	case MsgTypeResendRequest:
+		if err := s.gapFill(msg.GetInt(TagBeginSeqNo)); err != nil {
+			return err
+		}
+	case MsgTypeSequenceReset:
+		if newSeq := msg.GetInt(TagNewSeqNo); newSeq > s.nextTarget {
+			s.nextTarget = newSeq
+		}
+	case MsgTypeLogout:
+		s.track(s.nextTarget)()
+		return s.handleLogout(msg)
+	default:
+		commit := s.track(s.nextTarget)
+		if s.conf.IgnoreApplication {
+			commit()
+			return nil
+		}
+		select {
+		case s.appMsgs <- received{msg: msg, commit: commit}:
+		case <-s.closed:
+			return ErrSessionClosed
+		}
+		return nil
+	}
+	s.track(s.nextTarget)()
+	return nil
+}
+
+func (s *Session) handleLogout(msg *Message) error {
+	if atomic.LoadInt32(&s.loggingOut) == 1 {
+		return ErrSessionClosed
+	}
+	atomic.StoreInt32(&s.loggingOut, 1)
+	_ = s.send(NewMessage(MsgTypeLogout))
+	if text, _ := msg.Get(TagText); text != "" {
+		return fmt.Errorf("counterparty logged out: %v", text)
+	}
+	return errors.New("counterparty logged out")
+}
+
+func (s *Session) requestResend() error {
+	s.resendPending = true
+	return s.send(NewMessage(MsgTypeResendRequest).
+		Add(TagBeginSeqNo, strconv.Itoa(s.nextTarget)).
+		Add(TagEndSeqNo, "0"))
+}
+
+func (s *Session) heartbeatLoop() {
+	ticker := time.NewTicker(s.heartBtInt / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.closed:
+			return
+		}
+
+		now := time.Now()
+		if now.Sub(time.Unix(0, atomic.LoadInt64(&s.lastSent))) >= s.heartBtInt {
+			if err := s.send(NewMessage(MsgTypeHeartbeat)); err != nil {
+				s.fail(err)
+				return
+			}
+		}
+		if sent := atomic.LoadInt64(&s.testReqSent); sent != 0 {
+			if now.Sub(time.Unix(0, sent)) >= s.heartBtInt {
+				s.fail(errors.New("counterparty did not respond to a test request"))
+				return
+			}
+		} else if now.Sub(time.Unix(0, atomic.LoadInt64(&s.lastRecv))) >= s.heartBtInt+s.heartBtInt/5 {
+			atomic.StoreInt64(&s.testReqSent, now.UnixNano())
+			reqID := strconv.FormatInt(now.UnixNano(), 10)
+			if err := s.send(NewMessage(MsgTypeTestRequest).Add(TagTestReqID, reqID)); err != nil {
+				s.fail(err)
+				return
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func sendingTime(t time.Time) string {
+	return t.UTC().Format("20060102-15:04:05.000")
+}
+
+// withHeader returns a copy of a message with the standard header fields of
+// the session added.
+func (s *Session) withHeader(msg *Message, seq int) *Message {
+	out := &Message{Fields: make([]Field, 0, len(msg.Fields)+6)}
+	out.Add(TagBeginString, s.conf.BeginString).
+		Add(TagMsgType, msg.MsgType()).
+		Add(TagSenderCompID, s.conf.SenderCompID).
+		Add(TagTargetCompID, s.conf.TargetCompID).
+		Add(TagMsgSeqNum, strconv.Itoa(seq)).
+		Add(TagSendingTime, sendingTime(time.Now()))
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case TagBeginString, TagBodyLength, TagCheckSum, TagMsgType,
+			TagSenderCompID, TagTargetCompID, TagMsgSeqNum, TagSendingTime:
+			continue
+		}
+		out.Fields = append(out.Fields, f)
+	}
+	return out
+}
+
+func (s *Session) send(msg *Message) error {
+	s.writeMut.Lock()
+	defer s.writeMut.Unlock()
+
+	if _, err := s.conn.Write(s.withHeader(msg, s.nextSender).Bytes()); err != nil {
+		return err
+	}
+	s.nextSender++
+	atomic.StoreInt64(&s.lastSent, time.Now().UnixNano())
+	return s.conf.Store.SetSenderSeqNum(s.nextSender)
+}
+
+// gapFill responds to a resend request with a sequence reset that skips all
+// messages sent since the requested sequence number.
+func (s *Session) gapFill(begin int) error {
+	s.writeMut.Lock()
+	defer s.writeMut.Unlock()
+
+	if begin <= 0 || begin >= s.nextSender {
+		return nil
+	}
+	msg := NewMessage(MsgTypeSequenceReset).
+		Add(TagPossDupFlag, "Y").
+		Add(TagOrigSendingTime, sendingTime(time.Now())).
+		Add(TagGapFillFlag, "Y").
+		Add(TagNewSeqNo, strconv.Itoa(s.nextSender))
+	_, err := s.conn.Write(s.withHeader(msg, begin).Bytes())
+	return err
+}
+
+func (s *Session) logout(text string) {
+	if !atomic.CompareAndSwapInt32(&s.loggingOut, 0, 1) {
+		return
+	}
+	msg := NewMessage(MsgTypeLogout)
+	if text != "" {
+		msg.Add(TagText, text)
+	}
+	_ = s.send(msg)
+}
+
+func (s *Session) fail(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		close(s.closed)
+		s.conn.Close()
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// Send an application message to the counterparty. The standard header fields
+// of the message are set by the session.
+func (s *Session) Send(msg *Message) error {
+	select {
+	case <-s.closed:
+		return ErrSessionClosed
+	default:
+	}
+	msgType := msg.MsgType()
+	if msgType == "" {
+		return errors.New("message does not have a MsgType field")
+	}
+	if IsAdmin(msgType) {
+		return fmt.Errorf("session level message type %v cannot be sent", msgType)
+	}
+
+	body := &Message{Fields: make([]Field, 0, len(msg.Fields))}
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case TagPossDupFlag, TagPossResend, TagOrigSendingTime:
+			continue
+		}
+		body.Fields = append(body.Fields, f)
+	}
+	if err := s.send(body); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Receive the next application message from the counterparty along with a
+// func that must be called once the message has been processed, which allows
+// the sequence number of the message to be persisted.
+func (s *Session) Receive(ctx context.Context) (*Message, func(), error) {
+	select {
+	case r := <-s.appMsgs:
+		return r.msg, r.commit, nil
+	case <-s.closed:
+		return nil, nil, s.Err()
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed once the session has ended.
+func (s *Session) Done() <-chan struct{} {
+	return s.closed
+}
+
+// Err returns the reason that a session ended, or nil if it is active.
+func (s *Session) Err() error {
+	select {
+	case <-s.closed:
+		return s.err
+	default:
+	}
+	return nil
+}
+
+// Close the session by sending a Logout message and waiting for the
+// counterparty to respond, or for a timeout to elapse.
+func (s *Session) Close(timeout time.Duration) {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+	s.logout("")
+	select {
+	case <-s.closed:
+	case <-time.After(timeout):
+	}
+	s.fail(ErrSessionClosed)
+}
@@ -0,0 +1,252 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAcceptor(t *testing.T, conf SessionConfig) *Endpoint {
+	t.Helper()
+
+	conf.BeginString = "FIX.4.4"
+	conf.SenderCompID = "SERVER"
+	conf.TargetCompID = "CLIENT"
+	e := NewAcceptor("127.0.0.1:0", nil, time.Second*5, conf)
+	require.NoError(t, e.Connect(context.Background()))
+	t.Cleanup(func() {
+		e.Close(time.Second)
+	})
+	return e
+}
+
+type rawCounterparty struct {
+	t    *testing.T
+	conn net.Conn
+	rdr  *bufio.Reader
+}
+
+func dialRaw(t *testing.T, addr net.Addr) *rawCounterparty {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr.String())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return &rawCounterparty{t: t, conn: conn, rdr: bufio.NewReader(conn)}
+}
+
+func (c *rawCounterparty) send(seq int, msgType string, fields ...Field) {
+	c.t.Helper()
+
+	msg := NewMessage(msgType).
+		Add(TagBeginString, "FIX.4.4").
+		Add(TagSenderCompID, "CLIENT").
+		Add(TagTargetCompID, "SERVER").
+		Add(TagMsgSeqNum, strconv.Itoa(seq)).
+		Add(TagSendingTime, sendingTime(time.Now()))
+	msg.Fields = append(msg.Fields, fields...)
+	_, err := c.conn.Write(msg.Bytes())
+	require.NoError(c.t, err)
+}
+
+func (c *rawCounterparty) read() *Message {
+	c.t.Helper()
+
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(time.Second*5)))
+	raw, err := ReadMessage(c.rdr)
+	require.NoError(c.t, err)
+	msg, err := Parse(raw)
+	require.NoError(c.t, err)
+	return msg
+}
+
+func get(msg *Message, tag int) string {
+	v, _ := msg.Get(tag)
+	return v
+}
+
+func TestSessionInitiatorAcceptor(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_fix_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	acceptorStore, err := NewFileStore(tmpDir, "FIX.4.4", "SERVER", "CLIENT")
+	require.NoError(t, err)
+	acceptor := testAcceptor(t, SessionConfig{Store: acceptorStore})
+
+	initiatorStore := NewMemoryStore()
+	initiator := NewInitiator(acceptor.Addr().String(), nil, time.Second*5, SessionConfig{
+		BeginString:       "FIX.4.4",
+		SenderCompID:      "CLIENT",
+		TargetCompID:      "SERVER",
+		HeartBtInt:        time.Second * 30,
+		IgnoreApplication: true,
+		Store:             initiatorStore,
+	})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, err = initiator.Session(ctx)
+	require.Equal(t, ErrNoSession, err)
+
+	require.NoError(t, initiator.Connect(ctx))
+	clientSession, err := initiator.Session(ctx)
+	require.NoError(t, err)
+
+	serverSession, err := acceptor.Session(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, clientSession.Send(NewMessage("D").Add(11, "ORDER1").Add(TagPossDupFlag, "Y")))
+	require.NoError(t, clientSession.Send(NewMessage("D").Add(11, "ORDER2")))
+	assert.EqualError(t, clientSession.Send(NewMessage(MsgTypeLogout)), "session level message type 5 cannot be sent")
+
+	msg, commit, err := serverSession.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER1", get(msg, 11))
+	assert.Equal(t, "2", get(msg, TagMsgSeqNum))
+	_, isPossDup := msg.Get(TagPossDupFlag)
+	assert.False(t, isPossDup)
+
+	msg2, commit2, err := serverSession.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER2", get(msg2, 11))
+
+	// Sequence numbers are only persisted once all prior messages are
+	// processed.
+	commit2()
+	_, target, err := acceptorStore.SeqNums()
+	require.NoError(t, err)
+	assert.Equal(t, 2, target)
+
+	commit()
+	_, target, err = acceptorStore.SeqNums()
+	require.NoError(t, err)
+	assert.Equal(t, 4, target)
+
+	reopened, err := NewFileStore(tmpDir, "FIX.4.4", "SERVER", "CLIENT")
+	require.NoError(t, err)
+	sender, target, err := reopened.SeqNums()
+	require.NoError(t, err)
+	assert.Equal(t, 2, sender)
+	assert.Equal(t, 4, target)
+
+	initiator.Close(time.Second * 5)
+	assert.Equal(t, ErrSessionClosed, clientSession.Err())
+
+	select {
+	case <-serverSession.Done():
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+	assert.EqualError(t, serverSession.Err(), "counterparty logged out")
+
+	sender, target, err = initiatorStore.SeqNums()
+	require.NoError(t, err)
+	assert.Equal(t, 5, sender)
+	assert.Equal(t, 3, target)
+}
+
+func TestSessionSequenceHandling(t *testing.T) {
+	acceptor := testAcceptor(t, SessionConfig{})
+	client := dialRaw(t, acceptor.Addr())
+
+	client.send(1, MsgTypeLogon, Field{TagEncryptMethod, "0"}, Field{TagHeartBtInt, "30"})
+	logon := client.read()
+	assert.Equal(t, MsgTypeLogon, logon.MsgType())
+	assert.Equal(t, "30", get(logon, TagHeartBtInt))
+	assert.Equal(t, "SERVER", get(logon, TagSenderCompID))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	session, err := acceptor.Session(ctx)
+	require.NoError(t, err)
+
+	client.send(2, MsgTypeTestRequest, Field{TagTestReqID, "foo"})
+	hb := client.read()
+	assert.Equal(t, MsgTypeHeartbeat, hb.MsgType())
+	assert.Equal(t, "foo", get(hb, TagTestReqID))
+
+	// A gap results in a resend request and the message is discarded.
+	client.send(5, "D", Field{11, "ORDER1"})
+	resend := client.read()
+	assert.Equal(t, MsgTypeResendRequest, resend.MsgType())
+	assert.Equal(t, "3", get(resend, TagBeginSeqNo))
+	assert.Equal(t, "0", get(resend, TagEndSeqNo))
+
+	client.send(3, MsgTypeSequenceReset, Field{TagPossDupFlag, "Y"}, Field{TagGapFillFlag, "Y"}, Field{TagNewSeqNo, "5"})
+	client.send(5, "D", Field{11, "ORDER1"})
+
+	msg, commit, err := session.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER1", get(msg, 11))
+	commit()
+
+	// Resend requests are answered with a gap fill.
+	client.send(6, MsgTypeResendRequest, Field{TagBeginSeqNo, "1"}, Field{TagEndSeqNo, "0"})
+	gapFill := client.read()
+	assert.Equal(t, MsgTypeSequenceReset, gapFill.MsgType())
+	assert.Equal(t, "1", get(gapFill, TagMsgSeqNum))
+	assert.Equal(t, "Y", get(gapFill, TagGapFillFlag))
+	assert.Equal(t, "Y", get(gapFill, TagPossDupFlag))
+	assert.Equal(t, "4", get(gapFill, TagNewSeqNo))
+
+	// Possible duplicates with a low sequence number are ignored.
+	client.send(2, "D", Field{TagPossDupFlag, "Y"}, Field{11, "ORDER0"})
+
+	// A low sequence number without the possible duplicate flag ends the
+	// session.
+	client.send(3, "D", Field{11, "ORDER2"})
+	logout := client.read()
+	assert.Equal(t, MsgTypeLogout, logout.MsgType())
+	assert.Equal(t, "MsgSeqNum too low, expecting 7 but received 3", get(logout, TagText))
+
+	select {
+	case <-session.Done():
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+	assert.EqualError(t, session.Err(), "MsgSeqNum too low, expecting 7 but received 3")
+}
+
+func TestSessionLogonRejected(t *testing.T) {
+	acceptor := testAcceptor(t, SessionConfig{})
+	errs := make(chan error, 1)
+	acceptor.OnAcceptError = func(err error) {
+		errs <- err
+	}
+
+	client := dialRaw(t, acceptor.Addr())
+	client.send(1, "D", Field{11, "ORDER1"})
+
+	logout := client.read()
+	assert.Equal(t, MsgTypeLogout, logout.MsgType())
+	assert.Equal(t, "expected a Logon message, received message type D", get(logout, TagText))
+	assert.EqualError(t, <-errs, "expected a Logon message, received message type D")
+}
+
+func TestSessionHeartbeats(t *testing.T) {
+	acceptor := testAcceptor(t, SessionConfig{})
+	client := dialRaw(t, acceptor.Addr())
+
+	client.send(1, MsgTypeLogon, Field{TagEncryptMethod, "0"}, Field{TagHeartBtInt, "1"})
+	assert.Equal(t, MsgTypeLogon, client.read().MsgType())
+
+	assert.Equal(t, MsgTypeHeartbeat, client.read().MsgType())
+
+	testReq := client.read()
+	assert.Equal(t, MsgTypeTestRequest, testReq.MsgType())
+	assert.NotEmpty(t, get(testReq, TagTestReqID))
+}
@@ -0,0 +1,135 @@
+package fix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists the sequence numbers of a session.
+type Store interface {
+	// SeqNums returns the next sequence number to send and the next sequence
+	// number expected to be received.
+	SeqNums() (sender, target int, err error)
+
+	// SetSenderSeqNum sets the next sequence number to send.
+	SetSenderSeqNum(n int) error
+
+	// SetTargetSeqNum sets the next sequence number expected to be received.
+	SetTargetSeqNum(n int) error
+
+	// Reset both sequence numbers to 1.
+	Reset() error
+}
+
+// MemoryStore is a Store that does not persist sequence numbers beyond the
+// lifetime of the process.
+type MemoryStore struct {
+	mut            sync.Mutex
+	sender, target int
+}
+
+// NewMemoryStore returns a MemoryStore where both sequence numbers are 1.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sender: 1, target: 1}
+}
+
+// SeqNums returns the next sequence number to send and the next sequence
+// number expected to be received.
+func (s *MemoryStore) SeqNums() (sender, target int, err error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.sender, s.target, nil
+}
+
+// SetSenderSeqNum sets the next sequence number to send.
+func (s *MemoryStore) SetSenderSeqNum(n int) error {
+	s.mut.Lock()
+	s.sender = n
+	s.mut.Unlock()
+	return nil
+}
+
+// SetTargetSeqNum sets the next sequence number expected to be received.
+func (s *MemoryStore) SetTargetSeqNum(n int) error {
+	s.mut.Lock()
+	s.target = n
+	s.mut.Unlock()
+	return nil
+}
+
+// Reset both sequence numbers to 1.
+func (s *MemoryStore) Reset() error {
+	s.mut.Lock()
+	s.sender, s.target = 1, 1
+	s.mut.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// FileStore is a Store that persists sequence numbers to a file, which is
+// replaced atomically on each change.
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+// NewFileStore returns a FileStore that persists the sequence numbers of a
+// session within a directory, reading any sequence numbers that were
+// previously persisted.
+func NewFileStore(dir, beginString, senderCompID, targetCompID string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		MemoryStore: MemoryStore{sender: 1, target: 1},
+		path:        filepath.Join(dir, fmt.Sprintf("%v-%v-%v.seqnums", beginString, senderCompID, targetCompID)),
+	}
+
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if _, err = fmt.Sscanf(string(b), "%d %d", &s.sender, &s.target); err != nil {
+		return nil, fmt.Errorf("failed to read sequence numbers from %v: %w", s.path, err)
+	}
+	return s, nil
+}
+
+func (s *FileStore) persist() error {
+	tmpPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, []byte(fmt.Sprintf("%d %d\n", s.sender, s.target)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// SetSenderSeqNum sets the next sequence number to send.
+func (s *FileStore) SetSenderSeqNum(n int) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.sender = n
+	return s.persist()
+}
+
+// SetTargetSeqNum sets the next sequence number expected to be received.
+func (s *FileStore) SetTargetSeqNum(n int) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.target = n
+	return s.persist()
+}
+
+// Reset both sequence numbers to 1.
+func (s *FileStore) Reset() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.sender, s.target = 1, 1
+	return s.persist()
+}
@@ -0,0 +1,209 @@
+package flow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Protocol is a flow export protocol.
+type Protocol string
+
+// Supported protocols.
+const (
+	ProtocolNetFlowV5 Protocol = "netflow_v5"
+	ProtocolNetFlowV9 Protocol = "netflow_v9"
+	ProtocolIPFIX     Protocol = "ipfix"
+	ProtocolSFlowV5   Protocol = "sflow_v5"
+)
+
+// RecordType describes the contents of a record.
+type RecordType string
+
+// Record types.
+const (
+	// RecordFlow is a flow record of NetFlow or IPFIX.
+	RecordFlow RecordType = "flow"
+
+	// RecordOptions is a NetFlow v9 or IPFIX record of an options template,
+	// which describes the exporter rather than a flow.
+	RecordOptions RecordType = "options"
+
+	// RecordFlowSample is an sFlow flow sample.
+	RecordFlowSample RecordType = "flow_sample"
+
+	// RecordCounterSample is an sFlow counter sample.
+	RecordCounterSample RecordType = "counter_sample"
+)
+
+// Record is a single record decoded from a datagram, where the fields are
+// named after IPFIX information elements when applicable.
+type Record struct {
+	Protocol Protocol
+	Type     RecordType
+
+	// ExportTime is the time at which the datagram was exported, which is not
+	// provided by sFlow.
+	ExportTime time.Time
+
+	// UptimeMillis is the uptime of the exporter in milliseconds, which is not
+	// provided by IPFIX.
+	UptimeMillis uint32
+
+	Sequence uint32
+
+	// ObservationDomainID is the source ID of NetFlow v9, the observation
+	// domain of IPFIX and the sub agent ID of sFlow.
+	ObservationDomainID uint32
+
+	// AgentAddress is the address of the sFlow agent.
+	AgentAddress string
+
+	// TemplateID is the template of NetFlow v9 and IPFIX records.
+	TemplateID uint16
+
+	Fields map[string]interface{}
+}
+
+// Datagram is the result of decoding a datagram.
+type Datagram struct {
+	Protocol Protocol
+	Records  []Record
+
+	// MissingTemplates contains the IDs of the templates referenced by data
+	// sets before the template itself was received, the records of these sets
+	// are skipped.
+	MissingTemplates []uint16
+}
+
+//------------------------------------------------------------------------------
+
+type templateKey struct {
+	exporter string
+	protocol Protocol
+	domain   uint32
+	id       uint16
+}
+
+type template struct {
+	fields  []fieldSpec
+	options bool
+}
+
+// minLength returns the minimum length of a record of the template.
+func (t *template) minLength() int {
+	l := 0
+	for _, f := range t.fields {
+		if f.length == variableLength {
+			l++
+		} else {
+			l += int(f.length)
+		}
+	}
+	return l
+}
+
+// Decoder decodes datagrams, retaining the templates of each exporter.
+type Decoder struct {
+	mut       sync.Mutex
+	templates map[templateKey]*template
+}
+
+// NewDecoder creates a new decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		templates: map[templateKey]*template{},
+	}
+}
+
+// Decode a datagram received from an exporter, where the exporter identifies
+// the source of templates and is typically its IP address. The protocol is
+// detected from the version of the datagram.
+func (d *Decoder) Decode(exporter string, b []byte) (*Datagram, error) {
+	if len(b) < 4 {
+		return nil, errors.New("datagram too short")
+	}
+	switch binary.BigEndian.Uint16(b) {
+	case 5:
+		return decodeNetFlowV5(b)
+	case 9:
+		return d.decodeNetFlowV9(exporter, b)
+	case 10:
+		return d.decodeIPFIX(exporter, b)
+	case 0:
+		if v := binary.BigEndian.Uint32(b); v == 5 {
+			return decodeSFlowV5(b)
+		}
+	}
+	return nil, fmt.Errorf("datagram version not recognised: %v", binary.BigEndian.Uint16(b))
+}
+
+func (d *Decoder) setTemplate(k templateKey, t *template) {
+	d.mut.Lock()
+	if t == nil {
+		delete(d.templates, k)
+	} else {
+		d.templates[k] = t
+	}
+	d.mut.Unlock()
+}
+
+func (d *Decoder) getTemplate(k templateKey) *template {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.templates[k]
+}
+
+//------------------------------------------------------------------------------
+
+var errTruncated = errors.New("datagram truncated")
+
+// buffer reads big endian values, recording the first read beyond its end.
+type buffer struct {
+	b   []byte
+	err error
+}
+
+func (r *buffer) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errTruncated
+		r.b = nil
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *buffer) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *buffer) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *buffer) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *buffer) u64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
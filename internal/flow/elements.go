@@ -0,0 +1,234 @@
+package flow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type elementKind int
+
+const (
+	kindUnsigned elementKind = iota
+	kindIPv4
+	kindIPv6
+	kindMAC
+	kindString
+	kindOctets
+)
+
+type element struct {
+	name string
+	kind elementKind
+}
+
+// elements contains the IANA IPFIX information elements commonly exported by
+// devices, where the identifiers below 128 are shared with NetFlow v9.
+var elements = map[uint16]element{
+	1:   {"octetDeltaCount", kindUnsigned},
+	2:   {"packetDeltaCount", kindUnsigned},
+	3:   {"deltaFlowCount", kindUnsigned},
+	4:   {"protocolIdentifier", kindUnsigned},
+	5:   {"ipClassOfService", kindUnsigned},
+	6:   {"tcpControlBits", kindUnsigned},
+	7:   {"sourceTransportPort", kindUnsigned},
+	8:   {"sourceIPv4Address", kindIPv4},
+	9:   {"sourceIPv4PrefixLength", kindUnsigned},
+	10:  {"ingressInterface", kindUnsigned},
+	11:  {"destinationTransportPort", kindUnsigned},
+	12:  {"destinationIPv4Address", kindIPv4},
+	13:  {"destinationIPv4PrefixLength", kindUnsigned},
+	14:  {"egressInterface", kindUnsigned},
+	15:  {"ipNextHopIPv4Address", kindIPv4},
+	16:  {"bgpSourceAsNumber", kindUnsigned},
+	17:  {"bgpDestinationAsNumber", kindUnsigned},
+	18:  {"bgpNextHopIPv4Address", kindIPv4},
+	19:  {"postMCastPacketDeltaCount", kindUnsigned},
+	20:  {"postMCastOctetDeltaCount", kindUnsigned},
+	21:  {"flowEndSysUpTime", kindUnsigned},
+	22:  {"flowStartSysUpTime", kindUnsigned},
+	23:  {"postOctetDeltaCount", kindUnsigned},
+	24:  {"postPacketDeltaCount", kindUnsigned},
+	25:  {"minimumIpTotalLength", kindUnsigned},
+	26:  {"maximumIpTotalLength", kindUnsigned},
+	27:  {"sourceIPv6Address", kindIPv6},
+	28:  {"destinationIPv6Address", kindIPv6},
+	29:  {"sourceIPv6PrefixLength", kindUnsigned},
+	30:  {"destinationIPv6PrefixLength", kindUnsigned},
+	31:  {"flowLabelIPv6", kindUnsigned},
+	32:  {"icmpTypeCodeIPv4", kindUnsigned},
+	33:  {"igmpType", kindUnsigned},
+	34:  {"samplingInterval", kindUnsigned},
+	35:  {"samplingAlgorithm", kindUnsigned},
+	36:  {"flowActiveTimeout", kindUnsigned},
+	37:  {"flowIdleTimeout", kindUnsigned},
+	38:  {"engineType", kindUnsigned},
+	39:  {"engineId", kindUnsigned},
+	40:  {"exportedOctetTotalCount", kindUnsigned},
+	41:  {"exportedMessageTotalCount", kindUnsigned},
+	42:  {"exportedFlowRecordTotalCount", kindUnsigned},
+	44:  {"sourceIPv4Prefix", kindIPv4},
+	45:  {"destinationIPv4Prefix", kindIPv4},
+	46:  {"mplsTopLabelType", kindUnsigned},
+	47:  {"mplsTopLabelIPv4Address", kindIPv4},
+	48:  {"samplerId", kindUnsigned},
+	49:  {"samplerMode", kindUnsigned},
+	50:  {"samplerRandomInterval", kindUnsigned},
+	52:  {"minimumTTL", kindUnsigned},
+	53:  {"maximumTTL", kindUnsigned},
+	54:  {"fragmentIdentification", kindUnsigned},
+	55:  {"postIpClassOfService", kindUnsigned},
+	56:  {"sourceMacAddress", kindMAC},
+	57:  {"postDestinationMacAddress", kindMAC},
+	58:  {"vlanId", kindUnsigned},
+	59:  {"postVlanId", kindUnsigned},
+	60:  {"ipVersion", kindUnsigned},
+	61:  {"flowDirection", kindUnsigned},
+	62:  {"ipNextHopIPv6Address", kindIPv6},
+	63:  {"bgpNextHopIPv6Address", kindIPv6},
+	64:  {"ipv6ExtensionHeaders", kindUnsigned},
+	70:  {"mplsTopLabelStackSection", kindOctets},
+	80:  {"destinationMacAddress", kindMAC},
+	81:  {"postSourceMacAddress", kindMAC},
+	82:  {"interfaceName", kindString},
+	83:  {"interfaceDescription", kindString},
+	84:  {"samplerName", kindString},
+	85:  {"octetTotalCount", kindUnsigned},
+	86:  {"packetTotalCount", kindUnsigned},
+	88:  {"fragmentOffset", kindUnsigned},
+	89:  {"forwardingStatus", kindUnsigned},
+	90:  {"mplsVpnRouteDistinguisher", kindOctets},
+	95:  {"applicationId", kindOctets},
+	96:  {"applicationName", kindString},
+	98:  {"postIpDiffServCodePoint", kindUnsigned},
+	128: {"bgpNextAdjacentAsNumber", kindUnsigned},
+	129: {"bgpPrevAdjacentAsNumber", kindUnsigned},
+	130: {"exporterIPv4Address", kindIPv4},
+	131: {"exporterIPv6Address", kindIPv6},
+	136: {"flowEndReason", kindUnsigned},
+	139: {"icmpTypeCodeIPv6", kindUnsigned},
+	144: {"exportingProcessId", kindUnsigned},
+	148: {"flowId", kindUnsigned},
+	149: {"observationDomainId", kindUnsigned},
+	150: {"flowStartSeconds", kindUnsigned},
+	151: {"flowEndSeconds", kindUnsigned},
+	152: {"flowStartMilliseconds", kindUnsigned},
+	153: {"flowEndMilliseconds", kindUnsigned},
+	154: {"flowStartMicroseconds", kindUnsigned},
+	155: {"flowEndMicroseconds", kindUnsigned},
+	156: {"flowStartNanoseconds", kindUnsigned},
+	157: {"flowEndNanoseconds", kindUnsigned},
+	160: {"systemInitTimeMilliseconds", kindUnsigned},
+	161: {"flowDurationMilliseconds", kindUnsigned},
+	176: {"icmpTypeIPv4", kindUnsigned},
+	177: {"icmpCodeIPv4", kindUnsigned},
+	178: {"icmpTypeIPv6", kindUnsigned},
+	179: {"icmpCodeIPv6", kindUnsigned},
+	180: {"udpSourcePort", kindUnsigned},
+	181: {"udpDestinationPort", kindUnsigned},
+	182: {"tcpSourcePort", kindUnsigned},
+	183: {"tcpDestinationPort", kindUnsigned},
+	192: {"ipTTL", kindUnsigned},
+	195: {"ipDiffServCodePoint", kindUnsigned},
+	224: {"ipTotalLength", kindUnsigned},
+	225: {"postNATSourceIPv4Address", kindIPv4},
+	226: {"postNATDestinationIPv4Address", kindIPv4},
+	227: {"postNAPTSourceTransportPort", kindUnsigned},
+	228: {"postNAPTDestinationTransportPort", kindUnsigned},
+	230: {"natEvent", kindUnsigned},
+	234: {"ingressVRFID", kindUnsigned},
+	235: {"egressVRFID", kindUnsigned},
+	239: {"biflowDirection", kindUnsigned},
+	243: {"dot1qVlanId", kindUnsigned},
+	256: {"ethernetType", kindUnsigned},
+	281: {"postNATSourceIPv6Address", kindIPv6},
+	282: {"postNATDestinationIPv6Address", kindIPv6},
+	302: {"selectorId", kindUnsigned},
+	305: {"samplingPacketInterval", kindUnsigned},
+	306: {"samplingPacketSpace", kindUnsigned},
+	323: {"observationTimeMilliseconds", kindUnsigned},
+	352: {"layer2OctetDeltaCount", kindUnsigned},
+}
+
+// fieldSpec is a field of a template.
+type fieldSpec struct {
+	id         uint16
+	enterprise uint32
+	length     uint16
+
+	// v9Scope is set for the scope fields of NetFlow v9 options templates,
+	// which have identifiers of their own.
+	v9Scope bool
+}
+
+var v9ScopeNames = map[uint16]string{
+	1: "scopeSystem",
+	2: "scopeInterface",
+	3: "scopeLineCard",
+	4: "scopeCache",
+	5: "scopeTemplate",
+}
+
+// variableLength is the field length of IPFIX fields where the length is
+// encoded within each record.
+const variableLength = 0xffff
+
+// name returns the name of the information element of a field, where unknown
+// elements are named after their identifier, prefixed by the enterprise
+// number for enterprise specific elements.
+func (f fieldSpec) name() string {
+	if f.v9Scope {
+		if name, exists := v9ScopeNames[f.id]; exists {
+			return name
+		}
+		return "scope" + strconv.FormatUint(uint64(f.id), 10)
+	}
+	if f.enterprise != 0 {
+		return strconv.FormatUint(uint64(f.enterprise), 10) + "." + strconv.FormatUint(uint64(f.id), 10)
+	}
+	if e, exists := elements[f.id]; exists {
+		return e.name
+	}
+	return strconv.FormatUint(uint64(f.id), 10)
+}
+
+// value decodes the value of a field, unknown elements of the sizes of
+// unsigned integers are decoded as such and other values as hex strings.
+func (f fieldSpec) value(b []byte) interface{} {
+	kind := kindOctets
+	if e, exists := elements[f.id]; exists && f.enterprise == 0 && !f.v9Scope {
+		kind = e.kind
+	} else if len(b) == 1 || len(b) == 2 || len(b) == 4 || len(b) == 8 {
+		kind = kindUnsigned
+	}
+
+	switch kind {
+	case kindUnsigned:
+		if len(b) > 0 && len(b) <= 8 {
+			return uintValue(b)
+		}
+	case kindIPv4:
+		if len(b) == net.IPv4len {
+			return net.IP(b).String()
+		}
+	case kindIPv6:
+		if len(b) == net.IPv6len {
+			return net.IP(b).String()
+		}
+	case kindMAC:
+		if len(b) == 6 {
+			return net.HardwareAddr(b).String()
+		}
+	case kindString:
+		return strings.TrimRight(string(b), "\x00")
+	}
+	return hex.EncodeToString(b)
+}
+
+func uintValue(b []byte) uint64 {
+	var buf [8]byte
+	copy(buf[8-len(b):], b)
+	return binary.BigEndian.Uint64(buf[:])
+}
@@ -0,0 +1,284 @@
+package flow
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// packet builds big endian datagrams.
+type packet []byte
+
+func (p packet) u8(v uint8) packet   { return append(p, v) }
+func (p packet) u16(v uint16) packet { return append(p, byte(v>>8), byte(v)) }
+func (p packet) u32(v uint32) packet {
+	return binary.BigEndian.AppendUint32(p, v)
+}
+func (p packet) u64(v uint64) packet {
+	return binary.BigEndian.AppendUint64(p, v)
+}
+func (p packet) ip(s string) packet {
+	ip := net.ParseIP(s)
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return append(p, ip...)
+}
+func (p packet) raw(b ...byte) packet { return append(p, b...) }
+
+// set prefixes a flowset or set with its ID and length.
+func set(id uint16, body packet) packet {
+	return packet{}.u16(id).u16(uint16(len(body) + 4)).raw(body...)
+}
+
+func TestNetFlowV5(t *testing.T) {
+	p := packet{}.u16(5).u16(1).u32(60000).u32(1600000000).u32(500).u32(42).u8(1).u8(2).u16(0x4000 | 100)
+	p = p.ip("10.0.0.1").ip("10.0.0.2").ip("10.0.0.254").u16(3).u16(4).
+		u32(10).u32(1500).u32(59000).u32(59900).u16(443).u16(51000).
+		u8(0).u8(0x18).u8(6).u8(0).u16(64512).u16(64513).u8(24).u8(16).u16(0)
+
+	dgram, err := NewDecoder().Decode("10.0.0.1", p)
+	require.NoError(t, err)
+	require.Len(t, dgram.Records, 1)
+	assert.Equal(t, Record{
+		Protocol:     ProtocolNetFlowV5,
+		Type:         RecordFlow,
+		ExportTime:   time.Unix(1600000000, 500).UTC(),
+		UptimeMillis: 60000,
+		Sequence:     42,
+		Fields: map[string]interface{}{
+			"sourceIPv4Address":           "10.0.0.1",
+			"destinationIPv4Address":      "10.0.0.2",
+			"ipNextHopIPv4Address":        "10.0.0.254",
+			"ingressInterface":            uint64(3),
+			"egressInterface":             uint64(4),
+			"packetDeltaCount":            uint64(10),
+			"octetDeltaCount":             uint64(1500),
+			"flowStartSysUpTime":          uint64(59000),
+			"flowEndSysUpTime":            uint64(59900),
+			"sourceTransportPort":         uint64(443),
+			"destinationTransportPort":    uint64(51000),
+			"tcpControlBits":              uint64(0x18),
+			"protocolIdentifier":          uint64(6),
+			"ipClassOfService":            uint64(0),
+			"bgpSourceAsNumber":           uint64(64512),
+			"bgpDestinationAsNumber":      uint64(64513),
+			"sourceIPv4PrefixLength":      uint64(24),
+			"destinationIPv4PrefixLength": uint64(16),
+			"engineType":                  uint64(1),
+			"engineId":                    uint64(2),
+			"samplingAlgorithm":           uint64(1),
+			"samplingInterval":            uint64(100),
+		},
+	}, dgram.Records[0])
+
+	_, err = NewDecoder().Decode("10.0.0.1", p[:60])
+	assert.EqualError(t, err, "datagram of 60 bytes too short for 1 records")
+}
+
+func TestNetFlowV9(t *testing.T) {
+	header := func(seq uint32) packet {
+		return packet{}.u16(9).u16(0).u32(1000).u32(1600000000).u32(seq).u32(7)
+	}
+	templates := set(0, packet{}.
+		u16(256).u16(3).u16(8).u16(4).u16(12).u16(4).u16(2).u16(4))
+	options := set(1, packet{}.
+		u16(257).u16(4).u16(8).u16(1).u16(4).u16(34).u16(4).u16(35).u16(1).u16(0))
+	data := set(256, packet{}.
+		ip("192.168.0.1").ip("192.168.0.2").u32(5).
+		ip("192.168.0.3").ip("192.168.0.4").u32(6).
+		raw(0, 0))
+
+	d := NewDecoder()
+
+	// Data received before its template is skipped.
+	dgram, err := d.Decode("10.0.0.1", append(header(1), data...))
+	require.NoError(t, err)
+	assert.Empty(t, dgram.Records)
+	assert.Equal(t, []uint16{256}, dgram.MissingTemplates)
+
+	p := append(header(2), templates...)
+	p = append(p, options...)
+	p = append(p, data...)
+	dgram, err = d.Decode("10.0.0.1", p)
+	require.NoError(t, err)
+	assert.Empty(t, dgram.MissingTemplates)
+	require.Len(t, dgram.Records, 2)
+	assert.Equal(t, Record{
+		Protocol:            ProtocolNetFlowV9,
+		Type:                RecordFlow,
+		ExportTime:          time.Unix(1600000000, 0).UTC(),
+		UptimeMillis:        1000,
+		Sequence:            2,
+		ObservationDomainID: 7,
+		TemplateID:          256,
+		Fields: map[string]interface{}{
+			"sourceIPv4Address":      "192.168.0.1",
+			"destinationIPv4Address": "192.168.0.2",
+			"packetDeltaCount":       uint64(5),
+		},
+	}, dgram.Records[0])
+	assert.Equal(t, "192.168.0.3", dgram.Records[1].Fields["sourceIPv4Address"])
+
+	// Templates are retained for subsequent datagrams of the same exporter.
+	optionsData := set(257, packet{}.u32(0).u32(1000).u8(2).raw(0, 0, 0))
+	p = append(header(3), data...)
+	p = append(p, optionsData...)
+	dgram, err = d.Decode("10.0.0.1", p)
+	require.NoError(t, err)
+	require.Len(t, dgram.Records, 3)
+	assert.Equal(t, RecordOptions, dgram.Records[2].Type)
+	assert.Equal(t, map[string]interface{}{
+		"scopeSystem":       uint64(0),
+		"samplingInterval":  uint64(1000),
+		"samplingAlgorithm": uint64(2),
+	}, dgram.Records[2].Fields)
+
+	dgram, err = d.Decode("10.0.0.2", append(header(1), data...))
+	require.NoError(t, err)
+	assert.Empty(t, dgram.Records)
+	assert.Equal(t, []uint16{256}, dgram.MissingTemplates)
+}
+
+func TestIPFIX(t *testing.T) {
+	message := func(sets ...packet) packet {
+		var body packet
+		for _, s := range sets {
+			body = append(body, s...)
+		}
+		return packet{}.u16(10).u16(uint16(len(body) + 16)).u32(1600000000).u32(9).u32(3).raw(body...)
+	}
+
+	templates := set(2, packet{}.
+		u16(300).u16(4).
+		u16(27).u16(16).
+		u16(7).u16(2).
+		u16(82).u16(0xffff).
+		u16(0x8000|12).u16(4).u32(29305))
+	data := set(300, packet{}.
+		ip("2001:db8::1").u16(8080).u8(4).raw('e', 't', 'h', '0').u32(99).
+		ip("2001:db8::2").u16(53).u8(0).u32(1))
+
+	d := NewDecoder()
+	dgram, err := d.Decode("10.0.0.1", message(templates, data))
+	require.NoError(t, err)
+	require.Len(t, dgram.Records, 2)
+	assert.Equal(t, Record{
+		Protocol:            ProtocolIPFIX,
+		Type:                RecordFlow,
+		ExportTime:          time.Unix(1600000000, 0).UTC(),
+		Sequence:            9,
+		ObservationDomainID: 3,
+		TemplateID:          300,
+		Fields: map[string]interface{}{
+			"sourceIPv6Address":   "2001:db8::1",
+			"sourceTransportPort": uint64(8080),
+			"interfaceName":       "eth0",
+			"29305.12":            uint64(99),
+		},
+	}, dgram.Records[0])
+	assert.Equal(t, "", dgram.Records[1].Fields["interfaceName"])
+
+	// Withdrawn templates are forgotten.
+	dgram, err = d.Decode("10.0.0.1", message(set(2, packet{}.u16(300).u16(0)), data))
+	require.NoError(t, err)
+	assert.Empty(t, dgram.Records)
+	assert.Equal(t, []uint16{300}, dgram.MissingTemplates)
+
+	_, err = d.Decode("10.0.0.1", message(data)[:20])
+	assert.EqualError(t, err, "invalid message length: 70")
+}
+
+func TestSFlow(t *testing.T) {
+	frame := packet{}.
+		raw(0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb).
+		u16(0x8100).u16(10).u16(0x0800).
+		// IPv4 header
+		u8(0x45).u8(0).u16(60).u16(0).u16(0).u8(64).u8(6).u16(0).ip("10.1.0.1").ip("10.1.0.2").
+		// TCP header
+		u16(40000).u16(22).u32(0).u32(0).u8(0x50).u8(0x02).u16(0).u16(0).u16(0)
+	headerLen := len(frame)
+	for len(frame)%4 != 0 {
+		frame = frame.u8(0)
+	}
+
+	rawHeader := packet{}.u32(1).u32(1514).u32(4).u32(uint32(headerLen)).raw(frame...)
+	flowRecords := packet{}.u32(2).
+		u32(sflowRawPacketHeader).u32(uint32(len(rawHeader))).raw(rawHeader...).
+		u32(sflowExtendedSwitch).u32(16).u32(10).u32(0).u32(20).u32(0)
+	flowSample := packet{}.u32(100).u32(5).u32(512).u32(51200).u32(0).u32(5).u32(6).raw(flowRecords...)
+
+	counters := packet{}.u32(5).u32(6).u64(1e9).u32(1).u32(3).
+		u64(1000).u32(1).u32(2).u32(3).u32(4).u32(5).u32(6).
+		u64(2000).u32(7).u32(8).u32(9).u32(10).u32(11).u32(0)
+	counterSample := packet{}.u32(200).u32(5).u32(1).
+		u32(sflowGenericInterfaceCounters).u32(uint32(len(counters))).raw(counters...)
+
+	p := packet{}.u32(5).u32(1).ip("10.0.0.9").u32(0).u32(77).u32(123456).u32(3).
+		u32(sflowFlowSample).u32(uint32(len(flowSample))).raw(flowSample...).
+		u32(sflowCounterSample).u32(uint32(len(counterSample))).raw(counterSample...).
+		u32(1<<12|1).u32(4).u32(0)
+
+	dgram, err := NewDecoder().Decode("10.0.0.9", p)
+	require.NoError(t, err)
+	require.Len(t, dgram.Records, 2)
+
+	assert.Equal(t, Record{
+		Protocol:     ProtocolSFlowV5,
+		Type:         RecordFlowSample,
+		UptimeMillis: 123456,
+		Sequence:     77,
+		AgentAddress: "10.0.0.9",
+		Fields: map[string]interface{}{
+			"sampleSequence":           uint64(100),
+			"sourceIdType":             uint64(0),
+			"sourceIdIndex":            uint64(5),
+			"samplingRate":             uint64(512),
+			"samplePool":               uint64(51200),
+			"drops":                    uint64(0),
+			"ingressInterface":         uint64(5),
+			"egressInterface":          uint64(6),
+			"headerProtocol":           uint64(1),
+			"dataLinkFrameSize":        uint64(1514),
+			"destinationMacAddress":    "00:11:22:33:44:55",
+			"sourceMacAddress":         "66:77:88:99:aa:bb",
+			"vlanId":                   uint64(10),
+			"ethernetType":             uint64(0x0800),
+			"ipVersion":                uint64(4),
+			"ipClassOfService":         uint64(0),
+			"ipTotalLength":            uint64(60),
+			"ipTTL":                    uint64(64),
+			"protocolIdentifier":       uint64(6),
+			"sourceIPv4Address":        "10.1.0.1",
+			"destinationIPv4Address":   "10.1.0.2",
+			"sourceTransportPort":      uint64(40000),
+			"destinationTransportPort": uint64(22),
+			"tcpControlBits":           uint64(0x02),
+			"sourceVlan":               uint64(10),
+			"sourcePriority":           uint64(0),
+			"destinationVlan":          uint64(20),
+			"destinationPriority":      uint64(0),
+		},
+	}, dgram.Records[0])
+
+	assert.Equal(t, RecordCounterSample, dgram.Records[1].Type)
+	assert.Equal(t, uint64(1e9), dgram.Records[1].Fields["ifSpeed"])
+	assert.Equal(t, uint64(1000), dgram.Records[1].Fields["ifInOctets"])
+	assert.Equal(t, uint64(2000), dgram.Records[1].Fields["ifOutOctets"])
+	assert.Equal(t, uint64(11), dgram.Records[1].Fields["ifOutErrors"])
+
+	_, err = NewDecoder().Decode("10.0.0.9", p[:40])
+	assert.Error(t, err)
+}
+
+func TestDecodeUnknownVersion(t *testing.T) {
+	_, err := NewDecoder().Decode("10.0.0.1", []byte{0, 1, 0, 0})
+	assert.EqualError(t, err, "datagram version not recognised: 1")
+
+	_, err = NewDecoder().Decode("10.0.0.1", []byte{0})
+	assert.EqualError(t, err, "datagram too short")
+}
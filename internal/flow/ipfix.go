@@ -0,0 +1,104 @@
+package flow
+
+import (
+	"fmt"
+	"time"
+)
+
+// IPFIX set IDs.
+const (
+	ipfixTemplateSet        = 2
+	ipfixOptionsTemplateSet = 3
+)
+
+func (d *Decoder) decodeIPFIX(exporter string, b []byte) (*Datagram, error) {
+	r := &buffer{b: b}
+	r.u16()
+	length := int(r.u16())
+	secs := r.u32()
+	seq := r.u32()
+	domain := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if length < 16 || length > len(b) {
+		return nil, fmt.Errorf("invalid message length: %v", length)
+	}
+	r.b = b[16:length]
+
+	dgram := &Datagram{Protocol: ProtocolIPFIX}
+	exportTime := time.Unix(int64(secs), 0).UTC()
+	for len(r.b) >= 4 {
+		setID, setLen := r.u16(), int(r.u16())
+		if setLen < 4 {
+			return nil, fmt.Errorf("invalid set length: %v", setLen)
+		}
+		set := &buffer{b: r.bytes(setLen - 4)}
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		key := templateKey{exporter: exporter, protocol: ProtocolIPFIX, domain: domain}
+		switch {
+		case setID == ipfixTemplateSet, setID == ipfixOptionsTemplateSet:
+			options := setID == ipfixOptionsTemplateSet
+			for len(set.b) >= 4 {
+				key.id = set.u16()
+				count := int(set.u16())
+				if key.id < minDataSetID {
+					// Padding or an invalid template, either way the remainder
+					// of the set can't be trusted.
+					break
+				}
+				if count == 0 {
+					// Template withdrawal
+					d.setTemplate(key, nil)
+					continue
+				}
+				if options {
+					set.u16() // The scope fields are treated as regular fields.
+				}
+				t := &template{options: options}
+				for i := 0; i < count; i++ {
+					f := fieldSpec{id: set.u16(), length: set.u16()}
+					if f.id&0x8000 != 0 {
+						f.id &= 0x7fff
+						f.enterprise = set.u32()
+					}
+					t.fields = append(t.fields, f)
+				}
+				if set.err != nil {
+					return nil, set.err
+				}
+				d.setTemplate(key, t)
+			}
+		case setID >= minDataSetID:
+			key.id = setID
+			t := d.getTemplate(key)
+			if t == nil {
+				dgram.MissingTemplates = append(dgram.MissingTemplates, setID)
+				continue
+			}
+			records, err := decodeDataSet(set, t)
+			if err != nil {
+				return nil, err
+			}
+			for _, fields := range records {
+				rType := RecordFlow
+				if t.options {
+					rType = RecordOptions
+				}
+				dgram.Records = append(dgram.Records, Record{
+					Protocol:            ProtocolIPFIX,
+					Type:                rType,
+					ExportTime:          exportTime,
+					Sequence:            seq,
+					ObservationDomainID: domain,
+					TemplateID:          setID,
+					Fields:              fields,
+				})
+			}
+		}
+	}
+	return dgram, nil
+}
@@ -0,0 +1,195 @@
+package flow
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	netFlowV5HeaderLen = 24
+	netFlowV5RecordLen = 48
+)
+
+func decodeNetFlowV5(b []byte) (*Datagram, error) {
+	r := &buffer{b: b}
+	r.u16()
+	count := int(r.u16())
+	uptime := r.u32()
+	secs, nsecs := r.u32(), r.u32()
+	seq := r.u32()
+	engineType, engineID := r.u8(), r.u8()
+	sampling := r.u16()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(b) < netFlowV5HeaderLen+count*netFlowV5RecordLen {
+		return nil, fmt.Errorf("datagram of %v bytes too short for %v records", len(b), count)
+	}
+
+	dgram := &Datagram{Protocol: ProtocolNetFlowV5}
+	exportTime := time.Unix(int64(secs), int64(nsecs)).UTC()
+	for i := 0; i < count; i++ {
+		fields := map[string]interface{}{}
+		fields["sourceIPv4Address"] = net.IP(r.bytes(4)).String()
+		fields["destinationIPv4Address"] = net.IP(r.bytes(4)).String()
+		fields["ipNextHopIPv4Address"] = net.IP(r.bytes(4)).String()
+		fields["ingressInterface"] = uint64(r.u16())
+		fields["egressInterface"] = uint64(r.u16())
+		fields["packetDeltaCount"] = uint64(r.u32())
+		fields["octetDeltaCount"] = uint64(r.u32())
+		fields["flowStartSysUpTime"] = uint64(r.u32())
+		fields["flowEndSysUpTime"] = uint64(r.u32())
+		fields["sourceTransportPort"] = uint64(r.u16())
+		fields["destinationTransportPort"] = uint64(r.u16())
+		r.u8()
+		fields["tcpControlBits"] = uint64(r.u8())
+		fields["protocolIdentifier"] = uint64(r.u8())
+		fields["ipClassOfService"] = uint64(r.u8())
+		fields["bgpSourceAsNumber"] = uint64(r.u16())
+		fields["bgpDestinationAsNumber"] = uint64(r.u16())
+		fields["sourceIPv4PrefixLength"] = uint64(r.u8())
+		fields["destinationIPv4PrefixLength"] = uint64(r.u8())
+		r.u16()
+
+		fields["engineType"] = uint64(engineType)
+		fields["engineId"] = uint64(engineID)
+		fields["samplingAlgorithm"] = uint64(sampling >> 14)
+		fields["samplingInterval"] = uint64(sampling & 0x3fff)
+
+		dgram.Records = append(dgram.Records, Record{
+			Protocol:     ProtocolNetFlowV5,
+			Type:         RecordFlow,
+			ExportTime:   exportTime,
+			UptimeMillis: uptime,
+			Sequence:     seq + uint32(i),
+			Fields:       fields,
+		})
+	}
+	return dgram, r.err
+}
+
+//------------------------------------------------------------------------------
+
+// NetFlow v9 flowset IDs.
+const (
+	v9TemplateSet        = 0
+	v9OptionsTemplateSet = 1
+	minDataSetID         = 256
+)
+
+func (d *Decoder) decodeNetFlowV9(exporter string, b []byte) (*Datagram, error) {
+	r := &buffer{b: b}
+	r.u16()
+	r.u16() // The count of records is unreliable and ignored.
+	uptime := r.u32()
+	secs := r.u32()
+	seq := r.u32()
+	sourceID := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	dgram := &Datagram{Protocol: ProtocolNetFlowV9}
+	exportTime := time.Unix(int64(secs), 0).UTC()
+	for len(r.b) >= 4 {
+		setID, setLen := r.u16(), int(r.u16())
+		if setLen < 4 {
+			return nil, fmt.Errorf("invalid flowset length: %v", setLen)
+		}
+		set := &buffer{b: r.bytes(setLen - 4)}
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		key := templateKey{exporter: exporter, protocol: ProtocolNetFlowV9, domain: sourceID}
+		switch {
+		case setID == v9TemplateSet:
+			for len(set.b) >= 4 {
+				key.id = set.u16()
+				count := int(set.u16())
+				t := &template{}
+				for i := 0; i < count; i++ {
+					t.fields = append(t.fields, fieldSpec{id: set.u16(), length: set.u16()})
+				}
+				if set.err != nil {
+					return nil, set.err
+				}
+				d.setTemplate(key, t)
+			}
+		case setID == v9OptionsTemplateSet:
+			for len(set.b) >= 6 {
+				key.id = set.u16()
+				scopeLen, optionLen := int(set.u16()), int(set.u16())
+				t := &template{options: true}
+				for i := 0; i < scopeLen/4; i++ {
+					t.fields = append(t.fields, fieldSpec{id: set.u16(), length: set.u16(), v9Scope: true})
+				}
+				for i := 0; i < optionLen/4; i++ {
+					t.fields = append(t.fields, fieldSpec{id: set.u16(), length: set.u16()})
+				}
+				if set.err != nil {
+					return nil, set.err
+				}
+				d.setTemplate(key, t)
+			}
+		case setID >= minDataSetID:
+			key.id = setID
+			t := d.getTemplate(key)
+			if t == nil {
+				dgram.MissingTemplates = append(dgram.MissingTemplates, setID)
+				continue
+			}
+			records, err := decodeDataSet(set, t)
+			if err != nil {
+				return nil, err
+			}
+			for _, fields := range records {
+				rType := RecordFlow
+				if t.options {
+					rType = RecordOptions
+				}
+				dgram.Records = append(dgram.Records, Record{
+					Protocol:            ProtocolNetFlowV9,
+					Type:                rType,
+					ExportTime:          exportTime,
+					UptimeMillis:        uptime,
+					Sequence:            seq,
+					ObservationDomainID: sourceID,
+					TemplateID:          setID,
+					Fields:              fields,
+				})
+			}
+		}
+	}
+	return dgram, nil
+}
+
+// decodeDataSet decodes the records of a data set until the remaining bytes
+// are too few for a record, which are padding.
+func decodeDataSet(set *buffer, t *template) ([]map[string]interface{}, error) {
+	minLen := t.minLength()
+	if minLen == 0 {
+		return nil, nil
+	}
+
+	var records []map[string]interface{}
+	for len(set.b) >= minLen {
+		fields := make(map[string]interface{}, len(t.fields))
+		for _, f := range t.fields {
+			l := int(f.length)
+			if f.length == variableLength {
+				if l = int(set.u8()); l == 0xff {
+					l = int(set.u16())
+				}
+			}
+			v := set.bytes(l)
+			if set.err != nil {
+				return nil, set.err
+			}
+			fields[f.name()] = f.value(v)
+		}
+		records = append(records, fields)
+	}
+	return records, nil
+}
@@ -0,0 +1,4 @@
+// Package flow decodes network flow export datagrams of the NetFlow v5,
+// NetFlow v9 (RFC 3954), IPFIX (RFC 7011) and sFlow v5 protocols into records,
+// tracking the templates of NetFlow v9 and IPFIX exporters.
+package flow
@@ -0,0 +1,285 @@
+package flow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// sFlow v5 sample and record formats of the standard enterprise (0).
+const (
+	sflowFlowSample            = 1
+	sflowCounterSample         = 2
+	sflowExpandedFlowSample    = 3
+	sflowExpandedCounterSample = 4
+
+	sflowRawPacketHeader = 1
+	sflowExtendedSwitch  = 1001
+	sflowExtendedRouter  = 1002
+
+	sflowGenericInterfaceCounters = 1
+)
+
+// sFlow header protocols of raw packet header records.
+const (
+	sflowHeaderEthernet = 1
+	sflowHeaderIPv4     = 11
+	sflowHeaderIPv6     = 12
+)
+
+func readSFlowAddress(r *buffer) string {
+	switch r.u32() {
+	case 1:
+		return net.IP(r.bytes(4)).String()
+	case 2:
+		return net.IP(r.bytes(16)).String()
+	}
+	return ""
+}
+
+func decodeSFlowV5(b []byte) (*Datagram, error) {
+	r := &buffer{b: b}
+	r.u32()
+	agent := readSFlowAddress(r)
+	subAgentID := r.u32()
+	seq := r.u32()
+	uptime := r.u32()
+	count := int(r.u32())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	dgram := &Datagram{Protocol: ProtocolSFlowV5}
+	for i := 0; i < count; i++ {
+		format := r.u32()
+		sample := &buffer{b: r.bytes(int(r.u32()))}
+		if r.err != nil {
+			return nil, r.err
+		}
+		if format>>12 != 0 {
+			// Samples of other enterprises are skipped.
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		var rType RecordType
+		switch format & 0xfff {
+		case sflowFlowSample, sflowExpandedFlowSample:
+			rType = RecordFlowSample
+			fields["sampleSequence"] = uint64(sample.u32())
+			if format&0xfff == sflowFlowSample {
+				source := sample.u32()
+				fields["sourceIdType"] = uint64(source >> 24)
+				fields["sourceIdIndex"] = uint64(source & 0xffffff)
+			} else {
+				fields["sourceIdType"] = uint64(sample.u32())
+				fields["sourceIdIndex"] = uint64(sample.u32())
+			}
+			fields["samplingRate"] = uint64(sample.u32())
+			fields["samplePool"] = uint64(sample.u32())
+			fields["drops"] = uint64(sample.u32())
+			if format&0xfff == sflowFlowSample {
+				fields["ingressInterface"] = uint64(sample.u32())
+				fields["egressInterface"] = uint64(sample.u32())
+			} else {
+				sample.u32()
+				fields["ingressInterface"] = uint64(sample.u32())
+				sample.u32()
+				fields["egressInterface"] = uint64(sample.u32())
+			}
+			if err := decodeSFlowRecords(sample, fields, decodeSFlowFlowRecord); err != nil {
+				return nil, err
+			}
+		case sflowCounterSample, sflowExpandedCounterSample:
+			rType = RecordCounterSample
+			fields["sampleSequence"] = uint64(sample.u32())
+			if format&0xfff == sflowCounterSample {
+				source := sample.u32()
+				fields["sourceIdType"] = uint64(source >> 24)
+				fields["sourceIdIndex"] = uint64(source & 0xffffff)
+			} else {
+				fields["sourceIdType"] = uint64(sample.u32())
+				fields["sourceIdIndex"] = uint64(sample.u32())
+			}
+			if err := decodeSFlowRecords(sample, fields, decodeSFlowCounterRecord); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+
+		dgram.Records = append(dgram.Records, Record{
+			Protocol:            ProtocolSFlowV5,
+			Type:                rType,
+			UptimeMillis:        uptime,
+			Sequence:            seq,
+			ObservationDomainID: subAgentID,
+			AgentAddress:        agent,
+			Fields:              fields,
+		})
+	}
+	return dgram, nil
+}
+
+type sflowRecordFn func(format uint32, r *buffer, fields map[string]interface{})
+
+func decodeSFlowRecords(sample *buffer, fields map[string]interface{}, fn sflowRecordFn) error {
+	count := int(sample.u32())
+	for i := 0; i < count; i++ {
+		format := sample.u32()
+		record := &buffer{b: sample.bytes(int(sample.u32()))}
+		if sample.err != nil {
+			return fmt.Errorf("failed to decode sample: %w", sample.err)
+		}
+		if format>>12 == 0 {
+			fn(format&0xfff, record, fields)
+		}
+	}
+	return sample.err
+}
+
+func decodeSFlowFlowRecord(format uint32, r *buffer, fields map[string]interface{}) {
+	switch format {
+	case sflowRawPacketHeader:
+		proto := r.u32()
+		fields["headerProtocol"] = uint64(proto)
+		fields["dataLinkFrameSize"] = uint64(r.u32())
+		r.u32()
+		header := r.bytes(int(r.u32()))
+		if r.err != nil {
+			return
+		}
+		switch proto {
+		case sflowHeaderEthernet:
+			decodeEthernet(header, fields)
+		case sflowHeaderIPv4, sflowHeaderIPv6:
+			decodeIP(header, fields)
+		}
+	case sflowExtendedSwitch:
+		fields["sourceVlan"] = uint64(r.u32())
+		fields["sourcePriority"] = uint64(r.u32())
+		fields["destinationVlan"] = uint64(r.u32())
+		fields["destinationPriority"] = uint64(r.u32())
+	case sflowExtendedRouter:
+		nextHop := readSFlowAddress(r)
+		srcMask, dstMask := r.u32(), r.u32()
+		if r.err != nil {
+			return
+		}
+		if ip := net.ParseIP(nextHop); ip != nil && ip.To4() == nil {
+			fields["ipNextHopIPv6Address"] = nextHop
+		} else {
+			fields["ipNextHopIPv4Address"] = nextHop
+		}
+		fields["sourcePrefixLength"] = uint64(srcMask)
+		fields["destinationPrefixLength"] = uint64(dstMask)
+	}
+}
+
+var sflowInterfaceCounters = []struct {
+	name  string
+	bytes int
+}{
+	{"ifIndex", 4}, {"ifType", 4}, {"ifSpeed", 8}, {"ifDirection", 4}, {"ifStatus", 4},
+	{"ifInOctets", 8}, {"ifInUcastPkts", 4}, {"ifInMulticastPkts", 4}, {"ifInBroadcastPkts", 4},
+	{"ifInDiscards", 4}, {"ifInErrors", 4}, {"ifInUnknownProtos", 4},
+	{"ifOutOctets", 8}, {"ifOutUcastPkts", 4}, {"ifOutMulticastPkts", 4}, {"ifOutBroadcastPkts", 4},
+	{"ifOutDiscards", 4}, {"ifOutErrors", 4}, {"ifPromiscuousMode", 4},
+}
+
+func decodeSFlowCounterRecord(format uint32, r *buffer, fields map[string]interface{}) {
+	if format != sflowGenericInterfaceCounters {
+		return
+	}
+	for _, c := range sflowInterfaceCounters {
+		var v uint64
+		if c.bytes == 8 {
+			v = r.u64()
+		} else {
+			v = uint64(r.u32())
+		}
+		if r.err != nil {
+			return
+		}
+		fields[c.name] = v
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// decodeEthernet decodes the fields of a sampled ethernet frame, which is
+// usually truncated.
+func decodeEthernet(b []byte, fields map[string]interface{}) {
+	if len(b) < 14 {
+		return
+	}
+	fields["destinationMacAddress"] = net.HardwareAddr(b[0:6]).String()
+	fields["sourceMacAddress"] = net.HardwareAddr(b[6:12]).String()
+	etherType := binary.BigEndian.Uint16(b[12:14])
+	b = b[14:]
+	if etherType == 0x8100 && len(b) >= 4 {
+		fields["vlanId"] = uint64(binary.BigEndian.Uint16(b) & 0xfff)
+		etherType = binary.BigEndian.Uint16(b[2:])
+		b = b[4:]
+	}
+	fields["ethernetType"] = uint64(etherType)
+	if etherType == 0x0800 || etherType == 0x86dd {
+		decodeIP(b, fields)
+	}
+}
+
+// decodeIP decodes the fields of a sampled IPv4 or IPv6 packet.
+func decodeIP(b []byte, fields map[string]interface{}) {
+	if len(b) < 1 {
+		return
+	}
+	var proto byte
+	var transport []byte
+	switch b[0] >> 4 {
+	case 4:
+		ihl := int(b[0]&0x0f) * 4
+		if len(b) < 20 || ihl < 20 {
+			return
+		}
+		fields["ipVersion"] = uint64(4)
+		fields["ipClassOfService"] = uint64(b[1])
+		fields["ipTotalLength"] = uint64(binary.BigEndian.Uint16(b[2:]))
+		fields["ipTTL"] = uint64(b[8])
+		proto = b[9]
+		fields["sourceIPv4Address"] = net.IP(b[12:16]).String()
+		fields["destinationIPv4Address"] = net.IP(b[16:20]).String()
+		// Only the first fragment contains the transport header.
+		if binary.BigEndian.Uint16(b[6:])&0x1fff == 0 && len(b) >= ihl {
+			transport = b[ihl:]
+		}
+	case 6:
+		if len(b) < 40 {
+			return
+		}
+		fields["ipVersion"] = uint64(6)
+		fields["ipClassOfService"] = uint64(binary.BigEndian.Uint16(b) >> 4 & 0xff)
+		fields["flowLabelIPv6"] = uint64(binary.BigEndian.Uint32(b) & 0xfffff)
+		fields["ipTotalLength"] = uint64(binary.BigEndian.Uint16(b[4:])) + 40
+		proto = b[6]
+		fields["ipTTL"] = uint64(b[7])
+		fields["sourceIPv6Address"] = net.IP(b[8:24]).String()
+		fields["destinationIPv6Address"] = net.IP(b[24:40]).String()
+		transport = b[40:]
+	default:
+		return
+	}
+	fields["protocolIdentifier"] = uint64(proto)
+
+	switch proto {
+	case 6:
+		if len(transport) >= 14 {
+			fields["tcpControlBits"] = uint64(transport[13])
+		}
+		fallthrough
+	case 17:
+		if len(transport) >= 4 {
+			fields["sourceTransportPort"] = uint64(binary.BigEndian.Uint16(transport))
+			fields["destinationTransportPort"] = uint64(binary.BigEndian.Uint16(transport[2:]))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package hl7
+
+import (
+	"strings"
+	"time"
+)
+
+// Acknowledgement codes of the MSA segment.
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// AckHeader contains the fields of an MSH segment that are needed in order to
+// acknowledge a message.
+type AckHeader struct {
+	SendingApplication   string
+	SendingFacility      string
+	ReceivingApplication string
+	ReceivingFacility    string
+	TriggerEvent         string
+	ControlID            string
+	ProcessingID         string
+	Version              string
+}
+
+// AckHeader returns the fields of the MSH segment of the message that are
+// needed in order to acknowledge it.
+func (m *Message) AckHeader() AckHeader {
+	h := m.Header()
+	return AckHeader{
+		SendingApplication:   h.Get(3, 1, 1),
+		SendingFacility:      h.Get(4, 1, 1),
+		ReceivingApplication: h.Get(5, 1, 1),
+		ReceivingFacility:    h.Get(6, 1, 1),
+		TriggerEvent:         h.Get(9, 2, 1),
+		ControlID:            h.Get(10, 1, 1),
+		ProcessingID:         h.Get(11, 1, 1),
+		Version:              h.Get(12, 1, 1),
+	}
+}
+
+// Ack generates an acknowledgement message with a code and an optional error
+// text. The sender and receiver of the original message are swapped, and the
+// acknowledgement reuses the control ID of the original message.
+func (h AckHeader) Ack(code, text string, now time.Time) []byte {
+	e := DefaultEncoding
+	field := string(e.Field)
+
+	msh := []string{
+		"MSH",
+		e.Characters(),
+		e.EscapeValue(h.ReceivingApplication),
+		e.EscapeValue(h.ReceivingFacility),
+		e.EscapeValue(h.SendingApplication),
+		e.EscapeValue(h.SendingFacility),
+		now.Format("20060102150405"),
+		"",
+		"ACK" + string(e.Component) + e.EscapeValue(h.TriggerEvent) + string(e.Component) + "ACK",
+		e.EscapeValue(h.ControlID),
+		e.EscapeValue(h.ProcessingID),
+		e.EscapeValue(h.Version),
+	}
+	msa := []string{"MSA", code, e.EscapeValue(h.ControlID)}
+	if text != "" {
+		msa = append(msa, e.EscapeValue(text))
+	}
+	return []byte(strings.Join(msh, field) + "\r" + strings.Join(msa, field) + "\r")
+}
@@ -0,0 +1,369 @@
+package hl7
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ToFHIR converts a message into a FHIR R4 Bundle of type message. The MSH
+// segment is converted into a MessageHeader resource, and PID, PV1 and OBX
+// segments are converted into Patient, Encounter and Observation resources
+// respectively. Other segments are ignored.
+func (m *Message) ToFHIR() map[string]interface{} {
+	header := m.Header()
+	controlID := header.Get(10, 1, 1)
+
+	var entries []interface{}
+	addEntry := func(resource map[string]interface{}) {
+		entries = append(entries, map[string]interface{}{
+			"fullUrl":  fmt.Sprintf("%v/%v", resource["resourceType"], resource["id"]),
+			"resource": resource,
+		})
+	}
+
+	msgHeader := map[string]interface{}{
+		"resourceType": "MessageHeader",
+		"id":           controlID,
+		"eventCoding": map[string]interface{}{
+			"system": "http://terminology.hl7.org/CodeSystem/v2-0003",
+			"code":   header.Get(9, 2, 1),
+		},
+		"source": withoutEmpty(map[string]interface{}{
+			"name":     header.Get(3, 1, 1),
+			"software": header.Get(3, 1, 1),
+			"endpoint": header.Get(4, 1, 1),
+		}),
+		"destination": []interface{}{withoutEmpty(map[string]interface{}{
+			"name":     header.Get(5, 1, 1),
+			"endpoint": header.Get(6, 1, 1),
+		})},
+	}
+	addEntry(msgHeader)
+
+	var patientRef, encounterRef map[string]interface{}
+	var focus []interface{}
+	for _, s := range m.Segments {
+		var resource map[string]interface{}
+		switch s.ID {
+		case "PID":
+			resource = pidToPatient(s, controlID)
+			patientRef = map[string]interface{}{"reference": "Patient/" + resource["id"].(string)}
+		case "PV1":
+			resource = pv1ToEncounter(s, controlID)
+			if patientRef != nil {
+				resource["subject"] = patientRef
+			}
+			encounterRef = map[string]interface{}{"reference": "Encounter/" + resource["id"].(string)}
+		case "OBX":
+			resource = obxToObservation(s, controlID)
+			if patientRef != nil {
+				resource["subject"] = patientRef
+			}
+			if encounterRef != nil {
+				resource["encounter"] = encounterRef
+			}
+		default:
+			continue
+		}
+		focus = append(focus, map[string]interface{}{
+			"reference": fmt.Sprintf("%v/%v", resource["resourceType"], resource["id"]),
+		})
+		addEntry(resource)
+	}
+	if len(focus) > 0 {
+		msgHeader["focus"] = focus
+	}
+
+	bundle := map[string]interface{}{
+		"resourceType": "Bundle",
+		"type":         "message",
+		"entry":        entries,
+	}
+	if ts := FHIRDateTime(header.Get(7, 1, 1)); ts != "" {
+		bundle["timestamp"] = ts
+	}
+	return bundle
+}
+
+func withoutEmpty(obj map[string]interface{}) map[string]interface{} {
+	for k, v := range obj {
+		switch t := v.(type) {
+		case string:
+			if t == "" {
+				delete(obj, k)
+			}
+		case []interface{}:
+			if len(t) == 0 {
+				delete(obj, k)
+			}
+		case map[string]interface{}:
+			if len(t) == 0 {
+				delete(obj, k)
+			}
+		case nil:
+			delete(obj, k)
+		}
+	}
+	return obj
+}
+
+// FHIRDateTime converts an HL7 version 2 timestamp of the form
+// YYYY[MM[DD[HH[MM[SS[.S]]]]]][+/-ZZZZ] into a FHIR date or dateTime, or
+// returns an empty string if the timestamp is not valid.
+func FHIRDateTime(ts string) string {
+	zone := ""
+	if i := strings.IndexAny(ts, "+-"); i >= 0 {
+		ts, zone = ts[:i], ts[i:]
+		if len(zone) != 5 {
+			return ""
+		}
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	frac := ""
+	if i := strings.IndexByte(ts, '.'); i >= 0 {
+		ts, frac = ts[:i], ts[i:]
+	}
+	if _, err := strconv.Atoi(ts); err != nil {
+		return ""
+	}
+
+	switch len(ts) {
+	case 4:
+		return ts
+	case 6:
+		return ts[:4] + "-" + ts[4:6]
+	case 8:
+		return ts[:4] + "-" + ts[4:6] + "-" + ts[6:8]
+	case 10, 12, 14:
+		for len(ts) < 14 {
+			ts += "00"
+		}
+		return ts[:4] + "-" + ts[4:6] + "-" + ts[6:8] + "T" + ts[8:10] + ":" + ts[10:12] + ":" + ts[12:14] + frac + zone
+	}
+	return ""
+}
+
+func pidToPatient(s Segment, controlID string) map[string]interface{} {
+	patient := map[string]interface{}{"resourceType": "Patient"}
+
+	var identifiers []interface{}
+	for i := 1; i <= s.Repetitions(3); i++ {
+		value := s.GetRepetition(3, i, 1)
+		if value == "" {
+			continue
+		}
+		identifier := map[string]interface{}{"value": value}
+		if authority := s.GetRepetition(3, i, 4); authority != "" {
+			identifier["system"] = authority
+		}
+		if typeCode := s.GetRepetition(3, i, 5); typeCode != "" {
+			identifier["type"] = map[string]interface{}{
+				"coding": []interface{}{map[string]interface{}{
+					"system": "http://terminology.hl7.org/CodeSystem/v2-0203",
+					"code":   typeCode,
+				}},
+			}
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	if len(identifiers) > 0 {
+		patient["identifier"] = identifiers
+		patient["id"] = identifiers[0].(map[string]interface{})["value"]
+	} else {
+		patient["id"] = controlID + "-patient"
+	}
+
+	var names []interface{}
+	for i := 1; i <= s.Repetitions(5); i++ {
+		name := withoutEmpty(map[string]interface{}{
+			"family": s.GetRepetition(5, i, 1),
+			"given":  nonEmpty(s.GetRepetition(5, i, 2), s.GetRepetition(5, i, 3)),
+			"prefix": nonEmpty(s.GetRepetition(5, i, 5)),
+			"suffix": nonEmpty(s.GetRepetition(5, i, 4)),
+		})
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		patient["name"] = names
+	}
+
+	if birthDate := FHIRDateTime(s.Get(7, 1, 1)); len(birthDate) >= 10 {
+		patient["birthDate"] = birthDate[:10]
+	}
+	switch s.Get(8, 1, 1) {
+	case "M":
+		patient["gender"] = "male"
+	case "F":
+		patient["gender"] = "female"
+	case "O", "A":
+		patient["gender"] = "other"
+	case "U":
+		patient["gender"] = "unknown"
+	}
+
+	var addresses []interface{}
+	for i := 1; i <= s.Repetitions(11); i++ {
+		addr := withoutEmpty(map[string]interface{}{
+			"line":       nonEmpty(s.GetRepetition(11, i, 1), s.GetRepetition(11, i, 2)),
+			"city":       s.GetRepetition(11, i, 3),
+			"state":      s.GetRepetition(11, i, 4),
+			"postalCode": s.GetRepetition(11, i, 5),
+			"country":    s.GetRepetition(11, i, 6),
+		})
+		if len(addr) > 0 {
+			addresses = append(addresses, addr)
+		}
+	}
+	if len(addresses) > 0 {
+		patient["address"] = addresses
+	}
+
+	var telecoms []interface{}
+	for i := 1; i <= s.Repetitions(13); i++ {
+		if phone := s.GetRepetition(13, i, 1); phone != "" {
+			telecoms = append(telecoms, map[string]interface{}{
+				"system": "phone",
+				"value":  phone,
+				"use":    "home",
+			})
+		}
+	}
+	if len(telecoms) > 0 {
+		patient["telecom"] = telecoms
+	}
+	return patient
+}
+
+func nonEmpty(values ...string) []interface{} {
+	var result []interface{}
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func pv1ToEncounter(s Segment, controlID string) map[string]interface{} {
+	encounter := map[string]interface{}{
+		"resourceType": "Encounter",
+		"status":       "in-progress",
+	}
+	if visit := s.Get(19, 1, 1); visit != "" {
+		encounter["id"] = visit
+		encounter["identifier"] = []interface{}{map[string]interface{}{"value": visit}}
+	} else {
+		encounter["id"] = controlID + "-encounter"
+	}
+
+	class := map[string]interface{}{
+		"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode",
+	}
+	switch s.Get(2, 1, 1) {
+	case "I":
+		class["code"], class["display"] = "IMP", "inpatient encounter"
+	case "O":
+		class["code"], class["display"] = "AMB", "ambulatory"
+	case "E":
+		class["code"], class["display"] = "EMER", "emergency"
+	case "P":
+		class["code"], class["display"] = "PRENC", "pre-admission"
+	default:
+		class["code"] = s.Get(2, 1, 1)
+	}
+	if class["code"] != "" {
+		encounter["class"] = class
+	}
+
+	period := withoutEmpty(map[string]interface{}{
+		"start": FHIRDateTime(s.Get(44, 1, 1)),
+		"end":   FHIRDateTime(s.Get(45, 1, 1)),
+	})
+	if len(period) > 0 {
+		encounter["period"] = period
+	}
+	if _, ended := period["end"]; ended {
+		encounter["status"] = "finished"
+	}
+	return encounter
+}
+
+func obxToObservation(s Segment, controlID string) map[string]interface{} {
+	setID := s.Get(1, 1, 1)
+	if setID == "" {
+		setID = "1"
+	}
+	obs := map[string]interface{}{
+		"resourceType": "Observation",
+		"id":           controlID + "-obx-" + setID,
+	}
+
+	switch s.Get(11, 1, 1) {
+	case "F":
+		obs["status"] = "final"
+	case "P":
+		obs["status"] = "preliminary"
+	case "C":
+		obs["status"] = "corrected"
+	case "X", "D":
+		obs["status"] = "cancelled"
+	case "W":
+		obs["status"] = "entered-in-error"
+	default:
+		obs["status"] = "registered"
+	}
+
+	obs["code"] = codeableConcept(s, 3)
+	switch s.Get(2, 1, 1) {
+	case "NM", "SN":
+		if v, err := strconv.ParseFloat(s.Get(5, 1, 1), 64); err == nil {
+			quantity := map[string]interface{}{"value": v}
+			if unit := s.Get(6, 1, 1); unit != "" {
+				quantity["unit"] = unit
+			}
+			obs["valueQuantity"] = quantity
+		} else if v := s.Get(5, 1, 1); v != "" {
+			obs["valueString"] = v
+		}
+	case "CE", "CWE", "CNE":
+		obs["valueCodeableConcept"] = codeableConcept(s, 5)
+	default:
+		if v := s.Get(5, 1, 1); v != "" {
+			obs["valueString"] = v
+		}
+	}
+	if ref := s.Get(7, 1, 1); ref != "" {
+		obs["referenceRange"] = []interface{}{map[string]interface{}{"text": ref}}
+	}
+	if flag := s.Get(8, 1, 1); flag != "" {
+		obs["interpretation"] = []interface{}{map[string]interface{}{
+			"coding": []interface{}{map[string]interface{}{
+				"system": "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation",
+				"code":   flag,
+			}},
+		}}
+	}
+	if ts := FHIRDateTime(s.Get(14, 1, 1)); ts != "" {
+		obs["effectiveDateTime"] = ts
+	}
+	return obs
+}
+
+func codeableConcept(s Segment, field int) map[string]interface{} {
+	coding := withoutEmpty(map[string]interface{}{
+		"code":    s.Get(field, 1, 1),
+		"display": s.Get(field, 2, 1),
+		"system":  s.Get(field, 3, 1),
+	})
+	concept := map[string]interface{}{}
+	if len(coding) > 0 {
+		concept["coding"] = []interface{}{coding}
+	}
+	if text := s.Get(field, 2, 1); text != "" {
+		concept["text"] = text
+	}
+	return concept
+}
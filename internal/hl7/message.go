@@ -0,0 +1,354 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encoding describes the delimiters and escape character of a message, which
+// are declared by its MSH segment.
+type Encoding struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// DefaultEncoding is the encoding recommended by the HL7 standard.
+var DefaultEncoding = Encoding{
+	Field:        '|',
+	Component:    '^',
+	Repetition:   '~',
+	Escape:       '\\',
+	Subcomponent: '&',
+}
+
+// Characters returns the encoding characters of MSH-2.
+func (e Encoding) Characters() string {
+	return string([]byte{e.Component, e.Repetition, e.Escape, e.Subcomponent})
+}
+
+// Field is a parsed field of a segment, consisting of one or more
+// repetitions, each consisting of components, each consisting of
+// subcomponents.
+type Field [][][]string
+
+// Segment is a parsed segment of a message.
+type Segment struct {
+	ID     string
+	Fields []Field
+}
+
+// Message is a parsed HL7 version 2 message.
+type Message struct {
+	Encoding Encoding
+	Segments []Segment
+}
+
+// Get returns the value of a subcomponent of the first repetition of a field
+// by its position, where all positions start at 1, or an empty string if it
+// does not exist.
+func (s Segment) Get(field, component, subcomponent int) string {
+	if field < 1 || field > len(s.Fields) {
+		return ""
+	}
+	reps := s.Fields[field-1]
+	if component < 1 || component > len(reps[0]) {
+		return ""
+	}
+	subs := reps[0][component-1]
+	if subcomponent < 1 || subcomponent > len(subs) {
+		return ""
+	}
+	return subs[subcomponent-1]
+}
+
+// Repetitions returns the number of repetitions of a field.
+func (s Segment) Repetitions(field int) int {
+	if field < 1 || field > len(s.Fields) {
+		return 0
+	}
+	return len(s.Fields[field-1])
+}
+
+// GetRepetition returns the value of a component of a repetition of a field,
+// where all positions start at 1.
+func (s Segment) GetRepetition(field, repetition, component int) string {
+	if field < 1 || field > len(s.Fields) {
+		return ""
+	}
+	reps := s.Fields[field-1]
+	if repetition < 1 || repetition > len(reps) {
+		return ""
+	}
+	comps := reps[repetition-1]
+	if component < 1 || component > len(comps) {
+		return ""
+	}
+	return comps[component-1][0]
+}
+
+// Segment returns the first segment of the message with an ID, and whether it
+// exists.
+func (m *Message) Segment(id string) (Segment, bool) {
+	for _, s := range m.Segments {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Header returns the MSH segment of the message.
+func (m *Message) Header() Segment {
+	return m.Segments[0]
+}
+
+//------------------------------------------------------------------------------
+
+// Parse an HL7 version 2 message. Segments can be separated by carriage
+// returns, line feeds, or both.
+func Parse(msgBytes []byte) (*Message, error) {
+	str := strings.TrimSpace(string(msgBytes))
+	if !strings.HasPrefix(str, "MSH") || len(str) < 8 {
+		return nil, errors.New("message does not begin with an MSH segment")
+	}
+
+	enc := Encoding{
+		Field:        str[3],
+		Component:    str[4],
+		Repetition:   str[5],
+		Escape:       str[6],
+		Subcomponent: str[7],
+	}
+	if enc.Subcomponent == enc.Field {
+		// Some systems omit the subcomponent separator.
+		enc.Subcomponent = 0
+	}
+
+	m := &Message{Encoding: enc}
+	lines := strings.FieldsFunc(str, func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	for i, line := range lines {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		seg, err := enc.parseSegment(line, i == 0)
+		if err != nil {
+			return nil, fmt.Errorf("segment %v: %w", i+1, err)
+		}
+		m.Segments = append(m.Segments, seg)
+	}
+	return m, nil
+}
+
+func (e Encoding) parseSegment(line string, header bool) (Segment, error) {
+	parts := strings.Split(line, string(e.Field))
+	id := parts[0]
+	if len(id) != 3 {
+		return Segment{}, fmt.Errorf("invalid segment ID '%v'", id)
+	}
+	seg := Segment{ID: id}
+
+	if header {
+		if id != "MSH" {
+			return Segment{}, errors.New("message does not begin with an MSH segment")
+		}
+		// MSH-1 is the field separator and MSH-2 contains the encoding
+		// characters, neither are split.
+		seg.Fields = append(seg.Fields,
+			Field{{{string(e.Field)}}},
+			Field{{{parts[1]}}},
+		)
+		parts = parts[2:]
+	} else {
+		parts = parts[1:]
+	}
+
+	for _, p := range parts {
+		seg.Fields = append(seg.Fields, e.parseField(p))
+	}
+	return seg, nil
+}
+
+func (e Encoding) parseField(raw string) Field {
+	var field Field
+	for _, rep := range e.split(raw, e.Repetition) {
+		var comps [][]string
+		for _, comp := range e.split(rep, e.Component) {
+			var subs []string
+			for _, sub := range e.split(comp, e.Subcomponent) {
+				subs = append(subs, e.UnescapeValue(sub))
+			}
+			comps = append(comps, subs)
+		}
+		field = append(field, comps)
+	}
+	return field
+}
+
+// split a string by a separator, ignoring separators within escape sequences.
+func (e Encoding) split(s string, sep byte) []string {
+	if sep == 0 {
+		return []string{s}
+	}
+	var parts []string
+	start, escaped := 0, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case e.Escape:
+			escaped = !escaped
+		case sep:
+			if !escaped {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// UnescapeValue replaces the escape sequences of delimiters within a value with
+// the delimiters themselves. Other escape sequences, such as formatting
+// commands, are preserved.
+func (e Encoding) UnescapeValue(s string) string {
+	if strings.IndexByte(s, e.Escape) < 0 {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != e.Escape {
+			buf.WriteByte(s[i])
+			continue
+		}
+		end := strings.IndexByte(s[i+1:], e.Escape)
+		if end < 0 {
+			buf.WriteString(s[i:])
+			break
+		}
+		seq := s[i+1 : i+1+end]
+		switch seq {
+		case "F":
+			buf.WriteByte(e.Field)
+		case "S":
+			buf.WriteByte(e.Component)
+		case "R":
+			buf.WriteByte(e.Repetition)
+		case "E":
+			buf.WriteByte(e.Escape)
+		case "T":
+			buf.WriteByte(e.Subcomponent)
+		default:
+			buf.WriteString(s[i : i+end+2])
+		}
+		i += end + 1
+	}
+	return buf.String()
+}
+
+// EscapeValue replaces delimiters within a value with escape sequences.
+func (e Encoding) EscapeValue(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		var seq string
+		switch s[i] {
+		case e.Field:
+			seq = "F"
+		case e.Component:
+			seq = "S"
+		case e.Repetition:
+			seq = "R"
+		case e.Escape:
+			seq = "E"
+		case e.Subcomponent:
+			seq = "T"
+		default:
+			buf.WriteByte(s[i])
+			continue
+		}
+		buf.WriteByte(e.Escape)
+		buf.WriteString(seq)
+		buf.WriteByte(e.Escape)
+	}
+	return buf.String()
+}
+
+//------------------------------------------------------------------------------
+
+// ToMap converts the message into a generic structure where fields of each
+// segment are addressed by the segment ID followed by their two digit
+// position, e.g. the fifth field of a PID segment has the key PID05.
+//
+// Simple fields are strings, fields with components are arrays where each
+// component is either a string or, when it has subcomponents, an array of
+// strings, and repeated fields are arrays of arrays of components. Empty
+// fields are omitted.
+func (m *Message) ToMap() map[string]interface{} {
+	segments := make([]interface{}, 0, len(m.Segments))
+	for _, s := range m.Segments {
+		obj := map[string]interface{}{"segment": s.ID}
+		for i, f := range s.Fields {
+			if v := f.value(); v != nil {
+				obj[fmt.Sprintf("%v%02d", s.ID, i+1)] = v
+			}
+		}
+		segments = append(segments, obj)
+	}
+
+	header := m.Header()
+	return map[string]interface{}{
+		"message_type":  header.Get(9, 1, 1),
+		"trigger_event": header.Get(9, 2, 1),
+		"control_id":    header.Get(10, 1, 1),
+		"version":       header.Get(12, 1, 1),
+		"segments":      segments,
+	}
+}
+
+func componentValue(subs []string) interface{} {
+	for len(subs) > 1 && subs[len(subs)-1] == "" {
+		subs = subs[:len(subs)-1]
+	}
+	if len(subs) == 1 {
+		return subs[0]
+	}
+	values := make([]interface{}, len(subs))
+	for i, s := range subs {
+		values[i] = s
+	}
+	return values
+}
+
+func componentsValue(comps [][]string) []interface{} {
+	for len(comps) > 0 && len(comps[len(comps)-1]) == 1 && comps[len(comps)-1][0] == "" {
+		comps = comps[:len(comps)-1]
+	}
+	values := make([]interface{}, len(comps))
+	for i, c := range comps {
+		values[i] = componentValue(c)
+	}
+	return values
+}
+
+func (f Field) value() interface{} {
+	if len(f) > 1 {
+		values := make([]interface{}, len(f))
+		for i, comps := range f {
+			values[i] = componentsValue(comps)
+		}
+		return values
+	}
+	comps := componentsValue(f[0])
+	switch len(comps) {
+	case 0:
+		return nil
+	case 1:
+		if s, isStr := comps[0].(string); isStr {
+			return s
+		}
+	}
+	return comps
+}
@@ -0,0 +1,209 @@
+package hl7
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testADT = "MSH|^~\\&|ADT1|HOSP|EHR|HOSP|20210101120000+0100||ADT^A01^ADT_A01|MSG001|P|2.5\r" +
+	"PID|1||12345^^^HOSP^MR~67890^^^SSA^SS||Doe^John^A^Jr^Dr||19800101|M|||1 Main St^Apt 2^Springfield^IL^62701^USA||555-1234\r" +
+	"PV1|1|I|WARD^101^A||||||||||||||||V100|||||||||||||||||||||||||20210101110000|20210103090000\r" +
+	"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-99|N|||F|||20210101113000\r" +
+	"OBX|2|ST|8867-4^Note^LN||Patient \\F\\ stable \\T\\ resting||||||P\r" +
+	"OBX|3|CE|883-9^Blood group^LN||A^A positive^ABO||||||F\r"
+
+func jsonOf(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	msg, err := Parse([]byte(testADT))
+	require.NoError(t, err)
+
+	assert.Equal(t, DefaultEncoding, msg.Encoding)
+	require.Len(t, msg.Segments, 6)
+
+	h := msg.Header()
+	assert.Equal(t, "|", h.Get(1, 1, 1))
+	assert.Equal(t, "^~\\&", h.Get(2, 1, 1))
+	assert.Equal(t, "ADT1", h.Get(3, 1, 1))
+	assert.Equal(t, "A01", h.Get(9, 2, 1))
+	assert.Equal(t, "", h.Get(9, 4, 1))
+	assert.Equal(t, "", h.Get(40, 1, 1))
+
+	pid, ok := msg.Segment("PID")
+	require.True(t, ok)
+	assert.Equal(t, 2, pid.Repetitions(3))
+	assert.Equal(t, "67890", pid.GetRepetition(3, 2, 1))
+	assert.Equal(t, "SS", pid.GetRepetition(3, 2, 5))
+
+	obx := msg.Segments[4]
+	assert.Equal(t, "Patient | stable & resting", obx.Get(5, 1, 1))
+
+	_, ok = msg.Segment("NK1")
+	assert.False(t, ok)
+}
+
+func TestParseLineFeeds(t *testing.T) {
+	msg, err := Parse([]byte("MSH|^~\\&|A|B|C|D|||ORU^R01|1|P|2.3\nPID|1||5\r\n\nOBX|1|ST|X||Y\n"))
+	require.NoError(t, err)
+	require.Len(t, msg.Segments, 3)
+	assert.Equal(t, "OBX", msg.Segments[2].ID)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"not hl7":        "hello world",
+		"bad segment id": "MSH|^~\\&|A\rPIDX|1",
+	}
+	for name, input := range tests {
+		_, err := Parse([]byte(input))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	e := DefaultEncoding
+	for _, v := range []string{
+		"plain",
+		"a|b^c~d\\e&f",
+		"",
+	} {
+		assert.Equal(t, v, e.UnescapeValue(e.EscapeValue(v)))
+	}
+	assert.Equal(t, "a\\F\\b\\S\\c", e.EscapeValue("a|b^c"))
+	assert.Equal(t, "keep \\H\\highlight\\N\\", e.UnescapeValue("keep \\H\\highlight\\N\\"))
+}
+
+func TestToMap(t *testing.T) {
+	msg, err := Parse([]byte("MSH|^~\\&|LAB|HOSP|EHR|HOSP|20210101120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^HOSP^MR~999||Doe^John^A||19800101|M\r" +
+		"ZZZ|a&b^c|||\r"))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{`+
+		`"control_id":"MSG001",`+
+		`"message_type":"ORU",`+
+		`"segments":[`+
+		`{"MSH01":"|","MSH02":"^~\\\u0026","MSH03":"LAB","MSH04":"HOSP","MSH05":"EHR","MSH06":"HOSP","MSH07":"20210101120000","MSH09":["ORU","R01"],"MSH10":"MSG001","MSH11":"P","MSH12":"2.5","segment":"MSH"},`+
+		`{"PID01":"1","PID03":[["12345","","","HOSP","MR"],["999"]],"PID05":["Doe","John","A"],"PID07":"19800101","PID08":"M","segment":"PID"},`+
+		`{"ZZZ01":[["a","b"],"c"],"segment":"ZZZ"}`+
+		`],`+
+		`"trigger_event":"R01",`+
+		`"version":"2.5"`+
+		`}`, jsonOf(t, msg.ToMap()))
+}
+
+func TestFHIRDateTime(t *testing.T) {
+	tests := map[string]string{
+		"2021":                    "2021",
+		"202101":                  "2021-01",
+		"20210102":                "2021-01-02",
+		"2021010203":              "2021-01-02T03:00:00",
+		"20210102030405":          "2021-01-02T03:04:05",
+		"20210102030405.123-0500": "2021-01-02T03:04:05.123-05:00",
+		"20210102030405+01":       "",
+		"nope":                    "",
+		"202":                     "",
+	}
+	for input, exp := range tests {
+		assert.Equal(t, exp, FHIRDateTime(input), input)
+	}
+}
+
+func TestToFHIR(t *testing.T) {
+	msg, err := Parse([]byte(testADT))
+	require.NoError(t, err)
+
+	bundle := msg.ToFHIR()
+	assert.Equal(t, "Bundle", bundle["resourceType"])
+	assert.Equal(t, "message", bundle["type"])
+	assert.Equal(t, "2021-01-01T12:00:00+01:00", bundle["timestamp"])
+
+	entries := bundle["entry"].([]interface{})
+	require.Len(t, entries, 6)
+
+	resource := func(i int) map[string]interface{} {
+		return entries[i].(map[string]interface{})["resource"].(map[string]interface{})
+	}
+
+	assert.Equal(t, `{`+
+		`"destination":[{"endpoint":"HOSP","name":"EHR"}],`+
+		`"eventCoding":{"code":"A01","system":"http://terminology.hl7.org/CodeSystem/v2-0003"},`+
+		`"focus":[{"reference":"Patient/12345"},{"reference":"Encounter/V100"},{"reference":"Observation/MSG001-obx-1"},{"reference":"Observation/MSG001-obx-2"},{"reference":"Observation/MSG001-obx-3"}],`+
+		`"id":"MSG001",`+
+		`"resourceType":"MessageHeader",`+
+		`"source":{"endpoint":"HOSP","name":"ADT1","software":"ADT1"}`+
+		`}`, jsonOf(t, resource(0)))
+
+	assert.Equal(t, "Patient/12345", entries[1].(map[string]interface{})["fullUrl"])
+	assert.Equal(t, `{`+
+		`"address":[{"city":"Springfield","country":"USA","line":["1 Main St","Apt 2"],"postalCode":"62701","state":"IL"}],`+
+		`"birthDate":"1980-01-01",`+
+		`"gender":"male",`+
+		`"id":"12345",`+
+		`"identifier":[`+
+		`{"system":"HOSP","type":{"coding":[{"code":"MR","system":"http://terminology.hl7.org/CodeSystem/v2-0203"}]},"value":"12345"},`+
+		`{"system":"SSA","type":{"coding":[{"code":"SS","system":"http://terminology.hl7.org/CodeSystem/v2-0203"}]},"value":"67890"}`+
+		`],`+
+		`"name":[{"family":"Doe","given":["John","A"],"prefix":["Dr"],"suffix":["Jr"]}],`+
+		`"resourceType":"Patient",`+
+		`"telecom":[{"system":"phone","use":"home","value":"555-1234"}]`+
+		`}`, jsonOf(t, resource(1)))
+
+	assert.Equal(t, `{`+
+		`"class":{"code":"IMP","display":"inpatient encounter","system":"http://terminology.hl7.org/CodeSystem/v3-ActCode"},`+
+		`"id":"V100",`+
+		`"identifier":[{"value":"V100"}],`+
+		`"period":{"end":"2021-01-03T09:00:00","start":"2021-01-01T11:00:00"},`+
+		`"resourceType":"Encounter",`+
+		`"status":"finished",`+
+		`"subject":{"reference":"Patient/12345"}`+
+		`}`, jsonOf(t, resource(2)))
+
+	assert.Equal(t, `{`+
+		`"code":{"coding":[{"code":"2345-7","display":"Glucose","system":"LN"}],"text":"Glucose"},`+
+		`"effectiveDateTime":"2021-01-01T11:30:00",`+
+		`"encounter":{"reference":"Encounter/V100"},`+
+		`"id":"MSG001-obx-1",`+
+		`"interpretation":[{"coding":[{"code":"N","system":"http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"}]}],`+
+		`"referenceRange":[{"text":"70-99"}],`+
+		`"resourceType":"Observation",`+
+		`"status":"final",`+
+		`"subject":{"reference":"Patient/12345"},`+
+		`"valueQuantity":{"unit":"mg/dL","value":95}`+
+		`}`, jsonOf(t, resource(3)))
+
+	assert.Equal(t, "preliminary", resource(4)["status"])
+	assert.Equal(t, "Patient | stable & resting", resource(4)["valueString"])
+
+	assert.Equal(t, `{"coding":[{"code":"A","display":"A positive","system":"ABO"}],"text":"A positive"}`, jsonOf(t, resource(5)["valueCodeableConcept"]))
+}
+
+func TestAck(t *testing.T) {
+	msg, err := Parse([]byte(testADT))
+	require.NoError(t, err)
+
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	header := msg.AckHeader()
+
+	assert.Equal(t,
+		"MSH|^~\\&|EHR|HOSP|ADT1|HOSP|20210102030405||ACK^A01^ACK|MSG001|P|2.5\rMSA|AA|MSG001\r",
+		string(header.Ack(AckAccept, "", now)))
+	assert.Equal(t,
+		"MSH|^~\\&|EHR|HOSP|ADT1|HOSP|20210102030405||ACK^A01^ACK|MSG001|P|2.5\rMSA|AE|MSG001|bad \\F\\ thing\r",
+		string(header.Ack(AckError, "bad | thing", now)))
+
+	ack, err := Parse(header.Ack(AckError, "bad | thing", now))
+	require.NoError(t, err)
+	msa, ok := ack.Segment("MSA")
+	require.True(t, ok)
+	assert.Equal(t, "bad | thing", msa.Get(3, 1, 1))
+}
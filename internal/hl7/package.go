@@ -0,0 +1,3 @@
+// Package hl7 implements parsing of HL7 version 2 messages, conversion of
+// them into FHIR resources, and the generation of acknowledgements.
+package hl7
@@ -37,9 +37,10 @@ type Client struct {
 	dropOn    map[int]struct{}
 	successOn map[int]struct{}
 
-	url     *field.Expression
-	headers map[string]*field.Expression
-	host    *field.Expression
+	url       *field.Expression
+	headers   map[string]*field.Expression
+	host      *field.Expression
+	modifyReq func(req *http.Request)
 
 	conf          client.Config
 	retryThrottle *throttle.Type
@@ -229,6 +230,15 @@ func OptSetRoundTripper(rt http.RoundTripper) func(*Client) {
 	}
 }
 
+// OptModifyRequest sets a function that is called with each request after its
+// headers have been set and before it is signed, allowing components to add
+// headers that depend on their own state.
+func OptModifyRequest(fn func(req *http.Request)) func(*Client) {
+	return func(t *Client) {
+		t.modifyReq = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func (h *Client) incrCode(code int) {
@@ -282,6 +292,29 @@ func (h *Client) waitForAccess(ctx context.Context) bool {
 	}
 }
 
+// releaseAccess releases an access granted by the rate limit, which is only
+// necessary for rate limits that restrict concurrent requests.
+func (h *Client) releaseAccess() {
+	if h.conf.RateLimit == "" {
+		return
+	}
+	if err := interop.AccessRateLimit(context.Background(), h.mgr, h.conf.RateLimit, func(rl types.RateLimit) {
+		if r, ok := rl.(types.RateLimitReleaser); ok {
+			r.Release()
+		}
+	}); err != nil {
+		h.log.Errorf("Rate limit error: %v\n", err)
+		h.mLimitErr.Incr(1)
+	}
+}
+
+// doRequest performs an HTTP request and releases the access granted by the
+// rate limit once a response is received.
+func (h *Client) doRequest(req *http.Request) (*http.Response, error) {
+	defer h.releaseAccess()
+	return h.client.Do(req)
+}
+
 // CreateRequest forms an *http.Request from a message to be sent as the body,
 // and also a message used to form headers (they can be the same).
 func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request, err error) {
@@ -333,6 +366,9 @@ func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request
 		req.Header.Del("Content-Type")
 		req.Header.Add("Content-Type", overrideContentType)
 	}
+	if h.modifyReq != nil {
+		h.modifyReq(req)
+	}
 
 	err = h.conf.Config.Sign(req)
 	return
@@ -497,7 +533,7 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 	rateLimited := false
 	numRetries := h.conf.NumRetries
 
-	res, err = h.client.Do(req.WithContext(ctx))
+	res, err = h.doRequest(req.WithContext(ctx))
 	if err != nil {
 		if err, ok := err.(net.Error); ok && err.Timeout() {
 			h.mErrReqTimeout.Incr(1)
@@ -535,7 +571,7 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 			return nil, types.ErrTypeClosed
 		}
 		rateLimited = false
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+		if res, err = h.doRequest(req.WithContext(ctx)); err == nil {
 			h.incrCode(res.StatusCode)
 			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
 				rateLimited = retryStrat == retryBackoff
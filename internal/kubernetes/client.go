@@ -0,0 +1,379 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client makes requests to an API server.
+type Client struct {
+	conf   *Config
+	client *http.Client
+}
+
+// NewClient creates a client from a config.
+func NewClient(conf *Config) *Client {
+	return &Client{
+		conf: conf,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: conf.TLS,
+			},
+		},
+	}
+}
+
+// StatusError is returned when the API server responds with a failure status.
+type StatusError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (e *StatusError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("kubernetes API responded with status %v (%v): %v", e.Code, e.Reason, e.Message)
+	}
+	return fmt.Sprintf("kubernetes API responded with status %v: %v", e.Code, e.Message)
+}
+
+// IsGone returns whether an error indicates that a resource version is too old
+// to be watched from, in which case resources must be listed again.
+func IsGone(err error) bool {
+	var sErr *StatusError
+	return errors.As(err, &sErr) && sErr.Code == http.StatusGone
+}
+
+// IsNotFound returns whether an error indicates that a resource doesn't exist.
+func IsNotFound(err error) bool {
+	var sErr *StatusError
+	return errors.As(err, &sErr) && sErr.Code == http.StatusNotFound
+}
+
+// IsConflict returns whether an error indicates that a resource was modified
+// since the version that a write was based on, or already exists.
+func IsConflict(err error) bool {
+	var sErr *StatusError
+	return errors.As(err, &sErr) && sErr.Code == http.StatusConflict
+}
+
+// Do makes a request to a path of the API server, returning an error when the
+// response has a failure status.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body []byte, contentType string) (*http.Response, error) {
+	u := c.conf.Server + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	token := c.conf.Token
+	if c.conf.TokenFile != "" {
+		b, err := ioutil.ReadFile(c.conf.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.conf.Username != "" {
+		req.SetBasicAuth(c.conf.Username, c.conf.Password)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+		sErr := &StatusError{}
+		if json.Unmarshal(b, sErr) != nil || sErr.Message == "" {
+			sErr.Message = strings.TrimSpace(string(b))
+		}
+		sErr.Code = res.StatusCode
+		return nil, sErr
+	}
+	return res, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	res, err := c.Do(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+//------------------------------------------------------------------------------
+
+// Resource identifies a kind of resource served by the API server.
+type Resource struct {
+	APIVersion string
+	Kind       string
+
+	// Name is the plural name of the resource used within paths.
+	Name       string
+	Namespaced bool
+}
+
+// Path returns the path of the collection of the resource within a namespace,
+// or across all namespaces when the namespace is empty.
+func (r Resource) Path(namespace string) string {
+	var b strings.Builder
+	if strings.Contains(r.APIVersion, "/") {
+		b.WriteString("/apis/")
+	} else {
+		b.WriteString("/api/")
+	}
+	b.WriteString(r.APIVersion)
+	if r.Namespaced && namespace != "" {
+		b.WriteString("/namespaces/")
+		b.WriteString(url.PathEscape(namespace))
+	}
+	b.WriteString("/")
+	b.WriteString(r.Name)
+	return b.String()
+}
+
+// ObjectPath returns the path of a named object of the resource.
+func (r Resource) ObjectPath(namespace, name string) string {
+	return r.Path(namespace) + "/" + url.PathEscape(name)
+}
+
+// Discover looks up the resource of a kind served under an API version such
+// as v1 or apps/v1.
+func (c *Client) Discover(ctx context.Context, apiVersion, kind string) (Resource, error) {
+	path := "/api/" + apiVersion
+	if strings.Contains(apiVersion, "/") {
+		path = "/apis/" + apiVersion
+	}
+
+	var list struct {
+		Resources []struct {
+			Name       string `json:"name"`
+			Kind       string `json:"kind"`
+			Namespaced bool   `json:"namespaced"`
+		} `json:"resources"`
+	}
+	if err := c.getJSON(ctx, path, nil, &list); err != nil {
+		if IsNotFound(err) {
+			return Resource{}, fmt.Errorf("api version %v is not served", apiVersion)
+		}
+		return Resource{}, err
+	}
+	for _, r := range list.Resources {
+		// Subresources such as pods/log share the kind of their parent.
+		if r.Kind == kind && !strings.Contains(r.Name, "/") {
+			return Resource{
+				APIVersion: apiVersion,
+				Kind:       kind,
+				Name:       r.Name,
+				Namespaced: r.Namespaced,
+			}, nil
+		}
+	}
+	return Resource{}, fmt.Errorf("kind %v is not served under api version %v", kind, apiVersion)
+}
+
+//------------------------------------------------------------------------------
+
+// ObjectMeta contains the common metadata fields of an object.
+type ObjectMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	UID             string `json:"uid"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// MetaOf extracts the metadata of a JSON encoded object.
+func MetaOf(object []byte) (ObjectMeta, error) {
+	var obj struct {
+		Metadata ObjectMeta `json:"metadata"`
+	}
+	err := json.Unmarshal(object, &obj)
+	return obj.Metadata, err
+}
+
+// ApplyOptions configures a server-side apply.
+type ApplyOptions struct {
+	// FieldManager is the name of the manager that owns the applied fields.
+	FieldManager string
+
+	// Force takes ownership of fields owned by other managers instead of
+	// failing with a conflict.
+	Force bool
+
+	// DryRun validates and returns the result of the apply without persisting
+	// it.
+	DryRun bool
+}
+
+// Apply performs a server-side apply of a JSON encoded object, returning the
+// object as it is after the apply, including its status.
+func (c *Client) Apply(ctx context.Context, res Resource, namespace, name string, object []byte, opts ApplyOptions) (json.RawMessage, error) {
+	q := url.Values{}
+	q.Set("fieldManager", opts.FieldManager)
+	if opts.Force {
+		q.Set("force", "true")
+	}
+	if opts.DryRun {
+		q.Set("dryRun", "All")
+	}
+	resp, err := c.Do(ctx, http.MethodPatch, res.ObjectPath(namespace, name), q, object, "application/apply-patch+yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// ListOptions filters and paginates the objects of a list or watch.
+type ListOptions struct {
+	LabelSelector   string
+	FieldSelector   string
+	ResourceVersion string
+	Limit           int64
+	Continue        string
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.LabelSelector != "" {
+		q.Set("labelSelector", o.LabelSelector)
+	}
+	if o.FieldSelector != "" {
+		q.Set("fieldSelector", o.FieldSelector)
+	}
+	if o.ResourceVersion != "" {
+		q.Set("resourceVersion", o.ResourceVersion)
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+	if o.Continue != "" {
+		q.Set("continue", o.Continue)
+	}
+	return q
+}
+
+// ObjectList is a page of objects returned by List.
+type ObjectList struct {
+	ResourceVersion string
+	Continue        string
+
+	// Items are JSON encoded objects with their kind and api version set.
+	Items []json.RawMessage
+}
+
+// List returns a page of the objects of a resource within a namespace, or
+// across all namespaces when the namespace is empty.
+func (c *Client) List(ctx context.Context, res Resource, namespace string, opts ListOptions) (*ObjectList, error) {
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+			Continue        string `json:"continue"`
+		} `json:"metadata"`
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := c.getJSON(ctx, res.Path(namespace), opts.query(), &list); err != nil {
+		return nil, err
+	}
+
+	out := &ObjectList{
+		ResourceVersion: list.Metadata.ResourceVersion,
+		Continue:        list.Metadata.Continue,
+		Items:           make([]json.RawMessage, 0, len(list.Items)),
+	}
+	for _, item := range list.Items {
+		// Items of lists omit their kind and api version.
+		item["apiVersion"], item["kind"] = res.APIVersion, res.Kind
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		out.Items = append(out.Items, b)
+	}
+	return out, nil
+}
+
+// Watch event types.
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+	EventBookmark = "BOOKMARK"
+	EventError    = "ERROR"
+)
+
+// WatchEvent is a change to an object.
+type WatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// WatchStream is a stream of changes to the objects of a resource.
+type WatchStream struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// Next blocks until the next event is received. Error events are returned as
+// a *StatusError.
+func (w *WatchStream) Next() (WatchEvent, error) {
+	var ev WatchEvent
+	if err := w.dec.Decode(&ev); err != nil {
+		return ev, err
+	}
+	if ev.Type == EventError {
+		sErr := &StatusError{}
+		if err := json.Unmarshal(ev.Object, sErr); err != nil {
+			return ev, fmt.Errorf("failed to decode watch error: %w", err)
+		}
+		return ev, sErr
+	}
+	return ev, nil
+}
+
+// Close ends the stream.
+func (w *WatchStream) Close() error {
+	return w.body.Close()
+}
+
+// Watch opens a stream of changes to the objects of a resource after a resource
+// version, within a namespace or across all namespaces when the namespace is
+// empty. Bookmark events are requested, which carry only a resource version.
+func (c *Client) Watch(ctx context.Context, res Resource, namespace string, opts ListOptions) (*WatchStream, error) {
+	q := opts.query()
+	q.Set("watch", "true")
+	q.Set("allowWatchBookmarks", "true")
+	resp, err := c.Do(ctx, http.MethodGet, res.Path(namespace), q, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return &WatchStream{body: resp.Body, dec: json.NewDecoder(resp.Body)}, nil
+}
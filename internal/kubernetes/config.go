@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes how to reach and authenticate with an API server.
+type Config struct {
+	Server string
+	TLS    *tls.Config
+
+	// A bearer token, or a file to read a bearer token from before each
+	// request, allowing tokens to be rotated.
+	Token     string
+	TokenFile string
+
+	Username string
+	Password string
+
+	// Namespace is the default namespace of the context or service account.
+	Namespace string
+}
+
+// ServiceAccountDir is the directory that the credentials of the service
+// account of a pod are mounted to.
+var ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// InCluster returns whether the process appears to be running within a pod.
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// InClusterConfig creates a config from the service account of the pod that
+// the process runs within.
+func InClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running within a kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	caPEM, err := ioutil.ReadFile(filepath.Join(ServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("service account CA does not contain any certificates")
+	}
+
+	conf := &Config{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		TLS:       &tls.Config{RootCAs: pool},
+		TokenFile: filepath.Join(ServiceAccountDir, "token"),
+	}
+	if _, err := os.Stat(conf.TokenFile); err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	if ns, err := ioutil.ReadFile(filepath.Join(ServiceAccountDir, "namespace")); err == nil {
+		conf.Namespace = strings.TrimSpace(string(ns))
+	}
+	return conf, nil
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Username              string      `yaml:"username"`
+			Password              string      `yaml:"password"`
+			Exec                  interface{} `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// Resolve creates a config from a context of a kubeconfig file, or when no
+// file is specified from the service account of the pod when running within a
+// cluster, and otherwise from the kubeconfig file used by kubectl.
+func Resolve(kubeconfig, contextName string) (*Config, error) {
+	if kubeconfig == "" && InCluster() {
+		return InClusterConfig()
+	}
+	if kubeconfig == "" {
+		kubeconfig = DefaultKubeconfigPath()
+	}
+	return LoadKubeconfig(kubeconfig, contextName)
+}
+
+// DefaultKubeconfigPath returns the path of the kubeconfig file used by
+// kubectl, which is the first path of the KUBECONFIG environment variable when
+// set and otherwise ~/.kube/config.
+func DefaultKubeconfigPath() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// LoadKubeconfig creates a config from a context of a kubeconfig file, or its
+// current context when the name is empty. Credentials provided by exec or
+// auth provider plugins are not supported.
+func LoadKubeconfig(path, contextName string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kc kubeconfig
+	if err = yaml.Unmarshal(b, &kc); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	dir := filepath.Dir(path)
+
+	if contextName == "" {
+		if contextName = kc.CurrentContext; contextName == "" {
+			return nil, errors.New("kubeconfig does not specify a current context")
+		}
+	}
+
+	conf := &Config{}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, conf.Namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %v not found within kubeconfig", contextName)
+	}
+
+	tlsConf := &tls.Config{}
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		conf.Server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConf.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		tlsConf.ServerName = c.Cluster.TLSServerName
+
+		caPEM, err := fileOrData(dir, c.Cluster.CertificateAuthority, c.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate authority of cluster %v: %w", clusterName, err)
+		}
+		if caPEM != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("certificate authority of cluster %v does not contain any certificates", clusterName)
+			}
+			tlsConf.RootCAs = pool
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster %v not found within kubeconfig", clusterName)
+	}
+	if conf.Server == "" {
+		return nil, fmt.Errorf("cluster %v does not specify a server", clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("user %v uses a credential plugin, which is not supported", userName)
+		}
+		conf.Token, conf.Username, conf.Password = u.User.Token, u.User.Username, u.User.Password
+		if u.User.TokenFile != "" {
+			conf.TokenFile = resolvePath(dir, u.User.TokenFile)
+		}
+
+		certPEM, err := fileOrData(dir, u.User.ClientCertificate, u.User.ClientCertificateData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate of user %v: %w", userName, err)
+		}
+		keyPEM, err := fileOrData(dir, u.User.ClientKey, u.User.ClientKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key of user %v: %w", userName, err)
+		}
+		if certPEM != nil || keyPEM != nil {
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate of user %v: %w", userName, err)
+			}
+			tlsConf.Certificates = []tls.Certificate{cert}
+		}
+		break
+	}
+
+	conf.TLS = tlsConf
+	return conf, nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// fileOrData returns the contents of a file referenced by a kubeconfig, or the
+// base64 decoded data provided in its place.
+func fileOrData(dir, path, data string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return ioutil.ReadFile(resolvePath(dir, path))
+	}
+	return nil, nil
+}
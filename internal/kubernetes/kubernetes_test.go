@@ -0,0 +1,199 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("bar\n"), 0o600))
+
+	path := filepath.Join(dir, "config")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+current-context: dev
+clusters:
+  - name: dev
+    cluster:
+      server: https://dev.example.com:6443/
+      insecure-skip-tls-verify: true
+  - name: prod
+    cluster:
+      server: https://prod.example.com
+      certificate-authority-data: `+base64.StdEncoding.EncodeToString([]byte("not a cert"))+`
+users:
+  - name: dev
+    user:
+      tokenFile: token
+  - name: prod
+    user:
+      exec:
+        command: aws
+contexts:
+  - name: dev
+    context:
+      cluster: dev
+      user: dev
+      namespace: shop
+  - name: prod
+    context:
+      cluster: prod
+      user: prod
+`), 0o600))
+
+	conf, err := LoadKubeconfig(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://dev.example.com:6443", conf.Server)
+	assert.Equal(t, filepath.Join(dir, "token"), conf.TokenFile)
+	assert.Equal(t, "shop", conf.Namespace)
+	assert.True(t, conf.TLS.InsecureSkipVerify)
+
+	_, err = LoadKubeconfig(path, "prod")
+	assert.EqualError(t, err, "certificate authority of cluster prod does not contain any certificates")
+
+	_, err = LoadKubeconfig(path, "nope")
+	assert.EqualError(t, err, "context nope not found within kubeconfig")
+}
+
+func TestInClusterConfig(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	srv.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.crt"), caPEM, 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("foo"), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "namespace"), []byte("shop"), 0o600))
+
+	prevDir := ServiceAccountDir
+	ServiceAccountDir = dir
+	defer func() {
+		ServiceAccountDir = prevDir
+	}()
+	for k, v := range map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "KUBERNETES_SERVICE_PORT": "443"} {
+		prev, exists := os.LookupEnv(k)
+		require.NoError(t, os.Setenv(k, v))
+		defer func(k string) {
+			if exists {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k)
+	}
+
+	assert.True(t, InCluster())
+	conf, err := InClusterConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://10.0.0.1:443", conf.Server)
+	assert.Equal(t, filepath.Join(dir, "token"), conf.TokenFile)
+	assert.Equal(t, "shop", conf.Namespace)
+}
+
+func TestResourcePath(t *testing.T) {
+	pods := Resource{APIVersion: "v1", Kind: "Pod", Name: "pods", Namespaced: true}
+	assert.Equal(t, "/api/v1/pods", pods.Path(""))
+	assert.Equal(t, "/api/v1/namespaces/shop/pods", pods.Path("shop"))
+	assert.Equal(t, "/api/v1/namespaces/shop/pods/web-0", pods.ObjectPath("shop", "web-0"))
+
+	nodes := Resource{APIVersion: "v1", Kind: "Node", Name: "nodes"}
+	assert.Equal(t, "/api/v1/nodes", nodes.Path("shop"))
+
+	deployments := Resource{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployments", Namespaced: true}
+	assert.Equal(t, "/apis/apps/v1/namespaces/shop/deployments", deployments.Path("shop"))
+}
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer foo", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"resources":[
+			{"name":"pods/log","kind":"Pod","namespaced":true},
+			{"name":"pods","kind":"Pod","namespaced":true},
+			{"name":"nodes","kind":"Node","namespaced":false}
+		]}`))
+	})
+	mux.HandleFunc("/api/v1/namespaces/shop/pods", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			assert.Equal(t, "true", r.URL.Query().Get("allowWatchBookmarks"))
+			assert.Equal(t, "5", r.URL.Query().Get("resourceVersion"))
+			fmt.Fprint(w, `{"type":"ADDED","object":{"kind":"Pod","metadata":{"name":"web-1","resourceVersion":"6"}}}`)
+			fmt.Fprint(w, `{"type":"ERROR","object":{"kind":"Status","code":410,"reason":"Expired","message":"too old resource version: 5 (100)"}}`)
+			return
+		}
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		w.Write([]byte(`{"metadata":{"resourceVersion":"5","continue":"next"},"items":[{"metadata":{"name":"web-0","resourceVersion":"4","generation":9007199254740993}}]}`))
+	})
+	mux.HandleFunc("/api/v1/namespaces/shop/pods/web-2", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "application/apply-patch+yaml", r.Header.Get("Content-Type"))
+		assert.Equal(t, "benthos", r.URL.Query().Get("fieldManager"))
+		assert.Equal(t, "true", r.URL.Query().Get("force"))
+		assert.Equal(t, "All", r.URL.Query().Get("dryRun"))
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, `{"metadata":{"name":"web-2"}}`, string(b))
+		w.Write([]byte(`{"metadata":{"name":"web-2"},"status":{"phase":"Pending"}}`))
+	})
+	mux.HandleFunc("/api/v1/namespaces/nope/pods", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"kind":"Status","code":403,"reason":"Forbidden","message":"pods is forbidden"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient(&Config{Server: srv.URL, Token: "foo"})
+	ctx := context.Background()
+
+	pods, err := c.Discover(ctx, "v1", "Pod")
+	require.NoError(t, err)
+	assert.Equal(t, Resource{APIVersion: "v1", Kind: "Pod", Name: "pods", Namespaced: true}, pods)
+
+	_, err = c.Discover(ctx, "v1", "Deployment")
+	assert.EqualError(t, err, "kind Deployment is not served under api version v1")
+
+	_, err = c.Discover(ctx, "apps/v2", "Deployment")
+	assert.EqualError(t, err, "api version apps/v2 is not served")
+
+	list, err := c.List(ctx, pods, "shop", ListOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "5", list.ResourceVersion)
+	assert.Equal(t, "next", list.Continue)
+	require.Len(t, list.Items, 1)
+	assert.JSONEq(t, `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web-0","resourceVersion":"4","generation":9007199254740993}}`, string(list.Items[0]))
+	meta, err := MetaOf(list.Items[0])
+	require.NoError(t, err)
+	assert.Equal(t, ObjectMeta{Name: "web-0", ResourceVersion: "4"}, meta)
+
+	_, err = c.List(ctx, pods, "nope", ListOptions{})
+	assert.EqualError(t, err, "kubernetes API responded with status 403 (Forbidden): pods is forbidden")
+
+	applied, err := c.Apply(ctx, pods, "shop", "web-2", []byte(`{"metadata":{"name":"web-2"}}`), ApplyOptions{
+		FieldManager: "benthos",
+		Force:        true,
+		DryRun:       true,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"metadata":{"name":"web-2"},"status":{"phase":"Pending"}}`, string(applied))
+
+	stream, err := c.Watch(ctx, pods, "shop", ListOptions{ResourceVersion: "5"})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	ev, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, EventAdded, ev.Type)
+
+	_, err = stream.Next()
+	assert.True(t, IsGone(err))
+	assert.EqualError(t, err, "kubernetes API responded with status 410 (Expired): too old resource version: 5 (100)")
+}
@@ -0,0 +1,4 @@
+// Package kubernetes implements a minimal client of the Kubernetes API,
+// covering authentication with kubeconfig files and service accounts,
+// discovery of resource kinds, and listing and watching resources.
+package kubernetes
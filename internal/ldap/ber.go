@@ -0,0 +1,200 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tags used by the LDAP protocol (RFC 4511), including the class and
+// constructed bits.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	tagBindRequest           = 0x60
+	tagBindResponse          = 0x61
+	tagUnbindRequest         = 0x42
+	tagSearchRequest         = 0x63
+	tagSearchResultEntry     = 0x64
+	tagSearchResultDone      = 0x65
+	tagSearchResultReference = 0x73
+	tagExtendedResponse      = 0x78
+	tagControls              = 0xa0
+
+	tagSimpleAuth = 0x80
+)
+
+// maxPacketSize protects against allocating huge buffers when reading a
+// corrupt length.
+const maxPacketSize = 64 * 1024 * 1024
+
+// packet is a decoded BER element, where the children of constructed elements
+// are decoded recursively.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+func (p *packet) constructed() bool {
+	return p.tag&0x20 != 0
+}
+
+func (p *packet) child(i int) (*packet, error) {
+	if i >= len(p.children) {
+		return nil, fmt.Errorf("expected at least %v elements within tag 0x%x, got %v", i+1, p.tag, len(p.children))
+	}
+	return p.children[i], nil
+}
+
+func (p *packet) int() (int64, error) {
+	if len(p.value) == 0 || len(p.value) > 8 {
+		return 0, fmt.Errorf("invalid integer length: %v", len(p.value))
+	}
+	v := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeTLV(tag byte, value []byte) []byte {
+	b := append([]byte{tag}, encodeLength(len(value))...)
+	return append(b, value...)
+}
+
+func encodeConstructed(tag byte, children ...[]byte) []byte {
+	var value []byte
+	for _, c := range children {
+		value = append(value, c...)
+	}
+	return encodeTLV(tag, value)
+}
+
+func encodeInt(tag byte, v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return encodeTLV(tag, b)
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return encodeTLV(tagBoolean, []byte{0xff})
+	}
+	return encodeTLV(tagBoolean, []byte{0x00})
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encodeTLV(tag, []byte(s))
+}
+
+//------------------------------------------------------------------------------
+
+func readLength(r io.ByteReader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < 0x80 {
+		return int(b), nil
+	}
+	n := int(b & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, fmt.Errorf("unsupported length encoding: 0x%x", b)
+	}
+	var l int
+	for i := 0; i < n; i++ {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		l = l<<8 | int(b)
+	}
+	return l, nil
+}
+
+// readPacket reads a single BER element from a stream.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	l, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > maxPacketSize {
+		return nil, fmt.Errorf("packet length %v exceeds maximum", l)
+	}
+	value := make([]byte, l)
+	if _, err = io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return decodeValue(tag, value)
+}
+
+func decodeValue(tag byte, value []byte) (*packet, error) {
+	p := &packet{tag: tag, value: value}
+	if !p.constructed() {
+		return p, nil
+	}
+	for len(value) > 0 {
+		child, n, err := decodePacket(value)
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		value = value[n:]
+	}
+	return p, nil
+}
+
+// decodePacket decodes a BER element from a buffer, returning the number of
+// bytes consumed.
+func decodePacket(b []byte) (*packet, int, error) {
+	if len(b) < 2 {
+		return nil, 0, errors.New("truncated packet")
+	}
+	tag := b[0]
+	l, off := int(b[1]), 2
+	if l >= 0x80 {
+		n := l & 0x7f
+		if n == 0 || n > 4 || len(b) < 2+n {
+			return nil, 0, errors.New("invalid packet length")
+		}
+		l = 0
+		for _, c := range b[2 : 2+n] {
+			l = l<<8 | int(c)
+		}
+		off += n
+	}
+	if l < 0 || len(b)-off < l {
+		return nil, 0, errors.New("truncated packet")
+	}
+	p, err := decodeValue(tag, b[off:off+l])
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, off + l, nil
+}
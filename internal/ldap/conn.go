@@ -0,0 +1,253 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result codes of interest (RFC 4511 section 4.1.9).
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+)
+
+var resultNames = map[int]string{
+	0:  "success",
+	1:  "operationsError",
+	2:  "protocolError",
+	3:  "timeLimitExceeded",
+	4:  "sizeLimitExceeded",
+	7:  "authMethodNotSupported",
+	8:  "strongerAuthRequired",
+	10: "referral",
+	11: "adminLimitExceeded",
+	12: "unavailableCriticalExtension",
+	32: "noSuchObject",
+	34: "invalidDNSyntax",
+	48: "inappropriateAuthentication",
+	49: "invalidCredentials",
+	50: "insufficientAccessRights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+	80: "other",
+}
+
+// Error is a non-successful result returned by a directory server.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	name, exists := resultNames[e.Code]
+	if !exists {
+		name = "result code"
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("%v (%v)", name, e.Code)
+	}
+	return fmt.Sprintf("%v (%v): %v", name, e.Code, e.Message)
+}
+
+// ErrClosed is returned when attempting to use a closed connection.
+var ErrClosed = errors.New("connection closed")
+
+//------------------------------------------------------------------------------
+
+// Conn is a connection to a directory server, where requests are sent one at
+// a time.
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	mut       sync.Mutex
+	messageID int64
+	closed    bool
+}
+
+// Dial connects to the directory server of a URL, where the `ldaps` scheme, or
+// a non-nil TLS config, connects with TLS.
+func Dial(ctx context.Context, urlStr string, tlsConf *tls.Config, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+
+	port := "389"
+	switch u.Scheme {
+	case "ldap":
+	case "ldaps":
+		port = "636"
+		if tlsConf == nil {
+			tlsConf = &tls.Config{}
+		}
+	default:
+		return nil, fmt.Errorf("url scheme not recognised: %v", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("url must contain a host")
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf != nil {
+		tConf := tlsConf.Clone()
+		if tConf.ServerName == "" {
+			tConf.ServerName = u.Hostname()
+		}
+		conn = tls.Client(conn, tConf)
+	}
+	return &Conn{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// Close sends an unbind request and closes the connection.
+func (c *Conn) Close() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.messageID++
+	_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = c.conn.Write(encodeConstructed(tagSequence,
+		encodeInt(tagInteger, c.messageID),
+		encodeTLV(tagUnbindRequest, nil),
+	))
+	return c.conn.Close()
+}
+
+// Bind authenticates the connection with a simple bind, an empty DN and
+// password results in an anonymous bind.
+func (c *Conn) Bind(ctx context.Context, dn, password string) error {
+	req := encodeConstructed(tagBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	)
+
+	var res *packet
+	err := c.roundTrip(ctx, req, nil, func(op *packet, _ *packet) (bool, error) {
+		if op.tag != tagBindResponse {
+			return false, fmt.Errorf("unexpected response to bind request: 0x%x", op.tag)
+		}
+		res = op
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return resultErr(res)
+}
+
+// roundTrip sends a request and calls fn with the protocol op and controls of
+// each response with a matching message ID until fn returns true.
+func (c *Conn) roundTrip(ctx context.Context, op, controls []byte, fn func(op, controls *packet) (bool, error)) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	c.messageID++
+	msg := [][]byte{encodeInt(tagInteger, c.messageID), op}
+	if len(controls) > 0 {
+		msg = append(msg, encodeTLV(tagControls, controls))
+	}
+	if _, err := c.conn.Write(encodeConstructed(tagSequence, msg...)); err != nil {
+		return c.fail(err)
+	}
+
+	for {
+		p, err := readPacket(c.r)
+		if err != nil {
+			return c.fail(err)
+		}
+		if p.tag != tagSequence || len(p.children) < 2 {
+			return c.fail(errors.New("received malformed message"))
+		}
+		id, err := p.children[0].int()
+		if err != nil {
+			return c.fail(err)
+		}
+		resOp := p.children[1]
+		if id == 0 && resOp.tag == tagExtendedResponse {
+			// Notice of disconnection (RFC 4511 section 4.4.1)
+			return c.fail(fmt.Errorf("server closed connection: %w", resultErr(resOp)))
+		}
+		if id != c.messageID {
+			continue
+		}
+		var resControls *packet
+		if len(p.children) > 2 && p.children[2].tag == tagControls {
+			resControls = p.children[2]
+		}
+		done, err := fn(resOp, resControls)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// fail closes the connection after an IO or protocol error, as the state of
+// the stream can no longer be trusted.
+func (c *Conn) fail(err error) error {
+	c.closed = true
+	c.conn.Close()
+	if err == io.EOF {
+		return ErrClosed
+	}
+	return err
+}
+
+// resultErr returns an error for a non-successful LDAPResult.
+func resultErr(p *packet) error {
+	if p == nil || len(p.children) < 3 {
+		return errors.New("received malformed result")
+	}
+	code, err := p.children[0].int()
+	if err != nil {
+		return err
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{
+		Code:    int(code),
+		Message: strings.TrimSpace(string(p.children[2].value)),
+	}
+}
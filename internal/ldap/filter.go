@@ -0,0 +1,199 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Filter choice tags (RFC 4511 section 4.5.1).
+const (
+	filterAnd            = 0xa0
+	filterOr             = 0xa1
+	filterNot            = 0xa2
+	filterEqualityMatch  = 0xa3
+	filterSubstrings     = 0xa4
+	filterGreaterOrEqual = 0xa5
+	filterLessOrEqual    = 0xa6
+	filterPresent        = 0x87
+	filterApproxMatch    = 0xa8
+
+	substringInitial = 0x80
+	substringAny     = 0x81
+	substringFinal   = 0x82
+)
+
+// CompileFilter parses a string representation of a search filter (RFC 4515)
+// into its BER encoding. The enclosing parentheses may be omitted for filters
+// consisting of a single item. Extensible match items are not supported.
+func CompileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, errors.New("filter is empty")
+	}
+	if filter[0] != '(' {
+		filter = "(" + filter + ")"
+	}
+	b, n, err := compileFilter(filter, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(filter) {
+		return nil, fmt.Errorf("unexpected characters at position %v of filter", n)
+	}
+	return b, nil
+}
+
+// EscapeFilterValue escapes the characters of a value that have special
+// meaning within a filter.
+func EscapeFilterValue(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func compileFilter(f string, pos int) ([]byte, int, error) {
+	if pos >= len(f) || f[pos] != '(' {
+		return nil, pos, fmt.Errorf("expected '(' at position %v of filter", pos)
+	}
+	pos++
+	if pos >= len(f) {
+		return nil, pos, errors.New("unexpected end of filter")
+	}
+
+	var b []byte
+	var err error
+	switch f[pos] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if f[pos] == '|' {
+			tag = filterOr
+		}
+		pos++
+		var children [][]byte
+		for pos < len(f) && f[pos] == '(' {
+			var child []byte
+			if child, pos, err = compileFilter(f, pos); err != nil {
+				return nil, pos, err
+			}
+			children = append(children, child)
+		}
+		b = encodeConstructed(tag, children...)
+	case '!':
+		var child []byte
+		if child, pos, err = compileFilter(f, pos+1); err != nil {
+			return nil, pos, err
+		}
+		b = encodeConstructed(filterNot, child)
+	default:
+		end := strings.IndexByte(f[pos:], ')')
+		if end == -1 {
+			return nil, pos, errors.New("unexpected end of filter")
+		}
+		if b, err = compileItem(f[pos : pos+end]); err != nil {
+			return nil, pos, err
+		}
+		pos += end
+	}
+
+	if pos >= len(f) || f[pos] != ')' {
+		return nil, pos, fmt.Errorf("expected ')' at position %v of filter", pos)
+	}
+	return b, pos + 1, nil
+}
+
+func compileItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("invalid filter item: %v", item)
+	}
+
+	attr, value, tag := item[:eq], item[eq+1:], byte(filterEqualityMatch)
+	switch attr[len(attr)-1] {
+	case '~':
+		tag = filterApproxMatch
+	case '>':
+		tag = filterGreaterOrEqual
+	case '<':
+		tag = filterLessOrEqual
+	}
+	if tag != filterEqualityMatch {
+		attr = attr[:len(attr)-1]
+	}
+	if attr == "" || strings.ContainsAny(attr, "()*\\ ") {
+		return nil, fmt.Errorf("invalid attribute description in filter item: %v", item)
+	}
+	if strings.ContainsRune(attr, ':') {
+		return nil, fmt.Errorf("extensible match filters are not supported: %v", item)
+	}
+
+	if tag == filterEqualityMatch && strings.ContainsRune(value, '*') {
+		if value == "*" {
+			return encodeString(filterPresent, attr), nil
+		}
+		return compileSubstrings(attr, value)
+	}
+
+	v, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeConstructed(tag, encodeString(tagOctetString, attr), encodeTLV(tagOctetString, v)), nil
+}
+
+func compileSubstrings(attr, value string) ([]byte, error) {
+	parts := strings.Split(value, "*")
+
+	var subs [][]byte
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		v, err := unescapeFilterValue(p)
+		if err != nil {
+			return nil, err
+		}
+		tag := byte(substringAny)
+		if i == 0 {
+			tag = substringInitial
+		} else if i == len(parts)-1 {
+			tag = substringFinal
+		}
+		subs = append(subs, encodeTLV(tag, v))
+	}
+	return encodeConstructed(filterSubstrings,
+		encodeString(tagOctetString, attr),
+		encodeConstructed(tagSequence, subs...),
+	), nil
+}
+
+func unescapeFilterValue(v string) ([]byte, error) {
+	b := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			if i+2 >= len(v) {
+				return nil, fmt.Errorf("invalid escape sequence in filter value: %v", v)
+			}
+			c, err := hex.DecodeString(v[i+1 : i+3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape sequence in filter value: %v", v)
+			}
+			b = append(b, c[0])
+			i += 2
+		case '(', ')', '*':
+			return nil, fmt.Errorf("unescaped character '%c' in filter value: %v", v[i], v)
+		default:
+			b = append(b, v[i])
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,327 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := map[string]string{
+		"(cn=foo)":        "a3090402636e0403666f6f",
+		"cn=foo":          "a3090402636e0403666f6f",
+		"(objectClass=*)": "870b6f626a656374436c617373",
+		"(cn=a*b*c)":      "a40f0402636e3009800161810162820163",
+		"(cn=*b*)":        "a4090402636e3003810162",
+		"(n>=10)":         "a50704016e04023130",
+		"(n<=10)":         "a60704016e04023130",
+		"(cn~=foo)":       "a8090402636e0403666f6f",
+		"(cn=a\\2ab)":     "a3090402636e0403612a62",
+		"(&(a=1)(!(b=2)))": "a012" + "a306040161040131" +
+			"a208a306040162040132",
+		"(|(a=1)(b=2))": "a110a306040161040131a306040162040132",
+	}
+
+	for filter, exp := range tests {
+		expBytes, err := hex.DecodeString(exp)
+		require.NoError(t, err)
+
+		b, err := CompileFilter(filter)
+		require.NoError(t, err, filter)
+		assert.Equal(t, expBytes, b, filter)
+	}
+
+	for _, filter := range []string{
+		"",
+		"(cn=foo",
+		"(cn=foo))",
+		"(=foo)",
+		"(cn=a(b)",
+		"(cn=\\zz)",
+		"(cn=\\2)",
+		"(cn:dn:=foo)",
+		"(&(a=1)b=2)",
+	} {
+		_, err := CompileFilter(filter)
+		assert.Error(t, err, filter)
+	}
+
+	assert.Equal(t, `a\2a\28b\29\5c`, EscapeFilterValue(`a*(b)\`))
+}
+
+func TestBERRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40} {
+		p, n, err := decodePacket(encodeInt(tagInteger, v))
+		require.NoError(t, err)
+		got, err := p.int()
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+		assert.Equal(t, len(encodeInt(tagInteger, v)), n)
+	}
+
+	long := make([]byte, 300)
+	b := encodeConstructed(tagSequence, encodeTLV(tagOctetString, long), encodeBool(true))
+	assert.Equal(t, []byte{0x30, 0x82, 0x01, 0x33, 0x04, 0x82, 0x01, 0x2c}, b[:8])
+
+	p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+	require.NoError(t, err)
+	require.Len(t, p.children, 2)
+	assert.Equal(t, long, p.children[0].value)
+	assert.Equal(t, []byte{0xff}, p.children[1].value)
+}
+
+//------------------------------------------------------------------------------
+
+type fakeEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+// fakeDirectory is a directory server that supports simple binds and paged
+// searches over a fixed list of entries, where filters are recorded rather
+// than evaluated.
+type fakeDirectory struct {
+	t        *testing.T
+	listener net.Listener
+
+	bindDN   string
+	password string
+	entries  []fakeEntry
+
+	mut      sync.Mutex
+	filters  [][]byte
+	pageReqs []int
+}
+
+func newFakeDirectory(t *testing.T, entries []fakeEntry) *fakeDirectory {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	d := &fakeDirectory{
+		t:        t,
+		listener: l,
+		bindDN:   "cn=admin,dc=example,dc=com",
+		password: "secret",
+		entries:  entries,
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+	})
+	return d
+}
+
+func (d *fakeDirectory) url() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+func result(tag byte, code int64, msg string) []byte {
+	return encodeConstructed(tag,
+		encodeInt(tagEnumerated, code),
+		encodeString(tagOctetString, ""),
+		encodeString(tagOctetString, msg),
+	)
+}
+
+func (d *fakeDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	bound := false
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		id := p.children[0].value
+		op := p.children[1]
+
+		respond := func(parts ...[]byte) {
+			msg := append([][]byte{encodeTLV(tagInteger, id)}, parts...)
+			_, _ = conn.Write(encodeConstructed(tagSequence, msg...))
+		}
+
+		switch op.tag {
+		case tagUnbindRequest:
+			return
+		case tagBindRequest:
+			if string(op.children[1].value) == d.bindDN && string(op.children[2].value) == d.password {
+				bound = true
+				respond(result(tagBindResponse, 0, ""))
+			} else {
+				respond(result(tagBindResponse, 49, "invalid credentials"))
+			}
+		case tagSearchRequest:
+			if !bound {
+				respond(result(tagSearchResultDone, 50, "bind required"))
+				continue
+			}
+
+			d.mut.Lock()
+			d.filters = append(d.filters, encodeTLV(op.children[6].tag, op.children[6].value))
+			d.mut.Unlock()
+
+			pageSize, offset := len(d.entries), 0
+			paged := len(p.children) > 2
+			if paged {
+				ctrl := p.children[2].children[0]
+				v, _, err := decodePacket(ctrl.children[len(ctrl.children)-1].value)
+				require.NoError(d.t, err)
+				size, _ := v.children[0].int()
+				pageSize = int(size)
+				if len(v.children[1].value) > 0 {
+					offset, _ = strconv.Atoi(string(v.children[1].value))
+				}
+				d.mut.Lock()
+				d.pageReqs = append(d.pageReqs, pageSize)
+				d.mut.Unlock()
+			}
+
+			end := offset + pageSize
+			if end > len(d.entries) {
+				end = len(d.entries)
+			}
+			for _, e := range d.entries[offset:end] {
+				var attrs [][]byte
+				for k, vs := range e.attrs {
+					var vals [][]byte
+					for _, v := range vs {
+						vals = append(vals, encodeString(tagOctetString, v))
+					}
+					attrs = append(attrs, encodeConstructed(tagSequence,
+						encodeString(tagOctetString, k),
+						encodeConstructed(tagSet, vals...),
+					))
+				}
+				respond(encodeConstructed(tagSearchResultEntry,
+					encodeString(tagOctetString, e.dn),
+					encodeConstructed(tagSequence, attrs...),
+				))
+			}
+
+			if !paged {
+				respond(result(tagSearchResultDone, 0, ""))
+				continue
+			}
+			cookie := ""
+			if end < len(d.entries) && pageSize > 0 {
+				cookie = strconv.Itoa(end)
+			}
+			respond(result(tagSearchResultDone, 0, ""), encodeTLV(tagControls, encodeConstructed(tagSequence,
+				encodeString(tagOctetString, pagedResultsOID),
+				encodeTLV(tagOctetString, encodeConstructed(tagSequence,
+					encodeInt(tagInteger, 0),
+					encodeString(tagOctetString, cookie),
+				)),
+			)))
+		}
+	}
+}
+
+func TestSearchPaged(t *testing.T) {
+	d := newFakeDirectory(t, []fakeEntry{
+		{dn: "cn=a,dc=example,dc=com", attrs: map[string][]string{"cn": {"a"}}},
+		{dn: "cn=b,dc=example,dc=com", attrs: map[string][]string{"cn": {"b"}, "mail": {"b@example.com", "bee@example.com"}}},
+		{dn: "cn=c,dc=example,dc=com", attrs: map[string][]string{"cn": {"c"}}},
+	})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conn, err := Dial(ctx, d.url(), nil, time.Second*5)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.Bind(ctx, "cn=admin,dc=example,dc=com", "nope")
+	var lErr *Error
+	require.True(t, errors.As(err, &lErr), err)
+	assert.Equal(t, ResultInvalidCredentials, lErr.Code)
+	assert.Equal(t, "invalidCredentials (49): invalid credentials", err.Error())
+
+	require.NoError(t, conn.Bind(ctx, "cn=admin,dc=example,dc=com", "secret"))
+
+	cursor, err := conn.Search(SearchRequest{
+		BaseDN:   "dc=example,dc=com",
+		Scope:    ScopeWholeSubtree,
+		Filter:   "(objectClass=person)",
+		PageSize: 2,
+	})
+	require.NoError(t, err)
+
+	page, err := cursor.NextPage(ctx)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "cn=a,dc=example,dc=com", page[0].DN)
+	assert.Equal(t, [][]byte{[]byte("b@example.com"), []byte("bee@example.com")}, page[1].Get("MAIL"))
+
+	page, err = cursor.NextPage(ctx)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "cn=c,dc=example,dc=com", page[0].DN)
+
+	_, err = cursor.NextPage(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	expFilter, err := CompileFilter("(objectClass=person)")
+	require.NoError(t, err)
+	d.mut.Lock()
+	assert.Equal(t, [][]byte{expFilter, expFilter}, d.filters)
+	assert.Equal(t, []int{2, 2}, d.pageReqs)
+	d.mut.Unlock()
+
+	// Abandoning a search sends a request with a page size of zero.
+	cursor, err = conn.Search(SearchRequest{BaseDN: "dc=example,dc=com", Filter: "(cn=*)", PageSize: 1})
+	require.NoError(t, err)
+	_, err = cursor.NextPage(ctx)
+	require.NoError(t, err)
+	require.NoError(t, cursor.Close(ctx))
+	_, err = cursor.NextPage(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	d.mut.Lock()
+	assert.Equal(t, []int{2, 2, 1, 0}, d.pageReqs)
+	d.mut.Unlock()
+
+	// Without paging all results are returned in one page.
+	cursor, err = conn.Search(SearchRequest{BaseDN: "dc=example,dc=com", Filter: "(cn=*)"})
+	require.NoError(t, err)
+	page, err = cursor.NextPage(ctx)
+	require.NoError(t, err)
+	assert.Len(t, page, 3)
+	_, err = cursor.NextPage(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	require.NoError(t, conn.Close())
+	_, err = conn.Search(SearchRequest{Filter: "("})
+	require.Error(t, err)
+}
+
+func TestDialBadURL(t *testing.T) {
+	ctx := context.Background()
+	_, err := Dial(ctx, "http://localhost", nil, time.Second)
+	assert.EqualError(t, err, "url scheme not recognised: http")
+
+	_, err = Dial(ctx, "ldap://", nil, time.Second)
+	assert.EqualError(t, err, "url must contain a host")
+}
@@ -0,0 +1,3 @@
+// Package ldap implements a minimal LDAP v3 client (RFC 4511) supporting simple
+// binds and searches with the simple paged results control (RFC 2696).
+package ldap
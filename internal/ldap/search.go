@@ -0,0 +1,223 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pagedResultsOID identifies the simple paged results control (RFC 2696).
+const pagedResultsOID = "1.2.840.113556.1.4.319"
+
+// Scope determines which entries relative to the base DN are searched.
+type Scope int
+
+// Search scopes.
+const (
+	ScopeBaseObject Scope = iota
+	ScopeSingleLevel
+	ScopeWholeSubtree
+)
+
+// SearchRequest describes a search operation.
+type SearchRequest struct {
+	BaseDN     string
+	Scope      Scope
+	Filter     string
+	Attributes []string
+
+	// PageSize is the number of entries to request with each page of results,
+	// where zero disables paging.
+	PageSize int
+}
+
+// Attribute is an attribute of an entry and its values.
+type Attribute struct {
+	Name   string
+	Values [][]byte
+}
+
+// Entry is an entry returned by a search.
+type Entry struct {
+	DN         string
+	Attributes []Attribute
+}
+
+// Get returns the values of an attribute of the entry, where the name is
+// matched case insensitively.
+func (e *Entry) Get(name string) [][]byte {
+	for _, a := range e.Attributes {
+		if strings.EqualFold(a.Name, name) {
+			return a.Values
+		}
+	}
+	return nil
+}
+
+// Cursor reads the results of a search one page at a time.
+type Cursor struct {
+	conn     *Conn
+	req      SearchRequest
+	filter   []byte
+	cookie   []byte
+	started  bool
+	finished bool
+}
+
+// Search compiles the filter of a search request and returns a cursor for
+// reading its results. No request is sent until the first page is read.
+func (c *Conn) Search(req SearchRequest) (*Cursor, error) {
+	filter, err := CompileFilter(req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile filter: %w", err)
+	}
+	return &Cursor{
+		conn:   c,
+		req:    req,
+		filter: filter,
+	}, nil
+}
+
+func (s *Cursor) request(pageSize int) (op, controls []byte) {
+	attrs := make([][]byte, len(s.req.Attributes))
+	for i, a := range s.req.Attributes {
+		attrs[i] = encodeString(tagOctetString, a)
+	}
+	op = encodeConstructed(tagSearchRequest,
+		encodeString(tagOctetString, s.req.BaseDN),
+		encodeInt(tagEnumerated, int64(s.req.Scope)),
+		encodeInt(tagEnumerated, 0), // neverDerefAliases
+		encodeInt(tagInteger, 0),    // sizeLimit
+		encodeInt(tagInteger, 0),    // timeLimit
+		encodeBool(false),           // typesOnly
+		s.filter,
+		encodeConstructed(tagSequence, attrs...),
+	)
+	if s.req.PageSize > 0 {
+		controls = encodeConstructed(tagSequence,
+			encodeString(tagOctetString, pagedResultsOID),
+			encodeTLV(tagOctetString, encodeConstructed(tagSequence,
+				encodeInt(tagInteger, int64(pageSize)),
+				encodeTLV(tagOctetString, s.cookie),
+			)),
+		)
+	}
+	return
+}
+
+// NextPage returns the next page of entries, or io.EOF once all results have
+// been read. Search result references are ignored.
+func (s *Cursor) NextPage(ctx context.Context) ([]*Entry, error) {
+	if s.finished {
+		return nil, io.EOF
+	}
+
+	var entries []*Entry
+	var cookie []byte
+	op, controls := s.request(s.req.PageSize)
+	err := s.conn.roundTrip(ctx, op, controls, func(res, resControls *packet) (bool, error) {
+		switch res.tag {
+		case tagSearchResultEntry:
+			e, err := parseEntry(res)
+			if err != nil {
+				return false, err
+			}
+			entries = append(entries, e)
+			return false, nil
+		case tagSearchResultReference:
+			return false, nil
+		case tagSearchResultDone:
+			if err := resultErr(res); err != nil {
+				return true, err
+			}
+			var err error
+			cookie, err = pagedCookie(resControls)
+			return true, err
+		}
+		return false, fmt.Errorf("unexpected response to search request: 0x%x", res.tag)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.started = true
+	s.cookie = cookie
+	if len(cookie) == 0 {
+		s.finished = true
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+	}
+	return entries, nil
+}
+
+// Close abandons the remaining pages of a search that has not been read to
+// completion, allowing the server to release its resources.
+func (s *Cursor) Close(ctx context.Context) error {
+	if s.finished || !s.started || len(s.cookie) == 0 {
+		s.finished = true
+		return nil
+	}
+	s.finished = true
+
+	op, controls := s.request(0)
+	return s.conn.roundTrip(ctx, op, controls, func(res, _ *packet) (bool, error) {
+		return res.tag == tagSearchResultDone, nil
+	})
+}
+
+func parseEntry(p *packet) (*Entry, error) {
+	dn, err := p.child(0)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := p.child(1)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{DN: string(dn.value)}
+	for _, a := range attrs.children {
+		name, err := a.child(0)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := a.child(1)
+		if err != nil {
+			return nil, err
+		}
+		attr := Attribute{Name: string(name.value)}
+		for _, v := range vals.children {
+			attr.Values = append(attr.Values, v.value)
+		}
+		e.Attributes = append(e.Attributes, attr)
+	}
+	return e, nil
+}
+
+// pagedCookie extracts the cookie of a paged results control, an empty cookie
+// indicates there are no further pages.
+func pagedCookie(controls *packet) ([]byte, error) {
+	if controls == nil {
+		return nil, nil
+	}
+	for _, c := range controls.children {
+		if len(c.children) == 0 || string(c.children[0].value) != pagedResultsOID {
+			continue
+		}
+		value := c.children[len(c.children)-1]
+		if value.tag != tagOctetString {
+			return nil, nil
+		}
+		v, _, err := decodePacket(value.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode paged results control: %w", err)
+		}
+		cookie, err := v.child(1)
+		if err != nil {
+			return nil, err
+		}
+		return cookie.value, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,133 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+type cacheRecord struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// CacheLock is a lock stored as a key of a cache resource, where the value
+// contains the holder of the lock and the time at which it expires.
+//
+// The lock relies on the add operation of the cache failing when a key already
+// exists, and on the clocks of all holders being roughly in sync.
+type CacheLock struct {
+	mgr      types.Manager
+	resource string
+	key      string
+}
+
+// NewCacheLock creates a lock stored under a key of a cache resource.
+func NewCacheLock(mgr types.Manager, resource, key string) (*CacheLock, error) {
+	if err := interop.ProbeCache(context.Background(), mgr, resource); err != nil {
+		return nil, err
+	}
+	return &CacheLock{mgr: mgr, resource: resource, key: key}, nil
+}
+
+func (l *CacheLock) access(ctx context.Context, fn func(c types.Cache) error) error {
+	var err error
+	if cerr := interop.AccessCache(ctx, l.mgr, l.resource, func(c types.Cache) {
+		err = fn(c)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+func (l *CacheLock) read(c types.Cache) (*cacheRecord, error) {
+	b, err := c.Get(l.key)
+	if errors.Is(err, types.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec cacheRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (l *CacheLock) write(c types.Cache, rec cacheRecord, ttl time.Duration, add bool) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if tc, ok := c.(types.CacheWithTTL); ok {
+		if add {
+			return tc.AddWithTTL(l.key, b, &ttl)
+		}
+		return tc.SetWithTTL(l.key, b, &ttl)
+	}
+	if add {
+		return c.Add(l.key, b)
+	}
+	return c.Set(l.key, b)
+}
+
+// Acquire attempts to acquire the lock for a holder, or renew it when the
+// holder already holds it, for the duration of a ttl.
+func (l *CacheLock) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	var held bool
+	err := l.access(ctx, func(c types.Cache) error {
+		rec, err := l.read(c)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		next := cacheRecord{Holder: holder, Expires: now.Add(ttl)}
+
+		if rec != nil && rec.Holder == holder {
+			if err = l.write(c, next, ttl, false); err == nil {
+				held = true
+			}
+			return err
+		}
+		if rec != nil {
+			if now.Before(rec.Expires) {
+				return nil
+			}
+			// The lock has expired, and is deleted so that it can be added
+			// again, which only succeeds for one of any competing holders.
+			if err = c.Delete(l.key); err != nil && !errors.Is(err, types.ErrKeyNotFound) {
+				return err
+			}
+		}
+
+		err = l.write(c, next, ttl, true)
+		if errors.Is(err, types.ErrKeyAlreadyExists) {
+			return nil
+		}
+		if err == nil {
+			held = true
+		}
+		return err
+	})
+	return held, err
+}
+
+// Release gives up the lock if it is held by the holder.
+func (l *CacheLock) Release(ctx context.Context, holder string) error {
+	return l.access(ctx, func(c types.Cache) error {
+		rec, err := l.read(c)
+		if err != nil || rec == nil || rec.Holder != holder {
+			return err
+		}
+		if err = c.Delete(l.key); errors.Is(err, types.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	})
+}
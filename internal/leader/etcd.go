@@ -0,0 +1,129 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/etcd"
+)
+
+// EtcdLock is a lock stored as a key of an etcd cluster, which is attached to a
+// lease of the holder so that it is deleted once the lease expires. Requests
+// are made to the gRPC gateway of the cluster, which serves the v3 API as JSON
+// over HTTP.
+type EtcdLock struct {
+	client *etcd.Client
+	key    string
+
+	mut     sync.Mutex
+	leaseID int64
+}
+
+// NewEtcdLock creates a lock stored under a key of an etcd cluster, reached via
+// any of a list of endpoints.
+func NewEtcdLock(endpoints []string, key string) (*EtcdLock, error) {
+	client, err := etcd.NewClient(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdLock{
+		client: client,
+		key:    key,
+	}, nil
+}
+
+// Acquire attempts to acquire the lock for a holder, or renew it when the
+// holder already holds it, for the duration of a ttl.
+func (l *EtcdLock) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.leaseID != 0 {
+		alive, err := l.client.KeepAlive(ctx, l.leaseID)
+		if err != nil {
+			return false, err
+		}
+		if !alive {
+			l.leaseID = 0
+		}
+	}
+	if l.leaseID == 0 {
+		id, err := l.client.Grant(ctx, ttl)
+		if err != nil {
+			return false, err
+		}
+		l.leaseID = id
+	}
+
+	key := etcd.Encode(l.key)
+	var res struct {
+		Succeeded bool `json:"succeeded"`
+		Responses []struct {
+			ResponseRange struct {
+				Kvs []etcd.KeyValue `json:"kvs"`
+			} `json:"response_range"`
+		} `json:"responses"`
+	}
+	// The key is only written when it doesn't exist, and otherwise it is read
+	// in order to determine whether we already hold it.
+	if err := l.client.Call(ctx, "/v3/kv/txn", map[string]interface{}{
+		"compare": []interface{}{
+			map[string]interface{}{
+				"key":             key,
+				"target":          "CREATE",
+				"result":          "EQUAL",
+				"create_revision": etcd.Int(0),
+			},
+		},
+		"success": []interface{}{
+			map[string]interface{}{
+				"request_put": map[string]interface{}{
+					"key":   key,
+					"value": etcd.Encode(holder),
+					"lease": etcd.Int(l.leaseID),
+				},
+			},
+		},
+		"failure": []interface{}{
+			map[string]interface{}{
+				"request_range": map[string]interface{}{
+					"key": key,
+				},
+			},
+		},
+	}, &res); err != nil {
+		return false, err
+	}
+	if res.Succeeded {
+		return true, nil
+	}
+	for _, r := range res.Responses {
+		for _, kv := range r.ResponseRange.Kvs {
+			value, err := etcd.Decode(kv.Value)
+			if err != nil {
+				return false, err
+			}
+			if value == holder && int64(kv.Lease) == l.leaseID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Release gives up the lock if it is held by the holder by revoking its lease,
+// which deletes the key.
+func (l *EtcdLock) Release(ctx context.Context, holder string) error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.leaseID == 0 {
+		return nil
+	}
+	if err := l.client.Revoke(ctx, l.leaseID); err != nil {
+		return err
+	}
+	l.leaseID = 0
+	return nil
+}
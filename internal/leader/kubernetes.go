@@ -0,0 +1,152 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/kubernetes"
+)
+
+// microTimeFormat is the format of the timestamps of a lease.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+var leaseResource = kubernetes.Resource{
+	APIVersion: "coordination.k8s.io/v1",
+	Kind:       "Lease",
+	Name:       "leases",
+	Namespaced: true,
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+type lease struct {
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Spec       leaseSpec              `json:"spec"`
+}
+
+// expired returns whether the holder of a lease has failed to renew it within
+// its duration.
+func (l *lease) expired(now time.Time) bool {
+	if l.Spec.HolderIdentity == "" {
+		return true
+	}
+	renewed, err := time.Parse(microTimeFormat, l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// KubernetesLock is a lock stored as a Lease object of the coordination API,
+// which is also used by Kubernetes controllers for leader election.
+type KubernetesLock struct {
+	client    *kubernetes.Client
+	namespace string
+	name      string
+}
+
+// NewKubernetesLock creates a lock stored as a named Lease within a namespace.
+func NewKubernetesLock(client *kubernetes.Client, namespace, name string) *KubernetesLock {
+	return &KubernetesLock{client: client, namespace: namespace, name: name}
+}
+
+func (l *KubernetesLock) get(ctx context.Context) (*lease, error) {
+	res, err := l.client.Do(ctx, http.MethodGet, leaseResource.ObjectPath(l.namespace, l.name), nil, nil, "")
+	if err != nil {
+		if kubernetes.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var obj lease
+	if err := json.NewDecoder(res.Body).Decode(&obj); err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// write creates the lease when it doesn't exist, or otherwise replaces it.
+// Returns false if the lease was modified by another holder in the meantime.
+func (l *KubernetesLock) write(ctx context.Context, obj *lease, create bool) (bool, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return false, err
+	}
+
+	method, path := http.MethodPut, leaseResource.ObjectPath(l.namespace, l.name)
+	if create {
+		method, path = http.MethodPost, leaseResource.Path(l.namespace)
+	}
+	res, err := l.client.Do(ctx, method, path, nil, body, "application/json")
+	if err != nil {
+		if kubernetes.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	res.Body.Close()
+	return true, nil
+}
+
+// Acquire attempts to acquire the lock for a holder, or renew it when the
+// holder already holds it, for the duration of a ttl.
+func (l *KubernetesLock) Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	obj, err := l.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	nowStr := now.UTC().Format(microTimeFormat)
+
+	create := obj == nil
+	if create {
+		obj = &lease{
+			Metadata: map[string]interface{}{
+				"name":      l.name,
+				"namespace": l.namespace,
+			},
+		}
+	} else if obj.Spec.HolderIdentity != holder && !obj.expired(now) {
+		return false, nil
+	}
+
+	obj.APIVersion = leaseResource.APIVersion
+	obj.Kind = leaseResource.Kind
+	if obj.Spec.HolderIdentity != holder {
+		if !create {
+			obj.Spec.LeaseTransitions++
+		}
+		obj.Spec.HolderIdentity = holder
+		obj.Spec.AcquireTime = nowStr
+	}
+	obj.Spec.RenewTime = nowStr
+	obj.Spec.LeaseDurationSeconds = int(math.Ceil(ttl.Seconds()))
+	return l.write(ctx, obj, create)
+}
+
+// Release gives up the lock if it is held by the holder.
+func (l *KubernetesLock) Release(ctx context.Context, holder string) error {
+	obj, err := l.get(ctx)
+	if err != nil || obj == nil || obj.Spec.HolderIdentity != holder {
+		return err
+	}
+	obj.Spec.HolderIdentity = ""
+	obj.Spec.RenewTime = time.Now().UTC().Format(microTimeFormat)
+	obj.Spec.LeaseDurationSeconds = 1
+	_, err = l.write(ctx, obj, false)
+	return err
+}
@@ -0,0 +1,305 @@
+package leader
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/kubernetes"
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMgr struct {
+	caches map[string]types.Cache
+}
+
+func (f *fakeMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {}
+func (f *fakeMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (f *fakeMgr) GetPlugin(name string) (interface{}, error) {
+	return nil, types.ErrPluginNotFound
+}
+func (f *fakeMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func TestCacheLock(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeMgr{caches: map[string]types.Cache{"foo": memCache}}
+
+	_, err = NewCacheLock(mgr, "bar", "lock")
+	require.EqualError(t, err, "cache resource 'bar' was not found")
+
+	l, err := NewCacheLock(mgr, "foo", "lock")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	held, err := l.Acquire(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	held, err = l.Acquire(ctx, "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	held, err = l.Acquire(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	// Releasing a lock that isn't held has no effect.
+	require.NoError(t, l.Release(ctx, "b"))
+	held, err = l.Acquire(ctx, "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	require.NoError(t, l.Release(ctx, "a"))
+	held, err = l.Acquire(ctx, "b", time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	// An expired lock can be taken over.
+	<-time.After(time.Millisecond * 5)
+	held, err = l.Acquire(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	held, err = l.Acquire(ctx, "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+}
+
+func TestKubernetesLock(t *testing.T) {
+	var mut sync.Mutex
+	var stored *lease
+	version := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/coordination.k8s.io/v1/namespaces/shop/leases", func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		if stored != nil {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind":"Status","code":409,"reason":"AlreadyExists","message":"leases \"foo\" already exists"}`))
+			return
+		}
+		var obj lease
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&obj))
+		version++
+		obj.Metadata["resourceVersion"] = strconv.Itoa(version)
+		stored = &obj
+		json.NewEncoder(w).Encode(stored)
+	})
+	mux.HandleFunc("/apis/coordination.k8s.io/v1/namespaces/shop/leases/foo", func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"kind":"Status","code":404,"reason":"NotFound","message":"leases \"foo\" not found"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(stored)
+		case http.MethodPut:
+			var obj lease
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&obj))
+			if obj.Metadata["resourceVersion"] != stored.Metadata["resourceVersion"] {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"kind":"Status","code":409,"reason":"Conflict","message":"the object has been modified"}`))
+				return
+			}
+			version++
+			obj.Metadata["resourceVersion"] = strconv.Itoa(version)
+			stored = &obj
+			json.NewEncoder(w).Encode(stored)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	l := NewKubernetesLock(kubernetes.NewClient(&kubernetes.Config{Server: srv.URL}), "shop", "foo")
+	ctx := context.Background()
+
+	held, err := l.Acquire(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, "a", stored.Spec.HolderIdentity)
+	assert.Equal(t, 10, stored.Spec.LeaseDurationSeconds)
+	assert.Equal(t, 0, stored.Spec.LeaseTransitions)
+
+	held, err = l.Acquire(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	held, err = l.Acquire(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, "2", stored.Metadata["resourceVersion"])
+
+	require.NoError(t, l.Release(ctx, "a"))
+	assert.Equal(t, "", stored.Spec.HolderIdentity)
+
+	held, err = l.Acquire(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, "b", stored.Spec.HolderIdentity)
+	assert.Equal(t, 1, stored.Spec.LeaseTransitions)
+
+	// A lease that hasn't been renewed within its duration can be taken over.
+	stored.Spec.RenewTime = time.Now().Add(-time.Minute).UTC().Format(microTimeFormat)
+	held, err = l.Acquire(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, "a", stored.Spec.HolderIdentity)
+	assert.Equal(t, 2, stored.Spec.LeaseTransitions)
+}
+
+type fakeEtcd struct {
+	mut      sync.Mutex
+	nextID   int64
+	leases   map[int64]bool
+	key      string
+	value    string
+	keyLease int64
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parseID := func(v interface{}) int64 {
+		id, _ := strconv.ParseInt(v.(string), 10, 64)
+		return id
+	}
+
+	var res interface{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextID++
+		f.leases[f.nextID] = true
+		res = map[string]interface{}{"ID": strconv.FormatInt(f.nextID, 10), "TTL": req["TTL"]}
+	case "/v3/lease/keepalive":
+		id := parseID(req["ID"])
+		ttl := "0"
+		if f.leases[id] {
+			ttl = "10"
+		}
+		res = map[string]interface{}{"result": map[string]interface{}{"ID": req["ID"], "TTL": ttl}}
+	case "/v3/lease/revoke":
+		id := parseID(req["ID"])
+		delete(f.leases, id)
+		if f.keyLease == id {
+			f.key, f.value, f.keyLease = "", "", 0
+		}
+		res = map[string]interface{}{}
+	case "/v3/kv/txn":
+		if f.key == "" {
+			put := req["success"].([]interface{})[0].(map[string]interface{})["request_put"].(map[string]interface{})
+			f.key, f.value, f.keyLease = put["key"].(string), put["value"].(string), parseID(put["lease"])
+			res = map[string]interface{}{"succeeded": true}
+		} else {
+			res = map[string]interface{}{
+				"responses": []interface{}{
+					map[string]interface{}{
+						"response_range": map[string]interface{}{
+							"kvs": []interface{}{
+								map[string]interface{}{
+									"key":   f.key,
+									"value": f.value,
+									"lease": strconv.FormatInt(f.keyLease, 10),
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// expire simulates the expiry of the lease attached to the key.
+func (f *fakeEtcd) expire() {
+	f.mut.Lock()
+	delete(f.leases, f.keyLease)
+	f.key, f.value, f.keyLease = "", "", 0
+	f.mut.Unlock()
+}
+
+func TestEtcdLock(t *testing.T) {
+	fake := &fakeEtcd{leases: map[int64]bool{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	_, err := NewEtcdLock(nil, "foo")
+	require.EqualError(t, err, "at least one etcd endpoint must be specified")
+
+	// The first endpoint is unreachable and so the second is used.
+	lA, err := NewEtcdLock([]string{"http://127.0.0.1:1", srv.URL + "/"}, "foo")
+	require.NoError(t, err)
+	lB, err := NewEtcdLock([]string{srv.URL}, "foo")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	held, err := lA.Acquire(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("foo")), fake.key)
+
+	held, err = lB.Acquire(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	held, err = lA.Acquire(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	require.NoError(t, lA.Release(ctx, "a"))
+	held, err = lB.Acquire(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	fake.expire()
+	held, err = lA.Acquire(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	held, err = lB.Acquire(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.False(t, held)
+}
@@ -0,0 +1,19 @@
+package leader
+
+import (
+	"context"
+	"time"
+)
+
+// Lock is a lock that is held by a single holder until it either releases it
+// or fails to renew it before its ttl has passed.
+type Lock interface {
+	// Acquire attempts to acquire the lock for a holder, or renew it when the
+	// holder already holds it, for the duration of a ttl. Returns whether the
+	// holder holds the lock.
+	Acquire(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lock if it is held by the holder, allowing another
+	// holder to acquire it without waiting for it to expire.
+	Release(ctx context.Context, holder string) error
+}
@@ -0,0 +1,4 @@
+// Package leader implements locks with an expiry that allow one of many
+// instances to be elected as a leader, backed by either a cache resource, a
+// Kubernetes lease or an etcd cluster.
+package leader
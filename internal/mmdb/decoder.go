@@ -0,0 +1,204 @@
+package mmdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Data section types.
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEnd       = 13
+	typeBool      = 14
+	typeFloat     = 15
+)
+
+// maxDepth limits the nesting of decoded values, which also protects against
+// pointer cycles within corrupt databases.
+const maxDepth = 64
+
+var errInvalidData = errors.New("invalid database: data section is corrupt")
+
+// decoder decodes values of a data section.
+type decoder struct {
+	b []byte
+}
+
+// decode decodes the value at an offset, returning the value and the offset
+// that follows it.
+func (d decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errInvalidData
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == typePointer {
+		v, _, err := d.decode(size, depth+1)
+		return v, offset, err
+	}
+	return d.decodeValue(typ, size, offset, depth)
+}
+
+// control decodes a control byte, returning the type and size of the value
+// and the offset of its payload. For pointers the size is the offset being
+// pointed to.
+func (d decoder) control(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.b)) {
+		return 0, 0, 0, errInvalidData
+	}
+	ctrl := d.b[offset]
+	offset++
+
+	typ := int(ctrl >> 5)
+	if typ == typePointer {
+		ss := uint((ctrl >> 3) & 0x3)
+		if offset+ss+1 > uint(len(d.b)) {
+			return 0, 0, 0, errInvalidData
+		}
+		p := d.b[offset : offset+ss+1]
+		var ptr uint
+		switch ss {
+		case 0:
+			ptr = uint(ctrl&0x7)<<8 | uint(p[0])
+		case 1:
+			ptr = (uint(ctrl&0x7)<<16 | uint(p[0])<<8 | uint(p[1])) + 2048
+		case 2:
+			ptr = (uint(ctrl&0x7)<<24 | uint(p[0])<<16 | uint(p[1])<<8 | uint(p[2])) + 526336
+		case 3:
+			ptr = uint(binary.BigEndian.Uint32(p))
+		}
+		return typePointer, ptr, offset + ss + 1, nil
+	}
+	if typ == typeExtended {
+		if offset >= uint(len(d.b)) {
+			return 0, 0, 0, errInvalidData
+		}
+		typ = 7 + int(d.b[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.b)) {
+			return 0, 0, 0, errInvalidData
+		}
+		var v uint
+		for _, c := range d.b[offset : offset+n] {
+			v = v<<8 | uint(c)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + v
+		case 2:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+	return typ, size, offset, nil
+}
+
+func (d decoder) decodeValue(typ int, size, offset uint, depth int) (interface{}, uint, error) {
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errInvalidData
+			}
+			if m[key], offset, err = d.decode(next, depth+1); err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+	case typeArray:
+		s := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			s = append(s, v)
+			offset = next
+		}
+		return s, offset, nil
+	case typeBool:
+		if size > 1 {
+			return nil, 0, errInvalidData
+		}
+		return size == 1, offset, nil
+	case typeContainer, typeEnd:
+		return nil, 0, fmt.Errorf("invalid database: unexpected data type %v", typ)
+	}
+
+	if offset+size > uint(len(d.b)) {
+		return nil, 0, errInvalidData
+	}
+	b := d.b[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errInvalidData
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errInvalidData
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errInvalidData
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errInvalidData
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), next, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, errInvalidData
+		}
+		return new(big.Int).SetBytes(b), next, nil
+	}
+	return nil, 0, fmt.Errorf("invalid database: unknown data type %v", typ)
+}
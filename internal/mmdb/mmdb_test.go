@@ -0,0 +1,135 @@
+package mmdb
+
+import (
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	_, network, err := net.ParseCIDR(s)
+	require.NoError(t, err)
+	return network
+}
+
+func TestWriterReader(t *testing.T) {
+	w := NewWriter("Test-City", "en", "de")
+	require.NoError(t, w.Insert(mustCIDR(t, "81.2.69.0/24"), map[string]interface{}{
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": "London", "de": "London"},
+		},
+		"location": map[string]interface{}{
+			"latitude":        51.5142,
+			"longitude":       -0.0931,
+			"accuracy_radius": uint16(10),
+		},
+		"subdivisions": []interface{}{
+			map[string]interface{}{"iso_code": "ENG"},
+		},
+		"is_anycast": true,
+		"offset":     -5,
+		"float":      float32(1.5),
+		"raw":        []byte{1, 2},
+		"big":        uint64(1) << 40,
+		"long":       strings.Repeat("a", 300),
+	}))
+	require.NoError(t, w.Insert(mustCIDR(t, "2001:db8::/32"), map[string]interface{}{
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": "Nowhere"},
+		},
+	}))
+	assert.EqualError(t, w.Insert(mustCIDR(t, "81.2.69.128/25"), nil), "network 81.2.69.128/25 overlaps with an existing network")
+
+	b, err := w.Bytes()
+	require.NoError(t, err)
+
+	r, err := FromBytes(b)
+	require.NoError(t, err)
+	assert.Equal(t, "Test-City", r.Metadata().DatabaseType)
+	assert.Equal(t, []string{"en", "de"}, r.Metadata().Languages)
+	assert.Equal(t, uint(6), r.Metadata().IPVersion)
+
+	v, prefix, err := r.Lookup(net.ParseIP("81.2.69.142"))
+	require.NoError(t, err)
+	assert.Equal(t, 120, prefix)
+	assert.Equal(t, map[string]interface{}{
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": "London", "de": "London"},
+		},
+		"location": map[string]interface{}{
+			"latitude":        51.5142,
+			"longitude":       -0.0931,
+			"accuracy_radius": uint64(10),
+		},
+		"subdivisions": []interface{}{
+			map[string]interface{}{"iso_code": "ENG"},
+		},
+		"is_anycast": true,
+		"offset":     int64(-5),
+		"float":      float64(1.5),
+		"raw":        []byte{1, 2},
+		"big":        uint64(1) << 40,
+		"long":       strings.Repeat("a", 300),
+	}, v)
+
+	v, prefix, err = r.Lookup(net.ParseIP("2001:db8::1"))
+	require.NoError(t, err)
+	assert.Equal(t, 32, prefix)
+	assert.Equal(t, "Nowhere", v.(map[string]interface{})["city"].(map[string]interface{})["names"].(map[string]interface{})["en"])
+
+	v, _, err = r.Lookup(net.ParseIP("81.2.70.1"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, _, err = r.Lookup(net.ParseIP("2001:db9::1"))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestDecodePointers(t *testing.T) {
+	d := decoder{b: []byte{
+		// offset 0: map of one pair, where the value is a pointer to offset 8
+		0xe1, 0x43, 'f', 'o', 'o', 0x20, 0x08, 0x00,
+		// offset 8: "bar"
+		0x43, 'b', 'a', 'r',
+		// offset 12: uint128
+		0x03, 0x03, 0x01, 0x00, 0x00,
+		// offset 17: pointer to itself
+		0x20, 0x11,
+	}}
+
+	v, next, err := d.decode(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, v)
+	assert.Equal(t, uint(7), next)
+
+	v, _, err = d.decode(12, 0)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(65536), v)
+
+	_, _, err = d.decode(17, 0)
+	assert.Error(t, err)
+
+	_, _, err = d.decode(30, 0)
+	assert.Error(t, err)
+}
+
+func TestRecordSizes(t *testing.T) {
+	r := &Reader{meta: Metadata{RecordSize: 28}, tree: []byte{0x12, 0x34, 0x56, 0xab, 0x78, 0x9a, 0xbc}}
+	assert.Equal(t, uint(0xa123456), r.record(0, 0))
+	assert.Equal(t, uint(0xb789abc), r.record(0, 1))
+
+	r = &Reader{meta: Metadata{RecordSize: 32}, tree: []byte{0, 0, 0, 1, 0xff, 0, 0, 2}}
+	assert.Equal(t, uint(1), r.record(0, 0))
+	assert.Equal(t, uint(0xff000002), r.record(0, 1))
+}
+
+func TestInvalidDatabase(t *testing.T) {
+	_, err := FromBytes([]byte("not a database"))
+	assert.EqualError(t, err, "invalid database: metadata section not found")
+}
@@ -0,0 +1,4 @@
+// Package mmdb implements a reader of the MaxMind DB file format, which is
+// used by the GeoIP2 and GeoLite2 databases, and a writer of small databases
+// for tests.
+package mmdb
@@ -0,0 +1,189 @@
+package mmdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// metadataStart marks the beginning of the metadata section, which is the last
+// occurrence within a database.
+var metadataStart = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the size of the null bytes between the search tree
+// and the data section.
+const dataSectionSeparator = 16
+
+// Metadata describes a database.
+type Metadata struct {
+	DatabaseType string
+	Description  map[string]string
+	Languages    []string
+	IPVersion    uint
+	NodeCount    uint
+	RecordSize   uint
+	BuildTime    time.Time
+}
+
+// Reader looks up the records of IP addresses within a database. A Reader is
+// safe for concurrent use.
+type Reader struct {
+	meta      Metadata
+	tree      []byte
+	data      decoder
+	ipv4Start uint
+}
+
+// Open reads a database from a file.
+func Open(path string) (*Reader, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(b)
+}
+
+// FromBytes reads a database from its contents.
+func FromBytes(b []byte) (*Reader, error) {
+	i := bytes.LastIndex(b, metadataStart)
+	if i == -1 {
+		return nil, errors.New("invalid database: metadata section not found")
+	}
+	metaDec := decoder{b: b[i+len(metadataStart):]}
+	v, _, err := metaDec.decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid database: metadata is not a map")
+	}
+
+	r := &Reader{}
+	if r.meta, err = parseMetadata(m); err != nil {
+		return nil, err
+	}
+
+	treeSize := r.meta.NodeCount * r.meta.RecordSize / 4
+	if treeSize+dataSectionSeparator > uint(i) {
+		return nil, errors.New("invalid database: search tree exceeds database size")
+	}
+	r.tree = b[:treeSize]
+	r.data = decoder{b: b[treeSize+dataSectionSeparator : i]}
+
+	if r.meta.IPVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < r.meta.NodeCount; j++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+func parseMetadata(m map[string]interface{}) (Metadata, error) {
+	var meta Metadata
+	if v, _ := m["binary_format_major_version"].(uint64); v != 2 {
+		return meta, fmt.Errorf("unsupported database format version: %v", m["binary_format_major_version"])
+	}
+
+	meta.DatabaseType, _ = m["database_type"].(string)
+	meta.Description = map[string]string{}
+	if desc, ok := m["description"].(map[string]interface{}); ok {
+		for k, v := range desc {
+			meta.Description[k], _ = v.(string)
+		}
+	}
+	if langs, ok := m["languages"].([]interface{}); ok {
+		for _, l := range langs {
+			if s, ok := l.(string); ok {
+				meta.Languages = append(meta.Languages, s)
+			}
+		}
+	}
+	epoch, _ := m["build_epoch"].(uint64)
+	meta.BuildTime = time.Unix(int64(epoch), 0).UTC()
+
+	ipVersion, _ := m["ip_version"].(uint64)
+	nodeCount, _ := m["node_count"].(uint64)
+	recordSize, _ := m["record_size"].(uint64)
+	meta.IPVersion, meta.NodeCount, meta.RecordSize = uint(ipVersion), uint(nodeCount), uint(recordSize)
+
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return meta, fmt.Errorf("invalid database: unsupported IP version: %v", meta.IPVersion)
+	}
+	if meta.RecordSize != 24 && meta.RecordSize != 28 && meta.RecordSize != 32 {
+		return meta, fmt.Errorf("invalid database: unsupported record size: %v", meta.RecordSize)
+	}
+	return meta, nil
+}
+
+// Metadata returns the metadata of the database.
+func (r *Reader) Metadata() Metadata {
+	return r.meta
+}
+
+// record returns the left (0) or right (1) record of a node.
+func (r *Reader) record(node uint, bit byte) uint {
+	switch r.meta.RecordSize {
+	case 24:
+		b := r.tree[node*6+uint(bit)*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b := r.tree[node*8+uint(bit)*4:]
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
+	}
+}
+
+// Lookup returns the record of an IP address and the prefix length of the
+// network it belongs to, or a nil record when the address is not within the
+// database.
+//
+// Records are decoded into maps of type map[string]interface{}, arrays of type
+// []interface{}, and the types string, []byte, float64, uint64, int64, bool
+// and *big.Int.
+func (r *Reader) Lookup(ip net.IP) (interface{}, int, error) {
+	node, bits := uint(0), 128
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 32
+		if r.meta.IPVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if ip = ip.To16(); ip == nil {
+		return nil, 0, errors.New("invalid IP address")
+	} else if r.meta.IPVersion == 4 {
+		return nil, 0, fmt.Errorf("IPv6 address %v cannot be looked up in an IPv4 database", ip)
+	}
+
+	depth := 0
+	for ; depth < bits && node < r.meta.NodeCount; depth++ {
+		bit := (ip[depth/8] >> (7 - uint(depth%8))) & 1
+		node = r.record(node, bit)
+	}
+	if bits == 32 && r.meta.IPVersion == 6 {
+		depth += 96
+	}
+
+	switch {
+	case node == r.meta.NodeCount:
+		return nil, depth, nil
+	case node < r.meta.NodeCount:
+		return nil, 0, errors.New("invalid database: search tree is corrupt")
+	}
+
+	offset := node - r.meta.NodeCount - dataSectionSeparator
+	v, _, err := r.data.decode(offset, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, depth, nil
+}
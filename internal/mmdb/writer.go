@@ -0,0 +1,245 @@
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+// Writer builds IPv6 databases with 24 bit records, where IPv4 networks are
+// stored within ::/96. It does not deduplicate data and is intended for
+// building small databases for tests.
+type Writer struct {
+	databaseType string
+	languages    []string
+	root         *writerNode
+	records      []interface{}
+}
+
+type writerNode struct {
+	children [2]*writerNode
+	record   [2]int // 1 based index of the record of a leaf, 0 if none
+}
+
+// NewWriter creates a writer of a database type, such as GeoIP2-City.
+func NewWriter(databaseType string, languages ...string) *Writer {
+	return &Writer{
+		databaseType: databaseType,
+		languages:    languages,
+		root:         &writerNode{},
+	}
+}
+
+// Insert adds the record of a network, which must not overlap with networks
+// already inserted. Records are maps of type map[string]interface{}, arrays
+// of type []interface{} and values of the types string, []byte, float64,
+// float32, bool, int, int32, uint16, uint32 and uint64.
+func (w *Writer) Insert(network *net.IPNet, record interface{}) error {
+	ip, mask := network.IP.To16(), network.Mask
+	prefix, _ := mask.Size()
+	if v4 := network.IP.To4(); v4 != nil {
+		ip = append(make(net.IP, 12), v4...)
+		prefix += 96
+	}
+	if prefix == 0 {
+		return fmt.Errorf("network %v must have a prefix", network)
+	}
+
+	n := w.root
+	for depth := 0; depth < prefix; depth++ {
+		bit := (ip[depth/8] >> (7 - uint(depth%8))) & 1
+		if n.record[bit] != 0 || (depth == prefix-1 && n.children[bit] != nil) {
+			return fmt.Errorf("network %v overlaps with an existing network", network)
+		}
+		if n = n.children[bit]; n == nil {
+			break
+		}
+	}
+
+	w.records = append(w.records, record)
+	n = w.root
+	for depth := 0; depth < prefix; depth++ {
+		bit := (ip[depth/8] >> (7 - uint(depth%8))) & 1
+		if depth == prefix-1 {
+			n.record[bit] = len(w.records)
+			break
+		}
+		if n.children[bit] == nil {
+			n.children[bit] = &writerNode{}
+		}
+		n = n.children[bit]
+	}
+	return nil
+}
+
+// Bytes encodes the database.
+func (w *Writer) Bytes() ([]byte, error) {
+	var nodes []*writerNode
+	ids := map[*writerNode]int{}
+	queue := []*writerNode{w.root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		ids[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	var data bytes.Buffer
+	offsets := make([]int, len(w.records))
+	for i, r := range w.records {
+		offsets[i] = data.Len()
+		if err := encodeValue(&data, r); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	nodeCount := len(nodes)
+	for _, n := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			v := nodeCount
+			if c := n.children[bit]; c != nil {
+				v = ids[c]
+			} else if r := n.record[bit]; r != 0 {
+				v = nodeCount + dataSectionSeparator + offsets[r-1]
+			}
+			if v >= 1<<24 {
+				return nil, fmt.Errorf("database too large for 24 bit records")
+			}
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, dataSectionSeparator))
+	out.Write(data.Bytes())
+	out.Write(metadataStart)
+
+	langs := make([]interface{}, len(w.languages))
+	for i, l := range w.languages {
+		langs[i] = l
+	}
+	meta := map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               w.databaseType,
+		"description":                 map[string]interface{}{},
+		"ip_version":                  uint16(6),
+		"languages":                   langs,
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	}
+	if err := encodeValue(&out, meta); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeControl(buf *bytes.Buffer, typ int, size int) {
+	var ctrl byte
+	var ext []byte
+	if typ > 7 {
+		ext = []byte{byte(typ - 7)}
+	} else {
+		ctrl = byte(typ << 5)
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		ctrl |= 30
+		s := size - 285
+		sizeBytes = []byte{byte(s >> 8), byte(s)}
+	default:
+		ctrl |= 31
+		s := size - 65821
+		sizeBytes = []byte{byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+	buf.WriteByte(ctrl)
+	buf.Write(ext)
+	buf.Write(sizeBytes)
+}
+
+func writeUint(buf *bytes.Buffer, typ int, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	trimmed := bytes.TrimLeft(b[:], "\x00")
+	writeControl(buf, typ, len(trimmed))
+	buf.Write(trimmed)
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case string:
+		writeControl(buf, typeString, len(t))
+		buf.WriteString(t)
+	case []byte:
+		writeControl(buf, typeBytes, len(t))
+		buf.Write(t)
+	case float64:
+		writeControl(buf, typeDouble, 8)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(t))
+	case float32:
+		writeControl(buf, typeFloat, 4)
+		_ = binary.Write(buf, binary.BigEndian, math.Float32bits(t))
+	case bool:
+		size := 0
+		if t {
+			size = 1
+		}
+		writeControl(buf, typeBool, size)
+	case int:
+		if t < math.MinInt32 || t > math.MaxInt32 {
+			return fmt.Errorf("int value %v exceeds 32 bits", t)
+		}
+		writeControl(buf, typeInt32, 4)
+		_ = binary.Write(buf, binary.BigEndian, int32(t))
+	case int32:
+		writeControl(buf, typeInt32, 4)
+		_ = binary.Write(buf, binary.BigEndian, t)
+	case uint16:
+		writeUint(buf, typeUint16, uint64(t))
+	case uint32:
+		writeUint(buf, typeUint32, uint64(t))
+	case uint64:
+		writeUint(buf, typeUint64, t)
+	case []interface{}:
+		writeControl(buf, typeArray, len(t))
+		for _, e := range t {
+			if err := encodeValue(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeControl(buf, typeMap, len(keys))
+		for _, k := range keys {
+			if err := encodeValue(buf, k); err != nil {
+				return err
+			}
+			if err := encodeValue(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value type: %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package nlp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Undetermined is the ISO 639-2 code returned when the language of text
+// cannot be detected.
+const Undetermined = "und"
+
+// scriptLanguages maps scripts that are predominantly used by a single
+// language to that language.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// letterHints are letters that are distinctive of a language written in the
+// Latin script, which add weight to that language when present.
+var letterHints = map[rune]string{
+	'ß': "de", 'ä': "de", 'ü': "de",
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ã': "pt", 'õ': "pt",
+	'å': "sv",
+	'è': "it", 'ì': "it", 'ò': "it",
+	'ê': "fr", 'â': "fr", 'î': "fr", 'û': "fr", 'œ': "fr",
+	'ĳ': "nl",
+}
+
+// DetectLanguage attempts to detect the language of text and returns it as an
+// ISO 639-1 code, or "und" when it cannot be determined.
+//
+// Languages with a distinctive script, such as Chinese, Japanese, Korean,
+// Russian, Arabic, Greek, Hebrew, Hindi and Thai, are detected from the script
+// of the text. Languages written in the Latin script are detected from the
+// frequency of their stopwords and distinctive letters, and are limited to
+// those with stopword lists. Detection is more reliable for longer texts.
+func DetectLanguage(text string) string {
+	scriptCounts := map[string]int{}
+	var letters, latin, han, kana int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					scriptCounts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return Undetermined
+	}
+
+	// Japanese mixes kana with Han characters, whereas Chinese is written
+	// purely with Han characters.
+	if kana > 0 && (kana+han)*2 > letters {
+		return "ja"
+	}
+	if han*2 > letters {
+		return "zh"
+	}
+	for _, s := range scriptLanguages {
+		if scriptCounts[s.lang]*2 > letters {
+			return s.lang
+		}
+	}
+	if latin*2 <= letters {
+		return Undetermined
+	}
+	return detectLatin(text)
+}
+
+func detectLatin(text string) string {
+	scores := map[string]float64{}
+	for _, token := range Tokenize(strings.ToLower(text)) {
+		for lang, words := range stopwords {
+			if _, exists := words[token]; exists {
+				scores[lang]++
+			}
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if lang, exists := letterHints[r]; exists {
+			scores[lang] += 0.5
+		}
+	}
+
+	best, bestScore, tied := Undetermined, 0.0, false
+	for _, lang := range StopwordLanguages() {
+		switch score := scores[lang]; {
+		case score > bestScore:
+			best, bestScore, tied = lang, score, false
+		case score == bestScore && score > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return Undetermined
+	}
+	return best
+}
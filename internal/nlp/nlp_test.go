@@ -0,0 +1,90 @@
+package nlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := map[string][]string{
+		"":                                 {},
+		"Hello, world!":                    {"Hello", "world"},
+		"don't stop-believing 42 times":    {"don't", "stop", "believing", "42", "times"},
+		"  'quoted' words' ":               {"quoted", "words"},
+		"Ça va très bien, naïve café":      {"Ça", "va", "très", "bien", "naïve", "café"},
+		"東京タワー is tall":                    {"東", "京", "タ", "ワ", "ー", "is", "tall"},
+		"Привет, мир! Как дела?":           {"Привет", "мир", "Как", "дела"},
+		"e-mail: foo@example.com (v1.2.3)": {"e", "mail", "foo", "example", "com", "v1", "2", "3"},
+	}
+	for input, exp := range tests {
+		assert.Equal(t, exp, Tokenize(input), input)
+	}
+}
+
+func TestStopwords(t *testing.T) {
+	assert.True(t, IsStopword("en", "The"))
+	assert.False(t, IsStopword("en", "elephant"))
+	assert.True(t, IsStopword("de", "und"))
+	assert.False(t, IsStopword("xx", "the"))
+	assert.True(t, HasStopwords("fr"))
+	assert.False(t, HasStopwords("xx"))
+	assert.Equal(t, []string{"de", "en", "es", "fr", "it", "nl", "pt", "sv"}, StopwordLanguages())
+}
+
+func TestStemEnglish(t *testing.T) {
+	tests := map[string]string{
+		"caresses": "caress", "ponies": "poni", "ties": "ti", "caress": "caress",
+		"cats": "cat", "feed": "feed", "agreed": "agre", "plastered": "plaster",
+		"bled": "bled", "motoring": "motor", "sing": "sing", "conflated": "conflat",
+		"troubled": "troubl", "sized": "size", "hopping": "hop", "tanned": "tan",
+		"falling": "fall", "hissing": "hiss", "fizzed": "fizz", "failing": "fail",
+		"filing": "file", "happy": "happi", "sky": "sky", "relational": "relat",
+		"conditional": "condit", "rational": "ration", "valenci": "valenc",
+		"digitizer": "digit", "conformabli": "conform", "radicalli": "radic",
+		"differentli": "differ", "vileli": "vile", "analogousli": "analog",
+		"vietnamization": "vietnam", "predication": "predic", "operator": "oper",
+		"feudalism": "feudal", "decisiveness": "decis", "hopefulness": "hope",
+		"callousness": "callous", "formaliti": "formal", "sensitiviti": "sensit",
+		"sensibiliti": "sensibl", "triplicate": "triplic", "formative": "form",
+		"formalize": "formal", "electriciti": "electr", "electrical": "electr",
+		"hopeful": "hope", "goodness": "good", "revival": "reviv",
+		"allowance": "allow", "inference": "infer", "airliner": "airlin",
+		"gyroscopic": "gyroscop", "adjustable": "adjust", "defensible": "defens",
+		"irritant": "irrit", "replacement": "replac", "adjustment": "adjust",
+		"dependent": "depend", "adoption": "adopt", "homologou": "homolog",
+		"communism": "commun", "activate": "activ", "angulariti": "angular",
+		"homologous": "homolog", "effective": "effect", "bowdlerize": "bowdler",
+		"probate": "probat", "rate": "rate", "cease": "ceas", "controll": "control",
+		"roll": "roll", "Connections": "connect", "generalizations": "gener",
+		"is": "is", "naïve": "naïve",
+	}
+	for input, exp := range tests {
+		assert.Equal(t, exp, StemEnglish(input), input)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"The quick brown fox jumps over the lazy dog and runs into the forest":        "en",
+		"Le renard brun rapide saute par-dessus le chien paresseux dans la forêt":     "fr",
+		"Der schnelle braune Fuchs springt über den faulen Hund und in den Wald":      "de",
+		"El rápido zorro marrón salta sobre el perro perezoso y corre por el bosque":  "es",
+		"La volpe veloce salta sopra il cane pigro e corre nella foresta":             "it",
+		"A rápida raposa marrom pula sobre o cão preguiçoso e corre para a floresta":  "pt",
+		"De snelle bruine vos springt over de luie hond en rent naar het bos":         "nl",
+		"Den snabba bruna räven hoppar över den lata hunden och springer till skogen": "sv",
+		"敏捷的棕色狐狸跳过了懒狗":                                                                "zh",
+		"素早い茶色のキツネが怠け者の犬を飛び越える":                                                       "ja",
+		"빠른 갈색 여우가 게으른 개를 뛰어넘는다":                                                      "ko",
+		"Быстрая коричневая лиса прыгает через ленивую собаку":                        "ru",
+		"الثعلب البني السريع يقفز فوق الكلب الكسول":                                   "ar",
+		"Η γρήγορη καφέ αλεπού πηδάει πάνω από τον τεμπέλη σκύλο":                     "el",
+		"":          "und",
+		"12345 !!!": "und",
+		"xyzzy":     "und",
+	}
+	for input, exp := range tests {
+		assert.Equal(t, exp, DetectLanguage(input), input)
+	}
+}
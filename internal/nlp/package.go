@@ -0,0 +1,3 @@
+// Package nlp implements lightweight text processing utilities, including
+// tokenization, stopword removal, stemming and language detection.
+package nlp
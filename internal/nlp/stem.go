@@ -0,0 +1,228 @@
+package nlp
+
+import (
+	"strings"
+)
+
+// StemEnglish reduces an English word to its stem with the Porter stemming
+// algorithm, e.g. "connections" and "connected" both become "connect". Words
+// are lowercased, and words that contain characters other than ASCII letters
+// are returned lowercased but otherwise unchanged.
+func StemEnglish(word string) string {
+	w := strings.ToLower(word)
+	if len(w) <= 2 {
+		return w
+	}
+	for i := 0; i < len(w); i++ {
+		if w[i] < 'a' || w[i] > 'z' {
+			return w
+		}
+	}
+
+	s := porterStemmer{b: []byte(w)}
+	s.step1a()
+	s.step1b()
+	s.step1c()
+	s.step2()
+	s.step3()
+	s.step4()
+	s.step5()
+	return string(s.b)
+}
+
+type porterStemmer struct {
+	b []byte
+}
+
+// isConsonant returns whether the letter at index i is a consonant, where y is
+// a consonant unless it follows a consonant.
+func (s *porterStemmer) isConsonant(b []byte, i int) bool {
+	switch b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.isConsonant(b, i-1)
+	}
+	return true
+}
+
+// measure returns the number of vowel-consonant sequences within a stem.
+func (s *porterStemmer) measure(stem []byte) int {
+	m, i := 0, 0
+	for i < len(stem) && s.isConsonant(stem, i) {
+		i++
+	}
+	for i < len(stem) {
+		for i < len(stem) && !s.isConsonant(stem, i) {
+			i++
+		}
+		if i >= len(stem) {
+			break
+		}
+		for i < len(stem) && s.isConsonant(stem, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+func (s *porterStemmer) hasVowel(stem []byte) bool {
+	for i := range stem {
+		if !s.isConsonant(stem, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsDoubleConsonant returns whether a stem ends with a double consonant.
+func (s *porterStemmer) endsDoubleConsonant(stem []byte) bool {
+	n := len(stem)
+	return n >= 2 && stem[n-1] == stem[n-2] && s.isConsonant(stem, n-1)
+}
+
+// endsCVC returns whether a stem ends consonant-vowel-consonant, where the
+// final consonant is not w, x or y.
+func (s *porterStemmer) endsCVC(stem []byte) bool {
+	n := len(stem)
+	if n < 3 || !s.isConsonant(stem, n-1) || s.isConsonant(stem, n-2) || !s.isConsonant(stem, n-3) {
+		return false
+	}
+	switch stem[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func (s *porterStemmer) hasSuffix(suffix string) bool {
+	return len(s.b) > len(suffix) && string(s.b[len(s.b)-len(suffix):]) == suffix
+}
+
+func (s *porterStemmer) stem(suffix string) []byte {
+	return s.b[:len(s.b)-len(suffix)]
+}
+
+func (s *porterStemmer) replace(suffix, with string) {
+	s.b = append(s.stem(suffix), with...)
+}
+
+// replaceLongest replaces the longest matching suffix of a list when the
+// measure of the remaining stem is greater than min. Shorter suffixes are not
+// attempted when the longest does not meet the condition.
+func (s *porterStemmer) replaceLongest(rules [][2]string, min int) {
+	match := -1
+	for i, r := range rules {
+		if s.hasSuffix(r[0]) && (match < 0 || len(r[0]) > len(rules[match][0])) {
+			match = i
+		}
+	}
+	if match >= 0 && s.measure(s.stem(rules[match][0])) > min {
+		s.replace(rules[match][0], rules[match][1])
+	}
+}
+
+func (s *porterStemmer) step1a() {
+	switch {
+	case s.hasSuffix("sses"):
+		s.replace("sses", "ss")
+	case s.hasSuffix("ies"):
+		s.replace("ies", "i")
+	case s.hasSuffix("ss"):
+	case s.hasSuffix("s"):
+		s.replace("s", "")
+	}
+}
+
+func (s *porterStemmer) step1b() {
+	if s.hasSuffix("eed") {
+		if s.measure(s.stem("eed")) > 0 {
+			s.replace("eed", "ee")
+		}
+		return
+	}
+
+	removed := false
+	for _, suffix := range []string{"ed", "ing"} {
+		if s.hasSuffix(suffix) && s.hasVowel(s.stem(suffix)) {
+			s.replace(suffix, "")
+			removed = true
+			break
+		}
+	}
+	if !removed {
+		return
+	}
+
+	switch {
+	case s.hasSuffix("at"), s.hasSuffix("bl"), s.hasSuffix("iz"):
+		s.b = append(s.b, 'e')
+	case s.endsDoubleConsonant(s.b):
+		switch s.b[len(s.b)-1] {
+		case 'l', 's', 'z':
+		default:
+			s.b = s.b[:len(s.b)-1]
+		}
+	case s.measure(s.b) == 1 && s.endsCVC(s.b):
+		s.b = append(s.b, 'e')
+	}
+}
+
+func (s *porterStemmer) step1c() {
+	if s.hasSuffix("y") && s.hasVowel(s.stem("y")) {
+		s.replace("y", "i")
+	}
+}
+
+var porterStep2 = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+func (s *porterStemmer) step2() {
+	s.replaceLongest(porterStep2, 0)
+}
+
+var porterStep3 = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func (s *porterStemmer) step3() {
+	s.replaceLongest(porterStep3, 0)
+}
+
+var porterStep4 = [][2]string{
+	{"al", ""}, {"ance", ""}, {"ence", ""}, {"er", ""}, {"ic", ""},
+	{"able", ""}, {"ible", ""}, {"ant", ""}, {"ement", ""}, {"ment", ""},
+	{"ent", ""}, {"ou", ""}, {"ism", ""}, {"ate", ""}, {"iti", ""},
+	{"ous", ""}, {"ive", ""}, {"ize", ""},
+}
+
+func (s *porterStemmer) step4() {
+	if s.hasSuffix("ion") {
+		stem := s.stem("ion")
+		if n := len(stem); n > 0 && (stem[n-1] == 's' || stem[n-1] == 't') && s.measure(stem) > 1 {
+			s.b = stem
+		}
+		return
+	}
+	s.replaceLongest(porterStep4, 1)
+}
+
+func (s *porterStemmer) step5() {
+	if s.hasSuffix("e") {
+		stem := s.stem("e")
+		if m := s.measure(stem); m > 1 || (m == 1 && !s.endsCVC(stem)) {
+			s.b = stem
+		}
+	}
+	if s.measure(s.b) > 1 && s.endsDoubleConsonant(s.b) && s.b[len(s.b)-1] == 'l' {
+		s.b = s.b[:len(s.b)-1]
+	}
+}
@@ -0,0 +1,99 @@
+package nlp
+
+import (
+	"sort"
+	"strings"
+)
+
+// stopwordLists contains common words of each supported language that carry
+// little meaning for search and analysis.
+var stopwordLists = map[string]string{
+	"en": `a about above after again against all am an and any are as at be
+because been before being below between both but by can could did do does
+doing down during each few for from further had has have having he her here
+hers herself him himself his how i if in into is it its itself just me more
+most my myself no nor not now of off on once only or other our ours ourselves
+out over own same she should so some such than that the their theirs them
+themselves then there these they this those through to too under until up
+very was we were what when where which while who whom why will with would you
+your yours yourself yourselves`,
+
+	"fr": `a au aux avec ce ces cette dans de des du elle en et eux il ils je
+la le les leur leurs lui ma mais me mes moi mon ne nos notre nous on ou où par
+pas pour qu que qui sa se ses son sont sur ta te tes toi ton tu un une vos
+votre vous est été être avoir ai as avons avez ont était étaient fait comme
+plus très aussi bien`,
+
+	"de": `aber alle als also am an auch auf aus bei bin bis bist da dann das
+dass dem den der des die dies diese dieser doch du durch ein eine einem einen
+einer eines er es für hat hatte haben ich ihr ihre im in ist ja kann kein
+keine mein mich mir mit nach nicht noch nun nur ob oder ohne sehr sein seine
+sich sie sind so über um und uns unser unter vom von vor war waren was weil
+wenn wer wie wir wird wo zu zum zur`,
+
+	"es": `a al algo como con de del el ella ellas ellos en entre era es esa
+ese eso esta este esto están fue ha han hay la las le les lo los más me mi muy
+nada ni no nos o otro para pero por porque que quien se ser si sin sobre su
+sus también te tiene todo tu un una uno unos y ya yo`,
+
+	"it": `a ad al alla alle anche che chi ci come con da dal dalla degli dei
+del della delle di e ed gli ha hanno ho i il in io la le lei lo loro lui ma mi
+mio ne nei nel nella noi non o per più quella quello questa questo se si sono
+su sua suo tra tu un una uno vi voi è`,
+
+	"pt": `a ao aos as com como da das de dela dele do dos e ela elas ele eles
+em entre era essa esse esta este eu foi há isso isto já mais mas me meu minha
+muito na nas nem no nos não o os ou para pela pelo por quando que se sem ser
+seu sua são também te tem um uma você é`,
+
+	"nl": `aan al als ben bij dat de der deze die dit doch door dus een en er
+ge had heb heeft hem het hier hij hoe hun ik in is ja je kan kon maar me meer
+men met mij mijn moet na naar niet niets nog nu of om omdat ons ook op over
+reeds te tegen toch toen tot u uit uw van veel voor want waren was wat we wel
+werd wij wordt zal ze zei zelf zich zij zijn zo zonder zou`,
+
+	"sv": `alla att av blev bli de dem den denna deras dess det detta dig din
+dina ditt du där efter ej eller en er ett från för ha hade han hans har henne
+hennes hon honom hur här i icke ingen inom inte jag ju kan kunde man med mig
+min mina mitt mot mycket ni nu när och om oss på samma sedan sig sin sina sitt
+själv skulle som så till under upp ut utan vad var vara varit vi vid vilka
+vilken är än över`,
+}
+
+var stopwords = func() map[string]map[string]struct{} {
+	sets := make(map[string]map[string]struct{}, len(stopwordLists))
+	for lang, list := range stopwordLists {
+		words := strings.Fields(list)
+		set := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			set[w] = struct{}{}
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// StopwordLanguages returns the languages with stopword lists as ISO 639-1
+// codes.
+func StopwordLanguages() []string {
+	langs := make([]string, 0, len(stopwords))
+	for lang := range stopwords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// IsStopword returns whether a word is a stopword of a language, which is an
+// ISO 639-1 code. Words are compared case-insensitively.
+func IsStopword(lang, word string) bool {
+	_, exists := stopwords[lang][strings.ToLower(word)]
+	return exists
+}
+
+// HasStopwords returns whether a language, which is an ISO 639-1 code, has a
+// stopword list.
+func HasStopwords(lang string) bool {
+	_, exists := stopwords[lang]
+	return exists
+}
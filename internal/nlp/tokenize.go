@@ -0,0 +1,54 @@
+package nlp
+
+import (
+	"unicode"
+)
+
+// isIdeograph returns whether a rune belongs to a script that is written
+// without spaces between words, where each character is treated as a token.
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// Tokenize splits text into words, where a word is a sequence of Unicode
+// letters, numbers and marks. Apostrophes are retained when they occur within
+// a word, and ideographic characters, such as those of Chinese and Japanese,
+// are each a separate token. The case of words is preserved.
+func Tokenize(s string) []string {
+	runes := []rune(s)
+	tokens := []string{}
+
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, string(runes[start:end]))
+			start = -1
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case isIdeograph(r):
+			flush(i)
+			tokens = append(tokens, string(r))
+		case isWordRune(r):
+			if start < 0 {
+				start = i
+			}
+		case isApostrophe(r) && start >= 0 && i+1 < len(runes) && isWordRune(runes[i+1]) && !isIdeograph(runes[i+1]):
+			// Apostrophes within words, such as contractions, are retained.
+		default:
+			flush(i)
+		}
+	}
+	flush(len(runes))
+	return tokens
+}
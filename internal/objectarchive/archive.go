@@ -0,0 +1,276 @@
+// Package objectarchive implements a simple archive of message batches within
+// an object store. Each batch is written as a segment object within a time
+// partition, and each partition has a manifest listing its segments in the
+// order they were written. An index at the root of the archive lists the
+// partitions, allowing readers to replay the batches of a time range.
+package objectarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// IndexKey is the key of the index object of an archive.
+	IndexKey = "index.json"
+
+	manifestName = "manifest.json"
+	gzipSuffix   = ".gz"
+)
+
+// PartitionLayout returns the time layout of partition paths for a
+// partitioning period, which is either hour or day.
+func PartitionLayout(period string) (string, error) {
+	switch period {
+	case "hour":
+		return "2006/01/02/15", nil
+	case "day":
+		return "2006/01/02", nil
+	}
+	return "", fmt.Errorf("partition period not recognised: %v", period)
+}
+
+// ManifestKey returns the key of the manifest object of a partition.
+func ManifestKey(partition string) string {
+	return path.Join(partition, manifestName)
+}
+
+//------------------------------------------------------------------------------
+
+// Index lists the partitions of an archive in chronological order.
+type Index struct {
+	Partitions []string `json:"partitions"`
+}
+
+// Segment describes a batch of messages written as a single object.
+type Segment struct {
+	Key   string    `json:"key"`
+	Count int       `json:"count"`
+	Size  int       `json:"size"`
+	Time  time.Time `json:"time"`
+}
+
+// Manifest lists the segments of a partition in the order they were written.
+type Manifest struct {
+	Segments []Segment `json:"segments"`
+}
+
+func readJSON(ctx context.Context, store Store, key string, v interface{}) (bool, error) {
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read '%v': %w", key, err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse '%v': %w", key, err)
+	}
+	return true, nil
+}
+
+// ReadIndex reads the index of an archive, an empty index is returned when
+// it does not yet exist.
+func ReadIndex(ctx context.Context, store Store) (Index, error) {
+	var index Index
+	_, err := readJSON(ctx, store, IndexKey, &index)
+	return index, err
+}
+
+// ReadManifest reads the manifest of a partition, an empty manifest is
+// returned when it does not yet exist.
+func ReadManifest(ctx context.Context, store Store, partition string) (Manifest, error) {
+	var manifest Manifest
+	_, err := readJSON(ctx, store, ManifestKey(partition), &manifest)
+	return manifest, err
+}
+
+//------------------------------------------------------------------------------
+
+type record struct {
+	Content  []byte            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// EncodeSegment serialises a batch of messages, including their metadata, as
+// lines of JSON objects, optionally compressed with gzip.
+func EncodeSegment(msg types.Message, compress bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := msg.Iter(func(i int, p types.Part) error {
+		r := record{Content: p.Get()}
+		p.Metadata().Iter(func(k, v string) error {
+			if r.Metadata == nil {
+				r.Metadata = map[string]string{}
+			}
+			r.Metadata[k] = v
+			return nil
+		})
+		return enc.Encode(r)
+	})
+	if err != nil || !compress {
+		return buf.Bytes(), err
+	}
+
+	var gBuf bytes.Buffer
+	w := gzip.NewWriter(&gBuf)
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return gBuf.Bytes(), nil
+}
+
+// DecodeSegment parses a segment object into a batch of messages, the key of
+// the segment determines whether it is compressed.
+func DecodeSegment(key string, data []byte) (types.Message, error) {
+	if strings.HasSuffix(key, gzipSuffix) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := message.New(nil)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("failed to parse record %v: %w", msg.Len(), err)
+		}
+		part := message.NewPart(r.Content)
+		for k, v := range r.Metadata {
+			part.Metadata().Set(k, v)
+		}
+		msg.Append(part)
+	}
+	return msg, scanner.Err()
+}
+
+//------------------------------------------------------------------------------
+
+// Writer appends batches of messages to an archive. A Writer assumes that it
+// is the only writer of the archive and is not safe for concurrent use.
+type Writer struct {
+	store    Store
+	layout   string
+	compress bool
+
+	index     *Index
+	partition string
+	manifest  Manifest
+
+	nowFn func() time.Time
+}
+
+// NewWriter creates a Writer that partitions segments by a time layout.
+func NewWriter(store Store, layout string, compress bool) *Writer {
+	return &Writer{
+		store:    store,
+		layout:   layout,
+		compress: compress,
+		nowFn:    time.Now,
+	}
+}
+
+func (w *Writer) segmentKey(partition string, t time.Time) string {
+	name := fmt.Sprintf("%019d-%v.jsonl", t.UnixNano(), uuid.Must(uuid.NewV4()).String())
+	if w.compress {
+		name += gzipSuffix
+	}
+	return path.Join(partition, name)
+}
+
+// openPartition loads the manifest of a partition, adding the partition to the
+// index if it is new.
+func (w *Writer) openPartition(ctx context.Context, partition string) error {
+	if w.index == nil {
+		index, err := ReadIndex(ctx, w.store)
+		if err != nil {
+			return err
+		}
+		w.index = &index
+	}
+
+	manifest, err := ReadManifest(ctx, w.store, partition)
+	if err != nil {
+		return err
+	}
+
+	i := sort.SearchStrings(w.index.Partitions, partition)
+	if i == len(w.index.Partitions) || w.index.Partitions[i] != partition {
+		partitions := make([]string, 0, len(w.index.Partitions)+1)
+		partitions = append(partitions, w.index.Partitions[:i]...)
+		partitions = append(partitions, partition)
+		partitions = append(partitions, w.index.Partitions[i:]...)
+
+		data, err := json.Marshal(Index{Partitions: partitions})
+		if err != nil {
+			return err
+		}
+		if err = w.store.Put(ctx, IndexKey, data); err != nil {
+			return fmt.Errorf("failed to write index: %w", err)
+		}
+		w.index.Partitions = partitions
+	}
+
+	w.partition, w.manifest = partition, manifest
+	return nil
+}
+
+// WriteBatch writes a batch of messages as a segment of the current partition
+// and then adds the segment to the manifest of the partition. A batch is only
+// visible to readers once the manifest has been written.
+func (w *Writer) WriteBatch(ctx context.Context, msg types.Message) error {
+	now := w.nowFn().UTC()
+	partition := now.Format(w.layout)
+	if w.index == nil || partition != w.partition {
+		if err := w.openPartition(ctx, partition); err != nil {
+			return err
+		}
+	}
+
+	data, err := EncodeSegment(msg, w.compress)
+	if err != nil {
+		return err
+	}
+	seg := Segment{
+		Key:   w.segmentKey(partition, now),
+		Count: msg.Len(),
+		Size:  len(data),
+		Time:  now,
+	}
+	if err = w.store.Put(ctx, seg.Key, data); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+
+	manifest := Manifest{Segments: append(w.manifest.Segments[:len(w.manifest.Segments):len(w.manifest.Segments)], seg)}
+	if data, err = json.Marshal(manifest); err != nil {
+		return err
+	}
+	if err = w.store.Put(ctx, ManifestKey(partition), data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	w.manifest = manifest
+	return nil
+}
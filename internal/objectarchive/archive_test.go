@@ -0,0 +1,89 @@
+package objectarchive
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentRoundTrip(t *testing.T) {
+	msg := message.New([][]byte{[]byte(`{"id":1}`), []byte("not json\nwith lines")})
+	msg.Get(0).Metadata().Set("kafka_topic", "foo")
+
+	for _, compress := range []bool{false, true} {
+		key := "segment.jsonl"
+		if compress {
+			key += gzipSuffix
+		}
+		data, err := EncodeSegment(msg, compress)
+		require.NoError(t, err)
+
+		out, err := DecodeSegment(key, data)
+		require.NoError(t, err)
+		require.Equal(t, 2, out.Len())
+		assert.Equal(t, `{"id":1}`, string(out.Get(0).Get()))
+		assert.Equal(t, "foo", out.Get(0).Metadata().Get("kafka_topic"))
+		assert.Equal(t, "not json\nwith lines", string(out.Get(1).Get()))
+	}
+}
+
+func TestWriterPartitions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_object_archive_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ctx := context.Background()
+	store := NewFileStore(dir)
+
+	layout, err := PartitionLayout("hour")
+	require.NoError(t, err)
+
+	now := time.Date(2021, 6, 24, 13, 30, 0, 0, time.UTC)
+	w := NewWriter(store, layout, true)
+	w.nowFn = func() time.Time { return now }
+
+	require.NoError(t, w.WriteBatch(ctx, message.New([][]byte{[]byte("a"), []byte("b")})))
+	now = now.Add(time.Minute)
+	require.NoError(t, w.WriteBatch(ctx, message.New([][]byte{[]byte("c")})))
+
+	// A new writer picks up the existing index and inserts partitions in
+	// chronological order.
+	w = NewWriter(store, layout, true)
+	w.nowFn = func() time.Time { return now }
+	now = now.Add(-time.Hour)
+	require.NoError(t, w.WriteBatch(ctx, message.New([][]byte{[]byte("d")})))
+
+	index, err := ReadIndex(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2021/06/24/12", "2021/06/24/13"}, index.Partitions)
+
+	manifest, err := ReadManifest(ctx, store, "2021/06/24/13")
+	require.NoError(t, err)
+	require.Len(t, manifest.Segments, 2)
+	assert.Equal(t, 2, manifest.Segments[0].Count)
+	assert.Equal(t, time.Date(2021, 6, 24, 13, 31, 0, 0, time.UTC), manifest.Segments[1].Time)
+
+	data, err := store.Get(ctx, manifest.Segments[1].Key)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Segments[1].Size, len(data))
+
+	msg, err := DecodeSegment(manifest.Segments[1].Key, data)
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(msg.Get(0).Get()))
+
+	_, err = os.Stat(filepath.Join(dir, "2021", "06", "24", "12", manifestName))
+	require.NoError(t, err)
+
+	manifest, err = ReadManifest(ctx, store, "2021/06/24/11")
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Segments)
+}
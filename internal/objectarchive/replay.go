@@ -0,0 +1,210 @@
+package objectarchive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+var partitionPeriods = []struct {
+	layout string
+	period func(time.Time) time.Time
+}{
+	{"2006/01/02/15", func(t time.Time) time.Time { return t.Add(time.Hour) }},
+	{"2006/01/02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+}
+
+// PartitionRange returns the time range covered by a partition path.
+func PartitionRange(partition string) (start, end time.Time, err error) {
+	for _, p := range partitionPeriods {
+		if start, err = time.Parse(p.layout, partition); err == nil {
+			return start, p.period(start), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("partition path not recognised: %v", partition)
+}
+
+//------------------------------------------------------------------------------
+
+type segmentResult struct {
+	seg Segment
+	msg types.Message
+	err error
+}
+
+type segmentFuture struct {
+	seg Segment
+	res chan segmentResult
+}
+
+// Replay reads the segments of an archive that were written within a time
+// range in the order they were written. Segments are downloaded in parallel
+// ahead of being read, and the number of segments downloaded but not yet read
+// is limited by the concurrency of the Replay.
+type Replay struct {
+	store   Store
+	slots   chan struct{}
+	futures chan segmentFuture
+	errs    chan error
+
+	head *segmentFuture
+
+	ctx  context.Context
+	done func()
+}
+
+// NewReplay begins replaying the segments of an archive that were written at
+// or after start and before end. A zero start replays from the beginning of
+// the archive.
+func NewReplay(store Store, start, end time.Time, concurrency int) (*Replay, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("download concurrency must be greater than 0, got %v", concurrency)
+	}
+	if !end.After(start) {
+		return nil, errors.New("the end of the replay range must be after the start")
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	r := &Replay{
+		store:   store,
+		slots:   make(chan struct{}, concurrency),
+		futures: make(chan segmentFuture, concurrency),
+		errs:    make(chan error, 1),
+		ctx:     ctx,
+		done:    done,
+	}
+	go r.loop(start, end)
+	return r, nil
+}
+
+func (r *Replay) fetch(seg Segment) chan segmentResult {
+	res := make(chan segmentResult, 1)
+	go func() {
+		data, err := r.store.Get(r.ctx, seg.Key)
+		if err != nil {
+			res <- segmentResult{seg: seg, err: fmt.Errorf("failed to read segment '%v': %w", seg.Key, err)}
+			return
+		}
+		msg, err := DecodeSegment(seg.Key, data)
+		if err != nil {
+			err = fmt.Errorf("failed to decode segment '%v': %w", seg.Key, err)
+		}
+		res <- segmentResult{seg: seg, msg: msg, err: err}
+	}()
+	return res
+}
+
+func (r *Replay) loop(start, end time.Time) {
+	defer close(r.futures)
+
+	// Errors are surfaced to the reader and the failed read is retried.
+	retryErr := func(err error) bool {
+		select {
+		case r.errs <- err:
+		case <-r.ctx.Done():
+			return false
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-r.ctx.Done():
+			return false
+		}
+		return true
+	}
+
+	var index Index
+	var err error
+	for {
+		if index, err = ReadIndex(r.ctx, r.store); err == nil {
+			break
+		}
+		if !retryErr(err) {
+			return
+		}
+	}
+
+	for _, partition := range index.Partitions {
+		pStart, pEnd, err := PartitionRange(partition)
+		if err != nil {
+			select {
+			case r.errs <- err:
+			case <-r.ctx.Done():
+			}
+			return
+		}
+		if !pEnd.After(start) || !pStart.Before(end) {
+			continue
+		}
+
+		var manifest Manifest
+		for {
+			if manifest, err = ReadManifest(r.ctx, r.store, partition); err == nil {
+				break
+			}
+			if !retryErr(err) {
+				return
+			}
+		}
+
+		for _, seg := range manifest.Segments {
+			if seg.Time.Before(start) || !seg.Time.Before(end) {
+				continue
+			}
+			// A slot is held by each segment from the moment its download
+			// begins until it is read, which bounds memory usage.
+			select {
+			case r.slots <- struct{}{}:
+			case <-r.ctx.Done():
+				return
+			}
+			r.futures <- segmentFuture{seg: seg, res: r.fetch(seg)}
+		}
+	}
+}
+
+// Next returns the next segment of the replay and its messages, or io.EOF once
+// all segments of the range have been read. When a segment fails to be read
+// an error is returned and the same segment is attempted again by the
+// following call.
+func (r *Replay) Next(ctx context.Context) (Segment, types.Message, error) {
+	if r.head == nil {
+		select {
+		case err := <-r.errs:
+			return Segment{}, nil, err
+		case f, open := <-r.futures:
+			if !open {
+				select {
+				case err := <-r.errs:
+					return Segment{}, nil, err
+				default:
+				}
+				return Segment{}, nil, io.EOF
+			}
+			r.head = &f
+		case <-ctx.Done():
+			return Segment{}, nil, ctx.Err()
+		}
+	}
+
+	select {
+	case res := <-r.head.res:
+		if res.err != nil {
+			r.head.res = r.fetch(r.head.seg)
+			return res.seg, nil, res.err
+		}
+		r.head = nil
+		<-r.slots
+		return res.seg, res.msg, nil
+	case <-ctx.Done():
+		return Segment{}, nil, ctx.Err()
+	}
+}
+
+// Close stops the replay and any downloads in progress.
+func (r *Replay) Close() {
+	r.done()
+}
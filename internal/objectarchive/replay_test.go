@@ -0,0 +1,117 @@
+package objectarchive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flakyStore struct {
+	Store
+
+	mut   sync.Mutex
+	fails map[string]int
+}
+
+func (f *flakyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	f.mut.Lock()
+	fails := f.fails[key]
+	if fails > 0 {
+		f.fails[key] = fails - 1
+	}
+	f.mut.Unlock()
+	if fails > 0 {
+		return nil, errors.New("nope")
+	}
+	return f.Store.Get(ctx, key)
+}
+
+func TestPartitionRange(t *testing.T) {
+	start, end, err := PartitionRange("2021/06/24/13")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 6, 24, 13, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2021, 6, 24, 14, 0, 0, 0, time.UTC), end)
+
+	start, end, err = PartitionRange("2021/06/24")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 6, 24, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2021, 6, 25, 0, 0, 0, 0, time.UTC), end)
+
+	_, _, err = PartitionRange("foo")
+	require.EqualError(t, err, "partition path not recognised: foo")
+}
+
+func TestReplayRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_object_archive_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ctx := context.Background()
+	store := &flakyStore{Store: NewFileStore(dir), fails: map[string]int{}}
+
+	layout, err := PartitionLayout("hour")
+	require.NoError(t, err)
+
+	now := time.Date(2021, 6, 24, 11, 30, 0, 0, time.UTC)
+	w := NewWriter(store, layout, true)
+	w.nowFn = func() time.Time { return now }
+
+	// Write a segment every 20 minutes across four partitions.
+	for _, v := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+		require.NoError(t, w.WriteBatch(ctx, message.New([][]byte{[]byte(v)})))
+		now = now.Add(time.Minute * 20)
+	}
+
+	manifest, err := ReadManifest(ctx, store, "2021/06/24/13")
+	require.NoError(t, err)
+	require.Len(t, manifest.Segments, 3)
+	store.fails[manifest.Segments[1].Key] = 2
+
+	r, err := NewReplay(
+		store,
+		time.Date(2021, 6, 24, 12, 10, 0, 0, time.UTC),
+		time.Date(2021, 6, 24, 13, 50, 0, 0, time.UTC),
+		2,
+	)
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+
+	var results []string
+	var errCount int
+	for {
+		_, msg, err := r.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errCount++
+			require.Less(t, errCount, 10)
+			continue
+		}
+		results = append(results, string(msg.Get(0).Get()))
+	}
+	assert.Equal(t, []string{"c", "d", "e", "f", "g"}, results)
+	assert.Equal(t, 2, errCount)
+}
+
+func TestReplayConfigErrors(t *testing.T) {
+	store := NewFileStore("/tmp/foo")
+	now := time.Now()
+
+	_, err := NewReplay(store, now, now.Add(time.Hour), 0)
+	require.EqualError(t, err, "download concurrency must be greater than 0, got 0")
+
+	_, err = NewReplay(store, now, now, 1)
+	require.EqualError(t, err, "the end of the replay range must be after the start")
+}
@@ -0,0 +1,170 @@
+package objectarchive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// ErrNotFound is returned by a Store when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Store is an object store that an archive is written to and read from, keys
+// are slash separated paths relative to the root of the archive.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// AWSConfig contains configuration fields for connecting to S3.
+type AWSConfig struct {
+	sess.Config        `json:",inline" yaml:",inline"`
+	ForcePathStyleURLs bool `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+}
+
+// NewAWSConfig creates a new AWSConfig with default values.
+func NewAWSConfig() AWSConfig {
+	return AWSConfig{
+		Config:             sess.NewConfig(),
+		ForcePathStyleURLs: false,
+	}
+}
+
+// AWSFieldSpecs returns documentation specs for AWSConfig fields.
+func AWSFieldSpecs() docs.FieldSpecs {
+	return append(
+		sess.FieldSpecs(),
+		docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints.").AtVersion("3.47.0"),
+	)
+}
+
+// NewStore creates a Store from a path, which is either a directory of the
+// local filesystem or an S3 URL of the form s3://bucket/prefix, in which case
+// the AWS config is used in order to create a session.
+func NewStore(p string, awsConf AWSConfig) (Store, error) {
+	if p == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if strings.HasPrefix(p, "s3://") {
+		bucket := strings.TrimPrefix(p, "s3://")
+		var prefix string
+		if i := strings.Index(bucket, "/"); i >= 0 {
+			bucket, prefix = bucket[:i], strings.Trim(bucket[i+1:], "/")
+		}
+		if bucket == "" {
+			return nil, fmt.Errorf("path '%v' does not contain a bucket", p)
+		}
+		session, err := awsConf.GetSession(func(c *aws.Config) {
+			c.S3ForcePathStyle = aws.Bool(awsConf.ForcePathStyleURLs)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Store(s3.New(session), bucket, prefix), nil
+	}
+	return NewFileStore(strings.TrimPrefix(p, "file://")), nil
+}
+
+//------------------------------------------------------------------------------
+
+type fileStore struct {
+	root string
+}
+
+// NewFileStore creates a Store that writes objects as files within a
+// directory of the local filesystem.
+func NewFileStore(root string) Store {
+	return &fileStore{root: root}
+}
+
+func (f *fileStore) Put(ctx context.Context, key string, data []byte) error {
+	target := filepath.Join(f.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	// Objects are written to a temporary file first so that readers never
+	// observe partially written objects.
+	tmp, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (f *fileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.root, filepath.FromSlash(key)))
+	if err != nil && os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+//------------------------------------------------------------------------------
+
+type s3Store struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Store creates a Store that writes objects to an S3 bucket, with keys
+// beneath an optional prefix.
+func NewS3Store(client s3iface.S3API, bucket, prefix string) Store {
+	return &s3Store{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (s *s3Store) key(key string) string {
+	return path.Join(s.prefix, key)
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	obj, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	if err != nil {
+		var aErr awserr.Error
+		if errors.As(err, &aErr) && aErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return ioutil.ReadAll(obj.Body)
+}
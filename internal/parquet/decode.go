@@ -0,0 +1,174 @@
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	pqreader "github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/types"
+)
+
+const readChunkSize = 1000
+
+// schemaNode is an element of the schema of a file along with its children,
+// which is used in order to convert the values read from a file back into the
+// original names of its columns.
+type schemaNode struct {
+	name     string
+	element  *parquet.SchemaElement
+	children []*schemaNode
+}
+
+func newSchemaTree(sh *schema.SchemaHandler) (*schemaNode, error) {
+	pos := 0
+	var build func() (*schemaNode, error)
+	build = func() (*schemaNode, error) {
+		if pos >= len(sh.SchemaElements) {
+			return nil, errors.New("schema of file is malformed")
+		}
+		node := &schemaNode{
+			name:    sh.Infos[pos].ExName,
+			element: sh.SchemaElements[pos],
+		}
+		pos++
+		for i := int32(0); i < node.element.GetNumChildren(); i++ {
+			child, err := build()
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		return node, nil
+	}
+	return build()
+}
+
+func (n *schemaNode) isConverted(t parquet.ConvertedType) bool {
+	return n.element.ConvertedType != nil && *n.element.ConvertedType == t
+}
+
+// toJSONValue converts a value read from a file into a generic JSON value.
+func toJSONValue(v reflect.Value, node *schemaNode) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toJSONValue(v.Elem(), node)
+	case reflect.Struct:
+		obj := make(map[string]interface{}, len(node.children))
+		for i, child := range node.children {
+			obj[child.name] = toJSONValue(v.Field(i), child)
+		}
+		return obj
+	case reflect.Slice:
+		elemNode := node
+		if node.isConverted(parquet.ConvertedType_LIST) && len(node.children) == 1 && len(node.children[0].children) == 1 {
+			elemNode = node.children[0].children[0]
+		}
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = toJSONValue(v.Index(i), elemNode)
+		}
+		return arr
+	case reflect.Map:
+		keyNode, valueNode := node, node
+		if len(node.children) == 1 && len(node.children[0].children) == 2 {
+			keyNode, valueNode = node.children[0].children[0], node.children[0].children[1]
+		}
+		obj := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			obj[fmt.Sprintf("%v", toJSONValue(iter.Key(), keyNode))] = toJSONValue(iter.Value(), valueNode)
+		}
+		return obj
+	case reflect.String:
+		if node.element.GetType() == parquet.Type_INT96 {
+			return types.INT96ToTime(v.String()).Format(time.RFC3339Nano)
+		}
+	}
+	return v.Interface()
+}
+
+//------------------------------------------------------------------------------
+
+// Reader reads the rows of a Parquet file as generic JSON values.
+type Reader struct {
+	pr        *pqreader.ParquetReader
+	root      *schemaNode
+	remaining int64
+	pending   []interface{}
+}
+
+// NewReader creates a reader of the rows of a Parquet file held in memory.
+func NewReader(data []byte) (r *Reader, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("failed to read parquet file: %v", rec)
+		}
+	}()
+
+	pr, err := pqreader.NewParquetReader(newBytesFile(data), nil, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+	root, err := newSchemaTree(pr.SchemaHandler)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		pr:        pr,
+		root:      root,
+		remaining: pr.GetNumRows(),
+	}, nil
+}
+
+// NumRows returns the total number of rows within the file.
+func (r *Reader) NumRows() int64 {
+	return r.pr.GetNumRows()
+}
+
+// Next returns the next row of the file, or io.EOF once all rows are read.
+func (r *Reader) Next() (row interface{}, err error) {
+	if len(r.pending) == 0 {
+		if r.remaining <= 0 {
+			return nil, io.EOF
+		}
+		chunk := int64(readChunkSize)
+		if chunk > r.remaining {
+			chunk = r.remaining
+		}
+		if r.pending, err = r.readChunk(int(chunk)); err != nil {
+			return nil, err
+		}
+		if len(r.pending) == 0 {
+			return nil, errors.New("failed to read parquet rows: file ended early")
+		}
+		r.remaining -= int64(len(r.pending))
+	}
+	row, r.pending = r.pending[0], r.pending[1:]
+	return toJSONValue(reflect.ValueOf(row), r.root), nil
+}
+
+func (r *Reader) readChunk(n int) (rows []interface{}, err error) {
+	// The reader panics when the contents of a file are malformed.
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("failed to read parquet rows: %v", rec)
+		}
+	}()
+	if rows, err = r.pr.ReadByNumber(n); err != nil {
+		return nil, fmt.Errorf("failed to read parquet rows: %w", err)
+	}
+	return rows, nil
+}
+
+// Close releases the resources of the reader.
+func (r *Reader) Close() {
+	r.pr.ReadStop()
+}
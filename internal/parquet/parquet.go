@@ -0,0 +1,215 @@
+// Package parquet converts between JSON documents and the rows of Parquet
+// files, where files are encoded with a schema described by a list of columns
+// and decoded with the schema found within the file.
+package parquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	pqwriter "github.com/xitongsys/parquet-go/writer"
+)
+
+// Column describes a column of a Parquet schema, which is a group of nested
+// columns when fields are specified.
+type Column struct {
+	Name     string   `json:"name" yaml:"name"`
+	Type     string   `json:"type" yaml:"type"`
+	Repeated bool     `json:"repeated" yaml:"repeated"`
+	Optional bool     `json:"optional" yaml:"optional"`
+	Fields   []Column `json:"fields" yaml:"fields"`
+}
+
+var columnTypes = map[string]string{
+	"BOOLEAN":    "type=BOOLEAN",
+	"INT32":      "type=INT32",
+	"INT64":      "type=INT64",
+	"FLOAT":      "type=FLOAT",
+	"DOUBLE":     "type=DOUBLE",
+	"BYTE_ARRAY": "type=BYTE_ARRAY",
+	"UTF8":       "type=BYTE_ARRAY, convertedtype=UTF8",
+}
+
+// ColumnFieldSpecs returns the field specs of a column.
+func ColumnFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("name", "The name of the column.").HasType(docs.FieldString).HasDefault(""),
+		docs.FieldCommon("type", "The type of the column, which is ignored when `fields` are specified.").HasOptions(
+			"BOOLEAN", "INT32", "INT64", "FLOAT", "DOUBLE", "BYTE_ARRAY", "UTF8",
+		).HasType(docs.FieldString).HasDefault(""),
+		docs.FieldCommon("repeated", "Whether the column contains a list of values.").HasType(docs.FieldBool).HasDefault(false),
+		docs.FieldCommon("optional", "Whether the column can be null or omitted.").HasType(docs.FieldBool).HasDefault(false),
+		docs.FieldCommon("fields", "A list of nested columns, making this column a group, where each nested column has the same fields as a column.").Array().HasType(docs.FieldObject).HasDefault([]interface{}{}),
+	}
+}
+
+type schemaItem struct {
+	Tag    string        `json:"Tag"`
+	Fields []*schemaItem `json:"Fields,omitempty"`
+}
+
+func columnSchema(c Column) (*schemaItem, error) {
+	if c.Name == "" {
+		return nil, errors.New("column name must not be empty")
+	}
+	if strings.ContainsAny(c.Name, ",=") {
+		return nil, fmt.Errorf("column name '%v' must not contain ',' or '='", c.Name)
+	}
+
+	repetition := "REQUIRED"
+	if c.Repeated {
+		repetition = "REPEATED"
+	} else if c.Optional {
+		repetition = "OPTIONAL"
+	}
+
+	if len(c.Fields) > 0 {
+		item := &schemaItem{Tag: fmt.Sprintf("name=%v, repetitiontype=%v", c.Name, repetition)}
+		for _, f := range c.Fields {
+			child, err := columnSchema(f)
+			if err != nil {
+				return nil, fmt.Errorf("column '%v': %w", c.Name, err)
+			}
+			item.Fields = append(item.Fields, child)
+		}
+		return item, nil
+	}
+
+	typeTag, exists := columnTypes[strings.ToUpper(c.Type)]
+	if !exists {
+		return nil, fmt.Errorf("column '%v' type not recognised: %v", c.Name, c.Type)
+	}
+	return &schemaItem{Tag: fmt.Sprintf("name=%v, %v, repetitiontype=%v", c.Name, typeTag, repetition)}, nil
+}
+
+// CompressionCodec returns the Parquet compression codec of a name.
+func CompressionCodec(name string) (parquet.CompressionCodec, error) {
+	switch name {
+	case "uncompressed":
+		return parquet.CompressionCodec_UNCOMPRESSED, nil
+	case "snappy":
+		return parquet.CompressionCodec_SNAPPY, nil
+	case "gzip":
+		return parquet.CompressionCodec_GZIP, nil
+	case "zstd":
+		return parquet.CompressionCodec_ZSTD, nil
+	}
+	return 0, fmt.Errorf("compression type not recognised: %v", name)
+}
+
+//------------------------------------------------------------------------------
+
+// Encoder writes JSON documents as the rows of Parquet files.
+type Encoder struct {
+	schema       string
+	compression  parquet.CompressionCodec
+	rowGroupSize int64
+}
+
+// NewEncoder creates an encoder of Parquet files with a schema, compression
+// codec and target row group size in bytes.
+func NewEncoder(columns []Column, compression string, rowGroupSize int64) (*Encoder, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("schema must contain at least one column")
+	}
+	if rowGroupSize <= 0 {
+		return nil, fmt.Errorf("row group size must be greater than 0, got %v", rowGroupSize)
+	}
+
+	root := &schemaItem{Tag: "name=parquet_go_root, repetitiontype=REQUIRED"}
+	for _, c := range columns {
+		item, err := columnSchema(c)
+		if err != nil {
+			return nil, err
+		}
+		root.Fields = append(root.Fields, item)
+	}
+	schemaBytes, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := CompressionCodec(compression)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Encoder{
+		schema:       string(schemaBytes),
+		compression:  codec,
+		rowGroupSize: rowGroupSize,
+	}
+
+	// Parse the schema up front in order to surface any errors.
+	if _, err := pqwriter.NewJSONWriterFromWriter(e.schema, ioutil.Discard, 1); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return e, nil
+}
+
+// Encode writes a Parquet file containing a row for each JSON document.
+func (e *Encoder) Encode(rows [][]byte) (data []byte, err error) {
+	// The writer panics when a row does not fit the schema.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to write parquet file: %v", r)
+		}
+	}()
+
+	var buf bytes.Buffer
+	pw, err := pqwriter.NewJSONWriterFromWriter(e.schema, &buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = e.compression
+	pw.RowGroupSize = e.rowGroupSize
+
+	for i, r := range rows {
+		if !json.Valid(r) {
+			return nil, fmt.Errorf("row %v is not a valid JSON document", i)
+		}
+		if err := pw.Write(string(r)); err != nil {
+			return nil, fmt.Errorf("failed to write row %v: %w", i, err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// bytesFile is a read only source.ParquetFile of a file held in memory, where
+// each file opened from it has its own offset.
+type bytesFile struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newBytesFile(data []byte) bytesFile {
+	return bytesFile{Reader: bytes.NewReader(data), data: data}
+}
+
+func (b bytesFile) Write([]byte) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (b bytesFile) Close() error {
+	return nil
+}
+
+func (b bytesFile) Open(string) (source.ParquetFile, error) {
+	return newBytesFile(b.data), nil
+}
+
+func (b bytesFile) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("not supported")
+}
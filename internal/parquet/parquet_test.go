@@ -0,0 +1,114 @@
+package parquet
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllRows(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	r, err := NewReader(data)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var rows []string
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		b, err := json.Marshal(row)
+		require.NoError(t, err)
+		rows = append(rows, string(b))
+	}
+	assert.Equal(t, int64(len(rows)), r.NumRows())
+	return rows
+}
+
+func TestRoundTrip(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: "INT64"},
+		{Name: "first_name", Type: "UTF8"},
+		{Name: "score", Type: "DOUBLE", Optional: true},
+		{Name: "active", Type: "BOOLEAN", Optional: true},
+		{Name: "tags", Type: "UTF8", Repeated: true},
+		{Name: "address", Optional: true, Fields: []Column{
+			{Name: "city", Type: "UTF8"},
+			{Name: "post-code", Type: "UTF8", Optional: true},
+		}},
+	}
+
+	for _, compression := range []string{"uncompressed", "snappy", "gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			enc, err := NewEncoder(columns, compression, 1024*1024)
+			require.NoError(t, err)
+
+			data, err := enc.Encode([][]byte{
+				[]byte(`{"id":1,"first_name":"foo","score":1.5,"active":true,"tags":["a","b"],"address":{"city":"bar","post-code":"baz"}}`),
+				[]byte(`{"id":2,"first_name":"bar","ignored":"value"}`),
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{
+				`{"active":true,"address":{"city":"bar","post-code":"baz"},"first_name":"foo","id":1,"score":1.5,"tags":["a","b"]}`,
+				`{"active":null,"address":null,"first_name":"bar","id":2,"score":null,"tags":[]}`,
+			}, readAllRows(t, data))
+		})
+	}
+}
+
+func TestManyRows(t *testing.T) {
+	enc, err := NewEncoder([]Column{{Name: "id", Type: "INT32"}}, "snappy", 1024)
+	require.NoError(t, err)
+
+	var input [][]byte
+	for i := 0; i < 2500; i++ {
+		b, err := json.Marshal(map[string]interface{}{"id": i})
+		require.NoError(t, err)
+		input = append(input, b)
+	}
+	data, err := enc.Encode(input)
+	require.NoError(t, err)
+
+	rows := readAllRows(t, data)
+	require.Len(t, rows, 2500)
+	assert.Equal(t, `{"id":0}`, rows[0])
+	assert.Equal(t, `{"id":2499}`, rows[2499])
+}
+
+func TestEncoderErrors(t *testing.T) {
+	_, err := NewEncoder(nil, "snappy", 1024)
+	require.EqualError(t, err, "schema must contain at least one column")
+
+	_, err = NewEncoder([]Column{{Name: "id", Type: "INT8"}}, "snappy", 1024)
+	require.EqualError(t, err, "column 'id' type not recognised: INT8")
+
+	_, err = NewEncoder([]Column{{Name: "a", Fields: []Column{{Type: "INT64"}}}}, "snappy", 1024)
+	require.EqualError(t, err, "column 'a': column name must not be empty")
+
+	_, err = NewEncoder([]Column{{Name: "id", Type: "INT64"}}, "lz0", 1024)
+	require.EqualError(t, err, "compression type not recognised: lz0")
+
+	_, err = NewEncoder([]Column{{Name: "id", Type: "INT64"}}, "snappy", 0)
+	require.EqualError(t, err, "row group size must be greater than 0, got 0")
+
+	enc, err := NewEncoder([]Column{{Name: "id", Type: "INT64"}}, "snappy", 1024)
+	require.NoError(t, err)
+
+	_, err = enc.Encode([][]byte{[]byte(`{"id":1}`), []byte(`nope`)})
+	require.EqualError(t, err, "row 1 is not a valid JSON document")
+
+	_, err = enc.Encode([][]byte{[]byte(`{"id":"nope"}`)})
+	require.Error(t, err)
+}
+
+func TestReaderErrors(t *testing.T) {
+	_, err := NewReader([]byte("not a parquet file"))
+	require.Error(t, err)
+}
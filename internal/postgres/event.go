@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"time"
+)
+
+// Event operations.
+const (
+	OpInsert   = "insert"
+	OpUpdate   = "update"
+	OpDelete   = "delete"
+	OpTruncate = "truncate"
+	OpCommit   = "commit"
+)
+
+// pgEpoch is the epoch of timestamps within the replication protocol.
+var pgEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func pgTime(micros int64) time.Time {
+	return pgEpoch.Add(time.Duration(micros) * time.Microsecond)
+}
+
+func pgMicros(t time.Time) int64 {
+	return t.Sub(pgEpoch).Microseconds()
+}
+
+// Event is a change decoded from a replication slot. Commit events mark the end
+// of a transaction and carry the LSN that can be confirmed once every change of
+// the transaction, and all transactions before it, have been processed.
+type Event struct {
+	Operation  string
+	Schema     string
+	Table      string
+	LSN        LSN
+	XID        uint32
+	CommitTime time.Time
+
+	// Before contains the columns of the replica identity of a row prior to an
+	// update or delete, and is nil when unavailable.
+	Before map[string]interface{}
+
+	// After contains the columns of a row after an insert or update. Columns
+	// holding unchanged TOAST values are omitted.
+	After map[string]interface{}
+}
+
+// decoder decodes the messages of an output plugin into events.
+type decoder interface {
+	// Decode a message written at a given position of the log.
+	Decode(data []byte, walStart LSN) ([]*Event, error)
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LSN is a position within the write-ahead log.
+type LSN uint64
+
+// String returns the LSN in the format used by PostgreSQL, which is two
+// hexadecimal numbers separated by a slash.
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// ParseLSN parses an LSN in the format used by PostgreSQL.
+func ParseLSN(s string) (LSN, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid lsn: %v", s)
+	}
+	hi, err := strconv.ParseUint(s[:i], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lsn: %v", s)
+	}
+	lo, err := strconv.ParseUint(s[i+1:], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lsn: %v", s)
+	}
+	return LSN(hi<<32 | lo), nil
+}
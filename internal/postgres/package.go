@@ -0,0 +1,4 @@
+// Package postgres implements a client for consuming logical replication slots
+// of PostgreSQL over the streaming replication protocol, decoding the output of
+// the pgoutput and wal2json plugins into row change events.
+package postgres
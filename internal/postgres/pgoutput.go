@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Type OIDs with values that are converted from their text representation.
+const (
+	oidBool    = 16
+	oidInt8    = 20
+	oidInt2    = 21
+	oidInt4    = 23
+	oidOID     = 26
+	oidJSON    = 114
+	oidFloat4  = 700
+	oidFloat8  = 701
+	oidNumeric = 1700
+	oidJSONB   = 3802
+)
+
+var errShortMessage = errors.New("message too short")
+
+type relationColumn struct {
+	name string
+	oid  uint32
+}
+
+type relation struct {
+	schema  string
+	table   string
+	columns []relationColumn
+}
+
+// pgoutputDecoder decodes messages of version 1 of the pgoutput protocol.
+type pgoutputDecoder struct {
+	relations map[uint32]*relation
+
+	xid        uint32
+	commitTime time.Time
+}
+
+func newPgoutputDecoder() *pgoutputDecoder {
+	return &pgoutputDecoder{
+		relations: map[uint32]*relation{},
+	}
+}
+
+type msgReader struct {
+	b   []byte
+	err error
+}
+
+func (r *msgReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = errShortMessage
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *msgReader) uint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *msgReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *msgReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *msgReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *msgReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.b {
+		if c == 0 {
+			s := string(r.b[:i])
+			r.b = r.b[i+1:]
+			return s
+		}
+	}
+	r.err = errShortMessage
+	return ""
+}
+
+func (d *pgoutputDecoder) Decode(data []byte, walStart LSN) ([]*Event, error) {
+	if len(data) == 0 {
+		return nil, errShortMessage
+	}
+	r := &msgReader{b: data[1:]}
+
+	var events []*Event
+	switch data[0] {
+	case 'B':
+		r.uint64()
+		d.commitTime = pgTime(int64(r.uint64()))
+		d.xid = r.uint32()
+	case 'C':
+		r.uint8()
+		r.uint64()
+		end := LSN(r.uint64())
+		events = append(events, &Event{
+			Operation:  OpCommit,
+			LSN:        end,
+			XID:        d.xid,
+			CommitTime: pgTime(int64(r.uint64())),
+		})
+	case 'R':
+		id := r.uint32()
+		rel := &relation{
+			schema: r.string(),
+			table:  r.string(),
+		}
+		r.uint8()
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			r.uint8()
+			col := relationColumn{name: r.string(), oid: r.uint32()}
+			r.uint32()
+			rel.columns = append(rel.columns, col)
+		}
+		if r.err == nil {
+			d.relations[id] = rel
+		}
+	case 'I', 'U', 'D':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+		e := d.event(rel, walStart)
+		switch data[0] {
+		case 'I':
+			e.Operation = OpInsert
+		case 'U':
+			e.Operation = OpUpdate
+		case 'D':
+			e.Operation = OpDelete
+		}
+		for r.err == nil && len(r.b) > 0 {
+			switch kind := r.uint8(); kind {
+			case 'K', 'O':
+				e.Before = d.tuple(r, rel)
+			case 'N':
+				e.After = d.tuple(r, rel)
+			default:
+				return nil, fmt.Errorf("unexpected tuple type: %q", kind)
+			}
+		}
+		events = append(events, e)
+	case 'T':
+		n := int(r.uint32())
+		r.uint8()
+		for i := 0; i < n && r.err == nil; i++ {
+			rel, err := d.relation(r.uint32())
+			if err != nil {
+				return nil, err
+			}
+			e := d.event(rel, walStart)
+			e.Operation = OpTruncate
+			events = append(events, e)
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode %q message: %w", data[0], r.err)
+	}
+	return events, nil
+}
+
+func (d *pgoutputDecoder) relation(id uint32) (*relation, error) {
+	rel, exists := d.relations[id]
+	if !exists {
+		return nil, fmt.Errorf("received change for unknown relation: %v", id)
+	}
+	return rel, nil
+}
+
+func (d *pgoutputDecoder) event(rel *relation, walStart LSN) *Event {
+	return &Event{
+		Schema:     rel.schema,
+		Table:      rel.table,
+		LSN:        walStart,
+		XID:        d.xid,
+		CommitTime: d.commitTime,
+	}
+}
+
+func (d *pgoutputDecoder) tuple(r *msgReader, rel *relation) map[string]interface{} {
+	n := int(r.uint16())
+	values := make(map[string]interface{}, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		var col relationColumn
+		if i < len(rel.columns) {
+			col = rel.columns[i]
+		} else {
+			col.name = strconv.Itoa(i)
+		}
+		switch r.uint8() {
+		case 'n':
+			values[col.name] = nil
+		case 't':
+			values[col.name] = textValue(col.oid, string(r.next(int(r.uint32()))))
+		case 'u':
+			// Unchanged TOAST values are not sent.
+		}
+	}
+	return values
+}
+
+// textValue converts the text representation of a value into a structured
+// value where the type has an obvious equivalent, otherwise the text is
+// returned as is.
+func textValue(oid uint32, s string) interface{} {
+	switch oid {
+	case oidBool:
+		return s == "t"
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v
+		}
+	case oidFloat4, oidFloat8:
+		if v, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return v
+		}
+	case oidNumeric:
+		if v, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return json.Number(s)
+		}
+	case oidJSON, oidJSONB:
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err == nil {
+			return v
+		}
+	}
+	return s
+}
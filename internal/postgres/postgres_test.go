@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSN(t *testing.T) {
+	lsn, err := ParseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, LSN(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", lsn.String())
+
+	for _, s := range []string{"", "16", "16/", "G/1", "1/100000000"} {
+		_, err = ParseLSN(s)
+		assert.Error(t, err, s)
+	}
+}
+
+type msgBuilder []byte
+
+func (b msgBuilder) byte(v byte) msgBuilder { return append(b, v) }
+
+func (b msgBuilder) uint16(v uint16) msgBuilder {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func (b msgBuilder) uint32(v uint32) msgBuilder {
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], v)
+	return b
+}
+
+func (b msgBuilder) uint64(v uint64) msgBuilder {
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(b[len(b)-8:], v)
+	return b
+}
+
+func (b msgBuilder) string(s string) msgBuilder {
+	return append(append(b, s...), 0)
+}
+
+func (b msgBuilder) text(s string) msgBuilder {
+	return b.byte('t').uint32(uint32(len(s))).append(s)
+}
+
+func (b msgBuilder) append(s string) msgBuilder {
+	return append(b, s...)
+}
+
+func TestPgoutputDecode(t *testing.T) {
+	d := newPgoutputDecoder()
+	ts := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	events, err := d.Decode(msgBuilder{}.byte('B').uint64(0x200).uint64(uint64(pgMicros(ts))).uint32(7), 0x100)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	rel := msgBuilder{}.byte('R').uint32(42).string("public").string("foo").byte('d').uint16(6)
+	for _, col := range []struct {
+		name string
+		oid  uint32
+	}{
+		{"id", oidInt4}, {"ok", oidBool}, {"price", oidNumeric}, {"doc", oidJSONB}, {"name", 25}, {"blob", 25},
+	} {
+		rel = rel.byte(1).string(col.name).uint32(col.oid).uint32(0xFFFFFFFF)
+	}
+	events, err = d.Decode(rel, 0x100)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	insert := msgBuilder{}.byte('I').uint32(42).byte('N').uint16(6).
+		text("1").text("t").text("1.50").text(`{"a":[1]}`).byte('n').text("x")
+	events, err = d.Decode(insert, 0x110)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, &Event{
+		Operation:  OpInsert,
+		Schema:     "public",
+		Table:      "foo",
+		LSN:        0x110,
+		XID:        7,
+		CommitTime: ts,
+		After: map[string]interface{}{
+			"id":    int64(1),
+			"ok":    true,
+			"price": json.Number("1.50"),
+			"doc":   map[string]interface{}{"a": []interface{}{float64(1)}},
+			"name":  nil,
+			"blob":  "x",
+		},
+	}, events[0])
+
+	update := msgBuilder{}.byte('U').uint32(42).
+		byte('K').uint16(6).text("1").byte('n').byte('n').byte('n').byte('n').byte('n').
+		byte('N').uint16(6).text("2").text("f").text("NaN").text("null").text("bar").byte('u')
+	events, err = d.Decode(update, 0x120)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, OpUpdate, events[0].Operation)
+	assert.Equal(t, map[string]interface{}{
+		"id": int64(1), "ok": nil, "price": nil, "doc": nil, "name": nil, "blob": nil,
+	}, events[0].Before)
+	assert.Equal(t, map[string]interface{}{
+		"id": int64(2), "ok": false, "price": "NaN", "doc": nil, "name": "bar",
+	}, events[0].After)
+
+	events, err = d.Decode(msgBuilder{}.byte('D').uint32(42).byte('K').uint16(1).text("2"), 0x130)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, OpDelete, events[0].Operation)
+	assert.Equal(t, map[string]interface{}{"id": int64(2)}, events[0].Before)
+	assert.Nil(t, events[0].After)
+
+	events, err = d.Decode(msgBuilder{}.byte('T').uint32(1).byte(0).uint32(42), 0x140)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, OpTruncate, events[0].Operation)
+	assert.Equal(t, "foo", events[0].Table)
+
+	commit := msgBuilder{}.byte('C').byte(0).uint64(0x200).uint64(0x210).uint64(uint64(pgMicros(ts)))
+	events, err = d.Decode(commit, 0x200)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, &Event{Operation: OpCommit, LSN: 0x210, XID: 7, CommitTime: ts}, events[0])
+
+	_, err = d.Decode(msgBuilder{}.byte('I').uint32(43).byte('N').uint16(0), 0x300)
+	assert.EqualError(t, err, "received change for unknown relation: 43")
+
+	_, err = d.Decode(msgBuilder{}.byte('I').uint32(42).byte('N').uint16(1).byte('t').uint32(10), 0x300)
+	assert.EqualError(t, err, `failed to decode 'I' message: message too short`)
+}
+
+func TestWal2JSONDecode(t *testing.T) {
+	d := newWal2JSONDecoder()
+
+	events, err := d.Decode([]byte(`{"action":"B","xid":7,"lsn":"0/200","nextlsn":"0/210","timestamp":"2021-06-01 12:00:00.5+00"}`), 0x100)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	ts := time.Date(2021, 6, 1, 12, 0, 0, 500000000, time.UTC)
+
+	events, err = d.Decode([]byte(`{"action":"I","lsn":"0/110","schema":"public","table":"foo","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"a"}]}`), 0x100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, OpInsert, events[0].Operation)
+	assert.Equal(t, "public", events[0].Schema)
+	assert.Equal(t, "foo", events[0].Table)
+	assert.Equal(t, LSN(0x110), events[0].LSN)
+	assert.Equal(t, uint32(7), events[0].XID)
+	assert.True(t, ts.Equal(events[0].CommitTime))
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1"), "name": "a"}, events[0].After)
+	assert.Nil(t, events[0].Before)
+
+	events, err = d.Decode([]byte(`{"action":"U","schema":"public","table":"foo","columns":[{"name":"id","type":"integer","value":2}],"identity":[{"name":"id","type":"integer","value":1}]}`), 0x120)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, OpUpdate, events[0].Operation)
+	assert.Equal(t, LSN(0x120), events[0].LSN)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, events[0].Before)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("2")}, events[0].After)
+
+	events, err = d.Decode([]byte(`{"action":"M","transactional":false,"prefix":"foo","content":"bar"}`), 0x130)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	events, err = d.Decode([]byte(`{"action":"C","xid":7,"lsn":"0/200","nextlsn":"0/210","timestamp":"2021-06-01 12:00:00.5+00"}`), 0x200)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, OpCommit, events[0].Operation)
+	assert.Equal(t, LSN(0x210), events[0].LSN)
+
+	_, err = d.Decode([]byte(`{"action":"I","lsn":"nope"}`), 0x300)
+	assert.EqualError(t, err, "invalid lsn: nope")
+}
+
+func TestStreamConfigPluginArgs(t *testing.T) {
+	args, err := StreamConfig{Plugin: PluginPgoutput, Publications: []string{"foo", "b'ar"}}.pluginArgs()
+	require.NoError(t, err)
+	assert.Equal(t, `proto_version '1', publication_names 'foo,b''ar'`, args)
+
+	_, err = StreamConfig{Plugin: PluginPgoutput}.pluginArgs()
+	assert.Error(t, err)
+
+	args, err = StreamConfig{Plugin: PluginWal2JSON, Tables: []string{"public.foo", "public.bar"}}.pluginArgs()
+	require.NoError(t, err)
+	assert.Equal(t, `"format-version" '2', "include-lsn" 'true', "include-timestamp" 'true', "include-xids" 'true', "add-tables" 'public.foo,public.bar'`, args)
+}
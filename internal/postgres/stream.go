@@ -0,0 +1,346 @@
+package postgres
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// Output plugins that can be decoded.
+const (
+	PluginPgoutput = "pgoutput"
+	PluginWal2JSON = "wal2json"
+)
+
+// The SQLSTATE code returned when creating a slot that already exists.
+const codeDuplicateObject = "42710"
+
+var slotNameRegexp = regexp.MustCompile(`^[a-z0-9_]{1,63}$`)
+
+// StreamConfig configures a replication stream.
+type StreamConfig struct {
+	// DSN is the connection string of the database.
+	DSN string
+
+	// Slot is the name of the replication slot to consume.
+	Slot string
+
+	// CreateSlot creates the slot when it does not already exist.
+	CreateSlot bool
+
+	// Plugin is the output plugin of the slot.
+	Plugin string
+
+	// Publications are the publications to consume with pgoutput.
+	Publications []string
+
+	// Tables limits the tables consumed with wal2json, in the form
+	// schema.table.
+	Tables []string
+
+	// StatusInterval is the period between reporting the confirmed position
+	// of the stream to the server.
+	StatusInterval time.Duration
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (c StreamConfig) pluginArgs() (string, error) {
+	switch c.Plugin {
+	case PluginPgoutput:
+		if len(c.Publications) == 0 {
+			return "", errors.New("at least one publication must be specified for pgoutput")
+		}
+		return fmt.Sprintf("proto_version '1', publication_names %v", quoteLiteral(strings.Join(c.Publications, ","))), nil
+	case PluginWal2JSON:
+		args := `"format-version" '2', "include-lsn" 'true', "include-timestamp" 'true', "include-xids" 'true'`
+		if len(c.Tables) > 0 {
+			args += `, "add-tables" ` + quoteLiteral(strings.Join(c.Tables, ","))
+		}
+		return args, nil
+	}
+	return "", fmt.Errorf("plugin not recognised: %v", c.Plugin)
+}
+
+// Stream is a stream of changes from a logical replication slot.
+type Stream struct {
+	conn    *pgconn.PgConn
+	decoder decoder
+	timeout time.Duration
+
+	events chan *Event
+	err    error
+
+	// The highest position confirmed by the consumer, the position of the
+	// last commit decoded and the last position reported by the server.
+	confirmed  uint64
+	lastCommit LSN
+	walEnd     LSN
+	inTx       bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+// Open connects to a database and begins streaming changes from a replication
+// slot, resuming from the last position confirmed for the slot.
+func Open(ctx context.Context, conf StreamConfig) (*Stream, error) {
+	if !slotNameRegexp.MatchString(conf.Slot) {
+		return nil, fmt.Errorf("invalid slot name: %v", conf.Slot)
+	}
+	args, err := conf.pluginArgs()
+	if err != nil {
+		return nil, err
+	}
+	if conf.StatusInterval <= 0 {
+		return nil, errors.New("status interval must be greater than zero")
+	}
+
+	pgConf, err := pgconn.ParseConfig(conf.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
+	}
+	pgConf.RuntimeParams["replication"] = "database"
+
+	conn, err := pgconn.ConnectConfig(ctx, pgConf)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		conn:    conn,
+		timeout: conf.StatusInterval,
+		events:  make(chan *Event),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if conf.Plugin == PluginPgoutput {
+		s.decoder = newPgoutputDecoder()
+	} else {
+		s.decoder = newWal2JSONDecoder()
+	}
+
+	if err = s.start(ctx, conf, args); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *Stream) start(ctx context.Context, conf StreamConfig, args string) error {
+	if conf.CreateSlot {
+		_, err := s.conn.Exec(ctx, fmt.Sprintf("CREATE_REPLICATION_SLOT %v LOGICAL %v", conf.Slot, conf.Plugin)).ReadAll()
+		var pgErr *pgconn.PgError
+		if err != nil && !(errors.As(err, &pgErr) && pgErr.Code == codeDuplicateObject) {
+			return fmt.Errorf("failed to create slot: %w", err)
+		}
+	}
+
+	query := (&pgproto3.Query{
+		String: fmt.Sprintf("START_REPLICATION SLOT %v LOGICAL 0/0 (%v)", conf.Slot, args),
+	}).Encode(nil)
+	if err := s.conn.SendBytes(ctx, query); err != nil {
+		return err
+	}
+	for {
+		msg, err := s.conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		switch m := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			return fmt.Errorf("failed to start replication: %w", pgconn.ErrorResponseToPgError(m))
+		}
+	}
+}
+
+// statusPosition returns the position to report to the server. When every
+// decoded transaction has been confirmed the position of the server is
+// reported instead, otherwise changes to tables that are not consumed would
+// prevent the server from reclaiming the log.
+func (s *Stream) statusPosition() LSN {
+	confirmed := LSN(atomic.LoadUint64(&s.confirmed))
+	if !s.inTx && confirmed >= s.lastCommit && s.walEnd > confirmed {
+		return s.walEnd
+	}
+	return confirmed
+}
+
+func (s *Stream) sendStatus(ctx context.Context) error {
+	pos := uint64(s.statusPosition())
+
+	buf := make([]byte, 34)
+	buf[0] = 'r'
+	binary.BigEndian.PutUint64(buf[1:], pos)
+	binary.BigEndian.PutUint64(buf[9:], pos)
+	binary.BigEndian.PutUint64(buf[17:], pos)
+	binary.BigEndian.PutUint64(buf[25:], uint64(pgMicros(time.Now())))
+	return s.conn.SendBytes(ctx, (&pgproto3.CopyData{Data: buf}).Encode(nil))
+}
+
+func (s *Stream) run() {
+	defer close(s.done)
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	go func() {
+		select {
+		case <-s.closed:
+			done()
+		case <-ctx.Done():
+		}
+	}()
+
+	s.err = s.loop(ctx)
+	if ctx.Err() != nil {
+		s.err = nil
+		// Report the final confirmed position before disconnecting.
+		sCtx, sDone := context.WithTimeout(context.Background(), time.Second)
+		_ = s.sendStatus(sCtx)
+		sDone()
+	}
+	s.conn.Close(context.Background())
+}
+
+func (s *Stream) loop(ctx context.Context) error {
+	nextStatus := time.Now().Add(s.timeout)
+	for {
+		if !time.Now().Before(nextStatus) {
+			if err := s.sendStatus(ctx); err != nil {
+				return err
+			}
+			nextStatus = time.Now().Add(s.timeout)
+		}
+
+		rCtx, rDone := context.WithDeadline(ctx, nextStatus)
+		msg, err := s.conn.ReceiveMessage(rCtx)
+		rDone()
+		if err != nil {
+			if ctx.Err() == nil && pgconn.Timeout(err) {
+				continue
+			}
+			return err
+		}
+
+		var data []byte
+		switch m := msg.(type) {
+		case *pgproto3.CopyData:
+			data = m.Data
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(m)
+		case *pgproto3.CopyDone:
+			return errors.New("replication stream ended")
+		default:
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		switch data[0] {
+		case 'k':
+			if len(data) < 18 {
+				return errShortMessage
+			}
+			if end := LSN(binary.BigEndian.Uint64(data[1:])); end > s.walEnd {
+				s.walEnd = end
+			}
+			if data[17] == 1 {
+				nextStatus = time.Now()
+			}
+		case 'w':
+			if len(data) < 25 {
+				return errShortMessage
+			}
+			walStart := LSN(binary.BigEndian.Uint64(data[1:]))
+			events, err := s.decoder.Decode(data[25:], walStart)
+			if err != nil {
+				return err
+			}
+			for _, e := range events {
+				if e.Operation == OpCommit {
+					s.inTx = false
+					s.lastCommit = e.LSN
+				} else {
+					s.inTx = true
+				}
+				if nextStatus, err = s.send(ctx, e, nextStatus); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// send blocks until an event is consumed, reporting the status of the stream
+// to the server while waiting.
+func (s *Stream) send(ctx context.Context, e *Event, nextStatus time.Time) (time.Time, error) {
+	for {
+		timer := time.NewTimer(time.Until(nextStatus))
+		select {
+		case s.events <- e:
+			timer.Stop()
+			return nextStatus, nil
+		case <-timer.C:
+			if err := s.sendStatus(ctx); err != nil {
+				return nextStatus, err
+			}
+			nextStatus = time.Now().Add(s.timeout)
+		case <-ctx.Done():
+			timer.Stop()
+			return nextStatus, ctx.Err()
+		}
+	}
+}
+
+// Next blocks until the next event of the stream is received.
+func (s *Stream) Next(ctx context.Context) (*Event, error) {
+	select {
+	case e := <-s.events:
+		return e, nil
+	case <-s.done:
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, errors.New("stream closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Confirm that all changes up to and including a position have been processed,
+// which allows the server to discard the log prior to it. Positions lower than
+// those already confirmed are ignored.
+func (s *Stream) Confirm(lsn LSN) {
+	for {
+		cur := atomic.LoadUint64(&s.confirmed)
+		if uint64(lsn) <= cur || atomic.CompareAndSwapUint64(&s.confirmed, cur, uint64(lsn)) {
+			return
+		}
+	}
+}
+
+// Close the stream, reporting the confirmed position to the server before
+// disconnecting.
+func (s *Stream) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	<-s.done
+	return nil
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const wal2jsonTimeLayout = "2006-01-02 15:04:05.999999-07"
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type wal2jsonMessage struct {
+	Action    string           `json:"action"`
+	XID       uint32           `json:"xid"`
+	LSN       string           `json:"lsn"`
+	NextLSN   string           `json:"nextlsn"`
+	Timestamp string           `json:"timestamp"`
+	Schema    string           `json:"schema"`
+	Table     string           `json:"table"`
+	Columns   []wal2jsonColumn `json:"columns"`
+	Identity  []wal2jsonColumn `json:"identity"`
+}
+
+// wal2jsonDecoder decodes messages of version 2 of the wal2json format, where
+// each change is written as a separate message.
+type wal2jsonDecoder struct {
+	xid        uint32
+	commitTime time.Time
+}
+
+func newWal2JSONDecoder() *wal2jsonDecoder {
+	return &wal2jsonDecoder{}
+}
+
+func columnValues(cols []wal2jsonColumn) map[string]interface{} {
+	if cols == nil {
+		return nil
+	}
+	values := make(map[string]interface{}, len(cols))
+	for _, c := range cols {
+		values[c.Name] = c.Value
+	}
+	return values
+}
+
+func (d *wal2jsonDecoder) Decode(data []byte, walStart LSN) ([]*Event, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m wal2jsonMessage
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode wal2json message: %w", err)
+	}
+
+	// Commits are positioned at the end of the transaction.
+	lsnStr := m.LSN
+	if m.Action == "C" && m.NextLSN != "" {
+		lsnStr = m.NextLSN
+	}
+	lsn := walStart
+	if lsnStr != "" {
+		var err error
+		if lsn, err = ParseLSN(lsnStr); err != nil {
+			return nil, err
+		}
+	}
+
+	var ts time.Time
+	if m.Timestamp != "" {
+		var err error
+		if ts, err = time.Parse(wal2jsonTimeLayout, m.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+	}
+
+	e := &Event{
+		Schema:     m.Schema,
+		Table:      m.Table,
+		LSN:        lsn,
+		XID:        d.xid,
+		CommitTime: d.commitTime,
+	}
+	switch m.Action {
+	case "B":
+		d.xid, d.commitTime = m.XID, ts
+		return nil, nil
+	case "C":
+		e.Operation = OpCommit
+		if !ts.IsZero() {
+			e.CommitTime = ts
+		}
+	case "I":
+		e.Operation = OpInsert
+		e.After = columnValues(m.Columns)
+	case "U":
+		e.Operation = OpUpdate
+		e.Before = columnValues(m.Identity)
+		e.After = columnValues(m.Columns)
+	case "D":
+		e.Operation = OpDelete
+		e.Before = columnValues(m.Identity)
+	case "T":
+		e.Operation = OpTruncate
+	default:
+		// Logical decoding messages are ignored.
+		return nil, nil
+	}
+	return []*Event{e}, nil
+}
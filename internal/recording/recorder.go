@@ -0,0 +1,225 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Config contains configuration fields for recording a sample of messages to a
+// file.
+type Config struct {
+	Path        string  `json:"path" yaml:"path"`
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"`
+	Redact      string  `json:"redact" yaml:"redact"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Path:        "",
+		SampleRatio: 1,
+		Redact:      "",
+	}
+}
+
+// FieldSpecs returns documentation specs for recording fields.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("path", "The path of the recording file, which is appended to when it already exists. Recordings with the extension `.gz` are compressed with gzip.", "./recording.jsonl", "./recording.jsonl.gz"),
+		docs.FieldCommon("sample_ratio", "The ratio of messages, between `0` and `1`, that are recorded.", 0.01),
+		docs.FieldCommon(
+			"redact", "An optional [Bloblang mapping](/docs/guides/bloblang/about) applied to the copy of each sampled message before it is recorded, which can remove or mask sensitive data. The mapping is able to modify the metadata of the copy, and copies that are deleted by the mapping are not recorded.",
+			`root = this
+root.user.email = deleted()
+meta authorization = deleted()`,
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// recordingFile is an open recording that is shared by each Recorder of the
+// same path, so that records written from separate components aren't
+// interleaved.
+type recordingFile struct {
+	path string
+	refs int
+
+	mut  sync.Mutex
+	file *os.File
+	w    *Writer
+}
+
+var (
+	openFilesMut sync.Mutex
+	openFiles    = map[string]*recordingFile{}
+)
+
+func openRecordingFile(path string) (*recordingFile, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	openFilesMut.Lock()
+	defer openFilesMut.Unlock()
+
+	if f, exists := openFiles[absPath]; exists {
+		f.refs++
+		return f, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(absPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f := &recordingFile{
+		path: absPath,
+		refs: 1,
+		file: file,
+		w:    NewWriter(file, IsCompressed(absPath)),
+	}
+	openFiles[absPath] = f
+	return f, nil
+}
+
+func (f *recordingFile) release() error {
+	openFilesMut.Lock()
+	defer openFilesMut.Unlock()
+
+	if f.refs--; f.refs > 0 {
+		return nil
+	}
+	delete(openFiles, f.path)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	err := f.w.Close()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// Recorder appends a sample of messages to a recording file, and is safe to use
+// from multiple goroutines. Recorders of the same path share the file.
+type Recorder struct {
+	sampleRatio float64
+	redact      *mapping.Executor
+
+	mut    sync.Mutex
+	rng    *rand.Rand
+	file   *recordingFile
+	closed bool
+}
+
+// NewRecorder opens the recording file of a Config for appending and returns a
+// Recorder that writes to it.
+func (c Config) NewRecorder() (*Recorder, error) {
+	if c.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample_ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	r := &Recorder{
+		sampleRatio: c.SampleRatio,
+		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if c.Redact != "" {
+		var err error
+		if r.redact, err = bloblang.NewMapping("", c.Redact); err != nil {
+			return nil, fmt.Errorf("failed to parse redact mapping: %w", err)
+		}
+	}
+	var err error
+	if r.file, err = openRecordingFile(c.Path); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) sampled() bool {
+	if r.sampleRatio >= 1 {
+		return true
+	}
+	return r.rng.Float64() < r.sampleRatio
+}
+
+// Record writes a redacted copy of each sampled message of a batch to the
+// recording, returning the number of messages recorded. Messages that fail to
+// be redacted are not recorded, and the last redaction error is returned after
+// the remaining messages have been recorded.
+func (r *Recorder) Record(msg types.Message) (int, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	now := time.Now()
+	var records []Record
+	var redactErr error
+	for i := 0; i < msg.Len(); i++ {
+		if !r.sampled() {
+			continue
+		}
+		part := msg.Get(i)
+		if r.redact != nil {
+			var err error
+			if part, err = r.redact.MapPart(i, msg); err != nil {
+				redactErr = fmt.Errorf("failed to redact message: %w", err)
+				continue
+			}
+			if part == nil {
+				continue
+			}
+		}
+		records = append(records, NewRecord(part, now))
+	}
+	if len(records) == 0 {
+		return 0, redactErr
+	}
+
+	r.file.mut.Lock()
+	defer r.file.mut.Unlock()
+
+	for i, rec := range records {
+		if err := r.file.w.Write(rec); err != nil {
+			return i, err
+		}
+	}
+	if err := r.file.w.Flush(); err != nil {
+		return len(records), err
+	}
+	return len(records), redactErr
+}
+
+// Close releases the recording file, which is closed once every Recorder of
+// its path is closed.
+func (r *Recorder) Close() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.file.release()
+}
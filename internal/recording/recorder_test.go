@@ -0,0 +1,123 @@
+package recording
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	r, err := NewReader(file, IsCompressed(path))
+	require.NoError(t, err)
+	defer r.Close()
+
+	var records []Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestRecorderRedact(t *testing.T) {
+	conf := NewConfig()
+	conf.Path = filepath.Join(t.TempDir(), "nested", "recording.jsonl")
+	conf.Redact = `root = if this.drop == true { deleted() } else { this.without("secret") }
+meta token = deleted()`
+
+	r, err := conf.NewRecorder()
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":1,"secret":"foo"}`),
+		[]byte(`{"id":2,"drop":true}`),
+		[]byte(`not json`),
+		[]byte(`{"id":3}`),
+	})
+	msg.Get(0).Metadata().Set("token", "bar").Set("topic", "baz")
+
+	recorded, err := r.Record(msg)
+	assert.Equal(t, 2, recorded)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to redact message")
+	require.NoError(t, r.Close())
+
+	// The original messages are not modified.
+	assert.Equal(t, `{"id":1,"secret":"foo"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, "bar", msg.Get(0).Metadata().Get("token"))
+
+	records := readRecords(t, conf.Path)
+	require.Len(t, records, 2)
+	assert.Equal(t, `{"id":1}`, string(records[0].Content))
+	assert.Equal(t, map[string]string{"topic": "baz"}, records[0].Metadata)
+	assert.Equal(t, `{"id":3}`, string(records[1].Content))
+
+	_, err = r.Record(msg)
+	assert.Error(t, err)
+}
+
+func TestRecorderSharedFile(t *testing.T) {
+	conf := NewConfig()
+	conf.Path = filepath.Join(t.TempDir(), "recording.jsonl.gz")
+
+	a, err := conf.NewRecorder()
+	require.NoError(t, err)
+	b, err := conf.NewRecorder()
+	require.NoError(t, err)
+
+	_, err = a.Record(message.New([][]byte{[]byte("foo")}))
+	require.NoError(t, err)
+	_, err = b.Record(message.New([][]byte{[]byte("bar")}))
+	require.NoError(t, err)
+	require.NoError(t, a.Close())
+
+	_, err = b.Record(message.New([][]byte{[]byte("baz")}))
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	// Reopened recordings are appended to.
+	c, err := conf.NewRecorder()
+	require.NoError(t, err)
+	_, err = c.Record(message.New([][]byte{[]byte("qux")}))
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	var contents []string
+	for _, rec := range readRecords(t, conf.Path) {
+		contents = append(contents, string(rec.Content))
+	}
+	assert.Equal(t, []string{"foo", "bar", "baz", "qux"}, contents)
+}
+
+func TestRecorderSample(t *testing.T) {
+	conf := NewConfig()
+	conf.Path = filepath.Join(t.TempDir(), "recording.jsonl")
+	conf.SampleRatio = 0
+
+	r, err := conf.NewRecorder()
+	require.NoError(t, err)
+
+	recorded, err := r.Record(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, recorded)
+	require.NoError(t, r.Close())
+	assert.Empty(t, readRecords(t, conf.Path))
+
+	conf.SampleRatio = 1.5
+	_, err = conf.NewRecorder()
+	require.EqualError(t, err, "sample_ratio must be between 0 and 1, got 1.5")
+}
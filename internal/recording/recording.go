@@ -0,0 +1,164 @@
+// Package recording implements a file format for recordings of messages, where
+// each message is written as a line of JSON along with its metadata and the
+// time at which it was recorded, so that it can later be replayed with its
+// original timing.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+const gzipSuffix = ".gz"
+
+// IsCompressed returns whether a recording at a path is compressed with gzip,
+// which is determined by its extension.
+func IsCompressed(path string) bool {
+	return strings.HasSuffix(path, gzipSuffix)
+}
+
+// Record is a single recorded message.
+type Record struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Content   []byte            `json:"content"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// NewRecord creates a record of a message part recorded at a given time.
+func NewRecord(p types.Part, t time.Time) Record {
+	r := Record{
+		Timestamp: t,
+		Content:   p.Get(),
+	}
+	p.Metadata().Iter(func(k, v string) error {
+		if r.Metadata == nil {
+			r.Metadata = map[string]string{}
+		}
+		r.Metadata[k] = v
+		return nil
+	})
+	return r
+}
+
+// Part returns the recorded message as a message part.
+func (r Record) Part() types.Part {
+	part := message.NewPart(r.Content)
+	for k, v := range r.Metadata {
+		part.Metadata().Set(k, v)
+	}
+	return part
+}
+
+//------------------------------------------------------------------------------
+
+// Writer writes records to a recording.
+type Writer struct {
+	gw  *gzip.Writer
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewWriter creates a writer of records, which are compressed with gzip when
+// compress is true. The writer must be flushed or closed in order for records
+// to reach the underlying writer.
+func NewWriter(w io.Writer, compress bool) *Writer {
+	rw := &Writer{}
+	if compress {
+		rw.gw = gzip.NewWriter(w)
+		w = rw.gw
+	}
+	rw.buf = bufio.NewWriter(w)
+	rw.enc = json.NewEncoder(rw.buf)
+	return rw
+}
+
+// Write appends a record to the recording.
+func (w *Writer) Write(r Record) error {
+	return w.enc.Encode(r)
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gw != nil {
+		return w.gw.Flush()
+	}
+	return nil
+}
+
+// Close flushes any buffered records and ends the recording, but does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gw != nil {
+		return w.gw.Close()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Reader reads the records of a recording in the order they were written.
+type Reader struct {
+	r     *bufio.Reader
+	line  int
+	close func() error
+}
+
+// NewReader creates a reader of records, which are decompressed with gzip when
+// compressed is true.
+func NewReader(r io.Reader, compressed bool) (*Reader, error) {
+	rr := &Reader{close: func() error { return nil }}
+	if compressed {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compressed recording: %w", err)
+		}
+		r, rr.close = gr, gr.Close
+	}
+	rr.r = bufio.NewReader(r)
+	return rr, nil
+}
+
+// Next returns the next record of the recording, or io.EOF once all records
+// have been read.
+func (r *Reader) Next() (Record, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return Record{}, err
+		}
+		r.line++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec Record
+		if jerr := json.Unmarshal(line, &rec); jerr != nil {
+			return Record{}, fmt.Errorf("failed to parse record at line %v: %w", r.line, jerr)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Record{}, err
+		}
+		return rec, nil
+	}
+}
+
+// Close releases the resources of the reader, but does not close the
+// underlying reader.
+func (r *Reader) Close() error {
+	return r.close()
+}
@@ -0,0 +1,75 @@
+package recording
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Date(2021, 5, 21, 13, 0, 0, 500, time.UTC)
+
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, compress)
+
+		part := message.NewPart([]byte("foo"))
+		part.Metadata().Set("bar", "baz")
+		require.NoError(t, w.Write(NewRecord(part, ts)))
+		require.NoError(t, w.Write(NewRecord(message.NewPart([]byte{0xff, 0x00}), ts.Add(time.Second))))
+		require.NoError(t, w.Close())
+
+		r, err := NewReader(&buf, compress)
+		require.NoError(t, err)
+
+		rec, err := r.Next()
+		require.NoError(t, err)
+		assert.True(t, ts.Equal(rec.Timestamp))
+		assert.Equal(t, "foo", string(rec.Part().Get()))
+		assert.Equal(t, "baz", rec.Part().Metadata().Get("bar"))
+
+		rec, err = r.Next()
+		require.NoError(t, err)
+		assert.True(t, ts.Add(time.Second).Equal(rec.Timestamp))
+		assert.Equal(t, []byte{0xff, 0x00}, rec.Part().Get())
+
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestReaderFormats(t *testing.T) {
+	r, err := NewReader(bytes.NewReader([]byte(`{"timestamp":"2021-05-21T13:00:00Z","content":"Zm9v"}
+
+{"timestamp":"2021-05-21T13:00:01Z","content":"YmFy"}`)), false)
+	require.NoError(t, err)
+
+	for _, exp := range []string{"foo", "bar"} {
+		rec, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(rec.Content))
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	r, err = NewReader(bytes.NewReader([]byte("{\"content\":\"Zm9v\"}\nnope\n")), false)
+	require.NoError(t, err)
+
+	_, err = r.Next()
+	require.NoError(t, err)
+	_, err = r.Next()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse record at line 2")
+
+	_, err = NewReader(bytes.NewReader([]byte("nope")), true)
+	require.Error(t, err)
+
+	assert.True(t, IsCompressed("foo.jsonl.gz"))
+	assert.False(t, IsCompressed("foo.jsonl"))
+}
@@ -0,0 +1,52 @@
+package scaleproto
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"go.nanomsg.org/mangos/v3"
+)
+
+// SplitURLs expands a list of URLs where items may contain comma separated
+// URLs.
+func SplitURLs(urls []string) []string {
+	var split []string
+	for _, u := range urls {
+		for _, splitU := range strings.Split(u, ",") {
+			if len(splitU) > 0 {
+				// TODO: V4 Remove this work around
+				split = append(split, strings.Replace(splitU, "//*:", "//0.0.0.0:", 1))
+			}
+		}
+	}
+	return split
+}
+
+// IsTLSURL returns true if the URL uses a transport that supports TLS, which
+// are tls+tcp and wss.
+func IsTLSURL(u string) bool {
+	return strings.HasPrefix(u, "tls+tcp://") || strings.HasPrefix(u, "wss://")
+}
+
+// Connect either binds or dials a socket to each of a list of URLs. When a TLS
+// config is provided it is used for URLs with a TLS transport.
+func Connect(socket mangos.Socket, urls []string, bind bool, tlsConf *tls.Config) error {
+	for _, u := range urls {
+		var opts map[string]interface{}
+		if tlsConf != nil && IsTLSURL(u) {
+			opts = map[string]interface{}{
+				mangos.OptionTLSConfig: tlsConf,
+			}
+		}
+		var err error
+		if bind {
+			err = socket.ListenOptions(u, opts)
+		} else {
+			err = socket.DialOptions(u, opts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
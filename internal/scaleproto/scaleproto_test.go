@@ -0,0 +1,93 @@
+package scaleproto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nanomsg.org/mangos/v3"
+	"go.nanomsg.org/mangos/v3/protocol/pull"
+	"go.nanomsg.org/mangos/v3/protocol/push"
+
+	_ "go.nanomsg.org/mangos/v3/transport/all"
+)
+
+func TestSplitURLs(t *testing.T) {
+	assert.Equal(t, []string{
+		"tcp://0.0.0.0:5555",
+		"tcp://localhost:5556",
+		"tls+tcp://localhost:5557",
+	}, SplitURLs([]string{
+		"tcp://*:5555,tcp://localhost:5556",
+		"",
+		"tls+tcp://localhost:5557",
+	}))
+}
+
+func TestConnectTLS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	certTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	serverConf := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+	}
+	clientConf := &tls.Config{RootCAs: roots}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	pullSock, err := pull.NewSocket()
+	require.NoError(t, err)
+	defer pullSock.Close()
+	require.NoError(t, pullSock.SetOption(mangos.OptionRecvDeadline, time.Second*5))
+	require.NoError(t, Connect(pullSock, []string{"tls+tcp://" + addr}, true, serverConf))
+
+	pushSock, err := push.NewSocket()
+	require.NoError(t, err)
+	defer pushSock.Close()
+	require.NoError(t, Connect(pushSock, []string{"tls+tcp://" + addr}, false, clientConf))
+
+	require.NoError(t, pushSock.Send([]byte("hello world")))
+
+	data, err := pullSock.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+}
+
+func TestConnectTLSIgnoredForPlainTransports(t *testing.T) {
+	sock, err := pull.NewSocket()
+	require.NoError(t, err)
+	defer sock.Close()
+
+	require.NoError(t, Connect(sock, []string{"inproc://benthos_scaleproto_plain_test"}, true, &tls.Config{}))
+}
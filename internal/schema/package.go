@@ -0,0 +1,3 @@
+// Package schema implements the inference of JSON schemas from observed
+// documents, and the detection of documents that drift from them.
+package schema
@@ -0,0 +1,412 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Types of values described by a schema, matching the types of JSON Schema.
+const (
+	TypeArray   = "array"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeNull    = "null"
+	TypeNumber  = "number"
+	TypeObject  = "object"
+	TypeString  = "string"
+)
+
+// Draft is the JSON Schema draft that marshalled schemas declare.
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema describes the structure of JSON documents as the subset of JSON
+// Schema consisting of types, object properties, required properties and
+// array items.
+type Schema struct {
+	Types      []string
+	Properties map[string]*Schema
+	Required   []string
+	Items      *Schema
+
+	// Counts of observed objects and their properties, which determine the
+	// required properties of an inferred schema.
+	objects    int
+	properties map[string]int
+}
+
+// New returns an empty schema, which can be inferred by observing documents.
+func New() *Schema {
+	return &Schema{}
+}
+
+// TypeOf returns the schema type of a generic JSON value.
+func TypeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case string, []byte:
+		return TypeString
+	case int, int32, int64, uint, uint32, uint64:
+		return TypeInteger
+	case float32:
+		return numberType(float64(t))
+	case float64:
+		return numberType(t)
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return TypeInteger
+		}
+		if f, err := t.Float64(); err == nil {
+			return numberType(f)
+		}
+		return TypeNumber
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}:
+		return TypeObject
+	}
+	// Other numerical types, such as decimals, implement json.Marshaler.
+	if m, ok := v.(json.Marshaler); ok {
+		if b, err := m.MarshalJSON(); err == nil && len(b) > 0 && (b[0] == '-' || (b[0] >= '0' && b[0] <= '9')) {
+			return TypeOf(json.Number(b))
+		}
+	}
+	return TypeString
+}
+
+func numberType(f float64) string {
+	if f == math.Trunc(f) && !math.IsInf(f, 0) {
+		return TypeInteger
+	}
+	return TypeNumber
+}
+
+func (s *Schema) addType(t string) {
+	if s.hasType(t) {
+		return
+	}
+	if t == TypeInteger && s.hasType(TypeNumber) {
+		return
+	}
+	if t == TypeNumber {
+		s.removeType(TypeInteger)
+	}
+	s.Types = append(s.Types, t)
+	sort.Strings(s.Types)
+}
+
+func (s *Schema) hasType(t string) bool {
+	for _, st := range s.Types {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) removeType(t string) {
+	for i, st := range s.Types {
+		if st == t {
+			s.Types = append(s.Types[:i], s.Types[i+1:]...)
+			return
+		}
+	}
+}
+
+// allows returns whether a value of a type conforms to the types of the
+// schema, where integers conform to numbers. A schema without types allows
+// all values.
+func (s *Schema) allows(t string) bool {
+	if len(s.Types) == 0 || s.hasType(t) {
+		return true
+	}
+	return t == TypeInteger && s.hasType(TypeNumber)
+}
+
+//------------------------------------------------------------------------------
+
+// Observe a document and widen the schema in order to describe it. A property
+// of an object is required only when it has been present in all observed
+// objects.
+func (s *Schema) Observe(v interface{}) {
+	t := TypeOf(v)
+	s.addType(t)
+
+	switch t {
+	case TypeObject:
+		obj, _ := v.(map[string]interface{})
+		if s.Properties == nil {
+			s.Properties = map[string]*Schema{}
+		}
+		if s.properties == nil {
+			s.properties = map[string]int{}
+			for _, k := range s.Required {
+				s.properties[k] = s.objects
+			}
+		}
+		s.objects++
+		for k, pv := range obj {
+			prop, exists := s.Properties[k]
+			if !exists {
+				prop = New()
+				s.Properties[k] = prop
+			}
+			prop.Observe(pv)
+			s.properties[k]++
+		}
+		s.Required = s.Required[:0]
+		for k, n := range s.properties {
+			if n == s.objects {
+				s.Required = append(s.Required, k)
+			}
+		}
+		sort.Strings(s.Required)
+	case TypeArray:
+		arr, _ := v.([]interface{})
+		for _, e := range arr {
+			if s.Items == nil {
+				s.Items = New()
+			}
+			s.Items.Observe(e)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Drift describes a way in which a document does not conform to a schema.
+type Drift struct {
+	// Path is the dot separated path of the drifting value, where array
+	// elements are referenced by their index.
+	Path string
+
+	// Kind is either "type", "missing" or "new".
+	Kind string
+
+	// Description is a human readable description of the drift.
+	Description string
+}
+
+// Drift kinds.
+const (
+	DriftType    = "type"
+	DriftMissing = "missing"
+	DriftNew     = "new"
+)
+
+// CheckOptions customise which differences between a document and a schema
+// are considered drift.
+type CheckOptions struct {
+	AllowNewFields     bool
+	AllowMissingFields bool
+}
+
+// Check a document against the schema and return the ways in which it drifts,
+// or nothing if the document conforms.
+func (s *Schema) Check(v interface{}, opts CheckOptions) []Drift {
+	var drifts []Drift
+	s.check("", v, opts, &drifts)
+	return drifts
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
+
+func (s *Schema) check(path string, v interface{}, opts CheckOptions, drifts *[]Drift) {
+	t := TypeOf(v)
+	if !s.allows(t) {
+		*drifts = append(*drifts, Drift{
+			Path:        path,
+			Kind:        DriftType,
+			Description: fmt.Sprintf("%v has type %v, expected %v", displayPath(path), t, strings.Join(s.Types, " or ")),
+		})
+		return
+	}
+
+	switch t {
+	case TypeObject:
+		obj, _ := v.(map[string]interface{})
+		if !opts.AllowMissingFields {
+			for _, k := range s.Required {
+				if _, exists := obj[k]; !exists {
+					p := joinPath(path, k)
+					*drifts = append(*drifts, Drift{
+						Path:        p,
+						Kind:        DriftMissing,
+						Description: fmt.Sprintf("%v is missing", p),
+					})
+				}
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := joinPath(path, k)
+			prop, exists := s.Properties[k]
+			if !exists {
+				if !opts.AllowNewFields {
+					*drifts = append(*drifts, Drift{
+						Path:        p,
+						Kind:        DriftNew,
+						Description: fmt.Sprintf("%v is new", p),
+					})
+				}
+				continue
+			}
+			prop.check(p, obj[k], opts, drifts)
+		}
+	case TypeArray:
+		if s.Items == nil {
+			return
+		}
+		arr, _ := v.([]interface{})
+		for i, e := range arr {
+			s.Items.check(joinPath(path, fmt.Sprintf("%v", i)), e, opts, drifts)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (s *Schema) toMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		m["type"] = s.Types[0]
+	default:
+		types := make([]interface{}, len(s.Types))
+		for i, t := range s.Types {
+			types[i] = t
+		}
+		m["type"] = types
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]interface{}, len(s.Properties))
+		for k, p := range s.Properties {
+			props[k] = p.toMap()
+		}
+		m["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		required := make([]interface{}, len(s.Required))
+		for i, k := range s.Required {
+			required[i] = k
+		}
+		m["required"] = required
+	}
+	if s.Items != nil {
+		m["items"] = s.Items.toMap()
+	}
+	return m
+}
+
+// MarshalJSON returns the schema as a JSON Schema document.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	m := s.toMap()
+	m["$schema"] = Draft
+	return json.Marshal(m)
+}
+
+// Parse a JSON Schema document into a schema. Only the keywords type,
+// properties, required and items are supported, and other keywords are
+// ignored.
+func Parse(b []byte) (*Schema, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return fromMap("", m)
+}
+
+func fromMap(path string, m map[string]interface{}) (*Schema, error) {
+	s := New()
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.Types = []string{t}
+	case []interface{}:
+		for _, e := range t {
+			str, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v: expected type to be a string or an array of strings", displayPath(path))
+			}
+			s.Types = append(s.Types, str)
+		}
+		sort.Strings(s.Types)
+	default:
+		return nil, fmt.Errorf("%v: expected type to be a string or an array of strings", displayPath(path))
+	}
+	for _, t := range s.Types {
+		switch t {
+		case TypeArray, TypeBoolean, TypeInteger, TypeNull, TypeNumber, TypeObject, TypeString:
+		default:
+			return nil, fmt.Errorf("%v: unrecognised type: %v", displayPath(path), t)
+		}
+	}
+
+	if props, exists := m["properties"]; exists {
+		propsMap, ok := props.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v: expected properties to be an object", displayPath(path))
+		}
+		s.Properties = make(map[string]*Schema, len(propsMap))
+		for k, p := range propsMap {
+			pMap, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%v: expected schema to be an object", joinPath(path, k))
+			}
+			prop, err := fromMap(joinPath(path, k), pMap)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[k] = prop
+		}
+	}
+
+	if required, exists := m["required"]; exists {
+		reqArr, ok := required.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v: expected required to be an array of strings", displayPath(path))
+		}
+		for _, r := range reqArr {
+			str, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v: expected required to be an array of strings", displayPath(path))
+			}
+			s.Required = append(s.Required, str)
+		}
+		sort.Strings(s.Required)
+	}
+
+	if items, exists := m["items"]; exists {
+		itemsMap, ok := items.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v: expected items to be an object", displayPath(path))
+		}
+		var err error
+		if s.Items, err = fromMap(joinPath(path, "items"), itemsMap); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
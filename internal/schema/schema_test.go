@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseDoc(t *testing.T, s string) interface{} {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	require.NoError(t, dec.Decode(&v))
+	return v
+}
+
+func TestInferSchema(t *testing.T) {
+	s := New()
+	s.Observe(parseDoc(t, `{"id":1,"name":"foo","tags":["a"],"price":1,"nested":{"a":true}}`))
+	s.Observe(parseDoc(t, `{"id":2,"name":null,"tags":[],"price":1.5,"nested":{"a":false,"b":"x"}}`))
+	s.Observe(parseDoc(t, `{"id":3,"name":"bar","price":2,"nested":{"a":true}}`))
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["id","name","nested","price"],
+  "properties": {
+    "id": {"type":"integer"},
+    "name": {"type":["null","string"]},
+    "price": {"type":"number"},
+    "tags": {"type":"array","items":{"type":"string"}},
+    "nested": {
+      "type": "object",
+      "required": ["a"],
+      "properties": {
+        "a": {"type":"boolean"},
+        "b": {"type":"string"}
+      }
+    }
+  }
+}`, string(b))
+
+	parsed, err := Parse(b)
+	require.NoError(t, err)
+	reparsed, err := json.Marshal(parsed)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(b), string(reparsed))
+}
+
+func TestCheckSchema(t *testing.T) {
+	s := New()
+	s.Observe(parseDoc(t, `{"id":1,"name":"foo","price":1.5,"items":[{"sku":"a"}],"opt":"x"}`))
+	s.Observe(parseDoc(t, `{"id":2,"name":"bar","price":2,"items":[]}`))
+
+	tests := map[string]struct {
+		doc    string
+		opts   CheckOptions
+		drifts []string
+	}{
+		"conforms": {
+			doc: `{"id":3,"name":"baz","price":3,"items":[{"sku":"b"}]}`,
+		},
+		"integer conforms to number": {
+			doc: `{"id":3,"name":"baz","price":3,"items":[]}`,
+		},
+		"wrong type": {
+			doc:    `{"id":"3","name":"baz","price":3.5,"items":[{"sku":5}]}`,
+			drifts: []string{"id has type string, expected integer", "items.0.sku has type integer, expected string"},
+		},
+		"missing and new": {
+			doc:    `{"id":3,"price":3,"items":[],"extra":true}`,
+			drifts: []string{"name is missing", "extra is new"},
+		},
+		"missing and new allowed": {
+			doc:  `{"id":3,"price":3,"items":[],"extra":true}`,
+			opts: CheckOptions{AllowNewFields: true, AllowMissingFields: true},
+		},
+		"not an object": {
+			doc:    `[1,2]`,
+			drifts: []string{"root has type array, expected object"},
+		},
+	}
+
+	for name, test := range tests {
+		var descs []string
+		for _, d := range s.Check(parseDoc(t, test.doc), test.opts) {
+			descs = append(descs, d.Description)
+		}
+		assert.Equal(t, test.drifts, descs, name)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		err   string
+	}{
+		"bad type":       {input: `{"type":"thing"}`, err: "root: unrecognised type: thing"},
+		"bad properties": {input: `{"properties":[]}`, err: "root: expected properties to be an object"},
+		"bad nested":     {input: `{"properties":{"a":{"type":5}}}`, err: "a: expected type to be a string or an array of strings"},
+		"bad required":   {input: `{"required":[5]}`, err: "root: expected required to be an array of strings"},
+		"bad items":      {input: `{"items":true}`, err: "root: expected items to be an object"},
+		"not json":       {input: `nope`, err: "invalid character 'o' in literal null (expecting 'u')"},
+	}
+
+	for name, test := range tests {
+		_, err := Parse([]byte(test.input))
+		require.EqualError(t, err, test.err, name)
+	}
+}
@@ -0,0 +1,185 @@
+package confluent
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/x/service"
+)
+
+// schemaReference is a reference from one schema to another, where the name is
+// how the referenced schema is imported by the referencing schema.
+type schemaReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// schemaInfo is a schema as returned by the registry, an empty type implies an
+// Avro schema.
+type schemaInfo struct {
+	ID         int               `json:"id"`
+	Type       string            `json:"schemaType"`
+	Schema     string            `json:"schema"`
+	References []schemaReference `json:"references"`
+}
+
+type schemaRegistryClient struct {
+	base   *url.URL
+	client *http.Client
+	logger *service.Logger
+}
+
+func newSchemaRegistryClient(urlStr string, tlsConf *tls.Config, logger *service.Logger) (*schemaRegistryClient, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	c := &schemaRegistryClient{
+		base:   u,
+		client: http.DefaultClient,
+		logger: logger,
+	}
+	if tlsConf != nil {
+		c.client = &http.Client{}
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			c.client.Transport = cloned
+		} else {
+			c.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return c, nil
+}
+
+// getSchemaByID obtains a schema by its global ID.
+func (c *schemaRegistryClient) getSchemaByID(ctx context.Context, id int) (schemaInfo, error) {
+	var info schemaInfo
+	if err := c.get(ctx, fmt.Sprintf("schema '%v'", id), &info, "schemas", "ids", strconv.Itoa(id)); err != nil {
+		return info, err
+	}
+	// The ID isn't included in responses to this endpoint.
+	info.ID = id
+	return info, nil
+}
+
+// getSchemaBySubjectAndVersion obtains a version of the schema of a subject,
+// or the latest version when the version is nil.
+func (c *schemaRegistryClient) getSchemaBySubjectAndVersion(ctx context.Context, subject string, version *int) (schemaInfo, error) {
+	versionStr := "latest"
+	if version != nil {
+		versionStr = strconv.Itoa(*version)
+	}
+	var info schemaInfo
+	err := c.get(ctx, fmt.Sprintf("subject '%v' version '%v'", subject, versionStr), &info, "subjects", subject, "versions", versionStr)
+	return info, err
+}
+
+// walkReferences calls fn for each schema referenced by refs, including schemas
+// referenced by those schemas, with the name each schema is referenced by.
+func (c *schemaRegistryClient) walkReferences(ctx context.Context, refs []schemaReference, fn func(name string, info schemaInfo) error) error {
+	seen := map[string]struct{}{}
+	var walk func(refs []schemaReference) error
+	walk = func(refs []schemaReference) error {
+		for _, ref := range refs {
+			if _, exists := seen[ref.Name]; exists {
+				continue
+			}
+			seen[ref.Name] = struct{}{}
+
+			version := ref.Version
+			info, err := c.getSchemaBySubjectAndVersion(ctx, ref.Subject, &version)
+			if err != nil {
+				return err
+			}
+			if err := fn(ref.Name, info); err != nil {
+				return err
+			}
+			if err := walk(info.References); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(refs)
+}
+
+func (c *schemaRegistryClient) get(ctx context.Context, desc string, v interface{}, elems ...string) error {
+	ctx, done := context.WithTimeout(ctx, time.Second*5)
+	defer done()
+
+	escaped := make([]string, len(elems))
+	for i, e := range elems {
+		escaped[i] = url.PathEscape(e)
+	}
+	u := *c.base
+	u.RawPath = path.Join(append([]string{c.base.EscapedPath()}, escaped...)...)
+	u.Path = path.Join(append([]string{c.base.Path}, elems...)...)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+
+	var resBytes []byte
+	for i := 0; i < 3; i++ {
+		var res *http.Response
+		if res, err = c.client.Do(req); err != nil {
+			c.logger.Errorf("request failed for %v: %v", desc, err)
+			continue
+		}
+
+		if res.StatusCode == http.StatusNotFound {
+			res.Body.Close()
+			err = fmt.Errorf("%v not found by registry", desc)
+			c.logger.Errorf(err.Error())
+			break
+		}
+
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			err = fmt.Errorf("request failed for %v", desc)
+			c.logger.Errorf(err.Error())
+			// TODO: Best attempt at parsing out the body
+			continue
+		}
+
+		if res.Body == nil {
+			c.logger.Errorf("request for %v returned an empty body", desc)
+			err = errors.New("schema request returned an empty body")
+			continue
+		}
+
+		resBytes, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			c.logger.Errorf("failed to read response for %v: %v", desc, err)
+			continue
+		}
+
+		break
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(resBytes, v); err != nil {
+		c.logger.Errorf("failed to parse response for %v: %v", desc, err)
+		return err
+	}
+	return nil
+}
@@ -4,19 +4,14 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/public/x/service"
-	"github.com/linkedin/goavro/v2"
 )
 
 func schemaRegistryDecoderConfig() *service.ConfigSpec {
@@ -27,7 +22,9 @@ func schemaRegistryDecoderConfig() *service.ConfigSpec {
 		Description(`
 Decodes messages automatically from a schema stored within a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html) by extracting a schema ID from the message and obtaining the associated schema from the registry. If a message fails to match against the schema then it will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
 
-Currently only Avro schemas are supported.`).
+Avro, Protobuf and JSON schemas are supported. Avro messages are decoded into structured documents, Protobuf messages are converted into JSON documents following the [JSON mapping of Protobuf](https://developers.google.com/protocol-buffers/docs/proto3#json), and JSON messages are validated against their schema and are otherwise left unchanged. Schema references are only supported for Protobuf schemas.
+
+Schemas are cached once obtained and are removed from the cache when unused for ten minutes.`).
 		Field(service.NewStringField("url").Description("The base URL of the schema registry service.")).
 		Field(service.NewTLSField("tls"))
 }
@@ -55,8 +52,7 @@ func init() {
 //------------------------------------------------------------------------------
 
 type schemaRegistryDecoder struct {
-	surl   string
-	client *http.Client
+	client *schemaRegistryClient
 
 	schemas    map[int]*cachedSchemaDecoder
 	cacheMut   sync.RWMutex
@@ -67,33 +63,18 @@ type schemaRegistryDecoder struct {
 }
 
 func newSchemaRegistryDecoder(urlStr string, tlsConf *tls.Config, logger *service.Logger) (*schemaRegistryDecoder, error) {
-	u, err := url.Parse(urlStr)
+	client, err := newSchemaRegistryClient(urlStr, tlsConf, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse url: %w", err)
+		return nil, err
 	}
-	u.Path = "/schemas/ids/"
 
 	s := &schemaRegistryDecoder{
-		surl:    u.String() + "%v",
+		client:  client,
 		schemas: map[int]*cachedSchemaDecoder{},
 		shutSig: shutdown.NewSignaller(),
 		logger:  logger,
 	}
 
-	s.client = http.DefaultClient
-	if tlsConf != nil {
-		s.client = &http.Client{}
-		if c, ok := http.DefaultTransport.(*http.Transport); ok {
-			cloned := c.Clone()
-			cloned.TLSClientConfig = tlsConf
-			s.client.Transport = cloned
-		} else {
-			s.client.Transport = &http.Transport{
-				TLSClientConfig: tlsConf,
-			}
-		}
-	}
-
 	go func() {
 		for {
 			select {
@@ -147,8 +128,6 @@ func (s *schemaRegistryDecoder) Close(ctx context.Context) error {
 
 //------------------------------------------------------------------------------
 
-type schemaDecoder func(m *service.Message) error
-
 type cachedSchemaDecoder struct {
 	lastUsedUnixSeconds int64
 	decoder             schemaDecoder
@@ -159,6 +138,10 @@ func extractID(b []byte) (id int, remaining []byte, err error) {
 		err = errors.New("message is empty")
 		return
 	}
+	if len(b) < 5 {
+		err = errors.New("message is too short to contain a schema ID")
+		return
+	}
 	if b[0] != 0 {
 		err = fmt.Errorf("serialization format version number %v not supported", uint8(b[0]))
 		return
@@ -222,79 +205,17 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
 	defer done()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(s.surl, id), nil)
+	info, err := s.client.getSchemaByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
-
-	var resBytes []byte
-	for i := 0; i < 3; i++ {
-		var res *http.Response
-		if res, err = s.client.Do(req); err != nil {
-			s.logger.Errorf("request failed for schema '%v': %v", id, err)
-			continue
-		}
-
-		if res.StatusCode == http.StatusNotFound {
-			err = fmt.Errorf("schema '%v' not found by registry", id)
-			s.logger.Errorf(err.Error())
-			break
-		}
-
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("request failed for schema '%v'", id)
-			s.logger.Errorf(err.Error())
-			// TODO: Best attempt at parsing out the body
-			continue
-		}
-
-		if res.Body == nil {
-			s.logger.Errorf("request for schema '%v' returned an empty body", id)
-			err = errors.New("schema request returned an empty body")
-			continue
-		}
-
-		resBytes, err = ioutil.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			s.logger.Errorf("failed to read response for schema '%v': %v", id, err)
-			continue
-		}
 
-		break
-	}
+	decoder, err := s.client.decoderFor(ctx, info)
 	if err != nil {
-		return nil, err
-	}
-
-	resPayload := struct {
-		Schema string `json:"schema"`
-	}{}
-	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
 		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
 		return nil, err
 	}
 
-	var codec *goavro.Codec
-	if codec, err = goavro.NewCodec(resPayload.Schema); err != nil {
-		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
-		return nil, err
-	}
-
-	decoder := func(m *service.Message) error {
-		b, err := m.AsBytes()
-		if err != nil {
-			return err
-		}
-		native, _, err := codec.NativeFromBinary(b)
-		if err != nil {
-			return err
-		}
-		m.SetStructured(native)
-		return nil
-	}
-
 	s.cacheMut.Lock()
 	s.schemas[id] = &cachedSchemaDecoder{
 		lastUsedUnixSeconds: time.Now().Unix(),
@@ -0,0 +1,259 @@
+package confluent
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/x/service"
+)
+
+func schemaRegistryEncoderConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Parsing", "Integration").
+		Summary("Automatically encodes and validates messages with schemas from a Confluent Schema Registry service.").
+		Description(`
+Encodes messages automatically with the latest schema of a subject stored within a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html), and prefixes the result with the magic byte and schema ID of the [wire format](https://docs.confluent.io/platform/current/schema-registry/serdes-develop/index.html#wire-format) expected by consumers. If a message fails to encode then it will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
+
+Avro, Protobuf and JSON schemas are supported, and the message to encode must be a JSON document in each case:
+
+- Avro messages are expected in the [JSON encoding of Avro](https://avro.apache.org/docs/current/spec.html#json_encoding), where the values of union types are wrapped within an object keyed by their type.
+- Protobuf messages are expected in the [JSON mapping of Protobuf](https://developers.google.com/protocol-buffers/docs/proto3#json), and are encoded as the first message type defined by the schema.
+- JSON messages are validated against the schema and are otherwise left unchanged.
+
+Schema references are only supported for Protobuf schemas.
+
+The latest schema of each subject is cached and refreshed from the registry at the interval specified by ` + "`refresh_period`" + `. If a refresh fails then the cached schema continues to be used.`).
+		Field(service.NewStringField("url").Description("The base URL of the schema registry service.")).
+		Field(service.NewStringField("subject").Description("The subject of the schema to encode messages with. This field supports [interpolation functions](/docs/configuration/interpolation#bloblang-queries), where the common Kafka convention is a subject of the form `<topic>-value`.")).
+		Field(service.NewStringField("refresh_period").Description("The period after which the cached schema of a subject is refreshed.").Default("10m")).
+		Field(service.NewTLSField("tls"))
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"schema_registry_encode", schemaRegistryEncoderConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			urlStr, err := conf.FieldString("url")
+			if err != nil {
+				return nil, err
+			}
+			subjectStr, err := conf.FieldString("subject")
+			if err != nil {
+				return nil, err
+			}
+			refreshPeriodStr, err := conf.FieldString("refresh_period")
+			if err != nil {
+				return nil, err
+			}
+			tlsConf, err := conf.FieldTLS("tls")
+			if err != nil {
+				return nil, err
+			}
+			return newSchemaRegistryEncoder(urlStr, subjectStr, refreshPeriodStr, tlsConf, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cachedSchemaEncoder struct {
+	lastUsedUnixSeconds    int64
+	lastUpdatedUnixSeconds int64
+	id                     int
+	encoder                schemaEncoder
+}
+
+type schemaRegistryEncoder struct {
+	client        *schemaRegistryClient
+	subject       *service.InterpolatedField
+	refreshPeriod time.Duration
+
+	schemas    map[string]*cachedSchemaEncoder
+	cacheMut   sync.RWMutex
+	requestMut sync.Mutex
+	shutSig    *shutdown.Signaller
+
+	logger *service.Logger
+	nowFn  func() time.Time
+}
+
+func newSchemaRegistryEncoder(urlStr, subjectStr, refreshPeriodStr string, tlsConf *tls.Config, logger *service.Logger) (*schemaRegistryEncoder, error) {
+	client, err := newSchemaRegistryClient(urlStr, tlsConf, logger)
+	if err != nil {
+		return nil, err
+	}
+	if subjectStr == "" {
+		return nil, errors.New("a subject must be specified")
+	}
+	subject, err := service.NewInterpolatedField(subjectStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subject expression: %w", err)
+	}
+	refreshPeriod, err := time.ParseDuration(refreshPeriodStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse refresh period: %w", err)
+	}
+
+	s := &schemaRegistryEncoder{
+		client:        client,
+		subject:       subject,
+		refreshPeriod: refreshPeriod,
+		schemas:       map[string]*cachedSchemaEncoder{},
+		shutSig:       shutdown.NewSignaller(),
+		logger:        logger,
+		nowFn:         time.Now,
+	}
+
+	go func() {
+		for {
+			select {
+			case <-time.After(schemaCachePurgePeriod):
+				s.clearExpired()
+			case <-s.shutSig.CloseAtLeisureChan():
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+func (s *schemaRegistryEncoder) Process(ctx context.Context, msg *service.Message) ([]*service.Message, error) {
+	subject := s.subject.String(msg)
+	encoder, id, err := s.getEncoder(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	newMsg := msg.Copy()
+	if err := encoder(newMsg); err != nil {
+		return nil, err
+	}
+
+	b, err := newMsg.AsBytes()
+	if err != nil {
+		return nil, errors.New("unable to reference encoded message as bytes")
+	}
+	encoded := make([]byte, 5+len(b))
+	binary.BigEndian.PutUint32(encoded[1:5], uint32(id))
+	copy(encoded[5:], b)
+	newMsg.SetBytes(encoded)
+
+	return []*service.Message{newMsg}, nil
+}
+
+func (s *schemaRegistryEncoder) Close(ctx context.Context) error {
+	s.shutSig.CloseNow()
+	s.cacheMut.Lock()
+	defer s.cacheMut.Unlock()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for k := range s.schemas {
+		delete(s.schemas, k)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *schemaRegistryEncoder) clearExpired() {
+	// First pass in read only mode to gather candidates
+	s.cacheMut.RLock()
+	targetTime := s.nowFn().Add(-schemaStaleAfter).Unix()
+	var targets []string
+	for k, v := range s.schemas {
+		if atomic.LoadInt64(&v.lastUsedUnixSeconds) < targetTime {
+			targets = append(targets, k)
+		}
+	}
+	s.cacheMut.RUnlock()
+
+	// Second pass fully locks schemas and removes stale encoders
+	if len(targets) > 0 {
+		s.cacheMut.Lock()
+		for _, k := range targets {
+			if s.schemas[k].lastUsedUnixSeconds < targetTime {
+				delete(s.schemas, k)
+			}
+		}
+		s.cacheMut.Unlock()
+	}
+}
+
+func (s *schemaRegistryEncoder) getCached(subject string, now time.Time) (*cachedSchemaEncoder, bool) {
+	s.cacheMut.RLock()
+	c, ok := s.schemas[subject]
+	s.cacheMut.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	atomic.StoreInt64(&c.lastUsedUnixSeconds, now.Unix())
+	fresh := now.Sub(time.Unix(atomic.LoadInt64(&c.lastUpdatedUnixSeconds), 0)) < s.refreshPeriod
+	return c, fresh
+}
+
+func (s *schemaRegistryEncoder) getEncoder(subject string) (schemaEncoder, int, error) {
+	now := s.nowFn()
+	if c, fresh := s.getCached(subject, now); fresh {
+		return c.encoder, c.id, nil
+	}
+
+	s.requestMut.Lock()
+	defer s.requestMut.Unlock()
+
+	// We might've been beaten to making the request, so check once more whilst
+	// within the request lock.
+	c, fresh := s.getCached(subject, now)
+	if fresh {
+		return c.encoder, c.id, nil
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	encoder, id, err := s.fetchEncoder(ctx, subject)
+	if err != nil {
+		if c == nil {
+			return nil, 0, err
+		}
+		// Continue to use the cached schema until the next refresh period.
+		s.logger.Errorf("failed to refresh schema for subject '%v': %v", subject, err)
+		atomic.StoreInt64(&c.lastUpdatedUnixSeconds, now.Unix())
+		return c.encoder, c.id, nil
+	}
+
+	s.cacheMut.Lock()
+	s.schemas[subject] = &cachedSchemaEncoder{
+		lastUsedUnixSeconds:    now.Unix(),
+		lastUpdatedUnixSeconds: now.Unix(),
+		id:                     id,
+		encoder:                encoder,
+	}
+	s.cacheMut.Unlock()
+
+	return encoder, id, nil
+}
+
+func (s *schemaRegistryEncoder) fetchEncoder(ctx context.Context, subject string) (schemaEncoder, int, error) {
+	info, err := s.client.getSchemaBySubjectAndVersion(ctx, subject, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	encoder, err := s.client.encoderFor(ctx, info)
+	if err != nil {
+		s.logger.Errorf("failed to parse response for subject '%v': %v", subject, err)
+		return nil, 0, err
+	}
+	return encoder, info.ID, nil
+}
@@ -0,0 +1,222 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/x/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustJSONBytes(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
+
+func runEncodeDecode(t *testing.T, urlStr, subject, input string) (encoded, decoded string, err error) {
+	t.Helper()
+
+	encoder, err := newSchemaRegistryEncoder(urlStr, subject, "10m", nil, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, encoder.Close(context.Background()))
+	})
+
+	decoder, err := newSchemaRegistryDecoder(urlStr, nil, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, decoder.Close(context.Background()))
+	})
+
+	encMsgs, err := encoder.Process(context.Background(), service.NewMessage([]byte(input)))
+	if err != nil {
+		return "", "", err
+	}
+	require.Len(t, encMsgs, 1)
+	encBytes, err := encMsgs[0].AsBytes()
+	require.NoError(t, err)
+
+	decMsgs, err := decoder.Process(context.Background(), encMsgs[0])
+	require.NoError(t, err)
+	require.Len(t, decMsgs, 1)
+	decBytes, err := decMsgs[0].AsBytes()
+	require.NoError(t, err)
+
+	return string(encBytes), string(decBytes), nil
+}
+
+func TestSchemaRegistryEncodeAvro(t *testing.T) {
+	schema := `{
+	"type": "record",
+	"name": "person",
+	"fields": [
+		{ "name": "Name", "type": "string" },
+		{ "name": "Age", "type": ["null", "int"], "default": null }
+	]
+}`
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/people-value/versions/latest":
+			return mustJSONBytes(t, map[string]interface{}{"id": 4, "schema": schema}), nil
+		case "/schemas/ids/4":
+			return mustJSONBytes(t, map[string]interface{}{"schema": schema}), nil
+		}
+		return nil, nil
+	})
+
+	encoded, decoded, err := runEncodeDecode(t, urlStr, `${! meta("topic").or("people") }-value`, `{"Name":"foo","Age":{"int":30}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00\x00\x00\x04\x06foo\x02\x3c", encoded)
+	assert.Equal(t, `{"Age":{"int":30},"Name":"foo"}`, decoded)
+
+	_, _, err = runEncodeDecode(t, urlStr, "people-value", `{"Nope":"foo"}`)
+	require.Error(t, err)
+
+	_, _, err = runEncodeDecode(t, urlStr, "nope-value", `{"Name":"foo"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subject 'nope-value' version 'latest' not found by registry")
+}
+
+func TestSchemaRegistryEncodeProtobuf(t *testing.T) {
+	addressSchema := `
+syntax = "proto3";
+package testing;
+
+message Address {
+  string city = 1;
+}
+`
+	personSchema := `
+syntax = "proto3";
+package testing;
+
+import "address.proto";
+
+message Person {
+  string name = 1;
+  Address address = 2;
+
+  message Nested {
+    int32 id = 1;
+  }
+}
+`
+	references := []map[string]interface{}{
+		{"name": "address.proto", "subject": "address", "version": 2},
+	}
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/people-value/versions/latest":
+			return mustJSONBytes(t, map[string]interface{}{
+				"id": 7, "schemaType": "PROTOBUF", "schema": personSchema, "references": references,
+			}), nil
+		case "/schemas/ids/7":
+			return mustJSONBytes(t, map[string]interface{}{
+				"schemaType": "PROTOBUF", "schema": personSchema, "references": references,
+			}), nil
+		case "/subjects/address/versions/2":
+			return mustJSONBytes(t, map[string]interface{}{
+				"id": 6, "schemaType": "PROTOBUF", "schema": addressSchema,
+			}), nil
+		}
+		return nil, nil
+	})
+
+	encoded, decoded, err := runEncodeDecode(t, urlStr, "people-value", `{"name":"foo","address":{"city":"bar"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00\x00\x00\x07\x00", encoded[:6])
+	assert.JSONEq(t, `{"name":"foo","address":{"city":"bar"}}`, decoded)
+
+	decoder, err := newSchemaRegistryDecoder(urlStr, nil, nil)
+	require.NoError(t, err)
+	defer decoder.Close(context.Background())
+
+	// Message indexes [0, 0] refer to the nested message type of Person.
+	outMsgs, err := decoder.Process(context.Background(), service.NewMessage([]byte("\x00\x00\x00\x00\x07\x04\x00\x00\x08\x05")))
+	require.NoError(t, err)
+	b, err := outMsgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":5}`, string(b))
+
+	_, err = decoder.Process(context.Background(), service.NewMessage([]byte("\x00\x00\x00\x00\x07\x02\x06\x08\x05")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "message index [3] not found in schema")
+}
+
+func TestSchemaRegistryEncodeJSONSchema(t *testing.T) {
+	schema := `{
+	"type": "object",
+	"properties": {
+		"name": { "type": "string" }
+	},
+	"required": [ "name" ]
+}`
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/people-value/versions/latest":
+			return mustJSONBytes(t, map[string]interface{}{"id": 9, "schemaType": "JSON", "schema": schema}), nil
+		case "/schemas/ids/9":
+			return mustJSONBytes(t, map[string]interface{}{"schemaType": "JSON", "schema": schema}), nil
+		}
+		return nil, nil
+	})
+
+	encoded, decoded, err := runEncodeDecode(t, urlStr, "people-value", `{"name":"foo"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00\x00\x00\x09{\"name\":\"foo\"}", encoded)
+	assert.Equal(t, `{"name":"foo"}`, decoded)
+
+	_, _, err = runEncodeDecode(t, urlStr, "people-value", `{"name":5}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "json message does not match schema")
+}
+
+func TestSchemaRegistryEncodeRefresh(t *testing.T) {
+	schema := `{"type":"record","name":"person","fields":[{"name":"Name","type":"string"}]}`
+	id, fail := 1, false
+	requests := 0
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		requests++
+		if fail {
+			return nil, errors.New("nope")
+		}
+		return mustJSONBytes(t, map[string]interface{}{"id": id, "schema": schema}), nil
+	})
+
+	encoder, err := newSchemaRegistryEncoder(urlStr, "people-value", "1m", nil, nil)
+	require.NoError(t, err)
+	defer encoder.Close(context.Background())
+
+	now := time.Now()
+	encoder.nowFn = func() time.Time { return now }
+
+	encodedID := func() byte {
+		t.Helper()
+		outMsgs, err := encoder.Process(context.Background(), service.NewMessage([]byte(`{"Name":"foo"}`)))
+		require.NoError(t, err)
+		b, err := outMsgs[0].AsBytes()
+		require.NoError(t, err)
+		return b[4]
+	}
+
+	assert.Equal(t, byte(1), encodedID())
+	id = 2
+	assert.Equal(t, byte(1), encodedID())
+	assert.Equal(t, 1, requests)
+
+	now = now.Add(time.Minute * 2)
+	assert.Equal(t, byte(2), encodedID())
+	assert.Equal(t, 2, requests)
+
+	// Failed refreshes continue to use the cached schema.
+	fail = true
+	now = now.Add(time.Minute * 2)
+	assert.Equal(t, byte(2), encodedID())
+	assert.Equal(t, byte(2), encodedID())
+}
@@ -0,0 +1,282 @@
+package confluent
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/public/x/service"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xeipuuv/gojsonschema"
+
+	// nolint:staticcheck // Ignore SA1019 deprecation warning until we can switch to "google.golang.org/protobuf/types/dynamicpb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// schemaDecoder converts the payload of a message, with the magic byte and
+// schema ID already removed, from its serialised form.
+type schemaDecoder func(m *service.Message) error
+
+// schemaEncoder converts the payload of a message into its serialised form,
+// not including the magic byte and schema ID.
+type schemaEncoder func(m *service.Message) error
+
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+	schemaTypeJSON     = "JSON"
+)
+
+func (c *schemaRegistryClient) decoderFor(ctx context.Context, info schemaInfo) (schemaDecoder, error) {
+	switch schemaType := strings.ToUpper(info.Type); schemaType {
+	case "", schemaTypeAvro:
+		return avroDecoder(info)
+	case schemaTypeProtobuf:
+		return c.protobufDecoder(ctx, info)
+	case schemaTypeJSON:
+		return jsonSchemaDecoder(info)
+	default:
+		return nil, fmt.Errorf("schema type %v not supported", schemaType)
+	}
+}
+
+func (c *schemaRegistryClient) encoderFor(ctx context.Context, info schemaInfo) (schemaEncoder, error) {
+	switch schemaType := strings.ToUpper(info.Type); schemaType {
+	case "", schemaTypeAvro:
+		return avroEncoder(info)
+	case schemaTypeProtobuf:
+		return c.protobufEncoder(ctx, info)
+	case schemaTypeJSON:
+		return jsonSchemaEncoder(info)
+	default:
+		return nil, fmt.Errorf("schema type %v not supported", schemaType)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func avroCodec(info schemaInfo) (*goavro.Codec, error) {
+	if len(info.References) > 0 {
+		return nil, errors.New("schema references are not supported for avro schemas")
+	}
+	return goavro.NewCodec(info.Schema)
+}
+
+func avroDecoder(info schemaInfo) (schemaDecoder, error) {
+	codec, err := avroCodec(info)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		native, _, err := codec.NativeFromBinary(b)
+		if err != nil {
+			return err
+		}
+		m.SetStructured(native)
+		return nil
+	}, nil
+}
+
+func avroEncoder(info schemaInfo) (schemaEncoder, error) {
+	codec, err := avroCodec(info)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		native, _, err := codec.NativeFromTextual(b)
+		if err != nil {
+			return err
+		}
+		if b, err = codec.BinaryFromNative(nil, native); err != nil {
+			return err
+		}
+		m.SetBytes(b)
+		return nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func jsonSchema(info schemaInfo) (*gojsonschema.Schema, error) {
+	if len(info.References) > 0 {
+		return nil, errors.New("schema references are not supported for json schemas")
+	}
+	return gojsonschema.NewSchema(gojsonschema.NewStringLoader(info.Schema))
+}
+
+func validateJSONSchema(schema *gojsonschema.Schema, m *service.Message) error {
+	b, err := m.AsBytes()
+	if err != nil {
+		return err
+	}
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(b))
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		var errStrs []string
+		for _, desc := range result.Errors() {
+			errStrs = append(errStrs, desc.String())
+		}
+		return fmt.Errorf("json message does not match schema: %v", strings.Join(errStrs, ", "))
+	}
+	return nil
+}
+
+func jsonSchemaDecoder(info schemaInfo) (schemaDecoder, error) {
+	schema, err := jsonSchema(info)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *service.Message) error {
+		return validateJSONSchema(schema, m)
+	}, nil
+}
+
+func jsonSchemaEncoder(info schemaInfo) (schemaEncoder, error) {
+	schema, err := jsonSchema(info)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *service.Message) error {
+		return validateJSONSchema(schema, m)
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *schemaRegistryClient) protobufFile(ctx context.Context, info schemaInfo) (*desc.FileDescriptor, error) {
+	mainName := fmt.Sprintf("schema_%v.proto", info.ID)
+	files := map[string]string{mainName: info.Schema}
+	if err := c.walkReferences(ctx, info.References, func(name string, ref schemaInfo) error {
+		files[name] = ref.Schema
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(files),
+	}
+	fds, err := parser.ParseFiles(mainName)
+	if err != nil {
+		return nil, err
+	}
+	if len(fds[0].GetMessageTypes()) == 0 {
+		return nil, errors.New("protobuf schema does not contain any messages")
+	}
+	return fds[0], nil
+}
+
+// readMessageIndexes parses the message indexes that follow the schema ID of
+// protobuf messages, which identify the message type within the schema as a
+// path of nested message indexes. A single zero byte is shorthand for the
+// first message type of the schema.
+func readMessageIndexes(b []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(b)
+	if n <= 0 {
+		return nil, nil, errors.New("failed to read message indexes")
+	}
+	b = b[n:]
+	if count == 0 {
+		return []int{0}, b, nil
+	}
+	if count < 0 || int(count) > len(b) {
+		return nil, nil, fmt.Errorf("invalid message index count: %v", count)
+	}
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(b)
+		if n <= 0 {
+			return nil, nil, errors.New("failed to read message indexes")
+		}
+		indexes[i], b = int(index), b[n:]
+	}
+	return indexes, b, nil
+}
+
+func messageFromIndexes(fd *desc.FileDescriptor, indexes []int) (*desc.MessageDescriptor, error) {
+	candidates := fd.GetMessageTypes()
+	var md *desc.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || index >= len(candidates) {
+			return nil, fmt.Errorf("message index %v not found in schema", indexes)
+		}
+		md = candidates[index]
+		candidates = md.GetNestedMessageTypes()
+	}
+	return md, nil
+}
+
+func (c *schemaRegistryClient) protobufDecoder(ctx context.Context, info schemaInfo) (schemaDecoder, error) {
+	fd, err := c.protobufFile(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		indexes, remaining, err := readMessageIndexes(b)
+		if err != nil {
+			return err
+		}
+		md, err := messageFromIndexes(fd, indexes)
+		if err != nil {
+			return err
+		}
+
+		msg := dynamic.NewMessage(md)
+		if err := proto.Unmarshal(remaining, msg); err != nil {
+			return fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+		data, err := msg.MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("failed to marshal protobuf message: %w", err)
+		}
+		m.SetBytes(data)
+		return nil
+	}, nil
+}
+
+func (c *schemaRegistryClient) protobufEncoder(ctx context.Context, info schemaInfo) (schemaEncoder, error) {
+	fd, err := c.protobufFile(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	md := fd.GetMessageTypes()[0]
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		msg := dynamic.NewMessage(md)
+		if err := msg.UnmarshalJSON(b); err != nil {
+			return fmt.Errorf("failed to unmarshal JSON message: %w", err)
+		}
+		data, err := msg.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal protobuf message: %w", err)
+		}
+
+		// The message indexes of the first message type of a schema.
+		m.SetBytes(append([]byte{0}, data...))
+		return nil
+	}, nil
+}
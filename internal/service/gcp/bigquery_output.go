@@ -0,0 +1,393 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bundle"
+	ioutput "github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	mbatch "github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+func init() {
+	bundle.AllOutputs.Add(bundle.OutputConstructorFromSimple(func(c output.Config, nm bundle.NewManagement) (output.Type, error) {
+		g, err := newGCPBigQueryOutput(c.GCPBigQuery, nm.Logger(), nm.Metrics())
+		if err != nil {
+			return nil, err
+		}
+		w, err := output.NewAsyncWriter(output.TypeGCPBigQuery, c.GCPBigQuery.MaxInFlight, g, nm.Logger(), nm.Metrics())
+		if err != nil {
+			return nil, err
+		}
+		return output.NewBatcherFromConfig(c.GCPBigQuery.Batching, w, nm, nm.Logger(), nm.Metrics())
+	}), docs.ComponentSpec{
+		Name:    output.TypeGCPBigQuery,
+		Type:    docs.TypeOutput,
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Categories: []string{
+			string(input.CategoryServices),
+			string(input.CategoryGCP),
+		},
+		Summary: `
+Writes messages as rows of a Google Cloud BigQuery table, either with streaming
+inserts or with batched load jobs.`,
+		Description: ioutput.Description(true, true, `
+The `+"`table`"+` is calculated per message of a batch and supports [interpolation functions](/docs/configuration/interpolation#bloblang-queries), which makes it possible to write to a particular partition of a table with a partition decorator. For example, the table `+"`events$${!timestamp_utc(\"20060102\")}`"+` writes messages to the daily partition of the table `+"`events`"+` for the day on which they are sent.
+
+### Streaming Inserts
+
+When the `+"`method`"+` is `+"`streaming`"+` each message must be a JSON object, which is inserted as a row of the table and is available for querying within a few seconds. The table must already exist, and the messages of a batch are inserted with a single request per table, therefore batches should be kept within the [limits of streaming inserts](https://cloud.google.com/bigquery/quotas#streaming_inserts). When only some rows of a batch fail to insert only their messages are rejected, so that the rows that succeeded aren't resent by inputs that retry messages individually.
+
+### Load Jobs
+
+When the `+"`method`"+` is `+"`load`"+` the messages of each batch are staged as a file of the given `+"`format`"+` and loaded into the table with a load job, which is cheaper than streaming inserts for large volumes at the cost of latency. With the formats `+"`json`"+` and `+"`csv`"+` each message is a row, either a JSON object or a line of CSV, and the rows of a batch are loaded with a single job per table. With the format `+"`avro`"+` each message must be an Avro object container file, and is loaded with its own job.
+
+Load jobs are able to create the table when it doesn't exist, in which case the schema of the table is detected from the data when `+"`auto_detect`"+` is true.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to GCP
+services. You can find out more [in this document](/docs/guides/gcp).`),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Daily Partitions",
+				Summary: "In this example we load batches of JSON events from Kafka into the daily partitions of a table every minute.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: bigquery
+
+output:
+  gcp_bigquery:
+    project: my-project
+    dataset: analytics
+    table: events$${! timestamp_utc("20060102") }
+    method: load
+    format: json
+    auto_detect: true
+    batching:
+      count: 50000
+      period: 1m
+`,
+			},
+		},
+		Config: docs.FieldComponent().WithChildren(
+			docs.FieldCommon("project", "The project ID of the dataset."),
+			docs.FieldCommon("dataset", "The BigQuery dataset of the table."),
+			docs.FieldCommon(
+				"table", "The table to write messages to, which can include a partition decorator.",
+				"events", `events$${! timestamp_utc("20060102") }`, `${! meta("kafka_topic") }`,
+			).IsInterpolated(),
+			docs.FieldCommon("method", "The method with which messages are written to the table.").HasAnnotatedOptions(
+				"streaming", "Insert each message as a row with streaming inserts.",
+				"load", "Load the messages of each batch with load jobs.",
+			),
+			docs.FieldCommon("format", "The format of messages written with load jobs.").HasOptions("json", "csv", "avro"),
+			docs.FieldAdvanced("auto_detect", "Whether load jobs should detect the schema of the data when creating a table, which applies to the formats `json` and `csv`."),
+			docs.FieldAdvanced("ignore_unknown_values", "Whether values that don't match the schema of the table are ignored rather than failing the row, which applies to streaming inserts and the formats `json` and `csv`."),
+			docs.FieldAdvanced("create_disposition", "Whether load jobs are able to create the table when it doesn't exist.").HasOptions("CREATE_IF_NEEDED", "CREATE_NEVER"),
+			docs.FieldAdvanced("write_disposition", "How load jobs treat data that already exists within the table.").HasOptions("WRITE_APPEND", "WRITE_TRUNCATE", "WRITE_EMPTY"),
+			docs.FieldAdvanced("csv", "Options for loading messages with the format `csv`.").WithChildren(
+				docs.FieldAdvanced("field_delimiter", "The separator of the fields of each row."),
+			),
+			docs.FieldCommon("max_in_flight", "The maximum number of batches to have in flight at a given time. Increase this to improve throughput."),
+			mbatch.FieldSpec(),
+		),
+	})
+}
+
+//------------------------------------------------------------------------------
+
+var bigQueryFormats = map[string]bigquery.DataFormat{
+	"json": bigquery.JSON,
+	"csv":  bigquery.CSV,
+	"avro": bigquery.Avro,
+}
+
+// bigQueryRow is a row of a streaming insert.
+type bigQueryRow map[string]bigquery.Value
+
+// Save implements bigquery.ValueSaver, where an empty insert ID results in a
+// random one being generated for deduplicating retries of the request.
+func (r bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	return r, "", nil
+}
+
+func newBigQueryRow(p types.Part) (bigQueryRow, error) {
+	jObj, err := p.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	obj, ok := jObj.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected message to be a JSON object, got %T", jObj)
+	}
+	row := make(bigQueryRow, len(obj))
+	for k, v := range obj {
+		row[k] = v
+	}
+	return row, nil
+}
+
+// bigQueryStage is the staged data of a load job and the indexes of the
+// messages that it contains.
+type bigQueryStage struct {
+	indexes []int
+	data    []byte
+}
+
+//------------------------------------------------------------------------------
+
+// gcpBigQueryOutput is a benthos writer.Type implementation that writes
+// messages to a GCP BigQuery table.
+type gcpBigQueryOutput struct {
+	conf output.GCPBigQueryConfig
+
+	table  *field.Expression
+	format bigquery.DataFormat
+
+	client  *bigquery.Client
+	connMut sync.RWMutex
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// newGCPBigQueryOutput creates a new GCP BigQuery writer.Type.
+func newGCPBigQueryOutput(
+	conf output.GCPBigQueryConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*gcpBigQueryOutput, error) {
+	if conf.Project == "" {
+		return nil, errors.New("a project must be specified")
+	}
+	if conf.Dataset == "" {
+		return nil, errors.New("a dataset must be specified")
+	}
+	if conf.Table == "" {
+		return nil, errors.New("a table must be specified")
+	}
+	if conf.Method != "streaming" && conf.Method != "load" {
+		return nil, fmt.Errorf("unrecognised method: %v", conf.Method)
+	}
+	g := &gcpBigQueryOutput{
+		conf:  conf,
+		log:   log,
+		stats: stats,
+	}
+	var exists bool
+	if g.format, exists = bigQueryFormats[conf.Format]; !exists {
+		return nil, fmt.Errorf("unrecognised format: %v", conf.Format)
+	}
+	switch bigquery.TableCreateDisposition(conf.CreateDisposition) {
+	case bigquery.CreateIfNeeded, bigquery.CreateNever:
+	default:
+		return nil, fmt.Errorf("unrecognised create disposition: %v", conf.CreateDisposition)
+	}
+	switch bigquery.TableWriteDisposition(conf.WriteDisposition) {
+	case bigquery.WriteAppend, bigquery.WriteTruncate, bigquery.WriteEmpty:
+	default:
+		return nil, fmt.Errorf("unrecognised write disposition: %v", conf.WriteDisposition)
+	}
+	if g.format == bigquery.CSV && len(conf.CSV.FieldDelimiter) == 0 {
+		return nil, errors.New("a csv field delimiter must be specified")
+	}
+	var err error
+	if g.table, err = bloblang.NewField(conf.Table); err != nil {
+		return nil, fmt.Errorf("failed to parse table expression: %v", err)
+	}
+	return g, nil
+}
+
+// ConnectWithContext attempts to establish a connection to BigQuery.
+func (g *gcpBigQueryOutput) ConnectWithContext(ctx context.Context) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	var err error
+	if g.client, err = bigquery.NewClient(ctx, g.conf.Project); err != nil {
+		return err
+	}
+
+	g.log.Infof("Writing message parts as rows to GCP BigQuery dataset: %v.%v\n", g.conf.Project, g.conf.Dataset)
+	return nil
+}
+
+// tableIndexes groups the indexes of the messages of a batch by their table,
+// returning the tables in the order they first appear.
+func (g *gcpBigQueryOutput) tableIndexes(msg types.Message) ([]string, map[string][]int) {
+	var tables []string
+	indexes := map[string][]int{}
+	for i := 0; i < msg.Len(); i++ {
+		table := g.table.String(i, msg)
+		if _, exists := indexes[table]; !exists {
+			tables = append(tables, table)
+		}
+		indexes[table] = append(indexes[table], i)
+	}
+	return tables, indexes
+}
+
+// stage returns the staged data of the load jobs for messages of a batch.
+func (g *gcpBigQueryOutput) stage(msg types.Message, indexes []int) []bigQueryStage {
+	if g.format == bigquery.Avro {
+		stages := make([]bigQueryStage, 0, len(indexes))
+		for _, i := range indexes {
+			stages = append(stages, bigQueryStage{
+				indexes: []int{i},
+				data:    msg.Get(i).Get(),
+			})
+		}
+		return stages
+	}
+	var buf bytes.Buffer
+	for _, i := range indexes {
+		buf.Write(msg.Get(i).Get())
+		buf.WriteByte('\n')
+	}
+	return []bigQueryStage{{indexes: indexes, data: buf.Bytes()}}
+}
+
+func (g *gcpBigQueryOutput) insert(ctx context.Context, table *bigquery.Table, msg types.Message, indexes []int, failed func(int, error)) {
+	rows := make([]bigquery.ValueSaver, 0, len(indexes))
+	rowIndexes := make([]int, 0, len(indexes))
+	for _, i := range indexes {
+		row, err := newBigQueryRow(msg.Get(i))
+		if err != nil {
+			g.log.Errorf("Failed to create row: %v\n", err)
+			failed(i, err)
+			continue
+		}
+		rows = append(rows, row)
+		rowIndexes = append(rowIndexes, i)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	inserter := table.Inserter()
+	inserter.IgnoreUnknownValues = g.conf.IgnoreUnknownValues
+	err := inserter.Put(ctx, rows)
+	if err == nil {
+		return
+	}
+
+	var putErr bigquery.PutMultiError
+	if errors.As(err, &putErr) {
+		for _, rowErr := range putErr {
+			if rowErr.RowIndex >= 0 && rowErr.RowIndex < len(rowIndexes) {
+				failed(rowIndexes[rowErr.RowIndex], rowErr.Errors)
+			}
+		}
+		return
+	}
+	for _, i := range rowIndexes {
+		failed(i, err)
+	}
+}
+
+func (g *gcpBigQueryOutput) load(ctx context.Context, table *bigquery.Table, stage bigQueryStage) error {
+	src := bigquery.NewReaderSource(bytes.NewReader(stage.data))
+	src.SourceFormat = g.format
+	if g.format != bigquery.Avro {
+		src.AutoDetect = g.conf.AutoDetect
+		src.IgnoreUnknownValues = g.conf.IgnoreUnknownValues
+	}
+	if g.format == bigquery.CSV {
+		src.FieldDelimiter = g.conf.CSV.FieldDelimiter
+	}
+
+	loader := table.LoaderFrom(src)
+	loader.CreateDisposition = bigquery.TableCreateDisposition(g.conf.CreateDisposition)
+	loader.WriteDisposition = bigquery.TableWriteDisposition(g.conf.WriteDisposition)
+
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run load job: %w", err)
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for load job %v: %w", job.ID(), err)
+	}
+	if err = status.Err(); err != nil {
+		return fmt.Errorf("load job %v failed: %w", job.ID(), err)
+	}
+	return nil
+}
+
+// WriteWithContext attempts to write the messages of a batch to BigQuery.
+func (g *gcpBigQueryOutput) WriteWithContext(ctx context.Context, msg types.Message) error {
+	g.connMut.RLock()
+	client := g.client
+	g.connMut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var batchErr *batch.Error
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = batch.NewError(msg, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	tables, indexes := g.tableIndexes(msg)
+	for _, t := range tables {
+		table := client.Dataset(g.conf.Dataset).Table(t)
+		if g.conf.Method == "streaming" {
+			g.insert(ctx, table, msg, indexes[t], failed)
+			continue
+		}
+		for _, stage := range g.stage(msg, indexes[t]) {
+			if err := g.load(ctx, table, stage); err != nil {
+				g.log.Errorf("Failed to load messages into table %v: %v\n", t, err)
+				for _, i := range stage.indexes {
+					failed(i, err)
+				}
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (g *gcpBigQueryOutput) CloseAsync() {
+	go func() {
+		g.connMut.Lock()
+		if g.client != nil {
+			g.client.Close()
+			g.client = nil
+		}
+		g.connMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (g *gcpBigQueryOutput) WaitForClose(time.Duration) error {
+	return nil
+}
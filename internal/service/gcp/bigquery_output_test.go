@@ -0,0 +1,108 @@
+package gcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBigQueryConfig() output.GCPBigQueryConfig {
+	conf := output.NewGCPBigQueryConfig()
+	conf.Project = "foo"
+	conf.Dataset = "bar"
+	conf.Table = `events$${! meta("day") }`
+	return conf
+}
+
+func TestGCPBigQueryStage(t *testing.T) {
+	conf := testBigQueryConfig()
+	conf.Method = "load"
+
+	g, err := newGCPBigQueryOutput(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":2}`),
+		[]byte(`{"id":3}`),
+	})
+	msg.Get(0).Metadata().Set("day", "20210601")
+	msg.Get(1).Metadata().Set("day", "20210602")
+	msg.Get(2).Metadata().Set("day", "20210601")
+
+	tables, indexes := g.tableIndexes(msg)
+	assert.Equal(t, []string{"events$20210601", "events$20210602"}, tables)
+	assert.Equal(t, map[string][]int{
+		"events$20210601": {0, 2},
+		"events$20210602": {1},
+	}, indexes)
+
+	stages := g.stage(msg, indexes["events$20210601"])
+	require.Len(t, stages, 1)
+	assert.Equal(t, []int{0, 2}, stages[0].indexes)
+	assert.Equal(t, "{\"id\":1}\n{\"id\":3}\n", string(stages[0].data))
+
+	conf.Format = "avro"
+	g, err = newGCPBigQueryOutput(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	stages = g.stage(msg, indexes["events$20210601"])
+	require.Len(t, stages, 2)
+	assert.Equal(t, []int{2}, stages[1].indexes)
+	assert.Equal(t, `{"id":3}`, string(stages[1].data))
+}
+
+func TestGCPBigQueryRow(t *testing.T) {
+	row, err := newBigQueryRow(message.NewPart([]byte(`{"id":1,"name":"foo"}`)))
+	require.NoError(t, err)
+
+	values, insertID, err := row.Save()
+	require.NoError(t, err)
+	assert.Equal(t, "", insertID)
+	assert.Equal(t, json.Number("1"), values["id"])
+	assert.Equal(t, "foo", values["name"])
+
+	_, err = newBigQueryRow(message.NewPart([]byte(`[1,2]`)))
+	require.EqualError(t, err, "expected message to be a JSON object, got []interface {}")
+
+	_, err = newBigQueryRow(message.NewPart([]byte(`nope`)))
+	require.Error(t, err)
+}
+
+func TestGCPBigQueryConfigErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		fn  func(*output.GCPBigQueryConfig)
+		err string
+	}{
+		"no project": {
+			fn:  func(c *output.GCPBigQueryConfig) { c.Project = "" },
+			err: "a project must be specified",
+		},
+		"bad method": {
+			fn:  func(c *output.GCPBigQueryConfig) { c.Method = "nope" },
+			err: "unrecognised method: nope",
+		},
+		"bad format": {
+			fn:  func(c *output.GCPBigQueryConfig) { c.Format = "xml" },
+			err: "unrecognised format: xml",
+		},
+		"bad write disposition": {
+			fn:  func(c *output.GCPBigQueryConfig) { c.WriteDisposition = "WRITE_NOPE" },
+			err: "unrecognised write disposition: WRITE_NOPE",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := testBigQueryConfig()
+			test.fn(&conf)
+			_, err := newGCPBigQueryOutput(conf, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
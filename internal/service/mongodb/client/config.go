@@ -2,11 +2,13 @@ package client
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 // Config is a config struct for a mongo connection
@@ -61,6 +63,41 @@ func (m Config) Client() (*mongo.Client, error) {
 	return client, nil
 }
 
+// Get returns a mongodb write concern built from the configuration
+// parameters, or nil if no parameters are set, in which case the default write
+// concern of the server applies. A non-numeric w value is treated as a tag set.
+func (w WriteConcern) Get() (*writeconcern.WriteConcern, error) {
+	var opts []writeconcern.Option
+	if w.J {
+		opts = append(opts, writeconcern.J(w.J))
+	}
+	if w.WTimeout != "" {
+		timeout, err := time.ParseDuration(w.WTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse wtimeout string: %v", err)
+		}
+		opts = append(opts, writeconcern.WTimeout(timeout))
+	}
+	if w.W != "" {
+		if n, err := strconv.Atoi(w.W); err == nil {
+			opts = append(opts, writeconcern.W(n))
+		} else {
+			opts = append(opts, writeconcern.WTagSet(w.W))
+		}
+	}
+
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	writeConcern := writeconcern.New(opts...)
+
+	// This does some validation so we don't have to
+	if _, _, err := writeConcern.MarshalBSONValue(); err != nil {
+		return nil, fmt.Errorf("write_concern validation error: %w", err)
+	}
+	return writeConcern, nil
+}
+
 // ConfigDocs returns a documentation field spec for fields within a Config.
 func ConfigDocs() docs.FieldSpecs {
 	return docs.FieldSpecs{
@@ -4,12 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 	"sync"
 	"time"
 
 	ibatch "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/bundle"
 	ioutput "github.com/Jeffail/benthos/v3/internal/component/output"
@@ -28,6 +28,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
+var writeOps = map[string]bool{
+	"insert-one":  true,
+	"delete-one":  true,
+	"delete-many": true,
+	"replace-one": true,
+	"update-one":  true,
+	"update-many": true,
+}
+
 func init() {
 	bundle.AllOutputs.Add(bundle.OutputConstructorFromSimple(func(c output.Config, nm bundle.NewManagement) (output.Type, error) {
 		return NewOutput(c.MongoDB, nm, nm.Logger(), nm.Metrics())
@@ -39,15 +48,48 @@ func init() {
 		Categories: []string{
 			string(output.CategoryServices),
 		},
-		Summary:     `Inserts items into a MongoDB collection.`,
-		Description: ioutput.Description(true, true, ""),
+		Summary: `Inserts items into a MongoDB collection.`,
+		Description: ioutput.Description(true, true, `
+The messages of a batch are written as a single bulk write. By default the bulk write is ordered, meaning writes are executed in the order of the messages and a failed write prevents the remaining writes of the batch from being attempted. Setting `+"`ordered`"+` to `+"`false`"+` allows the writes to be executed in any order, which can improve throughput, and a failed write no longer prevents others from being attempted.
+
+When a write of a bulk write fails only the message responsible for it is reported as failed, along with any messages of an ordered bulk write that were not attempted as a result.
+
+The operation can be chosen for each message with interpolation functions, in which case the maps required by each operation must be specified, and are only executed for the operations that use them.`),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Upserts and Deletes",
+				Summary: "This example upserts documents into a collection, and deletes the documents of messages where the field `deleted` is true. The operation of each message is chosen with a metadata field set by a processor.",
+				Config: `
+pipeline:
+  processors:
+    - bloblang: |
+        meta operation = if this.deleted == true { "delete-one" } else { "replace-one" }
+        root = this.without("deleted")
+
+output:
+  mongodb:
+    url: mongodb://localhost:27017
+    database: shop
+    collection: products
+    operation: ${! meta("operation") }
+    upsert: true
+    ordered: false
+    filter_map: 'root._id = this.id'
+    document_map: 'root = this'
+    batching:
+      count: 100
+      period: 1s
+`,
+			},
+		},
 		Config: docs.FieldComponent().WithChildren(
 			client.ConfigDocs().Add(
 				docs.FieldCommon(
 					"operation",
-					"The mongo operation to perform. Must be one of the following: insert-one, delete-one, delete-many, "+
-						"replace-one, update-one.",
-				),
+					"The mongo operation to perform for each message.",
+				).HasOptions(
+					"insert-one", "delete-one", "delete-many", "replace-one", "update-one", "update-many",
+				).IsInterpolated(),
 				docs.FieldCommon(
 					"write_concern",
 					"The write concern settings for the mongo connection.",
@@ -56,7 +98,7 @@ func init() {
 					"document_map",
 					"A bloblang map representing the records in the mongo db. Used to generate the document for mongodb by "+
 						"mapping the fields in the message to the mongodb fields. The document map is required for the operations "+
-						"insert-one, replace-one and update-one.",
+						"insert-one, replace-one, update-one and update-many.",
 					mapExamples()...,
 				).Linter(docs.LintBloblangMapping),
 				docs.FieldCommon(
@@ -72,6 +114,16 @@ func init() {
 						"except insert-one. It is used to improve performance of finding the documents in the mongodb.",
 					mapExamples()...,
 				).Linter(docs.LintBloblangMapping),
+				docs.FieldCommon(
+					"upsert",
+					"Whether to insert a new document when the filter of a replace-one, update-one or update-many operation does not match "+
+						"any documents.",
+				).AtVersion("3.47.0"),
+				docs.FieldAdvanced(
+					"ordered",
+					"Whether the writes of a batch are executed in order, where a failed write prevents the remaining writes of the batch "+
+						"from being attempted. Disabling this can improve throughput.",
+				).AtVersion("3.47.0"),
 				docs.FieldCommon(
 					"max_in_flight",
 					"The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
@@ -121,51 +173,63 @@ func NewWriter(
 		return nil, errors.New("mongo collection must be specified")
 	}
 
-	var filterNeeded, documentNeeded bool
-	var hintAllowed bool
-
-	if _, ok := documentMapOps[conf.Operation]; !ok {
-		return nil, fmt.Errorf("mongodb operation '%s' unknown: must be insert-one, delete-one, delete-many, replace-one, or update-one", conf.Operation)
+	var err error
+	if db.operation, err = bloblang.NewField(conf.Operation); err != nil {
+		return nil, fmt.Errorf("failed to parse operation expression: %v", err)
 	}
 
-	documentNeeded = documentMapOps[conf.Operation]
-	filterNeeded = filterMapOps[conf.Operation]
-	hintAllowed = hintAllowedOps[conf.Operation]
+	// When the operation is static we can check the maps up front, otherwise
+	// the operation of each message is checked at the point of writing.
+	if db.operation.NumDynamicExpressions() == 0 {
+		if !writeOps[conf.Operation] {
+			return nil, fmt.Errorf("mongodb operation '%s' unknown: must be insert-one, delete-one, delete-many, replace-one, update-one or update-many", conf.Operation)
+		}
 
-	var err error
+		if filterMapOps[conf.Operation] {
+			if conf.FilterMap == "" {
+				return nil, errors.New("mongodb filter_map must be specified")
+			}
+		} else if conf.FilterMap != "" {
+			return nil, fmt.Errorf("mongodb filter_map not allowed for '%s' operation", conf.Operation)
+		}
 
-	if filterNeeded {
-		if conf.FilterMap == "" {
-			return nil, errors.New("mongodb filter_map must be specified")
+		if documentMapOps[conf.Operation] {
+			if conf.DocumentMap == "" {
+				return nil, errors.New("mongodb document_map must be specified")
+			}
+		} else if conf.DocumentMap != "" {
+			return nil, fmt.Errorf("mongodb document_map not allowed for '%s' operation", conf.Operation)
 		}
+
+		if !hintAllowedOps[conf.Operation] && conf.HintMap != "" {
+			return nil, fmt.Errorf("mongodb hint_map not allowed for '%s' operation", conf.Operation)
+		}
+
+		if conf.Upsert && !upsertAllowedOps[conf.Operation] {
+			return nil, fmt.Errorf("mongodb upsert not allowed for '%s' operation", conf.Operation)
+		}
+	}
+
+	if conf.FilterMap != "" {
 		if db.filterMap, err = bloblang.NewMapping("", conf.FilterMap); err != nil {
 			return nil, fmt.Errorf("failed to parse filter_map: %v", err)
 		}
-	} else if conf.FilterMap != "" {
-		return nil, fmt.Errorf("mongodb filter_map not allowed for '%s' operation", conf.Operation)
 	}
 
-	if documentNeeded {
-		if conf.DocumentMap == "" {
-			return nil, errors.New("mongodb document_map must be specified")
-		}
+	if conf.DocumentMap != "" {
 		if db.documentMap, err = bloblang.NewMapping("", conf.DocumentMap); err != nil {
 			return nil, fmt.Errorf("failed to parse document_map: %v", err)
 		}
-	} else if conf.DocumentMap != "" {
-		return nil, fmt.Errorf("mongodb document_map not allowed for '%s' operation", conf.Operation)
 	}
 
-	if hintAllowed && conf.HintMap != "" {
+	if conf.HintMap != "" {
 		if db.hintMap, err = bloblang.NewMapping("", conf.HintMap); err != nil {
 			return nil, fmt.Errorf("failed to parse hint_map: %v", err)
 		}
-	} else if conf.HintMap != "" {
-		return nil, fmt.Errorf("mongodb hint_map not allowed for '%s' operation", conf.Operation)
 	}
 
-	if db.wcTimeout, err = time.ParseDuration(conf.WriteConcern.WTimeout); err != nil {
-		return nil, fmt.Errorf("failed to parse write concern wtimeout string: %v", err)
+	if db.writeConcern, err = conf.WriteConcern.Get(); err != nil {
+		return nil, err
 	}
 	return db, nil
 }
@@ -177,7 +241,8 @@ type Writer struct {
 	log   log.Modular
 	stats metrics.Type
 
-	wcTimeout time.Duration
+	operation    *field.Expression
+	writeConcern *writeconcern.WriteConcern
 
 	filterMap   *mapping.Executor
 	documentMap *mapping.Executor
@@ -215,26 +280,9 @@ func (m *Writer) ConnectWithContext(ctx context.Context) error {
 		return fmt.Errorf("ping failed: %v", err)
 	}
 
-	writeConcern := writeconcern.New(
-		writeconcern.J(m.conf.WriteConcern.J),
-		writeconcern.WTimeout(m.wcTimeout))
-
-	w, err := strconv.Atoi(m.conf.WriteConcern.W)
-	if err != nil {
-		writeconcern.WTagSet(m.conf.WriteConcern.W)
-	} else {
-		writeconcern.W(w)(writeConcern)
-	}
-
-	// This does some validation so we don't have to
-	if _, _, err = writeConcern.MarshalBSONValue(); err != nil {
-		_ = client.Disconnect(ctx)
-		return fmt.Errorf("write_concern validation error: %w", err)
-	}
-
 	m.collection = client.
 		Database(m.conf.MongoConfig.Database).
-		Collection(m.conf.MongoConfig.Collection, options.Collection().SetWriteConcern(writeConcern))
+		Collection(m.conf.MongoConfig.Collection, options.Collection().SetWriteConcern(m.writeConcern))
 	m.client = client
 	return nil
 }
@@ -250,114 +298,172 @@ func (m *Writer) WriteWithContext(ctx context.Context, msg types.Message) error
 	}
 
 	var writeModels []mongo.WriteModel
+	var writeParts []int
 	err := writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
-		var err error
-		var filterVal, documentVal types.Part
-		var filterValWanted, documentValWanted bool
-
-		filterValWanted = filterMapOps[m.conf.Operation]
-		documentValWanted = documentMapOps[m.conf.Operation]
+		writeModel, err := m.writeModel(i, msg)
+		if err != nil {
+			return err
+		}
+		writeModels = append(writeModels, writeModel)
+		writeParts = append(writeParts, i)
+		return nil
+	})
 
-		if filterValWanted {
-			if filterVal, err = m.filterMap.MapPart(i, msg); err != nil {
-				return fmt.Errorf("failed to execute filter_map: %v", err)
-			}
+	var batchErr *ibatch.Error
+	if err != nil {
+		if !errors.As(err, &batchErr) {
+			return err
 		}
+	}
 
-		if (filterVal != nil || !filterValWanted) && documentValWanted {
-			if documentVal, err = m.documentMap.MapPart(i, msg); err != nil {
-				return fmt.Errorf("failed to execute document_map: %v", err)
+	if len(writeModels) > 0 {
+		opts := options.BulkWrite().SetOrdered(m.conf.Ordered)
+		if _, err = collection.BulkWrite(ctx, writeModels, opts); err != nil {
+			if msg.Len() == 1 {
+				return err
+			}
+			if batchErr == nil {
+				batchErr = ibatch.NewError(msg, err)
+			}
+			if !bulkWriteErrors(err, writeParts, m.conf.Ordered, func(i int, err error) {
+				batchErr.Failed(i, err)
+			}) {
+				for _, i := range writeParts {
+					batchErr.Failed(i, err)
+				}
 			}
 		}
+	}
 
-		if filterVal == nil && filterValWanted {
-			return fmt.Errorf("failed to generate filterVal")
-		}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
 
-		if documentVal == nil && documentValWanted {
-			return fmt.Errorf("failed to generate documentVal")
-		}
+func (m *Writer) writeModel(i int, msg types.Message) (mongo.WriteModel, error) {
+	op := m.operation.String(i, msg)
+	if !writeOps[op] {
+		return nil, fmt.Errorf("mongodb operation '%s' unknown", op)
+	}
 
-		var docJSON, filterJSON, hintJSON interface{}
+	var err error
+	var docJSON, filterJSON, hintJSON interface{}
 
-		if filterValWanted {
-			if filterJSON, err = filterVal.JSON(); err != nil {
-				return err
-			}
+	if filterMapOps[op] {
+		if m.filterMap == nil {
+			return nil, fmt.Errorf("mongodb filter_map must be specified for '%s' operation", op)
 		}
-
-		if documentValWanted {
-			if docJSON, err = documentVal.JSON(); err != nil {
-				return err
-			}
+		var filterVal types.Part
+		if filterVal, err = m.filterMap.MapPart(i, msg); err != nil {
+			return nil, fmt.Errorf("failed to execute filter_map: %v", err)
 		}
-
-		if m.hintMap != nil {
-			hintVal, err := m.hintMap.MapPart(i, msg)
-			if err != nil {
-				return fmt.Errorf("failed to execute hint_map: %v", err)
-			}
-			if hintJSON, err = hintVal.JSON(); err != nil {
-				return err
-			}
+		if filterVal == nil {
+			return nil, errors.New("failed to generate filterVal")
 		}
-
-		var writeModel mongo.WriteModel
-		upsertFalse := false
-		switch m.conf.Operation {
-		case "insert-one":
-			writeModel = &mongo.InsertOneModel{
-				Document: docJSON,
-			}
-		case "delete-one":
-			writeModel = &mongo.DeleteOneModel{
-				Filter: filterJSON,
-				Hint:   hintJSON,
-			}
-		case "delete-many":
-			writeModel = &mongo.DeleteManyModel{
-				Filter: filterJSON,
-				Hint:   hintJSON,
-			}
-		case "replace-one":
-			writeModel = &mongo.ReplaceOneModel{
-				Upsert:      &upsertFalse,
-				Filter:      filterJSON,
-				Replacement: docJSON,
-				Hint:        hintJSON,
-			}
-		case "update-one":
-			writeModel = &mongo.UpdateOneModel{
-				Upsert: &upsertFalse,
-				Filter: filterJSON,
-				Update: docJSON,
-				Hint:   hintJSON,
-			}
+		if filterJSON, err = filterVal.JSON(); err != nil {
+			return nil, err
 		}
+	}
 
-		if writeModel != nil {
-			writeModels = append(writeModels, writeModel)
+	if documentMapOps[op] {
+		if m.documentMap == nil {
+			return nil, fmt.Errorf("mongodb document_map must be specified for '%s' operation", op)
 		}
-		return nil
-	})
+		var documentVal types.Part
+		if documentVal, err = m.documentMap.MapPart(i, msg); err != nil {
+			return nil, fmt.Errorf("failed to execute document_map: %v", err)
+		}
+		if documentVal == nil {
+			return nil, errors.New("failed to generate documentVal")
+		}
+		if docJSON, err = documentVal.JSON(); err != nil {
+			return nil, err
+		}
+	}
 
-	var batchErr *ibatch.Error
-	if err != nil {
-		if !errors.As(err, &batchErr) {
-			return err
+	if m.hintMap != nil && hintAllowedOps[op] {
+		hintVal, err := m.hintMap.MapPart(i, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute hint_map: %v", err)
+		}
+		if hintJSON, err = hintVal.JSON(); err != nil {
+			return nil, err
 		}
 	}
 
-	if len(writeModels) > 0 {
-		if _, err = collection.BulkWrite(ctx, writeModels); err != nil {
-			return err
+	upsert := m.conf.Upsert
+	switch op {
+	case "insert-one":
+		return &mongo.InsertOneModel{
+			Document: docJSON,
+		}, nil
+	case "delete-one":
+		return &mongo.DeleteOneModel{
+			Filter: filterJSON,
+			Hint:   hintJSON,
+		}, nil
+	case "delete-many":
+		return &mongo.DeleteManyModel{
+			Filter: filterJSON,
+			Hint:   hintJSON,
+		}, nil
+	case "replace-one":
+		return &mongo.ReplaceOneModel{
+			Upsert:      &upsert,
+			Filter:      filterJSON,
+			Replacement: docJSON,
+			Hint:        hintJSON,
+		}, nil
+	case "update-one":
+		return &mongo.UpdateOneModel{
+			Upsert: &upsert,
+			Filter: filterJSON,
+			Update: docJSON,
+			Hint:   hintJSON,
+		}, nil
+	}
+	return &mongo.UpdateManyModel{
+		Upsert: &upsert,
+		Filter: filterJSON,
+		Update: docJSON,
+		Hint:   hintJSON,
+	}, nil
+}
+
+var errWriteNotAttempted = errors.New("write not attempted due to the failure of a prior write of an ordered bulk write")
+
+// bulkWriteErrors walks the write errors of a failed bulk write and calls fn
+// with the index of the message responsible for each one. When the bulk write
+// is ordered the messages following a failed write were never attempted and
+// are also reported. Returns false if the error cannot be attributed to
+// individual writes, in which case the whole batch should be considered
+// failed.
+func bulkWriteErrors(err error, writeParts []int, ordered bool, fn func(int, error)) bool {
+	var bwErr mongo.BulkWriteException
+	if !errors.As(err, &bwErr) || bwErr.WriteConcernError != nil || len(bwErr.WriteErrors) == 0 {
+		return false
+	}
+
+	lowest := len(writeParts)
+	for _, we := range bwErr.WriteErrors {
+		if we.Index < 0 || we.Index >= len(writeParts) {
+			return false
+		}
+		if we.Index < lowest {
+			lowest = we.Index
 		}
 	}
 
-	if batchErr != nil {
-		return batchErr
+	for _, we := range bwErr.WriteErrors {
+		fn(writeParts[we.Index], we.WriteError)
 	}
-	return nil
+	if ordered {
+		for _, i := range writeParts[lowest+1:] {
+			fn(i, errWriteNotAttempted)
+		}
+	}
+	return true
 }
 
 // CloseAsync begins cleaning up resources used by this writer asynchronously.
@@ -0,0 +1,157 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWriterDynamicOperation(t *testing.T) {
+	conf := output.NewMongoDBConfig()
+	conf.MongoConfig.Database = "TestDB"
+	conf.MongoConfig.Collection = "TestCollection"
+	conf.Operation = `${! meta("operation") }`
+	conf.FilterMap = "root._id = this.id"
+	conf.DocumentMap = "root = this"
+	conf.HintMap = "root.id = 1"
+	conf.Upsert = true
+
+	w, err := NewWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo","value":1}`),
+		[]byte(`{"id":"bar"}`),
+		[]byte(`{"id":"baz"}`),
+		[]byte(`{"id":"buz"}`),
+	})
+	msg.Get(0).Metadata().Set("operation", "update-many")
+	msg.Get(1).Metadata().Set("operation", "delete-one")
+	msg.Get(2).Metadata().Set("operation", "insert-one")
+	msg.Get(3).Metadata().Set("operation", "find-one")
+
+	model, err := w.writeModel(0, msg)
+	require.NoError(t, err)
+	upsert := true
+	assert.Equal(t, &mongo.UpdateManyModel{
+		Upsert: &upsert,
+		Filter: map[string]interface{}{"_id": "foo"},
+		Update: map[string]interface{}{"id": "foo", "value": json.Number("1")},
+		Hint:   map[string]interface{}{"id": int64(1)},
+	}, model)
+
+	model, err = w.writeModel(1, msg)
+	require.NoError(t, err)
+	assert.Equal(t, &mongo.DeleteOneModel{
+		Filter: map[string]interface{}{"_id": "bar"},
+		Hint:   map[string]interface{}{"id": int64(1)},
+	}, model)
+
+	model, err = w.writeModel(2, msg)
+	require.NoError(t, err)
+	assert.Equal(t, &mongo.InsertOneModel{
+		Document: map[string]interface{}{"id": "baz"},
+	}, model)
+
+	_, err = w.writeModel(3, msg)
+	require.EqualError(t, err, "mongodb operation 'find-one' unknown")
+
+	conf.DocumentMap = ""
+	w, err = NewWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, err = w.writeModel(0, msg)
+	require.EqualError(t, err, "mongodb document_map must be specified for 'update-many' operation")
+}
+
+func TestWriterBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf   func(c *output.MongoDBConfig)
+		errStr string
+	}{
+		"read operation": {
+			conf:   func(c *output.MongoDBConfig) { c.Operation = "find-one" },
+			errStr: "mongodb operation 'find-one' unknown: must be insert-one, delete-one, delete-many, replace-one, update-one or update-many",
+		},
+		"missing filter": {
+			conf:   func(c *output.MongoDBConfig) { c.Operation = "delete-many" },
+			errStr: "mongodb filter_map must be specified",
+		},
+		"missing document": {
+			conf: func(c *output.MongoDBConfig) {
+				c.Operation = "update-many"
+				c.FilterMap = "root._id = this.id"
+			},
+			errStr: "mongodb document_map must be specified",
+		},
+		"upsert not allowed": {
+			conf: func(c *output.MongoDBConfig) {
+				c.Operation = "insert-one"
+				c.DocumentMap = "root = this"
+				c.Upsert = true
+			},
+			errStr: "mongodb upsert not allowed for 'insert-one' operation",
+		},
+		"bad operation expression": {
+			conf:   func(c *output.MongoDBConfig) { c.Operation = "${! meta( }" },
+			errStr: "failed to parse operation expression: required: expected function argument, got: }",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := output.NewMongoDBConfig()
+			conf.MongoConfig.Database = "TestDB"
+			conf.MongoConfig.Collection = "TestCollection"
+			test.conf(&conf)
+
+			_, err := NewWriter(conf, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestBulkWriteErrors(t *testing.T) {
+	bwErr := mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}},
+		},
+	}
+
+	// Messages 0 and 2 of the batch failed before being written.
+	writeParts := []int{1, 3, 4, 5}
+
+	failed := map[int]string{}
+	require.True(t, bulkWriteErrors(bwErr, writeParts, false, func(i int, err error) {
+		failed[i] = err.Error()
+	}))
+	assert.Equal(t, map[int]string{3: "duplicate key"}, failed)
+
+	failed = map[int]string{}
+	require.True(t, bulkWriteErrors(bwErr, writeParts, true, func(i int, err error) {
+		failed[i] = err.Error()
+	}))
+	assert.Equal(t, map[int]string{
+		3: "duplicate key",
+		4: errWriteNotAttempted.Error(),
+		5: errWriteNotAttempted.Error(),
+	}, failed)
+
+	bwErr.WriteConcernError = &mongo.WriteConcernError{Message: "timed out"}
+	assert.False(t, bulkWriteErrors(bwErr, writeParts, true, func(int, error) {
+		t.Error("unexpected call")
+	}))
+
+	assert.False(t, bulkWriteErrors(errors.New("nope"), writeParts, true, func(int, error) {
+		t.Error("unexpected call")
+	}))
+}
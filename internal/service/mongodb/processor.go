@@ -2,9 +2,9 @@ package mongodb
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
@@ -19,9 +19,9 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/retries"
 	"github.com/opentracing/opentracing-go"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 var documentMapOps = map[string]bool{
@@ -30,7 +30,10 @@ var documentMapOps = map[string]bool{
 	"delete-many": false,
 	"replace-one": true,
 	"update-one":  true,
+	"update-many": true,
 	"find-one":    false,
+	"find-many":   false,
+	"aggregate":   false,
 }
 
 var filterMapOps = map[string]bool{
@@ -39,7 +42,10 @@ var filterMapOps = map[string]bool{
 	"delete-many": true,
 	"replace-one": true,
 	"update-one":  true,
+	"update-many": true,
 	"find-one":    true,
+	"find-many":   true,
+	"aggregate":   false,
 }
 
 var hintAllowedOps = map[string]bool{
@@ -48,7 +54,16 @@ var hintAllowedOps = map[string]bool{
 	"delete-many": true,
 	"replace-one": true,
 	"update-one":  true,
+	"update-many": true,
 	"find-one":    true,
+	"find-many":   true,
+	"aggregate":   true,
+}
+
+var upsertAllowedOps = map[string]bool{
+	"replace-one": true,
+	"update-one":  true,
+	"update-many": true,
 }
 
 //------------------------------------------------------------------------------
@@ -65,12 +80,62 @@ func init() {
 			string(processor.CategoryIntegration),
 		},
 		Summary: `Performs operations against MongoDB for each message, allowing you to store or retrieve data within message payloads.`,
+		Description: `
+The write operations insert-one, delete-one, delete-many, replace-one, update-one and update-many are executed as a single bulk write for each batch, and leave the contents of messages unchanged.
+
+The read operations replace the contents of each message with the result of the operation. The operation find-one results in an object containing the fields of the first document matching the filter, where each value is the extended JSON representation of the field encoded as a string (e.g. ` + "`{\"a\":\"\\\"foo\\\"\"}`" + `). The operations find-many and aggregate result in an array of documents matching the filter or produced by the pipeline respectively, where documents are converted to JSON following the [relaxed extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/) format, and therefore types such as object IDs are represented as objects (e.g. ` + "`{\"$oid\":\"5f8a...\"}`" + `).
+
+If an operation fails the message is left unchanged and flagged as having failed, allowing you to handle it with [error handling patterns](/docs/configuration/error_handling).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Enriching With A Document",
+				Summary: "This example replaces the `user` field of each message with the matching document from the `users` collection.",
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.id = this.user.id'
+        processors:
+          - mongodb:
+              url: mongodb://localhost:27017
+              database: shop
+              collection: users
+              operation: find-one
+              filter_map: 'root._id = this.id'
+        result_map: 'root.user = this'
+`,
+			},
+			{
+				Title:   "Aggregating Orders",
+				Summary: "This example adds the total spend of the customer of each order, calculated with an aggregation pipeline.",
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.customer_id = this.customer_id'
+        processors:
+          - mongodb:
+              url: mongodb://localhost:27017
+              database: shop
+              collection: orders
+              operation: aggregate
+              pipeline_map: |
+                root = [
+                  { "$match": { "customer_id": this.customer_id } },
+                  { "$group": { "_id": null, "total": { "$sum": "$amount" } } },
+                ]
+        result_map: 'root.customer_total = this.index(0).total'
+`,
+			},
+		},
 		Config: docs.FieldComponent().WithChildren(
 			client.ConfigDocs().Add(
 				docs.FieldCommon(
 					"operation",
-					"The mongodb operation to perform. Must be one of the following: insert-one, delete-one, delete-many, "+
-						"replace-one, update-one, find-one.",
+					"The mongodb operation to perform.",
+				).HasOptions(
+					"insert-one", "delete-one", "delete-many", "replace-one", "update-one", "update-many",
+					"find-one", "find-many", "aggregate",
 				),
 				docs.FieldCommon(
 					"write_concern",
@@ -80,13 +145,13 @@ func init() {
 					"document_map",
 					"A bloblang map representing the records in the mongo db. Used to generate the document for mongodb by "+
 						"mapping the fields in the message to the mongodb fields. The document map is required for the operations "+
-						"insert-one, replace-one and update-one.",
+						"insert-one, replace-one, update-one and update-many.",
 					mapExamples(),
 				).Linter(docs.LintBloblangMapping),
 				docs.FieldCommon(
 					"filter_map",
 					"A bloblang map representing the filter for the mongo db command. The filter map is required for all operations except "+
-						"insert-one. It is used to find the document(s) for the operation. For example in a delete-one case, the filter map should "+
+						"insert-one and aggregate. It is used to find the document(s) for the operation. For example in a delete-one case, the filter map should "+
 						"have the fields required to locate the document to delete.",
 					mapExamples(),
 				).Linter(docs.LintBloblangMapping),
@@ -96,6 +161,17 @@ func init() {
 						"except insert-one. It is used to improve performance of finding the documents in the mongodb.",
 					mapExamples(),
 				).Linter(docs.LintBloblangMapping),
+				docs.FieldCommon(
+					"pipeline_map",
+					"A bloblang map resulting in an array of stages representing the aggregation pipeline. The pipeline map is required "+
+						"for, and only allowed with, the aggregate operation.",
+					`root = [ { "$match": { "a": this.foo } }, { "$limit": 10 } ]`,
+				).Linter(docs.LintBloblangMapping).AtVersion("3.47.0"),
+				docs.FieldAdvanced(
+					"upsert",
+					"Whether to insert a new document when the filter of a replace-one, update-one or update-many operation does not match "+
+						"any documents.",
+				).AtVersion("3.47.0"),
 				processor.PartsFieldSpec,
 			).Merge(retries.FieldSpecs())...,
 		),
@@ -118,6 +194,7 @@ type Processor struct {
 	filterMap   *mapping.Executor
 	documentMap *mapping.Executor
 	hintMap     *mapping.Executor
+	pipelineMap *mapping.Executor
 
 	shutSig *shutdown.Signaller
 
@@ -167,7 +244,7 @@ func NewProcessor(
 	var hintAllowed bool
 
 	if _, ok := documentMapOps[conf.MongoDB.Operation]; !ok {
-		return nil, fmt.Errorf("mongodb operation '%s' unknown: must be insert-one, delete-one, delete-many, replace-one, update-one or find-one", conf.MongoDB.Operation)
+		return nil, fmt.Errorf("mongodb operation '%s' unknown: must be insert-one, delete-one, delete-many, replace-one, update-one, update-many, find-one, find-many or aggregate", conf.MongoDB.Operation)
 	}
 
 	documentNeeded = documentMapOps[conf.MongoDB.Operation]
@@ -206,37 +283,36 @@ func NewProcessor(
 		return nil, fmt.Errorf("mongodb hint_map not allowed for '%s' operation", conf.MongoDB.Operation)
 	}
 
-	if m.client, err = conf.MongoDB.MongoDB.Client(); err != nil {
-		return nil, fmt.Errorf("failed to create mongodb client: %v", err)
+	if conf.MongoDB.Operation == "aggregate" {
+		if conf.MongoDB.PipelineMap == "" {
+			return nil, errors.New("mongodb pipeline_map must be specified")
+		}
+		if m.pipelineMap, err = bloblang.NewMapping("", conf.MongoDB.PipelineMap); err != nil {
+			return nil, fmt.Errorf("failed to parse pipeline_map: %v", err)
+		}
+	} else if conf.MongoDB.PipelineMap != "" {
+		return nil, fmt.Errorf("mongodb pipeline_map not allowed for '%s' operation", conf.MongoDB.Operation)
 	}
 
-	if err = m.client.Connect(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to connect: %v", err)
+	if conf.MongoDB.Upsert && !upsertAllowedOps[conf.MongoDB.Operation] {
+		return nil, fmt.Errorf("mongodb upsert not allowed for '%s' operation", conf.MongoDB.Operation)
 	}
 
-	if err = m.client.Ping(context.Background(), nil); err != nil {
-		return nil, fmt.Errorf("ping failed: %v", err)
+	writeConcern, err := conf.MongoDB.WriteConcern.Get()
+	if err != nil {
+		return nil, err
 	}
 
-	var timeout time.Duration
-	if timeout, err = time.ParseDuration(conf.MongoDB.WriteConcern.WTimeout); err != nil {
-		return nil, fmt.Errorf("failed to parse wtimeout string: %v", err)
+	if m.client, err = conf.MongoDB.MongoDB.Client(); err != nil {
+		return nil, fmt.Errorf("failed to create mongodb client: %v", err)
 	}
 
-	writeConcern := writeconcern.New(
-		writeconcern.J(conf.MongoDB.WriteConcern.J),
-		writeconcern.WTimeout(timeout))
-
-	w, err := strconv.Atoi(conf.MongoDB.WriteConcern.W)
-	if err != nil {
-		writeconcern.WTagSet(conf.MongoDB.WriteConcern.W)
-	} else {
-		writeconcern.W(w)(writeConcern)
+	if err = m.client.Connect(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
 	}
 
-	// This does some validation so we don't have to
-	if _, _, err = writeConcern.MarshalBSONValue(); err != nil {
-		return nil, fmt.Errorf("write_concern validation error: %w", err)
+	if err = m.client.Ping(context.Background(), nil); err != nil {
+		return nil, fmt.Errorf("ping failed: %v", err)
 	}
 
 	m.collection = m.client.
@@ -253,6 +329,7 @@ func (m *Processor) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 	newMsg := msg.Copy()
 
 	var writeModels []mongo.WriteModel
+	var writeParts []int
 	processor.IteratePartsWithSpan("mongodb", m.parts, newMsg, func(i int, s opentracing.Span, p types.Part) error {
 		var err error
 		var filterVal, documentVal types.Part
@@ -295,7 +372,6 @@ func (m *Processor) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			}
 		}
 
-		findOptions := &options.FindOneOptions{}
 		if m.hintMap != nil {
 			hintVal, err := m.hintMap.MapPart(i, msg)
 			if err != nil {
@@ -304,11 +380,10 @@ func (m *Processor) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			if hintJSON, err = hintVal.JSON(); err != nil {
 				return err
 			}
-			findOptions.Hint = hintJSON
 		}
 
 		var writeModel mongo.WriteModel
-		upsertFalse := false
+		upsert := m.conf.Upsert
 		switch m.conf.Operation {
 		case "insert-one":
 			writeModel = &mongo.InsertOneModel{
@@ -326,51 +401,101 @@ func (m *Processor) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			}
 		case "replace-one":
 			writeModel = &mongo.ReplaceOneModel{
-				Upsert:      &upsertFalse,
+				Upsert:      &upsert,
 				Filter:      filterJSON,
 				Replacement: docJSON,
 				Hint:        hintJSON,
 			}
 		case "update-one":
 			writeModel = &mongo.UpdateOneModel{
-				Upsert: &upsertFalse,
+				Upsert: &upsert,
+				Filter: filterJSON,
+				Update: docJSON,
+				Hint:   hintJSON,
+			}
+		case "update-many":
+			writeModel = &mongo.UpdateManyModel{
+				Upsert: &upsert,
 				Filter: filterJSON,
 				Update: docJSON,
 				Hint:   hintJSON,
 			}
 		case "find-one":
-			result := m.collection.FindOne(context.Background(), filterJSON, findOptions)
-			bsonValue, err := result.DecodeBytes()
+			findOptions := options.FindOne()
+			if hintJSON != nil {
+				findOptions.SetHint(hintJSON)
+			}
+			raw, err := m.collection.FindOne(context.Background(), filterJSON, findOptions).DecodeBytes()
 			if err != nil {
-				m.log.Errorf("Error finding document in mongo db, filter = %v", filterJSON)
+				m.log.Debugf("Error finding document in mongo db, filter = %v: %v\n", filterJSON, err)
 				return err
 			}
 
 			data := map[string]interface{}{}
-			elements, err := bsonValue.Elements()
+			elements, err := raw.Elements()
 			if err != nil {
-				m.log.Errorf("Error getting elements from document in mongo db, filter = %v", filterJSON)
+				m.log.Debugf("Error getting elements from document in mongo db, filter = %v: %v\n", filterJSON, err)
 				return err
 			}
-
 			for _, e := range elements {
-				key := e.Key()
-				value := e.Value().String()
-				data[key] = value
+				data[e.Key()] = e.Value().String()
 			}
 			return p.SetJSON(data)
+		case "find-many":
+			findOptions := options.Find()
+			if hintJSON != nil {
+				findOptions.SetHint(hintJSON)
+			}
+			cursor, err := m.collection.Find(context.Background(), filterJSON, findOptions)
+			if err != nil {
+				m.log.Debugf("Error finding documents in mongo db, filter = %v: %v\n", filterJSON, err)
+				return err
+			}
+			docs, err := cursorToJSON(cursor)
+			if err != nil {
+				return err
+			}
+			return p.SetJSON(docs)
+		case "aggregate":
+			pipelineVal, err := m.pipelineMap.MapPart(i, msg)
+			if err != nil {
+				return fmt.Errorf("failed to execute pipeline_map: %v", err)
+			}
+			pipelineJSON, err := pipelineVal.JSON()
+			if err != nil {
+				return err
+			}
+			if _, ok := pipelineJSON.([]interface{}); !ok {
+				return fmt.Errorf("pipeline_map resulted in %T, expected an array", pipelineJSON)
+			}
+			aggOptions := options.Aggregate()
+			if hintJSON != nil {
+				aggOptions.SetHint(hintJSON)
+			}
+			cursor, err := m.collection.Aggregate(context.Background(), pipelineJSON, aggOptions)
+			if err != nil {
+				m.log.Debugf("Error executing aggregation in mongo db: %v\n", err)
+				return err
+			}
+			docs, err := cursorToJSON(cursor)
+			if err != nil {
+				return err
+			}
+			return p.SetJSON(docs)
 		}
 
 		if writeModel != nil {
 			writeModels = append(writeModels, writeModel)
+			writeParts = append(writeParts, i)
 		}
 		return nil
 	})
 
 	if len(writeModels) > 0 {
 		if _, err := m.collection.BulkWrite(context.Background(), writeModels); err != nil {
-			m.log.Errorf("Bulk write failed in mongodb processor: %v", err)
-			for _, n := range m.parts {
+			m.log.Errorf("Bulk write failed in mongodb processor: %v\n", err)
+			m.mErr.Incr(1)
+			for _, n := range writeParts {
 				processor.FlagErr(newMsg.Get(n), err)
 			}
 		}
@@ -382,6 +507,38 @@ func (m *Processor) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 	return msgs[:], nil
 }
 
+// documentToJSON converts a raw BSON document into a structured value
+// following the relaxed extended JSON format.
+func documentToJSON(raw bson.Raw) (interface{}, error) {
+	jBytes, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert document to JSON: %w", err)
+	}
+	var doc interface{}
+	if err = json.Unmarshal(jBytes, &doc); err != nil {
+		return nil, fmt.Errorf("failed to convert document to JSON: %w", err)
+	}
+	return doc, nil
+}
+
+func cursorToJSON(cursor *mongo.Cursor) ([]interface{}, error) {
+	ctx := context.Background()
+	defer cursor.Close(ctx)
+
+	docs := []interface{}{}
+	for cursor.Next(ctx) {
+		doc, err := documentToJSON(cursor.Current)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
 // CloseAsync shuts down the processor and stops processing requests.
 func (m *Processor) CloseAsync() {
 	go func() {
@@ -93,6 +93,69 @@ func TestProcessorIntegration(t *testing.T) {
 	t.Run("find one", func(t *testing.T) {
 		testMongoDBProcessorFindOne(port, t)
 	})
+	t.Run("update many", func(t *testing.T) {
+		testMongoDBProcessorUpdateMany(port, t)
+	})
+	t.Run("find many and aggregate", func(t *testing.T) {
+		testMongoDBProcessorFindManyAggregate(port, t)
+	})
+}
+
+func TestProcessorBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf   func(c *processor.MongoDBConfig)
+		errStr string
+	}{
+		"unknown operation": {
+			conf:   func(c *processor.MongoDBConfig) { c.Operation = "find-all" },
+			errStr: "mongodb operation 'find-all' unknown: must be insert-one, delete-one, delete-many, replace-one, update-one, update-many, find-one, find-many or aggregate",
+		},
+		"missing filter": {
+			conf:   func(c *processor.MongoDBConfig) { c.Operation = "find-many" },
+			errStr: "mongodb filter_map must be specified",
+		},
+		"missing pipeline": {
+			conf:   func(c *processor.MongoDBConfig) { c.Operation = "aggregate" },
+			errStr: "mongodb pipeline_map must be specified",
+		},
+		"pipeline not allowed": {
+			conf: func(c *processor.MongoDBConfig) {
+				c.Operation = "find-many"
+				c.FilterMap = "root.a = this.foo"
+				c.PipelineMap = "root = []"
+			},
+			errStr: "mongodb pipeline_map not allowed for 'find-many' operation",
+		},
+		"upsert not allowed": {
+			conf: func(c *processor.MongoDBConfig) {
+				c.Operation = "find-one"
+				c.FilterMap = "root.a = this.foo"
+				c.Upsert = true
+			},
+			errStr: "mongodb upsert not allowed for 'find-one' operation",
+		},
+		"bad write concern timeout": {
+			conf: func(c *processor.MongoDBConfig) {
+				c.DocumentMap = "root = this"
+				c.WriteConcern.WTimeout = "nope"
+			},
+			errStr: "failed to parse wtimeout string: time: invalid duration \"nope\"",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := processor.NewConfig()
+			conf.Type = processor.TypeMongoDB
+			conf.MongoDB.MongoDB.Database = "TestDB"
+			conf.MongoDB.MongoDB.Collection = "TestCollection"
+			test.conf(&conf.MongoDB)
+
+			_, err := mongodb.NewProcessor(conf, nil, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
 }
 
 func testMongoDBProcessorInsert(port string, t *testing.T) {
@@ -451,6 +514,112 @@ func testMongoDBProcessorFindOne(port string, t *testing.T) {
 	assert.True(t, match(expected, string(resMsgs[0].Get(0).Get())))
 }
 
+func testMongoDBProcessorUpdateMany(port string, t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = processor.TypeMongoDB
+	conf.MongoDB.MongoDB = client.Config{
+		URL:        "mongodb://localhost:" + port,
+		Database:   "TestDB",
+		Collection: "TestCollection",
+		Username:   "mongoadmin",
+		Password:   "secret",
+	}
+	conf.MongoDB.WriteConcern.W = "majority"
+	conf.MongoDB.Operation = "update-many"
+	conf.MongoDB.FilterMap = "root.a = this.foo"
+	conf.MongoDB.DocumentMap = `root."$set".b = this.bar`
+	conf.MongoDB.Upsert = true
+
+	mongoClient, err := conf.MongoDB.MongoDB.Client()
+	require.NoError(t, err)
+	require.NoError(t, mongoClient.Connect(context.Background()))
+	collection := mongoClient.Database("TestDB").Collection("TestCollection")
+	_, err = collection.InsertMany(context.Background(), []interface{}{
+		bson.M{"a": "foo_update_many", "b": "bar1"},
+		bson.M{"a": "foo_update_many", "b": "bar2"},
+	})
+	require.NoError(t, err)
+
+	m, err := mongodb.NewProcessor(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	resMsgs, response := m.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"foo_update_many","bar":"bar_new"}`),
+		[]byte(`{"foo":"foo_upserted","bar":"bar_new"}`),
+	}))
+	require.Nil(t, response)
+	require.Len(t, resMsgs, 1)
+	assert.Empty(t, processor.GetFail(resMsgs[0].Get(0)))
+	assert.Empty(t, processor.GetFail(resMsgs[0].Get(1)))
+
+	n, err := collection.CountDocuments(context.Background(), bson.M{"a": "foo_update_many", "b": "bar_new"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = collection.CountDocuments(context.Background(), bson.M{"a": "foo_upserted", "b": "bar_new"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func testMongoDBProcessorFindManyAggregate(port string, t *testing.T) {
+	c := client.Config{
+		URL:        "mongodb://localhost:" + port,
+		Database:   "TestDB",
+		Collection: "TestCollection",
+		Username:   "mongoadmin",
+		Password:   "secret",
+	}
+
+	mongoClient, err := c.Client()
+	require.NoError(t, err)
+	require.NoError(t, mongoClient.Connect(context.Background()))
+	collection := mongoClient.Database("TestDB").Collection("TestCollection")
+	_, err = collection.InsertMany(context.Background(), []interface{}{
+		bson.M{"_id": "order1", "customer": "c1", "amount": 10},
+		bson.M{"_id": "order2", "customer": "c1", "amount": 15},
+		bson.M{"_id": "order3", "customer": "c2", "amount": 20},
+	})
+	require.NoError(t, err)
+
+	conf := processor.NewConfig()
+	conf.Type = processor.TypeMongoDB
+	conf.MongoDB.MongoDB = c
+	conf.MongoDB.Operation = "find-many"
+	conf.MongoDB.FilterMap = "root.customer = this.customer"
+
+	m, err := mongodb.NewProcessor(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	resMsgs, response := m.ProcessMessage(message.New([][]byte{
+		[]byte(`{"customer":"c1"}`),
+		[]byte(`{"customer":"c3"}`),
+	}))
+	require.Nil(t, response)
+	require.Len(t, resMsgs, 1)
+	assert.JSONEq(t, `[
+	{"_id":"order1","customer":"c1","amount":10},
+	{"_id":"order2","customer":"c1","amount":15}
+]`, string(resMsgs[0].Get(0).Get()))
+	assert.Equal(t, `[]`, string(resMsgs[0].Get(1).Get()))
+
+	conf.MongoDB.Operation = "aggregate"
+	conf.MongoDB.FilterMap = ""
+	conf.MongoDB.PipelineMap = `root = [
+  { "$match": { "customer": this.customer } },
+  { "$group": { "_id": "$customer", "total": { "$sum": "$amount" } } },
+]`
+
+	m, err = mongodb.NewProcessor(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	resMsgs, response = m.ProcessMessage(message.New([][]byte{
+		[]byte(`{"customer":"c1"}`),
+	}))
+	require.Nil(t, response)
+	require.Len(t, resMsgs, 1)
+	assert.JSONEq(t, `[{"_id":"c1","total":25}]`, string(resMsgs[0].Get(0).Get()))
+}
+
 func createCollection(resource *dockertest.Resource, collectionName, username, password string) error {
 	_, err := resource.Exec([]string{
 		"mongo",
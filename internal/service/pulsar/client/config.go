@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// TLSConfig contains configuration fields for connecting to a Pulsar server
+// over TLS.
+type TLSConfig struct {
+	RootCAsFile    string `json:"root_cas_file" yaml:"root_cas_file"`
+	SkipCertVerify bool   `json:"skip_cert_verify" yaml:"skip_cert_verify"`
+}
+
+// NewTLSConfig returns a TLSConfig with default values.
+func NewTLSConfig() TLSConfig {
+	return TLSConfig{
+		RootCAsFile:    "",
+		SkipCertVerify: false,
+	}
+}
+
+// TokenConfig contains configuration fields for authenticating with a token.
+type TokenConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Token   string `json:"token" yaml:"token"`
+}
+
+// AuthConfig contains configuration fields for authenticating with a Pulsar
+// server.
+type AuthConfig struct {
+	Token TokenConfig `json:"token" yaml:"token"`
+}
+
+// NewAuthConfig returns an AuthConfig with default values.
+func NewAuthConfig() AuthConfig {
+	return AuthConfig{
+		Token: TokenConfig{
+			Enabled: false,
+			Token:   "",
+		},
+	}
+}
+
+// TLSFieldSpec returns a docs spec for the fields of a TLSConfig.
+func TLSFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("tls", "Custom TLS settings, which apply when connecting to a `pulsar+ssl` URL.").WithChildren(
+		docs.FieldCommon(
+			"root_cas_file", "The path of a root certificate authority file used to verify the certificates of brokers, if they are not signed by a trusted authority.",
+			"./root_cas.pem",
+		),
+		docs.FieldAdvanced("skip_cert_verify", "Whether to skip server side certificate verification."),
+	)
+}
+
+// AuthFieldSpec returns a docs spec for the fields of an AuthConfig.
+func AuthFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("auth", "Optional configuration of Pulsar authentication methods.").WithChildren(
+		docs.FieldAdvanced("token", "Authenticate with a token, such as a JSON Web Token issued by the Pulsar token authentication provider.").WithChildren(
+			docs.FieldCommon("enabled", "Whether to use token authentication."),
+			docs.FieldCommon("token", "The token to authenticate with."),
+		),
+	)
+}
+
+// ClientOptions returns options for a Pulsar client connecting to a URL using
+// the provided TLS and authentication configs.
+func ClientOptions(url string, tlsConf TLSConfig, authConf AuthConfig) (pulsar.ClientOptions, error) {
+	opts := pulsar.ClientOptions{
+		URL:                        url,
+		TLSTrustCertsFilePath:      tlsConf.RootCAsFile,
+		TLSAllowInsecureConnection: tlsConf.SkipCertVerify,
+	}
+	if authConf.Token.Enabled {
+		if authConf.Token.Token == "" {
+			return opts, errors.New("field auth.token.token must not be empty when token authentication is enabled")
+		}
+		opts.Authentication = pulsar.NewAuthenticationToken(authConf.Token.Token)
+	}
+	return opts, nil
+}
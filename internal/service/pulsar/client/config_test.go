@@ -0,0 +1,43 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientOptionsDefault(t *testing.T) {
+	opts, err := ClientOptions("pulsar://localhost:6650", NewTLSConfig(), NewAuthConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, "pulsar://localhost:6650", opts.URL)
+	assert.Equal(t, "", opts.TLSTrustCertsFilePath)
+	assert.False(t, opts.TLSAllowInsecureConnection)
+	assert.Nil(t, opts.Authentication)
+}
+
+func TestClientOptionsTLSAndToken(t *testing.T) {
+	tlsConf := NewTLSConfig()
+	tlsConf.RootCAsFile = "./root_cas.pem"
+	tlsConf.SkipCertVerify = true
+
+	authConf := NewAuthConfig()
+	authConf.Token.Enabled = true
+	authConf.Token.Token = "foo"
+
+	opts, err := ClientOptions("pulsar+ssl://localhost:6651", tlsConf, authConf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "./root_cas.pem", opts.TLSTrustCertsFilePath)
+	assert.True(t, opts.TLSAllowInsecureConnection)
+	assert.NotNil(t, opts.Authentication)
+}
+
+func TestClientOptionsEmptyToken(t *testing.T) {
+	authConf := NewAuthConfig()
+	authConf.Token.Enabled = true
+
+	_, err := ClientOptions("pulsar://localhost:6650", NewTLSConfig(), authConf)
+	assert.Error(t, err)
+}
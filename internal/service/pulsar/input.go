@@ -3,11 +3,14 @@ package pulsar
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bundle"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/pulsar/client"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
@@ -33,13 +36,29 @@ func init() {
 		Version: "3.43.0",
 		Summary: `Reads messages from an Apache Pulsar server.`,
 		Description: `
+### Subscription Types
+
+The field ` + "`subscription_type`" + ` determines how messages of the
+subscription are distributed amongst consumers:
+
+- ` + "`shared`" + `: Messages are distributed to consumers in a round robin fashion.
+- ` + "`key_shared`" + `: Messages are distributed across consumers with messages of the same key (or ordering key, when set) always delivered to the same consumer.
+- ` + "`failover`" + `: Messages are delivered to a single active consumer, with the remaining consumers taking over when it disconnects.
+- ` + "`exclusive`" + `: Only a single consumer may connect to the subscription.
+
+When the subscription type is ` + "`failover`" + ` or ` + "`exclusive`" + ` the
+order of messages is only preserved when ` + "`max_in_flight`" + ` of the
+pipeline is one, as with any other input.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
 
 ` + "```text" + `
 - pulsar_key
+- pulsar_ordering_key
 - pulsar_topic
+- pulsar_publish_time_unix
 - All properties of the message
 ` + "```" + `
 
@@ -57,6 +76,11 @@ You can access these metadata fields using
 			),
 			docs.FieldCommon("topics", "A list of topics to subscribe to.").Array(),
 			docs.FieldCommon("subscription_name", "Specify the subscription name for this consumer."),
+			docs.FieldCommon("subscription_type", "Specify the subscription type for this consumer.").HasOptions(
+				"shared", "key_shared", "failover", "exclusive",
+			).AtVersion("3.47.0"),
+			client.TLSFieldSpec().AtVersion("3.47.0"),
+			client.AuthFieldSpec().AtVersion("3.47.0"),
 		),
 	})
 }
@@ -67,8 +91,10 @@ type pulsarReader struct {
 	client   pulsar.Client
 	consumer pulsar.Consumer
 
-	conf  input.PulsarConfig
-	stats metrics.Type
+	conf    input.PulsarConfig
+	opts    pulsar.ClientOptions
+	subType pulsar.SubscriptionType
+	stats   metrics.Type
 	log   log.Modular
 
 	m       sync.RWMutex
@@ -91,9 +117,32 @@ func newPulsarReader(conf input.PulsarConfig, log log.Modular, stats metrics.Typ
 		log:     log,
 		shutSig: shutdown.NewSignaller(),
 	}
+	var err error
+	if p.subType, err = parseSubscriptionType(conf.SubscriptionType); err != nil {
+		return nil, err
+	}
+	if p.opts, err = client.ClientOptions(conf.URL, conf.TLS, conf.Auth); err != nil {
+		return nil, err
+	}
+	p.opts.Logger = NoopLogger()
+	p.opts.ConnectionTimeout = time.Second * 3
 	return &p, nil
 }
 
+func parseSubscriptionType(subType string) (pulsar.SubscriptionType, error) {
+	switch subType {
+	case "shared", "":
+		return pulsar.Shared, nil
+	case "key_shared":
+		return pulsar.KeyShared, nil
+	case "failover":
+		return pulsar.Failover, nil
+	case "exclusive":
+		return pulsar.Exclusive, nil
+	}
+	return pulsar.Shared, fmt.Errorf("subscription type %v not recognised", subType)
+}
+
 //------------------------------------------------------------------------------
 
 // ConnectWithContext establishes a connection to an Pulsar server.
@@ -111,18 +160,14 @@ func (p *pulsarReader) ConnectWithContext(ctx context.Context) error {
 		err      error
 	)
 
-	if client, err = pulsar.NewClient(pulsar.ClientOptions{
-		Logger:            NoopLogger(),
-		ConnectionTimeout: time.Second * 3,
-		URL:               p.conf.URL,
-	}); err != nil {
+	if client, err = pulsar.NewClient(p.opts); err != nil {
 		return err
 	}
 
 	if consumer, err = client.Subscribe(pulsar.ConsumerOptions{
 		Topics:           p.conf.Topics,
 		SubscriptionName: p.conf.SubscriptionName,
-		Type:             pulsar.Shared,
+		Type:             p.subType,
 	}); err != nil {
 		client.Close()
 		return err
@@ -191,7 +236,11 @@ func (p *pulsarReader) ReadWithContext(ctx context.Context) (types.Message, read
 	if key := pulMsg.Key(); len(key) > 0 {
 		part.Metadata().Set("pulsar_key", key)
 	}
+	if orderingKey := pulMsg.OrderingKey(); len(orderingKey) > 0 {
+		part.Metadata().Set("pulsar_ordering_key", orderingKey)
+	}
 	part.Metadata().Set("pulsar_topic", pulMsg.Topic())
+	part.Metadata().Set("pulsar_publish_time_unix", strconv.FormatInt(pulMsg.PublishTime().Unix(), 10))
 	for k, v := range pulMsg.Properties() {
 		part.Metadata().Set(k, v)
 	}
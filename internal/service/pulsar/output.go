@@ -3,11 +3,16 @@ package pulsar
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/bundle"
+	ioutput "github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/pulsar/client"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -34,6 +39,21 @@ func init() {
 		Status:  docs.StatusExperimental,
 		Version: "3.43.0",
 		Summary: `Write messages to an Apache Pulsar server.`,
+		Description: `
+### Keys
+
+The fields ` + "`key`" + ` and ` + "`ordering_key`" + ` can be interpolated from
+the contents and metadata of each message. Consumers of a ` + "`key_shared`" + `
+subscription receive messages according to their ordering key, or their key if
+an ordering key is not set, which ensures that all messages of a given key are
+delivered to the same consumer. When either field is set batching within the
+producer is disabled, as otherwise batches of messages with different keys would
+be delivered to a single consumer.
+
+### Metadata
+
+Metadata fields of each message are sent as properties of the Pulsar message,
+and can be filtered with the field ` + "`metadata`" + `.`,
 		Categories: []string{
 			string(output.CategoryServices),
 		},
@@ -45,6 +65,11 @@ func init() {
 				"pulsar+ssl://pulsar.us-west.example.com:6651",
 			),
 			docs.FieldCommon("topic", "A topic to publish to."),
+			docs.FieldCommon("key", "The key of messages, used for routing messages to consumers of a `key_shared` subscription and for selecting the partition of partitioned topics.", `${! meta("user_id") }`).IsInterpolated().AtVersion("3.47.0"),
+			docs.FieldAdvanced("ordering_key", "The ordering key of messages, which takes precedence over the key for routing messages to consumers of a `key_shared` subscription.", `${! json("session_id") }`).IsInterpolated().AtVersion("3.47.0"),
+			docs.FieldCommon("metadata", "Specify criteria for which metadata values are sent as properties of messages.").WithChildren(ioutput.MetadataFields()...).AtVersion("3.47.0"),
+			client.TLSFieldSpec().AtVersion("3.47.0"),
+			client.AuthFieldSpec().AtVersion("3.47.0"),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 		),
 	})
@@ -56,9 +81,13 @@ type pulsarWriter struct {
 	client   pulsar.Client
 	producer pulsar.Producer
 
-	conf  output.PulsarConfig
-	stats metrics.Type
-	log   log.Modular
+	conf        output.PulsarConfig
+	opts        pulsar.ClientOptions
+	key         *field.Expression
+	orderingKey *field.Expression
+	metaFilter  *ioutput.MetadataFilter
+	stats       metrics.Type
+	log         log.Modular
 
 	m       sync.RWMutex
 	shutSig *shutdown.Signaller
@@ -77,6 +106,20 @@ func newPulsarWriter(conf output.PulsarConfig, log log.Modular, stats metrics.Ty
 		log:     log,
 		shutSig: shutdown.NewSignaller(),
 	}
+	var err error
+	if p.opts, err = client.ClientOptions(conf.URL, conf.TLS, conf.Auth); err != nil {
+		return nil, err
+	}
+	p.opts.Logger = NoopLogger()
+	if p.key, err = bloblang.NewField(conf.Key); err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	if p.orderingKey, err = bloblang.NewField(conf.OrderingKey); err != nil {
+		return nil, fmt.Errorf("failed to parse ordering key expression: %v", err)
+	}
+	if p.metaFilter, err = conf.Metadata.Filter(); err != nil {
+		return nil, fmt.Errorf("failed to construct metadata filter: %w", err)
+	}
 	return &p, nil
 }
 
@@ -97,15 +140,13 @@ func (p *pulsarWriter) ConnectWithContext(ctx context.Context) error {
 		err      error
 	)
 
-	if client, err = pulsar.NewClient(pulsar.ClientOptions{
-		URL:    p.conf.URL,
-		Logger: NoopLogger(),
-	}); err != nil {
+	if client, err = pulsar.NewClient(p.opts); err != nil {
 		return err
 	}
 
 	if producer, err = client.CreateProducer(pulsar.ProducerOptions{
-		Topic: p.conf.Topic,
+		Topic:           p.conf.Topic,
+		DisableBatching: p.conf.Key != "" || p.conf.OrderingKey != "",
 	}); err != nil {
 		client.Close()
 		return err
@@ -155,10 +196,19 @@ func (p *pulsarWriter) WriteWithContext(ctx context.Context, msg types.Message)
 		return types.ErrNotConnected
 	}
 
-	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+	return writer.IterateBatchedSend(msg, func(i int, part types.Part) error {
 		m := &pulsar.ProducerMessage{
-			Payload: p.Get(),
+			Payload:     part.Get(),
+			Key:         p.key.String(i, msg),
+			OrderingKey: p.orderingKey.String(i, msg),
 		}
+		p.metaFilter.Iter(part.Metadata(), func(k, v string) error {
+			if m.Properties == nil {
+				m.Properties = map[string]string{}
+			}
+			m.Properties[k] = v
+			return nil
+		})
 		_, err := r.Send(context.Background(), m)
 		return err
 	})
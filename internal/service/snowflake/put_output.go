@@ -0,0 +1,566 @@
+package snowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bundle"
+	ioutput "github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	mbatch "github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/snowflakedb/gosnowflake"
+	"github.com/youmark/pkcs8"
+)
+
+func init() {
+	bundle.AllOutputs.Add(bundle.OutputConstructorFromSimple(func(c output.Config, nm bundle.NewManagement) (output.Type, error) {
+		s, err := newSnowflakePutOutput(c.SnowflakePut, nm.Logger(), nm.Metrics())
+		if err != nil {
+			return nil, err
+		}
+		w, err := output.NewAsyncWriter(output.TypeSnowflakePut, c.SnowflakePut.MaxInFlight, s, nm.Logger(), nm.Metrics())
+		if err != nil {
+			return nil, err
+		}
+		return output.NewBatcherFromConfig(c.SnowflakePut.Batching, w, nm, nm.Logger(), nm.Metrics())
+	}), docs.ComponentSpec{
+		Name:    output.TypeSnowflakePut,
+		Type:    docs.TypeOutput,
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Categories: []string{
+			string(output.CategoryServices),
+		},
+		Summary: `
+Stages batches of messages as files within a Snowflake internal stage and
+optionally loads them into a table with Snowpipe or a ` + "`COPY INTO`" + ` statement.`,
+		Description: ioutput.Description(true, true, `
+The messages of each batch are joined with newlines into a file, which is compressed with gzip unless the `+"`compression`"+` is `+"`none`"+`, and uploaded to an [internal stage](https://docs.snowflake.com/en/user-guide/data-load-local-file-system-create-stage.html) with a `+"`PUT`"+` statement. Files are given a random name, and the `+"`stage`"+` and `+"`path`"+` support [interpolation functions](/docs/configuration/interpolation#bloblang-queries) that are calculated per message of a batch, in which case a file is staged for each distinct location.
+
+### Authentication
+
+Benthos connects to Snowflake with [key pair authentication](https://docs.snowflake.com/en/user-guide/key-pair-auth.html), where the `+"`private_key_file`"+` is a PEM encoded PKCS #8 RSA private key of the `+"`user`"+`, which is decrypted with `+"`private_key_pass`"+` when it's encrypted.
+
+### Loading Data
+
+When a `+"`snowpipe`"+` is set the staged files are submitted to the pipe with the [Snowpipe REST API](https://docs.snowflake.com/en/user-guide/data-load-snowpipe-rest-apis.html), which loads them asynchronously. The pipe must copy from the same stage that files are uploaded to, as files are submitted with their path relative to the stage. Messages are acknowledged once Snowpipe has accepted their files, rather than once they are loaded.
+
+When `+"`copy_into`"+` is set to the name of a table each staged file is instead loaded into the table with a `+"`COPY INTO`"+` statement using the `+"`file_format`"+`, and messages are acknowledged once the statement has completed, which requires a `+"`warehouse`"+`.
+
+When neither is set files are only staged, and can be loaded with external tooling.`),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Snowpipe",
+				Summary: "In this example we stage batches of JSON events from Kafka to the stage of a table every minute, partitioned by the day on which they are sent, and submit them to a Snowpipe that copies from the same stage.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: snowflake
+
+output:
+  snowflake_put:
+    account: xy12345
+    region: eu-west-1
+    user: BENTHOS
+    private_key_file: ./rsa_key.p8
+    role: INGEST
+    database: ANALYTICS
+    schema: PUBLIC
+    stage: "@%EVENTS"
+    path: ${! timestamp_utc("2006/01/02") }
+    snowpipe: EVENTS_PIPE
+    batching:
+      count: 10000
+      period: 1m
+`,
+			},
+		},
+		Config: docs.FieldComponent().WithChildren(
+			docs.FieldCommon("account", "The [account identifier](https://docs.snowflake.com/en/user-guide/admin-account-identifier.html) of the Snowflake account.", "xy12345"),
+			docs.FieldCommon("region", "The region of the Snowflake account, which can be omitted when it's part of the `account` or for accounts within `us-west-2`.", "eu-west-1", "us-east-2.aws"),
+			docs.FieldCommon("user", "The user to connect as."),
+			docs.FieldCommon("private_key_file", "The path to a PEM encoded PKCS #8 RSA private key of the user.", "./rsa_key.p8"),
+			docs.FieldAdvanced("private_key_pass", "An optional passphrase for decrypting the private key."),
+			docs.FieldCommon("role", "An optional role to use for the session, which otherwise defaults to the default role of the user."),
+			docs.FieldCommon("database", "The database of the stage, pipe and table."),
+			docs.FieldCommon("warehouse", "An optional warehouse to use for the session, which is required by `copy_into`."),
+			docs.FieldCommon("schema", "The schema of the stage, pipe and table."),
+			docs.FieldCommon(
+				"stage", "The internal stage to upload files to, which can be a named stage, the stage of a table or the stage of the user.",
+				"@my_stage", "@%my_table", "@~", `@%${! meta("table") }`,
+			).IsInterpolated(),
+			docs.FieldCommon("path", "An optional path within the stage to upload files to.", "events", `${! timestamp_utc("2006/01/02") }`).IsInterpolated(),
+			docs.FieldAdvanced("compression", "The compression applied to the files.").HasOptions("gzip", "none"),
+			docs.FieldAdvanced("upload_parallel_threads", "The number of threads with which each file is uploaded, between `1` and `99`."),
+			docs.FieldCommon("snowpipe", "An optional Snowpipe to submit staged files to, which is qualified with the `database` and `schema` unless it contains a period.", "my_pipe"),
+			docs.FieldCommon("copy_into", "An optional table to load staged files into with a `COPY INTO` statement, which can't be used along with `snowpipe`.", "my_table"),
+			docs.FieldAdvanced("file_format", "The format options of `COPY INTO` statements, or the name of a file format in the form `FORMAT_NAME = my_format`.", "TYPE = JSON", "TYPE = CSV FIELD_DELIMITER = '|'", "FORMAT_NAME = my_format"),
+			docs.FieldCommon("max_in_flight", "The maximum number of batches to have in flight at a given time. Increase this to improve throughput."),
+			mbatch.FieldSpec(),
+		),
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// snowpipeJWTExpiry is the lifetime of the tokens used to authenticate Snowpipe
+// requests, which may be at most an hour.
+const snowpipeJWTExpiry = time.Minute * 59
+
+// snowflakeDB is the subset of *sql.DB used to run statements.
+type snowflakeDB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Close() error
+}
+
+// snowflakeUpload is a file staged from the messages of a batch that share a
+// location.
+type snowflakeUpload struct {
+	stage   string
+	path    string
+	indexes []int
+}
+
+// location returns the stage location of the file.
+func (u *snowflakeUpload) location() string {
+	if u.path == "" {
+		return u.stage
+	}
+	return strings.TrimSuffix(u.stage, "/") + "/" + u.path
+}
+
+//------------------------------------------------------------------------------
+
+// snowflakePutOutput is a benthos writer.Type implementation that uploads
+// messages to a Snowflake internal stage.
+type snowflakePutOutput struct {
+	conf output.SnowflakePutConfig
+
+	stage      *field.Expression
+	path       *field.Expression
+	privateKey *rsa.PrivateKey
+	pipe       string
+	pipeURL    string
+
+	httpClient *http.Client
+	openDB     func(ctx context.Context) (snowflakeDB, error)
+	fileName   func() (string, error)
+	now        func() time.Time
+
+	db      snowflakeDB
+	connMut sync.RWMutex
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// newSnowflakePutOutput creates a new Snowflake PUT writer.Type.
+func newSnowflakePutOutput(
+	conf output.SnowflakePutConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*snowflakePutOutput, error) {
+	if conf.Account == "" {
+		return nil, errors.New("an account must be specified")
+	}
+	if conf.User == "" {
+		return nil, errors.New("a user must be specified")
+	}
+	if conf.PrivateKeyFile == "" {
+		return nil, errors.New("a private_key_file must be specified")
+	}
+	if conf.Stage == "" {
+		return nil, errors.New("a stage must be specified")
+	}
+	if conf.Compression != "gzip" && conf.Compression != "none" {
+		return nil, fmt.Errorf("unrecognised compression: %v", conf.Compression)
+	}
+	if conf.UploadParallelThreads < 1 || conf.UploadParallelThreads > 99 {
+		return nil, fmt.Errorf("upload_parallel_threads must be between 1 and 99, got %v", conf.UploadParallelThreads)
+	}
+	if conf.Snowpipe != "" && conf.CopyInto != "" {
+		return nil, errors.New("cannot specify both a snowpipe and copy_into")
+	}
+	if conf.CopyInto != "" && conf.FileFormat == "" {
+		return nil, errors.New("a file_format must be specified in order to use copy_into")
+	}
+
+	s := &snowflakePutOutput{
+		conf:       conf,
+		httpClient: &http.Client{Timeout: time.Minute},
+		now:        time.Now,
+		log:        log,
+		stats:      stats,
+	}
+	s.openDB = s.openSnowflakeDB
+	s.fileName = s.randomFileName
+
+	var err error
+	if s.stage, err = bloblang.NewField(conf.Stage); err != nil {
+		return nil, fmt.Errorf("failed to parse stage expression: %v", err)
+	}
+	if s.path, err = bloblang.NewField(conf.Path); err != nil {
+		return nil, fmt.Errorf("failed to parse path expression: %v", err)
+	}
+	if s.privateKey, err = loadPrivateKey(conf.PrivateKeyFile, conf.PrivateKeyPass); err != nil {
+		return nil, err
+	}
+	if conf.Snowpipe != "" {
+		if s.pipe = conf.Snowpipe; !strings.Contains(s.pipe, ".") {
+			if conf.Database == "" || conf.Schema == "" {
+				return nil, errors.New("a database and schema must be specified in order to use an unqualified snowpipe")
+			}
+			s.pipe = conf.Database + "." + conf.Schema + "." + s.pipe
+		}
+		s.pipeURL = fmt.Sprintf("https://%v.snowflakecomputing.com/v1/data/pipes/%v/insertFiles", s.host(), url.PathEscape(s.pipe))
+	}
+	return s, nil
+}
+
+// loadPrivateKey reads a PEM encoded PKCS #8 RSA private key, which is
+// decrypted with the passphrase when one is provided.
+func loadPrivateKey(path, pass string) (*rsa.PrivateKey, error) {
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode private key: no PEM data found")
+	}
+	if pass != "" {
+		key, err := pkcs8.ParsePKCS8PrivateKeyRSA(block.Bytes, []byte(pass))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an RSA private key, got %T", key)
+	}
+	return rsaKey, nil
+}
+
+// host returns the account identifier along with its region, which forms the
+// host name of the account.
+func (s *snowflakePutOutput) host() string {
+	if s.conf.Region == "" {
+		return s.conf.Account
+	}
+	return s.conf.Account + "." + s.conf.Region
+}
+
+func (s *snowflakePutOutput) randomFileName() (string, error) {
+	u4, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	name := u4.String() + ".json"
+	if s.conf.Compression == "gzip" {
+		name += ".gz"
+	}
+	return name, nil
+}
+
+func (s *snowflakePutOutput) openSnowflakeDB(ctx context.Context) (snowflakeDB, error) {
+	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, gosnowflake.Config{
+		Account:       s.conf.Account,
+		Region:        s.conf.Region,
+		User:          s.conf.User,
+		Role:          s.conf.Role,
+		Database:      s.conf.Database,
+		Warehouse:     s.conf.Warehouse,
+		Schema:        s.conf.Schema,
+		Authenticator: gosnowflake.AuthTypeJwt,
+		PrivateKey:    s.privateKey,
+		Application:   "benthos",
+	}))
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// ConnectWithContext attempts to establish a connection to Snowflake.
+func (s *snowflakePutOutput) ConnectWithContext(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	var err error
+	if s.db, err = s.openDB(ctx); err != nil {
+		return err
+	}
+
+	s.log.Infof("Uploading message batches to Snowflake stage: %v\n", s.conf.Stage)
+	return nil
+}
+
+// uploads groups the indexes of the messages of a batch by their stage
+// location, returning the uploads in the order they first appear.
+func (s *snowflakePutOutput) uploads(msg types.Message) []*snowflakeUpload {
+	var uploads []*snowflakeUpload
+	locations := map[[2]string]*snowflakeUpload{}
+	for i := 0; i < msg.Len(); i++ {
+		stage := s.stage.String(i, msg)
+		if !strings.HasPrefix(stage, "@") {
+			stage = "@" + stage
+		}
+		path := strings.Trim(s.path.String(i, msg), "/")
+
+		u, exists := locations[[2]string{stage, path}]
+		if !exists {
+			u = &snowflakeUpload{stage: stage, path: path}
+			locations[[2]string{stage, path}] = u
+			uploads = append(uploads, u)
+		}
+		u.indexes = append(u.indexes, i)
+	}
+	return uploads
+}
+
+// stageFile returns the contents of the file staged for messages of a batch.
+func (s *snowflakePutOutput) stageFile(msg types.Message, indexes []int) ([]byte, error) {
+	var buf bytes.Buffer
+	if s.conf.Compression != "gzip" {
+		for _, i := range indexes {
+			buf.Write(msg.Get(i).Get())
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+	}
+	zw := gzip.NewWriter(&buf)
+	for _, i := range indexes {
+		if _, err := zw.Write(msg.Get(i).Get()); err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write([]byte{'\n'}); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *snowflakePutOutput) put(ctx context.Context, db snowflakeDB, u *snowflakeUpload, name string, data []byte) error {
+	compression := "NONE"
+	if s.conf.Compression == "gzip" {
+		compression = "GZIP"
+	}
+	query := fmt.Sprintf(
+		"PUT 'file://%v' '%v' AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = %v PARALLEL = %v",
+		name, u.location(), compression, s.conf.UploadParallelThreads,
+	)
+	if _, err := db.ExecContext(gosnowflake.WithFileStream(ctx, bytes.NewReader(data)), query); err != nil {
+		return fmt.Errorf("failed to put file: %w", err)
+	}
+	return nil
+}
+
+func (s *snowflakePutOutput) copyInto(ctx context.Context, db snowflakeDB, u *snowflakeUpload, name string) error {
+	query := fmt.Sprintf(
+		"COPY INTO %v FROM '%v' FILES = ('%v') FILE_FORMAT = (%v)",
+		s.conf.CopyInto, u.location(), name, s.conf.FileFormat,
+	)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to copy file into table: %w", err)
+	}
+	return nil
+}
+
+// snowpipeJWT creates a token for authenticating requests to the Snowpipe REST
+// API with the key pair of the user.
+func (s *snowflakePutOutput) snowpipeJWT() (string, error) {
+	pubBytes, err := x509.MarshalPKIXPublicKey(s.privateKey.Public())
+	if err != nil {
+		return "", err
+	}
+	fingerprint := sha256.Sum256(pubBytes)
+
+	account := strings.ToUpper(s.conf.Account)
+	if i := strings.Index(account, "."); i >= 0 {
+		account = account[:i]
+	}
+	user := strings.ToUpper(s.conf.User)
+
+	now := s.now()
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": fmt.Sprintf("%v.%v.SHA256:%v", account, user, base64.StdEncoding.EncodeToString(fingerprint[:])),
+		"sub": fmt.Sprintf("%v.%v", account, user),
+		"iat": now.Unix(),
+		"exp": now.Add(snowpipeJWTExpiry).Unix(),
+	}).SignedString(s.privateKey)
+}
+
+type snowpipeFile struct {
+	Path string `json:"path"`
+}
+
+// insertFiles submits staged files to the Snowpipe, where the paths are
+// relative to the stage of the pipe.
+func (s *snowflakePutOutput) insertFiles(ctx context.Context, paths []string) error {
+	files := make([]snowpipeFile, 0, len(paths))
+	for _, p := range paths {
+		files = append(files, snowpipeFile{Path: p})
+	}
+	body, err := json.Marshal(map[string]interface{}{"files": files})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.snowpipeJWT()
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	requestID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.pipeURL+"?requestId="+requestID.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit files to snowpipe: %w", err)
+	}
+	defer res.Body.Close()
+
+	resBody, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("snowpipe returned status %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	var resObj struct {
+		ResponseCode string `json:"responseCode"`
+	}
+	if err = json.Unmarshal(resBody, &resObj); err != nil {
+		return fmt.Errorf("failed to parse snowpipe response: %w", err)
+	}
+	if resObj.ResponseCode != "SUCCESS" {
+		return fmt.Errorf("snowpipe returned response code: %v", resObj.ResponseCode)
+	}
+	return nil
+}
+
+// WriteWithContext attempts to upload the messages of a batch to Snowflake.
+func (s *snowflakePutOutput) WriteWithContext(ctx context.Context, msg types.Message) error {
+	s.connMut.RLock()
+	db := s.db
+	s.connMut.RUnlock()
+
+	if db == nil {
+		return types.ErrNotConnected
+	}
+
+	var batchErr *batch.Error
+	failed := func(indexes []int, err error) {
+		if batchErr == nil {
+			batchErr = batch.NewError(msg, err)
+		}
+		for _, i := range indexes {
+			batchErr.Failed(i, err)
+		}
+	}
+
+	var piped []*snowflakeUpload
+	var pipePaths []string
+	for _, u := range s.uploads(msg) {
+		name, err := s.fileName()
+		if err != nil {
+			failed(u.indexes, err)
+			continue
+		}
+		data, err := s.stageFile(msg, u.indexes)
+		if err == nil {
+			err = s.put(ctx, db, u, name, data)
+		}
+		if err == nil && s.conf.CopyInto != "" {
+			err = s.copyInto(ctx, db, u, name)
+		}
+		if err != nil {
+			s.log.Errorf("Failed to upload messages to stage %v: %v\n", u.location(), err)
+			failed(u.indexes, err)
+			continue
+		}
+		if s.pipeURL != "" {
+			piped = append(piped, u)
+			pipePaths = append(pipePaths, strings.TrimPrefix(u.path+"/"+name, "/"))
+		}
+	}
+
+	if len(pipePaths) > 0 {
+		if err := s.insertFiles(ctx, pipePaths); err != nil {
+			s.log.Errorf("Failed to submit files to snowpipe %v: %v\n", s.pipe, err)
+			for _, u := range piped {
+				failed(u.indexes, err)
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (s *snowflakePutOutput) CloseAsync() {
+	go func() {
+		s.connMut.Lock()
+		if s.db != nil {
+			s.db.Close()
+			s.db = nil
+		}
+		s.connMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (s *snowflakePutOutput) WaitForClose(time.Duration) error {
+	return nil
+}
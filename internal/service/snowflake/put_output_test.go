@@ -0,0 +1,279 @@
+package snowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/youmark/pkcs8"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+func testPrivateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		var err error
+		testKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+	})
+	return testKey
+}
+
+func writePrivateKey(t *testing.T, pass string) string {
+	t.Helper()
+
+	key := testPrivateKey(t)
+	var der []byte
+	var err error
+	if pass == "" {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	} else {
+		der, err = pkcs8.MarshalPrivateKey(key, []byte(pass), nil)
+	}
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "rsa_key.p8")
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}), 0600))
+	return path
+}
+
+func testSnowflakePutConfig(t *testing.T) output.SnowflakePutConfig {
+	conf := output.NewSnowflakePutConfig()
+	conf.Account = "xy12345"
+	conf.Region = "eu-west-1"
+	conf.User = "benthos"
+	conf.PrivateKeyFile = writePrivateKey(t, "")
+	conf.Database = "ANALYTICS"
+	conf.Schema = "PUBLIC"
+	conf.Stage = `${! meta("stage") }`
+	conf.Path = `/${! meta("day") }/`
+	return conf
+}
+
+type fakeSnowflakeDB struct {
+	mut     sync.Mutex
+	queries []string
+	failOn  string
+}
+
+func (f *fakeSnowflakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.queries = append(f.queries, query)
+	if f.failOn != "" && strings.Contains(query, f.failOn) {
+		return nil, sql.ErrConnDone
+	}
+	return nil, nil
+}
+
+func (f *fakeSnowflakeDB) Close() error {
+	return nil
+}
+
+func newTestSnowflakePutOutput(t *testing.T, conf output.SnowflakePutConfig, db *fakeSnowflakeDB) *snowflakePutOutput {
+	t.Helper()
+
+	s, err := newSnowflakePutOutput(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	s.openDB = func(context.Context) (snowflakeDB, error) {
+		return db, nil
+	}
+	var files int
+	s.fileName = func() (string, error) {
+		files++
+		return fmt.Sprintf("file%v.json.gz", files), nil
+	}
+	require.NoError(t, s.ConnectWithContext(context.Background()))
+	return s
+}
+
+func testSnowflakeMessage() *message.Type {
+	msg := message.New([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":2}`),
+		[]byte(`{"id":3}`),
+	})
+	msg.Get(0).Metadata().Set("stage", "%EVENTS").Set("day", "2021/06/01")
+	msg.Get(1).Metadata().Set("stage", "@%EVENTS").Set("day", "2021/06/02")
+	msg.Get(2).Metadata().Set("stage", "@%EVENTS").Set("day", "2021/06/01")
+	return msg
+}
+
+func TestSnowflakePutStage(t *testing.T) {
+	s, err := newSnowflakePutOutput(testSnowflakePutConfig(t), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := testSnowflakeMessage()
+	uploads := s.uploads(msg)
+	require.Len(t, uploads, 2)
+	assert.Equal(t, "@%EVENTS/2021/06/01", uploads[0].location())
+	assert.Equal(t, []int{0, 2}, uploads[0].indexes)
+	assert.Equal(t, "@%EVENTS/2021/06/02", uploads[1].location())
+	assert.Equal(t, []int{1}, uploads[1].indexes)
+
+	data, err := s.stageFile(msg, uploads[0].indexes)
+	require.NoError(t, err)
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	raw, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":1}\n{\"id\":3}\n", string(raw))
+
+	s.conf.Compression = "none"
+	data, err = s.stageFile(msg, uploads[1].indexes)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":2}\n", string(data))
+}
+
+func TestSnowflakePutCopyInto(t *testing.T) {
+	conf := testSnowflakePutConfig(t)
+	conf.CopyInto = "EVENTS"
+
+	db := &fakeSnowflakeDB{}
+	s := newTestSnowflakePutOutput(t, conf, db)
+
+	require.NoError(t, s.WriteWithContext(context.Background(), testSnowflakeMessage()))
+	assert.Equal(t, []string{
+		"PUT 'file://file1.json.gz' '@%EVENTS/2021/06/01' AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP PARALLEL = 4",
+		"COPY INTO EVENTS FROM '@%EVENTS/2021/06/01' FILES = ('file1.json.gz') FILE_FORMAT = (TYPE = JSON)",
+		"PUT 'file://file2.json.gz' '@%EVENTS/2021/06/02' AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP PARALLEL = 4",
+		"COPY INTO EVENTS FROM '@%EVENTS/2021/06/02' FILES = ('file2.json.gz') FILE_FORMAT = (TYPE = JSON)",
+	}, db.queries)
+
+	db.failOn = "2021/06/02"
+	err := s.WriteWithContext(context.Background(), testSnowflakeMessage())
+	require.Error(t, err)
+
+	var bErr *batch.Error
+	require.ErrorAs(t, err, &bErr)
+	var failed []int
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+}
+
+func TestSnowflakePutSnowpipe(t *testing.T) {
+	var reqs []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/data/pipes/ANALYTICS.PUBLIC.EVENTS_PIPE/insertFiles", r.URL.Path)
+		assert.NotEmpty(t, r.URL.Query().Get("requestId"))
+
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims, func(*jwt.Token) (interface{}, error) {
+			return testPrivateKey(t).Public(), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "XY12345.BENTHOS", claims["sub"])
+		assert.True(t, strings.HasPrefix(claims["iss"].(string), "XY12345.BENTHOS.SHA256:"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		reqs = append(reqs, body)
+
+		w.Write([]byte(`{"responseCode":"SUCCESS"}`))
+	}))
+	defer server.Close()
+
+	conf := testSnowflakePutConfig(t)
+	conf.Snowpipe = "EVENTS_PIPE"
+
+	db := &fakeSnowflakeDB{}
+	s := newTestSnowflakePutOutput(t, conf, db)
+	s.pipeURL = server.URL + "/v1/data/pipes/" + s.pipe + "/insertFiles"
+
+	require.NoError(t, s.WriteWithContext(context.Background(), testSnowflakeMessage()))
+	assert.Len(t, db.queries, 2)
+	assert.Equal(t, []map[string]interface{}{
+		{"files": []interface{}{
+			map[string]interface{}{"path": "2021/06/01/file1.json.gz"},
+			map[string]interface{}{"path": "2021/06/02/file2.json.gz"},
+		}},
+	}, reqs)
+}
+
+func TestSnowflakePutPrivateKey(t *testing.T) {
+	key, err := loadPrivateKey(writePrivateKey(t, "foo"), "foo")
+	require.NoError(t, err)
+	assert.True(t, key.Equal(testPrivateKey(t)))
+
+	_, err = loadPrivateKey(writePrivateKey(t, "foo"), "bar")
+	require.Error(t, err)
+}
+
+func TestSnowflakePutConfigErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		fn  func(*output.SnowflakePutConfig)
+		err string
+	}{
+		"no stage": {
+			fn:  func(c *output.SnowflakePutConfig) { c.Stage = "" },
+			err: "a stage must be specified",
+		},
+		"bad compression": {
+			fn:  func(c *output.SnowflakePutConfig) { c.Compression = "zstd" },
+			err: "unrecognised compression: zstd",
+		},
+		"bad upload threads": {
+			fn:  func(c *output.SnowflakePutConfig) { c.UploadParallelThreads = 100 },
+			err: "upload_parallel_threads must be between 1 and 99, got 100",
+		},
+		"snowpipe and copy into": {
+			fn: func(c *output.SnowflakePutConfig) {
+				c.Snowpipe = "EVENTS_PIPE"
+				c.CopyInto = "EVENTS"
+			},
+			err: "cannot specify both a snowpipe and copy_into",
+		},
+		"unqualified snowpipe": {
+			fn: func(c *output.SnowflakePutConfig) {
+				c.Snowpipe = "EVENTS_PIPE"
+				c.Schema = ""
+			},
+			err: "a database and schema must be specified in order to use an unqualified snowpipe",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := testSnowflakePutConfig(t)
+			test.fn(&conf)
+			_, err := newSnowflakePutOutput(conf, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
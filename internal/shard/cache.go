@@ -0,0 +1,142 @@
+package shard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// cacheLockTTL is the duration after which a lock held by a member that failed
+// to release it is considered abandoned and can be broken by other members.
+const cacheLockTTL = time.Second * 5
+
+// CacheMembership is a membership stored as a key of a cache resource, where
+// the value contains each member and the time at which it expires.
+//
+// Updates to the group are serialised with a lock key that is created with the
+// atomic add operation of the cache, so that concurrent members do not
+// overwrite each other. A lock that is not released, for example due to a
+// member crashing whilst holding it, is broken once it has expired. The clocks
+// of all members are expected to be roughly in sync.
+type CacheMembership struct {
+	mgr      types.Manager
+	resource string
+	key      string
+	lockKey  string
+}
+
+// NewCacheMembership creates a membership stored under a key of a cache
+// resource.
+func NewCacheMembership(mgr types.Manager, resource, key string) (*CacheMembership, error) {
+	if err := interop.ProbeCache(context.Background(), mgr, resource); err != nil {
+		return nil, err
+	}
+	return &CacheMembership{
+		mgr:      mgr,
+		resource: resource,
+		key:      key,
+		lockKey:  key + "_lock",
+	}, nil
+}
+
+// lock blocks until the lock key of the group has been added to the cache, or
+// until the context is cancelled.
+func (m *CacheMembership) lock(ctx context.Context, c types.Cache) error {
+	for {
+		expires, err := time.Now().Add(cacheLockTTL).MarshalText()
+		if err != nil {
+			return err
+		}
+		if err = c.Add(m.lockKey, expires); err == nil {
+			return nil
+		}
+		if !errors.Is(err, types.ErrKeyAlreadyExists) {
+			return fmt.Errorf("failed to acquire lock: %w", err)
+		}
+
+		// Break the lock once it has expired, otherwise wait for it to be
+		// released.
+		var heldUntil time.Time
+		if b, err := c.Get(m.lockKey); err == nil && heldUntil.UnmarshalText(b) == nil && time.Now().After(heldUntil) {
+			if err = c.Delete(m.lockKey); err != nil {
+				return fmt.Errorf("failed to break expired lock: %w", err)
+			}
+			continue
+		}
+		select {
+		case <-time.After(time.Millisecond * 50):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (m *CacheMembership) update(ctx context.Context, fn func(members map[string]time.Time)) ([]string, error) {
+	var names []string
+	var err error
+	if cerr := interop.AccessCache(ctx, m.mgr, m.resource, func(c types.Cache) {
+		if err = m.lock(ctx, c); err != nil {
+			return
+		}
+		defer func() {
+			if derr := c.Delete(m.lockKey); derr != nil && err == nil {
+				err = fmt.Errorf("failed to release lock: %w", derr)
+			}
+		}()
+
+		members := map[string]time.Time{}
+
+		var b []byte
+		if b, err = c.Get(m.key); err == nil {
+			if err = json.Unmarshal(b, &members); err != nil {
+				return
+			}
+		} else if !errors.Is(err, types.ErrKeyNotFound) {
+			return
+		}
+
+		now := time.Now()
+		for k, expires := range members {
+			if now.After(expires) {
+				delete(members, k)
+			}
+		}
+		fn(members)
+
+		if b, err = json.Marshal(members); err != nil {
+			return
+		}
+		if err = c.Set(m.key, b); err != nil {
+			return
+		}
+		for k := range members {
+			names = append(names, k)
+		}
+	}); cerr != nil {
+		return nil, cerr
+	}
+	sort.Strings(names)
+	return names, err
+}
+
+// Join registers a member of the group, or renews it when it is already a
+// member, for the duration of a ttl.
+func (m *CacheMembership) Join(ctx context.Context, member string, ttl time.Duration) ([]string, error) {
+	return m.update(ctx, func(members map[string]time.Time) {
+		members[member] = time.Now().Add(ttl)
+	})
+}
+
+// Leave removes a member from the group.
+func (m *CacheMembership) Leave(ctx context.Context, member string) error {
+	_, err := m.update(ctx, func(members map[string]time.Time) {
+		delete(members, member)
+	})
+	return err
+}
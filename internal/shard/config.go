@@ -0,0 +1,71 @@
+package shard
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+// CacheConfig contains configuration fields of a membership stored within a
+// cache resource.
+type CacheConfig struct {
+	Resource string `json:"resource" yaml:"resource"`
+}
+
+// EtcdConfig contains configuration fields of a membership stored within an
+// etcd cluster.
+type EtcdConfig struct {
+	Endpoints []string `json:"endpoints" yaml:"endpoints"`
+}
+
+// Config contains configuration fields for sharding the keys of an input
+// between the members of a group.
+type Config struct {
+	Enabled           bool        `json:"enabled" yaml:"enabled"`
+	Group             string      `json:"group" yaml:"group"`
+	Member            string      `json:"member" yaml:"member"`
+	Membership        string      `json:"membership" yaml:"membership"`
+	TTL               string      `json:"ttl" yaml:"ttl"`
+	HeartbeatInterval string      `json:"heartbeat_interval" yaml:"heartbeat_interval"`
+	Cache             CacheConfig `json:"cache" yaml:"cache"`
+	Etcd              EtcdConfig  `json:"etcd" yaml:"etcd"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:           false,
+		Group:             "",
+		Member:            "",
+		Membership:        "cache",
+		TTL:               "15s",
+		HeartbeatInterval: "5s",
+		Cache: CacheConfig{
+			Resource: "",
+		},
+		Etcd: EtcdConfig{
+			Endpoints: []string{"http://localhost:2379"},
+		},
+	}
+}
+
+// FieldSpec returns the documentation of the sharding field of an input, where
+// keys describes what is divided between members and reassignment describes
+// what happens to the keys of a member that leaves the group.
+func FieldSpec(keys, reassignment string) docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"sharding",
+		"Divides "+keys+" between the live members of a group of instances that share the same config, so that each is consumed by only one of them. Each key is assigned to a member by hashing it along with the identities of all members, and so when a member joins or leaves the group only the keys of that member move. Upon joining the group a member waits for a `heartbeat_interval` before consuming so that the members agree on the assignment of keys. A member that fails to renew its membership for longer than `ttl` stops consuming until it rejoins. "+reassignment,
+	).WithChildren(
+		docs.FieldCommon("enabled", "Whether sharding is enabled."),
+		docs.FieldCommon("group", "The name of the group, which must be the same for all members that divide keys between them."),
+		docs.FieldAdvanced("member", "A unique identity of this instance. When empty the hostname followed by a random suffix is used."),
+		docs.FieldCommon("membership", "The backend that tracks the members of the group. The `cache` backend serialises updates to the group with a lock that is created with the atomic add operation of the cache, and expects the clocks of all members to be roughly in sync. The `etcd` backend does not have this limitation.").HasOptions("cache", "etcd"),
+		docs.FieldAdvanced("ttl", "The duration after which a member is removed from the group unless it renews its membership."),
+		docs.FieldAdvanced("heartbeat_interval", "The interval at which membership is renewed and the members of the group are refreshed. Must be less than `ttl`."),
+		docs.FieldCommon("cache", "Configures the `cache` membership.").WithChildren(
+			docs.FieldCommon("resource", "A [cache resource](/docs/components/caches/about) shared by all members, such as `redis`, to store the group within."),
+		),
+		docs.FieldCommon("etcd", "Configures the `etcd` membership, which is reached via the JSON gateway of the v3 API served by etcd v3.4 and later.").WithChildren(
+			docs.FieldCommon("endpoints", "A list of endpoints of the etcd cluster, which are attempted in order.").Array(),
+		),
+	)
+}
@@ -0,0 +1,240 @@
+package shard
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+// Coordinator divides keys between the live members of a group using
+// rendezvous hashing, such that each key is owned by exactly one member that
+// every member agrees on, and when a member joins or leaves only the keys of
+// that member are reassigned.
+type Coordinator struct {
+	membership Membership
+	member     string
+	ttl        time.Duration
+	interval   time.Duration
+
+	log      log.Modular
+	mMembers metrics.StatGauge
+	mErr     metrics.StatCounter
+
+	mut      sync.RWMutex
+	members  []string
+	seeds    []uint64
+	lastJoin time.Time
+	synced   bool
+
+	startOnce  sync.Once
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+// NewCoordinator creates a coordinator from a config. Membership of the group
+// begins with the first call to Sync.
+func NewCoordinator(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (*Coordinator, error) {
+	if conf.Group == "" {
+		return nil, errors.New("a sharding group must be specified")
+	}
+
+	ttl, err := time.ParseDuration(conf.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sharding ttl string: %v", err)
+	}
+	interval, err := time.ParseDuration(conf.HeartbeatInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sharding heartbeat_interval string: %v", err)
+	}
+	if interval <= 0 || interval >= ttl {
+		return nil, errors.New("sharding heartbeat_interval must be greater than zero and less than ttl")
+	}
+
+	var membership Membership
+	switch conf.Membership {
+	case "cache":
+		if membership, err = NewCacheMembership(mgr, conf.Cache.Resource, conf.Group); err != nil {
+			return nil, err
+		}
+	case "etcd":
+		if membership, err = NewEtcdMembership(conf.Etcd.Endpoints, conf.Group); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("sharding membership type '%v' not recognised", conf.Membership)
+	}
+
+	member := conf.Member
+	if member == "" {
+		hostname, _ := os.Hostname()
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		member = hostname + "-" + hex.EncodeToString(suffix)
+	}
+	return newCoordinator(membership, member, ttl, interval, log, stats), nil
+}
+
+func newCoordinator(membership Membership, member string, ttl, interval time.Duration, log log.Modular, stats metrics.Type) *Coordinator {
+	c := &Coordinator{
+		membership: membership,
+		member:     member,
+		ttl:        ttl,
+		interval:   interval,
+
+		log:      log,
+		mMembers: stats.GetGauge("sharding.members"),
+		mErr:     stats.GetCounter("sharding.error"),
+
+		closedChan: make(chan struct{}),
+	}
+	c.ctx, c.done = context.WithCancel(context.Background())
+	return c
+}
+
+// Member returns the identity of this member.
+func (c *Coordinator) Member() string {
+	return c.member
+}
+
+func (c *Coordinator) join(ctx context.Context) error {
+	members, err := c.membership.Join(ctx, c.member, c.ttl)
+	if err != nil {
+		c.mErr.Incr(1)
+		return err
+	}
+
+	seeds := make([]uint64, len(members))
+	for i, m := range members {
+		seeds[i] = xxhash.ChecksumString64(m)
+	}
+
+	c.mut.Lock()
+	if len(members) != len(c.members) {
+		c.log.Infof("Sharding group now has %v members\n", len(members))
+	}
+	c.members = members
+	c.seeds = seeds
+	c.lastJoin = time.Now()
+	c.mut.Unlock()
+
+	c.mMembers.Set(int64(len(members)))
+	return nil
+}
+
+func (c *Coordinator) loop() {
+	defer close(c.closedChan)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			ctx, done := context.WithTimeout(context.Background(), c.interval)
+			if err := c.membership.Leave(ctx, c.member); err != nil {
+				c.log.Errorf("Failed to leave sharding group: %v\n", err)
+			}
+			done()
+			return
+		}
+
+		ctx, done := context.WithTimeout(c.ctx, c.interval)
+		err := c.join(ctx)
+		done()
+		if err != nil && c.ctx.Err() == nil {
+			c.log.Errorf("Failed to renew sharding group membership: %v\n", err)
+		}
+	}
+}
+
+// Sync joins the group when this member has not yet done so, after which
+// membership is renewed in the background until the coordinator is closed.
+//
+// After joining Sync waits for a heartbeat interval and then refreshes the
+// members of the group before returning. This gives existing members the
+// chance to renew and observe this member, and members that are starting at
+// the same time the chance to join, so that all members agree on the owners of
+// keys before any are listed.
+//
+// Once joined an error is returned whilst membership has lapsed, as during
+// that time no keys are owned and listing them would skip those that are
+// assigned to this member.
+func (c *Coordinator) Sync(ctx context.Context) error {
+	c.mut.RLock()
+	synced, lastJoin := c.synced, c.lastJoin
+	c.mut.RUnlock()
+	if synced {
+		if time.Since(lastJoin) > c.ttl {
+			return errors.New("sharding group membership has lapsed")
+		}
+		return nil
+	}
+	if err := c.join(ctx); err != nil {
+		return fmt.Errorf("failed to join sharding group: %w", err)
+	}
+	select {
+	case <-time.After(c.interval):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := c.join(ctx); err != nil {
+		return fmt.Errorf("failed to join sharding group: %w", err)
+	}
+	c.mut.Lock()
+	c.synced = true
+	c.mut.Unlock()
+	c.startOnce.Do(func() {
+		go c.loop()
+	})
+	return nil
+}
+
+// Owns returns whether a key is assigned to this member. No keys are owned
+// until the group has been joined, or once membership has not been renewed
+// within the ttl, as by then other members will have taken them over.
+func (c *Coordinator) Owns(key string) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.lastJoin.IsZero() || time.Since(c.lastJoin) > c.ttl {
+		return false
+	}
+
+	var owner string
+	var highest uint64
+	for i, m := range c.members {
+		if w := xxhash.ChecksumString64S(key, c.seeds[i]); owner == "" || w > highest {
+			owner, highest = m, w
+		}
+	}
+	return owner == c.member
+}
+
+// Close leaves the group and stops renewing membership.
+func (c *Coordinator) Close(ctx context.Context) error {
+	c.done()
+	// When the group was never joined there is no loop to close the channel.
+	c.startOnce.Do(func() {
+		close(c.closedChan)
+	})
+	select {
+	case <-c.closedChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
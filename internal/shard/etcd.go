@@ -0,0 +1,95 @@
+package shard
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/etcd"
+)
+
+// EtcdMembership is a membership stored as keys of an etcd cluster that share
+// a prefix, where the key of each member is attached to a lease of that member
+// so that it is deleted once the lease expires.
+type EtcdMembership struct {
+	client *etcd.Client
+	prefix string
+
+	mut     sync.Mutex
+	leaseID int64
+}
+
+// NewEtcdMembership creates a membership stored under a prefix of an etcd
+// cluster, reached via any of a list of endpoints.
+func NewEtcdMembership(endpoints []string, prefix string) (*EtcdMembership, error) {
+	client, err := etcd.NewClient(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &EtcdMembership{
+		client: client,
+		prefix: prefix,
+	}, nil
+}
+
+// Join registers a member of the group, or renews it when it is already a
+// member, for the duration of a ttl.
+func (m *EtcdMembership) Join(ctx context.Context, member string, ttl time.Duration) ([]string, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.leaseID != 0 {
+		alive, err := m.client.KeepAlive(ctx, m.leaseID)
+		if err != nil {
+			return nil, err
+		}
+		if !alive {
+			m.leaseID = 0
+		}
+	}
+	if m.leaseID == 0 {
+		id, err := m.client.Grant(ctx, ttl)
+		if err != nil {
+			return nil, err
+		}
+		// The key is only written when the lease is granted, after which it
+		// lives for as long as the lease is kept alive.
+		if err = m.client.Put(ctx, m.prefix+member, member, id); err != nil {
+			_ = m.client.Revoke(ctx, id)
+			return nil, err
+		}
+		m.leaseID = id
+	}
+
+	kvs, err := m.client.RangePrefix(ctx, m.prefix)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		members = append(members, kv.Value)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// Leave removes a member from the group by revoking its lease, which deletes
+// its key.
+func (m *EtcdMembership) Leave(ctx context.Context, member string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.leaseID == 0 {
+		return nil
+	}
+	if err := m.client.Revoke(ctx, m.leaseID); err != nil {
+		return err
+	}
+	m.leaseID = 0
+	return nil
+}
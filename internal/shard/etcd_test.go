@@ -0,0 +1,225 @@
+package shard
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEtcdKV struct {
+	value string
+	lease int64
+}
+
+type fakeEtcd struct {
+	mut     sync.Mutex
+	nextID  int64
+	leases  map[int64]bool
+	kvs     map[string]fakeEtcdKV
+	grants  int
+	revokes int
+	failPut bool
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		leases: map[int64]bool{},
+		kvs:    map[string]fakeEtcdKV{},
+	}
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parseID := func(v interface{}) int64 {
+		s, _ := v.(string)
+		id, _ := strconv.ParseInt(s, 10, 64)
+		return id
+	}
+	decode := func(v interface{}) string {
+		s, _ := v.(string)
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return string(b)
+	}
+
+	var res interface{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.grants++
+		f.nextID++
+		f.leases[f.nextID] = true
+		res = map[string]interface{}{"ID": strconv.FormatInt(f.nextID, 10), "TTL": req["TTL"]}
+	case "/v3/lease/keepalive":
+		result := map[string]interface{}{"ID": req["ID"]}
+		if f.leases[parseID(req["ID"])] {
+			result["TTL"] = "10"
+		}
+		res = map[string]interface{}{"result": result}
+	case "/v3/lease/revoke":
+		f.revokes++
+		f.expireLocked(parseID(req["ID"]))
+		res = map[string]interface{}{}
+	case "/v3/kv/put":
+		if f.failPut {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"put failed"}`))
+			return
+		}
+		f.kvs[decode(req["key"])] = fakeEtcdKV{value: decode(req["value"]), lease: parseID(req["lease"])}
+		res = map[string]interface{}{}
+	case "/v3/kv/range":
+		start, end := decode(req["key"]), decode(req["range_end"])
+		var keys []string
+		for k := range f.kvs {
+			if k >= start && k < end {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var kvs []interface{}
+		for _, k := range keys {
+			kvs = append(kvs, map[string]interface{}{
+				"key":   base64.StdEncoding.EncodeToString([]byte(k)),
+				"value": base64.StdEncoding.EncodeToString([]byte(f.kvs[k].value)),
+				"lease": strconv.FormatInt(f.kvs[k].lease, 10),
+			})
+		}
+		res = map[string]interface{}{"kvs": kvs}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeEtcd) expireLocked(id int64) {
+	delete(f.leases, id)
+	for k, kv := range f.kvs {
+		if kv.lease == id {
+			delete(f.kvs, k)
+		}
+	}
+}
+
+// expire simulates the expiry of the lease attached to the key of a member.
+func (f *fakeEtcd) expire(key string) {
+	f.mut.Lock()
+	f.expireLocked(f.kvs[key].lease)
+	f.mut.Unlock()
+}
+
+func (f *fakeEtcd) counts() (grants, revokes, leases int) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.grants, f.revokes, len(f.leases)
+}
+
+func TestEtcdMembership(t *testing.T) {
+	fake := newFakeEtcd()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	_, err := NewEtcdMembership(nil, "group")
+	require.EqualError(t, err, "at least one etcd endpoint must be specified")
+
+	mA, err := NewEtcdMembership([]string{srv.URL}, "group")
+	require.NoError(t, err)
+	mB, err := NewEtcdMembership([]string{srv.URL}, "group/")
+	require.NoError(t, err)
+	mOther, err := NewEtcdMembership([]string{srv.URL}, "groupies")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	members, err := mA.Join(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, members)
+
+	members, err = mOther.Join(ctx, "c", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, members)
+
+	members, err = mB.Join(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+
+	// Renewing a membership keeps the existing lease.
+	members, err = mA.Join(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+	grants, _, _ := fake.counts()
+	assert.Equal(t, 3, grants)
+
+	// A member whose lease expired is absent until it joins again, at which
+	// point a new lease is granted.
+	fake.expire("group/a")
+	members, err = mB.Join(ctx, "b", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, members)
+
+	members, err = mA.Join(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+	grants, _, _ = fake.counts()
+	assert.Equal(t, 4, grants)
+
+	require.NoError(t, mB.Leave(ctx, "b"))
+	members, err = mA.Join(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, members)
+
+	// Leaving without a lease is a noop.
+	_, revokes, _ := fake.counts()
+	require.NoError(t, mB.Leave(ctx, "b"))
+	_, revokesAfter, _ := fake.counts()
+	assert.Equal(t, revokes, revokesAfter)
+}
+
+func TestEtcdMembershipErrors(t *testing.T) {
+	fake := newFakeEtcd()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	m, err := NewEtcdMembership([]string{srv.URL}, "group")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// A lease granted for a key that fails to be written is revoked.
+	fake.mut.Lock()
+	fake.failPut = true
+	fake.mut.Unlock()
+	_, err = m.Join(ctx, "a", time.Second*10)
+	require.EqualError(t, err, "etcd responded with status 500: put failed")
+	grants, revokes, leases := fake.counts()
+	assert.Equal(t, 1, grants)
+	assert.Equal(t, 1, revokes)
+	assert.Equal(t, 0, leases)
+
+	fake.mut.Lock()
+	fake.failPut = false
+	fake.mut.Unlock()
+	members, err := m.Join(ctx, "a", time.Second*10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, members)
+
+	// Errors are returned when the cluster is unreachable.
+	srv.Close()
+	_, err = m.Join(ctx, "a", time.Second*10)
+	require.Error(t, err)
+	require.Error(t, m.Leave(ctx, "a"))
+}
@@ -0,0 +1,19 @@
+package shard
+
+import (
+	"context"
+	"time"
+)
+
+// Membership tracks the live members of a group, where each member must renew
+// its membership before its ttl has passed.
+type Membership interface {
+	// Join registers a member of the group, or renews it when it is already a
+	// member, for the duration of a ttl. Returns the live members of the
+	// group, which includes the member itself.
+	Join(ctx context.Context, member string, ttl time.Duration) ([]string, error)
+
+	// Leave removes a member from the group, allowing its keys to be taken
+	// over by other members without waiting for it to expire.
+	Leave(ctx context.Context, member string) error
+}
@@ -0,0 +1,4 @@
+// Package shard implements a coordinator that divides keys between the live
+// members of a group of instances, where membership is tracked within either a
+// cache resource or an etcd cluster.
+package shard
@@ -0,0 +1,242 @@
+package shard
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMgr struct {
+	caches map[string]types.Cache
+}
+
+func (f *fakeMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {}
+func (f *fakeMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (f *fakeMgr) GetPlugin(name string) (interface{}, error) {
+	return nil, types.ErrPluginNotFound
+}
+func (f *fakeMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func newTestMgr(t *testing.T) types.Manager {
+	t.Helper()
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return &fakeMgr{caches: map[string]types.Cache{"foo": memCache}}
+}
+
+func TestCacheMembership(t *testing.T) {
+	mgr := newTestMgr(t)
+
+	_, err := NewCacheMembership(mgr, "bar", "group")
+	require.EqualError(t, err, "cache resource 'bar' was not found")
+
+	m, err := NewCacheMembership(mgr, "foo", "group")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	members, err := m.Join(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, members)
+
+	members, err = m.Join(ctx, "b", time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+
+	<-time.After(time.Millisecond * 5)
+
+	members, err = m.Join(ctx, "c", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, members)
+
+	require.NoError(t, m.Leave(ctx, "a"))
+	members, err = m.Join(ctx, "c", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, members)
+}
+
+func TestCacheMembershipConcurrent(t *testing.T) {
+	mgr := newTestMgr(t)
+	ctx := context.Background()
+
+	m, err := NewCacheMembership(mgr, "foo", "group")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var exp []string
+	for i := 0; i < 10; i++ {
+		member := "member" + strconv.Itoa(i)
+		exp = append(exp, member)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, jerr := m.Join(ctx, member, time.Minute)
+			assert.NoError(t, jerr)
+		}()
+	}
+	wg.Wait()
+
+	members, err := m.Join(ctx, "member0", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, exp, members)
+}
+
+func TestCacheMembershipExpiredLock(t *testing.T) {
+	mgr := newTestMgr(t)
+	ctx := context.Background()
+
+	m, err := NewCacheMembership(mgr, "foo", "group")
+	require.NoError(t, err)
+
+	c, err := mgr.GetCache("foo")
+	require.NoError(t, err)
+
+	heldUntil, err := time.Now().Add(time.Minute).MarshalText()
+	require.NoError(t, err)
+	require.NoError(t, c.Set("group_lock", heldUntil))
+
+	tCtx, done := context.WithTimeout(ctx, time.Millisecond*100)
+	_, err = m.Join(tCtx, "a", time.Minute)
+	done()
+	require.Error(t, err)
+
+	heldUntil, err = time.Now().Add(-time.Second).MarshalText()
+	require.NoError(t, err)
+	require.NoError(t, c.Set("group_lock", heldUntil))
+
+	members, err := m.Join(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, members)
+
+	_, err = c.Get("group_lock")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestCoordinatorLapsed(t *testing.T) {
+	mgr := newTestMgr(t)
+	ctx := context.Background()
+
+	m, err := NewCacheMembership(mgr, "foo", "group")
+	require.NoError(t, err)
+
+	c := newCoordinator(m, "a", time.Millisecond*50, time.Millisecond*10, log.Noop(), metrics.Noop())
+	require.NoError(t, c.Sync(ctx))
+	assert.True(t, c.Owns("foo"))
+
+	// Stop renewing membership.
+	c.done()
+	<-c.closedChan
+	<-time.After(time.Millisecond * 100)
+
+	assert.False(t, c.Owns("foo"))
+	require.EqualError(t, c.Sync(ctx), "sharding group membership has lapsed")
+}
+
+func TestCoordinatorOwnership(t *testing.T) {
+	mgr := newTestMgr(t)
+	ctx := context.Background()
+
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.Group = "group"
+	conf.Cache.Resource = "foo"
+	conf.TTL = "1s"
+	conf.HeartbeatInterval = "10ms"
+
+	var coords []*Coordinator
+	for _, member := range []string{"a", "b", "c"} {
+		conf.Member = member
+		c, err := NewCoordinator(conf, mgr, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		assert.False(t, c.Owns("foo"))
+		require.NoError(t, c.Sync(ctx))
+		coords = append(coords, c)
+	}
+	// Refresh the first members so that they see the later ones.
+	for _, c := range coords {
+		require.NoError(t, c.join(ctx))
+	}
+
+	owners := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		key := "key" + strconv.Itoa(i)
+		var owned []string
+		for _, c := range coords {
+			if c.Owns(key) {
+				owned = append(owned, c.Member())
+			}
+		}
+		require.Len(t, owned, 1, key)
+		owners[key] = owned[0]
+		counts[owned[0]]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		assert.Greater(t, counts[member], 50, member)
+	}
+
+	require.NoError(t, coords[2].Close(ctx))
+	for _, c := range coords[:2] {
+		require.NoError(t, c.join(ctx))
+	}
+
+	// Only the keys of the member that left are reassigned.
+	for key, owner := range owners {
+		var owned []string
+		for _, c := range coords[:2] {
+			if c.Owns(key) {
+				owned = append(owned, c.Member())
+			}
+		}
+		require.Len(t, owned, 1, key)
+		if owner != "c" {
+			assert.Equal(t, owner, owned[0], key)
+		}
+	}
+
+	for _, c := range coords[:2] {
+		require.NoError(t, c.Close(ctx))
+	}
+}
+
+func TestCoordinatorBadConfig(t *testing.T) {
+	mgr := newTestMgr(t)
+
+	conf := NewConfig()
+	_, err := NewCoordinator(conf, mgr, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a sharding group must be specified")
+
+	conf.Group = "group"
+	conf.HeartbeatInterval = "20s"
+	_, err = NewCoordinator(conf, mgr, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "sharding heartbeat_interval must be greater than zero and less than ttl")
+
+	conf.HeartbeatInterval = "5s"
+	conf.Membership = "nope"
+	_, err = NewCoordinator(conf, mgr, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "sharding membership type 'nope' not recognised")
+}
@@ -0,0 +1,294 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags of the types used by SNMP (RFC 3416).
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+
+	tagIPAddress = 0x40
+	tagCounter32 = 0x41
+	tagGauge32   = 0x42
+	tagTimeTicks = 0x43
+	tagOpaque    = 0x44
+	tagCounter64 = 0x46
+
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+)
+
+// element is a decoded BER element, where the children of sequences and PDUs
+// are decoded recursively.
+type element struct {
+	tag      byte
+	value    []byte
+	children []*element
+}
+
+func (e *element) child(i int) (*element, error) {
+	if i >= len(e.children) {
+		return nil, fmt.Errorf("expected at least %v elements within tag 0x%x, got %v", i+1, e.tag, len(e.children))
+	}
+	return e.children[i], nil
+}
+
+func (e *element) int() (int64, error) {
+	if e.tag != tagInteger {
+		return 0, fmt.Errorf("expected integer, got tag 0x%x", e.tag)
+	}
+	if len(e.value) == 0 || len(e.value) > 8 {
+		return 0, fmt.Errorf("invalid integer length: %v", len(e.value))
+	}
+	v := int64(int8(e.value[0]))
+	for _, b := range e.value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+func (e *element) uint() (uint64, error) {
+	b := e.value
+	if len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	if len(b) == 0 || len(b) > 8 {
+		return 0, fmt.Errorf("invalid unsigned integer length: %v", len(e.value))
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (e *element) bytes() ([]byte, error) {
+	if e.tag != tagOctetString {
+		return nil, fmt.Errorf("expected octet string, got tag 0x%x", e.tag)
+	}
+	return e.value, nil
+}
+
+func (e *element) oid() (string, error) {
+	if e.tag != tagOID {
+		return "", fmt.Errorf("expected object identifier, got tag 0x%x", e.tag)
+	}
+	return decodeOID(e.value)
+}
+
+//------------------------------------------------------------------------------
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeTLV(tag byte, value []byte) []byte {
+	b := append([]byte{tag}, encodeLength(len(value))...)
+	return append(b, value...)
+}
+
+func encodeConstructed(tag byte, children ...[]byte) []byte {
+	var value []byte
+	for _, c := range children {
+		value = append(value, c...)
+	}
+	return encodeTLV(tag, value)
+}
+
+func encodeInt(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return encodeTLV(tagInteger, b)
+}
+
+func encodeUint(tag byte, v uint64) []byte {
+	b := []byte{byte(v)}
+	for v > 0xff {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return encodeTLV(tag, b)
+}
+
+func encodeBytes(b []byte) []byte {
+	return encodeTLV(tagOctetString, b)
+}
+
+func encodeOID(oid string) ([]byte, error) {
+	parts, err := parseOID(oid)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("object identifier must have at least two components: %v", oid)
+	}
+	if parts[0] > 2 || (parts[0] < 2 && parts[1] > 39) {
+		return nil, fmt.Errorf("invalid object identifier: %v", oid)
+	}
+	b := encodeBase128(nil, parts[0]*40+parts[1])
+	for _, p := range parts[2:] {
+		b = encodeBase128(b, p)
+	}
+	return encodeTLV(tagOID, b), nil
+}
+
+func encodeBase128(b []byte, v uint64) []byte {
+	n := 1
+	for t := v >> 7; t > 0; t >>= 7 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := byte(v>>(uint(i)*7)) & 0x7f
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// parseOID parses the dotted representation of an object identifier, a
+// leading dot is permitted.
+func parseOID(oid string) ([]uint64, error) {
+	oid = strings.TrimPrefix(oid, ".")
+	if oid == "" {
+		return nil, errors.New("object identifier is empty")
+	}
+	strs := strings.Split(oid, ".")
+	parts := make([]uint64, len(strs))
+	for i, s := range strs {
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object identifier: %v", oid)
+		}
+		parts[i] = v
+	}
+	return parts, nil
+}
+
+func decodeOID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", errors.New("object identifier is empty")
+	}
+	var parts []uint64
+	var v uint64
+	for i, c := range b {
+		if v > 1<<56 {
+			return "", errors.New("object identifier component overflows")
+		}
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errors.New("truncated object identifier")
+			}
+			continue
+		}
+		if len(parts) == 0 {
+			switch {
+			case v < 40:
+				parts = append(parts, 0, v)
+			case v < 80:
+				parts = append(parts, 1, v-40)
+			default:
+				parts = append(parts, 2, v-80)
+			}
+		} else {
+			parts = append(parts, v)
+		}
+		v = 0
+	}
+	strs := make([]string, len(parts))
+	for i, p := range parts {
+		strs[i] = strconv.FormatUint(p, 10)
+	}
+	return strings.Join(strs, "."), nil
+}
+
+// oidHasPrefix returns whether an object identifier is within the subtree of
+// another.
+func oidHasPrefix(oid, prefix string) bool {
+	return oid == prefix || strings.HasPrefix(oid, prefix+".")
+}
+
+// oidLess returns whether an object identifier is lexicographically before
+// another.
+func oidLess(a, b string) bool {
+	aParts, _ := parseOID(a)
+	bParts, _ := parseOID(b)
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] != bParts[i] {
+			return aParts[i] < bParts[i]
+		}
+	}
+	return len(aParts) < len(bParts)
+}
+
+//------------------------------------------------------------------------------
+
+func decodeValue(tag byte, value []byte) (*element, error) {
+	e := &element{tag: tag, value: value}
+	// Universal sequences and context specific constructed tags (PDUs).
+	if tag != tagSequence && !(tag&0xe0 == 0xa0) {
+		return e, nil
+	}
+	for len(value) > 0 {
+		child, n, err := decodeElement(value)
+		if err != nil {
+			return nil, err
+		}
+		e.children = append(e.children, child)
+		value = value[n:]
+	}
+	return e, nil
+}
+
+// decodeElement decodes a BER element from a buffer, returning the number of
+// bytes consumed.
+func decodeElement(b []byte) (*element, int, error) {
+	if len(b) < 2 {
+		return nil, 0, errors.New("truncated packet")
+	}
+	tag := b[0]
+	l, off := int(b[1]), 2
+	if l >= 0x80 {
+		n := l & 0x7f
+		if n == 0 || n > 4 || len(b) < 2+n {
+			return nil, 0, errors.New("invalid packet length")
+		}
+		l = 0
+		for _, c := range b[2 : 2+n] {
+			l = l<<8 | int(c)
+		}
+		off += n
+	}
+	if l < 0 || len(b)-off < l {
+		return nil, 0, errors.New("truncated packet")
+	}
+	e, err := decodeValue(tag, b[off:off+l])
+	if err != nil {
+		return nil, 0, err
+	}
+	return e, off + l, nil
+}
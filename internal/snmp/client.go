@@ -0,0 +1,447 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Version is an SNMP protocol version.
+type Version string
+
+// Supported protocol versions.
+const (
+	Version2c Version = "2c"
+	Version3  Version = "3"
+)
+
+var errorStatusNames = map[int64]string{
+	1:  "tooBig",
+	2:  "noSuchName",
+	3:  "badValue",
+	4:  "readOnly",
+	5:  "genErr",
+	6:  "noAccess",
+	7:  "wrongType",
+	8:  "wrongLength",
+	9:  "wrongEncoding",
+	10: "wrongValue",
+	11: "noCreation",
+	12: "inconsistentValue",
+	13: "resourceUnavailable",
+	14: "commitFailed",
+	15: "undoFailed",
+	16: "authorizationError",
+	17: "notWritable",
+	18: "inconsistentName",
+}
+
+// Error is a non-zero error status returned by an agent.
+type Error struct {
+	Status int64
+	Index  int64
+}
+
+func (e *Error) Error() string {
+	name, exists := errorStatusNames[e.Status]
+	if !exists {
+		name = "error status"
+	}
+	return fmt.Sprintf("%v (%v) at index %v", name, e.Status, e.Index)
+}
+
+// Counters of the user based security model reported by agents (RFC 3414
+// section 5).
+const (
+	oidUnsupportedSecLevels = "1.3.6.1.6.3.15.1.1.1.0"
+	oidNotInTimeWindows     = "1.3.6.1.6.3.15.1.1.2.0"
+	oidUnknownUserNames     = "1.3.6.1.6.3.15.1.1.3.0"
+	oidUnknownEngineIDs     = "1.3.6.1.6.3.15.1.1.4.0"
+	oidWrongDigests         = "1.3.6.1.6.3.15.1.1.5.0"
+	oidDecryptionErrors     = "1.3.6.1.6.3.15.1.1.6.0"
+)
+
+var reportNames = map[string]string{
+	oidUnsupportedSecLevels: "unsupported security level",
+	oidNotInTimeWindows:     "not in time window",
+	oidUnknownUserNames:     "unknown user name",
+	oidUnknownEngineIDs:     "unknown engine id",
+	oidWrongDigests:         "wrong digest",
+	oidDecryptionErrors:     "decryption error",
+}
+
+// ErrTimeout is returned when an agent does not respond to a request within
+// the timeout and retries of a client.
+var ErrTimeout = errors.New("request timed out")
+
+//------------------------------------------------------------------------------
+
+// ClientConfig contains the parameters of a client.
+type ClientConfig struct {
+	Address     string
+	Version     Version
+	Community   string
+	User        User
+	ContextName string
+	Timeout     time.Duration
+	Retries     int
+}
+
+// Client sends requests to a single agent over UDP.
+type Client struct {
+	conf ClientConfig
+	conn net.Conn
+
+	mut        sync.Mutex
+	requestID  int64
+	engineID   []byte
+	boots      int64
+	engineTime int64
+	syncedAt   time.Time
+	user       *localizedUser
+}
+
+// Dial creates a client for the agent at an address, where the port defaults
+// to 161.
+func Dial(ctx context.Context, conf ClientConfig) (*Client, error) {
+	switch conf.Version {
+	case Version2c:
+	case Version3:
+		if err := conf.User.Validate(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("version not supported: %v", conf.Version)
+	}
+
+	addr := conf.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "161")
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second * 5
+	}
+	return &Client{
+		conf:      conf,
+		conn:      conn,
+		requestID: time.Now().UnixNano() & 0x7fffffff,
+	}, nil
+}
+
+// Close the client.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Get the values of a list of object identifiers.
+func (c *Client) Get(ctx context.Context, oids ...string) ([]Variable, error) {
+	return c.request(ctx, &pdu{tag: pduGetRequest, variables: nullVariables(oids)})
+}
+
+// GetBulk requests the successors of a list of object identifiers, where the
+// first nonRepeaters are retrieved once and the remaining up to
+// maxRepetitions times.
+func (c *Client) GetBulk(ctx context.Context, nonRepeaters, maxRepetitions int, oids ...string) ([]Variable, error) {
+	return c.request(ctx, &pdu{
+		tag:         pduGetBulkRequest,
+		errorStatus: int64(nonRepeaters),
+		errorIndex:  int64(maxRepetitions),
+		variables:   nullVariables(oids),
+	})
+}
+
+// Walk returns all variables within the subtree of an object identifier using
+// GetBulk requests. When the subtree is empty the object identifier itself is
+// requested, allowing scalar instances to be walked.
+func (c *Client) Walk(ctx context.Context, root string, maxRepetitions int) ([]Variable, error) {
+	if _, err := parseOID(root); err != nil {
+		return nil, err
+	}
+	if maxRepetitions <= 0 {
+		maxRepetitions = 10
+	}
+
+	var vars []Variable
+	current := root
+walk:
+	for {
+		res, err := c.GetBulk(ctx, 0, maxRepetitions, current)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 {
+			break
+		}
+		for _, v := range res {
+			if v.Type == TypeEndOfMibView || !oidHasPrefix(v.OID, root) {
+				break walk
+			}
+			if !oidLess(current, v.OID) {
+				return nil, fmt.Errorf("agent returned object identifier %v out of order after %v", v.OID, current)
+			}
+			vars = append(vars, v)
+			current = v.OID
+		}
+	}
+
+	if len(vars) == 0 {
+		res, err := c.Get(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range res {
+			if !v.Exception() {
+				vars = append(vars, v)
+			}
+		}
+	}
+	return vars, nil
+}
+
+func nullVariables(oids []string) []Variable {
+	vars := make([]Variable, len(oids))
+	for i, oid := range oids {
+		vars[i] = Variable{OID: oid, Type: TypeNull}
+	}
+	return vars
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Client) request(ctx context.Context, p *pdu) ([]Variable, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	var res *pdu
+	var err error
+	if c.conf.Version == Version3 {
+		res, err = c.requestV3(ctx, p)
+	} else {
+		res, err = c.roundTrip(ctx, p, func(id int64) *message {
+			return &message{
+				version:   versionV2c,
+				community: []byte(c.conf.Community),
+			}
+		}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.tag != pduResponse {
+		return nil, fmt.Errorf("unexpected response pdu: 0x%x", res.tag)
+	}
+	if res.errorStatus != 0 {
+		return nil, &Error{Status: res.errorStatus, Index: res.errorIndex}
+	}
+	return res.variables, nil
+}
+
+// requestV3 sends an SNMPv3 request, discovering the engine of the agent
+// first if necessary and resynchronising its clock once when reported as out
+// of the time window.
+func (c *Client) requestV3(ctx context.Context, p *pdu) (*pdu, error) {
+	if c.engineID == nil {
+		if err := c.discover(ctx); err != nil {
+			return nil, fmt.Errorf("engine discovery failed: %w", err)
+		}
+	}
+
+	lookup := func(engineID []byte, userName string) (*localizedUser, error) {
+		if !bytes.Equal(engineID, c.engineID) || userName != c.conf.User.Name {
+			return nil, errors.New("response does not match the engine id and user of the request")
+		}
+		return c.user, nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.roundTrip(ctx, p, func(id int64) *message {
+			elapsed := int64(time.Since(c.syncedAt) / time.Second)
+			return &message{
+				version:         versionV3,
+				msgID:           id,
+				flags:           c.user.flags() | flagReportable,
+				engineID:        c.engineID,
+				engineBoots:     c.boots,
+				engineTime:      c.engineTime + elapsed,
+				userName:        []byte(c.user.Name),
+				contextEngineID: c.engineID,
+				contextName:     []byte(c.conf.ContextName),
+			}
+		}, lookup)
+		if err != nil {
+			return nil, err
+		}
+		if res.tag != pduReport {
+			return res, nil
+		}
+
+		if attempt == 0 && len(res.variables) > 0 && res.variables[0].OID == oidNotInTimeWindows {
+			// The report carries the current boots and time of the agent,
+			// which are synchronised by roundTrip.
+			continue
+		}
+		return nil, reportErr(res)
+	}
+}
+
+// discover the engine ID, boots and time of the agent by sending an
+// unauthenticated request and reading the parameters of its report.
+func (c *Client) discover(ctx context.Context) error {
+	var discovered *message
+	res, err := c.roundTripMsg(ctx, func(id int64) *message {
+		return &message{
+			version: versionV3,
+			msgID:   id,
+			flags:   flagReportable,
+			pdu:     &pdu{tag: pduGetRequest},
+		}
+	}, func(b []byte) (*message, error) {
+		m, err := decodeMessage(b, func([]byte, string) (*localizedUser, error) {
+			return nil, errors.New("unexpected authenticated response to discovery")
+		})
+		discovered = m
+		return m, err
+	})
+	if err != nil {
+		return err
+	}
+	if res.tag != pduReport || len(discovered.engineID) == 0 {
+		return errors.New("agent did not report its engine id")
+	}
+	if !bytes.Equal(c.engineID, discovered.engineID) || c.user == nil {
+		c.engineID = discovered.engineID
+		c.user = c.conf.User.localize(c.engineID)
+	}
+	c.boots = discovered.engineBoots
+	c.engineTime = discovered.engineTime
+	c.syncedAt = time.Now()
+	if c.user.AuthProtocol != AuthNone && c.engineTime == 0 && c.boots == 0 {
+		// Agents only report their time to unauthenticated requests when
+		// discovering the engine ID, therefore synchronise with an
+		// authenticated request (RFC 3414 section 4).
+		res, err := c.roundTrip(ctx, &pdu{tag: pduGetRequest}, func(id int64) *message {
+			return &message{
+				version:         versionV3,
+				msgID:           id,
+				flags:           c.user.flags() | flagReportable,
+				engineID:        c.engineID,
+				userName:        []byte(c.user.Name),
+				contextEngineID: c.engineID,
+			}
+		}, func(engineID []byte, userName string) (*localizedUser, error) {
+			return c.user, nil
+		})
+		if err != nil {
+			return err
+		}
+		if res.tag == pduReport && (len(res.variables) == 0 || res.variables[0].OID != oidNotInTimeWindows) {
+			return reportErr(res)
+		}
+	}
+	return nil
+}
+
+func reportErr(p *pdu) error {
+	if len(p.variables) == 0 {
+		return errors.New("agent responded with an empty report")
+	}
+	name, exists := reportNames[p.variables[0].OID]
+	if !exists {
+		name = p.variables[0].OID
+	}
+	return fmt.Errorf("agent reported: %v", name)
+}
+
+// roundTrip sends a request created by newMsg, returning the PDU of the first
+// response with a matching ID.
+func (c *Client) roundTrip(ctx context.Context, p *pdu, newMsg func(id int64) *message, lookup userLookup) (*pdu, error) {
+	var lastV3 *message
+	res, err := c.roundTripMsg(ctx, func(id int64) *message {
+		m := newMsg(id)
+		m.pdu = p
+		return m
+	}, func(b []byte) (*message, error) {
+		m, err := decodeMessage(b, lookup)
+		if err == nil && m.version == versionV3 {
+			lastV3 = m
+		}
+		return m, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lastV3 != nil && res.tag == pduReport {
+		// Reports carry the current boots and time of the agent.
+		c.boots = lastV3.engineBoots
+		c.engineTime = lastV3.engineTime
+		c.syncedAt = time.Now()
+	}
+	return res, nil
+}
+
+func (c *Client) roundTripMsg(ctx context.Context, newMsg func(id int64) *message, decode func([]byte) (*message, error)) (*pdu, error) {
+	buf := make([]byte, maxMessageSize)
+	for attempt := 0; attempt <= c.conf.Retries; attempt++ {
+		c.requestID = (c.requestID + 1) & 0x7fffffff
+		id := c.requestID
+
+		var user *localizedUser
+		m := newMsg(id)
+		if m.flags&(flagAuth|flagPriv) != 0 {
+			user = c.user
+		}
+		m.pdu.requestID = id
+		b, err := m.encode(user)
+		if err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(c.conf.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if _, err := c.conn.Write(b); err != nil {
+			return nil, err
+		}
+
+		for {
+			n, err := c.conn.Read(buf)
+			if err != nil {
+				var nErr net.Error
+				if errors.As(err, &nErr) && nErr.Timeout() {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					break
+				}
+				return nil, err
+			}
+			res, err := decode(buf[:n])
+			if err != nil {
+				// Ignore datagrams that fail to decode, the real response
+				// may still arrive.
+				continue
+			}
+			if m.version == versionV3 && res.msgID != id {
+				continue
+			}
+			if res.pdu.requestID != id && !(res.pdu.tag == pduReport && m.version == versionV3) {
+				continue
+			}
+			return res.pdu, nil
+		}
+	}
+	return nil, ErrTimeout
+}
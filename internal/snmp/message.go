@@ -0,0 +1,317 @@
+package snmp
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+)
+
+// PDU tags (RFC 3416).
+const (
+	pduGetRequest     = 0xa0
+	pduGetNextRequest = 0xa1
+	pduResponse       = 0xa2
+	pduSetRequest     = 0xa3
+	pduGetBulkRequest = 0xa5
+	pduInformRequest  = 0xa6
+	pduTrapV2         = 0xa7
+	pduReport         = 0xa8
+)
+
+// Message versions and constants.
+const (
+	versionV1  = 0
+	versionV2c = 1
+	versionV3  = 3
+
+	securityModelUSM = 3
+	maxMessageSize   = 65507
+)
+
+// ErrAuthFailed is returned when the digest of an authenticated message does
+// not match its contents.
+var ErrAuthFailed = errors.New("message authentication failed")
+
+type pdu struct {
+	tag       byte
+	requestID int64
+
+	// Within GetBulk requests these are non-repeaters and max-repetitions
+	// respectively.
+	errorStatus int64
+	errorIndex  int64
+
+	variables []Variable
+}
+
+func (p *pdu) encode() ([]byte, error) {
+	vars := make([][]byte, len(p.variables))
+	for i, v := range p.variables {
+		var err error
+		if vars[i], err = encodeVariable(v); err != nil {
+			return nil, err
+		}
+	}
+	return encodeConstructed(p.tag,
+		encodeInt(p.requestID),
+		encodeInt(p.errorStatus),
+		encodeInt(p.errorIndex),
+		encodeConstructed(tagSequence, vars...),
+	), nil
+}
+
+func decodePDU(e *element) (*pdu, error) {
+	if e.tag&0xe0 != 0xa0 || len(e.children) != 4 {
+		return nil, fmt.Errorf("malformed pdu with tag 0x%x", e.tag)
+	}
+	p := &pdu{tag: e.tag}
+	var err error
+	if p.requestID, err = e.children[0].int(); err != nil {
+		return nil, err
+	}
+	if p.errorStatus, err = e.children[1].int(); err != nil {
+		return nil, err
+	}
+	if p.errorIndex, err = e.children[2].int(); err != nil {
+		return nil, err
+	}
+	if e.children[3].tag != tagSequence {
+		return nil, errors.New("malformed variable bindings")
+	}
+	for _, c := range e.children[3].children {
+		v, err := decodeVariable(c)
+		if err != nil {
+			return nil, err
+		}
+		p.variables = append(p.variables, v)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// message is an SNMPv2c or SNMPv3 message, where the fields used depend on the
+// version.
+type message struct {
+	version int64
+
+	// SNMPv2c
+	community []byte
+
+	// SNMPv3
+	msgID           int64
+	flags           byte
+	engineID        []byte
+	engineBoots     int64
+	engineTime      int64
+	userName        []byte
+	contextEngineID []byte
+	contextName     []byte
+
+	pdu *pdu
+}
+
+// encode a message, where user provides the keys of SNMPv3 messages that are
+// authenticated or encrypted.
+func (m *message) encode(user *localizedUser) ([]byte, error) {
+	pduBytes, err := m.pdu.encode()
+	if err != nil {
+		return nil, err
+	}
+	if m.version != versionV3 {
+		return encodeConstructed(tagSequence,
+			encodeInt(m.version),
+			encodeBytes(m.community),
+			pduBytes,
+		), nil
+	}
+
+	if m.flags&(flagAuth|flagPriv) != 0 && user == nil {
+		return nil, errors.New("authenticated messages require a user")
+	}
+
+	msgData := encodeConstructed(tagSequence,
+		encodeBytes(m.contextEngineID),
+		encodeBytes(m.contextName),
+		pduBytes,
+	)
+	var privParams []byte
+	if m.flags&flagPriv != 0 {
+		var encrypted []byte
+		if encrypted, privParams, err = user.encrypt(msgData, m.engineBoots, m.engineTime); err != nil {
+			return nil, err
+		}
+		msgData = encodeBytes(encrypted)
+	}
+
+	var authParams []byte
+	if m.flags&flagAuth != 0 {
+		authParams = make([]byte, authParamsLen)
+	}
+	privTLV := encodeBytes(privParams)
+	secParams := encodeConstructed(tagSequence,
+		encodeBytes(m.engineID),
+		encodeInt(m.engineBoots),
+		encodeInt(m.engineTime),
+		encodeBytes(m.userName),
+		encodeBytes(authParams),
+		privTLV,
+	)
+
+	b := encodeConstructed(tagSequence,
+		encodeInt(versionV3),
+		encodeConstructed(tagSequence,
+			encodeInt(m.msgID),
+			encodeInt(maxMessageSize),
+			encodeBytes([]byte{m.flags}),
+			encodeInt(securityModelUSM),
+		),
+		encodeBytes(secParams),
+		msgData,
+	)
+	if m.flags&flagAuth != 0 {
+		// The authentication parameters directly precede the privacy
+		// parameters at the end of the security parameters.
+		off := len(b) - len(msgData) - len(privTLV) - authParamsLen
+		copy(b[off:], user.mac(b))
+	}
+	return b, nil
+}
+
+// userLookup returns the user of an SNMPv3 message with keys localized to its
+// authoritative engine.
+type userLookup func(engineID []byte, userName string) (*localizedUser, error)
+
+// decodeMessage decodes an SNMPv2c or SNMPv3 message, authenticating and
+// decrypting SNMPv3 messages with the keys of the user returned by lookup.
+func decodeMessage(b []byte, lookup userLookup) (*message, error) {
+	root, n, err := decodeElement(b)
+	if err != nil {
+		return nil, err
+	}
+	b = b[:n]
+	if root.tag != tagSequence || len(root.children) < 3 {
+		return nil, errors.New("malformed message")
+	}
+
+	m := &message{}
+	if m.version, err = root.children[0].int(); err != nil {
+		return nil, err
+	}
+	switch m.version {
+	case versionV2c:
+		if m.community, err = root.children[1].bytes(); err != nil {
+			return nil, err
+		}
+		if m.pdu, err = decodePDU(root.children[2]); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case versionV3:
+	case versionV1:
+		return nil, errors.New("SNMPv1 messages are not supported")
+	default:
+		return nil, fmt.Errorf("message version not recognised: %v", m.version)
+	}
+
+	if len(root.children) != 4 {
+		return nil, errors.New("malformed SNMPv3 message")
+	}
+	global := root.children[1]
+	if global.tag != tagSequence || len(global.children) != 4 {
+		return nil, errors.New("malformed SNMPv3 header")
+	}
+	if m.msgID, err = global.children[0].int(); err != nil {
+		return nil, err
+	}
+	flags, err := global.children[2].bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) != 1 {
+		return nil, errors.New("malformed SNMPv3 message flags")
+	}
+	m.flags = flags[0]
+	if model, err := global.children[3].int(); err != nil {
+		return nil, err
+	} else if model != securityModelUSM {
+		return nil, fmt.Errorf("security model not supported: %v", model)
+	}
+
+	secBytes, err := root.children[2].bytes()
+	if err != nil {
+		return nil, err
+	}
+	sec, _, err := decodeElement(secBytes)
+	if err != nil {
+		return nil, err
+	}
+	if sec.tag != tagSequence || len(sec.children) != 6 {
+		return nil, errors.New("malformed SNMPv3 security parameters")
+	}
+	if m.engineID, err = sec.children[0].bytes(); err != nil {
+		return nil, err
+	}
+	if m.engineBoots, err = sec.children[1].int(); err != nil {
+		return nil, err
+	}
+	if m.engineTime, err = sec.children[2].int(); err != nil {
+		return nil, err
+	}
+	if m.userName, err = sec.children[3].bytes(); err != nil {
+		return nil, err
+	}
+
+	var user *localizedUser
+	if m.flags&(flagAuth|flagPriv) != 0 {
+		if user, err = lookup(m.engineID, string(m.userName)); err != nil {
+			return nil, err
+		}
+		if m.flags&flagAuth == 0 || user.AuthProtocol == AuthNone {
+			return nil, errors.New("message security level does not match user")
+		}
+		if m.flags&flagPriv != 0 && user.PrivProtocol == PrivNone {
+			return nil, errors.New("message security level does not match user")
+		}
+
+		authParams := sec.children[4].value
+		if len(authParams) != authParamsLen {
+			return nil, ErrAuthFailed
+		}
+		// The parameters are a sub slice of the message and therefore share
+		// the end of its capacity, giving us their offset.
+		off := cap(b) - cap(authParams)
+		zeroed := append([]byte(nil), b...)
+		copy(zeroed[off:off+authParamsLen], make([]byte, authParamsLen))
+		if !hmac.Equal(user.mac(zeroed), authParams) {
+			return nil, ErrAuthFailed
+		}
+	}
+
+	scoped := root.children[3]
+	if m.flags&flagPriv != 0 {
+		if scoped.tag != tagOctetString {
+			return nil, errors.New("expected encrypted scoped pdu")
+		}
+		plain, err := user.decrypt(scoped.value, sec.children[5].value, m.engineBoots, m.engineTime)
+		if err != nil {
+			return nil, err
+		}
+		if scoped, _, err = decodeElement(plain); err != nil {
+			return nil, fmt.Errorf("failed to decrypt scoped pdu: %w", err)
+		}
+	}
+	if scoped.tag != tagSequence || len(scoped.children) != 3 {
+		return nil, errors.New("malformed scoped pdu")
+	}
+	if m.contextEngineID, err = scoped.children[0].bytes(); err != nil {
+		return nil, err
+	}
+	if m.contextName, err = scoped.children[1].bytes(); err != nil {
+		return nil, err
+	}
+	if m.pdu, err = decodePDU(scoped.children[2]); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
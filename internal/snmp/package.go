@@ -0,0 +1,5 @@
+// Package snmp implements the subset of the Simple Network Management Protocol
+// needed for polling agents and receiving notifications, covering community
+// based SNMPv2c and SNMPv3 with the user based security model (RFC 3414) and
+// AES privacy (RFC 3826).
+package snmp
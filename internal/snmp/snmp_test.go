@@ -0,0 +1,404 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDEncoding(t *testing.T) {
+	tests := map[string]string{
+		"1.3.6.1.2.1.1.3.0":   "06082b06010201010300",
+		".1.3.6.1.4.1.2636.3": "06082b06010401944c03",
+		"2.100.3":             "0603813403",
+	}
+	for oid, exp := range tests {
+		b, err := encodeOID(oid)
+		require.NoError(t, err, oid)
+		assert.Equal(t, exp, hex.EncodeToString(b), oid)
+
+		e, _, err := decodeElement(b)
+		require.NoError(t, err)
+		got, err := e.oid()
+		require.NoError(t, err)
+		assert.Equal(t, oid[len(oid)-len(got):], got)
+	}
+
+	for _, oid := range []string{"", "1", "1.3.foo", "3.1", "1.40"} {
+		_, err := encodeOID(oid)
+		assert.Error(t, err, oid)
+	}
+
+	assert.True(t, oidHasPrefix("1.3.6.1.2", "1.3.6.1"))
+	assert.False(t, oidHasPrefix("1.3.6.10", "1.3.6.1"))
+	assert.True(t, oidLess("1.3.6.1.2", "1.3.6.1.10"))
+	assert.True(t, oidLess("1.3.6", "1.3.6.1"))
+	assert.False(t, oidLess("1.3.6.1", "1.3.6.1"))
+}
+
+func TestVariableRoundTrip(t *testing.T) {
+	vars := []Variable{
+		{OID: "1.3.6.1.2.1.1.1.0", Type: TypeOctetString, Value: []byte("router")},
+		{OID: "1.3.6.1.2.1.1.2.0", Type: TypeOID, Value: "1.3.6.1.4.1.9"},
+		{OID: "1.3.6.1.2.1.1.3.0", Type: TypeTimeTicks, Value: uint64(4294967295)},
+		{OID: "1.3.6.1.2.1.2.2.1.8.1", Type: TypeInteger, Value: int64(-5)},
+		{OID: "1.3.6.1.2.1.4.20.1.1.10", Type: TypeIPAddress, Value: "10.0.0.1"},
+		{OID: "1.3.6.1.2.1.31.1.1.1.6.1", Type: TypeCounter64, Value: uint64(1 << 63)},
+		{OID: "1.3.6.1.2.1.2.2.1.5.1", Type: TypeGauge32, Value: uint64(128)},
+		{OID: "1.3.6.1.2.1.2.2.1.99", Type: TypeNoSuchObject},
+	}
+	for _, v := range vars {
+		b, err := encodeVariable(v)
+		require.NoError(t, err, v.OID)
+		e, _, err := decodeElement(b)
+		require.NoError(t, err)
+		got, err := decodeVariable(e)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+	}
+}
+
+func TestPasswordToKey(t *testing.T) {
+	// Test vectors of RFC 3414 appendix A.3
+	engineID, err := hex.DecodeString("000000000000000000000002")
+	require.NoError(t, err)
+
+	key := passwordToKey(md5.New, "maplesyrup")
+	assert.Equal(t, "9faf3283884e92834ebc9847d8edd963", hex.EncodeToString(key))
+	assert.Equal(t, "526f5eed9fcce26f8964c2930787d82b", hex.EncodeToString(localizeKey(md5.New, key, engineID)))
+
+	key = passwordToKey(sha1.New, "maplesyrup")
+	assert.Equal(t, "9fb5cc0381497b3793528939ff788d5d79145211", hex.EncodeToString(key))
+	assert.Equal(t, "6695febc9288e36282235fc7151f128497b38f3f", hex.EncodeToString(localizeKey(sha1.New, key, engineID)))
+}
+
+func TestUserValidate(t *testing.T) {
+	for _, test := range []struct {
+		user User
+		err  string
+	}{
+		{User{}, "user name must not be empty"},
+		{User{Name: "foo", PrivProtocol: PrivAES}, "user foo: privacy requires an authentication protocol"},
+		{User{Name: "foo", AuthProtocol: AuthMD5, AuthPassword: "short"}, "user foo: authentication password must be at least 8 characters"},
+		{User{Name: "foo", AuthProtocol: "SHA512"}, "user foo: authentication protocol not recognised: SHA512"},
+		{User{Name: "foo", AuthProtocol: AuthSHA, AuthPassword: "password", PrivProtocol: "3DES"}, "user foo: privacy protocol not recognised: 3DES"},
+	} {
+		assert.EqualError(t, test.user.Validate(), test.err)
+	}
+	assert.NoError(t, User{Name: "foo"}.Validate())
+}
+
+//------------------------------------------------------------------------------
+
+func trapPDU(tag byte, vars ...Variable) *pdu {
+	return &pdu{
+		tag:       tag,
+		requestID: 42,
+		variables: append([]Variable{
+			{OID: oidSysUpTime, Type: TypeTimeTicks, Value: uint64(1234)},
+			{OID: oidSnmpTrapOID, Type: TypeOID, Value: "1.3.6.1.6.3.1.1.5.3"},
+		}, vars...),
+	}
+}
+
+func TestTrapV2c(t *testing.T) {
+	r, err := NewTrapReceiver([]string{"public"}, nil)
+	require.NoError(t, err)
+
+	ifIndex := Variable{OID: "1.3.6.1.2.1.2.2.1.1.2", Type: TypeInteger, Value: int64(2)}
+	b, err := (&message{
+		version:   versionV2c,
+		community: []byte("public"),
+		pdu:       trapPDU(pduTrapV2, ifIndex),
+	}).encode(nil)
+	require.NoError(t, err)
+
+	trap, res, err := r.Decode(b)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, &Trap{
+		Version:   Version2c,
+		Community: "public",
+		Uptime:    1234,
+		TrapOID:   "1.3.6.1.6.3.1.1.5.3",
+		Variables: []Variable{ifIndex},
+	}, trap)
+
+	b, err = (&message{
+		version:   versionV2c,
+		community: []byte("public"),
+		pdu:       trapPDU(pduInformRequest),
+	}).encode(nil)
+	require.NoError(t, err)
+
+	trap, res, err = r.Decode(b)
+	require.NoError(t, err)
+	assert.True(t, trap.Inform)
+	resMsg, err := decodeMessage(res, nil)
+	require.NoError(t, err)
+	assert.Equal(t, byte(pduResponse), resMsg.pdu.tag)
+	assert.Equal(t, int64(42), resMsg.pdu.requestID)
+	assert.Len(t, resMsg.pdu.variables, 2)
+
+	b, err = (&message{
+		version:   versionV2c,
+		community: []byte("private"),
+		pdu:       trapPDU(pduTrapV2),
+	}).encode(nil)
+	require.NoError(t, err)
+	_, _, err = r.Decode(b)
+	assert.EqualError(t, err, "unknown community: private")
+
+	_, _, err = r.Decode([]byte{0x30, 0x05, 0x02})
+	assert.Error(t, err)
+}
+
+func TestTrapV3(t *testing.T) {
+	users := []User{
+		{Name: "md5des", AuthProtocol: AuthMD5, AuthPassword: "authpassword", PrivProtocol: PrivDES, PrivPassword: "privpassword"},
+		{Name: "shaaes", AuthProtocol: AuthSHA, AuthPassword: "authpassword", PrivProtocol: PrivAES, PrivPassword: "privpassword"},
+		{Name: "shaonly", AuthProtocol: AuthSHA, AuthPassword: "authpassword"},
+		{Name: "noauth"},
+	}
+	r, err := NewTrapReceiver(nil, users)
+	require.NoError(t, err)
+
+	engineID := []byte{0x80, 0x00, 0x1f, 0x88, 0x04, 'a', 'g', 'e', 'n', 't'}
+	ifIndex := Variable{OID: "1.3.6.1.2.1.2.2.1.1.2", Type: TypeInteger, Value: int64(2)}
+	encode := func(u User, flags byte) []byte {
+		t.Helper()
+		b, err := (&message{
+			version:         versionV3,
+			msgID:           7,
+			flags:           flags,
+			engineID:        engineID,
+			engineBoots:     3,
+			engineTime:      1000,
+			userName:        []byte(u.Name),
+			contextEngineID: engineID,
+			contextName:     []byte("ctx"),
+			pdu:             trapPDU(pduTrapV2, ifIndex),
+		}).encode(u.localize(engineID))
+		require.NoError(t, err)
+		return b
+	}
+
+	for _, u := range users {
+		b := encode(u, u.flags())
+		if u.PrivProtocol != PrivNone {
+			assert.False(t, bytes.Contains(b, []byte("ctx")), u.Name)
+		}
+		trap, res, err := r.Decode(b)
+		require.NoError(t, err, u.Name)
+		assert.Nil(t, res)
+		assert.Equal(t, &Trap{
+			Version:     Version3,
+			User:        u.Name,
+			EngineID:    hex.EncodeToString(engineID),
+			ContextName: "ctx",
+			Uptime:      1234,
+			TrapOID:     "1.3.6.1.6.3.1.1.5.3",
+			Variables:   []Variable{ifIndex},
+		}, trap, u.Name)
+	}
+
+	wrongPass := users[1]
+	wrongPass.AuthPassword = "notthepassword"
+	_, _, err = r.Decode(encode(wrongPass, wrongPass.flags()))
+	assert.Equal(t, ErrAuthFailed, err)
+
+	_, _, err = r.Decode(encode(users[2], 0))
+	assert.EqualError(t, err, "message security level does not match user")
+
+	_, _, err = r.Decode(encode(User{Name: "nope"}, 0))
+	assert.EqualError(t, err, "unknown user: nope")
+}
+
+//------------------------------------------------------------------------------
+
+// fakeAgent responds to requests for a fixed set of variables, supporting
+// SNMPv3 engine discovery for a single user.
+type fakeAgent struct {
+	t        *testing.T
+	conn     net.PacketConn
+	engineID []byte
+	user     *localizedUser
+	vars     []Variable
+}
+
+func newFakeAgent(t *testing.T, user User, vars []Variable) *fakeAgent {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	a := &fakeAgent{
+		t:        t,
+		conn:     conn,
+		engineID: []byte{0x80, 0x00, 0x1f, 0x88, 0x04, 'f', 'a', 'k', 'e'},
+		vars:     vars,
+	}
+	a.user = user.localize(a.engineID)
+	go a.serve()
+	return a
+}
+
+func (a *fakeAgent) serve() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		m, err := decodeMessage(buf[:n], func(engineID []byte, userName string) (*localizedUser, error) {
+			return a.user, nil
+		})
+		if !assert.NoError(a.t, err) {
+			continue
+		}
+
+		res := &message{
+			version:         m.version,
+			community:       m.community,
+			msgID:           m.msgID,
+			engineID:        a.engineID,
+			engineBoots:     1,
+			engineTime:      500,
+			userName:        m.userName,
+			contextEngineID: a.engineID,
+			contextName:     m.contextName,
+			pdu:             &pdu{tag: pduResponse, requestID: m.pdu.requestID},
+		}
+		if m.version == versionV3 {
+			if len(m.engineID) == 0 {
+				res.pdu.tag = pduReport
+				res.pdu.variables = []Variable{{OID: oidUnknownEngineIDs, Type: TypeCounter32, Value: uint64(1)}}
+				b, err := res.encode(nil)
+				require.NoError(a.t, err)
+				_, _ = a.conn.WriteTo(b, addr)
+				continue
+			}
+			res.flags = m.flags &^ flagReportable
+		}
+
+		switch m.pdu.tag {
+		case pduGetRequest:
+			for _, req := range m.pdu.variables {
+				v := Variable{OID: req.OID, Type: TypeNoSuchObject}
+				for _, existing := range a.vars {
+					if existing.OID == req.OID {
+						v = existing
+					}
+				}
+				res.pdu.variables = append(res.pdu.variables, v)
+			}
+		case pduGetBulkRequest:
+			from := m.pdu.variables[0].OID
+			for _, v := range a.vars {
+				if len(res.pdu.variables) == int(m.pdu.errorIndex) {
+					break
+				}
+				if oidLess(from, v.OID) {
+					res.pdu.variables = append(res.pdu.variables, v)
+				}
+			}
+			if len(res.pdu.variables) < int(m.pdu.errorIndex) {
+				res.pdu.variables = append(res.pdu.variables, Variable{OID: from, Type: TypeEndOfMibView})
+			}
+		}
+
+		b, err := res.encode(a.user)
+		require.NoError(a.t, err)
+		_, _ = a.conn.WriteTo(b, addr)
+	}
+}
+
+var agentVars = []Variable{
+	{OID: "1.3.6.1.2.1.1.1.0", Type: TypeOctetString, Value: []byte("fake agent")},
+	{OID: "1.3.6.1.2.1.1.3.0", Type: TypeTimeTicks, Value: uint64(5000)},
+	{OID: "1.3.6.1.2.1.2.2.1.10.1", Type: TypeCounter32, Value: uint64(100)},
+	{OID: "1.3.6.1.2.1.2.2.1.10.2", Type: TypeCounter32, Value: uint64(200)},
+	{OID: "1.3.6.1.2.1.2.2.1.10.3", Type: TypeCounter32, Value: uint64(300)},
+	{OID: "1.3.6.1.2.1.2.2.1.16.1", Type: TypeCounter32, Value: uint64(10)},
+}
+
+func TestClientWalk(t *testing.T) {
+	users := map[string]User{
+		"v2c":    {},
+		"noauth": {Name: "noauth"},
+		"md5des": {Name: "md5des", AuthProtocol: AuthMD5, AuthPassword: "authpassword", PrivProtocol: PrivDES, PrivPassword: "privpassword"},
+		"shaaes": {Name: "shaaes", AuthProtocol: AuthSHA, AuthPassword: "authpassword", PrivProtocol: PrivAES, PrivPassword: "privpassword"},
+	}
+	for name, user := range users {
+		user := user
+		t.Run(name, func(t *testing.T) {
+			agent := newFakeAgent(t, user, agentVars)
+
+			conf := ClientConfig{
+				Address:   agent.conn.LocalAddr().String(),
+				Version:   Version3,
+				User:      user,
+				Timeout:   time.Second * 5,
+				Community: "public",
+			}
+			if name == "v2c" {
+				conf.Version = Version2c
+			}
+
+			ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+			defer done()
+
+			client, err := Dial(ctx, conf)
+			require.NoError(t, err)
+			defer client.Close()
+
+			vars, err := client.Walk(ctx, "1.3.6.1.2.1.2.2.1.10", 2)
+			require.NoError(t, err)
+			assert.Equal(t, agentVars[2:5], vars)
+
+			vars, err = client.Walk(ctx, "1.3.6.1.2.1.1.3.0", 2)
+			require.NoError(t, err)
+			assert.Equal(t, agentVars[1:2], vars)
+
+			vars, err = client.Walk(ctx, "1.3.6.1.2.1.99", 2)
+			require.NoError(t, err)
+			assert.Empty(t, vars)
+
+			vars, err = client.Get(ctx, "1.3.6.1.2.1.1.1.0")
+			require.NoError(t, err)
+			assert.Equal(t, agentVars[:1], vars)
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client, err := Dial(context.Background(), ClientConfig{
+		Address: conn.LocalAddr().String(),
+		Version: Version2c,
+		Timeout: time.Millisecond * 10,
+		Retries: 1,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Get(context.Background(), "1.3.6.1.2.1.1.1.0")
+	assert.Equal(t, ErrTimeout, err)
+
+	_, err = Dial(context.Background(), ClientConfig{Version: "1"})
+	assert.EqualError(t, err, "version not supported: 1")
+}
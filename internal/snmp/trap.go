@@ -0,0 +1,157 @@
+package snmp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Object identifiers of the variables that lead every notification (RFC 3416
+// section 4.2.6).
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// Trap is a notification received from an agent.
+type Trap struct {
+	Version Version
+
+	// Community is set for SNMPv2c notifications.
+	Community string
+
+	// User, EngineID and ContextName are set for SNMPv3 notifications.
+	User        string
+	EngineID    string
+	ContextName string
+
+	// Inform is true when the notification was an inform request, which is
+	// acknowledged with a response.
+	Inform bool
+
+	Uptime    uint64
+	TrapOID   string
+	Variables []Variable
+}
+
+// TrapReceiver decodes notifications, authenticating them against a set of
+// communities for SNMPv2c or users for SNMPv3.
+type TrapReceiver struct {
+	communities map[string]struct{}
+	users       map[string]User
+
+	mut       sync.Mutex
+	localized map[string]*localizedUser
+}
+
+// NewTrapReceiver creates a receiver that accepts notifications of the given
+// communities and users, where an empty list of communities accepts any.
+func NewTrapReceiver(communities []string, users []User) (*TrapReceiver, error) {
+	r := &TrapReceiver{
+		users:     map[string]User{},
+		localized: map[string]*localizedUser{},
+	}
+	if len(communities) > 0 {
+		r.communities = map[string]struct{}{}
+		for _, c := range communities {
+			r.communities[c] = struct{}{}
+		}
+	}
+	for _, u := range users {
+		if err := u.Validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := r.users[u.Name]; exists {
+			return nil, fmt.Errorf("user %v specified more than once", u.Name)
+		}
+		r.users[u.Name] = u
+	}
+	return r, nil
+}
+
+// lookup returns a user with keys localized to the engine ID of the agent,
+// which is authoritative for the traps it sends.
+func (r *TrapReceiver) lookup(engineID []byte, userName string) (*localizedUser, error) {
+	u, exists := r.users[userName]
+	if !exists {
+		return nil, fmt.Errorf("unknown user: %v", userName)
+	}
+
+	key := string(engineID) + "\x00" + userName
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	l, exists := r.localized[key]
+	if !exists {
+		l = u.localize(engineID)
+		r.localized[key] = l
+	}
+	return l, nil
+}
+
+// Decode a packet into a notification, returning a response to send back to
+// the agent when the notification is an inform request.
+func (r *TrapReceiver) Decode(b []byte) (*Trap, []byte, error) {
+	m, err := decodeMessage(b, r.lookup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t := &Trap{}
+	switch m.version {
+	case versionV2c:
+		if r.communities != nil {
+			if _, exists := r.communities[string(m.community)]; !exists {
+				return nil, nil, fmt.Errorf("unknown community: %v", string(m.community))
+			}
+		}
+		t.Version = Version2c
+		t.Community = string(m.community)
+	case versionV3:
+		u, exists := r.users[string(m.userName)]
+		if !exists {
+			return nil, nil, fmt.Errorf("unknown user: %v", string(m.userName))
+		}
+		if m.flags&(flagAuth|flagPriv) != u.flags() {
+			return nil, nil, errors.New("message security level does not match user")
+		}
+		t.Version = Version3
+		t.User = u.Name
+		t.EngineID = hex.EncodeToString(m.engineID)
+		t.ContextName = string(m.contextName)
+	}
+
+	switch m.pdu.tag {
+	case pduTrapV2:
+	case pduInformRequest:
+		if m.version == versionV3 {
+			// Receivers of SNMPv3 informs are authoritative, which requires
+			// an engine of our own.
+			return nil, nil, errors.New("SNMPv3 inform requests are not supported")
+		}
+		t.Inform = true
+	default:
+		return nil, nil, fmt.Errorf("unexpected pdu type: 0x%x", m.pdu.tag)
+	}
+
+	for i, v := range m.pdu.variables {
+		switch {
+		case i == 0 && v.OID == oidSysUpTime && v.Type == TypeTimeTicks:
+			t.Uptime, _ = v.Value.(uint64)
+		case i == 1 && v.OID == oidSnmpTrapOID && v.Type == TypeOID:
+			t.TrapOID, _ = v.Value.(string)
+		default:
+			t.Variables = append(t.Variables, v)
+		}
+	}
+
+	var res []byte
+	if t.Inform {
+		m.pdu.tag = pduResponse
+		m.pdu.errorStatus, m.pdu.errorIndex = 0, 0
+		if res, err = m.encode(nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return t, res, nil
+}
@@ -0,0 +1,257 @@
+package snmp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+	"sync/atomic"
+)
+
+// AuthProtocol is an SNMPv3 authentication protocol.
+type AuthProtocol string
+
+// Authentication protocols.
+const (
+	AuthNone AuthProtocol = ""
+	AuthMD5  AuthProtocol = "MD5"
+	AuthSHA  AuthProtocol = "SHA"
+)
+
+// PrivProtocol is an SNMPv3 privacy protocol.
+type PrivProtocol string
+
+// Privacy protocols.
+const (
+	PrivNone PrivProtocol = ""
+	PrivDES  PrivProtocol = "DES"
+	PrivAES  PrivProtocol = "AES"
+)
+
+// Message flags of SNMPv3 messages.
+const (
+	flagAuth       = 0x01
+	flagPriv       = 0x02
+	flagReportable = 0x04
+)
+
+// authParamsLen is the length of the truncated HMAC of HMAC-MD5-96 and
+// HMAC-SHA-96.
+const authParamsLen = 12
+
+// User is an SNMPv3 user of the user based security model.
+type User struct {
+	Name         string
+	AuthProtocol AuthProtocol
+	AuthPassword string
+	PrivProtocol PrivProtocol
+	PrivPassword string
+}
+
+// Validate returns an error if the protocols or passwords of a user are
+// invalid.
+func (u User) Validate() error {
+	if u.Name == "" {
+		return errors.New("user name must not be empty")
+	}
+	switch u.AuthProtocol {
+	case AuthNone:
+		if u.PrivProtocol != PrivNone {
+			return fmt.Errorf("user %v: privacy requires an authentication protocol", u.Name)
+		}
+	case AuthMD5, AuthSHA:
+		if len(u.AuthPassword) < 8 {
+			return fmt.Errorf("user %v: authentication password must be at least 8 characters", u.Name)
+		}
+	default:
+		return fmt.Errorf("user %v: authentication protocol not recognised: %v", u.Name, u.AuthProtocol)
+	}
+	switch u.PrivProtocol {
+	case PrivNone:
+	case PrivDES, PrivAES:
+		if len(u.PrivPassword) < 8 {
+			return fmt.Errorf("user %v: privacy password must be at least 8 characters", u.Name)
+		}
+	default:
+		return fmt.Errorf("user %v: privacy protocol not recognised: %v", u.Name, u.PrivProtocol)
+	}
+	return nil
+}
+
+func (u User) flags() byte {
+	var f byte
+	if u.AuthProtocol != AuthNone {
+		f |= flagAuth
+	}
+	if u.PrivProtocol != PrivNone {
+		f |= flagPriv
+	}
+	return f
+}
+
+func (u User) hashFn() func() hash.Hash {
+	if u.AuthProtocol == AuthSHA {
+		return sha1.New
+	}
+	return md5.New
+}
+
+// passwordToKey converts a password into a key as per RFC 3414 A.2, which
+// hashes a megabyte of the repeated password.
+func passwordToKey(hashFn func() hash.Hash, password string) []byte {
+	h := hashFn()
+	pw := []byte(password)
+	buf := make([]byte, 64)
+	for i, n := 0, 0; n < 1048576; n += 64 {
+		for j := range buf {
+			buf[j] = pw[i%len(pw)]
+			i++
+		}
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+func localizeKey(hashFn func() hash.Hash, key, engineID []byte) []byte {
+	h := hashFn()
+	h.Write(key)
+	h.Write(engineID)
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+// keyCache caches the non-localized keys of passwords, which are expensive to
+// compute.
+var keyCache sync.Map
+
+func cachedPasswordToKey(proto AuthProtocol, hashFn func() hash.Hash, password string) []byte {
+	cacheKey := string(proto) + ":" + password
+	if k, ok := keyCache.Load(cacheKey); ok {
+		return k.([]byte)
+	}
+	k := passwordToKey(hashFn, password)
+	keyCache.Store(cacheKey, k)
+	return k
+}
+
+// localizedUser is a user with keys localized to an authoritative engine.
+type localizedUser struct {
+	User
+	engineID []byte
+	authKey  []byte
+	privKey  []byte
+}
+
+func (u User) localize(engineID []byte) *localizedUser {
+	l := &localizedUser{User: u, engineID: engineID}
+	if u.AuthProtocol == AuthNone {
+		return l
+	}
+	hashFn := u.hashFn()
+	l.authKey = localizeKey(hashFn, cachedPasswordToKey(u.AuthProtocol, hashFn, u.AuthPassword), engineID)
+	if u.PrivProtocol != PrivNone {
+		l.privKey = localizeKey(hashFn, cachedPasswordToKey(u.AuthProtocol, hashFn, u.PrivPassword), engineID)
+	}
+	return l
+}
+
+func (l *localizedUser) mac(msg []byte) []byte {
+	h := hmac.New(l.hashFn(), l.authKey)
+	h.Write(msg)
+	return h.Sum(nil)[:authParamsLen]
+}
+
+//------------------------------------------------------------------------------
+
+var saltCounter uint64
+
+func init() {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	saltCounter = binary.BigEndian.Uint64(b[:])
+}
+
+// encrypt encrypts a scoped PDU, returning the cipher text and the privacy
+// parameters of the message.
+func (l *localizedUser) encrypt(plain []byte, boots, engineTime int64) ([]byte, []byte, error) {
+	salt := make([]byte, 8)
+	switch l.PrivProtocol {
+	case PrivDES:
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		binary.BigEndian.PutUint32(salt[4:], uint32(atomic.AddUint64(&saltCounter, 1)))
+
+		block, err := des.NewCipher(l.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		if r := len(plain) % des.BlockSize; r != 0 {
+			plain = append(append([]byte(nil), plain...), make([]byte, des.BlockSize-r)...)
+		}
+		out := make([]byte, len(plain))
+		cipher.NewCBCEncrypter(block, l.desIV(salt)).CryptBlocks(out, plain)
+		return out, salt, nil
+	case PrivAES:
+		binary.BigEndian.PutUint64(salt, atomic.AddUint64(&saltCounter, 1))
+
+		block, err := aes.NewCipher(l.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		out := make([]byte, len(plain))
+		cipher.NewCFBEncrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, plain)
+		return out, salt, nil
+	}
+	return nil, nil, fmt.Errorf("privacy protocol not recognised: %v", l.PrivProtocol)
+}
+
+func (l *localizedUser) decrypt(data, salt []byte, boots, engineTime int64) ([]byte, error) {
+	if len(salt) != 8 {
+		return nil, fmt.Errorf("invalid privacy parameters length: %v", len(salt))
+	}
+	switch l.PrivProtocol {
+	case PrivDES:
+		if len(data)%des.BlockSize != 0 {
+			return nil, errors.New("encrypted data is not a multiple of the block size")
+		}
+		block, err := des.NewCipher(l.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, l.desIV(salt)).CryptBlocks(out, data)
+		return out, nil
+	case PrivAES:
+		block, err := aes.NewCipher(l.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, data)
+		return out, nil
+	}
+	return nil, fmt.Errorf("privacy protocol not recognised: %v", l.PrivProtocol)
+}
+
+func (l *localizedUser) desIV(salt []byte) []byte {
+	iv := make([]byte, 8)
+	for i := range iv {
+		iv[i] = l.privKey[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+func aesIV(boots, engineTime int64, salt []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+	return iv
+}
@@ -0,0 +1,169 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Type describes the type of the value of a variable binding.
+type Type string
+
+// Variable binding types.
+const (
+	TypeInteger        Type = "integer"
+	TypeOctetString    Type = "octet_string"
+	TypeNull           Type = "null"
+	TypeOID            Type = "object_identifier"
+	TypeIPAddress      Type = "ip_address"
+	TypeCounter32      Type = "counter32"
+	TypeGauge32        Type = "gauge32"
+	TypeTimeTicks      Type = "timeticks"
+	TypeOpaque         Type = "opaque"
+	TypeCounter64      Type = "counter64"
+	TypeNoSuchObject   Type = "no_such_object"
+	TypeNoSuchInstance Type = "no_such_instance"
+	TypeEndOfMibView   Type = "end_of_mib_view"
+)
+
+var typeTags = map[Type]byte{
+	TypeInteger:        tagInteger,
+	TypeOctetString:    tagOctetString,
+	TypeNull:           tagNull,
+	TypeOID:            tagOID,
+	TypeIPAddress:      tagIPAddress,
+	TypeCounter32:      tagCounter32,
+	TypeGauge32:        tagGauge32,
+	TypeTimeTicks:      tagTimeTicks,
+	TypeOpaque:         tagOpaque,
+	TypeCounter64:      tagCounter64,
+	TypeNoSuchObject:   tagNoSuchObject,
+	TypeNoSuchInstance: tagNoSuchInstance,
+	TypeEndOfMibView:   tagEndOfMibView,
+}
+
+var tagTypes = map[byte]Type{}
+
+func init() {
+	for t, tag := range typeTags {
+		tagTypes[tag] = t
+	}
+}
+
+// Variable is an object identifier bound to a value, where the Go type of the
+// value depends on its SNMP type:
+//
+// - integer: int64
+// - octet_string, opaque: []byte
+// - object_identifier, ip_address: string
+// - counter32, gauge32, timeticks, counter64: uint64
+// - null and the exception types: nil
+type Variable struct {
+	OID   string
+	Type  Type
+	Value interface{}
+}
+
+// Exception returns whether the variable is one of the exceptions returned in
+// place of a value when an object does not exist.
+func (v Variable) Exception() bool {
+	switch v.Type {
+	case TypeNoSuchObject, TypeNoSuchInstance, TypeEndOfMibView:
+		return true
+	}
+	return false
+}
+
+func decodeVariable(e *element) (Variable, error) {
+	if e.tag != tagSequence || len(e.children) != 2 {
+		return Variable{}, errors.New("malformed variable binding")
+	}
+	oid, err := e.children[0].oid()
+	if err != nil {
+		return Variable{}, err
+	}
+	v := Variable{OID: oid}
+
+	val := e.children[1]
+	t, exists := tagTypes[val.tag]
+	if !exists {
+		return Variable{}, fmt.Errorf("unrecognised value type of variable %v: 0x%x", oid, val.tag)
+	}
+	v.Type = t
+
+	switch t {
+	case TypeInteger:
+		v.Value, err = val.int()
+	case TypeOctetString, TypeOpaque:
+		v.Value = append([]byte(nil), val.value...)
+	case TypeOID:
+		v.Value, err = decodeOID(val.value)
+	case TypeIPAddress:
+		if len(val.value) != 4 {
+			err = fmt.Errorf("invalid ip address length: %v", len(val.value))
+		} else {
+			v.Value = net.IP(val.value).String()
+		}
+	case TypeCounter32, TypeGauge32, TypeTimeTicks, TypeCounter64:
+		v.Value, err = val.uint()
+	}
+	if err != nil {
+		return Variable{}, fmt.Errorf("failed to decode variable %v: %w", oid, err)
+	}
+	return v, nil
+}
+
+func encodeVariable(v Variable) ([]byte, error) {
+	oid, err := encodeOID(v.OID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, exists := typeTags[v.Type]
+	if !exists {
+		if v.Type != "" {
+			return nil, fmt.Errorf("unrecognised variable type: %v", v.Type)
+		}
+		tag = tagNull
+	}
+
+	var val []byte
+	switch tag {
+	case tagInteger:
+		i, ok := v.Value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("expected int64 value for variable %v, got %T", v.OID, v.Value)
+		}
+		val = encodeInt(i)
+	case tagOctetString, tagOpaque:
+		b, ok := v.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("expected []byte value for variable %v, got %T", v.OID, v.Value)
+		}
+		val = encodeTLV(tag, b)
+	case tagOID:
+		s, ok := v.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string value for variable %v, got %T", v.OID, v.Value)
+		}
+		if val, err = encodeOID(s); err != nil {
+			return nil, err
+		}
+	case tagIPAddress:
+		s, _ := v.Value.(string)
+		ip := net.ParseIP(s).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("expected ipv4 address value for variable %v, got %v", v.OID, v.Value)
+		}
+		val = encodeTLV(tag, ip)
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		u, ok := v.Value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("expected uint64 value for variable %v, got %T", v.OID, v.Value)
+		}
+		val = encodeUint(tag, u)
+	default:
+		val = encodeTLV(tag, nil)
+	}
+	return encodeConstructed(tagSequence, oid, val), nil
+}
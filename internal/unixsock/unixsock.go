@@ -0,0 +1,218 @@
+package unixsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+// Config contains options that apply to unix domain sockets created by a
+// server.
+type Config struct {
+	FileMode string `json:"file_mode" yaml:"file_mode"`
+	Owner    string `json:"owner" yaml:"owner"`
+	Group    string `json:"group" yaml:"group"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		FileMode: "",
+		Owner:    "",
+		Group:    "",
+	}
+}
+
+// FieldSpec returns the documentation of the unix socket config fields.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"unix_socket", "Options that apply to socket files created when listening on a `unix` or `unixgram` network. These options are ignored for addresses within the abstract namespace.",
+	).WithChildren(
+		docs.FieldAdvanced("file_mode", "An optional octal file mode to set on the socket file, which controls which users are able to connect. When empty the mode is determined by the process umask.", "0660", "0600"),
+		docs.FieldAdvanced("owner", "An optional user name or numeric ID to set as the owner of the socket file. Changing ownership usually requires elevated privileges."),
+		docs.FieldAdvanced("group", "An optional group name or numeric ID to set as the group of the socket file.", "benthos"),
+	)
+}
+
+//------------------------------------------------------------------------------
+
+// IsUnixNetwork returns true if the network is a unix domain socket type.
+func IsUnixNetwork(network string) bool {
+	switch network {
+	case "unix", "unixgram", "unixpacket":
+		return true
+	}
+	return false
+}
+
+// IsAbstract returns true if the address refers to a socket within the Linux
+// abstract namespace, which is denoted by a leading `@`.
+func IsAbstract(address string) bool {
+	return strings.HasPrefix(address, "@")
+}
+
+// CheckAddress returns an error if the address of a unix socket cannot be used
+// on the current platform.
+func CheckAddress(address string) error {
+	if IsAbstract(address) && runtime.GOOS != "linux" {
+		return fmt.Errorf("abstract unix socket address '%v' is only supported on linux", address)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type fileOpts struct {
+	mode     os.FileMode
+	hasMode  bool
+	uid, gid int
+}
+
+func (c Config) parse() (opts fileOpts, err error) {
+	opts.uid, opts.gid = -1, -1
+	if c.FileMode != "" {
+		var m uint64
+		if m, err = strconv.ParseUint(c.FileMode, 8, 32); err != nil || m > 0777 {
+			return opts, fmt.Errorf("invalid file_mode '%v': expected an octal permission such as 0660", c.FileMode)
+		}
+		opts.mode, opts.hasMode = os.FileMode(m), true
+	}
+	if c.Owner != "" {
+		if opts.uid, err = strconv.Atoi(c.Owner); err != nil {
+			var u *user.User
+			if u, err = user.Lookup(c.Owner); err != nil {
+				return opts, fmt.Errorf("failed to resolve owner: %w", err)
+			}
+			if opts.uid, err = strconv.Atoi(u.Uid); err != nil {
+				return opts, fmt.Errorf("owner '%v' does not have a numeric ID", c.Owner)
+			}
+		}
+	}
+	if c.Group != "" {
+		if opts.gid, err = strconv.Atoi(c.Group); err != nil {
+			var g *user.Group
+			if g, err = user.LookupGroup(c.Group); err != nil {
+				return opts, fmt.Errorf("failed to resolve group: %w", err)
+			}
+			if opts.gid, err = strconv.Atoi(g.Gid); err != nil {
+				return opts, fmt.Errorf("group '%v' does not have a numeric ID", c.Group)
+			}
+		}
+	}
+	return opts, nil
+}
+
+func (o fileOpts) apply(path string) error {
+	if o.hasMode {
+		if err := os.Chmod(path, o.mode); err != nil {
+			return fmt.Errorf("failed to set socket file mode: %w", err)
+		}
+	}
+	if o.uid != -1 || o.gid != -1 {
+		if err := os.Chown(path, o.uid, o.gid); err != nil {
+			return fmt.Errorf("failed to set socket file ownership: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeStale deletes a socket file left behind by a previous process, which
+// would otherwise prevent the address from being bound. Files that are not
+// sockets, or sockets that are still accepting connections, are left alone.
+func removeStale(network, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout(network, path, time.Second)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket file: %w", err)
+	}
+	return nil
+}
+
+func prepare(network, address string, conf Config) (fileOpts, error) {
+	opts, err := conf.parse()
+	if err != nil {
+		return opts, err
+	}
+	if err = CheckAddress(address); err != nil {
+		return opts, err
+	}
+	if !IsAbstract(address) {
+		err = removeStale(network, address)
+	}
+	return opts, err
+}
+
+// Listen creates a stream listener on a unix domain socket address and applies
+// the file mode and ownership options of the config to the socket file.
+func Listen(network, address string, conf Config) (net.Listener, error) {
+	opts, err := prepare(network, address, conf)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if !IsAbstract(address) {
+		if err = opts.apply(address); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// ListenPacket creates a datagram listener on a unix domain socket address and
+// applies the file mode and ownership options of the config to the socket file.
+// The socket file is removed when the returned connection is closed.
+func ListenPacket(network, address string, conf Config) (net.PacketConn, error) {
+	opts, err := prepare(network, address, conf)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if IsAbstract(address) {
+		return conn, nil
+	}
+	if err = opts.apply(address); err != nil {
+		conn.Close()
+		os.Remove(address)
+		return nil, err
+	}
+	return &unlinkPacketConn{PacketConn: conn, path: address}, nil
+}
+
+type unlinkPacketConn struct {
+	net.PacketConn
+	path string
+}
+
+func (u *unlinkPacketConn) Close() error {
+	err := u.PacketConn.Close()
+	if rerr := os.Remove(u.path); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package unixsock
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigParse(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+		exp  fileOpts
+		err  string
+	}{
+		{
+			name: "empty",
+			conf: NewConfig(),
+			exp:  fileOpts{uid: -1, gid: -1},
+		},
+		{
+			name: "mode and ids",
+			conf: Config{FileMode: "0660", Owner: "10", Group: "20"},
+			exp:  fileOpts{mode: 0660, hasMode: true, uid: 10, gid: 20},
+		},
+		{
+			name: "bad mode",
+			conf: Config{FileMode: "0999"},
+			err:  "invalid file_mode '0999': expected an octal permission such as 0660",
+		},
+		{
+			name: "mode too large",
+			conf: Config{FileMode: "1777"},
+			err:  "invalid file_mode '1777': expected an octal permission such as 0660",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := test.conf.parse()
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.exp, opts)
+		})
+	}
+}
+
+func TestListenFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	conf := NewConfig()
+	conf.FileMode = "0600"
+	conf.Group = strconv.Itoa(os.Getgid())
+
+	ln, err := Listen("unix", path, conf)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, ln.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), err)
+}
+
+func TestListenStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.sock")
+
+	// Create a socket file that nothing is listening on.
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	ln, err = Listen("unix", path, NewConfig())
+	require.NoError(t, err)
+
+	// A socket with an active listener must not be removed.
+	_, err = Listen("unix", path, NewConfig())
+	require.Error(t, err)
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+	require.NoError(t, ln.Close())
+
+	// Regular files are never removed.
+	filePath := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(filePath, []byte("foo"), 0644))
+	_, err = Listen("unix", filePath, NewConfig())
+	require.Error(t, err)
+	_, err = os.Stat(filePath)
+	require.NoError(t, err)
+}
+
+func TestListenPacket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	conf := NewConfig()
+	conf.FileMode = "0620"
+
+	pc, err := ListenPacket("unixgram", path, conf)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0620), info.Mode().Perm())
+
+	conn, err := net.Dial("unixgram", path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("hello world"))
+	require.NoError(t, err)
+	conn.Close()
+
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second*5)))
+	buf := make([]byte, 64)
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(buf[:n]))
+
+	require.NoError(t, pc.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), err)
+}
+
+func TestListenAbstract(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		_, err := Listen("unix", "@benthos_test", NewConfig())
+		require.Error(t, err)
+		return
+	}
+
+	addr := "@benthos_unixsock_test_" + strconv.Itoa(os.Getpid())
+
+	conf := NewConfig()
+	conf.FileMode = "0600"
+
+	ln, err := Listen("unix", addr, conf)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	_, err = os.Stat(addr)
+	assert.True(t, os.IsNotExist(err), err)
+
+	conn, err := net.Dial("unix", addr)
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -0,0 +1,71 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DeviceType is a broad classification of a device.
+type DeviceType string
+
+// Device types.
+const (
+	DeviceBot     DeviceType = "bot"
+	DeviceMobile  DeviceType = "mobile"
+	DeviceTablet  DeviceType = "tablet"
+	DeviceDesktop DeviceType = "desktop"
+	DeviceTV      DeviceType = "tv"
+	DeviceConsole DeviceType = "console"
+	DeviceOther   DeviceType = "other"
+)
+
+var (
+	tvRegexp     = regexp.MustCompile(`(?i)smart-?tv|googletv|hbbtv|netcast|viera|bravia|aquos|roku|crkey|appletv|\bAFT[A-Z0-9]+\b|web0s|tizen.+\btv\b|\btv\b.+tizen`)
+	tabletRegexp = regexp.MustCompile(`(?i)ipad|tablet|kindle|\bKF[A-Z]{2,6}\b|silk|playbook|\bSM-[TX]\d`)
+	mobileRegexp = regexp.MustCompile(`(?i)mobi|iphone|ipod|windows phone|iemobile|blackberry|opera mini|kaios`)
+)
+
+var desktopOSes = map[string]struct{}{
+	"Windows":    {},
+	"Mac OS X":   {},
+	"Chrome OS":  {},
+	"Linux":      {},
+	"Ubuntu":     {},
+	"Fedora":     {},
+	"Debian":     {},
+	"CentOS":     {},
+	"Red Hat":    {},
+	"SUSE":       {},
+	"Gentoo":     {},
+	"Arch Linux": {},
+	"FreeBSD":    {},
+	"OpenBSD":    {},
+	"NetBSD":     {},
+}
+
+// classify determines the type of device of a user agent from the result of
+// parsing it and from hints within the user agent itself.
+func classify(ua string, res Result) DeviceType {
+	switch {
+	case res.Device.Family == "Spider":
+		return DeviceBot
+	case res.Device.Brand == "Sony" && strings.HasPrefix(res.Device.Family, "PlayStation"),
+		res.Device.Brand == "Microsoft" && strings.HasPrefix(res.Device.Family, "Xbox"),
+		res.Device.Brand == "Nintendo":
+		return DeviceConsole
+	case tvRegexp.MatchString(ua):
+		return DeviceTV
+	case tabletRegexp.MatchString(ua):
+		return DeviceTablet
+	case mobileRegexp.MatchString(ua):
+		return DeviceMobile
+	case res.OS.Family == "Android":
+		// Android devices that omit "Mobile" from their user agent are tablets
+		// by convention.
+		return DeviceTablet
+	}
+	if _, exists := desktopOSes[res.OS.Family]; exists {
+		return DeviceDesktop
+	}
+	return DeviceOther
+}
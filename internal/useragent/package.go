@@ -0,0 +1,4 @@
+// Package useragent parses user agent strings into the browser, operating
+// system and device that sent them, using regexes databases in the format of
+// uap-core, and classifies the type of device.
+package useragent
@@ -0,0 +1,277 @@
+package useragent
+
+import (
+	_ "embed" // Required for the default regexes
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed regexes.yaml
+var defaultRegexes []byte
+
+// Version is the version of a browser or operating system, where absent parts
+// are empty.
+type Version struct {
+	Major string
+	Minor string
+	Patch string
+}
+
+// String returns the dot separated parts of the version that are present.
+func (v Version) String() string {
+	parts := []string{v.Major, v.Minor, v.Patch}
+	n := 0
+	for n < len(parts) && parts[n] != "" {
+		n++
+	}
+	return strings.Join(parts[:n], ".")
+}
+
+// Client is the browser, or other software, that sent a user agent.
+type Client struct {
+	Family string
+	Version
+}
+
+// OS is the operating system of a user agent.
+type OS struct {
+	Family string
+	Version
+}
+
+// Device is the device of a user agent.
+type Device struct {
+	Family string
+	Brand  string
+	Model  string
+	Type   DeviceType
+}
+
+// Result is the result of parsing a user agent.
+type Result struct {
+	Browser Client
+	OS      OS
+	Device  Device
+}
+
+// unknownFamily is the family of parts of a user agent that aren't matched.
+const unknownFamily = "Other"
+
+//------------------------------------------------------------------------------
+
+type patternConfig struct {
+	Regex     string `yaml:"regex"`
+	RegexFlag string `yaml:"regex_flag"`
+
+	FamilyReplacement string `yaml:"family_replacement"`
+	V1Replacement     string `yaml:"v1_replacement"`
+	V2Replacement     string `yaml:"v2_replacement"`
+	V3Replacement     string `yaml:"v3_replacement"`
+
+	OSReplacement   string `yaml:"os_replacement"`
+	OSV1Replacement string `yaml:"os_v1_replacement"`
+	OSV2Replacement string `yaml:"os_v2_replacement"`
+	OSV3Replacement string `yaml:"os_v3_replacement"`
+
+	DeviceReplacement string `yaml:"device_replacement"`
+	BrandReplacement  string `yaml:"brand_replacement"`
+	ModelReplacement  string `yaml:"model_replacement"`
+}
+
+type regexesConfig struct {
+	UserAgentParsers []patternConfig `yaml:"user_agent_parsers"`
+	OSParsers        []patternConfig `yaml:"os_parsers"`
+	DeviceParsers    []patternConfig `yaml:"device_parsers"`
+}
+
+type pattern struct {
+	re           *regexp.Regexp
+	replacements [4]string
+
+	// positional values default to the group at the same position when there
+	// is no replacement.
+	positional bool
+}
+
+// match returns the four values of a pattern, where each is either its
+// replacement, with references to groups expanded, or for positional patterns
+// the group at the same position when there's no replacement.
+func (p *pattern) match(ua string) ([4]string, bool) {
+	var values [4]string
+	groups := p.re.FindStringSubmatch(ua)
+	if groups == nil {
+		return values, false
+	}
+	for i, r := range p.replacements {
+		if r != "" {
+			values[i] = strings.TrimSpace(expandGroups(r, groups))
+		} else if p.positional && i+1 < len(groups) {
+			values[i] = groups[i+1]
+		}
+	}
+	return values, true
+}
+
+var groupRefRegexp = regexp.MustCompile(`\$(\d)`)
+
+func expandGroups(replacement string, groups []string) string {
+	return groupRefRegexp.ReplaceAllStringFunc(replacement, func(ref string) string {
+		i, _ := strconv.Atoi(ref[1:])
+		if i < len(groups) {
+			return groups[i]
+		}
+		return ""
+	})
+}
+
+// Parser parses user agents with the patterns of a regexes database in the
+// format of uap-core. A Parser is safe for concurrent use.
+type Parser struct {
+	browsers []pattern
+	oses     []pattern
+	devices  []pattern
+
+	// Skipped contains the patterns of the database that aren't supported by
+	// the Go regular expression syntax, which are ignored.
+	Skipped []string
+}
+
+// NewParser creates a parser from a regexes database in the YAML format of
+// uap-core (https://github.com/ua-parser/uap-core).
+func NewParser(regexesYAML []byte) (*Parser, error) {
+	var conf regexesConfig
+	if err := yaml.Unmarshal(regexesYAML, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse regexes: %w", err)
+	}
+	if len(conf.UserAgentParsers) == 0 && len(conf.OSParsers) == 0 && len(conf.DeviceParsers) == 0 {
+		return nil, fmt.Errorf("regexes do not contain any parsers")
+	}
+
+	p := &Parser{}
+	compile := func(pc patternConfig, replacements [4]string, positional bool) (pattern, bool) {
+		expr := pc.Regex
+		if pc.RegexFlag == "i" {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			p.Skipped = append(p.Skipped, pc.Regex)
+			return pattern{}, false
+		}
+		return pattern{re: re, replacements: replacements, positional: positional}, true
+	}
+	for _, pc := range conf.UserAgentParsers {
+		if pat, ok := compile(pc, [4]string{pc.FamilyReplacement, pc.V1Replacement, pc.V2Replacement, pc.V3Replacement}, true); ok {
+			p.browsers = append(p.browsers, pat)
+		}
+	}
+	for _, pc := range conf.OSParsers {
+		if pat, ok := compile(pc, [4]string{pc.OSReplacement, pc.OSV1Replacement, pc.OSV2Replacement, pc.OSV3Replacement}, true); ok {
+			p.oses = append(p.oses, pat)
+		}
+	}
+	for _, pc := range conf.DeviceParsers {
+		// Device families and models default to the first group, and brands
+		// are only set by replacements.
+		family, model := pc.DeviceReplacement, pc.ModelReplacement
+		if family == "" {
+			family = "$1"
+		}
+		if model == "" {
+			model = "$1"
+		}
+		if pat, ok := compile(pc, [4]string{family, pc.BrandReplacement, model, ""}, false); ok {
+			p.devices = append(p.devices, pat)
+		}
+	}
+	return p, nil
+}
+
+var (
+	defaultParser     *Parser
+	defaultParserOnce sync.Once
+)
+
+// Default returns a parser of the regexes database embedded within this
+// package.
+func Default() *Parser {
+	defaultParserOnce.Do(func() {
+		var err error
+		if defaultParser, err = NewParser(defaultRegexes); err != nil {
+			panic(err)
+		}
+	})
+	return defaultParser
+}
+
+// Parse parses a user agent.
+func (p *Parser) Parse(ua string) Result {
+	res := Result{
+		Browser: Client{Family: unknownFamily},
+		OS:      OS{Family: unknownFamily},
+		Device:  Device{Family: unknownFamily},
+	}
+	for i := range p.browsers {
+		if v, ok := p.browsers[i].match(ua); ok && v[0] != "" {
+			res.Browser = Client{Family: v[0], Version: Version{v[1], v[2], v[3]}}
+			break
+		}
+	}
+	for i := range p.oses {
+		if v, ok := p.oses[i].match(ua); ok && v[0] != "" {
+			res.OS = OS{Family: v[0], Version: Version{v[1], v[2], v[3]}}
+			break
+		}
+	}
+	for i := range p.devices {
+		if v, ok := p.devices[i].match(ua); ok && v[0] != "" {
+			res.Device = Device{Family: v[0], Brand: v[1], Model: v[2]}
+			break
+		}
+	}
+	res.Device.Type = classify(ua, res)
+	return res
+}
+
+func (v Version) addTo(m map[string]interface{}) {
+	if s := v.String(); s != "" {
+		m["version"] = s
+	}
+	for k, p := range map[string]string{"major": v.Major, "minor": v.Minor, "patch": v.Patch} {
+		if p != "" {
+			m[k] = p
+		}
+	}
+}
+
+// ToMap converts a result into a structure of maps, where absent versions,
+// brands and models are omitted.
+func (r Result) ToMap() map[string]interface{} {
+	browser := map[string]interface{}{"family": r.Browser.Family}
+	r.Browser.Version.addTo(browser)
+
+	os := map[string]interface{}{"family": r.OS.Family}
+	r.OS.Version.addTo(os)
+
+	device := map[string]interface{}{
+		"family": r.Device.Family,
+		"type":   string(r.Device.Type),
+	}
+	if r.Device.Brand != "" {
+		device["brand"] = r.Device.Brand
+	}
+	if r.Device.Model != "" {
+		device["model"] = r.Device.Model
+	}
+
+	return map[string]interface{}{
+		"browser": browser,
+		"os":      os,
+		"device":  device,
+	}
+}
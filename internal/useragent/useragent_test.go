@@ -0,0 +1,247 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefault(t *testing.T) {
+	tests := []struct {
+		ua       string
+		browser  string
+		bVersion string
+		os       string
+		osVer    string
+		device   string
+		brand    string
+		model    string
+		devType  DeviceType
+	}{
+		{
+			ua:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+			browser:  "Chrome",
+			bVersion: "91.0.4472",
+			os:       "Windows",
+			osVer:    "10",
+			device:   "Other",
+			devType:  DeviceDesktop,
+		},
+		{
+			ua:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36 Edg/91.0.864.59",
+			browser:  "Edge",
+			bVersion: "91.0.864",
+			os:       "Windows",
+			osVer:    "10",
+			device:   "Other",
+			devType:  DeviceDesktop,
+		},
+		{
+			ua:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Safari/605.1.15",
+			browser:  "Safari",
+			bVersion: "14.1.1",
+			os:       "Mac OS X",
+			osVer:    "10.15.7",
+			device:   "Mac",
+			brand:    "Apple",
+			model:    "Mac",
+			devType:  DeviceDesktop,
+		},
+		{
+			ua:       "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0",
+			browser:  "Firefox",
+			bVersion: "89.0",
+			os:       "Ubuntu",
+			device:   "Other",
+			devType:  DeviceDesktop,
+		},
+		{
+			ua:       "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Mobile/15E148 Safari/604.1",
+			browser:  "Mobile Safari",
+			bVersion: "14.1.1",
+			os:       "iOS",
+			osVer:    "14.6",
+			device:   "iPhone",
+			brand:    "Apple",
+			model:    "iPhone",
+			devType:  DeviceMobile,
+		},
+		{
+			ua:       "Mozilla/5.0 (iPad; CPU OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/91.0.4472.80 Mobile/15E148 Safari/604.1",
+			browser:  "Chrome Mobile iOS",
+			bVersion: "91.0.4472",
+			os:       "iOS",
+			osVer:    "14.6",
+			device:   "iPad",
+			brand:    "Apple",
+			model:    "iPad",
+			devType:  DeviceTablet,
+		},
+		{
+			ua:       "Mozilla/5.0 (Linux; Android 11; SM-G991B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36",
+			browser:  "Chrome Mobile",
+			bVersion: "91.0.4472",
+			os:       "Android",
+			osVer:    "11",
+			device:   "Samsung SM-G991B",
+			brand:    "Samsung",
+			model:    "SM-G991B",
+			devType:  DeviceMobile,
+		},
+		{
+			ua:       "Mozilla/5.0 (Linux; Android 11; SM-T870) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/14.2 Chrome/87.0.4280.141 Safari/537.36",
+			browser:  "Samsung Internet",
+			bVersion: "14.2",
+			os:       "Android",
+			osVer:    "11",
+			device:   "Samsung SM-T870",
+			brand:    "Samsung",
+			model:    "SM-T870",
+			devType:  DeviceTablet,
+		},
+		{
+			ua:       "Mozilla/5.0 (Linux; Android 11; Pixel 5 Build/RQ3A.210605.005; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/91.0.4472.120 Mobile Safari/537.36",
+			browser:  "Chrome Mobile WebView",
+			bVersion: "91.0.4472",
+			os:       "Android",
+			osVer:    "11",
+			device:   "Pixel 5",
+			brand:    "Google",
+			model:    "Pixel 5",
+			devType:  DeviceMobile,
+		},
+		{
+			ua:       "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			browser:  "Googlebot",
+			bVersion: "2.1",
+			os:       "Other",
+			device:   "Spider",
+			brand:    "Spider",
+			model:    "Desktop",
+			devType:  DeviceBot,
+		},
+		{
+			ua:       "Mozilla/5.0 (compatible; MyCustomCrawler/1.2; +https://example.com)",
+			browser:  "MyCustomCrawler",
+			bVersion: "1.2",
+			os:       "Other",
+			device:   "Spider",
+			brand:    "Spider",
+			model:    "Desktop",
+			devType:  DeviceBot,
+		},
+		{
+			ua:       "curl/7.68.0",
+			browser:  "curl",
+			bVersion: "7.68.0",
+			os:       "Other",
+			device:   "Other",
+			devType:  DeviceOther,
+		},
+		{
+			ua:       "Mozilla/5.0 (PlayStation 5 3.11) AppleWebKit/605.1.15 (KHTML, like Gecko)",
+			browser:  "Other",
+			os:       "PlayStation 5",
+			device:   "PlayStation 5",
+			brand:    "Sony",
+			model:    "PlayStation 5",
+			devType:  DeviceConsole,
+		},
+		{
+			ua:       "Mozilla/5.0 (SMART-TV; Linux; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/4.0 Chrome/76.0.3809.146 TV Safari/537.36",
+			browser:  "Samsung Internet",
+			bVersion: "4.0",
+			os:       "Tizen",
+			osVer:    "6.0",
+			device:   "Other",
+			devType:  DeviceTV,
+		},
+		{
+			ua:       "Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			browser:  "IE",
+			bVersion: "11.0",
+			os:       "Windows",
+			osVer:    "7",
+			device:   "Other",
+			devType:  DeviceDesktop,
+		},
+		{
+			ua:      "",
+			browser: "Other",
+			os:      "Other",
+			device:  "Other",
+			devType: DeviceOther,
+		},
+	}
+
+	p := Default()
+	assert.Empty(t, p.Skipped)
+	for _, test := range tests {
+		res := p.Parse(test.ua)
+		assert.Equal(t, test.browser, res.Browser.Family, test.ua)
+		assert.Equal(t, test.bVersion, res.Browser.Version.String(), test.ua)
+		assert.Equal(t, test.os, res.OS.Family, test.ua)
+		assert.Equal(t, test.osVer, res.OS.Version.String(), test.ua)
+		assert.Equal(t, test.device, res.Device.Family, test.ua)
+		assert.Equal(t, test.brand, res.Device.Brand, test.ua)
+		assert.Equal(t, test.model, res.Device.Model, test.ua)
+		assert.Equal(t, test.devType, res.Device.Type, test.ua)
+	}
+}
+
+func TestParseCustomRegexes(t *testing.T) {
+	p, err := NewParser([]byte(`
+user_agent_parsers:
+  - regex: '(?!unsupported)'
+  - regex: 'acme-agent/(\d+)\.(\d+)'
+    family_replacement: 'Acme'
+    v1_replacement: '$1'
+    v2_replacement: '$2'
+os_parsers:
+  - regex: 'acmeos'
+    regex_flag: 'i'
+    os_replacement: 'AcmeOS'
+device_parsers:
+  - regex: '\(([a-z]+)phone\)'
+    device_replacement: 'Acme $1phone'
+    brand_replacement: 'Acme'
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"(?!unsupported)"}, p.Skipped)
+
+	res := p.Parse("acme-agent/3.14 (tinyphone) ACMEOS")
+	assert.Equal(t, Client{Family: "Acme", Version: Version{Major: "3", Minor: "14"}}, res.Browser)
+	assert.Equal(t, OS{Family: "AcmeOS"}, res.OS)
+	assert.Equal(t, Device{Family: "Acme tinyphone", Brand: "Acme", Model: "tiny", Type: DeviceOther}, res.Device)
+
+	_, err = NewParser([]byte(`foo: bar`))
+	assert.EqualError(t, err, "regexes do not contain any parsers")
+
+	_, err = NewParser([]byte(`{{{`))
+	assert.Error(t, err)
+}
+
+func TestResultToMap(t *testing.T) {
+	res := Default().Parse("Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1 Mobile/15E148 Safari/604.1")
+	assert.Equal(t, map[string]interface{}{
+		"browser": map[string]interface{}{
+			"family":  "Mobile Safari",
+			"version": "14.1",
+			"major":   "14",
+			"minor":   "1",
+		},
+		"os": map[string]interface{}{
+			"family":  "iOS",
+			"version": "14.6",
+			"major":   "14",
+			"minor":   "6",
+		},
+		"device": map[string]interface{}{
+			"family": "iPhone",
+			"brand":  "Apple",
+			"model":  "iPhone",
+			"type":   "mobile",
+		},
+	}, res.ToMap())
+}
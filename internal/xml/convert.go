@@ -0,0 +1,84 @@
+package xml
+
+// ToMapOptions configures the conversion of a parsed XML document into a
+// generic structure.
+type ToMapOptions struct {
+	// AttrPrefix is prefixed to the keys of attributes.
+	AttrPrefix string
+
+	// KeepNamespaces retains namespace prefixes of elements and attributes in
+	// keys, otherwise only local names are used.
+	KeepNamespaces bool
+
+	// Arrays is a set of dot separated paths of elements that are always
+	// converted into arrays, even when they only appear once.
+	Arrays map[string]struct{}
+}
+
+// NewToMapOptions returns options that result in the same structure as ToMap.
+func NewToMapOptions() ToMapOptions {
+	return ToMapOptions{
+		AttrPrefix: "-",
+		Arrays:     map[string]struct{}{},
+	}
+}
+
+// ToMapWithOptions converts a parsed XML document into a generic structure
+// that can be serialized to JSON.
+func (n *Node) ToMapWithOptions(opts ToMapOptions) map[string]interface{} {
+	key := opts.elementKey(n)
+	var value interface{} = opts.convert(n, key)
+	if _, isArray := opts.Arrays[key]; isArray {
+		value = []interface{}{value}
+	}
+	return map[string]interface{}{key: value}
+}
+
+func (o ToMapOptions) elementKey(n *Node) string {
+	if o.KeepNamespaces {
+		return n.Name()
+	}
+	return n.Local
+}
+
+func (o ToMapOptions) attrKey(a Attr) string {
+	if o.KeepNamespaces {
+		return o.AttrPrefix + a.Name()
+	}
+	return o.AttrPrefix + a.Local
+}
+
+func (o ToMapOptions) convert(n *Node, path string) interface{} {
+	if len(n.Attrs) == 0 && len(n.Children) == 0 {
+		return n.Text
+	}
+
+	obj := make(map[string]interface{}, len(n.Attrs)+len(n.Children)+1)
+	for _, a := range n.Attrs {
+		obj[o.attrKey(a)] = a.Value
+	}
+	for _, c := range n.Children {
+		key := o.elementKey(c)
+		childPath := path + "." + key
+		value := o.convert(c, childPath)
+
+		existing, exists := obj[key]
+		if !exists {
+			if _, isArray := o.Arrays[childPath]; isArray {
+				obj[key] = []interface{}{value}
+			} else {
+				obj[key] = value
+			}
+			continue
+		}
+		if arr, isArr := existing.([]interface{}); isArr {
+			obj[key] = append(arr, value)
+		} else {
+			obj[key] = []interface{}{existing, value}
+		}
+	}
+	if n.Text != "" {
+		obj["#text"] = n.Text
+	}
+	return obj
+}
@@ -0,0 +1,59 @@
+package xml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConvertXML = `<root xmlns:ns="http://example.com/ns">
+  <title>This is a title</title>
+  <description tone="boring">This is a description</description>
+  <elements id="1">foo1</elements>
+  <elements id="2">foo2</elements>
+  <elements>foo3</elements>
+  <ns:single ns:attr="bar"><empty/></ns:single>
+</root>`
+
+func TestToMapWithOptionsDefault(t *testing.T) {
+	exp, err := ToMap([]byte(testConvertXML))
+	require.NoError(t, err)
+
+	doc, err := Parse([]byte(testConvertXML))
+	require.NoError(t, err)
+
+	assert.Equal(t, exp, doc.ToMapWithOptions(NewToMapOptions()))
+}
+
+func TestToMapWithOptions(t *testing.T) {
+	doc, err := Parse([]byte(testConvertXML))
+	require.NoError(t, err)
+
+	opts := NewToMapOptions()
+	opts.AttrPrefix = "@"
+	opts.KeepNamespaces = true
+	opts.Arrays = map[string]struct{}{
+		"root.title":     {},
+		"root.ns:single": {},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"root": map[string]interface{}{
+			"@xmlns:ns": "http://example.com/ns",
+			"title":     []interface{}{"This is a title"},
+			"description": map[string]interface{}{
+				"@tone": "boring",
+				"#text": "This is a description",
+			},
+			"elements": []interface{}{
+				map[string]interface{}{"@id": "1", "#text": "foo1"},
+				map[string]interface{}{"@id": "2", "#text": "foo2"},
+				"foo3",
+			},
+			"ns:single": []interface{}{
+				map[string]interface{}{"@ns:attr": "bar", "empty": ""},
+			},
+		},
+	}, doc.ToMapWithOptions(opts))
+}
@@ -0,0 +1,173 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Attr is an attribute of an element, where the prefix is the namespace prefix
+// as written in the document.
+type Attr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+// Name returns the qualified name of the attribute as written in the document.
+func (a Attr) Name() string {
+	if a.Prefix == "" {
+		return a.Local
+	}
+	return a.Prefix + ":" + a.Local
+}
+
+// Node is an element of a parsed XML document.
+type Node struct {
+	Prefix   string
+	Local    string
+	Attrs    []Attr
+	Children []*Node
+	Parent   *Node
+
+	// Text contains the character data that is an immediate child of the
+	// element, trimmed of surrounding whitespace.
+	Text string
+}
+
+// Name returns the qualified name of the element as written in the document.
+func (n *Node) Name() string {
+	if n.Prefix == "" {
+		return n.Local
+	}
+	return n.Prefix + ":" + n.Local
+}
+
+// Attr returns the value of an attribute by its local name, and whether it
+// exists.
+func (n *Node) Attr(local string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Local == local && a.Prefix != "xmlns" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// StringValue returns the text content of the element and all of its
+// descendants concatenated in document order.
+func (n *Node) StringValue() string {
+	if len(n.Children) == 0 {
+		return n.Text
+	}
+	var buf strings.Builder
+	var walk func(*Node)
+	walk = func(c *Node) {
+		buf.WriteString(c.Text)
+		for _, cc := range c.Children {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return buf.String()
+}
+
+// Path returns a simple location path of the element within its document,
+// used for error messages.
+func (n *Node) Path() string {
+	var segments []string
+	for c := n; c != nil; c = c.Parent {
+		seg := c.Name()
+		if c.Parent != nil {
+			index, count := 0, 0
+			for _, sibling := range c.Parent.Children {
+				if sibling.Name() == seg {
+					count++
+				}
+				if sibling == c {
+					index = count
+				}
+			}
+			if count > 1 {
+				seg = fmt.Sprintf("%v[%v]", seg, index)
+			}
+		}
+		segments = append([]string{seg}, segments...)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Parse an XML document into a tree of nodes. Comments, directives and
+// processing instructions are ignored.
+func Parse(xmlBytes []byte) (*Node, error) {
+	dec := xml.NewDecoder(bytes.NewReader(xmlBytes))
+	dec.Strict = false
+	dec.CharsetReader = charset.NewReaderLabel
+
+	var root, current *Node
+	var text []*strings.Builder
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil && current == nil {
+				return nil, errors.New("document contains more than one root element")
+			}
+			n := &Node{
+				Prefix: t.Name.Space,
+				Local:  t.Name.Local,
+				Parent: current,
+			}
+			for _, a := range t.Attr {
+				n.Attrs = append(n.Attrs, Attr{
+					Prefix: a.Name.Space,
+					Local:  a.Name.Local,
+					Value:  a.Value,
+				})
+			}
+			if current == nil {
+				root = n
+			} else {
+				current.Children = append(current.Children, n)
+			}
+			current = n
+			text = append(text, &strings.Builder{})
+		case xml.EndElement:
+			if current == nil || current.Name() != nameOf(t.Name) {
+				return nil, fmt.Errorf("unexpected end element </%v>", nameOf(t.Name))
+			}
+			current.Text = strings.TrimSpace(text[len(text)-1].String())
+			text = text[:len(text)-1]
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				text[len(text)-1].Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("document does not contain a root element")
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unexpected end of document within <%v>", current.Name())
+	}
+	return root, nil
+}
+
+func nameOf(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
@@ -1,7 +1,9 @@
-// Package xml is a temporary way to convert XML to JSON. This package is only
-// necessary because github.com/clbanning/mxj has global configuration. If we
-// are able to configure a decoder etc at the API level then this package can be
-// removed.
+// Package xml provides utilities for parsing XML documents, converting them
+// into generic structures, selecting nodes with XPath expressions and
+// validating them against XSD schemas.
+//
+// The ToMap conversion is a wrapper around github.com/clbanning/mxj, which is
+// only necessary because mxj has global configuration.
 package xml
 
 import (
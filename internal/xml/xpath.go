@@ -0,0 +1,410 @@
+package xml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// XPath is a compiled expression of a subset of XPath 1.0 that selects nodes
+// from a parsed document. Supported are absolute and relative location paths
+// with the child (`/`) and descendant (`//`) separators, the steps `.`, `..`,
+// `*`, `text()`, `node()`, element names and attributes (`@name` or `@*`), and
+// predicates that are either a position (`[1]`, `[last()]`), an existence test
+// of a relative path (`[@id]`) or a comparison of a relative path with a
+// literal (`[@id='foo']`, `[price!=0]`).
+//
+// Names without a prefix match elements and attributes by their local name
+// regardless of their namespace, names with a prefix must match both.
+type XPath struct {
+	expr     string
+	absolute bool
+	steps    []xpathStep
+}
+
+type xpathStepKind int
+
+const (
+	xpathSelf xpathStepKind = iota
+	xpathParent
+	xpathElement
+	xpathAttribute
+	xpathText
+	xpathNode
+)
+
+type xpathStep struct {
+	descendant bool
+	kind       xpathStepKind
+	prefix     string
+	local      string
+	preds      []xpathPred
+}
+
+type xpathPred struct {
+	position int
+	last     bool
+	path     *XPath
+	op       string
+	literal  string
+}
+
+// XPathResult is a node selected by an XPath expression. Node is set when the
+// result is an element, and Value contains the string value of the result.
+type XPathResult struct {
+	Node  *Node
+	Value string
+}
+
+// CompileXPath parses an XPath expression.
+func CompileXPath(expr string) (*XPath, error) {
+	p := &xpathParser{input: expr}
+	x, err := p.parsePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse xpath '%v': %w", expr, err)
+	}
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("failed to parse xpath '%v': unexpected '%v' at char %v", expr, p.input[p.pos:], p.pos)
+	}
+	return x, nil
+}
+
+// String returns the expression the XPath was compiled from.
+func (x *XPath) String() string {
+	return x.expr
+}
+
+//------------------------------------------------------------------------------
+
+type xpathParser struct {
+	input string
+	pos   int
+}
+
+func (p *xpathParser) peek(s string) bool {
+	return strings.HasPrefix(p.input[p.pos:], s)
+}
+
+func (p *xpathParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func isXPathNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == ':' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *xpathParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.input) && isXPathNameChar(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *xpathParser) parsePath() (*XPath, error) {
+	start := p.pos
+	x := &XPath{}
+	p.skipSpace()
+
+	descendant := false
+	if p.peek("//") {
+		x.absolute, descendant = true, true
+		p.pos += 2
+	} else if p.peek("/") {
+		x.absolute = true
+		p.pos++
+		if p.pos == len(p.input) {
+			x.expr = p.input[start:p.pos]
+			return x, nil
+		}
+	}
+
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		step.descendant = descendant
+		x.steps = append(x.steps, step)
+
+		if p.peek("//") {
+			descendant = true
+			p.pos += 2
+		} else if p.peek("/") {
+			descendant = false
+			p.pos++
+		} else {
+			break
+		}
+	}
+	x.expr = strings.TrimSpace(p.input[start:p.pos])
+	return x, nil
+}
+
+func (p *xpathParser) parseStep() (xpathStep, error) {
+	var step xpathStep
+	switch {
+	case p.peek(".."):
+		p.pos += 2
+		step.kind = xpathParent
+	case p.peek("text()"):
+		p.pos += 6
+		step.kind = xpathText
+	case p.peek("node()"):
+		p.pos += 6
+		step.kind = xpathNode
+	case p.peek("."):
+		p.pos++
+		step.kind = xpathSelf
+	case p.peek("*"):
+		p.pos++
+		step.kind, step.local = xpathElement, "*"
+	case p.peek("@"):
+		p.pos++
+		step.kind = xpathAttribute
+		if p.peek("*") {
+			p.pos++
+			step.local = "*"
+		} else if step.prefix, step.local = splitXPathName(p.parseName()); step.local == "" {
+			return step, fmt.Errorf("expected attribute name at char %v", p.pos)
+		}
+	default:
+		step.kind = xpathElement
+		if step.prefix, step.local = splitXPathName(p.parseName()); step.local == "" {
+			if p.pos < len(p.input) {
+				return step, fmt.Errorf("unexpected '%c' at char %v", p.input[p.pos], p.pos)
+			}
+			return step, fmt.Errorf("expected a step at char %v", p.pos)
+		}
+	}
+
+	for p.peek("[") {
+		p.pos++
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return step, err
+		}
+		p.skipSpace()
+		if !p.peek("]") {
+			return step, fmt.Errorf("expected ']' at char %v", p.pos)
+		}
+		p.pos++
+		step.preds = append(step.preds, pred)
+	}
+	return step, nil
+}
+
+func splitXPathName(name string) (prefix, local string) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func (p *xpathParser) parsePredicate() (xpathPred, error) {
+	var pred xpathPred
+	p.skipSpace()
+
+	if p.peek("last()") {
+		p.pos += 6
+		pred.last = true
+		return pred, nil
+	}
+	if p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		start := p.pos
+		for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+			p.pos++
+		}
+		n, _ := strconv.Atoi(p.input[start:p.pos])
+		if n < 1 {
+			return pred, fmt.Errorf("position must be greater than zero at char %v", start)
+		}
+		pred.position = n
+		return pred, nil
+	}
+
+	path, err := p.parsePath()
+	if err != nil {
+		return pred, err
+	}
+	pred.path = path
+
+	p.skipSpace()
+	switch {
+	case p.peek("!="):
+		pred.op = "!="
+		p.pos += 2
+	case p.peek("="):
+		pred.op = "="
+		p.pos++
+	default:
+		return pred, nil
+	}
+
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return pred, fmt.Errorf("expected a literal at char %v", p.pos)
+	}
+	if quote := p.input[p.pos]; quote == '\'' || quote == '"' {
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return pred, fmt.Errorf("unterminated literal at char %v", p.pos)
+		}
+		pred.literal = p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return pred, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && strings.IndexByte("0123456789.-", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return pred, fmt.Errorf("expected a literal at char %v", p.pos)
+	}
+	pred.literal = p.input[start:p.pos]
+	return pred, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Select evaluates the XPath against a node and returns the results in
+// document order. Absolute paths are evaluated from the root of the document
+// the node belongs to.
+func (x *XPath) Select(n *Node) []XPathResult {
+	var context []XPathResult
+	if x.absolute {
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		context = []XPathResult{{Node: &Node{Children: []*Node{root}}}}
+	} else {
+		context = []XPathResult{{Node: n, Value: n.StringValue()}}
+	}
+	if len(x.steps) == 0 {
+		return context[0].Node.Children[0].selfResult()
+	}
+
+	for _, step := range x.steps {
+		var next []XPathResult
+		seen := map[*Node]struct{}{}
+		for _, c := range context {
+			if c.Node == nil {
+				continue
+			}
+			targets := []*Node{c.Node}
+			if step.descendant {
+				targets = append(targets, descendants(c.Node)...)
+			}
+			for _, t := range targets {
+				for _, r := range step.filter(step.apply(t)) {
+					if r.Node != nil {
+						if _, exists := seen[r.Node]; exists {
+							continue
+						}
+						seen[r.Node] = struct{}{}
+					}
+					next = append(next, r)
+				}
+			}
+		}
+		context = next
+	}
+	return context
+}
+
+func (n *Node) selfResult() []XPathResult {
+	return []XPathResult{{Node: n, Value: n.StringValue()}}
+}
+
+func descendants(n *Node) []*Node {
+	var nodes []*Node
+	for _, c := range n.Children {
+		nodes = append(nodes, c)
+		nodes = append(nodes, descendants(c)...)
+	}
+	return nodes
+}
+
+func (s xpathStep) matches(prefix, local string) bool {
+	if s.local != "*" && s.local != local {
+		return false
+	}
+	return s.prefix == "" || s.prefix == prefix
+}
+
+func (s xpathStep) apply(n *Node) []XPathResult {
+	var results []XPathResult
+	switch s.kind {
+	case xpathSelf:
+		if n.Local != "" {
+			results = n.selfResult()
+		}
+	case xpathParent:
+		if n.Parent != nil {
+			results = n.Parent.selfResult()
+		}
+	case xpathElement, xpathNode:
+		for _, c := range n.Children {
+			if s.kind == xpathNode || s.matches(c.Prefix, c.Local) {
+				results = append(results, c.selfResult()...)
+			}
+		}
+	case xpathAttribute:
+		for _, a := range n.Attrs {
+			if a.Prefix == "xmlns" || (a.Prefix == "" && a.Local == "xmlns") {
+				continue
+			}
+			if s.matches(a.Prefix, a.Local) {
+				results = append(results, XPathResult{Value: a.Value})
+			}
+		}
+	case xpathText:
+		if n.Text != "" {
+			results = append(results, XPathResult{Value: n.Text})
+		}
+	}
+	return results
+}
+
+func (s xpathStep) filter(results []XPathResult) []XPathResult {
+	for _, pred := range s.preds {
+		var filtered []XPathResult
+		for i, r := range results {
+			if pred.test(i+1, len(results), r) {
+				filtered = append(filtered, r)
+			}
+		}
+		results = filtered
+	}
+	return results
+}
+
+func (p xpathPred) test(position, size int, r XPathResult) bool {
+	switch {
+	case p.last:
+		return position == size
+	case p.position > 0:
+		return position == p.position
+	case r.Node == nil:
+		return false
+	}
+	for _, v := range p.path.Select(r.Node) {
+		switch p.op {
+		case "":
+			return true
+		case "=":
+			if v.Value == p.literal {
+				return true
+			}
+		case "!=":
+			if v.Value != p.literal {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package xml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXPathSelect(t *testing.T) {
+	doc, err := Parse([]byte(`<?xml version="1.0"?>
+<orders xmlns:ns="http://example.com/ns">
+  <order id="1" status="open">
+    <ns:ref>A1</ns:ref>
+    <item sku="foo"><price>10.50</price></item>
+    <item sku="bar"><price>3</price></item>
+  </order>
+  <order id="2" status="closed">
+    <ns:ref>B2</ns:ref>
+    <item sku="baz"><price>7</price></item>
+  </order>
+</orders>`))
+	require.NoError(t, err)
+
+	tests := map[string][]string{
+		`/orders/order/@id`:                      {"1", "2"},
+		`/orders/order[2]/@id`:                   {"2"},
+		`/orders/order[last()]/ref`:              {"B2"},
+		`//item/@sku`:                            {"foo", "bar", "baz"},
+		`//order[@status='open']//price`:         {"10.50", "3"},
+		`//order[@status!="open"]/item/@sku`:     {"baz"},
+		`//item[price=3]/@sku`:                   {"bar"},
+		`//item[1]/@sku`:                         {"foo", "baz"},
+		`/orders/order/ns:ref/text()`:            {"A1", "B2"},
+		`//other:ref`:                            nil,
+		`//item[@sku='baz']/../@id`:              {"2"},
+		`/orders/*[@id='1']/item[2]/price`:       {"3"},
+		`//order[item/@sku='baz']/ref`:           {"B2"},
+		`/orders/order[1]/item[@sku='foo']/.`:    {"10.50"},
+		`/orders/order[@id='1'][@status='open']`: {"A110.503"},
+		`order/@id`:                              {"1", "2"},
+	}
+
+	for expr, exp := range tests {
+		x, err := CompileXPath(expr)
+		require.NoError(t, err, expr)
+
+		var values []string
+		for _, r := range x.Select(doc) {
+			values = append(values, r.Value)
+		}
+		assert.Equal(t, exp, values, expr)
+	}
+}
+
+func TestXPathCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		`/orders/`:        "failed to parse xpath '/orders/': expected a step at char 8",
+		`/orders[@id='1'`: "failed to parse xpath '/orders[@id='1'': expected ']' at char 15",
+		`/orders[0]`:      "failed to parse xpath '/orders[0]': position must be greater than zero at char 8",
+		`/orders[@id=]`:   "failed to parse xpath '/orders[@id=]': expected a literal at char 12",
+		`count(/orders)`:  "failed to parse xpath 'count(/orders)': unexpected '(/orders)' at char 5",
+	}
+
+	for expr, exp := range tests {
+		_, err := CompileXPath(expr)
+		require.Error(t, err, expr)
+		assert.Equal(t, exp, err.Error(), expr)
+	}
+}
@@ -0,0 +1,1243 @@
+package xml
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// Schema is a compiled XML Schema (XSD) document that XML documents can be
+// validated against.
+//
+// Only a subset of XSD 1.0 is supported: global and local element
+// declarations with occurrence constraints, named and anonymous complex and
+// simple types, sequence, choice and all model groups, named groups and
+// attribute groups, wildcards, simple and complex content extensions, and
+// simple type restrictions with the enumeration, pattern, length, range and
+// digits facets, lists and unions. Namespaces are not validated, and elements
+// and types are matched by their local names. Schemas that use include,
+// import or redefine are rejected.
+type Schema struct {
+	xsdPrefixes map[string]struct{}
+
+	elements    map[string]*xsdElement
+	types       map[string]*xsdType
+	groups      map[string]*xsdParticle
+	attrGroups  map[string]*xsdAttrGroup
+	attributes  map[string]*xsdAttribute
+	typeNodes   map[string]*Node
+	filled      map[*xsdType]int
+	groupNodes  map[string]*Node
+	agroupNodes map[string]*Node
+}
+
+type xsdElement struct {
+	name string
+	typ  *xsdType
+}
+
+type xsdType struct {
+	name    string
+	simple  *xsdSimpleType
+	complex *xsdComplexType
+}
+
+type xsdComplexType struct {
+	anyType      bool
+	mixed        bool
+	content      *xsdParticle
+	attrs        []*xsdAttribute
+	anyAttribute bool
+	text         *xsdSimpleType
+}
+
+type xsdParticleKind int
+
+const (
+	xsdParticleElement xsdParticleKind = iota
+	xsdParticleAny
+	xsdParticleSequence
+	xsdParticleChoice
+	xsdParticleAll
+)
+
+type xsdParticle struct {
+	kind     xsdParticleKind
+	elem     *xsdElement
+	items    []*xsdParticle
+	min, max int
+}
+
+type xsdAttribute struct {
+	name     string
+	required bool
+	typ      *xsdSimpleType
+}
+
+type xsdAttrGroup struct {
+	attrs        []*xsdAttribute
+	anyAttribute bool
+}
+
+type xsdSimpleType struct {
+	builtin string
+	base    *xsdSimpleType
+	list    *xsdSimpleType
+	union   []*xsdSimpleType
+
+	enums          []string
+	patterns       []*regexp.Regexp
+	length         *int
+	minLength      *int
+	maxLength      *int
+	minInclusive   *string
+	maxInclusive   *string
+	minExclusive   *string
+	maxExclusive   *string
+	totalDigits    *int
+	fractionDigits *int
+}
+
+var xsdAnyType = &xsdType{name: "anyType", complex: &xsdComplexType{anyType: true}}
+
+//------------------------------------------------------------------------------
+
+// CompileSchema parses an XSD document.
+func CompileSchema(xsdBytes []byte) (*Schema, error) {
+	root, err := Parse(xsdBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if root.Local != "schema" {
+		return nil, fmt.Errorf("expected root element schema, got %v", root.Name())
+	}
+
+	s := &Schema{
+		xsdPrefixes: map[string]struct{}{},
+		elements:    map[string]*xsdElement{},
+		types:       map[string]*xsdType{},
+		groups:      map[string]*xsdParticle{},
+		attrGroups:  map[string]*xsdAttrGroup{},
+		attributes:  map[string]*xsdAttribute{},
+		typeNodes:   map[string]*Node{},
+		filled:      map[*xsdType]int{},
+		groupNodes:  map[string]*Node{},
+		agroupNodes: map[string]*Node{},
+	}
+	for _, a := range root.Attrs {
+		if a.Value != xsdNamespace {
+			continue
+		}
+		if a.Prefix == "xmlns" {
+			s.xsdPrefixes[a.Local] = struct{}{}
+		} else if a.Prefix == "" && a.Local == "xmlns" {
+			s.xsdPrefixes[""] = struct{}{}
+		}
+	}
+
+	// Register named definitions before compiling anything so that they can
+	// be referenced regardless of the order they're defined in.
+	for _, c := range root.Children {
+		name, _ := c.Attr("name")
+		switch c.Local {
+		case "complexType":
+			s.types[name] = &xsdType{name: name, complex: &xsdComplexType{}}
+			s.typeNodes[name] = c
+		case "simpleType":
+			s.types[name] = &xsdType{name: name, simple: &xsdSimpleType{}}
+			s.typeNodes[name] = c
+		case "group":
+			s.groupNodes[name] = c
+		case "attributeGroup":
+			s.agroupNodes[name] = c
+		case "element":
+			s.elements[name] = &xsdElement{name: name}
+		case "include", "import", "redefine", "override":
+			return nil, fmt.Errorf("schema %v is not supported", c.Local)
+		}
+	}
+
+	for _, c := range root.Children {
+		if c.Local == "attribute" {
+			attr, err := s.compileAttribute(c)
+			if err != nil {
+				return nil, err
+			}
+			if attr != nil {
+				s.attributes[attr.name] = attr
+			}
+		}
+	}
+	for name, t := range s.types {
+		if err := s.fillType(t, s.typeNodes[name]); err != nil {
+			return nil, fmt.Errorf("type %v: %w", name, err)
+		}
+	}
+	for _, c := range root.Children {
+		if c.Local == "element" {
+			name, _ := c.Attr("name")
+			typ, err := s.elementType(c)
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", name, err)
+			}
+			s.elements[name].typ = typ
+		}
+	}
+	if len(s.elements) == 0 {
+		return nil, errors.New("schema does not declare any global elements")
+	}
+	return s, nil
+}
+
+func (s *Schema) fillType(t *xsdType, n *Node) error {
+	switch s.filled[t] {
+	case 1:
+		return errors.New("circular type derivation")
+	case 2:
+		return nil
+	}
+	s.filled[t] = 1
+	defer func() { s.filled[t] = 2 }()
+
+	if t.simple != nil {
+		st, err := s.compileSimpleType(n)
+		if err != nil {
+			return err
+		}
+		*t.simple = *st
+		return nil
+	}
+	ct, err := s.compileComplexType(n)
+	if err != nil {
+		return err
+	}
+	*t.complex = *ct
+	return nil
+}
+
+// resolveType returns a type by its qualified name. Named types that have not
+// been compiled yet are returned as a reference and compiled later, which
+// allows them to be recursive.
+func (s *Schema) resolveType(qname string) (*xsdType, error) {
+	prefix, local := splitXPathName(qname)
+	if _, isXSD := s.xsdPrefixes[prefix]; !isXSD {
+		if t, exists := s.types[local]; exists {
+			return t, nil
+		}
+	}
+	if local == "anyType" {
+		return xsdAnyType, nil
+	}
+	if _, exists := xsdBuiltins[local]; exists {
+		return &xsdType{name: local, simple: &xsdSimpleType{builtin: local}}, nil
+	}
+	return nil, fmt.Errorf("type %v not recognised", qname)
+}
+
+// resolveBaseType returns a type by its qualified name, compiling it first if
+// necessary as the contents of base types are needed by their derivations.
+func (s *Schema) resolveBaseType(qname string) (*xsdType, error) {
+	t, err := s.resolveType(qname)
+	if err != nil {
+		return nil, err
+	}
+	if node, exists := s.typeNodes[t.name]; exists && s.types[t.name] == t {
+		if err := s.fillType(t, node); err != nil {
+			return nil, fmt.Errorf("base type %v: %w", t.name, err)
+		}
+	}
+	return t, nil
+}
+
+func (s *Schema) resolveSimpleType(qname string) (*xsdSimpleType, error) {
+	t, err := s.resolveBaseType(qname)
+	if err != nil {
+		return nil, err
+	}
+	if t.simple == nil {
+		return nil, fmt.Errorf("type %v is not a simple type", qname)
+	}
+	return t.simple, nil
+}
+
+func parseOccurs(n *Node) (min, max int, err error) {
+	min, max = 1, 1
+	if v, exists := n.Attr("minOccurs"); exists {
+		if min, err = strconv.Atoi(v); err != nil || min < 0 {
+			return 0, 0, fmt.Errorf("invalid minOccurs: %v", v)
+		}
+	}
+	if v, exists := n.Attr("maxOccurs"); exists {
+		if v == "unbounded" {
+			max = -1
+		} else if max, err = strconv.Atoi(v); err != nil || max < 0 {
+			return 0, 0, fmt.Errorf("invalid maxOccurs: %v", v)
+		}
+	}
+	if max >= 0 && max < min {
+		return 0, 0, errors.New("maxOccurs must not be less than minOccurs")
+	}
+	return min, max, nil
+}
+
+func (s *Schema) elementType(n *Node) (*xsdType, error) {
+	if typeName, exists := n.Attr("type"); exists {
+		return s.resolveType(typeName)
+	}
+	for _, c := range n.Children {
+		switch c.Local {
+		case "complexType":
+			ct, err := s.compileComplexType(c)
+			if err != nil {
+				return nil, err
+			}
+			return &xsdType{complex: ct}, nil
+		case "simpleType":
+			st, err := s.compileSimpleType(c)
+			if err != nil {
+				return nil, err
+			}
+			return &xsdType{simple: st}, nil
+		}
+	}
+	return xsdAnyType, nil
+}
+
+func (s *Schema) compileParticle(n *Node) (*xsdParticle, error) {
+	min, max, err := parseOccurs(n)
+	if err != nil {
+		return nil, err
+	}
+	p := &xsdParticle{min: min, max: max}
+
+	switch n.Local {
+	case "element":
+		if ref, exists := n.Attr("ref"); exists {
+			_, local := splitXPathName(ref)
+			elem, exists := s.elements[local]
+			if !exists {
+				return nil, fmt.Errorf("referenced element %v not found", ref)
+			}
+			p.elem = elem
+		} else {
+			name, _ := n.Attr("name")
+			typ, err := s.elementType(n)
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", name, err)
+			}
+			p.elem = &xsdElement{name: name, typ: typ}
+		}
+		p.kind = xsdParticleElement
+		return p, nil
+	case "any":
+		p.kind = xsdParticleAny
+		return p, nil
+	case "group":
+		ref, _ := n.Attr("ref")
+		_, local := splitXPathName(ref)
+		group, err := s.compileGroup(local)
+		if err != nil {
+			return nil, err
+		}
+		gp := *group
+		gp.min, gp.max = min, max
+		return &gp, nil
+	case "sequence":
+		p.kind = xsdParticleSequence
+	case "choice":
+		p.kind = xsdParticleChoice
+	case "all":
+		p.kind = xsdParticleAll
+	default:
+		return nil, fmt.Errorf("unexpected particle %v", n.Name())
+	}
+
+	for _, c := range n.Children {
+		if c.Local == "annotation" {
+			continue
+		}
+		item, err := s.compileParticle(c)
+		if err != nil {
+			return nil, err
+		}
+		if p.kind == xsdParticleAll && (item.kind != xsdParticleElement || item.max > 1) {
+			return nil, errors.New("all groups may only contain elements that occur at most once")
+		}
+		p.items = append(p.items, item)
+	}
+	return p, nil
+}
+
+func (s *Schema) compileGroup(name string) (*xsdParticle, error) {
+	if group, exists := s.groups[name]; exists {
+		if group == nil {
+			return nil, fmt.Errorf("group %v is circular", name)
+		}
+		return group, nil
+	}
+	n, exists := s.groupNodes[name]
+	if !exists {
+		return nil, fmt.Errorf("group %v not found", name)
+	}
+	s.groups[name] = nil
+	for _, c := range n.Children {
+		switch c.Local {
+		case "sequence", "choice", "all":
+			group, err := s.compileParticle(c)
+			if err != nil {
+				return nil, fmt.Errorf("group %v: %w", name, err)
+			}
+			s.groups[name] = group
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("group %v does not contain a model group", name)
+}
+
+func (s *Schema) compileAttribute(n *Node) (*xsdAttribute, error) {
+	attr := &xsdAttribute{}
+	if ref, exists := n.Attr("ref"); exists {
+		_, local := splitXPathName(ref)
+		global, exists := s.attributes[local]
+		if !exists {
+			return nil, fmt.Errorf("referenced attribute %v not found", ref)
+		}
+		*attr = *global
+	} else {
+		attr.name, _ = n.Attr("name")
+		attr.typ = &xsdSimpleType{builtin: "anySimpleType"}
+		if typeName, exists := n.Attr("type"); exists {
+			var err error
+			if attr.typ, err = s.resolveSimpleType(typeName); err != nil {
+				return nil, fmt.Errorf("attribute %v: %w", attr.name, err)
+			}
+		}
+		for _, c := range n.Children {
+			if c.Local == "simpleType" {
+				var err error
+				if attr.typ, err = s.compileSimpleType(c); err != nil {
+					return nil, fmt.Errorf("attribute %v: %w", attr.name, err)
+				}
+			}
+		}
+	}
+	use, _ := n.Attr("use")
+	attr.required = use == "required"
+	if use == "prohibited" {
+		return nil, nil
+	}
+	return attr, nil
+}
+
+func (s *Schema) compileAttrGroup(name string) (*xsdAttrGroup, error) {
+	if group, exists := s.attrGroups[name]; exists {
+		if group == nil {
+			return nil, fmt.Errorf("attribute group %v is circular", name)
+		}
+		return group, nil
+	}
+	n, exists := s.agroupNodes[name]
+	if !exists {
+		return nil, fmt.Errorf("attribute group %v not found", name)
+	}
+	s.attrGroups[name] = nil
+	group := &xsdAttrGroup{}
+	if err := s.compileAttrs(n, &group.attrs, &group.anyAttribute); err != nil {
+		return nil, fmt.Errorf("attribute group %v: %w", name, err)
+	}
+	s.attrGroups[name] = group
+	return group, nil
+}
+
+// compileAttrs appends the attribute declarations that are children of a node.
+func (s *Schema) compileAttrs(n *Node, attrs *[]*xsdAttribute, anyAttribute *bool) error {
+	for _, c := range n.Children {
+		switch c.Local {
+		case "attribute":
+			attr, err := s.compileAttribute(c)
+			if err != nil {
+				return err
+			}
+			if attr != nil {
+				*attrs = append(*attrs, attr)
+			}
+		case "attributeGroup":
+			ref, _ := c.Attr("ref")
+			_, local := splitXPathName(ref)
+			group, err := s.compileAttrGroup(local)
+			if err != nil {
+				return err
+			}
+			*attrs = append(*attrs, group.attrs...)
+			*anyAttribute = *anyAttribute || group.anyAttribute
+		case "anyAttribute":
+			*anyAttribute = true
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compileComplexType(n *Node) (*xsdComplexType, error) {
+	ct := &xsdComplexType{}
+	if mixed, _ := n.Attr("mixed"); mixed == "true" {
+		ct.mixed = true
+	}
+
+	for _, c := range n.Children {
+		switch c.Local {
+		case "sequence", "choice", "all", "group":
+			p, err := s.compileParticle(c)
+			if err != nil {
+				return nil, err
+			}
+			ct.content = p
+		case "simpleContent", "complexContent":
+			if err := s.compileDerivation(ct, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.compileAttrs(n, &ct.attrs, &ct.anyAttribute); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+func (s *Schema) compileDerivation(ct *xsdComplexType, n *Node) error {
+	var derivation *Node
+	for _, c := range n.Children {
+		if c.Local == "extension" || c.Local == "restriction" {
+			derivation = c
+		}
+	}
+	if derivation == nil {
+		return fmt.Errorf("%v must contain an extension or restriction", n.Local)
+	}
+	baseName, _ := derivation.Attr("base")
+	base, err := s.resolveBaseType(baseName)
+	if err != nil {
+		return err
+	}
+
+	if n.Local == "simpleContent" {
+		if base.simple != nil {
+			ct.text = base.simple
+		} else if base.complex.text != nil {
+			ct.text = base.complex.text
+			if derivation.Local == "extension" {
+				ct.attrs = append(ct.attrs, base.complex.attrs...)
+			}
+		} else {
+			return fmt.Errorf("base type %v does not have simple content", baseName)
+		}
+		if derivation.Local == "restriction" {
+			facets := &xsdSimpleType{base: ct.text}
+			if err := s.compileFacets(facets, derivation); err != nil {
+				return err
+			}
+			ct.text = facets
+		}
+		return s.compileAttrs(derivation, &ct.attrs, &ct.anyAttribute)
+	}
+
+	if base.complex == nil {
+		return fmt.Errorf("base type %v is not a complex type", baseName)
+	}
+	if mixed, _ := n.Attr("mixed"); mixed == "true" {
+		ct.mixed = true
+	}
+	var content *xsdParticle
+	for _, c := range derivation.Children {
+		switch c.Local {
+		case "sequence", "choice", "all", "group":
+			if content, err = s.compileParticle(c); err != nil {
+				return err
+			}
+		}
+	}
+	if derivation.Local == "extension" {
+		ct.anyType = base.complex.anyType
+		ct.mixed = ct.mixed || base.complex.mixed
+		ct.attrs = append(ct.attrs, base.complex.attrs...)
+		ct.anyAttribute = base.complex.anyAttribute
+		switch {
+		case base.complex.content == nil:
+			ct.content = content
+		case content == nil:
+			ct.content = base.complex.content
+		default:
+			ct.content = &xsdParticle{
+				kind:  xsdParticleSequence,
+				items: []*xsdParticle{base.complex.content, content},
+				min:   1, max: 1,
+			}
+		}
+	} else {
+		ct.content = content
+	}
+	return s.compileAttrs(derivation, &ct.attrs, &ct.anyAttribute)
+}
+
+func (s *Schema) compileSimpleType(n *Node) (*xsdSimpleType, error) {
+	for _, c := range n.Children {
+		switch c.Local {
+		case "restriction":
+			st := &xsdSimpleType{}
+			if baseName, exists := c.Attr("base"); exists {
+				base, err := s.resolveSimpleType(baseName)
+				if err != nil {
+					return nil, err
+				}
+				st.base = base
+			} else {
+				for _, cc := range c.Children {
+					if cc.Local == "simpleType" {
+						base, err := s.compileSimpleType(cc)
+						if err != nil {
+							return nil, err
+						}
+						st.base = base
+					}
+				}
+			}
+			if st.base == nil {
+				return nil, errors.New("restriction requires a base type")
+			}
+			if err := s.compileFacets(st, c); err != nil {
+				return nil, err
+			}
+			return st, nil
+		case "list":
+			st := &xsdSimpleType{}
+			var err error
+			if itemType, exists := c.Attr("itemType"); exists {
+				st.list, err = s.resolveSimpleType(itemType)
+			} else {
+				for _, cc := range c.Children {
+					if cc.Local == "simpleType" {
+						st.list, err = s.compileSimpleType(cc)
+					}
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			if st.list == nil {
+				return nil, errors.New("list requires an item type")
+			}
+			return st, nil
+		case "union":
+			st := &xsdSimpleType{}
+			if members, exists := c.Attr("memberTypes"); exists {
+				for _, m := range strings.Fields(members) {
+					member, err := s.resolveSimpleType(m)
+					if err != nil {
+						return nil, err
+					}
+					st.union = append(st.union, member)
+				}
+			}
+			for _, cc := range c.Children {
+				if cc.Local == "simpleType" {
+					member, err := s.compileSimpleType(cc)
+					if err != nil {
+						return nil, err
+					}
+					st.union = append(st.union, member)
+				}
+			}
+			if len(st.union) == 0 {
+				return nil, errors.New("union requires member types")
+			}
+			return st, nil
+		}
+	}
+	return nil, errors.New("simple type must contain a restriction, list or union")
+}
+
+func (s *Schema) compileFacets(st *xsdSimpleType, n *Node) error {
+	for _, c := range n.Children {
+		value, _ := c.Attr("value")
+		intFacet := func(target **int) error {
+			i, err := strconv.Atoi(value)
+			if err != nil || i < 0 {
+				return fmt.Errorf("invalid %v facet: %v", c.Local, value)
+			}
+			*target = &i
+			return nil
+		}
+		var err error
+		switch c.Local {
+		case "enumeration":
+			st.enums = append(st.enums, value)
+		case "pattern":
+			var re *regexp.Regexp
+			if re, err = regexp.Compile("^(?:" + value + ")$"); err != nil {
+				return fmt.Errorf("invalid pattern facet: %w", err)
+			}
+			st.patterns = append(st.patterns, re)
+		case "length":
+			err = intFacet(&st.length)
+		case "minLength":
+			err = intFacet(&st.minLength)
+		case "maxLength":
+			err = intFacet(&st.maxLength)
+		case "totalDigits":
+			err = intFacet(&st.totalDigits)
+		case "fractionDigits":
+			err = intFacet(&st.fractionDigits)
+		case "minInclusive":
+			st.minInclusive = &value
+		case "maxInclusive":
+			st.maxInclusive = &value
+		case "minExclusive":
+			st.minExclusive = &value
+		case "maxExclusive":
+			st.maxExclusive = &value
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Validate checks a parsed document against the schema and returns an error
+// describing each violation found.
+func (s *Schema) Validate(root *Node) error {
+	decl, exists := s.elements[root.Local]
+	if !exists {
+		return fmt.Errorf("%v: element is not declared by the schema", root.Path())
+	}
+	var errs []string
+	s.validateElement(root, decl.typ, &errs)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func isXSDIgnoredAttr(a Attr) bool {
+	return a.Prefix == "xmlns" || a.Prefix == "xsi" || a.Prefix == "xml" ||
+		(a.Prefix == "" && a.Local == "xmlns")
+}
+
+func (s *Schema) validateElement(n *Node, t *xsdType, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, n.Path()+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t.simple != nil {
+		if len(n.Children) > 0 {
+			fail("element must not contain child elements")
+			return
+		}
+		for _, a := range n.Attrs {
+			if !isXSDIgnoredAttr(a) {
+				fail("attribute %v is not allowed", a.Name())
+			}
+		}
+		if err := t.simple.validate(n.Text); err != nil {
+			fail("%v", err)
+		}
+		return
+	}
+
+	ct := t.complex
+	if ct.anyType {
+		return
+	}
+
+	for _, decl := range ct.attrs {
+		v, exists := n.Attr(decl.name)
+		if !exists {
+			if decl.required {
+				fail("missing required attribute %v", decl.name)
+			}
+			continue
+		}
+		if err := decl.typ.validate(v); err != nil {
+			fail("attribute %v: %v", decl.name, err)
+		}
+	}
+	if !ct.anyAttribute {
+	attrLoop:
+		for _, a := range n.Attrs {
+			if isXSDIgnoredAttr(a) {
+				continue
+			}
+			for _, decl := range ct.attrs {
+				if decl.name == a.Local {
+					continue attrLoop
+				}
+			}
+			fail("attribute %v is not allowed", a.Name())
+		}
+	}
+
+	if ct.text != nil {
+		if len(n.Children) > 0 {
+			fail("element must not contain child elements")
+			return
+		}
+		if err := ct.text.validate(n.Text); err != nil {
+			fail("%v", err)
+		}
+		return
+	}
+	if !ct.mixed && n.Text != "" {
+		fail("element must not contain text")
+	}
+
+	if ct.content == nil {
+		if len(n.Children) > 0 {
+			fail("element must not contain child elements")
+		}
+		return
+	}
+
+	furthest := 0
+	ends := ct.content.match(n.Children, 0, &furthest)
+	if _, complete := ends[len(n.Children)]; !complete {
+		if furthest < len(n.Children) {
+			fail("element %v is not expected", n.Children[furthest].Name())
+		} else {
+			fail("element content is incomplete")
+		}
+		return
+	}
+
+	decls := map[string]*xsdElement{}
+	ct.content.collectElements(decls)
+	for _, c := range n.Children {
+		if decl, exists := decls[c.Local]; exists {
+			s.validateElement(c, decl.typ, errs)
+		} else if global, exists := s.elements[c.Local]; exists {
+			// Matched by a wildcard, which we validate laxly.
+			s.validateElement(c, global.typ, errs)
+		}
+	}
+}
+
+func (p *xsdParticle) collectElements(decls map[string]*xsdElement) {
+	switch p.kind {
+	case xsdParticleElement:
+		if _, exists := decls[p.elem.name]; !exists {
+			decls[p.elem.name] = p.elem
+		}
+	case xsdParticleSequence, xsdParticleChoice, xsdParticleAll:
+		for _, item := range p.items {
+			item.collectElements(decls)
+		}
+	}
+}
+
+type xsdPositions map[int]struct{}
+
+// match returns the set of positions within the children that the particle
+// could finish matching at when starting from pos. The furthest position that
+// any element has been matched up to is tracked for error messages.
+func (p *xsdParticle) match(children []*Node, pos int, furthest *int) xsdPositions {
+	results := xsdPositions{}
+	if p.kind == xsdParticleElement || p.kind == xsdParticleAny {
+		if p.min == 0 {
+			results[pos] = struct{}{}
+		}
+		for count, cur := 0, pos; (p.max < 0 || count < p.max) && cur < len(children); {
+			if p.kind == xsdParticleElement && children[cur].Local != p.elem.name {
+				break
+			}
+			cur++
+			count++
+			if cur > *furthest {
+				*furthest = cur
+			}
+			if count >= p.min {
+				results[cur] = struct{}{}
+			}
+		}
+		return results
+	}
+
+	if p.min == 0 {
+		results[pos] = struct{}{}
+	}
+	frontier := xsdPositions{pos: {}}
+	for count := 0; p.max < 0 || count < p.max; count++ {
+		next := xsdPositions{}
+		for start := range frontier {
+			for end := range p.matchOnce(children, start, furthest) {
+				next[end] = struct{}{}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		if count+1 >= p.min {
+			progressed := false
+			for end := range next {
+				if _, exists := results[end]; !exists {
+					results[end] = struct{}{}
+					progressed = true
+				}
+			}
+			if !progressed {
+				break
+			}
+		}
+		frontier = next
+		if count > len(children)+p.min {
+			break
+		}
+	}
+	return results
+}
+
+func (p *xsdParticle) matchOnce(children []*Node, pos int, furthest *int) xsdPositions {
+	switch p.kind {
+	case xsdParticleSequence:
+		current := xsdPositions{pos: {}}
+		for _, item := range p.items {
+			next := xsdPositions{}
+			for start := range current {
+				for end := range item.match(children, start, furthest) {
+					next[end] = struct{}{}
+				}
+			}
+			if current = next; len(current) == 0 {
+				break
+			}
+		}
+		return current
+	case xsdParticleChoice:
+		results := xsdPositions{}
+		for _, item := range p.items {
+			for end := range item.match(children, pos, furthest) {
+				results[end] = struct{}{}
+			}
+		}
+		return results
+	}
+
+	// All groups match each of their elements at most once in any order.
+	results := xsdPositions{}
+	used := make([]bool, len(p.items))
+	var walk func(pos int)
+	walk = func(pos int) {
+		satisfied := true
+		for i, item := range p.items {
+			if !used[i] && item.min > 0 {
+				satisfied = false
+			}
+		}
+		if satisfied {
+			results[pos] = struct{}{}
+		}
+		if pos >= len(children) {
+			return
+		}
+		for i, item := range p.items {
+			if !used[i] && children[pos].Local == item.elem.name {
+				used[i] = true
+				if pos+1 > *furthest {
+					*furthest = pos + 1
+				}
+				walk(pos + 1)
+				used[i] = false
+			}
+		}
+	}
+	walk(pos)
+	return results
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	xsdDecimalRegexp  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	xsdFloatRegexp    = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	xsdIntegerRegexp  = regexp.MustCompile(`^[+-]?\d+$`)
+	xsdDurationRegexp = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+)
+
+func xsdIntRange(min, max string) func(string) error {
+	var lower, upper *big.Int
+	if min != "" {
+		lower, _ = new(big.Int).SetString(min, 10)
+	}
+	if max != "" {
+		upper, _ = new(big.Int).SetString(max, 10)
+	}
+	return func(v string) error {
+		if !xsdIntegerRegexp.MatchString(v) {
+			return errors.New("not an integer")
+		}
+		i, _ := new(big.Int).SetString(strings.TrimPrefix(v, "+"), 10)
+		if (lower != nil && i.Cmp(lower) < 0) || (upper != nil && i.Cmp(upper) > 0) {
+			return errors.New("out of range")
+		}
+		return nil
+	}
+}
+
+func xsdTimeLayouts(layouts ...string) func(string) error {
+	return func(v string) error {
+		for _, layout := range layouts {
+			for _, zone := range []string{"", "Z07:00"} {
+				if _, err := time.Parse(layout+zone, v); err == nil {
+					return nil
+				}
+			}
+		}
+		return errors.New("invalid format")
+	}
+}
+
+var xsdBuiltins = map[string]func(string) error{
+	"anySimpleType":    nil,
+	"string":           nil,
+	"normalizedString": nil,
+	"token":            nil,
+	"language":         nil,
+	"Name":             nil,
+	"NCName":           nil,
+	"QName":            nil,
+	"ID":               nil,
+	"IDREF":            nil,
+	"IDREFS":           nil,
+	"ENTITY":           nil,
+	"NMTOKEN":          nil,
+	"NMTOKENS":         nil,
+	"anyURI":           nil,
+	"gYear":            nil,
+	"gYearMonth":       nil,
+	"gMonth":           nil,
+	"gMonthDay":        nil,
+	"gDay":             nil,
+	"boolean": func(v string) error {
+		switch v {
+		case "true", "false", "1", "0":
+			return nil
+		}
+		return errors.New("not a boolean")
+	},
+	"decimal": func(v string) error {
+		if !xsdDecimalRegexp.MatchString(v) {
+			return errors.New("not a decimal")
+		}
+		return nil
+	},
+	"float":              xsdFloat,
+	"double":             xsdFloat,
+	"integer":            xsdIntRange("", ""),
+	"nonNegativeInteger": xsdIntRange("0", ""),
+	"positiveInteger":    xsdIntRange("1", ""),
+	"nonPositiveInteger": xsdIntRange("", "0"),
+	"negativeInteger":    xsdIntRange("", "-1"),
+	"long":               xsdIntRange("-9223372036854775808", "9223372036854775807"),
+	"int":                xsdIntRange("-2147483648", "2147483647"),
+	"short":              xsdIntRange("-32768", "32767"),
+	"byte":               xsdIntRange("-128", "127"),
+	"unsignedLong":       xsdIntRange("0", "18446744073709551615"),
+	"unsignedInt":        xsdIntRange("0", "4294967295"),
+	"unsignedShort":      xsdIntRange("0", "65535"),
+	"unsignedByte":       xsdIntRange("0", "255"),
+	"date":               xsdTimeLayouts("2006-01-02"),
+	"dateTime":           xsdTimeLayouts("2006-01-02T15:04:05", "2006-01-02T15:04:05.999999999"),
+	"time":               xsdTimeLayouts("15:04:05", "15:04:05.999999999"),
+	"duration": func(v string) error {
+		if !xsdDurationRegexp.MatchString(v) || strings.HasSuffix(v, "P") || strings.HasSuffix(v, "T") {
+			return errors.New("not a duration")
+		}
+		return nil
+	},
+	"base64Binary": func(v string) error {
+		if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(v), "")); err != nil {
+			return errors.New("not base64 encoded")
+		}
+		return nil
+	},
+	"hexBinary": func(v string) error {
+		if _, err := hex.DecodeString(v); err != nil {
+			return errors.New("not hex encoded")
+		}
+		return nil
+	},
+}
+
+func xsdFloat(v string) error {
+	switch v {
+	case "INF", "-INF", "NaN":
+		return nil
+	}
+	if !xsdFloatRegexp.MatchString(v) {
+		return errors.New("not a number")
+	}
+	return nil
+}
+
+// primitive returns the built in type that a simple type is derived from.
+func (st *xsdSimpleType) primitive() string {
+	for t := st; t != nil; t = t.base {
+		if t.builtin != "" {
+			return t.builtin
+		}
+	}
+	return ""
+}
+
+func (st *xsdSimpleType) validate(v string) error {
+	prim := st.primitive()
+	switch prim {
+	case "string", "anySimpleType", "":
+	default:
+		v = strings.TrimSpace(v)
+	}
+
+	if st.builtin != "" {
+		if check := xsdBuiltins[st.builtin]; check != nil {
+			if err := check(v); err != nil {
+				return fmt.Errorf("value '%v' is not a valid %v: %v", v, st.builtin, err)
+			}
+		}
+		return nil
+	}
+
+	if len(st.union) > 0 {
+		for _, member := range st.union {
+			if member.validate(v) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("value '%v' does not match any member of the union", v)
+	}
+
+	length := len([]rune(v))
+	if st.list != nil {
+		items := strings.Fields(v)
+		for _, item := range items {
+			if err := st.list.validate(item); err != nil {
+				return err
+			}
+		}
+		length = len(items)
+	}
+
+	if st.base != nil {
+		if err := st.base.validate(v); err != nil {
+			return err
+		}
+	}
+
+	if len(st.enums) > 0 {
+		found := false
+		for _, e := range st.enums {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value '%v' is not one of the allowed values %v", v, st.enums)
+		}
+	}
+	for _, re := range st.patterns {
+		if !re.MatchString(v) {
+			return fmt.Errorf("value '%v' does not match pattern %v", v, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"))
+		}
+	}
+	if st.length != nil && length != *st.length {
+		return fmt.Errorf("value '%v' must have a length of %v", v, *st.length)
+	}
+	if st.minLength != nil && length < *st.minLength {
+		return fmt.Errorf("value '%v' must have a length of at least %v", v, *st.minLength)
+	}
+	if st.maxLength != nil && length > *st.maxLength {
+		return fmt.Errorf("value '%v' must have a length of at most %v", v, *st.maxLength)
+	}
+	if err := st.validateDigits(v); err != nil {
+		return err
+	}
+	return st.validateRange(prim, v)
+}
+
+func (st *xsdSimpleType) validateDigits(v string) error {
+	if st.totalDigits == nil && st.fractionDigits == nil {
+		return nil
+	}
+	digits := strings.TrimLeft(v, "+-")
+	integer, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integer, fraction = digits[:i], digits[i+1:]
+	}
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if st.fractionDigits != nil && len(fraction) > *st.fractionDigits {
+		return fmt.Errorf("value '%v' must have at most %v fraction digits", v, *st.fractionDigits)
+	}
+	if st.totalDigits != nil && len(integer)+len(fraction) > *st.totalDigits {
+		return fmt.Errorf("value '%v' must have at most %v digits", v, *st.totalDigits)
+	}
+	return nil
+}
+
+func (st *xsdSimpleType) validateRange(prim, v string) error {
+	if st.minInclusive == nil && st.maxInclusive == nil &&
+		st.minExclusive == nil && st.maxExclusive == nil {
+		return nil
+	}
+
+	var compare func(a, b string) (int, bool)
+	switch prim {
+	case "date", "dateTime", "time":
+		check := xsdBuiltins[prim]
+		compare = func(a, b string) (int, bool) {
+			if check(a) != nil || check(b) != nil {
+				return 0, false
+			}
+			return strings.Compare(a, b), true
+		}
+	default:
+		compare = func(a, b string) (int, bool) {
+			ra, okA := new(big.Rat).SetString(strings.TrimPrefix(a, "+"))
+			rb, okB := new(big.Rat).SetString(strings.TrimPrefix(b, "+"))
+			if !okA || !okB {
+				return 0, false
+			}
+			return ra.Cmp(rb), true
+		}
+	}
+
+	for _, bound := range []struct {
+		limit *string
+		fails func(int) bool
+		desc  string
+	}{
+		{st.minInclusive, func(c int) bool { return c < 0 }, "greater than or equal to"},
+		{st.maxInclusive, func(c int) bool { return c > 0 }, "less than or equal to"},
+		{st.minExclusive, func(c int) bool { return c <= 0 }, "greater than"},
+		{st.maxExclusive, func(c int) bool { return c >= 0 }, "less than"},
+	} {
+		if bound.limit == nil {
+			continue
+		}
+		c, ok := compare(v, *bound.limit)
+		if !ok {
+			return fmt.Errorf("value '%v' cannot be compared with %v", v, *bound.limit)
+		}
+		if bound.fails(c) {
+			return fmt.Errorf("value '%v' must be %v %v", v, bound.desc, *bound.limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package xml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testXSD = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="payment" type="Payment"/>
+
+  <xs:complexType name="Payment">
+    <xs:sequence>
+      <xs:element name="id" type="xs:string"/>
+      <xs:element name="amount" type="Amount"/>
+      <xs:choice>
+        <xs:element name="iban" type="xs:string"/>
+        <xs:element name="account" type="xs:string"/>
+      </xs:choice>
+      <xs:element name="date" type="xs:date"/>
+      <xs:element name="note" type="xs:string" minOccurs="0" maxOccurs="3"/>
+      <xs:element name="party" type="Party" minOccurs="0" maxOccurs="unbounded"/>
+      <xs:any minOccurs="0" processContents="lax"/>
+    </xs:sequence>
+    <xs:attribute name="priority" type="Priority" use="required"/>
+  </xs:complexType>
+
+  <xs:complexType name="Amount">
+    <xs:simpleContent>
+      <xs:extension base="Money">
+        <xs:attribute name="currency" use="required">
+          <xs:simpleType>
+            <xs:restriction base="xs:string">
+              <xs:pattern value="[A-Z]{3}"/>
+            </xs:restriction>
+          </xs:simpleType>
+        </xs:attribute>
+      </xs:extension>
+    </xs:simpleContent>
+  </xs:complexType>
+
+  <xs:complexType name="Party">
+    <xs:all>
+      <xs:element name="name" type="xs:string"/>
+      <xs:element name="country" type="xs:string" minOccurs="0"/>
+      <xs:element name="party" type="Party" minOccurs="0"/>
+    </xs:all>
+  </xs:complexType>
+
+  <xs:simpleType name="Money">
+    <xs:restriction base="xs:decimal">
+      <xs:minExclusive value="0"/>
+      <xs:maxInclusive value="1000000"/>
+      <xs:fractionDigits value="2"/>
+    </xs:restriction>
+  </xs:simpleType>
+
+  <xs:simpleType name="Priority">
+    <xs:restriction base="xs:string">
+      <xs:enumeration value="HIGH"/>
+      <xs:enumeration value="NORM"/>
+    </xs:restriction>
+  </xs:simpleType>
+</xs:schema>`
+
+func TestXSDValidate(t *testing.T) {
+	schema, err := CompileSchema([]byte(testXSD))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{
+			name: "valid minimal",
+			doc:  `<payment priority="HIGH"><id>a</id><amount currency="EUR">10.50</amount><iban>X</iban><date>2021-03-04</date></payment>`,
+		},
+		{
+			name: "valid full",
+			doc: `<payment priority="NORM" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <id>a</id>
+  <amount currency="USD">999</amount>
+  <account>123</account>
+  <date>2021-03-04Z</date>
+  <note>one</note>
+  <note>two</note>
+  <party><country>GB</country><name>foo</name><party><name>bar</name></party></party>
+  <party><name>baz</name></party>
+  <extra><anything/></extra>
+</payment>`,
+		},
+		{
+			name: "bad enum and attribute",
+			doc:  `<payment priority="LOW" other="x"><id>a</id><amount currency="EUR">1</amount><iban>X</iban><date>2021-03-04</date></payment>`,
+			err:  "/payment: attribute priority: value 'LOW' is not one of the allowed values [HIGH NORM]; /payment: attribute other is not allowed",
+		},
+		{
+			name: "bad simple content",
+			doc:  `<payment priority="HIGH"><id>a</id><amount currency="eur">1.234</amount><iban>X</iban><date>2021-13-04</date></payment>`,
+			err:  "/payment/amount: attribute currency: value 'eur' does not match pattern [A-Z]{3}; /payment/amount: value '1.234' must have at most 2 fraction digits; /payment/date: value '2021-13-04' is not a valid date: invalid format",
+		},
+		{
+			name: "out of range",
+			doc:  `<payment priority="HIGH"><id>a</id><amount currency="EUR">0</amount><iban>X</iban><date>2021-03-04</date></payment>`,
+			err:  "/payment/amount: value '0' must be greater than 0",
+		},
+		{
+			name: "missing choice",
+			doc:  `<payment priority="HIGH"><id>a</id><amount currency="EUR">1</amount><date>2021-03-04</date></payment>`,
+			err:  "/payment: element date is not expected",
+		},
+		{
+			name: "too many notes",
+			doc:  `<payment priority="HIGH"><id>a</id><amount currency="EUR">1</amount><iban>X</iban><date>2021-03-04</date><note/><note/><note/><note/><note/></payment>`,
+			err:  "/payment: element note is not expected",
+		},
+		{
+			name: "incomplete",
+			doc:  `<payment priority="HIGH"><id>a</id></payment>`,
+			err:  "/payment: element content is incomplete",
+		},
+		{
+			name: "nested all group",
+			doc:  `<payment priority="HIGH"><id>a</id><amount currency="EUR">1</amount><iban>X</iban><date>2021-03-04</date><party><country>GB</country></party></payment>`,
+			err:  "/payment/party: element content is incomplete",
+		},
+		{
+			name: "undeclared root",
+			doc:  `<refund/>`,
+			err:  "/refund: element is not declared by the schema",
+		},
+	}
+
+	for _, test := range tests {
+		doc, err := Parse([]byte(test.doc))
+		require.NoError(t, err, test.name)
+
+		err = schema.Validate(doc)
+		if test.err == "" {
+			assert.NoError(t, err, test.name)
+		} else {
+			require.Error(t, err, test.name)
+			assert.Equal(t, test.err, err.Error(), test.name)
+		}
+	}
+}
+
+func TestXSDBuiltins(t *testing.T) {
+	tests := map[string]struct {
+		valid   []string
+		invalid []string
+	}{
+		"xs:int":             {valid: []string{"0", "-5", "+2147483647"}, invalid: []string{"2147483648", "1.0", "a"}},
+		"xs:unsignedByte":    {valid: []string{"255"}, invalid: []string{"256", "-1"}},
+		"xs:boolean":         {valid: []string{"true", "0"}, invalid: []string{"yes"}},
+		"xs:decimal":         {valid: []string{"1.5", "-.5", "10."}, invalid: []string{"1e5", ""}},
+		"xs:double":          {valid: []string{"1e5", "INF", "NaN"}, invalid: []string{"inf"}},
+		"xs:dateTime":        {valid: []string{"2021-03-04T10:11:12", "2021-03-04T10:11:12.5+01:00"}, invalid: []string{"2021-03-04"}},
+		"xs:duration":        {valid: []string{"P1Y2M", "PT1.5S", "-P3D"}, invalid: []string{"P", "PT", "1D"}},
+		"xs:hexBinary":       {valid: []string{"0fA1"}, invalid: []string{"0fA"}},
+		"xs:positiveInteger": {valid: []string{"1"}, invalid: []string{"0"}},
+	}
+
+	for typ, test := range tests {
+		schema, err := CompileSchema([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="v" type="` + typ + `"/>
+</xs:schema>`))
+		require.NoError(t, err, typ)
+
+		for _, v := range test.valid {
+			doc, err := Parse([]byte("<v>" + v + "</v>"))
+			require.NoError(t, err)
+			assert.NoError(t, schema.Validate(doc), "%v: %v", typ, v)
+		}
+		for _, v := range test.invalid {
+			doc, err := Parse([]byte("<v>" + v + "</v>"))
+			require.NoError(t, err)
+			assert.Error(t, schema.Validate(doc), "%v: %v", typ, v)
+		}
+	}
+}
+
+func TestXSDCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		`<root/>`: "expected root element schema, got root",
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:import namespace="foo"/></xs:schema>`:                                                                      "schema import is not supported",
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="Nope"/></xs:schema>`:                                                                "element a: type Nope not recognised",
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="xs:nope"/></xs:schema>`:                                                             "element a: type xs:nope not recognised",
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:complexType name="A"/></xs:schema>`:                                                                        "schema does not declare any global elements",
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a"><xs:simpleType/></xs:element></xs:schema>`:                                                "element a: simple type must contain a restriction, list or union",
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="a" type="A"/><xs:simpleType name="A"><xs:restriction base="A"/></xs:simpleType></xs:schema>`: "type A: base type A: circular type derivation",
+	}
+
+	for schema, exp := range tests {
+		_, err := CompileSchema([]byte(schema))
+		require.Error(t, err, schema)
+		assert.Equal(t, exp, err.Error(), schema)
+	}
+}
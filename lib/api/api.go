@@ -14,6 +14,8 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	httputil "github.com/Jeffail/benthos/v3/lib/util/http"
+	httpserver "github.com/Jeffail/benthos/v3/lib/util/http/server"
 	"github.com/gorilla/mux"
 	yaml "gopkg.in/yaml.v3"
 )
@@ -22,13 +24,18 @@ import (
 
 // Config contains the configuration fields for the Benthos API.
 type Config struct {
-	Address        string `json:"address" yaml:"address"`
-	Enabled        bool   `json:"enabled" yaml:"enabled"`
-	ReadTimeout    string `json:"read_timeout" yaml:"read_timeout"`
-	RootPath       string `json:"root_path" yaml:"root_path"`
-	DebugEndpoints bool   `json:"debug_endpoints" yaml:"debug_endpoints"`
-	CertFile       string `json:"cert_file" yaml:"cert_file"`
-	KeyFile        string `json:"key_file" yaml:"key_file"`
+	Address           string                `json:"address" yaml:"address"`
+	Enabled           bool                  `json:"enabled" yaml:"enabled"`
+	ReadTimeout       string                `json:"read_timeout" yaml:"read_timeout"`
+	RootPath          string                `json:"root_path" yaml:"root_path"`
+	DebugEndpoints    bool                  `json:"debug_endpoints" yaml:"debug_endpoints"`
+	CertFile          string                `json:"cert_file" yaml:"cert_file"`
+	KeyFile           string                `json:"key_file" yaml:"key_file"`
+	Auth              httpserver.AuthConfig `json:"auth" yaml:"auth"`
+	CORS              httpserver.CORSConfig `json:"cors" yaml:"cors"`
+	CompressResponses bool                  `json:"compress_responses" yaml:"compress_responses"`
+	MaxBodySize       int64                 `json:"max_body_size" yaml:"max_body_size"`
+	RequestTimeout    string                `json:"request_timeout" yaml:"request_timeout"`
 }
 
 // NewConfig creates a new API config with default values.
@@ -41,6 +48,12 @@ func NewConfig() Config {
 		DebugEndpoints: false,
 		CertFile:       "",
 		KeyFile:        "",
+		Auth:           httpserver.NewAuthConfig(),
+
+		CORS:              httpserver.NewCORSConfig(),
+		CompressResponses: false,
+		MaxBodySize:       0,
+		RequestTimeout:    "",
 	}
 }
 
@@ -77,6 +90,8 @@ type Type struct {
 	handlers    map[string]http.HandlerFunc
 	handlersMut sync.RWMutex
 
+	requestTimeout time.Duration
+
 	mux    *mux.Router
 	server *http.Server
 }
@@ -110,12 +125,37 @@ func New(
 			return nil, fmt.Errorf("failed to parse read timeout string: %v", err)
 		}
 	}
+	authenticator, err := httpserver.NewAuthenticator(conf.Auth, log, metrics.Namespaced(stats, "http"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticator: %v", err)
+	}
+	if authenticator.MTLSEnabled() {
+		if conf.CertFile == "" {
+			return nil, errors.New("a cert_file and key_file must be specified in order to use mtls authentication")
+		}
+		server.TLSConfig = authenticator.TLSConfig()
+	}
+	var requestTimeout time.Duration
+	if tout := conf.RequestTimeout; len(tout) > 0 {
+		if requestTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse request timeout string: %v", err)
+		}
+	}
+
+	rootHandler := httpserver.MaxBodySizeHandler(conf.MaxBodySize, handler.ServeHTTP)
+	if conf.CompressResponses {
+		rootHandler = httputil.GzipHandler(rootHandler)
+	}
+	rootHandler = authenticator.WrapHandler(rootHandler)
+	server.Handler = httpserver.CORSHandler(conf.CORS, rootHandler)
+
 	t := &Type{
-		conf:      conf,
-		endpoints: map[string]string{},
-		handlers:  map[string]http.HandlerFunc{},
-		mux:       handler,
-		server:    server,
+		conf:           conf,
+		endpoints:      map[string]string{},
+		handlers:       map[string]http.HandlerFunc{},
+		requestTimeout: requestTimeout,
+		mux:            handler,
+		server:         server,
 	}
 	t.ctx, t.cancel = context.WithCancel(context.Background())
 
@@ -186,9 +226,11 @@ func New(
 			"/debug/stack", "DEBUG: Returns a snapshot of the current service stack trace.",
 			handleStackTrace,
 		)
-		t.RegisterEndpoint(
+		// The profile and trace endpoints block for a duration specified by
+		// the request and are therefore exempt from the request timeout.
+		t.registerEndpoint(
 			"/debug/pprof/profile", "DEBUG: Responds with a pprof-formatted cpu profile.",
-			pprof.Profile,
+			pprof.Profile, false,
 		)
 		t.RegisterEndpoint(
 			"/debug/pprof/heap", "DEBUG: Responds with a pprof-formatted heap profile.",
@@ -208,12 +250,12 @@ func New(
 				" counters to function names.",
 			pprof.Symbol,
 		)
-		t.RegisterEndpoint(
+		t.registerEndpoint(
 			"/debug/pprof/trace",
 			"DEBUG: Responds with the execution trace in binary form."+
 				" Tracing lasts for duration specified in seconds GET"+
 				" parameter, or for 1 second if not specified.",
-			pprof.Trace,
+			pprof.Trace, false,
 		)
 	}
 
@@ -243,6 +285,14 @@ func New(
 // RegisterEndpoint registers a http.HandlerFunc under a path with a
 // description that will be displayed under the /endpoints path.
 func (t *Type) RegisterEndpoint(path, desc string, handler http.HandlerFunc) {
+	t.registerEndpoint(path, desc, handler, true)
+}
+
+func (t *Type) registerEndpoint(path, desc string, handler http.HandlerFunc, withTimeout bool) {
+	if withTimeout {
+		handler = httpserver.TimeoutHandler(t.requestTimeout, handler)
+	}
+
 	t.endpointsMut.Lock()
 	defer t.endpointsMut.Unlock()
 
@@ -270,12 +320,12 @@ func (t *Type) ListenAndServe() error {
 		<-t.ctx.Done()
 		return nil
 	}
-	if t.server.TLSConfig != nil {
-		return t.server.ListenAndServeTLS("", "")
-	}
 	if len(t.conf.CertFile) > 0 {
 		return t.server.ListenAndServeTLS(t.conf.CertFile, t.conf.KeyFile)
 	}
+	if t.server.TLSConfig != nil {
+		return t.server.ListenAndServeTLS("", "")
+	}
 	return t.server.ListenAndServe()
 }
 
@@ -1,6 +1,9 @@
 package api
 
-import "github.com/Jeffail/benthos/v3/internal/docs"
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	httpserver "github.com/Jeffail/benthos/v3/lib/util/http/server"
+)
 
 // Spec returns a field spec for the API configuration fields.
 func Spec() docs.FieldSpecs {
@@ -11,6 +14,11 @@ func Spec() docs.FieldSpecs {
 		docs.FieldAdvanced("debug_endpoints", "Whether to register a few extra endpoints that can be useful for debugging performance or behavioral problems."),
 		docs.FieldAdvanced("cert_file", "An optional certificate file for enabling TLS."),
 		docs.FieldAdvanced("key_file", "An optional key file for enabling TLS."),
+		httpserver.AuthFieldSpec().AtVersion("3.47.0"),
+		httpserver.CORSFieldSpec().AtVersion("3.47.0"),
+		docs.FieldAdvanced("compress_responses", "Whether to gzip compress responses for requests that accept it.").AtVersion("3.47.0"),
+		docs.FieldAdvanced("max_body_size", "The maximum size in bytes of request bodies, requests with larger bodies are rejected. Set to zero in order to disable the limit.").AtVersion("3.47.0"),
+		docs.FieldAdvanced("request_timeout", "An optional timeout for requests that have not begun a response, after which a 503 status code is returned. This applies to all endpoints including those registered by components, with the exception of the `/debug/pprof/profile` and `/debug/pprof/trace` endpoints and websocket connections. Streamed responses are not interrupted once they have begun.", "5s").AtVersion("3.47.0"),
 		docs.FieldDeprecated("read_timeout"),
 	}
 }
@@ -60,25 +60,30 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each buffer type.
 const (
-	TypeMemory = "memory"
-	TypeNone   = "none"
+	TypeMemory   = "memory"
+	TypeNone     = "none"
+	TypePriority = "priority"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all buffer types.
 type Config struct {
-	Type   string       `json:"type" yaml:"type"`
-	Memory MemoryConfig `json:"memory" yaml:"memory"`
-	None   struct{}     `json:"none" yaml:"none"`
+	Label    string         `json:"label" yaml:"label"`
+	Type     string         `json:"type" yaml:"type"`
+	Memory   MemoryConfig   `json:"memory" yaml:"memory"`
+	None     struct{}       `json:"none" yaml:"none"`
+	Priority PriorityConfig `json:"priority" yaml:"priority"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:   "none",
-		Memory: NewMemoryConfig(),
-		None:   struct{}{},
+		Label:    "",
+		Type:     "none",
+		Memory:   NewMemoryConfig(),
+		None:     struct{}{},
+		Priority: NewPriorityConfig(),
 	}
 }
 
@@ -161,12 +166,14 @@ different options and their qualities:
 | Type      | Throughput | Consumers | Capacity |
 | --------- | ---------- | --------- | -------- |
 | Memory    | Highest    | Parallel  | RAM      |
+| Priority  | High       | Parallel  | RAM      |
 
 #### Delivery Guarantees
 
 | Event     | Shutdown  | Crash     | Disk Corruption |
 | --------- | --------- | --------- | --------------- |
 | Memory    | Flushed\* | Lost      | Lost            |
+| Priority  | Flushed\* | Lost      | Lost            |
 
 \* Makes a best attempt at flushing the remaining messages before closing
   gracefully.`
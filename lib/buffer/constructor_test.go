@@ -73,6 +73,7 @@ func TestSanitise(t *testing.T) {
 
 	exp := `{` +
 		`"type":"none",` +
+		`"label":"",` +
 		`"none":{}` +
 		`}`
 
@@ -92,9 +93,11 @@ func TestSanitise(t *testing.T) {
 
 	exp = `{` +
 		`"type":"memory",` +
+		`"label":"",` +
 		`"memory":{` +
 		`"batch_policy":{"byte_size":0,"check":"","count":0,"enabled":false,"period":"","processors":[]},` +
-		`"limit":20` +
+		`"limit":20,` +
+		`"overflow":{"spill_directory":"","strategy":"backpressure"}` +
 		`}` +
 		`}`
 
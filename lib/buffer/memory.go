@@ -24,18 +24,42 @@ messages before exiting cleanly.`,
 This buffer is appropriate when consuming messages from inputs that do not
 gracefully handle back pressure and where delivery guarantees aren't critical.
 
-This buffer has a configurable limit, where consumption will be stopped with
-back pressure upstream if the total size of messages in the buffer reaches this
-amount. Since this calculation is only an estimate, and the real size of
-messages in RAM is always higher, it is recommended to set the limit
-significantly below the amount of RAM available.
+This buffer has a configurable limit, which acts as a memory budget for the
+stream. By default consumption will be stopped with back pressure upstream if
+the total size of messages in the buffer reaches this amount. Since this
+calculation is only an estimate, and the real size of messages in RAM is always
+higher, it is recommended to set the limit significantly below the amount of
+RAM available.
+
+### Overflow
+
+The behaviour when the limit is reached can be changed with the field
+` + "`overflow.strategy`" + `. The ` + "`drop_oldest`" + ` strategy removes the oldest
+messages from the buffer in order to make room for new ones, which keeps
+consumption flowing at the cost of losing data. The ` + "`spill`" + ` strategy
+writes messages that do not fit in memory into a file within the directory
+` + "`overflow.spill_directory`" + `, and reads them back into memory in order once
+there is room. Spilled messages are not persisted across restarts and any that
+remain when Benthos shuts down are lost.
+
+The number of messages dropped and spilled are tracked with the metrics
+` + "`overflow.dropped` and `overflow.spilled`" + `, and the size in bytes of
+messages currently spilled to disk with the gauge ` + "`spill.backlog`" + `.
 
 ### Batching
 
 It is possible to batch up messages sent from this buffer using a
 [batch policy](/docs/configuration/batching#batch-policy).`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("limit", "The maximum buffer size (in bytes) to allow before applying backpressure upstream."),
+			docs.FieldCommon("limit", "The maximum buffer size (in bytes) to allow before applying the overflow strategy."),
+			docs.FieldAdvanced("overflow", "Describes what happens when the limit of the buffer is reached.").WithChildren(
+				docs.FieldCommon("strategy", "The strategy applied when adding a message would exceed the limit.").HasAnnotatedOptions(
+					"backpressure", "Stop consuming messages until there is room in the buffer.",
+					"drop_oldest", "Remove the oldest messages from the buffer until there is room.",
+					"spill", "Write messages to a file on disk until there is room in the buffer.",
+				),
+				docs.FieldCommon("spill_directory", "The directory to write spilled messages to when the `spill` strategy is used, when empty the default temporary directory of the system is used."),
+			).AtVersion("3.47.0"),
 			docs.FieldCommon("batch_policy", "Optionally configure a policy to flush buffered messages in batches.").WithChildren(
 				append(docs.FieldSpecs{
 					docs.FieldCommon("enabled", "Whether to batch messages as they are flushed."),
@@ -53,9 +77,17 @@ type EnabledBatchPolicyConfig struct {
 	batch.PolicyConfig `json:",inline" yaml:",inline"`
 }
 
+// MemoryOverflowConfig describes the strategy applied by a memory buffer when
+// its limit is reached.
+type MemoryOverflowConfig struct {
+	Strategy       string `json:"strategy" yaml:"strategy"`
+	SpillDirectory string `json:"spill_directory" yaml:"spill_directory"`
+}
+
 // MemoryConfig is config values for a purely memory based ring buffer type.
 type MemoryConfig struct {
 	Limit       int                      `json:"limit" yaml:"limit"`
+	Overflow    MemoryOverflowConfig     `json:"overflow" yaml:"overflow"`
 	BatchPolicy EnabledBatchPolicyConfig `json:"batch_policy" yaml:"batch_policy"`
 }
 
@@ -63,6 +95,10 @@ type MemoryConfig struct {
 func NewMemoryConfig() MemoryConfig {
 	return MemoryConfig{
 		Limit: 1024 * 1024 * 500, // 500MB
+		Overflow: MemoryOverflowConfig{
+			Strategy:       parallel.OverflowBackpressure,
+			SpillDirectory: "",
+		},
 		BatchPolicy: EnabledBatchPolicyConfig{
 			Enabled:      false,
 			PolicyConfig: batch.NewPolicyConfig(),
@@ -74,7 +110,16 @@ func NewMemoryConfig() MemoryConfig {
 
 // NewMemory creates a buffer held in memory.
 func NewMemory(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	wrap := NewParallelWrapper(config, parallel.NewMemory(config.Memory.Limit), log, stats)
+	mem, err := parallel.NewMemoryWithOverflow(
+		config.Memory.Limit,
+		config.Memory.Overflow.Strategy,
+		config.Memory.Overflow.SpillDirectory,
+		stats,
+	)
+	if err != nil {
+		return nil, err
+	}
+	wrap := NewParallelWrapper(config, mem, log, stats)
 	if !config.Memory.BatchPolicy.Enabled {
 		return wrap, nil
 	}
@@ -1,13 +1,25 @@
 package parallel
 
 import (
+	"fmt"
 	"sync"
 
+	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
 //------------------------------------------------------------------------------
 
+// Strategies applied by a memory buffer when pushing a message would exceed its
+// capacity.
+const (
+	OverflowBackpressure = "backpressure"
+	OverflowDropOldest   = "drop_oldest"
+	OverflowSpill        = "spill"
+)
+
+//------------------------------------------------------------------------------
+
 // Memory is a parallel buffer implementation that allows multiple parallel
 // consumers to read and purge messages from the buffer asynchronously.
 type Memory struct {
@@ -18,16 +30,83 @@ type Memory struct {
 	cap  int
 	cond *sync.Cond
 
+	dropOldest bool
+	spill      *spillQueue
+
+	mDropped      metrics.StatCounter
+	mSpilled      metrics.StatCounter
+	mSpillBacklog metrics.StatGauge
+
 	closed bool
 }
 
 // NewMemory creates a memory based parallel buffer.
 func NewMemory(capacity int) *Memory {
-	return &Memory{
+	m, _ := NewMemoryWithOverflow(capacity, OverflowBackpressure, "", metrics.Noop())
+	return m
+}
+
+// NewMemoryWithOverflow creates a memory based parallel buffer that applies an
+// overflow strategy when pushing a message would exceed its capacity. Messages
+// are either dropped from the front of the buffer, or spilled into a file
+// within a directory (or the default temporary directory when empty) until
+// there is capacity to read them back into memory.
+func NewMemoryWithOverflow(capacity int, strategy, spillDir string, stats metrics.Type) (*Memory, error) {
+	m := &Memory{
 		bytes: 0,
 		cap:   capacity,
 		cond:  sync.NewCond(&sync.Mutex{}),
+
+		mDropped:      stats.GetCounter("overflow.dropped"),
+		mSpilled:      stats.GetCounter("overflow.spilled"),
+		mSpillBacklog: stats.GetGauge("spill.backlog"),
+	}
+	switch strategy {
+	case "", OverflowBackpressure:
+	case OverflowDropOldest:
+		m.dropOldest = true
+	case OverflowSpill:
+		var err error
+		if m.spill, err = newSpillQueue(spillDir); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("overflow strategy '%v' not recognised", strategy)
+	}
+	return m, nil
+}
+
+func messageSize(msg types.Message) int {
+	size := 0
+	_ = msg.Iter(func(i int, b types.Part) error {
+		size += len(b.Get())
+		return nil
+	})
+	return size
+}
+
+func (m *Memory) spilled() int {
+	if m.spill == nil {
+		return 0
+	}
+	return m.spill.len()
+}
+
+// promote reads the next spilled message back into memory if there is capacity
+// for it, and returns true if a message was promoted. Must be called with the
+// lock held.
+func (m *Memory) promote() (bool, error) {
+	if m.spilled() == 0 || (m.bytes > 0 && m.bytes+m.spill.peekSize() > m.cap) {
+		return false, nil
+	}
+	msg, size, err := m.spill.pop()
+	if err != nil {
+		return false, err
 	}
+	m.messages = append(m.messages, msg)
+	m.bytes += size
+	m.mSpillBacklog.Set(int64(m.spill.bytes))
+	return true, nil
 }
 
 //------------------------------------------------------------------------------
@@ -37,7 +116,14 @@ func NewMemory(capacity int) *Memory {
 func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 	m.cond.L.Lock()
 	for len(m.messages) == 0 && !m.closed {
-		m.cond.Wait()
+		promoted, err := m.promote()
+		if err != nil {
+			m.cond.L.Unlock()
+			return nil, nil, err
+		}
+		if !promoted {
+			m.cond.Wait()
+		}
 	}
 
 	if m.closed {
@@ -50,11 +136,7 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 	m.messages[0] = nil
 	m.messages = m.messages[1:]
 
-	messageSize := 0
-	msg.Iter(func(i int, b types.Part) error {
-		messageSize += len(b.Get())
-		return nil
-	})
+	messageSize := messageSize(msg)
 	m.pendingBytes += messageSize
 
 	m.cond.Broadcast()
@@ -75,6 +157,9 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 		m.cond.Broadcast()
 
 		backlog := m.bytes
+		if m.spill != nil {
+			backlog += m.spill.bytes
+		}
 		m.cond.L.Unlock()
 
 		return backlog, nil
@@ -83,11 +168,7 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 
 // PushMessage adds a new message to the stack. Returns the backlog in bytes.
 func (m *Memory) PushMessage(msg types.Message) (int, error) {
-	extraBytes := 0
-	msg.Iter(func(i int, b types.Part) error {
-		extraBytes += len(b.Get())
-		return nil
-	})
+	extraBytes := messageSize(msg)
 
 	if extraBytes > m.cap {
 		return 0, types.ErrMessageTooLarge
@@ -100,7 +181,31 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		return 0, types.ErrTypeClosed
 	}
 
+	// Once messages have been spilled all subsequent messages are also spilled
+	// until the spill is emptied in order to preserve ordering.
+	if m.spill != nil && (m.spill.len() > 0 || (m.bytes+extraBytes) > m.cap) {
+		if err := m.spill.push(msg, extraBytes); err != nil {
+			m.cond.L.Unlock()
+			return 0, err
+		}
+		m.mSpilled.Incr(1)
+		m.mSpillBacklog.Set(int64(m.spill.bytes))
+
+		backlog := m.bytes + m.spill.bytes
+
+		m.cond.Broadcast()
+		m.cond.L.Unlock()
+		return backlog, nil
+	}
+
 	for (m.bytes + extraBytes) > m.cap {
+		if m.dropOldest && len(m.messages) > 0 {
+			m.bytes -= messageSize(m.messages[0])
+			m.messages[0] = nil
+			m.messages = m.messages[1:]
+			m.mDropped.Incr(1)
+			continue
+		}
 		m.cond.Wait()
 		if m.closed {
 			m.cond.L.Unlock()
@@ -130,11 +235,14 @@ func (m *Memory) CloseOnceEmpty() {
 	// don't count them then we don't have any way to signal to a batcher at the
 	// upper level that it should flush the final batch. We need a cleaner
 	// mechanism here.
-	for (m.bytes-m.pendingBytes > 0) && !m.closed {
+	for (m.bytes-m.pendingBytes > 0 || m.spilled() > 0) && !m.closed {
 		m.cond.Wait()
 	}
 	if !m.closed {
 		m.closed = true
+		if m.spill != nil {
+			m.spill.close()
+		}
 		m.cond.Broadcast()
 	}
 	m.cond.L.Unlock()
@@ -144,6 +252,9 @@ func (m *Memory) CloseOnceEmpty() {
 // unblocked.
 func (m *Memory) Close() {
 	m.cond.L.Lock()
+	if !m.closed && m.spill != nil {
+		m.spill.close()
+	}
 	m.closed = true
 	m.cond.Broadcast()
 	m.cond.L.Unlock()
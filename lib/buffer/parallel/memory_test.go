@@ -2,13 +2,18 @@ package parallel
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMemoryBasic(t *testing.T) {
@@ -322,3 +327,67 @@ func TestMemoryRejectLargeMessage(t *testing.T) {
 		t.Errorf("Unexpected error: %v != %v", exp, actual)
 	}
 }
+
+func TestMemoryOverflowDropOldest(t *testing.T) {
+	block, err := NewMemoryWithOverflow(10, OverflowDropOldest, "", metrics.Noop())
+	require.NoError(t, err)
+
+	for _, v := range []string{"aaaa", "bbbb", "cccc", "dddd"} {
+		_, err := block.PushMessage(message.New([][]byte{[]byte(v)}))
+		require.NoError(t, err)
+	}
+
+	for _, exp := range []string{"cccc", "dddd"} {
+		m, ackFunc, err := block.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(m.Get(0).Get()))
+		_, err = ackFunc(true)
+		require.NoError(t, err)
+	}
+	block.Close()
+}
+
+func TestMemoryOverflowSpill(t *testing.T) {
+	dir := t.TempDir()
+
+	block, err := NewMemoryWithOverflow(10, OverflowSpill, dir, metrics.Noop())
+	require.NoError(t, err)
+
+	for i, v := range []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"} {
+		msg := message.New([][]byte{[]byte(v)})
+		msg.Get(0).Metadata().Set("index", strconv.Itoa(i))
+		backlog, err := block.PushMessage(msg)
+		require.NoError(t, err)
+		assert.Equal(t, (i+1)*4, backlog)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Greater(t, files[0].Size(), int64(0))
+
+	for i, exp := range []string{"aaaa", "bbbb", "cccc", "dddd", "eeee"} {
+		m, ackFunc, err := block.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(m.Get(0).Get()))
+		assert.Equal(t, strconv.Itoa(i), m.Get(0).Metadata().Get("index"))
+		_, err = ackFunc(true)
+		require.NoError(t, err)
+	}
+
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(0), files[0].Size())
+
+	block.Close()
+
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestMemoryOverflowBadStrategy(t *testing.T) {
+	_, err := NewMemoryWithOverflow(10, "nope", "", metrics.Noop())
+	require.EqualError(t, err, "overflow strategy 'nope' not recognised")
+}
@@ -0,0 +1,195 @@
+package parallel
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type priorityItem struct {
+	msg      types.Message
+	priority int64
+	seq      uint64
+}
+
+// priorityQueue is a heap of messages ordered by highest priority first, and
+// for messages of equal priority in the order they were pushed.
+type priorityQueue []*priorityItem
+
+func (q priorityQueue) Len() int { return len(q) }
+
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q priorityQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *priorityQueue) Push(x interface{}) {
+	*q = append(*q, x.(*priorityItem))
+}
+
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+//------------------------------------------------------------------------------
+
+// Priority is a parallel buffer implementation held in memory where messages
+// are read in order of their priority, and messages of the same priority are
+// read in the order they were pushed.
+type Priority struct {
+	queue        priorityQueue
+	seq          uint64
+	bytes        int
+	pendingBytes int
+
+	priorityFn func(types.Message) int64
+
+	cap  int
+	cond *sync.Cond
+
+	closed bool
+}
+
+// NewPriority creates a memory based parallel buffer where the priority of each
+// message is determined by a function, and higher values are read first.
+func NewPriority(capacity int, priorityFn func(types.Message) int64) *Priority {
+	return &Priority{
+		bytes:      0,
+		priorityFn: priorityFn,
+		cap:        capacity,
+		cond:       sync.NewCond(&sync.Mutex{}),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NextMessage reads the message with the highest priority, the message is
+// preserved until the returned AckFunc is called.
+func (m *Priority) NextMessage() (types.Message, AckFunc, error) {
+	m.cond.L.Lock()
+	for len(m.queue) == 0 && !m.closed {
+		m.cond.Wait()
+	}
+
+	if m.closed {
+		m.cond.L.Unlock()
+		return nil, nil, types.ErrTypeClosed
+	}
+
+	item := heap.Pop(&m.queue).(*priorityItem)
+
+	messageSize := 0
+	item.msg.Iter(func(i int, b types.Part) error {
+		messageSize += len(b.Get())
+		return nil
+	})
+	m.pendingBytes += messageSize
+
+	m.cond.Broadcast()
+	m.cond.L.Unlock()
+
+	return item.msg, func(ack bool) (int, error) {
+		m.cond.L.Lock()
+		if m.closed {
+			m.cond.L.Unlock()
+			return 0, types.ErrTypeClosed
+		}
+		m.pendingBytes -= messageSize
+		if ack {
+			m.bytes -= messageSize
+		} else {
+			// Rejected messages keep their original position.
+			heap.Push(&m.queue, item)
+		}
+		m.cond.Broadcast()
+
+		backlog := m.bytes
+		m.cond.L.Unlock()
+
+		return backlog, nil
+	}, nil
+}
+
+// PushMessage adds a new message to the buffer. Returns the backlog in bytes.
+func (m *Priority) PushMessage(msg types.Message) (int, error) {
+	extraBytes := 0
+	msg.Iter(func(i int, b types.Part) error {
+		extraBytes += len(b.Get())
+		return nil
+	})
+
+	if extraBytes > m.cap {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	priority := m.priorityFn(msg)
+
+	m.cond.L.Lock()
+
+	if m.closed {
+		m.cond.L.Unlock()
+		return 0, types.ErrTypeClosed
+	}
+
+	for (m.bytes + extraBytes) > m.cap {
+		m.cond.Wait()
+		if m.closed {
+			m.cond.L.Unlock()
+			return 0, types.ErrTypeClosed
+		}
+	}
+
+	heap.Push(&m.queue, &priorityItem{
+		msg:      msg.DeepCopy(),
+		priority: priority,
+		seq:      m.seq,
+	})
+	m.seq++
+	m.bytes += extraBytes
+
+	backlog := m.bytes
+
+	m.cond.Broadcast()
+	m.cond.L.Unlock()
+
+	return backlog, nil
+}
+
+// CloseOnceEmpty closes the Buffer once the buffer has been emptied, this is a
+// way for a writer to signal to a reader that it is finished writing messages,
+// and therefore the reader can close once it is caught up. This call blocks
+// until the close is completed.
+func (m *Priority) CloseOnceEmpty() {
+	m.cond.L.Lock()
+	for (m.bytes-m.pendingBytes > 0) && !m.closed {
+		m.cond.Wait()
+	}
+	if !m.closed {
+		m.closed = true
+		m.cond.Broadcast()
+	}
+	m.cond.L.Unlock()
+}
+
+// Close closes the Buffer so that blocked readers or writers become
+// unblocked.
+func (m *Priority) Close() {
+	m.cond.L.Lock()
+	m.closed = true
+	m.cond.Broadcast()
+	m.cond.L.Unlock()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,58 @@
+package parallel
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityOrdering(t *testing.T) {
+	block := NewPriority(100000, func(msg types.Message) int64 {
+		p, _ := strconv.ParseInt(msg.Get(0).Metadata().Get("priority"), 10, 64)
+		return p
+	})
+
+	for _, in := range [][2]string{
+		{"bulk1", "0"},
+		{"urgent1", "10"},
+		{"bulk2", "0"},
+		{"normal1", "5"},
+		{"urgent2", "10"},
+	} {
+		msg := message.New([][]byte{[]byte(in[0])})
+		msg.Get(0).Metadata().Set("priority", in[1])
+		_, err := block.PushMessage(msg)
+		require.NoError(t, err)
+	}
+
+	next := func() (string, AckFunc) {
+		t.Helper()
+		m, ackFn, err := block.NextMessage()
+		require.NoError(t, err)
+		return string(m.Get(0).Get()), ackFn
+	}
+
+	content, ackFn := next()
+	assert.Equal(t, "urgent1", content)
+
+	// A rejected message returns to its original position.
+	_, err := ackFn(false)
+	require.NoError(t, err)
+
+	var results []string
+	for i := 0; i < 5; i++ {
+		content, ackFn := next()
+		results = append(results, content)
+		_, err := ackFn(true)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"urgent1", "urgent2", "normal1", "bulk1", "bulk2"}, results)
+
+	block.CloseOnceEmpty()
+	_, _, err = block.NextMessage()
+	assert.Equal(t, types.ErrTypeClosed, err)
+}
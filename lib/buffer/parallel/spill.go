@@ -0,0 +1,129 @@
+package parallel
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type spilledPart struct {
+	Content  []byte            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// spillQueue is a FIFO queue of messages stored within a file on disk, where
+// each message is written as a length prefixed JSON document. The file is
+// truncated each time the queue is emptied.
+type spillQueue struct {
+	file        *os.File
+	readOffset  int64
+	writeOffset int64
+
+	// The sizes of the contents of each message in the queue, which allows
+	// callers to check whether the next message fits in memory before reading
+	// it.
+	sizes []int
+	bytes int
+}
+
+func newSpillQueue(dir string) (*spillQueue, error) {
+	f, err := ioutil.TempFile(dir, "benthos_spill_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	return &spillQueue{file: f}, nil
+}
+
+func (s *spillQueue) len() int {
+	return len(s.sizes)
+}
+
+// peekSize returns the content size of the next message of the queue.
+func (s *spillQueue) peekSize() int {
+	return s.sizes[0]
+}
+
+func (s *spillQueue) push(msg types.Message, size int) error {
+	parts := make([]spilledPart, 0, msg.Len())
+	_ = msg.Iter(func(i int, p types.Part) error {
+		part := spilledPart{Content: p.Get()}
+		_ = p.Metadata().Iter(func(k, v string) error {
+			if part.Metadata == nil {
+				part.Metadata = map[string]string{}
+			}
+			part.Metadata[k] = v
+			return nil
+		})
+		parts = append(parts, part)
+		return nil
+	})
+	docBytes, err := json.Marshal(parts)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, 4+len(docBytes))
+	binary.BigEndian.PutUint32(record, uint32(len(docBytes)))
+	copy(record[4:], docBytes)
+	if _, err := s.file.WriteAt(record, s.writeOffset); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+
+	s.writeOffset += int64(len(record))
+	s.sizes = append(s.sizes, size)
+	s.bytes += size
+	return nil
+}
+
+func (s *spillQueue) pop() (types.Message, int, error) {
+	lenBytes := make([]byte, 4)
+	if _, err := s.file.ReadAt(lenBytes, s.readOffset); err != nil {
+		return nil, 0, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	docBytes := make([]byte, binary.BigEndian.Uint32(lenBytes))
+	if _, err := s.file.ReadAt(docBytes, s.readOffset+4); err != nil {
+		return nil, 0, fmt.Errorf("failed to read spill file: %w", err)
+	}
+
+	var parts []spilledPart
+	if err := json.Unmarshal(docBytes, &parts); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse spilled message: %w", err)
+	}
+	msg := message.New(nil)
+	for _, p := range parts {
+		part := message.NewPart(p.Content)
+		for k, v := range p.Metadata {
+			part.Metadata().Set(k, v)
+		}
+		msg.Append(part)
+	}
+
+	size := s.sizes[0]
+	s.sizes[0] = 0
+	s.sizes = s.sizes[1:]
+	s.bytes -= size
+	s.readOffset += int64(4 + len(docBytes))
+
+	if len(s.sizes) == 0 {
+		if err := s.file.Truncate(0); err != nil {
+			return nil, 0, fmt.Errorf("failed to truncate spill file: %w", err)
+		}
+		s.readOffset, s.writeOffset = 0, 0
+	}
+	return msg, size, nil
+}
+
+// close the queue and remove its file, discarding any spilled messages.
+func (s *spillQueue) close() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+	s.sizes = nil
+	s.bytes = 0
+}
@@ -0,0 +1,103 @@
+package buffer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer/parallel"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypePriority] = TypeSpec{
+		constructor: NewPriority,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Stores consumed messages in memory and acknowledges them at the input level, where messages with a higher priority are flushed before those with a lower priority.`,
+		Description: `
+The priority of each message is an integer resolved from the ` + "`priority`" + ` field when it enters the buffer, where messages with higher values jump the queue and messages of equal priority are flushed in the order they were consumed. Messages with a priority that is empty or isn't an integer are given a priority of zero.
+
+During a backlog this allows urgent messages to overtake bulk traffic, as processing pipelines and outputs always read the highest priority message available. A priority is usually assigned with [input processors](/docs/components/processors/about) such as ` + "[`bloblang`](/docs/components/processors/bloblang)" + `, which can set it as metadata. Messages rejected downstream are returned to their original position in the buffer.
+
+Like the ` + "[`memory` buffer](#memory)" + ` this buffer has a configurable limit, where consumption will be stopped with back pressure upstream if the total size of messages in the buffer reaches this amount. During shutdown Benthos will make a best attempt at flushing all remaining messages before exiting cleanly.
+
+### Batching
+
+It is possible to batch up messages sent from this buffer using a [batch policy](/docs/configuration/batching#batch-policy). Batches are formed from messages in order of priority, and so may contain messages of differing priorities.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("priority", "An integer priority resolved for each message, where higher values are flushed first.", `${! meta("priority") }`, `${! if this.severity == "critical" { 10 } else { 0 } }`).IsInterpolated(),
+			docs.FieldCommon("limit", "The maximum buffer size (in bytes) to allow before applying backpressure upstream."),
+			docs.FieldCommon("batch_policy", "Optionally configure a policy to flush buffered messages in batches.").WithChildren(
+				append(docs.FieldSpecs{
+					docs.FieldCommon("enabled", "Whether to batch messages as they are flushed."),
+				}, batch.FieldSpec().Children...)...,
+			),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// PriorityConfig is config values for a memory based buffer that flushes
+// messages in order of their priority.
+type PriorityConfig struct {
+	Priority    string                   `json:"priority" yaml:"priority"`
+	Limit       int                      `json:"limit" yaml:"limit"`
+	BatchPolicy EnabledBatchPolicyConfig `json:"batch_policy" yaml:"batch_policy"`
+}
+
+// NewPriorityConfig creates a new PriorityConfig with default values.
+func NewPriorityConfig() PriorityConfig {
+	return PriorityConfig{
+		Priority: `${! meta("priority") }`,
+		Limit:    1024 * 1024 * 500, // 500MB
+		BatchPolicy: EnabledBatchPolicyConfig{
+			Enabled:      false,
+			PolicyConfig: batch.NewPolicyConfig(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewPriority creates a buffer held in memory that flushes messages in order of
+// their priority.
+func NewPriority(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	priority, err := bloblang.NewField(config.Priority.Priority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse priority expression: %v", err)
+	}
+	priorityFn := func(msg types.Message) int64 {
+		str := strings.TrimSpace(priority.String(0, msg))
+		if str == "" {
+			return 0
+		}
+		p, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			log.Debugf("Failed to parse message priority '%v': %v\n", str, err)
+			return 0
+		}
+		return p
+	}
+
+	wrap := NewParallelWrapper(config, parallel.NewPriority(config.Priority.Limit, priorityFn), log, stats)
+	if !config.Priority.BatchPolicy.Enabled {
+		return wrap, nil
+	}
+	pol, err := batch.NewPolicy(config.Priority.BatchPolicy.PolicyConfig, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("batch policy config error: %v", err)
+	}
+	return NewParallelBatcher(pol, wrap, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
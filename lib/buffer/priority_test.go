@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityBuffer(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypePriority
+	conf.Priority.Priority = `${! json("priority") }`
+
+	buf, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	require.NoError(t, buf.Consume(tChan))
+
+	for _, doc := range []string{
+		`{"id":"c","priority":3}`,
+		`{"id":"a","priority":1}`,
+		`{"id":"b"}`,
+		`{"id":"d","priority":"nope"}`,
+		`{"id":"e","priority":-1}`,
+	} {
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte(doc)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	// The first message might be read before the others are buffered, and so
+	// it has the highest priority in order to keep the results deterministic.
+	var ids []string
+	for i := 0; i < 5; i++ {
+		select {
+		case tran := <-buf.TransactionChan():
+			id, err := tran.Payload.Get(0).JSON()
+			require.NoError(t, err)
+			ids = append(ids, id.(map[string]interface{})["id"].(string))
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	assert.Equal(t, []string{"c", "a", "b", "d", "e"}, ids)
+
+	buf.CloseAsync()
+	require.NoError(t, buf.WaitForClose(time.Second))
+}
+
+func TestPriorityBufferBadExpression(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypePriority
+	conf.Priority.Priority = `${! json( }`
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Contains(t, err.Error(), "failed to parse priority expression")
+}
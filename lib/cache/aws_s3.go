@@ -41,6 +41,8 @@ allowing you to transfer data across accounts. You can find out more
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("bucket", "The S3 bucket to store items in."),
 			docs.FieldCommon("content_type", "The content type to set for each item."),
+			docs.FieldCommon("prefix", "An optional prefix added to the key of each item, allowing multiple caches to share a bucket.", "benthos/cache/").AtVersion("3.47.0"),
+			docs.FieldCommon("ttl", "An optional duration after which items expire, measured from the last time they were written. Expired objects are not removed from the bucket, and therefore it is recommended to also configure a lifecycle rule for the bucket.", "60s", "24h").AtVersion("3.47.0"),
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on requests before abandoning it."),
 			docs.FieldAdvanced("retries", "The maximum number of retry attempts to make before abandoning a request."),
@@ -70,6 +72,8 @@ allowing you to transfer data across accounts. You can find out more
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("bucket", "The S3 bucket to store items in."),
 			docs.FieldCommon("content_type", "The content type to set for each item."),
+			docs.FieldCommon("prefix", "An optional prefix added to the key of each item, allowing multiple caches to share a bucket.", "benthos/cache/").AtVersion("3.47.0"),
+			docs.FieldCommon("ttl", "An optional duration after which items expire, measured from the last time they were written. Expired objects are not removed from the bucket, and therefore it is recommended to also configure a lifecycle rule for the bucket.", "60s", "24h").AtVersion("3.47.0"),
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on requests before abandoning it."),
 			docs.FieldAdvanced("retries", "The maximum number of retry attempts to make before abandoning a request."),
@@ -85,6 +89,8 @@ type S3Config struct {
 	Bucket             string `json:"bucket" yaml:"bucket"`
 	ForcePathStyleURLs bool   `json:"force_path_style_urls" yaml:"force_path_style_urls"`
 	ContentType        string `json:"content_type" yaml:"content_type"`
+	Prefix             string `json:"prefix" yaml:"prefix"`
+	TTL                string `json:"ttl" yaml:"ttl"`
 	Timeout            string `json:"timeout" yaml:"timeout"`
 	Retries            int    `json:"retries" yaml:"retries"`
 }
@@ -96,6 +102,8 @@ func NewS3Config() S3Config {
 		Bucket:             "",
 		ForcePathStyleURLs: false,
 		ContentType:        "application/octet-stream",
+		Prefix:             "",
+		TTL:                "",
 		Timeout:            "5s",
 		Retries:            3,
 	}
@@ -111,6 +119,8 @@ type S3 struct {
 	s3         *s3.S3
 
 	bucket      string
+	prefix      string
+	ttl         time.Duration
 	timeout     time.Duration
 	retries     int
 	contentType string
@@ -161,6 +171,12 @@ func newS3(conf S3Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse timeout: %v", err)
 	}
+	var ttl time.Duration
+	if len(conf.TTL) > 0 {
+		if ttl, err = time.ParseDuration(conf.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+	}
 	sess, err := conf.GetSession(func(c *aws.Config) {
 		c.S3ForcePathStyle = aws.Bool(conf.ForcePathStyleURLs)
 	})
@@ -174,6 +190,8 @@ func newS3(conf S3Config, mgr types.Manager, log log.Modular, stats metrics.Type
 		s3:         s3.New(sess),
 
 		bucket:      conf.Bucket,
+		prefix:      conf.Prefix,
+		ttl:         ttl,
 		timeout:     timeout,
 		retries:     conf.Retries,
 		contentType: conf.ContentType,
@@ -212,9 +230,16 @@ func newS3(conf S3Config, mgr types.Manager, log log.Modular, stats metrics.Type
 
 //------------------------------------------------------------------------------
 
+// isExpired returns true if an object was last modified beyond the TTL.
+func (s *S3) isExpired(lastModified *time.Time) bool {
+	return s.ttl > 0 && lastModified != nil && time.Since(*lastModified) >= s.ttl
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (s *S3) Get(key string) ([]byte, error) {
+	key = s.prefix + key
+
 	ctx, cancel := context.WithTimeout(
 		aws.BackgroundContext(), s.timeout,
 	)
@@ -246,6 +271,10 @@ func (s *S3) Get(key string) ([]byte, error) {
 
 	var bytes []byte
 	if err == nil {
+		if s.isExpired(obj.LastModified) {
+			obj.Body.Close()
+			return nil, types.ErrKeyNotFound
+		}
 		bytes, err = ioutil.ReadAll(obj.Body)
 		obj.Body.Close()
 	}
@@ -254,6 +283,10 @@ func (s *S3) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (s *S3) Set(key string, value []byte) error {
+	return s.set(s.prefix+key, value)
+}
+
+func (s *S3) set(key string, value []byte) error {
 	ctx, cancel := context.WithTimeout(
 		aws.BackgroundContext(), s.timeout,
 	)
@@ -296,18 +329,21 @@ func (s *S3) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (s *S3) Add(key string, value []byte) error {
-	_, err := s.s3.HeadObject(&s3.HeadObjectInput{
+	key = s.prefix + key
+	head, err := s.s3.HeadObject(&s3.HeadObjectInput{
 		Bucket: &s.bucket,
 		Key:    &key,
 	})
-	if err == nil {
+	if err == nil && !s.isExpired(head.LastModified) {
 		return types.ErrKeyAlreadyExists
 	}
-	return s.Set(key, value)
+	return s.set(key, value)
 }
 
 // Delete attempts to remove a key.
 func (s *S3) Delete(key string) error {
+	key = s.prefix + key
+
 	ctx, cancel := context.WithTimeout(
 		aws.BackgroundContext(), s.timeout,
 	)
@@ -1,9 +1,11 @@
 package cache
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -21,10 +23,19 @@ func init() {
 Stores each item in a directory as a file, where an item ID is the path relative
 to the configured directory.`,
 		Description: `
-This type currently offers no form of item expiry or garbage collection, and is
-intended to be used for development and debugging purposes only.`,
+By default items never expire. When a ` + "`ttl`" + ` is set an item expires once
+the modification time of its file is older than the TTL, at which point reads of
+the item fail as if it does not exist. Expired files are removed from the
+directory during a compaction, which only occurs during a write where the time
+since the last compaction is above the compaction interval.
+
+This cache is useful for storing large items such as cached artifacts, but it
+does not coordinate access across processes and is therefore not suitable for
+deduplication across multiple Benthos instances.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("directory", "The directory within which to store items."),
+			docs.FieldCommon("ttl", "An optional duration after which items expire, measured from the last time they were written.", "60s", "24h").AtVersion("3.47.0"),
+			docs.FieldAdvanced("compaction_interval", "The period of time to wait before each compaction, at which point the files of expired items are removed. Compactions only occur when a `ttl` is set.").AtVersion("3.47.0"),
 		},
 	}
 }
@@ -33,13 +44,17 @@ intended to be used for development and debugging purposes only.`,
 
 // FileConfig contains config fields for the File cache type.
 type FileConfig struct {
-	Directory string `json:"directory" yaml:"directory"`
+	Directory          string `json:"directory" yaml:"directory"`
+	TTL                string `json:"ttl" yaml:"ttl"`
+	CompactionInterval string `json:"compaction_interval" yaml:"compaction_interval"`
 }
 
 // NewFileConfig creates a FileConfig populated with default values.
 func NewFileConfig() FileConfig {
 	return FileConfig{
-		Directory: "",
+		Directory:          "",
+		TTL:                "",
+		CompactionInterval: "60s",
 	}
 }
 
@@ -48,21 +63,103 @@ func NewFileConfig() FileConfig {
 // File is a file system based cache implementation.
 type File struct {
 	dir string
+	ttl time.Duration
+
+	compactionInterval time.Duration
+	lastCompaction     time.Time
+	compactionMut      sync.Mutex
+
+	log          log.Modular
+	mCompactions metrics.StatCounter
+	mExpired     metrics.StatCounter
 }
 
 // NewFile creates a new File cache type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
-	return &File{
-		dir: conf.File.Directory,
-	}, nil
+	f := &File{
+		dir:            conf.File.Directory,
+		lastCompaction: time.Now(),
+
+		log:          log,
+		mCompactions: stats.GetCounter("compaction"),
+		mExpired:     stats.GetCounter("expired"),
+	}
+	if len(conf.File.TTL) > 0 {
+		var err error
+		if f.ttl, err = time.ParseDuration(conf.File.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+	}
+	if len(conf.File.CompactionInterval) > 0 {
+		var err error
+		if f.compactionInterval, err = time.ParseDuration(conf.File.CompactionInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse compaction interval string: %v", err)
+		}
+	}
+	return f, nil
 }
 
 //------------------------------------------------------------------------------
 
+// isExpired returns true if the file of an item has not been written to within
+// the TTL.
+func (f *File) isExpired(info os.FileInfo) bool {
+	return f.ttl > 0 && time.Since(info.ModTime()) >= f.ttl
+}
+
+// compaction removes the files of expired items if the time since the last
+// compaction is above the compaction interval.
+func (f *File) compaction() {
+	if f.ttl <= 0 {
+		return
+	}
+
+	f.compactionMut.Lock()
+	if time.Since(f.lastCompaction) < f.compactionInterval {
+		f.compactionMut.Unlock()
+		return
+	}
+	f.lastCompaction = time.Now()
+	f.compactionMut.Unlock()
+
+	f.mCompactions.Incr(1)
+	if err := filepath.Walk(f.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() && f.isExpired(info) {
+			if err := os.Remove(path); err == nil {
+				f.mExpired.Incr(1)
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		f.log.Errorf("Failed to remove expired items: %v\n", err)
+	}
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (f *File) Get(key string) ([]byte, error) {
-	b, err := ioutil.ReadFile(filepath.Join(f.dir, key))
+	path := filepath.Join(f.dir, key)
+	if f.ttl > 0 {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return nil, types.ErrKeyNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.isExpired(info) {
+			return nil, types.ErrKeyNotFound
+		}
+	}
+	b, err := ioutil.ReadFile(path)
 	if os.IsNotExist(err) {
 		return nil, types.ErrKeyNotFound
 	}
@@ -71,6 +168,7 @@ func (f *File) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (f *File) Set(key string, value []byte) error {
+	f.compaction()
 	return ioutil.WriteFile(filepath.Join(f.dir, key), value, 0644)
 }
 
@@ -88,7 +186,18 @@ func (f *File) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (f *File) Add(key string, value []byte) error {
-	file, err := os.OpenFile(filepath.Join(f.dir, key), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	f.compaction()
+
+	path := filepath.Join(f.dir, key)
+	if f.ttl > 0 {
+		if info, err := os.Stat(path); err == nil && f.isExpired(info) {
+			if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
 		if os.IsExist(err) {
 			return types.ErrKeyAlreadyExists
@@ -3,11 +3,15 @@ package cache
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //------------------------------------------------------------------------------
@@ -79,4 +83,51 @@ func TestFileCache(t *testing.T) {
 	}
 }
 
+func TestFileCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+
+	conf := NewConfig()
+	conf.Type = TypeFile
+	conf.File.Directory = dir
+	conf.File.TTL = "1h"
+	conf.File.CompactionInterval = "0s"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	expire := func(key string) {
+		t.Helper()
+		past := time.Now().Add(-time.Hour * 2)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, key), past, past))
+	}
+
+	require.NoError(t, c.Set("foo", []byte("1")))
+	require.NoError(t, c.Set("bar", []byte("2")))
+
+	act, err := c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(act))
+
+	expire("foo")
+
+	_, err = c.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Add("foo", []byte("3")))
+
+	act, err = c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(act))
+
+	expire("bar")
+	require.NoError(t, c.Set("baz", []byte("4")))
+
+	_, err = os.Stat(filepath.Join(dir, "bar"))
+	assert.True(t, os.IsNotExist(err), err)
+
+	act, err = c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(act))
+}
+
 //------------------------------------------------------------------------------
@@ -3,12 +3,14 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
 	"github.com/dgraph-io/ristretto"
 )
 
@@ -23,13 +25,17 @@ func init() {
 Stores key/value pairs in a map held in the memory-bound
 [Ristretto cache](https://github.com/dgraph-io/ristretto).`,
 		Description: `
-This cache is more efficient and appropriate for high-volume use cases than the standard memory cache. However, the add command is non-atomic, and therefore this cache is not suitable for deduplication.`,
+This cache is more efficient and appropriate for high-volume use cases than the standard memory cache, as items are stored with near-zero garbage collection overhead and are evicted based on their cost once the cache is full. The cost of each item is the size of its value in bytes, and therefore the field ` + "`max_cost`" + ` bounds the total memory consumed by values.
+
+Writes to the cache are buffered and become visible to reads shortly after, which can be compensated for with the ` + "`retries`" + ` field. However, the add command is atomic within a single instance of this cache and also accounts for buffered writes, which makes this cache suitable for deduplication of a large number of keys. Note that keys may still be evicted before their TTL once the cache is full.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
 				"ttl",
 				"The TTL of each item as a duration string. After this period an item will be eligible for removal during the next compaction.",
 				"60s", "5m", "36h",
 			),
+			docs.FieldAdvanced("max_cost", "The maximum total cost of items held by the cache, where the cost of an item is the size of its value in bytes. Once reached items are evicted in order to admit new ones.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("num_counters", "The number of keys to track the access frequency of, which determines the accuracy of evictions. This should be roughly ten times the number of items expected to fit within the cache.").AtVersion("3.47.0"),
 			docs.FieldAdvanced("retries", "The maximum number of retry attempts to make before abandoning a request."),
 			docs.FieldAdvanced("retry_period", "The duration to wait between retry attempts."),
 		},
@@ -41,6 +47,8 @@ This cache is more efficient and appropriate for high-volume use cases than the
 // RistrettoConfig contains config fields for the Ristretto cache type.
 type RistrettoConfig struct {
 	TTL         string `json:"ttl" yaml:"ttl"`
+	MaxCost     int64  `json:"max_cost" yaml:"max_cost"`
+	NumCounters int64  `json:"num_counters" yaml:"num_counters"`
 	Retries     int    `json:"retries" yaml:"retries"`
 	RetryPeriod string `json:"retry_period" yaml:"retry_period"`
 }
@@ -49,6 +57,8 @@ type RistrettoConfig struct {
 func NewRistrettoConfig() RistrettoConfig {
 	return RistrettoConfig{
 		TTL:         "",
+		MaxCost:     1 << 30, // 1GB
+		NumCounters: 1e7,
 		Retries:     0,
 		RetryPeriod: "50ms",
 	}
@@ -56,10 +66,46 @@ func NewRistrettoConfig() RistrettoConfig {
 
 //------------------------------------------------------------------------------
 
+// ristrettoPendingWindow is the period of time for which a write is tracked
+// after being submitted, as it might not yet be visible to reads.
+const ristrettoPendingWindow = time.Second
+
+// ristrettoShard tracks the keys of recent writes in order to make adds atomic.
+type ristrettoShard struct {
+	sync.Mutex
+	pending   map[string]time.Time
+	lastPrune time.Time
+}
+
+// isPending returns true if a key was written within the pending window.
+func (s *ristrettoShard) isPending(key string) bool {
+	deadline, exists := s.pending[key]
+	return exists && time.Now().Before(deadline)
+}
+
+// setPending tracks a write of a key until the end of the pending window, or
+// until its TTL if sooner, and removes writes that are beyond their window.
+func (s *ristrettoShard) setPending(key string, ttl time.Duration) {
+	now := time.Now()
+	if now.Sub(s.lastPrune) >= ristrettoPendingWindow {
+		for k, deadline := range s.pending {
+			if !now.Before(deadline) {
+				delete(s.pending, k)
+			}
+		}
+		s.lastPrune = now
+	}
+	if ttl <= 0 || ttl > ristrettoPendingWindow {
+		ttl = ristrettoPendingWindow
+	}
+	s.pending[key] = now.Add(ttl)
+}
+
 // Ristretto is a memory based cache implementation.
 type Ristretto struct {
-	ttl   time.Duration
-	cache *ristretto.Cache
+	ttl    time.Duration
+	cache  *ristretto.Cache
+	shards []*ristrettoShard
 
 	retries     int
 	retryPeriod time.Duration
@@ -84,10 +130,17 @@ func NewRistretto(conf Config, mgr types.Manager, log log.Modular, stats metrics
 		}
 	}
 
+	if conf.Ristretto.MaxCost <= 0 {
+		return nil, fmt.Errorf("max_cost must be greater than zero, got: %v", conf.Ristretto.MaxCost)
+	}
+	if conf.Ristretto.NumCounters <= 0 {
+		return nil, fmt.Errorf("num_counters must be greater than zero, got: %v", conf.Ristretto.NumCounters)
+	}
+
 	cache, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64,      // number of keys per Get buffer.
+		NumCounters: conf.Ristretto.NumCounters,
+		MaxCost:     conf.Ristretto.MaxCost,
+		BufferItems: 64, // number of keys per Get buffer.
 	})
 	if err != nil {
 		return nil, err
@@ -98,12 +151,35 @@ func NewRistretto(conf Config, mgr types.Manager, log log.Modular, stats metrics
 		retries:     conf.Ristretto.Retries,
 		retryPeriod: retryPeriod,
 	}
+	for i := 0; i < 64; i++ {
+		r.shards = append(r.shards, &ristrettoShard{
+			pending: map[string]time.Time{},
+		})
+	}
 
 	return r, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (r *Ristretto) getShard(key string) *ristrettoShard {
+	h := xxhash.New64()
+	h.WriteString(key)
+	return r.shards[h.Sum64()%uint64(len(r.shards))]
+}
+
+func (r *Ristretto) set(shard *ristrettoShard, key string, value []byte, ttl *time.Duration) error {
+	t := r.ttl
+	if ttl != nil {
+		t = *ttl
+	}
+	if !r.cache.SetWithTTL(key, value, int64(len(value)), t) {
+		return errors.New("set operation was dropped")
+	}
+	shard.setPending(key, t)
+	return nil
+}
+
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (r *Ristretto) Get(key string) ([]byte, error) {
@@ -120,16 +196,11 @@ func (r *Ristretto) Get(key string) ([]byte, error) {
 
 // SetWithTTL attempts to set the value of a key.
 func (r *Ristretto) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
-	var t time.Duration
-	if ttl != nil {
-		t = *ttl
-	} else {
-		t = r.ttl
-	}
-	if !r.cache.SetWithTTL(key, value, 1, t) {
-		return errors.New("set operation was dropped")
-	}
-	return nil
+	shard := r.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	return r.set(shard, key, value, ttl)
 }
 
 // Set attempts to set the value of a key.
@@ -141,14 +212,8 @@ func (r *Ristretto) Set(key string, value []byte) error {
 // keys fail.
 func (r *Ristretto) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
 	for k, v := range items {
-		var t time.Duration
-		if v.TTL != nil {
-			t = *v.TTL
-		} else {
-			t = r.ttl
-		}
-		if !r.cache.SetWithTTL(k, v.Value, 1, t) {
-			return errors.New("set operation was dropped")
+		if err := r.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -169,7 +234,17 @@ func (r *Ristretto) SetMulti(items map[string][]byte) error {
 // AddWithTTL attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (r *Ristretto) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
-	return r.SetWithTTL(key, value, ttl)
+	shard := r.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	if shard.isPending(key) {
+		return types.ErrKeyAlreadyExists
+	}
+	if _, exists := r.cache.Get(key); exists {
+		return types.ErrKeyAlreadyExists
+	}
+	return r.set(shard, key, value, ttl)
 }
 
 // Add attempts to set the value of a key only if the key does not already exist
@@ -180,7 +255,11 @@ func (r *Ristretto) Add(key string, value []byte) error {
 
 // Delete attempts to remove a key.
 func (r *Ristretto) Delete(key string) error {
+	shard := r.getShard(key)
+	shard.Lock()
 	r.cache.Del(key)
+	delete(shard.pending, key)
+	shard.Unlock()
 	return nil
 }
 
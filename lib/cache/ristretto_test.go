@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -66,3 +68,58 @@ func TestRistrettoCacheWithTTL(t *testing.T) {
 		assert.Fail(t, "ristretto should implement CacheWithTTL interface")
 	}
 }
+
+func TestRistrettoCacheAdd(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRistretto
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(c.CloseAsync)
+
+	// Adds must observe writes that have not yet become visible to reads.
+	require.NoError(t, c.Add("foo", []byte("1")))
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.Add("foo", []byte("2")))
+
+	require.NoError(t, c.Set("bar", []byte("1")))
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.Add("bar", []byte("2")))
+
+	require.NoError(t, c.Delete("foo"))
+	require.NoError(t, c.Add("foo", []byte("3")))
+}
+
+func TestRistrettoCacheAddConcurrent(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRistretto
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(c.CloseAsync)
+
+	var wg sync.WaitGroup
+	var added int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Add("foo", []byte("bar")); err == nil {
+				atomic.AddInt64(&added, 1)
+			} else {
+				assert.Equal(t, types.ErrKeyAlreadyExists, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), added)
+}
+
+func TestRistrettoCacheBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRistretto
+	conf.Ristretto.MaxCost = 0
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_cost must be greater than zero")
+}
@@ -89,12 +89,14 @@ func NewBloblang(
 func (c *Bloblang) Check(msg types.Message) bool {
 	c.mCount.Incr(1)
 
+	batch, index := query.UnlockBatch(msg, 0)
+
 	var valuePtr *interface{}
 	var parseErr error
 
 	lazyValue := func() *interface{} {
 		if valuePtr == nil && parseErr == nil {
-			if jObj, err := msg.Get(0).JSON(); err == nil {
+			if jObj, err := batch.Get(index).JSON(); err == nil {
 				valuePtr = &jObj
 			} else {
 				parseErr = err
@@ -106,7 +108,8 @@ func (c *Bloblang) Check(msg types.Message) bool {
 	result, err := c.fn.Exec(query.FunctionContext{
 		Maps:     map[string]query.Function{},
 		Vars:     map[string]interface{}{},
-		MsgBatch: msg,
+		Index:    index,
+		MsgBatch: batch,
 	}.WithValueFunc(lazyValue))
 	if err != nil {
 		c.log.Errorf("Failed to check query: %v\n", err)
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/util/text"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+const whenKey = "when"
+
+// ResolveConditions takes a config, executes the `when` conditions of
+// components within lists and removes the components where the condition
+// resolved to false, along with the `when` fields of the remaining components.
+// The config is returned unchanged when it contains no conditions.
+func ResolveConditions(configBytes []byte) ([]byte, error) {
+	if !bytes.Contains(configBytes, []byte(whenKey)) {
+		return configBytes, nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(configBytes, &root); err != nil {
+		return nil, err
+	}
+
+	changed, err := resolveNodeConditions(&root, false)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return configBytes, nil
+	}
+	if configBytes, err = yaml.Marshal(&root); err != nil {
+		return nil, fmt.Errorf("failed to marshal condition resolved structure: %v", err)
+	}
+	return configBytes, nil
+}
+
+func conditionKeyIndex(node *yaml.Node) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == whenKey {
+			return i
+		}
+	}
+	return -1
+}
+
+func evalCondition(node *yaml.Node) (bool, error) {
+	if node.Kind != yaml.ScalarNode {
+		return false, errors.New("expected a boolean or a Bloblang query")
+	}
+	if node.Tag == "!!bool" {
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	}
+	res, err := text.ExecEnvironmentQuery(node.Value)
+	if err != nil {
+		return false, err
+	}
+	b, ok := res.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected query to return a boolean, got %T", res)
+	}
+	return b, nil
+}
+
+// resolveNodeConditions walks a node and resolves the conditions of all
+// objects within lists, conditions of objects that are not within a list are
+// rejected as they cannot be removed.
+func resolveNodeConditions(node *yaml.Node, inList bool) (changed bool, err error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, c := range node.Content {
+			var cChanged bool
+			if cChanged, err = resolveNodeConditions(c, false); err != nil {
+				return
+			}
+			changed = changed || cChanged
+		}
+	case yaml.SequenceNode:
+		newContent := make([]*yaml.Node, 0, len(node.Content))
+		for _, c := range node.Content {
+			if i := conditionKeyIndex(c); i >= 0 {
+				changed = true
+				include, err := evalCondition(c.Content[i+1])
+				if err != nil {
+					return false, fmt.Errorf("line %v: failed to resolve condition: %w", c.Content[i].Line, err)
+				}
+				if !include {
+					continue
+				}
+				c.Content = append(c.Content[:i], c.Content[i+2:]...)
+			}
+			cChanged, err := resolveNodeConditions(c, true)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || cChanged
+			newContent = append(newContent, c)
+		}
+		node.Content = newContent
+	case yaml.MappingNode:
+		if i := conditionKeyIndex(node); i >= 0 && !inList {
+			return false, fmt.Errorf("line %v: conditions are only supported by components within a list", node.Content[i].Line)
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			var cChanged bool
+			if cChanged, err = resolveNodeConditions(node.Content[i], false); err != nil {
+				return
+			}
+			changed = changed || cChanged
+		}
+	}
+	return
+}
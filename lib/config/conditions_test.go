@@ -0,0 +1,116 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveConditions(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_CONDITION_ENV", "prod")
+	defer os.Unsetenv("BENTHOS_TEST_CONDITION_ENV")
+
+	tests := map[string]struct {
+		input  string
+		output string
+		err    string
+	}{
+		"no conditions": {
+			input: `pipeline:
+  processors:
+    - bloblang: 'root = this'
+`,
+			output: `pipeline:
+  processors:
+    - bloblang: 'root = this'
+`,
+		},
+		"conditions resolved": {
+			input: `pipeline:
+  processors:
+    - when: env("BENTHOS_TEST_CONDITION_ENV") == "prod"
+      bloblang: 'root = "prod"'
+    - when: env("BENTHOS_TEST_CONDITION_ENV") != "prod"
+      bloblang: 'root = "dev"'
+    - when: false
+      log:
+        message: nope
+    - when: true
+      switch:
+        - when: '"${BENTHOS_TEST_CONDITION_ENV}" == "dev"'
+          processors: []
+`,
+			output: `pipeline:
+    processors:
+        - bloblang: 'root = "prod"'
+        - switch: []
+`,
+		},
+		"condition not in a list": {
+			input: `input:
+  when: true
+  stdin: {}
+`,
+			err: "line 2: conditions are only supported by components within a list",
+		},
+		"condition not a boolean": {
+			input: `pipeline:
+  processors:
+    - when: env("BENTHOS_TEST_CONDITION_ENV")
+      bloblang: 'root = this'
+`,
+			err: "line 3: failed to resolve condition: expected query to return a boolean, got string",
+		},
+		"condition bad query": {
+			input: `pipeline:
+  processors:
+    - bloblang: 'root = this'
+    - when: content() == "foo"
+      bloblang: 'root = this'
+`,
+			err: "line 4: failed to resolve condition: ",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			res, err := ResolveConditions([]byte(test.input))
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(res))
+		})
+	}
+}
+
+func TestReadConditions(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_CONDITION_ENV", "prod")
+	defer os.Unsetenv("BENTHOS_TEST_CONDITION_ENV")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+pipeline:
+  processors:
+    - when: env("BENTHOS_TEST_CONDITION_ENV") == "prod"
+      bloblang: 'root = "prod"'
+    - when: env("BENTHOS_TEST_CONDITION_ENV") == "dev"
+      bloblang: 'root = "dev"'
+      nope: this field is linted even though the component is excluded
+`), 0644))
+
+	conf := New()
+	lints, err := Read(path, true, &conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"line 8: field nope is invalid when the component type is bloblang (processor)"}, lints)
+
+	require.Len(t, conf.Pipeline.Processors, 1)
+	assert.Equal(t, `root = "prod"`, string(conf.Pipeline.Processors[0].Bloblang))
+}
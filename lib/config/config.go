@@ -221,16 +221,21 @@ func AddExamples(conf *Type, examples ...string) {
 // Read will attempt to read a configuration file path into a structure. Returns
 // an array of lint messages or an error.
 func Read(path string, replaceEnvs bool, config *Type) ([]string, error) {
-	configBytes, lints, err := ReadWithJSONPointersLinted(path, replaceEnvs)
+	rawBytes, lints, err := readWithJSONPointers(path, replaceEnvs)
 	if err != nil {
 		return nil, err
 	}
 
+	configBytes, err := ResolveConditions(rawBytes)
+	if err != nil {
+		return nil, err
+	}
 	if err := yaml.Unmarshal(configBytes, config); err != nil {
 		return nil, err
 	}
 
-	newLints, err := Lint(configBytes, *config)
+	// Components excluded by conditions are still linted.
+	newLints, err := Lint(rawBytes, *config)
 	if err != nil {
 		return nil, err
 	}
@@ -25,12 +25,24 @@ func ReadWithJSONPointers(path string, replaceEnvs bool) ([]byte, error) {
 }
 
 // ReadWithJSONPointersLinted takes a config file path, reads the contents,
-// performs a generic parse, resolves any JSON Pointers, marshals the result
-// back into bytes and returns it so that it can be unmarshalled into a typed
-// structure.
+// performs a generic parse, resolves any JSON Pointers and conditions, marshals
+// the result back into bytes and returns it so that it can be unmarshalled into
+// a typed structure.
 //
 // If any non-fatal errors occur lints are returned along with the result.
 func ReadWithJSONPointersLinted(path string, replaceEnvs bool) (configBytes []byte, lints []string, err error) {
+	if configBytes, lints, err = readWithJSONPointers(path, replaceEnvs); err != nil {
+		return nil, lints, err
+	}
+	if configBytes, err = ResolveConditions(configBytes); err != nil {
+		return nil, lints, err
+	}
+	return configBytes, lints, nil
+}
+
+// readWithJSONPointers reads a config file and resolves any JSON Pointers, but
+// leaves conditions unresolved in order for all components to be linted.
+func readWithJSONPointers(path string, replaceEnvs bool) (configBytes []byte, lints []string, err error) {
 	configBytes, err = ioutil.ReadFile(path)
 	if err != nil {
 		return nil, nil, err
@@ -41,6 +53,9 @@ func ReadWithJSONPointersLinted(path string, replaceEnvs bool) (configBytes []by
 	}
 
 	if replaceEnvs {
+		if configBytes, err = text.ReplaceComputedValues(configBytes); err != nil {
+			return nil, lints, err
+		}
 		configBytes = text.ReplaceEnvVariables(configBytes)
 	}
 
@@ -168,6 +183,9 @@ func expandRefVal(path string, level int, root, v interface{}) (interface{}, err
 		if err != nil {
 			return nil, fmt.Errorf("failed to read relative $ref path '%v' in config '%v': %v", rPath, path, err)
 		}
+		if configBytes, err = text.ReplaceComputedValues(configBytes); err != nil {
+			return nil, fmt.Errorf("failed to read relative $ref path '%v' in config '%v': %v", rPath, path, err)
+		}
 		configBytes = text.ReplaceEnvVariables(configBytes)
 
 		var gen interface{}
@@ -348,4 +348,54 @@ func TestConfigNoRefs(t *testing.T) {
 	}
 }
 
+func TestConfigComputedValues(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_config_ref_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err = os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	os.Setenv("BENTHOS_TEST_COMPUTED_ENV", "prod")
+	defer os.Unsetenv("BENTHOS_TEST_COMPUTED_ENV")
+
+	rootPath := filepath.Join(tmpDir, "root.yaml")
+	rootFile := []byte(`foo: ${= "client-" + env("BENTHOS_TEST_COMPUTED_ENV") }
+bar: ${BENTHOS_TEST_COMPUTED_ENV}
+`)
+
+	if err = ioutil.WriteFile(rootPath, rootFile, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ReadWithJSONPointers(rootPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `foo: client-prod
+bar: prod
+`
+	if act := string(res); exp != act {
+		t.Errorf("Wrong config result: %v != %v", act, exp)
+	}
+
+	if res, err = ReadWithJSONPointers(rootPath, false); err != nil {
+		t.Fatal(err)
+	}
+	if act := string(res); string(rootFile) != act {
+		t.Errorf("Wrong config result: %v != %v", act, string(rootFile))
+	}
+
+	if err = ioutil.WriteFile(rootPath, []byte(`foo: ${= content() }`), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadWithJSONPointers(rootPath, true); err == nil {
+		t.Error("Expected error from message function")
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,195 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/arangodb"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeArangoDB] = TypeSpec{
+		constructor: fromSimpleConstructor(NewArangoDB),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Executes an AQL query against an [ArangoDB](https://www.arangodb.com/) database and creates a message for each result.`,
+		Description: `
+The results of the query are read from a cursor in batches of ` + "`batch_size`" + `, and once all results have been consumed the input shuts down, which also gracefully terminates the pipeline when it is the only input. Values within ` + "`bind_vars`" + ` are passed to the query as bind parameters, which should be used instead of building queries dynamically in order to avoid injection.
+
+Each result of the query becomes a message containing its JSON representation. In order to consume the results of a query periodically use a [` + "`sequence`" + `](/docs/components/inputs/sequence) or [` + "`read_until`" + `](/docs/components/inputs/read_until) input.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Exporting Recent Orders",
+				Summary: "This example reads all orders placed since a given date along with the name of their customer.",
+				Config: `
+input:
+  arangodb:
+    url: http://localhost:8529
+    database: shop
+    username: root
+    password: ${ARANGO_PASSWORD}
+    query: |
+      FOR o IN orders
+        FILTER o.placed_at >= @since
+        LET c = DOCUMENT("customers", o.customer_id)
+        RETURN MERGE(o, { customer_name: c.name })
+    bind_vars:
+      since: "2021-01-01T00:00:00Z"
+`,
+			},
+		},
+		FieldSpecs: arangodb.FieldSpecs().Add(
+			docs.FieldCommon("query", "The AQL query to execute."),
+			docs.FieldCommon("bind_vars", "Values of the bind parameters of the query.").Map(),
+			docs.FieldAdvanced("batch_size", "The maximum number of results to read from the cursor with each request."),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ArangoDBConfig contains configuration fields for the ArangoDB input type.
+type ArangoDBConfig struct {
+	arangodb.Config `json:",inline" yaml:",inline"`
+	Query           string                 `json:"query" yaml:"query"`
+	BindVars        map[string]interface{} `json:"bind_vars" yaml:"bind_vars"`
+	BatchSize       int                    `json:"batch_size" yaml:"batch_size"`
+}
+
+// NewArangoDBConfig creates a new ArangoDBConfig with default values.
+func NewArangoDBConfig() ArangoDBConfig {
+	return ArangoDBConfig{
+		Config:    arangodb.NewConfig(),
+		Query:     "",
+		BindVars:  map[string]interface{}{},
+		BatchSize: 1000,
+	}
+}
+
+// NewArangoDB creates a new ArangoDB input type.
+func NewArangoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newArangoDBReader(conf.ArangoDB, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeArangoDB, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type arangoDBReader struct {
+	conf   ArangoDBConfig
+	client *arangodb.Client
+	log    log.Modular
+
+	mut     sync.Mutex
+	cursor  *arangodb.Cursor
+	pending []json.RawMessage
+	done    bool
+}
+
+func newArangoDBReader(conf ArangoDBConfig, log log.Modular) (*arangoDBReader, error) {
+	if conf.Query == "" {
+		return nil, errors.New("a query must be specified")
+	}
+	client, err := arangodb.NewClient(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &arangoDBReader{
+		conf:   conf,
+		client: client,
+		log:    log,
+	}, nil
+}
+
+// ConnectWithContext executes the query.
+func (a *arangoDBReader) ConnectWithContext(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.done {
+		return types.ErrTypeClosed
+	}
+	if a.cursor != nil {
+		return nil
+	}
+
+	cursor, err := a.client.Query(ctx, a.conf.Query, a.conf.BindVars, a.conf.BatchSize)
+	if err != nil {
+		return err
+	}
+	a.cursor = cursor
+	a.log.Infof("Reading query results from ArangoDB database: %v\n", a.conf.Database)
+	return nil
+}
+
+// ReadWithContext attempts to read the next result of the query.
+func (a *arangoDBReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.cursor == nil {
+		if a.done {
+			return nil, nil, types.ErrTypeClosed
+		}
+		return nil, nil, types.ErrNotConnected
+	}
+
+	for len(a.pending) == 0 {
+		batch, err := a.cursor.NextBatch(ctx)
+		if err == io.EOF {
+			a.cursor = nil
+			a.done = true
+			return nil, nil, types.ErrTypeClosed
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, nil, types.ErrTimeout
+			}
+			return nil, nil, err
+		}
+		a.pending = batch
+	}
+
+	result := a.pending[0]
+	a.pending = a.pending[1:]
+
+	msg := message.New([][]byte{result})
+	return msg, func(ctx context.Context, res types.Response) error {
+		return nil
+	}, nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (a *arangoDBReader) CloseAsync() {
+	go func() {
+		a.mut.Lock()
+		if a.cursor != nil {
+			if err := a.cursor.Close(context.Background()); err != nil {
+				a.log.Debugf("Failed to delete cursor: %v\n", err)
+			}
+			a.cursor = nil
+		}
+		a.mut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (a *arangoDBReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
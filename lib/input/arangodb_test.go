@@ -0,0 +1,82 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArangoDB(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/_db/shop/_api/cursor":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "FOR o IN orders FILTER o.total > @min RETURN o", body["query"])
+			assert.Equal(t, map[string]interface{}{"min": float64(10)}, body["bindVars"])
+			w.Write([]byte(`{"id":"1","result":[{"id":"a"},{"id":"b"}],"hasMore":true}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/_db/shop/_api/cursor/1":
+			w.Write([]byte(`{"id":"1","result":[{"id":"c"}],"hasMore":false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewArangoDBConfig()
+	conf.URL = ts.URL
+	conf.Database = "shop"
+	conf.Query = "FOR o IN orders FILTER o.total > @min RETURN o"
+	conf.BindVars = map[string]interface{}{"min": 10}
+	conf.BatchSize = 2
+
+	rdr, err := newArangoDBReader(conf, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	var results []string
+	for {
+		msg, ack, err := rdr.ReadWithContext(ctx)
+		if err == types.ErrTypeClosed {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, ack(ctx, nil))
+		results = append(results, string(msg.Get(0).Get()))
+	}
+	assert.Equal(t, []string{`{"id":"a"}`, `{"id":"b"}`, `{"id":"c"}`}, results)
+
+	assert.Equal(t, types.ErrTypeClosed, rdr.ConnectWithContext(ctx))
+}
+
+func TestArangoDBBadQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":true,"code":400,"errorNum":1501,"errorMessage":"syntax error, unexpected identifier"}`))
+	}))
+	defer ts.Close()
+
+	conf := NewArangoDBConfig()
+	conf.URL = ts.URL
+	conf.Query = "FOR o IN"
+
+	rdr, err := newArangoDBReader(conf, log.Noop())
+	require.NoError(t, err)
+
+	err = rdr.ConnectWithContext(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "syntax error")
+
+	conf.Query = ""
+	_, err = newArangoDBReader(conf, log.Noop())
+	require.EqualError(t, err, "a query must be specified")
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/shard"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -32,7 +33,7 @@ func init() {
 		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 			var r reader.Async
 			var err error
-			if r, err = newAmazonS3(conf.AWSS3, log, stats); err != nil {
+			if r, err = newAmazonS3(conf.AWSS3, mgr, log, stats); err != nil {
 				return nil, err
 			}
 			// If we're not pulling events directly from an SQS queue then
@@ -45,7 +46,7 @@ func init() {
 		}),
 		Status: docs.StatusStable,
 		Summary: `
-Downloads objects within an Amazon S3 bucket, optionally filtered by a prefix, either by walking the items in the bucket or by streaming upload notifications in realtime.`,
+Downloads objects within an Amazon S3 bucket, optionally filtered by a prefix and suffix, either by walking the items in the bucket or by streaming upload notifications in realtime.`,
 		Description: `
 ## Streaming Objects on Upload with SQS
 
@@ -53,6 +54,8 @@ A common pattern for consuming S3 objects is to emit upload notification events
 
 Benthos is able to follow this pattern when you configure an ` + "`sqs.url`" + `, where it consumes events from SQS and only downloads object keys received within those events. In order for this to work Benthos needs to know where within the event the key and bucket names can be found, specified as [dot paths](/docs/configuration/field_paths) with the fields ` + "`sqs.key_path` and `sqs.bucket_path`" + `. The default values for these fields should already be correct when following the guide above.
 
+Notifications of objects that don't match the ` + "`prefix` and `suffix`" + ` filters are ignored, and SQS messages where none of the objects match are deleted from the queue without downloading anything. When ` + "`delete_objects`" + ` is true each object is deleted from the bucket once all of its messages have been successfully sent onwards, and before the SQS message is deleted.
+
 If your notification events are being routed to SQS via an SNS topic then the events will be enveloped by SNS, in which case you also need to specify the field ` + "`sqs.envelope_path`" + `, which in the case of SNS to SQS will usually be ` + "`Message`" + `.
 
 When using SQS please make sure you have sensible values for ` + "`sqs.max_messages`" + ` and also the visibility timeout of the queue itself. When Benthos consumes an S3 object the SQS message that triggered it is not deleted until the S3 object has been sent onwards. This ensures at-least-once crash resiliency, but also means that if the S3 object takes longer to process than the visibility timeout of your queue then the same objects might be processed multiple times.
@@ -61,6 +64,8 @@ When using SQS please make sure you have sensible values for ` + "`sqs.max_messa
 
 When downloading large files it's often necessary to process it in streamed parts in order to avoid loading the entire file in memory at a given time. In order to do this a ` + "[`codec`](#codec)" + ` can be specified that determines how to break the input into smaller individual messages.
 
+When a bucket contains objects of different formats the codec ` + "`auto`" + ` can be used, which selects a codec for each object based on its extension, including decompression for objects ending in ` + "`.gz`" + `. For example, an object ` + "`logs/foo.jsonl.gz`" + ` is consumed with the codec ` + "`gzip/lines`" + ` and an object ` + "`reports/bar.csv`" + ` with the codec ` + "`csv`" + `.
+
 ## Credentials
 
 By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/aws).
@@ -83,7 +88,8 @@ You can access these metadata fields using [function interpolation](/docs/config
 		FieldSpecs: append(
 			append(docs.FieldSpecs{
 				docs.FieldCommon("bucket", "The bucket to consume from. If the field `sqs.url` is specified this field is optional."),
-				docs.FieldCommon("prefix", "An optional path prefix, if set only objects with the prefix are consumed when walking a bucket."),
+				docs.FieldCommon("prefix", "An optional path prefix, if set only objects with the prefix are consumed, either when walking a bucket or from SQS notifications."),
+				docs.FieldCommon("suffix", "An optional path suffix, if set only objects with the suffix are consumed, either when walking a bucket or from SQS notifications.", ".json.gz", ".csv").AtVersion("3.47.0"),
 			}, sess.FieldSpecs()...),
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs for downloading keys, which is often required when connecting to custom endpoints."),
 			docs.FieldAdvanced("delete_objects", "Whether to delete downloaded objects from the bucket once they are processed."),
@@ -101,6 +107,10 @@ You can access these metadata fields using [function interpolation](/docs/config
 				),
 				docs.FieldAdvanced("max_messages", "The maximum number of SQS messages to consume from each request."),
 			),
+			shard.FieldSpec(
+				"the objects found when walking a bucket",
+				"Objects are assigned as each page of the bucket is listed, and therefore objects of a member that leaves the group before consuming them are not reassigned to the remaining members, and are only consumed once the bucket is walked again, such as when the input is restarted.",
+			).AtVersion("3.47.0"),
 		),
 		Categories: []Category{
 			CategoryServices,
@@ -141,9 +151,11 @@ type AWSS3Config struct {
 	Bucket             string         `json:"bucket" yaml:"bucket"`
 	Codec              string         `json:"codec" yaml:"codec"`
 	Prefix             string         `json:"prefix" yaml:"prefix"`
+	Suffix             string         `json:"suffix" yaml:"suffix"`
 	ForcePathStyleURLs bool           `json:"force_path_style_urls" yaml:"force_path_style_urls"`
 	DeleteObjects      bool           `json:"delete_objects" yaml:"delete_objects"`
 	SQS                AWSS3SQSConfig `json:"sqs" yaml:"sqs"`
+	Sharding           shard.Config   `json:"sharding" yaml:"sharding"`
 }
 
 // NewAWSS3Config creates a new AWSS3Config with default values.
@@ -152,13 +164,21 @@ func NewAWSS3Config() AWSS3Config {
 		Config:             sess.NewConfig(),
 		Bucket:             "",
 		Prefix:             "",
+		Suffix:             "",
 		Codec:              "all-bytes",
 		ForcePathStyleURLs: false,
 		DeleteObjects:      false,
 		SQS:                NewAWSS3SQSConfig(),
+		Sharding:           shard.NewConfig(),
 	}
 }
 
+// keyMatches returns whether an object key matches the configured prefix and
+// suffix filters.
+func (c AWSS3Config) keyMatches(key string) bool {
+	return strings.HasPrefix(key, c.Prefix) && strings.HasSuffix(key, c.Suffix)
+}
+
 //------------------------------------------------------------------------------
 
 type s3ObjectTarget struct {
@@ -214,6 +234,7 @@ type staticTargetReader struct {
 	pending    []*s3ObjectTarget
 	s3         *s3.S3
 	conf       AWSS3Config
+	shards     *shard.Coordinator
 	startAfter *string
 }
 
@@ -222,53 +243,67 @@ func newStaticTargetReader(
 	conf AWSS3Config,
 	log log.Modular,
 	s3Client *s3.S3,
+	shards *shard.Coordinator,
 ) (*staticTargetReader, error) {
+	staticKeys := staticTargetReader{
+		s3:     s3Client,
+		conf:   conf,
+		shards: shards,
+	}
+	if err := staticKeys.listPage(ctx); err != nil {
+		return nil, err
+	}
+	return &staticKeys, nil
+}
+
+// listPage lists the next page of objects, adding those that are assigned to
+// this instance to the pending targets. Once all pages have been listed
+// startAfter is nil.
+func (s *staticTargetReader) listPage(ctx context.Context) error {
+	if s.shards != nil {
+		// Waits for membership of the group to settle before the first page,
+		// and fails whilst membership has lapsed rather than skipping keys
+		// that are assigned to this instance.
+		if err := s.shards.Sync(ctx); err != nil {
+			return err
+		}
+	}
 	listInput := &s3.ListObjectsV2Input{
-		Bucket:  aws.String(conf.Bucket),
-		MaxKeys: aws.Int64(100),
+		Bucket:     aws.String(s.conf.Bucket),
+		MaxKeys:    aws.Int64(100),
+		StartAfter: s.startAfter,
 	}
-	if len(conf.Prefix) > 0 {
-		listInput.Prefix = aws.String(conf.Prefix)
+	if len(s.conf.Prefix) > 0 {
+		listInput.Prefix = aws.String(s.conf.Prefix)
 	}
-	output, err := s3Client.ListObjectsV2WithContext(ctx, listInput)
+	output, err := s.s3.ListObjectsV2WithContext(ctx, listInput)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list objects: %v", err)
-	}
-	staticKeys := staticTargetReader{
-		s3:   s3Client,
-		conf: conf,
+		return fmt.Errorf("failed to list objects: %v", err)
 	}
 	for _, obj := range output.Contents {
-		ackFn := deleteS3ObjectAckFn(s3Client, conf.Bucket, *obj.Key, conf.DeleteObjects, nil)
-		staticKeys.pending = append(staticKeys.pending, newS3ObjectTarget(*obj.Key, conf.Bucket, time.Time{}, ackFn))
+		if !s.conf.keyMatches(*obj.Key) {
+			continue
+		}
+		if s.shards != nil && !s.shards.Owns(*obj.Key) {
+			continue
+		}
+		ackFn := deleteS3ObjectAckFn(s.s3, s.conf.Bucket, *obj.Key, s.conf.DeleteObjects, nil)
+		s.pending = append(s.pending, newS3ObjectTarget(*obj.Key, s.conf.Bucket, time.Time{}, ackFn))
 	}
+	s.startAfter = nil
 	if len(output.Contents) > 0 {
-		staticKeys.startAfter = output.Contents[len(output.Contents)-1].Key
+		s.startAfter = output.Contents[len(output.Contents)-1].Key
 	}
-	return &staticKeys, nil
+	return nil
 }
 
 func (s *staticTargetReader) Pop(ctx context.Context) (*s3ObjectTarget, error) {
-	if len(s.pending) == 0 && s.startAfter != nil {
+	// Pages might contain no objects assigned to this instance, in which case
+	// we keep listing until either one is found or the bucket is exhausted.
+	for len(s.pending) == 0 && s.startAfter != nil {
 		s.pending = nil
-		listInput := &s3.ListObjectsV2Input{
-			Bucket:     aws.String(s.conf.Bucket),
-			MaxKeys:    aws.Int64(100),
-			StartAfter: s.startAfter,
-		}
-		if len(s.conf.Prefix) > 0 {
-			listInput.Prefix = aws.String(s.conf.Prefix)
-		}
-		output, err := s.s3.ListObjectsV2WithContext(ctx, listInput)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list objects: %v", err)
-		}
-		for _, obj := range output.Contents {
-			ackFn := deleteS3ObjectAckFn(s.s3, s.conf.Bucket, *obj.Key, s.conf.DeleteObjects, nil)
-			s.pending = append(s.pending, newS3ObjectTarget(*obj.Key, s.conf.Bucket, time.Time{}, ackFn))
-		}
-		if len(output.Contents) > 0 {
-			s.startAfter = output.Contents[len(output.Contents)-1].Key
+		if err := s.listPage(ctx); err != nil {
+			return nil, err
 		}
 	}
 	if len(s.pending) == 0 {
@@ -466,6 +501,22 @@ func (s *sqsTargetReader) readSQSEvents(ctx context.Context) ([]*s3ObjectTarget,
 			continue
 		}
 
+		matched := objects[:0]
+		for _, object := range objects {
+			if s.conf.keyMatches(object.key) {
+				matched = append(matched, object)
+			}
+		}
+		if objects = matched; len(objects) == 0 {
+			// None of the objects are wanted, so the notification is removed
+			// from the queue rather than being redelivered.
+			s.log.Debugln("Discarding SQS message as no target keys match the prefix and suffix filters")
+			if err := s.ackSQSMessage(ctx, sqsMsg); err != nil {
+				s.log.Errorf("Failed to delete SQS message: %v\n", err)
+			}
+			continue
+		}
+
 		pendingAcks := int32(len(objects))
 		var nackOnce sync.Once
 		for _, object := range objects {
@@ -552,6 +603,7 @@ type awsS3 struct {
 	session *session.Session
 	s3      *s3.S3
 	sqs     *sqs.SQS
+	shards  *shard.Coordinator
 
 	gracePeriod time.Duration
 
@@ -572,14 +624,15 @@ type s3PendingObject struct {
 // NewAmazonS3 creates a new Amazon S3 bucket reader.Type.
 func newAmazonS3(
 	conf AWSS3Config,
+	mgr types.Manager,
 	log log.Modular,
 	stats metrics.Type,
 ) (*awsS3, error) {
 	if conf.Bucket == "" && conf.SQS.URL == "" {
 		return nil, errors.New("either a bucket or an sqs.url must be specified")
 	}
-	if conf.Prefix != "" && conf.SQS.URL != "" {
-		return nil, errors.New("cannot specify both a prefix and sqs.url")
+	if conf.Sharding.Enabled && conf.SQS.URL != "" {
+		return nil, errors.New("cannot enable sharding when consuming from sqs.url")
 	}
 	s := &awsS3{
 		conf:  conf,
@@ -595,6 +648,11 @@ func newAmazonS3(
 			return nil, fmt.Errorf("failed to parse grace period: %w", err)
 		}
 	}
+	if conf.Sharding.Enabled {
+		if s.shards, err = shard.NewCoordinator(conf.Sharding, mgr, log, stats); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
@@ -602,7 +660,7 @@ func (a *awsS3) getTargetReader(ctx context.Context) (s3ObjectTargetReader, erro
 	if a.sqs != nil {
 		return newSQSTargetReader(a.conf, a.log, a.s3, a.sqs), nil
 	}
-	return newStaticTargetReader(ctx, a.conf, a.log, a.s3)
+	return newStaticTargetReader(ctx, a.conf, a.log, a.s3, a.shards)
 }
 
 // ConnectWithContext attempts to establish a connection to the target S3 bucket
@@ -773,6 +831,11 @@ func (a *awsS3) CloseAsync() {
 			a.object.scanner.Close(context.Background())
 			a.object = nil
 		}
+		if a.shards != nil {
+			if err := a.shards.Close(context.Background()); err != nil {
+				a.log.Errorf("Failed to close sharding coordinator: %v\n", err)
+			}
+		}
 		a.objectMut.Unlock()
 	}()
 }
@@ -0,0 +1,49 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSS3KeyMatches(t *testing.T) {
+	conf := NewAWSS3Config()
+	assert.True(t, conf.keyMatches("foo/bar.json"))
+
+	conf.Prefix = "foo/"
+	conf.Suffix = ".json.gz"
+	assert.True(t, conf.keyMatches("foo/bar.json.gz"))
+	assert.False(t, conf.keyMatches("foo/bar.json"))
+	assert.False(t, conf.keyMatches("baz/bar.json.gz"))
+}
+
+func TestAWSS3SQSParseObjectPaths(t *testing.T) {
+	conf := NewAWSS3Config()
+	conf.SQS.URL = "http://localhost:4566/000000000000/foo"
+	conf.Prefix = "logs/"
+	conf.Suffix = ".gz"
+
+	_, err := newAmazonS3(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	r := newSQSTargetReader(conf, log.Noop(), nil, nil)
+
+	body := `{"Records":[
+	{"s3":{"bucket":{"name":"foo"},"object":{"key":"logs/a+b.jsonl.gz"}}},
+	{"s3":{"bucket":{"name":"bar"},"object":{"key":"logs/c.txt"}}}
+]}`
+	objects, err := r.parseObjectPaths(&body)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+
+	assert.Equal(t, "logs/a b.jsonl.gz", objects[0].key)
+	assert.Equal(t, "foo", objects[0].bucket)
+	assert.True(t, conf.keyMatches(objects[0].key))
+
+	assert.Equal(t, "logs/c.txt", objects[1].key)
+	assert.Equal(t, "bar", objects[1].bucket)
+	assert.False(t, conf.keyMatches(objects[1].key))
+}
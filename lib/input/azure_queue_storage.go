@@ -4,7 +4,9 @@ package input
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Azure/azure-storage-queue-go/azqueue"
@@ -13,6 +15,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -22,7 +25,11 @@ type azureQueueStorage struct {
 	conf AzureQueueStorageConfig
 
 	queueURL                 *azqueue.QueueURL
+	poisonQueueURL           *azqueue.QueueURL
 	dequeueVisibilityTimeout time.Duration
+	nackVisibilityTimeout    *time.Duration
+
+	mPoisoned metrics.StatCounter
 
 	log   log.Modular
 	stats metrics.Type
@@ -41,6 +48,17 @@ func newAzureQueueStorage(conf AzureQueueStorageConfig, log log.Modular, stats m
 		log:      log,
 		stats:    stats,
 		queueURL: &queueURL,
+
+		mPoisoned: stats.GetCounter("poisoned"),
+	}
+
+	if conf.MaxDequeueCount > 0 {
+		poisonName := conf.PoisonQueueName
+		if poisonName == "" {
+			poisonName = conf.QueueName + "-poison"
+		}
+		poisonURL := serviceURL.NewQueueURL(poisonName)
+		a.poisonQueueURL = &poisonURL
 	}
 
 	if len(conf.DequeueVisibilityTimeout) > 0 {
@@ -50,6 +68,14 @@ func newAzureQueueStorage(conf AzureQueueStorageConfig, log log.Modular, stats m
 		}
 	}
 
+	if len(conf.NackVisibilityTimeout) > 0 {
+		tout, err := time.ParseDuration(conf.NackVisibilityTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse nack visibility timeout duration string: %w", err)
+		}
+		a.nackVisibilityTimeout = &tout
+	}
+
 	return a, nil
 }
 
@@ -77,23 +103,58 @@ func (a *azureQueueStorage) ReadWithContext(ctx context.Context) (msg types.Mess
 		props, _ := a.queueURL.GetProperties(ctx)
 		metadata := props.NewMetadata()
 		msg := message.New(nil)
-		dqm := make([]*azqueue.DequeuedMessage, n)
+		dqm := make([]*azqueue.DequeuedMessage, 0, n)
 		for i := int32(0); i < n; i++ {
 			queueMsg := dequeue.Message(i)
-			part := message.NewPart([]byte(queueMsg.Text))
+			if a.conf.MaxDequeueCount > 0 && queueMsg.DequeueCount > a.conf.MaxDequeueCount {
+				if err := a.poison(ctx, messageURL, queueMsg); err != nil {
+					a.log.Errorf("Failed to move message '%v' to poison queue: %v\n", queueMsg.ID, err)
+				}
+				continue
+			}
+			content := []byte(queueMsg.Text)
+			var decodeErr error
+			if a.conf.Base64Decode {
+				if decoded, derr := base64.StdEncoding.DecodeString(queueMsg.Text); derr != nil {
+					a.log.Errorf("Failed to base64 decode message '%v': %v\n", queueMsg.ID, derr)
+					decodeErr = fmt.Errorf("failed to base64 decode message: %w", derr)
+				} else {
+					content = decoded
+				}
+			}
+			part := message.NewPart(content)
+			if decodeErr != nil {
+				processor.FlagErr(part, decodeErr)
+			}
 			meta := part.Metadata()
 			meta.Set("queue_storage_insertion_time", queueMsg.InsertionTime.Format(time.RFC3339))
+			meta.Set("queue_storage_message_id", queueMsg.ID.String())
+			meta.Set("queue_storage_dequeue_count", strconv.FormatInt(queueMsg.DequeueCount, 10))
 			for k, v := range metadata {
 				meta.Set(k, v)
 			}
 			msg.Append(part)
-			dqm[i] = queueMsg
+			dqm = append(dqm, queueMsg)
+		}
+		if len(dqm) == 0 {
+			return nil, nil, nil
 		}
 		return msg, func(ctx context.Context, res types.Response) error {
-			for i := int32(0); i < n; i++ {
-				msgIDURL := messageURL.NewMessageIDURL(dqm[i].ID)
-				_, err = msgIDURL.Delete(ctx, dqm[i].PopReceipt)
-				if err != nil {
+			if res.Error() != nil {
+				if a.nackVisibilityTimeout == nil {
+					return nil
+				}
+				for _, m := range dqm {
+					msgIDURL := messageURL.NewMessageIDURL(m.ID)
+					if _, err := msgIDURL.Update(ctx, m.PopReceipt, *a.nackVisibilityTimeout, m.Text); err != nil {
+						return fmt.Errorf("error updating message visibility: %v", err)
+					}
+				}
+				return nil
+			}
+			for _, m := range dqm {
+				msgIDURL := messageURL.NewMessageIDURL(m.ID)
+				if _, err := msgIDURL.Delete(ctx, m.PopReceipt); err != nil {
 					return fmt.Errorf("error deleting message: %v", err)
 				}
 			}
@@ -103,6 +164,29 @@ func (a *azureQueueStorage) ReadWithContext(ctx context.Context) (msg types.Mess
 	return nil, nil, nil
 }
 
+// poison moves a message that has exceeded the maximum dequeue count into the
+// poison queue and removes it from the source queue.
+func (a *azureQueueStorage) poison(ctx context.Context, messageURL azqueue.MessagesURL, m *azqueue.DequeuedMessage) error {
+	poisonMsgURL := a.poisonQueueURL.NewMessagesURL()
+	if _, err := poisonMsgURL.Enqueue(ctx, m.Text, 0, 0); err != nil {
+		cerr, ok := err.(azqueue.StorageError)
+		if !ok || cerr.ServiceCode() != azqueue.ServiceCodeQueueNotFound {
+			return err
+		}
+		if _, err = a.poisonQueueURL.Create(ctx, azqueue.Metadata{}); err != nil {
+			return fmt.Errorf("error creating poison queue: %v", err)
+		}
+		if _, err = poisonMsgURL.Enqueue(ctx, m.Text, 0, 0); err != nil {
+			return err
+		}
+	}
+	if _, err := messageURL.NewMessageIDURL(m.ID).Delete(ctx, m.PopReceipt); err != nil {
+		return fmt.Errorf("error deleting message: %v", err)
+	}
+	a.mPoisoned.Incr(1)
+	return nil
+}
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *azureQueueStorage) CloseAsync() {
 }
@@ -27,9 +27,15 @@ This input adds the following metadata fields to each message:
 
 ` + "```" + `
 - queue_storage_insertion_time
+- queue_storage_message_id
+- queue_storage_dequeue_count
 - All user defined queue metadata
 ` + "```" + `
 
+### Poison Messages
+
+When ` + "`max_dequeue_count`" + ` is set to a value greater than zero, messages that have been dequeued more times than this limit are moved to a poison queue instead of being consumed. The poison queue defaults to the name of the source queue suffixed with ` + "`-poison`" + `, and is created if it does not yet exist.
+
 Only one authentication method is required, ` + "`storage_connection_string`" + ` or ` + "`storage_account` and `storage_access_key`" + `. If both are set then the ` + "`storage_connection_string`" + ` is given priority.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
@@ -54,6 +60,13 @@ Only one authentication method is required, ` + "`storage_connection_string`" +
 			docs.FieldAdvanced(
 				"dequeue_visibility_timeout", "The timeout duration until a dequeued message gets visible again, 30s by default",
 			).AtVersion("3.45.0"),
+			docs.FieldAdvanced(
+				"nack_visibility_timeout", "An optional duration to set as the visibility timeout of messages that are rejected downstream, allowing them to be redelivered sooner (or later) than the `dequeue_visibility_timeout`. When empty rejected messages become visible again once the dequeue visibility timeout elapses.",
+				"0s", "5m",
+			).AtVersion("3.47.0"),
+			docs.FieldAdvanced("max_dequeue_count", "The maximum number of times a message can be dequeued before it is considered a poison message and moved to the poison queue. Set to zero in order to disable poison message handling.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("poison_queue_name", "The name of the queue to move poison messages to. When empty the name of the source queue suffixed with `-poison` is used.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("base64_decode", "Whether message payloads should be base64 decoded, which is required when consuming messages written by clients that encode payloads, such as Azure Functions. Messages that fail to be decoded are passed on unchanged and flagged as errored, which allows them to be handled with [error handling patterns](/docs/configuration/error_handling).").AtVersion("3.47.0"),
 			docs.FieldAdvanced("max_in_flight", "The maximum number of unprocessed messages to fetch at a given time."),
 		},
 		Categories: []Category{
@@ -74,6 +87,10 @@ type AzureQueueStorageConfig struct {
 	StorageConnectionString  string `json:"storage_connection_string" yaml:"storage_connection_string"`
 	QueueName                string `json:"queue_name" yaml:"queue_name"`
 	DequeueVisibilityTimeout string `json:"dequeue_visibility_timeout" yaml:"dequeue_visibility_timeout"`
+	NackVisibilityTimeout    string `json:"nack_visibility_timeout" yaml:"nack_visibility_timeout"`
+	MaxDequeueCount          int64  `json:"max_dequeue_count" yaml:"max_dequeue_count"`
+	PoisonQueueName          string `json:"poison_queue_name" yaml:"poison_queue_name"`
+	Base64Decode             bool   `json:"base64_decode" yaml:"base64_decode"`
 	MaxInFlight              int32  `json:"max_in_flight" yaml:"max_in_flight"`
 }
 
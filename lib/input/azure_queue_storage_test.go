@@ -0,0 +1,216 @@
+// +build !wasm
+
+package input
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testQueueMessage struct {
+	id           string
+	text         string
+	dequeueCount int
+}
+
+// testQueueServer mocks the subset of the Azure Queue Storage API used by the
+// azure_queue_storage input, recording each request it receives.
+type testQueueServer struct {
+	mut      sync.Mutex
+	messages []testQueueMessage
+	requests []string
+}
+
+func (s *testQueueServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/devstoreaccount1/")
+	query := r.URL.Query()
+
+	req := r.Method + " " + path
+	if v := query.Get("visibilitytimeout"); v != "" && r.Method == http.MethodPut {
+		req += " visibilitytimeout=" + v
+	}
+	if r.Method != http.MethodGet {
+		s.requests = append(s.requests, req)
+	}
+
+	now := time.Now().UTC().Format(http.TimeFormat)
+	w.Header().Set("x-ms-version", "2018-03-28")
+	switch {
+	case r.Method == http.MethodGet && query.Get("comp") == "metadata":
+		w.Header().Set("x-ms-meta-foo", "bar")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet:
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0" encoding="utf-8"?><QueueMessagesList>`)
+		for _, m := range s.messages {
+			fmt.Fprintf(&body, `<QueueMessage><MessageId>%v</MessageId><InsertionTime>%v</InsertionTime><ExpirationTime>%v</ExpirationTime><PopReceipt>receipt-%v</PopReceipt><TimeNextVisible>%v</TimeNextVisible><DequeueCount>%v</DequeueCount><MessageText>%v</MessageText></QueueMessage>`,
+				m.id, now, now, m.id, now, m.dequeueCount, m.text)
+		}
+		body.WriteString(`</QueueMessagesList>`)
+		s.messages = nil
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(body.String()))
+	case r.Method == http.MethodPost:
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><QueueMessagesList><QueueMessage><MessageId>poisoned</MessageId><InsertionTime>%v</InsertionTime><ExpirationTime>%v</ExpirationTime><PopReceipt>receipt</PopReceipt><TimeNextVisible>%v</TimeNextVisible></QueueMessage></QueueMessagesList>`, now, now, now)
+	case r.Method == http.MethodPut:
+		w.Header().Set("x-ms-popreceipt", "receipt")
+		w.Header().Set("x-ms-time-next-visible", now)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (s *testQueueServer) reqs() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	reqs := s.requests
+	s.requests = nil
+	return reqs
+}
+
+func newTestAzureQueueStorage(t *testing.T, messages []testQueueMessage, fn func(*AzureQueueStorageConfig)) (*azureQueueStorage, *testQueueServer) {
+	t.Helper()
+
+	server := &testQueueServer{messages: messages}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	require.NoError(t, os.Setenv("AZURITE_QUEUE_ENDPOINT_PORT", u.Port()))
+	t.Cleanup(func() {
+		os.Unsetenv("AZURITE_QUEUE_ENDPOINT_PORT")
+	})
+
+	conf := NewAzureQueueStorageConfig()
+	conf.StorageConnectionString = "UseDevelopmentStorage=true;"
+	conf.QueueName = "foo"
+	if fn != nil {
+		fn(&conf)
+	}
+
+	a, err := newAzureQueueStorage(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, a.ConnectWithContext(context.Background()))
+	return a, server
+}
+
+func TestAzureQueueStorageAck(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []testQueueMessage{
+		{id: "a", text: "hello", dequeueCount: 1},
+		{id: "b", text: "world", dequeueCount: 1},
+	}, nil)
+
+	ctx := context.Background()
+	msg, ackFn, err := a.ReadWithContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, msg.Len())
+	assert.Equal(t, "hello", string(msg.Get(0).Get()))
+	assert.Equal(t, "world", string(msg.Get(1).Get()))
+	assert.Equal(t, "a", msg.Get(0).Metadata().Get("queue_storage_message_id"))
+	assert.Equal(t, "1", msg.Get(0).Metadata().Get("queue_storage_dequeue_count"))
+	assert.Equal(t, "bar", msg.Get(0).Metadata().Get("foo"))
+
+	require.NoError(t, ackFn(ctx, response.NewAck()))
+	assert.Equal(t, []string{
+		"DELETE foo/messages/a",
+		"DELETE foo/messages/b",
+	}, server.reqs())
+}
+
+func TestAzureQueueStorageNack(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []testQueueMessage{
+		{id: "a", text: "hello", dequeueCount: 1},
+	}, nil)
+
+	ctx := context.Background()
+	_, ackFn, err := a.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	// Without a nack visibility timeout rejected messages are left to become
+	// visible again once their dequeue visibility timeout elapses.
+	require.NoError(t, ackFn(ctx, response.NewError(errors.New("nope"))))
+	assert.Empty(t, server.reqs())
+
+	a, server = newTestAzureQueueStorage(t, []testQueueMessage{
+		{id: "a", text: "hello", dequeueCount: 1},
+	}, func(c *AzureQueueStorageConfig) {
+		c.NackVisibilityTimeout = "5s"
+	})
+
+	_, ackFn, err = a.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, ackFn(ctx, response.NewError(errors.New("nope"))))
+	assert.Equal(t, []string{
+		"PUT foo/messages/a visibilitytimeout=5",
+	}, server.reqs())
+}
+
+func TestAzureQueueStoragePoison(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []testQueueMessage{
+		{id: "a", text: "hello", dequeueCount: 4},
+		{id: "b", text: "world", dequeueCount: 3},
+	}, func(c *AzureQueueStorageConfig) {
+		c.MaxDequeueCount = 3
+	})
+
+	ctx := context.Background()
+	msg, ackFn, err := a.ReadWithContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, msg.Len())
+	assert.Equal(t, "world", string(msg.Get(0).Get()))
+	assert.Equal(t, []string{
+		"POST foo-poison/messages",
+		"DELETE foo/messages/a",
+	}, server.reqs())
+
+	require.NoError(t, ackFn(ctx, response.NewAck()))
+	assert.Equal(t, []string{
+		"DELETE foo/messages/b",
+	}, server.reqs())
+}
+
+func TestAzureQueueStorageBase64Decode(t *testing.T) {
+	a, _ := newTestAzureQueueStorage(t, []testQueueMessage{
+		{id: "a", text: base64.StdEncoding.EncodeToString([]byte("hello")), dequeueCount: 1},
+		{id: "b", text: "not base64!", dequeueCount: 1},
+	}, func(c *AzureQueueStorageConfig) {
+		c.Base64Decode = true
+	})
+
+	msg, _, err := a.ReadWithContext(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, msg.Len())
+
+	assert.Equal(t, "hello", string(msg.Get(0).Get()))
+	assert.Equal(t, "", msg.Get(0).Metadata().Get(processor.FailFlagKey))
+
+	assert.Equal(t, "not base64!", string(msg.Get(1).Get()))
+	assert.Contains(t, msg.Get(1).Metadata().Get(processor.FailFlagKey), "failed to base64 decode message")
+}
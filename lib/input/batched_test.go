@@ -0,0 +1,70 @@
+package input
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestBatched(t *testing.T) {
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+batched:
+  child:
+    generate:
+      count: 5
+      interval: ""
+      mapping: 'root = "hello world"'
+  policy:
+    count: 2
+    period: 100ms
+`), &conf))
+
+	in, err := New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	var sizes []int
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-in.TransactionChan():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		if !open {
+			break
+		}
+		sizes = append(sizes, ts.Payload.Len())
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+
+	in.CloseAsync()
+	require.NoError(t, in.WaitForClose(time.Second*5))
+}
+
+func TestBatchedBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBatched
+
+	_, err := New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.Contains(t, err.Error(), "cannot create a batched input without a child")
+
+	child := NewConfig()
+	conf.Batched.Child = &child
+	_, err = New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	assert.Contains(t, err.Error(), "a batching policy must be specified")
+}
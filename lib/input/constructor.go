@@ -202,45 +202,64 @@ const (
 	TypeAMQP              = "amqp"
 	TypeAMQP09            = "amqp_0_9"
 	TypeAMQP1             = "amqp_1"
+	TypeArangoDB          = "arangodb"
 	TypeAWSKinesis        = "aws_kinesis"
 	TypeAWSS3             = "aws_s3"
 	TypeAWSSQS            = "aws_sqs"
 	TypeAzureBlobStorage  = "azure_blob_storage"
 	TypeAzureQueueStorage = "azure_queue_storage"
+	TypeBatched           = "batched"
 	TypeBloblang          = "bloblang"
 	TypeBroker            = "broker"
+	TypeCouchbaseDCP      = "couchbase_dcp"
 	TypeCSVFile           = "csv"
+	TypeDockerLogs        = "docker_logs"
 	TypeDynamic           = "dynamic"
 	TypeFile              = "file"
 	TypeFiles             = "files"
+	TypeFlowCollector     = "flow_collector"
+	TypeFIX               = "fix"
 	TypeGCPCloudStorage   = "gcp_cloud_storage"
 	TypeGCPPubSub         = "gcp_pubsub"
 	TypeGenerate          = "generate"
+	TypeGitWebhook        = "git_webhook"
 	TypeHDFS              = "hdfs"
 	TypeHTTPClient        = "http_client"
 	TypeHTTPServer        = "http_server"
+	TypeHTTPSSE           = "http_sse"
 	TypeInproc            = "inproc"
+	TypeJournald          = "journald"
 	TypeKafka             = "kafka"
 	TypeKafkaBalanced     = "kafka_balanced"
+	TypeKubernetesWatch   = "kubernetes_watch"
 	TypeKinesis           = "kinesis"
 	TypeKinesisBalanced   = "kinesis_balanced"
+	TypeLDAP              = "ldap"
+	TypeLeaderElection    = "leader_election"
 	TypeMQTT              = "mqtt"
 	TypeNanomsg           = "nanomsg"
 	TypeNATS              = "nats"
 	TypeNATSJetStream     = "nats_jetstream"
 	TypeNATSStream        = "nats_stream"
 	TypeNSQ               = "nsq"
+	TypeObjectArchive     = "object_archive"
+	TypePostgresCDC       = "postgres_cdc"
 	TypePulsar            = "pulsar"
 	TypeReadUntil         = "read_until"
 	TypeRedisList         = "redis_list"
 	TypeRedisPubSub       = "redis_pubsub"
 	TypeRedisStreams      = "redis_streams"
+	TypeReplay            = "replay"
+	TypeReplayFile        = "replay_file"
 	TypeResource          = "resource"
 	TypeS3                = "s3"
 	TypeSequence          = "sequence"
 	TypeSFTP              = "sftp"
+	TypeSNMP              = "snmp"
+	TypeSNMPTrap          = "snmp_trap"
 	TypeSocket            = "socket"
 	TypeSocketServer      = "socket_server"
+	TypeSQLQuery          = "sql_query"
 	TypeSQS               = "sqs"
 	TypeSTDIN             = "stdin"
 	TypeSubprocess        = "subprocess"
@@ -249,6 +268,7 @@ const (
 	TypeUDPServer         = "udp_server"
 	TypeWebsocket         = "websocket"
 	TypeZMQ4              = "zmq4"
+	TypeZMQ4N             = "zmq4n"
 )
 
 //------------------------------------------------------------------------------
@@ -260,46 +280,65 @@ type Config struct {
 	AMQP              reader.AMQPConfig            `json:"amqp" yaml:"amqp"`
 	AMQP09            reader.AMQP09Config          `json:"amqp_0_9" yaml:"amqp_0_9"`
 	AMQP1             reader.AMQP1Config           `json:"amqp_1" yaml:"amqp_1"`
+	ArangoDB          ArangoDBConfig               `json:"arangodb" yaml:"arangodb"`
 	AWSKinesis        AWSKinesisConfig             `json:"aws_kinesis" yaml:"aws_kinesis"`
 	AWSS3             AWSS3Config                  `json:"aws_s3" yaml:"aws_s3"`
 	AWSSQS            AWSSQSConfig                 `json:"aws_sqs" yaml:"aws_sqs"`
 	AzureBlobStorage  AzureBlobStorageConfig       `json:"azure_blob_storage" yaml:"azure_blob_storage"`
 	AzureQueueStorage AzureQueueStorageConfig      `json:"azure_queue_storage" yaml:"azure_queue_storage"`
+	Batched           BatchedConfig                `json:"batched" yaml:"batched"`
 	Bloblang          BloblangConfig               `json:"bloblang" yaml:"bloblang"`
 	Broker            BrokerConfig                 `json:"broker" yaml:"broker"`
+	CouchbaseDCP      CouchbaseDCPConfig           `json:"couchbase_dcp" yaml:"couchbase_dcp"`
 	CSVFile           CSVFileConfig                `json:"csv" yaml:"csv"`
+	DockerLogs        DockerLogsConfig             `json:"docker_logs" yaml:"docker_logs"`
 	Dynamic           DynamicConfig                `json:"dynamic" yaml:"dynamic"`
 	File              FileConfig                   `json:"file" yaml:"file"`
 	Files             reader.FilesConfig           `json:"files" yaml:"files"`
+	FlowCollector     FlowCollectorConfig          `json:"flow_collector" yaml:"flow_collector"`
+	FIX               FIXConfig                    `json:"fix" yaml:"fix"`
 	GCPCloudStorage   GCPCloudStorageConfig        `json:"gcp_cloud_storage" yaml:"gcp_cloud_storage"`
 	GCPPubSub         reader.GCPPubSubConfig       `json:"gcp_pubsub" yaml:"gcp_pubsub"`
 	Generate          BloblangConfig               `json:"generate" yaml:"generate"`
+	GitWebhook        GitWebhookConfig             `json:"git_webhook" yaml:"git_webhook"`
 	HDFS              reader.HDFSConfig            `json:"hdfs" yaml:"hdfs"`
 	HTTPClient        HTTPClientConfig             `json:"http_client" yaml:"http_client"`
 	HTTPServer        HTTPServerConfig             `json:"http_server" yaml:"http_server"`
+	HTTPSSE           HTTPSSEConfig                `json:"http_sse" yaml:"http_sse"`
 	Inproc            InprocConfig                 `json:"inproc" yaml:"inproc"`
+	Journald          JournaldConfig               `json:"journald" yaml:"journald"`
 	Kafka             reader.KafkaConfig           `json:"kafka" yaml:"kafka"`
 	KafkaBalanced     reader.KafkaBalancedConfig   `json:"kafka_balanced" yaml:"kafka_balanced"`
+	KubernetesWatch   KubernetesWatchConfig        `json:"kubernetes_watch" yaml:"kubernetes_watch"`
 	Kinesis           reader.KinesisConfig         `json:"kinesis" yaml:"kinesis"`
 	KinesisBalanced   reader.KinesisBalancedConfig `json:"kinesis_balanced" yaml:"kinesis_balanced"`
+	LDAP              LDAPConfig                   `json:"ldap" yaml:"ldap"`
+	LeaderElection    LeaderElectionConfig         `json:"leader_election" yaml:"leader_election"`
 	MQTT              reader.MQTTConfig            `json:"mqtt" yaml:"mqtt"`
 	Nanomsg           reader.ScaleProtoConfig      `json:"nanomsg" yaml:"nanomsg"`
 	NATS              reader.NATSConfig            `json:"nats" yaml:"nats"`
 	NATSJetStream     NATSJetStreamConfig          `json:"nats_jetstream" yaml:"nats_jetstream"`
 	NATSStream        reader.NATSStreamConfig      `json:"nats_stream" yaml:"nats_stream"`
 	NSQ               reader.NSQConfig             `json:"nsq" yaml:"nsq"`
+	ObjectArchive     ObjectArchiveConfig          `json:"object_archive" yaml:"object_archive"`
 	Plugin            interface{}                  `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	PostgresCDC       PostgresCDCConfig            `json:"postgres_cdc" yaml:"postgres_cdc"`
 	Pulsar            PulsarConfig                 `json:"pulsar" yaml:"pulsar"`
 	ReadUntil         ReadUntilConfig              `json:"read_until" yaml:"read_until"`
 	RedisList         reader.RedisListConfig       `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub       reader.RedisPubSubConfig     `json:"redis_pubsub" yaml:"redis_pubsub"`
 	RedisStreams      reader.RedisStreamsConfig    `json:"redis_streams" yaml:"redis_streams"`
+	Replay            ReplayConfig                 `json:"replay" yaml:"replay"`
+	ReplayFile        ReplayFileConfig             `json:"replay_file" yaml:"replay_file"`
 	Resource          string                       `json:"resource" yaml:"resource"`
 	S3                reader.AmazonS3Config        `json:"s3" yaml:"s3"`
 	Sequence          SequenceConfig               `json:"sequence" yaml:"sequence"`
 	SFTP              SFTPConfig                   `json:"sftp" yaml:"sftp"`
+	SNMP              SNMPConfig                   `json:"snmp" yaml:"snmp"`
+	SNMPTrap          SNMPTrapConfig               `json:"snmp_trap" yaml:"snmp_trap"`
 	Socket            SocketConfig                 `json:"socket" yaml:"socket"`
 	SocketServer      SocketServerConfig           `json:"socket_server" yaml:"socket_server"`
+	SQLQuery          SQLQueryConfig               `json:"sql_query" yaml:"sql_query"`
 	SQS               reader.AmazonSQSConfig       `json:"sqs" yaml:"sqs"`
 	STDIN             STDINConfig                  `json:"stdin" yaml:"stdin"`
 	Subprocess        SubprocessConfig             `json:"subprocess" yaml:"subprocess"`
@@ -308,6 +347,7 @@ type Config struct {
 	UDPServer         UDPServerConfig              `json:"udp_server" yaml:"udp_server"`
 	Websocket         reader.WebsocketConfig       `json:"websocket" yaml:"websocket"`
 	ZMQ4              *reader.ZMQ4Config           `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
+	ZMQ4N             ZMQ4NConfig                  `json:"zmq4n" yaml:"zmq4n"`
 	Processors        []processor.Config           `json:"processors" yaml:"processors"`
 }
 
@@ -319,46 +359,65 @@ func NewConfig() Config {
 		AMQP:              reader.NewAMQPConfig(),
 		AMQP09:            reader.NewAMQP09Config(),
 		AMQP1:             reader.NewAMQP1Config(),
+		ArangoDB:          NewArangoDBConfig(),
 		AWSKinesis:        NewAWSKinesisConfig(),
 		AWSS3:             NewAWSS3Config(),
 		AWSSQS:            NewAWSSQSConfig(),
 		AzureBlobStorage:  NewAzureBlobStorageConfig(),
 		AzureQueueStorage: NewAzureQueueStorageConfig(),
+		Batched:           NewBatchedConfig(),
 		Bloblang:          NewBloblangConfig(),
 		Broker:            NewBrokerConfig(),
+		CouchbaseDCP:      NewCouchbaseDCPConfig(),
 		CSVFile:           NewCSVFileConfig(),
+		DockerLogs:        NewDockerLogsConfig(),
 		Dynamic:           NewDynamicConfig(),
 		File:              NewFileConfig(),
 		Files:             reader.NewFilesConfig(),
+		FlowCollector:     NewFlowCollectorConfig(),
+		FIX:               NewFIXConfig(),
 		GCPCloudStorage:   NewGCPCloudStorageConfig(),
 		GCPPubSub:         reader.NewGCPPubSubConfig(),
 		Generate:          NewBloblangConfig(),
+		GitWebhook:        NewGitWebhookConfig(),
 		HDFS:              reader.NewHDFSConfig(),
 		HTTPClient:        NewHTTPClientConfig(),
 		HTTPServer:        NewHTTPServerConfig(),
+		HTTPSSE:           NewHTTPSSEConfig(),
 		Inproc:            NewInprocConfig(),
+		Journald:          NewJournaldConfig(),
 		Kafka:             reader.NewKafkaConfig(),
 		KafkaBalanced:     reader.NewKafkaBalancedConfig(),
+		KubernetesWatch:   NewKubernetesWatchConfig(),
 		Kinesis:           reader.NewKinesisConfig(),
 		KinesisBalanced:   reader.NewKinesisBalancedConfig(),
+		LDAP:              NewLDAPConfig(),
+		LeaderElection:    NewLeaderElectionConfig(),
 		MQTT:              reader.NewMQTTConfig(),
 		Nanomsg:           reader.NewScaleProtoConfig(),
 		NATS:              reader.NewNATSConfig(),
 		NATSJetStream:     NewNATSJetStreamConfig(),
 		NATSStream:        reader.NewNATSStreamConfig(),
 		NSQ:               reader.NewNSQConfig(),
+		ObjectArchive:     NewObjectArchiveConfig(),
 		Plugin:            nil,
+		PostgresCDC:       NewPostgresCDCConfig(),
 		Pulsar:            NewPulsarConfig(),
 		ReadUntil:         NewReadUntilConfig(),
 		RedisList:         reader.NewRedisListConfig(),
 		RedisPubSub:       reader.NewRedisPubSubConfig(),
 		RedisStreams:      reader.NewRedisStreamsConfig(),
+		Replay:            NewReplayConfig(),
+		ReplayFile:        NewReplayFileConfig(),
 		Resource:          "",
 		S3:                reader.NewAmazonS3Config(),
 		Sequence:          NewSequenceConfig(),
 		SFTP:              NewSFTPConfig(),
+		SNMP:              NewSNMPConfig(),
+		SNMPTrap:          NewSNMPTrapConfig(),
 		Socket:            NewSocketConfig(),
 		SocketServer:      NewSocketServerConfig(),
+		SQLQuery:          NewSQLQueryConfig(),
 		SQS:               reader.NewAmazonSQSConfig(),
 		STDIN:             NewSTDINConfig(),
 		Subprocess:        NewSubprocessConfig(),
@@ -367,6 +426,7 @@ func NewConfig() Config {
 		UDPServer:         NewUDPServerConfig(),
 		Websocket:         reader.NewWebsocketConfig(),
 		ZMQ4:              reader.NewZMQ4Config(),
+		ZMQ4N:             NewZMQ4NConfig(),
 		Processors:        []processor.Config{},
 	}
 }
@@ -0,0 +1,406 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/couchbase"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCouchbaseDCP] = TypeSpec{
+		constructor: fromSimpleConstructor(NewCouchbaseDCP),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Streams changes to the documents of a [Couchbase](https://www.couchbase.com/) bucket with the Database Change Protocol (DCP).`,
+		Description: `
+A stream is opened for every vbucket of the bucket directly from the node that hosts it, and a message is created for each mutation of a document containing the document. Deletions and expirations of documents are only consumed when ` + "`include_deletions`" + ` is ` + "`true`" + `, in which case the messages are empty.
+
+When ` + "`from`" + ` is ` + "`beginning`" + ` the current state of every document is streamed before ongoing changes, otherwise only changes made after the input connects are streamed.
+
+### Checkpoints
+
+The position of each vbucket is only advanced once a message, and all messages of the vbucket received before it, have been delivered by the pipeline. When a ` + "`checkpoint_cache`" + ` is specified the positions are periodically stored within it under ` + "`checkpoint_key`" + `, which allows the input to resume where it left off after restarts. When the history of a vbucket has diverged since its position was stored, for example after a failover, the cluster rolls the stream back to a consistent point and changes after that point are consumed again.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- couchbase_key
+- couchbase_event
+- couchbase_vbucket
+- couchbase_seqno
+- couchbase_cas
+- couchbase_expiry
+` + "```" + `
+
+The field ` + "`couchbase_event`" + ` is one of ` + "`mutation`, `deletion` or `expiration`" + `, and ` + "`couchbase_expiry`" + ` is only set for mutations of documents with an expiry.
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Replicating Changes",
+				Summary: "This example streams all documents of a bucket followed by ongoing changes, storing positions within a Redis cache so that restarts resume where they left off.",
+				Config: `
+input:
+  couchbase_dcp:
+    url: couchbase://localhost
+    bucket: orders
+    username: benthos
+    password: ${CB_PASSWORD}
+    connection_name: orders_replicator
+    from: beginning
+    include_deletions: true
+    checkpoint_cache: positions
+
+cache_resources:
+  - label: positions
+    redis:
+      url: redis://localhost:6379
+`,
+			},
+		},
+		FieldSpecs: couchbase.FieldSpecs().Add(
+			docs.FieldCommon("connection_name", "A name that identifies the stream to the cluster."),
+			docs.FieldCommon("from", "Where to begin streaming vbuckets that do not have a stored position.").HasAnnotatedOptions(
+				"beginning", "Stream the current state of every document followed by ongoing changes.",
+				"now", "Stream only changes made after connecting.",
+			),
+			docs.FieldCommon("include_deletions", "Whether to consume deletions and expirations of documents."),
+			docs.FieldCommon("checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store the positions of vbuckets within."),
+			docs.FieldAdvanced("checkpoint_key", "The key to store positions under within the `checkpoint_cache`."),
+			docs.FieldAdvanced("checkpoint_period", "The period between storing positions within the `checkpoint_cache`."),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CouchbaseDCPConfig contains configuration fields for the CouchbaseDCP input
+// type.
+type CouchbaseDCPConfig struct {
+	couchbase.Config `json:",inline" yaml:",inline"`
+	ConnectionName   string `json:"connection_name" yaml:"connection_name"`
+	From             string `json:"from" yaml:"from"`
+	IncludeDeletions bool   `json:"include_deletions" yaml:"include_deletions"`
+	CheckpointCache  string `json:"checkpoint_cache" yaml:"checkpoint_cache"`
+	CheckpointKey    string `json:"checkpoint_key" yaml:"checkpoint_key"`
+	CheckpointPeriod string `json:"checkpoint_period" yaml:"checkpoint_period"`
+}
+
+// NewCouchbaseDCPConfig creates a new CouchbaseDCPConfig with default values.
+func NewCouchbaseDCPConfig() CouchbaseDCPConfig {
+	return CouchbaseDCPConfig{
+		Config:           couchbase.NewConfig(),
+		ConnectionName:   "benthos",
+		From:             "beginning",
+		IncludeDeletions: false,
+		CheckpointCache:  "",
+		CheckpointKey:    "couchbase_dcp_positions",
+		CheckpointPeriod: "5s",
+	}
+}
+
+// NewCouchbaseDCP creates a new CouchbaseDCP input type.
+func NewCouchbaseDCP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	client, err := couchbase.NewClient(conf.CouchbaseDCP.Config)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := newCouchbaseDCPReader(conf.CouchbaseDCP, func(ctx context.Context, opts couchbase.DCPOptions) (couchbaseDCPStream, error) {
+		return client.OpenDCP(ctx, opts)
+	}, mgr, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeCouchbaseDCP, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type couchbaseDCPStream interface {
+	Next(ctx context.Context) (*couchbase.DCPEvent, error)
+	Close() error
+}
+
+type couchbaseDCPOpenFn func(ctx context.Context, opts couchbase.DCPOptions) (couchbaseDCPStream, error)
+
+type couchbaseDCPReader struct {
+	conf   CouchbaseDCPConfig
+	open   couchbaseDCPOpenFn
+	period time.Duration
+	mgr    types.Manager
+	log    log.Modular
+
+	streamMut sync.Mutex
+	stream    couchbaseDCPStream
+
+	cpMut     sync.Mutex
+	pending   map[uint16]*checkpoint.Type
+	positions map[uint16]couchbase.StreamPosition
+	dirty     bool
+	lastStore time.Time
+}
+
+func newCouchbaseDCPReader(conf CouchbaseDCPConfig, open couchbaseDCPOpenFn, mgr types.Manager, log log.Modular) (*couchbaseDCPReader, error) {
+	if conf.ConnectionName == "" {
+		return nil, errors.New("a connection_name must be specified")
+	}
+	switch conf.From {
+	case "beginning", "now":
+	default:
+		return nil, fmt.Errorf("from value not recognised: %v", conf.From)
+	}
+
+	r := &couchbaseDCPReader{
+		conf:      conf,
+		open:      open,
+		mgr:       mgr,
+		log:       log,
+		pending:   map[uint16]*checkpoint.Type{},
+		positions: map[uint16]couchbase.StreamPosition{},
+	}
+	if conf.CheckpointCache != "" {
+		if conf.CheckpointKey == "" {
+			return nil, errors.New("a checkpoint_key must be specified")
+		}
+		if err := interop.ProbeCache(context.Background(), mgr, conf.CheckpointCache); err != nil {
+			return nil, err
+		}
+		var err error
+		if r.period, err = time.ParseDuration(conf.CheckpointPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint period string: %v", err)
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loadPositions reads stored positions from the checkpoint cache, positions
+// already held in memory take precedence as they cannot be behind.
+func (r *couchbaseDCPReader) loadPositions(ctx context.Context) error {
+	if r.conf.CheckpointCache == "" {
+		return nil
+	}
+
+	var stored []byte
+	var getErr error
+	if err := interop.AccessCache(ctx, r.mgr, r.conf.CheckpointCache, func(cache types.Cache) {
+		stored, getErr = cache.Get(r.conf.CheckpointKey)
+	}); err != nil {
+		return err
+	}
+	if getErr != nil {
+		if getErr == types.ErrKeyNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to read positions: %w", getErr)
+	}
+
+	var positions map[string]couchbase.StreamPosition
+	if err := json.Unmarshal(stored, &positions); err != nil {
+		return fmt.Errorf("failed to parse positions: %w", err)
+	}
+	for k, pos := range positions {
+		vb, err := strconv.ParseUint(k, 10, 16)
+		if err != nil {
+			return fmt.Errorf("failed to parse positions: invalid vbucket %v", k)
+		}
+		if _, exists := r.positions[uint16(vb)]; !exists {
+			r.positions[uint16(vb)] = pos
+		}
+	}
+	return nil
+}
+
+// storePositions writes positions to the checkpoint cache, the checkpoint
+// mutex must be held.
+func (r *couchbaseDCPReader) storePositions(ctx context.Context) error {
+	if r.conf.CheckpointCache == "" || !r.dirty {
+		return nil
+	}
+
+	positions := make(map[string]couchbase.StreamPosition, len(r.positions))
+	for vb, pos := range r.positions {
+		positions[strconv.Itoa(int(vb))] = pos
+	}
+	b, err := json.Marshal(positions)
+	if err != nil {
+		return err
+	}
+
+	var setErr error
+	if err := interop.AccessCache(ctx, r.mgr, r.conf.CheckpointCache, func(cache types.Cache) {
+		setErr = cache.Set(r.conf.CheckpointKey, b)
+	}); err != nil {
+		return err
+	}
+	if setErr != nil {
+		return setErr
+	}
+	r.dirty = false
+	r.lastStore = time.Now()
+	return nil
+}
+
+// ConnectWithContext opens the streams of all vbuckets.
+func (r *couchbaseDCPReader) ConnectWithContext(ctx context.Context) error {
+	r.streamMut.Lock()
+	defer r.streamMut.Unlock()
+	if r.stream != nil {
+		return nil
+	}
+
+	r.cpMut.Lock()
+	err := r.loadPositions(ctx)
+	positions := make(map[uint16]couchbase.StreamPosition, len(r.positions))
+	for vb, pos := range r.positions {
+		positions[vb] = pos
+	}
+	r.pending = map[uint16]*checkpoint.Type{}
+	r.cpMut.Unlock()
+	if err != nil {
+		return err
+	}
+
+	stream, err := r.open(ctx, couchbase.DCPOptions{
+		Name:      r.conf.ConnectionName,
+		FromNow:   r.conf.From == "now",
+		Positions: positions,
+	})
+	if err != nil {
+		return err
+	}
+	r.stream = stream
+	r.log.Infof("Streaming changes from Couchbase bucket: %v\n", r.conf.Bucket)
+	return nil
+}
+
+// track registers the position of an event, returning a function that marks
+// it as delivered.
+func (r *couchbaseDCPReader) track(e *couchbase.DCPEvent) func() {
+	r.cpMut.Lock()
+	defer r.cpMut.Unlock()
+
+	cp, exists := r.pending[e.VBucket]
+	if !exists {
+		cp = checkpoint.New()
+		r.pending[e.VBucket] = cp
+	}
+	resolve := cp.Track(couchbase.StreamPosition{VBUUID: e.VBUUID, Seqno: e.Seqno}, 1)
+	vb := e.VBucket
+
+	return func() {
+		r.cpMut.Lock()
+		defer r.cpMut.Unlock()
+		// Messages of a previous stream may be acknowledged after reconnecting,
+		// in which case they must not move the position backwards.
+		if pos, ok := resolve().(couchbase.StreamPosition); ok {
+			if cur, exists := r.positions[vb]; !exists || cur.VBUUID != pos.VBUUID || cur.Seqno < pos.Seqno {
+				r.positions[vb] = pos
+				r.dirty = true
+			}
+		}
+		if time.Since(r.lastStore) >= r.period {
+			if err := r.storePositions(context.Background()); err != nil {
+				r.log.Errorf("Failed to store positions: %v\n", err)
+			}
+		}
+	}
+}
+
+// ReadWithContext attempts to read a change from the stream.
+func (r *couchbaseDCPReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	r.streamMut.Lock()
+	stream := r.stream
+	r.streamMut.Unlock()
+	if stream == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	for {
+		e, err := stream.Next(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, nil, types.ErrTimeout
+			}
+			r.log.Errorf("Stream failed: %v\n", err)
+			r.streamMut.Lock()
+			if r.stream == stream {
+				r.stream.Close()
+				r.stream = nil
+			}
+			r.streamMut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+
+		resolve := r.track(e)
+		if e.Type != couchbase.EventMutation && !r.conf.IncludeDeletions {
+			resolve()
+			continue
+		}
+
+		part := message.NewPart(e.Value)
+		meta := part.Metadata()
+		meta.Set("couchbase_key", string(e.Key))
+		meta.Set("couchbase_event", e.Type)
+		meta.Set("couchbase_vbucket", strconv.Itoa(int(e.VBucket)))
+		meta.Set("couchbase_seqno", strconv.FormatUint(e.Seqno, 10))
+		meta.Set("couchbase_cas", strconv.FormatUint(e.CAS, 10))
+		if e.Expiry > 0 {
+			meta.Set("couchbase_expiry", strconv.FormatUint(uint64(e.Expiry), 10))
+		}
+
+		msg := message.New(nil)
+		msg.Append(part)
+		return msg, func(ctx context.Context, res types.Response) error {
+			if res.Error() == nil {
+				resolve()
+			}
+			return nil
+		}, nil
+	}
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (r *couchbaseDCPReader) CloseAsync() {
+	go func() {
+		r.streamMut.Lock()
+		if r.stream != nil {
+			r.stream.Close()
+			r.stream = nil
+		}
+		r.streamMut.Unlock()
+
+		r.cpMut.Lock()
+		if err := r.storePositions(context.Background()); err != nil {
+			r.log.Errorf("Failed to store positions: %v\n", err)
+		}
+		r.cpMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (r *couchbaseDCPReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
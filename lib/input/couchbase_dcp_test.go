@@ -0,0 +1,158 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/couchbase"
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCacheMgr struct {
+	fakeProcMgr
+	caches map[string]types.Cache
+}
+
+func (f *fakeCacheMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+type fakeDCPStream struct {
+	events []*couchbase.DCPEvent
+	closed bool
+}
+
+func (f *fakeDCPStream) Next(ctx context.Context) (*couchbase.DCPEvent, error) {
+	if len(f.events) == 0 {
+		return nil, errors.New("stream for vbucket 0 ended")
+	}
+	e := f.events[0]
+	f.events = f.events[1:]
+	return e, nil
+}
+
+func (f *fakeDCPStream) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestCouchbaseDCP(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, memCache.Set("positions", []byte(`{"1":{"uuid":7,"seqno":3}}`)))
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"foo": memCache}}
+
+	var opened []couchbase.DCPOptions
+	stream := &fakeDCPStream{events: []*couchbase.DCPEvent{
+		{Type: couchbase.EventMutation, Key: []byte("a"), Value: []byte(`{"a":1}`), VBucket: 0, VBUUID: 5, Seqno: 1, CAS: 10},
+		{Type: couchbase.EventDeletion, Key: []byte("b"), VBucket: 1, VBUUID: 7, Seqno: 4, CAS: 11},
+		{Type: couchbase.EventMutation, Key: []byte("c"), Value: []byte(`{"c":1}`), VBucket: 0, VBUUID: 5, Seqno: 2, CAS: 12, Expiry: 1700000000},
+	}}
+
+	conf := NewCouchbaseDCPConfig()
+	conf.Bucket = "bucket"
+	conf.CheckpointCache = "foo"
+	conf.CheckpointKey = "positions"
+	conf.CheckpointPeriod = "0s"
+	rdr, err := newCouchbaseDCPReader(conf, func(ctx context.Context, opts couchbase.DCPOptions) (couchbaseDCPStream, error) {
+		opened = append(opened, opts)
+		return stream, nil
+	}, mgr, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+	require.Len(t, opened, 1)
+	assert.Equal(t, "benthos", opened[0].Name)
+	assert.False(t, opened[0].FromNow)
+	assert.Equal(t, map[uint16]couchbase.StreamPosition{1: {VBUUID: 7, Seqno: 3}}, opened[0].Positions)
+
+	msg, ackA, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(msg.Get(0).Get()))
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, "a", meta.Get("couchbase_key"))
+	assert.Equal(t, "mutation", meta.Get("couchbase_event"))
+	assert.Equal(t, "0", meta.Get("couchbase_vbucket"))
+	assert.Equal(t, "1", meta.Get("couchbase_seqno"))
+	assert.Equal(t, "10", meta.Get("couchbase_cas"))
+	assert.Equal(t, "", meta.Get("couchbase_expiry"))
+
+	// The deletion is skipped but still advances its vbucket.
+	msg, ackC, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "c", msg.Get(0).Metadata().Get("couchbase_key"))
+	assert.Equal(t, "1700000000", msg.Get(0).Metadata().Get("couchbase_expiry"))
+
+	// Acknowledging out of order only stores the position once all prior
+	// messages of the vbucket are acknowledged.
+	require.NoError(t, ackC(ctx, response.NewAck()))
+	stored, err := memCache.Get("positions")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"1":{"uuid":7,"seqno":4}}`, string(stored))
+
+	require.NoError(t, ackA(ctx, response.NewAck()))
+	stored, err = memCache.Get("positions")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"0":{"uuid":5,"seqno":2},"1":{"uuid":7,"seqno":4}}`, string(stored))
+
+	// A failed stream is closed and reopened from the latest positions.
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	assert.True(t, stream.closed)
+
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+	require.Len(t, opened, 2)
+	assert.Equal(t, map[uint16]couchbase.StreamPosition{
+		0: {VBUUID: 5, Seqno: 2},
+		1: {VBUUID: 7, Seqno: 4},
+	}, opened[1].Positions)
+}
+
+func TestCouchbaseDCPDeletions(t *testing.T) {
+	stream := &fakeDCPStream{events: []*couchbase.DCPEvent{
+		{Type: couchbase.EventExpiration, Key: []byte("b"), VBucket: 1, VBUUID: 7, Seqno: 4, CAS: 11},
+	}}
+
+	conf := NewCouchbaseDCPConfig()
+	conf.From = "now"
+	conf.IncludeDeletions = true
+	rdr, err := newCouchbaseDCPReader(conf, func(ctx context.Context, opts couchbase.DCPOptions) (couchbaseDCPStream, error) {
+		assert.True(t, opts.FromNow)
+		return stream, nil
+	}, nil, log.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, ack, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "", string(msg.Get(0).Get()))
+	assert.Equal(t, "expiration", msg.Get(0).Metadata().Get("couchbase_event"))
+	require.NoError(t, ack(ctx, response.NewAck()))
+}
+
+func TestCouchbaseDCPBadConfig(t *testing.T) {
+	conf := NewCouchbaseDCPConfig()
+	conf.From = "yesterday"
+	_, err := newCouchbaseDCPReader(conf, nil, nil, log.Noop())
+	require.EqualError(t, err, "from value not recognised: yesterday")
+
+	conf = NewCouchbaseDCPConfig()
+	conf.CheckpointCache = "nope"
+	_, err = newCouchbaseDCPReader(conf, nil, &fakeCacheMgr{}, log.Noop())
+	require.Error(t, err)
+}
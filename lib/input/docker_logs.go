@@ -0,0 +1,508 @@
+package input
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docker"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDockerLogs] = TypeSpec{
+		constructor: fromSimpleConstructor(NewDockerLogs),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Tails the logs of containers through the Docker Engine API.`,
+		Description: `
+The logs of all running containers that have each of the configured ` + "`labels`" + ` are tailed, and containers that start while Benthos is running are tailed as they start. Any daemon implementing the Docker Engine API can be used, such as Podman with its API service enabled.
+
+Each log line becomes a message, with the timestamp and container attached as metadata. By default only logs written after Benthos starts are read, and when the connection to the daemon is lost the logs of each container are resumed from the last line read.
+
+As logs can't be read again once they have been consumed, messages are not redelivered when they are rejected by the pipeline.
+
+### Multiline Logs
+
+Lines belonging to the same log entry, such as the lines of a stack trace, can be joined into a single message by setting ` + "`multiline.start_pattern`" + ` to a regular expression that matches the first line of each entry. Lines that don't match it are appended to the entry before them, separated by newlines. An entry is emitted once the next entry starts, once it reaches ` + "`multiline.max_lines`" + ` lines, or once no line has been appended for the ` + "`multiline.timeout`" + `. Lines of stdout and stderr are never joined together.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- docker_container_id
+- docker_container_name
+- docker_container_image
+- docker_stream
+- docker_timestamp
+- docker_label_<name>
+` + "```" + `
+
+Where ` + "`docker_stream`" + ` is either ` + "`stdout` or `stderr`" + `, and a ` + "`docker_label_`" + ` field is added for each label of the container.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Java Service Logs",
+				Summary: "This example tails the logs of containers labelled as part of the `checkout` service, joining the lines of stack traces into their log entries, and writes them to Elasticsearch with the name and image of their container.",
+				Config: `
+input:
+  docker_logs:
+    labels: [ service=checkout ]
+    multiline:
+      start_pattern: '^\d{4}-\d{2}-\d{2}'
+
+pipeline:
+  processors:
+    - bloblang: |
+        root.message = content().string()
+        root.container = meta("docker_container_name")
+        root.image = meta("docker_container_image")
+        root.stream = meta("docker_stream")
+        root."@timestamp" = meta("docker_timestamp")
+
+output:
+  elasticsearch:
+    urls: [ http://localhost:9200 ]
+    index: logs
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"host", "The address of the Docker daemon, which can be a unix socket or a TCP address.",
+				"unix:///var/run/docker.sock", "tcp://localhost:2376",
+			),
+			docs.FieldCommon(
+				"labels", "A list of labels that containers must have in order for their logs to be read, where each label is either a name or of the form `name=value`.",
+				[]string{"logging=enabled"}, []string{"com.docker.compose.project=shop", "tier=backend"},
+			).Array(),
+			docs.FieldAdvanced("start_from_oldest", "Whether to read the existing logs of containers that are running when Benthos starts, otherwise only logs written since are read."),
+			docs.FieldCommon("multiline", "Joins the lines of multiline log entries into single messages.").WithChildren(
+				docs.FieldCommon("start_pattern", "A regular expression matching the first line of each log entry. When empty each line is a message.", `^\d{4}-\d{2}-\d{2}`, `^[^\s]`),
+				docs.FieldAdvanced("max_lines", "The maximum number of lines of a log entry, after which a new entry is started."),
+				docs.FieldAdvanced("timeout", "The period of time after the last line of a log entry was received before it is emitted."),
+			),
+			btls.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DockerLogsMultilineConfig contains configuration fields for joining the
+// lines of multiline log entries.
+type DockerLogsMultilineConfig struct {
+	StartPattern string `json:"start_pattern" yaml:"start_pattern"`
+	MaxLines     int    `json:"max_lines" yaml:"max_lines"`
+	Timeout      string `json:"timeout" yaml:"timeout"`
+}
+
+// DockerLogsConfig contains configuration fields for the DockerLogs input
+// type.
+type DockerLogsConfig struct {
+	Host            string                    `json:"host" yaml:"host"`
+	Labels          []string                  `json:"labels" yaml:"labels"`
+	StartFromOldest bool                      `json:"start_from_oldest" yaml:"start_from_oldest"`
+	Multiline       DockerLogsMultilineConfig `json:"multiline" yaml:"multiline"`
+	TLS             btls.Config               `json:"tls" yaml:"tls"`
+}
+
+// NewDockerLogsConfig creates a new DockerLogsConfig with default values.
+func NewDockerLogsConfig() DockerLogsConfig {
+	return DockerLogsConfig{
+		Host:            docker.DefaultHost,
+		Labels:          []string{},
+		StartFromOldest: false,
+		Multiline: DockerLogsMultilineConfig{
+			StartPattern: "",
+			MaxLines:     500,
+			Timeout:      "1s",
+		},
+		TLS: btls.NewConfig(),
+	}
+}
+
+// NewDockerLogs creates a new DockerLogs input type.
+func NewDockerLogs(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newDockerLogsReader(conf.DockerLogs, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeDockerLogs, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type dockerLogsReader struct {
+	conf         DockerLogsConfig
+	log          log.Modular
+	tlsConf      *tls.Config
+	startPattern *regexp.Regexp
+	flushTimeout time.Duration
+
+	// Logs of containers are read from the timestamp of the last line read
+	// from them, which persists across reconnects, or otherwise from since.
+	seenMut  sync.Mutex
+	since    time.Time
+	lastSeen map[string]time.Time
+
+	mut    sync.Mutex
+	msgs   chan types.Message
+	cancel func()
+	shutC  chan struct{}
+}
+
+func newDockerLogsReader(conf DockerLogsConfig, log log.Modular) (*dockerLogsReader, error) {
+	d := &dockerLogsReader{
+		conf:     conf,
+		log:      log,
+		lastSeen: map[string]time.Time{},
+		shutC:    make(chan struct{}),
+	}
+	if !conf.StartFromOldest {
+		d.since = time.Now()
+	}
+	if conf.TLS.Enabled {
+		var err error
+		if d.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Multiline.StartPattern != "" {
+		var err error
+		if d.startPattern, err = regexp.Compile(conf.Multiline.StartPattern); err != nil {
+			return nil, fmt.Errorf("failed to compile multiline start pattern: %w", err)
+		}
+		if conf.Multiline.MaxLines <= 0 {
+			return nil, fmt.Errorf("multiline max lines must be greater than zero, got: %v", conf.Multiline.MaxLines)
+		}
+		if d.flushTimeout, err = time.ParseDuration(conf.Multiline.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse multiline timeout: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// ConnectWithContext starts tailing the logs of running containers and
+// watching for containers to start.
+func (d *dockerLogsReader) ConnectWithContext(ctx context.Context) error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	select {
+	case <-d.shutC:
+		return types.ErrTypeClosed
+	default:
+	}
+	if d.msgs != nil {
+		return nil
+	}
+
+	client, err := docker.NewClient(d.conf.Host, d.tlsConf)
+	if err != nil {
+		return err
+	}
+	if err = client.Ping(ctx); err != nil {
+		return err
+	}
+
+	// Events are subscribed to before listing containers so that none that
+	// start in between are missed.
+	sessionCtx, cancel := context.WithCancel(context.Background())
+	events, err := client.ContainerEvents(sessionCtx, d.conf.Labels, "start", "destroy")
+	if err != nil {
+		cancel()
+		return err
+	}
+	containers, err := client.ListContainers(ctx, d.conf.Labels)
+	if err != nil {
+		events.Close()
+		cancel()
+		return err
+	}
+
+	d.msgs, d.cancel = make(chan types.Message), cancel
+	go d.watch(sessionCtx, cancel, client, events, containers, d.msgs)
+
+	d.log.Infof("Tailing the logs of %v containers from docker daemon at: %v\n", len(containers), d.conf.Host)
+	return nil
+}
+
+func (d *dockerLogsReader) watch(
+	ctx context.Context,
+	cancel func(),
+	client *docker.Client,
+	events *docker.EventStream,
+	containers []docker.Container,
+	msgs chan types.Message,
+) {
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		close(msgs)
+	}()
+
+	// Containers being tailed, and whether they've restarted before their
+	// previous log stream ended, in which case it is reopened.
+	var tailMut sync.Mutex
+	tailing := map[string]bool{}
+
+	tail := func(id string) {
+		tailMut.Lock()
+		defer tailMut.Unlock()
+		if _, exists := tailing[id]; exists {
+			tailing[id] = true
+			return
+		}
+		tailing[id] = false
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := d.tail(ctx, client, id, msgs); err != nil && ctx.Err() == nil {
+					d.log.Errorf("Failed to tail logs of container %v: %v\n", id, err)
+				}
+
+				tailMut.Lock()
+				restarted := tailing[id] && ctx.Err() == nil
+				if !restarted {
+					delete(tailing, id)
+					tailMut.Unlock()
+					return
+				}
+				tailing[id] = false
+				tailMut.Unlock()
+			}
+		}()
+	}
+
+	for _, c := range containers {
+		tail(c.ID)
+	}
+
+	go func() {
+		<-ctx.Done()
+		events.Close()
+	}()
+	for {
+		ev, err := events.Next()
+		if err != nil {
+			if ctx.Err() == nil {
+				d.log.Errorf("Lost connection to docker daemon events: %v\n", err)
+			}
+			return
+		}
+		switch ev.Action {
+		case "start":
+			tail(ev.Actor.ID)
+		case "destroy":
+			d.seenMut.Lock()
+			delete(d.lastSeen, ev.Actor.ID)
+			d.seenMut.Unlock()
+		}
+	}
+}
+
+func (d *dockerLogsReader) tail(ctx context.Context, client *docker.Client, id string, msgs chan types.Message) error {
+	info, err := client.InspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	d.seenMut.Lock()
+	since := d.since
+	if last, exists := d.lastSeen[id]; exists {
+		since = last.Add(time.Nanosecond)
+	}
+	d.seenMut.Unlock()
+
+	body, err := client.ContainerLogs(ctx, id, docker.LogsOptions{
+		Stdout: true,
+		Stderr: true,
+		Follow: true,
+		Since:  since,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.log.Debugf("Tailing the logs of container %v\n", info.Name)
+
+	lines := make(chan docker.Line)
+	readErrC := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		readErrC <- docker.ReadLogs(body, !info.Config.Tty, func(line docker.Line) error {
+			select {
+			case lines <- line:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	d.joinLines(ctx, info, lines, msgs)
+	body.Close()
+	if err := <-readErrC; err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// joinLines groups lines into log entries and sends them as messages until
+// the lines are exhausted or the context is cancelled.
+func (d *dockerLogsReader) joinLines(ctx context.Context, info *docker.ContainerInfo, lines <-chan docker.Line, msgs chan types.Message) {
+	var pending []docker.Line
+	var timer *time.Timer
+	var timerC <-chan time.Time
+
+	flush := func() bool {
+		if len(pending) == 0 {
+			return true
+		}
+		if timer != nil {
+			timer.Stop()
+			timer, timerC = nil, nil
+		}
+		msg := d.newMessage(info, pending)
+		last := pending[len(pending)-1].Timestamp
+		pending = nil
+
+		if !last.IsZero() {
+			d.seenMut.Lock()
+			d.lastSeen[info.ID] = last
+			d.seenMut.Unlock()
+		}
+
+		select {
+		case msgs <- msg:
+		case <-ctx.Done():
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case line, open := <-lines:
+			if !open {
+				flush()
+				return
+			}
+			if len(pending) > 0 && (line.Stream != pending[0].Stream ||
+				len(pending) >= d.conf.Multiline.MaxLines ||
+				d.startPattern.MatchString(line.Text)) {
+				if !flush() {
+					return
+				}
+			}
+			pending = append(pending, line)
+			if d.startPattern == nil {
+				if !flush() {
+					return
+				}
+			} else if timer == nil {
+				timer = time.NewTimer(d.flushTimeout)
+				timerC = timer.C
+			}
+		case <-timerC:
+			timer, timerC = nil, nil
+			if !flush() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *dockerLogsReader) newMessage(info *docker.ContainerInfo, lines []docker.Line) types.Message {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+	}
+
+	part := message.NewPart([]byte(strings.Join(texts, "\n")))
+	meta := part.Metadata().
+		Set("docker_container_id", info.ID).
+		Set("docker_container_name", info.Name).
+		Set("docker_container_image", info.Config.Image).
+		Set("docker_stream", lines[0].Stream)
+	if !lines[0].Timestamp.IsZero() {
+		meta.Set("docker_timestamp", lines[0].Timestamp.Format(time.RFC3339Nano))
+	}
+	for k, v := range info.Config.Labels {
+		meta.Set("docker_label_"+k, v)
+	}
+
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg
+}
+
+// ReadWithContext returns the next log entry read from a container.
+func (d *dockerLogsReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	d.mut.Lock()
+	msgs := d.msgs
+	d.mut.Unlock()
+
+	if msgs == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case msg, open := <-msgs:
+		if !open {
+			d.mut.Lock()
+			if d.msgs == msgs {
+				d.msgs, d.cancel = nil, nil
+			}
+			d.mut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		return msg, func(context.Context, types.Response) error {
+			return nil
+		}, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (d *dockerLogsReader) CloseAsync() {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	select {
+	case <-d.shutC:
+	default:
+		close(d.shutC)
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (d *dockerLogsReader) WaitForClose(time.Duration) error {
+	return nil
+}
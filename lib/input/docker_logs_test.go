@@ -0,0 +1,119 @@
+package input
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dockerLogFrame(stream byte, data string) []byte {
+	b := make([]byte, 8, 8+len(data))
+	b[0] = stream
+	binary.BigEndian.PutUint32(b[4:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func TestDockerLogs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id":"c1","Names":["/api"],"Image":"shop/api:1.0"}]`))
+	})
+	mux.HandleFunc("/containers/c1/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"c1","Name":"/api","Config":{"Image":"shop/api:1.0","Labels":{"service":"api"}}}`))
+	})
+	mux.HandleFunc("/containers/c2/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"c2","Name":"/shell","Config":{"Image":"alpine","Tty":true}}`))
+	})
+	mux.HandleFunc("/containers/c1/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(dockerLogFrame(1, "2021-06-01T10:00:00Z 2021-06-01 ERROR boom\n2021-06-01T10:00:00.1Z \tat Foo\n"))
+		w.Write(dockerLogFrame(2, "2021-06-01T10:00:00.2Z warning\n"))
+		w.Write(dockerLogFrame(1, "2021-06-01T10:00:00.3Z \tat Bar\n2021-06-01T10:00:01Z 2021-06-01 INFO ok\n"))
+	})
+	mux.HandleFunc("/containers/c2/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2021-06-01T10:00:02Z hello from tty\r\n"))
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Type":"container","Action":"start","Actor":{"ID":"c2"}}`))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conf := NewDockerLogsConfig()
+	conf.Host = srv.URL
+	conf.StartFromOldest = true
+	conf.Multiline.StartPattern = `^\d{4}-\d{2}-\d{2}`
+	conf.Multiline.Timeout = "100ms"
+
+	rdr, err := newDockerLogsReader(conf, log.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	type logEntry struct {
+		content   string
+		stream    string
+		name      string
+		timestamp string
+	}
+	var entries []logEntry
+	for len(entries) < 5 {
+		msg, _, err := rdr.ReadWithContext(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, msg.Len())
+		p := msg.Get(0)
+		entries = append(entries, logEntry{
+			content:   string(p.Get()),
+			stream:    p.Metadata().Get("docker_stream"),
+			name:      p.Metadata().Get("docker_container_name"),
+			timestamp: p.Metadata().Get("docker_timestamp"),
+		})
+		if p.Metadata().Get("docker_container_id") == "c1" {
+			assert.Equal(t, "shop/api:1.0", p.Metadata().Get("docker_container_image"))
+			assert.Equal(t, "api", p.Metadata().Get("docker_label_service"))
+		}
+	}
+
+	assert.ElementsMatch(t, []logEntry{
+		{content: "2021-06-01 ERROR boom\n\tat Foo", stream: "stdout", name: "api", timestamp: "2021-06-01T10:00:00Z"},
+		{content: "warning", stream: "stderr", name: "api", timestamp: "2021-06-01T10:00:00.2Z"},
+		{content: "\tat Bar", stream: "stdout", name: "api", timestamp: "2021-06-01T10:00:00.3Z"},
+		{content: "2021-06-01 INFO ok", stream: "stdout", name: "api", timestamp: "2021-06-01T10:00:01Z"},
+		{content: "hello from tty", stream: "stdout", name: "shell", timestamp: "2021-06-01T10:00:02Z"},
+	}, entries)
+
+	rdr.seenMut.Lock()
+	assert.Equal(t, "2021-06-01T10:00:01Z", rdr.lastSeen["c1"].Format(time.RFC3339Nano))
+	rdr.seenMut.Unlock()
+
+	rdr.CloseAsync()
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	assert.Equal(t, types.ErrTypeClosed, rdr.ConnectWithContext(ctx))
+}
+
+func TestDockerLogsBadConfig(t *testing.T) {
+	conf := NewDockerLogsConfig()
+	conf.Multiline.StartPattern = `(`
+	_, err := newDockerLogsReader(conf, log.Noop())
+	assert.Error(t, err)
+
+	conf.Multiline.StartPattern = `^\S`
+	conf.Multiline.Timeout = "nope"
+	_, err = newDockerLogsReader(conf, log.Noop())
+	assert.Error(t, err)
+}
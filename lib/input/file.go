@@ -29,6 +29,7 @@ Consumes data from files on disk, emitting messages according to a chosen codec.
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("paths", "A list of paths to consume sequentially. Glob patterns are supported, including super globs (double star).").Array(),
 			codec.ReaderDocs,
+			codec.MultilineDocs.AtVersion("3.47.0"),
 			docs.FieldAdvanced("max_buffer", "The largest token size expected when consuming delimited files."),
 			docs.FieldDeprecated("path"),
 			docs.FieldDeprecated("delimiter"),
@@ -68,13 +69,14 @@ input:
 
 // FileConfig contains configuration values for the File input type.
 type FileConfig struct {
-	Path           string   `json:"path" yaml:"path"`
-	Paths          []string `json:"paths" yaml:"paths"`
-	Codec          string   `json:"codec" yaml:"codec"`
-	Multipart      bool     `json:"multipart" yaml:"multipart"`
-	MaxBuffer      int      `json:"max_buffer" yaml:"max_buffer"`
-	Delim          string   `json:"delimiter" yaml:"delimiter"`
-	DeleteOnFinish bool     `json:"delete_on_finish" yaml:"delete_on_finish"`
+	Path           string                `json:"path" yaml:"path"`
+	Paths          []string              `json:"paths" yaml:"paths"`
+	Codec          string                `json:"codec" yaml:"codec"`
+	Multiline      codec.MultilineConfig `json:"multiline" yaml:"multiline"`
+	Multipart      bool                  `json:"multipart" yaml:"multipart"`
+	MaxBuffer      int                   `json:"max_buffer" yaml:"max_buffer"`
+	Delim          string                `json:"delimiter" yaml:"delimiter"`
+	DeleteOnFinish bool                  `json:"delete_on_finish" yaml:"delete_on_finish"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
@@ -84,6 +86,7 @@ func NewFileConfig() FileConfig {
 		Paths: []string{},
 		// TODO: V4 change this default
 		Codec:          "lines",
+		Multiline:      codec.NewMultilineConfig(),
 		Multipart:      false,
 		MaxBuffer:      1000000,
 		Delim:          "",
@@ -134,6 +137,7 @@ func newFileConsumer(conf FileConfig, log log.Modular) (*fileConsumer, error) {
 
 	codecConf := codec.NewReaderConfig()
 	codecConf.MaxScanTokenSize = conf.MaxBuffer
+	codecConf.Multiline = conf.Multiline
 	ctor, err := codec.GetReader(conf.Codec, codecConf)
 	if err != nil {
 		return nil, err
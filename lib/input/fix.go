@@ -0,0 +1,251 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/fix"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFIX] = TypeSpec{
+		constructor: fromSimpleConstructor(NewFIX),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Establishes a FIX session with a counterparty, either as the initiator or the acceptor, and consumes the application messages that it sends.`,
+		Description: `
+The session layer of the protocol is handled by this input, including logons, heartbeats, test requests, sequence gaps and logouts, and only application messages such as market data and execution reports are consumed.
+
+When ` + "`mode` is `initiator`" + ` the input connects to the counterparty at ` + "`address`" + ` and reconnects whenever the session ends. When ` + "`mode` is `acceptor`" + ` the input listens on ` + "`address`" + ` for the counterparty to connect, and only one session is active at a time.
+
+### Sequence Numbers
+
+The sequence number expected from the counterparty is only advanced within ` + "`store_path`" + ` once a message, and all messages received before it, have been delivered by the pipeline. Therefore, when the input restarts the counterparty is asked to resend any messages that were not delivered.
+
+Messages sent by this input are not retained, and so resend requests from the counterparty are answered with a gap fill.
+
+### Formats
+
+With the ` + "`json`" + ` format messages are converted into JSON objects keyed by the names of common tags, or the tag number for tags without a known name. The BodyLength and CheckSum fields are omitted, and tags that appear more than once, such as the fields of repeating groups, are converted into arrays of values in the order that they appear. For example, the market data snapshot ` + "`8=FIX.4.4|9=...|35=W|49=BROKER|56=CLIENT|34=12|55=EUR/USD|268=2|269=0|270=1.1|269=1|270=1.2|10=...`" + ` would be converted into:
+
+` + "```json" + `
+{
+  "BeginString": "FIX.4.4",
+  "MsgType": "W",
+  "SenderCompID": "BROKER",
+  "TargetCompID": "CLIENT",
+  "MsgSeqNum": "12",
+  "Symbol": "EUR/USD",
+  "NoMDEntries": "2",
+  "MDEntryType": ["0", "1"],
+  "MDEntryPx": ["1.1", "1.2"]
+}
+` + "```" + `
+
+With the ` + "`raw`" + ` format messages are consumed in their encoded form, delimited by the SOH character.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- fix_begin_string
+- fix_msg_type
+- fix_msg_seq_num
+- fix_sender_comp_id
+- fix_target_comp_id
+- fix_sending_time
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`,
+		FieldSpecs: append(
+			fix.FieldSpecs(),
+			docs.FieldCommon("format", "The format of consumed messages.").HasOptions("json", "raw"),
+		),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Market Data to Kafka",
+				Summary: "In this example we initiate a session with a broker, persisting sequence numbers so that gaps are recovered after restarts, and write market data snapshots to Kafka keyed by their symbol.",
+				Config: `
+input:
+  fix:
+    mode: initiator
+    address: fix.example.com:9876
+    begin_string: FIX.4.4
+    sender_comp_id: CLIENT
+    target_comp_id: BROKER
+    store_path: /var/lib/benthos/fix
+  processors:
+    - bloblang: |
+        root = if this.MsgType != "W" { deleted() }
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: market_data
+    key: ${! json("Symbol") }
+`,
+			},
+		},
+		Categories: []Category{
+			CategoryNetwork,
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FIXConfig contains configuration fields for the FIX input type.
+type FIXConfig struct {
+	fix.Config `json:",inline" yaml:",inline"`
+	Format     string `json:"format" yaml:"format"`
+}
+
+// NewFIXConfig creates a new FIXConfig with default values.
+func NewFIXConfig() FIXConfig {
+	return FIXConfig{
+		Config: fix.NewConfig(),
+		Format: "json",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewFIX creates a new FIX input type.
+func NewFIX(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newFIXReader(conf.FIX, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeFIX, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+type fixReader struct {
+	conf     FIXConfig
+	log      log.Modular
+	endpoint *fix.Endpoint
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+}
+
+func newFIXReader(conf FIXConfig, log log.Modular) (*fixReader, error) {
+	switch conf.Format {
+	case "json", "raw":
+	default:
+		return nil, fmt.Errorf("format not recognised: %v", conf.Format)
+	}
+	endpoint, err := conf.Endpoint(false)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.OnAcceptError = func(err error) {
+		log.Warnf("Failed to accept FIX session: %v\n", err)
+	}
+	return &fixReader{
+		conf:       conf,
+		log:        log,
+		endpoint:   endpoint,
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+// ConnectWithContext establishes a session as the initiator, or begins
+// listening for sessions as the acceptor.
+func (f *fixReader) ConnectWithContext(ctx context.Context) error {
+	if err := f.endpoint.Connect(ctx); err != nil {
+		return err
+	}
+	if f.conf.Mode == "acceptor" {
+		f.log.Infof("Accepting FIX sessions at: %v\n", f.endpoint.Addr())
+	} else {
+		f.log.Infof("Established FIX session with: %v\n", f.conf.Address)
+	}
+	return nil
+}
+
+// ReadWithContext attempts to read the next application message of the
+// session.
+func (f *fixReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	session, err := f.endpoint.Session(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, types.ErrTimeout
+		}
+		return nil, nil, types.ErrNotConnected
+	}
+
+	fixMsg, commit, err := session.Receive(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, types.ErrTimeout
+		}
+		f.log.Errorf("FIX session ended: %v\n", err)
+		return nil, nil, types.ErrNotConnected
+	}
+
+	part := message.NewPart(nil)
+	if f.conf.Format == "raw" {
+		part.Set(fixMsg.Bytes())
+	} else if err = part.SetJSON(fixMsg.ToMap()); err != nil {
+		return nil, nil, err
+	}
+
+	meta := part.Metadata()
+	for k, tag := range map[string]int{
+		"fix_begin_string":   fix.TagBeginString,
+		"fix_msg_type":       fix.TagMsgType,
+		"fix_msg_seq_num":    fix.TagMsgSeqNum,
+		"fix_sender_comp_id": fix.TagSenderCompID,
+		"fix_target_comp_id": fix.TagTargetCompID,
+		"fix_sending_time":   fix.TagSendingTime,
+	} {
+		if v, exists := fixMsg.Get(tag); exists {
+			meta.Set(k, v)
+		}
+	}
+
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg, func(rctx context.Context, res types.Response) error {
+		if res.Error() == nil {
+			commit()
+		}
+		return nil
+	}, nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (f *fixReader) CloseAsync() {
+	f.closeOnce.Do(func() {
+		go func() {
+			f.endpoint.Close(time.Second * 5)
+			close(f.closedChan)
+		}()
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (f *fixReader) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
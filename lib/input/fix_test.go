@@ -0,0 +1,121 @@
+package input
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/fix"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIXAcceptor(t *testing.T) {
+	for _, format := range []string{"json", "raw"} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			conf := NewFIXConfig()
+			conf.Mode = "acceptor"
+			conf.Address = "127.0.0.1:0"
+			conf.SenderCompID = "CLIENT"
+			conf.TargetCompID = "BROKER"
+			conf.Format = format
+
+			rdr, err := newFIXReader(conf, log.Noop())
+			require.NoError(t, err)
+
+			ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+			defer done()
+
+			require.NoError(t, rdr.ConnectWithContext(ctx))
+			t.Cleanup(func() {
+				rdr.CloseAsync()
+				require.NoError(t, rdr.WaitForClose(time.Second*10))
+			})
+
+			broker := fix.NewInitiator(rdr.endpoint.Addr().String(), nil, time.Second*5, fix.SessionConfig{
+				BeginString:  "FIX.4.4",
+				SenderCompID: "BROKER",
+				TargetCompID: "CLIENT",
+				HeartBtInt:   time.Second * 30,
+			})
+			require.NoError(t, broker.Connect(ctx))
+			defer broker.Close(time.Second)
+
+			session, err := broker.Session(ctx)
+			require.NoError(t, err)
+			require.NoError(t, session.Send(fix.NewMessage("W").
+				Add(55, "EUR/USD").
+				Add(268, "2").
+				Add(269, "0").Add(270, "1.1").
+				Add(269, "1").Add(270, "1.2")))
+
+			msg, ackFn, err := rdr.ReadWithContext(ctx)
+			require.NoError(t, err)
+			require.NoError(t, ackFn(ctx, response.NewAck()))
+			require.Equal(t, 1, msg.Len())
+
+			part := msg.Get(0)
+			meta := part.Metadata()
+			assert.Equal(t, "FIX.4.4", meta.Get("fix_begin_string"))
+			assert.Equal(t, "W", meta.Get("fix_msg_type"))
+			assert.Equal(t, "2", meta.Get("fix_msg_seq_num"))
+			assert.Equal(t, "BROKER", meta.Get("fix_sender_comp_id"))
+			assert.Equal(t, "CLIENT", meta.Get("fix_target_comp_id"))
+			assert.NotEmpty(t, meta.Get("fix_sending_time"))
+
+			if format == "raw" {
+				fixMsg, err := fix.Parse(part.Get())
+				require.NoError(t, err)
+				symbol, _ := fixMsg.Get(55)
+				assert.Equal(t, "EUR/USD", symbol)
+				return
+			}
+
+			jObj, err := part.JSON()
+			require.NoError(t, err)
+			obj := jObj.(map[string]interface{})
+			assert.Equal(t, "EUR/USD", obj["Symbol"])
+			assert.Equal(t, []interface{}{"0", "1"}, obj["MDEntryType"])
+			assert.Equal(t, []interface{}{"1.1", "1.2"}, obj["MDEntryPx"])
+		})
+	}
+}
+
+func TestFIXBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *FIXConfig)
+		err  string
+	}{
+		"no address": {
+			conf: func(c *FIXConfig) { c.Address = "" },
+			err:  "an address must be specified",
+		},
+		"bad format": {
+			conf: func(c *FIXConfig) { c.Format = "nope" },
+			err:  "format not recognised: nope",
+		},
+		"bad mode": {
+			conf: func(c *FIXConfig) { c.Mode = "nope" },
+			err:  "mode not recognised: nope",
+		},
+		"bad logon field": {
+			conf: func(c *FIXConfig) { c.LogonFields = map[string]string{"Nope": "foo"} },
+			err:  "unrecognised logon field: Nope",
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewFIXConfig()
+		conf.Address = "localhost:9876"
+		conf.SenderCompID = "CLIENT"
+		conf.TargetCompID = "BROKER"
+		test.conf(&conf)
+
+		_, err := newFIXReader(conf, log.Noop())
+		require.Error(t, err, name)
+		assert.Equal(t, test.err, err.Error(), name)
+	}
+}
@@ -0,0 +1,297 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/flow"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFlowCollector] = TypeSpec{
+		constructor: fromSimpleConstructor(NewFlowCollector),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Receives NetFlow v5, NetFlow v9, IPFIX and sFlow v5 datagrams over UDP and decodes them into structured records.`,
+		Description: `
+The protocol of each datagram is detected from its header, and therefore a single listener is able to collect from exporters of any of the supported protocols at once.
+
+NetFlow v9 and IPFIX data sets are decoded with the templates previously received from the same exporter address and observation domain. Data sets received before their template are dropped and logged at debug level, which is expected for a short while after the collector starts as exporters only periodically resend their templates. Templates are held in memory and are therefore lost when the collector restarts.
+
+Each datagram becomes a batch of messages, one for each record, containing a JSON object with the fields of the record under ` + "`record`" + `:
+
+` + "```json" + `
+{
+  "exporter": "10.0.0.1",
+  "protocol": "ipfix",
+  "record_type": "flow",
+  "export_time": "2021-05-01T12:00:00Z",
+  "sequence": 1024,
+  "observation_domain_id": 0,
+  "template_id": 256,
+  "record": {
+    "sourceIPv4Address": "192.168.0.1",
+    "destinationIPv4Address": "192.168.0.2",
+    "protocolIdentifier": 6,
+    "octetDeltaCount": 1500
+  }
+}
+` + "```" + `
+
+The ` + "`protocol`" + ` is one of ` + "`netflow_v5`, `netflow_v9`, `ipfix` or `sflow_v5`" + `. The ` + "`record_type`" + ` is ` + "`flow`" + ` for flow records, ` + "`options`" + ` for NetFlow v9 and IPFIX records of options templates, and ` + "`flow_sample`" + ` or ` + "`counter_sample`" + ` for sFlow samples. The fields ` + "`export_time`, `uptime`, `template_id` and `agent_address`" + ` are only present for the protocols that provide them.
+
+Record fields are named after [IPFIX information elements](https://www.iana.org/assignments/ipfix/ipfix.xhtml) for all protocols, where enterprise specific elements are named ` + "`<enterprise>.<id>`" + ` and unknown elements are named after their identifier. Addresses are formatted as strings, integers as numbers, and any other values as hex encoded strings. For sFlow flow samples the fields of the sampled packet header, such as addresses and ports, are decoded into the record alongside the fields of the sample.
+
+As UDP offers no delivery guarantees datagrams are not redelivered when a message is rejected by the pipeline.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- flow_exporter
+- flow_protocol
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Top Talkers",
+				Summary: "This example collects flows from routers and writes the bytes sent between each pair of addresses to Elasticsearch, dropping options records and sFlow counters.",
+				Config: `
+input:
+  flow_collector:
+    address: 0.0.0.0:2055
+
+pipeline:
+  processors:
+    - bloblang: |
+        root.exporter = this.exporter
+        root.source = this.record.sourceIPv4Address | this.record.sourceIPv6Address
+        root.destination = this.record.destinationIPv4Address | this.record.destinationIPv6Address
+        root.bytes = this.record.octetDeltaCount | (this.record.ipTotalLength * this.record.samplingRate)
+        root = if !["flow", "flow_sample"].contains(this.record_type) { deleted() }
+
+output:
+  elasticsearch:
+    urls: [ http://localhost:9200 ]
+    index: flows
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "The address to listen on for datagrams."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FlowCollectorConfig contains configuration fields for the FlowCollector
+// input type.
+type FlowCollectorConfig struct {
+	Address string `json:"address" yaml:"address"`
+}
+
+// NewFlowCollectorConfig creates a new FlowCollectorConfig with default values.
+func NewFlowCollectorConfig() FlowCollectorConfig {
+	return FlowCollectorConfig{
+		Address: "0.0.0.0:2055",
+	}
+}
+
+// NewFlowCollector creates a new FlowCollector input type.
+func NewFlowCollector(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr := newFlowCollectorReader(conf.FlowCollector, log)
+	return NewAsyncReader(TypeFlowCollector, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type flowCollectorReader struct {
+	conf    FlowCollectorConfig
+	log     log.Modular
+	decoder *flow.Decoder
+
+	mut   sync.Mutex
+	conn  net.PacketConn
+	msgs  chan types.Message
+	shutC chan struct{}
+}
+
+func newFlowCollectorReader(conf FlowCollectorConfig, log log.Modular) *flowCollectorReader {
+	return &flowCollectorReader{
+		conf:    conf,
+		log:     log,
+		decoder: flow.NewDecoder(),
+		shutC:   make(chan struct{}),
+	}
+}
+
+// ConnectWithContext binds the UDP listener.
+func (f *flowCollectorReader) ConnectWithContext(ctx context.Context) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	select {
+	case <-f.shutC:
+		return types.ErrTypeClosed
+	default:
+	}
+	if f.conn != nil {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", f.conf.Address)
+	if err != nil {
+		return err
+	}
+	f.conn = conn
+	f.msgs = make(chan types.Message)
+	go f.loop(conn, f.msgs)
+
+	f.log.Infof("Receiving flow datagrams at: %v\n", conn.LocalAddr())
+	return nil
+}
+
+func (f *flowCollectorReader) loop(conn net.PacketConn, msgs chan types.Message) {
+	defer close(msgs)
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-f.shutC:
+			default:
+				f.log.Errorf("Failed to read datagram: %v\n", err)
+			}
+			return
+		}
+
+		exporter := addr.String()
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			exporter = udpAddr.IP.String()
+		}
+
+		dgram, err := f.decoder.Decode(exporter, buf[:n])
+		if err != nil {
+			f.log.Debugf("Dropping datagram from %v: %v\n", exporter, err)
+			continue
+		}
+		if len(dgram.MissingTemplates) > 0 {
+			f.log.Debugf("Dropping data sets from %v with unknown templates: %v\n", exporter, dgram.MissingTemplates)
+		}
+		if len(dgram.Records) == 0 {
+			continue
+		}
+
+		msg := message.New(nil)
+		for _, record := range dgram.Records {
+			b, err := json.Marshal(flowRecordToJSON(exporter, record))
+			if err != nil {
+				f.log.Errorf("Failed to marshal flow record from %v: %v\n", exporter, err)
+				continue
+			}
+			part := message.NewPart(b)
+			part.Metadata().
+				Set("flow_exporter", exporter).
+				Set("flow_protocol", string(record.Protocol))
+			msg.Append(part)
+		}
+		if msg.Len() == 0 {
+			continue
+		}
+
+		select {
+		case msgs <- msg:
+		case <-f.shutC:
+			return
+		}
+	}
+}
+
+func flowRecordToJSON(exporter string, record flow.Record) map[string]interface{} {
+	obj := map[string]interface{}{
+		"exporter":              exporter,
+		"protocol":              string(record.Protocol),
+		"record_type":           string(record.Type),
+		"sequence":              record.Sequence,
+		"observation_domain_id": record.ObservationDomainID,
+		"record":                record.Fields,
+	}
+	if !record.ExportTime.IsZero() {
+		obj["export_time"] = record.ExportTime.Format(time.RFC3339)
+	}
+	if record.Protocol != flow.ProtocolIPFIX {
+		obj["uptime"] = record.UptimeMillis
+	}
+	if record.Protocol == flow.ProtocolNetFlowV9 || record.Protocol == flow.ProtocolIPFIX {
+		obj["template_id"] = record.TemplateID
+	}
+	if record.AgentAddress != "" {
+		obj["agent_address"] = record.AgentAddress
+	}
+	return obj
+}
+
+// ReadWithContext returns the records of the next datagram received.
+func (f *flowCollectorReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	f.mut.Lock()
+	msgs := f.msgs
+	f.mut.Unlock()
+
+	if msgs == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case msg, open := <-msgs:
+		if !open {
+			f.mut.Lock()
+			if f.msgs == msgs {
+				f.conn, f.msgs = nil, nil
+			}
+			f.mut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		return msg, func(context.Context, types.Response) error {
+			return nil
+		}, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (f *flowCollectorReader) CloseAsync() {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	select {
+	case <-f.shutC:
+	default:
+		close(f.shutC)
+	}
+	if f.conn != nil {
+		f.conn.Close()
+	}
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (f *flowCollectorReader) WaitForClose(time.Duration) error {
+	return nil
+}
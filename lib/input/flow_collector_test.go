@@ -0,0 +1,100 @@
+package input
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowCollector(t *testing.T) {
+	conf := NewFlowCollectorConfig()
+	conf.Address = "127.0.0.1:0"
+	rdr := newFlowCollectorReader(conf, log.Noop())
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	conn, err := net.Dial("udp", rdr.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// A NetFlow v5 datagram with two records.
+	dgram := make([]byte, 24+2*48)
+	binary.BigEndian.PutUint16(dgram[0:], 5)
+	binary.BigEndian.PutUint16(dgram[2:], 2)
+	binary.BigEndian.PutUint32(dgram[4:], 1000)
+	binary.BigEndian.PutUint32(dgram[8:], 1600000000)
+	binary.BigEndian.PutUint32(dgram[16:], 10)
+	for i, b := range [][]byte{dgram[24:72], dgram[72:]} {
+		copy(b[0:], net.IPv4(10, 0, 0, byte(i+1)).To4())
+		copy(b[4:], net.IPv4(10, 0, 1, 1).To4())
+		binary.BigEndian.PutUint32(b[20:], 1500)
+		b[38] = 17
+	}
+
+	_, err = conn.Write([]byte("not a flow datagram"))
+	require.NoError(t, err)
+	_, err = conn.Write(dgram)
+	require.NoError(t, err)
+
+	msg, _, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, msg.Len())
+	assert.JSONEq(t, `{
+		"exporter": "127.0.0.1",
+		"protocol": "netflow_v5",
+		"record_type": "flow",
+		"export_time": "2020-09-13T12:26:40Z",
+		"uptime": 1000,
+		"sequence": 10,
+		"observation_domain_id": 0,
+		"record": {
+			"sourceIPv4Address": "10.0.0.1",
+			"destinationIPv4Address": "10.0.1.1",
+			"ipNextHopIPv4Address": "0.0.0.0",
+			"ingressInterface": 0,
+			"egressInterface": 0,
+			"packetDeltaCount": 0,
+			"octetDeltaCount": 1500,
+			"flowStartSysUpTime": 0,
+			"flowEndSysUpTime": 0,
+			"sourceTransportPort": 0,
+			"destinationTransportPort": 0,
+			"tcpControlBits": 0,
+			"protocolIdentifier": 17,
+			"ipClassOfService": 0,
+			"bgpSourceAsNumber": 0,
+			"bgpDestinationAsNumber": 0,
+			"sourceIPv4PrefixLength": 0,
+			"destinationIPv4PrefixLength": 0,
+			"engineType": 0,
+			"engineId": 0,
+			"samplingAlgorithm": 0,
+			"samplingInterval": 0
+		}
+	}`, string(msg.Get(0).Get()))
+	assert.Contains(t, string(msg.Get(1).Get()), `"sourceIPv4Address":"10.0.0.2"`)
+	assert.Contains(t, string(msg.Get(1).Get()), `"sequence":11`)
+
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, "127.0.0.1", meta.Get("flow_exporter"))
+	assert.Equal(t, "netflow_v5", meta.Get("flow_protocol"))
+
+	readCtx, readDone := context.WithTimeout(ctx, time.Millisecond*50)
+	_, _, err = rdr.ReadWithContext(readCtx)
+	readDone()
+	assert.Equal(t, types.ErrTimeout, err)
+
+	rdr.CloseAsync()
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	assert.Equal(t, types.ErrTypeClosed, rdr.ConnectWithContext(ctx))
+}
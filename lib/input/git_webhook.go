@@ -0,0 +1,466 @@
+package input
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGitWebhook] = TypeSpec{
+		constructor: fromSimpleConstructor(NewGitWebhook),
+		Summary: `
+Receives webhook deliveries from GitHub or GitLab, validating their signatures and filtering them by event type.`,
+		Description: `
+You can leave the ` + "`address`" + ` field blank in order to register the endpoint on the instance wide HTTP server.
+
+When a ` + "`secret`" + ` is set deliveries are validated before they are consumed and requests that fail validation are rejected with a 401 status code. For GitHub this is done by checking the ` + "`X-Hub-Signature-256`" + ` HMAC header (or the legacy ` + "`X-Hub-Signature`" + ` header) against the payload, and for GitLab by comparing the ` + "`X-Gitlab-Token`" + ` header with the secret.
+
+Deliveries of events that are not listed in ` + "`events`" + ` are acknowledged with a 204 status code without being consumed. GitHub ` + "`ping`" + ` events, which are sent when a webhook is created, are always acknowledged this way unless they are listed explicitly. Payloads that GitHub delivers with the ` + "`application/x-www-form-urlencoded`" + ` content type are unwrapped so that the message is always the JSON payload of the event.
+
+The response to a delivery is only returned once the message has been processed, and a 502 status code is returned when processing fails so that the delivery can be redelivered.
+
+### Event Names
+
+GitHub event names are taken from the ` + "`X-GitHub-Event`" + ` header as they are, e.g. ` + "`push` or `pull_request`" + `. GitLab event names are taken from the ` + "`X-Gitlab-Event`" + ` header and normalised to lowercase with the ` + "`Hook`" + ` suffix removed and spaces replaced by underscores, e.g. ` + "`Merge Request Hook` becomes `merge_request`" + `.
+
+Entries of ` + "`events`" + ` can also be qualified with an action of the form ` + "`event.action`, e.g. `pull_request.opened`" + `, which only matches deliveries where the ` + "`action`" + ` field of the payload (or ` + "`object_attributes.action`" + ` for GitLab) matches.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- webhook_provider
+- webhook_event
+- webhook_action
+- webhook_delivery_id
+` + "```" + `
+
+The ` + "`webhook_delivery_id`" + ` is unique to each delivery and is retained by redeliveries, which makes it suitable for deduplication.
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "An alternative address to host from. If left empty the service wide address is used."),
+			docs.FieldCommon("path", "The endpoint path to receive deliveries on."),
+			docs.FieldCommon("provider", "The provider of the webhook deliveries.").HasOptions("github", "gitlab"),
+			docs.FieldCommon("secret", "The secret that deliveries are validated against, which is the webhook secret for GitHub and the secret token for GitLab. If left empty deliveries are not validated."),
+			docs.FieldCommon("events", "An optional list of event names, optionally qualified with an action, to consume. If left empty all events are consumed.", []string{"push", "pull_request.opened"}, []string{"merge_request.merge", "pipeline"}).Array(),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a delivery to be processed before responding with a timeout."),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Deploy on Release",
+				Summary: "In this example we consume published releases from a GitHub repository and deduplicate redeliveries.",
+				Config: `
+input:
+  git_webhook:
+    path: /github
+    provider: github
+    secret: ${GITHUB_WEBHOOK_SECRET}
+    events: [ release.published ]
+
+pipeline:
+  processors:
+    - dedupe:
+        cache: deliveries
+        key: ${! meta("webhook_delivery_id") }
+
+cache_resources:
+  - label: deliveries
+    memory:
+      ttl: 86400
+`,
+			},
+		},
+		Categories: []Category{
+			CategoryNetwork,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GitWebhookConfig contains configuration for the GitWebhook input type.
+type GitWebhookConfig struct {
+	Address  string   `json:"address" yaml:"address"`
+	Path     string   `json:"path" yaml:"path"`
+	Provider string   `json:"provider" yaml:"provider"`
+	Secret   string   `json:"secret" yaml:"secret"`
+	Events   []string `json:"events" yaml:"events"`
+	Timeout  string   `json:"timeout" yaml:"timeout"`
+}
+
+// NewGitWebhookConfig creates a new GitWebhookConfig with default values.
+func NewGitWebhookConfig() GitWebhookConfig {
+	return GitWebhookConfig{
+		Address:  "",
+		Path:     "/webhook",
+		Provider: "github",
+		Secret:   "",
+		Events:   []string{},
+		Timeout:  "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// gitWebhookMaxBodySize matches the maximum payload size delivered by GitHub.
+const gitWebhookMaxBodySize = 25 * 1024 * 1024
+
+// gitWebhookDelivery describes a delivery extracted from a request.
+type gitWebhookDelivery struct {
+	event      string
+	action     string
+	deliveryID string
+	payload    []byte
+}
+
+// GitWebhook is an input type that receives webhook deliveries from GitHub or
+// GitLab.
+type GitWebhook struct {
+	running int32
+
+	conf    GitWebhookConfig
+	log     log.Modular
+	server  *http.Server
+	timeout time.Duration
+	events  map[string]struct{}
+
+	handlerWG    sync.WaitGroup
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	mCount    metrics.StatCounter
+	mRejected metrics.StatCounter
+	mIgnored  metrics.StatCounter
+	mTimeout  metrics.StatCounter
+	mErr      metrics.StatCounter
+	mSucc     metrics.StatCounter
+}
+
+// NewGitWebhook creates a new GitWebhook input type.
+func NewGitWebhook(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	switch conf.GitWebhook.Provider {
+	case "github", "gitlab":
+	default:
+		return nil, fmt.Errorf("provider not recognised: %v", conf.GitWebhook.Provider)
+	}
+	if conf.GitWebhook.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+
+	var timeout time.Duration
+	if len(conf.GitWebhook.Timeout) > 0 {
+		var err error
+		if timeout, err = time.ParseDuration(conf.GitWebhook.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	g := &GitWebhook{
+		running:      1,
+		conf:         conf.GitWebhook,
+		log:          log,
+		timeout:      timeout,
+		events:       map[string]struct{}{},
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+
+		mCount:    stats.GetCounter("count"),
+		mRejected: stats.GetCounter("rejected"),
+		mIgnored:  stats.GetCounter("ignored"),
+		mTimeout:  stats.GetCounter("send.timeout"),
+		mErr:      stats.GetCounter("send.error"),
+		mSucc:     stats.GetCounter("send.success"),
+	}
+	for _, e := range conf.GitWebhook.Events {
+		g.events[e] = struct{}{}
+	}
+
+	if len(conf.GitWebhook.Address) > 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc(g.conf.Path, g.handler)
+		g.server = &http.Server{Addr: conf.GitWebhook.Address, Handler: mux}
+	} else {
+		mgr.RegisterEndpoint(
+			g.conf.Path, "Receive webhook deliveries from "+g.conf.Provider+".", g.handler,
+		)
+	}
+
+	go g.loop()
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GitWebhook) verifyGitHub(r *http.Request, body []byte) error {
+	var sig, prefix string
+	var hashFn func() hash.Hash
+	if sig = r.Header.Get("X-Hub-Signature-256"); sig != "" {
+		prefix, hashFn = "sha256=", sha256.New
+	} else if sig = r.Header.Get("X-Hub-Signature"); sig != "" {
+		prefix, hashFn = "sha1=", sha1.New
+	} else {
+		return errors.New("missing signature header")
+	}
+	if !strings.HasPrefix(sig, prefix) {
+		return errors.New("unexpected signature format")
+	}
+	sigBytes, err := hex.DecodeString(strings.TrimPrefix(sig, prefix))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	mac := hmac.New(hashFn, []byte(g.conf.Secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), sigBytes) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (g *GitWebhook) verifyGitLab(r *http.Request) error {
+	token := r.Header.Get("X-Gitlab-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(g.conf.Secret)) != 1 {
+		return errors.New("token mismatch")
+	}
+	return nil
+}
+
+func normaliseGitLabEvent(event string) string {
+	event = strings.TrimSuffix(strings.TrimSpace(event), " Hook")
+	return strings.ReplaceAll(strings.ToLower(event), " ", "_")
+}
+
+func (g *GitWebhook) readDelivery(w http.ResponseWriter, r *http.Request) (*gitWebhookDelivery, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, gitWebhookMaxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	if g.conf.Secret != "" {
+		if g.conf.Provider == "github" {
+			err = g.verifyGitHub(r, body)
+		} else {
+			err = g.verifyGitLab(r)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d := &gitWebhookDelivery{payload: body}
+	if g.conf.Provider == "github" {
+		d.event = r.Header.Get("X-GitHub-Event")
+		d.deliveryID = r.Header.Get("X-GitHub-Delivery")
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			values, err := url.ParseQuery(string(body))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse form payload: %w", err)
+			}
+			d.payload = []byte(values.Get("payload"))
+		}
+	} else {
+		d.event = normaliseGitLabEvent(r.Header.Get("X-Gitlab-Event"))
+		d.deliveryID = r.Header.Get("X-Gitlab-Event-UUID")
+	}
+
+	var payload struct {
+		Action           string `json:"action"`
+		ObjectAttributes struct {
+			Action string `json:"action"`
+		} `json:"object_attributes"`
+	}
+	if err := json.Unmarshal(d.payload, &payload); err == nil {
+		d.action = payload.Action
+		if d.action == "" {
+			d.action = payload.ObjectAttributes.Action
+		}
+	}
+	return d, nil
+}
+
+func (g *GitWebhook) accepts(d *gitWebhookDelivery) bool {
+	if len(g.events) == 0 {
+		return d.event != "ping" || g.conf.Provider != "github"
+	}
+	if _, exists := g.events[d.event]; exists {
+		return true
+	}
+	if d.action != "" {
+		_, exists := g.events[d.event+"."+d.action]
+		return exists
+	}
+	return false
+}
+
+func (g *GitWebhook) handler(w http.ResponseWriter, r *http.Request) {
+	g.handlerWG.Add(1)
+	defer g.handlerWG.Done()
+	defer r.Body.Close()
+
+	if r.Method != "POST" {
+		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d, err := g.readDelivery(w, r)
+	if err != nil {
+		g.mRejected.Incr(1)
+		g.log.Debugf("Rejecting webhook delivery: %v\n", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !g.accepts(d) {
+		g.mIgnored.Incr(1)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	g.mCount.Incr(1)
+
+	part := message.NewPart(d.payload)
+	part.Metadata().
+		Set("webhook_provider", g.conf.Provider).
+		Set("webhook_event", d.event).
+		Set("webhook_action", d.action).
+		Set("webhook_delivery_id", d.deliveryID)
+	msg := message.New(nil)
+	msg.Append(part)
+
+	tracing.InitSpans("input_git_webhook", msg)
+	defer tracing.FinishSpans(msg)
+
+	ctx, done := context.WithTimeout(r.Context(), g.timeout)
+	defer done()
+
+	resChan := make(chan types.Response)
+	select {
+	case g.transactions <- types.NewTransaction(msg, resChan):
+	case <-ctx.Done():
+		g.mTimeout.Incr(1)
+		http.Error(w, "Request timed out", http.StatusRequestTimeout)
+		return
+	case <-g.closeChan:
+		http.Error(w, "Server closing", http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case res, open := <-resChan:
+		if !open {
+			http.Error(w, "Server closing", http.StatusServiceUnavailable)
+			return
+		} else if res.Error() != nil {
+			g.mErr.Incr(1)
+			http.Error(w, res.Error().Error(), http.StatusBadGateway)
+			return
+		}
+		g.mSucc.Incr(1)
+		w.WriteHeader(http.StatusOK)
+	case <-ctx.Done():
+		g.mTimeout.Incr(1)
+		http.Error(w, "Request timed out", http.StatusRequestTimeout)
+		go func() {
+			// Even if the request times out, we still need to drain a response.
+			if res := <-resChan; res != nil && res.Error() != nil {
+				g.mErr.Incr(1)
+			} else {
+				g.mSucc.Incr(1)
+			}
+		}()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GitWebhook) loop() {
+	defer func() {
+		atomic.StoreInt32(&g.running, 0)
+
+		if g.server != nil {
+			if err := g.server.Shutdown(context.Background()); err != nil {
+				g.log.Errorf("Failed to gracefully terminate git_webhook server: %v\n", err)
+			}
+		}
+
+		g.handlerWG.Wait()
+
+		close(g.transactions)
+		close(g.closedChan)
+	}()
+
+	if g.server != nil {
+		go func() {
+			g.log.Infof(
+				"Receiving webhook deliveries at: http://%s\n",
+				g.conf.Address+g.conf.Path,
+			)
+			if err := g.server.ListenAndServe(); err != http.ErrServerClosed {
+				g.log.Errorf("Server error: %v\n", err)
+			}
+		}()
+	}
+
+	<-g.closeChan
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input.
+func (g *GitWebhook) TransactionChan() <-chan types.Transaction {
+	return g.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (g *GitWebhook) Connected() bool {
+	return true
+}
+
+// CloseAsync shuts down the GitWebhook input and stops processing requests.
+func (g *GitWebhook) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&g.running, 1, 0) {
+		close(g.closeChan)
+	}
+}
+
+// WaitForClose blocks until the GitWebhook input has closed down.
+func (g *GitWebhook) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,204 @@
+package input_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGitWebhookTestInput(t *testing.T, conf input.Config) (input.Type, *httptest.Server) {
+	t.Helper()
+
+	reg := apiRegMutWrapper{mut: &http.ServeMux{}}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	g, err := input.NewGitWebhook(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	t.Cleanup(func() {
+		server.Close()
+		g.CloseAsync()
+		assert.NoError(t, g.WaitForClose(time.Second*5))
+	})
+	return g, server
+}
+
+func githubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func sendGitWebhook(t *testing.T, target string, headers map[string]string, body string) chan int {
+	t.Helper()
+
+	codeChan := make(chan int, 1)
+	go func() {
+		req, err := http.NewRequest("POST", target, strings.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		codeChan <- res.StatusCode
+	}()
+	return codeChan
+}
+
+func readGitWebhookTran(t *testing.T, g input.Type, res types.Response) types.Message {
+	t.Helper()
+
+	select {
+	case tran := <-g.TransactionChan():
+		select {
+		case tran.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return tran.Payload
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestGitWebhookGitHub(t *testing.T) {
+	conf := input.NewConfig()
+	conf.GitWebhook.Secret = "foosecret"
+	conf.GitWebhook.Events = []string{"push", "pull_request.opened"}
+
+	g, server := newGitWebhookTestInput(t, conf)
+	target := server.URL + "/webhook"
+
+	body := `{"action":"opened","number":1}`
+	codeChan := sendGitWebhook(t, target, map[string]string{
+		"X-GitHub-Event":      "pull_request",
+		"X-GitHub-Delivery":   "delivery-1",
+		"X-Hub-Signature-256": githubSignature("foosecret", body),
+	}, body)
+
+	msg := readGitWebhookTran(t, g, response.NewAck())
+	assert.Equal(t, body, string(msg.Get(0).Get()))
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, "github", meta.Get("webhook_provider"))
+	assert.Equal(t, "pull_request", meta.Get("webhook_event"))
+	assert.Equal(t, "opened", meta.Get("webhook_action"))
+	assert.Equal(t, "delivery-1", meta.Get("webhook_delivery_id"))
+	assert.Equal(t, http.StatusOK, <-codeChan)
+
+	// Form encoded payloads are unwrapped.
+	form := url.Values{"payload": []string{`{"ref":"refs/heads/main"}`}}.Encode()
+	codeChan = sendGitWebhook(t, target, map[string]string{
+		"Content-Type":        "application/x-www-form-urlencoded",
+		"X-GitHub-Event":      "push",
+		"X-GitHub-Delivery":   "delivery-2",
+		"X-Hub-Signature-256": githubSignature("foosecret", form),
+	}, form)
+
+	msg = readGitWebhookTran(t, g, response.NewAck())
+	assert.Equal(t, `{"ref":"refs/heads/main"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, http.StatusOK, <-codeChan)
+}
+
+func TestGitWebhookGitHubRejected(t *testing.T) {
+	conf := input.NewConfig()
+	conf.GitWebhook.Secret = "foosecret"
+
+	_, server := newGitWebhookTestInput(t, conf)
+	target := server.URL + "/webhook"
+
+	body := `{"ref":"refs/heads/main"}`
+	assert.Equal(t, http.StatusUnauthorized, <-sendGitWebhook(t, target, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": githubSignature("wrongsecret", body),
+	}, body))
+
+	assert.Equal(t, http.StatusUnauthorized, <-sendGitWebhook(t, target, map[string]string{
+		"X-GitHub-Event": "push",
+	}, body))
+}
+
+func TestGitWebhookFiltered(t *testing.T) {
+	conf := input.NewConfig()
+	conf.GitWebhook.Events = []string{"pull_request.opened"}
+
+	g, server := newGitWebhookTestInput(t, conf)
+	target := server.URL + "/webhook"
+
+	for _, test := range []struct {
+		event string
+		body  string
+	}{
+		{event: "ping", body: `{"zen":"keep it simple"}`},
+		{event: "push", body: `{}`},
+		{event: "pull_request", body: `{"action":"closed"}`},
+	} {
+		assert.Equal(t, http.StatusNoContent, <-sendGitWebhook(t, target, map[string]string{
+			"X-GitHub-Event": test.event,
+		}, test.body), test.event)
+	}
+
+	select {
+	case <-g.TransactionChan():
+		t.Error("unexpected transaction")
+	default:
+	}
+}
+
+func TestGitWebhookGitLab(t *testing.T) {
+	conf := input.NewConfig()
+	conf.GitWebhook.Provider = "gitlab"
+	conf.GitWebhook.Secret = "footoken"
+	conf.GitWebhook.Events = []string{"merge_request.merge"}
+
+	g, server := newGitWebhookTestInput(t, conf)
+	target := server.URL + "/webhook"
+
+	body := `{"object_kind":"merge_request","object_attributes":{"action":"merge"}}`
+	assert.Equal(t, http.StatusUnauthorized, <-sendGitWebhook(t, target, map[string]string{
+		"X-Gitlab-Event": "Merge Request Hook",
+		"X-Gitlab-Token": "nope",
+	}, body))
+
+	codeChan := sendGitWebhook(t, target, map[string]string{
+		"X-Gitlab-Event":      "Merge Request Hook",
+		"X-Gitlab-Event-UUID": "uuid-1",
+		"X-Gitlab-Token":      "footoken",
+	}, body)
+
+	msg := readGitWebhookTran(t, g, response.NewError(errors.New("nope")))
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, "gitlab", meta.Get("webhook_provider"))
+	assert.Equal(t, "merge_request", meta.Get("webhook_event"))
+	assert.Equal(t, "merge", meta.Get("webhook_action"))
+	assert.Equal(t, "uuid-1", meta.Get("webhook_delivery_id"))
+	assert.Equal(t, http.StatusBadGateway, <-codeChan)
+}
+
+func TestGitWebhookBadConfig(t *testing.T) {
+	conf := input.NewConfig()
+	conf.GitWebhook.Provider = "bitbucket"
+
+	_, err := input.NewGitWebhook(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "provider not recognised: bitbucket")
+}
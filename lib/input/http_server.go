@@ -29,6 +29,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	httputil "github.com/Jeffail/benthos/v3/lib/util/http"
+	httpserver "github.com/Jeffail/benthos/v3/lib/util/http/server"
 	"github.com/Jeffail/benthos/v3/lib/util/throttle"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -89,6 +90,16 @@ established.
 It's also possible to specify a ` + "`ws_rate_limit_message`" + `, which is a
 static payload to be sent to clients that have triggered the servers rate limit.
 
+### Authentication
+
+Access to the endpoints of this input can be restricted with the ` + "`auth`" + ` field, which supports basic authentication, static API keys, JWTs validated against a JSON Web Key Set, TLS client certificates and CIDR allowlists. The ` + "`auth`" + ` configuration of this input always applies to its endpoints, whether or not a custom ` + "`address`" + ` is set. When a custom ` + "`address`" + ` is not specified the endpoints are registered on the service-wide HTTP server, in which case requests must additionally satisfy the ` + "`auth`" + ` configuration of that server.
+
+Client certificate authentication with ` + "`mtls`" + ` requires a custom ` + "`address`" + ` along with a ` + "`cert_file`" + ` and ` + "`key_file`" + `.
+
+### Push Verification
+
+Requests pushed by cloud services can be verified with the ` + "`push_verification`" + ` field. When ` + "`google_oidc`" + ` is enabled the OIDC token attached to requests by Cloud Tasks, Cloud Scheduler or Pub/Sub push subscriptions is validated against the Google signing keys, along with its issuer, audience and (optionally) service account email. When ` + "`aws_sns`" + ` is enabled the signature of each SNS delivery is validated against the signing certificate of the message, and subscription confirmation requests are confirmed automatically.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -115,6 +126,10 @@ You can access these metadata fields using
 			docs.FieldCommon("rate_limit", "An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by."),
 			docs.FieldAdvanced("cert_file", "Only valid with a custom `address`."),
 			docs.FieldAdvanced("key_file", "Only valid with a custom `address`."),
+			httpserver.AuthFieldSpec().AtVersion("3.47.0"),
+			httpserver.CORSFieldSpec().AtVersion("3.47.0"),
+			docs.FieldAdvanced("max_body_size", "The maximum size in bytes of request bodies, requests with larger bodies are rejected with a 413 status code. Set to zero in order to disable the limit.").AtVersion("3.47.0"),
+			httpserver.PushVerifyFieldSpec().AtVersion("3.47.0"),
 			docs.FieldAdvanced("sync_response", "Customise messages returned via [synchronous responses](/docs/guides/sync_responses).").WithChildren(
 				docs.FieldCommon(
 					"status",
@@ -151,17 +166,21 @@ func NewHTTPServerResponseConfig() HTTPServerResponseConfig {
 
 // HTTPServerConfig contains configuration for the HTTPServer input type.
 type HTTPServerConfig struct {
-	Address            string                   `json:"address" yaml:"address"`
-	Path               string                   `json:"path" yaml:"path"`
-	WSPath             string                   `json:"ws_path" yaml:"ws_path"`
-	WSWelcomeMessage   string                   `json:"ws_welcome_message" yaml:"ws_welcome_message"`
-	WSRateLimitMessage string                   `json:"ws_rate_limit_message" yaml:"ws_rate_limit_message"`
-	AllowedVerbs       []string                 `json:"allowed_verbs" yaml:"allowed_verbs"`
-	Timeout            string                   `json:"timeout" yaml:"timeout"`
-	RateLimit          string                   `json:"rate_limit" yaml:"rate_limit"`
-	CertFile           string                   `json:"cert_file" yaml:"cert_file"`
-	KeyFile            string                   `json:"key_file" yaml:"key_file"`
-	Response           HTTPServerResponseConfig `json:"sync_response" yaml:"sync_response"`
+	Address            string                      `json:"address" yaml:"address"`
+	Path               string                      `json:"path" yaml:"path"`
+	WSPath             string                      `json:"ws_path" yaml:"ws_path"`
+	WSWelcomeMessage   string                      `json:"ws_welcome_message" yaml:"ws_welcome_message"`
+	WSRateLimitMessage string                      `json:"ws_rate_limit_message" yaml:"ws_rate_limit_message"`
+	AllowedVerbs       []string                    `json:"allowed_verbs" yaml:"allowed_verbs"`
+	Timeout            string                      `json:"timeout" yaml:"timeout"`
+	RateLimit          string                      `json:"rate_limit" yaml:"rate_limit"`
+	CertFile           string                      `json:"cert_file" yaml:"cert_file"`
+	KeyFile            string                      `json:"key_file" yaml:"key_file"`
+	Auth               httpserver.AuthConfig       `json:"auth" yaml:"auth"`
+	CORS               httpserver.CORSConfig       `json:"cors" yaml:"cors"`
+	MaxBodySize        int64                       `json:"max_body_size" yaml:"max_body_size"`
+	PushVerification   httpserver.PushVerifyConfig `json:"push_verification" yaml:"push_verification"`
+	Response           HTTPServerResponseConfig    `json:"sync_response" yaml:"sync_response"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
@@ -179,7 +198,12 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		RateLimit: "",
 		CertFile:  "",
 		KeyFile:   "",
-		Response:  NewHTTPServerResponseConfig(),
+
+		Auth:             httpserver.NewAuthConfig(),
+		CORS:             httpserver.NewCORSConfig(),
+		MaxBodySize:      0,
+		PushVerification: httpserver.NewPushVerifyConfig(),
+		Response:         NewHTTPServerResponseConfig(),
 	}
 }
 
@@ -298,8 +322,26 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		}
 	}
 
-	postHdlr := httputil.GzipHandler(h.postHandler)
-	wsHdlr := httputil.GzipHandler(h.wsHandler)
+	verifier, err := httpserver.NewPushVerifier(h.conf.PushVerification, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create push verifier: %v", err)
+	}
+
+	authenticator, err := httpserver.NewAuthenticator(h.conf.Auth, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticator: %v", err)
+	}
+	if authenticator.MTLSEnabled() {
+		if server == nil || h.conf.CertFile == "" {
+			return nil, errors.New("a custom address, cert_file and key_file must be specified in order to use mtls authentication")
+		}
+		server.TLSConfig = authenticator.TLSConfig()
+	}
+
+	postHdlr := httpserver.MaxBodySizeHandler(h.conf.MaxBodySize, verifier.WrapHandler(h.postHandler))
+	postHdlr = httpserver.CORSHandler(h.conf.CORS, authenticator.WrapHandler(postHdlr))
+	postHdlr = httputil.GzipHandler(postHdlr)
+	wsHdlr := httputil.GzipHandler(authenticator.WrapHandler(h.wsHandler))
 	if mux != nil {
 		if len(h.conf.Path) > 0 {
 			mux.HandleFunc(h.conf.Path, postHdlr)
@@ -435,6 +477,10 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 
 	msg, err := h.extractMessageFromRequest(r)
 	if err != nil {
+		if httpserver.IsBodyTooLarge(err) {
+			http.Error(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		h.log.Warnf("Request read failed: %v\n", err)
 		return
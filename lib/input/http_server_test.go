@@ -367,6 +367,45 @@ func TestHTTPBadRequests(t *testing.T) {
 	}
 }
 
+func TestHTTPMaxBodySize(t *testing.T) {
+	t.Parallel()
+
+	reg := apiRegMutWrapper{mut: &http.ServeMux{}}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.MaxBodySize = 5
+
+	h, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	res, err := http.Post(
+		server.URL+"/testpost",
+		"application/octet-stream",
+		bytes.NewBuffer([]byte("hello world")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := http.StatusRequestEntityTooLarge, res.StatusCode; exp != act {
+		t.Errorf("Unexpected status code: %v != %v", exp, act)
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestHTTPTimeout(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,613 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	gohttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/http"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeHTTPSSE] = TypeSpec{
+		constructor: fromSimpleConstructor(NewHTTPSSE),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Connects to a server and consumes a stream of [Server-Sent Events](https://html.spec.whatwg.org/multipage/server-sent-events.html), emitting the data of each event as a message.`,
+		Description: `
+The data of each event becomes the contents of a message, where events containing multiple ` + "`data`" + ` lines are joined with line breaks, and events without data are ignored. When ` + "`event_types`" + ` is specified only events of those types are emitted, where events without an ` + "`event`" + ` field have the type ` + "`message`" + `.
+
+### Reconnecting
+
+When the stream ends or the connection is lost the input reconnects after waiting ` + "`reconnect.initial_interval`" + `, or the period most recently sent by the server within a ` + "`retry`" + ` field. Each consecutive failure to connect or stream events doubles the period, up to ` + "`reconnect.max_interval`" + `.
+
+Reconnection requests include the ID of the last event received within a ` + "`Last-Event-ID`" + ` header, allowing servers that support it to resume the stream where it left off. When a ` + "`checkpoint_cache`" + ` is specified the ID of the latest event for which all events before it have been delivered is periodically stored within it under ` + "`checkpoint_key`" + `, allowing the input to resume where it left off after restarts. Without a stored ID the first request includes ` + "`last_event_id`" + ` when it is set.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- sse_event
+- sse_id
+` + "```" + `
+
+Where ` + "`sse_id`" + ` is the last event ID at the time the event was received, which may have been set by an earlier event.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Wikipedia Edits",
+				Summary: "This example consumes the public stream of changes made to Wikipedia, storing the ID of the last change delivered within a file cache so that the stream is resumed after restarts.",
+				Config: `
+input:
+  http_sse:
+    url: https://stream.wikimedia.org/v2/stream/recentchange
+    checkpoint_cache: positions
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = if this.type != "edit" { deleted() }
+        root.title = this.title
+        root.user = this.user
+
+cache_resources:
+  - label: positions
+    file:
+      directory: /var/lib/benthos/positions
+`,
+			},
+		},
+		FieldSpecs: append(client.FieldSpecs(),
+			docs.FieldCommon("event_types", "An optional list of event types to emit, where all events are emitted when empty.", []string{"update", "delete"}).Array(),
+			docs.FieldAdvanced("last_event_id", "An optional event ID to send with the first request when no ID has been stored within the `checkpoint_cache`."),
+			docs.FieldAdvanced("reconnect", "Controls the periods between reconnection attempts.").WithChildren(
+				docs.FieldAdvanced("initial_interval", "The period to wait before reconnecting, unless the server sends a different period."),
+				docs.FieldAdvanced("max_interval", "The maximum period to wait before reconnecting after consecutive failures."),
+			),
+			docs.FieldAdvanced("max_buffer", "The maximum size of a line of the stream, which must be larger than the largest line of data sent by the server."),
+			docs.FieldCommon("checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store the last event ID within."),
+			docs.FieldAdvanced("checkpoint_key", "The key to store the last event ID under within the `checkpoint_cache`."),
+			docs.FieldAdvanced("checkpoint_period", "The period between storing the last event ID within the `checkpoint_cache`."),
+		),
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// HTTPSSEReconnectConfig contains configuration fields for the periods between
+// reconnection attempts of the HTTPSSE input type.
+type HTTPSSEReconnectConfig struct {
+	InitialInterval string `json:"initial_interval" yaml:"initial_interval"`
+	MaxInterval     string `json:"max_interval" yaml:"max_interval"`
+}
+
+// HTTPSSEConfig contains configuration fields for the HTTPSSE input type.
+type HTTPSSEConfig struct {
+	client.Config    `json:",inline" yaml:",inline"`
+	EventTypes       []string               `json:"event_types" yaml:"event_types"`
+	LastEventID      string                 `json:"last_event_id" yaml:"last_event_id"`
+	Reconnect        HTTPSSEReconnectConfig `json:"reconnect" yaml:"reconnect"`
+	MaxBuffer        int                    `json:"max_buffer" yaml:"max_buffer"`
+	CheckpointCache  string                 `json:"checkpoint_cache" yaml:"checkpoint_cache"`
+	CheckpointKey    string                 `json:"checkpoint_key" yaml:"checkpoint_key"`
+	CheckpointPeriod string                 `json:"checkpoint_period" yaml:"checkpoint_period"`
+}
+
+// NewHTTPSSEConfig creates a new HTTPSSEConfig with default values.
+func NewHTTPSSEConfig() HTTPSSEConfig {
+	cConf := client.NewConfig()
+	cConf.Verb = "GET"
+	cConf.URL = "http://localhost:4195/events"
+	cConf.Timeout = ""
+	cConf.Headers = map[string]string{
+		"Accept": "text/event-stream",
+	}
+	return HTTPSSEConfig{
+		Config:      cConf,
+		EventTypes:  []string{},
+		LastEventID: "",
+		Reconnect: HTTPSSEReconnectConfig{
+			InitialInterval: "3s",
+			MaxInterval:     "1m",
+		},
+		MaxBuffer:        1000000,
+		CheckpointCache:  "",
+		CheckpointKey:    "http_sse_last_event_id",
+		CheckpointPeriod: "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewHTTPSSE creates a new HTTPSSE input type.
+func NewHTTPSSE(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newHTTPSSEReader(conf.HTTPSSE, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeHTTPSSE, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+// sseEvent is an event dispatched from a Server-Sent Events stream.
+type sseEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+// sseParser reads the events of a Server-Sent Events stream following
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type sseParser struct {
+	scanner *bufio.Scanner
+	first   bool
+
+	lastID  string
+	retry   time.Duration
+	evType  string
+	data    strings.Builder
+	hasData bool
+}
+
+func newSSEParser(r io.Reader, lastID string, maxBuffer int) *sseParser {
+	scanner := bufio.NewScanner(r)
+	if maxBuffer > 0 {
+		scanner.Buffer(nil, maxBuffer)
+	}
+	return &sseParser{
+		scanner: scanner,
+		first:   true,
+		lastID:  lastID,
+	}
+}
+
+// Next returns the next event of the stream that contains data. The last event
+// ID and retry period of the parser are updated by each field read.
+func (p *sseParser) Next() (sseEvent, error) {
+	for p.scanner.Scan() {
+		line := p.scanner.Bytes()
+		if p.first {
+			line = bytes.TrimPrefix(line, []byte("\xEF\xBB\xBF"))
+			p.first = false
+		}
+
+		if len(line) == 0 {
+			if !p.hasData {
+				p.evType = ""
+				continue
+			}
+			ev := sseEvent{
+				id:        p.lastID,
+				eventType: p.evType,
+				data:      p.data.String(),
+			}
+			if ev.eventType == "" {
+				ev.eventType = "message"
+			}
+			p.evType, p.hasData = "", false
+			p.data.Reset()
+			return ev, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte{}
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "event":
+			p.evType = string(value)
+		case "data":
+			if p.hasData {
+				p.data.WriteByte('\n')
+			}
+			p.data.Write(value)
+			p.hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				p.lastID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil {
+				p.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := p.scanner.Err(); err != nil {
+		return sseEvent{}, err
+	}
+	return sseEvent{}, io.EOF
+}
+
+//------------------------------------------------------------------------------
+
+type httpSSEEvent struct {
+	msg     types.Message
+	resolve func() interface{}
+}
+
+type httpSSEReader struct {
+	conf   HTTPSSEConfig
+	mgr    types.Manager
+	log    log.Modular
+	client *http.Client
+
+	eventTypes  map[string]struct{}
+	initial     time.Duration
+	maxInterval time.Duration
+	period      time.Duration
+
+	// The ID of the last event received and the latest retry period sent by
+	// the server, which persist across reconnects.
+	idMut  sync.Mutex
+	lastID string
+	idSet  bool
+	retry  time.Duration
+
+	cpMut     sync.Mutex
+	pending   *checkpoint.Type
+	committed string
+	dirty     bool
+	lastStore time.Time
+
+	mut    sync.Mutex
+	events chan httpSSEEvent
+	cancel func()
+	shutC  chan struct{}
+}
+
+func newHTTPSSEReader(conf HTTPSSEConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*httpSSEReader, error) {
+	// The timeout would otherwise apply to the entire stream.
+	conf.Timeout = ""
+
+	s := &httpSSEReader{
+		conf:       conf,
+		mgr:        mgr,
+		log:        log,
+		eventTypes: map[string]struct{}{},
+		pending:    checkpoint.New(),
+		shutC:      make(chan struct{}),
+	}
+	for _, t := range conf.EventTypes {
+		s.eventTypes[t] = struct{}{}
+	}
+
+	var err error
+	if s.initial, err = time.ParseDuration(conf.Reconnect.InitialInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect initial interval string: %v", err)
+	}
+	if s.maxInterval, err = time.ParseDuration(conf.Reconnect.MaxInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect max interval string: %v", err)
+	}
+	if conf.CheckpointCache != "" {
+		if conf.CheckpointKey == "" {
+			return nil, errors.New("a checkpoint_key must be specified")
+		}
+		if err := interop.ProbeCache(context.Background(), mgr, conf.CheckpointCache); err != nil {
+			return nil, err
+		}
+		if s.period, err = time.ParseDuration(conf.CheckpointPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint period string: %v", err)
+		}
+	}
+
+	cMgr, cLog, cStats := interop.LabelChild("client", mgr, log, stats)
+	if s.client, err = http.NewClient(
+		conf.Config,
+		http.OptSetManager(cMgr),
+		http.OptSetLogger(cLog),
+		http.OptSetStats(cStats),
+		http.OptModifyRequest(s.setLastEventID),
+	); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// setLastEventID adds the ID of the last event received to a request.
+func (s *httpSSEReader) setLastEventID(req *gohttp.Request) {
+	s.idMut.Lock()
+	id := s.lastID
+	s.idMut.Unlock()
+
+	if id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
+}
+
+// loadLastEventID reads the stored event ID from the checkpoint cache.
+func (s *httpSSEReader) loadLastEventID(ctx context.Context) (string, bool, error) {
+	if s.conf.CheckpointCache == "" {
+		return "", false, nil
+	}
+
+	var stored []byte
+	var getErr error
+	if err := interop.AccessCache(ctx, s.mgr, s.conf.CheckpointCache, func(cache types.Cache) {
+		stored, getErr = cache.Get(s.conf.CheckpointKey)
+	}); err != nil {
+		return "", false, err
+	}
+	if getErr != nil {
+		if getErr == types.ErrKeyNotFound {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to read last event ID: %w", getErr)
+	}
+	return string(stored), true, nil
+}
+
+// storeLastEventID writes the committed event ID to the checkpoint cache, the
+// checkpoint mutex must be held.
+func (s *httpSSEReader) storeLastEventID(ctx context.Context) error {
+	if s.conf.CheckpointCache == "" || !s.dirty {
+		return nil
+	}
+
+	var setErr error
+	if err := interop.AccessCache(ctx, s.mgr, s.conf.CheckpointCache, func(cache types.Cache) {
+		setErr = cache.Set(s.conf.CheckpointKey, []byte(s.committed))
+	}); err != nil {
+		return err
+	}
+	if setErr != nil {
+		return setErr
+	}
+	s.dirty = false
+	s.lastStore = time.Now()
+	return nil
+}
+
+// ConnectWithContext determines the event ID to resume from and begins
+// consuming the stream, which is reconnected to whenever it ends.
+func (s *httpSSEReader) ConnectWithContext(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	select {
+	case <-s.shutC:
+		return types.ErrTypeClosed
+	default:
+	}
+	if s.events != nil {
+		return nil
+	}
+
+	s.idMut.Lock()
+	idSet := s.idSet
+	s.idMut.Unlock()
+	if !idSet {
+		id, stored, err := s.loadLastEventID(ctx)
+		if err != nil {
+			return err
+		}
+		if !stored {
+			id = s.conf.LastEventID
+		}
+		s.idMut.Lock()
+		s.lastID, s.idSet = id, true
+		s.idMut.Unlock()
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	s.events, s.cancel = make(chan httpSSEEvent), cancel
+	go s.loop(streamCtx, s.events)
+	return nil
+}
+
+// loop consumes the stream until the context is cancelled, reconnecting after
+// a delay each time the stream ends or fails.
+func (s *httpSSEReader) loop(ctx context.Context, events chan<- httpSSEEvent) {
+	defer close(events)
+
+	boff := backoff.NewExponentialBackOff()
+	boff.MaxInterval = s.maxInterval
+	boff.MaxElapsedTime = 0
+	boff.RandomizationFactor = 0
+	boff.Multiplier = 2
+
+	for {
+		received, err := s.stream(ctx, events)
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.idMut.Lock()
+		initial := s.initial
+		if s.retry > 0 {
+			initial = s.retry
+		}
+		s.idMut.Unlock()
+		if received || boff.InitialInterval != initial {
+			boff.InitialInterval = initial
+			boff.Reset()
+		}
+
+		delay := boff.NextBackOff()
+		if err != nil {
+			s.log.Errorf("Event stream failed, reconnecting in %v: %v\n", delay, err)
+		} else {
+			s.log.Debugf("Event stream ended, reconnecting in %v\n", delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stream sends the events of a single connection to the server until it ends,
+// returning whether any events were received.
+func (s *httpSSEReader) stream(ctx context.Context, events chan<- httpSSEEvent) (bool, error) {
+	res, err := s.client.SendToResponse(ctx, nil, message.New(nil))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return false, fmt.Errorf("unexpected content type: %v", res.Header.Get("Content-Type"))
+	}
+
+	s.idMut.Lock()
+	lastID := s.lastID
+	s.idMut.Unlock()
+	s.log.Infof("Receiving server-sent events from: %v\n", s.conf.URL)
+
+	parser := newSSEParser(res.Body, lastID, s.conf.MaxBuffer)
+	received := false
+	for {
+		ev, err := parser.Next()
+
+		s.idMut.Lock()
+		s.lastID = parser.lastID
+		if parser.retry > 0 {
+			s.retry = parser.retry
+		}
+		s.idMut.Unlock()
+
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return received, nil
+			}
+			return received, err
+		}
+		received = true
+
+		s.cpMut.Lock()
+		resolve := s.pending.Track(ev.id, 1)
+		s.cpMut.Unlock()
+
+		if len(s.eventTypes) > 0 {
+			if _, exists := s.eventTypes[ev.eventType]; !exists {
+				s.commit(resolve)
+				continue
+			}
+		}
+
+		part := message.NewPart([]byte(ev.data))
+		part.Metadata().
+			Set("sse_event", ev.eventType).
+			Set("sse_id", ev.id)
+		msg := message.New(nil)
+		msg.Append(part)
+
+		select {
+		case events <- httpSSEEvent{msg: msg, resolve: resolve}:
+		case <-ctx.Done():
+			return received, nil
+		}
+	}
+}
+
+// commit marks an event as delivered, storing the latest event ID for which
+// all events before it have been delivered when the checkpoint period has
+// elapsed.
+func (s *httpSSEReader) commit(resolve func() interface{}) {
+	s.cpMut.Lock()
+	defer s.cpMut.Unlock()
+
+	if id, ok := resolve().(string); ok && id != s.committed {
+		s.committed = id
+		s.dirty = true
+	}
+	if time.Since(s.lastStore) >= s.period {
+		if err := s.storeLastEventID(context.Background()); err != nil {
+			s.log.Errorf("Failed to store last event ID: %v\n", err)
+		}
+	}
+}
+
+// ReadWithContext returns the next event received from the stream.
+func (s *httpSSEReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	s.mut.Lock()
+	events := s.events
+	s.mut.Unlock()
+
+	if events == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case e, open := <-events:
+		if !open {
+			s.mut.Lock()
+			if s.events == events {
+				s.events, s.cancel = nil, nil
+			}
+			s.mut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		return e.msg, func(ctx context.Context, res types.Response) error {
+			if res.Error() == nil {
+				s.commit(e.resolve)
+			}
+			return nil
+		}, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (s *httpSSEReader) CloseAsync() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	select {
+	case <-s.shutC:
+	default:
+		close(s.shutC)
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	go func() {
+		s.client.Close(context.Background())
+		s.cpMut.Lock()
+		if err := s.storeLastEventID(context.Background()); err != nil {
+			s.log.Errorf("Failed to store last event ID: %v\n", err)
+		}
+		s.cpMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (s *httpSSEReader) WaitForClose(time.Duration) error {
+	return nil
+}
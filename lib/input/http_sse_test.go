@@ -0,0 +1,182 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEParser(t *testing.T) {
+	stream := "\xEF\xBB\xBF: comment\n" +
+		"data: first\n" +
+		"\n" +
+		"event: update\n" +
+		"id: 1\n" +
+		"data: second\n" +
+		"data:  two lines\n" +
+		"\r\n" +
+		"id: 2\n" +
+		"retry: 500\n" +
+		"\n" +
+		"event: ignored\n" +
+		"\n" +
+		"data\n" +
+		"id: 3\n" +
+		"retry: nope\n" +
+		"\n" +
+		"data: unterminated\n"
+
+	p := newSSEParser(strings.NewReader(stream), "0", 0)
+
+	var events []sseEvent
+	for {
+		ev, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		events = append(events, ev)
+	}
+	assert.Equal(t, []sseEvent{
+		{id: "0", eventType: "message", data: "first"},
+		{id: "1", eventType: "update", data: "second\n two lines"},
+		{id: "3", eventType: "message", data: ""},
+	}, events)
+	assert.Equal(t, "3", p.lastID)
+	assert.Equal(t, time.Millisecond*500, p.retry)
+}
+
+func TestHTTPSSE(t *testing.T) {
+	var reqMut sync.Mutex
+	var lastEventIDs []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		id := r.Header.Get("Last-Event-ID")
+		reqMut.Lock()
+		lastEventIDs = append(lastEventIDs, id)
+		reqMut.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		switch id {
+		case "":
+			fmt.Fprint(w, "retry: 10\n\n")
+			fmt.Fprint(w, "id: 1\nevent: update\ndata: {\"n\":1}\n\n")
+			fmt.Fprint(w, "id: 2\nevent: heartbeat\ndata: {}\n\n")
+			fmt.Fprint(w, "id: 3\nevent: update\ndata: {\"n\":3}\n\n")
+		case "3":
+			fmt.Fprint(w, "id: 4\nevent: update\ndata: {\"n\":4}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "4":
+			fmt.Fprint(w, "id: 5\nevent: update\ndata: {\"n\":5}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"state": memCache}}
+
+	conf := NewHTTPSSEConfig()
+	conf.URL = srv.URL
+	conf.EventTypes = []string{"update"}
+	conf.CheckpointCache = "state"
+	conf.CheckpointPeriod = "0s"
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	rdr, err := newHTTPSSEReader(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	for _, exp := range []string{"1", "3", "4"} {
+		msg, ackFn, err := rdr.ReadWithContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"n":%v}`, exp), string(msg.Get(0).Get()))
+		assert.Equal(t, exp, msg.Get(0).Metadata().Get("sse_id"))
+		assert.Equal(t, "update", msg.Get(0).Metadata().Get("sse_event"))
+		require.NoError(t, ackFn(ctx, response.NewAck()))
+	}
+
+	stored, err := memCache.Get(conf.CheckpointKey)
+	require.NoError(t, err)
+	assert.Equal(t, "4", string(stored))
+
+	rdr.CloseAsync()
+	require.NoError(t, rdr.WaitForClose(time.Second))
+
+	// A new reader resumes from the stored event ID.
+	rdr, err = newHTTPSSEReader(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, _, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"n":5}`, string(msg.Get(0).Get()))
+	rdr.CloseAsync()
+
+	reqMut.Lock()
+	assert.Equal(t, []string{"", "3", "4"}, lastEventIDs)
+	reqMut.Unlock()
+}
+
+func TestHTTPSSEBadContentType(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		reqs++
+		n := reqs
+		reqMut.Unlock()
+
+		if n == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, "data: nope\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: yep\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	conf := NewHTTPSSEConfig()
+	conf.URL = srv.URL
+	conf.LastEventID = "foo"
+	conf.Reconnect.InitialInterval = "10ms"
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	rdr, err := newHTTPSSEReader(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, _, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "yep", string(msg.Get(0).Get()))
+	assert.Equal(t, "foo", msg.Get(0).Metadata().Get("sse_id"))
+	assert.Equal(t, "message", msg.Get(0).Metadata().Get("sse_event"))
+	rdr.CloseAsync()
+}
@@ -0,0 +1,513 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJournald] = TypeSpec{
+		constructor: fromSimpleConstructor(NewJournald),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Reads entries from the [systemd journal](https://www.freedesktop.org/software/systemd/man/systemd-journald.service.html) of the host.`,
+		Description: `
+The journal is followed with the ` + "`journalctl`" + ` command, which must be installed on the host, and the ` + "`MESSAGE`" + ` field of each entry becomes the contents of a message. The entries read can be limited to those of specific ` + "`units`" + `, up to a maximum ` + "`priority`" + `, and those matching arbitrary fields of the journal with ` + "`matches`" + `, where multiple matches of the same field are alternatives and matches of different fields must all apply.
+
+When running within a container the journal of the host can be read by mounting its journal directory, usually ` + "`/var/log/journal`" + `, and setting it as the ` + "`directory`" + ` to read from.
+
+### Checkpointing
+
+Each entry of the journal has a cursor that identifies its position, and when the ` + "`journalctl`" + ` command exits it is restarted from the cursor of the last entry read. When a ` + "`checkpoint_cache`" + ` is specified the cursor of the latest entry for which all entries before it have been delivered is periodically stored within it under ` + "`checkpoint_key`" + `, which allows the input to resume where it left off after restarts. Without a stored cursor reading begins either at the oldest entry of the journal or with entries added after the input starts depending on ` + "`start_from_oldest`" + `.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- journald_cursor
+- journald_timestamp
+- All journal fields listed in ` + "`fields`" + `
+` + "```" + `
+
+Journal fields are added with their names lowercased, without any leading underscores, and prefixed with ` + "`journald_`" + `, for example ` + "`_SYSTEMD_UNIT`" + ` is added as ` + "`journald_systemd_unit`" + `. The timestamp is the time at which the entry was received by the journal in RFC 3339 format.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Unit Errors",
+				Summary: "In this example the errors of a couple of services are read from the journal of the host, with the position stored within a file cache so that no entries are missed across restarts.",
+				Config: `
+input:
+  journald:
+    units: [ nginx.service, postgresql.service ]
+    priority: err
+    checkpoint_cache: positions
+
+cache_resources:
+  - label: positions
+    file:
+      directory: /var/lib/benthos/positions
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("units", "A list of systemd units to read the entries of, or all entries when empty.", []string{"nginx.service"}).Array(),
+			docs.FieldCommon(
+				"priority", "An optional maximum priority of entries to read, either as a name or number, or a range of priorities separated by `..`.",
+				"warning", "3", "crit..notice",
+			),
+			docs.FieldAdvanced("matches", "A list of matches of journal fields in the form `FIELD=value` that entries must satisfy.", []string{"_TRANSPORT=kernel"}).Array(),
+			docs.FieldCommon("fields", "A list of journal fields to add as metadata when present within an entry.").Array(),
+			docs.FieldCommon("start_from_oldest", "Whether to begin reading from the oldest entry of the journal when no cursor has been stored, rather than the entries added after the input starts."),
+			docs.FieldAdvanced("directory", "An optional directory of journal files to read rather than the journal of the system.", "/var/log/journal"),
+			docs.FieldAdvanced("journalctl_path", "The path of the `journalctl` command."),
+			docs.FieldCommon("checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store the cursor of the journal within."),
+			docs.FieldAdvanced("checkpoint_key", "The key to store the cursor under within the `checkpoint_cache`."),
+			docs.FieldAdvanced("checkpoint_period", "The period between storing the cursor within the `checkpoint_cache`."),
+		},
+		Categories: []Category{
+			CategoryLocal,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JournaldConfig contains configuration fields for the journald input type.
+type JournaldConfig struct {
+	Units            []string `json:"units" yaml:"units"`
+	Priority         string   `json:"priority" yaml:"priority"`
+	Matches          []string `json:"matches" yaml:"matches"`
+	Fields           []string `json:"fields" yaml:"fields"`
+	StartFromOldest  bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	Directory        string   `json:"directory" yaml:"directory"`
+	JournalctlPath   string   `json:"journalctl_path" yaml:"journalctl_path"`
+	CheckpointCache  string   `json:"checkpoint_cache" yaml:"checkpoint_cache"`
+	CheckpointKey    string   `json:"checkpoint_key" yaml:"checkpoint_key"`
+	CheckpointPeriod string   `json:"checkpoint_period" yaml:"checkpoint_period"`
+}
+
+// NewJournaldConfig creates a new JournaldConfig with default values.
+func NewJournaldConfig() JournaldConfig {
+	return JournaldConfig{
+		Units:    []string{},
+		Priority: "",
+		Matches:  []string{},
+		Fields: []string{
+			"_SYSTEMD_UNIT",
+			"SYSLOG_IDENTIFIER",
+			"PRIORITY",
+			"_HOSTNAME",
+			"_PID",
+		},
+		StartFromOldest:  false,
+		Directory:        "",
+		JournalctlPath:   "journalctl",
+		CheckpointCache:  "",
+		CheckpointKey:    "journald_cursor",
+		CheckpointPeriod: "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewJournald creates a new journald input type.
+func NewJournald(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newJournaldReader(conf.Journald, mgr, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeJournald, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+var journaldPriorityRegexp = regexp.MustCompile(`^(emerg|alert|crit|err|warning|notice|info|debug|[0-7])(\.\.(emerg|alert|crit|err|warning|notice|info|debug|[0-7]))?$`)
+
+// journaldMetaKey converts the name of a journal field to a metadata key.
+func journaldMetaKey(field string) string {
+	return "journald_" + strings.ToLower(strings.TrimLeft(field, "_"))
+}
+
+// journaldValue decodes the value of a journal field, which is either a
+// string, an array of bytes when the value isn't valid text, or an array of
+// either when an entry contains the field multiple times, in which case the
+// first value is used.
+func journaldValue(raw json.RawMessage) ([]byte, bool) {
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return []byte(str), true
+	}
+	var bytesValue []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		bytesValue = make([]byte, len(ints))
+		for i, n := range ints {
+			bytesValue[i] = byte(n)
+		}
+		return bytesValue, true
+	}
+	var values []json.RawMessage
+	if json.Unmarshal(raw, &values) == nil && len(values) > 0 {
+		return journaldValue(values[0])
+	}
+	return nil, false
+}
+
+type journaldReader struct {
+	conf   JournaldConfig
+	mgr    types.Manager
+	log    log.Modular
+	period time.Duration
+
+	// The cursor of the last entry read, which persists across restarts of
+	// journalctl.
+	cursorMut sync.Mutex
+	cursor    string
+
+	cpMut     sync.Mutex
+	pending   *checkpoint.Type
+	committed string
+	dirty     bool
+	lastStore time.Time
+
+	mut    sync.Mutex
+	msgs   chan journaldEntry
+	cancel func()
+	shutC  chan struct{}
+}
+
+type journaldEntry struct {
+	msg     types.Message
+	resolve func() interface{}
+}
+
+func newJournaldReader(conf JournaldConfig, mgr types.Manager, log log.Modular) (*journaldReader, error) {
+	if conf.JournalctlPath == "" {
+		return nil, errors.New("a journalctl_path must be specified")
+	}
+	if conf.Priority != "" && !journaldPriorityRegexp.MatchString(conf.Priority) {
+		return nil, fmt.Errorf("priority value not recognised: %v", conf.Priority)
+	}
+	for _, m := range conf.Matches {
+		if !strings.Contains(m, "=") {
+			return nil, fmt.Errorf("match %q must be in the form FIELD=value", m)
+		}
+	}
+
+	j := &journaldReader{
+		conf:    conf,
+		mgr:     mgr,
+		log:     log,
+		pending: checkpoint.New(),
+		shutC:   make(chan struct{}),
+	}
+	if conf.CheckpointCache != "" {
+		if conf.CheckpointKey == "" {
+			return nil, errors.New("a checkpoint_key must be specified")
+		}
+		if err := interop.ProbeCache(context.Background(), mgr, conf.CheckpointCache); err != nil {
+			return nil, err
+		}
+		var err error
+		if j.period, err = time.ParseDuration(conf.CheckpointPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint period string: %v", err)
+		}
+	}
+	return j, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loadCursor reads the stored cursor from the checkpoint cache.
+func (j *journaldReader) loadCursor(ctx context.Context) (string, error) {
+	if j.conf.CheckpointCache == "" {
+		return "", nil
+	}
+
+	var stored []byte
+	var getErr error
+	if err := interop.AccessCache(ctx, j.mgr, j.conf.CheckpointCache, func(cache types.Cache) {
+		stored, getErr = cache.Get(j.conf.CheckpointKey)
+	}); err != nil {
+		return "", err
+	}
+	if getErr != nil {
+		if getErr == types.ErrKeyNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read cursor: %w", getErr)
+	}
+	return string(stored), nil
+}
+
+// storeCursor writes the committed cursor to the checkpoint cache, the
+// checkpoint mutex must be held.
+func (j *journaldReader) storeCursor(ctx context.Context) error {
+	if j.conf.CheckpointCache == "" || !j.dirty {
+		return nil
+	}
+
+	var setErr error
+	if err := interop.AccessCache(ctx, j.mgr, j.conf.CheckpointCache, func(cache types.Cache) {
+		setErr = cache.Set(j.conf.CheckpointKey, []byte(j.committed))
+	}); err != nil {
+		return err
+	}
+	if setErr != nil {
+		return setErr
+	}
+	j.dirty = false
+	j.lastStore = time.Now()
+	return nil
+}
+
+func (j *journaldReader) args(cursor string) []string {
+	args := []string{"--follow", "--no-pager", "--output=json"}
+	if j.conf.Directory != "" {
+		args = append(args, "--directory="+j.conf.Directory)
+	}
+	switch {
+	case cursor != "":
+		args = append(args, "--after-cursor="+cursor)
+	case j.conf.StartFromOldest:
+		args = append(args, "--lines=all")
+	default:
+		args = append(args, "--lines=0")
+	}
+	for _, u := range j.conf.Units {
+		args = append(args, "--unit="+u)
+	}
+	if j.conf.Priority != "" {
+		args = append(args, "--priority="+j.conf.Priority)
+	}
+	return append(args, j.conf.Matches...)
+}
+
+// ConnectWithContext starts following the journal from the cursor of the last
+// entry read, or otherwise the stored cursor.
+func (j *journaldReader) ConnectWithContext(ctx context.Context) error {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	select {
+	case <-j.shutC:
+		return types.ErrTypeClosed
+	default:
+	}
+	if j.msgs != nil {
+		return nil
+	}
+
+	j.cursorMut.Lock()
+	cursor := j.cursor
+	j.cursorMut.Unlock()
+	if cursor == "" {
+		var err error
+		if cursor, err = j.loadCursor(ctx); err != nil {
+			return err
+		}
+	}
+
+	cmdCtx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(cmdCtx, j.conf.JournalctlPath, j.args(cursor)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+
+	j.msgs, j.cancel = make(chan journaldEntry), cancel
+	go j.read(cmdCtx, cmd, stdout, &stderr, j.msgs)
+
+	if cursor != "" {
+		j.log.Infof("Reading journal entries after cursor: %v\n", cursor)
+	} else {
+		j.log.Infoln("Reading journal entries")
+	}
+	return nil
+}
+
+func (j *journaldReader) read(ctx context.Context, cmd *exec.Cmd, stdout io.Reader, stderr *bytes.Buffer, msgs chan journaldEntry) {
+	defer close(msgs)
+
+	dec := json.NewDecoder(stdout)
+	for {
+		var fields map[string]json.RawMessage
+		if err := dec.Decode(&fields); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				j.log.Errorf("Failed to decode journal entry: %v\n", err)
+			}
+			break
+		}
+
+		entry, cursor := j.entry(fields)
+		if cursor == "" {
+			continue
+		}
+		j.cursorMut.Lock()
+		j.cursor = cursor
+		j.cursorMut.Unlock()
+
+		j.cpMut.Lock()
+		resolve := j.pending.Track(cursor, 1)
+		j.cpMut.Unlock()
+
+		select {
+		case msgs <- journaldEntry{msg: entry, resolve: resolve}:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	// Stdout must be fully read before waiting for the command.
+	io.Copy(ioutil.Discard, stdout)
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		j.log.Errorf("journalctl exited: %v: %s\n", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+}
+
+// entry converts the fields of a journal entry to a message, returning it
+// along with the cursor of the entry.
+func (j *journaldReader) entry(fields map[string]json.RawMessage) (types.Message, string) {
+	var cursor string
+	if raw, exists := fields["__CURSOR"]; exists {
+		if v, ok := journaldValue(raw); ok {
+			cursor = string(v)
+		}
+	}
+
+	var content []byte
+	if raw, exists := fields["MESSAGE"]; exists {
+		content, _ = journaldValue(raw)
+	}
+
+	part := message.NewPart(content)
+	meta := part.Metadata()
+	meta.Set("journald_cursor", cursor)
+	if raw, exists := fields["__REALTIME_TIMESTAMP"]; exists {
+		v, _ := journaldValue(raw)
+		if us, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			meta.Set("journald_timestamp", time.Unix(0, us*int64(time.Microsecond)).UTC().Format(time.RFC3339Nano))
+		}
+	}
+	for _, f := range j.conf.Fields {
+		if raw, exists := fields[f]; exists {
+			if v, ok := journaldValue(raw); ok {
+				meta.Set(journaldMetaKey(f), string(v))
+			}
+		}
+	}
+
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg, cursor
+}
+
+// commit marks an entry as delivered, storing the latest cursor for which all
+// entries before it have been delivered when the checkpoint period has
+// elapsed.
+func (j *journaldReader) commit(resolve func() interface{}) {
+	j.cpMut.Lock()
+	defer j.cpMut.Unlock()
+
+	if cursor, ok := resolve().(string); ok && cursor != j.committed {
+		j.committed = cursor
+		j.dirty = true
+	}
+	if time.Since(j.lastStore) >= j.period {
+		if err := j.storeCursor(context.Background()); err != nil {
+			j.log.Errorf("Failed to store cursor: %v\n", err)
+		}
+	}
+}
+
+// ReadWithContext returns the next entry read from the journal.
+func (j *journaldReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	j.mut.Lock()
+	msgs := j.msgs
+	j.mut.Unlock()
+
+	if msgs == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case e, open := <-msgs:
+		if !open {
+			j.mut.Lock()
+			if j.msgs == msgs {
+				j.msgs, j.cancel = nil, nil
+			}
+			j.mut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		return e.msg, func(ctx context.Context, res types.Response) error {
+			if res.Error() == nil {
+				j.commit(e.resolve)
+			}
+			return nil
+		}, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (j *journaldReader) CloseAsync() {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	select {
+	case <-j.shutC:
+	default:
+		close(j.shutC)
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+
+	go func() {
+		j.cpMut.Lock()
+		if err := j.storeCursor(context.Background()); err != nil {
+			j.log.Errorf("Failed to store cursor: %v\n", err)
+		}
+		j.cpMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (j *journaldReader) WaitForClose(time.Duration) error {
+	return nil
+}
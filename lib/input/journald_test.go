@@ -0,0 +1,122 @@
+package input
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournald(t *testing.T) {
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args")
+	journalctl := filepath.Join(dir, "journalctl")
+	require.NoError(t, ioutil.WriteFile(journalctl, []byte(`#!/bin/sh
+echo "$@" >> `+argsPath+`
+case "$*" in
+  *--after-cursor=c2*)
+    echo '{"__CURSOR":"c3","MESSAGE":"three","_SYSTEMD_UNIT":"nginx.service"}'
+    exec sleep 60
+    ;;
+  *)
+    echo '{"__CURSOR":"c1","__REALTIME_TIMESTAMP":"1600000000123456","MESSAGE":"one","_SYSTEMD_UNIT":"nginx.service","PRIORITY":"3","_PID":"42","_COMM":"nginx"}'
+    echo '{"__CURSOR":"c2","MESSAGE":[104,105,0],"_SYSTEMD_UNIT":["nginx.service","other.service"]}'
+    ;;
+esac
+`), 0o755))
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"state": memCache}}
+
+	conf := NewJournaldConfig()
+	conf.JournalctlPath = journalctl
+	conf.Units = []string{"nginx.service"}
+	conf.Priority = "err"
+	conf.Matches = []string{"_TRANSPORT=stdout"}
+	conf.CheckpointCache = "state"
+	conf.CheckpointPeriod = "0s"
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	rdr, err := newJournaldReader(conf, mgr, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, ackOne, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	p := msg.Get(0)
+	assert.Equal(t, "one", string(p.Get()))
+	assert.Equal(t, "c1", p.Metadata().Get("journald_cursor"))
+	assert.Equal(t, "2020-09-13T12:26:40.123456Z", p.Metadata().Get("journald_timestamp"))
+	assert.Equal(t, "nginx.service", p.Metadata().Get("journald_systemd_unit"))
+	assert.Equal(t, "3", p.Metadata().Get("journald_priority"))
+	assert.Equal(t, "42", p.Metadata().Get("journald_pid"))
+	assert.Equal(t, "", p.Metadata().Get("journald_comm"))
+
+	msg, ackTwo, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	p = msg.Get(0)
+	assert.Equal(t, []byte("hi\x00"), p.Get())
+	assert.Equal(t, "nginx.service", p.Metadata().Get("journald_systemd_unit"))
+
+	// The command exits and is restarted after the last entry read.
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, _, err = rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "three", string(msg.Get(0).Get()))
+
+	// Nothing is stored until all earlier entries are delivered.
+	require.NoError(t, ackTwo(ctx, response.NewAck()))
+	_, err = memCache.Get("journald_cursor")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, ackOne(ctx, response.NewAck()))
+	stored, err := memCache.Get("journald_cursor")
+	require.NoError(t, err)
+	assert.Equal(t, "c2", string(stored))
+	rdr.CloseAsync()
+
+	// A new reader resumes from the stored cursor.
+	rdr, err = newJournaldReader(conf, mgr, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+	msg, _, err = rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "three", string(msg.Get(0).Get()))
+	rdr.CloseAsync()
+
+	args, err := ioutil.ReadFile(argsPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"--follow --no-pager --output=json --lines=0 --unit=nginx.service --priority=err _TRANSPORT=stdout",
+		"--follow --no-pager --output=json --after-cursor=c2 --unit=nginx.service --priority=err _TRANSPORT=stdout",
+		"--follow --no-pager --output=json --after-cursor=c2 --unit=nginx.service --priority=err _TRANSPORT=stdout",
+	}, strings.Split(strings.TrimSpace(string(args)), "\n"))
+}
+
+func TestJournaldBadConfig(t *testing.T) {
+	conf := NewJournaldConfig()
+	conf.Priority = "loud"
+	_, err := newJournaldReader(conf, nil, log.Noop())
+	assert.EqualError(t, err, "priority value not recognised: loud")
+
+	conf = NewJournaldConfig()
+	conf.Matches = []string{"_TRANSPORT"}
+	_, err = newJournaldReader(conf, nil, log.Noop())
+	assert.EqualError(t, err, `match "_TRANSPORT" must be in the form FIELD=value`)
+}
@@ -55,7 +55,31 @@ This input adds the following metadata fields to each message:
 
 The field ` + "`kafka_lag`" + ` is the calculated difference between the high water mark offset of the partition at the time of ingestion and the current message offset.
 
-You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).
+
+### Rolling Deploys
+
+By default each restart of a consumer triggers two rebalances of the consumer group, one when it leaves and another when it rejoins, during which the consumption of all members is paused. Setting ` + "`group.instance_id`" + ` to an identifier that is unique to each member and stable across restarts, such as a Kubernetes StatefulSet pod name, enables static membership, where a member that restarts within ` + "`group.session_timeout`" + ` rejoins with its previous assignments without a rebalance. The session timeout should therefore be increased beyond the expected restart time of a member.
+
+When rebalances do occur the ` + "`sticky`" + ` value of ` + "`group.rebalance_strategy`" + ` minimises partition movement by preserving as many existing assignments as possible. Note that the Kafka client used by this input does not support the incremental cooperative rebalance protocol, and therefore members still revoke their partitions for the duration of a rebalance.
+
+### Autoscaling
+
+When ` + "`scaling.enabled`" + ` is true the offsets of all partitions consumed by the consumer group are obtained at the interval ` + "`scaling.interval`" + `, from which the total lag of the group and its rates of consumption and production (in messages per second) are calculated. These are exposed as the gauges ` + "`scaling.lag`, `scaling.consume_rate` and `scaling.produce_rate`" + `, along with the gauge ` + "`scaling.desired_replicas`" + `, which is a hint of the number of replicas required in order for each to have a lag no greater than ` + "`scaling.target_lag_per_replica`" + `.
+
+Since the signal describes the entire consumer group every replica reports the same values, and they are also served as a JSON object from the endpoint ` + "`/scaling`" + `, which can be used directly as an external metrics source for tools such as [KEDA](https://keda.sh/) and the Kubernetes Horizontal Pod Autoscaler:
+
+` + "```json" + `
+{
+  "consumer_group": "benthos_consumer_group",
+  "partitions": 12,
+  "lag": 5230,
+  "consume_rate": 850.5,
+  "produce_rate": 1020.2,
+  "desired_replicas": 6,
+  "timestamp": "2021-06-01T12:00:00Z"
+}
+` + "```" + ``,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
 				"addresses", "A list of broker addresses to connect to. If an item of the list contains commas it will be expanded into multiple addresses.",
@@ -85,6 +109,8 @@ You can access these metadata fields using [function interpolation](/docs/config
 				docs.FieldAdvanced("session_timeout", "A period after which a consumer of the group is kicked after no heartbeats."),
 				docs.FieldAdvanced("heartbeat_interval", "A period in which heartbeats should be sent out."),
 				docs.FieldAdvanced("rebalance_timeout", "A period after which rebalancing is abandoned if unresolved."),
+				docs.FieldAdvanced("rebalance_strategy", "The strategy for allocating topic partitions to members of the consumer group. The `sticky` strategy preserves as many existing assignments as possible during a rebalance, which minimises partition movement during rolling deploys. The incremental cooperative rebalance protocol (`cooperative-sticky`) is not supported, and partitions are always revoked from all members during a rebalance.").HasOptions("range", "roundrobin", "sticky").AtVersion("3.47.0"),
+				docs.FieldAdvanced("instance_id", "An optional identifier that enables static membership of the consumer group, which must be unique for each member of the group and remain the same across restarts. A static member that restarts within the `session_timeout` rejoins the group with its previous assignments without triggering a rebalance. Requires a `target_version` of at least `2.3.0`.", "${HOSTNAME}").AtVersion("3.47.0"),
 			),
 			docs.FieldAdvanced("fetch_buffer_cap", "The maximum number of unprocessed messages to fetch at a given time."),
 			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use."),
@@ -93,6 +119,13 @@ You can access these metadata fields using [function interpolation](/docs/config
 				b.Advanced = true
 				return b
 			}(),
+			docs.FieldAdvanced("scaling", "Expose the lag and throughput of the consumer group as an autoscaling signal.").WithChildren(
+				docs.FieldAdvanced("enabled", "Whether to calculate the autoscaling signal."),
+				docs.FieldAdvanced("interval", "The period of time between each calculation of the signal."),
+				docs.FieldAdvanced("target_lag_per_replica", "The lag that each replica should have at most, which determines the desired number of replicas."),
+				docs.FieldAdvanced("min_replicas", "The minimum number of desired replicas."),
+				docs.FieldAdvanced("max_replicas", "The maximum number of desired replicas, when zero the number of partitions consumed is used."),
+			).AtVersion("3.47.0"),
 
 			// TODO: Remove V4
 			docs.FieldDeprecated("max_batch_count"),
@@ -171,6 +204,7 @@ type kafkaReader struct {
 	sessionTimeout    time.Duration
 	heartbeatInterval time.Duration
 	rebalanceTimeout  time.Duration
+	balanceStrategy   sarama.BalanceStrategy
 	maxProcPeriod     time.Duration
 
 	// Connection resources
@@ -182,6 +216,9 @@ type kafkaReader struct {
 
 	mRebalanced metrics.StatCounter
 
+	scaler     *kafkaScaler
+	scalerOnce sync.Once
+
 	conf  reader.KafkaConfig
 	stats metrics.Type
 	log   log.Modular
@@ -314,11 +351,28 @@ func newKafkaReader(
 	if conf.ConsumerGroup == "" && len(k.balancedTopics) > 0 {
 		return nil, errors.New("a consumer group must be specified when consuming balanced topics")
 	}
+	if conf.Scaling.Enabled {
+		var err error
+		if k.scaler, err = newKafkaScaler(conf, log, stats); err != nil {
+			return nil, err
+		}
+		mgr.RegisterEndpoint(
+			KafkaScalingEndpoint,
+			"Get the lag, throughput and desired number of replicas of the Kafka consumer group.",
+			k.scaler.handleSnapshot,
+		)
+	}
 
 	var err error
+	if k.balanceStrategy, err = reader.KafkaBalanceStrategy(conf.Group.RebalanceStrategy); err != nil {
+		return nil, err
+	}
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if conf.Group.InstanceID != "" && !k.version.IsAtLeast(sarama.V2_3_0_0) {
+		return nil, fmt.Errorf("a target_version of at least 2.3.0 is required for static membership with group.instance_id, got %v", conf.TargetVersion)
+	}
 	return &k, nil
 }
 
@@ -471,6 +525,8 @@ func (k *kafkaReader) ConnectWithContext(ctx context.Context) error {
 	config.Consumer.Group.Session.Timeout = k.sessionTimeout
 	config.Consumer.Group.Heartbeat.Interval = k.heartbeatInterval
 	config.Consumer.Group.Rebalance.Timeout = k.rebalanceTimeout
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{k.balanceStrategy}
+	config.Consumer.Group.InstanceId = k.conf.Group.InstanceID
 	config.ChannelBufferSize = k.conf.FetchBufferCap
 
 	if config.Net.ReadTimeout <= k.sessionTimeout {
@@ -492,6 +548,12 @@ func (k *kafkaReader) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
+	if k.scaler != nil {
+		k.scalerOnce.Do(func() {
+			go k.runScaler(config)
+		})
+	}
+
 	if len(k.topicPartitions) > 0 {
 		return k.connectExplicitTopics(ctx, config)
 	}
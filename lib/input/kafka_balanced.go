@@ -72,6 +72,8 @@ You can access these metadata fields using
 				docs.FieldAdvanced("session_timeout", "A period after which a consumer of the group is kicked after no heartbeats."),
 				docs.FieldAdvanced("heartbeat_interval", "A period in which heartbeats should be sent out."),
 				docs.FieldAdvanced("rebalance_timeout", "A period after which rebalancing is abandoned if unresolved."),
+				docs.FieldAdvanced("rebalance_strategy", "The strategy for allocating topic partitions to members of the consumer group. The incremental cooperative rebalance protocol (`cooperative-sticky`) is not supported.").HasOptions("range", "roundrobin", "sticky").AtVersion("3.47.0"),
+				docs.FieldAdvanced("instance_id", "An optional identifier that enables static membership of the consumer group, which must be unique for each member of the group and remain the same across restarts. Requires a `target_version` of at least `2.3.0`.").AtVersion("3.47.0"),
 			),
 			docs.FieldAdvanced("fetch_buffer_cap", "The maximum number of unprocessed messages to fetch at a given time."),
 			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use."),
@@ -184,7 +184,7 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 		// checkpointer that uses it already does this, but it's not
 		// particularly clear, hence this comment.
 		fn: func(topic string, partition int32, offset int64, metadata string) {
-			offsetPutReq.AddBlock(topic, partition, offset, time.Now().Unix(), metadata)
+			offsetPutReq.AddBlock(topic, partition, offset, 0, time.Now().Unix(), metadata)
 		},
 	}
 
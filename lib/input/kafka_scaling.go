@@ -0,0 +1,255 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+// KafkaScalingEndpoint is the path of the HTTP endpoint that serves the
+// autoscaling signal of a Kafka consumer group.
+const KafkaScalingEndpoint = "/scaling"
+
+// KafkaScalingSnapshot is the autoscaling signal of a Kafka consumer group,
+// calculated from the offsets of all partitions consumed by the group.
+type KafkaScalingSnapshot struct {
+	ConsumerGroup   string  `json:"consumer_group"`
+	Partitions      int     `json:"partitions"`
+	Lag             int64   `json:"lag"`
+	ConsumeRate     float64 `json:"consume_rate"`
+	ProduceRate     float64 `json:"produce_rate"`
+	DesiredReplicas int     `json:"desired_replicas"`
+	Timestamp       string  `json:"timestamp"`
+}
+
+type kafkaPartitionOffsets struct {
+	oldest    int64
+	newest    int64
+	committed int64
+}
+
+// kafkaScaler periodically calculates the lag and throughput of a consumer
+// group, along with a hint of the number of replicas required to consume it.
+type kafkaScaler struct {
+	conf            reader.KafkaScalingConfig
+	group           string
+	startFromOldest bool
+	interval        time.Duration
+	log             log.Modular
+
+	mLag             metrics.StatGauge
+	mConsumeRate     metrics.StatGauge
+	mProduceRate     metrics.StatGauge
+	mDesiredReplicas metrics.StatGauge
+
+	mut           sync.Mutex
+	snapshot      *KafkaScalingSnapshot
+	prevTime      time.Time
+	prevCommitted int64
+	prevNewest    int64
+}
+
+func newKafkaScaler(conf reader.KafkaConfig, log log.Modular, stats metrics.Type) (*kafkaScaler, error) {
+	if conf.ConsumerGroup == "" {
+		return nil, errors.New("a consumer group must be specified in order to enable scaling")
+	}
+	if conf.Scaling.TargetLagPerReplica <= 0 {
+		return nil, errors.New("scaling target_lag_per_replica must be greater than zero")
+	}
+	if conf.Scaling.MaxReplicas > 0 && conf.Scaling.MaxReplicas < conf.Scaling.MinReplicas {
+		return nil, errors.New("scaling max_replicas must not be less than min_replicas")
+	}
+	interval, err := time.ParseDuration(conf.Scaling.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scaling interval string: %v", err)
+	}
+	return &kafkaScaler{
+		conf:            conf.Scaling,
+		group:           conf.ConsumerGroup,
+		startFromOldest: conf.StartFromOldest,
+		interval:        interval,
+		log:             log,
+
+		mLag:             stats.GetGauge("scaling.lag"),
+		mConsumeRate:     stats.GetGauge("scaling.consume_rate"),
+		mProduceRate:     stats.GetGauge("scaling.produce_rate"),
+		mDesiredReplicas: stats.GetGauge("scaling.desired_replicas"),
+	}, nil
+}
+
+// update calculates a new snapshot from the offsets of each partition consumed
+// by the group, where the rates are calculated from the change in offsets since
+// the previous update.
+func (s *kafkaScaler) update(offsets []kafkaPartitionOffsets, now time.Time) KafkaScalingSnapshot {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	snap := KafkaScalingSnapshot{
+		ConsumerGroup: s.group,
+		Partitions:    len(offsets),
+		Timestamp:     now.Format(time.RFC3339Nano),
+	}
+
+	var sumCommitted, sumNewest int64
+	for _, o := range offsets {
+		committed := o.committed
+		if committed < 0 {
+			// The group has no committed offset for the partition yet, and
+			// will either begin at the oldest or newest offset.
+			committed = o.newest
+			if s.startFromOldest {
+				committed = o.oldest
+			}
+		}
+		if lag := o.newest - committed; lag > 0 {
+			snap.Lag += lag
+		}
+		sumCommitted += committed
+		sumNewest += o.newest
+	}
+
+	if !s.prevTime.IsZero() {
+		if elapsed := now.Sub(s.prevTime).Seconds(); elapsed > 0 {
+			snap.ConsumeRate = math.Max(0, float64(sumCommitted-s.prevCommitted)/elapsed)
+			snap.ProduceRate = math.Max(0, float64(sumNewest-s.prevNewest)/elapsed)
+		}
+	}
+	s.prevTime, s.prevCommitted, s.prevNewest = now, sumCommitted, sumNewest
+
+	// There is no benefit in having more consumers of a group than partitions.
+	maxReplicas := len(offsets)
+	if s.conf.MaxReplicas > 0 {
+		maxReplicas = s.conf.MaxReplicas
+	}
+	snap.DesiredReplicas = int(math.Ceil(float64(snap.Lag) / float64(s.conf.TargetLagPerReplica)))
+	if snap.DesiredReplicas > maxReplicas {
+		snap.DesiredReplicas = maxReplicas
+	}
+	if snap.DesiredReplicas < s.conf.MinReplicas {
+		snap.DesiredReplicas = s.conf.MinReplicas
+	}
+
+	s.mLag.Set(snap.Lag)
+	s.mConsumeRate.Set(int64(math.Round(snap.ConsumeRate)))
+	s.mProduceRate.Set(int64(math.Round(snap.ProduceRate)))
+	s.mDesiredReplicas.Set(int64(snap.DesiredReplicas))
+
+	s.snapshot = &snap
+	return snap
+}
+
+func (s *kafkaScaler) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	snap := s.snapshot
+	s.mut.Unlock()
+
+	if snap == nil {
+		http.Error(w, "the scaling signal has not yet been calculated", http.StatusServiceUnavailable)
+		return
+	}
+	resBytes, err := json.Marshal(snap)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resBytes)
+}
+
+//------------------------------------------------------------------------------
+
+// fetchOffsets obtains the oldest, newest and committed offsets of each
+// partition consumed by the group.
+func (k *kafkaReader) fetchOffsets(client sarama.Client) ([]kafkaPartitionOffsets, error) {
+	topicPartitions := map[string][]int32{}
+	for topic, parts := range k.topicPartitions {
+		topicPartitions[topic] = parts
+	}
+	for _, topic := range k.balancedTopics {
+		parts, err := client.Partitions(topic)
+		if err != nil {
+			return nil, err
+		}
+		topicPartitions[topic] = parts
+	}
+
+	// Closing the admin would also close the client, and so it is left for the
+	// client to be closed by the caller.
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := admin.ListConsumerGroupOffsets(k.conf.ConsumerGroup, topicPartitions)
+	if err != nil {
+		return nil, err
+	}
+
+	var offsets []kafkaPartitionOffsets
+	for topic, parts := range topicPartitions {
+		for _, part := range parts {
+			o := kafkaPartitionOffsets{committed: -1}
+			if o.newest, err = client.GetOffset(topic, part, sarama.OffsetNewest); err != nil {
+				return nil, err
+			}
+			if o.oldest, err = client.GetOffset(topic, part, sarama.OffsetOldest); err != nil {
+				return nil, err
+			}
+			if block := committed.GetBlock(topic, part); block != nil && block.Err == sarama.ErrNoError {
+				o.committed = block.Offset
+			}
+			offsets = append(offsets, o)
+		}
+	}
+	return offsets, nil
+}
+
+// runScaler updates the scaling signal at the configured interval until the
+// reader is closed.
+func (k *kafkaReader) runScaler(config *sarama.Config) {
+	ticker := time.NewTicker(k.scaler.interval)
+	defer ticker.Stop()
+
+	var client sarama.Client
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+
+	for {
+		var err error
+		if client == nil {
+			client, err = sarama.NewClient(k.addresses, config)
+		}
+		var offsets []kafkaPartitionOffsets
+		if err == nil {
+			offsets, err = k.fetchOffsets(client)
+		}
+		if err != nil {
+			k.log.Errorf("Failed to obtain offsets for scaling signal: %v\n", err)
+			if client != nil {
+				client.Close()
+				client = nil
+			}
+		} else {
+			k.scaler.update(offsets, time.Now())
+		}
+
+		select {
+		case <-ticker.C:
+		case <-k.closedChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package input
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaScalerUpdate(t *testing.T) {
+	conf := reader.NewKafkaConfig()
+	conf.Scaling.Enabled = true
+	conf.Scaling.TargetLagPerReplica = 100
+	conf.Scaling.MinReplicas = 1
+
+	s, err := newKafkaScaler(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	s.handleSnapshot(rec, httptest.NewRequest("GET", KafkaScalingEndpoint, nil))
+	assert.Equal(t, 503, rec.Code)
+
+	start := time.Unix(1000, 0)
+	snap := s.update([]kafkaPartitionOffsets{
+		{oldest: 0, newest: 200, committed: 100},
+		{oldest: 0, newest: 150, committed: 150},
+		{oldest: 50, newest: 300, committed: -1},
+	}, start)
+	assert.Equal(t, int64(100+0+250), snap.Lag)
+	assert.Equal(t, 3, snap.Partitions)
+	assert.Equal(t, 3, snap.DesiredReplicas)
+	assert.Equal(t, 0.0, snap.ConsumeRate)
+
+	snap = s.update([]kafkaPartitionOffsets{
+		{oldest: 0, newest: 400, committed: 380},
+		{oldest: 0, newest: 150, committed: 150},
+		{oldest: 50, newest: 300, committed: 290},
+	}, start.Add(10*time.Second))
+	assert.Equal(t, int64(20+0+10), snap.Lag)
+	assert.Equal(t, 1, snap.DesiredReplicas)
+	assert.Equal(t, float64(280+240)/10, snap.ConsumeRate)
+	assert.Equal(t, float64(200)/10, snap.ProduceRate)
+
+	rec = httptest.NewRecorder()
+	s.handleSnapshot(rec, httptest.NewRequest("GET", KafkaScalingEndpoint, nil))
+	require.Equal(t, 200, rec.Code)
+
+	var served map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, "benthos_consumer_group", served["consumer_group"])
+	assert.Equal(t, float64(30), served["lag"])
+	assert.Equal(t, float64(1), served["desired_replicas"])
+}
+
+func TestKafkaScalerReplicaBounds(t *testing.T) {
+	conf := reader.NewKafkaConfig()
+	conf.StartFromOldest = false
+	conf.Scaling.TargetLagPerReplica = 10
+	conf.Scaling.MinReplicas = 2
+	conf.Scaling.MaxReplicas = 4
+
+	s, err := newKafkaScaler(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	snap := s.update([]kafkaPartitionOffsets{
+		{oldest: 0, newest: 10, committed: -1},
+	}, time.Now())
+	assert.Equal(t, int64(0), snap.Lag)
+	assert.Equal(t, 2, snap.DesiredReplicas)
+
+	snap = s.update([]kafkaPartitionOffsets{
+		{oldest: 0, newest: 1000, committed: 0},
+	}, time.Now())
+	assert.Equal(t, 4, snap.DesiredReplicas)
+
+	conf.ConsumerGroup = ""
+	_, err = newKafkaScaler(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a consumer group must be specified in order to enable scaling")
+}
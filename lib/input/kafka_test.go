@@ -3,6 +3,7 @@ package input
 import (
 	"testing"
 
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +13,7 @@ func TestKafkaBadParams(t *testing.T) {
 	testCases := []struct {
 		name   string
 		topics []string
+		conf   func(c *reader.KafkaConfig)
 		errStr string
 	}{
 		{
@@ -29,6 +31,30 @@ func TestKafkaBadParams(t *testing.T) {
 			topics: []string{"foo:1-2-3"},
 			errStr: "failed to create input 'kafka': partition '1-2-3' is invalid, only one range can be specified",
 		},
+		{
+			name:   "bad rebalance strategy",
+			topics: []string{"foo"},
+			conf: func(c *reader.KafkaConfig) {
+				c.Group.RebalanceStrategy = "nope"
+			},
+			errStr: "failed to create input 'kafka': rebalance strategy not recognised: nope",
+		},
+		{
+			name:   "cooperative rebalance strategy",
+			topics: []string{"foo"},
+			conf: func(c *reader.KafkaConfig) {
+				c.Group.RebalanceStrategy = "cooperative-sticky"
+			},
+			errStr: "failed to create input 'kafka': rebalance strategy cooperative-sticky is not supported as the incremental cooperative rebalance protocol is not implemented by this input, use sticky instead",
+		},
+		{
+			name:   "static membership with old version",
+			topics: []string{"foo"},
+			conf: func(c *reader.KafkaConfig) {
+				c.Group.InstanceID = "foo-0"
+			},
+			errStr: "failed to create input 'kafka': a target_version of at least 2.3.0 is required for static membership with group.instance_id, got 1.0.0",
+		},
 	}
 
 	for _, test := range testCases {
@@ -38,6 +64,9 @@ func TestKafkaBadParams(t *testing.T) {
 			conf.Type = TypeKafka
 			conf.Kafka.Addresses = []string{"example.com:1234"}
 			conf.Kafka.Topics = test.topics
+			if test.conf != nil {
+				test.conf(&conf.Kafka)
+			}
 
 			_, err := New(conf, nil, log.Noop(), metrics.Noop())
 			assert.EqualError(t, err, test.errStr)
@@ -0,0 +1,601 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/kubernetes"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeKubernetesWatch] = TypeSpec{
+		constructor: fromSimpleConstructor(NewKubernetesWatch),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Watches resources of a Kubernetes cluster and emits an event each time one is added, modified or deleted.`,
+		Description: `
+Any kind of resource served by the cluster can be watched, including custom resources, by specifying its ` + "`api_version`" + ` and ` + "`kind`" + `. Each change becomes a message containing a JSON object with the type of the change and the resource as it was after the change, or before it in the case of a deletion:
+
+` + "```json" + `
+{
+  "type": "MODIFIED",
+  "object": {
+    "apiVersion": "apps/v1",
+    "kind": "Deployment",
+    "metadata": { "name": "web", "namespace": "shop", "resourceVersion": "48213" },
+    "spec": { "replicas": 3 }
+  }
+}
+` + "```" + `
+
+When ` + "`include_existing`" + ` is set the resources that already exist are emitted as ` + "`ADDED`" + ` events when the input starts watching them.
+
+### Credentials
+
+When running within a pod the service account of the pod is used, and otherwise a context of a [kubeconfig file](https://kubernetes.io/docs/concepts/configuration/organize-cluster-access-kubeconfig/) is used, by default the current context of the same file that ` + "`kubectl`" + ` uses. Credentials of kubeconfig files provided by exec or auth provider plugins are not supported.
+
+The account requires the ` + "`list`" + ` and ` + "`watch`" + ` verbs on each resource. When a resource lists ` + "`namespaces`" + ` it is watched within each of them separately, and therefore only requires permissions granted by a Role within those namespaces, whereas otherwise it is watched across the cluster and requires a ClusterRole.
+
+### Checkpointing
+
+The position of each watch is tracked by the resource version of the last change received, and is resumed from when the connection to the cluster is interrupted. When a ` + "`checkpoint_cache`" + ` is specified the resource version of each watch is also stored within it once all changes before it have been delivered, allowing the input to resume where it left off after restarts. The resource version of each resource and namespace is stored under a key made up of the ` + "`checkpoint_key`" + `, the api version, kind and namespace of the watch, separated by colons.
+
+Clusters only retain changes for a short period, and when a watch is resumed from a resource version that is no longer retained the resources are listed again, where ` + "`include_existing`" + ` determines whether they are emitted as ` + "`ADDED`" + ` events. Changes made while the watch couldn't be resumed are otherwise missed.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- kubernetes_event_type
+- kubernetes_api_version
+- kubernetes_kind
+- kubernetes_namespace
+- kubernetes_name
+- kubernetes_resource_version
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Scaled Deployments",
+				Summary: "This example watches the deployments of two namespaces and posts a notification to a webhook whenever the number of replicas of one changes, storing its progress within a Redis cache.",
+				Config: `
+input:
+  kubernetes_watch:
+    resources:
+      - api_version: apps/v1
+        kind: Deployment
+        namespaces: [ shop, payments ]
+    checkpoint_cache: k8s_state
+
+pipeline:
+  processors:
+    - bloblang: |
+        let key = meta("kubernetes_namespace") + "/" + meta("kubernetes_name")
+        root.text = "%v has %v replicas".format($key, this.object.spec.replicas)
+        root = if this.type != "MODIFIED" { deleted() }
+
+output:
+  http_client:
+    url: https://hooks.example.com/deployments
+    verb: POST
+
+cache_resources:
+  - label: k8s_state
+    redis:
+      url: redis://localhost:6379
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("kubeconfig", "An optional path to a kubeconfig file. When empty the service account of the pod is used when running within a cluster, and otherwise the kubeconfig file that `kubectl` uses."),
+			docs.FieldAdvanced("context", "The context of the kubeconfig file to use, or its current context when empty."),
+			docs.FieldCommon("resources", "A list of resources to watch.").Array().WithChildren(
+				docs.FieldCommon("api_version", "The api version of the resource.", "v1", "apps/v1", "batch/v1").HasDefault(""),
+				docs.FieldCommon("kind", "The kind of the resource.", "Pod", "Deployment", "Event").HasDefault(""),
+				docs.FieldCommon("namespaces", "An optional list of namespaces to watch the resource within. When empty the resource is watched across all namespaces, which is also the case for resources that aren't namespaced.").Array().HasDefault([]string{}),
+				docs.FieldAdvanced("label_selector", "An optional [label selector](https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors) of the resources to watch.", "app=web,tier!=cache").HasDefault(""),
+				docs.FieldAdvanced("field_selector", "An optional [field selector](https://kubernetes.io/docs/concepts/overview/working-with-objects/field-selectors/) of the resources to watch.", "status.phase=Running").HasDefault(""),
+			),
+			docs.FieldCommon("include_existing", "Whether to emit resources that already exist as `ADDED` events when they are first listed."),
+			docs.FieldCommon("checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store the resource version of each watch within."),
+			docs.FieldAdvanced("checkpoint_key", "The prefix of the keys that resource versions are stored under within the `checkpoint_cache`."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// KubernetesWatchResourceConfig contains configuration fields of a resource
+// to watch.
+type KubernetesWatchResourceConfig struct {
+	APIVersion    string   `json:"api_version" yaml:"api_version"`
+	Kind          string   `json:"kind" yaml:"kind"`
+	Namespaces    []string `json:"namespaces" yaml:"namespaces"`
+	LabelSelector string   `json:"label_selector" yaml:"label_selector"`
+	FieldSelector string   `json:"field_selector" yaml:"field_selector"`
+}
+
+// KubernetesWatchConfig contains configuration fields for the KubernetesWatch
+// input type.
+type KubernetesWatchConfig struct {
+	Kubeconfig      string                          `json:"kubeconfig" yaml:"kubeconfig"`
+	Context         string                          `json:"context" yaml:"context"`
+	Resources       []KubernetesWatchResourceConfig `json:"resources" yaml:"resources"`
+	IncludeExisting bool                            `json:"include_existing" yaml:"include_existing"`
+	CheckpointCache string                          `json:"checkpoint_cache" yaml:"checkpoint_cache"`
+	CheckpointKey   string                          `json:"checkpoint_key" yaml:"checkpoint_key"`
+}
+
+// NewKubernetesWatchConfig creates a new KubernetesWatchConfig with default
+// values.
+func NewKubernetesWatchConfig() KubernetesWatchConfig {
+	return KubernetesWatchConfig{
+		Kubeconfig:      "",
+		Context:         "",
+		Resources:       []KubernetesWatchResourceConfig{},
+		IncludeExisting: true,
+		CheckpointCache: "",
+		CheckpointKey:   "kubernetes_watch",
+	}
+}
+
+// NewKubernetesWatch creates a new KubernetesWatch input type.
+func NewKubernetesWatch(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newKubernetesWatchReader(conf.KubernetesWatch, mgr, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeKubernetesWatch, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+// kubeWatchCheckpoint tracks the resource versions of the changes of a watch
+// in order to commit the latest one for which all changes before it have been
+// delivered.
+type kubeWatchCheckpoint struct {
+	mut     sync.Mutex
+	pending []*kubeWatchPending
+	commit  func(resourceVersion string)
+}
+
+type kubeWatchPending struct {
+	resourceVersion string
+	done            bool
+}
+
+func (c *kubeWatchCheckpoint) track(resourceVersion string) *kubeWatchPending {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	p := &kubeWatchPending{resourceVersion: resourceVersion}
+	c.pending = append(c.pending, p)
+	return p
+}
+
+func (c *kubeWatchCheckpoint) done(p *kubeWatchPending) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	p.done = true
+	latest := ""
+	for len(c.pending) > 0 && c.pending[0].done {
+		if rv := c.pending[0].resourceVersion; rv != "" {
+			latest = rv
+		}
+		c.pending = c.pending[1:]
+	}
+	if latest != "" {
+		c.commit(latest)
+	}
+}
+
+type kubeWatch struct {
+	resource   kubernetes.Resource
+	namespace  string
+	opts       kubernetes.ListOptions
+	key        string
+	checkpoint *kubeWatchCheckpoint
+}
+
+type kubeWatchMsg struct {
+	msg   types.Message
+	ackFn reader.AsyncAckFn
+}
+
+type kubernetesWatchReader struct {
+	conf KubernetesWatchConfig
+	mgr  types.Manager
+	log  log.Modular
+
+	// Resource versions received by each watch, which persist across
+	// reconnects.
+	versionsMut sync.Mutex
+	versions    map[string]string
+
+	mut    sync.Mutex
+	msgs   chan kubeWatchMsg
+	cancel func()
+	shutC  chan struct{}
+}
+
+func newKubernetesWatchReader(conf KubernetesWatchConfig, mgr types.Manager, log log.Modular) (*kubernetesWatchReader, error) {
+	if len(conf.Resources) == 0 {
+		return nil, errors.New("at least one resource must be specified")
+	}
+	for i, res := range conf.Resources {
+		if res.APIVersion == "" || res.Kind == "" {
+			return nil, fmt.Errorf("resource %v must specify an api_version and kind", i)
+		}
+	}
+	if conf.CheckpointCache != "" {
+		if conf.CheckpointKey == "" {
+			return nil, errors.New("a checkpoint_key must be specified")
+		}
+		if err := interop.ProbeCache(context.Background(), mgr, conf.CheckpointCache); err != nil {
+			return nil, err
+		}
+	}
+	return &kubernetesWatchReader{
+		conf:     conf,
+		mgr:      mgr,
+		log:      log,
+		versions: map[string]string{},
+		shutC:    make(chan struct{}),
+	}, nil
+}
+
+// ConnectWithContext resolves the resources to watch and starts watching them.
+func (k *kubernetesWatchReader) ConnectWithContext(ctx context.Context) error {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+
+	select {
+	case <-k.shutC:
+		return types.ErrTypeClosed
+	default:
+	}
+	if k.msgs != nil {
+		return nil
+	}
+
+	clientConf, err := kubernetes.Resolve(k.conf.Kubeconfig, k.conf.Context)
+	if err != nil {
+		return err
+	}
+	client := kubernetes.NewClient(clientConf)
+
+	var watches []*kubeWatch
+	for _, resConf := range k.conf.Resources {
+		res, err := client.Discover(ctx, resConf.APIVersion, resConf.Kind)
+		if err != nil {
+			return err
+		}
+		namespaces := resConf.Namespaces
+		if len(namespaces) == 0 || !res.Namespaced {
+			namespaces = []string{""}
+		}
+		for _, ns := range namespaces {
+			w := &kubeWatch{
+				resource:  res,
+				namespace: ns,
+				opts: kubernetes.ListOptions{
+					LabelSelector: resConf.LabelSelector,
+					FieldSelector: resConf.FieldSelector,
+				},
+				key: fmt.Sprintf("%v:%v:%v:%v", k.conf.CheckpointKey, res.APIVersion, res.Kind, ns),
+			}
+			w.checkpoint = &kubeWatchCheckpoint{commit: func(rv string) {
+				k.storeVersion(w, rv)
+			}}
+			if err := k.loadVersion(ctx, w); err != nil {
+				return err
+			}
+			watches = append(watches, w)
+		}
+	}
+
+	sessionCtx, cancel := context.WithCancel(context.Background())
+	msgs := make(chan kubeWatchMsg)
+
+	var wg sync.WaitGroup
+	wg.Add(len(watches))
+	for _, w := range watches {
+		go func(w *kubeWatch) {
+			defer wg.Done()
+			k.watch(sessionCtx, client, w, msgs)
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		close(msgs)
+	}()
+
+	k.msgs, k.cancel = msgs, cancel
+	k.log.Infof("Watching %v kubernetes resources at: %v\n", len(watches), clientConf.Server)
+	return nil
+}
+
+// loadVersion reads the stored resource version of a watch from the
+// checkpoint cache when one hasn't already been received.
+func (k *kubernetesWatchReader) loadVersion(ctx context.Context, w *kubeWatch) error {
+	k.versionsMut.Lock()
+	defer k.versionsMut.Unlock()
+
+	if _, exists := k.versions[w.key]; exists || k.conf.CheckpointCache == "" {
+		return nil
+	}
+
+	var stored []byte
+	var getErr error
+	if err := interop.AccessCache(ctx, k.mgr, k.conf.CheckpointCache, func(cache types.Cache) {
+		stored, getErr = cache.Get(w.key)
+	}); err != nil {
+		return err
+	}
+	if getErr != nil && getErr != types.ErrKeyNotFound {
+		return fmt.Errorf("failed to read resource version: %w", getErr)
+	}
+	if getErr == nil {
+		k.versions[w.key] = string(stored)
+	}
+	return nil
+}
+
+func (k *kubernetesWatchReader) storeVersion(w *kubeWatch, rv string) {
+	if k.conf.CheckpointCache == "" {
+		return
+	}
+	var setErr error
+	if err := interop.AccessCache(context.Background(), k.mgr, k.conf.CheckpointCache, func(cache types.Cache) {
+		setErr = cache.Set(w.key, []byte(rv))
+	}); err != nil {
+		setErr = err
+	}
+	if setErr != nil {
+		k.log.Errorf("Failed to store resource version: %v\n", setErr)
+	}
+}
+
+func (k *kubernetesWatchReader) setVersion(w *kubeWatch, rv string) {
+	k.versionsMut.Lock()
+	k.versions[w.key] = rv
+	k.versionsMut.Unlock()
+}
+
+func (k *kubernetesWatchReader) version(w *kubeWatch) string {
+	k.versionsMut.Lock()
+	defer k.versionsMut.Unlock()
+	return k.versions[w.key]
+}
+
+// watch streams the changes to the objects of a watch until the context is
+// cancelled, listing them again whenever the watch can't be resumed.
+func (k *kubernetesWatchReader) watch(ctx context.Context, client *kubernetes.Client, w *kubeWatch, msgs chan<- kubeWatchMsg) {
+	boff := backoff.NewExponentialBackOff()
+	boff.InitialInterval = time.Millisecond * 500
+	boff.MaxInterval = time.Second * 30
+	boff.MaxElapsedTime = 0
+
+	retry := func(err error) bool {
+		k.log.Errorf("Failed to watch %v %v: %v\n", w.resource.Kind, w.namespace, err)
+		select {
+		case <-time.After(boff.NextBackOff()):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for ctx.Err() == nil {
+		if k.version(w) == "" {
+			if err := k.list(ctx, client, w, msgs); err != nil {
+				if ctx.Err() != nil || !retry(err) {
+					return
+				}
+				continue
+			}
+		}
+
+		opts := w.opts
+		opts.ResourceVersion = k.version(w)
+		stream, err := client.Watch(ctx, w.resource, w.namespace, opts)
+		if err != nil {
+			if kubernetes.IsGone(err) {
+				k.log.Warnf("Resource version of %v %v has expired, listing them again\n", w.resource.Kind, w.namespace)
+				k.setVersion(w, "")
+				continue
+			}
+			if ctx.Err() != nil || !retry(err) {
+				return
+			}
+			continue
+		}
+
+		if err = k.readStream(ctx, stream, w, msgs); err != nil && ctx.Err() == nil {
+			if kubernetes.IsGone(err) {
+				k.log.Warnf("Resource version of %v %v has expired, listing them again\n", w.resource.Kind, w.namespace)
+				k.setVersion(w, "")
+				continue
+			}
+			if !retry(err) {
+				return
+			}
+			continue
+		}
+		boff.Reset()
+	}
+}
+
+// readStream sends the changes of a watch stream until it ends, which happens
+// routinely when the API server times out the watch.
+func (k *kubernetesWatchReader) readStream(ctx context.Context, stream *kubernetes.WatchStream, w *kubeWatch, msgs chan<- kubeWatchMsg) error {
+	defer stream.Close()
+
+	for {
+		ev, err := stream.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		meta, err := kubernetes.MetaOf(ev.Object)
+		if err != nil {
+			return fmt.Errorf("failed to decode object: %w", err)
+		}
+		if ev.Type == kubernetes.EventBookmark {
+			w.checkpoint.done(w.checkpoint.track(meta.ResourceVersion))
+			k.setVersion(w, meta.ResourceVersion)
+			continue
+		}
+		if !k.send(ctx, w, ev.Type, ev.Object, meta, meta.ResourceVersion, msgs) {
+			return nil
+		}
+		k.setVersion(w, meta.ResourceVersion)
+	}
+}
+
+// list pages through the objects of a watch in order to obtain a resource
+// version to watch from, sending them as added events when existing objects
+// are included.
+func (k *kubernetesWatchReader) list(ctx context.Context, client *kubernetes.Client, w *kubeWatch, msgs chan<- kubeWatchMsg) error {
+	opts := w.opts
+	opts.Limit = 500
+	if !k.conf.IncludeExisting {
+		opts.Limit = 1
+	}
+
+	for {
+		list, err := client.List(ctx, w.resource, w.namespace, opts)
+		if err != nil {
+			return err
+		}
+		if k.conf.IncludeExisting {
+			for _, item := range list.Items {
+				meta, err := kubernetes.MetaOf(item)
+				if err != nil {
+					return fmt.Errorf("failed to decode object: %w", err)
+				}
+				if !k.send(ctx, w, kubernetes.EventAdded, item, meta, "", msgs) {
+					return ctx.Err()
+				}
+			}
+		}
+		if list.Continue == "" || !k.conf.IncludeExisting {
+			w.checkpoint.done(w.checkpoint.track(list.ResourceVersion))
+			k.setVersion(w, list.ResourceVersion)
+			return nil
+		}
+		opts.Continue = list.Continue
+	}
+}
+
+func (k *kubernetesWatchReader) send(
+	ctx context.Context,
+	w *kubeWatch,
+	eventType string,
+	object json.RawMessage,
+	meta kubernetes.ObjectMeta,
+	checkpointVersion string,
+	msgs chan<- kubeWatchMsg,
+) bool {
+	b, err := json.Marshal(map[string]interface{}{
+		"type":   eventType,
+		"object": object,
+	})
+	if err != nil {
+		k.log.Errorf("Failed to marshal event: %v\n", err)
+		return true
+	}
+
+	part := message.NewPart(b)
+	part.Metadata().
+		Set("kubernetes_event_type", eventType).
+		Set("kubernetes_api_version", w.resource.APIVersion).
+		Set("kubernetes_kind", w.resource.Kind).
+		Set("kubernetes_namespace", meta.Namespace).
+		Set("kubernetes_name", meta.Name).
+		Set("kubernetes_resource_version", meta.ResourceVersion)
+	msg := message.New(nil)
+	msg.Append(part)
+
+	pending := w.checkpoint.track(checkpointVersion)
+	select {
+	case msgs <- kubeWatchMsg{
+		msg: msg,
+		ackFn: func(context.Context, types.Response) error {
+			w.checkpoint.done(pending)
+			return nil
+		},
+	}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ReadWithContext returns the next change received by any of the watches.
+func (k *kubernetesWatchReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	k.mut.Lock()
+	msgs := k.msgs
+	k.mut.Unlock()
+
+	if msgs == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case m, open := <-msgs:
+		if !open {
+			k.mut.Lock()
+			if k.msgs == msgs {
+				k.msgs, k.cancel = nil, nil
+			}
+			k.mut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		return m.msg, m.ackFn, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (k *kubernetesWatchReader) CloseAsync() {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+
+	select {
+	case <-k.shutC:
+	default:
+		close(k.shutC)
+	}
+	if k.cancel != nil {
+		k.cancel()
+	}
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (k *kubernetesWatchReader) WaitForClose(time.Duration) error {
+	return nil
+}
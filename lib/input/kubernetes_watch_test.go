@@ -0,0 +1,186 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func kubeTestDeployment(name, rv string) string {
+	return fmt.Sprintf(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":%q,"namespace":"shop","resourceVersion":%q},"spec":{"replicas":1}}`, name, rv)
+}
+
+func TestKubernetesWatch(t *testing.T) {
+	var watchMut sync.Mutex
+	var watchedFrom []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/apps/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"resources":[
+			{"name":"deployments/scale","kind":"Scale","namespaced":true},
+			{"name":"deployments","kind":"Deployment","namespaced":true}
+		]}`))
+	})
+	mux.HandleFunc("/apis/apps/v1/namespaces/shop/deployments", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer foo", r.Header.Get("Authorization"))
+		assert.Equal(t, "app=web", r.URL.Query().Get("labelSelector"))
+
+		if r.URL.Query().Get("watch") != "true" {
+			if r.URL.Query().Get("continue") == "" {
+				fmt.Fprintf(w, `{"metadata":{"continue":"page2"},"items":[%v,%v]}`, kubeTestDeployment("a", "90"), kubeTestDeployment("b", "91"))
+			} else {
+				fmt.Fprintf(w, `{"metadata":{"resourceVersion":"100"},"items":[%v]}`, kubeTestDeployment("c", "92"))
+			}
+			return
+		}
+
+		rv := r.URL.Query().Get("resourceVersion")
+		watchMut.Lock()
+		watchedFrom = append(watchedFrom, rv)
+		watchMut.Unlock()
+
+		switch rv {
+		case "100":
+			fmt.Fprintf(w, `{"type":"MODIFIED","object":%v}`, kubeTestDeployment("a", "101"))
+			fmt.Fprintf(w, `{"type":"BOOKMARK","object":{"kind":"Deployment","metadata":{"resourceVersion":"102"}}}`)
+			fmt.Fprintf(w, `{"type":"DELETED","object":%v}`, kubeTestDeployment("b", "103"))
+		case "103":
+			fmt.Fprintf(w, `{"type":"ADDED","object":%v}`, kubeTestDeployment("d", "104"))
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, ioutil.WriteFile(kubeconfig, []byte(fmt.Sprintf(`
+apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+  - name: test
+    cluster:
+      server: %v
+users:
+  - name: test
+    user:
+      token: foo
+contexts:
+  - name: test
+    context:
+      cluster: test
+      user: test
+`, srv.URL)), 0o600))
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"state": memCache}}
+
+	conf := NewKubernetesWatchConfig()
+	conf.Kubeconfig = kubeconfig
+	conf.Resources = []KubernetesWatchResourceConfig{{
+		APIVersion:    "apps/v1",
+		Kind:          "Deployment",
+		Namespaces:    []string{"shop"},
+		LabelSelector: "app=web",
+	}}
+	conf.CheckpointCache = "state"
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	rdr, err := newKubernetesWatchReader(conf, mgr, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	type event struct {
+		eventType, name, rv string
+	}
+	var events []event
+	var acks []func()
+	for i := 0; i < 5; i++ {
+		msg, ackFn, err := rdr.ReadWithContext(ctx)
+		require.NoError(t, err)
+		p := msg.Get(0)
+		jObj, err := p.JSON()
+		require.NoError(t, err)
+		obj := jObj.(map[string]interface{})["object"].(map[string]interface{})
+		assert.Equal(t, "Deployment", obj["kind"])
+		assert.Equal(t, "apps/v1", obj["apiVersion"])
+		assert.Equal(t, "shop", p.Metadata().Get("kubernetes_namespace"))
+
+		events = append(events, event{
+			eventType: p.Metadata().Get("kubernetes_event_type"),
+			name:      p.Metadata().Get("kubernetes_name"),
+			rv:        p.Metadata().Get("kubernetes_resource_version"),
+		})
+		acks = append(acks, func() {
+			require.NoError(t, ackFn(ctx, response.NewAck()))
+		})
+	}
+	assert.Equal(t, []event{
+		{"ADDED", "a", "90"},
+		{"ADDED", "b", "91"},
+		{"ADDED", "c", "92"},
+		{"MODIFIED", "a", "101"},
+		{"DELETED", "b", "103"},
+	}, events)
+
+	key := "kubernetes_watch:apps/v1:Deployment:shop"
+
+	// Nothing is committed until the first change is delivered.
+	for _, ack := range acks[1:] {
+		ack()
+	}
+	_, err = memCache.Get(key)
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	acks[0]()
+	stored, err := memCache.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "103", string(stored))
+
+	rdr.CloseAsync()
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+
+	// A new reader resumes from the stored resource version.
+	rdr, err = newKubernetesWatchReader(conf, mgr, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, _, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "d", msg.Get(0).Metadata().Get("kubernetes_name"))
+	assert.Equal(t, "ADDED", msg.Get(0).Metadata().Get("kubernetes_event_type"))
+	rdr.CloseAsync()
+
+	watchMut.Lock()
+	assert.Equal(t, []string{"100", "103"}, watchedFrom)
+	watchMut.Unlock()
+}
+
+func TestKubernetesWatchBadConfig(t *testing.T) {
+	conf := NewKubernetesWatchConfig()
+	_, err := newKubernetesWatchReader(conf, nil, log.Noop())
+	assert.EqualError(t, err, "at least one resource must be specified")
+
+	conf.Resources = []KubernetesWatchResourceConfig{{Kind: "Pod"}}
+	_, err = newKubernetesWatchReader(conf, nil, log.Noop())
+	assert.EqualError(t, err, "resource 0 must specify an api_version and kind")
+}
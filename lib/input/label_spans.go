@@ -0,0 +1,107 @@
+package input
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// labelSpans wraps an input and renames the root tracing spans of consumed
+// messages after the label of the input.
+type labelSpans struct {
+	label string
+	in    Type
+
+	transactions chan types.Transaction
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// WithLabelSpans wraps an input so that the root tracing spans of each message
+// it produces are named by its label rather than its type.
+func WithLabelSpans(label string, in Type) Type {
+	l := &labelSpans{
+		label:        label,
+		in:           in,
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+	go l.loop()
+	return l
+}
+
+//------------------------------------------------------------------------------
+
+func (l *labelSpans) loop() {
+	defer func() {
+		close(l.transactions)
+		close(l.closedChan)
+	}()
+
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-l.in.TransactionChan():
+			if !open {
+				return
+			}
+		case <-l.closeChan:
+			return
+		}
+
+		_ = ts.Payload.Iter(func(i int, p types.Part) error {
+			if span := tracing.GetSpan(p); span != nil {
+				span.SetOperationName(l.label)
+			}
+			return nil
+		})
+
+		select {
+		case l.transactions <- ts:
+		case <-l.closeChan:
+			return
+		}
+	}
+}
+
+// TransactionChan returns the channel used for consuming transactions from this
+// input.
+func (l *labelSpans) TransactionChan() <-chan types.Transaction {
+	return l.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (l *labelSpans) Connected() bool {
+	return l.in.Connected()
+}
+
+// CloseAsync triggers a closure of this object but does not block.
+func (l *labelSpans) CloseAsync() {
+	l.in.CloseAsync()
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+	})
+}
+
+// WaitForClose is a blocking call to wait until the object has finished closing
+// down and cleaning up resources.
+func (l *labelSpans) WaitForClose(timeout time.Duration) error {
+	tStarted := time.Now()
+	select {
+	case <-l.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return l.in.WaitForClose(timeout - time.Since(tStarted))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,573 @@
+package input
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/ldap"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeLDAP] = TypeSpec{
+		constructor: fromSimpleConstructor(NewLDAP),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Periodically searches an LDAP directory, such as Active Directory or OpenLDAP, and creates a message for each entry found.`,
+		Description: `
+Searches are executed with the simple paged results control, where each page contains at most ` + "`page_size`" + ` entries. When an ` + "`interval`" + ` is specified a search is executed at that interval, otherwise the input performs a single search and then shuts down, which also gracefully terminates the pipeline when it is the only input.
+
+Each entry becomes a message containing a JSON object with the DN of the entry and its attributes, where every attribute is an array of values:
+
+` + "```json" + `
+{
+  "dn": "CN=Jane Doe,OU=Staff,DC=example,DC=com",
+  "attributes": {
+    "cn": [ "Jane Doe" ],
+    "memberOf": [ "CN=Admins,OU=Groups,DC=example,DC=com" ]
+  }
+}
+` + "```" + `
+
+Values that aren't valid UTF-8, such as the ` + "`objectGUID`" + ` attribute of Active Directory, are base64 encoded.
+
+### Delta Sync
+
+When a ` + "`delta_attribute`" + ` is specified the first search returns all entries matching the filter, and subsequent searches only return entries where the attribute is at least the highest value seen so far. For Active Directory the attribute ` + "`uSNChanged`" + ` is incremented whenever an entry is modified, and for numeric values the following searches start from the highest value plus one. For directories such as OpenLDAP the operational attribute ` + "`modifyTimestamp`" + ` can be used, in which case the entries modified at the highest timestamp are returned again.
+
+The highest value seen by a search is only committed once all entries of the search have been delivered by the pipeline. When a ` + "`checkpoint_cache`" + ` is specified the value is also stored within it under ` + "`checkpoint_key`" + `, which allows the input to resume where it left off after restarts.
+
+Deleted entries are not returned by regular searches, and are therefore not captured by delta sync.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- ldap_dn
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Active Directory Users",
+				Summary: "This example consumes changes to the user accounts of an Active Directory domain every five minutes, storing its progress within a Redis cache.",
+				Config: `
+input:
+  ldap:
+    url: ldaps://dc1.example.com
+    bind_dn: CN=benthos,OU=Service Accounts,DC=example,DC=com
+    password: ${LDAP_PASSWORD}
+    base_dn: DC=example,DC=com
+    filter: (&(objectCategory=person)(objectClass=user))
+    attributes: [ sAMAccountName, mail, memberOf, userAccountControl, uSNChanged ]
+    interval: 5m
+    delta_attribute: uSNChanged
+    checkpoint_cache: ldap_state
+
+cache_resources:
+  - label: ldap_state
+    redis:
+      url: redis://localhost:6379
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The URL of the directory server, where the `ldaps` scheme connects with TLS.", "ldap://localhost:389", "ldaps://dc1.example.com"),
+			docs.FieldCommon("bind_dn", "The DN to bind with, if empty the connection is anonymous."),
+			docs.FieldCommon("password", "The password to bind with."),
+			docs.FieldCommon("base_dn", "The DN of the entry at which to start the search."),
+			docs.FieldAdvanced("scope", "The scope of the search relative to the `base_dn`.").HasAnnotatedOptions(
+				"base", "Only the base entry.",
+				"one", "The direct children of the base entry.",
+				"sub", "The base entry and all of its descendants.",
+			),
+			docs.FieldCommon("filter", "The filter of the search, following the string representation of [RFC 4515](https://tools.ietf.org/html/rfc4515).", "(objectClass=person)", "(&(objectClass=group)(cn=admins*))"),
+			docs.FieldCommon("attributes", "The attributes to return for each entry, if empty all user attributes are returned.").Array().HasType(docs.FieldString).HasDefault([]interface{}{}),
+			docs.FieldAdvanced("page_size", "The maximum number of entries to request with each page of a search, where zero disables paging."),
+			docs.FieldCommon("interval", "The period of time between searches, if empty a single search is performed.", "5m", "1h"),
+			docs.FieldCommon("delta_attribute", "An optional attribute used to only return entries that have changed since the previous search.", "uSNChanged", "modifyTimestamp"),
+			docs.FieldCommon("checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store the highest value of the `delta_attribute` within."),
+			docs.FieldAdvanced("checkpoint_key", "The key to store the highest value of the `delta_attribute` under within the `checkpoint_cache`."),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for an operation to complete."),
+			btls.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// LDAPConfig contains configuration fields for the LDAP input type.
+type LDAPConfig struct {
+	URL             string      `json:"url" yaml:"url"`
+	BindDN          string      `json:"bind_dn" yaml:"bind_dn"`
+	Password        string      `json:"password" yaml:"password"`
+	BaseDN          string      `json:"base_dn" yaml:"base_dn"`
+	Scope           string      `json:"scope" yaml:"scope"`
+	Filter          string      `json:"filter" yaml:"filter"`
+	Attributes      []string    `json:"attributes" yaml:"attributes"`
+	PageSize        int         `json:"page_size" yaml:"page_size"`
+	Interval        string      `json:"interval" yaml:"interval"`
+	DeltaAttribute  string      `json:"delta_attribute" yaml:"delta_attribute"`
+	CheckpointCache string      `json:"checkpoint_cache" yaml:"checkpoint_cache"`
+	CheckpointKey   string      `json:"checkpoint_key" yaml:"checkpoint_key"`
+	Timeout         string      `json:"timeout" yaml:"timeout"`
+	TLS             btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewLDAPConfig creates a new LDAPConfig with default values.
+func NewLDAPConfig() LDAPConfig {
+	return LDAPConfig{
+		URL:             "",
+		BindDN:          "",
+		Password:        "",
+		BaseDN:          "",
+		Scope:           "sub",
+		Filter:          "(objectClass=*)",
+		Attributes:      []string{},
+		PageSize:        500,
+		Interval:        "",
+		DeltaAttribute:  "",
+		CheckpointCache: "",
+		CheckpointKey:   "ldap_delta",
+		Timeout:         "30s",
+		TLS:             btls.NewConfig(),
+	}
+}
+
+// NewLDAP creates a new LDAP input type.
+func NewLDAP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	dial, err := newLDAPDialFn(conf.LDAP)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := newLDAPReader(conf.LDAP, dial, mgr, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeLDAP, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type ldapCursor interface {
+	NextPage(ctx context.Context) ([]*ldap.Entry, error)
+	Close(ctx context.Context) error
+}
+
+type ldapClient interface {
+	Search(req ldap.SearchRequest) (ldapCursor, error)
+	Close() error
+}
+
+type ldapDialFn func(ctx context.Context) (ldapClient, error)
+
+type ldapConnClient struct {
+	*ldap.Conn
+}
+
+func (c ldapConnClient) Search(req ldap.SearchRequest) (ldapCursor, error) {
+	return c.Conn.Search(req)
+}
+
+func newLDAPDialFn(conf LDAPConfig) (ldapDialFn, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+	}
+	tlsConf, err := conf.TLS.Get()
+	if err != nil {
+		return nil, err
+	}
+	if !conf.TLS.Enabled {
+		tlsConf = nil
+	}
+	return func(ctx context.Context) (ldapClient, error) {
+		conn, err := ldap.Dial(ctx, conf.URL, tlsConf, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if err = conn.Bind(ctx, conf.BindDN, conf.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to bind: %w", err)
+		}
+		return ldapConnClient{conn}, nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ldapSync tracks the entries of a single search in order to commit the highest
+// value of the delta attribute once all of them are delivered.
+type ldapSync struct {
+	pending int
+	ended   bool
+	highest string
+}
+
+type ldapReader struct {
+	conf     LDAPConfig
+	dial     ldapDialFn
+	mgr      types.Manager
+	log      log.Modular
+	scope    ldap.Scope
+	interval time.Duration
+
+	mut      sync.Mutex
+	client   ldapClient
+	cursor   ldapCursor
+	sync     *ldapSync
+	entries  []*ldap.Entry
+	nextSync time.Time
+	done     bool
+
+	deltaMut    sync.Mutex
+	delta       string
+	deltaLoaded bool
+}
+
+func newLDAPReader(conf LDAPConfig, dial ldapDialFn, mgr types.Manager, log log.Modular) (*ldapReader, error) {
+	r := &ldapReader{
+		conf: conf,
+		dial: dial,
+		mgr:  mgr,
+		log:  log,
+	}
+
+	switch conf.Scope {
+	case "base":
+		r.scope = ldap.ScopeBaseObject
+	case "one":
+		r.scope = ldap.ScopeSingleLevel
+	case "sub":
+		r.scope = ldap.ScopeWholeSubtree
+	default:
+		return nil, fmt.Errorf("scope value not recognised: %v", conf.Scope)
+	}
+
+	if _, err := ldap.CompileFilter(conf.Filter); err != nil {
+		return nil, fmt.Errorf("failed to parse filter: %w", err)
+	}
+
+	if conf.Interval != "" {
+		var err error
+		if r.interval, err = time.ParseDuration(conf.Interval); err != nil {
+			return nil, fmt.Errorf("failed to parse interval string: %v", err)
+		}
+	}
+
+	if conf.CheckpointCache != "" {
+		if conf.DeltaAttribute == "" {
+			return nil, errors.New("a delta_attribute must be specified in order to use a checkpoint_cache")
+		}
+		if conf.CheckpointKey == "" {
+			return nil, errors.New("a checkpoint_key must be specified")
+		}
+		if err := interop.ProbeCache(context.Background(), mgr, conf.CheckpointCache); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loadDelta reads the stored delta value from the checkpoint cache the first
+// time it is called.
+func (r *ldapReader) loadDelta(ctx context.Context) error {
+	r.deltaMut.Lock()
+	defer r.deltaMut.Unlock()
+
+	if r.conf.CheckpointCache == "" || r.deltaLoaded {
+		return nil
+	}
+
+	var stored []byte
+	var getErr error
+	if err := interop.AccessCache(ctx, r.mgr, r.conf.CheckpointCache, func(cache types.Cache) {
+		stored, getErr = cache.Get(r.conf.CheckpointKey)
+	}); err != nil {
+		return err
+	}
+	if getErr != nil && getErr != types.ErrKeyNotFound {
+		return fmt.Errorf("failed to read delta value: %w", getErr)
+	}
+	if getErr == nil && deltaGreater(string(stored), r.delta) {
+		r.delta = string(stored)
+	}
+	r.deltaLoaded = true
+	return nil
+}
+
+// commitDelta moves the delta value forward and stores it within the
+// checkpoint cache.
+func (r *ldapReader) commitDelta(value string) {
+	r.deltaMut.Lock()
+	defer r.deltaMut.Unlock()
+
+	if !deltaGreater(value, r.delta) {
+		return
+	}
+	r.delta = value
+
+	if r.conf.CheckpointCache == "" {
+		return
+	}
+	var setErr error
+	if err := interop.AccessCache(context.Background(), r.mgr, r.conf.CheckpointCache, func(cache types.Cache) {
+		setErr = cache.Set(r.conf.CheckpointKey, []byte(value))
+	}); err != nil {
+		setErr = err
+	}
+	if setErr != nil {
+		r.log.Errorf("Failed to store delta value: %v\n", setErr)
+	}
+}
+
+// deltaGreater returns whether a delta value is greater than another, values
+// are compared numerically when both are integers.
+func deltaGreater(a, b string) bool {
+	if b == "" {
+		return a != ""
+	}
+	aN, aErr := strconv.ParseInt(a, 10, 64)
+	bN, bErr := strconv.ParseInt(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		return aN > bN
+	}
+	return a > b
+}
+
+// filter returns the filter of the next search.
+func (r *ldapReader) filter() string {
+	r.deltaMut.Lock()
+	delta := r.delta
+	r.deltaMut.Unlock()
+
+	if r.conf.DeltaAttribute == "" || delta == "" {
+		return r.conf.Filter
+	}
+	if n, err := strconv.ParseInt(delta, 10, 64); err == nil {
+		delta = strconv.FormatInt(n+1, 10)
+	}
+
+	base := strings.TrimSpace(r.conf.Filter)
+	if !strings.HasPrefix(base, "(") {
+		base = "(" + base + ")"
+	}
+	return fmt.Sprintf("(&%v(%v>=%v))", base, r.conf.DeltaAttribute, ldap.EscapeFilterValue(delta))
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext connects and binds to the directory server.
+func (r *ldapReader) ConnectWithContext(ctx context.Context) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.done {
+		return types.ErrTypeClosed
+	}
+	if r.client != nil {
+		return nil
+	}
+	if err := r.loadDelta(ctx); err != nil {
+		return err
+	}
+
+	client, err := r.dial(ctx)
+	if err != nil {
+		return err
+	}
+	r.client = client
+	r.log.Infof("Searching LDAP directory: %v\n", r.conf.URL)
+	return nil
+}
+
+// endSync marks the current search as complete, committing its delta value
+// if all of its entries have already been delivered.
+func (r *ldapReader) endSync() {
+	s := r.sync
+	r.cursor, r.sync = nil, nil
+
+	r.deltaMut.Lock()
+	s.ended = true
+	commit := s.pending == 0
+	r.deltaMut.Unlock()
+
+	if commit {
+		r.commitDelta(s.highest)
+	}
+}
+
+// disconnect drops the connection after a failure, abandoning the current
+// search without committing its delta value.
+func (r *ldapReader) disconnect() {
+	if r.client != nil {
+		r.client.Close()
+	}
+	r.client, r.cursor, r.sync, r.entries = nil, nil, nil, nil
+	r.nextSync = time.Time{}
+}
+
+// ReadWithContext attempts to read the next entry of a search, starting a new
+// search when the interval has elapsed.
+func (r *ldapReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	for len(r.entries) == 0 {
+		if r.client == nil {
+			if r.done {
+				return nil, nil, types.ErrTypeClosed
+			}
+			return nil, nil, types.ErrNotConnected
+		}
+
+		if r.cursor == nil {
+			if !r.nextSync.IsZero() {
+				if r.interval == 0 {
+					r.done = true
+					r.client.Close()
+					r.client = nil
+					return nil, nil, types.ErrTypeClosed
+				}
+				select {
+				case <-time.After(time.Until(r.nextSync)):
+				case <-ctx.Done():
+					return nil, nil, types.ErrTimeout
+				}
+			}
+
+			cursor, err := r.client.Search(ldap.SearchRequest{
+				BaseDN:     r.conf.BaseDN,
+				Scope:      r.scope,
+				Filter:     r.filter(),
+				Attributes: r.conf.Attributes,
+				PageSize:   r.conf.PageSize,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			r.cursor, r.sync = cursor, &ldapSync{}
+			r.nextSync = time.Now().Add(r.interval)
+		}
+
+		entries, err := r.cursor.NextPage(ctx)
+		if err == io.EOF {
+			r.endSync()
+			continue
+		}
+		if err != nil {
+			var lErr *ldap.Error
+			if errors.As(err, &lErr) {
+				// The connection remains usable after an unsuccessful result,
+				// and the search is retried at the next interval.
+				r.log.Errorf("Search failed: %v\n", err)
+				r.cursor, r.sync = nil, nil
+				continue
+			}
+			r.log.Errorf("Lost connection to directory server: %v\n", err)
+			r.disconnect()
+			return nil, nil, types.ErrNotConnected
+		}
+		r.entries = entries
+	}
+
+	entry := r.entries[0]
+	r.entries = r.entries[1:]
+
+	b, err := json.Marshal(ldapEntryToJSON(entry))
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := message.New([][]byte{b})
+	msg.Get(0).Metadata().Set("ldap_dn", entry.DN)
+
+	s := r.sync
+	r.deltaMut.Lock()
+	s.pending++
+	if r.conf.DeltaAttribute != "" {
+		for _, v := range entry.Get(r.conf.DeltaAttribute) {
+			if deltaGreater(string(v), s.highest) {
+				s.highest = string(v)
+			}
+		}
+	}
+	r.deltaMut.Unlock()
+
+	return msg, func(ctx context.Context, res types.Response) error {
+		r.deltaMut.Lock()
+		s.pending--
+		commit := s.ended && s.pending == 0
+		r.deltaMut.Unlock()
+		if commit {
+			r.commitDelta(s.highest)
+		}
+		return nil
+	}, nil
+}
+
+func ldapEntryToJSON(e *ldap.Entry) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(e.Attributes))
+	for _, a := range e.Attributes {
+		vals := make([]interface{}, len(a.Values))
+		for i, v := range a.Values {
+			if utf8.Valid(v) {
+				vals[i] = string(v)
+			} else {
+				vals[i] = base64.StdEncoding.EncodeToString(v)
+			}
+		}
+		attrs[a.Name] = vals
+	}
+	return map[string]interface{}{
+		"dn":         e.DN,
+		"attributes": attrs,
+	}
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (r *ldapReader) CloseAsync() {
+	go func() {
+		r.mut.Lock()
+		if r.cursor != nil {
+			if err := r.cursor.Close(context.Background()); err != nil {
+				r.log.Debugf("Failed to abandon search: %v\n", err)
+			}
+		}
+		if r.client != nil {
+			r.client.Close()
+		}
+		r.client, r.cursor = nil, nil
+		r.mut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (r *ldapReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,197 @@
+package input
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/ldap"
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLDAPCursor struct {
+	pages [][]*ldap.Entry
+}
+
+func (f *fakeLDAPCursor) NextPage(ctx context.Context) ([]*ldap.Entry, error) {
+	if len(f.pages) == 0 {
+		return nil, io.EOF
+	}
+	p := f.pages[0]
+	f.pages = f.pages[1:]
+	return p, nil
+}
+
+func (f *fakeLDAPCursor) Close(ctx context.Context) error {
+	return nil
+}
+
+type fakeLDAPClient struct {
+	searches []ldap.SearchRequest
+	results  [][][]*ldap.Entry
+}
+
+func (f *fakeLDAPClient) Search(req ldap.SearchRequest) (ldapCursor, error) {
+	f.searches = append(f.searches, req)
+	var pages [][]*ldap.Entry
+	if len(f.results) > 0 {
+		pages = f.results[0]
+		f.results = f.results[1:]
+	}
+	return &fakeLDAPCursor{pages: pages}, nil
+}
+
+func (f *fakeLDAPClient) Close() error {
+	return nil
+}
+
+func ldapEntry(dn, usn string) *ldap.Entry {
+	return &ldap.Entry{
+		DN: dn,
+		Attributes: []ldap.Attribute{
+			{Name: "cn", Values: [][]byte{[]byte(dn[3:])}},
+			{Name: "uSNChanged", Values: [][]byte{[]byte(usn)}},
+		},
+	}
+}
+
+func TestLDAPSingleSearch(t *testing.T) {
+	client := &fakeLDAPClient{results: [][][]*ldap.Entry{{
+		{
+			{DN: "cn=a,dc=example,dc=com", Attributes: []ldap.Attribute{
+				{Name: "mail", Values: [][]byte{[]byte("a@example.com"), []byte("aa@example.com")}},
+				{Name: "objectGUID", Values: [][]byte{{0xff, 0xfe, 0x00}}},
+			}},
+		},
+		{ldapEntry("cn=b,dc=example,dc=com", "5")},
+	}}}
+
+	conf := NewLDAPConfig()
+	conf.BaseDN = "dc=example,dc=com"
+	conf.Scope = "one"
+	conf.Attributes = []string{"mail", "objectGUID"}
+	conf.PageSize = 1
+	rdr, err := newLDAPReader(conf, func(ctx context.Context) (ldapClient, error) {
+		return client, nil
+	}, nil, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	msg, ack, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"dn":"cn=a,dc=example,dc=com",
+		"attributes":{"mail":["a@example.com","aa@example.com"],"objectGUID":["//4A"]}
+	}`, string(msg.Get(0).Get()))
+	assert.Equal(t, "cn=a,dc=example,dc=com", msg.Get(0).Metadata().Get("ldap_dn"))
+	require.NoError(t, ack(ctx, response.NewAck()))
+
+	msg, _, err = rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cn=b,dc=example,dc=com", msg.Get(0).Metadata().Get("ldap_dn"))
+
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrTypeClosed, err)
+	assert.Equal(t, types.ErrTypeClosed, rdr.ConnectWithContext(ctx))
+
+	assert.Equal(t, []ldap.SearchRequest{{
+		BaseDN:     "dc=example,dc=com",
+		Scope:      ldap.ScopeSingleLevel,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"mail", "objectGUID"},
+		PageSize:   1,
+	}}, client.searches)
+}
+
+func TestLDAPDeltaSync(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, memCache.Set("delta", []byte("9")))
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"foo": memCache}}
+
+	client := &fakeLDAPClient{results: [][][]*ldap.Entry{
+		{{ldapEntry("cn=a,dc=example,dc=com", "12"), ldapEntry("cn=b,dc=example,dc=com", "10")}},
+		{},
+		{{ldapEntry("cn=c,dc=example,dc=com", "13")}},
+	}}
+
+	conf := NewLDAPConfig()
+	conf.Filter = "objectClass=user"
+	conf.Interval = "1ms"
+	conf.DeltaAttribute = "uSNChanged"
+	conf.CheckpointCache = "foo"
+	conf.CheckpointKey = "delta"
+	rdr, err := newLDAPReader(conf, func(ctx context.Context) (ldapClient, error) {
+		return client, nil
+	}, mgr, log.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	_, ackA, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	_, ackB, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	// Searches that start before the entries of the first are delivered still
+	// use the previous delta value.
+	msg, ackC, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "cn=c,dc=example,dc=com", msg.Get(0).Metadata().Get("ldap_dn"))
+
+	require.Len(t, client.searches, 3)
+	assert.Equal(t, "(&(objectClass=user)(uSNChanged>=10))", client.searches[0].Filter)
+	assert.Equal(t, "(&(objectClass=user)(uSNChanged>=10))", client.searches[1].Filter)
+
+	require.NoError(t, ackB(ctx, response.NewAck()))
+	stored, err := memCache.Get("delta")
+	require.NoError(t, err)
+	assert.Equal(t, "9", string(stored))
+
+	require.NoError(t, ackA(ctx, response.NewAck()))
+	stored, err = memCache.Get("delta")
+	require.NoError(t, err)
+	assert.Equal(t, "12", string(stored))
+
+	require.NoError(t, ackC(ctx, response.NewAck()))
+	msgCtx, msgDone := context.WithTimeout(ctx, time.Millisecond*50)
+	_, _, err = rdr.ReadWithContext(msgCtx)
+	msgDone()
+	require.Equal(t, types.ErrTimeout, err)
+
+	stored, err = memCache.Get("delta")
+	require.NoError(t, err)
+	assert.Equal(t, "13", string(stored))
+	assert.Equal(t, "(&(objectClass=user)(uSNChanged>=14))", client.searches[len(client.searches)-1].Filter)
+}
+
+func TestLDAPBadConfig(t *testing.T) {
+	conf := NewLDAPConfig()
+	_, err := newLDAPDialFn(conf)
+	require.EqualError(t, err, "a url must be specified")
+
+	conf.Scope = "all"
+	_, err = newLDAPReader(conf, nil, nil, log.Noop())
+	require.EqualError(t, err, "scope value not recognised: all")
+
+	conf = NewLDAPConfig()
+	conf.Filter = "(cn=foo"
+	_, err = newLDAPReader(conf, nil, nil, log.Noop())
+	require.EqualError(t, err, "failed to parse filter: unexpected end of filter")
+
+	conf = NewLDAPConfig()
+	conf.CheckpointCache = "foo"
+	_, err = newLDAPReader(conf, nil, &fakeCacheMgr{}, log.Noop())
+	require.EqualError(t, err, "a delta_attribute must be specified in order to use a checkpoint_cache")
+}
@@ -0,0 +1,520 @@
+package input
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/kubernetes"
+	"github.com/Jeffail/benthos/v3/internal/leader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeLeaderElection] = TypeSpec{
+		constructor: fromSimpleConstructor(NewLeaderElection),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Consumes from a child input only while this instance holds a lock, allowing a single instance of many identical deployments to consume from inputs that can't be partitioned, with another instance taking over when it fails.`,
+		Description: `
+Each instance attempts to acquire the lock every ` + "`renew_interval`" + `, and the instance that holds it renews it at the same interval. Once acquired the child input is created and consumed from until either the lock is lost or this input is closed. If the lock can't be renewed, either because another instance has taken it over or because the lock backend can't be reached for longer than ` + "`ttl`" + ` minus ` + "`renew_interval`" + `, the child input is closed and this instance returns to standby. When this input closes gracefully the lock is released, allowing another instance to take over immediately rather than once the lock expires.
+
+This is useful for inputs such as ` + "`sftp`" + ` or ` + "`sql_select`" + ` that would otherwise consume the same data once for each instance. If the child input closes itself then this input also closes.
+
+Since a new leader only takes over once the lock of the previous leader has expired, it's possible for messages that were in flight when a leader failed to be consumed again by its successor, and therefore outputs should tolerate duplicates.
+
+### Locks
+
+The ` + "`cache`" + ` lock stores the holder and expiry time of the lock as a key of a [cache resource](/docs/components/caches/about). It relies on the cache failing to add a key that already exists, and on the clocks of all instances being roughly in sync, and so a cache that is shared between instances such as ` + "`redis`" + ` should be used.
+
+The ` + "`kubernetes`" + ` lock stores the lock as a [Lease](https://kubernetes.io/docs/reference/kubernetes-api/cluster-resources/lease-v1/) object, the same mechanism used by Kubernetes controllers for leader election. The service account of the instances must be permitted to get, create and update leases within the namespace. When running within a pod the service account of the pod is used, and otherwise a context of a kubeconfig file is used.
+
+The ` + "`etcd`" + ` lock stores the lock as a key attached to a lease of the holder, which is deleted by etcd once the lease expires. Requests are made to the JSON gateway of the v3 API served by etcd v3.4 and later, authentication is not supported.
+
+### Metrics
+
+The gauge ` + "`leader`" + ` is set to 1 while this instance holds the lock and 0 otherwise, and the counters ` + "`leadership.acquired` and `leadership.lost`" + ` are incremented each time it is acquired or lost.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Single SFTP Consumer",
+				Summary: "Polling an SFTP server from only one of several replicas of a Kubernetes deployment, where each replica uses the name of its pod as its identity:",
+				Config: `
+input:
+  leader_election:
+    lock: kubernetes
+    key: benthos-sftp-poller
+    identity: ${POD_NAME}
+    input:
+      sftp:
+        address: sftp.example.com:22
+        credentials:
+          username: foo
+          password: bar
+        paths: [ /uploads/*.csv ]
+        codec: csv
+        delete_on_finish: true
+        watcher:
+          enabled: true
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("input", "The child input to consume from while this instance is the leader.").HasType(docs.FieldInput),
+			docs.FieldCommon("lock", "The backend of the lock.").HasOptions("cache", "kubernetes", "etcd"),
+			docs.FieldCommon("key", "The name of the lock, which must be the same for all instances that share it. For the `kubernetes` lock this is the name of the Lease object."),
+			docs.FieldAdvanced("identity", "A unique identity of this instance. When empty the hostname followed by a random suffix is used."),
+			docs.FieldAdvanced("ttl", "The duration after which the lock expires unless it is renewed by its holder, which is also the maximum time taken for another instance to take over after the leader fails."),
+			docs.FieldAdvanced("renew_interval", "The interval at which the leader renews the lock, and at which other instances attempt to acquire it. Must be less than `ttl`."),
+			docs.FieldCommon("cache", "Configures the `cache` lock.").WithChildren(
+				docs.FieldCommon("resource", "The [cache resource](/docs/components/caches/about) to store the lock within."),
+			),
+			docs.FieldCommon("kubernetes", "Configures the `kubernetes` lock.").WithChildren(
+				docs.FieldCommon("namespace", "The namespace of the Lease. When empty the namespace of the service account or kubeconfig context is used."),
+				docs.FieldAdvanced("kubeconfig", "An optional path to a kubeconfig file. When empty the service account of the pod is used when running within a cluster, and otherwise the kubeconfig file that `kubectl` uses."),
+				docs.FieldAdvanced("context", "The context of the kubeconfig file to use, or its current context when empty."),
+			),
+			docs.FieldCommon("etcd", "Configures the `etcd` lock.").WithChildren(
+				docs.FieldCommon("endpoints", "A list of endpoints of the etcd cluster, which are attempted in order.").Array(),
+			),
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// LeaderElectionCacheConfig contains configuration fields of a lock stored
+// within a cache resource.
+type LeaderElectionCacheConfig struct {
+	Resource string `json:"resource" yaml:"resource"`
+}
+
+// LeaderElectionKubernetesConfig contains configuration fields of a lock
+// stored as a Kubernetes Lease.
+type LeaderElectionKubernetesConfig struct {
+	Namespace  string `json:"namespace" yaml:"namespace"`
+	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig"`
+	Context    string `json:"context" yaml:"context"`
+}
+
+// LeaderElectionEtcdConfig contains configuration fields of a lock stored
+// within an etcd cluster.
+type LeaderElectionEtcdConfig struct {
+	Endpoints []string `json:"endpoints" yaml:"endpoints"`
+}
+
+// LeaderElectionConfig contains configuration values for the LeaderElection
+// input type.
+type LeaderElectionConfig struct {
+	Input         *Config                        `json:"input" yaml:"input"`
+	Lock          string                         `json:"lock" yaml:"lock"`
+	Key           string                         `json:"key" yaml:"key"`
+	Identity      string                         `json:"identity" yaml:"identity"`
+	TTL           string                         `json:"ttl" yaml:"ttl"`
+	RenewInterval string                         `json:"renew_interval" yaml:"renew_interval"`
+	Cache         LeaderElectionCacheConfig      `json:"cache" yaml:"cache"`
+	Kubernetes    LeaderElectionKubernetesConfig `json:"kubernetes" yaml:"kubernetes"`
+	Etcd          LeaderElectionEtcdConfig       `json:"etcd" yaml:"etcd"`
+}
+
+// NewLeaderElectionConfig creates a new LeaderElectionConfig with default
+// values.
+func NewLeaderElectionConfig() LeaderElectionConfig {
+	return LeaderElectionConfig{
+		Input:         nil,
+		Lock:          "cache",
+		Key:           "benthos_leader",
+		Identity:      "",
+		TTL:           "15s",
+		RenewInterval: "5s",
+		Cache: LeaderElectionCacheConfig{
+			Resource: "",
+		},
+		Kubernetes: LeaderElectionKubernetesConfig{
+			Namespace:  "",
+			Kubeconfig: "",
+			Context:    "",
+		},
+		Etcd: LeaderElectionEtcdConfig{
+			Endpoints: []string{"http://localhost:2379"},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyLeaderElectionConfig struct {
+	Input         interface{}                    `json:"input" yaml:"input"`
+	Lock          string                         `json:"lock" yaml:"lock"`
+	Key           string                         `json:"key" yaml:"key"`
+	Identity      string                         `json:"identity" yaml:"identity"`
+	TTL           string                         `json:"ttl" yaml:"ttl"`
+	RenewInterval string                         `json:"renew_interval" yaml:"renew_interval"`
+	Cache         LeaderElectionCacheConfig      `json:"cache" yaml:"cache"`
+	Kubernetes    LeaderElectionKubernetesConfig `json:"kubernetes" yaml:"kubernetes"`
+	Etcd          LeaderElectionEtcdConfig       `json:"etcd" yaml:"etcd"`
+}
+
+func (l LeaderElectionConfig) dummy() dummyLeaderElectionConfig {
+	dummy := dummyLeaderElectionConfig{
+		Input:         l.Input,
+		Lock:          l.Lock,
+		Key:           l.Key,
+		Identity:      l.Identity,
+		TTL:           l.TTL,
+		RenewInterval: l.RenewInterval,
+		Cache:         l.Cache,
+		Kubernetes:    l.Kubernetes,
+		Etcd:          l.Etcd,
+	}
+	if l.Input == nil {
+		dummy.Input = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (l LeaderElectionConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (l LeaderElectionConfig) MarshalYAML() (interface{}, error) {
+	return l.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// LeaderElection is an input type that only consumes from a child input while
+// it holds a lock shared with other instances.
+type LeaderElection struct {
+	conf          LeaderElectionConfig
+	lock          leader.Lock
+	identity      string
+	ttl           time.Duration
+	renewInterval time.Duration
+
+	wrapperMgr   types.Manager
+	wrapperLog   log.Modular
+	wrapperStats metrics.Type
+
+	log       log.Modular
+	mLeader   metrics.StatGauge
+	mAcquired metrics.StatCounter
+	mLost     metrics.StatCounter
+	mErr      metrics.StatCounter
+
+	// Only accessed by the election loop.
+	leading   bool
+	lastRenew time.Time
+
+	childMut sync.Mutex
+	child    Type
+	children chan Type
+
+	transactions chan types.Transaction
+
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+// NewLeaderElection creates a new LeaderElection input type.
+func NewLeaderElection(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	lConf := conf.LeaderElection
+	if lConf.Input == nil {
+		return nil, errors.New("cannot create leader_election input without a child")
+	}
+	if lConf.Key == "" {
+		return nil, errors.New("a lock key must be specified")
+	}
+
+	ttl, err := time.ParseDuration(lConf.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ttl string: %v", err)
+	}
+	renewInterval, err := time.ParseDuration(lConf.RenewInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse renew_interval string: %v", err)
+	}
+	if renewInterval <= 0 || renewInterval >= ttl {
+		return nil, errors.New("renew_interval must be greater than zero and less than ttl")
+	}
+
+	var lock leader.Lock
+	switch lConf.Lock {
+	case "cache":
+		if lock, err = leader.NewCacheLock(mgr, lConf.Cache.Resource, lConf.Key); err != nil {
+			return nil, err
+		}
+	case "kubernetes":
+		clientConf, err := kubernetes.Resolve(lConf.Kubernetes.Kubeconfig, lConf.Kubernetes.Context)
+		if err != nil {
+			return nil, err
+		}
+		namespace := lConf.Kubernetes.Namespace
+		if namespace == "" {
+			if namespace = clientConf.Namespace; namespace == "" {
+				namespace = "default"
+			}
+		}
+		lock = leader.NewKubernetesLock(kubernetes.NewClient(clientConf), namespace, lConf.Key)
+	case "etcd":
+		if lock, err = leader.NewEtcdLock(lConf.Etcd.Endpoints, lConf.Key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("lock type '%v' not recognised", lConf.Lock)
+	}
+
+	identity := lConf.Identity
+	if identity == "" {
+		hostname, _ := os.Hostname()
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		identity = hostname + "-" + hex.EncodeToString(suffix)
+	}
+
+	_, lLog, lStats := interop.LabelChild("leader_election", mgr, log, stats)
+	l := &LeaderElection{
+		conf:          lConf,
+		lock:          lock,
+		identity:      identity,
+		ttl:           ttl,
+		renewInterval: renewInterval,
+
+		wrapperMgr:   mgr,
+		wrapperLog:   log,
+		wrapperStats: stats,
+
+		log:       lLog,
+		mLeader:   lStats.GetGauge("leader"),
+		mAcquired: lStats.GetCounter("leadership.acquired"),
+		mLost:     lStats.GetCounter("leadership.lost"),
+		mErr:      lStats.GetCounter("lock.error"),
+
+		children:     make(chan Type, 1),
+		transactions: make(chan types.Transaction),
+		closedChan:   make(chan struct{}),
+	}
+	l.ctx, l.done = context.WithCancel(context.Background())
+
+	l.log.Infof("Participating in leader election of lock '%v' with identity '%v'\n", lConf.Key, identity)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		l.electionLoop()
+	}()
+	go func() {
+		defer wg.Done()
+		l.forwardLoop()
+	}()
+	go func() {
+		wg.Wait()
+		close(l.transactions)
+		close(l.closedChan)
+	}()
+	return l, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (l *LeaderElection) stepUp() {
+	child, err := New(*l.conf.Input, l.wrapperMgr, l.wrapperLog, l.wrapperStats)
+	if err != nil {
+		l.log.Errorf("Failed to create input '%v': %v\n", l.conf.Input.Type, err)
+		ctx, done := context.WithTimeout(l.ctx, l.renewInterval)
+		if err := l.lock.Release(ctx, l.identity); err != nil {
+			l.log.Errorf("Failed to release lock: %v\n", err)
+		}
+		done()
+		return
+	}
+
+	l.log.Infoln("Acquired leadership, consuming from child input")
+	l.leading = true
+	l.mLeader.Set(1)
+	l.mAcquired.Incr(1)
+
+	l.childMut.Lock()
+	l.child = child
+	l.childMut.Unlock()
+
+	select {
+	case l.children <- child:
+	case <-l.ctx.Done():
+	}
+}
+
+func (l *LeaderElection) stepDown() {
+	if !l.leading {
+		return
+	}
+	l.leading = false
+	l.mLeader.Set(0)
+	l.mLost.Incr(1)
+
+	l.childMut.Lock()
+	child := l.child
+	l.child = nil
+	l.childMut.Unlock()
+
+	child.CloseAsync()
+	if err := child.WaitForClose(l.ttl); err != nil {
+		l.log.Errorf("Failed to close child input: %v\n", err)
+	}
+}
+
+func (l *LeaderElection) renew() {
+	ctx, done := context.WithTimeout(l.ctx, l.renewInterval)
+	held, err := l.lock.Acquire(ctx, l.identity, l.ttl)
+	done()
+	if l.ctx.Err() != nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		l.mErr.Incr(1)
+		l.log.Errorf("Failed to acquire lock: %v\n", err)
+		// Another instance may take over once the lock expires, and so we stop
+		// consuming before it does.
+		if l.leading && time.Since(l.lastRenew) >= l.ttl-l.renewInterval {
+			l.log.Warnln("Unable to renew lock, stepping down as leader")
+			l.stepDown()
+		}
+	case held:
+		l.lastRenew = time.Now()
+		if !l.leading {
+			l.stepUp()
+		}
+	case l.leading:
+		l.log.Warnln("Lock was acquired by another instance, stepping down as leader")
+		l.stepDown()
+	}
+}
+
+func (l *LeaderElection) electionLoop() {
+	defer func() {
+		wasLeading := l.leading
+		l.stepDown()
+		if wasLeading {
+			ctx, done := context.WithTimeout(context.Background(), l.renewInterval)
+			if err := l.lock.Release(ctx, l.identity); err != nil {
+				l.log.Errorf("Failed to release lock: %v\n", err)
+			}
+			done()
+		}
+	}()
+
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		l.renew()
+		select {
+		case <-ticker.C:
+		case <-l.ctx.Done():
+			return
+		}
+	}
+}
+
+func (l *LeaderElection) forwardLoop() {
+	for {
+		var child Type
+		select {
+		case child = <-l.children:
+		case <-l.ctx.Done():
+			return
+		}
+
+		for {
+			var tran types.Transaction
+			var open bool
+			select {
+			case tran, open = <-child.TransactionChan():
+			case <-l.ctx.Done():
+				return
+			}
+			if !open {
+				break
+			}
+			select {
+			case l.transactions <- tran:
+			case <-l.ctx.Done():
+				return
+			}
+		}
+
+		// If the child is still current then it closed itself rather than
+		// being closed due to losing leadership.
+		l.childMut.Lock()
+		ended := l.child == child
+		l.childMut.Unlock()
+		if ended {
+			l.log.Infoln("Child input has closed, shutting down")
+			l.CloseAsync()
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (l *LeaderElection) TransactionChan() <-chan types.Transaction {
+	return l.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target. An instance on standby is considered connected.
+func (l *LeaderElection) Connected() bool {
+	l.childMut.Lock()
+	child := l.child
+	l.childMut.Unlock()
+	return child == nil || child.Connected()
+}
+
+// CloseAsync shuts down the LeaderElection input and stops processing
+// requests.
+func (l *LeaderElection) CloseAsync() {
+	l.done()
+}
+
+// WaitForClose blocks until the LeaderElection input has closed down.
+func (l *LeaderElection) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-l.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
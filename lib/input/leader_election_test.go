@@ -0,0 +1,128 @@
+package input
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLeaderElectionTestInput(t *testing.T, mgr types.Manager, identity, mapping string) Type {
+	t.Helper()
+
+	child := NewConfig()
+	child.Type = TypeGenerate
+	child.Generate.Mapping = mapping
+	child.Generate.Interval = "1ms"
+
+	conf := NewConfig()
+	conf.Type = TypeLeaderElection
+	conf.LeaderElection.Input = &child
+	conf.LeaderElection.Identity = identity
+	conf.LeaderElection.TTL = "200ms"
+	conf.LeaderElection.RenewInterval = "10ms"
+	conf.LeaderElection.Cache.Resource = "foo"
+
+	in, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return in
+}
+
+func readLeaderElectionTestMessage(t *testing.T, in Type) string {
+	t.Helper()
+
+	select {
+	case tran, open := <-in.TransactionChan():
+		require.True(t, open)
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return string(tran.Payload.Get(0).Get())
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return ""
+}
+
+func TestLeaderElectionFailover(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"foo": memCache}}
+
+	inA := newLeaderElectionTestInput(t, mgr, "a", `root = "a"`)
+	assert.Equal(t, "a", readLeaderElectionTestMessage(t, inA))
+
+	inB := newLeaderElectionTestInput(t, mgr, "b", `root = "b"`)
+	select {
+	case <-inB.TransactionChan():
+		t.Fatal("received message from standby instance")
+	case <-time.After(time.Millisecond * 50):
+	}
+	assert.True(t, inB.Connected())
+
+	inA.CloseAsync()
+	require.NoError(t, inA.WaitForClose(time.Second*5))
+
+	assert.Equal(t, "b", readLeaderElectionTestMessage(t, inB))
+
+	inB.CloseAsync()
+	require.NoError(t, inB.WaitForClose(time.Second*5))
+
+	_, err = memCache.Get("benthos_leader")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestLeaderElectionChildCloses(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeCacheMgr{caches: map[string]types.Cache{"foo": memCache}}
+
+	child := NewConfig()
+	child.Type = TypeGenerate
+	child.Generate.Mapping = `root = "foo"`
+	child.Generate.Interval = ""
+	child.Generate.Count = 1
+
+	conf := NewConfig()
+	conf.Type = TypeLeaderElection
+	conf.LeaderElection.Input = &child
+	conf.LeaderElection.Cache.Resource = "foo"
+
+	in, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo", readLeaderElectionTestMessage(t, in))
+	require.NoError(t, in.WaitForClose(time.Second*5))
+}
+
+func TestLeaderElectionErrors(t *testing.T) {
+	mgr := &fakeCacheMgr{}
+	child := NewConfig()
+
+	conf := NewConfig()
+	conf.Type = TypeLeaderElection
+	_, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'leader_election': cannot create leader_election input without a child")
+
+	conf.LeaderElection.Input = &child
+	conf.LeaderElection.RenewInterval = "20s"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'leader_election': renew_interval must be greater than zero and less than ttl")
+
+	conf.LeaderElection.RenewInterval = "5s"
+	conf.LeaderElection.Cache.Resource = "foo"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'leader_election': cache resource 'foo' was not found")
+
+	conf.LeaderElection.Lock = "nope"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'leader_election': lock type 'nope' not recognised")
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 )
 
 //------------------------------------------------------------------------------
@@ -16,12 +17,21 @@ func init() {
 		Summary: `
 Consumes messages via Nanomsg sockets (scalability protocols).`,
 		Description: `
-Currently only PULL and SUB sockets are supported.`,
+The sockets are compatible with both nanomsg and its successor nng. PULL and SUB sockets consume messages sent by PUSH and PUB peers respectively.
+
+### Request/Reply and Surveys
+
+REP and RESPONDENT sockets consume requests from REQ peers and surveys from SURVEYOR peers respectively, and send a reply once each message has been delivered. If the message has a [synchronous response](/docs/guides/sync_responses) then its payload is sent as the reply, where the messages of a batch are joined with newlines, otherwise the reply is empty. Messages that fail to be delivered are not replied to, and a REQ peer resends the request once its retry period elapses.
+
+### TLS
+
+URLs using the ` + "`tls+tcp`" + ` or ` + "`wss`" + ` transports, such as ` + "`tls+tcp://localhost:5555`" + `, are secured with the settings within the field ` + "`tls`" + `. When binding, the certificates listed in ` + "`tls.client_certs`" + ` are presented to peers as the server certificates.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to (or as). If an item of the list contains commas it will be expanded into multiple URLs.").Array(),
 			docs.FieldCommon("bind", "Whether the URLs provided should be connected to, or bound as."),
-			docs.FieldCommon("socket_type", "The socket type to use.").HasOptions("PULL", "SUB"),
+			docs.FieldCommon("socket_type", "The socket type to use.").HasOptions("PULL", "SUB", "REP", "RESPONDENT"),
 			docs.FieldCommon("sub_filters", "A list of subscription topic filters to use when consuming from a SUB socket. Specifying a single sub_filter of `''` will subscribe to everything.").Array(),
+			btls.FieldSpec().AtVersion("3.47.0"),
 			docs.FieldAdvanced("poll_timeout", "The period to wait until a poll is abandoned and reattempted."),
 			docs.FieldDeprecated("reply_timeout"),
 		},
@@ -0,0 +1,202 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/objectarchive"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeObjectArchive] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			r, err := newObjectArchiveReader(conf.ObjectArchive, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncReader(
+				TypeObjectArchive,
+				true,
+				reader.NewAsyncPreserver(r),
+				log, stats,
+			)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+			CategoryLocal,
+		},
+		Summary: `
+Replays the messages of an archive written by the ` + "[`object_archive` output](/docs/components/outputs/object_archive)" + ` that were archived within a time range, in the order that they were written.`,
+		Description: `
+The manifests of the archive are used in order to find the segments written within the range set by ` + "`from` and `to`" + `, and partitions outside of the range are skipped without listing the objects within them. Each segment is emitted as a batch containing the messages it was written with, including their original metadata, and the input closes once all segments of the range have been consumed.
+
+Segments are downloaded in parallel ahead of being consumed, up to the number set by ` + "`download_concurrency`" + `, and a segment counts towards this limit until it has been consumed. Memory usage is therefore bounded by the concurrency multiplied by the size of segments, which is determined by the batching policy of the output that wrote the archive.
+
+### Storage
+
+The ` + "`path`" + ` field is either a directory of the local filesystem, or an S3 URL of the form ` + "`s3://bucket/prefix`" + `, in which case the fields of ` + "`aws`" + ` are used in order to connect.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Replaying an Incident",
+				Summary: "In this example we reprocess the archived messages of a topic that were written during an incident by sending them to a new topic.",
+				Config: `
+input:
+  object_archive:
+    path: s3://company-archives/orders
+    from: 2021-05-21T13:00:00Z
+    to: 2021-05-21T14:30:00Z
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders_reprocessed
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The root of the archive, either a local directory or an S3 URL.", "/data/archives/orders", "s3://company-archives/orders"),
+			docs.FieldCommon("from", "An optional RFC 3339 timestamp, segments written before this are skipped. When empty the archive is replayed from the beginning.", "2021-05-21T13:00:00Z"),
+			docs.FieldCommon("to", "An optional RFC 3339 timestamp, segments written at or after this are skipped. When empty the archive is replayed up until the time the input connects.", "2021-05-21T14:30:00Z"),
+			docs.FieldAdvanced("download_concurrency", "The maximum number of segments to download ahead of being consumed."),
+			docs.FieldAdvanced("aws", "Configuration for connecting to S3, which is only used when the `path` is an S3 URL.").WithChildren(objectarchive.AWSFieldSpecs()...),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ObjectArchiveConfig contains configuration fields for the ObjectArchive
+// input type.
+type ObjectArchiveConfig struct {
+	Path                string                  `json:"path" yaml:"path"`
+	From                string                  `json:"from" yaml:"from"`
+	To                  string                  `json:"to" yaml:"to"`
+	DownloadConcurrency int                     `json:"download_concurrency" yaml:"download_concurrency"`
+	AWS                 objectarchive.AWSConfig `json:"aws" yaml:"aws"`
+}
+
+// NewObjectArchiveConfig creates a new ObjectArchiveConfig with default
+// values.
+func NewObjectArchiveConfig() ObjectArchiveConfig {
+	return ObjectArchiveConfig{
+		Path:                "",
+		From:                "",
+		To:                  "",
+		DownloadConcurrency: 4,
+		AWS:                 objectarchive.NewAWSConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type objectArchiveReader struct {
+	conf     ObjectArchiveConfig
+	log      log.Modular
+	from, to time.Time
+
+	mSegments metrics.StatCounter
+
+	mut    sync.Mutex
+	replay *objectarchive.Replay
+}
+
+func newObjectArchiveReader(conf ObjectArchiveConfig, log log.Modular, stats metrics.Type) (*objectArchiveReader, error) {
+	if conf.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if conf.DownloadConcurrency < 1 {
+		return nil, fmt.Errorf("download_concurrency must be greater than 0, got %v", conf.DownloadConcurrency)
+	}
+	a := &objectArchiveReader{
+		conf:      conf,
+		log:       log,
+		mSegments: stats.GetCounter("segments.read"),
+	}
+	var err error
+	if conf.From != "" {
+		if a.from, err = time.Parse(time.RFC3339Nano, conf.From); err != nil {
+			return nil, fmt.Errorf("failed to parse from timestamp: %w", err)
+		}
+	}
+	if conf.To != "" {
+		if a.to, err = time.Parse(time.RFC3339Nano, conf.To); err != nil {
+			return nil, fmt.Errorf("failed to parse to timestamp: %w", err)
+		}
+		if !a.to.After(a.from) {
+			return nil, errors.New("the to timestamp must be after the from timestamp")
+		}
+	}
+	return a, nil
+}
+
+func (a *objectArchiveReader) ConnectWithContext(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.replay != nil {
+		return nil
+	}
+	store, err := objectarchive.NewStore(a.conf.Path, a.conf.AWS)
+	if err != nil {
+		return err
+	}
+	to := a.to
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if a.replay, err = objectarchive.NewReplay(store, a.from, to, a.conf.DownloadConcurrency); err != nil {
+		return err
+	}
+	a.log.Infof("Replaying archive %v from %v to %v\n", a.conf.Path, a.from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return nil
+}
+
+func (a *objectArchiveReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.replay == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+	seg, msg, err := a.replay.Next(ctx)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, types.ErrTypeClosed
+		}
+		if errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			err = types.ErrTimeout
+		}
+		return nil, nil, err
+	}
+	a.mSegments.Incr(1)
+	a.log.Debugf("Read segment '%v' with %v messages\n", seg.Key, seg.Count)
+	return msg, func(context.Context, types.Response) error {
+		return nil
+	}, nil
+}
+
+func (a *objectArchiveReader) CloseAsync() {
+	a.mut.Lock()
+	if a.replay != nil {
+		a.replay.Close()
+	}
+	a.mut.Unlock()
+}
+
+func (a *objectArchiveReader) WaitForClose(time.Duration) error {
+	return nil
+}
@@ -0,0 +1,76 @@
+package input
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/objectarchive"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectArchiveInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_object_archive_input_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	ctx := context.Background()
+	layout, err := objectarchive.PartitionLayout("hour")
+	require.NoError(t, err)
+
+	w := objectarchive.NewWriter(objectarchive.NewFileStore(dir), layout, false)
+	inMsg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	inMsg.Get(1).Metadata().Set("baz", "buz")
+	require.NoError(t, w.WriteBatch(ctx, inMsg))
+	require.NoError(t, w.WriteBatch(ctx, message.New([][]byte{[]byte("qux")})))
+
+	conf := NewObjectArchiveConfig()
+	conf.Path = dir
+
+	r, err := newObjectArchiveReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(r.CloseAsync)
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrNotConnected, err)
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	msg, _, err := r.ReadWithContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, msg.Len())
+	assert.Equal(t, "foo", string(msg.Get(0).Get()))
+	assert.Equal(t, "buz", msg.Get(1).Metadata().Get("baz"))
+
+	msg, _, err = r.ReadWithContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, msg.Len())
+	assert.Equal(t, "qux", string(msg.Get(0).Get()))
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTypeClosed, err)
+}
+
+func TestObjectArchiveInputConfigErrors(t *testing.T) {
+	conf := NewObjectArchiveConfig()
+	_, err := newObjectArchiveReader(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a path must be specified")
+
+	conf.Path = "/tmp/foo"
+	conf.DownloadConcurrency = 0
+	_, err = newObjectArchiveReader(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "download_concurrency must be greater than 0, got 0")
+
+	conf.DownloadConcurrency = 1
+	conf.From = "2021-05-21T14:30:00Z"
+	conf.To = "2021-05-21T13:00:00Z"
+	_, err = newObjectArchiveReader(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "the to timestamp must be after the from timestamp")
+}
@@ -0,0 +1,164 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/postgres"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePostgresStream struct {
+	events    []*postgres.Event
+	confirmed []postgres.LSN
+	closed    bool
+}
+
+func (f *fakePostgresStream) Next(ctx context.Context) (*postgres.Event, error) {
+	if len(f.events) == 0 {
+		return nil, errors.New("replication stream ended")
+	}
+	e := f.events[0]
+	f.events = f.events[1:]
+	return e, nil
+}
+
+func (f *fakePostgresStream) Confirm(lsn postgres.LSN) {
+	f.confirmed = append(f.confirmed, lsn)
+}
+
+func (f *fakePostgresStream) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestPostgresCDC(t *testing.T) {
+	commitTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	stream := &fakePostgresStream{events: []*postgres.Event{
+		{Operation: postgres.OpInsert, Schema: "public", Table: "foo", LSN: 0x100, XID: 5, CommitTime: commitTime, After: map[string]interface{}{"id": int64(1)}},
+		{Operation: postgres.OpUpdate, Schema: "public", Table: "foo", LSN: 0x110, XID: 5, CommitTime: commitTime, Before: map[string]interface{}{"id": int64(1)}, After: map[string]interface{}{"id": int64(2)}},
+		{Operation: postgres.OpCommit, LSN: 0x120, XID: 5},
+		{Operation: postgres.OpDelete, Schema: "public", Table: "bar", LSN: 0x130, XID: 6, Before: map[string]interface{}{"id": "a"}},
+		{Operation: postgres.OpCommit, LSN: 0x140, XID: 6},
+	}}
+
+	var opened []postgres.StreamConfig
+	conf := NewPostgresCDCConfig()
+	conf.DSN = "postgres://localhost:5432/db"
+	conf.SlotName = "foo"
+	conf.Publications = []string{"pub"}
+	rdr, err := newPostgresCDCReader(conf, func(ctx context.Context, conf postgres.StreamConfig) (postgresCDCStream, error) {
+		opened = append(opened, conf)
+		return stream, nil
+	}, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+	require.Len(t, opened, 1)
+	assert.Equal(t, "foo", opened[0].Slot)
+	assert.Equal(t, []string{"pub"}, opened[0].Publications)
+	assert.Equal(t, time.Second*10, opened[0].StatusInterval)
+
+	msg, ackInsert, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"operation":"insert","schema":"public","table":"foo","lsn":"0/100","xid":5,"before":null,"after":{"id":1}}`, string(msg.Get(0).Get()))
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, "insert", meta.Get("postgres_operation"))
+	assert.Equal(t, "public", meta.Get("postgres_schema"))
+	assert.Equal(t, "foo", meta.Get("postgres_table"))
+	assert.Equal(t, "0/100", meta.Get("postgres_lsn"))
+	assert.Equal(t, "5", meta.Get("postgres_xid"))
+	assert.Equal(t, "2021-06-01T12:00:00Z", meta.Get("postgres_commit_time"))
+
+	msg, ackUpdate, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"operation":"update","schema":"public","table":"foo","lsn":"0/110","xid":5,"before":{"id":1},"after":{"id":2}}`, string(msg.Get(0).Get()))
+
+	// The commit is skipped, and nothing is confirmed until both changes of
+	// the transaction are delivered.
+	msg, ackDelete, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "delete", msg.Get(0).Metadata().Get("postgres_operation"))
+	assert.Equal(t, "", msg.Get(0).Metadata().Get("postgres_commit_time"))
+
+	require.NoError(t, ackUpdate(ctx, response.NewAck()))
+	require.NoError(t, ackDelete(ctx, response.NewAck()))
+	assert.Empty(t, stream.confirmed)
+
+	require.NoError(t, ackInsert(ctx, response.NewAck()))
+	assert.Equal(t, []postgres.LSN{0x120}, stream.confirmed)
+
+	// The second commit is confirmed as soon as it is read as all changes
+	// before it are delivered, after which the failed stream is closed.
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	assert.Equal(t, []postgres.LSN{0x120, 0x140}, stream.confirmed)
+	assert.True(t, stream.closed)
+
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+	require.Len(t, opened, 2)
+}
+
+func TestPostgresCDCCheckpointLimit(t *testing.T) {
+	stream := &fakePostgresStream{events: []*postgres.Event{
+		{Operation: postgres.OpInsert, Table: "foo", LSN: 0x100},
+		{Operation: postgres.OpInsert, Table: "foo", LSN: 0x110},
+		{Operation: postgres.OpCommit, LSN: 0x120},
+	}}
+
+	conf := NewPostgresCDCConfig()
+	conf.DSN = "postgres://localhost:5432/db"
+	conf.SlotName = "foo"
+	conf.Plugin = "wal2json"
+	conf.CheckpointLimit = 1
+	rdr, err := newPostgresCDCReader(conf, func(ctx context.Context, conf postgres.StreamConfig) (postgresCDCStream, error) {
+		return stream, nil
+	}, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	_, ackA, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	// The second change is held until the first is delivered.
+	tCtx, done := context.WithTimeout(ctx, time.Millisecond*50)
+	_, _, err = rdr.ReadWithContext(tCtx)
+	done()
+	assert.Equal(t, types.ErrTimeout, err)
+
+	require.NoError(t, ackA(ctx, response.NewAck()))
+
+	msg, ackB, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0/110", msg.Get(0).Metadata().Get("postgres_lsn"))
+	require.NoError(t, ackB(ctx, response.NewAck()))
+
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	assert.Equal(t, postgres.LSN(0x120), stream.confirmed[len(stream.confirmed)-1])
+}
+
+func TestPostgresCDCBadConfig(t *testing.T) {
+	open := func(ctx context.Context, conf postgres.StreamConfig) (postgresCDCStream, error) {
+		return nil, errors.New("nope")
+	}
+
+	conf := NewPostgresCDCConfig()
+	conf.DSN = "postgres://localhost:5432/db"
+	conf.SlotName = "foo"
+	_, err := newPostgresCDCReader(conf, open, log.Noop())
+	assert.EqualError(t, err, "at least one publication must be specified when the plugin is pgoutput")
+
+	conf.Plugin = "nope"
+	_, err = newPostgresCDCReader(conf, open, log.Noop())
+	assert.EqualError(t, err, "plugin not recognised: nope")
+}
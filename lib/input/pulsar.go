@@ -1,10 +1,17 @@
 package input
 
+import (
+	"github.com/Jeffail/benthos/v3/internal/service/pulsar/client"
+)
+
 // PulsarConfig contains configuration for the Pulsar input type.
 type PulsarConfig struct {
-	URL              string   `json:"url" yaml:"url"`
-	Topics           []string `json:"topics" yaml:"topics"`
-	SubscriptionName string   `json:"subscription_name" yaml:"subscription_name"`
+	URL              string            `json:"url" yaml:"url"`
+	Topics           []string          `json:"topics" yaml:"topics"`
+	SubscriptionName string            `json:"subscription_name" yaml:"subscription_name"`
+	SubscriptionType string            `json:"subscription_type" yaml:"subscription_type"`
+	TLS              client.TLSConfig  `json:"tls" yaml:"tls"`
+	Auth             client.AuthConfig `json:"auth" yaml:"auth"`
 }
 
 // NewPulsarConfig creates a new PulsarConfig with default values.
@@ -13,5 +20,8 @@ func NewPulsarConfig() PulsarConfig {
 		URL:              "",
 		Topics:           []string{},
 		SubscriptionName: "",
+		SubscriptionType: "shared",
+		TLS:              client.NewTLSConfig(),
+		Auth:             client.NewAuthConfig(),
 	}
 }
@@ -39,6 +39,7 @@ type KafkaConfig struct {
 	TLS                 btls.Config              `json:"tls" yaml:"tls"`
 	SASL                sasl.Config              `json:"sasl" yaml:"sasl"`
 	Batching            batch.PolicyConfig       `json:"batching" yaml:"batching"`
+	Scaling             KafkaScalingConfig       `json:"scaling" yaml:"scaling"`
 
 	// TODO: V4 Remove this.
 	Topic         string `json:"topic" yaml:"topic"`
@@ -74,6 +75,28 @@ func NewKafkaConfig() KafkaConfig {
 		TLS:                 btls.NewConfig(),
 		SASL:                sasl.NewConfig(),
 		Batching:            batch.NewPolicyConfig(),
+		Scaling:             NewKafkaScalingConfig(),
+	}
+}
+
+// KafkaScalingConfig contains configuration fields for the autoscaling signal
+// of a Kafka consumer group.
+type KafkaScalingConfig struct {
+	Enabled             bool   `json:"enabled" yaml:"enabled"`
+	Interval            string `json:"interval" yaml:"interval"`
+	TargetLagPerReplica int64  `json:"target_lag_per_replica" yaml:"target_lag_per_replica"`
+	MinReplicas         int    `json:"min_replicas" yaml:"min_replicas"`
+	MaxReplicas         int    `json:"max_replicas" yaml:"max_replicas"`
+}
+
+// NewKafkaScalingConfig creates a new KafkaScalingConfig with default values.
+func NewKafkaScalingConfig() KafkaScalingConfig {
+	return KafkaScalingConfig{
+		Enabled:             false,
+		Interval:            "10s",
+		TargetLagPerReplica: 1000,
+		MinReplicas:         1,
+		MaxReplicas:         0,
 	}
 }
 
@@ -438,7 +461,7 @@ func (k *Kafka) commit() error {
 
 	commitReq := sarama.OffsetCommitRequest{}
 	commitReq.ConsumerGroup = k.conf.ConsumerGroup
-	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, "")
+	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, 0, "")
 
 	commitRes, err := coordinator.CommitOffset(&commitReq)
 	if err == nil {
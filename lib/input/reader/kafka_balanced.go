@@ -28,6 +28,8 @@ type KafkaBalancedGroupConfig struct {
 	SessionTimeout    string `json:"session_timeout" yaml:"session_timeout"`
 	HeartbeatInterval string `json:"heartbeat_interval" yaml:"heartbeat_interval"`
 	RebalanceTimeout  string `json:"rebalance_timeout" yaml:"rebalance_timeout"`
+	RebalanceStrategy string `json:"rebalance_strategy" yaml:"rebalance_strategy"`
+	InstanceID        string `json:"instance_id" yaml:"instance_id"`
 }
 
 // NewKafkaBalancedGroupConfig returns a KafkaBalancedGroupConfig with default
@@ -37,9 +39,27 @@ func NewKafkaBalancedGroupConfig() KafkaBalancedGroupConfig {
 		SessionTimeout:    "10s",
 		HeartbeatInterval: "3s",
 		RebalanceTimeout:  "60s",
+		RebalanceStrategy: "range",
+		InstanceID:        "",
 	}
 }
 
+// KafkaBalanceStrategy returns the strategy for allocating topic partitions to
+// members of a consumer group by name.
+func KafkaBalanceStrategy(name string) (sarama.BalanceStrategy, error) {
+	switch name {
+	case "range", "":
+		return sarama.BalanceStrategyRange, nil
+	case "roundrobin":
+		return sarama.BalanceStrategyRoundRobin, nil
+	case "sticky":
+		return sarama.BalanceStrategySticky, nil
+	case "cooperative-sticky":
+		return nil, fmt.Errorf("rebalance strategy %v is not supported as the incremental cooperative rebalance protocol is not implemented by this input, use sticky instead", name)
+	}
+	return nil, fmt.Errorf("rebalance strategy not recognised: %v", name)
+}
+
 // KafkaBalancedConfig contains configuration for the KafkaBalanced input type.
 type KafkaBalancedConfig struct {
 	Addresses           []string                 `json:"addresses" yaml:"addresses"`
@@ -99,6 +119,7 @@ type KafkaBalanced struct {
 	sessionTimeout    time.Duration
 	heartbeatInterval time.Duration
 	rebalanceTimeout  time.Duration
+	balanceStrategy   sarama.BalanceStrategy
 	maxProcPeriod     time.Duration
 
 	cMut          sync.Mutex
@@ -187,9 +208,15 @@ func NewKafkaBalanced(
 	}
 
 	var err error
+	if k.balanceStrategy, err = KafkaBalanceStrategy(conf.Group.RebalanceStrategy); err != nil {
+		return nil, err
+	}
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if conf.Group.InstanceID != "" && !k.version.IsAtLeast(sarama.V2_3_0_0) {
+		return nil, fmt.Errorf("a target_version of at least 2.3.0 is required for static membership with group.instance_id, got %v", conf.TargetVersion)
+	}
 	return &k, nil
 }
 
@@ -283,6 +310,8 @@ func (k *KafkaBalanced) Connect() error {
 	config.Consumer.Group.Session.Timeout = k.sessionTimeout
 	config.Consumer.Group.Heartbeat.Interval = k.heartbeatInterval
 	config.Consumer.Group.Rebalance.Timeout = k.rebalanceTimeout
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{k.balanceStrategy}
+	config.Consumer.Group.InstanceId = k.conf.Group.InstanceID
 	config.ChannelBufferSize = k.conf.FetchBufferCap
 
 	if config.Net.ReadTimeout <= k.sessionTimeout {
@@ -2,18 +2,23 @@ package reader
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/scaleproto"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 	"go.nanomsg.org/mangos/v3"
 	"go.nanomsg.org/mangos/v3/protocol/pull"
+	"go.nanomsg.org/mangos/v3/protocol/rep"
+	"go.nanomsg.org/mangos/v3/protocol/respondent"
 	"go.nanomsg.org/mangos/v3/protocol/sub"
 
 	// Import all transport types
@@ -24,12 +29,13 @@ import (
 
 // ScaleProtoConfig contains configuration fields for the ScaleProto input type.
 type ScaleProtoConfig struct {
-	URLs        []string `json:"urls" yaml:"urls"`
-	Bind        bool     `json:"bind" yaml:"bind"`
-	SocketType  string   `json:"socket_type" yaml:"socket_type"`
-	SubFilters  []string `json:"sub_filters" yaml:"sub_filters"`
-	PollTimeout string   `json:"poll_timeout" yaml:"poll_timeout"`
-	RepTimeout  string   `json:"reply_timeout" yaml:"reply_timeout"`
+	URLs        []string    `json:"urls" yaml:"urls"`
+	Bind        bool        `json:"bind" yaml:"bind"`
+	SocketType  string      `json:"socket_type" yaml:"socket_type"`
+	SubFilters  []string    `json:"sub_filters" yaml:"sub_filters"`
+	TLS         btls.Config `json:"tls" yaml:"tls"`
+	PollTimeout string      `json:"poll_timeout" yaml:"poll_timeout"`
+	RepTimeout  string      `json:"reply_timeout" yaml:"reply_timeout"`
 }
 
 // NewScaleProtoConfig creates a new ScaleProtoConfig with default values.
@@ -39,6 +45,7 @@ func NewScaleProtoConfig() ScaleProtoConfig {
 		Bind:        true,
 		SocketType:  "PULL",
 		SubFilters:  []string{},
+		TLS:         btls.NewConfig(),
 		PollTimeout: "5s",
 		RepTimeout:  "5s",
 	}
@@ -53,6 +60,7 @@ type ScaleProto struct {
 
 	pollTimeout time.Duration
 	repTimeout  time.Duration
+	tlsConf     *tls.Config
 
 	urls  []string
 	conf  ScaleProtoConfig
@@ -68,14 +76,7 @@ func NewScaleProto(conf ScaleProtoConfig, log log.Modular, stats metrics.Type) (
 		log:   log,
 	}
 
-	for _, u := range conf.URLs {
-		for _, splitU := range strings.Split(u, ",") {
-			if len(splitU) > 0 {
-				// TODO: V4 Remove this work around
-				s.urls = append(s.urls, strings.Replace(splitU, "//*:", "//0.0.0.0:", 1))
-			}
-		}
-	}
+	s.urls = scaleproto.SplitURLs(conf.URLs)
 
 	if conf.SocketType == "SUB" && len(conf.SubFilters) == 0 {
 		return nil, errors.New("must provide at least one sub filter when connecting with a SUB socket, in order to subscribe to all messages add an empty string")
@@ -94,6 +95,13 @@ func NewScaleProto(conf ScaleProtoConfig, log log.Modular, stats metrics.Type) (
 		}
 	}
 
+	if conf.TLS.Enabled {
+		var err error
+		if s.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &s, nil
 }
 
@@ -106,6 +114,10 @@ func getSocketFromType(t string) (mangos.Socket, error) {
 		return pull.NewSocket()
 	case "SUB":
 		return sub.NewSocket()
+	case "REP":
+		return rep.NewSocket()
+	case "RESPONDENT":
+		return respondent.NewSocket()
 	}
 	return nil, types.ErrInvalidScaleProtoType
 }
@@ -140,20 +152,7 @@ func (s *ScaleProto) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
-	if s.conf.Bind {
-		for _, addr := range s.urls {
-			if err = socket.Listen(addr); err != nil {
-				break
-			}
-		}
-	} else {
-		for _, addr := range s.urls {
-			if err = socket.Dial(addr); err != nil {
-				break
-			}
-		}
-	}
-	if err != nil {
+	if err = scaleproto.Connect(socket, s.urls, s.conf.Bind, s.tlsConf); err != nil {
 		return err
 	}
 
@@ -170,9 +169,11 @@ func (s *ScaleProto) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
-	for _, filter := range s.conf.SubFilters {
-		if err := socket.SetOption(mangos.OptionSubscribe, []byte(filter)); err != nil {
-			return err
+	if s.conf.SocketType == "SUB" {
+		for _, filter := range s.conf.SubFilters {
+			if err = socket.SetOption(mangos.OptionSubscribe, []byte(filter)); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -207,6 +208,9 @@ func (s *ScaleProto) ReadWithContext(ctx context.Context) (types.Message, AsyncA
 	if socket == nil {
 		return nil, nil, types.ErrNotConnected
 	}
+	if s.conf.SocketType == "REP" || s.conf.SocketType == "RESPONDENT" {
+		return s.readRequest(socket)
+	}
 	data, err := socket.Recv()
 	if err != nil {
 		if err == mangos.ErrRecvTimeout {
@@ -217,6 +221,52 @@ func (s *ScaleProto) ReadWithContext(ctx context.Context) (types.Message, AsyncA
 	return message.New([][]byte{data}), noopAsyncAckFn, nil
 }
 
+// readRequest reads a request (or survey) on a dedicated context of the socket
+// so that many requests can be in flight at once, and replies to it once the
+// message has been delivered.
+func (s *ScaleProto) readRequest(socket mangos.Socket) (types.Message, AsyncAckFn, error) {
+	reqCtx, err := socket.OpenContext()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = reqCtx.SetOption(mangos.OptionRecvDeadline, s.repTimeout); err != nil {
+		reqCtx.Close()
+		return nil, nil, err
+	}
+
+	data, err := reqCtx.Recv()
+	if err != nil {
+		reqCtx.Close()
+		if err == mangos.ErrRecvTimeout {
+			return nil, nil, types.ErrTimeout
+		}
+		return nil, nil, err
+	}
+
+	msg := message.New([][]byte{data})
+	store := roundtrip.NewResultStore()
+	roundtrip.AddResultStore(msg, store)
+
+	return msg, func(ctx context.Context, res types.Response) error {
+		defer reqCtx.Close()
+		if res != nil && res.Error() != nil {
+			// Without a reply the peer will eventually resend the request.
+			return nil
+		}
+		var reply []byte
+		for _, resMsg := range store.Get() {
+			_ = resMsg.Iter(func(i int, part types.Part) error {
+				if len(reply) > 0 {
+					reply = append(reply, '\n')
+				}
+				reply = append(reply, part.Get()...)
+				return nil
+			})
+		}
+		return reqCtx.Send(reply)
+	}, nil
+}
+
 // Acknowledge instructs whether the pending messages were propagated
 // successfully.
 func (s *ScaleProto) Acknowledge(err error) error {
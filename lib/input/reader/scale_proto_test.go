@@ -0,0 +1,147 @@
+package reader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nanomsg.org/mangos/v3"
+	"go.nanomsg.org/mangos/v3/protocol/req"
+	"go.nanomsg.org/mangos/v3/protocol/surveyor"
+)
+
+func TestScaleProtoRep(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := NewScaleProtoConfig()
+	conf.URLs = []string{"inproc://benthos_scale_proto_rep_test"}
+	conf.SocketType = "REP"
+	conf.RepTimeout = "100ms"
+
+	s, err := NewScaleProto(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, s.ConnectWithContext(ctx))
+	defer s.CloseAsync()
+
+	_, _, err = s.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrTimeout, err)
+
+	reqSock, err := req.NewSocket()
+	require.NoError(t, err)
+	defer reqSock.Close()
+	require.NoError(t, reqSock.Dial("inproc://benthos_scale_proto_rep_test"))
+
+	replies := make(chan string, 2)
+	for _, reqStr := range []string{"foo", "bar"} {
+		reqCtx, err := reqSock.OpenContext()
+		require.NoError(t, err)
+		require.NoError(t, reqCtx.SetOption(mangos.OptionRecvDeadline, time.Second*5))
+		go func(reqCtx mangos.Context, reqStr string) {
+			defer reqCtx.Close()
+			if err := reqCtx.Send([]byte(reqStr)); err != nil {
+				replies <- err.Error()
+				return
+			}
+			res, err := reqCtx.Recv()
+			if err != nil {
+				replies <- err.Error()
+				return
+			}
+			replies <- reqStr + ":" + string(res)
+		}(reqCtx, reqStr)
+	}
+
+	// Read both requests before replying in order to test that they are
+	// handled concurrently.
+	var msgs []types.Message
+	var ackFns []AsyncAckFn
+	for len(msgs) < 2 {
+		msg, ackFn, err := s.ReadWithContext(ctx)
+		if err == types.ErrTimeout {
+			continue
+		}
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+		ackFns = append(ackFns, ackFn)
+	}
+
+	for i, msg := range msgs {
+		resMsg := msg.Copy()
+		resMsg.Get(0).Set(append([]byte("re:"), msg.Get(0).Get()...))
+		if i == 0 {
+			part := msg.Get(0).Copy()
+			part.Set([]byte("second"))
+			resMsg.Append(part)
+		}
+		require.NoError(t, roundtrip.SetAsResponse(resMsg))
+	}
+	for _, ackFn := range ackFns {
+		require.NoError(t, ackFn(ctx, response.NewAck()))
+	}
+
+	first := string(msgs[0].Get(0).Get())
+	second := string(msgs[1].Get(0).Get())
+	exp := map[string]struct{}{
+		first + ":re:" + first + "\nsecond": {},
+		second + ":re:" + second:            {},
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-replies:
+			assert.Contains(t, exp, r)
+			delete(exp, r)
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestScaleProtoRespondent(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	survSock, err := surveyor.NewSocket()
+	require.NoError(t, err)
+	defer survSock.Close()
+	require.NoError(t, survSock.Listen("inproc://benthos_scale_proto_respondent_test"))
+	require.NoError(t, survSock.SetOption(mangos.OptionSurveyTime, time.Second*5))
+
+	conf := NewScaleProtoConfig()
+	conf.URLs = []string{"inproc://benthos_scale_proto_respondent_test"}
+	conf.Bind = false
+	conf.RepTimeout = "100ms"
+	conf.SocketType = "RESPONDENT"
+
+	s, err := NewScaleProto(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, s.ConnectWithContext(ctx))
+	defer s.CloseAsync()
+
+	// Surveys are only delivered to respondents that are already connected.
+	require.Eventually(t, func() bool {
+		if err := survSock.Send([]byte("ping")); err != nil {
+			return false
+		}
+		rCtx, rDone := context.WithTimeout(ctx, time.Millisecond*100)
+		defer rDone()
+		msg, ackFn, err := s.ReadWithContext(rCtx)
+		if err != nil {
+			return false
+		}
+		assert.Equal(t, "ping", string(msg.Get(0).Get()))
+		require.NoError(t, ackFn(ctx, response.NewAck()))
+		return true
+	}, time.Second*5, time.Millisecond*10)
+
+	res, err := survSock.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "", string(res))
+}
@@ -0,0 +1,366 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeReplay] = TypeSpec{
+		constructor: fromSimpleConstructor(NewReplay),
+		Summary: `
+Reads archived messages from a child input, selecting those with a timestamp within a given range and emitting them either as fast as possible or paced to their original timing.`,
+		Description: `
+The timestamp of each message is obtained with a [Bloblang mapping](/docs/guides/bloblang/about/), which must result in either a unix timestamp in seconds or an RFC 3339 formatted string. Messages with a timestamp outside of the range set by ` + "`from` and `to`" + ` are acknowledged and dropped.
+
+Messages where the timestamp mapping fails are emitted immediately and flagged as having failed, allowing them to be handled with [error handling patterns](/docs/configuration/error_handling).
+
+When ` + "`speed`" + ` is greater than zero messages are emitted with the same intervals that separate their timestamps, divided by the speed. For example, a speed of ` + "`1`" + ` replays messages in real time, and a speed of ` + "`10`" + ` replays them ten times faster. Messages are expected to be consumed in timestamp order, messages with a timestamp earlier than those before it are emitted immediately.
+
+If the archive being replayed is ordered by time then ` + "`stop_after_range`" + ` can be set in order to close the input as soon as a message beyond the range is consumed, rather than reading the remainder of the archive.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Replaying Kafka",
+				Summary: "In this example we reprocess the messages of a topic that were produced during an incident, with their original timing.",
+				Config: `
+input:
+  replay:
+    timestamp: meta("kafka_timestamp_unix").number()
+    from: 2021-05-21T13:00:00Z
+    to: 2021-05-21T14:30:00Z
+    speed: 1
+    stop_after_range: true
+    input:
+      kafka:
+        addresses: [ TODO ]
+        topics: [ foo ]
+        consumer_group: foo_replay
+        start_from_oldest: true
+`,
+			},
+			{
+				Title:   "Replaying an S3 Archive",
+				Summary: "In this example we replay archived JSON documents from S3 as fast as possible, where each document contains its own timestamp.",
+				Config: `
+input:
+  replay:
+    timestamp: this.created_at
+    from: 2021-05-21T13:00:00Z
+    to: 2021-05-21T14:30:00Z
+    input:
+      s3:
+        bucket: TODO
+        prefix: archive/2021-05-21/
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("input", "The child input to consume from.").HasType(docs.FieldInput),
+			docs.FieldCommon(
+				"timestamp",
+				"A [Bloblang mapping](/docs/guides/bloblang/about/) that results in the timestamp of a message, either as a unix timestamp in seconds or an RFC 3339 formatted string.",
+				`meta("kafka_timestamp_unix").number()`,
+				`this.created_at`,
+			).Linter(docs.LintBloblangMapping),
+			docs.FieldCommon("from", "An optional RFC 3339 timestamp, messages with a timestamp before this are dropped.", "2021-05-21T13:00:00Z"),
+			docs.FieldCommon("to", "An optional RFC 3339 timestamp, messages with a timestamp after this are dropped.", "2021-05-21T14:30:00Z"),
+			docs.FieldCommon("speed", "The speed at which to replay messages relative to their original timing. If set to zero messages are emitted as fast as possible.", 0, 1, 60),
+			docs.FieldAdvanced("stop_after_range", "Whether the input should close once a message with a timestamp after `to` is consumed."),
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ReplayConfig contains configuration values for the Replay input type.
+type ReplayConfig struct {
+	Input          *Config `json:"input" yaml:"input"`
+	Timestamp      string  `json:"timestamp" yaml:"timestamp"`
+	From           string  `json:"from" yaml:"from"`
+	To             string  `json:"to" yaml:"to"`
+	Speed          float64 `json:"speed" yaml:"speed"`
+	StopAfterRange bool    `json:"stop_after_range" yaml:"stop_after_range"`
+}
+
+// NewReplayConfig creates a new ReplayConfig with default values.
+func NewReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		Input:          nil,
+		Timestamp:      "",
+		From:           "",
+		To:             "",
+		Speed:          0,
+		StopAfterRange: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyReplayConfig struct {
+	Input          interface{} `json:"input" yaml:"input"`
+	Timestamp      string      `json:"timestamp" yaml:"timestamp"`
+	From           string      `json:"from" yaml:"from"`
+	To             string      `json:"to" yaml:"to"`
+	Speed          float64     `json:"speed" yaml:"speed"`
+	StopAfterRange bool        `json:"stop_after_range" yaml:"stop_after_range"`
+}
+
+func (r ReplayConfig) dummy() dummyReplayConfig {
+	dummy := dummyReplayConfig{
+		Input:          r.Input,
+		Timestamp:      r.Timestamp,
+		From:           r.From,
+		To:             r.To,
+		Speed:          r.Speed,
+		StopAfterRange: r.StopAfterRange,
+	}
+	if r.Input == nil {
+		dummy.Input = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (r ReplayConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (r ReplayConfig) MarshalYAML() (interface{}, error) {
+	return r.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// Replay is an input type that reads messages from a child input within a
+// time range, optionally paced to the original timing of the messages.
+type Replay struct {
+	running int32
+
+	wrapped   Type
+	timestamp *mapping.Executor
+	from, to  time.Time
+	speed     float64
+	stopAfter bool
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewReplay creates a new Replay input type.
+func NewReplay(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.Replay.Input == nil {
+		return nil, errors.New("cannot create replay input without a child")
+	}
+	if conf.Replay.Timestamp == "" {
+		return nil, errors.New("a timestamp mapping is required")
+	}
+	if conf.Replay.Speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative, got %v", conf.Replay.Speed)
+	}
+
+	timestamp, err := bloblang.NewMapping("", conf.Replay.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp mapping: %w", err)
+	}
+
+	var from, to time.Time
+	if conf.Replay.From != "" {
+		if from, err = time.Parse(time.RFC3339Nano, conf.Replay.From); err != nil {
+			return nil, fmt.Errorf("failed to parse from timestamp: %w", err)
+		}
+	}
+	if conf.Replay.To != "" {
+		if to, err = time.Parse(time.RFC3339Nano, conf.Replay.To); err != nil {
+			return nil, fmt.Errorf("failed to parse to timestamp: %w", err)
+		}
+		if !from.IsZero() && to.Before(from) {
+			return nil, errors.New("the to timestamp must not be before the from timestamp")
+		}
+	}
+
+	wrapped, err := New(*conf.Replay.Input, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input '%v': %v", conf.Replay.Input.Type, err)
+	}
+
+	_, rLog, rStats := interop.LabelChild("replay", mgr, log, stats)
+	r := &Replay{
+		running: 1,
+
+		wrapped:   wrapped,
+		timestamp: timestamp,
+		from:      from,
+		to:        to,
+		speed:     conf.Replay.Speed,
+		stopAfter: conf.Replay.StopAfterRange,
+
+		log:          rLog,
+		stats:        rStats,
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+
+	go r.loop()
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Replay) getTimestamp(msg types.Message) (time.Time, error) {
+	v, err := r.timestamp.Exec(query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{},
+		Index:    0,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(0).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	}))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return query.IGetTimestamp(v)
+}
+
+func (r *Replay) loop() {
+	var (
+		mCount      = r.stats.GetCounter("count")
+		mPropagated = r.stats.GetCounter("propagated")
+		mSkipped    = r.stats.GetCounter("skipped")
+		mErr        = r.stats.GetCounter("error")
+	)
+
+	defer func() {
+		r.wrapped.CloseAsync()
+		err := r.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = r.wrapped.WaitForClose(time.Second) {
+		}
+		close(r.transactions)
+		close(r.closedChan)
+	}()
+
+	// The timestamp of the first message emitted and the time at which it was
+	// emitted, used for pacing all subsequent messages.
+	var firstTS, startedAt time.Time
+
+	for atomic.LoadInt32(&r.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-r.wrapped.TransactionChan():
+			if !open {
+				return
+			}
+		case <-r.closeChan:
+			return
+		}
+		mCount.Incr(1)
+
+		ts, err := r.getTimestamp(tran.Payload)
+		if err != nil {
+			mErr.Incr(1)
+			r.log.Debugf("Failed to obtain message timestamp: %v\n", err)
+			tran.Payload.Iter(func(i int, p types.Part) error {
+				processor.FlagErr(p, fmt.Errorf("failed to obtain message timestamp: %w", err))
+				return nil
+			})
+		} else if (!r.from.IsZero() && ts.Before(r.from)) || (!r.to.IsZero() && ts.After(r.to)) {
+			mSkipped.Incr(1)
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-r.closeChan:
+				return
+			}
+			if r.stopAfter && !r.to.IsZero() && ts.After(r.to) {
+				r.log.Infoln("Replay range exceeded, closing input.")
+				return
+			}
+			continue
+		} else if r.speed > 0 {
+			if firstTS.IsZero() {
+				firstTS, startedAt = ts, time.Now()
+			} else if offset := ts.Sub(firstTS); offset > 0 {
+				target := startedAt.Add(time.Duration(float64(offset) / r.speed))
+				select {
+				case <-time.After(time.Until(target)):
+				case <-r.closeChan:
+					return
+				}
+			}
+		}
+
+		select {
+		case r.transactions <- tran:
+			mPropagated.Incr(1)
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (r *Replay) TransactionChan() <-chan types.Transaction {
+	return r.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (r *Replay) Connected() bool {
+	return r.wrapped.Connected()
+}
+
+// CloseAsync shuts down the Replay input and stops processing requests.
+func (r *Replay) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Replay input has closed down.
+func (r *Replay) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,262 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/recording"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeReplayFile] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			r, err := newReplayFileReader(conf.ReplayFile, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncReader(
+				TypeReplayFile,
+				true,
+				reader.NewAsyncPreserver(r),
+				log, stats,
+			)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryLocal,
+			CategoryUtility,
+		},
+		Summary: `
+Replays the messages of a recording file along with their original metadata,
+preserving the relative timing with which they were recorded.`,
+		Description: `
+A recording contains a line of JSON for each message, which is an object with the fields ` + "`timestamp`" + `, the RFC 3339 time at which the message was recorded, ` + "`content`" + `, the base64 encoded contents of the message, and ` + "`metadata`" + `, an object of its metadata. Recordings with the extension ` + "`.gz`" + ` are decompressed with gzip. Recordings of live traffic can be captured with the [` + "`record`" + ` processor](/docs/components/processors/record) and the [` + "`record`" + ` output](/docs/components/outputs/record).
+
+When ` + "`speed`" + ` is greater than zero messages are emitted with the same intervals that separate their timestamps, divided by the speed. For example, a speed of ` + "`1`" + ` replays messages in real time, and a speed of ` + "`10`" + ` replays them ten times faster. If set to zero messages are emitted as fast as possible. Long gaps within a recording can be shortened with ` + "`max_delay`" + `, and messages with a timestamp earlier than those before it are emitted immediately.
+
+Each message is emitted with its recorded metadata, and the metadata field ` + "`replay_timestamp`" + ` is set to the time at which it was recorded. The input closes once all messages have been replayed, unless ` + "`loop`" + ` is true, in which case the recording is replayed from the beginning again.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Reproducing an Incident",
+				Summary: "In this example we replay a recording of production traffic at twice its original speed into a local pipeline, without waiting more than a second between messages.",
+				Config: `
+input:
+  replay_file:
+    path: ./incident_traffic.jsonl.gz
+    speed: 2
+    max_delay: 1s
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = this
+        root.processed_at = now()
+
+output:
+  stdout: {}
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The path of the recording file.", "./recording.jsonl", "./recording.jsonl.gz"),
+			docs.FieldCommon("speed", "The speed at which to replay messages relative to their original timing. If set to zero messages are emitted as fast as possible.", 0, 1, 60),
+			docs.FieldAdvanced("max_delay", "An optional maximum period to wait between messages, which shortens long gaps within a recording.", "1s", "1m"),
+			docs.FieldAdvanced("loop", "Whether to replay the recording from the beginning once all of its messages have been replayed."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ReplayFileConfig contains configuration fields for the ReplayFile input
+// type.
+type ReplayFileConfig struct {
+	Path     string  `json:"path" yaml:"path"`
+	Speed    float64 `json:"speed" yaml:"speed"`
+	MaxDelay string  `json:"max_delay" yaml:"max_delay"`
+	Loop     bool    `json:"loop" yaml:"loop"`
+}
+
+// NewReplayFileConfig creates a new ReplayFileConfig with default values.
+func NewReplayFileConfig() ReplayFileConfig {
+	return ReplayFileConfig{
+		Path:     "",
+		Speed:    1,
+		MaxDelay: "",
+		Loop:     false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type replayFileReader struct {
+	conf     ReplayFileConfig
+	maxDelay time.Duration
+	log      log.Modular
+
+	mLoops metrics.StatCounter
+
+	mut     sync.Mutex
+	file    *os.File
+	records *recording.Reader
+	pending *recording.Record
+
+	// The timestamp of the previous record and the time at which it was due,
+	// used for pacing the next record.
+	prevTS  time.Time
+	prevDue time.Time
+}
+
+func newReplayFileReader(conf ReplayFileConfig, log log.Modular, stats metrics.Type) (*replayFileReader, error) {
+	if conf.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if conf.Speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative, got %v", conf.Speed)
+	}
+	r := &replayFileReader{
+		conf:   conf,
+		log:    log,
+		mLoops: stats.GetCounter("loops"),
+	}
+	if conf.MaxDelay != "" {
+		var err error
+		if r.maxDelay, err = time.ParseDuration(conf.MaxDelay); err != nil {
+			return nil, fmt.Errorf("failed to parse max_delay: %w", err)
+		}
+	}
+	return r, nil
+}
+
+func (r *replayFileReader) open() error {
+	file, err := os.Open(r.conf.Path)
+	if err != nil {
+		return err
+	}
+	records, err := recording.NewReader(file, recording.IsCompressed(r.conf.Path))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.records = file, records
+	r.prevTS, r.prevDue = time.Time{}, time.Time{}
+	return nil
+}
+
+func (r *replayFileReader) closeFile() {
+	if r.file != nil {
+		r.records.Close()
+		r.file.Close()
+		r.file, r.records = nil, nil
+	}
+}
+
+func (r *replayFileReader) ConnectWithContext(ctx context.Context) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.file != nil {
+		return nil
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.log.Infof("Replaying recording: %v\n", r.conf.Path)
+	return nil
+}
+
+// next returns the next record of the recording, reopening the recording once
+// it ends when looping.
+func (r *replayFileReader) next() (recording.Record, error) {
+	rec, err := r.records.Next()
+	if !errors.Is(err, io.EOF) || !r.conf.Loop {
+		return rec, err
+	}
+	r.closeFile()
+	if err = r.open(); err != nil {
+		return rec, err
+	}
+	r.mLoops.Incr(1)
+	return r.records.Next()
+}
+
+// due returns the time at which a record should be emitted.
+func (r *replayFileReader) due(ts time.Time) time.Time {
+	now := time.Now()
+	if r.conf.Speed == 0 || r.prevDue.IsZero() {
+		return now
+	}
+	gap := time.Duration(float64(ts.Sub(r.prevTS)) / r.conf.Speed)
+	if gap < 0 {
+		gap = 0
+	}
+	if r.maxDelay > 0 && gap > r.maxDelay {
+		gap = r.maxDelay
+	}
+	return r.prevDue.Add(gap)
+}
+
+func (r *replayFileReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.file == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	if r.pending == nil {
+		rec, err := r.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				r.closeFile()
+				return nil, nil, types.ErrTypeClosed
+			}
+			return nil, nil, err
+		}
+		r.pending = &rec
+	}
+
+	due := r.due(r.pending.Timestamp)
+	select {
+	case <-time.After(time.Until(due)):
+	case <-ctx.Done():
+		// The record is kept pending so that it's emitted by the next read.
+		return nil, nil, types.ErrTimeout
+	}
+
+	rec := r.pending
+	r.pending = nil
+	r.prevTS, r.prevDue = rec.Timestamp, due
+
+	part := rec.Part()
+	part.Metadata().Set("replay_timestamp", rec.Timestamp.Format(time.RFC3339Nano))
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg, func(context.Context, types.Response) error {
+		return nil
+	}, nil
+}
+
+func (r *replayFileReader) CloseAsync() {
+	r.mut.Lock()
+	r.closeFile()
+	r.mut.Unlock()
+}
+
+func (r *replayFileReader) WaitForClose(time.Duration) error {
+	return nil
+}
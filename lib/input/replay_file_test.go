@@ -0,0 +1,138 @@
+package input
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/recording"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestRecording(t *testing.T, path string, start time.Time, offsets ...time.Duration) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := recording.NewWriter(f, recording.IsCompressed(path))
+	for i, offset := range offsets {
+		part := message.NewPart([]byte{byte('a' + i)})
+		part.Metadata().Set("index", string(rune('0'+i)))
+		require.NoError(t, w.Write(recording.NewRecord(part, start.Add(offset))))
+	}
+	require.NoError(t, w.Close())
+}
+
+func TestReplayFileTiming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_replay_file_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	start := time.Date(2021, 5, 21, 13, 0, 0, 0, time.UTC)
+	path := filepath.Join(dir, "recording.jsonl.gz")
+	writeTestRecording(t, path, start, 0, 200*time.Millisecond, time.Hour)
+
+	conf := NewReplayFileConfig()
+	conf.Path = path
+	conf.Speed = 2
+	conf.MaxDelay = "150ms"
+
+	r, err := newReplayFileReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(r.CloseAsync)
+
+	ctx := context.Background()
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrNotConnected, err)
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	began := time.Now()
+	msg, _, err := r.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(msg.Get(0).Get()))
+	assert.Equal(t, "0", msg.Get(0).Metadata().Get("index"))
+	assert.Equal(t, "2021-05-21T13:00:00Z", msg.Get(0).Metadata().Get("replay_timestamp"))
+
+	// A read that times out keeps the message pending.
+	tCtx, done := context.WithTimeout(ctx, 10*time.Millisecond)
+	_, _, err = r.ReadWithContext(tCtx)
+	done()
+	require.Equal(t, types.ErrTimeout, err)
+
+	msg, _, err = r.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(msg.Get(0).Get()))
+	assert.GreaterOrEqual(t, int64(time.Since(began)), int64(100*time.Millisecond))
+
+	// The gap of an hour is capped by the max delay.
+	msg, _, err = r.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(msg.Get(0).Get()))
+	assert.Less(t, int64(time.Since(began)), int64(time.Second))
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTypeClosed, err)
+}
+
+func TestReplayFileLoop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_replay_file_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "recording.jsonl")
+	writeTestRecording(t, path, time.Now(), 0, time.Hour)
+
+	conf := NewReplayFileConfig()
+	conf.Path = path
+	conf.Speed = 0
+	conf.Loop = true
+
+	r, err := newReplayFileReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(r.CloseAsync)
+
+	ctx := context.Background()
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	for _, exp := range []string{"a", "b", "a", "b", "a"} {
+		msg, _, err := r.ReadWithContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(msg.Get(0).Get()))
+	}
+}
+
+func TestReplayFileConfigErrors(t *testing.T) {
+	conf := NewReplayFileConfig()
+	_, err := newReplayFileReader(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a path must be specified")
+
+	conf.Path = "/tmp/foo"
+	conf.Speed = -1
+	_, err = newReplayFileReader(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "speed must not be negative, got -1")
+
+	conf.Speed = 1
+	conf.MaxDelay = "nope"
+	_, err = newReplayFileReader(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.MaxDelay = ""
+	conf.Path = "/does/not/exist.jsonl"
+	r, err := newReplayFileReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.Error(t, r.ConnectWithContext(context.Background()))
+}
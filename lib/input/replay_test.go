@@ -0,0 +1,166 @@
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replayTestConfig(t *testing.T, lines ...string) Config {
+	t.Helper()
+
+	tmpDir, err := ioutil.TempDir("", "benthos_replay_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	path := filepath.Join(tmpDir, "archive.jsonl")
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644))
+
+	inConf := NewConfig()
+	inConf.Type = TypeFile
+	inConf.File.Path = path
+
+	conf := NewConfig()
+	conf.Type = TypeReplay
+	conf.Replay.Input = &inConf
+	conf.Replay.Timestamp = "this.ts"
+	return conf
+}
+
+func readReplay(t *testing.T, in Type) []string {
+	t.Helper()
+
+	var contents []string
+	for {
+		select {
+		case tran, open := <-in.TransactionChan():
+			if !open {
+				return contents
+			}
+			contents = append(contents, string(tran.Payload.Get(0).Get()))
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second * 5):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestReplayRange(t *testing.T) {
+	conf := replayTestConfig(t,
+		`{"ts":100}`,
+		`{"ts":"1970-01-01T00:01:41Z"}`,
+		`{"ts":102}`,
+		`{"ts":103}`,
+		`{"ts":104}`,
+	)
+	conf.Replay.From = "1970-01-01T00:01:41Z"
+	conf.Replay.To = "1970-01-01T00:01:43Z"
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`{"ts":"1970-01-01T00:01:41Z"}`,
+		`{"ts":102}`,
+		`{"ts":103}`,
+	}, readReplay(t, in))
+	require.NoError(t, in.WaitForClose(time.Second*5))
+}
+
+func TestReplayStopAfterRange(t *testing.T) {
+	conf := replayTestConfig(t,
+		`{"ts":100}`,
+		`{"ts":101}`,
+		`{"ts":102}`,
+		`{"ts":101}`,
+	)
+	conf.Replay.To = "1970-01-01T00:01:41Z"
+	conf.Replay.StopAfterRange = true
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`{"ts":100}`, `{"ts":101}`}, readReplay(t, in))
+	require.NoError(t, in.WaitForClose(time.Second*5))
+}
+
+func TestReplaySpeed(t *testing.T) {
+	conf := replayTestConfig(t,
+		`{"ts":100}`,
+		`{"ts":101}`,
+		`{"ts":102}`,
+	)
+	conf.Replay.Speed = 10
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	started := time.Now()
+	assert.Len(t, readReplay(t, in), 3)
+	assert.GreaterOrEqual(t, int64(time.Since(started)), int64(time.Millisecond*200))
+	require.NoError(t, in.WaitForClose(time.Second*5))
+}
+
+func TestReplayTimestampErr(t *testing.T) {
+	conf := replayTestConfig(t,
+		`{"ts":100}`,
+		`{"nope":101}`,
+		`{"ts":102}`,
+	)
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	var flags []string
+	for range []int{0, 1, 2} {
+		select {
+		case tran := <-in.TransactionChan():
+			flags = append(flags, tran.Payload.Get(0).Metadata().Get(processor.FailFlagKey))
+			tran.ResponseChan <- response.NewAck()
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+	require.Len(t, flags, 3)
+	assert.Empty(t, flags[0])
+	assert.Contains(t, flags[1], "failed to obtain message timestamp")
+	assert.Empty(t, flags[2])
+
+	in.CloseAsync()
+	require.NoError(t, in.WaitForClose(time.Second*5))
+}
+
+func TestReplayErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeReplay
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'replay': cannot create replay input without a child")
+
+	conf = replayTestConfig(t)
+	conf.Replay.Timestamp = ""
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'replay': a timestamp mapping is required")
+
+	conf = replayTestConfig(t)
+	conf.Replay.From = "1970-01-01T00:01:41Z"
+	conf.Replay.To = "1970-01-01T00:01:40Z"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "failed to create input 'replay': the to timestamp must not be before the from timestamp")
+}
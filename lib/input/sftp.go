@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	sftpSetup "github.com/Jeffail/benthos/v3/internal/service/sftp"
+	"github.com/Jeffail/benthos/v3/internal/shard"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -88,6 +89,10 @@ You can access these metadata fields using [function interpolation](/docs/config
 				"watcher",
 				"An experimental mode whereby the input will periodically scan the target paths for new files and consume them, when all files are consumed the input will continue polling for new files.",
 			).WithChildren(watcherDocs...).AtVersion("3.42.0"),
+			shard.FieldSpec(
+				"the files found within the target paths",
+				"Files are assigned as the target paths are scanned. When watcher mode is enabled the paths are scanned at each poll, and therefore files of a member that leaves the group are consumed by the remaining members at their next poll. Otherwise files of a member that leaves the group before consuming them are not reassigned to the remaining members.",
+			).AtVersion("3.47.0"),
 		},
 		Categories: []Category{
 			CategoryNetwork,
@@ -113,6 +118,7 @@ type SFTPConfig struct {
 	DeleteOnFinish bool                  `json:"delete_on_finish" yaml:"delete_on_finish"`
 	MaxBuffer      int                   `json:"max_buffer" yaml:"max_buffer"`
 	Watcher        watcherConfig         `json:"watcher" yaml:"watcher"`
+	Sharding       shard.Config          `json:"sharding" yaml:"sharding"`
 }
 
 // NewSFTPConfig creates a new SFTPConfig with default values.
@@ -130,6 +136,7 @@ func NewSFTPConfig() SFTPConfig {
 			PollInterval: "1s",
 			Cache:        "",
 		},
+		Sharding: shard.NewConfig(),
 	}
 }
 
@@ -153,6 +160,8 @@ type sftpReader struct {
 
 	watcherPollInterval time.Duration
 	watcherMinAge       time.Duration
+
+	shards *shard.Coordinator
 }
 
 func newSFTPReader(conf SFTPConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*sftpReader, error) {
@@ -192,6 +201,12 @@ func newSFTPReader(conf SFTPConfig, mgr types.Manager, log log.Modular, stats me
 		watcherMinAge:       watcherMinAge,
 	}
 
+	if conf.Sharding.Enabled {
+		if s.shards, err = shard.NewCoordinator(conf.Sharding, mgr, log, stats); err != nil {
+			return nil, err
+		}
+	}
+
 	return s, err
 }
 
@@ -206,6 +221,12 @@ func (s *sftpReader) ConnectWithContext(ctx context.Context) error {
 		return nil
 	}
 
+	if s.shards != nil {
+		if err = s.shards.Sync(ctx); err != nil {
+			return err
+		}
+	}
+
 	if s.client == nil {
 		if s.client, err = s.conf.Credentials.GetClient(s.conf.Address); err != nil {
 			return err
@@ -315,6 +336,11 @@ func (s *sftpReader) CloseAsync() {
 			s.client.Close()
 			s.client = nil
 		}
+		if s.shards != nil {
+			if err := s.shards.Close(context.Background()); err != nil {
+				s.log.Errorf("Failed to close sharding coordinator: %v\n", err)
+			}
+		}
 		s.scannerMut.Unlock()
 	}()
 }
@@ -325,6 +351,12 @@ func (s *sftpReader) WaitForClose(timeout time.Duration) error {
 	return nil
 }
 
+// ownsPath returns whether a path is assigned to this instance when sharding
+// is enabled.
+func (s *sftpReader) ownsPath(path string) bool {
+	return s.shards == nil || s.shards.Owns(path)
+}
+
 func (s *sftpReader) getFilePaths() ([]string, error) {
 	var filepaths []string
 	if !s.conf.Watcher.Enabled {
@@ -334,7 +366,11 @@ func (s *sftpReader) getFilePaths() ([]string, error) {
 				s.log.Warnf("Failed to scan files from path %v: %v\n", p, err)
 				continue
 			}
-			filepaths = append(filepaths, paths...)
+			for _, path := range paths {
+				if s.ownsPath(path) {
+					filepaths = append(filepaths, path)
+				}
+			}
 		}
 		return filepaths, nil
 	}
@@ -348,6 +384,9 @@ func (s *sftpReader) getFilePaths() ([]string, error) {
 			}
 
 			for _, path := range paths {
+				if !s.ownsPath(path) {
+					continue
+				}
 				info, err := s.client.Stat(path)
 				if err != nil {
 					s.log.Warnf("Failed to stat path %v: %v\n", path, err)
@@ -0,0 +1,409 @@
+package input
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/snmp"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func snmpUserFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("name", "The name of the user.").HasType(docs.FieldString).HasDefault(""),
+		docs.FieldCommon("auth_protocol", "The authentication protocol of the user, if empty messages are not authenticated.").HasOptions("", "MD5", "SHA").HasType(docs.FieldString).HasDefault(""),
+		docs.FieldCommon("auth_password", "The authentication password of the user, which must be at least 8 characters.").HasType(docs.FieldString).HasDefault(""),
+		docs.FieldCommon("priv_protocol", "The privacy protocol of the user, if empty messages are not encrypted. Privacy requires an authentication protocol.").HasOptions("", "DES", "AES").HasType(docs.FieldString).HasDefault(""),
+		docs.FieldCommon("priv_password", "The privacy password of the user, which must be at least 8 characters.").HasType(docs.FieldString).HasDefault(""),
+	}
+}
+
+func init() {
+	Constructors[TypeSNMP] = TypeSpec{
+		constructor: fromSimpleConstructor(NewSNMP),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Periodically walks a list of OIDs on one or more SNMP agents, creating a message with the values found for each agent.`,
+		Description: `
+Each OID is walked with GetBulk requests, returning every variable within its subtree. An OID of a scalar instance, such as ` + "`1.3.6.1.2.1.1.3.0`" + ` (` + "`sysUpTime.0`" + `), results in just that variable. Agents are polled in turn on every ` + "`interval`" + `, and agents that fail to respond are logged and skipped until the next interval.
+
+The message of each agent contains a JSON object with the address of the agent and the variables found in the order they were walked:
+
+` + "```json" + `
+{
+  "address": "10.0.0.1:161",
+  "variables": [
+    { "oid": "1.3.6.1.2.1.1.3.0", "type": "timeticks", "value": 123456 },
+    { "oid": "1.3.6.1.2.1.2.2.1.10.1", "type": "counter32", "value": 98765 },
+    { "oid": "1.3.6.1.2.1.2.2.1.2.1", "type": "octet_string", "value": "eth0" }
+  ]
+}
+` + "```" + `
+
+The ` + "`type`" + ` of a variable is one of ` + "`integer`, `octet_string`, `null`, `object_identifier`, `ip_address`, `counter32`, `gauge32`, `timeticks`, `opaque` or `counter64`" + `. Octet strings that aren't printable UTF-8, such as MAC addresses, are formatted as colon separated hex bytes (` + "`00:1a:2b:3c:4d:5e`" + `).
+
+OIDs must be numeric as MIBs are not loaded. SNMPv1 is not supported.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- snmp_address
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Interface Counters",
+				Summary: "This example polls the interface names and traffic counters of two switches every minute using SNMPv3, emitting a message for each variable.",
+				Config: `
+input:
+  snmp:
+    addresses: [ 10.0.0.1, 10.0.0.2 ]
+    version: "3"
+    user:
+      name: benthos
+      auth_protocol: SHA
+      auth_password: ${SNMP_AUTH_PASSWORD}
+      priv_protocol: AES
+      priv_password: ${SNMP_PRIV_PASSWORD}
+    oids:
+      - 1.3.6.1.2.1.31.1.1.1.1 # ifName
+      - 1.3.6.1.2.1.31.1.1.1.6 # ifHCInOctets
+      - 1.3.6.1.2.1.31.1.1.1.10 # ifHCOutOctets
+    interval: 60s
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = this.variables.map_each(v -> v.merge({ "device": this.address }))
+    - unarchive:
+        format: json_array
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("addresses", "A list of agent addresses to poll, where the port defaults to 161.", []string{"10.0.0.1", "switch1.example.com:1161"}).Array(),
+			docs.FieldCommon("version", "The SNMP version to use.").HasOptions("2c", "3"),
+			docs.FieldCommon("community", "The community of SNMPv2c requests."),
+			docs.FieldCommon("user", "The user of SNMPv3 requests.").WithChildren(snmpUserFieldSpecs()...),
+			docs.FieldAdvanced("context_name", "The context name of SNMPv3 requests."),
+			docs.FieldCommon("oids", "A list of numeric OIDs to walk.", []string{"1.3.6.1.2.1.1.3.0", "1.3.6.1.2.1.2.2.1.10"}).Array(),
+			docs.FieldCommon("interval", "The period of time between polls.", "30s", "5m"),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a response to a request."),
+			docs.FieldAdvanced("retries", "The number of times to retry a request that times out."),
+			docs.FieldAdvanced("max_repetitions", "The maximum number of variables to request with each GetBulk request."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SNMPUserConfig contains configuration fields of an SNMPv3 user.
+type SNMPUserConfig struct {
+	Name         string `json:"name" yaml:"name"`
+	AuthProtocol string `json:"auth_protocol" yaml:"auth_protocol"`
+	AuthPassword string `json:"auth_password" yaml:"auth_password"`
+	PrivProtocol string `json:"priv_protocol" yaml:"priv_protocol"`
+	PrivPassword string `json:"priv_password" yaml:"priv_password"`
+}
+
+// NewSNMPUserConfig creates a new SNMPUserConfig with default values.
+func NewSNMPUserConfig() SNMPUserConfig {
+	return SNMPUserConfig{
+		Name:         "",
+		AuthProtocol: "",
+		AuthPassword: "",
+		PrivProtocol: "",
+		PrivPassword: "",
+	}
+}
+
+func (s SNMPUserConfig) user() snmp.User {
+	return snmp.User{
+		Name:         s.Name,
+		AuthProtocol: snmp.AuthProtocol(s.AuthProtocol),
+		AuthPassword: s.AuthPassword,
+		PrivProtocol: snmp.PrivProtocol(s.PrivProtocol),
+		PrivPassword: s.PrivPassword,
+	}
+}
+
+// SNMPConfig contains configuration fields for the SNMP input type.
+type SNMPConfig struct {
+	Addresses      []string       `json:"addresses" yaml:"addresses"`
+	Version        string         `json:"version" yaml:"version"`
+	Community      string         `json:"community" yaml:"community"`
+	User           SNMPUserConfig `json:"user" yaml:"user"`
+	ContextName    string         `json:"context_name" yaml:"context_name"`
+	OIDs           []string       `json:"oids" yaml:"oids"`
+	Interval       string         `json:"interval" yaml:"interval"`
+	Timeout        string         `json:"timeout" yaml:"timeout"`
+	Retries        int            `json:"retries" yaml:"retries"`
+	MaxRepetitions int            `json:"max_repetitions" yaml:"max_repetitions"`
+}
+
+// NewSNMPConfig creates a new SNMPConfig with default values.
+func NewSNMPConfig() SNMPConfig {
+	return SNMPConfig{
+		Addresses:      []string{},
+		Version:        "2c",
+		Community:      "public",
+		User:           NewSNMPUserConfig(),
+		ContextName:    "",
+		OIDs:           []string{},
+		Interval:       "60s",
+		Timeout:        "5s",
+		Retries:        2,
+		MaxRepetitions: 10,
+	}
+}
+
+// NewSNMP creates a new SNMP input type.
+func NewSNMP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	dial, err := newSNMPDialFn(conf.SNMP)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := newSNMPReader(conf.SNMP, dial, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeSNMP, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type snmpClient interface {
+	Walk(ctx context.Context, root string, maxRepetitions int) ([]snmp.Variable, error)
+	Close() error
+}
+
+type snmpDialFn func(ctx context.Context, address string) (snmpClient, error)
+
+func newSNMPDialFn(conf SNMPConfig) (snmpDialFn, error) {
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+	}
+	cConf := snmp.ClientConfig{
+		Version:     snmp.Version(conf.Version),
+		Community:   conf.Community,
+		User:        conf.User.user(),
+		ContextName: conf.ContextName,
+		Timeout:     timeout,
+		Retries:     conf.Retries,
+	}
+	switch cConf.Version {
+	case snmp.Version2c:
+	case snmp.Version3:
+		if err := cConf.User.Validate(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("version not recognised: %v", conf.Version)
+	}
+	return func(ctx context.Context, address string) (snmpClient, error) {
+		c := cConf
+		c.Address = address
+		return snmp.Dial(ctx, c)
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type snmpReader struct {
+	conf     SNMPConfig
+	dial     snmpDialFn
+	log      log.Modular
+	interval time.Duration
+
+	mut      sync.Mutex
+	clients  map[string]snmpClient
+	pending  []types.Message
+	nextPoll time.Time
+}
+
+func newSNMPReader(conf SNMPConfig, dial snmpDialFn, log log.Modular) (*snmpReader, error) {
+	if len(conf.Addresses) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+	if len(conf.OIDs) == 0 {
+		return nil, errors.New("at least one oid must be specified")
+	}
+	interval, err := time.ParseDuration(conf.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval string: %v", err)
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be greater than zero")
+	}
+	return &snmpReader{
+		conf:     conf,
+		dial:     dial,
+		log:      log,
+		interval: interval,
+	}, nil
+}
+
+// ConnectWithContext creates a client for each agent.
+func (s *snmpReader) ConnectWithContext(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.clients != nil {
+		return nil
+	}
+
+	clients := map[string]snmpClient{}
+	for _, addr := range s.conf.Addresses {
+		c, err := s.dial(ctx, addr)
+		if err != nil {
+			for _, c := range clients {
+				c.Close()
+			}
+			return fmt.Errorf("failed to create client for %v: %w", addr, err)
+		}
+		clients[addr] = c
+	}
+	s.clients = clients
+	s.log.Infof("Polling %v SNMP agents\n", len(clients))
+	return nil
+}
+
+// poll walks the OIDs of each agent, returning a message for each agent that
+// responded.
+func (s *snmpReader) poll(ctx context.Context) []types.Message {
+	var msgs []types.Message
+agents:
+	for _, addr := range s.conf.Addresses {
+		client := s.clients[addr]
+
+		var vars []interface{}
+		for _, oid := range s.conf.OIDs {
+			res, err := client.Walk(ctx, oid, s.conf.MaxRepetitions)
+			if err != nil {
+				s.log.Errorf("Failed to walk %v of agent %v: %v\n", oid, addr, err)
+				continue agents
+			}
+			for _, v := range res {
+				vars = append(vars, snmpVariableToJSON(v))
+			}
+		}
+
+		b, err := json.Marshal(map[string]interface{}{
+			"address":   addr,
+			"variables": vars,
+		})
+		if err != nil {
+			s.log.Errorf("Failed to marshal variables of agent %v: %v\n", addr, err)
+			continue
+		}
+		msg := message.New([][]byte{b})
+		msg.Get(0).Metadata().Set("snmp_address", addr)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// ReadWithContext returns the message of the next agent, polling all agents
+// once the interval has elapsed.
+func (s *snmpReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.clients == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	for len(s.pending) == 0 {
+		select {
+		case <-time.After(time.Until(s.nextPoll)):
+		case <-ctx.Done():
+			return nil, nil, types.ErrTimeout
+		}
+		s.nextPoll = time.Now().Add(s.interval)
+		s.pending = s.poll(ctx)
+	}
+
+	msg := s.pending[0]
+	s.pending = s.pending[1:]
+	return msg, func(context.Context, types.Response) error {
+		return nil
+	}, nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (s *snmpReader) CloseAsync() {
+	s.mut.Lock()
+	for _, c := range s.clients {
+		c.Close()
+	}
+	s.clients = nil
+	s.mut.Unlock()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (s *snmpReader) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func snmpVariableToJSON(v snmp.Variable) map[string]interface{} {
+	value := v.Value
+	if b, ok := value.([]byte); ok {
+		value = snmpBytesToString(v.Type, b)
+	}
+	return map[string]interface{}{
+		"oid":   v.OID,
+		"type":  string(v.Type),
+		"value": value,
+	}
+}
+
+// snmpBytesToString returns octet strings as text when they are printable
+// UTF-8, and as colon separated hex bytes otherwise.
+func snmpBytesToString(t snmp.Type, b []byte) string {
+	printable := t == snmp.TypeOctetString && utf8.Valid(b)
+	if printable {
+		for _, r := range string(b) {
+			if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+				printable = false
+				break
+			}
+		}
+	}
+	if printable {
+		return string(b)
+	}
+	if len(b) == 0 {
+		return ""
+	}
+	h := hex.EncodeToString(b)
+	out := make([]byte, 0, len(h)+len(b)-1)
+	for i := 0; i < len(h); i += 2 {
+		if i > 0 {
+			out = append(out, ':')
+		}
+		out = append(out, h[i:i+2]...)
+	}
+	return string(out)
+}
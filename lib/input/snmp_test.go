@@ -0,0 +1,157 @@
+package input
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/snmp"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSNMPClient struct {
+	vars map[string][]snmp.Variable
+	err  error
+}
+
+func (f *fakeSNMPClient) Walk(ctx context.Context, root string, maxRepetitions int) ([]snmp.Variable, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.vars[root], nil
+}
+
+func (f *fakeSNMPClient) Close() error {
+	return nil
+}
+
+func TestSNMPPoll(t *testing.T) {
+	clients := map[string]*fakeSNMPClient{
+		"10.0.0.1": {vars: map[string][]snmp.Variable{
+			"1.3.6.1.2.1.1.3.0": {
+				{OID: "1.3.6.1.2.1.1.3.0", Type: snmp.TypeTimeTicks, Value: uint64(5000)},
+			},
+			"1.3.6.1.2.1.2.2.1.6": {
+				{OID: "1.3.6.1.2.1.2.2.1.6.1", Type: snmp.TypeOctetString, Value: []byte{0, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}},
+				{OID: "1.3.6.1.2.1.2.2.1.6.2", Type: snmp.TypeOctetString, Value: []byte("eth1")},
+			},
+		}},
+		"10.0.0.2": {err: errors.New("request timed out")},
+	}
+
+	conf := NewSNMPConfig()
+	conf.Addresses = []string{"10.0.0.1", "10.0.0.2"}
+	conf.OIDs = []string{"1.3.6.1.2.1.1.3.0", "1.3.6.1.2.1.2.2.1.6"}
+	conf.Interval = "1ms"
+	rdr, err := newSNMPReader(conf, func(ctx context.Context, address string) (snmpClient, error) {
+		return clients[address], nil
+	}, log.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	for i := 0; i < 2; i++ {
+		msg, _, err := rdr.ReadWithContext(ctx)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"address": "10.0.0.1",
+			"variables": [
+				{"oid":"1.3.6.1.2.1.1.3.0","type":"timeticks","value":5000},
+				{"oid":"1.3.6.1.2.1.2.2.1.6.1","type":"octet_string","value":"00:1a:2b:3c:4d:5e"},
+				{"oid":"1.3.6.1.2.1.2.2.1.6.2","type":"octet_string","value":"eth1"}
+			]
+		}`, string(msg.Get(0).Get()))
+		assert.Equal(t, "10.0.0.1", msg.Get(0).Metadata().Get("snmp_address"))
+	}
+
+	rdr.CloseAsync()
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+}
+
+func TestSNMPBadConfig(t *testing.T) {
+	conf := NewSNMPConfig()
+	_, err := newSNMPReader(conf, nil, log.Noop())
+	require.EqualError(t, err, "at least one address must be specified")
+
+	conf.Addresses = []string{"localhost"}
+	_, err = newSNMPReader(conf, nil, log.Noop())
+	require.EqualError(t, err, "at least one oid must be specified")
+
+	conf.Version = "1"
+	_, err = newSNMPDialFn(conf)
+	require.EqualError(t, err, "version not recognised: 1")
+
+	conf.Version = "3"
+	conf.User.Name = "foo"
+	conf.User.AuthProtocol = "SHA"
+	_, err = newSNMPDialFn(conf)
+	require.EqualError(t, err, "user foo: authentication password must be at least 8 characters")
+}
+
+func TestSNMPTrap(t *testing.T) {
+	conf := NewSNMPTrapConfig()
+	conf.Address = "127.0.0.1:0"
+	conf.Communities = []string{"public"}
+	rdr, err := newSNMPTrapReader(conf, log.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	conn, err := net.Dial("udp", rdr.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// A linkDown trap of the community "private" followed by one of "public".
+	trap, err := hex.DecodeString("306802010104067075626c6963a75b0201010201000201003050300e06082b06010201010300430204d23017060a2b06010603010104010006092b0601060301010503300f060a2b0601020102020101020201023014060a2b0601020102020106020406001a2b3c4d5e")
+	require.NoError(t, err)
+	badTrap := append([]byte(nil), trap...)
+	copy(badTrap[7:], "privat")
+
+	_, err = conn.Write(badTrap)
+	require.NoError(t, err)
+	_, err = conn.Write(trap)
+	require.NoError(t, err)
+
+	msg, _, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": "2c",
+		"community": "public",
+		"source": "`+conn.LocalAddr().String()+`",
+		"inform": false,
+		"uptime": 1234,
+		"trap_oid": "1.3.6.1.6.3.1.1.5.3",
+		"variables": [
+			{"oid":"1.3.6.1.2.1.2.2.1.1.2","type":"integer","value":2},
+			{"oid":"1.3.6.1.2.1.2.2.1.6.2","type":"octet_string","value":"00:1a:2b:3c:4d:5e"}
+		]
+	}`, string(msg.Get(0).Get()))
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, conn.LocalAddr().String(), meta.Get("snmp_source"))
+	assert.Equal(t, "2c", meta.Get("snmp_version"))
+	assert.Equal(t, "1.3.6.1.6.3.1.1.5.3", meta.Get("snmp_trap_oid"))
+
+	readCtx, readDone := context.WithTimeout(ctx, time.Millisecond*50)
+	_, _, err = rdr.ReadWithContext(readCtx)
+	readDone()
+	assert.Equal(t, types.ErrTimeout, err)
+
+	rdr.CloseAsync()
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+	assert.Equal(t, types.ErrTypeClosed, rdr.ConnectWithContext(ctx))
+}
@@ -0,0 +1,291 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/snmp"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSNMPTrap] = TypeSpec{
+		constructor: fromSimpleConstructor(NewSNMPTrap),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Receives SNMPv2c and SNMPv3 notifications (traps and informs) over UDP.`,
+		Description: `
+SNMPv2c notifications are accepted when their community is listed in ` + "`communities`" + `, or for any community when the list is empty. SNMPv3 notifications are accepted when sent by one of the configured ` + "`users`" + ` with the security level of that user, and are authenticated and decrypted with the keys of the user localized to the engine ID of the sending agent. Notifications that fail these checks or cannot be decoded are dropped and logged at debug level.
+
+SNMPv2c inform requests are acknowledged with a response once received, SNMPv3 inform requests and SNMPv1 traps are not supported. As UDP offers no delivery guarantees notifications are not redelivered when a message is rejected by the pipeline.
+
+Each notification becomes a message containing a JSON object, where the leading ` + "`sysUpTime.0`" + ` and ` + "`snmpTrapOID.0`" + ` variables are extracted into the fields ` + "`uptime`" + ` and ` + "`trap_oid`" + `:
+
+` + "```json" + `
+{
+  "version": "2c",
+  "community": "public",
+  "source": "10.0.0.1:50123",
+  "inform": false,
+  "uptime": 123456,
+  "trap_oid": "1.3.6.1.6.3.1.1.5.3",
+  "variables": [
+    { "oid": "1.3.6.1.2.1.2.2.1.1.2", "type": "integer", "value": 2 }
+  ]
+}
+` + "```" + `
+
+SNMPv3 notifications contain the fields ` + "`user`, `engine_id` and `context_name`" + ` instead of ` + "`community`" + `. Variables are formatted the same way as the [` + "`snmp`" + ` input](/docs/components/inputs/snmp).
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- snmp_source
+- snmp_version
+- snmp_trap_oid
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Link Down Alerts",
+				Summary: "This example receives notifications from agents using an SNMPv2c community or an SNMPv3 user, and only keeps `linkDown` traps.",
+				Config: `
+input:
+  snmp_trap:
+    address: 0.0.0.0:162
+    communities: [ "${SNMP_COMMUNITY}" ]
+    users:
+      - name: benthos
+        auth_protocol: SHA
+        auth_password: ${SNMP_AUTH_PASSWORD}
+        priv_protocol: AES
+        priv_password: ${SNMP_PRIV_PASSWORD}
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = if this.trap_oid != "1.3.6.1.6.3.1.1.5.3" { deleted() }
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "The address to listen on for notifications."),
+			docs.FieldCommon("communities", "A list of SNMPv2c communities to accept notifications from, if empty any community is accepted.").Array(),
+			docs.FieldCommon("users", "A list of SNMPv3 users to accept notifications from.").Array().WithChildren(snmpUserFieldSpecs()...),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SNMPTrapConfig contains configuration fields for the SNMPTrap input type.
+type SNMPTrapConfig struct {
+	Address     string           `json:"address" yaml:"address"`
+	Communities []string         `json:"communities" yaml:"communities"`
+	Users       []SNMPUserConfig `json:"users" yaml:"users"`
+}
+
+// NewSNMPTrapConfig creates a new SNMPTrapConfig with default values.
+func NewSNMPTrapConfig() SNMPTrapConfig {
+	return SNMPTrapConfig{
+		Address:     "0.0.0.0:162",
+		Communities: []string{},
+		Users:       []SNMPUserConfig{},
+	}
+}
+
+// NewSNMPTrap creates a new SNMPTrap input type.
+func NewSNMPTrap(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newSNMPTrapReader(conf.SNMPTrap, log)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeSNMPTrap, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+//------------------------------------------------------------------------------
+
+type snmpTrapReader struct {
+	conf     SNMPTrapConfig
+	log      log.Modular
+	receiver *snmp.TrapReceiver
+
+	mut   sync.Mutex
+	conn  net.PacketConn
+	msgs  chan types.Message
+	shutC chan struct{}
+}
+
+func newSNMPTrapReader(conf SNMPTrapConfig, log log.Modular) (*snmpTrapReader, error) {
+	users := make([]snmp.User, len(conf.Users))
+	for i, u := range conf.Users {
+		users[i] = u.user()
+	}
+	receiver, err := snmp.NewTrapReceiver(conf.Communities, users)
+	if err != nil {
+		return nil, err
+	}
+	return &snmpTrapReader{
+		conf:     conf,
+		log:      log,
+		receiver: receiver,
+		shutC:    make(chan struct{}),
+	}, nil
+}
+
+// ConnectWithContext binds the UDP listener.
+func (s *snmpTrapReader) ConnectWithContext(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	select {
+	case <-s.shutC:
+		return types.ErrTypeClosed
+	default:
+	}
+	if s.conn != nil {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", s.conf.Address)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.msgs = make(chan types.Message)
+	go s.loop(conn, s.msgs)
+
+	s.log.Infof("Receiving SNMP notifications at: %v\n", conn.LocalAddr())
+	return nil
+}
+
+func (s *snmpTrapReader) loop(conn net.PacketConn, msgs chan types.Message) {
+	defer close(msgs)
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.shutC:
+			default:
+				s.log.Errorf("Failed to read packet: %v\n", err)
+			}
+			return
+		}
+
+		trap, res, err := s.receiver.Decode(buf[:n])
+		if err != nil {
+			s.log.Debugf("Dropping packet from %v: %v\n", addr, err)
+			continue
+		}
+		if res != nil {
+			if _, err := conn.WriteTo(res, addr); err != nil {
+				s.log.Errorf("Failed to acknowledge inform request from %v: %v\n", addr, err)
+			}
+		}
+
+		b, err := json.Marshal(snmpTrapToJSON(trap, addr.String()))
+		if err != nil {
+			s.log.Errorf("Failed to marshal notification from %v: %v\n", addr, err)
+			continue
+		}
+		msg := message.New([][]byte{b})
+		meta := msg.Get(0).Metadata()
+		meta.Set("snmp_source", addr.String())
+		meta.Set("snmp_version", string(trap.Version))
+		meta.Set("snmp_trap_oid", trap.TrapOID)
+
+		select {
+		case msgs <- msg:
+		case <-s.shutC:
+			return
+		}
+	}
+}
+
+func snmpTrapToJSON(trap *snmp.Trap, source string) map[string]interface{} {
+	vars := make([]interface{}, len(trap.Variables))
+	for i, v := range trap.Variables {
+		vars[i] = snmpVariableToJSON(v)
+	}
+	obj := map[string]interface{}{
+		"version":   string(trap.Version),
+		"source":    source,
+		"inform":    trap.Inform,
+		"uptime":    trap.Uptime,
+		"trap_oid":  trap.TrapOID,
+		"variables": vars,
+	}
+	if trap.Version == snmp.Version3 {
+		obj["user"] = trap.User
+		obj["engine_id"] = trap.EngineID
+		obj["context_name"] = trap.ContextName
+	} else {
+		obj["community"] = trap.Community
+	}
+	return obj
+}
+
+// ReadWithContext returns the next notification received.
+func (s *snmpTrapReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	s.mut.Lock()
+	msgs := s.msgs
+	s.mut.Unlock()
+
+	if msgs == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case msg, open := <-msgs:
+		if !open {
+			s.mut.Lock()
+			if s.msgs == msgs {
+				s.conn, s.msgs = nil, nil
+			}
+			s.mut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+		return msg, func(context.Context, types.Response) error {
+			return nil
+		}, nil
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (s *snmpTrapReader) CloseAsync() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	select {
+	case <-s.shutC:
+	default:
+		close(s.shutC)
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (s *snmpTrapReader) WaitForClose(time.Duration) error {
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/unixsock"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -26,12 +27,15 @@ func init() {
 		constructor: fromSimpleConstructor(NewSocket),
 		Summary: `
 Connects to a tcp or unix socket and consumes a continuous stream of messages.`,
+		Description: `
+On Linux a unix address beginning with ` + "`@`" + ` refers to a socket within the abstract namespace, such as ` + "`@benthos`" + `.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("network", "A network type to assume (unix|tcp).").HasOptions(
 				"unix", "tcp",
 			),
-			docs.FieldCommon("address", "The address to connect to.", "/tmp/benthos.sock", "127.0.0.1:6000"),
+			docs.FieldCommon("address", "The address to connect to.", "/tmp/benthos.sock", "@benthos", "127.0.0.1:6000"),
 			codec.ReaderDocs.AtVersion("3.42.0"),
+			codec.MultilineDocs.AtVersion("3.47.0"),
 			docs.FieldDeprecated("delimiter"),
 			docs.FieldDeprecated("multipart"),
 			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
@@ -46,10 +50,11 @@ Connects to a tcp or unix socket and consumes a continuous stream of messages.`,
 
 // SocketConfig contains configuration values for the Socket input type.
 type SocketConfig struct {
-	Network   string `json:"network" yaml:"network"`
-	Address   string `json:"address" yaml:"address"`
-	Codec     string `json:"codec" yaml:"codec"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
+	Network   string                `json:"network" yaml:"network"`
+	Address   string                `json:"address" yaml:"address"`
+	Codec     string                `json:"codec" yaml:"codec"`
+	Multiline codec.MultilineConfig `json:"multiline" yaml:"multiline"`
+	MaxBuffer int                   `json:"max_buffer" yaml:"max_buffer"`
 	// TODO: V4 remove these fields.
 	Multipart bool   `json:"multipart" yaml:"multipart"`
 	Delim     string `json:"delimiter" yaml:"delimiter"`
@@ -61,6 +66,7 @@ func NewSocketConfig() SocketConfig {
 		Network:   "unix",
 		Address:   "/tmp/benthos.sock",
 		Codec:     "lines",
+		Multiline: codec.NewMultilineConfig(),
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
@@ -96,7 +102,11 @@ type socketClient struct {
 
 func newSocketClient(conf SocketConfig, logger log.Modular) (*socketClient, error) {
 	switch conf.Network {
-	case "tcp", "unix":
+	case "tcp":
+	case "unix":
+		if err := unixsock.CheckAddress(conf.Address); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("socket network '%v' is not supported by this input", conf.Network)
 	}
@@ -110,6 +120,7 @@ func newSocketClient(conf SocketConfig, logger log.Modular) (*socketClient, erro
 
 	codecConf := codec.NewReaderConfig()
 	codecConf.MaxScanTokenSize = conf.MaxBuffer
+	codecConf.Multiline = conf.Multiline
 	ctor, err := codec.GetReader(conf.Codec, codecConf)
 	if err != nil {
 		return nil, err
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/unixsock"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -24,13 +25,23 @@ func init() {
 		constructor: fromSimpleConstructor(NewSocketServer),
 		Summary:     `Creates a server that receives a stream of messages over a tcp, udp or unix socket.`,
 		Description: `
-The field ` + "`max_buffer`" + ` specifies the maximum amount of memory to allocate _per connection_ for buffering lines of data. If a line of data from a connection exceeds this value then the connection will be closed.`,
+The field ` + "`max_buffer`" + ` specifies the maximum amount of memory to allocate _per connection_ for buffering lines of data. If a line of data from a connection exceeds this value then the connection will be closed.
+
+### Unix Domain Sockets
+
+The ` + "`unix`" + ` network accepts stream connections and the ` + "`unixgram`" + ` network receives datagrams in the same way as ` + "`udp`" + `. When the socket file already exists but no process is listening on it then it is treated as stale and removed before binding. The socket file is removed again when the input shuts down.
+
+The permissions and ownership of the socket file can be set with the fields within ` + "`unix_socket`" + `, which makes it possible to restrict access to a sidecar process running as a specific user or group.
+
+On Linux an address beginning with ` + "`@`" + ` refers to a socket within the abstract namespace, such as ` + "`@benthos`" + `. Abstract sockets are not backed by a file and are removed automatically when closed, but are also not subject to file permissions.`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("network", "A network type to accept (unix|tcp|udp).").HasOptions(
-				"unix", "tcp", "udp",
+			docs.FieldCommon("network", "A network type to accept.").HasOptions(
+				"unix", "unixgram", "tcp", "udp",
 			),
-			docs.FieldCommon("address", "The address to listen from.", "/tmp/benthos.sock", "0.0.0.0:6000"),
+			docs.FieldCommon("address", "The address to listen from.", "/tmp/benthos.sock", "@benthos", "0.0.0.0:6000"),
+			unixsock.FieldSpec().AtVersion("3.47.0"),
 			codec.ReaderDocs.AtVersion("3.42.0"),
+			codec.MultilineDocs.AtVersion("3.47.0"),
 			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
 			docs.FieldDeprecated("multipart"),
 			docs.FieldDeprecated("delimiter"),
@@ -45,21 +56,25 @@ The field ` + "`max_buffer`" + ` specifies the maximum amount of memory to alloc
 
 // SocketServerConfig contains configuration for the SocketServer input type.
 type SocketServerConfig struct {
-	Network   string `json:"network" yaml:"network"`
-	Address   string `json:"address" yaml:"address"`
-	Codec     string `json:"codec" yaml:"codec"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
-	Multipart bool   `json:"multipart" yaml:"multipart"`
-	Delim     string `json:"delimiter" yaml:"delimiter"`
+	Network    string                `json:"network" yaml:"network"`
+	Address    string                `json:"address" yaml:"address"`
+	UnixSocket unixsock.Config       `json:"unix_socket" yaml:"unix_socket"`
+	Codec      string                `json:"codec" yaml:"codec"`
+	Multiline  codec.MultilineConfig `json:"multiline" yaml:"multiline"`
+	MaxBuffer  int                   `json:"max_buffer" yaml:"max_buffer"`
+	Multipart  bool                  `json:"multipart" yaml:"multipart"`
+	Delim      string                `json:"delimiter" yaml:"delimiter"`
 }
 
 // NewSocketServerConfig creates a new SocketServerConfig with default values.
 func NewSocketServerConfig() SocketServerConfig {
 	return SocketServerConfig{
-		Network:   "unix",
-		Address:   "/tmp/benthos.sock",
-		Codec:     "lines",
-		MaxBuffer: 1000000,
+		Network:    "unix",
+		Address:    "/tmp/benthos.sock",
+		UnixSocket: unixsock.NewConfig(),
+		Codec:      "lines",
+		Multiline:  codec.NewMultilineConfig(),
+		MaxBuffer:  1000000,
 
 		// TODO: V4 Remove these fields
 		Multipart: false,
@@ -115,14 +130,19 @@ func NewSocketServer(conf Config, mgr types.Manager, log log.Modular, stats metr
 
 	codecConf := codec.NewReaderConfig()
 	codecConf.MaxScanTokenSize = sconf.MaxBuffer
+	codecConf.Multiline = sconf.Multiline
 	ctor, err := codec.GetReader(sconf.Codec, codecConf)
 	if err != nil {
 		return nil, err
 	}
 
 	switch sconf.Network {
-	case "tcp", "unix":
+	case "tcp":
 		ln, err = net.Listen(sconf.Network, sconf.Address)
+	case "unix":
+		ln, err = unixsock.Listen(sconf.Network, sconf.Address, sconf.UnixSocket)
+	case "unixgram":
+		cn, err = unixsock.ListenPacket(sconf.Network, sconf.Address, sconf.UnixSocket)
 	case "udp":
 		cn, err = net.ListenPacket(sconf.Network, sconf.Address)
 	default:
@@ -328,6 +348,8 @@ func (t *SocketServer) udpLoop() {
 		// nolint:staticcheck, gocritic // Ignore SA2001 empty critical section, Ignore badLock
 		t.retriesMut.Unlock()
 
+		t.conn.Close()
+
 		close(t.transactions)
 		close(t.closedChan)
 	}()
@@ -346,7 +368,7 @@ func (t *SocketServer) udpLoop() {
 		t.conn.Close()
 	}()
 
-	t.log.Infof("Receiving udp socket messages from address: %v\n", t.conn.LocalAddr())
+	t.log.Infof("Receiving %v socket messages from address: %v\n", t.conf.Network, t.conn.LocalAddr())
 
 	for {
 		parts, ackFn, err := codec.Next(t.ctx)
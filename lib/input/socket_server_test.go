@@ -1264,3 +1264,53 @@ func TestTCPSocketServerMultipartShutdown(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestSocketUnixgramServerBasic(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_socket_test")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	conf := NewConfig()
+	conf.SocketServer.Network = "unixgram"
+	conf.SocketServer.Address = filepath.Join(tmpDir, "benthos.sock")
+	conf.SocketServer.UnixSocket.FileMode = "0600"
+
+	rdr, err := NewSocketServer(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	info, err := os.Stat(conf.SocketServer.Address)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	conn, err := net.Dial("unixgram", conf.SocketServer.Address)
+	require.NoError(t, err)
+
+	for _, p := range []string{"foo\n", "bar\n"} {
+		_, err = conn.Write([]byte(p))
+		require.NoError(t, err)
+	}
+	conn.Close()
+
+	for _, exp := range []string{"foo", "bar"} {
+		select {
+		case tran := <-rdr.TransactionChan():
+			assert.Equal(t, [][]byte{[]byte(exp)}, message.GetAllBytes(tran.Payload))
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	rdr.CloseAsync()
+	assert.NoError(t, rdr.WaitForClose(time.Second))
+
+	_, err = os.Stat(conf.SocketServer.Address)
+	assert.True(t, os.IsNotExist(err), err)
+}
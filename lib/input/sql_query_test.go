@@ -0,0 +1,255 @@
+package input
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeSQLDriver struct{}
+
+func (fakeSQLDriver) Open(name string) (driver.Conn, error) {
+	return fakeSQLConn{}, nil
+}
+
+type fakeSQLConn struct{}
+
+func (fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeSQLStmt{}, nil
+}
+
+func (fakeSQLConn) Close() error {
+	return nil
+}
+
+func (fakeSQLConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeSQLStmt struct{}
+
+func (fakeSQLStmt) Close() error {
+	return nil
+}
+
+func (fakeSQLStmt) NumInput() int {
+	return -1
+}
+
+func (fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+// fakeSQLQueryErrs is the number of subsequent queries that fail.
+var fakeSQLQueryErrs int32
+
+func (fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if atomic.AddInt32(&fakeSQLQueryErrs, -1) >= 0 {
+		return nil, errors.New("query failed")
+	}
+	var limit int64 = 3
+	if len(args) > 0 {
+		limit = args[0].(int64)
+	}
+	return &fakeSQLRows{limit: limit}, nil
+}
+
+type fakeSQLRows struct {
+	limit int64
+	index int64
+}
+
+func (r *fakeSQLRows) Columns() []string {
+	return []string{"id", "name"}
+}
+
+func (r *fakeSQLRows) Close() error {
+	return nil
+}
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if r.index >= r.limit {
+		return io.EOF
+	}
+	r.index++
+	dest[0] = r.index
+	dest[1] = []byte("foo")
+	return nil
+}
+
+func init() {
+	sql.Register("benthos_fake_sql", fakeSQLDriver{})
+}
+
+//------------------------------------------------------------------------------
+
+func TestSQLQueryOnce(t *testing.T) {
+	conf := NewSQLQueryConfig()
+	conf.Driver = "benthos_fake_sql"
+	conf.Query = "SELECT id, name FROM foo LIMIT ?;"
+	conf.ArgsMapping = `[ 2 ]`
+
+	r, err := newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrNotConnected, err)
+
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	for _, exp := range []string{`{"id":1,"name":"foo"}`, `{"id":2,"name":"foo"}`} {
+		msg, ackFn, err := r.ReadWithContext(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+		require.Equal(t, 1, msg.Len())
+		assert.Equal(t, exp, string(msg.Get(0).Get()))
+		assert.Equal(t, "1", msg.Get(0).Metadata().Get("sql_query_run"))
+		assert.NotEmpty(t, msg.Get(0).Metadata().Get("sql_query_started_at"))
+	}
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTimeout, err)
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTypeClosed, err)
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second))
+}
+
+func TestSQLQueryInterval(t *testing.T) {
+	conf := NewSQLQueryConfig()
+	conf.Driver = "benthos_fake_sql"
+	conf.Query = "SELECT id, name FROM foo;"
+	conf.Interval = "1ms"
+
+	r, err := newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	for _, run := range []string{"1", "2"} {
+		for _, row := range []string{"1", "2", "3"} {
+			msg, _, err := r.ReadWithContext(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, run, msg.Get(0).Metadata().Get("sql_query_run"))
+			assert.Equal(t, row, msg.Get(0).Metadata().Get("sql_query_row"))
+		}
+		_, _, err = r.ReadWithContext(ctx)
+		require.Equal(t, types.ErrTimeout, err)
+	}
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second))
+}
+
+func TestSQLQueryCloseDuringInterval(t *testing.T) {
+	conf := NewSQLQueryConfig()
+	conf.Driver = "benthos_fake_sql"
+	conf.Query = "SELECT id, name FROM foo;"
+	conf.Interval = "1h"
+
+	r, err := newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	for i := 0; i < 3; i++ {
+		_, _, err := r.ReadWithContext(ctx)
+		require.NoError(t, err)
+	}
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTimeout, err)
+
+	readErrChan := make(chan error)
+	go func() {
+		_, _, err := r.ReadWithContext(ctx)
+		readErrChan <- err
+	}()
+
+	// The read is blocked until the next run is due.
+	select {
+	case err := <-readErrChan:
+		t.Fatalf("unexpected read result: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second))
+	assert.Equal(t, types.ErrTypeClosed, <-readErrChan)
+}
+
+func TestSQLQueryOnceRetry(t *testing.T) {
+	conf := NewSQLQueryConfig()
+	conf.Driver = "benthos_fake_sql"
+	conf.Query = "SELECT id, name FROM foo LIMIT ?;"
+	conf.ArgsMapping = `[ 1 ]`
+
+	r, err := newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	require.NoError(t, r.ConnectWithContext(ctx))
+
+	atomic.StoreInt32(&fakeSQLQueryErrs, 2)
+	defer atomic.StoreInt32(&fakeSQLQueryErrs, 0)
+
+	for i := 0; i < 2; i++ {
+		_, _, err = r.ReadWithContext(ctx)
+		require.EqualError(t, err, "failed to run query: query failed")
+	}
+
+	msg, _, err := r.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"name":"foo"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, "1", msg.Get(0).Metadata().Get("sql_query_run"))
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTimeout, err)
+
+	_, _, err = r.ReadWithContext(ctx)
+	require.Equal(t, types.ErrTypeClosed, err)
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second))
+}
+
+func TestSQLQueryBadConfig(t *testing.T) {
+	conf := NewSQLQueryConfig()
+	_, err := newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a query must be specified")
+
+	conf.Query = "SELECT * FROM foo;"
+	conf.Interval = "nope"
+	_, err = newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Interval = ""
+	conf.ArgsMapping = "root = ["
+	_, err = newSQLQueryReader(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -23,14 +23,14 @@ func init() {
 	Constructors[TypeSTDIN] = TypeSpec{
 		constructor: fromSimpleConstructor(NewSTDIN),
 		Summary: `
-Consumes data piped to stdin as line delimited messages.`,
+Consumes data piped to stdin, dividing it into messages according to the specified codec.`,
 		Description: `
-If the multipart option is set to true then lines are interpretted as message
-parts, and an empty line indicates the end of the message.
+By default each line of data is consumed as a message. Any of the codecs listed below can be used in order to consume other forms of data, including compressed streams and archives, e.g. ` + "`gzip/tar`" + ` consumes each file of a gzip compressed tar archive piped to stdin as a message.
 
-If the delimiter field is left empty then line feed (\n) is used.`,
+Batches of messages can be framed with the ` + "`multipart`" + ` codec, where an empty message indicates the end of each batch. For example, the codec ` + "`lines/multipart`" + ` consumes each line as a message of a batch, and an empty line ends the batch. This matches the framing written by the ` + "[`stdout` output](/docs/components/outputs/stdout)" + ` with the ` + "`lines`" + ` codec, allowing batches to be piped between Benthos instances.`,
 		FieldSpecs: docs.FieldSpecs{
 			codec.ReaderDocs.AtVersion("3.42.0"),
+			codec.MultilineDocs.AtVersion("3.47.0"),
 			docs.FieldAdvanced("max_buffer", "The maximum message buffer size. Must exceed the largest message to be consumed."),
 			docs.FieldDeprecated("delimiter"),
 			docs.FieldDeprecated("multipart"),
@@ -45,16 +45,18 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 
 // STDINConfig contains config fields for the STDIN input type.
 type STDINConfig struct {
-	Codec     string `json:"codec" yaml:"codec"`
-	Multipart bool   `json:"multipart" yaml:"multipart"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
-	Delim     string `json:"delimiter" yaml:"delimiter"`
+	Codec     string                `json:"codec" yaml:"codec"`
+	Multiline codec.MultilineConfig `json:"multiline" yaml:"multiline"`
+	Multipart bool                  `json:"multipart" yaml:"multipart"`
+	MaxBuffer int                   `json:"max_buffer" yaml:"max_buffer"`
+	Delim     string                `json:"delimiter" yaml:"delimiter"`
 }
 
 // NewSTDINConfig creates a STDINConfig populated with default values.
 func NewSTDINConfig() STDINConfig {
 	return STDINConfig{
 		Codec:     "lines",
+		Multiline: codec.NewMultilineConfig(),
 		Multipart: false,
 		MaxBuffer: 1000000,
 		Delim:     "",
@@ -92,6 +94,7 @@ func newStdinConsumer(conf STDINConfig) (*stdinConsumer, error) {
 
 	codecConf := codec.NewReaderConfig()
 	codecConf.MaxScanTokenSize = conf.MaxBuffer
+	codecConf.Multiline = conf.Multiline
 	ctor, err := codec.GetReader(conf.Codec, codecConf)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,255 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	llog "log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-zeromq/zmq4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeZMQ4N] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			r, err := newZMQ4NReader(conf.ZMQ4N, log)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncReader(TypeZMQ4N, true, reader.NewAsyncPreserver(r), log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Summary: `
+Consumes messages from a ZeroMQ socket using a pure Go implementation of the protocol.`,
+		Description: `
+Unlike the ` + "[`zmq4` input](/docs/components/inputs/zmq4)" + ` this input does not depend on C bindings to libzmq, and is therefore included within all builds of Benthos, including statically compiled and cross compiled binaries.
+
+Messages consisting of multiple frames are consumed as a batch where each frame is a message of the batch.
+
+This input supports PULL and SUB sockets. Only the ` + "`tcp`" + `, ` + "`ipc`" + ` and ` + "`inproc`" + ` transports are supported, and socket options such as the high water mark of the ` + "`zmq4`" + ` input are not available.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"tcp://localhost:5555"}).Array(),
+			docs.FieldCommon("bind", "Whether to bind to the specified URLs or connect."),
+			docs.FieldCommon("socket_type", "The socket type to connect as.").HasOptions("PULL", "SUB"),
+			docs.FieldCommon("sub_filters", "A list of subscription topic filters to use when consuming from a SUB socket. Specifying a single sub_filter of `''` will subscribe to everything.").Array(),
+			docs.FieldAdvanced("dial_retry_delay", "The period of time to wait between failed attempts to connect to a URL."),
+		},
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ZMQ4NConfig contains configuration fields for the ZMQ4N input type.
+type ZMQ4NConfig struct {
+	URLs           []string `json:"urls" yaml:"urls"`
+	Bind           bool     `json:"bind" yaml:"bind"`
+	SocketType     string   `json:"socket_type" yaml:"socket_type"`
+	SubFilters     []string `json:"sub_filters" yaml:"sub_filters"`
+	DialRetryDelay string   `json:"dial_retry_delay" yaml:"dial_retry_delay"`
+}
+
+// NewZMQ4NConfig creates a new ZMQ4NConfig with default values.
+func NewZMQ4NConfig() ZMQ4NConfig {
+	return ZMQ4NConfig{
+		URLs:           []string{"tcp://localhost:5555"},
+		Bind:           false,
+		SocketType:     "PULL",
+		SubFilters:     []string{},
+		DialRetryDelay: "250ms",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type zmq4nReader struct {
+	urls       []string
+	conf       ZMQ4NConfig
+	retryDelay time.Duration
+	log        log.Modular
+
+	sockMut sync.Mutex
+	socket  zmq4.Socket
+	closeFn func()
+	msgChan chan zmq4.Msg
+	errChan chan error
+}
+
+func newZMQ4NReader(conf ZMQ4NConfig, log log.Modular) (*zmq4nReader, error) {
+	z := &zmq4nReader{
+		conf: conf,
+		log:  log,
+	}
+
+	for _, u := range conf.URLs {
+		for _, splitU := range strings.Split(u, ",") {
+			if len(splitU) > 0 {
+				z.urls = append(z.urls, splitU)
+			}
+		}
+	}
+	if len(z.urls) == 0 {
+		return nil, errors.New("at least one url must be specified")
+	}
+
+	if _, err := getZMQ4NSocketCtor(conf.SocketType); err != nil {
+		return nil, err
+	}
+
+	if conf.SocketType == "SUB" && len(conf.SubFilters) == 0 {
+		return nil, errors.New("must provide at least one sub filter when connecting with a SUB socket, in order to subscribe to all messages add an empty string")
+	}
+
+	var err error
+	if z.retryDelay, err = time.ParseDuration(conf.DialRetryDelay); err != nil {
+		return nil, fmt.Errorf("failed to parse dial retry delay: %v", err)
+	}
+	return z, nil
+}
+
+func getZMQ4NSocketCtor(t string) (func(context.Context, ...zmq4.Option) zmq4.Socket, error) {
+	switch t {
+	case "SUB":
+		return zmq4.NewSub, nil
+	case "PULL":
+		return zmq4.NewPull, nil
+	}
+	return nil, types.ErrInvalidZMQType
+}
+
+//------------------------------------------------------------------------------
+
+func (z *zmq4nReader) ConnectWithContext(ignored context.Context) (err error) {
+	z.sockMut.Lock()
+	defer z.sockMut.Unlock()
+
+	if z.socket != nil {
+		return nil
+	}
+
+	ctor, err := getZMQ4NSocketCtor(z.conf.SocketType)
+	if err != nil {
+		return err
+	}
+
+	sockCtx, closeFn := context.WithCancel(context.Background())
+	socket := ctor(
+		sockCtx,
+		zmq4.WithDialerRetry(z.retryDelay),
+		zmq4.WithLogger(llog.New(ioutil.Discard, "", llog.Flags())),
+	)
+	defer func() {
+		if err != nil {
+			closeFn()
+			socket.Close()
+		}
+	}()
+
+	for _, address := range z.urls {
+		if z.conf.Bind {
+			err = socket.Listen(address)
+		} else {
+			err = socket.Dial(address)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if z.conf.SocketType == "SUB" {
+		for _, filter := range z.conf.SubFilters {
+			if err = socket.SetOption(zmq4.OptionSubscribe, filter); err != nil {
+				return err
+			}
+		}
+	}
+
+	z.socket, z.closeFn = socket, closeFn
+	z.msgChan = make(chan zmq4.Msg)
+	z.errChan = make(chan error, 1)
+	go z.loop(sockCtx, socket, z.msgChan, z.errChan)
+
+	if z.conf.Bind {
+		z.log.Infof("Receiving ZMQ4N messages on bound URLs: %s\n", z.urls)
+	} else {
+		z.log.Infof("Receiving ZMQ4N messages on connected URLs: %s\n", z.urls)
+	}
+	return nil
+}
+
+// loop reads messages from the socket until it is closed or fails, allowing
+// reads to be abandoned when their context is cancelled.
+func (z *zmq4nReader) loop(ctx context.Context, socket zmq4.Socket, msgChan chan<- zmq4.Msg, errChan chan<- error) {
+	for {
+		msg, err := socket.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				errChan <- err
+			}
+			return
+		}
+		select {
+		case msgChan <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (z *zmq4nReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	z.sockMut.Lock()
+	socket, msgChan, errChan := z.socket, z.msgChan, z.errChan
+	z.sockMut.Unlock()
+
+	if socket == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case msg := <-msgChan:
+		return message.New(msg.Frames), func(context.Context, types.Response) error { return nil }, nil
+	case err := <-errChan:
+		z.log.Errorf("Failed to receive ZMQ4N message: %v\n", err)
+		z.sockMut.Lock()
+		if z.socket == socket {
+			z.closeSocket()
+		}
+		z.sockMut.Unlock()
+		return nil, nil, types.ErrNotConnected
+	case <-ctx.Done():
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+func (z *zmq4nReader) closeSocket() {
+	if z.socket != nil {
+		z.closeFn()
+		z.socket.Close()
+		z.socket = nil
+	}
+}
+
+func (z *zmq4nReader) CloseAsync() {
+	z.sockMut.Lock()
+	z.closeSocket()
+	z.sockMut.Unlock()
+}
+
+func (z *zmq4nReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package input
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-zeromq/zmq4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZMQ4NConfigErrors(t *testing.T) {
+	conf := NewZMQ4NConfig()
+	conf.SocketType = "PUSH"
+	_, err := newZMQ4NReader(conf, log.Noop())
+	assert.Equal(t, types.ErrInvalidZMQType, err)
+
+	conf = NewZMQ4NConfig()
+	conf.SocketType = "SUB"
+	_, err = newZMQ4NReader(conf, log.Noop())
+	assert.EqualError(t, err, "must provide at least one sub filter when connecting with a SUB socket, in order to subscribe to all messages add an empty string")
+
+	conf = NewZMQ4NConfig()
+	conf.URLs = []string{","}
+	_, err = newZMQ4NReader(conf, log.Noop())
+	assert.EqualError(t, err, "at least one url must be specified")
+}
+
+func TestZMQ4NPull(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	push := zmq4.NewPush(ctx)
+	defer push.Close()
+	require.NoError(t, push.Listen("inproc://benthos_zmq4n_pull_test"))
+
+	conf := NewZMQ4NConfig()
+	conf.URLs = []string{"inproc://benthos_zmq4n_pull_test"}
+
+	rdr, err := newZMQ4NReader(conf, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, rdr.ConnectWithContext(ctx))
+
+	go func() {
+		_ = push.Send(zmq4.NewMsgString("hello world"))
+		_ = push.Send(zmq4.NewMsgFromString([]string{"foo", "bar"}))
+	}()
+
+	msg, ackFn, err := rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hello world")}, message.GetAllBytes(msg))
+	require.NoError(t, ackFn(ctx, nil))
+
+	msg, _, err = rdr.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msg))
+
+	tCtx, tDone := context.WithTimeout(ctx, time.Millisecond*50)
+	_, _, err = rdr.ReadWithContext(tCtx)
+	tDone()
+	assert.Equal(t, types.ErrTimeout, err)
+
+	rdr.CloseAsync()
+	_, _, err = rdr.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrNotConnected, err)
+}
@@ -374,7 +374,15 @@ func closeWithContext(ctx context.Context, c types.Closable) error {
 
 // NewBuffer attempts to create a new buffer component from a config.
 func (t *Type) NewBuffer(conf buffer.Config) (buffer.Type, error) {
-	return t.bufferBundle.Init(conf, t)
+	mgr := t
+	// A configured label overrides any previously set component label.
+	if len(conf.Label) > 0 && t.component != conf.Label {
+		if err := docs.ValidateLabel(conf.Label); err != nil {
+			return nil, err
+		}
+		mgr = t.forComponent(conf.Label)
+	}
+	return t.bufferBundle.Init(conf, mgr)
 }
 
 //------------------------------------------------------------------------------
@@ -475,7 +483,11 @@ func (t *Type) NewInput(conf input.Config, hasBatchProc bool, pipelines ...types
 		}
 		mgr = t.forComponent(conf.Label)
 	}
-	return t.inputBundle.Init(hasBatchProc, conf, mgr, pipelines...)
+	in, err := t.inputBundle.Init(hasBatchProc, conf, mgr, pipelines...)
+	if err != nil || len(conf.Label) == 0 {
+		return in, err
+	}
+	return input.WithLabelSpans(conf.Label, in), nil
 }
 
 // StoreInput attempts to store a new input resource. If an existing resource
@@ -544,7 +556,14 @@ func (t *Type) NewProcessor(conf processor.Config) (types.Processor, error) {
 		}
 		mgr = t.forComponent(conf.Label)
 	}
-	return t.processorBundle.Init(conf, mgr)
+	proc, err := t.processorBundle.Init(conf, mgr)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Label) > 0 {
+		proc = processor.WithLabelSpans(conf.Label, proc)
+	}
+	return processor.WithErrorMetadata(mgr.component, proc), nil
 }
 
 // StoreProcessor attempts to store a new processor resource. If an existing
@@ -612,7 +631,11 @@ func (t *Type) NewOutput(conf output.Config, pipelines ...types.PipelineConstruc
 		}
 		mgr = t.forComponent(conf.Label)
 	}
-	return t.outputBundle.Init(conf, mgr, pipelines...)
+	out, err := t.outputBundle.Init(conf, mgr, pipelines...)
+	if err != nil || len(conf.Label) == 0 {
+		return out, err
+	}
+	return output.WithLabelSpans(conf.Label, out)
 }
 
 // StoreOutput attempts to store a new output resource. If an existing resource
@@ -3,6 +3,7 @@ package manager_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/cache"
@@ -10,11 +11,15 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/ratelimit"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -64,6 +69,116 @@ func TestManagerProcessorLabels(t *testing.T) {
 	}
 }
 
+func TestManagerLabelSpans(t *testing.T) {
+	tracer := mocktracer.New()
+	prevTracer := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() {
+		opentracing.SetGlobalTracer(prevTracer)
+	})
+
+	mgr, err := manager.New(manager.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = "root = content().uppercase()"
+	procConf.Label = "foo"
+
+	proc, err := mgr.NewProcessor(procConf)
+	require.NoError(t, err)
+
+	outConf := output.NewConfig()
+	outConf.Type = output.TypeDrop
+	outConf.Label = "bar"
+
+	out, err := mgr.NewOutput(outConf)
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, out.Consume(tChan))
+
+	msg := message.New([][]byte{[]byte("hello world")})
+	tracing.InitSpans("root", msg)
+	rootSpan := tracing.GetSpan(msg.Get(0)).(*mocktracer.MockSpan)
+
+	msgs, res := proc.ProcessMessage(msg)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, rootSpan, tracing.GetSpan(msgs[0].Get(0)))
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msgs[0], resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		require.NoError(t, res.Error())
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	out.CloseAsync()
+	require.NoError(t, out.WaitForClose(time.Second*5))
+
+	spanParents := map[string]int{}
+	for _, s := range tracer.FinishedSpans() {
+		spanParents[s.OperationName] = s.ParentID
+	}
+	assert.Equal(t, rootSpan.SpanContext.SpanID, spanParents["foo"])
+	assert.Equal(t, rootSpan.SpanContext.SpanID, spanParents["bar"])
+}
+
+func TestManagerProcessorErrorMetadata(t *testing.T) {
+	mgr, err := manager.New(manager.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := processor.NewConfig()
+	conf.Type = processor.TypeBloblang
+	conf.Bloblang = "root = this.foo.uppercase()"
+	conf.Label = "foo"
+
+	innerConf := processor.NewConfig()
+	innerConf.Type = processor.TypeBloblang
+	innerConf.Bloblang = "root = this.bar.uppercase()"
+	innerConf.Label = "bar"
+
+	outerConf := processor.NewConfig()
+	outerConf.Type = processor.TypeForEach
+	outerConf.ForEach = append(outerConf.ForEach, innerConf)
+
+	for _, test := range []struct {
+		conf   processor.Config
+		source string
+	}{
+		{conf: conf, source: "foo"},
+		{conf: outerConf, source: "bar"},
+	} {
+		p, err := mgr.NewProcessor(test.conf)
+		require.NoError(t, err)
+
+		msgs, res := p.ProcessMessage(message.New([][]byte{
+			[]byte(`{"foo":"a","bar":"b"}`),
+			[]byte(`not json`),
+		}))
+		require.Nil(t, res)
+		require.Len(t, msgs, 1)
+
+		assert.False(t, processor.HasFailed(msgs[0].Get(0)))
+
+		failed := msgs[0].Get(1).Metadata()
+		assert.NotEmpty(t, failed.Get(types.FailFlagKey))
+		assert.Equal(t, test.source, failed.Get(types.FailSourceKey))
+		assert.Equal(t, "1", failed.Get(types.FailAttemptsKey))
+		assert.Equal(t, processor.PayloadHash([]byte(`not json`)), failed.Get(types.FailPayloadHashKey))
+
+		processor.ClearFail(msgs[0].Get(1))
+		assert.Empty(t, failed.Get(types.FailSourceKey))
+	}
+}
+
 func TestManagerCache(t *testing.T) {
 	testLog := log.Noop()
 
@@ -47,6 +47,12 @@ func (m *lockedMessage) DeepCopy() types.Message {
 	return msg
 }
 
+// LockedBatch returns the batch that this message was locked from and the index
+// of the message part within it.
+func (m *lockedMessage) LockedBatch() (types.Message, int) {
+	return m.m, m.part
+}
+
 func (m *lockedMessage) Get(index int) types.Part {
 	if index != 0 && index != -1 {
 		return NewPart(nil)
@@ -87,6 +87,7 @@ const (
 // types.
 type Config struct {
 	Type          string           `json:"type" yaml:"type"`
+	Mapping       string           `json:"mapping" yaml:"mapping"`
 	AWSCloudWatch CloudWatchConfig `json:"aws_cloudwatch" yaml:"aws_cloudwatch"`
 	Blacklist     BlacklistConfig  `json:"blacklist" yaml:"blacklist"`
 	CloudWatch    CloudWatchConfig `json:"cloudwatch" yaml:"cloudwatch"`
@@ -104,6 +105,7 @@ type Config struct {
 func NewConfig() Config {
 	return Config{
 		Type:          "http_server",
+		Mapping:       "",
 		AWSCloudWatch: NewCloudWatchConfig(),
 		Blacklist:     NewBlacklistConfig(),
 		CloudWatch:    NewCloudWatchConfig(),
@@ -254,10 +256,27 @@ func Descriptions() string {
 
 // New creates a metric output type based on a configuration.
 func New(conf Config, opts ...func(Type)) (Type, error) {
-	if c, ok := Constructors[conf.Type]; ok {
+	c, ok := Constructors[conf.Type]
+	if !ok {
+		return nil, ErrInvalidMetricOutputType
+	}
+	if conf.Mapping == "" {
 		return c.constructor(conf, opts...)
 	}
-	return nil, ErrInvalidMetricOutputType
+
+	child, err := c.constructor(conf, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m, err := WithMapping(conf.Mapping, child, log.Noop())
+	if err != nil {
+		child.Close()
+		return nil, fmt.Errorf("failed to parse mapping: %w", err)
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
 }
 
 //------------------------------------------------------------------------------
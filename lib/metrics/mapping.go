@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// mappedWrapper wraps a metrics type with a Bloblang mapping that is executed
+// against the path and labels of each metric before it reaches the child,
+// allowing metrics to be renamed, dropped, relabelled and aggregated.
+type mappedWrapper struct {
+	mapping *pathMapping
+	child   Type
+	log     log.Modular
+
+	// The label names of each path registered with the child, mapping results
+	// with different label names are rejected as they would conflict.
+	labelsMut sync.Mutex
+	labels    map[string]string
+}
+
+// WithMapping wraps a metrics type with a Bloblang mapping that is executed
+// against the path of each metric as it is registered, and against the label
+// values of each labelled metric as they are set.
+func WithMapping(mapping string, child Type, logger log.Modular) (Type, error) {
+	m, err := newPathMapping(mapping, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &mappedWrapper{
+		mapping: m,
+		child:   child,
+		log:     logger,
+		labels:  map[string]string{},
+	}, nil
+}
+
+// Unwrap to the underlying metrics type.
+func (m *mappedWrapper) Unwrap() Type {
+	return unwrapMetric(m.child)
+}
+
+// mapPath executes the mapping and returns the resulting path and labels, the
+// path is empty when the metric is dropped.
+func (m *mappedWrapper) mapPath(kind, path string, names, values []string) (string, []string, []string) {
+	outPath, outNames, outValues := m.mapping.mapPathAndLabels(path, names, values, true)
+	if outPath == "" {
+		return "", nil, nil
+	}
+
+	key := kind + ":" + outPath
+	joined := strings.Join(outNames, ",")
+
+	m.labelsMut.Lock()
+	defer m.labelsMut.Unlock()
+	if existing, exists := m.labels[key]; exists && existing != joined {
+		m.log.Errorf("Metrics path '%v' mapped to '%v' with labels [%v], which conflict with the labels [%v] of a previously registered %v, the metric will be dropped.\n", path, outPath, joined, existing, kind)
+		return "", nil, nil
+	}
+	m.labels[key] = joined
+	return outPath, outNames, outValues
+}
+
+// labelCache caches the stats of a labelled metric by their label values so
+// that the mapping is only executed once per unique combination.
+type labelCache struct {
+	mut   sync.RWMutex
+	stats map[string]interface{}
+}
+
+func (c *labelCache) get(values []string, fn func() interface{}) interface{} {
+	key := strings.Join(values, "\x00")
+
+	c.mut.RLock()
+	stat, exists := c.stats[key]
+	c.mut.RUnlock()
+	if exists {
+		return stat
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if stat, exists = c.stats[key]; !exists {
+		if c.stats == nil {
+			c.stats = map[string]interface{}{}
+		}
+		stat = fn()
+		c.stats[key] = stat
+	}
+	return stat
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mappedWrapper) getCounter(path string, names, values []string) StatCounter {
+	path, names, values = m.mapPath("counter", path, names, values)
+	if path == "" {
+		return DudStat{}
+	}
+	if len(names) > 0 {
+		return m.child.GetCounterVec(path, names).With(values...)
+	}
+	return m.child.GetCounter(path)
+}
+
+// GetCounter returns an editable counter stat for a given path.
+func (m *mappedWrapper) GetCounter(path string) StatCounter {
+	return m.getCounter(path, nil, nil)
+}
+
+// GetCounterVec returns an editable counter stat for a given path with labels.
+func (m *mappedWrapper) GetCounterVec(path string, n []string) StatCounterVec {
+	cache := &labelCache{}
+	return fakeCounterVec(func(values []string) StatCounter {
+		return cache.get(values, func() interface{} {
+			return m.getCounter(path, n, values)
+		}).(StatCounter)
+	})
+}
+
+func (m *mappedWrapper) getTimer(path string, names, values []string) StatTimer {
+	path, names, values = m.mapPath("timer", path, names, values)
+	if path == "" {
+		return DudStat{}
+	}
+	if len(names) > 0 {
+		return m.child.GetTimerVec(path, names).With(values...)
+	}
+	return m.child.GetTimer(path)
+}
+
+// GetTimer returns an editable timer stat for a given path.
+func (m *mappedWrapper) GetTimer(path string) StatTimer {
+	return m.getTimer(path, nil, nil)
+}
+
+// GetTimerVec returns an editable timer stat for a given path with labels.
+func (m *mappedWrapper) GetTimerVec(path string, n []string) StatTimerVec {
+	cache := &labelCache{}
+	return fakeTimerVec(func(values []string) StatTimer {
+		return cache.get(values, func() interface{} {
+			return m.getTimer(path, n, values)
+		}).(StatTimer)
+	})
+}
+
+func (m *mappedWrapper) getGauge(path string, names, values []string) StatGauge {
+	path, names, values = m.mapPath("gauge", path, names, values)
+	if path == "" {
+		return DudStat{}
+	}
+	if len(names) > 0 {
+		return m.child.GetGaugeVec(path, names).With(values...)
+	}
+	return m.child.GetGauge(path)
+}
+
+// GetGauge returns an editable gauge stat for a given path.
+func (m *mappedWrapper) GetGauge(path string) StatGauge {
+	return m.getGauge(path, nil, nil)
+}
+
+// GetGaugeVec returns an editable gauge stat for a given path with labels.
+func (m *mappedWrapper) GetGaugeVec(path string, n []string) StatGaugeVec {
+	cache := &labelCache{}
+	return fakeGaugeVec(func(values []string) StatGauge {
+		return cache.get(values, func() interface{} {
+			return m.getGauge(path, n, values)
+		}).(StatGauge)
+	})
+}
+
+// SetLogger sets the logger of the mapping and the child.
+func (m *mappedWrapper) SetLogger(log log.Modular) {
+	m.log = log
+	m.mapping.logger = log
+	m.child.SetLogger(log)
+}
+
+// Close stops the child from aggregating metrics.
+func (m *mappedWrapper) Close() error {
+	return m.child.Close()
+}
+
+// HandlerFunc returns an http.HandlerFunc for accessing metrics of the child
+// when it supports them.
+func (m *mappedWrapper) HandlerFunc() http.HandlerFunc {
+	if wHandlerFunc, ok := m.child.(WithHandlerFunc); ok {
+		return wHandlerFunc.HandlerFunc()
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(501)
+		w.Write([]byte("The child of this mapping does not support HTTP metrics."))
+	}
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingRenameAndDrop(t *testing.T) {
+	local := NewLocal()
+	m, err := WithMapping(`root = if this.has_suffix(".dropped") { deleted() } else { this.replace("input", "source") }`, local, log.Noop())
+	require.NoError(t, err)
+
+	m.GetCounter("input.received").Incr(2)
+	m.GetCounter("input.dropped").Incr(5)
+	m.GetTimer("input.latency").Timing(10)
+	m.GetGauge("output.connections").Set(3)
+
+	assert.Equal(t, map[string]int64{
+		"source.received":    2,
+		"output.connections": 3,
+	}, local.GetCounters())
+	assert.Equal(t, map[string]int64{
+		"source.latency": 10,
+	}, local.GetTimings())
+}
+
+func TestMappingLabels(t *testing.T) {
+	local := NewLocal()
+	m, err := WithMapping(`
+meta topic = deleted()
+meta source = meta("label").or("unknown")
+meta label = deleted()
+`, local, log.Noop())
+	require.NoError(t, err)
+
+	m.GetCounterVec("input.received", []string{"label", "topic"}).With("foo", "bar").Incr(1)
+	m.GetCounter("output.sent").Incr(2)
+
+	counters := local.GetCountersWithLabels()
+	require.Len(t, counters, 2)
+
+	received := counters["input.received"]
+	assert.True(t, received.HasLabelWithValue("source", "foo"))
+	assert.False(t, received.HasLabelWithValue("topic", "bar"))
+	assert.False(t, received.HasLabelWithValue("label", "foo"))
+
+	sent := counters["output.sent"]
+	assert.True(t, sent.HasLabelWithValue("source", "unknown"))
+	assert.Equal(t, int64(2), *sent.Value)
+}
+
+func TestMappingAggregate(t *testing.T) {
+	local := NewLocal()
+	m, err := WithMapping(`root = this.re_replace("^(input|output)\\.[a-z]+\\.", "${1}.")
+meta = deleted()`, local, log.Noop())
+	require.NoError(t, err)
+
+	vec := m.GetCounterVec("input.foo.received", []string{"topic"})
+	vec.With("a").Incr(1)
+	vec.With("b").Incr(2)
+	m.GetCounter("input.bar.received").Incr(3)
+	m.GetCounter("output.baz.sent").Incr(4)
+
+	assert.Equal(t, map[string]int64{
+		"input.received": 6,
+		"output.sent":    4,
+	}, local.GetCounters())
+}
+
+func TestMappingConflictingLabels(t *testing.T) {
+	local := NewLocal()
+	m, err := WithMapping(`root = "foo"`, local, log.Noop())
+	require.NoError(t, err)
+
+	m.GetCounterVec("bar", []string{"a"}).With("x").Incr(1)
+
+	// Mapping to a name that already exists with different labels would
+	// conflict within most targets, and so the metric is dropped.
+	m.GetCounterVec("baz", []string{"b"}).With("y").Incr(2)
+	m.GetCounter("buz").Incr(3)
+
+	assert.Equal(t, map[string]int64{"foo": 1}, local.GetCounters())
+}
+
+func TestMappingConstructor(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNone
+	conf.Mapping = `root = this.uppercase()`
+
+	m, err := New(conf)
+	require.NoError(t, err)
+	_, isMapped := m.(*mappedWrapper)
+	assert.True(t, isMapped)
+	assert.IsType(t, DudType{}, unwrapMetric(m))
+
+	conf.Mapping = `root = this.`
+	_, err = New(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse mapping")
+}
@@ -71,12 +71,27 @@ func (m *pathMapping) mapPathWithTags(path string) (outPath string, labelNames,
 }
 
 func (m *pathMapping) mapPath(path string, allowLabels bool) (outPath string, labelNames, labelValues []string) {
+	return m.mapPathAndLabels(path, nil, nil, allowLabels)
+}
+
+// mapPathAndLabels executes the mapping with the path as the input document
+// and any existing labels as metadata, the labels of the result are sorted by
+// name.
+func (m *pathMapping) mapPathAndLabels(path string, inNames, inValues []string, allowLabels bool) (outPath string, labelNames, labelValues []string) {
 	if m == nil || m.m == nil {
-		return path, nil, nil
+		return path, inNames, inValues
 	}
 
 	var input interface{} = path
-	meta := metadata.New(nil)
+	inMeta := metadata.New(nil)
+	for i, k := range inNames {
+		if i < len(inValues) {
+			inMeta.Set(k, inValues[i])
+		}
+	}
+	msg := message.New(nil)
+	msg.Append(message.NewPart(nil).SetMetadata(inMeta))
+	meta := inMeta.Copy()
 	vars := map[string]interface{}{}
 
 	var v interface{} = query.Nothing(nil)
@@ -84,14 +99,14 @@ func (m *pathMapping) mapPath(path string, allowLabels bool) (outPath string, la
 	if err := m.m.ExecOnto(query.FunctionContext{
 		Maps:     map[string]query.Function{},
 		Vars:     vars,
-		MsgBatch: message.New(nil),
+		MsgBatch: msg,
 	}.WithValue(input), mapping.AssignmentContext{
 		Vars:  vars,
 		Meta:  meta,
 		Value: &v,
 	}); err != nil {
 		m.logger.Errorf("Failed to apply path mapping on '%v': %v\n", path, err)
-		return path, nil, nil
+		return path, inNames, inValues
 	}
 
 	meta.Iter(func(k, v string) error {
@@ -108,7 +123,7 @@ func (m *pathMapping) mapPath(path string, allowLabels bool) (outPath string, la
 		sort.Strings(labelNames)
 		for _, k := range labelNames {
 			v := meta.Get(k)
-			m.logger.Tracef("Metrics label '%v' created with static value '%v'.\n", k, v)
+			m.logger.Tracef("Metrics label '%v' created with value '%v'.\n", k, v)
 			labelValues = append(labelValues, v)
 		}
 	}
@@ -0,0 +1,222 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/arangodb"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeArangoDB] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			a, err := newArangoDBWriter(conf.ArangoDB, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeArangoDB, conf.ArangoDB.MaxInFlight, a, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.ArangoDB.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Writes messages as documents to [ArangoDB](https://www.arangodb.com/) collections.`,
+		Description: `
+Each message must be a JSON object, and when the interpolated field ` + "`key`" + ` is set its result is used as the ` + "`_key`" + ` of the document. The field ` + "`overwrite_mode`" + ` determines what happens when a document with the same key already exists: it is either replaced, updated by merging the new document into it, left unchanged, or the write fails with a conflict.
+
+The messages of a batch are written with a request for each collection that they target, and messages that are rejected individually, for example due to a unique constraint, are retried without the rest of the batch.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Users By Region",
+				Summary: "In this example user profiles are written to a collection for each region, updating the existing document of a user when one exists.",
+				Config: `
+output:
+  arangodb:
+    url: http://localhost:8529
+    database: accounts
+    username: root
+    password: ${ARANGO_PASSWORD}
+    collection: users_${! json("region") }
+    key: ${! json("user_id") }
+    overwrite_mode: update
+    batching:
+      count: 500
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: arangodb.FieldSpecs().Add(
+			docs.FieldCommon("collection", "The collection to write documents to.").IsInterpolated(),
+			docs.FieldCommon("key", "An optional key of each document, which overrides any `_key` field of the message.", `${! json("id") }`).IsInterpolated(),
+			docs.FieldCommon("overwrite_mode", "What to do when a document with the same key already exists.").HasAnnotatedOptions(
+				"replace", "Replace the existing document.",
+				"update", "Merge the new document into the existing document.",
+				"ignore", "Leave the existing document unchanged.",
+				"conflict", "Fail the write of the message.",
+			),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ArangoDBConfig contains configuration fields for the ArangoDB output.
+type ArangoDBConfig struct {
+	arangodb.Config `json:",inline" yaml:",inline"`
+	Collection      string             `json:"collection" yaml:"collection"`
+	Key             string             `json:"key" yaml:"key"`
+	OverwriteMode   string             `json:"overwrite_mode" yaml:"overwrite_mode"`
+	MaxInFlight     int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewArangoDBConfig returns a ArangoDBConfig with default values.
+func NewArangoDBConfig() ArangoDBConfig {
+	return ArangoDBConfig{
+		Config:        arangodb.NewConfig(),
+		Collection:    "",
+		Key:           "",
+		OverwriteMode: "replace",
+		MaxInFlight:   1,
+		Batching:      batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type arangoDBWriter struct {
+	log  log.Modular
+	conf ArangoDBConfig
+
+	collection *field.Expression
+	key        *field.Expression
+	client     *arangodb.Client
+}
+
+func newArangoDBWriter(conf ArangoDBConfig, log log.Modular) (*arangoDBWriter, error) {
+	if conf.Collection == "" {
+		return nil, errors.New("a collection must be specified")
+	}
+	switch conf.OverwriteMode {
+	case "replace", "update", "ignore", "conflict":
+	default:
+		return nil, fmt.Errorf("overwrite_mode not recognised: %v", conf.OverwriteMode)
+	}
+
+	a := &arangoDBWriter{
+		log:  log,
+		conf: conf,
+	}
+
+	var err error
+	if a.client, err = arangodb.NewClient(conf.Config); err != nil {
+		return nil, err
+	}
+	if a.collection, err = bloblang.NewField(conf.Collection); err != nil {
+		return nil, fmt.Errorf("failed to parse collection expression: %v", err)
+	}
+	if conf.Key != "" {
+		if a.key, err = bloblang.NewField(conf.Key); err != nil {
+			return nil, fmt.Errorf("failed to parse key expression: %v", err)
+		}
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext checks that the database is reachable.
+func (a *arangoDBWriter) ConnectWithContext(ctx context.Context) error {
+	if err := a.client.Ping(ctx); err != nil {
+		return err
+	}
+	a.log.Infof("Writing documents to ArangoDB database: %v\n", a.conf.Database)
+	return nil
+}
+
+// WriteWithContext attempts to write the messages of a batch to ArangoDB.
+func (a *arangoDBWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	errs := make([]error, msg.Len())
+
+	var collections []string
+	docs := map[string][]json.RawMessage{}
+	indexes := map[string][]int{}
+
+	msg.Iter(func(i int, p types.Part) error {
+		collection := a.collection.String(i, msg)
+		if collection == "" {
+			errs[i] = errors.New("collection is empty")
+			return nil
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal(p.Get(), &doc); err != nil || doc == nil {
+			errs[i] = fmt.Errorf("message is not a JSON object: %v", err)
+			return nil
+		}
+		var raw json.RawMessage = p.Get()
+		if a.key != nil {
+			doc["_key"] = a.key.String(i, msg)
+			var err error
+			if raw, err = json.Marshal(doc); err != nil {
+				errs[i] = err
+				return nil
+			}
+		}
+
+		if _, exists := docs[collection]; !exists {
+			collections = append(collections, collection)
+		}
+		docs[collection] = append(docs[collection], raw)
+		indexes[collection] = append(indexes[collection], i)
+		return nil
+	})
+
+	for _, collection := range collections {
+		results, err := a.client.InsertDocuments(ctx, collection, a.conf.OverwriteMode, docs[collection])
+		for j, i := range indexes[collection] {
+			if err != nil {
+				errs[i] = err
+			} else if results[j].Err != nil {
+				errs[i] = results[j].Err
+			}
+		}
+	}
+
+	return writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
+		return errs[i]
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *arangoDBWriter) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (a *arangoDBWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArangoDBWriter(t *testing.T) {
+	var reqMut sync.Mutex
+	requests := map[string][]map[string]interface{}{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_db/accounts/_api/database/current" {
+			w.Write([]byte(`{"result":{"name":"accounts"}}`))
+			return
+		}
+		assert.Equal(t, "update", r.URL.Query().Get("overwriteMode"))
+
+		var docs []map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&docs))
+		reqMut.Lock()
+		requests[r.URL.Path] = docs
+		reqMut.Unlock()
+
+		results := make([]map[string]interface{}, len(docs))
+		for i, d := range docs {
+			if d["_key"] == "dupe" {
+				results[i] = map[string]interface{}{"error": true, "errorNum": 1210, "errorMessage": "unique constraint violated"}
+			} else {
+				results[i] = map[string]interface{}{"_key": d["_key"]}
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer ts.Close()
+
+	conf := NewArangoDBConfig()
+	conf.URL = ts.URL
+	conf.Database = "accounts"
+	conf.Collection = `users_${! json("region") }`
+	conf.Key = `${! json("user_id") }`
+	conf.OverwriteMode = "update"
+
+	w, err := newArangoDBWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, w.ConnectWithContext(ctx))
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"region":"eu","user_id":"a","name":"foo"}`),
+		[]byte(`{"region":"us","user_id":"b","name":"bar"}`),
+		[]byte(`{"region":"eu","user_id":"dupe"}`),
+		[]byte(`not json`),
+		[]byte(`{"region":"eu","user_id":"c"}`),
+	}))
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+
+	failed := map[int]string{}
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	require.Len(t, failed, 2)
+	assert.Equal(t, "unique constraint violated (1210)", failed[2])
+	assert.Contains(t, failed[3], "message is not a JSON object")
+
+	assert.Equal(t, map[string][]map[string]interface{}{
+		"/_db/accounts/_api/document/users_eu": {
+			{"_key": "a", "region": "eu", "user_id": "a", "name": "foo"},
+			{"_key": "dupe", "region": "eu", "user_id": "dupe"},
+			{"_key": "c", "region": "eu", "user_id": "c"},
+		},
+		"/_db/accounts/_api/document/users_us": {
+			{"_key": "b", "region": "us", "user_id": "b", "name": "bar"},
+		},
+	}, requests)
+}
+
+func TestArangoDBWriterBadConfig(t *testing.T) {
+	conf := NewArangoDBConfig()
+	conf.URL = "http://localhost:8529"
+	_, err := newArangoDBWriter(conf, log.Noop())
+	require.EqualError(t, err, "a collection must be specified")
+
+	conf.Collection = "foo"
+	conf.OverwriteMode = "nope"
+	_, err = newArangoDBWriter(conf, log.Noop())
+	require.EqualError(t, err, "overwrite_mode not recognised: nope")
+}
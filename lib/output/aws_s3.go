@@ -90,6 +90,26 @@ output:
       processors:
         - archive:
             format: json_array
+` + "```" + `
+
+### Multipart Uploads
+
+By default each message is uploaded as an object in full, and therefore large objects must be held in memory as a single message. When ` + "`multipart.enabled`" + ` is true messages are instead written into objects with [multipart uploads](https://docs.aws.amazon.com/AmazonS3/latest/userguide/mpuoverview.html), where messages that share a path are appended to the same object using ` + "`multipart.codec`" + `, and the object is uploaded in parts of ` + "`multipart.part_size`" + ` bytes as messages are written to it. At most ` + "`multipart.concurrency`" + ` parts are buffered and uploaded in parallel for an object, which therefore bounds the memory used regardless of the size of the object.
+
+An object is completed when a message with a different path is written, or when the output is closed, and so the path would typically include a time based interpolation such as ` + "`${! timestamp(\"2006-01-02T15\") }`" + `. The content type, tags and other fields of an object are interpolated from the first message written to it. Objects can be made of at most 10000 parts, and so the maximum size of an object is 10000 times the part size.
+
+Messages are acknowledged once they are written into a part rather than once their object is completed, and so messages can be lost if an upload fails to complete or Benthos is stopped abruptly. The ` + "`timeout`" + ` field does not apply to multipart uploads.
+
+` + "```yaml" + `
+output:
+  aws_s3:
+    bucket: TODO
+    path: 'events/${! timestamp("2006-01-02T15") }.jsonl'
+    multipart:
+      enabled: true
+      codec: lines
+      part_size: 67108864
+      concurrency: 4
 ` + "```" + ``,
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
@@ -117,6 +137,7 @@ output:
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on an upload before abandoning it and reattempting."),
+			s3MultipartFieldSpec(),
 			batch.FieldSpec(),
 		}.Merge(session.FieldSpecs()),
 		Categories: []Category{
@@ -232,6 +253,7 @@ output:
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on an upload before abandoning it and reattempting."),
+			s3MultipartFieldSpec(),
 			batch.FieldSpec(),
 		}.Merge(session.FieldSpecs()),
 		Categories: []Category{
@@ -243,6 +265,19 @@ output:
 
 //------------------------------------------------------------------------------
 
+func s3MultipartFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"multipart", "Write messages that share a path into a single object with a streaming multipart upload, rather than uploading each message as an object in full.",
+	).WithChildren(
+		docs.FieldAdvanced("enabled", "Whether to use multipart uploads."),
+		docs.FieldAdvanced("codec", "The way in which the bytes of messages are written into an object. It's possible to write lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter.", "lines", "append", "delim:\t"),
+		docs.FieldAdvanced("part_size", "The size in bytes of each part of an upload, which must be at least 5MiB."),
+		docs.FieldAdvanced("concurrency", "The maximum number of parts of an object to buffer and upload in parallel."),
+	).AtVersion("3.47.0")
+}
+
+//------------------------------------------------------------------------------
+
 // NewAWSS3 creates a new AmazonS3 output type.
 func NewAWSS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	return newAmazonS3(TypeAWSS3, conf.AWSS3, mgr, log, stats)
@@ -33,6 +33,11 @@ In order to set the ` + "`queue_name`" + ` you can use function interpolations d
 				"ttl", "The TTL of each individual message as a duration string. Defaults to 0, meaning no retention period is set",
 				"60s", "5m", "36h",
 			).IsInterpolated(),
+			docs.FieldAdvanced(
+				"visibility_timeout", "An optional duration string that delays the visibility of each message after it is enqueued, defaults to zero meaning messages are visible immediately.",
+				"10s", "1m",
+			).IsInterpolated().AtVersion("3.47.0"),
+			docs.FieldAdvanced("base64_encode", "Whether message payloads should be base64 encoded before being enqueued, which is expected by some consumers such as Azure Functions.").AtVersion("3.47.0"),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").AtVersion("3.45.0"),
 			batch.FieldSpec(),
 		},
@@ -155,6 +155,7 @@ const (
 	TypeAMQP               = "amqp"
 	TypeAMQP09             = "amqp_0_9"
 	TypeAMQP1              = "amqp_1"
+	TypeArangoDB           = "arangodb"
 	TypeAWSDynamoDB        = "aws_dynamodb"
 	TypeAWSKinesis         = "aws_kinesis"
 	TypeAWSKinesisFirehose = "aws_kinesis_firehose"
@@ -168,14 +169,19 @@ const (
 	TypeBroker             = "broker"
 	TypeCache              = "cache"
 	TypeCassandra          = "cassandra"
+	TypeCouchbase          = "couchbase"
+	TypeDeltaLake          = "delta_lake"
 	TypeDrop               = "drop"
 	TypeDropOn             = "drop_on"
 	TypeDropOnError        = "drop_on_error"
 	TypeDynamic            = "dynamic"
 	TypeDynamoDB           = "dynamodb"
 	TypeElasticsearch      = "elasticsearch"
+	TypeFaultInjection     = "fault_injection"
 	TypeFile               = "file"
 	TypeFiles              = "files"
+	TypeFIX                = "fix"
+	TypeGCPBigQuery        = "gcp_bigquery"
 	TypeGCPCloudStorage    = "gcp_cloud_storage"
 	TypeGCPPubSub          = "gcp_pubsub"
 	TypeHDFS               = "hdfs"
@@ -185,6 +191,8 @@ const (
 	TypeKafka              = "kafka"
 	TypeKinesis            = "kinesis"
 	TypeKinesisFirehose    = "kinesis_firehose"
+	TypeKubernetesApply    = "kubernetes_apply"
+	TypeMirror             = "mirror"
 	TypeMongoDB            = "mongodb"
 	TypeMQTT               = "mqtt"
 	TypeNanomsg            = "nanomsg"
@@ -192,7 +200,14 @@ const (
 	TypeNATSJetStream      = "nats_jetstream"
 	TypeNATSStream         = "nats_stream"
 	TypeNSQ                = "nsq"
+	TypeObjectArchive      = "object_archive"
+	TypeOrdered            = "ordered"
+	TypePGVector           = "pgvector"
+	TypePinecone           = "pinecone"
 	TypePulsar             = "pulsar"
+	TypeQdrant             = "qdrant"
+	TypeQuota              = "quota"
+	TypeRecord             = "record"
 	TypeRedisHash          = "redis_hash"
 	TypeRedisList          = "redis_list"
 	TypeRedisPubSub        = "redis_pubsub"
@@ -202,6 +217,7 @@ const (
 	TypeRetry              = "retry"
 	TypeS3                 = "s3"
 	TypeSFTP               = "sftp"
+	TypeSnowflakePut       = "snowflake_put"
 	TypeSNS                = "sns"
 	TypeSQL                = "sql"
 	TypeSQS                = "sqs"
@@ -214,8 +230,11 @@ const (
 	TypeTry                = "try"
 	TypeUDP                = "udp"
 	TypeSocket             = "socket"
+	TypeWebhook            = "webhook"
 	TypeWebsocket          = "websocket"
+	TypeWebsocketServer    = "websocket_server"
 	TypeZMQ4               = "zmq4"
+	TypeZMQ4N              = "zmq4n"
 )
 
 //------------------------------------------------------------------------------
@@ -227,6 +246,7 @@ type Config struct {
 	AMQP               writer.AMQPConfig              `json:"amqp" yaml:"amqp"`
 	AMQP09             writer.AMQPConfig              `json:"amqp_0_9" yaml:"amqp_0_9"`
 	AMQP1              writer.AMQP1Config             `json:"amqp_1" yaml:"amqp_1"`
+	ArangoDB           ArangoDBConfig                 `json:"arangodb" yaml:"arangodb"`
 	AWSDynamoDB        writer.DynamoDBConfig          `json:"aws_dynamodb" yaml:"aws_dynamodb"`
 	AWSKinesis         writer.KinesisConfig           `json:"aws_kinesis" yaml:"aws_kinesis"`
 	AWSKinesisFirehose writer.KinesisFirehoseConfig   `json:"aws_kinesis_firehose" yaml:"aws_kinesis_firehose"`
@@ -240,14 +260,19 @@ type Config struct {
 	Broker             BrokerConfig                   `json:"broker" yaml:"broker"`
 	Cache              writer.CacheConfig             `json:"cache" yaml:"cache"`
 	Cassandra          CassandraConfig                `json:"cassandra" yaml:"cassandra"`
+	Couchbase          CouchbaseConfig                `json:"couchbase" yaml:"couchbase"`
+	DeltaLake          DeltaLakeConfig                `json:"delta_lake" yaml:"delta_lake"`
 	Drop               writer.DropConfig              `json:"drop" yaml:"drop"`
 	DropOn             DropOnConfig                   `json:"drop_on" yaml:"drop_on"`
 	DropOnError        DropOnErrorConfig              `json:"drop_on_error" yaml:"drop_on_error"`
 	Dynamic            DynamicConfig                  `json:"dynamic" yaml:"dynamic"`
 	DynamoDB           writer.DynamoDBConfig          `json:"dynamodb" yaml:"dynamodb"`
 	Elasticsearch      writer.ElasticsearchConfig     `json:"elasticsearch" yaml:"elasticsearch"`
+	FaultInjection     FaultInjectionConfig           `json:"fault_injection" yaml:"fault_injection"`
 	File               FileConfig                     `json:"file" yaml:"file"`
 	Files              writer.FilesConfig             `json:"files" yaml:"files"`
+	FIX                FIXConfig                      `json:"fix" yaml:"fix"`
+	GCPBigQuery        GCPBigQueryConfig              `json:"gcp_bigquery" yaml:"gcp_bigquery"`
 	GCPCloudStorage    GCPCloudStorageConfig          `json:"gcp_cloud_storage" yaml:"gcp_cloud_storage"`
 	GCPPubSub          writer.GCPPubSubConfig         `json:"gcp_pubsub" yaml:"gcp_pubsub"`
 	HDFS               writer.HDFSConfig              `json:"hdfs" yaml:"hdfs"`
@@ -257,6 +282,8 @@ type Config struct {
 	Kafka              writer.KafkaConfig             `json:"kafka" yaml:"kafka"`
 	Kinesis            writer.KinesisConfig           `json:"kinesis" yaml:"kinesis"`
 	KinesisFirehose    writer.KinesisFirehoseConfig   `json:"kinesis_firehose" yaml:"kinesis_firehose"`
+	KubernetesApply    KubernetesApplyConfig          `json:"kubernetes_apply" yaml:"kubernetes_apply"`
+	Mirror             MirrorConfig                   `json:"mirror" yaml:"mirror"`
 	MongoDB            MongoDBConfig                  `json:"mongodb" yaml:"mongodb"`
 	MQTT               writer.MQTTConfig              `json:"mqtt" yaml:"mqtt"`
 	Nanomsg            writer.NanomsgConfig           `json:"nanomsg" yaml:"nanomsg"`
@@ -264,8 +291,15 @@ type Config struct {
 	NATSJetStream      NATSJetStreamConfig            `json:"nats_jetstream" yaml:"nats_jetstream"`
 	NATSStream         writer.NATSStreamConfig        `json:"nats_stream" yaml:"nats_stream"`
 	NSQ                writer.NSQConfig               `json:"nsq" yaml:"nsq"`
+	ObjectArchive      ObjectArchiveConfig            `json:"object_archive" yaml:"object_archive"`
+	Ordered            OrderedConfig                  `json:"ordered" yaml:"ordered"`
+	PGVector           PGVectorConfig                 `json:"pgvector" yaml:"pgvector"`
+	Pinecone           PineconeConfig                 `json:"pinecone" yaml:"pinecone"`
 	Plugin             interface{}                    `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 	Pulsar             PulsarConfig                   `json:"pulsar" yaml:"pulsar"`
+	Qdrant             QdrantConfig                   `json:"qdrant" yaml:"qdrant"`
+	Quota              QuotaConfig                    `json:"quota" yaml:"quota"`
+	Record             RecordConfig                   `json:"record" yaml:"record"`
 	RedisHash          writer.RedisHashConfig         `json:"redis_hash" yaml:"redis_hash"`
 	RedisList          writer.RedisListConfig         `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub        writer.RedisPubSubConfig       `json:"redis_pubsub" yaml:"redis_pubsub"`
@@ -275,6 +309,7 @@ type Config struct {
 	Retry              RetryConfig                    `json:"retry" yaml:"retry"`
 	S3                 writer.AmazonS3Config          `json:"s3" yaml:"s3"`
 	SFTP               SFTPConfig                     `json:"sftp" yaml:"sftp"`
+	SnowflakePut       SnowflakePutConfig             `json:"snowflake_put" yaml:"snowflake_put"`
 	SNS                writer.SNSConfig               `json:"sns" yaml:"sns"`
 	SQL                SQLConfig                      `json:"sql" yaml:"sql"`
 	SQS                writer.AmazonSQSConfig         `json:"sqs" yaml:"sqs"`
@@ -287,8 +322,11 @@ type Config struct {
 	Try                TryConfig                      `json:"try" yaml:"try"`
 	UDP                writer.UDPConfig               `json:"udp" yaml:"udp"`
 	Socket             writer.SocketConfig            `json:"socket" yaml:"socket"`
+	Webhook            WebhookConfig                  `json:"webhook" yaml:"webhook"`
 	Websocket          writer.WebsocketConfig         `json:"websocket" yaml:"websocket"`
+	WebsocketServer    WebsocketServerConfig          `json:"websocket_server" yaml:"websocket_server"`
 	ZMQ4               *writer.ZMQ4Config             `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
+	ZMQ4N              ZMQ4NConfig                    `json:"zmq4n" yaml:"zmq4n"`
 	Processors         []processor.Config             `json:"processors" yaml:"processors"`
 }
 
@@ -300,6 +338,7 @@ func NewConfig() Config {
 		AMQP:               writer.NewAMQPConfig(),
 		AMQP09:             writer.NewAMQPConfig(),
 		AMQP1:              writer.NewAMQP1Config(),
+		ArangoDB:           NewArangoDBConfig(),
 		AWSDynamoDB:        writer.NewDynamoDBConfig(),
 		AWSKinesis:         writer.NewKinesisConfig(),
 		AWSKinesisFirehose: writer.NewKinesisFirehoseConfig(),
@@ -313,14 +352,19 @@ func NewConfig() Config {
 		Broker:             NewBrokerConfig(),
 		Cache:              writer.NewCacheConfig(),
 		Cassandra:          NewCassandraConfig(),
+		Couchbase:          NewCouchbaseConfig(),
+		DeltaLake:          NewDeltaLakeConfig(),
 		Drop:               writer.NewDropConfig(),
 		DropOn:             NewDropOnConfig(),
 		DropOnError:        NewDropOnErrorConfig(),
 		Dynamic:            NewDynamicConfig(),
 		DynamoDB:           writer.NewDynamoDBConfig(),
 		Elasticsearch:      writer.NewElasticsearchConfig(),
+		FaultInjection:     NewFaultInjectionConfig(),
 		File:               NewFileConfig(),
 		Files:              writer.NewFilesConfig(),
+		FIX:                NewFIXConfig(),
+		GCPBigQuery:        NewGCPBigQueryConfig(),
 		GCPCloudStorage:    NewGCPCloudStorageConfig(),
 		GCPPubSub:          writer.NewGCPPubSubConfig(),
 		HDFS:               writer.NewHDFSConfig(),
@@ -330,6 +374,8 @@ func NewConfig() Config {
 		Kafka:              writer.NewKafkaConfig(),
 		Kinesis:            writer.NewKinesisConfig(),
 		KinesisFirehose:    writer.NewKinesisFirehoseConfig(),
+		KubernetesApply:    NewKubernetesApplyConfig(),
+		Mirror:             NewMirrorConfig(),
 		MQTT:               writer.NewMQTTConfig(),
 		MongoDB:            NewMongoDBConfig(),
 		Nanomsg:            writer.NewNanomsgConfig(),
@@ -337,8 +383,15 @@ func NewConfig() Config {
 		NATSJetStream:      NewNATSJetStreamConfig(),
 		NATSStream:         writer.NewNATSStreamConfig(),
 		NSQ:                writer.NewNSQConfig(),
+		ObjectArchive:      NewObjectArchiveConfig(),
+		Ordered:            NewOrderedConfig(),
+		PGVector:           NewPGVectorConfig(),
+		Pinecone:           NewPineconeConfig(),
 		Plugin:             nil,
 		Pulsar:             NewPulsarConfig(),
+		Qdrant:             NewQdrantConfig(),
+		Quota:              NewQuotaConfig(),
+		Record:             NewRecordConfig(),
 		RedisHash:          writer.NewRedisHashConfig(),
 		RedisList:          writer.NewRedisListConfig(),
 		RedisPubSub:        writer.NewRedisPubSubConfig(),
@@ -348,6 +401,7 @@ func NewConfig() Config {
 		Retry:              NewRetryConfig(),
 		S3:                 writer.NewAmazonS3Config(),
 		SFTP:               NewSFTPConfig(),
+		SnowflakePut:       NewSnowflakePutConfig(),
 		SNS:                writer.NewSNSConfig(),
 		SQL:                NewSQLConfig(),
 		SQS:                writer.NewAmazonSQSConfig(),
@@ -360,8 +414,11 @@ func NewConfig() Config {
 		Try:                NewTryConfig(),
 		UDP:                writer.NewUDPConfig(),
 		Socket:             writer.NewSocketConfig(),
+		Webhook:            NewWebhookConfig(),
 		Websocket:          writer.NewWebsocketConfig(),
+		WebsocketServer:    NewWebsocketServerConfig(),
 		ZMQ4:               writer.NewZMQ4Config(),
+		ZMQ4N:              NewZMQ4NConfig(),
 		Processors:         []processor.Config{},
 	}
 }
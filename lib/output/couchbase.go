@@ -0,0 +1,209 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/couchbase"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCouchbase] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			c, err := newCouchbaseWriter(conf.Couchbase, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeCouchbase, conf.Couchbase.MaxInFlight, c, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.Couchbase.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Writes messages as documents to a [Couchbase](https://www.couchbase.com/) bucket.`,
+		Description: `
+The document of each message is identified by the interpolated field ` + "`id`" + ` and written with the result of the interpolated field ` + "`content`" + `, which is the entire contents of the message by default. An ` + "`insert`" + ` fails when the document already exists, a ` + "`replace`" + ` fails when it does not, and a ` + "`remove`" + ` deletes the document rather than writing it.
+
+Operations are routed directly to the node that hosts each document, and the messages of a batch are written individually such that only the messages that fail are retried.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Caching Orders",
+				Summary: "In this example orders are written to a bucket keyed by their ID, expiring after a week.",
+				Config: `
+output:
+  couchbase:
+    url: couchbase://localhost
+    bucket: orders
+    username: benthos
+    password: ${CB_PASSWORD}
+    operation: upsert
+    id: order::${! json("order_id") }
+    expiry: 168h
+    max_in_flight: 64
+`,
+			},
+		},
+		FieldSpecs: couchbase.FieldSpecs().Add(
+			docs.FieldCommon("operation", "The operation to perform for each message.").HasOptions("upsert", "insert", "replace", "remove"),
+			docs.FieldCommon("id", "The ID of the document of each message.", `${! json("id") }`, `${! meta("kafka_key") }`).IsInterpolated(),
+			docs.FieldAdvanced("content", "The document to write for each message.").IsInterpolated(),
+			docs.FieldAdvanced("expiry", "An optional period after which written documents expire.", "1h", "168h"),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CouchbaseConfig contains configuration fields for the Couchbase output.
+type CouchbaseConfig struct {
+	couchbase.Config `json:",inline" yaml:",inline"`
+	Operation        string             `json:"operation" yaml:"operation"`
+	ID               string             `json:"id" yaml:"id"`
+	Content          string             `json:"content" yaml:"content"`
+	Expiry           string             `json:"expiry" yaml:"expiry"`
+	MaxInFlight      int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching         batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewCouchbaseConfig returns a CouchbaseConfig with default values.
+func NewCouchbaseConfig() CouchbaseConfig {
+	return CouchbaseConfig{
+		Config:      couchbase.NewConfig(),
+		Operation:   "upsert",
+		ID:          "",
+		Content:     "${! content() }",
+		Expiry:      "",
+		MaxInFlight: 1,
+		Batching:    batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// couchbaseClient is the subset of the Couchbase client used by the output.
+type couchbaseClient interface {
+	Connect(ctx context.Context) error
+	Upsert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error)
+	Insert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error)
+	Replace(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error)
+	Remove(ctx context.Context, key string) (uint64, error)
+	Close() error
+}
+
+type couchbaseWriter struct {
+	log  log.Modular
+	conf CouchbaseConfig
+
+	id      *field.Expression
+	content *field.Expression
+	expiry  time.Duration
+	client  couchbaseClient
+}
+
+func newCouchbaseWriter(conf CouchbaseConfig, log log.Modular) (*couchbaseWriter, error) {
+	client, err := couchbase.NewClient(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newCouchbaseWriterFromClient(conf, client, log)
+}
+
+func newCouchbaseWriterFromClient(conf CouchbaseConfig, client couchbaseClient, log log.Modular) (*couchbaseWriter, error) {
+	c := &couchbaseWriter{
+		log:    log,
+		conf:   conf,
+		client: client,
+	}
+	switch conf.Operation {
+	case "upsert", "insert", "replace", "remove":
+	default:
+		return nil, fmt.Errorf("operation not recognised: %v", conf.Operation)
+	}
+
+	var err error
+	if conf.ID == "" {
+		return nil, errors.New("an id must be specified")
+	}
+	if c.id, err = bloblang.NewField(conf.ID); err != nil {
+		return nil, fmt.Errorf("failed to parse id expression: %v", err)
+	}
+	if c.content, err = bloblang.NewField(conf.Content); err != nil {
+		return nil, fmt.Errorf("failed to parse content expression: %v", err)
+	}
+	if conf.Expiry != "" {
+		if c.expiry, err = time.ParseDuration(conf.Expiry); err != nil {
+			return nil, fmt.Errorf("failed to parse expiry string: %v", err)
+		}
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext fetches the configuration of the bucket.
+func (c *couchbaseWriter) ConnectWithContext(ctx context.Context) error {
+	if err := c.client.Connect(ctx); err != nil {
+		return err
+	}
+	c.log.Infof("Writing documents to Couchbase bucket: %v\n", c.conf.Bucket)
+	return nil
+}
+
+// WriteWithContext attempts to write the messages of a batch to Couchbase.
+func (c *couchbaseWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+		id := c.id.String(i, msg)
+		if id == "" {
+			return errors.New("id is empty")
+		}
+
+		var err error
+		switch c.conf.Operation {
+		case "upsert":
+			_, err = c.client.Upsert(ctx, id, c.content.Bytes(i, msg), c.expiry)
+		case "insert":
+			_, err = c.client.Insert(ctx, id, c.content.Bytes(i, msg), c.expiry)
+		case "replace":
+			_, err = c.client.Replace(ctx, id, c.content.Bytes(i, msg), c.expiry)
+		case "remove":
+			_, err = c.client.Remove(ctx, id)
+		}
+		if err != nil {
+			c.log.Debugf("Failed to %v document '%v': %v\n", c.conf.Operation, id, err)
+		}
+		return err
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (c *couchbaseWriter) CloseAsync() {
+	c.client.Close()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (c *couchbaseWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,117 @@
+package output
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/couchbase"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCouchbaseClient struct {
+	docs   map[string][]byte
+	expiry time.Duration
+}
+
+func (m *mockCouchbaseClient) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockCouchbaseClient) Upsert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	m.docs[key] = value
+	m.expiry = expiry
+	return 1, nil
+}
+
+func (m *mockCouchbaseClient) Insert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	if _, exists := m.docs[key]; exists {
+		return 0, couchbase.ErrKeyExists
+	}
+	return m.Upsert(ctx, key, value, expiry)
+}
+
+func (m *mockCouchbaseClient) Replace(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	if _, exists := m.docs[key]; !exists {
+		return 0, couchbase.ErrKeyNotFound
+	}
+	return m.Upsert(ctx, key, value, expiry)
+}
+
+func (m *mockCouchbaseClient) Remove(ctx context.Context, key string) (uint64, error) {
+	if _, exists := m.docs[key]; !exists {
+		return 0, couchbase.ErrKeyNotFound
+	}
+	delete(m.docs, key)
+	return 1, nil
+}
+
+func (m *mockCouchbaseClient) Close() error {
+	return nil
+}
+
+func TestCouchbaseWriter(t *testing.T) {
+	client := &mockCouchbaseClient{docs: map[string][]byte{}}
+
+	conf := NewCouchbaseConfig()
+	conf.Operation = "insert"
+	conf.ID = `${! json("id") }`
+	conf.Expiry = "1h"
+	w, err := newCouchbaseWriterFromClient(conf, client, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, w.ConnectWithContext(ctx))
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"id":"foo","n":1}`),
+		[]byte(`{"id":"bar","n":2}`),
+	})))
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte(`{"id":"foo","n":1}`),
+		"bar": []byte(`{"id":"bar","n":2}`),
+	}, client.docs)
+	assert.Equal(t, time.Hour, client.expiry)
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"id":"baz","n":3}`),
+		[]byte(`{"id":"foo","n":4}`),
+		[]byte(`{"id":"","n":5}`),
+	}))
+	require.Error(t, err)
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok, err)
+
+	failed := map[int]string{}
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	assert.Equal(t, map[int]string{
+		1: couchbase.ErrKeyExists.Error(),
+		2: "id is empty",
+	}, failed)
+
+	conf.Operation = "remove"
+	w, err = newCouchbaseWriterFromClient(conf, client, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{[]byte(`{"id":"foo"}`)})))
+	assert.NotContains(t, client.docs, "foo")
+}
+
+func TestCouchbaseWriterBadConfig(t *testing.T) {
+	conf := NewCouchbaseConfig()
+	_, err := newCouchbaseWriterFromClient(conf, &mockCouchbaseClient{}, log.Noop())
+	require.EqualError(t, err, "an id must be specified")
+
+	conf.ID = "foo"
+	conf.Operation = "get"
+	_, err = newCouchbaseWriterFromClient(conf, &mockCouchbaseClient{}, log.Noop())
+	require.EqualError(t, err, "operation not recognised: get")
+}
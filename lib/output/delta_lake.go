@@ -0,0 +1,879 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gofrs/uuid"
+	"github.com/xitongsys/parquet-go/parquet"
+	pqwriter "github.com/xitongsys/parquet-go/writer"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDeltaLake] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			d, err := newDeltaLakeWriter(conf.DeltaLake, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeDeltaLake, 1, d, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.DeltaLake.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryLocal,
+		},
+		Summary: `
+Writes batches of messages as Parquet data files to a [Delta Lake](https://delta.io/) table.`,
+		Description: `
+Each message must be a JSON object, where each top level field is a column of the table. The messages of a batch are written to one Parquet file for each partition of the table that they belong to, and the files are then committed to the table as a single transaction in its log. Messages are acknowledged once the transaction is committed, and therefore the size of files written is determined by the [batching policy](/docs/configuration/batching) of the output.
+
+If the table does not yet exist it is created with the columns of the first batch and the partition columns listed in ` + "`partition_by`" + `. The partitioning of an existing table cannot be changed, and the field ` + "`partition_by`" + ` must either be empty or match it.
+
+### Schema Evolution
+
+When ` + "`schema_evolution`" + ` is enabled fields of a batch that are not yet columns of the table are added to its schema as part of the same transaction as the data files, with a type inferred from their values: strings are added as ` + "`string`" + `, whole numbers as ` + "`long`" + `, other numbers as ` + "`double`" + ` and booleans as ` + "`boolean`" + `. Objects and arrays are added as ` + "`string`" + ` columns containing their JSON serialisation. Columns are never removed or changed, and values are converted to the type of their existing column where possible, otherwise the batch is rejected.
+
+When ` + "`schema_evolution`" + ` is disabled a batch containing a field that is not a column of the table is rejected.
+
+### Concurrent Writers
+
+Transactions are committed to the table log with optimistic concurrency, and therefore multiple writers (including other Benthos instances) may append to the same table at the same time. In order for this to be safe the underlying filesystem must provide atomic hard links, which rules out most object stores when mounted as a filesystem.
+
+Reading checkpoints of the table log is not supported, and therefore tables where log entries have been removed after a checkpoint cannot be written to.
+
+### Apache Iceberg
+
+Only Delta Lake tables are supported, writing to [Apache Iceberg](https://iceberg.apache.org/) tables via a REST or Glue catalog is not supported by this output.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Partitioned Events",
+				Summary: "In this example events are written to a table partitioned by the day that they occurred, with a new transaction committed at most every minute.",
+				Config: `
+pipeline:
+  processors:
+    - bloblang: |
+        root = this
+        root.day = this.timestamp.format_timestamp("2006-01-02")
+
+output:
+  delta_lake:
+    path: /data/tables/events
+    partition_by: [ day ]
+    batching:
+      count: 10000
+      period: 1m
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The path of the directory of the table, which is created if it does not exist.", "/data/tables/events"),
+			docs.FieldCommon("partition_by", "A list of columns to partition a new table by.", []string{"day"}).Array(),
+			docs.FieldCommon("schema_evolution", "Whether fields that are not yet columns of the table are added to its schema."),
+			docs.FieldAdvanced("compression", "The compression codec of the Parquet data files.").HasOptions("uncompressed", "snappy", "gzip", "zstd"),
+			batch.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DeltaLakeConfig contains configuration fields for the Delta Lake output.
+type DeltaLakeConfig struct {
+	Path            string             `json:"path" yaml:"path"`
+	PartitionBy     []string           `json:"partition_by" yaml:"partition_by"`
+	SchemaEvolution bool               `json:"schema_evolution" yaml:"schema_evolution"`
+	Compression     string             `json:"compression" yaml:"compression"`
+	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewDeltaLakeConfig returns a DeltaLakeConfig with default values.
+func NewDeltaLakeConfig() DeltaLakeConfig {
+	return DeltaLakeConfig{
+		Path:            "",
+		PartitionBy:     []string{},
+		SchemaEvolution: true,
+		Compression:     "snappy",
+		Batching:        batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	deltaLogDir             = "_delta_log"
+	deltaCommitAttempts     = 10
+	deltaNullPartitionValue = "__HIVE_DEFAULT_PARTITION__"
+)
+
+var errDeltaCommitConflict = errors.New("a transaction with the same version has already been committed")
+
+// deltaField is a column of the schema of a Delta Lake table.
+type deltaField struct {
+	Name     string                 `json:"name"`
+	Type     interface{}            `json:"type"`
+	Nullable bool                   `json:"nullable"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func (f deltaField) typeStr() string {
+	s, _ := f.Type.(string)
+	return s
+}
+
+type deltaSchema struct {
+	Type   string       `json:"type"`
+	Fields []deltaField `json:"fields"`
+}
+
+// deltaTableState is the current state of a table replayed from its log.
+type deltaTableState struct {
+	// The version of the latest commit, or -1 if the table does not exist.
+	version          int64
+	metaData         map[string]interface{}
+	fields           []deltaField
+	partitionColumns []string
+}
+
+func findDeltaField(fields []deltaField, name string) (deltaField, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return deltaField{}, false
+}
+
+// deltaDataFile is a Parquet file written to a table and yet to be committed.
+type deltaDataFile struct {
+	path            string
+	partitionValues map[string]interface{}
+	size            int64
+	records         int
+}
+
+type deltaLakeWriter struct {
+	conf DeltaLakeConfig
+	log  log.Modular
+
+	compression parquet.CompressionCodec
+	fileSuffix  string
+
+	mCommitted metrics.StatCounter
+	mConflicts metrics.StatCounter
+
+	mut   sync.Mutex
+	state *deltaTableState
+}
+
+func newDeltaLakeWriter(conf DeltaLakeConfig, log log.Modular, stats metrics.Type) (*deltaLakeWriter, error) {
+	if conf.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	d := &deltaLakeWriter{
+		conf:       conf,
+		log:        log,
+		mCommitted: stats.GetCounter("commits.committed"),
+		mConflicts: stats.GetCounter("commits.conflicts"),
+	}
+	switch conf.Compression {
+	case "uncompressed":
+		d.compression, d.fileSuffix = parquet.CompressionCodec_UNCOMPRESSED, ".parquet"
+	case "snappy":
+		d.compression, d.fileSuffix = parquet.CompressionCodec_SNAPPY, ".snappy.parquet"
+	case "gzip":
+		d.compression, d.fileSuffix = parquet.CompressionCodec_GZIP, ".gz.parquet"
+	case "zstd":
+		d.compression, d.fileSuffix = parquet.CompressionCodec_ZSTD, ".zstd.parquet"
+	default:
+		return nil, fmt.Errorf("compression '%v' not recognised", conf.Compression)
+	}
+	for _, c := range conf.PartitionBy {
+		if err := checkDeltaColumnName(c); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func checkDeltaColumnName(name string) error {
+	if name == "" || strings.ContainsAny(name, ",=") {
+		return fmt.Errorf("column name '%v' is not supported", name)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext creates the directory of the table if it does not exist
+// and reads the current state of the table from its log.
+func (d *deltaLakeWriter) ConnectWithContext(ctx context.Context) error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.state != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(d.conf.Path, deltaLogDir), 0o755); err != nil {
+		return err
+	}
+	state, err := d.readState()
+	if err != nil {
+		return err
+	}
+	d.state = state
+	d.log.Infof("Writing to Delta Lake table at %v from version %v\n", d.conf.Path, state.version)
+	return nil
+}
+
+// readState replays the log of the table in order to find its latest version
+// and schema.
+func (d *deltaLakeWriter) readState() (*deltaTableState, error) {
+	logDir := filepath.Join(d.conf.Path, deltaLogDir)
+	entries, err := ioutil.ReadDir(logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || len(name) != 25 || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if v, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	state := &deltaTableState{version: -1}
+	if len(versions) == 0 {
+		state.partitionColumns = d.conf.PartitionBy
+		return state, nil
+	}
+	state.version = versions[0]
+
+	var protocol map[string]interface{}
+	for _, v := range versions {
+		if state.metaData != nil && protocol != nil {
+			break
+		}
+		actions, err := readDeltaCommit(filepath.Join(logDir, fmt.Sprintf("%020d.json", v)))
+		if err != nil {
+			return nil, err
+		}
+		for i := len(actions) - 1; i >= 0; i-- {
+			if m, ok := actions[i]["metaData"].(map[string]interface{}); ok && state.metaData == nil {
+				state.metaData = m
+			}
+			if p, ok := actions[i]["protocol"].(map[string]interface{}); ok && protocol == nil {
+				protocol = p
+			}
+		}
+	}
+	if state.metaData == nil || protocol == nil {
+		return nil, fmt.Errorf("the metadata of table version %v could not be found in its log, reading checkpoints is not supported", state.version)
+	}
+	if v, _ := protocol["minWriterVersion"].(float64); v > 2 {
+		return nil, fmt.Errorf("the table requires writer version %v, which is not supported", v)
+	}
+
+	var schema deltaSchema
+	schemaStr, _ := state.metaData["schemaString"].(string)
+	if err := json.Unmarshal([]byte(schemaStr), &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse table schema: %w", err)
+	}
+	state.fields = schema.Fields
+	if cols, ok := state.metaData["partitionColumns"].([]interface{}); ok {
+		for _, c := range cols {
+			state.partitionColumns = append(state.partitionColumns, fmt.Sprintf("%v", c))
+		}
+	}
+
+	if len(d.conf.PartitionBy) > 0 && strings.Join(d.conf.PartitionBy, ",") != strings.Join(state.partitionColumns, ",") {
+		return nil, fmt.Errorf("partition_by %v does not match the partition columns %v of the table", d.conf.PartitionBy, state.partitionColumns)
+	}
+	return state, nil
+}
+
+func readDeltaCommit(path string) ([]map[string]interface{}, error) {
+	commitBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var actions []map[string]interface{}
+	for _, line := range bytes.Split(commitBytes, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var action map[string]interface{}
+		if err := json.Unmarshal(line, &action); err != nil {
+			return nil, fmt.Errorf("failed to parse commit %v: %w", filepath.Base(path), err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+//------------------------------------------------------------------------------
+
+// inferDeltaType returns the column type of a field added to a table for a
+// JSON value, or an empty string for null values.
+func inferDeltaType(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "long"
+		}
+		return "double"
+	case bool:
+		return "boolean"
+	}
+	return "string"
+}
+
+// mergeDeltaFields returns the columns of a table after adding the fields of a
+// batch of rows that are not yet columns.
+func (d *deltaLakeWriter) mergeDeltaFields(state *deltaTableState, rows []map[string]interface{}) ([]deltaField, error) {
+	fields := append([]deltaField{}, state.fields...)
+	added := map[string]int{}
+
+	addField := func(name, typeStr string) error {
+		if _, exists := findDeltaField(state.fields, name); exists {
+			return nil
+		}
+		if i, exists := added[name]; exists {
+			if typeStr == "double" && fields[i].Type == "long" {
+				fields[i].Type = "double"
+			}
+			return nil
+		}
+		if !d.conf.SchemaEvolution {
+			return fmt.Errorf("field '%v' is not a column of the table", name)
+		}
+		if err := checkDeltaColumnName(name); err != nil {
+			return err
+		}
+		added[name] = len(fields)
+		fields = append(fields, deltaField{
+			Name:     name,
+			Type:     typeStr,
+			Nullable: true,
+			Metadata: map[string]interface{}{},
+		})
+		return nil
+	}
+
+	for _, row := range rows {
+		keys := make([]string, 0, len(row))
+		for k := range row {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			typeStr := inferDeltaType(row[k])
+			if typeStr == "" {
+				continue
+			}
+			if err := addField(k, typeStr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Partition columns of a new table that have only null values are added as
+	// strings.
+	for _, c := range state.partitionColumns {
+		if err := addField(c, "string"); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// reconcileDeltaFields returns the columns of a table that has changed since a
+// batch was written, which must be compatible with the columns of the batch.
+func reconcileDeltaFields(state *deltaTableState, batchFields []deltaField) ([]deltaField, error) {
+	fields := append([]deltaField{}, state.fields...)
+	for _, f := range batchFields {
+		existing, exists := findDeltaField(state.fields, f.Name)
+		if !exists {
+			fields = append(fields, f)
+			continue
+		}
+		if existing.typeStr() != f.typeStr() {
+			return nil, fmt.Errorf("column '%v' was concurrently changed to type %v", f.Name, existing.Type)
+		}
+	}
+	return fields, nil
+}
+
+// convertDeltaValue converts a JSON value into the representation of a column
+// type expected by the Parquet writer.
+func convertDeltaValue(v interface{}, typeStr string) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	str, isStr := v.(string)
+	num, isNum := v.(json.Number)
+
+	switch typeStr {
+	case "string":
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case json.Number:
+			return t.String(), nil
+		case bool:
+			return strconv.FormatBool(t), nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "long", "integer", "short", "byte":
+		if isNum {
+			str = num.String()
+		} else if !isStr {
+			break
+		}
+		i, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return i, nil
+	case "double", "float":
+		if isNum {
+			str = num.String()
+		} else if !isStr {
+			break
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		} else if !isStr {
+			break
+		}
+		return strconv.ParseBool(str)
+	case "timestamp":
+		if isNum {
+			f, err := num.Float64()
+			if err != nil {
+				return nil, err
+			}
+			return int64(f * 1e6), nil
+		} else if !isStr {
+			break
+		}
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return nil, err
+		}
+		return t.UnixNano() / 1000, nil
+	case "date":
+		if !isStr {
+			break
+		}
+		t, err := time.Parse("2006-01-02", str)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339Nano, str); err != nil {
+				return nil, err
+			}
+		}
+		return int32(t.Unix() / 86400), nil
+	default:
+		return nil, fmt.Errorf("column type %v is not supported", typeStr)
+	}
+	return nil, fmt.Errorf("value of type %T cannot be converted to %v", v, typeStr)
+}
+
+func deltaParquetTag(index int, f deltaField) (string, error) {
+	var pqType string
+	switch f.typeStr() {
+	case "string":
+		pqType = "type=BYTE_ARRAY, convertedtype=UTF8"
+	case "long":
+		pqType = "type=INT64"
+	case "integer":
+		pqType = "type=INT32"
+	case "short":
+		pqType = "type=INT32, convertedtype=INT_16"
+	case "byte":
+		pqType = "type=INT32, convertedtype=INT_8"
+	case "double":
+		pqType = "type=DOUBLE"
+	case "float":
+		pqType = "type=FLOAT"
+	case "boolean":
+		pqType = "type=BOOLEAN"
+	case "timestamp":
+		pqType = "type=INT64, convertedtype=TIMESTAMP_MICROS"
+	case "date":
+		pqType = "type=INT32, convertedtype=DATE"
+	default:
+		return "", fmt.Errorf("column '%v' has type %v, which is not supported", f.Name, f.Type)
+	}
+	return fmt.Sprintf("name=%v, inname=C%v, %v, repetitiontype=OPTIONAL", f.Name, index, pqType), nil
+}
+
+//------------------------------------------------------------------------------
+
+// escapeDeltaPartitionValue escapes a partition value for use within a
+// directory name the same way as Hive.
+func escapeDeltaPartitionValue(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// writeDataFiles writes the rows of a batch as a Parquet file for each
+// partition that they belong to.
+func (d *deltaLakeWriter) writeDataFiles(fields []deltaField, partitionColumns []string, rows []map[string]interface{}) ([]deltaDataFile, error) {
+	isPartition := map[string]bool{}
+	for _, c := range partitionColumns {
+		isPartition[c] = true
+	}
+
+	tags := []string{}
+	dataFields := []deltaField{}
+	for _, f := range fields {
+		if isPartition[f.Name] {
+			continue
+		}
+		tag, err := deltaParquetTag(len(dataFields), f)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+		dataFields = append(dataFields, f)
+	}
+	schemaFields := make([]map[string]string, len(tags))
+	for i, t := range tags {
+		schemaFields[i] = map[string]string{"Tag": t}
+	}
+	schemaBytes, err := json.Marshal(map[string]interface{}{
+		"Tag":    "name=parquet_go_root, repetitiontype=REQUIRED",
+		"Fields": schemaFields,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type partition struct {
+		dir     string
+		values  map[string]interface{}
+		records []string
+	}
+	var partitions []*partition
+	partitionsByDir := map[string]*partition{}
+
+	for i, row := range rows {
+		values := map[string]interface{}{}
+		dirs := make([]string, 0, len(partitionColumns))
+		for _, c := range partitionColumns {
+			f, _ := findDeltaField(fields, c)
+			v, err := convertDeltaValue(row[c], f.typeStr())
+			if err != nil {
+				return nil, fmt.Errorf("message %v column '%v': %w", i, c, err)
+			}
+			if v == nil {
+				values[c] = nil
+				dirs = append(dirs, c+"="+deltaNullPartitionValue)
+				continue
+			}
+			vStr := fmt.Sprintf("%v", v)
+			if s, ok := row[c].(string); ok && (f.typeStr() == "date" || f.typeStr() == "timestamp") {
+				vStr = s
+			}
+			values[c] = vStr
+			dirs = append(dirs, escapeDeltaPartitionValue(c)+"="+escapeDeltaPartitionValue(vStr))
+		}
+
+		record := make(map[string]interface{}, len(dataFields))
+		for j, f := range dataFields {
+			v, err := convertDeltaValue(row[f.Name], f.typeStr())
+			if err != nil {
+				return nil, fmt.Errorf("message %v column '%v': %w", i, f.Name, err)
+			}
+			if v != nil {
+				record["C"+strconv.Itoa(j)] = v
+			}
+		}
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+
+		dir := strings.Join(dirs, "/")
+		p, exists := partitionsByDir[dir]
+		if !exists {
+			p = &partition{dir: dir, values: values}
+			partitionsByDir[dir] = p
+			partitions = append(partitions, p)
+		}
+		p.records = append(p.records, string(recordBytes))
+	}
+
+	files := make([]deltaDataFile, 0, len(partitions))
+	for _, p := range partitions {
+		var buf bytes.Buffer
+		pw, err := pqwriter.NewJSONWriterFromWriter(string(schemaBytes), &buf, 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+		}
+		pw.CompressionType = d.compression
+		for _, r := range p.records {
+			if err := pw.Write(r); err != nil {
+				return nil, fmt.Errorf("failed to write parquet record: %w", err)
+			}
+		}
+		if err := pw.WriteStop(); err != nil {
+			return nil, fmt.Errorf("failed to write parquet file: %w", err)
+		}
+
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		relPath := path.Join(p.dir, fmt.Sprintf("part-00000-%v.c000%v", id, d.fileSuffix))
+		fullPath := filepath.Join(d.conf.Path, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(fullPath, buf.Bytes(), 0o644); err != nil {
+			return nil, err
+		}
+		files = append(files, deltaDataFile{
+			path:            (&url.URL{Path: relPath}).EscapedPath(),
+			partitionValues: p.values,
+			size:            int64(buf.Len()),
+			records:         len(p.records),
+		})
+	}
+	return files, nil
+}
+
+//------------------------------------------------------------------------------
+
+// commit attempts to commit data files to the next version of the table,
+// along with a change to its schema when columns have been added.
+func (d *deltaLakeWriter) commit(fields []deltaField, files []deltaDataFile) error {
+	version := d.state.version + 1
+	nowMillis := time.Now().UnixNano() / int64(time.Millisecond)
+
+	var actions []interface{}
+	var metaData map[string]interface{}
+	if version == 0 || len(fields) != len(d.state.fields) {
+		schemaBytes, err := json.Marshal(deltaSchema{Type: "struct", Fields: fields})
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			id, err := uuid.NewV4()
+			if err != nil {
+				return err
+			}
+			actions = append(actions, map[string]interface{}{
+				"protocol": map[string]interface{}{
+					"minReaderVersion": 1,
+					"minWriterVersion": 2,
+				},
+			})
+			partitionColumns := d.state.partitionColumns
+			if partitionColumns == nil {
+				partitionColumns = []string{}
+			}
+			metaData = map[string]interface{}{
+				"id": id.String(),
+				"format": map[string]interface{}{
+					"provider": "parquet",
+					"options":  map[string]interface{}{},
+				},
+				"partitionColumns": partitionColumns,
+				"configuration":    map[string]interface{}{},
+				"createdTime":      nowMillis,
+			}
+		} else {
+			metaData = make(map[string]interface{}, len(d.state.metaData))
+			for k, v := range d.state.metaData {
+				metaData[k] = v
+			}
+		}
+		metaData["schemaString"] = string(schemaBytes)
+		actions = append(actions, map[string]interface{}{"metaData": metaData})
+	}
+
+	for _, f := range files {
+		actions = append(actions, map[string]interface{}{
+			"add": map[string]interface{}{
+				"path":             f.path,
+				"partitionValues":  f.partitionValues,
+				"size":             f.size,
+				"modificationTime": nowMillis,
+				"dataChange":       true,
+				"stats":            fmt.Sprintf(`{"numRecords":%v}`, f.records),
+			},
+		})
+	}
+	actions = append(actions, map[string]interface{}{
+		"commitInfo": map[string]interface{}{
+			"timestamp":           nowMillis,
+			"operation":           "WRITE",
+			"operationParameters": map[string]interface{}{"mode": "Append"},
+			"engineInfo":          "Benthos",
+			"isBlindAppend":       true,
+		},
+	})
+
+	var buf bytes.Buffer
+	for _, a := range actions {
+		actionBytes, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		buf.Write(actionBytes)
+		buf.WriteByte('\n')
+	}
+
+	// The commit is written to a temporary file and then linked to its final
+	// name, which fails if another writer has already committed the version.
+	logDir := filepath.Join(d.conf.Path, deltaLogDir)
+	tmp, err := ioutil.TempFile(logDir, ".tmp_commit_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Link(tmp.Name(), filepath.Join(logDir, fmt.Sprintf("%020d.json", version))); err != nil {
+		if os.IsExist(err) {
+			return errDeltaCommitConflict
+		}
+		return err
+	}
+
+	d.state.version = version
+	d.state.fields = fields
+	if metaData != nil {
+		d.state.metaData = metaData
+	}
+	return nil
+}
+
+// WriteWithContext writes a batch of messages as data files and commits them
+// to the table.
+func (d *deltaLakeWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	rows := make([]map[string]interface{}, msg.Len())
+	if err := msg.Iter(func(i int, p types.Part) error {
+		dec := json.NewDecoder(bytes.NewReader(p.Get()))
+		dec.UseNumber()
+		if err := dec.Decode(&rows[i]); err != nil || rows[i] == nil {
+			return fmt.Errorf("message %v is not a JSON object", i)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.state == nil {
+		return types.ErrNotConnected
+	}
+
+	fields, err := d.mergeDeltaFields(d.state, rows)
+	if err != nil {
+		return err
+	}
+	files, err := d.writeDataFiles(fields, d.state.partitionColumns, rows)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err = d.commit(fields, files); err != errDeltaCommitConflict {
+			break
+		}
+		d.mConflicts.Incr(1)
+		if attempt >= deltaCommitAttempts {
+			return fmt.Errorf("failed to commit after %v attempts: %w", attempt, err)
+		}
+		// Another writer has committed to the table, and so we refresh its
+		// state and try again with the next version.
+		state, err := d.readState()
+		if err != nil {
+			return err
+		}
+		if strings.Join(state.partitionColumns, ",") != strings.Join(d.state.partitionColumns, ",") {
+			return fmt.Errorf("the partition columns of the table were concurrently changed to %v", state.partitionColumns)
+		}
+		if fields, err = reconcileDeltaFields(state, fields); err != nil {
+			return err
+		}
+		d.state = state
+	}
+	if err != nil {
+		return err
+	}
+	d.mCommitted.Incr(1)
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (d *deltaLakeWriter) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (d *deltaLakeWriter) WaitForClose(time.Duration) error {
+	return nil
+}
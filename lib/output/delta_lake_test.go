@@ -0,0 +1,244 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+type bytesParquetFile struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newBytesParquetFile(data []byte) bytesParquetFile {
+	return bytesParquetFile{Reader: bytes.NewReader(data), data: data}
+}
+
+func (b bytesParquetFile) Write([]byte) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (b bytesParquetFile) Close() error {
+	return nil
+}
+
+func (b bytesParquetFile) Open(string) (source.ParquetFile, error) {
+	return newBytesParquetFile(b.data), nil
+}
+
+func (b bytesParquetFile) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("not supported")
+}
+
+func readDeltaTestRows(t *testing.T, path string) string {
+	t.Helper()
+
+	fileBytes, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	pr, err := reader.NewParquetReader(newBytesParquetFile(fileBytes), nil, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	numRows := int(pr.GetNumRows())
+	rows := make([]map[string]interface{}, numRows)
+	for i := range rows {
+		rows[i] = map[string]interface{}{}
+	}
+	for i, info := range pr.SchemaHandler.Infos[1:] {
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), int64(numRows))
+		require.NoError(t, err)
+		for j, v := range values {
+			rows[j][info.ExName] = v
+		}
+	}
+
+	rowsBytes, err := json.Marshal(rows)
+	require.NoError(t, err)
+	return string(rowsBytes)
+}
+
+func readDeltaTestLog(t *testing.T, dir string, version int) []map[string]interface{} {
+	t.Helper()
+
+	actions, err := readDeltaCommit(filepath.Join(dir, deltaLogDir, fmt.Sprintf("%020d.json", version)))
+	require.NoError(t, err)
+	return actions
+}
+
+func writeDeltaTestBatch(t *testing.T, w *deltaLakeWriter, docs ...string) error {
+	t.Helper()
+
+	parts := make([][]byte, len(docs))
+	for i, d := range docs {
+		parts[i] = []byte(d)
+	}
+	return w.WriteWithContext(context.Background(), message.New(parts))
+}
+
+func TestDeltaLakeCreateAndEvolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_delta_lake_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	conf := NewDeltaLakeConfig()
+	conf.Path = dir
+	conf.PartitionBy = []string{"day"}
+
+	w, err := newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+
+	require.NoError(t, writeDeltaTestBatch(t, w,
+		`{"day":"2021-06-01","id":1,"name":"foo"}`,
+		`{"day":"2021-06-02","id":2,"name":"bar"}`,
+		`{"day":"2021-06-01","id":3,"name":null}`,
+	))
+
+	actions := readDeltaTestLog(t, dir, 0)
+	require.Len(t, actions, 5)
+	assert.Equal(t, map[string]interface{}{
+		"minReaderVersion": float64(1),
+		"minWriterVersion": float64(2),
+	}, actions[0]["protocol"])
+
+	metaData := actions[1]["metaData"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"day"}, metaData["partitionColumns"])
+	assert.JSONEq(t, `{"type":"struct","fields":[
+		{"name":"day","type":"string","nullable":true,"metadata":{}},
+		{"name":"id","type":"long","nullable":true,"metadata":{}},
+		{"name":"name","type":"string","nullable":true,"metadata":{}}
+	]}`, metaData["schemaString"].(string))
+
+	add := actions[2]["add"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"day": "2021-06-01"}, add["partitionValues"])
+	assert.Equal(t, `{"numRecords":2}`, add["stats"])
+	assert.Regexp(t, `^day=2021-06-01/part-00000-.*\.c000\.snappy\.parquet$`, add["path"])
+	assert.JSONEq(t,
+		`[{"id":1,"name":"foo"},{"id":3,"name":null}]`,
+		readDeltaTestRows(t, filepath.Join(dir, add["path"].(string))),
+	)
+
+	add = actions[3]["add"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"day": "2021-06-02"}, add["partitionValues"])
+	assert.Contains(t, actions[4], "commitInfo")
+
+	// A new writer reads the state of the existing table and adds new columns.
+	conf.PartitionBy = nil
+	w, err = newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+	assert.Equal(t, int64(0), w.state.version)
+
+	require.NoError(t, writeDeltaTestBatch(t, w,
+		`{"day":"2021-06-03","id":"4","score":1,"tags":["a","b"]}`,
+		`{"day":"2021-06-03","id":5,"score":1.5}`,
+	))
+
+	actions = readDeltaTestLog(t, dir, 1)
+	require.Len(t, actions, 3)
+	metaData = actions[0]["metaData"].(map[string]interface{})
+	assert.JSONEq(t, `{"type":"struct","fields":[
+		{"name":"day","type":"string","nullable":true,"metadata":{}},
+		{"name":"id","type":"long","nullable":true,"metadata":{}},
+		{"name":"name","type":"string","nullable":true,"metadata":{}},
+		{"name":"score","type":"double","nullable":true,"metadata":{}},
+		{"name":"tags","type":"string","nullable":true,"metadata":{}}
+	]}`, metaData["schemaString"].(string))
+
+	add = actions[1]["add"].(map[string]interface{})
+	assert.JSONEq(t,
+		`[{"id":4,"name":null,"score":1,"tags":"[\"a\",\"b\"]"},{"id":5,"name":null,"score":1.5,"tags":null}]`,
+		readDeltaTestRows(t, filepath.Join(dir, add["path"].(string))),
+	)
+
+	// Batches with values that cannot be converted to existing columns are
+	// rejected.
+	require.EqualError(t,
+		writeDeltaTestBatch(t, w, `{"day":"2021-06-03","id":"nope"}`),
+		`message 0 column 'id': strconv.ParseInt: parsing "nope": invalid syntax`,
+	)
+
+	conf.PartitionBy = []string{"id"}
+	w, err = newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.EqualError(t, w.ConnectWithContext(context.Background()), "partition_by [id] does not match the partition columns [day] of the table")
+}
+
+func TestDeltaLakeNoSchemaEvolution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_delta_lake_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	conf := NewDeltaLakeConfig()
+	conf.Path = dir
+
+	w, err := newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+	require.NoError(t, writeDeltaTestBatch(t, w, `{"id":1}`))
+
+	conf.SchemaEvolution = false
+	w, err = newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+
+	require.NoError(t, writeDeltaTestBatch(t, w, `{"id":2}`))
+	require.EqualError(t, writeDeltaTestBatch(t, w, `{"id":3,"name":"foo"}`), "field 'name' is not a column of the table")
+	require.EqualError(t, writeDeltaTestBatch(t, w, `[1,2,3]`), "message 0 is not a JSON object")
+}
+
+func TestDeltaLakeConcurrentCommits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_delta_lake_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	conf := NewDeltaLakeConfig()
+	conf.Path = dir
+
+	wOne, err := newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, wOne.ConnectWithContext(context.Background()))
+
+	wTwo, err := newDeltaLakeWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, wTwo.ConnectWithContext(context.Background()))
+
+	require.NoError(t, writeDeltaTestBatch(t, wOne, `{"id":1,"foo":"a"}`))
+	require.NoError(t, writeDeltaTestBatch(t, wTwo, `{"id":2,"bar":"b"}`))
+
+	assert.Equal(t, int64(1), wTwo.state.version)
+
+	actions := readDeltaTestLog(t, dir, 1)
+	require.Len(t, actions, 3)
+	metaData := actions[0]["metaData"].(map[string]interface{})
+	assert.JSONEq(t, `{"type":"struct","fields":[
+		{"name":"foo","type":"string","nullable":true,"metadata":{}},
+		{"name":"id","type":"long","nullable":true,"metadata":{}},
+		{"name":"bar","type":"string","nullable":true,"metadata":{}}
+	]}`, metaData["schemaString"].(string))
+
+	_, err = os.Stat(filepath.Join(dir, deltaLogDir, "00000000000000000002.json"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -32,13 +35,32 @@ func init() {
 		Summary: `
 Attempts to write messages to a child output and if the write fails for one of a list of configurable reasons the message is dropped instead of being reattempted.`,
 		Description: `
-Regular Benthos outputs will apply back pressure when downstream services aren't accessible, and Benthos retries (or nacks) all messages that fail to be delivered. However, in some circumstances, or for certain output types, we instead might want to relax these mechanisms, which is when this output becomes useful.`,
+Regular Benthos outputs will apply back pressure when downstream services aren't accessible, and Benthos retries (or nacks) all messages that fail to be delivered. However, in some circumstances, or for certain output types, we instead might want to relax these mechanisms, which is when this output becomes useful.
+
+### Error Checks
+
+For finer control over which failures result in a dropped message the field ` + "`error_check`" + ` can be set to a [Bloblang mapping](/docs/guides/bloblang/about) that is executed each time a write fails, and decides whether the message should be dropped, retried or rejected. When set, the field ` + "`error`" + ` is ignored.
+
+The mapping is executed against a document describing the failure, and functions such as ` + "`meta`" + ` and ` + "`content`" + ` refer to the first message of the batch. The document has the following fields:
+
+- ` + "`error`" + `: The error returned by the child output as a string.
+- ` + "`status_code`" + `: The response status code when the error was caused by an unexpected HTTP response, otherwise ` + "`null`" + `.
+- ` + "`back_pressure`" + `: The number of seconds waited for the child output to accept the message when the failure was caused by back pressure, otherwise ` + "`null`" + `.
+- ` + "`attempt`" + `: The number of attempts made to write the message so far, starting at 1.
+
+The mapping must result in either the string ` + "`drop`, `retry` or `reject`" + `, or a boolean where ` + "`true`" + ` drops the message and ` + "`false`" + ` rejects it. A rejected message is nacked and therefore reattempted as usual by the input, whereas a retried message is written to the child output again after waiting for ` + "`retry_interval`" + `. If the mapping fails or results in any other value then the message is rejected.`,
 		Categories: []Category{
 			CategoryUtility,
 		},
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("error", "Whether messages should be dropped when the child output returns an error. For example, this could be when an http_client output gets a 4XX response code."),
 			docs.FieldCommon("back_pressure", "An optional duration string that determines the maximum length of time to wait for a given message to be accepted by the child output before the message should be dropped instead. The most common reason for an output to block is when waiting for a lost connection to be re-established. Once a message has been dropped due to back pressure all subsequent messages are dropped immediately until the output is ready to process them again. Note that if `error` is set to `false` and this field is specified then messages dropped due to back pressure will return an error response.", "30s", "1m"),
+			docs.FieldCommon(
+				"error_check", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that is executed when a write fails and decides whether the message should be dropped, retried or rejected. When set the field `error` is ignored.",
+				`this.status_code.or(0) == 404`,
+				`if this.back_pressure != null || this.attempt >= 3 { "drop" } else { "retry" }`,
+			).Linter(docs.LintBloblangMapping).AtVersion("3.47.0"),
+			docs.FieldAdvanced("retry_interval", "The duration to wait before writing a message again when `error_check` results in `retry`.").AtVersion("3.47.0"),
 			docs.FieldCommon("output", "A child output.").HasType(docs.FieldOutput),
 		},
 		Examples: []docs.AnnotatedExample{
@@ -71,6 +93,27 @@ output:
     output:
       websocket:
         url: ws://example.com/foo/messages
+`,
+			},
+			{
+				Title:   "Dropping by status code",
+				Summary: "Requests that fail with a 4XX status code are unlikely to succeed when reattempted, and therefore in this example they are dropped. Requests that fail for any other reason are retried up to three times before being rejected.",
+				Config: `
+output:
+  drop_on:
+    error_check: |
+      root = if this.status_code.or(0) >= 400 && this.status_code.or(0) < 500 {
+        "drop"
+      } else if this.attempt < 3 {
+        "retry"
+      } else {
+        "reject"
+      }
+    retry_interval: 500ms
+    output:
+      http_client:
+        url: http://example.com/foo/messages
+        verb: POST
 `,
 			},
 		},
@@ -82,8 +125,10 @@ output:
 // DropOnConditions is a config struct representing the different circumstances
 // under which messages should be dropped.
 type DropOnConditions struct {
-	Error        bool   `json:"error" yaml:"error"`
-	BackPressure string `json:"back_pressure" yaml:"back_pressure"`
+	Error         bool   `json:"error" yaml:"error"`
+	BackPressure  string `json:"back_pressure" yaml:"back_pressure"`
+	ErrorCheck    string `json:"error_check" yaml:"error_check"`
+	RetryInterval string `json:"retry_interval" yaml:"retry_interval"`
 }
 
 // DropOnConfig contains configuration values for the DropOn output type.
@@ -96,8 +141,10 @@ type DropOnConfig struct {
 func NewDropOnConfig() DropOnConfig {
 	return DropOnConfig{
 		DropOnConditions: DropOnConditions{
-			Error:        false,
-			BackPressure: "",
+			Error:         false,
+			BackPressure:  "",
+			ErrorCheck:    "",
+			RetryInterval: "1s",
 		},
 		Output: nil,
 	}
@@ -144,8 +191,12 @@ type dropOn struct {
 
 	onError        bool
 	onBackpressure time.Duration
+	errorCheck     *mapping.Executor
+	retryInterval  time.Duration
 	wrapped        Type
 
+	gotBackPressure bool
+
 	transactionsIn  <-chan types.Transaction
 	transactionsOut chan types.Transaction
 
@@ -163,6 +214,22 @@ func newDropOn(conf DropOnConditions, wrapped Type, log log.Modular, stats metri
 		}
 	}
 
+	var errorCheck *mapping.Executor
+	if len(conf.ErrorCheck) > 0 {
+		var err error
+		if errorCheck, err = bloblang.NewMapping("", conf.ErrorCheck); err != nil {
+			return nil, fmt.Errorf("failed to parse error_check mapping: %w", err)
+		}
+	}
+
+	var retryInterval time.Duration
+	if len(conf.RetryInterval) > 0 {
+		var err error
+		if retryInterval, err = time.ParseDuration(conf.RetryInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse retry_interval duration: %w", err)
+		}
+	}
+
 	ctx, done := context.WithCancel(context.Background())
 	return &dropOn{
 		log:             log,
@@ -172,6 +239,8 @@ func newDropOn(conf DropOnConditions, wrapped Type, log log.Modular, stats metri
 
 		onError:        conf.Error,
 		onBackpressure: backPressure,
+		errorCheck:     errorCheck,
+		retryInterval:  retryInterval,
 
 		ctx:        ctx,
 		done:       done,
@@ -181,11 +250,121 @@ func newDropOn(conf DropOnConditions, wrapped Type, log log.Modular, stats metri
 
 //------------------------------------------------------------------------------
 
+// The actions that an error check can result in.
+const (
+	dropOnActionDrop   = "drop"
+	dropOnActionRetry  = "retry"
+	dropOnActionReject = "reject"
+)
+
+// write attempts to deliver a message to the child output and returns whether
+// the write failed due to back pressure, whether the output remains open, and
+// the resulting error.
+func (d *dropOn) write(msg types.Message, resChan chan types.Response) (backPressure, ok bool, err error) {
+	if d.onBackpressure <= 0 {
+		// Push data as usual, if the output blocks due to a disconnect then
+		// we wait as long as it takes.
+		select {
+		case d.transactionsOut <- types.NewTransaction(msg, resChan):
+		case <-d.ctx.Done():
+			return false, false, nil
+		}
+		select {
+		case res := <-resChan:
+			return false, true, res.Error()
+		case <-d.ctx.Done():
+			return false, false, nil
+		}
+	}
+
+	// Use a ticker here and call Stop explicitly.
+	ticker := time.NewTicker(d.onBackpressure)
+	defer ticker.Stop()
+
+	if d.gotBackPressure {
+		select {
+		case d.transactionsOut <- types.NewTransaction(msg, resChan):
+			d.gotBackPressure = false
+		default:
+		}
+	} else {
+		select {
+		case d.transactionsOut <- types.NewTransaction(msg, resChan):
+		case <-ticker.C:
+			d.gotBackPressure = true
+		case <-d.ctx.Done():
+			return false, false, nil
+		}
+	}
+	if !d.gotBackPressure {
+		select {
+		case res := <-resChan:
+			return false, true, res.Error()
+		case <-ticker.C:
+			d.gotBackPressure = true
+			go func() {
+				// We must pull the response that we're due, since the
+				// component isn't being shut down.
+				<-resChan
+			}()
+		case <-d.ctx.Done():
+			return false, false, nil
+		}
+	}
+	return true, true, fmt.Errorf("experienced back pressure beyond: %v", d.onBackpressure)
+}
+
+// checkError executes the error check mapping against a failed write and
+// returns the resulting action.
+func (d *dropOn) checkError(msg types.Message, err error, backPressure bool, attempt int) string {
+	failure := map[string]interface{}{
+		"error":         err.Error(),
+		"status_code":   nil,
+		"back_pressure": nil,
+		"attempt":       int64(attempt),
+	}
+	var httpErr types.ErrUnexpectedHTTPRes
+	if errors.As(err, &httpErr) {
+		failure["status_code"] = int64(httpErr.Code)
+	}
+	if backPressure {
+		failure["back_pressure"] = d.onBackpressure.Seconds()
+	}
+
+	var value interface{} = failure
+	res, cErr := d.errorCheck.Exec(query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{},
+		Index:    0,
+		MsgBatch: msg,
+	}.WithValue(value))
+	if cErr != nil {
+		d.log.Errorf("Failed to execute error_check mapping: %v\n", cErr)
+		return dropOnActionReject
+	}
+
+	switch t := res.(type) {
+	case bool:
+		if t {
+			return dropOnActionDrop
+		}
+		return dropOnActionReject
+	case string:
+		switch t {
+		case dropOnActionDrop, dropOnActionRetry, dropOnActionReject:
+			return t
+		}
+	}
+	d.log.Errorf("Error check mapping resulted in unrecognised action: %v\n", res)
+	return dropOnActionReject
+}
+
 func (d *dropOn) loop() {
 	// Metrics paths
 	var (
 		mDropped      = d.stats.GetCounter("drop_on.dropped")
 		mDroppedBatch = d.stats.GetCounter("drop_on.batch.dropped")
+		mRetry        = d.stats.GetCounter("drop_on.retry")
 	)
 
 	defer func() {
@@ -199,7 +378,6 @@ func (d *dropOn) loop() {
 
 	resChan := make(chan types.Response)
 
-	var gotBackPressure bool
 	for {
 		var ts types.Transaction
 		var open bool
@@ -213,75 +391,57 @@ func (d *dropOn) loop() {
 		}
 
 		var res types.Response
-		if d.onBackpressure > 0 {
-			if !func() bool {
-				// Use a ticker here and call Stop explicitly.
-				ticker := time.NewTicker(d.onBackpressure)
-				defer ticker.Stop()
-
-				if gotBackPressure {
-					select {
-					case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
-						gotBackPressure = false
-					default:
-					}
-				} else {
-					select {
-					case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
-					case <-ticker.C:
-						gotBackPressure = true
-					case <-d.ctx.Done():
-						return false
-					}
-				}
-				if !gotBackPressure {
-					select {
-					case res = <-resChan:
-					case <-ticker.C:
-						gotBackPressure = true
-						go func() {
-							// We must pull the response that we're due, since
-							// the component isn't being shut down.
-							<-resChan
-						}()
-					case <-d.ctx.Done():
-						return false
-					}
-				}
-				if gotBackPressure {
+	attempts:
+		for attempt := 1; ; attempt++ {
+			backPressure, ok, err := d.write(ts.Payload, resChan)
+			if !ok {
+				return
+			}
+			if err == nil {
+				res = response.NewAck()
+				break
+			}
+
+			if d.errorCheck == nil {
+				if backPressure {
 					mDropped.Incr(int64(ts.Payload.Len()))
 					mDroppedBatch.Incr(1)
 					d.log.Warnln("Message dropped due to back pressure.")
 					if d.onError {
 						res = response.NewAck()
 					} else {
-						res = response.NewError(fmt.Errorf("experienced back pressure beyond: %v", d.onBackpressure))
+						res = response.NewError(err)
 					}
+				} else if d.onError {
+					mDropped.Incr(int64(ts.Payload.Len()))
+					mDroppedBatch.Incr(1)
+					d.log.Warnf("Message dropped due to: %v\n", err)
+					res = response.NewAck()
+				} else {
+					res = response.NewError(err)
 				}
-				return true
-			}() {
-				return
+				break
 			}
-		} else {
-			// Push data as usual, if the output blocks due to a disconnect then
-			// we wait as long as it takes.
-			select {
-			case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
-			case <-d.ctx.Done():
-				return
-			}
-			select {
-			case res = <-resChan:
-			case <-d.ctx.Done():
-				return
-			}
-		}
 
-		if res.Error() != nil && d.onError {
-			mDropped.Incr(int64(ts.Payload.Len()))
-			mDroppedBatch.Incr(1)
-			d.log.Warnf("Message dropped due to: %v\n", res.Error())
-			res = response.NewAck()
+			switch d.checkError(ts.Payload, err, backPressure, attempt) {
+			case dropOnActionDrop:
+				mDropped.Incr(int64(ts.Payload.Len()))
+				mDroppedBatch.Incr(1)
+				d.log.Warnf("Message dropped due to: %v\n", err)
+				res = response.NewAck()
+				break attempts
+			case dropOnActionRetry:
+				mRetry.Incr(1)
+				d.log.Debugf("Retrying message due to: %v\n", err)
+				select {
+				case <-time.After(d.retryInterval):
+				case <-d.ctx.Done():
+					return
+				}
+			default:
+				res = response.NewError(err)
+				break attempts
+			}
 		}
 
 		select {
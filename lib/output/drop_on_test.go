@@ -1,6 +1,7 @@
 package output
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -313,3 +314,138 @@ func TestDropOnDisconnectBackpressureNoErrors(t *testing.T) {
 
 	assert.Equal(t, []string{"first", "second"}, wsReceived)
 }
+
+func TestDropOnErrorCheck(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) == "foo" {
+			http.Error(w, "test error", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "test error", http.StatusBadRequest)
+	}))
+	t.Cleanup(func() {
+		ts.Close()
+	})
+
+	childConf := NewConfig()
+	childConf.Type = TypeHTTPClient
+	childConf.HTTPClient.URL = ts.URL
+	childConf.HTTPClient.DropOn = []int{http.StatusForbidden, http.StatusBadRequest}
+
+	child, err := New(childConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		child.CloseAsync()
+		assert.NoError(t, child.WaitForClose(time.Second*5))
+	})
+
+	dropConf := NewDropOnConfig()
+	dropConf.Error = true
+	dropConf.ErrorCheck = `root = if this.status_code == 403 && this.back_pressure == null { "drop" } else { "reject" }`
+
+	d, err := newDropOn(dropConf.DropOnConditions, child, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		d.CloseAsync()
+		assert.NoError(t, d.WaitForClose(time.Second*5))
+	})
+
+	tChan := make(chan types.Transaction)
+	rChan := make(chan types.Response)
+
+	require.NoError(t, d.Consume(tChan))
+
+	sendAndGet := func(msg string) error {
+		t.Helper()
+
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte(msg)}), rChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+
+		var res types.Response
+		select {
+		case res = <-rChan:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return res.Error()
+	}
+
+	assert.NoError(t, sendAndGet("foo"))
+	assert.EqualError(t, sendAndGet("bar"), "HTTP request returned unexpected response code (400): 400 Bad Request")
+}
+
+func TestDropOnErrorCheckRetry(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		reqs++
+		n := reqs
+		reqMut.Unlock()
+		if n%3 != 0 {
+			http.Error(w, "test error", http.StatusBadGateway)
+		}
+	}))
+	t.Cleanup(func() {
+		ts.Close()
+	})
+
+	childConf := NewConfig()
+	childConf.Type = TypeHTTPClient
+	childConf.HTTPClient.URL = ts.URL
+	childConf.HTTPClient.DropOn = []int{http.StatusBadGateway}
+
+	child, err := New(childConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		child.CloseAsync()
+		assert.NoError(t, child.WaitForClose(time.Second*5))
+	})
+
+	dropConf := NewDropOnConfig()
+	dropConf.ErrorCheck = `root = if this.attempt < 3 { "retry" } else { false }`
+	dropConf.RetryInterval = "1ms"
+
+	d, err := newDropOn(dropConf.DropOnConditions, child, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		d.CloseAsync()
+		assert.NoError(t, d.WaitForClose(time.Second*5))
+	})
+
+	tChan := make(chan types.Transaction)
+	rChan := make(chan types.Response)
+
+	require.NoError(t, d.Consume(tChan))
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foobar")}), rChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	var res types.Response
+	select {
+	case res = <-rChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	require.NoError(t, res.Error())
+
+	reqMut.Lock()
+	assert.Equal(t, 3, reqs)
+	reqMut.Unlock()
+}
+
+func TestDropOnErrorCheckBadMapping(t *testing.T) {
+	dropConf := NewDropOnConfig()
+	dropConf.ErrorCheck = `root = this.status_code ==`
+
+	_, err := newDropOn(dropConf.DropOnConditions, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse error_check mapping")
+}
@@ -0,0 +1,336 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/faults"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFaultInjection] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			if conf.FaultInjection.Output == nil {
+				return nil, errors.New("cannot create a fault_injection output without a child")
+			}
+			wrapped, err := New(*conf.FaultInjection.Output, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output '%v': %v", conf.FaultInjection.Output.Type, err)
+			}
+			return newFaultInjection(conf.FaultInjection.Config, wrapped, log, stats)
+		}),
+		Summary: `
+Injects random delays, errors, duplicates and reordering into the writes of a child output in order to test the resilience of a pipeline.`,
+		Description: `
+This output is intended for staging environments where you wish to verify that the retry and deduplication mechanisms of a pipeline behave as expected under failure. For each message (or batch) the following faults might be injected:
+
+- A delay before the message is written to the child output.
+- An error response for the message without it being written to the child output, which causes it to be reattempted.
+- A second write of the message to the child output after the first has succeeded.
+- The message is held back and written after the message that follows it. If no message follows within the maximum delay duration the held message is written regardless.
+
+The sequence of injected faults can be reproduced by setting a non-zero ` + "`seed`" + `.`,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		FieldSpecs: append(faults.FieldSpecs(),
+			docs.FieldCommon("output", "A child output.").HasType(docs.FieldOutput),
+		),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Unreliable HTTP Endpoint",
+				Summary: "In this example we simulate an HTTP endpoint that occasionally fails, is slow to respond and receives duplicate requests.",
+				Config: `
+output:
+  fault_injection:
+    seed: 42
+    delay:
+      probability: 0.2
+      min: 100ms
+      max: 2s
+    error_probability: 0.1
+    duplicate_probability: 0.05
+    output:
+      http_client:
+        url: http://example.com/foo/messages
+        verb: POST
+`,
+			},
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FaultInjectionConfig contains configuration values for the FaultInjection
+// output type.
+type FaultInjectionConfig struct {
+	faults.Config `json:",inline" yaml:",inline"`
+	Output        *Config `json:"output" yaml:"output"`
+}
+
+// NewFaultInjectionConfig creates a new FaultInjectionConfig with default
+// values.
+func NewFaultInjectionConfig() FaultInjectionConfig {
+	return FaultInjectionConfig{
+		Config: faults.NewConfig(),
+		Output: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyFaultInjectionConfig struct {
+	faults.Config `json:",inline" yaml:",inline"`
+	Output        interface{} `json:"output" yaml:"output"`
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (f FaultInjectionConfig) MarshalJSON() ([]byte, error) {
+	dummy := dummyFaultInjectionConfig{
+		Config: f.Config,
+		Output: f.Output,
+	}
+	if f.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return json.Marshal(dummy)
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (f FaultInjectionConfig) MarshalYAML() (interface{}, error) {
+	dummy := dummyFaultInjectionConfig{
+		Config: f.Config,
+		Output: f.Output,
+	}
+	if f.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy, nil
+}
+
+//------------------------------------------------------------------------------
+
+// faultInjection forwards messages to a child output, injecting random faults
+// along the way.
+type faultInjection struct {
+	stats metrics.Type
+	log   log.Modular
+
+	faults  *faults.Injector
+	holdFor time.Duration
+	wrapped Type
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+func newFaultInjection(conf faults.Config, wrapped Type, log log.Modular, stats metrics.Type) (*faultInjection, error) {
+	injector, err := conf.NewInjector()
+	if err != nil {
+		return nil, err
+	}
+
+	var holdFor time.Duration
+	if conf.Delay.Max != "" {
+		// Already validated by the injector.
+		holdFor, _ = time.ParseDuration(conf.Delay.Max)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	return &faultInjection{
+		log:             log,
+		stats:           stats,
+		faults:          injector,
+		holdFor:         holdFor,
+		wrapped:         wrapped,
+		transactionsOut: make(chan types.Transaction),
+
+		ctx:        ctx,
+		done:       done,
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (f *faultInjection) write(msg types.Message, resChan chan types.Response) (types.Response, bool) {
+	select {
+	case f.transactionsOut <- types.NewTransaction(msg, resChan):
+	case <-f.ctx.Done():
+		return nil, false
+	}
+	select {
+	case res := <-resChan:
+		return res, true
+	case <-f.ctx.Done():
+		return nil, false
+	}
+}
+
+func (f *faultInjection) loop() {
+	// Metrics paths
+	var (
+		mDelayed    = f.stats.GetCounter("fault_injection.delayed")
+		mErrored    = f.stats.GetCounter("fault_injection.errored")
+		mDuplicated = f.stats.GetCounter("fault_injection.duplicated")
+		mReordered  = f.stats.GetCounter("fault_injection.reordered")
+	)
+
+	defer func() {
+		close(f.transactionsOut)
+		f.wrapped.CloseAsync()
+		err := f.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = f.wrapped.WaitForClose(time.Second) {
+		}
+		close(f.closedChan)
+	}()
+
+	resChan := make(chan types.Response)
+
+	send := func(ts types.Transaction) bool {
+		if delay := f.faults.Delay(); delay > 0 {
+			mDelayed.Incr(1)
+			select {
+			case <-time.After(delay):
+			case <-f.ctx.Done():
+				return false
+			}
+		}
+
+		var res types.Response
+		if f.faults.Error() {
+			mErrored.Incr(1)
+			res = response.NewError(faults.ErrInjected)
+		} else {
+			var open bool
+			if res, open = f.write(ts.Payload, resChan); !open {
+				return false
+			}
+			if res.Error() == nil && f.faults.Duplicate() {
+				mDuplicated.Incr(1)
+				dupRes, open := f.write(ts.Payload.Copy(), resChan)
+				if !open {
+					return false
+				}
+				if err := dupRes.Error(); err != nil {
+					f.log.Debugf("Failed to write duplicate message: %v\n", err)
+				}
+			}
+		}
+
+		select {
+		case ts.ResponseChan <- res:
+		case <-f.ctx.Done():
+			return false
+		}
+		return true
+	}
+
+	var held *types.Transaction
+	for {
+		var ts types.Transaction
+		var open bool
+		if held != nil {
+			timer := time.NewTimer(f.holdFor)
+			select {
+			case ts, open = <-f.transactionsIn:
+				timer.Stop()
+			case <-timer.C:
+				if !send(*held) {
+					return
+				}
+				held = nil
+				continue
+			case <-f.ctx.Done():
+				timer.Stop()
+				return
+			}
+			if !open {
+				send(*held)
+				return
+			}
+		} else {
+			select {
+			case ts, open = <-f.transactionsIn:
+				if !open {
+					return
+				}
+			case <-f.ctx.Done():
+				return
+			}
+		}
+
+		if held == nil && f.faults.Reorder() {
+			mReordered.Incr(1)
+			held = &ts
+			continue
+		}
+		if !send(ts) {
+			return
+		}
+		if held != nil {
+			if !send(*held) {
+				return
+			}
+			held = nil
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (f *faultInjection) Consume(ts <-chan types.Transaction) error {
+	if f.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := f.wrapped.Consume(f.transactionsOut); err != nil {
+		return err
+	}
+	f.transactionsIn = ts
+	go f.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (f *faultInjection) Connected() bool {
+	return f.wrapped.Connected()
+}
+
+func (f *faultInjection) MaxInFlight() (int, bool) {
+	return output.GetMaxInFlight(f.wrapped)
+}
+
+// CloseAsync shuts down the output and stops processing requests.
+func (f *faultInjection) CloseAsync() {
+	f.done()
+}
+
+// WaitForClose blocks until the output has closed down.
+func (f *faultInjection) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
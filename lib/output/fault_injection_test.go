@@ -0,0 +1,117 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/faults"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func faultInjectionHarness(t *testing.T, conf faults.Config) (chan types.Transaction, *mockOutput) {
+	t.Helper()
+
+	child := &mockOutput{}
+	f, err := newFaultInjection(conf, child, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, f.Consume(tChan))
+	t.Cleanup(func() {
+		close(tChan)
+		assert.NoError(t, f.WaitForClose(time.Second*5))
+	})
+	return tChan, child
+}
+
+func sendFaultInjection(t *testing.T, tChan chan types.Transaction, content string) chan types.Response {
+	t.Helper()
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return resChan
+}
+
+func readFaultInjectionChild(t *testing.T, child *mockOutput, exp string) {
+	t.Helper()
+	select {
+	case ts := <-child.ts:
+		assert.Equal(t, exp, string(ts.Payload.Get(0).Get()))
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+}
+
+func readFaultInjectionRes(t *testing.T, resChan chan types.Response) error {
+	t.Helper()
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestFaultInjectionOutputErrors(t *testing.T) {
+	conf := faults.NewConfig()
+	conf.ErrorProbability = 1
+
+	tChan, child := faultInjectionHarness(t, conf)
+
+	resChan := sendFaultInjection(t, tChan, "foo")
+	assert.Equal(t, faults.ErrInjected, readFaultInjectionRes(t, resChan))
+
+	select {
+	case <-child.ts:
+		t.Fatal("unexpected write")
+	default:
+	}
+}
+
+func TestFaultInjectionOutputDuplicates(t *testing.T) {
+	conf := faults.NewConfig()
+	conf.DuplicateProbability = 1
+
+	tChan, child := faultInjectionHarness(t, conf)
+
+	resChan := sendFaultInjection(t, tChan, "foo")
+	readFaultInjectionChild(t, child, "foo")
+	readFaultInjectionChild(t, child, "foo")
+	assert.NoError(t, readFaultInjectionRes(t, resChan))
+}
+
+func TestFaultInjectionOutputReorder(t *testing.T) {
+	conf := faults.NewConfig()
+	conf.ReorderProbability = 1
+	conf.Delay.Max = "10ms"
+
+	tChan, child := faultInjectionHarness(t, conf)
+
+	firstRes := sendFaultInjection(t, tChan, "first")
+	secondRes := sendFaultInjection(t, tChan, "second")
+
+	readFaultInjectionChild(t, child, "second")
+	assert.NoError(t, readFaultInjectionRes(t, secondRes))
+	readFaultInjectionChild(t, child, "first")
+	assert.NoError(t, readFaultInjectionRes(t, firstRes))
+
+	// Without a following message the held message is flushed.
+	thirdRes := sendFaultInjection(t, tChan, "third")
+	readFaultInjectionChild(t, child, "third")
+	assert.NoError(t, readFaultInjectionRes(t, thirdRes))
+}
@@ -0,0 +1,214 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/fix"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFIX] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			w, err := newFIXWriter(conf.FIX, log)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncWriter(TypeFIX, 1, w, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Summary: `
+Establishes a FIX session with a counterparty, either as the initiator or the acceptor, and sends messages to it as application messages.`,
+		Description: `
+The session layer of the protocol is handled by this output, including logons, heartbeats, test requests, sequence numbers and logouts. Application messages sent by the counterparty, such as execution reports, are discarded, and so they should be consumed with a separate session using the ` + "[`fix` input](/docs/components/inputs/fix)" + `.
+
+When ` + "`mode` is `initiator`" + ` the output connects to the counterparty at ` + "`address`" + ` and reconnects whenever the session ends. When ` + "`mode` is `acceptor`" + ` the output listens on ` + "`address`" + ` for the counterparty to connect, and messages are not sent until a session is established.
+
+Messages sent by this output are not retained, and so resend requests from the counterparty are answered with a gap fill.
+
+### Formats
+
+Messages can either be JSON objects or FIX messages encoded as ` + "`tag=value`" + ` pairs delimited by either the SOH character or a pipe (` + "`|`" + `), where the type of each message is given by its MsgType field. The standard header fields such as BeginString, SenderCompID, TargetCompID, MsgSeqNum and SendingTime are always set by the session, and so they can be omitted.
+
+JSON objects are keyed by the names of common tags or by tag numbers, and fields are written in the order that they appear within the object. Repeating groups can be written as an array of objects keyed by the count tag of the group, where each object is an entry of the group:
+
+` + "```json" + `
+{
+  "MsgType": "V",
+  "MDReqID": "req1",
+  "SubscriptionRequestType": "1",
+  "MarketDepth": 1,
+  "NoMDEntryTypes": [ { "MDEntryType": "0" }, { "MDEntryType": "1" } ],
+  "NoRelatedSym": [ { "Symbol": "EUR/USD" } ]
+}
+` + "```" + `
+
+Arrays of values are written as repeated tags, where the values of consecutive arrays are interleaved, which allows messages consumed by the ` + "`fix`" + ` input with the ` + "`json`" + ` format to be sent as they are.`,
+		FieldSpecs: fix.FieldSpecs(),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Orders from Kafka",
+				Summary: "In this example we consume orders from Kafka and send them to a broker as new order singles.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: fix_gateway
+  processors:
+    - bloblang: |
+        root.MsgType = "D"
+        root.ClOrdID = this.id
+        root.Symbol = this.symbol
+        root.Side = if this.side == "buy" { "1" } else { "2" }
+        root.OrderQty = this.quantity
+        root.OrdType = "2"
+        root.Price = this.price
+        root.TransactTime = now().format_timestamp("20060102-15:04:05.000", "UTC")
+
+output:
+  fix:
+    mode: initiator
+    address: fix.example.com:9876
+    begin_string: FIX.4.4
+    sender_comp_id: CLIENT
+    target_comp_id: BROKER
+    store_path: /var/lib/benthos/fix
+`,
+			},
+		},
+		Categories: []Category{
+			CategoryNetwork,
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FIXConfig contains configuration fields for the FIX output type.
+type FIXConfig struct {
+	fix.Config `json:",inline" yaml:",inline"`
+}
+
+// NewFIXConfig creates a new FIXConfig with default values.
+func NewFIXConfig() FIXConfig {
+	return FIXConfig{
+		Config: fix.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type fixWriter struct {
+	conf     FIXConfig
+	log      log.Modular
+	endpoint *fix.Endpoint
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+}
+
+func newFIXWriter(conf FIXConfig, log log.Modular) (*fixWriter, error) {
+	endpoint, err := conf.Endpoint(true)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.OnAcceptError = func(err error) {
+		log.Warnf("Failed to accept FIX session: %v\n", err)
+	}
+	return &fixWriter{
+		conf:       conf,
+		log:        log,
+		endpoint:   endpoint,
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+// toFIXMessage converts a message payload in either the JSON or tag=value
+// format into a FIX message.
+func toFIXMessage(b []byte) (*fix.Message, error) {
+	b = bytes.TrimSpace(b)
+	if bytes.HasPrefix(b, []byte("{")) {
+		return fix.FromJSON(b)
+	}
+	delim := byte(fix.SOH)
+	if bytes.IndexByte(b, fix.SOH) == -1 {
+		delim = '|'
+	}
+	return fix.ParseFields(b, delim)
+}
+
+// ConnectWithContext establishes a session as the initiator, or begins
+// listening for sessions as the acceptor.
+func (f *fixWriter) ConnectWithContext(ctx context.Context) error {
+	if err := f.endpoint.Connect(ctx); err != nil {
+		return err
+	}
+	if f.conf.Mode == "acceptor" {
+		f.log.Infof("Accepting FIX sessions at: %v\n", f.endpoint.Addr())
+	} else {
+		f.log.Infof("Established FIX session with: %v\n", f.conf.Address)
+	}
+	return nil
+}
+
+// WriteWithContext attempts to send each message of a batch over the session.
+func (f *fixWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	session, err := f.endpoint.Session(ctx)
+	if err != nil {
+		if errors.Is(err, fix.ErrNoSession) {
+			return types.ErrNotConnected
+		}
+		return err
+	}
+
+	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+		fixMsg, err := toFIXMessage(p.Get())
+		if err != nil {
+			return fmt.Errorf("failed to convert message: %w", err)
+		}
+		if err = session.Send(fixMsg); err != nil {
+			if sessErr := session.Err(); sessErr != nil {
+				f.log.Errorf("FIX session ended: %v\n", sessErr)
+				return types.ErrNotConnected
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (f *fixWriter) CloseAsync() {
+	f.closeOnce.Do(func() {
+		go func() {
+			f.endpoint.Close(time.Second * 5)
+			close(f.closedChan)
+		}()
+	})
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (f *fixWriter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
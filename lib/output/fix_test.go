@@ -0,0 +1,81 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/fix"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIXInitiator(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	broker := fix.NewAcceptor("127.0.0.1:0", nil, time.Second*5, fix.SessionConfig{
+		BeginString:  "FIX.4.2",
+		SenderCompID: "BROKER",
+		TargetCompID: "CLIENT",
+	})
+	require.NoError(t, broker.Connect(ctx))
+	defer broker.Close(time.Second)
+
+	conf := NewFIXConfig()
+	conf.Address = broker.Addr().String()
+	conf.BeginString = "FIX.4.2"
+	conf.SenderCompID = "CLIENT"
+	conf.TargetCompID = "BROKER"
+	conf.LogonFields = map[string]string{"Username": "foo"}
+
+	w, err := newFIXWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, types.ErrNotConnected, w.WriteWithContext(ctx, message.New([][]byte{[]byte(`{"MsgType":"D"}`)})))
+
+	require.NoError(t, w.ConnectWithContext(ctx))
+	t.Cleanup(func() {
+		w.CloseAsync()
+		require.NoError(t, w.WaitForClose(time.Second*10))
+	})
+
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"MsgType":"D","ClOrdID":"O1","Symbol":"AAPL","Side":1,"NoPartyIDs":[{"PartyRole":3,"PartyID":"P1"}]}`),
+		[]byte("35=F|41=O1|11=O2|55=AAPL|54=1"),
+		[]byte("8=FIX.4.2\x019=5\x0135=D\x0111=O3\x0110=000\x01"),
+	})))
+
+	session, err := broker.Session(ctx)
+	require.NoError(t, err)
+
+	var received []string
+	for i := 0; i < 3; i++ {
+		msg, commit, err := session.Receive(ctx)
+		require.NoError(t, err)
+		commit()
+
+		var body []string
+		for _, f := range msg.Fields {
+			switch f.Tag {
+			case fix.TagBeginString, fix.TagBodyLength, fix.TagCheckSum, fix.TagSendingTime:
+				continue
+			}
+			body = append(body, fix.TagName(f.Tag)+"="+f.Value)
+		}
+		received = append(received, fmt.Sprintf("%v", body))
+	}
+
+	assert.Equal(t, []string{
+		"[MsgType=D SenderCompID=CLIENT TargetCompID=BROKER MsgSeqNum=2 ClOrdID=O1 Symbol=AAPL Side=1 NoPartyIDs=1 PartyID=P1 PartyRole=3]",
+		"[MsgType=F SenderCompID=CLIENT TargetCompID=BROKER MsgSeqNum=3 OrigClOrdID=O1 ClOrdID=O2 Symbol=AAPL Side=1]",
+		"[MsgType=D SenderCompID=CLIENT TargetCompID=BROKER MsgSeqNum=4 ClOrdID=O3]",
+	}, received)
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{[]byte(`{"Symbol":"AAPL"}`)}))
+	require.EqualError(t, err, "message does not have a MsgType field")
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+)
+
+// GCPBigQueryCSVConfig contains configuration fields for loading CSV data with
+// the GCP BigQuery output type.
+type GCPBigQueryCSVConfig struct {
+	FieldDelimiter string `json:"field_delimiter" yaml:"field_delimiter"`
+}
+
+// NewGCPBigQueryCSVConfig creates a new CSV config with default values.
+func NewGCPBigQueryCSVConfig() GCPBigQueryCSVConfig {
+	return GCPBigQueryCSVConfig{
+		FieldDelimiter: ",",
+	}
+}
+
+// GCPBigQueryConfig contains configuration fields for the GCP BigQuery output
+// type.
+type GCPBigQueryConfig struct {
+	Project             string               `json:"project" yaml:"project"`
+	Dataset             string               `json:"dataset" yaml:"dataset"`
+	Table               string               `json:"table" yaml:"table"`
+	Method              string               `json:"method" yaml:"method"`
+	Format              string               `json:"format" yaml:"format"`
+	AutoDetect          bool                 `json:"auto_detect" yaml:"auto_detect"`
+	IgnoreUnknownValues bool                 `json:"ignore_unknown_values" yaml:"ignore_unknown_values"`
+	CreateDisposition   string               `json:"create_disposition" yaml:"create_disposition"`
+	WriteDisposition    string               `json:"write_disposition" yaml:"write_disposition"`
+	CSV                 GCPBigQueryCSVConfig `json:"csv" yaml:"csv"`
+	MaxInFlight         int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching            batch.PolicyConfig   `json:"batching" yaml:"batching"`
+}
+
+// NewGCPBigQueryConfig creates a new Config with default values.
+func NewGCPBigQueryConfig() GCPBigQueryConfig {
+	return GCPBigQueryConfig{
+		Project:             "",
+		Dataset:             "",
+		Table:               "",
+		Method:              "streaming",
+		Format:              "json",
+		AutoDetect:          false,
+		IgnoreUnknownValues: false,
+		CreateDisposition:   "CREATE_IF_NEEDED",
+		WriteDisposition:    "WRITE_APPEND",
+		CSV:                 NewGCPBigQueryCSVConfig(),
+		MaxInFlight:         1,
+		Batching:            batch.NewPolicyConfig(),
+	}
+}
@@ -19,16 +19,74 @@ Sends message parts as files to a HDFS directory.`,
 		Description: `
 Each file is written with the path specified with the 'path' field, in order to
 have a different path for each object you should use function interpolations
-described [here](/docs/configuration/interpolation#bloblang-queries).`,
+described [here](/docs/configuration/interpolation#bloblang-queries). The
+directory can also be interpolated, which allows files to be organised by the
+time of the events that they contain.
+
+### Append and Atomic Writes
+
+By default a file is created for each message, and writes fail if the file
+already exists. When ` + "`append`" + ` is enabled messages are instead appended
+to the file at their path when it exists.
+
+When ` + "`atomic_rename`" + ` is enabled each file is written to a hidden
+temporary file within the same directory, which is renamed to its final path
+once fully written. This prevents tools from reading partially written files.
+
+### Kerberos
+
+When ` + "`kerberos.enabled`" + ` is true the client authenticates with the
+cluster using Kerberos, with credentials from either a keytab file or a
+password. In this case the HDFS user is determined by the Kerberos principal and
+the field ` + "`user`" + ` is ignored.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Event Time Directories",
+				Summary: "In this example messages are appended to an hourly file within a directory for each day, determined by the timestamp of each event, on a cluster secured with Kerberos.",
+				Config: `
+output:
+  hdfs:
+    hosts: [ namenode:8020 ]
+    kerberos:
+      enabled: true
+      realm: EXAMPLE.COM
+      username: benthos
+      keytab_file: /etc/security/keytabs/benthos.keytab
+    directory: /data/events/${! json("timestamp").format_timestamp("2006-01-02") }
+    path: ${! json("timestamp").format_timestamp("15") }.jsonl
+    append: true
+    batching:
+      count: 1000
+      period: 10s
+      processors:
+        - archive:
+            format: lines
+`,
+			},
+		},
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("hosts", "A list of hosts to connect to.", "localhost:9000").Array(),
 			docs.FieldCommon("user", "A user identifier."),
-			docs.FieldCommon("directory", "A directory to store message files within. If the directory does not exist it will be created."),
+			docs.FieldAdvanced("kerberos", "Configure Kerberos authentication with the cluster.").WithChildren(
+				docs.FieldCommon("enabled", "Whether to authenticate using Kerberos."),
+				docs.FieldCommon("config_file", "The path of the Kerberos configuration file."),
+				docs.FieldCommon("realm", "The realm of the principal.", "EXAMPLE.COM"),
+				docs.FieldCommon("username", "The username of the principal."),
+				docs.FieldCommon("keytab_file", "The path of a keytab file containing the credentials of the principal."),
+				docs.FieldCommon("password", "The password of the principal, used when a keytab file is not specified."),
+				docs.FieldAdvanced("service_principal_name", "The service principal name of the name nodes, where `_HOST` is replaced with the host of each name node."),
+			).AtVersion("3.47.0"),
+			docs.FieldCommon(
+				"directory", "A directory to store message files within. If the directory does not exist it will be created.",
+				`/data/events/${! timestamp("2006-01-02") }`,
+			).IsInterpolated(),
 			docs.FieldCommon(
 				"path", "The path to upload messages as, interpolation functions should be used in order to generate unique file paths.",
 				`${!count("files")}-${!timestamp_unix_nano()}.txt`,
 			).IsInterpolated(),
+			docs.FieldAdvanced("append", "Whether to append messages to the file at their path when it already exists.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("atomic_rename", "Whether to write each file to a temporary path and rename it to its final path once written. This cannot be combined with `append`.").AtVersion("3.47.0"),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			batch.FieldSpec(),
 		},
@@ -322,6 +322,10 @@ func (h *HTTPServer) streamHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Begin the response immediately so that the stream isn't subject to
+	// request timeouts whilst waiting for the first message.
+	flusher.Flush()
+
 	for atomic.LoadInt32(&h.running) == 1 {
 		var ts types.Transaction
 		var open bool
@@ -35,7 +35,36 @@ When strict ordering is required for messages written to topic partitions it is
 
 You must also ensure that failed batches are never rerouted back to the same output. This can be done by setting the field ` + "`max_retries` to `0` and `backoff.max_elapsed_time`" + ` to empty, which will apply back pressure indefinitely until the batch is sent successfully.
 
-However, this also means that manual intervention will eventually be required in cases where the batch cannot be sent due to configuration problems such as an incorrect ` + "`max_msg_bytes`" + ` estimate. A less strict but automated alternative would be to route failed batches to a dead letter queue using a ` + "[`try` broker](/docs/components/outputs/try)" + `, but this would allow subsequent batches to be delivered in the meantime whilst those failed batches are dealt with.`,
+However, this also means that manual intervention will eventually be required in cases where the batch cannot be sent due to configuration problems such as an incorrect ` + "`max_msg_bytes`" + ` estimate. A less strict but automated alternative would be to route failed batches to a dead letter queue using a ` + "[`try` broker](/docs/components/outputs/try)" + `, but this would allow subsequent batches to be delivered in the meantime whilst those failed batches are dealt with.
+
+### Exactly-Once Delivery
+
+When ` + "`transaction.enabled`" + ` is set each batch is written within a Kafka transaction, and failed transactions are aborted and retried in their entirety. Setting ` + "`transaction.consumer_group`" + ` also commits the offsets of the consumed messages within the same transaction, which allows pipelines that read from and write to Kafka to process each message exactly once:
+
+` + "```yaml" + `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: orders_enricher
+    target_version: 2.1.0
+
+pipeline:
+  processors:
+    - bloblang: root = this.merge({"enriched": true})
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: enriched_orders
+    target_version: 2.1.0
+    transaction:
+      enabled: true
+      id: orders_enricher_0
+      consumer_group: orders_enricher
+` + "```" + `
+
+Downstream consumers must read with an isolation level of ` + "`read_committed`" + ` in order to ignore messages of aborted transactions. Transactions are written sequentially and therefore ` + "`max_in_flight`" + ` must be ` + "`1`" + `.`,
 		Async:   true,
 		Batches: true,
 		FieldSpecs: append(docs.FieldSpecs{
@@ -56,6 +85,19 @@ However, this also means that manual intervention will eventually be required in
 			docs.FieldAdvanced("max_msg_bytes", "The maximum size in bytes of messages sent to the target topic."),
 			docs.FieldAdvanced("timeout", "The maximum period of time to wait for message sends before abandoning the request and retrying."),
 			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use."),
+			docs.FieldAdvanced("create_topics", "Create topics that do not yet exist before writing messages to them, which is useful when the `topic` is interpolated per message, such as a topic per tenant. Topics are checked once per topic per connection, and topics that were created by another client in the meantime are ignored.").WithChildren(
+				docs.FieldCommon("enabled", "Whether missing topics should be created."),
+				docs.FieldCommon("partitions", "The number of partitions of created topics. Set to `-1` in order to use the broker default, which requires Kafka 2.4 or later."),
+				docs.FieldCommon("replication_factor", "The replication factor of created topics. Set to `-1` in order to use the broker default, which requires Kafka 2.4 or later."),
+				docs.FieldCommon("configs", "A map of topic level configs to set on created topics.", map[string]string{"retention.ms": "604800000", "cleanup.policy": "compact"}).Map(),
+			).AtVersion("3.47.0"),
+			docs.FieldAdvanced("idempotent_write", "Enable the idempotent producer, which prevents duplicate messages from being written to a partition when sends are retried. This requires a `target_version` of at least `0.11.0` and overrides `ack_replicas`, as acknowledgements from all replicas are required.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("transaction", "Write each batch of messages within a Kafka transaction, which implies `idempotent_write`. Consumers reading with an `isolation.level` of `read_committed` only observe the messages of committed transactions.").WithChildren(
+				docs.FieldCommon("enabled", "Whether batches should be written within transactions."),
+				docs.FieldCommon("id", "A transactional ID that identifies this producer across restarts, which must be unique to each instance of the output in order for stale producers to be fenced off.", "benthos-orders-0"),
+				docs.FieldCommon("timeout", "The maximum period of time a transaction may remain open before it is aborted by the broker."),
+				docs.FieldCommon("consumer_group", "An optional consumer group to commit offsets for within each transaction. Offsets are obtained from the `kafka_topic`, `kafka_partition` and `kafka_offset` metadata fields added by the `kafka` input, which should consume with the same consumer group."),
+			).AtVersion("3.47.0"),
 			docs.FieldAdvanced("retry_as_batch", "When enabled forces an entire batch of messages to be retried if any individual message fails on a send, otherwise only the individual messages that failed are retried. Disabling this helps to reduce message duplicates during intermittent errors, but also makes it impossible to guarantee strict ordering of messages."),
 			batch.FieldSpec(),
 		}, retries.FieldSpecs()...),
@@ -0,0 +1,342 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/kubernetes"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeKubernetesApply] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			k, err := newKubernetesApplyWriter(conf.KubernetesApply, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeKubernetesApply, conf.KubernetesApply.MaxInFlight, k, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.KubernetesApply.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Applies manifests to a Kubernetes cluster with [server-side apply](https://kubernetes.io/docs/reference/using-api/server-side-apply/).`,
+		Description: `
+Each message is parsed as a YAML or JSON manifest, which may contain multiple documents separated by ` + "`---`" + `, and every object within it is applied to the cluster. Any kind of resource served by the cluster can be applied, including custom resources. Namespaced objects that don't specify a namespace are applied within the ` + "`namespace`" + ` field when set, otherwise the namespace of the kubeconfig context, or ` + "`default`" + `.
+
+The fields of each object are owned by the ` + "`field_manager`" + `, and applying an object that sets fields owned by another manager fails with a conflict unless ` + "`force_conflicts`" + ` is set. When ` + "`dry_run`" + ` is set objects are validated and admitted by the cluster without being persisted, which is useful for checking manifests before applying them for real.
+
+Messages are applied individually, and when a message fails to apply only that message of a batch is considered failed.
+
+### Credentials
+
+When running within a pod the service account of the pod is used, and otherwise a context of a [kubeconfig file](https://kubernetes.io/docs/concepts/configuration/organize-cluster-access-kubeconfig/) is used, by default the current context of the same file that ` + "`kubectl`" + ` uses. Credentials of kubeconfig files provided by exec or auth provider plugins are not supported.
+
+The account requires the ` + "`get`" + `, ` + "`create`" + ` and ` + "`patch`" + ` verbs on each kind of resource applied.
+
+### Responses
+
+When ` + "`propagate_response`" + ` is set the objects returned by the cluster after each apply, including their status, replace the contents of the message and are propagated back to the input when it supports [synchronous responses](/docs/guides/sync_responses). A message with a single object is replaced with that object, and a message with several objects is replaced with an array of them.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "GitOps Webhook",
+				Summary: "In this example manifests are posted to an HTTP endpoint, which responds with the resulting objects of a dry run apply.",
+				Config: `
+input:
+  http_server:
+    path: /apply
+    sync_response:
+      headers:
+        Content-Type: application/json
+
+output:
+  kubernetes_apply:
+    field_manager: gitops
+    namespace: staging
+    dry_run: true
+    propagate_response: true
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("kubeconfig", "The path of a kubeconfig file to obtain credentials from. When empty the service account of the pod is used when running within a cluster, and otherwise the kubeconfig file that `kubectl` uses.", "/etc/benthos/kubeconfig"),
+			docs.FieldAdvanced("context", "The context of the kubeconfig file to use, or the current context when empty."),
+			docs.FieldCommon("field_manager", "The name of the manager that owns the fields applied by this output."),
+			docs.FieldAdvanced("force_conflicts", "Whether to take ownership of fields owned by other managers rather than failing with a conflict."),
+			docs.FieldCommon("namespace", "The namespace to apply namespaced objects within when they don't specify one.", "shop", `${! meta("tenant") }`).IsInterpolated(),
+			docs.FieldCommon("dry_run", "Whether to validate objects with the cluster without persisting them."),
+			docs.FieldAdvanced("propagate_response", "Whether the objects returned by the cluster should be propagated back to the input as a response."),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// KubernetesApplyConfig contains configuration fields for the
+// kubernetes_apply output.
+type KubernetesApplyConfig struct {
+	Kubeconfig        string             `json:"kubeconfig" yaml:"kubeconfig"`
+	Context           string             `json:"context" yaml:"context"`
+	FieldManager      string             `json:"field_manager" yaml:"field_manager"`
+	ForceConflicts    bool               `json:"force_conflicts" yaml:"force_conflicts"`
+	Namespace         string             `json:"namespace" yaml:"namespace"`
+	DryRun            bool               `json:"dry_run" yaml:"dry_run"`
+	PropagateResponse bool               `json:"propagate_response" yaml:"propagate_response"`
+	MaxInFlight       int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching          batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewKubernetesApplyConfig returns a KubernetesApplyConfig with default
+// values.
+func NewKubernetesApplyConfig() KubernetesApplyConfig {
+	return KubernetesApplyConfig{
+		Kubeconfig:        "",
+		Context:           "",
+		FieldManager:      "benthos",
+		ForceConflicts:    false,
+		Namespace:         "",
+		DryRun:            false,
+		PropagateResponse: false,
+		MaxInFlight:       1,
+		Batching:          batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type kubernetesApplyWriter struct {
+	log  log.Modular
+	conf KubernetesApplyConfig
+
+	namespace *field.Expression
+
+	mut              sync.Mutex
+	client           *kubernetes.Client
+	defaultNamespace string
+	resources        map[string]kubernetes.Resource
+}
+
+func newKubernetesApplyWriter(conf KubernetesApplyConfig, log log.Modular) (*kubernetesApplyWriter, error) {
+	if conf.FieldManager == "" {
+		return nil, errors.New("a field_manager must be specified")
+	}
+	k := &kubernetesApplyWriter{
+		log:       log,
+		conf:      conf,
+		resources: map[string]kubernetes.Resource{},
+	}
+	if conf.Namespace != "" {
+		var err error
+		if k.namespace, err = bloblang.NewField(conf.Namespace); err != nil {
+			return nil, fmt.Errorf("failed to parse namespace expression: %v", err)
+		}
+	}
+	return k, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext loads the credentials of the cluster, requests are made
+// per message and so no connection is established.
+func (k *kubernetesApplyWriter) ConnectWithContext(ctx context.Context) error {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+	if k.client != nil {
+		return nil
+	}
+
+	clientConf, err := kubernetes.Resolve(k.conf.Kubeconfig, k.conf.Context)
+	if err != nil {
+		return err
+	}
+	k.client = kubernetes.NewClient(clientConf)
+	if k.defaultNamespace = clientConf.Namespace; k.defaultNamespace == "" {
+		k.defaultNamespace = "default"
+	}
+	k.log.Infof("Applying manifests to Kubernetes cluster at: %v\n", clientConf.Server)
+	return nil
+}
+
+func (k *kubernetesApplyWriter) resource(ctx context.Context, client *kubernetes.Client, apiVersion, kind string) (kubernetes.Resource, error) {
+	key := apiVersion + ":" + kind
+
+	k.mut.Lock()
+	res, exists := k.resources[key]
+	k.mut.Unlock()
+	if exists {
+		return res, nil
+	}
+
+	res, err := client.Discover(ctx, apiVersion, kind)
+	if err != nil {
+		return res, err
+	}
+
+	k.mut.Lock()
+	k.resources[key] = res
+	k.mut.Unlock()
+	return res, nil
+}
+
+// kubeManifestObjects parses the objects of a YAML or JSON manifest, which may
+// contain multiple documents.
+func kubeManifestObjects(manifest []byte) ([]map[string]interface{}, error) {
+	var objs []map[string]interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		// Documents that are empty or only contain comments are skipped.
+		if len(obj) > 0 {
+			objs = append(objs, obj)
+		}
+	}
+	if len(objs) == 0 {
+		return nil, errors.New("manifest does not contain any objects")
+	}
+	return objs, nil
+}
+
+func (k *kubernetesApplyWriter) apply(ctx context.Context, client *kubernetes.Client, namespace string, obj map[string]interface{}) (json.RawMessage, error) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return nil, errors.New("object must specify an apiVersion and kind")
+	}
+	meta, _ := obj["metadata"].(map[string]interface{})
+	name, _ := meta["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("%v object must specify a metadata.name", kind)
+	}
+
+	res, err := k.resource(ctx, client, apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+	if res.Namespaced {
+		if ns, _ := meta["namespace"].(string); ns != "" {
+			namespace = ns
+		} else {
+			meta["namespace"] = namespace
+		}
+	}
+
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %v %v: %w", kind, name, err)
+	}
+	applied, err := client.Apply(ctx, res, namespace, name, body, kubernetes.ApplyOptions{
+		FieldManager: k.conf.FieldManager,
+		Force:        k.conf.ForceConflicts,
+		DryRun:       k.conf.DryRun,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply %v %v: %w", kind, name, err)
+	}
+	return applied, nil
+}
+
+func (k *kubernetesApplyWriter) applyManifest(ctx context.Context, client *kubernetes.Client, namespace string, manifest []byte) ([]byte, error) {
+	objs, err := kubeManifestObjects(manifest)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]json.RawMessage, 0, len(objs))
+	for _, obj := range objs {
+		applied, err := k.apply(ctx, client, namespace, obj)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, applied)
+	}
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return json.Marshal(results)
+}
+
+// WriteWithContext attempts to apply the manifests of a batch to the cluster.
+func (k *kubernetesApplyWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	k.mut.Lock()
+	client, defaultNamespace := k.client, k.defaultNamespace
+	k.mut.Unlock()
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var batchErr *batchInternal.Error
+	results := make([][]byte, msg.Len())
+	_ = msg.Iter(func(i int, p types.Part) error {
+		namespace := defaultNamespace
+		if k.namespace != nil {
+			if ns := k.namespace.String(i, msg); ns != "" {
+				namespace = ns
+			}
+		}
+		var err error
+		if results[i], err = k.applyManifest(ctx, client, namespace, p.Get()); err != nil {
+			k.log.Debugf("Failed to apply manifest: %v\n", err)
+			if batchErr == nil {
+				batchErr = batchInternal.NewError(msg, err)
+			}
+			batchErr.Failed(i, err)
+		}
+		return nil
+	})
+	if batchErr != nil {
+		return batchErr
+	}
+
+	if k.conf.PropagateResponse {
+		msgCopy := msg.Copy()
+		_ = msgCopy.Iter(func(i int, p types.Part) error {
+			p.Set(results[i])
+			return nil
+		})
+		roundtrip.SetAsResponse(msgCopy)
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (k *kubernetesApplyWriter) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (k *kubernetesApplyWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
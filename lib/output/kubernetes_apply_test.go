@@ -0,0 +1,174 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubernetesApply(t *testing.T) {
+	var applyMut sync.Mutex
+	applied := map[string]map[string]interface{}{}
+	var discoveries int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"resources":[
+			{"name":"configmaps","kind":"ConfigMap","namespaced":true},
+			{"name":"namespaces","kind":"Namespace","namespaced":false}
+		]}`))
+	})
+	mux.HandleFunc("/apis/apps/v1", func(w http.ResponseWriter, r *http.Request) {
+		applyMut.Lock()
+		discoveries++
+		applyMut.Unlock()
+		w.Write([]byte(`{"resources":[{"name":"deployments","kind":"Deployment","namespaced":true}]}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "Bearer foo", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/apply-patch+yaml", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gitops", r.URL.Query().Get("fieldManager"))
+		assert.Equal(t, "All", r.URL.Query().Get("dryRun"))
+		assert.Equal(t, "", r.URL.Query().Get("force"))
+
+		var obj map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&obj))
+		if obj["metadata"].(map[string]interface{})["name"] == "bad" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"kind":"Status","code":422,"reason":"Invalid","message":"spec.replicas: Invalid value"}`))
+			return
+		}
+
+		applyMut.Lock()
+		applied[r.URL.Path] = obj
+		applyMut.Unlock()
+
+		obj["status"] = map[string]interface{}{"observedGeneration": 1}
+		json.NewEncoder(w).Encode(obj)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, ioutil.WriteFile(kubeconfig, []byte(fmt.Sprintf(`
+current-context: test
+clusters:
+  - name: test
+    cluster:
+      server: %v
+users:
+  - name: test
+    user:
+      token: foo
+contexts:
+  - name: test
+    context:
+      cluster: test
+      user: test
+      namespace: shop
+`, srv.URL)), 0o600))
+
+	conf := NewKubernetesApplyConfig()
+	conf.Kubeconfig = kubeconfig
+	conf.FieldManager = "gitops"
+	conf.Namespace = `${! meta("tenant") }`
+	conf.DryRun = true
+	conf.PropagateResponse = true
+
+	k, err := newKubernetesApplyWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	assert.Equal(t, types.ErrNotConnected, k.WriteWithContext(ctx, message.New([][]byte{[]byte(`{}`)})))
+	require.NoError(t, k.ConnectWithContext(ctx))
+
+	msg := message.New([][]byte{
+		[]byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant-a
+---
+# Comments only
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+`),
+		[]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"foo":"bar"}}`),
+		[]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"api","namespace":"other"}}`),
+	})
+	msg.Get(0).Metadata().Set("tenant", "tenant-a")
+	resultStore := roundtrip.NewResultStore()
+	roundtrip.AddResultStore(msg, resultStore)
+
+	require.NoError(t, k.WriteWithContext(ctx, msg))
+
+	applyMut.Lock()
+	assert.Equal(t, 1, discoveries)
+	assert.Len(t, applied, 4)
+	assert.Equal(t, map[string]interface{}{"name": "tenant-a"}, applied["/api/v1/namespaces/tenant-a"]["metadata"])
+	assert.Equal(t, map[string]interface{}{"name": "web", "namespace": "tenant-a"}, applied["/apis/apps/v1/namespaces/tenant-a/deployments/web"]["metadata"])
+	assert.Equal(t, map[string]interface{}{"replicas": float64(2)}, applied["/apis/apps/v1/namespaces/tenant-a/deployments/web"]["spec"])
+	assert.Equal(t, map[string]interface{}{"name": "settings", "namespace": "shop"}, applied["/api/v1/namespaces/shop/configmaps/settings"]["metadata"])
+	assert.Contains(t, applied, "/apis/apps/v1/namespaces/other/deployments/api")
+	applyMut.Unlock()
+
+	resMsgs := resultStore.Get()
+	require.Len(t, resMsgs, 1)
+	require.Equal(t, 3, resMsgs[0].Len())
+	assert.JSONEq(t, `[
+		{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"tenant-a"},"status":{"observedGeneration":1}},
+		{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"tenant-a"},"spec":{"replicas":2},"status":{"observedGeneration":1}}
+	]`, string(resMsgs[0].Get(0).Get()))
+	assert.JSONEq(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"shop"},"data":{"foo":"bar"},"status":{"observedGeneration":1}}`, string(resMsgs[0].Get(1).Get()))
+
+	err = k.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"fine"}}`),
+		[]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"bad"}}`),
+		[]byte(`{"apiVersion":"v1","kind":"ConfigMap"}`),
+		[]byte(`# nothing`),
+	}))
+	var batchErr *batchInternal.Error
+	require.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 3, batchErr.IndexedErrors())
+
+	var failed []string
+	batchErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", i, err))
+		}
+		return true
+	})
+	assert.Equal(t, []string{
+		"1: failed to apply Deployment bad: kubernetes API responded with status 422 (Invalid): spec.replicas: Invalid value",
+		"2: ConfigMap object must specify a metadata.name",
+		"3: manifest does not contain any objects",
+	}, failed)
+}
+
+func TestKubernetesApplyBadConfig(t *testing.T) {
+	conf := NewKubernetesApplyConfig()
+	conf.FieldManager = ""
+	_, err := newKubernetesApplyWriter(conf, log.Noop())
+	assert.EqualError(t, err, "a field_manager must be specified")
+}
@@ -0,0 +1,142 @@
+package output
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// labelSpans wraps an output and traces the delivery of each message within a
+// span named by the label of the output.
+type labelSpans struct {
+	label string
+	out   Type
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// WithLabelSpans wraps an output so that the delivery of each message is traced
+// within a span named by its label, which is the parent of any spans created by
+// the output itself.
+func WithLabelSpans(label string, out Type) (Type, error) {
+	l := &labelSpans{
+		label:           label,
+		out:             out,
+		transactionsOut: make(chan types.Transaction),
+		closeChan:       make(chan struct{}),
+		closedChan:      make(chan struct{}),
+	}
+	if err := out.Consume(l.transactionsOut); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (l *labelSpans) loop() {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		close(l.transactionsOut)
+		l.out.CloseAsync()
+		for l.out.WaitForClose(time.Second) != nil {
+		}
+		close(l.closedChan)
+	}()
+
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-l.transactionsIn:
+			if !open {
+				return
+			}
+		case <-l.closeChan:
+			return
+		}
+
+		msg, spans := tracing.WithChildSpans(l.label, ts.Payload)
+		resChan := make(chan types.Response)
+		select {
+		case l.transactionsOut <- types.NewTransaction(msg, resChan):
+		case <-l.closeChan:
+			return
+		}
+
+		wg.Add(1)
+		go func(ts types.Transaction) {
+			defer wg.Done()
+			var res types.Response
+			select {
+			case res = <-resChan:
+			case <-l.closeChan:
+				return
+			}
+			for _, s := range spans {
+				s.Finish()
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-l.closeChan:
+			}
+		}(ts)
+	}
+}
+
+// Consume starts the type listening to a message channel from a producer.
+func (l *labelSpans) Consume(ts <-chan types.Transaction) error {
+	if l.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	l.transactionsIn = ts
+	go l.loop()
+	return nil
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// output. This value can be used to determine a sensible value for parent
+// outputs, but should not be relied upon as part of dispatcher logic.
+func (l *labelSpans) MaxInFlight() (int, bool) {
+	return output.GetMaxInFlight(l.out)
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (l *labelSpans) Connected() bool {
+	return l.out.Connected()
+}
+
+//------------------------------------------------------------------------------
+
+// CloseAsync triggers a closure of this object but does not block.
+func (l *labelSpans) CloseAsync() {
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+	})
+	l.out.CloseAsync()
+}
+
+// WaitForClose is a blocking call to wait until the object has finished closing
+// down and cleaning up resources.
+func (l *labelSpans) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-l.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
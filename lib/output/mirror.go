@@ -0,0 +1,316 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMirror] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			if conf.Mirror.Output == nil {
+				return nil, errors.New("cannot create a mirror output without a child output")
+			}
+			if conf.Mirror.Shadow == nil {
+				return nil, errors.New("cannot create a mirror output without a shadow output")
+			}
+			primary, err := New(*conf.Mirror.Output, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output '%v': %v", conf.Mirror.Output.Type, err)
+			}
+			sMgr, sLog, sStats := interop.LabelChild("mirror.shadow", mgr, log, stats)
+			shadow, err := New(*conf.Mirror.Shadow, sMgr, sLog, sStats)
+			if err != nil {
+				primary.CloseAsync()
+				return nil, fmt.Errorf("failed to create shadow output '%v': %v", conf.Mirror.Shadow.Type, err)
+			}
+			return newMirror(conf.Mirror, primary, shadow, log, stats)
+		}),
+		Summary: `
+Writes messages to a child output and sends a sample of copies to a shadow output without affecting the child.`,
+		Description: `
+This output is useful for testing new backends against live traffic. Messages are forwarded to the child ` + "`output`" + ` as normal and the acknowledgement of each message is determined solely by the child, a copy of each sampled message is also written to the ` + "`shadow`" + ` output in the background.
+
+The shadow output never applies back pressure to the child output. When the number of shadow writes in flight reaches ` + "`max_pending`" + ` further copies are dropped until writes complete, and failed shadow writes are logged and dropped rather than retried. The metrics of the shadow output are namespaced with ` + "`mirror.shadow`" + `, which allows them to be compared with those of the child output.`,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("output", "A child output that messages are written to and acknowledged by.").HasType(docs.FieldOutput),
+			docs.FieldCommon("shadow", "An output that copies of messages are written to.").HasType(docs.FieldOutput),
+			docs.FieldCommon("sample_ratio", "The ratio of messages, between `0` and `1`, that are copied to the shadow output.", 0.1),
+			docs.FieldAdvanced("max_pending", "The maximum number of shadow writes that can be in flight at any given time, beyond which copies are dropped."),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Shadow HTTP Traffic",
+				Summary: "In this example all requests are sent to a production endpoint, and a tenth of them are also sent to a new version of the service that we wish to test.",
+				Config: `
+output:
+  mirror:
+    sample_ratio: 0.1
+    output:
+      http_client:
+        url: http://example.com/v1/messages
+        verb: POST
+    shadow:
+      http_client:
+        url: http://staging.example.com/v2/messages
+        verb: POST
+        retries: 0
+`,
+			},
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MirrorConfig contains configuration values for the Mirror output type.
+type MirrorConfig struct {
+	Output      *Config `json:"output" yaml:"output"`
+	Shadow      *Config `json:"shadow" yaml:"shadow"`
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"`
+	MaxPending  int     `json:"max_pending" yaml:"max_pending"`
+}
+
+// NewMirrorConfig creates a new MirrorConfig with default values.
+func NewMirrorConfig() MirrorConfig {
+	return MirrorConfig{
+		Output:      nil,
+		Shadow:      nil,
+		SampleRatio: 1,
+		MaxPending:  64,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyMirrorConfig struct {
+	Output      interface{} `json:"output" yaml:"output"`
+	Shadow      interface{} `json:"shadow" yaml:"shadow"`
+	SampleRatio float64     `json:"sample_ratio" yaml:"sample_ratio"`
+	MaxPending  int         `json:"max_pending" yaml:"max_pending"`
+}
+
+func (m MirrorConfig) dummy() dummyMirrorConfig {
+	dummy := dummyMirrorConfig{
+		Output:      m.Output,
+		Shadow:      m.Shadow,
+		SampleRatio: m.SampleRatio,
+		MaxPending:  m.MaxPending,
+	}
+	if m.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if m.Shadow == nil {
+		dummy.Shadow = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints empty objects instead of nil.
+func (m MirrorConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.dummy())
+}
+
+// MarshalYAML prints empty objects instead of nil.
+func (m MirrorConfig) MarshalYAML() (interface{}, error) {
+	return m.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// mirror forwards messages to a child output and copies a sample of them to a
+// shadow output, ignoring the responses of the shadow.
+type mirror struct {
+	stats metrics.Type
+	log   log.Modular
+
+	sampleRatio float64
+	rand        *rand.Rand
+	pending     chan struct{}
+	pendingWG   sync.WaitGroup
+
+	primary Type
+	shadow  Type
+
+	transactionsIn <-chan types.Transaction
+	primaryOut     chan types.Transaction
+	shadowOut      chan types.Transaction
+
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+func newMirror(conf MirrorConfig, primary, shadow Type, log log.Modular, stats metrics.Type) (*mirror, error) {
+	if conf.SampleRatio < 0 || conf.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample_ratio must be between 0 and 1, got %v", conf.SampleRatio)
+	}
+	if conf.MaxPending < 1 {
+		return nil, fmt.Errorf("max_pending must be greater than 0, got %v", conf.MaxPending)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	return &mirror{
+		log:         log,
+		stats:       stats,
+		sampleRatio: conf.SampleRatio,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		pending:     make(chan struct{}, conf.MaxPending),
+		primary:     primary,
+		shadow:      shadow,
+		primaryOut:  make(chan types.Transaction),
+		shadowOut:   make(chan types.Transaction),
+
+		ctx:        ctx,
+		done:       done,
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mirror) sampled() bool {
+	if m.sampleRatio >= 1 {
+		return true
+	}
+	return m.rand.Float64() < m.sampleRatio
+}
+
+func (m *mirror) writeShadow(msg types.Message, mSent, mError metrics.StatCounter) {
+	defer func() {
+		<-m.pending
+		m.pendingWG.Done()
+	}()
+
+	resChan := make(chan types.Response)
+	select {
+	case m.shadowOut <- types.NewTransaction(msg, resChan):
+	case <-m.ctx.Done():
+		return
+	}
+	select {
+	case res := <-resChan:
+		if err := res.Error(); err != nil {
+			mError.Incr(1)
+			m.log.Debugf("Failed to write message to shadow output: %v\n", err)
+		} else {
+			mSent.Incr(1)
+		}
+	case <-m.ctx.Done():
+	}
+}
+
+func (m *mirror) loop() {
+	// Metrics paths
+	var (
+		mSent    = m.stats.GetCounter("mirror.sent")
+		mError   = m.stats.GetCounter("mirror.error")
+		mDropped = m.stats.GetCounter("mirror.dropped")
+	)
+
+	defer func() {
+		close(m.primaryOut)
+		m.pendingWG.Wait()
+		close(m.shadowOut)
+
+		m.primary.CloseAsync()
+		m.shadow.CloseAsync()
+		for _, o := range []Type{m.primary, m.shadow} {
+			err := o.WaitForClose(time.Second)
+			for ; err != nil; err = o.WaitForClose(time.Second) {
+			}
+		}
+		close(m.closedChan)
+	}()
+
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-m.transactionsIn:
+			if !open {
+				return
+			}
+		case <-m.ctx.Done():
+			return
+		}
+
+		if m.sampled() {
+			select {
+			case m.pending <- struct{}{}:
+				m.pendingWG.Add(1)
+				go m.writeShadow(ts.Payload.Copy(), mSent, mError)
+			default:
+				mDropped.Incr(1)
+			}
+		}
+
+		select {
+		case m.primaryOut <- ts:
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (m *mirror) Consume(ts <-chan types.Transaction) error {
+	if m.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := m.primary.Consume(m.primaryOut); err != nil {
+		return err
+	}
+	if err := m.shadow.Consume(m.shadowOut); err != nil {
+		return err
+	}
+	m.transactionsIn = ts
+	go m.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether the child output is currently
+// connected to its target.
+func (m *mirror) Connected() bool {
+	return m.primary.Connected()
+}
+
+func (m *mirror) MaxInFlight() (int, bool) {
+	return output.GetMaxInFlight(m.primary)
+}
+
+// CloseAsync shuts down the output and stops processing requests.
+func (m *mirror) CloseAsync() {
+	m.done()
+}
+
+// WaitForClose blocks until the output has closed down.
+func (m *mirror) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-m.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
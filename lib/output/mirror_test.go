@@ -0,0 +1,154 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mirrorHarness(t *testing.T, conf MirrorConfig) (chan types.Transaction, *mockOutput, *mockOutput) {
+	t.Helper()
+
+	primary, shadow := &mockOutput{}, &mockOutput{}
+	m, err := newMirror(conf, primary, shadow, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, m.Consume(tChan))
+	t.Cleanup(func() {
+		close(tChan)
+		m.CloseAsync()
+		assert.NoError(t, m.WaitForClose(time.Second*5))
+	})
+	return tChan, primary, shadow
+}
+
+// sendMirror sends a message with a buffered response channel, as the child
+// output responds directly rather than via the mirror.
+func sendMirror(t *testing.T, tChan chan types.Transaction, content string) chan types.Response {
+	t.Helper()
+	resChan := make(chan types.Response, 1)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return resChan
+}
+
+func readMirrorChild(t *testing.T, child *mockOutput, exp string, res types.Response) {
+	t.Helper()
+	select {
+	case ts := <-child.ts:
+		assert.Equal(t, exp, string(ts.Payload.Get(0).Get()))
+		select {
+		case ts.ResponseChan <- res:
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+}
+
+func TestMirrorOutput(t *testing.T) {
+	tChan, primary, shadow := mirrorHarness(t, NewMirrorConfig())
+
+	resChan := sendMirror(t, tChan, "foo")
+	readMirrorChild(t, shadow, "foo", response.NewError(errors.New("shadow failed")))
+	readMirrorChild(t, primary, "foo", response.NewAck())
+	assert.NoError(t, readFaultInjectionRes(t, resChan))
+
+	resChan = sendMirror(t, tChan, "bar")
+	readMirrorChild(t, primary, "bar", response.NewError(errors.New("primary failed")))
+	readMirrorChild(t, shadow, "bar", response.NewAck())
+	assert.EqualError(t, readFaultInjectionRes(t, resChan), "primary failed")
+}
+
+func TestMirrorOutputNoBackPressure(t *testing.T) {
+	conf := NewMirrorConfig()
+	conf.MaxPending = 1
+	tChan, primary, shadow := mirrorHarness(t, conf)
+
+	// The shadow output is not read from, and therefore only the first copy
+	// is pending and the rest are dropped.
+	for _, content := range []string{"foo", "bar", "baz"} {
+		resChan := sendMirror(t, tChan, content)
+		readMirrorChild(t, primary, content, response.NewAck())
+		assert.NoError(t, readFaultInjectionRes(t, resChan))
+	}
+
+	readMirrorChild(t, shadow, "foo", response.NewAck())
+	select {
+	case ts := <-shadow.ts:
+		t.Errorf("unexpected shadow message: %s", ts.Payload.Get(0).Get())
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestMirrorOutputNoSamples(t *testing.T) {
+	conf := NewMirrorConfig()
+	conf.SampleRatio = 0
+	tChan, primary, shadow := mirrorHarness(t, conf)
+
+	resChan := sendMirror(t, tChan, "foo")
+	readMirrorChild(t, primary, "foo", response.NewAck())
+	assert.NoError(t, readFaultInjectionRes(t, resChan))
+
+	select {
+	case ts := <-shadow.ts:
+		t.Errorf("unexpected shadow message: %s", ts.Payload.Get(0).Get())
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestMirrorOutputConfigErrors(t *testing.T) {
+	conf := NewMirrorConfig()
+	conf.SampleRatio = 1.5
+	_, err := newMirror(conf, &mockOutput{}, &mockOutput{}, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "sample_ratio must be between 0 and 1, got 1.5")
+
+	conf = NewMirrorConfig()
+	conf.MaxPending = 0
+	_, err = newMirror(conf, &mockOutput{}, &mockOutput{}, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "max_pending must be greater than 0, got 0")
+
+	outConf := NewConfig()
+	outConf.Type = TypeMirror
+	_, err = New(outConf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "without a child output")
+}
+
+func TestMirrorOutputMessageCopied(t *testing.T) {
+	tChan, primary, shadow := mirrorHarness(t, NewMirrorConfig())
+
+	resChan := sendMirror(t, tChan, "foo")
+
+	var shadowTs types.Transaction
+	select {
+	case shadowTs = <-shadow.ts:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case ts := <-primary.ts:
+		ts.Payload.Get(0).Set([]byte("changed"))
+		ts.ResponseChan <- response.NewAck()
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	assert.NoError(t, readFaultInjectionRes(t, resChan))
+
+	assert.Equal(t, "foo", string(shadowTs.Payload.Get(0).Get()))
+	shadowTs.ResponseChan <- response.NewAck()
+}
@@ -16,6 +16,8 @@ type MongoDBConfig struct {
 	FilterMap   string `json:"filter_map" yaml:"filter_map"`
 	DocumentMap string `json:"document_map" yaml:"document_map"`
 	HintMap     string `json:"hint_map" yaml:"hint_map"`
+	Upsert      bool   `json:"upsert" yaml:"upsert"`
+	Ordered     bool   `json:"ordered" yaml:"ordered"`
 
 	// DeleteEmptyValue bool `json:"delete_empty_value" yaml:"delete_empty_value"`
 	MaxInFlight int                `json:"max_in_flight" yaml:"max_in_flight"`
@@ -34,6 +36,7 @@ func NewMongoDBConfig() MongoDBConfig {
 	return MongoDBConfig{
 		MongoConfig:  client.NewConfig(),
 		Operation:    "update-one",
+		Ordered:      true,
 		MaxInFlight:  1,
 		RetryConfig:  rConf,
 		Batching:     batch.NewPolicyConfig(),
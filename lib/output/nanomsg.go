@@ -6,6 +6,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 )
 
 //------------------------------------------------------------------------------
@@ -16,13 +17,24 @@ func init() {
 		Summary: `
 Send messages over a Nanomsg socket.`,
 		Description: `
-Currently only PUSH and PUB sockets are supported.`,
+The sockets are compatible with both nanomsg and its successor nng. PUSH and PUB sockets send messages to PULL and SUB peers respectively.
+
+### Request/Reply and Surveys
+
+REQ sockets send each message as a request to a REP peer and wait for a reply for up to ` + "`poll_timeout`" + `, and SURVEYOR sockets send each message as a survey to RESPONDENT peers and collect their responses until ` + "`survey_timeout`" + ` elapses. When ` + "`propagate_response`" + ` is set the replies are [propagated back](/docs/guides/sync_responses) to the input, where the responses to a survey form a batch.
+
+### TLS
+
+URLs using the ` + "`tls+tcp`" + ` or ` + "`wss`" + ` transports, such as ` + "`tls+tcp://localhost:5556`" + `, are secured with the settings within the field ` + "`tls`" + `. When binding, the certificates listed in ` + "`tls.client_certs`" + ` are presented to peers as the server certificates.`,
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"tcp://localhost:5556"}).Array(),
 			docs.FieldCommon("bind", "Whether the URLs listed should be bind (otherwise they are connected to)."),
-			docs.FieldCommon("socket_type", "The socket type to send with.").HasOptions("PUSH", "PUB"),
-			docs.FieldCommon("poll_timeout", "The maximum period of time to wait for a message to send before the request is abandoned and reattempted."),
+			docs.FieldCommon("socket_type", "The socket type to send with.").HasOptions("PUSH", "PUB", "REQ", "SURVEYOR"),
+			btls.FieldSpec().AtVersion("3.47.0"),
+			docs.FieldCommon("poll_timeout", "The maximum period of time to wait for a message to send, or for the reply to a request, before the request is abandoned and reattempted."),
+			docs.FieldAdvanced("survey_timeout", "The period of time to collect responses to a survey when the socket type is `SURVEYOR`.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("propagate_response", "Whether replies to requests and surveys should be [propagated back](/docs/guides/sync_responses) to the input.").AtVersion("3.47.0"),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 		},
 		Categories: []Category{
@@ -0,0 +1,180 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/objectarchive"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeObjectArchive] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			a, err := newObjectArchiveWriter(conf.ObjectArchive, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeObjectArchive, 1, a, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.ObjectArchive.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+			CategoryLocal,
+		},
+		Summary: `
+Archives batches of messages to object storage within time partitioned paths, maintaining manifests that allow them to be replayed by time range.`,
+		Description: `
+Each batch is written as a single segment object containing a line of JSON for each message, which includes its raw contents and metadata. Segments are written within a partition path determined by the time that they were written, such as ` + "`2021/06/24/13/`" + ` for hourly partitions, and the size of segments is therefore determined by the [batching policy](/docs/configuration/batching) of the output.
+
+After each segment is written it is added to the ` + "`manifest.json`" + ` object of its partition, which lists the segments of the partition in the order they were written along with their time, message count and size. An ` + "`index.json`" + ` object at the root of the archive lists all partitions in chronological order. Batches are acknowledged once the manifest is written, and segments that are not listed within a manifest (due to a failed write) are ignored by readers. Archives can be replayed by time range with the ` + "[`object_archive` input](/docs/components/inputs/object_archive)" + `.
+
+### Storage
+
+The ` + "`path`" + ` field is either a directory of the local filesystem, or an S3 URL of the form ` + "`s3://bucket/prefix`" + `, in which case the fields of ` + "`aws`" + ` are used in order to connect.
+
+Manifests are rewritten by each batch, and therefore only a single output should write to an archive path at any given time.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Topic Archive",
+				Summary: "In this example the messages of a Kafka topic are archived to S3 in hourly partitions, with a segment written at least every minute.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: orders_archiver
+
+output:
+  object_archive:
+    path: s3://company-archives/orders
+    partition_period: hour
+    batching:
+      count: 50000
+      period: 1m
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The root of the archive, either a local directory or an S3 URL.", "/data/archives/orders", "s3://company-archives/orders"),
+			docs.FieldCommon("partition_period", "The period of time covered by each partition path.").HasOptions("hour", "day"),
+			docs.FieldAdvanced("compression", "The compression algorithm of segment objects.").HasOptions("none", "gzip"),
+			docs.FieldAdvanced("aws", "Configuration for connecting to S3, which is only used when the `path` is an S3 URL.").WithChildren(objectarchive.AWSFieldSpecs()...),
+			batch.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ObjectArchiveConfig contains configuration fields for the ObjectArchive
+// output type.
+type ObjectArchiveConfig struct {
+	Path            string                  `json:"path" yaml:"path"`
+	PartitionPeriod string                  `json:"partition_period" yaml:"partition_period"`
+	Compression     string                  `json:"compression" yaml:"compression"`
+	AWS             objectarchive.AWSConfig `json:"aws" yaml:"aws"`
+	Batching        batch.PolicyConfig      `json:"batching" yaml:"batching"`
+}
+
+// NewObjectArchiveConfig creates a new ObjectArchiveConfig with default
+// values.
+func NewObjectArchiveConfig() ObjectArchiveConfig {
+	return ObjectArchiveConfig{
+		Path:            "",
+		PartitionPeriod: "hour",
+		Compression:     "gzip",
+		AWS:             objectarchive.NewAWSConfig(),
+		Batching:        batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type objectArchiveWriter struct {
+	conf     ObjectArchiveConfig
+	log      log.Modular
+	layout   string
+	compress bool
+
+	mSegments metrics.StatCounter
+
+	mut    sync.Mutex
+	writer *objectarchive.Writer
+}
+
+func newObjectArchiveWriter(conf ObjectArchiveConfig, log log.Modular, stats metrics.Type) (*objectArchiveWriter, error) {
+	layout, err := objectarchive.PartitionLayout(conf.PartitionPeriod)
+	if err != nil {
+		return nil, err
+	}
+	a := &objectArchiveWriter{
+		conf:      conf,
+		log:       log,
+		layout:    layout,
+		mSegments: stats.GetCounter("segments.written"),
+	}
+	switch conf.Compression {
+	case "none":
+	case "gzip":
+		a.compress = true
+	default:
+		return nil, fmt.Errorf("compression type not recognised: %v", conf.Compression)
+	}
+	if conf.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	return a, nil
+}
+
+func (a *objectArchiveWriter) ConnectWithContext(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.writer != nil {
+		return nil
+	}
+	store, err := objectarchive.NewStore(a.conf.Path, a.conf.AWS)
+	if err != nil {
+		return err
+	}
+	a.writer = objectarchive.NewWriter(store, a.layout, a.compress)
+	a.log.Infof("Writing message batches to archive: %v\n", a.conf.Path)
+	return nil
+}
+
+func (a *objectArchiveWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.writer == nil {
+		return types.ErrNotConnected
+	}
+	if err := a.writer.WriteBatch(ctx, msg); err != nil {
+		return err
+	}
+	a.mSegments.Incr(1)
+	return nil
+}
+
+func (a *objectArchiveWriter) CloseAsync() {
+}
+
+func (a *objectArchiveWriter) WaitForClose(time.Duration) error {
+	return nil
+}
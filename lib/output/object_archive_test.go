@@ -0,0 +1,63 @@
+package output
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/objectarchive"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectArchiveOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_object_archive_output_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	conf := NewObjectArchiveConfig()
+	conf.Path = dir
+	conf.Compression = "none"
+
+	a, err := newObjectArchiveWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.Equal(t, types.ErrNotConnected, a.WriteWithContext(ctx, message.New([][]byte{[]byte("foo")})))
+	require.NoError(t, a.ConnectWithContext(ctx))
+	require.NoError(t, a.WriteWithContext(ctx, message.New([][]byte{[]byte("foo"), []byte("bar")})))
+
+	store := objectarchive.NewFileStore(dir)
+	index, err := objectarchive.ReadIndex(ctx, store)
+	require.NoError(t, err)
+	require.Len(t, index.Partitions, 1)
+
+	manifest, err := objectarchive.ReadManifest(ctx, store, index.Partitions[0])
+	require.NoError(t, err)
+	require.Len(t, manifest.Segments, 1)
+	assert.Equal(t, 2, manifest.Segments[0].Count)
+	assert.Regexp(t, `\.jsonl$`, manifest.Segments[0].Key)
+}
+
+func TestObjectArchiveOutputConfigErrors(t *testing.T) {
+	conf := NewObjectArchiveConfig()
+	_, err := newObjectArchiveWriter(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a path must be specified")
+
+	conf.Path = "/tmp/foo"
+	conf.PartitionPeriod = "week"
+	_, err = newObjectArchiveWriter(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "partition period not recognised: week")
+
+	conf.PartitionPeriod = "day"
+	conf.Compression = "lz4"
+	_, err = newObjectArchiveWriter(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "compression type not recognised: lz4")
+}
@@ -0,0 +1,393 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOrdered] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			if conf.Ordered.Output == nil {
+				return nil, errors.New("cannot create an ordered output without a child")
+			}
+			wrapped, err := New(*conf.Ordered.Output, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output '%v': %v", conf.Ordered.Output.Type, err)
+			}
+			return newOrdered(conf.Ordered, wrapped, log, stats)
+		}),
+		Summary: `
+Writes messages to a child output with a limit on the number of messages of the same key that may be in flight at any given time, and retries failed messages in place so that messages of a key are never delivered out of order.`,
+		Description: `
+Regular Benthos outputs propagate failed writes back to the source of the message, which reattempts it at a later time. When messages are sent in parallel this means that a failed message can be delivered after messages that were consumed after it.
+
+This output resolves a key for each message and only permits ` + "`max_in_flight_per_key`" + ` messages of that key to be written at a time, with any further messages of the same key held back until the pending ones have been delivered. Failed writes are retried by this output until they succeed, or until the retry limits are reached, at which point the message is rejected. Messages of different keys are written in parallel up to a limit of ` + "`max_in_flight`" + `.
+
+With the default ` + "`max_in_flight_per_key`" + ` of 1 messages that share a key are delivered in the order that they reached the output. In order to preserve that order from a partitioned input through parallel processing threads see the pipeline field [` + "`key_affinity`" + `](/docs/configuration/processing_pipelines#preserving-order-by-key).`,
+		FieldSpecs: retries.FieldSpecs().Add(
+			docs.FieldCommon("key", "An interpolated string that resolves the key of each message. When writing batches the key is resolved from the first message of the batch.", `${! meta("kafka_key") }`, `${! json("user.id") }`).IsInterpolated(),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight across all keys at a given time."),
+			docs.FieldCommon("max_in_flight_per_key", "The maximum number of messages of the same key to have in flight at a given time. Values greater than 1 allow messages of a key to be delivered out of order when writes fail."),
+			docs.FieldCommon("output", "A child output.").HasType(docs.FieldOutput),
+		),
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Ordered delivery per user",
+				Summary: "In this example we write events to an HTTP endpoint in parallel, but only ever have one event of a given user in flight at a time. If a write fails it is retried before any other events of that user are sent.",
+				Config: `
+output:
+  ordered:
+    key: ${! json("user.id") }
+    max_in_flight: 64
+    output:
+      http_client:
+        url: http://example.com/events
+        verb: POST
+        max_in_flight: 64
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OrderedConfig contains configuration values for the Ordered output type.
+type OrderedConfig struct {
+	Key               string  `json:"key" yaml:"key"`
+	MaxInFlight       int     `json:"max_in_flight" yaml:"max_in_flight"`
+	MaxInFlightPerKey int     `json:"max_in_flight_per_key" yaml:"max_in_flight_per_key"`
+	Output            *Config `json:"output" yaml:"output"`
+	retries.Config    `json:",inline" yaml:",inline"`
+}
+
+// NewOrderedConfig creates a new OrderedConfig with default values.
+func NewOrderedConfig() OrderedConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 0
+	rConf.Backoff.InitialInterval = "100ms"
+	rConf.Backoff.MaxInterval = "1s"
+	rConf.Backoff.MaxElapsedTime = "0s"
+	return OrderedConfig{
+		Key:               "",
+		MaxInFlight:       64,
+		MaxInFlightPerKey: 1,
+		Output:            nil,
+		Config:            rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyOrderedConfig struct {
+	Key               string      `json:"key" yaml:"key"`
+	MaxInFlight       int         `json:"max_in_flight" yaml:"max_in_flight"`
+	MaxInFlightPerKey int         `json:"max_in_flight_per_key" yaml:"max_in_flight_per_key"`
+	Output            interface{} `json:"output" yaml:"output"`
+	retries.Config    `json:",inline" yaml:",inline"`
+}
+
+func (o OrderedConfig) dummy() dummyOrderedConfig {
+	dummy := dummyOrderedConfig{
+		Key:               o.Key,
+		MaxInFlight:       o.MaxInFlight,
+		MaxInFlightPerKey: o.MaxInFlightPerKey,
+		Output:            o.Output,
+		Config:            o.Config,
+	}
+	if o.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (o OrderedConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (o OrderedConfig) MarshalYAML() (interface{}, error) {
+	return o.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// orderedKeyState tracks the messages of a key that are in flight, and those
+// that are waiting for a slot.
+type orderedKeyState struct {
+	inFlight int
+	pending  []types.Transaction
+}
+
+// ordered writes messages to a child output whilst limiting the number of
+// messages of each key that are in flight, retrying failed writes in place.
+type ordered struct {
+	stats metrics.Type
+	log   log.Modular
+
+	key               *field.Expression
+	maxInFlight       int
+	maxInFlightPerKey int
+	backoffCtor       func() backoff.BackOff
+	wrapped           Type
+
+	keysMut sync.Mutex
+	keys    map[string]*orderedKeyState
+
+	mSent     metrics.StatCounter
+	mError    metrics.StatCounter
+	mEndRetry metrics.StatCounter
+	mBlocked  metrics.StatCounter
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+func newOrdered(conf OrderedConfig, wrapped Type, log log.Modular, stats metrics.Type) (*ordered, error) {
+	if conf.MaxInFlight < 1 {
+		return nil, fmt.Errorf("max_in_flight must be at least 1, got %v", conf.MaxInFlight)
+	}
+	if conf.MaxInFlightPerKey < 1 {
+		return nil, fmt.Errorf("max_in_flight_per_key must be at least 1, got %v", conf.MaxInFlightPerKey)
+	}
+	key, err := bloblang.NewField(conf.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	boffCtor, err := conf.GetCtor()
+	if err != nil {
+		return nil, err
+	}
+	return &ordered{
+		log:   log,
+		stats: stats,
+
+		key:               key,
+		maxInFlight:       conf.MaxInFlight,
+		maxInFlightPerKey: conf.MaxInFlightPerKey,
+		backoffCtor:       boffCtor,
+		wrapped:           wrapped,
+
+		keys: map[string]*orderedKeyState{},
+
+		mSent:     stats.GetCounter("ordered.send.success"),
+		mError:    stats.GetCounter("ordered.send.error"),
+		mEndRetry: stats.GetCounter("ordered.end_of_retries"),
+		mBlocked:  stats.GetCounter("ordered.key.blocked"),
+
+		transactionsOut: make(chan types.Transaction),
+		closeChan:       make(chan struct{}),
+		closedChan:      make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// send writes a transaction to the child output, retrying failed writes until
+// success, the end of retries, or the output closing. Returns false if the
+// output was closed before a response could be delivered.
+func (o *ordered) send(ts types.Transaction) bool {
+	var boff backoff.BackOff
+	resChan := make(chan types.Response)
+
+	var resOut types.Response
+	for {
+		select {
+		case o.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+		case <-o.closeChan:
+			return false
+		}
+
+		var res types.Response
+		select {
+		case res = <-resChan:
+		case <-o.closeChan:
+			return false
+		}
+
+		if res.Error() == nil {
+			o.mSent.Incr(1)
+			resOut = response.NewAck()
+			break
+		}
+
+		o.mError.Incr(1)
+		if boff == nil {
+			boff = o.backoffCtor()
+		}
+		nextBackoff := boff.NextBackOff()
+		if nextBackoff == backoff.Stop {
+			o.mEndRetry.Incr(1)
+			o.log.Errorf("Failed to send message: %v\n", res.Error())
+			resOut = response.NewNoack()
+			break
+		}
+		o.log.Warnf("Failed to send message: %v\n", res.Error())
+		select {
+		case <-time.After(nextBackoff):
+		case <-o.closeChan:
+			return false
+		}
+	}
+
+	select {
+	case ts.ResponseChan <- resOut:
+	case <-o.closeChan:
+		return false
+	}
+	return true
+}
+
+// run writes a transaction and then any transactions of the same key that were
+// held back whilst it was in flight.
+func (o *ordered) run(key string, ts types.Transaction, slots chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		if !o.send(ts) {
+			return
+		}
+		<-slots
+
+		o.keysMut.Lock()
+		state := o.keys[key]
+		if len(state.pending) == 0 {
+			if state.inFlight--; state.inFlight == 0 {
+				delete(o.keys, key)
+			}
+			o.keysMut.Unlock()
+			return
+		}
+		ts = state.pending[0]
+		state.pending[0] = types.Transaction{}
+		state.pending = state.pending[1:]
+		o.keysMut.Unlock()
+	}
+}
+
+func (o *ordered) loop() {
+	wg := sync.WaitGroup{}
+	defer func() {
+		wg.Wait()
+		close(o.transactionsOut)
+		o.wrapped.CloseAsync()
+		err := o.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = o.wrapped.WaitForClose(time.Second) {
+		}
+		close(o.closedChan)
+	}()
+
+	// Each transaction consumes a slot until it is resolved, including those
+	// held back behind other messages of the same key.
+	slots := make(chan struct{}, o.maxInFlight)
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-o.closeChan:
+			return
+		}
+
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-o.transactionsIn:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+
+		var key string
+		if ts.Payload.Len() > 0 {
+			key = o.key.String(0, ts.Payload)
+		}
+
+		o.keysMut.Lock()
+		state, exists := o.keys[key]
+		if !exists {
+			state = &orderedKeyState{}
+			o.keys[key] = state
+		}
+		if state.inFlight >= o.maxInFlightPerKey {
+			o.mBlocked.Incr(1)
+			state.pending = append(state.pending, ts)
+			o.keysMut.Unlock()
+			continue
+		}
+		state.inFlight++
+		o.keysMut.Unlock()
+
+		wg.Add(1)
+		go o.run(key, ts, slots, &wg)
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (o *ordered) Consume(ts <-chan types.Transaction) error {
+	if o.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := o.wrapped.Consume(o.transactionsOut); err != nil {
+		return err
+	}
+	o.transactionsIn = ts
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *ordered) Connected() bool {
+	return o.wrapped.Connected()
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// output. This value can be used to determine a sensible value for parent
+// outputs, but should not be relied upon as part of dispatcher logic.
+func (o *ordered) MaxInFlight() (int, bool) {
+	return o.maxInFlight, true
+}
+
+// CloseAsync shuts down the ordered output and stops processing requests.
+func (o *ordered) CloseAsync() {
+	o.closeOnce.Do(func() {
+		close(o.closeChan)
+	})
+}
+
+// WaitForClose blocks until the ordered output has closed down.
+func (o *ordered) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,151 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedPerKey(t *testing.T) {
+	conf := NewOrderedConfig()
+	conf.Key = `${! meta("key") }`
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	child := &mockOutput{}
+	o, err := newOrdered(conf, child, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, o.Consume(tChan))
+	require.Error(t, o.Consume(tChan))
+
+	resChans := map[string]chan types.Response{}
+	sendMsg := func(key, content string) {
+		t.Helper()
+		part := message.NewPart([]byte(content))
+		part.Metadata().Set("key", key)
+		msg := message.New(nil)
+		msg.Append(part)
+		resChans[content] = make(chan types.Response)
+		select {
+		case tChan <- types.NewTransaction(msg, resChans[content]):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	readChild := func() types.Transaction {
+		t.Helper()
+		select {
+		case ts := <-child.ts:
+			return ts
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		return types.Transaction{}
+	}
+
+	readRes := func(content string) types.Response {
+		t.Helper()
+		select {
+		case res := <-resChans[content]:
+			return res
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		return nil
+	}
+
+	sendMsg("a", "a1")
+	sendMsg("b", "b1")
+	sendMsg("a", "a2")
+
+	received := map[string]types.Transaction{}
+	for i := 0; i < 2; i++ {
+		ts := readChild()
+		received[string(ts.Payload.Get(0).Get())] = ts
+	}
+	require.Contains(t, received, "a1")
+	require.Contains(t, received, "b1")
+
+	// Message a2 must not be written until a1 succeeds, even after a failure.
+	received["a1"].ResponseChan <- response.NewError(errors.New("nope"))
+	ts := readChild()
+	assert.Equal(t, "a1", string(ts.Payload.Get(0).Get()))
+
+	received["b1"].ResponseChan <- response.NewAck()
+	assert.NoError(t, readRes("b1").Error())
+
+	ts.ResponseChan <- response.NewAck()
+	assert.NoError(t, readRes("a1").Error())
+
+	ts = readChild()
+	assert.Equal(t, "a2", string(ts.Payload.Get(0).Get()))
+	ts.ResponseChan <- response.NewAck()
+	assert.NoError(t, readRes("a2").Error())
+
+	close(tChan)
+	require.NoError(t, o.WaitForClose(time.Second*5))
+}
+
+func TestOrderedEndOfRetries(t *testing.T) {
+	conf := NewOrderedConfig()
+	conf.Key = `${! meta("key") }`
+	conf.MaxRetries = 1
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	child := &mockOutput{}
+	o, err := newOrdered(conf, child, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, o.Consume(tChan))
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case ts := <-child.ts:
+			ts.ResponseChan <- response.NewError(errors.New("nope"))
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		assert.Error(t, res.Error())
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	o.CloseAsync()
+	require.NoError(t, o.WaitForClose(time.Second*5))
+}
+
+func TestOrderedBadConfig(t *testing.T) {
+	conf := NewOrderedConfig()
+	conf.MaxInFlightPerKey = 0
+	_, err := newOrdered(conf, &mockOutput{}, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf = NewOrderedConfig()
+	conf.Key = `${! meta("key" }`
+	_, err = newOrdered(conf, &mockOutput{}, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
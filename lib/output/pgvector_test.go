@@ -0,0 +1,67 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGVectorUpsertQuery(t *testing.T) {
+	conf := NewPGVectorConfig()
+	conf.Table = `${! json("table") }`
+	conf.ID = `${! json("id") }`
+	conf.VectorMapping = `root = this.vec`
+	conf.PayloadColumn = "payload"
+	conf.PayloadMapping = `root.title = this.title`
+
+	w, err := newPGVectorWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	groups, errs := w.docs.groups(message.New([][]byte{
+		[]byte(`{"table":"public.docs","id":"a","vec":[0.1,2],"title":"first"}`),
+		[]byte(`{"table":"public.docs","id":"b","vec":[3,-0.25],"title":"second"}`),
+		[]byte(`{"table":"public.docs","id":"a","vec":[1,1],"title":"third"}`),
+	}))
+	require.Equal(t, []error{nil, nil, nil}, errs)
+	require.Len(t, groups, 1)
+	assert.Equal(t, []int{0, 1, 2}, groups[0].indexes)
+
+	query, args, err := w.upsertQuery(groups[0].target, groups[0].docs)
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "public"."docs" ("id", "embedding", "payload") VALUES ($1, $2::vector, $3::jsonb), ($4, $5::vector, $6::jsonb) ON CONFLICT ("id") DO UPDATE SET "embedding" = EXCLUDED."embedding", "payload" = EXCLUDED."payload"`, query)
+	assert.Equal(t, []interface{}{
+		"a", "[1,1]", `{"title":"third"}`,
+		"b", "[3,-0.25]", `{"title":"second"}`,
+	}, args)
+}
+
+func TestPGVectorUpsertQueryNoPayload(t *testing.T) {
+	conf := NewPGVectorConfig()
+	conf.Table = `my"table`
+	conf.ID = `${! json("id") }`
+	conf.VectorMapping = `root = this.vec`
+	conf.IDColumn = "doc_id"
+	conf.VectorColumn = "vec"
+
+	w, err := newPGVectorWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	query, args, err := w.upsertQuery(`my"table`, []vectorDoc{{id: "a", vector: []float64{0.5}}})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "my""table" ("doc_id", "vec") VALUES ($1, $2::vector) ON CONFLICT ("doc_id") DO UPDATE SET "vec" = EXCLUDED."vec"`, query)
+	assert.Equal(t, []interface{}{"a", "[0.5]"}, args)
+}
+
+func TestPGVectorBadConfig(t *testing.T) {
+	conf := NewPGVectorConfig()
+	conf.Table = "foo"
+	conf.ID = `${! json("id") }`
+	conf.VectorMapping = `root = this.vec`
+	conf.PayloadMapping = `root = this`
+
+	_, err := newPGVectorWriter(conf, log.Noop())
+	require.EqualError(t, err, "a payload_column must be specified in order to use a payload_mapping")
+}
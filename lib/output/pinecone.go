@@ -0,0 +1,237 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypePinecone] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			p, err := newPineconeWriter(conf.Pinecone, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypePinecone, conf.Pinecone.MaxInFlight, p, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.Pinecone.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Upserts documents with embedding vectors into a [Pinecone](https://www.pinecone.io/) index.`,
+		Description: `
+Each message is upserted as a vector with the ID resolved from ` + "`id`" + `, the values resulting from ` + "`vector_mapping`" + ` and, optionally, the metadata resulting from ` + "`metadata_mapping`" + `.
+
+The messages of a batch are upserted with requests for each namespace that they target, where each request contains up to 100 vectors, and when a batch contains several messages with the same ID for a namespace only the last of them is upserted.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Product Embeddings",
+				Summary: "In this example product embeddings are upserted into a namespace for each catalogue, with the category of each product as metadata in order to filter queries by it.",
+				Config: `
+output:
+  pinecone:
+    url: https://products-abc123.svc.us-east1-gcp.pinecone.io
+    api_key: ${PINECONE_API_KEY}
+    namespace: ${! json("catalogue") }
+    id: ${! json("sku") }
+    vector_mapping: root = this.embedding
+    metadata_mapping: 'root.category = this.category'
+    batching:
+      count: 100
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The URL of the Pinecone index.", "https://example-abc123.svc.us-east1-gcp.pinecone.io"),
+			docs.FieldCommon("api_key", "The API key to authenticate with."),
+			docs.FieldCommon("namespace", "The namespace to upsert vectors into, where an empty namespace is the default namespace of the index.").IsInterpolated(),
+			docs.FieldCommon("id", "The ID of each vector.", `${! json("id") }`, `${! meta("kafka_key") }`).IsInterpolated(),
+			docs.FieldCommon(
+				"vector_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that produces the values of each vector as an array of numbers.",
+				`root = this.embedding`,
+			).HasType(docs.FieldString).Linter(docs.LintBloblangMapping),
+			docs.FieldCommon(
+				"metadata_mapping", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that produces the metadata of each vector as an object.",
+				`root.category = this.category`,
+			).HasType(docs.FieldString).Linter(docs.LintBloblangMapping),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a request to complete."),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// PineconeConfig contains configuration fields for the Pinecone output.
+type PineconeConfig struct {
+	URL             string             `json:"url" yaml:"url"`
+	APIKey          string             `json:"api_key" yaml:"api_key"`
+	Namespace       string             `json:"namespace" yaml:"namespace"`
+	ID              string             `json:"id" yaml:"id"`
+	VectorMapping   string             `json:"vector_mapping" yaml:"vector_mapping"`
+	MetadataMapping string             `json:"metadata_mapping" yaml:"metadata_mapping"`
+	Timeout         string             `json:"timeout" yaml:"timeout"`
+	MaxInFlight     int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewPineconeConfig returns a PineconeConfig with default values.
+func NewPineconeConfig() PineconeConfig {
+	return PineconeConfig{
+		URL:             "",
+		APIKey:          "",
+		Namespace:       "",
+		ID:              "",
+		VectorMapping:   "",
+		MetadataMapping: "",
+		Timeout:         "5s",
+		MaxInFlight:     1,
+		Batching:        batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// pineconeMaxVectors is the maximum number of vectors of an upsert request.
+const pineconeMaxVectors = 100
+
+type pineconeWriter struct {
+	log  log.Modular
+	conf PineconeConfig
+
+	baseURL string
+	docs    *vectorDocBuilder
+	client  *http.Client
+}
+
+func newPineconeWriter(conf PineconeConfig, log log.Modular) (*pineconeWriter, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+
+	p := &pineconeWriter{
+		log:     log,
+		conf:    conf,
+		baseURL: strings.TrimSuffix(conf.URL, "/"),
+		client:  &http.Client{},
+	}
+	if conf.Timeout != "" {
+		var err error
+		if p.client.Timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	var err error
+	if p.docs, err = newVectorDocBuilder(conf.ID, conf.Namespace, conf.VectorMapping, conf.MetadataMapping); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext prepares the writer, requests are made per batch and so no
+// connection is established.
+func (p *pineconeWriter) ConnectWithContext(ctx context.Context) error {
+	p.log.Infof("Writing vectors to Pinecone index at: %v\n", p.baseURL)
+	return nil
+}
+
+type pineconeVector struct {
+	ID       string                 `json:"id"`
+	Values   []float64              `json:"values"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type pineconeUpsert struct {
+	Vectors   []pineconeVector `json:"vectors"`
+	Namespace string           `json:"namespace,omitempty"`
+}
+
+func (p *pineconeWriter) upsert(ctx context.Context, namespace string, docs []vectorDoc) error {
+	upsert := pineconeUpsert{
+		Vectors:   make([]pineconeVector, len(docs)),
+		Namespace: namespace,
+	}
+	for i, d := range docs {
+		upsert.Vectors[i] = pineconeVector{
+			ID:       d.id,
+			Values:   d.vector,
+			Metadata: d.payload,
+		}
+	}
+	body, err := json.Marshal(upsert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/vectors/upsert", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Api-Key", p.conf.APIKey)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("upsert into namespace '%v' failed with status %v: %s", namespace, res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	return nil
+}
+
+// WriteWithContext attempts to upsert the messages of a batch into Pinecone.
+func (p *pineconeWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return vectorStoreWrite(p.docs, msg, func(group vectorDocGroup) error {
+		for start := 0; start < len(group.docs); start += pineconeMaxVectors {
+			end := start + pineconeMaxVectors
+			if end > len(group.docs) {
+				end = len(group.docs)
+			}
+			if err := p.upsert(ctx, group.target, group.docs[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (p *pineconeWriter) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (p *pineconeWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
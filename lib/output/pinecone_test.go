@@ -0,0 +1,90 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPineconeWriter(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs []pineconeUpsert
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/vectors/upsert", r.URL.Path)
+		assert.Equal(t, "foosecret", r.Header.Get("Api-Key"))
+
+		var upsert pineconeUpsert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&upsert))
+
+		reqMut.Lock()
+		reqs = append(reqs, upsert)
+		reqMut.Unlock()
+
+		fmt.Fprintf(w, `{"upsertedCount":%v}`, len(upsert.Vectors))
+	}))
+	defer ts.Close()
+
+	conf := NewPineconeConfig()
+	conf.URL = ts.URL
+	conf.APIKey = "foosecret"
+	conf.Namespace = `${! json("ns") }`
+	conf.ID = `${! json("id") }`
+	conf.VectorMapping = `root = [ this.n, this.n + 1 ]`
+	conf.MetadataMapping = `root.n = this.n`
+
+	w, err := newPineconeWriter(conf, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+
+	var input [][]byte
+	for i := 0; i < 150; i++ {
+		input = append(input, []byte(fmt.Sprintf(`{"ns":"","id":"%v","n":%v}`, i, i)))
+	}
+	input = append(input, []byte(`{"ns":"other","id":"foo","n":1}`))
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New(input)))
+
+	require.Len(t, reqs, 3)
+	assert.Equal(t, "", reqs[0].Namespace)
+	assert.Len(t, reqs[0].Vectors, 100)
+	assert.Equal(t, "", reqs[1].Namespace)
+	assert.Len(t, reqs[1].Vectors, 50)
+	assert.Equal(t, pineconeVector{
+		ID:       "149",
+		Values:   []float64{149, 150},
+		Metadata: map[string]interface{}{"n": 149.0},
+	}, reqs[1].Vectors[49])
+	assert.Equal(t, "other", reqs[2].Namespace)
+	assert.Len(t, reqs[2].Vectors, 1)
+}
+
+func TestPineconeWriterError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	conf := NewPineconeConfig()
+	conf.URL = ts.URL
+	conf.ID = `${! json("id") }`
+	conf.VectorMapping = `root = this.vec`
+
+	w, err := newPineconeWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"foo","vec":[1,2]}`),
+	}))
+	require.EqualError(t, err, "upsert into namespace '' failed with status 401: unauthorized")
+}
@@ -1,10 +1,20 @@
 package output
 
+import (
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/service/pulsar/client"
+)
+
 // PulsarConfig contains configuration for the Pulsar input type.
 type PulsarConfig struct {
-	URL         string `json:"url" yaml:"url"`
-	Topic       string `json:"topic" yaml:"topic"`
-	MaxInFlight int    `json:"max_in_flight" yaml:"max_in_flight"`
+	URL         string            `json:"url" yaml:"url"`
+	Topic       string            `json:"topic" yaml:"topic"`
+	Key         string            `json:"key" yaml:"key"`
+	OrderingKey string            `json:"ordering_key" yaml:"ordering_key"`
+	Metadata    output.Metadata   `json:"metadata" yaml:"metadata"`
+	TLS         client.TLSConfig  `json:"tls" yaml:"tls"`
+	Auth        client.AuthConfig `json:"auth" yaml:"auth"`
+	MaxInFlight int               `json:"max_in_flight" yaml:"max_in_flight"`
 }
 
 // NewPulsarConfig creates a new PulsarConfig with default values.
@@ -12,6 +22,11 @@ func NewPulsarConfig() PulsarConfig {
 	return PulsarConfig{
 		URL:         "",
 		Topic:       "",
+		Key:         "",
+		OrderingKey: "",
+		Metadata:    output.NewMetadata(),
+		TLS:         client.NewTLSConfig(),
+		Auth:        client.NewAuthConfig(),
 		MaxInFlight: 1,
 	}
 }
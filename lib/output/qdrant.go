@@ -0,0 +1,236 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeQdrant] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			q, err := newQdrantWriter(conf.Qdrant, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeQdrant, conf.Qdrant.MaxInFlight, q, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewBatcherFromConfig(conf.Qdrant.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.47.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Upserts documents with embedding vectors into [Qdrant](https://qdrant.tech/) collections.`,
+		Description: `
+Each message is upserted as a point with the ID resolved from ` + "`id`" + `, the vector resulting from ` + "`vector_mapping`" + ` and, optionally, the payload resulting from ` + "`payload_mapping`" + `. Qdrant requires IDs to be either unsigned integers or UUIDs, and IDs that parse as unsigned integers are sent as such.
+
+The messages of a batch are upserted with a request for each collection that they target, and when a batch contains several messages with the same ID for a collection only the last of them is upserted. Requests wait for the points to be persisted before the messages are acknowledged.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Document Embeddings",
+				Summary: "In this example documents that already contain an embedding are upserted into a collection for each tenant, with the title and body of each document as the payload of its point.",
+				Config: `
+output:
+  qdrant:
+    url: http://localhost:6333
+    collection: docs_${! json("tenant") }
+    id: ${! json("id") }
+    vector_mapping: root = this.embedding
+    payload_mapping: |
+      root.title = this.title
+      root.body = this.body
+    batching:
+      count: 100
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The base URL of the Qdrant API.", "http://localhost:6333"),
+			docs.FieldCommon("collection", "The collection to upsert points into.").IsInterpolated(),
+			docs.FieldCommon("id", "The ID of each point.", `${! json("id") }`, `${! meta("kafka_key") }`).IsInterpolated(),
+			docs.FieldCommon(
+				"vector_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that produces the vector of each point as an array of numbers.",
+				`root = this.embedding`,
+			).HasType(docs.FieldString).Linter(docs.LintBloblangMapping),
+			docs.FieldCommon(
+				"payload_mapping", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that produces the payload of each point as an object.",
+				`root = this.without("embedding")`,
+			).HasType(docs.FieldString).Linter(docs.LintBloblangMapping),
+			docs.FieldAdvanced("api_key", "An optional API key to authenticate with."),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a request to complete."),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// QdrantConfig contains configuration fields for the Qdrant output.
+type QdrantConfig struct {
+	URL            string             `json:"url" yaml:"url"`
+	Collection     string             `json:"collection" yaml:"collection"`
+	ID             string             `json:"id" yaml:"id"`
+	VectorMapping  string             `json:"vector_mapping" yaml:"vector_mapping"`
+	PayloadMapping string             `json:"payload_mapping" yaml:"payload_mapping"`
+	APIKey         string             `json:"api_key" yaml:"api_key"`
+	Timeout        string             `json:"timeout" yaml:"timeout"`
+	MaxInFlight    int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewQdrantConfig returns a QdrantConfig with default values.
+func NewQdrantConfig() QdrantConfig {
+	return QdrantConfig{
+		URL:            "",
+		Collection:     "",
+		ID:             "",
+		VectorMapping:  "",
+		PayloadMapping: "",
+		APIKey:         "",
+		Timeout:        "5s",
+		MaxInFlight:    1,
+		Batching:       batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type qdrantWriter struct {
+	log  log.Modular
+	conf QdrantConfig
+
+	baseURL string
+	docs    *vectorDocBuilder
+	client  *http.Client
+}
+
+func newQdrantWriter(conf QdrantConfig, log log.Modular) (*qdrantWriter, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	if conf.Collection == "" {
+		return nil, errors.New("a collection must be specified")
+	}
+
+	q := &qdrantWriter{
+		log:     log,
+		conf:    conf,
+		baseURL: strings.TrimSuffix(conf.URL, "/"),
+		client:  &http.Client{},
+	}
+	if conf.Timeout != "" {
+		var err error
+		if q.client.Timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	var err error
+	if q.docs, err = newVectorDocBuilder(conf.ID, conf.Collection, conf.VectorMapping, conf.PayloadMapping); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext prepares the writer, requests are made per batch and so no
+// connection is established.
+func (q *qdrantWriter) ConnectWithContext(ctx context.Context) error {
+	q.log.Infof("Writing points to Qdrant at: %v\n", q.baseURL)
+	return nil
+}
+
+type qdrantPoint struct {
+	ID      interface{}            `json:"id"`
+	Vector  []float64              `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+func (q *qdrantWriter) upsert(ctx context.Context, group vectorDocGroup) error {
+	if group.target == "" {
+		return errors.New("collection is empty")
+	}
+
+	points := make([]qdrantPoint, len(group.docs))
+	for i, d := range group.docs {
+		points[i] = qdrantPoint{
+			ID:      d.id,
+			Vector:  d.vector,
+			Payload: d.payload,
+		}
+		if n, err := strconv.ParseUint(d.id, 10, 64); err == nil {
+			points[i].ID = n
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"points": points})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPut,
+		q.baseURL+"/collections/"+url.PathEscape(group.target)+"/points?wait=true",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.conf.APIKey != "" {
+		req.Header.Set("api-key", q.conf.APIKey)
+	}
+
+	res, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("upsert into collection '%v' failed with status %v: %s", group.target, res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	return nil
+}
+
+// WriteWithContext attempts to upsert the messages of a batch into Qdrant.
+func (q *qdrantWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return vectorStoreWrite(q.docs, msg, func(group vectorDocGroup) error {
+		return q.upsert(ctx, group)
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (q *qdrantWriter) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (q *qdrantWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQdrantWriter(t *testing.T) {
+	var reqMut sync.Mutex
+	reqs := map[string]interface{}{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "true", r.URL.Query().Get("wait"))
+		assert.Equal(t, "foosecret", r.Header.Get("api-key"))
+		if r.URL.Path == "/collections/docs_bad/points" {
+			http.Error(w, `{"status":{"error":"Not found: Collection docs_bad doesn't exist!"}}`, http.StatusNotFound)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var v interface{}
+		require.NoError(t, json.Unmarshal(body, &v))
+
+		reqMut.Lock()
+		reqs[r.URL.Path] = v
+		reqMut.Unlock()
+	}))
+	defer ts.Close()
+
+	conf := NewQdrantConfig()
+	conf.URL = ts.URL + "/"
+	conf.APIKey = "foosecret"
+	conf.Collection = `docs_${! json("tenant") }`
+	conf.ID = `${! json("id") }`
+	conf.VectorMapping = `root = this.vec`
+	conf.PayloadMapping = `root.title = this.title`
+
+	w, err := newQdrantWriter(conf, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"tenant":"a","id":"1","vec":[0.1,0.2],"title":"first"}`),
+		[]byte(`{"tenant":"b","id":"3ac1b8a6-9d5c-4b9b-9b3f-0a6e3f6c1f9e","vec":[0.3,0.4],"title":"second"}`),
+		[]byte(`{"tenant":"a","id":"1","vec":[0.5,0.6],"title":"third"}`),
+		[]byte(`{"tenant":"bad","id":"2","vec":[0.7,0.8],"title":"fourth"}`),
+		[]byte(`{"tenant":"a","id":"4","vec":"nope"}`),
+	}))
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+
+	var failed []int
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{3, 4}, failed)
+
+	assert.Equal(t, map[string]interface{}{
+		"/collections/docs_a/points": map[string]interface{}{
+			"points": []interface{}{
+				map[string]interface{}{
+					"id":      1.0,
+					"vector":  []interface{}{0.5, 0.6},
+					"payload": map[string]interface{}{"title": "third"},
+				},
+			},
+		},
+		"/collections/docs_b/points": map[string]interface{}{
+			"points": []interface{}{
+				map[string]interface{}{
+					"id":      "3ac1b8a6-9d5c-4b9b-9b3f-0a6e3f6c1f9e",
+					"vector":  []interface{}{0.3, 0.4},
+					"payload": map[string]interface{}{"title": "second"},
+				},
+			},
+		},
+	}, reqs)
+}
+
+func TestQdrantWriterBadConfig(t *testing.T) {
+	conf := NewQdrantConfig()
+	conf.URL = "http://localhost:6333"
+	conf.Collection = "foo"
+	conf.ID = `${! json("id") }`
+
+	_, err := newQdrantWriter(conf, log.Noop())
+	require.EqualError(t, err, "a vector_mapping must be specified")
+}
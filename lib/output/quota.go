@@ -0,0 +1,439 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"golang.org/x/sync/errgroup"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeQuota] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			if conf.Quota.Output == nil {
+				return nil, errors.New("cannot create a quota output without a child")
+			}
+			wrapped, err := New(*conf.Quota.Output, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output '%v': %v", conf.Quota.Output.Type, err)
+			}
+			if conf.Quota.RejectedOutput == nil {
+				return nil, errors.New("cannot create a quota output without a rejected output")
+			}
+			rejected, err := New(*conf.Quota.RejectedOutput, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create rejected output '%v': %v", conf.Quota.RejectedOutput.Type, err)
+			}
+			return newQuota(conf.Quota, wrapped, rejected, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Summary: `
+Writes messages to a child output whilst enforcing per-tenant limits on the number of messages and bytes written within an interval, and routes messages that exceed the quota of their tenant to a separate output.`,
+		Description: `
+Each message is attributed to a tenant with the interpolated ` + "`tenant`" + ` field, and the messages and bytes of each tenant are counted within a fixed window of ` + "`interval`" + `. Messages that would exceed the ` + "`count`" + ` or ` + "`byte_size`" + ` limit of their tenant within the current window are sent to the ` + "`rejected_output`" + ` instead, which can be a ` + "[`drop`](/docs/components/outputs/drop)" + ` output in order to discard them. A limit of zero disables it, and the limits of individual tenants can be set with ` + "`overrides`" + `.
+
+Rejected messages are given the metadata field ` + "`quota_exceeded`" + `, which is set to either ` + "`count` or `byte_size`" + ` depending on the limit that was reached. Batches are split so that only the messages over quota are rejected.
+
+### Metrics
+
+The number of messages accepted and rejected for each tenant are exposed as the metrics ` + "`quota.accepted` and `quota.rejected`" + ` with the label ` + "`tenant`" + `, which requires a metrics type that supports labels.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("tenant", "An interpolated string that resolves the tenant of each message.", `${! meta("tenant_id") }`, `${! json("account.id") }`).IsInterpolated(),
+			docs.FieldCommon("interval", "The time window that limits are counted within."),
+			docs.FieldCommon("count", "The maximum number of messages a tenant may write within the interval, or zero for no limit."),
+			docs.FieldCommon("byte_size", "The maximum number of bytes a tenant may write within the interval, or zero for no limit."),
+			docs.FieldAdvanced("overrides", "A map of tenants to limits that replace the default `count` and `byte_size` for that tenant.").Map().WithChildren(
+				docs.FieldCommon("count", "The maximum number of messages the tenant may write within the interval, or zero for no limit.").HasDefault(0),
+				docs.FieldCommon("byte_size", "The maximum number of bytes the tenant may write within the interval, or zero for no limit.").HasDefault(0),
+			).HasDefault(map[string]interface{}{}),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time."),
+			docs.FieldCommon("output", "A child output that messages within quota are written to.").HasType(docs.FieldOutput),
+			docs.FieldCommon("rejected_output", "An output that messages exceeding the quota of their tenant are written to.").HasType(docs.FieldOutput),
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Multi-tenant ingestion",
+				Summary: "In this example events of each customer are limited to 100 per second, with a larger allowance for a single customer. Events exceeding the quota are written to a separate topic for later inspection.",
+				Config: `
+output:
+  quota:
+    tenant: ${! meta("customer_id") }
+    interval: 1s
+    count: 100
+    overrides:
+      acme:
+        count: 1000
+    output:
+      kafka:
+        addresses: [ TODO ]
+        topic: events
+    rejected_output:
+      kafka:
+        addresses: [ TODO ]
+        topic: events_over_quota
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// QuotaLimitConfig contains the limits of a quota.
+type QuotaLimitConfig struct {
+	Count    int `json:"count" yaml:"count"`
+	ByteSize int `json:"byte_size" yaml:"byte_size"`
+}
+
+// QuotaConfig contains configuration values for the Quota output type.
+type QuotaConfig struct {
+	Tenant         string                      `json:"tenant" yaml:"tenant"`
+	Interval       string                      `json:"interval" yaml:"interval"`
+	Count          int                         `json:"count" yaml:"count"`
+	ByteSize       int                         `json:"byte_size" yaml:"byte_size"`
+	Overrides      map[string]QuotaLimitConfig `json:"overrides" yaml:"overrides"`
+	MaxInFlight    int                         `json:"max_in_flight" yaml:"max_in_flight"`
+	Output         *Config                     `json:"output" yaml:"output"`
+	RejectedOutput *Config                     `json:"rejected_output" yaml:"rejected_output"`
+}
+
+// NewQuotaConfig creates a new QuotaConfig with default values.
+func NewQuotaConfig() QuotaConfig {
+	return QuotaConfig{
+		Tenant:         "",
+		Interval:       "1s",
+		Count:          0,
+		ByteSize:       0,
+		Overrides:      map[string]QuotaLimitConfig{},
+		MaxInFlight:    64,
+		Output:         nil,
+		RejectedOutput: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyQuotaConfig struct {
+	Tenant         string                      `json:"tenant" yaml:"tenant"`
+	Interval       string                      `json:"interval" yaml:"interval"`
+	Count          int                         `json:"count" yaml:"count"`
+	ByteSize       int                         `json:"byte_size" yaml:"byte_size"`
+	Overrides      map[string]QuotaLimitConfig `json:"overrides" yaml:"overrides"`
+	MaxInFlight    int                         `json:"max_in_flight" yaml:"max_in_flight"`
+	Output         interface{}                 `json:"output" yaml:"output"`
+	RejectedOutput interface{}                 `json:"rejected_output" yaml:"rejected_output"`
+}
+
+func (q QuotaConfig) dummy() dummyQuotaConfig {
+	dummy := dummyQuotaConfig{
+		Tenant:         q.Tenant,
+		Interval:       q.Interval,
+		Count:          q.Count,
+		ByteSize:       q.ByteSize,
+		Overrides:      q.Overrides,
+		MaxInFlight:    q.MaxInFlight,
+		Output:         q.Output,
+		RejectedOutput: q.RejectedOutput,
+	}
+	if q.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if q.RejectedOutput == nil {
+		dummy.RejectedOutput = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (q QuotaConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (q QuotaConfig) MarshalYAML() (interface{}, error) {
+	return q.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// quotaWindow tracks the usage of a tenant within the current interval.
+type quotaWindow struct {
+	start time.Time
+	count int
+	bytes int
+}
+
+// quota writes messages to a child output whilst enforcing per-tenant limits,
+// and routes messages exceeding those limits to a rejected output.
+type quota struct {
+	log log.Modular
+
+	tenant      *field.Expression
+	interval    time.Duration
+	limits      QuotaLimitConfig
+	overrides   map[string]QuotaLimitConfig
+	maxInFlight int
+
+	windowsMut sync.Mutex
+	windows    map[string]*quotaWindow
+	lastSweep  time.Time
+
+	wrapped      Type
+	wrappedChan  chan types.Transaction
+	rejected     Type
+	rejectedChan chan types.Transaction
+
+	mAccepted metrics.StatCounterVec
+	mRejected metrics.StatCounterVec
+
+	transactionsIn <-chan types.Transaction
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+func newQuota(conf QuotaConfig, wrapped, rejected Type, log log.Modular, stats metrics.Type) (*quota, error) {
+	if conf.MaxInFlight < 1 {
+		return nil, fmt.Errorf("max_in_flight must be at least 1, got %v", conf.MaxInFlight)
+	}
+	tenant, err := bloblang.NewField(conf.Tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tenant expression: %v", err)
+	}
+	interval, err := time.ParseDuration(conf.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be greater than zero, got: %v", conf.Interval)
+	}
+	return &quota{
+		log: log,
+
+		tenant:      tenant,
+		interval:    interval,
+		limits:      QuotaLimitConfig{Count: conf.Count, ByteSize: conf.ByteSize},
+		overrides:   conf.Overrides,
+		maxInFlight: conf.MaxInFlight,
+
+		windows:   map[string]*quotaWindow{},
+		lastSweep: time.Now(),
+
+		wrapped:      wrapped,
+		wrappedChan:  make(chan types.Transaction),
+		rejected:     rejected,
+		rejectedChan: make(chan types.Transaction),
+
+		mAccepted: stats.GetCounterVec("quota.accepted", []string{"tenant"}),
+		mRejected: stats.GetCounterVec("quota.rejected", []string{"tenant"}),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// take attempts to consume a message of a given size from the quota of a
+// tenant, and returns the name of the limit that was reached if it fails.
+func (q *quota) take(tenant string, size int) string {
+	limits, exists := q.overrides[tenant]
+	if !exists {
+		limits = q.limits
+	}
+
+	q.windowsMut.Lock()
+	defer q.windowsMut.Unlock()
+
+	now := time.Now()
+	if now.Sub(q.lastSweep) >= q.interval {
+		for k, w := range q.windows {
+			if now.Sub(w.start) >= q.interval {
+				delete(q.windows, k)
+			}
+		}
+		q.lastSweep = now
+	}
+
+	w, exists := q.windows[tenant]
+	if !exists || now.Sub(w.start) >= q.interval {
+		w = &quotaWindow{start: now}
+		q.windows[tenant] = w
+	}
+	if limits.Count > 0 && w.count+1 > limits.Count {
+		return "count"
+	}
+	if limits.ByteSize > 0 && w.bytes+size > limits.ByteSize {
+		return "byte_size"
+	}
+	w.count++
+	w.bytes += size
+	return ""
+}
+
+func (q *quota) dispatch(target chan types.Transaction, msg types.Message) error {
+	resChan := make(chan types.Response)
+	select {
+	case target <- types.NewTransaction(msg, resChan):
+	case <-q.closeChan:
+		return types.ErrTypeClosed
+	}
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-q.closeChan:
+		return types.ErrTypeClosed
+	}
+}
+
+func (q *quota) outputs() []Type {
+	return []Type{q.wrapped, q.rejected}
+}
+
+func (q *quota) loop() {
+	wg := sync.WaitGroup{}
+	defer func() {
+		wg.Wait()
+		close(q.wrappedChan)
+		close(q.rejectedChan)
+		for _, o := range q.outputs() {
+			o.CloseAsync()
+		}
+		for _, o := range q.outputs() {
+			for o.WaitForClose(time.Second) != nil {
+			}
+		}
+		close(q.closedChan)
+	}()
+
+	sendLoop := func() {
+		defer wg.Done()
+		for {
+			var ts types.Transaction
+			var open bool
+			select {
+			case ts, open = <-q.transactionsIn:
+				if !open {
+					return
+				}
+			case <-q.closeChan:
+				return
+			}
+
+			var acceptedParts, rejectedParts []types.Part
+			_ = ts.Payload.Iter(func(i int, p types.Part) error {
+				tenant := q.tenant.String(i, ts.Payload)
+				if exceeded := q.take(tenant, len(p.Get())); exceeded != "" {
+					q.mRejected.With(tenant).Incr(1)
+					p = p.Copy()
+					p.Metadata().Set("quota_exceeded", exceeded)
+					rejectedParts = append(rejectedParts, p)
+				} else {
+					q.mAccepted.With(tenant).Incr(1)
+					acceptedParts = append(acceptedParts, p)
+				}
+				return nil
+			})
+
+			var owg errgroup.Group
+			if len(acceptedParts) > 0 {
+				msg := message.New(nil)
+				msg.SetAll(acceptedParts)
+				owg.Go(func() error {
+					return q.dispatch(q.wrappedChan, msg)
+				})
+			}
+			if len(rejectedParts) > 0 {
+				msg := message.New(nil)
+				msg.SetAll(rejectedParts)
+				owg.Go(func() error {
+					return q.dispatch(q.rejectedChan, msg)
+				})
+			}
+
+			var res types.Response = response.NewAck()
+			if err := owg.Wait(); err != nil {
+				if err == types.ErrTypeClosed {
+					return
+				}
+				res = response.NewError(err)
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-q.closeChan:
+				return
+			}
+		}
+	}
+
+	for i := 0; i < q.maxInFlight; i++ {
+		wg.Add(1)
+		go sendLoop()
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (q *quota) Consume(ts <-chan types.Transaction) error {
+	if q.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := q.wrapped.Consume(q.wrappedChan); err != nil {
+		return err
+	}
+	if err := q.rejected.Consume(q.rejectedChan); err != nil {
+		return err
+	}
+	q.transactionsIn = ts
+	go q.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether both outputs are currently
+// connected to their targets.
+func (q *quota) Connected() bool {
+	return q.wrapped.Connected() && q.rejected.Connected()
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// output. This value can be used to determine a sensible value for parent
+// outputs, but should not be relied upon as part of dispatcher logic.
+func (q *quota) MaxInFlight() (int, bool) {
+	return q.maxInFlight, true
+}
+
+// CloseAsync shuts down the quota output and stops processing requests.
+func (q *quota) CloseAsync() {
+	q.closeOnce.Do(func() {
+		close(q.closeChan)
+	})
+}
+
+// WaitForClose blocks until the quota output has closed down.
+func (q *quota) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-q.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota(t *testing.T) {
+	conf := NewQuotaConfig()
+	conf.Tenant = `${! meta("tenant") }`
+	conf.Interval = "1h"
+	conf.Count = 2
+	conf.Overrides = map[string]QuotaLimitConfig{
+		"big": {Count: 0, ByteSize: 8},
+	}
+
+	child, rejected := &mockOutput{}, &mockOutput{}
+	q, err := newQuota(conf, child, rejected, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, q.Consume(tChan))
+
+	resChan := make(chan types.Response)
+	send := func(parts ...[2]string) {
+		t.Helper()
+		msg := message.New(nil)
+		for _, p := range parts {
+			part := message.NewPart([]byte(p[1]))
+			part.Metadata().Set("tenant", p[0])
+			msg.Append(part)
+		}
+		select {
+		case tChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	readFrom := func(o *mockOutput) (contents []string, exceeded []string) {
+		t.Helper()
+		select {
+		case ts := <-o.ts:
+			_ = ts.Payload.Iter(func(i int, p types.Part) error {
+				contents = append(contents, string(p.Get()))
+				exceeded = append(exceeded, p.Metadata().Get("quota_exceeded"))
+				return nil
+			})
+			select {
+			case ts.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second * 5):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		return
+	}
+
+	readRes := func() {
+		t.Helper()
+		select {
+		case res := <-resChan:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	send([2]string{"a", "a1"}, [2]string{"b", "b1"}, [2]string{"a", "a2"})
+	contents, _ := readFrom(child)
+	assert.Equal(t, []string{"a1", "b1", "a2"}, contents)
+	readRes()
+
+	send([2]string{"a", "a3"}, [2]string{"b", "b2"}, [2]string{"big", "12345"}, [2]string{"big", "6789"})
+	var rejectedContents, exceeded []string
+	done := make(chan struct{})
+	go func() {
+		rejectedContents, exceeded = readFrom(rejected)
+		close(done)
+	}()
+	contents, _ = readFrom(child)
+	<-done
+	assert.Equal(t, []string{"b2", "12345"}, contents)
+	assert.Equal(t, []string{"a3", "6789"}, rejectedContents)
+	assert.Equal(t, []string{"count", "byte_size"}, exceeded)
+	readRes()
+
+	q.CloseAsync()
+	require.NoError(t, q.WaitForClose(time.Second*5))
+}
+
+func TestQuotaBadConfig(t *testing.T) {
+	conf := NewQuotaConfig()
+	conf.Interval = "0s"
+	_, err := newQuota(conf, &mockOutput{}, &mockOutput{}, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "interval must be greater than zero, got: 0s")
+}
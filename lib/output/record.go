@@ -0,0 +1,240 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/recording"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRecord] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			if conf.Record.Output == nil {
+				return nil, errors.New("cannot create a record output without a child output")
+			}
+			wrapped, err := New(*conf.Record.Output, mgr, log, stats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output '%v': %v", conf.Record.Output.Type, err)
+			}
+			recorder, err := conf.Record.NewRecorder()
+			if err != nil {
+				wrapped.CloseAsync()
+				return nil, err
+			}
+			return newRecord(recorder, wrapped, log, stats), nil
+		}),
+		Summary: `
+Writes messages to a child output while appending a sample of redacted copies,
+along with their metadata and the time at which they were sent, to a recording
+file that can be replayed with the ` + "[`replay_file`](/docs/components/inputs/replay_file)" + ` input.`,
+		Description: `
+This output is useful for capturing the real traffic sent to an output in order to reproduce issues or to test changes locally. Messages are forwarded to the child ` + "`output`" + ` as normal and the acknowledgement of each message is determined solely by the child. Since recordings are written to a local file the output should only be used with a sample of traffic from a single instance.
+
+Messages are recorded as they are sent to the child output, regardless of whether the child succeeds in writing them. Messages that fail to be redacted are logged and not recorded, and failures to record messages never affect the child output. The same recording file can be shared with ` + "`record`" + ` processors and outputs of the same path.`,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		FieldSpecs: append(
+			recording.FieldSpecs(),
+			docs.FieldCommon("output", "A child output that messages are written to and acknowledged by.").HasType(docs.FieldOutput),
+		),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Capturing Requests",
+				Summary: "In this example all requests are sent to an HTTP endpoint, and a tenth of them are recorded without their authorization header so that the traffic can later be replayed against a local version of the service.",
+				Config: `
+output:
+  record:
+    path: ./requests.jsonl.gz
+    sample_ratio: 0.1
+    redact: |
+      root = this
+      meta authorization = deleted()
+    output:
+      http_client:
+        url: http://example.com/v1/messages
+        verb: POST
+`,
+			},
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RecordConfig contains configuration values for the Record output type.
+type RecordConfig struct {
+	recording.Config `json:",inline" yaml:",inline"`
+	Output           *Config `json:"output" yaml:"output"`
+}
+
+// NewRecordConfig creates a new RecordConfig with default values.
+func NewRecordConfig() RecordConfig {
+	return RecordConfig{
+		Config: recording.NewConfig(),
+		Output: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyRecordConfig struct {
+	recording.Config `json:",inline" yaml:",inline"`
+	Output           interface{} `json:"output" yaml:"output"`
+}
+
+func (r RecordConfig) dummy() dummyRecordConfig {
+	dummy := dummyRecordConfig{
+		Config: r.Config,
+		Output: r.Output,
+	}
+	if r.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (r RecordConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (r RecordConfig) MarshalYAML() (interface{}, error) {
+	return r.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// record forwards messages to a child output and appends a sample of them to a
+// recording.
+type record struct {
+	stats metrics.Type
+	log   log.Modular
+
+	recorder *recording.Recorder
+	wrapped  Type
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+func newRecord(recorder *recording.Recorder, wrapped Type, log log.Modular, stats metrics.Type) *record {
+	ctx, done := context.WithCancel(context.Background())
+	return &record{
+		log:             log,
+		stats:           stats,
+		recorder:        recorder,
+		wrapped:         wrapped,
+		transactionsOut: make(chan types.Transaction),
+
+		ctx:        ctx,
+		done:       done,
+		closedChan: make(chan struct{}),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (r *record) loop() {
+	// Metrics paths
+	var (
+		mRecorded = r.stats.GetCounter("record.recorded")
+		mError    = r.stats.GetCounter("record.error")
+	)
+
+	defer func() {
+		close(r.transactionsOut)
+		r.wrapped.CloseAsync()
+		err := r.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = r.wrapped.WaitForClose(time.Second) {
+		}
+		if err = r.recorder.Close(); err != nil {
+			r.log.Errorf("Failed to close recording: %v\n", err)
+		}
+		close(r.closedChan)
+	}()
+
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-r.transactionsIn:
+			if !open {
+				return
+			}
+		case <-r.ctx.Done():
+			return
+		}
+
+		recorded, err := r.recorder.Record(ts.Payload)
+		mRecorded.Incr(int64(recorded))
+		if err != nil {
+			mError.Incr(1)
+			r.log.Errorf("Failed to record messages: %v\n", err)
+		}
+
+		select {
+		case r.transactionsOut <- ts:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (r *record) Consume(ts <-chan types.Transaction) error {
+	if r.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := r.wrapped.Consume(r.transactionsOut); err != nil {
+		return err
+	}
+	r.transactionsIn = ts
+	go r.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether the child output is currently
+// connected to its target.
+func (r *record) Connected() bool {
+	return r.wrapped.Connected()
+}
+
+func (r *record) MaxInFlight() (int, bool) {
+	return output.GetMaxInFlight(r.wrapped)
+}
+
+// CloseAsync shuts down the output and stops processing requests.
+func (r *record) CloseAsync() {
+	r.done()
+}
+
+// WaitForClose blocks until the output has closed down.
+func (r *record) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
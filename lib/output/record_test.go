@@ -0,0 +1,88 @@
+package output
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/recording"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordOutput(t *testing.T) {
+	conf := recording.NewConfig()
+	conf.Path = filepath.Join(t.TempDir(), "recording.jsonl")
+	conf.Redact = `meta key = deleted()`
+
+	recorder, err := conf.NewRecorder()
+	require.NoError(t, err)
+
+	child := &mockOutput{}
+	r := newRecord(recorder, child, log.Noop(), metrics.Noop())
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, r.Consume(tChan))
+
+	for _, content := range []string{"foo", "bar"} {
+		msg := message.New([][]byte{[]byte(content)})
+		msg.Get(0).Metadata().Set("key", "secret").Set("topic", "baz")
+
+		resChan := make(chan types.Response, 1)
+		select {
+		case tChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+
+		var res types.Response = response.NewAck()
+		if content == "bar" {
+			res = response.NewError(errors.New("child failed"))
+		}
+		readMirrorChild(t, child, content, res)
+
+		// The child output determines the response, but messages are recorded
+		// regardless.
+		if content == "bar" {
+			assert.EqualError(t, readFaultInjectionRes(t, resChan), "child failed")
+		} else {
+			assert.NoError(t, readFaultInjectionRes(t, resChan))
+		}
+	}
+
+	close(tChan)
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second*5))
+
+	data, err := ioutil.ReadFile(conf.Path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	for i, exp := range []string{"Zm9v", "YmFy"} {
+		assert.Contains(t, lines[i], `"content":"`+exp+`"`)
+		assert.Contains(t, lines[i], `"metadata":{"topic":"baz"}`)
+	}
+	assert.NotContains(t, string(data), "secret")
+}
+
+func TestRecordOutputConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRecord
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create output 'record': cannot create a record output without a child output")
+
+	child := NewConfig()
+	child.Type = TypeDrop
+	conf.Record.Output = &child
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create output 'record': a path must be specified")
+}
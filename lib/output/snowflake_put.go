@@ -0,0 +1,52 @@
+package output
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+)
+
+// SnowflakePutConfig contains configuration fields for the Snowflake PUT
+// output type.
+type SnowflakePutConfig struct {
+	Account               string             `json:"account" yaml:"account"`
+	Region                string             `json:"region" yaml:"region"`
+	User                  string             `json:"user" yaml:"user"`
+	PrivateKeyFile        string             `json:"private_key_file" yaml:"private_key_file"`
+	PrivateKeyPass        string             `json:"private_key_pass" yaml:"private_key_pass"`
+	Role                  string             `json:"role" yaml:"role"`
+	Database              string             `json:"database" yaml:"database"`
+	Warehouse             string             `json:"warehouse" yaml:"warehouse"`
+	Schema                string             `json:"schema" yaml:"schema"`
+	Stage                 string             `json:"stage" yaml:"stage"`
+	Path                  string             `json:"path" yaml:"path"`
+	Compression           string             `json:"compression" yaml:"compression"`
+	UploadParallelThreads int                `json:"upload_parallel_threads" yaml:"upload_parallel_threads"`
+	Snowpipe              string             `json:"snowpipe" yaml:"snowpipe"`
+	CopyInto              string             `json:"copy_into" yaml:"copy_into"`
+	FileFormat            string             `json:"file_format" yaml:"file_format"`
+	MaxInFlight           int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching              batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewSnowflakePutConfig creates a new SnowflakePutConfig with default values.
+func NewSnowflakePutConfig() SnowflakePutConfig {
+	return SnowflakePutConfig{
+		Account:               "",
+		Region:                "",
+		User:                  "",
+		PrivateKeyFile:        "",
+		PrivateKeyPass:        "",
+		Role:                  "",
+		Database:              "",
+		Warehouse:             "",
+		Schema:                "",
+		Stage:                 "",
+		Path:                  "",
+		Compression:           "gzip",
+		UploadParallelThreads: 4,
+		Snowpipe:              "",
+		CopyInto:              "",
+		FileFormat:            "TYPE = JSON",
+		MaxInFlight:           1,
+		Batching:              batch.NewPolicyConfig(),
+	}
+}
@@ -16,11 +16,13 @@ func init() {
 		constructor: fromSimpleConstructor(NewSocket),
 		Summary: `
 Connects to a (tcp/udp/unix) server and sends a continuous stream of data, dividing messages according to the specified codec.`,
+		Description: `
+The ` + "`unixgram`" + ` network sends data as datagrams to a unix socket, which is useful for targets such as a local syslog daemon. On Linux a unix address beginning with ` + "`@`" + ` refers to a socket within the abstract namespace, such as ` + "`@benthos`" + `.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("network", "The network type to connect as.").HasOptions(
-				"unix", "tcp", "udp",
+				"unix", "unixgram", "tcp", "udp",
 			),
-			docs.FieldCommon("address", "The address (or path) to connect to.", "/tmp/benthos.sock", "localhost:9000"),
+			docs.FieldCommon("address", "The address (or path) to connect to.", "/tmp/benthos.sock", "@benthos", "localhost:9000"),
 			codec.WriterDocs,
 		},
 		Categories: []Category{
@@ -1,17 +1,26 @@
 package output
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/fatih/color"
 )
 
 //------------------------------------------------------------------------------
@@ -36,9 +45,21 @@ Whereas a multipart message [ "foo", "bar", "baz" ] would be written as:
 foo\n
 bar\n
 baz\n\n
-` + "```" + ``,
+` + "```" + `
+
+### Formatting
+
+The field ` + "`format`" + ` can be used in order to make messages easier to read when debugging a pipeline interactively. Messages that are not valid JSON are written unchanged by the ` + "`json_pretty`" + ` and ` + "`json_color`" + ` formats. The ` + "`table`" + ` format writes each batch of JSON objects as rows of a table with a column per field, prefixed by a header row.
+
+When piping the output of Benthos into other tools you can run Benthos with the ` + "`--quiet`" + ` flag, which disables logging and forces the ` + "`raw`" + ` format regardless of this field.`,
 		FieldSpecs: docs.FieldSpecs{
 			codec.WriterDocs.AtVersion("3.46.0"),
+			docs.FieldCommon("format", "The format in which the contents of messages are written.").HasAnnotatedOptions(
+				"raw", "Write the contents of messages unchanged.",
+				"json_pretty", "Write JSON documents with indentation.",
+				"json_color", "Write JSON documents with indentation and syntax highlighting when stdout is a terminal.",
+				"table", "Write batches of JSON objects as a table.",
+			).AtVersion("3.47.0"),
 			docs.FieldDeprecated("delimiter"),
 		},
 		Categories: []Category{
@@ -51,15 +72,17 @@ baz\n\n
 
 // STDOUTConfig contains configuration fields for the stdout based output type.
 type STDOUTConfig struct {
-	Codec string `json:"codec" yaml:"codec"`
-	Delim string `json:"delimiter" yaml:"delimiter"`
+	Codec  string `json:"codec" yaml:"codec"`
+	Format string `json:"format" yaml:"format"`
+	Delim  string `json:"delimiter" yaml:"delimiter"`
 }
 
 // NewSTDOUTConfig creates a new STDOUTConfig with default values.
 func NewSTDOUTConfig() STDOUTConfig {
 	return STDOUTConfig{
-		Codec: "lines",
-		Delim: "",
+		Codec:  "lines",
+		Format: "raw",
+		Delim:  "",
 	}
 }
 
@@ -70,7 +93,7 @@ func NewSTDOUT(conf Config, mgr types.Manager, log log.Modular, stats metrics.Ty
 	if len(conf.STDOUT.Delim) > 0 {
 		conf.STDOUT.Codec = "delim:" + conf.STDOUT.Delim
 	}
-	f, err := newStdoutWriter(conf.STDOUT.Codec, log, stats)
+	f, err := newStdoutWriter(conf.STDOUT.Codec, conf.STDOUT.Format, os.Stdout, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -86,24 +109,37 @@ func NewSTDOUT(conf Config, mgr types.Manager, log log.Modular, stats metrics.Ty
 
 type stdoutWriter struct {
 	handle  codec.Writer
+	format  func(msg types.Message) ([][]byte, error)
 	shutSig *shutdown.Signaller
 }
 
-func newStdoutWriter(codecStr string, log log.Modular, stats metrics.Type) (*stdoutWriter, error) {
+func newStdoutWriter(codecStr, format string, out io.WriteCloser, log log.Modular, stats metrics.Type) (*stdoutWriter, error) {
 	codec, _, err := codec.GetWriter(codecStr)
 	if err != nil {
 		return nil, err
 	}
 
-	handle, err := codec(os.Stdout)
+	handle, err := codec(out)
 	if err != nil {
 		return nil, err
 	}
 
-	return &stdoutWriter{
+	w := &stdoutWriter{
 		handle:  handle,
 		shutSig: shutdown.NewSignaller(),
-	}, nil
+	}
+	switch format {
+	case "", "raw":
+	case "json_pretty":
+		w.format = formatPartsWith(prettyJSON)
+	case "json_color":
+		w.format = formatPartsWith(colorJSON)
+	case "table":
+		w.format = formatTable
+	default:
+		return nil, fmt.Errorf("format was not recognised: %v", format)
+	}
+	return w, nil
 }
 
 func (w *stdoutWriter) ConnectWithContext(ctx context.Context) error {
@@ -111,11 +147,23 @@ func (w *stdoutWriter) ConnectWithContext(ctx context.Context) error {
 }
 
 func (w *stdoutWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
-	err := writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
-		return w.handle.Write(ctx, p)
-	})
-	if err != nil {
-		return err
+	if w.format != nil {
+		lines, err := w.format(msg)
+		if err != nil {
+			return err
+		}
+		for _, l := range lines {
+			if err := w.handle.Write(ctx, message.NewPart(l)); err != nil {
+				return err
+			}
+		}
+	} else {
+		err := writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+			return w.handle.Write(ctx, p)
+		})
+		if err != nil {
+			return err
+		}
 	}
 	if msg.Len() > 1 {
 		if w.handle != nil {
@@ -131,3 +179,158 @@ func (w *stdoutWriter) CloseAsync() {
 func (w *stdoutWriter) WaitForClose(timeout time.Duration) error {
 	return nil
 }
+
+//------------------------------------------------------------------------------
+
+func formatPartsWith(fn func(b []byte) []byte) func(msg types.Message) ([][]byte, error) {
+	return func(msg types.Message) ([][]byte, error) {
+		lines := make([][]byte, 0, msg.Len())
+		_ = msg.Iter(func(i int, p types.Part) error {
+			lines = append(lines, fn(p.Get()))
+			return nil
+		})
+		return lines, nil
+	}
+}
+
+func prettyJSON(b []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return b
+	}
+	return buf.Bytes()
+}
+
+var (
+	colorJSONKey    = color.New(color.FgBlue, color.Bold)
+	colorJSONString = color.New(color.FgGreen)
+	colorJSONNumber = color.New(color.FgCyan)
+	colorJSONOther  = color.New(color.FgMagenta)
+)
+
+func colorJSON(b []byte) []byte {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return b
+	}
+	var buf bytes.Buffer
+	writeColorJSON(&buf, v, "")
+	return buf.Bytes()
+}
+
+func writeColorJSON(buf *bytes.Buffer, v interface{}, indent string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{\n")
+		for i, k := range keys {
+			keyBytes, _ := json.Marshal(k)
+			buf.WriteString(indent + "  ")
+			colorJSONKey.Fprint(buf, string(keyBytes))
+			buf.WriteString(": ")
+			writeColorJSON(buf, t[k], indent+"  ")
+			if i < len(keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, e := range t {
+			buf.WriteString(indent + "  ")
+			writeColorJSON(buf, e, indent+"  ")
+			if i < len(t)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	case string:
+		strBytes, _ := json.Marshal(t)
+		colorJSONString.Fprint(buf, string(strBytes))
+	case json.Number:
+		colorJSONNumber.Fprint(buf, t.String())
+	default:
+		otherBytes, _ := json.Marshal(t)
+		colorJSONOther.Fprint(buf, string(otherBytes))
+	}
+}
+
+// formatTable writes a batch of JSON objects as the rows of a table with a
+// column for each field found in the batch. Messages that are not JSON objects
+// are written within the first column.
+func formatTable(msg types.Message) ([][]byte, error) {
+	rows := make([]map[string]interface{}, msg.Len())
+	columnsSet := map[string]struct{}{}
+	_ = msg.Iter(func(i int, p types.Part) error {
+		v, err := p.JSON()
+		if err != nil {
+			return nil
+		}
+		if obj, ok := v.(map[string]interface{}); ok {
+			rows[i] = obj
+			for k := range obj {
+				columnsSet[k] = struct{}{}
+			}
+		}
+		return nil
+	})
+
+	columns := make([]string, 0, len(columnsSet))
+	for k := range columnsSet {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	if len(columns) > 0 {
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	}
+	_ = msg.Iter(func(i int, p types.Part) error {
+		if rows[i] == nil {
+			fmt.Fprintln(tw, string(p.Get()))
+			return nil
+		}
+		values := make([]string, len(columns))
+		for j, k := range columns {
+			switch v := rows[i][k].(type) {
+			case nil:
+				if _, exists := rows[i][k]; exists {
+					values[j] = "null"
+				}
+			case string:
+				values[j] = v
+			default:
+				vBytes, _ := json.Marshal(v)
+				values[j] = string(vBytes)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+		return nil
+	})
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+
+	var lines [][]byte
+	for _, l := range bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n")) {
+		lines = append(lines, bytes.TrimRight(l, " "))
+	}
+	return lines, nil
+}
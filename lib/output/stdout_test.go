@@ -0,0 +1,82 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopCloseBuffer struct {
+	bytes.Buffer
+}
+
+func (n *noopCloseBuffer) Close() error {
+	return nil
+}
+
+func TestSTDOUTFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  [][]byte
+		output string
+	}{
+		{
+			name:   "raw",
+			format: "raw",
+			input:  [][]byte{[]byte(`{"id":1}`)},
+			output: "{\"id\":1}\n",
+		},
+		{
+			name:   "json pretty",
+			format: "json_pretty",
+			input:  [][]byte{[]byte(`{"id":1,"tags":["a"]}`)},
+			output: "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}\n",
+		},
+		{
+			name:   "json pretty not json",
+			format: "json_pretty",
+			input:  [][]byte{[]byte(`not json`)},
+			output: "not json\n",
+		},
+		{
+			name:   "json color without terminal",
+			format: "json_color",
+			input:  [][]byte{[]byte(`{"name":"foo","id":1,"ok":true}`)},
+			output: "{\n  \"id\": 1,\n  \"name\": \"foo\",\n  \"ok\": true\n}\n",
+		},
+		{
+			name:   "table",
+			format: "table",
+			input: [][]byte{
+				[]byte(`{"id":1,"name":"foo"}`),
+				[]byte(`{"id":22,"tags":["a","b"]}`),
+				[]byte(`not json`),
+			},
+			output: "ID  NAME  TAGS\n1   foo\n22        [\"a\",\"b\"]\nnot json\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			buf := &noopCloseBuffer{}
+			w, err := newStdoutWriter("lines", test.format, buf, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			require.NoError(t, w.WriteWithContext(context.Background(), message.New(test.input)))
+			assert.Equal(t, test.output, buf.String())
+		})
+	}
+}
+
+func TestSTDOUTBadFormat(t *testing.T) {
+	_, err := newStdoutWriter("lines", "nope", &noopCloseBuffer{}, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "format was not recognised: nope")
+}
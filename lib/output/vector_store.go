@@ -0,0 +1,165 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// vectorDoc is a document with an embedding vector to be upserted into a
+// vector store.
+type vectorDoc struct {
+	id      string
+	vector  []float64
+	payload map[string]interface{}
+}
+
+// vectorDocGroup is a set of documents to be upserted into the same collection,
+// namespace or table, along with the indexes of all messages of the batch that
+// belong to it in order to report errors.
+type vectorDocGroup struct {
+	target  string
+	docs    []vectorDoc
+	indexes []int
+}
+
+// vectorDocBuilder extracts documents with embedding vectors from the messages
+// of a batch for the vector store outputs.
+type vectorDocBuilder struct {
+	id             *field.Expression
+	target         *field.Expression
+	vectorMapping  *mapping.Executor
+	payloadMapping *mapping.Executor
+}
+
+func newVectorDocBuilder(id, target, vectorMapping, payloadMapping string) (*vectorDocBuilder, error) {
+	if id == "" {
+		return nil, errors.New("an id must be specified")
+	}
+	if vectorMapping == "" {
+		return nil, errors.New("a vector_mapping must be specified")
+	}
+
+	b := &vectorDocBuilder{}
+	var err error
+	if b.id, err = bloblang.NewField(id); err != nil {
+		return nil, fmt.Errorf("failed to parse id expression: %v", err)
+	}
+	if b.target, err = bloblang.NewField(target); err != nil {
+		return nil, fmt.Errorf("failed to parse target expression: %v", err)
+	}
+	if b.vectorMapping, err = bloblang.NewMapping("", vectorMapping); err != nil {
+		return nil, fmt.Errorf("failed to parse vector_mapping: %w", err)
+	}
+	if payloadMapping != "" {
+		if b.payloadMapping, err = bloblang.NewMapping("", payloadMapping); err != nil {
+			return nil, fmt.Errorf("failed to parse payload mapping: %w", err)
+		}
+	}
+	return b, nil
+}
+
+func (b *vectorDocBuilder) doc(index int, msg types.Message) (vectorDoc, error) {
+	d := vectorDoc{
+		id: b.id.String(index, msg),
+	}
+	if d.id == "" {
+		return d, errors.New("id is empty")
+	}
+
+	vPart, err := b.vectorMapping.MapPart(index, msg)
+	if err != nil {
+		return d, fmt.Errorf("vector mapping failed: %w", err)
+	}
+	vJSON, err := vPart.JSON()
+	if err != nil {
+		return d, fmt.Errorf("vector mapping returned non-structured result: %w", err)
+	}
+	vArr, ok := vJSON.([]interface{})
+	if !ok || len(vArr) == 0 {
+		return d, fmt.Errorf("vector mapping must return a non-empty array of numbers, got %T", vJSON)
+	}
+	d.vector = make([]float64, len(vArr))
+	for i, v := range vArr {
+		if d.vector[i], err = query.IGetNumber(v); err != nil {
+			return d, fmt.Errorf("vector element %v: %w", i, err)
+		}
+	}
+
+	if b.payloadMapping == nil {
+		return d, nil
+	}
+	pPart, err := b.payloadMapping.MapPart(index, msg)
+	if err != nil {
+		return d, fmt.Errorf("payload mapping failed: %w", err)
+	}
+	pJSON, err := pPart.JSON()
+	if err != nil {
+		return d, fmt.Errorf("payload mapping returned non-structured result: %w", err)
+	}
+	if d.payload, ok = pJSON.(map[string]interface{}); !ok {
+		return d, fmt.Errorf("payload mapping must return an object, got %T", pJSON)
+	}
+	return d, nil
+}
+
+// groups extracts the documents of a batch grouped by their target, in the
+// order that each target first appears. When a document ID appears more than
+// once within a group only the last document is kept, as it would supersede
+// the others. Messages that fail extraction are reported within errs.
+func (b *vectorDocBuilder) groups(msg types.Message) (groups []vectorDocGroup, errs []error) {
+	errs = make([]error, msg.Len())
+	groupIndexes := map[string]int{}
+	docIndexes := map[string]map[string]int{}
+
+	for i := 0; i < msg.Len(); i++ {
+		d, err := b.doc(i, msg)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		target := b.target.String(i, msg)
+		gIndex, exists := groupIndexes[target]
+		if !exists {
+			gIndex = len(groups)
+			groupIndexes[target] = gIndex
+			docIndexes[target] = map[string]int{}
+			groups = append(groups, vectorDocGroup{target: target})
+		}
+		groups[gIndex].indexes = append(groups[gIndex].indexes, i)
+		if dIndex, exists := docIndexes[target][d.id]; exists {
+			groups[gIndex].docs[dIndex] = d
+			continue
+		}
+		docIndexes[target][d.id] = len(groups[gIndex].docs)
+		groups[gIndex].docs = append(groups[gIndex].docs, d)
+	}
+	return
+}
+
+// vectorStoreWrite upserts the documents of a batch group by group with fn,
+// where the messages of a group that fails are given the error of the group,
+// and returns an error that supports index specific error handling.
+func vectorStoreWrite(
+	b *vectorDocBuilder, msg types.Message, fn func(group vectorDocGroup) error,
+) error {
+	groups, errs := b.groups(msg)
+	for _, g := range groups {
+		if err := fn(g); err != nil {
+			for _, i := range g.indexes {
+				errs[i] = err
+			}
+		}
+	}
+	return writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
+		return errs[i]
+	})
+}
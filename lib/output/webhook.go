@@ -0,0 +1,470 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWebhook] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			w, err := newWebhookWriter(conf.Webhook, mgr, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncWriter(TypeWebhook, conf.Webhook.MaxInFlight, w, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Async:   true,
+		Summary: `
+Delivers each message to a dynamic list of subscriber URLs as a signed HTTP POST request.`,
+		Description: `
+The list of subscribers is resolved for each message either with a [Bloblang mapping](/docs/guides/bloblang/about/) set in ` + "`subscribers`" + `, which allows the list to be derived from the message or its metadata, or by reading a JSON array from a cache resource with the ` + "`cache` and `cache_key`" + ` fields.
+
+Each element of the list can either be a URL string or an object of the following form:
+
+` + "```json" + `
+{
+  "url": "https://example.com/hooks",
+  "secret": "a secret specific to this subscriber",
+  "headers": { "X-Custom": "foo" },
+  "body": { "summary": "a body specific to this subscriber" }
+}
+` + "```" + `
+
+Only the ` + "`url`" + ` field is required. When a ` + "`body`" + ` is provided it is sent instead of the message contents, strings are sent as they are and any other value is serialised as JSON, which allows a subscribers mapping to template the payload of each endpoint.
+
+### Signatures
+
+When a subscriber has a secret, or a default ` + "`secret`" + ` is configured, the header set by ` + "`signature_header`" + ` is added to the request with a value of the form ` + "`sha256=<hex digest>`" + `, where the digest is the HMAC-SHA256 of the request body keyed with the secret.
+
+### Delivery
+
+Subscribers of a message are delivered to in parallel. A delivery succeeds when the subscriber responds with a 2XX status code and is otherwise retried according to ` + "`max_retries` and `backoff`" + `, with the exception of 4XX status codes other than 408 and 429, which are not retried.
+
+If the delivery to any subscriber fails the message is rejected, which usually results in the message being delivered again to all of its subscribers. Therefore subscribers should expect to receive duplicate deliveries.
+
+### Metrics
+
+The following metrics are emitted with a label ` + "`url`" + ` for each subscriber:
+
+` + "```text" + `
+- webhook.delivery.success
+- webhook.delivery.error
+- webhook.delivery.retry
+- webhook.delivery.latency
+` + "```" + ``,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Subscribers From Metadata",
+				Summary: "In this example the subscribers of each message are listed as a JSON array in the metadata field `subscribers`, and each subscriber is sent a payload containing only the fields it has subscribed to.",
+				Config: `
+output:
+  webhook:
+    subscribers: |
+      root = meta("subscribers").parse_json().map_each(sub -> {
+        "url": sub.url,
+        "secret": sub.secret,
+        "body": this.filter(kv -> sub.fields.contains(kv.key)),
+      })
+`,
+			},
+			{
+				Title:   "Subscribers From a Cache",
+				Summary: "Here the subscribers of each tenant are stored in a Redis cache as a JSON array keyed by the tenant ID.",
+				Config: `
+output:
+  webhook:
+    cache: subscriptions
+    cache_key: ${! json("tenant_id") }
+    secret: ${WEBHOOK_SECRET}
+    max_retries: 5
+
+cache_resources:
+  - label: subscriptions
+    redis:
+      url: tcp://localhost:6379
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"subscribers",
+				"A [Bloblang mapping](/docs/guides/bloblang/about/) that results in an array of subscribers for a message. Either this or `cache` must be set.",
+				`root = meta("subscribers").split(",")`,
+			).Linter(docs.LintBloblangMapping),
+			docs.FieldCommon("cache", "A [cache resource](/docs/components/caches/about) to read a JSON array of subscribers from. Either this or `subscribers` must be set."),
+			docs.FieldCommon("cache_key", "The key to read subscribers from the `cache` with.").IsInterpolated(),
+			docs.FieldCommon("secret", "A default secret to sign requests with for subscribers that do not specify their own. If left empty requests to these subscribers are not signed."),
+			docs.FieldAdvanced("signature_header", "The header to set the request signature in."),
+			docs.FieldAdvanced("headers", "A map of headers to add to each request.").IsInterpolated().Map(),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a subscriber to respond to a request."),
+			btls.FieldSpec(),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+		}.Merge(retries.FieldSpecs()),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WebhookConfig contains configuration fields for the webhook output type.
+type WebhookConfig struct {
+	Subscribers     string            `json:"subscribers" yaml:"subscribers"`
+	Cache           string            `json:"cache" yaml:"cache"`
+	CacheKey        string            `json:"cache_key" yaml:"cache_key"`
+	Secret          string            `json:"secret" yaml:"secret"`
+	SignatureHeader string            `json:"signature_header" yaml:"signature_header"`
+	Headers         map[string]string `json:"headers" yaml:"headers"`
+	Timeout         string            `json:"timeout" yaml:"timeout"`
+	TLS             btls.Config       `json:"tls" yaml:"tls"`
+	MaxInFlight     int               `json:"max_in_flight" yaml:"max_in_flight"`
+	retries.Config  `json:",inline" yaml:",inline"`
+}
+
+// NewWebhookConfig creates a new WebhookConfig with default values.
+func NewWebhookConfig() WebhookConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "30s"
+	rConf.Backoff.MaxElapsedTime = "5m"
+
+	return WebhookConfig{
+		Subscribers:     "",
+		Cache:           "",
+		CacheKey:        "",
+		Secret:          "",
+		SignatureHeader: "X-Webhook-Signature",
+		Headers:         map[string]string{},
+		Timeout:         "5s",
+		TLS:             btls.NewConfig(),
+		MaxInFlight:     64,
+		Config:          rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type webhookSubscriber struct {
+	URL     string            `json:"url"`
+	Secret  string            `json:"secret"`
+	Headers map[string]string `json:"headers"`
+	Body    interface{}       `json:"body"`
+}
+
+func (s *webhookSubscriber) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		s.URL = url
+		return nil
+	}
+	type subscriberObj webhookSubscriber
+	var obj subscriberObj
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*s = webhookSubscriber(obj)
+	return nil
+}
+
+// errWebhookPermanent wraps delivery errors that should not be retried.
+type errWebhookPermanent struct {
+	err error
+}
+
+func (e errWebhookPermanent) Error() string {
+	return e.err.Error()
+}
+
+type webhookWriter struct {
+	conf WebhookConfig
+	mgr  types.Manager
+	log  log.Modular
+
+	subscribers *mapping.Executor
+	cacheKey    *field.Expression
+	headers     map[string]*field.Expression
+	client      *http.Client
+	boffCtor    func() backoff.BackOff
+
+	mSuccess metrics.StatCounterVec
+	mError   metrics.StatCounterVec
+	mRetry   metrics.StatCounterVec
+	mLatency metrics.StatTimerVec
+}
+
+func newWebhookWriter(conf WebhookConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*webhookWriter, error) {
+	if (conf.Subscribers == "") == (conf.Cache == "") {
+		return nil, errors.New("exactly one of subscribers or cache must be set")
+	}
+	if conf.Cache != "" && conf.CacheKey == "" {
+		return nil, errors.New("a cache_key is required when reading subscribers from a cache")
+	}
+
+	w := &webhookWriter{
+		conf:    conf,
+		mgr:     mgr,
+		log:     log,
+		headers: map[string]*field.Expression{},
+
+		mSuccess: stats.GetCounterVec("webhook.delivery.success", []string{"url"}),
+		mError:   stats.GetCounterVec("webhook.delivery.error", []string{"url"}),
+		mRetry:   stats.GetCounterVec("webhook.delivery.retry", []string{"url"}),
+		mLatency: stats.GetTimerVec("webhook.delivery.latency", []string{"url"}),
+	}
+
+	var err error
+	if conf.Subscribers != "" {
+		if w.subscribers, err = bloblang.NewMapping("", conf.Subscribers); err != nil {
+			return nil, fmt.Errorf("failed to parse subscribers mapping: %w", err)
+		}
+	} else {
+		if err = interop.ProbeCache(context.Background(), mgr, conf.Cache); err != nil {
+			return nil, err
+		}
+		if w.cacheKey, err = bloblang.NewField(conf.CacheKey); err != nil {
+			return nil, fmt.Errorf("failed to parse cache_key expression: %w", err)
+		}
+	}
+	for k, v := range conf.Headers {
+		if w.headers[k], err = bloblang.NewField(v); err != nil {
+			return nil, fmt.Errorf("failed to parse header '%v' expression: %w", k, err)
+		}
+	}
+
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.TLS.Enabled {
+		if transport.TLSClientConfig, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	w.client = &http.Client{Timeout: timeout, Transport: transport}
+
+	if w.boffCtor, err = conf.Config.GetCtor(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *webhookWriter) resolveSubscribers(index int, msg types.Message) ([]webhookSubscriber, error) {
+	var raw []byte
+	if w.subscribers != nil {
+		p, err := w.subscribers.MapPart(index, msg)
+		if err != nil {
+			return nil, fmt.Errorf("subscribers mapping failed: %w", err)
+		}
+		if p == nil {
+			return nil, nil
+		}
+		raw = p.Get()
+	} else {
+		key := w.cacheKey.String(index, msg)
+		var cerr error
+		if err := interop.AccessCache(context.Background(), w.mgr, w.conf.Cache, func(cache types.Cache) {
+			raw, cerr = cache.Get(key)
+		}); err != nil {
+			return nil, err
+		}
+		if cerr != nil {
+			if errors.Is(cerr, types.ErrKeyNotFound) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read subscribers from cache: %w", cerr)
+		}
+	}
+
+	var subs []webhookSubscriber
+	if err := json.Unmarshal(raw, &subs); err != nil {
+		return nil, fmt.Errorf("failed to parse subscribers as a JSON array: %w", err)
+	}
+	for i, s := range subs {
+		if s.URL == "" {
+			return nil, fmt.Errorf("subscriber %v is missing a url", i)
+		}
+	}
+	return subs, nil
+}
+
+func (w *webhookWriter) sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *webhookWriter) send(ctx context.Context, sub webhookSubscriber, body []byte, headers http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return errWebhookPermanent{err}
+	}
+	req.Header = headers.Clone()
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+	secret := sub.Secret
+	if secret == "" {
+		secret = w.conf.Secret
+	}
+	if secret != "" {
+		req.Header.Set(w.conf.SignatureHeader, w.sign(secret, body))
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	err = types.ErrUnexpectedHTTPRes{Code: res.StatusCode, S: res.Status}
+	if res.StatusCode >= 400 && res.StatusCode < 500 &&
+		res.StatusCode != http.StatusRequestTimeout &&
+		res.StatusCode != http.StatusTooManyRequests {
+		return errWebhookPermanent{err}
+	}
+	return err
+}
+
+func (w *webhookWriter) deliver(ctx context.Context, sub webhookSubscriber, body []byte, headers http.Header) error {
+	if sub.Body != nil {
+		if str, ok := sub.Body.(string); ok {
+			body = []byte(str)
+		} else {
+			var err error
+			if body, err = json.Marshal(sub.Body); err != nil {
+				return fmt.Errorf("failed to serialise body: %w", err)
+			}
+		}
+	}
+
+	boff := w.boffCtor()
+	for {
+		t0 := time.Now()
+		err := w.send(ctx, sub, body, headers)
+		w.mLatency.With(sub.URL).Timing(time.Since(t0).Nanoseconds())
+		if err == nil {
+			w.mSuccess.With(sub.URL).Incr(1)
+			return nil
+		}
+
+		var permErr errWebhookPermanent
+		if errors.As(err, &permErr) {
+			w.mError.With(sub.URL).Incr(1)
+			return permErr.err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			w.mError.With(sub.URL).Incr(1)
+			return err
+		}
+		w.mRetry.With(sub.URL).Incr(1)
+		w.log.Debugf("Retrying webhook delivery to %v: %v\n", sub.URL, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			w.mError.With(sub.URL).Incr(1)
+			return ctx.Err()
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext does nothing as connections are established per request.
+func (w *webhookWriter) ConnectWithContext(ctx context.Context) error {
+	return nil
+}
+
+// WriteWithContext delivers each message of a batch to its subscribers.
+func (w *webhookWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+		subs, err := w.resolveSubscribers(i, msg)
+		if err != nil {
+			return err
+		}
+		if len(subs) == 0 {
+			return nil
+		}
+
+		headers := http.Header{}
+		headers.Set("Content-Type", "application/json")
+		for k, v := range w.headers {
+			headers.Set(k, v.String(i, msg))
+		}
+
+		body := p.Get()
+		errs := make([]error, len(subs))
+
+		var wg sync.WaitGroup
+		wg.Add(len(subs))
+		for j, sub := range subs {
+			go func(j int, sub webhookSubscriber) {
+				defer wg.Done()
+				errs[j] = w.deliver(ctx, sub, body, headers)
+			}(j, sub)
+		}
+		wg.Wait()
+
+		var failed []string
+		for j, err := range errs {
+			if err != nil {
+				w.log.Errorf("Failed to deliver webhook to %v: %v\n", subs[j].URL, err)
+				failed = append(failed, subs[j].URL)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to deliver to subscribers: %v", strings.Join(failed, ", "))
+		}
+		return nil
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (w *webhookWriter) CloseAsync() {
+	w.client.CloseIdleConnections()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (w *webhookWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
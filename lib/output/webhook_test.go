@@ -0,0 +1,198 @@
+package output_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookReceived struct {
+	body      string
+	signature string
+	header    string
+}
+
+func webhookTestServer(t *testing.T, status func(attempt int) int) (*httptest.Server, func() []webhookReceived) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var received []webhookReceived
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mut.Lock()
+		received = append(received, webhookReceived{
+			body:      string(body),
+			signature: r.Header.Get("X-Webhook-Signature"),
+			header:    r.Header.Get("X-Foo"),
+		})
+		attempt := len(received)
+		mut.Unlock()
+
+		w.WriteHeader(status(attempt))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []webhookReceived {
+		mut.Lock()
+		defer mut.Unlock()
+		return append([]webhookReceived{}, received...)
+	}
+}
+
+func webhookSign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookWrite(t *testing.T, conf output.Config, mgr types.Manager, msg types.Message) error {
+	t.Helper()
+
+	w, err := output.New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		w.CloseAsync()
+		assert.NoError(t, w.WaitForClose(time.Second*5))
+	}()
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, w.Consume(tChan))
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestWebhookMappingSubscribers(t *testing.T) {
+	serverA, receivedA := webhookTestServer(t, func(int) int { return http.StatusOK })
+	serverB, receivedB := webhookTestServer(t, func(int) int { return http.StatusNoContent })
+
+	conf := output.NewConfig()
+	conf.Type = output.TypeWebhook
+	conf.Webhook.Subscribers = `root = [
+  meta("a"),
+  { "url": meta("b"), "secret": "bsecret", "headers": { "X-Foo": "bar" }, "body": { "id": this.id } },
+]`
+	conf.Webhook.Secret = "defaultsecret"
+
+	part := message.NewPart([]byte(`{"id":"foo","value":10}`))
+	part.Metadata().Set("a", serverA.URL).Set("b", serverB.URL)
+
+	msg := message.New(nil)
+	msg.Append(part)
+
+	require.NoError(t, webhookWrite(t, conf, nil, msg))
+
+	assert.Equal(t, []webhookReceived{
+		{
+			body:      `{"id":"foo","value":10}`,
+			signature: webhookSign("defaultsecret", `{"id":"foo","value":10}`),
+		},
+	}, receivedA())
+	assert.Equal(t, []webhookReceived{
+		{
+			body:      `{"id":"foo"}`,
+			signature: webhookSign("bsecret", `{"id":"foo"}`),
+			header:    "bar",
+		},
+	}, receivedB())
+}
+
+func TestWebhookCacheSubscribers(t *testing.T) {
+	server, received := webhookTestServer(t, func(int) int { return http.StatusOK })
+
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["subs"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	c, err := mgr.GetCache("subs")
+	require.NoError(t, err)
+	require.NoError(t, c.Set("tenant1", []byte(`["`+server.URL+`"]`)))
+
+	conf := output.NewConfig()
+	conf.Type = output.TypeWebhook
+	conf.Webhook.Cache = "subs"
+	conf.Webhook.CacheKey = `${! json("tenant") }`
+
+	require.NoError(t, webhookWrite(t, conf, mgr, message.New([][]byte{
+		[]byte(`{"tenant":"tenant1"}`),
+	})))
+	require.NoError(t, webhookWrite(t, conf, mgr, message.New([][]byte{
+		[]byte(`{"tenant":"tenant2"}`),
+	})))
+
+	assert.Equal(t, []webhookReceived{
+		{body: `{"tenant":"tenant1"}`},
+	}, received())
+}
+
+func TestWebhookRetries(t *testing.T) {
+	server, received := webhookTestServer(t, func(attempt int) int {
+		if attempt < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+
+	conf := output.NewConfig()
+	conf.Type = output.TypeWebhook
+	conf.Webhook.Subscribers = `root = [ "` + server.URL + `" ]`
+	conf.Webhook.Backoff.InitialInterval = "1ms"
+	conf.Webhook.Backoff.MaxInterval = "1ms"
+
+	require.NoError(t, webhookWrite(t, conf, nil, message.New([][]byte{[]byte(`hello`)})))
+	assert.Len(t, received(), 3)
+}
+
+func TestWebhookPermanentFailure(t *testing.T) {
+	server, received := webhookTestServer(t, func(int) int { return http.StatusNotFound })
+
+	conf := output.NewConfig()
+	conf.Type = output.TypeWebhook
+	conf.Webhook.Subscribers = `root = [ "` + server.URL + `" ]`
+	conf.Webhook.Backoff.InitialInterval = "1ms"
+	conf.Webhook.Backoff.MaxInterval = "1ms"
+
+	err := webhookWrite(t, conf, nil, message.New([][]byte{[]byte(`hello`)}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), server.URL)
+	assert.Len(t, received(), 1)
+}
+
+func TestWebhookBadConfig(t *testing.T) {
+	conf := output.NewConfig()
+	conf.Type = output.TypeWebhook
+
+	_, err := output.New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exactly one of subscribers or cache must be set")
+}
@@ -0,0 +1,544 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gorilla/websocket"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWebsocketServer] = TypeSpec{
+		constructor: fromSimpleConstructor(NewWebsocketServer),
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+Sets up a websocket server that broadcasts messages to all connected clients.`,
+		Description: `
+Each message is sent to every client connected at the time, where the messages of a batch are sent individually. Messages are acknowledged once they have been queued for each client, regardless of whether any clients are connected, and therefore this output is best suited to live feeds such as dashboards where clients are only interested in messages while they're connected.
+
+Messages are queued for each client separately, and when a client is too slow to receive messages and its queue of ` + "`buffer_size`" + ` messages is full further messages are dropped for that client only.
+
+You can leave the ` + "`address`" + ` config field blank in order to use the default service wide server address, but this will ignore TLS options.
+
+### Subscriptions
+
+When a ` + "`topic`" + ` is specified it is resolved for each message, and clients only receive the messages of topics they have subscribed to. Clients subscribe and unsubscribe by sending text messages containing a JSON object with a list of topics:
+
+` + "```json" + `
+{"subscribe":["orders","payments"]}
+{"unsubscribe":["payments"]}
+` + "```" + `
+
+The topic ` + "`*`" + ` subscribes to all topics. Clients have no subscriptions when they connect, and messages sent by clients are otherwise ignored when no ` + "`topic`" + ` is specified.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Live Dashboard",
+				Summary: "This example broadcasts the events of a Kafka topic to dashboards, where each dashboard chooses the regions of the events it receives by subscribing to them.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: dashboards
+
+output:
+  websocket_server:
+    address: 0.0.0.0:8080
+    path: /events
+    topic: ${! json("region") }
+    allowed_origins: [ https://dashboard.example.com ]
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "An optional address to listen from. If left empty the service wide HTTP server is used."),
+			docs.FieldCommon("path", "The path from which websocket connections can be established."),
+			docs.FieldCommon("topic", "An optional topic to resolve for each message, which enables [subscriptions](#subscriptions).", `${! meta("kafka_topic") }`).IsInterpolated(),
+			docs.FieldAdvanced("message_type", "The type of websocket message to send, text messages must contain valid UTF-8.").HasOptions("text", "binary"),
+			docs.FieldAdvanced("buffer_size", "The maximum number of messages to queue for each client before messages are dropped for that client."),
+			docs.FieldAdvanced("allowed_origins", "A list of origins that clients are allowed to connect from, where `*` allows all origins. When empty only clients from the same origin as the server, or without an origin, are allowed.", []string{"https://example.com"}).Array(),
+			docs.FieldAdvanced("cert_file", "An optional certificate file to use for TLS connections. Only applicable when an `address` is specified."),
+			docs.FieldAdvanced("key_file", "An optional certificate key file to use for TLS connections. Only applicable when an `address` is specified."),
+		},
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WebsocketServerConfig contains configuration fields for the WebsocketServer
+// output type.
+type WebsocketServerConfig struct {
+	Address        string   `json:"address" yaml:"address"`
+	Path           string   `json:"path" yaml:"path"`
+	Topic          string   `json:"topic" yaml:"topic"`
+	MessageType    string   `json:"message_type" yaml:"message_type"`
+	BufferSize     int      `json:"buffer_size" yaml:"buffer_size"`
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+	CertFile       string   `json:"cert_file" yaml:"cert_file"`
+	KeyFile        string   `json:"key_file" yaml:"key_file"`
+}
+
+// NewWebsocketServerConfig creates a new WebsocketServerConfig with default
+// values.
+func NewWebsocketServerConfig() WebsocketServerConfig {
+	return WebsocketServerConfig{
+		Address:        "",
+		Path:           "/ws",
+		Topic:          "",
+		MessageType:    "text",
+		BufferSize:     64,
+		AllowedOrigins: []string{},
+		CertFile:       "",
+		KeyFile:        "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// wsServerSubscription is a message sent by clients to change their
+// subscriptions.
+type wsServerSubscription struct {
+	Subscribe   []string `json:"subscribe"`
+	Unsubscribe []string `json:"unsubscribe"`
+}
+
+// wsServerClient is a client connected to a WebsocketServer.
+type wsServerClient struct {
+	ws    *websocket.Conn
+	sendC chan []byte
+
+	topicsMut sync.RWMutex
+	topics    map[string]struct{}
+}
+
+func (c *wsServerClient) subscribed(topic string) bool {
+	c.topicsMut.RLock()
+	defer c.topicsMut.RUnlock()
+
+	if _, exists := c.topics["*"]; exists {
+		return true
+	}
+	_, exists := c.topics[topic]
+	return exists
+}
+
+func (c *wsServerClient) update(sub wsServerSubscription) {
+	c.topicsMut.Lock()
+	defer c.topicsMut.Unlock()
+
+	for _, t := range sub.Subscribe {
+		c.topics[t] = struct{}{}
+	}
+	for _, t := range sub.Unsubscribe {
+		delete(c.topics, t)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WebsocketServer is an output type that broadcasts messages to clients
+// connected via websockets.
+type WebsocketServer struct {
+	running int32
+
+	conf  WebsocketServerConfig
+	stats metrics.Type
+	log   log.Modular
+
+	mux      *http.ServeMux
+	server   *http.Server
+	topic    *field.Expression
+	msgType  int
+	upgrader websocket.Upgrader
+
+	clientsMut sync.RWMutex
+	clients    map[*wsServerClient]struct{}
+
+	transactions <-chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	mRunning       metrics.StatGauge
+	mClients       metrics.StatGauge
+	mConnected     metrics.StatCounter
+	mCount         metrics.StatCounter
+	mPartsCount    metrics.StatCounter
+	mSent          metrics.StatCounter
+	mPartsSent     metrics.StatCounter
+	mSendSucc      metrics.StatCounter
+	mSendErr       metrics.StatCounter
+	mDropped       metrics.StatCounter
+	mSubscribeErr  metrics.StatCounter
+	mUpgradeFailed metrics.StatCounter
+}
+
+// NewWebsocketServer creates a new WebsocketServer output type.
+func NewWebsocketServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	wConf := conf.WebsocketServer
+
+	if wConf.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if wConf.BufferSize < 1 {
+		return nil, errors.New("buffer_size must be greater than zero")
+	}
+
+	var msgType int
+	switch wConf.MessageType {
+	case "text":
+		msgType = websocket.TextMessage
+	case "binary":
+		msgType = websocket.BinaryMessage
+	default:
+		return nil, fmt.Errorf("message type not recognised: %v", wConf.MessageType)
+	}
+
+	var topic *field.Expression
+	if wConf.Topic != "" {
+		var err error
+		if topic, err = bloblang.NewField(wConf.Topic); err != nil {
+			return nil, fmt.Errorf("failed to parse topic expression: %v", err)
+		}
+	}
+
+	w := &WebsocketServer{
+		running:    1,
+		conf:       wConf,
+		stats:      stats,
+		log:        log,
+		topic:      topic,
+		msgType:    msgType,
+		clients:    map[*wsServerClient]struct{}{},
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+
+		mRunning:       stats.GetGauge("running"),
+		mClients:       stats.GetGauge("clients"),
+		mConnected:     stats.GetCounter("client.connected"),
+		mCount:         stats.GetCounter("count"),
+		mPartsCount:    stats.GetCounter("parts.count"),
+		mSent:          stats.GetCounter("batch.sent"),
+		mPartsSent:     stats.GetCounter("sent"),
+		mSendSucc:      stats.GetCounter("send.success"),
+		mSendErr:       stats.GetCounter("send.error"),
+		mDropped:       stats.GetCounter("dropped"),
+		mSubscribeErr:  stats.GetCounter("subscribe.error"),
+		mUpgradeFailed: stats.GetCounter("upgrade.error"),
+	}
+	w.upgrader.CheckOrigin = w.checkOrigin(wConf.AllowedOrigins)
+
+	if len(wConf.Address) > 0 {
+		w.mux = http.NewServeMux()
+		w.server = &http.Server{Addr: wConf.Address, Handler: w.mux}
+		w.mux.HandleFunc(wConf.Path, w.wsHandler)
+	} else {
+		mgr.RegisterEndpoint(
+			wConf.Path, "Receive messages broadcast by Benthos via websockets.",
+			w.wsHandler,
+		)
+	}
+	return w, nil
+}
+
+// checkOrigin returns a function that checks the origin of upgrade requests
+// against a list of allowed origins, where an empty list results in the
+// default check of the upgrader.
+func (w *WebsocketServer) checkOrigin(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+	origins := map[string]struct{}{}
+	for _, o := range allowed {
+		if o == "*" {
+			return func(*http.Request) bool {
+				return true
+			}
+		}
+		origins[o] = struct{}{}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+			return true
+		}
+		_, exists := origins[origin]
+		return exists
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (w *WebsocketServer) wsHandler(rw http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&w.running) != 1 {
+		http.Error(rw, "Server closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	ws, err := w.upgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		w.mUpgradeFailed.Incr(1)
+		w.log.Warnf("Websocket request failed: %v\n", err)
+		return
+	}
+
+	c := &wsServerClient{
+		ws:     ws,
+		sendC:  make(chan []byte, w.conf.BufferSize),
+		topics: map[string]struct{}{},
+	}
+	if !w.addClient(c) {
+		ws.Close()
+		return
+	}
+	w.mConnected.Incr(1)
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for b := range c.sendC {
+			if err := ws.WriteMessage(w.msgType, b); err != nil {
+				w.mSendErr.Incr(1)
+				w.log.Debugf("Failed to send message to client: %v\n", err)
+				ws.Close()
+				// Drain the queue until the client is removed.
+				for range c.sendC {
+				}
+				return
+			}
+			w.mSendSucc.Incr(1)
+		}
+		_ = ws.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+			time.Now().Add(time.Second),
+		)
+		ws.Close()
+	}()
+
+	for {
+		msgType, b, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		if w.topic == nil || msgType != websocket.TextMessage {
+			continue
+		}
+		var sub wsServerSubscription
+		if err := json.Unmarshal(b, &sub); err != nil {
+			w.mSubscribeErr.Incr(1)
+			w.log.Debugf("Failed to parse subscription message: %v\n", err)
+			continue
+		}
+		c.update(sub)
+	}
+
+	w.removeClient(c)
+	<-writerDone
+}
+
+func (w *WebsocketServer) addClient(c *wsServerClient) bool {
+	w.clientsMut.Lock()
+	defer w.clientsMut.Unlock()
+
+	select {
+	case <-w.closeChan:
+		return false
+	default:
+	}
+	w.clients[c] = struct{}{}
+	w.mClients.Set(int64(len(w.clients)))
+	return true
+}
+
+func (w *WebsocketServer) removeClient(c *wsServerClient) {
+	w.clientsMut.Lock()
+	defer w.clientsMut.Unlock()
+
+	if _, exists := w.clients[c]; exists {
+		delete(w.clients, c)
+		close(c.sendC)
+		w.mClients.Set(int64(len(w.clients)))
+	}
+}
+
+func (w *WebsocketServer) removeAllClients() {
+	w.clientsMut.Lock()
+	defer w.clientsMut.Unlock()
+
+	for c := range w.clients {
+		close(c.sendC)
+	}
+	w.clients = map[*wsServerClient]struct{}{}
+	w.mClients.Set(0)
+}
+
+// broadcast queues the messages of a transaction for each client that is
+// subscribed to them.
+func (w *WebsocketServer) broadcast(ts types.Transaction) {
+	w.clientsMut.RLock()
+	defer w.clientsMut.RUnlock()
+
+	for i := 0; i < ts.Payload.Len(); i++ {
+		var topic string
+		if w.topic != nil {
+			topic = w.topic.String(i, ts.Payload)
+		}
+		b := ts.Payload.Get(i).Get()
+		for c := range w.clients {
+			if w.topic != nil && !c.subscribed(topic) {
+				continue
+			}
+			select {
+			case c.sendC <- b:
+			default:
+				w.mDropped.Incr(1)
+			}
+		}
+	}
+}
+
+// shutdown stops new clients from connecting.
+func (w *WebsocketServer) shutdown() {
+	w.clientsMut.Lock()
+	defer w.clientsMut.Unlock()
+
+	select {
+	case <-w.closeChan:
+	default:
+		close(w.closeChan)
+	}
+}
+
+func (w *WebsocketServer) loop() {
+	defer func() {
+		atomic.StoreInt32(&w.running, 0)
+		w.shutdown()
+		w.removeAllClients()
+		if w.server != nil {
+			w.server.Shutdown(context.Background())
+		}
+		w.mRunning.Decr(1)
+		close(w.closedChan)
+	}()
+	w.mRunning.Incr(1)
+
+	for {
+		var ts types.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-w.transactions:
+			if !open {
+				return
+			}
+		case <-w.closeChan:
+			return
+		}
+		w.mCount.Incr(1)
+		w.mPartsCount.Incr(int64(ts.Payload.Len()))
+
+		w.broadcast(ts)
+
+		w.mSent.Incr(1)
+		w.mPartsSent.Incr(int64(ts.Payload.Len()))
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-w.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a messages channel for the output to read.
+func (w *WebsocketServer) Consume(ts <-chan types.Transaction) error {
+	if w.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	w.transactions = ts
+
+	if w.server != nil {
+		go func() {
+			if len(w.conf.KeyFile) > 0 || len(w.conf.CertFile) > 0 {
+				w.log.Infof(
+					"Broadcasting messages through websockets at: wss://%s\n",
+					w.conf.Address+w.conf.Path,
+				)
+				if err := w.server.ListenAndServeTLS(
+					w.conf.CertFile, w.conf.KeyFile,
+				); err != http.ErrServerClosed {
+					w.log.Errorf("Server error: %v\n", err)
+				}
+			} else {
+				w.log.Infof(
+					"Broadcasting messages through websockets at: ws://%s\n",
+					w.conf.Address+w.conf.Path,
+				)
+				if err := w.server.ListenAndServe(); err != http.ErrServerClosed {
+					w.log.Errorf("Server error: %v\n", err)
+				}
+			}
+		}()
+	}
+	go w.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (w *WebsocketServer) Connected() bool {
+	return true
+}
+
+// CloseAsync shuts down the WebsocketServer output and stops processing
+// requests.
+func (w *WebsocketServer) CloseAsync() {
+	w.shutdown()
+
+	if w.transactions == nil {
+		// The loop was never started.
+		atomic.StoreInt32(&w.running, 0)
+		select {
+		case <-w.closedChan:
+		default:
+			close(w.closedChan)
+		}
+	}
+}
+
+// WaitForClose blocks until the WebsocketServer output has closed down.
+func (w *WebsocketServer) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-w.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
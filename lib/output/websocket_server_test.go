@@ -0,0 +1,219 @@
+package output
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type wsServerTestReg struct {
+	types.Manager
+	mux *http.ServeMux
+}
+
+func (w wsServerTestReg) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	w.mux.HandleFunc(path, h)
+}
+
+func wsServerTestSetup(t *testing.T, conf Config) (string, chan types.Transaction, Type) {
+	t.Helper()
+
+	mgr := wsServerTestReg{Manager: types.NoopMgr(), mux: http.NewServeMux()}
+	srv := httptest.NewServer(mgr.mux)
+	t.Cleanup(srv.Close)
+
+	w, err := NewWebsocketServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, w.Consume(tChan))
+	t.Cleanup(func() {
+		w.CloseAsync()
+		assert.NoError(t, w.WaitForClose(time.Second*5))
+	})
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + conf.WebsocketServer.Path, tChan, w
+}
+
+func wsServerTestSend(t *testing.T, tChan chan types.Transaction, parts ...string) {
+	t.Helper()
+
+	msg := message.New(nil)
+	for _, p := range parts {
+		part := message.NewPart([]byte(p))
+		part.Metadata().Set("topic", strings.Split(p, ":")[0])
+		msg.Append(part)
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		require.NoError(t, res.Error())
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+}
+
+func wsServerTestRead(t *testing.T, ws *websocket.Conn) string {
+	t.Helper()
+
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(time.Second*5)))
+	msgType, b, err := ws.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, msgType)
+	return string(b)
+}
+
+func wsServerTestWaitClients(t *testing.T, w Type, n int) {
+	t.Helper()
+
+	srv := w.(*WebsocketServer)
+	require.Eventually(t, func() bool {
+		srv.clientsMut.RLock()
+		defer srv.clientsMut.RUnlock()
+		return len(srv.clients) == n
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func wsServerTestSubscribed(w Type, topic string) (n int) {
+	srv := w.(*WebsocketServer)
+	srv.clientsMut.RLock()
+	defer srv.clientsMut.RUnlock()
+	for c := range srv.clients {
+		if c.subscribed(topic) {
+			n++
+		}
+	}
+	return
+}
+
+func TestWebsocketServerBroadcast(t *testing.T) {
+	conf := NewConfig()
+	conf.WebsocketServer.Path = "/events"
+
+	url, tChan, w := wsServerTestSetup(t, conf)
+
+	// Messages without clients are acknowledged and dropped.
+	wsServerTestSend(t, tChan, "a:0")
+
+	var clients []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		clients = append(clients, ws)
+	}
+	wsServerTestWaitClients(t, w, 2)
+
+	wsServerTestSend(t, tChan, "a:1", "b:2")
+	for _, ws := range clients {
+		assert.Equal(t, "a:1", wsServerTestRead(t, ws))
+		assert.Equal(t, "b:2", wsServerTestRead(t, ws))
+	}
+
+	clients[0].Close()
+	wsServerTestWaitClients(t, w, 1)
+
+	wsServerTestSend(t, tChan, "c:3")
+	assert.Equal(t, "c:3", wsServerTestRead(t, clients[1]))
+}
+
+func TestWebsocketServerSubscriptions(t *testing.T) {
+	conf := NewConfig()
+	conf.WebsocketServer.Topic = `${! meta("topic") }`
+
+	url, tChan, w := wsServerTestSetup(t, conf)
+
+	subs := []string{
+		`{"subscribe":["a","b"]}`,
+		`{"subscribe":["*"]}`,
+		``,
+	}
+	var clients []*websocket.Conn
+	for _, sub := range subs {
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		if sub != "" {
+			require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(sub)))
+		}
+		clients = append(clients, ws)
+	}
+	wsServerTestWaitClients(t, w, 3)
+
+	// Subscriptions are processed asynchronously.
+	require.Eventually(t, func() bool {
+		return wsServerTestSubscribed(w, "a") == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	require.NoError(t, clients[0].WriteMessage(websocket.TextMessage, []byte(`{"unsubscribe":["a"]}`)))
+	require.NoError(t, clients[0].WriteMessage(websocket.TextMessage, []byte(`{"subscribe":["c"]}`)))
+	require.NoError(t, clients[2].WriteMessage(websocket.TextMessage, []byte(`not json`)))
+	require.Eventually(t, func() bool {
+		return wsServerTestSubscribed(w, "c") == 2 && wsServerTestSubscribed(w, "a") == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	wsServerTestSend(t, tChan, "a:1", "b:2", "c:3", "d:4")
+
+	assert.Equal(t, "b:2", wsServerTestRead(t, clients[0]))
+	assert.Equal(t, "c:3", wsServerTestRead(t, clients[0]))
+
+	for _, exp := range []string{"a:1", "b:2", "c:3", "d:4"} {
+		assert.Equal(t, exp, wsServerTestRead(t, clients[1]))
+	}
+
+	require.NoError(t, clients[2].SetReadDeadline(time.Now().Add(time.Millisecond*100)))
+	_, _, err := clients[2].ReadMessage()
+	assert.Error(t, err)
+}
+
+func TestWebsocketServerClose(t *testing.T) {
+	conf := NewConfig()
+
+	url, tChan, w := wsServerTestSetup(t, conf)
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	wsServerTestWaitClients(t, w, 1)
+
+	close(tChan)
+	require.NoError(t, w.WaitForClose(time.Second*5))
+
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(time.Second*5)))
+	_, _, err = ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	_, res, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
+
+func TestWebsocketServerOrigins(t *testing.T) {
+	conf := NewConfig()
+	conf.WebsocketServer.AllowedOrigins = []string{"https://example.com"}
+
+	url, _, _ := wsServerTestSetup(t, conf)
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"https://example.com"}})
+	require.NoError(t, err)
+	ws.Close()
+
+	_, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{"https://evil.example.com"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
@@ -4,6 +4,7 @@ package writer
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"time"
 
@@ -22,9 +23,10 @@ import (
 type AzureQueueStorage struct {
 	conf AzureQueueStorageConfig
 
-	queueName  *field.Expression
-	ttl        *field.Expression
-	serviceURL *azqueue.ServiceURL
+	queueName         *field.Expression
+	ttl               *field.Expression
+	visibilityTimeout *field.Expression
+	serviceURL        *azqueue.ServiceURL
 
 	log   log.Modular
 	stats metrics.Type
@@ -51,6 +53,10 @@ func NewAzureQueueStorage(
 		return nil, fmt.Errorf("failed to parse ttl expression: %v", err)
 	}
 
+	if s.visibilityTimeout, err = bloblang.NewField(conf.VisibilityTimeout); err != nil {
+		return nil, fmt.Errorf("failed to parse visibility timeout expression: %v", err)
+	}
+
 	if s.queueName, err = bloblang.NewField(conf.QueueName); err != nil {
 		return nil, fmt.Errorf("failed to parse table name expression: %v", err)
 	}
@@ -94,8 +100,19 @@ func (a *AzureQueueStorage) WriteWithContext(ctx context.Context, msg types.Mess
 			}
 			return 0
 		}()
+		var visibilityTimeout time.Duration
+		if vts := a.visibilityTimeout.String(i, msg); vts != "" {
+			var err error
+			if visibilityTimeout, err = time.ParseDuration(vts); err != nil {
+				a.log.Debugf("Visibility timeout must be a duration: %v\n", err)
+				return err
+			}
+		}
 		message := string(p.Get())
-		_, err := msgURL.Enqueue(ctx, message, 0, timeToLive)
+		if a.conf.Base64Encode {
+			message = base64.StdEncoding.EncodeToString(p.Get())
+		}
+		_, err := msgURL.Enqueue(ctx, message, visibilityTimeout, timeToLive)
 		if err != nil {
 			if cerr, ok := err.(azqueue.StorageError); ok {
 				if cerr.ServiceCode() == azqueue.ServiceCodeQueueNotFound {
@@ -104,7 +121,7 @@ func (a *AzureQueueStorage) WriteWithContext(ctx context.Context, msg types.Mess
 					if err != nil {
 						return fmt.Errorf("error creating queue: %v", err)
 					}
-					_, err := msgURL.Enqueue(ctx, message, 0, 0)
+					_, err := msgURL.Enqueue(ctx, message, visibilityTimeout, timeToLive)
 					if err != nil {
 						return fmt.Errorf("error retrying to enqueue message: %v", err)
 					}
@@ -11,6 +11,8 @@ type AzureQueueStorageConfig struct {
 	StorageConnectionString string             `json:"storage_connection_string" yaml:"storage_connection_string"`
 	QueueName               string             `json:"queue_name" yaml:"queue_name"`
 	TTL                     string             `json:"ttl" yaml:"ttl"`
+	VisibilityTimeout       string             `json:"visibility_timeout" yaml:"visibility_timeout"`
+	Base64Encode            bool               `json:"base64_encode" yaml:"base64_encode"`
 	MaxInFlight             int                `json:"max_in_flight" yaml:"max_in_flight"`
 	Batching                batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
@@ -23,6 +25,8 @@ func NewAzureQueueStorageConfig() AzureQueueStorageConfig {
 		StorageConnectionString: "",
 		QueueName:               "",
 		TTL:                     "",
+		VisibilityTimeout:       "",
+		Base64Encode:            false,
 		MaxInFlight:             1,
 		Batching:                batch.NewPolicyConfig(),
 	}
@@ -0,0 +1,188 @@
+// +build !wasm
+
+package writer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testQueueServer mocks the subset of the Azure Queue Storage API used by the
+// azure_queue_storage output, recording each request it receives.
+type testQueueServer struct {
+	mut      sync.Mutex
+	queues   map[string]bool
+	requests []string
+}
+
+func (s *testQueueServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/devstoreaccount1/")
+	queue := strings.TrimSuffix(path, "/messages")
+	query := r.URL.Query()
+
+	w.Header().Set("x-ms-version", "2018-03-28")
+	switch r.Method {
+	case http.MethodPut:
+		s.requests = append(s.requests, "PUT "+path)
+		s.queues[queue] = true
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPost:
+		if !s.queues[queue] {
+			s.requests = append(s.requests, "POST "+path+" not found")
+			w.Header().Set("x-ms-error-code", "QueueNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var qMsg struct {
+			MessageText string `xml:"MessageText"`
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &qMsg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		req := fmt.Sprintf("POST %v %v visibilitytimeout=%v", path, qMsg.MessageText, query.Get("visibilitytimeout"))
+		if ttl := query.Get("messagettl"); ttl != "" {
+			req += " messagettl=" + ttl
+		}
+		s.requests = append(s.requests, req)
+
+		now := time.Now().UTC().Format(http.TimeFormat)
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><QueueMessagesList><QueueMessage><MessageId>foo</MessageId><InsertionTime>%v</InsertionTime><ExpirationTime>%v</ExpirationTime><PopReceipt>receipt</PopReceipt><TimeNextVisible>%v</TimeNextVisible></QueueMessage></QueueMessagesList>`, now, now, now)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (s *testQueueServer) reqs() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	reqs := s.requests
+	s.requests = nil
+	return reqs
+}
+
+func newTestAzureQueueStorage(t *testing.T, queues []string, fn func(*AzureQueueStorageConfig)) (*AzureQueueStorage, *testQueueServer) {
+	t.Helper()
+
+	server := &testQueueServer{queues: map[string]bool{}}
+	for _, q := range queues {
+		server.queues[q] = true
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	require.NoError(t, os.Setenv("AZURITE_QUEUE_ENDPOINT_PORT", u.Port()))
+	t.Cleanup(func() {
+		os.Unsetenv("AZURITE_QUEUE_ENDPOINT_PORT")
+	})
+
+	conf := NewAzureQueueStorageConfig()
+	conf.StorageConnectionString = "UseDevelopmentStorage=true;"
+	conf.QueueName = "foo"
+	if fn != nil {
+		fn(&conf)
+	}
+
+	a, err := NewAzureQueueStorage(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, a.ConnectWithContext(context.Background()))
+	return a, server
+}
+
+func TestAzureQueueStorageWrite(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []string{"foo"}, nil)
+
+	require.NoError(t, a.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("hello"),
+		[]byte("world"),
+	})))
+	assert.Equal(t, []string{
+		"POST foo/messages hello visibilitytimeout=0",
+		"POST foo/messages world visibilitytimeout=0",
+	}, server.reqs())
+}
+
+func TestAzureQueueStorageWriteBase64Encode(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []string{"foo"}, func(c *AzureQueueStorageConfig) {
+		c.Base64Encode = true
+	})
+
+	require.NoError(t, a.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("hello world"),
+	})))
+	assert.Equal(t, []string{
+		"POST foo/messages " + base64.StdEncoding.EncodeToString([]byte("hello world")) + " visibilitytimeout=0",
+	}, server.reqs())
+}
+
+func TestAzureQueueStorageWriteTTLAndVisibility(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []string{"foo", "bar"}, func(c *AzureQueueStorageConfig) {
+		c.QueueName = `${! meta("queue") }`
+		c.TTL = `${! meta("ttl") }`
+		c.VisibilityTimeout = "5s"
+	})
+
+	msg := message.New([][]byte{
+		[]byte("hello"),
+		[]byte("world"),
+	})
+	msg.Get(0).Metadata().Set("queue", "foo").Set("ttl", "1m")
+	msg.Get(1).Metadata().Set("queue", "bar")
+
+	require.NoError(t, a.WriteWithContext(context.Background(), msg))
+	assert.Equal(t, []string{
+		"POST foo/messages hello visibilitytimeout=5 messagettl=60",
+		"POST bar/messages world visibilitytimeout=5",
+	}, server.reqs())
+}
+
+func TestAzureQueueStorageWriteBadTTL(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, []string{"foo"}, func(c *AzureQueueStorageConfig) {
+		c.TTL = "nope"
+	})
+
+	require.Error(t, a.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("hello"),
+	})))
+	assert.Empty(t, server.reqs())
+}
+
+func TestAzureQueueStorageWriteCreatesQueue(t *testing.T) {
+	a, server := newTestAzureQueueStorage(t, nil, nil)
+
+	require.NoError(t, a.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("hello"),
+	})))
+	assert.Equal(t, []string{
+		"POST foo/messages not found",
+		"PUT foo",
+		"POST foo/messages hello visibilitytimeout=0",
+	}, server.reqs())
+}
@@ -2,6 +2,7 @@ package writer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,30 +14,97 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
-	"github.com/colinmarc/hdfs"
+	"github.com/colinmarc/hdfs/v2"
+	krbclient "github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 )
 
 //------------------------------------------------------------------------------
 
+// HDFSKerberosConfig contains configuration fields for authenticating with a
+// HDFS cluster using Kerberos.
+type HDFSKerberosConfig struct {
+	Enabled              bool   `json:"enabled" yaml:"enabled"`
+	ConfigFile           string `json:"config_file" yaml:"config_file"`
+	Realm                string `json:"realm" yaml:"realm"`
+	Username             string `json:"username" yaml:"username"`
+	KeytabFile           string `json:"keytab_file" yaml:"keytab_file"`
+	Password             string `json:"password" yaml:"password"`
+	ServicePrincipalName string `json:"service_principal_name" yaml:"service_principal_name"`
+}
+
+// NewHDFSKerberosConfig creates a new HDFSKerberosConfig with default values.
+func NewHDFSKerberosConfig() HDFSKerberosConfig {
+	return HDFSKerberosConfig{
+		Enabled:              false,
+		ConfigFile:           "/etc/krb5.conf",
+		Realm:                "",
+		Username:             "",
+		KeytabFile:           "",
+		Password:             "",
+		ServicePrincipalName: "nn/_HOST",
+	}
+}
+
+func (k HDFSKerberosConfig) validate() error {
+	if k.Username == "" || k.Realm == "" {
+		return errors.New("kerberos requires a username and realm")
+	}
+	if k.KeytabFile == "" && k.Password == "" {
+		return errors.New("kerberos requires either a keytab_file or password")
+	}
+	return nil
+}
+
+// client creates a Kerberos client and logs in with the configured
+// credentials.
+func (k HDFSKerberosConfig) client() (*krbclient.Client, error) {
+	cfg, err := krbconfig.Load(k.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kerberos config: %w", err)
+	}
+	var client *krbclient.Client
+	if k.KeytabFile != "" {
+		kt, err := keytab.Load(k.KeytabFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keytab: %w", err)
+		}
+		client = krbclient.NewWithKeytab(k.Username, k.Realm, kt, cfg)
+	} else {
+		client = krbclient.NewWithPassword(k.Username, k.Realm, k.Password, cfg)
+	}
+	if err := client.Login(); err != nil {
+		return nil, fmt.Errorf("failed to login with kerberos: %w", err)
+	}
+	return client, nil
+}
+
 // HDFSConfig contains configuration fields for the HDFS output type.
 type HDFSConfig struct {
-	Hosts       []string           `json:"hosts" yaml:"hosts"`
-	User        string             `json:"user" yaml:"user"`
-	Directory   string             `json:"directory" yaml:"directory"`
-	Path        string             `json:"path" yaml:"path"`
-	MaxInFlight int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Batching    batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Hosts        []string           `json:"hosts" yaml:"hosts"`
+	User         string             `json:"user" yaml:"user"`
+	Kerberos     HDFSKerberosConfig `json:"kerberos" yaml:"kerberos"`
+	Directory    string             `json:"directory" yaml:"directory"`
+	Path         string             `json:"path" yaml:"path"`
+	Append       bool               `json:"append" yaml:"append"`
+	AtomicRename bool               `json:"atomic_rename" yaml:"atomic_rename"`
+	MaxInFlight  int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching     batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
 
 // NewHDFSConfig creates a new Config with default values.
 func NewHDFSConfig() HDFSConfig {
 	return HDFSConfig{
-		Hosts:       []string{"localhost:9000"},
-		User:        "benthos_hdfs",
-		Directory:   "",
-		Path:        `${!count("files")}-${!timestamp_unix_nano()}.txt`,
-		MaxInFlight: 1,
-		Batching:    batch.NewPolicyConfig(),
+		Hosts:        []string{"localhost:9000"},
+		User:         "benthos_hdfs",
+		Kerberos:     NewHDFSKerberosConfig(),
+		Directory:    "",
+		Path:         `${!count("files")}-${!timestamp_unix_nano()}.txt`,
+		Append:       false,
+		AtomicRename: false,
+		MaxInFlight:  1,
+		Batching:     batch.NewPolicyConfig(),
 	}
 }
 
@@ -47,7 +115,8 @@ func NewHDFSConfig() HDFSConfig {
 type HDFS struct {
 	conf HDFSConfig
 
-	path *field.Expression
+	directory *field.Expression
+	path      *field.Expression
 
 	client *hdfs.Client
 
@@ -61,15 +130,28 @@ func NewHDFS(
 	log log.Modular,
 	stats metrics.Type,
 ) (*HDFS, error) {
+	if conf.Append && conf.AtomicRename {
+		return nil, errors.New("append and atomic_rename cannot both be enabled")
+	}
+	if conf.Kerberos.Enabled {
+		if err := conf.Kerberos.validate(); err != nil {
+			return nil, err
+		}
+	}
+	directory, err := bloblang.NewField(conf.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse directory expression: %v", err)
+	}
 	path, err := bloblang.NewField(conf.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse path expression: %v", err)
 	}
 	return &HDFS{
-		conf:  conf,
-		path:  path,
-		log:   log,
-		stats: stats,
+		conf:      conf,
+		directory: directory,
+		path:      path,
+		log:       log,
+		stats:     stats,
 	}, nil
 }
 
@@ -85,10 +167,22 @@ func (h *HDFS) Connect() error {
 		return nil
 	}
 
-	client, err := hdfs.NewClient(hdfs.ClientOptions{
+	opts := hdfs.ClientOptions{
 		Addresses: h.conf.Hosts,
 		User:      h.conf.User,
-	})
+	}
+	if h.conf.Kerberos.Enabled {
+		krbClient, err := h.conf.Kerberos.client()
+		if err != nil {
+			return err
+		}
+		// The user is determined from the Kerberos credentials.
+		opts.User = ""
+		opts.KerberosClient = krbClient
+		opts.KerberosServicePrincipleName = h.conf.Kerberos.ServicePrincipalName
+	}
+
+	client, err := hdfs.NewClient(opts)
 	if err != nil {
 		return err
 	}
@@ -112,27 +206,51 @@ func (h *HDFS) Write(msg types.Message) error {
 	}
 
 	return IterateBatchedSend(msg, func(i int, p types.Part) error {
-		path := h.path.String(i, msg)
-		filePath := filepath.Join(h.conf.Directory, path)
+		filePath := filepath.Join(h.directory.String(i, msg), h.path.String(i, msg))
 
-		err := h.client.MkdirAll(h.conf.Directory, os.ModeDir|0644)
+		err := h.client.MkdirAll(filepath.Dir(filePath), os.ModeDir|0644)
 		if err != nil {
 			return err
 		}
 
-		fw, err := h.client.Create(filePath)
+		var fw *hdfs.FileWriter
+		writePath := filePath
+		switch {
+		case h.conf.Append:
+			if fw, err = h.client.Append(filePath); errors.Is(err, os.ErrNotExist) {
+				fw, err = h.client.Create(filePath)
+			}
+		case h.conf.AtomicRename:
+			writePath = hdfsTempPath(filePath)
+			_ = h.client.Remove(writePath)
+			fw, err = h.client.Create(writePath)
+		default:
+			fw, err = h.client.Create(filePath)
+		}
 		if err != nil {
 			return err
 		}
 
 		if _, err := fw.Write(p.Get()); err != nil {
+			fw.Close()
 			return err
 		}
-		fw.Close()
+		if err := fw.Close(); err != nil {
+			return err
+		}
+		if writePath != filePath {
+			return h.client.Rename(writePath, filePath)
+		}
 		return nil
 	})
 }
 
+// hdfsTempPath returns the path of a hidden file in the same directory as a
+// target path, which Hadoop tools ignore until it is renamed.
+func hdfsTempPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp")
+}
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (h *HDFS) CloseAsync() {
 }
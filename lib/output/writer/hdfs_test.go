@@ -0,0 +1,63 @@
+package writer
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHDFSConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf   func(c *HDFSConfig)
+		errStr string
+	}{
+		"append and atomic rename": {
+			conf: func(c *HDFSConfig) {
+				c.Append = true
+				c.AtomicRename = true
+			},
+			errStr: "append and atomic_rename cannot both be enabled",
+		},
+		"kerberos without realm": {
+			conf: func(c *HDFSConfig) {
+				c.Kerberos.Enabled = true
+				c.Kerberos.Username = "foo"
+				c.Kerberos.Password = "bar"
+			},
+			errStr: "kerberos requires a username and realm",
+		},
+		"kerberos without credentials": {
+			conf: func(c *HDFSConfig) {
+				c.Kerberos.Enabled = true
+				c.Kerberos.Username = "foo"
+				c.Kerberos.Realm = "EXAMPLE.COM"
+			},
+			errStr: "kerberos requires either a keytab_file or password",
+		},
+		"bad directory": {
+			conf: func(c *HDFSConfig) {
+				c.Directory = "${! nope( }"
+			},
+			errStr: "failed to parse directory expression",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewHDFSConfig()
+			test.conf(&conf)
+			_, err := NewHDFS(conf, log.Noop(), metrics.Noop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+		})
+	}
+}
+
+func TestHDFSTempPath(t *testing.T) {
+	assert.Equal(t, "/data/2021-06-01/.foo.json.tmp", hdfsTempPath("/data/2021-06-01/foo.json"))
+	assert.Equal(t, ".foo.json.tmp", hdfsTempPath("foo.json"))
+}
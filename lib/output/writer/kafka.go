@@ -3,7 +3,9 @@ package writer
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -42,11 +44,14 @@ type KafkaConfig struct {
 	SASL             sasl.Config `json:"sasl" yaml:"sasl"`
 	MaxInFlight      int         `json:"max_in_flight" yaml:"max_in_flight"`
 	retries.Config   `json:",inline" yaml:",inline"`
-	RetryAsBatch     bool               `json:"retry_as_batch" yaml:"retry_as_batch"`
-	Batching         batch.PolicyConfig `json:"batching" yaml:"batching"`
-	StaticHeaders    map[string]string  `json:"static_headers" yaml:"static_headers"`
-	Metadata         output.Metadata    `json:"metadata" yaml:"metadata"`
-	InjectTracingMap string             `json:"inject_tracing_map" yaml:"inject_tracing_map"`
+	RetryAsBatch     bool                     `json:"retry_as_batch" yaml:"retry_as_batch"`
+	Batching         batch.PolicyConfig       `json:"batching" yaml:"batching"`
+	StaticHeaders    map[string]string        `json:"static_headers" yaml:"static_headers"`
+	Metadata         output.Metadata          `json:"metadata" yaml:"metadata"`
+	InjectTracingMap string                   `json:"inject_tracing_map" yaml:"inject_tracing_map"`
+	CreateTopics     KafkaTopicCreationConfig `json:"create_topics" yaml:"create_topics"`
+	IdempotentWrite  bool                     `json:"idempotent_write" yaml:"idempotent_write"`
+	Transaction      KafkaTransactionConfig   `json:"transaction" yaml:"transaction"`
 
 	// TODO: V4 remove this.
 	RoundRobinPartitions bool `json:"round_robin_partitions" yaml:"round_robin_partitions"`
@@ -79,6 +84,65 @@ func NewKafkaConfig() KafkaConfig {
 		Config:               rConf,
 		RetryAsBatch:         false,
 		Batching:             batch.NewPolicyConfig(),
+		CreateTopics:         NewKafkaTopicCreationConfig(),
+		IdempotentWrite:      false,
+		Transaction:          NewKafkaTransactionConfig(),
+	}
+}
+
+// KafkaTransactionConfig contains configuration fields for writing batches of
+// messages within Kafka transactions.
+type KafkaTransactionConfig struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	ID            string `json:"id" yaml:"id"`
+	Timeout       string `json:"timeout" yaml:"timeout"`
+	ConsumerGroup string `json:"consumer_group" yaml:"consumer_group"`
+}
+
+// NewKafkaTransactionConfig creates a new KafkaTransactionConfig with default
+// values.
+func NewKafkaTransactionConfig() KafkaTransactionConfig {
+	return KafkaTransactionConfig{
+		Enabled:       false,
+		ID:            "",
+		Timeout:       "1m",
+		ConsumerGroup: "",
+	}
+}
+
+// KafkaTopicCreationConfig contains configuration fields for creating topics
+// that do not yet exist before writing to them.
+type KafkaTopicCreationConfig struct {
+	Enabled           bool              `json:"enabled" yaml:"enabled"`
+	Partitions        int32             `json:"partitions" yaml:"partitions"`
+	ReplicationFactor int16             `json:"replication_factor" yaml:"replication_factor"`
+	Configs           map[string]string `json:"configs" yaml:"configs"`
+}
+
+// NewKafkaTopicCreationConfig creates a new KafkaTopicCreationConfig with
+// default values.
+func NewKafkaTopicCreationConfig() KafkaTopicCreationConfig {
+	return KafkaTopicCreationConfig{
+		Enabled:           false,
+		Partitions:        -1,
+		ReplicationFactor: -1,
+		Configs:           map[string]string{},
+	}
+}
+
+func (c KafkaTopicCreationConfig) detail() *sarama.TopicDetail {
+	var entries map[string]*string
+	if len(c.Configs) > 0 {
+		entries = make(map[string]*string, len(c.Configs))
+		for k, v := range c.Configs {
+			v := v
+			entries[k] = &v
+		}
+	}
+	return &sarama.TopicDetail{
+		NumPartitions:     c.Partitions,
+		ReplicationFactor: c.ReplicationFactor,
+		ConfigEntries:     entries,
 	}
 }
 
@@ -92,8 +156,9 @@ type Kafka struct {
 
 	backoffCtor func() backoff.BackOff
 
-	tlsConf *tls.Config
-	timeout time.Duration
+	tlsConf    *tls.Config
+	timeout    time.Duration
+	txnTimeout time.Duration
 
 	addresses []string
 	version   sarama.KafkaVersion
@@ -104,6 +169,11 @@ type Kafka struct {
 
 	producer    sarama.SyncProducer
 	compression sarama.CompressionCodec
+
+	topicCreator  kafkaTopicCreator
+	knownTopics   map[string]struct{}
+	knownTopicMut sync.Mutex
+
 	partitioner sarama.PartitionerConstructor
 
 	staticHeaders map[string]string
@@ -138,6 +208,7 @@ func NewKafka(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metric
 		compression:   compression,
 		partitioner:   partitioner,
 		staticHeaders: conf.StaticHeaders,
+		knownTopics:   map[string]struct{}{},
 	}
 
 	if k.metaFilter, err = conf.Metadata.Filter(); err != nil {
@@ -172,6 +243,25 @@ func NewKafka(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metric
 		return nil, err
 	}
 
+	if conf.IdempotentWrite || conf.Transaction.Enabled {
+		if !k.version.IsAtLeast(sarama.V0_11_0_0) {
+			return nil, fmt.Errorf("a target_version of at least 0.11.0 is required for idempotent and transactional writes, got %v", k.version)
+		}
+	}
+	if conf.Transaction.Enabled {
+		if conf.Transaction.ID == "" {
+			return nil, errors.New("a transaction id must be specified when transactions are enabled")
+		}
+		if conf.MaxInFlight > 1 {
+			return nil, errors.New("max_in_flight must be 1 when transactions are enabled")
+		}
+		if tout := conf.Transaction.Timeout; len(tout) > 0 {
+			if k.txnTimeout, err = time.ParseDuration(tout); err != nil {
+				return nil, fmt.Errorf("failed to parse transaction timeout string: %v", err)
+			}
+		}
+	}
+
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
 			if trimmed := strings.TrimSpace(splitAddr); len(trimmed) > 0 {
@@ -299,13 +389,77 @@ func (k *Kafka) Connect() error {
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	if k.conf.IdempotentWrite || k.conf.Transaction.Enabled {
+		// Idempotent producers require acknowledgement from all replicas and
+		// a single request in flight per broker in order to preserve ordering.
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+	}
+	if k.conf.Transaction.Enabled {
+		config.Producer.Transaction.ID = k.conf.Transaction.ID
+		if k.txnTimeout > 0 {
+			config.Producer.Transaction.Timeout = k.txnTimeout
+		}
+	}
+
 	var err error
+	if k.conf.CreateTopics.Enabled {
+		// Topics may have been deleted while disconnected, and so the cache
+		// of known topics is only valid for the lifetime of a connection.
+		k.knownTopicMut.Lock()
+		k.knownTopics = map[string]struct{}{}
+		k.knownTopicMut.Unlock()
+
+		if k.topicCreator, err = sarama.NewClusterAdmin(k.addresses, config); err != nil {
+			return err
+		}
+	}
+
 	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
+	if err != nil {
+		if k.topicCreator != nil {
+			k.topicCreator.Close()
+			k.topicCreator = nil
+		}
+		return err
+	}
 
-	if err == nil {
-		k.log.Infof("Sending Kafka messages to addresses: %s\n", k.addresses)
+	k.log.Infof("Sending Kafka messages to addresses: %s\n", k.addresses)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// kafkaTopicCreator is the subset of a sarama.ClusterAdmin used for creating
+// topics.
+type kafkaTopicCreator interface {
+	CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error
+	Close() error
+}
+
+// ensureTopics creates any topics of a batch of messages that are not yet known
+// to exist.
+func (k *Kafka) ensureTopics(creator kafkaTopicCreator, msgs []*sarama.ProducerMessage) error {
+	k.knownTopicMut.Lock()
+	defer k.knownTopicMut.Unlock()
+
+	for _, m := range msgs {
+		if _, exists := k.knownTopics[m.Topic]; exists {
+			continue
+		}
+		err := creator.CreateTopic(m.Topic, k.conf.CreateTopics.detail(), false)
+		if err != nil {
+			var tErr *sarama.TopicError
+			if !errors.As(err, &tErr) || tErr.Err != sarama.ErrTopicAlreadyExists {
+				return fmt.Errorf("failed to create topic '%v': %w", m.Topic, err)
+			}
+		} else {
+			k.log.Infof("Created Kafka topic: %v\n", m.Topic)
+		}
+		k.knownTopics[m.Topic] = struct{}{}
 	}
-	return err
+	return nil
 }
 
 // Write will attempt to write a message to Kafka, wait for acknowledgement, and
@@ -318,7 +472,7 @@ func (k *Kafka) Write(msg types.Message) error {
 // acknowledgement, and returns an error if applicable.
 func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 	k.connMut.RLock()
-	producer := k.producer
+	producer, topicCreator := k.producer, k.topicCreator
 	k.connMut.RUnlock()
 
 	if producer == nil {
@@ -344,6 +498,16 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 		return nil
 	})
 
+	if topicCreator != nil {
+		if err := k.ensureTopics(topicCreator, msgs); err != nil {
+			return err
+		}
+	}
+
+	if k.conf.Transaction.Enabled {
+		return k.writeTransactional(ctx, producer, msg, msgs)
+	}
+
 	err := producer.SendMessages(msgs)
 	for err != nil {
 		if pErrs, ok := err.(sarama.ProducerErrors); !k.conf.RetryAsBatch && ok {
@@ -395,6 +559,127 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 	return nil
 }
 
+// txnOffsets extracts the offsets to commit within a transaction from the
+// metadata of messages consumed by a kafka input. The offset committed for
+// each partition is the offset following the highest consumed offset.
+func txnOffsets(msg types.Message) map[string][]*sarama.PartitionOffsetMetadata {
+	highest := map[string]map[int32]int64{}
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		topic := meta.Get("kafka_topic")
+		if topic == "" {
+			return nil
+		}
+		partition, err := strconv.ParseInt(meta.Get("kafka_partition"), 10, 32)
+		if err != nil {
+			return nil
+		}
+		offset, err := strconv.ParseInt(meta.Get("kafka_offset"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		partitions, exists := highest[topic]
+		if !exists {
+			partitions = map[int32]int64{}
+			highest[topic] = partitions
+		}
+		if current, exists := partitions[int32(partition)]; !exists || offset > current {
+			partitions[int32(partition)] = offset
+		}
+		return nil
+	})
+
+	offsets := make(map[string][]*sarama.PartitionOffsetMetadata, len(highest))
+	for topic, partitions := range highest {
+		for partition, offset := range partitions {
+			offsets[topic] = append(offsets[topic], &sarama.PartitionOffsetMetadata{
+				Partition: partition,
+				Offset:    offset + 1,
+			})
+		}
+	}
+	return offsets
+}
+
+// sendTransaction sends a batch of messages within a single transaction,
+// along with consumer offsets when a consumer group is configured, and aborts
+// the transaction on failure.
+func (k *Kafka) sendTransaction(producer sarama.SyncProducer, msgs []*sarama.ProducerMessage, offsets map[string][]*sarama.PartitionOffsetMetadata) error {
+	if err := producer.BeginTxn(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err := producer.SendMessages(msgs)
+	if err == nil && len(offsets) > 0 {
+		if err = producer.AddOffsetsToTxn(offsets, k.conf.Transaction.ConsumerGroup); err != nil {
+			err = fmt.Errorf("failed to add offsets to transaction: %w", err)
+		}
+	}
+	if err == nil {
+		if err = producer.CommitTxn(); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError == 0 {
+		if aErr := producer.AbortTxn(); aErr != nil {
+			k.log.Errorf("Failed to abort transaction: %v\n", aErr)
+		}
+	}
+	return err
+}
+
+// writeTransactional writes a batch of messages within a transaction, the
+// entire transaction is retried on failure and therefore messages are never
+// retried individually.
+func (k *Kafka) writeTransactional(ctx context.Context, producer sarama.SyncProducer, msg types.Message, msgs []*sarama.ProducerMessage) error {
+	var offsets map[string][]*sarama.PartitionOffsetMetadata
+	if k.conf.Transaction.ConsumerGroup != "" {
+		offsets = txnOffsets(msg)
+	}
+
+	boff := k.backoffCtor()
+	for {
+		err := k.sendTransaction(producer, msgs, offsets)
+		if err == nil {
+			return nil
+		}
+		k.log.Errorf("Failed to send messages within transaction: %v\n", err)
+
+		if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0 {
+			// The producer can no longer be used and must be recreated, which
+			// also fences off any stale transactions.
+			k.connMut.Lock()
+			if k.producer == producer {
+				k.producer.Close()
+				k.producer = nil
+			}
+			k.connMut.Unlock()
+			return types.ErrNotConnected
+		}
+
+		tNext := boff.NextBackOff()
+		if tNext == backoff.Stop {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(tNext):
+		}
+
+		// Recheck connection is alive
+		k.connMut.RLock()
+		producer = k.producer
+		k.connMut.RUnlock()
+
+		if producer == nil {
+			return types.ErrNotConnected
+		}
+	}
+}
+
 // CloseAsync shuts down the Kafka writer and stops processing messages.
 func (k *Kafka) CloseAsync() {
 	go func() {
@@ -403,6 +688,10 @@ func (k *Kafka) CloseAsync() {
 			k.producer.Close()
 			k.producer = nil
 		}
+		if k.topicCreator != nil {
+			k.topicCreator.Close()
+			k.topicCreator = nil
+		}
 		k.connMut.Unlock()
 	}()
 }
@@ -0,0 +1,174 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockKafkaTopicCreator struct {
+	created map[string]*sarama.TopicDetail
+	errs    map[string]error
+}
+
+func (m *mockKafkaTopicCreator) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	if err, exists := m.errs[topic]; exists {
+		return err
+	}
+	m.created[topic] = detail
+	return nil
+}
+
+func (m *mockKafkaTopicCreator) Close() error {
+	return nil
+}
+
+func TestKafkaEnsureTopics(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.CreateTopics.Enabled = true
+	conf.CreateTopics.Partitions = 3
+	conf.CreateTopics.ReplicationFactor = 2
+	conf.CreateTopics.Configs = map[string]string{"cleanup.policy": "compact"}
+
+	k, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	creator := &mockKafkaTopicCreator{
+		created: map[string]*sarama.TopicDetail{},
+		errs: map[string]error{
+			"exists": &sarama.TopicError{Err: sarama.ErrTopicAlreadyExists},
+			"broken": errors.New("nope"),
+		},
+	}
+
+	require.NoError(t, k.ensureTopics(creator, []*sarama.ProducerMessage{
+		{Topic: "foo"}, {Topic: "exists"}, {Topic: "foo"}, {Topic: "bar"},
+	}))
+
+	require.Len(t, creator.created, 2)
+	detail := creator.created["foo"]
+	assert.Equal(t, int32(3), detail.NumPartitions)
+	assert.Equal(t, int16(2), detail.ReplicationFactor)
+	require.Contains(t, detail.ConfigEntries, "cleanup.policy")
+	assert.Equal(t, "compact", *detail.ConfigEntries["cleanup.policy"])
+
+	// Known topics are not created again.
+	delete(creator.created, "foo")
+	require.NoError(t, k.ensureTopics(creator, []*sarama.ProducerMessage{{Topic: "foo"}}))
+	assert.NotContains(t, creator.created, "foo")
+
+	err = k.ensureTopics(creator, []*sarama.ProducerMessage{{Topic: "broken"}})
+	require.EqualError(t, err, "failed to create topic 'broken': nope")
+}
+
+func TestKafkaKnownTopicsReset(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+
+	conf := NewKafkaConfig()
+	conf.Addresses = []string{broker.Addr()}
+	conf.CreateTopics.Enabled = true
+
+	k, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, k.Connect())
+	k.knownTopics["foo"] = struct{}{}
+
+	// Reconnecting must forget the topics known to the previous connection.
+	k.connMut.Lock()
+	k.producer.Close()
+	k.producer = nil
+	k.topicCreator.Close()
+	k.topicCreator = nil
+	k.connMut.Unlock()
+
+	require.NoError(t, k.Connect())
+	assert.Empty(t, k.knownTopics)
+
+	k.CloseAsync()
+	require.NoError(t, k.WaitForClose(time.Second))
+}
+
+func TestKafkaTransactionConfigErrors(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.Enabled = true
+
+	_, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a transaction id must be specified when transactions are enabled")
+
+	conf.Transaction.ID = "foo"
+	conf.MaxInFlight = 2
+	_, err = NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "max_in_flight must be 1 when transactions are enabled")
+
+	conf.MaxInFlight = 1
+	conf.TargetVersion = "0.10.2.0"
+	_, err = NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a target_version of at least 0.11.0 is required for idempotent and transactional writes, got 0.10.2.0")
+
+	conf.TargetVersion = "2.1.0"
+	_, err = NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+}
+
+func TestKafkaTxnOffsets(t *testing.T) {
+	msg := message.New([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")})
+	setMeta := func(i int, topic, partition, offset string) {
+		msg.Get(i).Metadata().
+			Set("kafka_topic", topic).
+			Set("kafka_partition", partition).
+			Set("kafka_offset", offset)
+	}
+	setMeta(0, "foo", "0", "10")
+	setMeta(1, "foo", "0", "12")
+	setMeta(2, "foo", "1", "5")
+	setMeta(3, "bar", "0", "nope")
+
+	offsets := txnOffsets(msg)
+	require.Len(t, offsets, 1)
+	assert.ElementsMatch(t, []*sarama.PartitionOffsetMetadata{
+		{Partition: 0, Offset: 13},
+		{Partition: 1, Offset: 6},
+	}, offsets["foo"])
+}
+
+func TestKafkaTransactionalWrite(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.Enabled = true
+	conf.Transaction.ID = "foo"
+	conf.Transaction.ConsumerGroup = "bar"
+
+	k, err := NewKafka(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageAndSucceed()
+	producer.ExpectSendMessageAndSucceed()
+	k.producer = producer
+
+	msg := message.New([][]byte{[]byte("a"), []byte("b")})
+	msg.Get(0).Metadata().
+		Set("kafka_topic", "in").
+		Set("kafka_partition", "0").
+		Set("kafka_offset", "3")
+
+	require.NoError(t, k.WriteWithContext(context.Background(), msg))
+	assert.Equal(t, sarama.ProducerTxnFlagReady, producer.TxnStatus())
+	require.NoError(t, producer.Close())
+}
@@ -2,17 +2,22 @@ package writer
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/scaleproto"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 	"go.nanomsg.org/mangos/v3"
 	"go.nanomsg.org/mangos/v3/protocol/pub"
 	"go.nanomsg.org/mangos/v3/protocol/push"
+	"go.nanomsg.org/mangos/v3/protocol/req"
+	"go.nanomsg.org/mangos/v3/protocol/surveyor"
 
 	// Import all transport types
 	_ "go.nanomsg.org/mangos/v3/transport/all"
@@ -22,21 +27,27 @@ import (
 
 // NanomsgConfig contains configuration fields for the Nanomsg output type.
 type NanomsgConfig struct {
-	URLs        []string `json:"urls" yaml:"urls"`
-	Bind        bool     `json:"bind" yaml:"bind"`
-	SocketType  string   `json:"socket_type" yaml:"socket_type"`
-	PollTimeout string   `json:"poll_timeout" yaml:"poll_timeout"`
-	MaxInFlight int      `json:"max_in_flight" yaml:"max_in_flight"`
+	URLs              []string    `json:"urls" yaml:"urls"`
+	Bind              bool        `json:"bind" yaml:"bind"`
+	SocketType        string      `json:"socket_type" yaml:"socket_type"`
+	TLS               btls.Config `json:"tls" yaml:"tls"`
+	PollTimeout       string      `json:"poll_timeout" yaml:"poll_timeout"`
+	SurveyTimeout     string      `json:"survey_timeout" yaml:"survey_timeout"`
+	PropagateResponse bool        `json:"propagate_response" yaml:"propagate_response"`
+	MaxInFlight       int         `json:"max_in_flight" yaml:"max_in_flight"`
 }
 
 // NewNanomsgConfig creates a new NanomsgConfig with default values.
 func NewNanomsgConfig() NanomsgConfig {
 	return NanomsgConfig{
-		URLs:        []string{"tcp://localhost:5556"},
-		Bind:        false,
-		SocketType:  "PUSH",
-		PollTimeout: "5s",
-		MaxInFlight: 1,
+		URLs:              []string{"tcp://localhost:5556"},
+		Bind:              false,
+		SocketType:        "PUSH",
+		TLS:               btls.NewConfig(),
+		PollTimeout:       "5s",
+		SurveyTimeout:     "1s",
+		PropagateResponse: false,
+		MaxInFlight:       1,
 	}
 }
 
@@ -50,7 +61,9 @@ type Nanomsg struct {
 	urls []string
 	conf NanomsgConfig
 
-	timeout time.Duration
+	timeout       time.Duration
+	surveyTimeout time.Duration
+	tlsConf       *tls.Config
 
 	socket  mangos.Socket
 	sockMut sync.RWMutex
@@ -63,14 +76,7 @@ func NewNanomsg(conf NanomsgConfig, log log.Modular, stats metrics.Type) (*Nanom
 		stats: stats,
 		conf:  conf,
 	}
-	for _, u := range conf.URLs {
-		for _, splitU := range strings.Split(u, ",") {
-			if len(splitU) > 0 {
-				// TODO: V4 Remove this work around
-				s.urls = append(s.urls, strings.Replace(splitU, "//*:", "//0.0.0.0:", 1))
-			}
-		}
-	}
+	s.urls = scaleproto.SplitURLs(conf.URLs)
 
 	if tout := conf.PollTimeout; len(tout) > 0 {
 		var err error
@@ -78,6 +84,18 @@ func NewNanomsg(conf NanomsgConfig, log log.Modular, stats metrics.Type) (*Nanom
 			return nil, fmt.Errorf("failed to parse poll timeout string: %v", err)
 		}
 	}
+	if tout := conf.SurveyTimeout; len(tout) > 0 {
+		var err error
+		if s.surveyTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse survey timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		var err error
+		if s.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
 
 	socket, err := getSocketFromType(conf.SocketType)
 	if err != nil {
@@ -96,6 +114,10 @@ func getSocketFromType(t string) (mangos.Socket, error) {
 		return push.NewSocket()
 	case "PUB":
 		return pub.NewSocket()
+	case "REQ":
+		return req.NewSocket()
+	case "SURVEYOR":
+		return surveyor.NewSocket()
 	}
 	return nil, types.ErrInvalidScaleProtoType
 }
@@ -120,28 +142,17 @@ func (s *Nanomsg) Connect() error {
 	}
 
 	// Set timeout to prevent endless lock.
-	if s.conf.SocketType == "PUSH" {
+	if s.conf.SocketType == "PUSH" || s.conf.SocketType == "REQ" {
 		if err := socket.SetOption(
 			mangos.OptionSendDeadline, s.timeout,
 		); err != nil {
+			socket.Close()
 			return err
 		}
 	}
 
-	if s.conf.Bind {
-		for _, addr := range s.urls {
-			if err = socket.Listen(addr); err != nil {
-				break
-			}
-		}
-	} else {
-		for _, addr := range s.urls {
-			if err = socket.Dial(addr); err != nil {
-				break
-			}
-		}
-	}
-	if err != nil {
+	if err = scaleproto.Connect(socket, s.urls, s.conf.Bind, s.tlsConf); err != nil {
+		socket.Close()
 		return err
 	}
 
@@ -178,11 +189,97 @@ func (s *Nanomsg) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
+	switch s.conf.SocketType {
+	case "REQ", "SURVEYOR":
+		return s.writeRequests(socket, msg)
+	}
 	return IterateBatchedSend(msg, func(i int, p types.Part) error {
 		return socket.Send(p.Get())
 	})
 }
 
+// writeRequests sends each message as a request (or survey) on a dedicated
+// context of the socket and waits for the reply (or responses).
+func (s *Nanomsg) writeRequests(socket mangos.Socket, msg types.Message) error {
+	responses := make([][][]byte, msg.Len())
+	if err := IterateBatchedSend(msg, func(i int, p types.Part) error {
+		var err error
+		responses[i], err = s.request(socket, p.Get())
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if s.conf.PropagateResponse {
+		resMsg := msg.Copy()
+		parts := make([]types.Part, 0, msg.Len())
+		_ = resMsg.Iter(func(i int, p types.Part) error {
+			for _, res := range responses[i] {
+				part := p.Copy()
+				part.Set(res)
+				parts = append(parts, part)
+			}
+			return nil
+		})
+		resMsg.SetAll(parts)
+		if err := roundtrip.SetAsResponse(resMsg); err != nil {
+			s.log.Debugf("Failed to propagate response: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func (s *Nanomsg) request(socket mangos.Socket, data []byte) ([][]byte, error) {
+	reqCtx, err := socket.OpenContext()
+	if err != nil {
+		return nil, err
+	}
+	defer reqCtx.Close()
+
+	if s.conf.SocketType == "SURVEYOR" {
+		if err = reqCtx.SetOption(mangos.OptionSurveyTime, s.surveyTimeout); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, opt := range []string{mangos.OptionSendDeadline, mangos.OptionRecvDeadline} {
+			if err = reqCtx.SetOption(opt, s.timeout); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = reqCtx.Send(data); err != nil {
+		if err == mangos.ErrSendTimeout {
+			return nil, types.ErrTimeout
+		}
+		return nil, err
+	}
+
+	if s.conf.SocketType == "REQ" {
+		var res []byte
+		if res, err = reqCtx.Recv(); err != nil {
+			if err == mangos.ErrRecvTimeout {
+				return nil, types.ErrTimeout
+			}
+			return nil, err
+		}
+		return [][]byte{res}, nil
+	}
+
+	// Responses to a survey are collected until it expires.
+	var responses [][]byte
+	for {
+		res, err := reqCtx.Recv()
+		if err != nil {
+			if err == mangos.ErrProtoState {
+				return responses, nil
+			}
+			return nil, err
+		}
+		responses = append(responses, res)
+	}
+}
+
 // CloseAsync shuts down the Nanomsg output and stops processing messages.
 func (s *Nanomsg) CloseAsync() {
 	go func() {
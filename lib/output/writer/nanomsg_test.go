@@ -0,0 +1,136 @@
+package writer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nanomsg.org/mangos/v3"
+	"go.nanomsg.org/mangos/v3/protocol/rep"
+	"go.nanomsg.org/mangos/v3/protocol/respondent"
+)
+
+func TestNanomsgReq(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	repSock, err := rep.NewSocket()
+	require.NoError(t, err)
+	defer repSock.Close()
+	require.NoError(t, repSock.Listen("inproc://benthos_nanomsg_req_test"))
+
+	go func() {
+		for {
+			req, err := repSock.Recv()
+			if err != nil {
+				return
+			}
+			if err = repSock.Send(append([]byte("re:"), req...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	conf := NewNanomsgConfig()
+	conf.URLs = []string{"inproc://benthos_nanomsg_req_test"}
+	conf.SocketType = "REQ"
+	conf.PropagateResponse = true
+
+	w, err := NewNanomsg(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(ctx))
+	defer w.CloseAsync()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	store := roundtrip.NewResultStore()
+	roundtrip.AddResultStore(msg, store)
+
+	require.NoError(t, w.WriteWithContext(ctx, msg))
+
+	results := store.Get()
+	require.Len(t, results, 1)
+	assert.Equal(t, [][]byte{[]byte("re:foo")}, message.GetAllBytes(results[0]))
+	assert.Equal(t, "foo", string(msg.Get(0).Get()))
+}
+
+func TestNanomsgReqTimeout(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	repSock, err := rep.NewSocket()
+	require.NoError(t, err)
+	defer repSock.Close()
+	require.NoError(t, repSock.Listen("inproc://benthos_nanomsg_req_timeout_test"))
+
+	conf := NewNanomsgConfig()
+	conf.URLs = []string{"inproc://benthos_nanomsg_req_timeout_test"}
+	conf.SocketType = "REQ"
+	conf.PollTimeout = "100ms"
+
+	w, err := NewNanomsg(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(ctx))
+	defer w.CloseAsync()
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{[]byte("foo")}))
+	assert.Equal(t, types.ErrTimeout, err)
+}
+
+func TestNanomsgSurveyor(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := NewNanomsgConfig()
+	conf.URLs = []string{"inproc://benthos_nanomsg_surveyor_test"}
+	conf.Bind = true
+	conf.SocketType = "SURVEYOR"
+	conf.SurveyTimeout = "200ms"
+	conf.PropagateResponse = true
+
+	w, err := NewNanomsg(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(ctx))
+	defer w.CloseAsync()
+
+	for _, name := range []string{"a", "b"} {
+		respSock, err := respondent.NewSocket()
+		require.NoError(t, err)
+		defer respSock.Close()
+		require.NoError(t, respSock.Dial("inproc://benthos_nanomsg_surveyor_test"))
+
+		go func(sock mangos.Socket, name string) {
+			for {
+				survey, err := sock.Recv()
+				if err != nil {
+					return
+				}
+				if err = sock.Send(append([]byte(name+":"), survey...)); err != nil {
+					return
+				}
+			}
+		}(respSock, name)
+	}
+
+	// Wait for both respondents to be connected.
+	var results []types.Message
+	require.Eventually(t, func() bool {
+		msg := message.New([][]byte{[]byte("ping")})
+		store := roundtrip.NewResultStore()
+		roundtrip.AddResultStore(msg, store)
+		require.NoError(t, w.WriteWithContext(ctx, msg))
+		results = store.Get()
+		return len(results) == 1 && results[0].Len() == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	assert.ElementsMatch(t, []string{"a:ping", "b:ping"}, []string{
+		string(results[0].Get(0).Get()),
+		string(results[0].Get(1).Get()),
+	})
+}
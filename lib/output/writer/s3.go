@@ -3,15 +3,20 @@ package writer
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -20,25 +25,47 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 )
 
 //------------------------------------------------------------------------------
 
+// AmazonS3MultipartConfig contains configuration fields for streaming messages
+// into objects with multipart uploads.
+type AmazonS3MultipartConfig struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	Codec       string `json:"codec" yaml:"codec"`
+	PartSize    int64  `json:"part_size" yaml:"part_size"`
+	Concurrency int    `json:"concurrency" yaml:"concurrency"`
+}
+
+// NewAmazonS3MultipartConfig creates a new AmazonS3MultipartConfig with default
+// values.
+func NewAmazonS3MultipartConfig() AmazonS3MultipartConfig {
+	return AmazonS3MultipartConfig{
+		Enabled:     false,
+		Codec:       "lines",
+		PartSize:    s3manager.MinUploadPartSize,
+		Concurrency: 4,
+	}
+}
+
 // AmazonS3Config contains configuration fields for the AmazonS3 output type.
 type AmazonS3Config struct {
 	sess.Config        `json:",inline" yaml:",inline"`
-	Bucket             string             `json:"bucket" yaml:"bucket"`
-	ForcePathStyleURLs bool               `json:"force_path_style_urls" yaml:"force_path_style_urls"`
-	Path               string             `json:"path" yaml:"path"`
-	Tags               map[string]string  `json:"tags" yaml:"tags"`
-	ContentType        string             `json:"content_type" yaml:"content_type"`
-	ContentEncoding    string             `json:"content_encoding" yaml:"content_encoding"`
-	Metadata           output.Metadata    `json:"metadata" yaml:"metadata"`
-	StorageClass       string             `json:"storage_class" yaml:"storage_class"`
-	Timeout            string             `json:"timeout" yaml:"timeout"`
-	KMSKeyID           string             `json:"kms_key_id" yaml:"kms_key_id"`
-	MaxInFlight        int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Batching           batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Bucket             string                  `json:"bucket" yaml:"bucket"`
+	ForcePathStyleURLs bool                    `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	Path               string                  `json:"path" yaml:"path"`
+	Tags               map[string]string       `json:"tags" yaml:"tags"`
+	ContentType        string                  `json:"content_type" yaml:"content_type"`
+	ContentEncoding    string                  `json:"content_encoding" yaml:"content_encoding"`
+	Metadata           output.Metadata         `json:"metadata" yaml:"metadata"`
+	StorageClass       string                  `json:"storage_class" yaml:"storage_class"`
+	Timeout            string                  `json:"timeout" yaml:"timeout"`
+	KMSKeyID           string                  `json:"kms_key_id" yaml:"kms_key_id"`
+	MaxInFlight        int                     `json:"max_in_flight" yaml:"max_in_flight"`
+	Multipart          AmazonS3MultipartConfig `json:"multipart" yaml:"multipart"`
+	Batching           batch.PolicyConfig      `json:"batching" yaml:"batching"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
@@ -56,6 +83,7 @@ func NewAmazonS3Config() AmazonS3Config {
 		Timeout:            "5s",
 		KMSKeyID:           "",
 		MaxInFlight:        1,
+		Multipart:          NewAmazonS3MultipartConfig(),
 		Batching:           batch.NewPolicyConfig(),
 	}
 }
@@ -80,11 +108,16 @@ type AmazonS3 struct {
 	metaFilter      *output.MetadataFilter
 
 	session  *session.Session
-	uploader *s3manager.Uploader
+	uploader s3manageriface.UploaderAPI
 	timeout  time.Duration
 
-	log   log.Modular
-	stats metrics.Type
+	multipartCodec codec.WriterConstructor
+	streamMut      sync.Mutex
+	stream         *s3Stream
+
+	log     log.Modular
+	stats   metrics.Type
+	shutSig *shutdown.Signaller
 }
 
 // NewAmazonS3 creates a new Amazon S3 bucket writer.Type.
@@ -105,8 +138,24 @@ func NewAmazonS3(
 		log:     log,
 		stats:   stats,
 		timeout: timeout,
+		shutSig: shutdown.NewSignaller(),
 	}
 	var err error
+	if conf.Multipart.Enabled {
+		var codecConf codec.WriterConfig
+		if a.multipartCodec, codecConf, err = codec.GetWriter(conf.Multipart.Codec); err != nil {
+			return nil, err
+		}
+		if codecConf.CloseAfter {
+			return nil, fmt.Errorf("codec %v is not supported with multipart uploads", conf.Multipart.Codec)
+		}
+		if conf.Multipart.PartSize < s3manager.MinUploadPartSize {
+			return nil, fmt.Errorf("multipart part size must be at least %v bytes, got %v", s3manager.MinUploadPartSize, conf.Multipart.PartSize)
+		}
+		if conf.Multipart.Concurrency < 1 {
+			return nil, fmt.Errorf("multipart concurrency must be greater than 0, got %v", conf.Multipart.Concurrency)
+		}
+	}
 	if a.path, err = bloblang.NewField(conf.Path); err != nil {
 		return nil, fmt.Errorf("failed to parse path expression: %v", err)
 	}
@@ -172,55 +221,152 @@ func (a *AmazonS3) Write(msg types.Message) error {
 	return a.WriteWithContext(context.Background(), msg)
 }
 
+// uploadInput creates the input of an upload of an object, where all fields
+// other than the body are interpolated from a message.
+func (a *AmazonS3) uploadInput(i int, msg types.Message, path string, body io.Reader) *s3manager.UploadInput {
+	metadata := map[string]*string{}
+	a.metaFilter.Iter(msg.Get(i).Metadata(), func(k, v string) error {
+		metadata[k] = aws.String(v)
+		return nil
+	})
+
+	var contentEncoding *string
+	if ce := a.contentEncoding.String(i, msg); len(ce) > 0 {
+		contentEncoding = aws.String(ce)
+	}
+
+	uploadInput := &s3manager.UploadInput{
+		Bucket:          &a.conf.Bucket,
+		Key:             aws.String(path),
+		Body:            body,
+		ContentType:     aws.String(a.contentType.String(i, msg)),
+		ContentEncoding: contentEncoding,
+		StorageClass:    aws.String(a.storageClass.String(i, msg)),
+		Metadata:        metadata,
+	}
+
+	// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
+	if len(a.tags) > 0 {
+		tags := make([]string, len(a.tags))
+		for j, pair := range a.tags {
+			tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(pair.value.String(i, msg))
+		}
+		uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
+	}
+
+	if a.conf.KMSKeyID != "" {
+		uploadInput.ServerSideEncryption = aws.String("aws:kms")
+		uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
+	}
+	return uploadInput
+}
+
 // WriteWithContext attempts to write message contents to a target S3 bucket as
 // files.
 func (a *AmazonS3) WriteWithContext(wctx context.Context, msg types.Message) error {
-	if a.session == nil {
+	if a.uploader == nil {
 		return types.ErrNotConnected
 	}
 
+	if a.conf.Multipart.Enabled {
+		return a.writeMultipart(wctx, msg)
+	}
+
 	ctx, cancel := context.WithTimeout(
 		wctx, a.timeout,
 	)
 	defer cancel()
 
 	return IterateBatchedSend(msg, func(i int, p types.Part) error {
-		metadata := map[string]*string{}
-		a.metaFilter.Iter(p.Metadata(), func(k, v string) error {
-			metadata[k] = aws.String(v)
-			return nil
+		uploadInput := a.uploadInput(i, msg, a.path.String(i, msg), bytes.NewReader(p.Get()))
+		if _, err := a.uploader.UploadWithContext(ctx, uploadInput); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// s3Stream is an object that is uploaded in parts as messages are written to
+// it.
+type s3Stream struct {
+	path   string
+	pipeW  *io.PipeWriter
+	writer codec.Writer
+	done   chan error
+}
+
+// openStream begins the upload of an object, where the fields of the upload are
+// interpolated from the first message written to it.
+func (a *AmazonS3) openStream(i int, msg types.Message, path string) (*s3Stream, error) {
+	pipeR, pipeW := io.Pipe()
+	writer, err := a.multipartCodec(pipeW)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &s3Stream{
+		path:   path,
+		pipeW:  pipeW,
+		writer: writer,
+		done:   make(chan error, 1),
+	}
+
+	uploadInput := a.uploadInput(i, msg, path, pipeR)
+	go func() {
+		_, err := a.uploader.UploadWithContext(context.Background(), uploadInput, func(u *s3manager.Uploader) {
+			u.PartSize = a.conf.Multipart.PartSize
+			u.Concurrency = a.conf.Multipart.Concurrency
 		})
+		// Unblocks any pending writes when the upload ends early.
+		pipeR.CloseWithError(err)
+		s.done <- err
+	}()
+	return s, nil
+}
 
-		var contentEncoding *string
-		if ce := a.contentEncoding.String(i, msg); len(ce) > 0 {
-			contentEncoding = aws.String(ce)
-		}
+// complete finishes writing to the object and waits for its upload to finish.
+func (s *s3Stream) complete(ctx context.Context) error {
+	if err := s.writer.Close(ctx); err != nil {
+		s.pipeW.CloseWithError(err)
+		<-s.done
+		return err
+	}
+	return <-s.done
+}
 
-		uploadInput := &s3manager.UploadInput{
-			Bucket:          &a.conf.Bucket,
-			Key:             aws.String(a.path.String(i, msg)),
-			Body:            bytes.NewReader(p.Get()),
-			ContentType:     aws.String(a.contentType.String(i, msg)),
-			ContentEncoding: contentEncoding,
-			StorageClass:    aws.String(a.storageClass.String(i, msg)),
-			Metadata:        metadata,
-		}
+// abort ends the upload of the object, which discards its uploaded parts.
+func (s *s3Stream) abort(err error) {
+	s.pipeW.CloseWithError(err)
+	<-s.done
+}
 
-		// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
-		if len(a.tags) > 0 {
-			tags := make([]string, len(a.tags))
-			for j, pair := range a.tags {
-				tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(pair.value.String(i, msg))
+var errS3StreamAborted = errors.New("upload aborted")
+
+func (a *AmazonS3) writeMultipart(ctx context.Context, msg types.Message) error {
+	a.streamMut.Lock()
+	defer a.streamMut.Unlock()
+
+	return IterateBatchedSend(msg, func(i int, p types.Part) error {
+		path := a.path.String(i, msg)
+		if a.stream != nil && a.stream.path != path {
+			prevPath := a.stream.path
+			err := a.stream.complete(ctx)
+			a.stream = nil
+			if err != nil {
+				return fmt.Errorf("failed to complete upload of object '%v': %w", prevPath, err)
 			}
-			uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
 		}
-
-		if a.conf.KMSKeyID != "" {
-			uploadInput.ServerSideEncryption = aws.String("aws:kms")
-			uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
+		if a.stream == nil {
+			var err error
+			if a.stream, err = a.openStream(i, msg, path); err != nil {
+				return err
+			}
 		}
-
-		if _, err := a.uploader.UploadWithContext(ctx, uploadInput); err != nil {
+		if err := a.stream.writer.Write(ctx, p); err != nil {
+			a.stream.abort(errS3StreamAborted)
+			a.stream = nil
 			return err
 		}
 		return nil
@@ -229,11 +375,27 @@ func (a *AmazonS3) WriteWithContext(wctx context.Context, msg types.Message) err
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonS3) CloseAsync() {
+	go func() {
+		a.streamMut.Lock()
+		if a.stream != nil {
+			if err := a.stream.complete(context.Background()); err != nil {
+				a.log.Errorf("Failed to complete upload of object '%v': %v\n", a.stream.path, err)
+			}
+			a.stream = nil
+		}
+		a.streamMut.Unlock()
+		a.shutSig.ShutdownComplete()
+	}()
 }
 
 // WaitForClose will block until either the reader is closed or a specified
 // timeout occurs.
-func (a *AmazonS3) WaitForClose(time.Duration) error {
+func (a *AmazonS3) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.shutSig.HasClosedChan():
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
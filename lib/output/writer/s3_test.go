@@ -0,0 +1,133 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockS3Object struct {
+	contentType string
+	body        string
+	partSize    int64
+}
+
+type mockS3Uploader struct {
+	s3manageriface.UploaderAPI
+
+	mut     sync.Mutex
+	objects map[string]mockS3Object
+	failKey string
+}
+
+func (m *mockS3Uploader) UploadWithContext(ctx aws.Context, input *s3manager.UploadInput, opts ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	u := &s3manager.Uploader{}
+	for _, opt := range opts {
+		opt(u)
+	}
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if *input.Key == m.failKey {
+		return nil, errors.New("upload failed")
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.objects[*input.Key] = mockS3Object{
+		contentType: *input.ContentType,
+		body:        string(body),
+		partSize:    u.PartSize,
+	}
+	return &s3manager.UploadOutput{}, nil
+}
+
+func TestAmazonS3Multipart(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = `${! meta("path") }`
+	conf.ContentType = `${! meta("type") }`
+	conf.Multipart.Enabled = true
+	conf.Multipart.PartSize = 10 * 1024 * 1024
+
+	w, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	uploader := &mockS3Uploader{objects: map[string]mockS3Object{}}
+	w.uploader = uploader
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	msg.Get(0).Metadata().Set("path", "a.txt").Set("type", "text/plain")
+	msg.Get(1).Metadata().Set("path", "a.txt").Set("type", "ignored")
+	msg.Get(2).Metadata().Set("path", "b.txt").Set("type", "text/plain")
+	require.NoError(t, w.WriteWithContext(context.Background(), msg))
+
+	msg = message.New([][]byte{[]byte("qux")})
+	msg.Get(0).Metadata().Set("path", "b.txt")
+	require.NoError(t, w.WriteWithContext(context.Background(), msg))
+
+	w.CloseAsync()
+	require.NoError(t, w.WaitForClose(time.Second))
+
+	assert.Equal(t, map[string]mockS3Object{
+		"a.txt": {contentType: "text/plain", body: "foo\nbar\n", partSize: 10 * 1024 * 1024},
+		"b.txt": {contentType: "text/plain", body: "baz\nqux\n", partSize: 10 * 1024 * 1024},
+	}, uploader.objects)
+}
+
+func TestAmazonS3MultipartUploadError(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = `${! content() }`
+	conf.Multipart.Enabled = true
+
+	w, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	uploader := &mockS3Uploader{objects: map[string]mockS3Object{}, failKey: "foo"}
+	w.uploader = uploader
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{[]byte("foo")})))
+
+	// Completing the failed object fails the write of the next message.
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{[]byte("bar")}))
+	require.EqualError(t, err, "failed to complete upload of object 'foo': upload failed")
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{[]byte("bar")})))
+
+	w.CloseAsync()
+	require.NoError(t, w.WaitForClose(time.Second))
+
+	assert.Equal(t, map[string]mockS3Object{
+		"bar": {contentType: "application/octet-stream", body: "bar\n", partSize: 5 * 1024 * 1024},
+	}, uploader.objects)
+}
+
+func TestAmazonS3MultipartConfigErrors(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Multipart.Enabled = true
+	conf.Multipart.Codec = "all-bytes"
+	_, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "codec all-bytes is not supported with multipart uploads")
+
+	conf.Multipart.Codec = "lines"
+	conf.Multipart.PartSize = 1024
+	_, err = NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "multipart part size must be at least 5242880 bytes, got 1024")
+
+	conf.Multipart.PartSize = 5 * 1024 * 1024
+	conf.Multipart.Concurrency = 0
+	_, err = NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "multipart concurrency must be greater than 0, got 0")
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
+	"github.com/Jeffail/benthos/v3/internal/unixsock"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -56,7 +57,11 @@ func NewSocket(
 	stats metrics.Type,
 ) (*Socket, error) {
 	switch conf.Network {
-	case "tcp", "udp", "unix":
+	case "tcp", "udp":
+	case "unix", "unixgram":
+		if err := unixsock.CheckAddress(conf.Address); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("socket network '%v' is not supported by this output", conf.Network)
 	}
@@ -3,10 +3,13 @@
 package writer
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -21,12 +24,16 @@ type ZMQ4 struct {
 	log   log.Modular
 	stats metrics.Type
 
-	urls []string
-	conf *ZMQ4Config
+	urls     []string
+	conf     *ZMQ4Config
+	identity *field.Expression
 
 	pollTimeout time.Duration
 	poller      *zmq4.Poller
 	socket      *zmq4.Socket
+
+	mBlocked metrics.StatCounter
+	mTimeout metrics.StatCounter
 }
 
 // NewZMQ4 creates a new ZMQ4 output type.
@@ -35,6 +42,9 @@ func NewZMQ4(conf *ZMQ4Config, log log.Modular, stats metrics.Type) (*ZMQ4, erro
 		log:   log,
 		stats: stats,
 		conf:  conf,
+
+		mBlocked: stats.GetCounter("send.blocked"),
+		mTimeout: stats.GetCounter("send.blocked.timeout"),
 	}
 
 	_, err := getZMQType(conf.SocketType)
@@ -42,6 +52,14 @@ func NewZMQ4(conf *ZMQ4Config, log log.Modular, stats metrics.Type) (*ZMQ4, erro
 		return nil, err
 	}
 
+	if conf.Identity != "" {
+		if z.identity, err = bloblang.NewField(conf.Identity); err != nil {
+			return nil, fmt.Errorf("failed to parse identity expression: %v", err)
+		}
+	} else if conf.SocketType == "ROUTER" {
+		return nil, errors.New("an identity must be specified when sending with a ROUTER socket")
+	}
+
 	if tout := conf.PollTimeout; len(tout) > 0 {
 		var err error
 		if z.pollTimeout, err = time.ParseDuration(tout); err != nil {
@@ -68,6 +86,10 @@ func getZMQType(t string) (zmq4.Type, error) {
 		return zmq4.PUB, nil
 	case "PUSH":
 		return zmq4.PUSH, nil
+	case "DEALER":
+		return zmq4.DEALER, nil
+	case "ROUTER":
+		return zmq4.ROUTER, nil
 	}
 	return zmq4.PULL, types.ErrInvalidZMQType
 }
@@ -102,6 +124,24 @@ func (z *ZMQ4) Connect() error {
 	}()
 
 	socket.SetSndhwm(z.conf.HighWaterMark)
+	if err = socket.SetBacklog(z.conf.Backlog); err != nil {
+		return err
+	}
+
+	switch z.conf.SocketType {
+	case "DEALER":
+		if z.identity != nil {
+			if err = socket.SetIdentity(z.identity.String(0, message.New(nil))); err != nil {
+				return err
+			}
+		}
+	case "ROUTER":
+		// Messages addressed to unknown peers are rejected rather than
+		// silently dropped so that they can be retried.
+		if err = socket.SetRouterMandatory(1); err != nil {
+			return err
+		}
+	}
 
 	for _, address := range z.urls {
 		if z.conf.Bind {
@@ -127,12 +167,22 @@ func (z *ZMQ4) Write(msg types.Message) error {
 	if z.socket == nil {
 		return types.ErrNotConnected
 	}
-	_, err := z.socket.SendMessageDontwait(message.GetAllBytes(msg))
+	parts := []interface{}{message.GetAllBytes(msg)}
+	if z.conf.SocketType == "ROUTER" {
+		// The first frame sent with a ROUTER socket is the identity of the
+		// peer that the message is routed to.
+		parts = append([]interface{}{z.identity.Bytes(0, msg)}, parts...)
+	}
+	_, err := z.socket.SendMessageDontwait(parts...)
 	if err != nil {
+		// The socket has reached its high water mark, wait until it is able to
+		// accept more messages.
+		z.mBlocked.Incr(1)
 		var polled []zmq4.Polled
 		if polled, err = z.poller.Poll(z.pollTimeout); len(polled) == 1 {
-			_, err = z.socket.SendMessage(message.GetAllBytes(msg))
+			_, err = z.socket.SendMessage(parts...)
 		} else if err == nil {
+			z.mTimeout.Incr(1)
 			return types.ErrTimeout
 		}
 	}
@@ -1,24 +1,36 @@
 package writer
 
+import (
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+)
+
 //------------------------------------------------------------------------------
 
 // ZMQ4Config contains configuration fields for the ZMQ4 output type.
 type ZMQ4Config struct {
-	URLs          []string `json:"urls" yaml:"urls"`
-	Bind          bool     `json:"bind" yaml:"bind"`
-	SocketType    string   `json:"socket_type" yaml:"socket_type"`
-	HighWaterMark int      `json:"high_water_mark" yaml:"high_water_mark"`
-	PollTimeout   string   `json:"poll_timeout" yaml:"poll_timeout"`
+	URLs             []string           `json:"urls" yaml:"urls"`
+	Bind             bool               `json:"bind" yaml:"bind"`
+	SocketType       string             `json:"socket_type" yaml:"socket_type"`
+	Identity         string             `json:"identity" yaml:"identity"`
+	HighWaterMark    int                `json:"high_water_mark" yaml:"high_water_mark"`
+	Backlog          int                `json:"backlog" yaml:"backlog"`
+	PollTimeout      string             `json:"poll_timeout" yaml:"poll_timeout"`
+	BatchAsMultipart bool               `json:"batch_as_multipart" yaml:"batch_as_multipart"`
+	Batching         batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
 
 // NewZMQ4Config creates a new ZMQ4Config with default values.
 func NewZMQ4Config() *ZMQ4Config {
 	return &ZMQ4Config{
-		URLs:          []string{"tcp://*:5556"},
-		Bind:          true,
-		SocketType:    "PUSH",
-		HighWaterMark: 0,
-		PollTimeout:   "5s",
+		URLs:             []string{"tcp://*:5556"},
+		Bind:             true,
+		SocketType:       "PUSH",
+		Identity:         "",
+		HighWaterMark:    0,
+		Backlog:          100,
+		PollTimeout:      "5s",
+		BatchAsMultipart: false,
+		Batching:         batch.NewPolicyConfig(),
 	}
 }
 
@@ -5,6 +5,7 @@ package output
 import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -15,9 +16,10 @@ import (
 func init() {
 	Constructors[TypeZMQ4] = TypeSpec{
 		constructor: fromSimpleConstructor(NewZMQ4),
+		Batches:     true,
 		Summary: `
 The zmq4 output type attempts to send messages to a ZMQ4 port, currently only
-PUSH and PUB sockets are supported.`,
+PUSH, PUB, DEALER and ROUTER sockets are supported.`,
 		Description: `
 ZMQ4 is supported but currently depends on C bindings. Since this is an
 annoyance when building or using Benthos it is not compiled by default.
@@ -30,13 +32,49 @@ machine, then build with the tag:
 
 ` + "```sh" + `
 go install -tags "ZMQ4" github.com/Jeffail/benthos/v3/cmd/benthos
-` + "```" + ``,
+` + "```" + `
+
+### Batches and Multipart Messages
+
+By default each message is sent as an individual ZMQ message. When the field
+` + "`batch_as_multipart`" + ` is set to ` + "`true`" + ` each batch of messages
+is instead sent as a single multipart ZMQ message, where each message of the
+batch is a frame. This is the same framing that the ` + "`zmq4`" + ` input uses
+when it consumes multipart messages as batches. Batches can be formed at the
+output level with the field ` + "`batching`" + `.
+
+### Blocked Sends
+
+When the number of queued messages of the socket reaches the high water mark
+further sends are blocked until the socket is able to accept more messages, or
+until the ` + "`poll_timeout`" + ` elapses, at which point the send is
+reattempted. Each time a send is blocked the counter ` + "`send.blocked`" + ` is
+incremented, and each time a blocked send times out the counter
+` + "`send.blocked.timeout`" + ` is incremented.
+
+### Routing
+
+With a ROUTER socket each message is sent to the connected peer with the routing
+identity resolved from the field ` + "`identity`" + `, which can be interpolated
+from the contents or metadata of the message. Messages addressed to a peer that
+is not connected are rejected and reattempted. When batches are sent as
+multipart messages the identity is resolved from the first message of each
+batch.
+
+With a DEALER socket the field ` + "`identity`" + ` sets the routing identity of
+the socket itself, allowing a ROUTER peer to address replies to it. In this case
+the identity is resolved once when connecting, and therefore should not depend
+on message contents.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"tcp://localhost:5556"}),
 			docs.FieldCommon("bind", "Whether the URLs listed should be bind (otherwise they are connected to)."),
-			docs.FieldCommon("socket_type", "The socket type to send with.").HasOptions("PUSH", "PUB"),
+			docs.FieldCommon("socket_type", "The socket type to send with.").HasOptions("PUSH", "PUB", "DEALER", "ROUTER"),
+			docs.FieldCommon("identity", "The routing identity of the peer to send each message to when using a ROUTER socket, or the identity of the socket itself when using a DEALER socket.", `${! meta("peer_id") }`, "worker-1").IsInterpolated().AtVersion("3.47.0"),
 			docs.FieldAdvanced("high_water_mark", "The message high water mark to use."),
+			docs.FieldAdvanced("backlog", "The maximum length of the queue of pending connections when binding to URLs.").AtVersion("3.47.0"),
 			docs.FieldCommon("poll_timeout", "The maximum period of time to wait for a message to send before the request is abandoned and reattempted."),
+			docs.FieldAdvanced("batch_as_multipart", "Whether to send each batch of messages as a single multipart message, where each message of the batch is a frame. If disabled messages of a batch are sent individually.").AtVersion("3.47.0"),
+			batch.FieldSpec().AtVersion("3.47.0"),
 		},
 		Categories: []Category{
 			CategoryNetwork,
@@ -52,13 +90,16 @@ func NewZMQ4(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if err != nil {
 		return nil, err
 	}
-	s, err := NewWriter(
+	var s Type
+	if s, err = NewWriter(
 		"zmq4", z, log, stats,
-	)
-	if err != nil {
+	); err != nil {
 		return nil, err
 	}
-	return OnlySinglePayloads(s), nil
+	if !conf.ZMQ4.BatchAsMultipart {
+		s = OnlySinglePayloads(s)
+	}
+	return NewBatcherFromConfig(conf.ZMQ4.Batching, s, mgr, log, stats)
 }
 
 //------------------------------------------------------------------------------
@@ -0,0 +1,188 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	llog "log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-zeromq/zmq4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeZMQ4N] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			w, err := newZMQ4NWriter(conf.ZMQ4N, log)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncWriter(TypeZMQ4N, 1, w, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+		Summary: `
+Writes messages to a ZeroMQ socket using a pure Go implementation of the protocol.`,
+		Description: `
+Unlike the ` + "[`zmq4` output](/docs/components/outputs/zmq4)" + ` this output does not depend on C bindings to libzmq, and is therefore included within all builds of Benthos, including statically compiled and cross compiled binaries.
+
+Batches of messages are sent as a single multipart message where each message of the batch is a frame.
+
+This output supports PUSH and PUB sockets. Only the ` + "`tcp`" + `, ` + "`ipc`" + ` and ` + "`inproc`" + ` transports are supported, and socket options such as the high water mark of the ` + "`zmq4`" + ` output are not available.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"tcp://localhost:5556"}).Array(),
+			docs.FieldCommon("bind", "Whether the URLs listed should be bind (otherwise they are connected to)."),
+			docs.FieldCommon("socket_type", "The socket type to send with.").HasOptions("PUSH", "PUB"),
+			docs.FieldAdvanced("dial_retry_delay", "The period of time to wait between failed attempts to connect to a URL."),
+		},
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ZMQ4NConfig contains configuration fields for the ZMQ4N output type.
+type ZMQ4NConfig struct {
+	URLs           []string `json:"urls" yaml:"urls"`
+	Bind           bool     `json:"bind" yaml:"bind"`
+	SocketType     string   `json:"socket_type" yaml:"socket_type"`
+	DialRetryDelay string   `json:"dial_retry_delay" yaml:"dial_retry_delay"`
+}
+
+// NewZMQ4NConfig creates a new ZMQ4NConfig with default values.
+func NewZMQ4NConfig() ZMQ4NConfig {
+	return ZMQ4NConfig{
+		URLs:           []string{"tcp://*:5556"},
+		Bind:           true,
+		SocketType:     "PUSH",
+		DialRetryDelay: "250ms",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type zmq4nWriter struct {
+	urls       []string
+	conf       ZMQ4NConfig
+	retryDelay time.Duration
+	log        log.Modular
+
+	sockMut sync.RWMutex
+	socket  zmq4.Socket
+}
+
+func newZMQ4NWriter(conf ZMQ4NConfig, log log.Modular) (*zmq4nWriter, error) {
+	z := &zmq4nWriter{
+		conf: conf,
+		log:  log,
+	}
+
+	for _, u := range conf.URLs {
+		for _, splitU := range strings.Split(u, ",") {
+			if len(splitU) > 0 {
+				z.urls = append(z.urls, splitU)
+			}
+		}
+	}
+	if len(z.urls) == 0 {
+		return nil, errors.New("at least one url must be specified")
+	}
+
+	if _, err := getZMQ4NSocketCtor(conf.SocketType); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if z.retryDelay, err = time.ParseDuration(conf.DialRetryDelay); err != nil {
+		return nil, fmt.Errorf("failed to parse dial retry delay: %v", err)
+	}
+	return z, nil
+}
+
+func getZMQ4NSocketCtor(t string) (func(context.Context, ...zmq4.Option) zmq4.Socket, error) {
+	switch t {
+	case "PUB":
+		return zmq4.NewPub, nil
+	case "PUSH":
+		return zmq4.NewPush, nil
+	}
+	return nil, types.ErrInvalidZMQType
+}
+
+//------------------------------------------------------------------------------
+
+func (z *zmq4nWriter) ConnectWithContext(ctx context.Context) (err error) {
+	z.sockMut.Lock()
+	defer z.sockMut.Unlock()
+
+	if z.socket != nil {
+		return nil
+	}
+
+	ctor, err := getZMQ4NSocketCtor(z.conf.SocketType)
+	if err != nil {
+		return err
+	}
+
+	socket := ctor(
+		context.Background(),
+		zmq4.WithDialerRetry(z.retryDelay),
+		zmq4.WithLogger(llog.New(ioutil.Discard, "", llog.Flags())),
+	)
+	defer func() {
+		if err != nil {
+			socket.Close()
+		}
+	}()
+
+	for _, address := range z.urls {
+		if z.conf.Bind {
+			err = socket.Listen(address)
+		} else {
+			err = socket.Dial(address)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	z.socket = socket
+	z.log.Infof("Sending ZMQ4N messages to URLs: %s\n", z.urls)
+	return nil
+}
+
+func (z *zmq4nWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	z.sockMut.RLock()
+	socket := z.socket
+	z.sockMut.RUnlock()
+
+	if socket == nil {
+		return types.ErrNotConnected
+	}
+	return socket.Send(zmq4.NewMsgFrom(message.GetAllBytes(msg)...))
+}
+
+func (z *zmq4nWriter) CloseAsync() {
+	z.sockMut.Lock()
+	if z.socket != nil {
+		z.socket.Close()
+		z.socket = nil
+	}
+	z.sockMut.Unlock()
+}
+
+func (z *zmq4nWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-zeromq/zmq4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZMQ4NPush(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := NewZMQ4NConfig()
+	conf.URLs = []string{"inproc://benthos_zmq4n_push_test"}
+
+	w, err := newZMQ4NWriter(conf, log.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, types.ErrNotConnected, w.WriteWithContext(ctx, message.New(nil)))
+
+	require.NoError(t, w.ConnectWithContext(ctx))
+	defer w.CloseAsync()
+
+	pull := zmq4.NewPull(ctx)
+	defer pull.Close()
+	require.NoError(t, pull.Dial("inproc://benthos_zmq4n_push_test"))
+
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{[]byte("hello world")})))
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{[]byte("foo"), []byte("bar")})))
+
+	msg, err := pull.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hello world")}, msg.Frames)
+
+	msg, err = pull.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, msg.Frames)
+}
+
+func TestZMQ4NBadSocketType(t *testing.T) {
+	conf := NewZMQ4NConfig()
+	conf.SocketType = "PULL"
+	_, err := newZMQ4NWriter(conf, log.Noop())
+	assert.Equal(t, types.ErrInvalidZMQType, err)
+}
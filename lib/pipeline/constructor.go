@@ -3,6 +3,8 @@ package pipeline
 import (
 	"fmt"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -20,16 +22,28 @@ import (
 // In order to fully utilise each processing thread you must either have a
 // number of parallel inputs that matches or surpasses the number of pipeline
 // threads, or use a memory buffer.
+//
+// When a key affinity is specified messages are dispatched to threads by the
+// hash of the key, which guarantees that messages sharing a key are processed
+// in order.
+//
+// When profiling is enabled the time spent and allocations made within each
+// processor are aggregated across threads, and are served from the HTTP
+// endpoint ProfilingEndpoint as well as logged once the pipeline is closed.
 type Config struct {
-	Threads    int                `json:"threads" yaml:"threads"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	Threads     int                `json:"threads" yaml:"threads"`
+	KeyAffinity string             `json:"key_affinity" yaml:"key_affinity"`
+	Profiling   ProfilingConfig    `json:"profiling" yaml:"profiling"`
+	Processors  []processor.Config `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Threads:    1,
-		Processors: []processor.Config{},
+		Threads:     1,
+		KeyAffinity: "",
+		Profiling:   NewProfilingConfig(),
+		Processors:  []processor.Config{},
 	}
 }
 
@@ -50,10 +64,17 @@ func (conf Config) Sanitised(removeDeprecated bool) (interface{}, error) {
 			return nil, err
 		}
 	}
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"threads":    conf.Threads,
 		"processors": procConfs,
-	}, nil
+	}
+	if len(conf.KeyAffinity) > 0 {
+		m["key_affinity"] = conf.KeyAffinity
+	}
+	if conf.Profiling.Enabled {
+		m["profiling"] = conf.Profiling
+	}
+	return m, nil
 }
 
 //------------------------------------------------------------------------------
@@ -66,6 +87,35 @@ func New(
 	stats metrics.Type,
 	processorCtors ...types.ProcessorConstructorFunc,
 ) (Type, error) {
+	return NewWithErrorHandling(conf, NewErrorHandlingConfig(), mgr, log, stats, processorCtors...)
+}
+
+// NewWithErrorHandling creates a new pipeline from a config, where an error
+// handling policy is applied to messages that fail the processors of each
+// pipeline.
+func NewWithErrorHandling(
+	conf Config,
+	errConf ErrorHandlingConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+	processorCtors ...types.ProcessorConstructorFunc,
+) (Type, error) {
+	if err := errConf.Validate(); err != nil {
+		return nil, err
+	}
+	var prof *profiler
+	if conf.Profiling.Enabled {
+		var err error
+		if prof, err = newProfiler(conf.Profiling, conf.Processors, log); err != nil {
+			return nil, err
+		}
+		mgr.RegisterEndpoint(
+			ProfilingEndpoint,
+			"Returns the time spent and allocations made within each processor of the pipeline.",
+			prof.handleSummary,
+		)
+	}
 	procs := 0
 	procCtor := func(i *int) (types.Pipeline, error) {
 		processors := make([]types.Processor, len(conf.Processors)+len(processorCtors))
@@ -76,6 +126,9 @@ func New(
 			if err != nil {
 				return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
 			}
+			if prof != nil {
+				processors[j] = prof.wrap(j, processors[j])
+			}
 			*i++
 		}
 		for j, procCtor := range processorCtors {
@@ -85,11 +138,35 @@ func New(
 				return nil, fmt.Errorf("failed to create processor: %v", err)
 			}
 		}
+		if errConf.Enabled() {
+			labels := make([]string, len(processors))
+			for j, procConf := range conf.Processors {
+				labels[j] = procConf.Label
+			}
+			handler, err := NewErrorHandler(errConf, processors, labels, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			processors = []types.Processor{handler}
+		}
 		return NewProcessor(log, stats, processors...), nil
 	}
+	var key *field.Expression
+	if len(conf.KeyAffinity) > 0 {
+		var err error
+		if key, err = bloblang.NewField(conf.KeyAffinity); err != nil {
+			return nil, fmt.Errorf("failed to parse key_affinity expression: %v", err)
+		}
+	}
 	if conf.Threads == 1 {
+		if key != nil {
+			log.Warnln("The pipeline field key_affinity has no effect when threads is 1, as all messages are already processed in order by a single thread")
+		}
 		return procCtor(&procs)
 	}
+	if key != nil {
+		return NewKeyedPool(procCtor, conf.Threads, key, log, stats)
+	}
 	return NewPool(procCtor, conf.Threads, log, stats)
 }
 
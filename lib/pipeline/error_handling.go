@@ -0,0 +1,433 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Error handling strategies.
+const (
+	ErrorStrategyNone   = "none"
+	ErrorStrategyDrop   = "drop"
+	ErrorStrategyReject = "reject"
+	ErrorStrategyRoute  = "route"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrorPolicyConfig describes how messages that fail a processor are handled,
+// and is used in order to override the stream-wide policy for individual
+// processors. An empty strategy or a negative number of retries indicates that
+// the stream-wide value is used.
+type ErrorPolicyConfig struct {
+	Strategy   string `json:"strategy" yaml:"strategy"`
+	MaxRetries int    `json:"max_retries" yaml:"max_retries"`
+}
+
+// NewErrorPolicyConfig returns an ErrorPolicyConfig with default values, which
+// inherit the stream-wide policy.
+func NewErrorPolicyConfig() ErrorPolicyConfig {
+	return ErrorPolicyConfig{
+		Strategy:   "",
+		MaxRetries: -1,
+	}
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (e *ErrorPolicyConfig) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias ErrorPolicyConfig
+	aliased := confAlias(NewErrorPolicyConfig())
+	if err := value.Decode(&aliased); err != nil {
+		return err
+	}
+	*e = ErrorPolicyConfig(aliased)
+	return nil
+}
+
+// ErrorHandlingConfig describes a policy applied to messages that fail
+// processors of a pipeline, with optional overrides for processors identified
+// by their label.
+type ErrorHandlingConfig struct {
+	Strategy   string                       `json:"strategy" yaml:"strategy"`
+	MaxRetries int                          `json:"max_retries" yaml:"max_retries"`
+	Processors map[string]ErrorPolicyConfig `json:"processors" yaml:"processors"`
+}
+
+// NewErrorHandlingConfig returns an ErrorHandlingConfig with default values.
+func NewErrorHandlingConfig() ErrorHandlingConfig {
+	return ErrorHandlingConfig{
+		Strategy:   ErrorStrategyNone,
+		MaxRetries: 0,
+		Processors: map[string]ErrorPolicyConfig{},
+	}
+}
+
+func validateErrorStrategy(strategy string) error {
+	switch strategy {
+	case ErrorStrategyNone, ErrorStrategyDrop, ErrorStrategyReject, ErrorStrategyRoute:
+		return nil
+	}
+	return fmt.Errorf("error handling strategy '%v' not recognised", strategy)
+}
+
+// Enabled returns true if the error handling config results in any behaviour
+// beyond flagging failed messages.
+func (e ErrorHandlingConfig) Enabled() bool {
+	if e.Strategy != ErrorStrategyNone || e.MaxRetries > 0 {
+		return true
+	}
+	for _, p := range e.Processors {
+		if (p.Strategy != "" && p.Strategy != ErrorStrategyNone) || p.MaxRetries > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes returns true if any policy of the error handling config routes failed
+// messages to a dedicated output.
+func (e ErrorHandlingConfig) Routes() bool {
+	if e.Strategy == ErrorStrategyRoute {
+		return true
+	}
+	for _, p := range e.Processors {
+		if p.Strategy == ErrorStrategyRoute {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the error handling config is invalid.
+func (e ErrorHandlingConfig) Validate() error {
+	if err := validateErrorStrategy(e.Strategy); err != nil {
+		return err
+	}
+	for label, p := range e.Processors {
+		if p.Strategy == "" {
+			continue
+		}
+		if err := validateErrorStrategy(p.Strategy); err != nil {
+			return fmt.Errorf("processor '%v': %w", label, err)
+		}
+	}
+	return nil
+}
+
+// policy returns the error policy of a processor by its label.
+func (e ErrorHandlingConfig) policy(label string) ErrorPolicyConfig {
+	policy := ErrorPolicyConfig{
+		Strategy:   e.Strategy,
+		MaxRetries: e.MaxRetries,
+	}
+	if label == "" {
+		return policy
+	}
+	if o, exists := e.Processors[label]; exists {
+		if o.Strategy != "" {
+			policy.Strategy = o.Strategy
+		}
+		if o.MaxRetries >= 0 {
+			policy.MaxRetries = o.MaxRetries
+		}
+	}
+	return policy
+}
+
+//------------------------------------------------------------------------------
+
+// errorHandlingStep is a processor within an error handling chain along with
+// the error policy that applies to it.
+type errorHandlingStep struct {
+	proc   types.Processor
+	policy ErrorPolicyConfig
+}
+
+// ErrorHandler is a processor that executes a chain of child processors and
+// applies an error policy to message parts that fail each step.
+//
+// Message parts that are routed skip all remaining processors and are emitted
+// as a separate message with their failure flags intact, in order to be picked
+// up by an output that dispatches failed messages.
+type ErrorHandler struct {
+	log log.Modular
+
+	steps  []errorHandlingStep
+	policy ErrorPolicyConfig
+
+	mRetry    metrics.StatCounter
+	mDropped  metrics.StatCounter
+	mRejected metrics.StatCounter
+	mRouted   metrics.StatCounter
+}
+
+// NewErrorHandler wraps a slice of processors, where labels contains the label
+// of each processor (or an empty string), with an error handling policy.
+func NewErrorHandler(
+	conf ErrorHandlingConfig,
+	procs []types.Processor,
+	labels []string,
+	log log.Modular,
+	stats metrics.Type,
+) (*ErrorHandler, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	e := &ErrorHandler{
+		log:       log,
+		policy:    conf.policy(""),
+		mRetry:    stats.GetCounter("error_handling.retry"),
+		mDropped:  stats.GetCounter("error_handling.dropped"),
+		mRejected: stats.GetCounter("error_handling.rejected"),
+		mRouted:   stats.GetCounter("error_handling.routed"),
+	}
+	for i, p := range procs {
+		var label string
+		if i < len(labels) {
+			label = labels[i]
+		}
+		e.steps = append(e.steps, errorHandlingStep{
+			proc:   p,
+			policy: conf.policy(label),
+		})
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+// newlyFailed returns the indexes of parts of a processing result that were
+// failed by the step, where parts that were already flagged with the same
+// error before the step (when cardinality permits a comparison) are ignored.
+func newlyFailed(before types.Message, after types.Message) []int {
+	var failed []int
+	aligned := before != nil && before.Len() == after.Len()
+	_ = after.Iter(func(i int, p types.Part) error {
+		fail := processor.GetFail(p)
+		if fail == "" {
+			return nil
+		}
+		if aligned && processor.GetFail(before.Get(i)) == fail {
+			return nil
+		}
+		failed = append(failed, i)
+		return nil
+	})
+	return failed
+}
+
+// execStep executes a single step on a message and reattempts failed parts
+// according to the policy of the step.
+func (e *ErrorHandler) execStep(step errorHandlingStep, msg types.Message) (msgs []types.Message, res types.Response) {
+	var original types.Message
+	if step.policy.MaxRetries > 0 {
+		original = msg.DeepCopy()
+	}
+
+	retries := 0
+	defer func() {
+		if retries == 0 {
+			return
+		}
+		// Parts that still fail were attempted with every retry.
+		for _, m := range msgs {
+			var before types.Message
+			if len(msgs) == 1 {
+				before = original
+			}
+			for _, i := range newlyFailed(before, m) {
+				processor.SetFailAttempts(m.Get(i), retries+1)
+			}
+		}
+	}()
+
+	msgs, res = step.proc.ProcessMessage(msg)
+	for attempt := 0; attempt < step.policy.MaxRetries && len(msgs) > 0; attempt++ {
+		if len(msgs) > 1 || msgs[0].Len() != original.Len() {
+			// The cardinality of the result has changed and so we can only
+			// reattempt the entire batch.
+			failed := false
+			for _, m := range msgs {
+				if len(newlyFailed(nil, m)) > 0 {
+					failed = true
+					break
+				}
+			}
+			if !failed {
+				break
+			}
+			e.mRetry.Incr(1)
+			msgs, res = step.proc.ProcessMessage(original.DeepCopy())
+			retries++
+			continue
+		}
+
+		failed := newlyFailed(original, msgs[0])
+		if len(failed) == 0 {
+			break
+		}
+		e.mRetry.Incr(1)
+
+		retryMsg := message.New(nil)
+		for _, i := range failed {
+			retryMsg.Append(original.Get(i))
+		}
+		retryMsgs, retryRes := step.proc.ProcessMessage(retryMsg.DeepCopy())
+		if len(retryMsgs) != 1 || retryMsgs[0].Len() != len(failed) {
+			if retryRes != nil && retryRes.Error() != nil {
+				return nil, retryRes
+			}
+			// The result of the reattempt can't be merged back into the
+			// batch, therefore we abandon it.
+			break
+		}
+		parts := make([]types.Part, msgs[0].Len())
+		_ = msgs[0].Iter(func(i int, p types.Part) error {
+			parts[i] = p
+			return nil
+		})
+		for j, i := range failed {
+			parts[i] = retryMsgs[0].Get(j)
+		}
+		merged := message.New(nil)
+		merged.SetAll(parts)
+		msgs = []types.Message{merged}
+		retries++
+	}
+	return msgs, res
+}
+
+// applyPolicy applies an error strategy to parts that failed a step. Parts that
+// are routed are returned separately, and a non-nil response is returned if
+// the entire batch must be rejected.
+func (e *ErrorHandler) applyPolicy(strategy string, before types.Message, msgs []types.Message) (kept []types.Message, routed []types.Part, res types.Response) {
+	if strategy == ErrorStrategyNone {
+		return msgs, nil, nil
+	}
+	for _, m := range msgs {
+		var b types.Message
+		if len(msgs) == 1 {
+			b = before
+		}
+		failed := newlyFailed(b, m)
+		if len(failed) == 0 {
+			kept = append(kept, m)
+			continue
+		}
+		if strategy == ErrorStrategyReject {
+			e.mRejected.Incr(1)
+			err := errors.New(processor.GetFail(m.Get(failed[0])))
+			e.log.Debugf("Rejecting message due to processing error: %v\n", err)
+			return nil, nil, response.NewError(fmt.Errorf("message rejected due to processing error: %w", err))
+		}
+
+		isFailed := make(map[int]struct{}, len(failed))
+		for _, i := range failed {
+			isFailed[i] = struct{}{}
+		}
+		var parts []types.Part
+		_ = m.Iter(func(i int, p types.Part) error {
+			if _, exists := isFailed[i]; !exists {
+				parts = append(parts, p)
+			} else if strategy == ErrorStrategyRoute {
+				routed = append(routed, p)
+			}
+			return nil
+		})
+		if strategy == ErrorStrategyDrop {
+			e.mDropped.Incr(int64(len(failed)))
+		} else {
+			e.mRouted.Incr(int64(len(failed)))
+		}
+		if len(parts) > 0 {
+			newMsg := message.New(nil)
+			newMsg.SetAll(parts)
+			kept = append(kept, newMsg)
+		}
+	}
+	return kept, routed, nil
+}
+
+// ProcessMessage executes the chain of processors against a message and
+// applies error policies to failed message parts.
+func (e *ErrorHandler) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	// Messages that were failed before reaching the chain (by input processors)
+	// are subject to the stream-wide strategy.
+	msgs, routed, res := e.applyPolicy(e.policy.Strategy, nil, []types.Message{msg})
+	if res != nil {
+		return nil, res
+	}
+
+	var lastRes types.Response
+
+	for _, step := range e.steps {
+		if len(msgs) == 0 {
+			break
+		}
+		var nextMsgs []types.Message
+		for _, m := range msgs {
+			var before types.Message
+			if step.policy.Strategy != ErrorStrategyNone {
+				before = m.Copy()
+			}
+			rMsgs, rRes := e.execStep(step, m)
+			if rRes != nil {
+				if rRes.Error() != nil {
+					return nil, rRes
+				}
+				lastRes = rRes
+			}
+			var stepRouted []types.Part
+			if rMsgs, stepRouted, rRes = e.applyPolicy(step.policy.Strategy, before, rMsgs); rRes != nil {
+				return nil, rRes
+			}
+			routed = append(routed, stepRouted...)
+			nextMsgs = append(nextMsgs, rMsgs...)
+		}
+		msgs = nextMsgs
+	}
+
+	if len(routed) > 0 {
+		routedMsg := message.New(nil)
+		routedMsg.SetAll(routed)
+		msgs = append(msgs, routedMsg)
+	}
+	if len(msgs) == 0 {
+		if lastRes == nil {
+			// All messages were dropped by the error policy.
+			lastRes = response.NewAck()
+		}
+		return nil, lastRes
+	}
+	return msgs, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (e *ErrorHandler) CloseAsync() {
+	for _, s := range e.steps {
+		s.proc.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (e *ErrorHandler) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, s := range e.steps {
+		if err := s.proc.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
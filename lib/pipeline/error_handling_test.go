@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProc flags parts with the content "bad" as failed until they've been
+// attempted failAttempts times, and appends a suffix to all other parts.
+type failingProc struct {
+	suffix       string
+	failAttempts int
+
+	mut      sync.Mutex
+	attempts int
+}
+
+func (f *failingProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	newMsg := msg.Copy()
+	_ = newMsg.Iter(func(i int, p types.Part) error {
+		if string(p.Get()) == "bad" {
+			f.attempts++
+			if f.attempts <= f.failAttempts {
+				processor.FlagErr(p, errors.New("nope"))
+				return nil
+			}
+		}
+		p.Set(append(p.Get(), f.suffix...))
+		return nil
+	})
+	return []types.Message{newMsg}, nil
+}
+
+func (f *failingProc) CloseAsync() {}
+
+func (f *failingProc) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func partStrs(msgs []types.Message) [][]string {
+	var res [][]string
+	for _, m := range msgs {
+		var strs []string
+		_ = m.Iter(func(i int, p types.Part) error {
+			strs = append(strs, string(p.Get()))
+			return nil
+		})
+		res = append(res, strs)
+	}
+	return res
+}
+
+func TestErrorHandlerStrategies(t *testing.T) {
+	tests := map[string]struct {
+		strategy   string
+		maxRetries int
+		attempts   int
+
+		output   [][]string
+		rejected bool
+	}{
+		"none": {
+			strategy: ErrorStrategyNone,
+			attempts: 10,
+			output:   [][]string{{"foo12", "bad2"}},
+		},
+		"drop": {
+			strategy: ErrorStrategyDrop,
+			attempts: 10,
+			output:   [][]string{{"foo12"}},
+		},
+		"reject": {
+			strategy: ErrorStrategyReject,
+			attempts: 10,
+			rejected: true,
+		},
+		"route": {
+			strategy: ErrorStrategyRoute,
+			attempts: 10,
+			output:   [][]string{{"foo12"}, {"bad"}},
+		},
+		"retry success": {
+			strategy:   ErrorStrategyDrop,
+			maxRetries: 2,
+			attempts:   2,
+			output:     [][]string{{"foo12", "bad12"}},
+		},
+		"retry failure": {
+			strategy:   ErrorStrategyRoute,
+			maxRetries: 2,
+			attempts:   3,
+			output:     [][]string{{"foo12"}, {"bad"}},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewErrorHandlingConfig()
+			conf.Strategy = test.strategy
+			conf.MaxRetries = test.maxRetries
+
+			handler, err := NewErrorHandler(conf, []types.Processor{
+				&failingProc{suffix: "1", failAttempts: test.attempts},
+				&failingProc{suffix: "2"},
+			}, nil, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			msgs, res := handler.ProcessMessage(message.New([][]byte{
+				[]byte("foo"), []byte("bad"),
+			}))
+			if test.rejected {
+				require.NotNil(t, res)
+				assert.Error(t, res.Error())
+				return
+			}
+			assert.Equal(t, test.output, partStrs(msgs))
+		})
+	}
+}
+
+func TestErrorHandlerOverrides(t *testing.T) {
+	conf := NewErrorHandlingConfig()
+	conf.Strategy = ErrorStrategyReject
+	conf.Processors["first"] = ErrorPolicyConfig{
+		Strategy:   ErrorStrategyDrop,
+		MaxRetries: -1,
+	}
+	conf.Processors["second"] = ErrorPolicyConfig{
+		Strategy:   "",
+		MaxRetries: 1,
+	}
+
+	handler, err := NewErrorHandler(conf, []types.Processor{
+		&failingProc{suffix: "1", failAttempts: 1},
+		&failingProc{suffix: "2"},
+	}, []string{"first", "second"}, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := handler.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bad"),
+	}))
+	assert.Nil(t, res)
+	assert.Equal(t, [][]string{{"foo12"}}, partStrs(msgs))
+
+	handler, err = NewErrorHandler(conf, []types.Processor{
+		&failingProc{suffix: "1"},
+		&failingProc{suffix: "2", failAttempts: 1},
+	}, []string{"first", "second"}, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = handler.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bad"),
+	}))
+	assert.Nil(t, res)
+	assert.Equal(t, [][]string{{"foo12", "bad12"}}, partStrs(msgs))
+}
+
+func TestErrorHandlerPreviouslyFailed(t *testing.T) {
+	conf := NewErrorHandlingConfig()
+	conf.Strategy = ErrorStrategyDrop
+
+	handler, err := NewErrorHandler(conf, []types.Processor{
+		&failingProc{suffix: "1"},
+	}, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	processor.FlagErr(msg.Get(1), errors.New("failed by input"))
+
+	msgs, res := handler.ProcessMessage(msg)
+	assert.Nil(t, res)
+	assert.Equal(t, [][]string{{"foo1"}}, partStrs(msgs))
+
+	handler, err = NewErrorHandler(conf, []types.Processor{
+		&failingProc{suffix: "1", failAttempts: 1},
+	}, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = handler.ProcessMessage(message.New([][]byte{[]byte("bad")}))
+	require.NotNil(t, res)
+	assert.NoError(t, res.Error())
+	assert.Empty(t, msgs)
+}
+
+func TestErrorHandlingConfigValidate(t *testing.T) {
+	conf := NewErrorHandlingConfig()
+	require.NoError(t, conf.Validate())
+	assert.False(t, conf.Enabled())
+
+	conf.Processors["foo"] = ErrorPolicyConfig{Strategy: "nah", MaxRetries: -1}
+	assert.EqualError(t, conf.Validate(), "processor 'foo': error handling strategy 'nah' not recognised")
+}
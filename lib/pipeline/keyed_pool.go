@@ -0,0 +1,224 @@
+package pipeline
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+// KeyedPool is a pool of pipelines where each transaction is dispatched to a
+// pipeline chosen by the hash of a key resolved from its message. Messages that
+// share a key are therefore always processed by the same pipeline in the order
+// that they were consumed, whilst messages of different keys are processed in
+// parallel.
+type KeyedPool struct {
+	running uint32
+
+	key     *field.Expression
+	workers []types.Pipeline
+
+	log   log.Modular
+	stats metrics.Type
+
+	messagesIn  <-chan types.Transaction
+	messagesOut chan types.Transaction
+
+	closeChan chan struct{}
+	closed    chan struct{}
+}
+
+// NewKeyedPool returns a new pipeline pool that utilises multiple processor
+// threads, where messages are dispatched to threads by the hash of a key.
+func NewKeyedPool(
+	constructor types.PipelineConstructorFunc,
+	threads int,
+	key *field.Expression,
+	log log.Modular,
+	stats metrics.Type,
+) (*KeyedPool, error) {
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	p := &KeyedPool{
+		running:     1,
+		key:         key,
+		workers:     make([]types.Pipeline, threads),
+		log:         log,
+		stats:       stats,
+		messagesOut: make(chan types.Transaction),
+		closeChan:   make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+
+	for i := range p.workers {
+		procs := 0
+		var err error
+		if p.workers[i], err = constructor(&procs); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// dispatchLoop reads transactions from the input and feeds them into the
+// worker chosen by the hash of their key.
+func (p *KeyedPool) dispatchLoop(workerChans []chan types.Transaction) {
+	defer func() {
+		for _, c := range workerChans {
+			close(c)
+		}
+	}()
+
+	for {
+		var t types.Transaction
+		var open bool
+		select {
+		case t, open = <-p.messagesIn:
+			if !open {
+				return
+			}
+		case <-p.closeChan:
+			return
+		}
+
+		var index uint64
+		if t.Payload.Len() > 0 {
+			index = xxhash.Checksum64(p.key.Bytes(0, t.Payload)) % uint64(len(workerChans))
+		}
+
+		select {
+		case workerChans[index] <- t:
+		case <-p.closeChan:
+			return
+		}
+	}
+}
+
+// loop is the processing loop of this pipeline.
+func (p *KeyedPool) loop() {
+	defer func() {
+		atomic.StoreUint32(&p.running, 0)
+
+		// Signal all workers to close.
+		for _, worker := range p.workers {
+			worker.CloseAsync()
+		}
+
+		// Wait for all workers to be closed before closing our response and
+		// messages channels as the workers may still have access to them.
+		for _, worker := range p.workers {
+			err := worker.WaitForClose(time.Second)
+			for err != nil {
+				err = worker.WaitForClose(time.Second)
+			}
+		}
+
+		close(p.messagesOut)
+		close(p.closed)
+	}()
+
+	internalMessages := make(chan types.Transaction)
+	remainingWorkers := int64(len(p.workers))
+
+	workerChans := make([]chan types.Transaction, len(p.workers))
+	for i, worker := range p.workers {
+		workerChans[i] = make(chan types.Transaction)
+		if err := worker.Consume(workerChans[i]); err != nil {
+			p.log.Errorf("Failed to start pipeline worker: %v\n", err)
+			return
+		}
+	}
+	go p.dispatchLoop(workerChans)
+
+	for _, worker := range p.workers {
+		go func(w types.Pipeline) {
+			defer func() {
+				if atomic.AddInt64(&remainingWorkers, -1) == 0 {
+					close(internalMessages)
+				}
+			}()
+			for {
+				var t types.Transaction
+				var open bool
+				select {
+				case t, open = <-w.TransactionChan():
+					if !open {
+						return
+					}
+				case <-p.closeChan:
+					return
+				}
+				select {
+				case internalMessages <- t:
+				case <-p.closeChan:
+					return
+				}
+			}
+		}(worker)
+	}
+
+	for atomic.LoadUint32(&p.running) == 1 {
+		select {
+		case t, open := <-internalMessages:
+			if !open {
+				return
+			}
+			select {
+			case p.messagesOut <- t:
+			case <-p.closeChan:
+				return
+			}
+		case <-p.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a messages channel for the pipeline to read.
+func (p *KeyedPool) Consume(msgs <-chan types.Transaction) error {
+	if p.messagesIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	p.messagesIn = msgs
+	go p.loop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming messages from this
+// pipeline.
+func (p *KeyedPool) TransactionChan() <-chan types.Transaction {
+	return p.messagesOut
+}
+
+// CloseAsync shuts down the pipeline and stops processing messages.
+func (p *KeyedPool) CloseAsync() {
+	if atomic.CompareAndSwapUint32(&p.running, 1, 0) {
+		close(p.closeChan)
+	}
+}
+
+// WaitForClose blocks until the pipeline has closed down.
+func (p *KeyedPool) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-p.closed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,143 @@
+package pipeline
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type workerTagProcessor struct {
+	worker string
+}
+
+func (w *workerTagProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	_ = msg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set("worker", w.worker)
+		return nil
+	})
+	return []types.Message{msg}, nil
+}
+
+func (w *workerTagProcessor) CloseAsync() {}
+
+func (w *workerTagProcessor) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestKeyedPoolAffinity(t *testing.T) {
+	workers := 0
+	constr := func(i *int) (types.Pipeline, error) {
+		workers++
+		return NewProcessor(
+			log.Noop(),
+			metrics.Noop(),
+			&workerTagProcessor{worker: strconv.Itoa(workers)},
+		), nil
+	}
+
+	key, err := bloblang.NewField(`${! meta("key") }`)
+	require.NoError(t, err)
+
+	pool, err := NewKeyedPool(constr, 4, key, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, pool.Consume(tChan))
+	require.Error(t, pool.Consume(tChan))
+
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	n := 60
+
+	go func() {
+		for i := 0; i < n; i++ {
+			part := message.NewPart([]byte(strconv.Itoa(i)))
+			part.Metadata().Set("key", keys[i%len(keys)])
+			msg := message.New(nil)
+			msg.Append(part)
+
+			resChan := make(chan types.Response, 1)
+			select {
+			case tChan <- types.NewTransaction(msg, resChan):
+			case <-time.After(time.Second * 5):
+				t.Error("Timed out")
+				return
+			}
+		}
+		close(tChan)
+	}()
+
+	workerByKey := map[string]string{}
+	lastByKey := map[string]int{}
+	for i := 0; i < n; i++ {
+		var ts types.Transaction
+		select {
+		case ts = <-pool.TransactionChan():
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+
+		part := ts.Payload.Get(0)
+		k, w := part.Metadata().Get("key"), part.Metadata().Get("worker")
+		if exp, exists := workerByKey[k]; exists {
+			assert.Equal(t, exp, w, k)
+		} else {
+			workerByKey[k] = w
+		}
+
+		index, err := strconv.Atoi(string(part.Get()))
+		require.NoError(t, err)
+		if last, exists := lastByKey[k]; exists {
+			assert.Greater(t, index, last, k)
+		}
+		lastByKey[k] = index
+
+		ts.ResponseChan <- response.NewAck()
+	}
+
+	select {
+	case _, open := <-pool.TransactionChan():
+		assert.False(t, open)
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+	require.NoError(t, pool.WaitForClose(time.Second*5))
+}
+
+func TestKeyedPoolFromConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Threads = 2
+	conf.KeyAffinity = `${! meta("key") }`
+
+	pipe, err := New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.IsType(t, &KeyedPool{}, pipe)
+
+	conf.KeyAffinity = `${! meta("key" }`
+	_, err = New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	// The key affinity is still parsed with a single thread, and a warning is
+	// logged as it has no effect.
+	conf.Threads = 1
+	_, err = New(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	var logBuf bytes.Buffer
+	logConf := log.NewConfig()
+	logConf.Format = "logfmt"
+	conf.KeyAffinity = `${! meta("key") }`
+	pipe, err = New(conf, types.NoopMgr(), log.New(&logBuf, logConf), metrics.Noop())
+	require.NoError(t, err)
+	assert.IsType(t, &Processor{}, pipe)
+	assert.Contains(t, logBuf.String(), "key_affinity has no effect when threads is 1")
+}
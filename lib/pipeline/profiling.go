@@ -0,0 +1,252 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime/metrics"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ProfilingConfig describes whether the processors of a pipeline are profiled,
+// where the time spent within each processor is measured for every call and
+// the allocations made by each processor are measured for a sample of calls.
+type ProfilingConfig struct {
+	Enabled    bool    `json:"enabled" yaml:"enabled"`
+	SampleRate float64 `json:"sample_rate" yaml:"sample_rate"`
+}
+
+// NewProfilingConfig returns a ProfilingConfig with default values.
+func NewProfilingConfig() ProfilingConfig {
+	return ProfilingConfig{
+		Enabled:    false,
+		SampleRate: 0.01,
+	}
+}
+
+// ProfilingEndpoint is the path of the HTTP endpoint that serves the profile of
+// the processors of a pipeline.
+const ProfilingEndpoint = "/debug/processors"
+
+//------------------------------------------------------------------------------
+
+var allocMetricNames = []string{
+	"/gc/heap/allocs:bytes",
+	"/gc/heap/allocs:objects",
+}
+
+type processorProfile struct {
+	index   int
+	label   string
+	typeStr string
+	labels  pprof.LabelSet
+
+	calls        int64
+	nanos        int64
+	sampled      int64
+	allocBytes   int64
+	allocObjects int64
+}
+
+// ProcessorProfile is a summary of the time spent and the allocations made
+// within a processor of a pipeline.
+type ProcessorProfile struct {
+	Index            int     `json:"index"`
+	Label            string  `json:"label,omitempty"`
+	Type             string  `json:"type"`
+	Calls            int64   `json:"calls"`
+	TotalTime        string  `json:"total_time"`
+	MeanTime         string  `json:"mean_time"`
+	TimePercent      float64 `json:"time_percent"`
+	SampledCalls     int64   `json:"sampled_calls"`
+	MeanAllocBytes   int64   `json:"mean_alloc_bytes"`
+	MeanAllocObjects int64   `json:"mean_alloc_objects"`
+
+	nanos int64
+}
+
+// profiler aggregates the profiles of processors across all threads of a
+// pipeline.
+type profiler struct {
+	sampleEvery int64
+	profiles    []*processorProfile
+	log         log.Modular
+
+	open int64
+}
+
+func newProfiler(conf ProfilingConfig, procConfs []processor.Config, log log.Modular) (*profiler, error) {
+	if conf.SampleRate < 0 || conf.SampleRate > 1 {
+		return nil, errors.New("profiling sample_rate must be between 0 and 1")
+	}
+	p := &profiler{
+		log: log,
+	}
+	if conf.SampleRate > 0 {
+		p.sampleEvery = int64(math.Round(1 / conf.SampleRate))
+	}
+	for i, pConf := range procConfs {
+		name := pConf.Label
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		p.profiles = append(p.profiles, &processorProfile{
+			index:   i,
+			label:   pConf.Label,
+			typeStr: pConf.Type,
+			labels:  pprof.Labels("processor", name, "processor_type", pConf.Type),
+		})
+	}
+	return p, nil
+}
+
+// wrap returns the processor at a given index of the pipeline wrapped such that
+// it is profiled.
+func (p *profiler) wrap(index int, proc types.Processor) types.Processor {
+	atomic.AddInt64(&p.open, 1)
+	return &profiledProcessor{
+		wrapped:  proc,
+		profile:  p.profiles[index],
+		profiler: p,
+	}
+}
+
+// Summary returns the profiles of all processors ordered by the total time
+// spent within them.
+func (p *profiler) Summary() []ProcessorProfile {
+	var totalNanos int64
+	summary := make([]ProcessorProfile, len(p.profiles))
+	for i, prof := range p.profiles {
+		s := ProcessorProfile{
+			Index:        prof.index,
+			Label:        prof.label,
+			Type:         prof.typeStr,
+			Calls:        atomic.LoadInt64(&prof.calls),
+			SampledCalls: atomic.LoadInt64(&prof.sampled),
+			nanos:        atomic.LoadInt64(&prof.nanos),
+		}
+		s.TotalTime = time.Duration(s.nanos).String()
+		s.MeanTime = time.Duration(0).String()
+		if s.Calls > 0 {
+			s.MeanTime = time.Duration(s.nanos / s.Calls).String()
+		}
+		if s.SampledCalls > 0 {
+			s.MeanAllocBytes = atomic.LoadInt64(&prof.allocBytes) / s.SampledCalls
+			s.MeanAllocObjects = atomic.LoadInt64(&prof.allocObjects) / s.SampledCalls
+		}
+		totalNanos += s.nanos
+		summary[i] = s
+	}
+	for i := range summary {
+		if totalNanos > 0 {
+			summary[i].TimePercent = math.Round(float64(summary[i].nanos)/float64(totalNanos)*10000) / 100
+		}
+	}
+	sort.SliceStable(summary, func(i, j int) bool {
+		return summary[i].nanos > summary[j].nanos
+	})
+	return summary
+}
+
+func (p *profiler) handleSummary(w http.ResponseWriter, r *http.Request) {
+	resBytes, err := json.Marshal(p.Summary())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resBytes)
+}
+
+// release is called when a profiled processor is closed, and once all
+// processors of all threads are closed the summary is logged.
+func (p *profiler) release() {
+	if atomic.AddInt64(&p.open, -1) != 0 {
+		return
+	}
+	p.log.Infoln("Processor profile summary:")
+	for _, s := range p.Summary() {
+		name := fmt.Sprintf("%v (%v)", s.Index, s.Type)
+		if s.Label != "" {
+			name = fmt.Sprintf("%v (%v)", s.Label, s.Type)
+		}
+		p.log.Infof(
+			"%v: %v%% of time, %v calls, %v total, %v mean, %v bytes and %v objects allocated per call\n",
+			name, s.TimePercent, s.Calls, s.TotalTime, s.MeanTime, s.MeanAllocBytes, s.MeanAllocObjects,
+		)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type profiledProcessor struct {
+	wrapped   types.Processor
+	profile   *processorProfile
+	profiler  *profiler
+	closeOnce sync.Once
+}
+
+func readAllocs() (bytes, objects int64) {
+	samples := make([]metrics.Sample, len(allocMetricNames))
+	for i, name := range allocMetricNames {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		bytes = int64(samples[0].Value.Uint64())
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		objects = int64(samples[1].Value.Uint64())
+	}
+	return
+}
+
+func (p *profiledProcessor) ProcessMessage(msg types.Message) (msgs []types.Message, res types.Response) {
+	calls := atomic.AddInt64(&p.profile.calls, 1)
+	sample := p.profiler.sampleEvery > 0 && calls%p.profiler.sampleEvery == 0
+
+	var bytesBefore, objectsBefore int64
+	if sample {
+		bytesBefore, objectsBefore = readAllocs()
+	}
+
+	start := time.Now()
+	pprof.Do(context.Background(), p.profile.labels, func(context.Context) {
+		msgs, res = p.wrapped.ProcessMessage(msg)
+	})
+	atomic.AddInt64(&p.profile.nanos, int64(time.Since(start)))
+
+	if sample {
+		bytesAfter, objectsAfter := readAllocs()
+		atomic.AddInt64(&p.profile.sampled, 1)
+		atomic.AddInt64(&p.profile.allocBytes, bytesAfter-bytesBefore)
+		atomic.AddInt64(&p.profile.allocObjects, objectsAfter-objectsBefore)
+	}
+	return
+}
+
+func (p *profiledProcessor) CloseAsync() {
+	p.wrapped.CloseAsync()
+}
+
+func (p *profiledProcessor) WaitForClose(timeout time.Duration) error {
+	err := p.wrapped.WaitForClose(timeout)
+	if err == nil {
+		p.closeOnce.Do(p.profiler.release)
+	}
+	return err
+}
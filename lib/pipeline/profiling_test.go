@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var allocSink []byte
+
+type allocatingProc struct {
+	size  int
+	sleep time.Duration
+}
+
+func (a *allocatingProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	allocSink = make([]byte, a.size)
+	time.Sleep(a.sleep)
+	return []types.Message{msg}, nil
+}
+
+func (a *allocatingProc) CloseAsync() {}
+
+func (a *allocatingProc) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestProfilerSummary(t *testing.T) {
+	fooConf := processor.NewConfig()
+	fooConf.Label = "foo"
+	fooConf.Type = processor.TypeBloblang
+
+	barConf := processor.NewConfig()
+	barConf.Type = processor.TypeNoop
+
+	conf := NewProfilingConfig()
+	conf.Enabled = true
+	conf.SampleRate = 0.5
+
+	prof, err := newProfiler(conf, []processor.Config{fooConf, barConf}, log.Noop())
+	require.NoError(t, err)
+
+	foo := prof.wrap(0, &allocatingProc{size: 1 << 20, sleep: time.Millisecond * 5})
+	bar := prof.wrap(1, &allocatingProc{size: 0})
+
+	for i := 0; i < 4; i++ {
+		msgs, res := foo.ProcessMessage(message.New([][]byte{[]byte("hello")}))
+		require.Nil(t, res)
+		require.Len(t, msgs, 1)
+		_, _ = bar.ProcessMessage(msgs[0])
+	}
+
+	summary := prof.Summary()
+	require.Len(t, summary, 2)
+
+	assert.Equal(t, 0, summary[0].Index)
+	assert.Equal(t, "foo", summary[0].Label)
+	assert.Equal(t, "bloblang", summary[0].Type)
+	assert.Equal(t, int64(4), summary[0].Calls)
+	assert.Equal(t, int64(2), summary[0].SampledCalls)
+	assert.GreaterOrEqual(t, summary[0].MeanAllocBytes, int64(1<<20))
+	assert.Greater(t, summary[0].TimePercent, 50.0)
+
+	assert.Equal(t, 1, summary[1].Index)
+	assert.Equal(t, "noop", summary[1].Type)
+	assert.Equal(t, int64(4), summary[1].Calls)
+
+	rec := httptest.NewRecorder()
+	prof.handleSummary(rec, httptest.NewRequest("GET", ProfilingEndpoint, nil))
+
+	var served []map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served, 2)
+	assert.Equal(t, "foo", served[0]["label"])
+	assert.Equal(t, float64(4), served[0]["calls"])
+
+	foo.CloseAsync()
+	bar.CloseAsync()
+	require.NoError(t, foo.WaitForClose(time.Second))
+	require.NoError(t, bar.WaitForClose(time.Second))
+	assert.Equal(t, int64(0), prof.open)
+}
+
+func TestProfilerBadSampleRate(t *testing.T) {
+	conf := NewProfilingConfig()
+	conf.SampleRate = 2
+	_, err := newProfiler(conf, nil, log.Noop())
+	require.EqualError(t, err, "profiling sample_rate must be between 0 and 1")
+}
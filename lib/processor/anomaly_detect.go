@@ -0,0 +1,392 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeAnomalyDetect] = TypeSpec{
+		constructor: NewAnomalyDetect,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Tracks the moving average and standard deviation of a numeric value for each
+key, and marks messages with values that deviate from them beyond a threshold.`,
+		Description: `
+The value of each message is obtained with the ` + "`value`" + ` query, and is compared against an exponentially weighted moving average (EWMA) and standard deviation of the previous values of its key, which is obtained with the interpolated ` + "`key`" + ` field. The deviation of the value from the average is measured in standard deviations, and when the absolute deviation exceeds ` + "`threshold`" + ` the message is an anomaly.
+
+The field ` + "`alpha`" + ` is the weight given to each new value, where higher values track recent changes more closely and lower values provide a more stable baseline. Every value is added to the statistics of its key, including anomalies, so that a lasting change in the level of a value eventually becomes its new baseline. Messages are not marked as anomalies until ` + "`warmup`" + ` values of their key have been observed.
+
+When ` + "`rate_of_change`" + ` is true the statistics track the difference between each value and the previous value of the same key rather than the values themselves, which is useful for detecting sudden changes in values that are expected to drift, such as counters.
+
+### Metadata
+
+Each message is given the following metadata fields, where the score, average and deviation are omitted until the key is warmed up:
+
+` + "``` text" + `
+- anomaly ("true" or "false")
+- anomaly_score
+- anomaly_mean
+- anomaly_stddev
+` + "```" + `
+
+The score is the signed number of standard deviations between the value and the average before the value was added, and is ` + "`+Inf` or `-Inf`" + ` when a value differs from a key that has not yet deviated at all. Messages where the value query fails or does not result in a number are flagged as having failed, and can be handled with [error handling patterns](/docs/configuration/error_handling).
+
+### State
+
+By default the statistics of each key are held in memory, which means they are lost on restart and grow with the number of distinct keys. When ` + "`cache`" + ` is set the statistics are instead stored in a [` + "`cache`" + ` resource](/docs/components/caches/about) under the key of the message, which allows them to be shared across instances and restarts, and allows keys to be expired with a cache TTL. Instances that share a cache may concurrently update the statistics of a key, and so the updates of one instance can occasionally be lost.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Latency Alerts",
+				Summary: "In this example we track the latency of requests for each endpoint, and send requests with an unusual latency to an alerts topic.",
+				Config: `
+pipeline:
+  processors:
+    - anomaly_detect:
+        value: this.latency_ms
+        key: ${! json("endpoint") }
+        threshold: 4
+        warmup: 50
+
+output:
+  switch:
+    cases:
+      - check: meta("anomaly") == "true"
+        output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: latency_alerts
+          processors:
+            - bloblang: |
+                root = this
+                root.score = meta("anomaly_score").number()
+                root.expected_ms = meta("anomaly_mean").number()
+        continue: true
+      - output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: requests
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("value", "A [Bloblang query](/docs/guides/bloblang/about) that results in the numeric value of a message.", "this.latency_ms", `meta("queue_depth").number()`),
+			docs.FieldCommon("key", "The key of the statistics that a message is compared against, messages with different keys are tracked separately.", `${! json("endpoint") }`, `${! meta("kafka_key") }`).IsInterpolated(),
+			docs.FieldCommon("threshold", "The number of standard deviations from the average beyond which a value is an anomaly."),
+			docs.FieldCommon("warmup", "The number of values of a key to observe before its messages can be marked as anomalies."),
+			docs.FieldAdvanced("alpha", "The smoothing factor of the moving average and standard deviation, between 0 and 1, where higher values give more weight to recent values."),
+			docs.FieldAdvanced("rate_of_change", "Whether to track the change in value since the previous message of the same key rather than the value itself."),
+			docs.FieldAdvanced("cache", "An optional [`cache` resource](/docs/components/caches/about) to store the statistics of each key in."),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AnomalyDetectConfig contains configuration fields for the AnomalyDetect
+// processor.
+type AnomalyDetectConfig struct {
+	Parts        []int   `json:"parts" yaml:"parts"`
+	Value        string  `json:"value" yaml:"value"`
+	Key          string  `json:"key" yaml:"key"`
+	Threshold    float64 `json:"threshold" yaml:"threshold"`
+	Warmup       int64   `json:"warmup" yaml:"warmup"`
+	Alpha        float64 `json:"alpha" yaml:"alpha"`
+	RateOfChange bool    `json:"rate_of_change" yaml:"rate_of_change"`
+	Cache        string  `json:"cache" yaml:"cache"`
+}
+
+// NewAnomalyDetectConfig returns a AnomalyDetectConfig with default values.
+func NewAnomalyDetectConfig() AnomalyDetectConfig {
+	return AnomalyDetectConfig{
+		Parts:        []int{},
+		Value:        "",
+		Key:          "",
+		Threshold:    3,
+		Warmup:       10,
+		Alpha:        0.1,
+		RateOfChange: false,
+		Cache:        "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// anomalyStats are the statistics of the values of a key.
+type anomalyStats struct {
+	Count    int64   `json:"count"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Last     float64 `json:"last"`
+	Primed   bool    `json:"primed"`
+}
+
+// anomalyResult describes how a value compares with the statistics of its key.
+type anomalyResult struct {
+	warm    bool
+	anomaly bool
+	score   float64
+	mean    float64
+	stddev  float64
+}
+
+// observe compares a value against the statistics and then adds it to them.
+func (s *anomalyStats) observe(v float64, alpha, threshold float64, warmup int64, rateOfChange bool) (res anomalyResult) {
+	if rateOfChange {
+		if !s.Primed {
+			s.Primed, s.Last = true, v
+			return
+		}
+		v, s.Last = v-s.Last, v
+	}
+
+	if s.Count == 0 {
+		s.Mean = v
+		s.Count = 1
+		return
+	}
+
+	diff := v - s.Mean
+	if s.Count >= warmup {
+		res.warm = true
+		res.mean = s.Mean
+		res.stddev = math.Sqrt(s.Variance)
+		switch {
+		case res.stddev > 0:
+			res.score = diff / res.stddev
+		case diff != 0:
+			res.score = math.Inf(int(math.Copysign(1, diff)))
+		}
+		res.anomaly = math.Abs(res.score) > threshold
+	}
+
+	incr := alpha * diff
+	s.Mean += incr
+	s.Variance = (1 - alpha) * (s.Variance + diff*incr)
+	s.Count++
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// AnomalyDetect is a processor that tracks the statistics of a numeric value
+// per key and marks messages that deviate from them.
+type AnomalyDetect struct {
+	parts        []int
+	value        *mapping.Executor
+	key          *field.Expression
+	threshold    float64
+	warmup       int64
+	alpha        float64
+	rateOfChange bool
+	cacheName    string
+
+	mut   sync.Mutex
+	stats map[string]*anomalyStats
+
+	mgr types.Manager
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mAnomaly   metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewAnomalyDetect returns an AnomalyDetect processor.
+func NewAnomalyDetect(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	aConf := conf.AnomalyDetect
+	if aConf.Value == "" {
+		return nil, errors.New("a value query must be specified")
+	}
+	if aConf.Alpha <= 0 || aConf.Alpha > 1 {
+		return nil, fmt.Errorf("alpha must be greater than 0 and at most 1, got %v", aConf.Alpha)
+	}
+	if aConf.Threshold <= 0 {
+		return nil, fmt.Errorf("threshold must be greater than 0, got %v", aConf.Threshold)
+	}
+
+	value, err := bloblang.NewMapping("", aConf.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value query: %w", err)
+	}
+	key, err := bloblang.NewField(aConf.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	if aConf.Cache != "" {
+		if err := interop.ProbeCache(context.Background(), mgr, aConf.Cache); err != nil {
+			return nil, err
+		}
+	}
+
+	warmup := aConf.Warmup
+	if warmup < 1 {
+		warmup = 1
+	}
+
+	return &AnomalyDetect{
+		parts:        aConf.Parts,
+		value:        value,
+		key:          key,
+		threshold:    aConf.Threshold,
+		warmup:       warmup,
+		alpha:        aConf.Alpha,
+		rateOfChange: aConf.RateOfChange,
+		cacheName:    aConf.Cache,
+
+		stats: map[string]*anomalyStats{},
+
+		mgr: mgr,
+		log: log,
+
+		mCount:     stats.GetCounter("count"),
+		mAnomaly:   stats.GetCounter("anomaly"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (a *AnomalyDetect) getValue(index int, msg types.Message) (float64, error) {
+	v, err := a.value.Exec(query.FunctionContext{
+		Maps:     a.value.Maps(),
+		Vars:     map[string]interface{}{},
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute value query: %w", err)
+	}
+	f, err := query.IGetNumber(v)
+	if err != nil {
+		return 0, fmt.Errorf("value query result: %w", err)
+	}
+	return f, nil
+}
+
+// observe adds a value to the statistics of its key, which are read from and
+// written back to the cache when one is configured.
+func (a *AnomalyDetect) observe(key string, v float64) (anomalyResult, error) {
+	if a.cacheName == "" {
+		s, exists := a.stats[key]
+		if !exists {
+			s = &anomalyStats{}
+			a.stats[key] = s
+		}
+		return s.observe(v, a.alpha, a.threshold, a.warmup, a.rateOfChange), nil
+	}
+
+	var s anomalyStats
+	var res anomalyResult
+	var err error
+	if cerr := interop.AccessCache(context.Background(), a.mgr, a.cacheName, func(c types.Cache) {
+		var stateBytes []byte
+		if stateBytes, err = c.Get(key); err != nil {
+			if !errors.Is(err, types.ErrKeyNotFound) {
+				return
+			}
+		} else if err = json.Unmarshal(stateBytes, &s); err != nil {
+			err = fmt.Errorf("failed to parse statistics of key '%v': %w", key, err)
+			return
+		}
+		res = s.observe(v, a.alpha, a.threshold, a.warmup, a.rateOfChange)
+		if stateBytes, err = json.Marshal(s); err != nil {
+			return
+		}
+		err = c.Set(key, stateBytes)
+	}); cerr != nil {
+		err = cerr
+	}
+	return res, err
+}
+
+func formatAnomalyFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (a *AnomalyDetect) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	a.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		v, err := a.getValue(index, newMsg)
+		if err != nil {
+			a.mErr.Incr(1)
+			a.log.Debugf("Failed to obtain value: %v\n", err)
+			return err
+		}
+
+		res, err := a.observe(a.key.String(index, newMsg), v)
+		if err != nil {
+			a.mErr.Incr(1)
+			a.log.Errorf("Failed to update statistics: %v\n", err)
+			return err
+		}
+
+		meta := part.Metadata()
+		meta.Set("anomaly", strconv.FormatBool(res.anomaly))
+		if res.warm {
+			meta.Set("anomaly_score", formatAnomalyFloat(res.score))
+			meta.Set("anomaly_mean", formatAnomalyFloat(res.mean))
+			meta.Set("anomaly_stddev", formatAnomalyFloat(res.stddev))
+		}
+		if res.anomaly {
+			a.mAnomaly.Incr(1)
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeAnomalyDetect, a.parts, newMsg, proc)
+
+	a.mBatchSent.Incr(1)
+	a.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (a *AnomalyDetect) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (a *AnomalyDetect) WaitForClose(timeout time.Duration) error {
+	return nil
+}
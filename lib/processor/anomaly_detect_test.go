@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func anomalyInput(key string, values ...interface{}) types.Message {
+	var parts [][]byte
+	for _, v := range values {
+		parts = append(parts, []byte(fmt.Sprintf(`{"key":%q,"value":%v}`, key, v)))
+	}
+	return message.New(parts)
+}
+
+func TestAnomalyDetect(t *testing.T) {
+	conf := NewConfig()
+	conf.AnomalyDetect.Value = "this.value"
+	conf.AnomalyDetect.Key = `${! json("key") }`
+	conf.AnomalyDetect.Warmup = 4
+	conf.AnomalyDetect.Alpha = 0.5
+
+	proc, err := NewAnomalyDetect(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(anomalyInput("foo", 10, 12, 10, 12))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	msgs[0].Iter(func(i int, part types.Part) error {
+		assert.Equal(t, "false", part.Metadata().Get("anomaly"), i)
+		assert.Equal(t, "", part.Metadata().Get("anomaly_score"), i)
+		return nil
+	})
+
+	msgs, res = proc.ProcessMessage(anomalyInput("foo", 11, 50))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	meta := msgs[0].Get(0).Metadata()
+	assert.Equal(t, "false", meta.Get("anomaly"))
+	assert.Equal(t, "-0.2581988897471611", meta.Get("anomaly_score"))
+	assert.Equal(t, "11.25", meta.Get("anomaly_mean"))
+	assert.Equal(t, "0.9682458365518543", meta.Get("anomaly_stddev"))
+
+	meta = msgs[0].Get(1).Metadata()
+	assert.Equal(t, "true", meta.Get("anomaly"))
+	assert.Equal(t, "55.857248930326996", meta.Get("anomaly_score"))
+	assert.Equal(t, "11.125", meta.Get("anomaly_mean"))
+
+	// A different key has its own statistics.
+	msgs, res = proc.ProcessMessage(anomalyInput("bar", 50, `"nope"`))
+	require.Nil(t, res)
+	assert.Equal(t, "false", msgs[0].Get(0).Metadata().Get("anomaly"))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.NotEqual(t, "", GetFail(msgs[0].Get(1)))
+	assert.Equal(t, "", msgs[0].Get(1).Metadata().Get("anomaly"))
+}
+
+func TestAnomalyDetectConstant(t *testing.T) {
+	conf := NewConfig()
+	conf.AnomalyDetect.Value = "this.value"
+	conf.AnomalyDetect.Warmup = 2
+
+	proc, err := NewAnomalyDetect(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(anomalyInput("", 0, 0, 0, -5))
+	require.Nil(t, res)
+
+	assert.Equal(t, "false", msgs[0].Get(2).Metadata().Get("anomaly"))
+	assert.Equal(t, "0", msgs[0].Get(2).Metadata().Get("anomaly_score"))
+	assert.Equal(t, "true", msgs[0].Get(3).Metadata().Get("anomaly"))
+	assert.Equal(t, "-Inf", msgs[0].Get(3).Metadata().Get("anomaly_score"))
+}
+
+func TestAnomalyDetectRateOfChange(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.AnomalyDetect.Value = "this.value"
+	conf.AnomalyDetect.Key = `${! json("key") }`
+	conf.AnomalyDetect.Warmup = 3
+	conf.AnomalyDetect.RateOfChange = true
+	conf.AnomalyDetect.Cache = "foocache"
+
+	proc, err := NewAnomalyDetect(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	// A steadily increasing counter followed by a sudden jump.
+	msgs, res := proc.ProcessMessage(anomalyInput("foo", 100, 110, 121, 130))
+	require.Nil(t, res)
+	msgs[0].Iter(func(i int, part types.Part) error {
+		assert.Equal(t, "false", part.Metadata().Get("anomaly"), i)
+		return nil
+	})
+
+	stateBytes, err := memCache.Get("foo")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"count":3,"mean":9.99,"variance":0.18989999999999996,"last":130,"primed":true}`, string(stateBytes))
+
+	// The statistics are loaded from the cache by a new processor.
+	proc, err = NewAnomalyDetect(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = proc.ProcessMessage(anomalyInput("foo", 141, 300))
+	require.Nil(t, res)
+	assert.Equal(t, "false", msgs[0].Get(0).Metadata().Get("anomaly"))
+	assert.Equal(t, "true", msgs[0].Get(1).Metadata().Get("anomaly"))
+
+	conf.AnomalyDetect.Cache = "nope"
+	_, err = NewAnomalyDetect(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestAnomalyDetectConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	_, err := NewAnomalyDetect(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a value query must be specified")
+
+	conf.AnomalyDetect.Value = "this.value"
+	conf.AnomalyDetect.Alpha = 1.5
+	_, err = NewAnomalyDetect(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "alpha must be greater than 0 and at most 1, got 1.5")
+
+	conf.AnomalyDetect.Alpha = 0.1
+	conf.AnomalyDetect.Threshold = 0
+	_, err = NewAnomalyDetect(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "threshold must be greater than 0, got 0")
+}
@@ -0,0 +1,207 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeArtificialLoad] = TypeSpec{
+		constructor: NewArtificialLoad,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Passes messages through unchanged while burning CPU time and allocating memory
+for each message, in order to simulate heavier processing for capacity tests.`,
+		Description: `
+This processor is intended for capacity planning, where a pipeline is load tested before the transformations that it will eventually run exist or are representative. For each message of a batch the processor spins a CPU core for ` + "`cpu`" + `, and allocates and writes to ` + "`memory`" + ` bytes, which are retained until the whole batch has been processed. The peak memory used by each pipeline thread is therefore roughly ` + "`memory`" + ` multiplied by the size of the batches it processes.
+
+When ` + "`jitter`" + ` is set the CPU time and memory of each message are randomly varied by up to that fraction in either direction, so that a jitter of ` + "`0.2`" + ` with a ` + "`cpu`" + ` of ` + "`10ms`" + ` results in between 8ms and 12ms of CPU time per message.
+
+Since CPU time is burned by spinning, the CPU time of a message is measured in wall time, and it takes longer than configured when the pipeline has more threads than available cores.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Simulating Enrichment",
+				Summary: "In this example we simulate an enrichment step that costs around 5ms of CPU time and 64KB of memory per message, in order to find how many pipeline threads are needed to keep up with a production load.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: capacity_test
+
+pipeline:
+  threads: 8
+  processors:
+    - artificial_load:
+        cpu: 5ms
+        memory: 65536
+        jitter: 0.3
+
+output:
+  drop: {}
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("cpu", "The CPU time to burn for each message, or an empty string for none.", "5ms", "100us"),
+			docs.FieldCommon("memory", "The number of bytes to allocate for each message."),
+			docs.FieldCommon("jitter", "The maximum fraction, between 0 and 1, by which the CPU time and memory of each message are randomly varied."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ArtificialLoadConfig contains configuration fields for the ArtificialLoad
+// processor.
+type ArtificialLoadConfig struct {
+	CPU    string  `json:"cpu" yaml:"cpu"`
+	Memory int     `json:"memory" yaml:"memory"`
+	Jitter float64 `json:"jitter" yaml:"jitter"`
+}
+
+// NewArtificialLoadConfig returns a ArtificialLoadConfig with default values.
+func NewArtificialLoadConfig() ArtificialLoadConfig {
+	return ArtificialLoadConfig{
+		CPU:    "",
+		Memory: 0,
+		Jitter: 0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ArtificialLoad is a processor that burns CPU time and allocates memory for
+// each message without modifying it.
+type ArtificialLoad struct {
+	closed int32
+
+	cpu    time.Duration
+	memory int
+	jitter float64
+
+	// Results of the CPU burn are stored here so that it isn't optimised away.
+	sink uint64
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewArtificialLoad returns an ArtificialLoad processor.
+func NewArtificialLoad(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	a := &ArtificialLoad{
+		memory: conf.ArtificialLoad.Memory,
+		jitter: conf.ArtificialLoad.Jitter,
+		log:    log,
+
+		mCount:     stats.GetCounter("count"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if conf.ArtificialLoad.CPU != "" {
+		var err error
+		if a.cpu, err = time.ParseDuration(conf.ArtificialLoad.CPU); err != nil {
+			return nil, fmt.Errorf("failed to parse cpu duration: %v", err)
+		}
+	}
+	if a.cpu < 0 {
+		return nil, errors.New("cpu duration must not be negative")
+	}
+	if a.memory < 0 {
+		return nil, errors.New("memory must not be negative")
+	}
+	if a.jitter < 0 || a.jitter > 1 {
+		return nil, fmt.Errorf("jitter must be between 0 and 1, got %v", a.jitter)
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// jittered returns a random amount within the jitter of n.
+func (a *ArtificialLoad) jittered(n float64) float64 {
+	if a.jitter == 0 {
+		return n
+	}
+	return n * (1 + a.jitter*(2*rand.Float64()-1))
+}
+
+// burn spins until the duration has elapsed or the processor is closed.
+func (a *ArtificialLoad) burn(d time.Duration) {
+	deadline := time.Now().Add(d)
+	x := uint64(d)
+	for i := 0; ; i++ {
+		x = x*6364136223846793005 + 1442695040888963407
+		if i%1000 == 0 {
+			if !time.Now().Before(deadline) || atomic.LoadInt32(&a.closed) == 1 {
+				break
+			}
+		}
+	}
+	atomic.StoreUint64(&a.sink, x)
+}
+
+// allocateLoad returns a buffer of n bytes where each page has been written to.
+func allocateLoad(n int) []byte {
+	b := make([]byte, n)
+	for i := 0; i < len(b); i += 4096 {
+		b[i] = 1
+	}
+	return b
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (a *ArtificialLoad) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	a.mCount.Incr(1)
+
+	spans := tracing.CreateChildSpans(TypeArtificialLoad, msg)
+
+	buffers := make([][]byte, 0, msg.Len())
+	for i := range spans {
+		if a.memory > 0 {
+			buffers = append(buffers, allocateLoad(int(a.jittered(float64(a.memory)))))
+		}
+		if a.cpu > 0 {
+			a.burn(time.Duration(a.jittered(float64(a.cpu))))
+		}
+		spans[i].Finish()
+	}
+	runtime.KeepAlive(buffers)
+
+	a.mBatchSent.Incr(1)
+	a.mSent.Incr(int64(msg.Len()))
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (a *ArtificialLoad) CloseAsync() {
+	atomic.StoreInt32(&a.closed, 1)
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (a *ArtificialLoad) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtificialLoad(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeArtificialLoad
+	conf.ArtificialLoad.CPU = "10ms"
+	conf.ArtificialLoad.Memory = 1024 * 1024
+	conf.ArtificialLoad.Jitter = 0.5
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	input.Get(0).Metadata().Set("baz", "qux")
+
+	start := time.Now()
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(10*time.Millisecond))
+
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msgs[0]))
+	assert.Equal(t, "qux", msgs[0].Get(0).Metadata().Get("baz"))
+}
+
+func TestArtificialLoadClose(t *testing.T) {
+	conf := NewConfig()
+	conf.ArtificialLoad.CPU = "1h"
+
+	proc, err := NewArtificialLoad(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		proc.CloseAsync()
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.NoError(t, proc.WaitForClose(time.Second))
+}
+
+func TestArtificialLoadConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.ArtificialLoad.CPU = "nope"
+	_, err := NewArtificialLoad(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.ArtificialLoad.CPU = ""
+	conf.ArtificialLoad.Memory = -1
+	_, err = NewArtificialLoad(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "memory must not be negative")
+
+	conf.ArtificialLoad.Memory = 0
+	conf.ArtificialLoad.Jitter = 2
+	_, err = NewArtificialLoad(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "jitter must be between 0 and 1, got 2")
+}
@@ -0,0 +1,479 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/comprehend"
+	"github.com/aws/aws-sdk-go/service/comprehend/comprehendiface"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeAWSComprehend] = TypeSpec{
+		constructor: NewAWSComprehend,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Analyses the text of messages with [AWS Comprehend](https://aws.amazon.com/comprehend/),
+replacing the contents of each message with the result.`,
+		Description: `
+The text analysed for each message is resolved from the interpolated field ` + "`text`" + `, which is the entire contents of the message by default. The operators ` + "`sentiment`, `entities`, `key_phrases` and `dominant_language`" + ` send the messages of a batch to Comprehend in requests of up to 25 documents, whereas ` + "`pii_entities`" + ` sends a request for each message. The ` + "`rate_limit`" + ` field can be used to specify a rate limit [resource](/docs/components/rate_limits/about) to cap the rate of requests across parallel components service wide.
+
+Messages that fail analysis, for example because their text is empty or too long, continue through the pipeline with their contents unchanged but flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+In order to map the result into the original message instead of replacing it entirely you can use the ` + "[`branch` processor](/docs/components/processors/branch)" + `.
+
+## Operators
+
+### ` + "`sentiment`" + `
+
+Detects the prevailing sentiment of the text, resulting in a document such as ` + "`{\"sentiment\":\"POSITIVE\",\"scores\":{\"positive\":0.98,\"negative\":0.01,\"neutral\":0.01,\"mixed\":0}}`" + `.
+
+### ` + "`entities`" + `
+
+Detects named entities within the text, resulting in a document such as ` + "`{\"entities\":[{\"text\":\"Seattle\",\"type\":\"LOCATION\",\"score\":0.99,\"begin_offset\":12,\"end_offset\":19}]}`" + `.
+
+### ` + "`key_phrases`" + `
+
+Detects key phrases within the text, resulting in a document such as ` + "`{\"key_phrases\":[{\"text\":\"the new store\",\"score\":0.97,\"begin_offset\":0,\"end_offset\":13}]}`" + `.
+
+### ` + "`dominant_language`" + `
+
+Detects the languages of the text, resulting in a document such as ` + "`{\"languages\":[{\"language_code\":\"en\",\"score\":0.99}]}`" + `. The field ` + "`language_code`" + ` is ignored by this operator.
+
+### ` + "`pii_entities`" + `
+
+Detects personally identifiable information within the text, resulting in a document such as ` + "`{\"entities\":[{\"text\":\"jane@example.com\",\"type\":\"EMAIL\",\"score\":0.99,\"begin_offset\":9,\"end_offset\":25}]}`" + `, where the text of each entity is extracted from the analysed text by its offsets.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS
+services. It's also possible to set them explicitly at the component level,
+allowing you to transfer data across accounts. You can find out more
+[in this document](/docs/guides/aws).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Review Sentiment",
+				Summary: "This example uses a [`branch` processor](/docs/components/processors/branch/) to detect the sentiment of the body of product reviews, and adds the result to each review.",
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - aws_comprehend:
+              operator: sentiment
+              text: ${! json("review.body") }
+              region: eu-west-1
+        result_map: |
+          root.review.sentiment = this.sentiment
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("operator", "The [analysis](#operators) to perform.").HasOptions("sentiment", "entities", "key_phrases", "dominant_language", "pii_entities"),
+			docs.FieldCommon("text", "The text to analyse for each message.").IsInterpolated(),
+			docs.FieldCommon("language_code", "The language of the text, which must be supported by the operator.", "en", "es", "de"),
+			docs.FieldAdvanced("rate_limit", "An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle requests by."),
+		}.Merge(session.FieldSpecs()).Add(
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait before abandoning a request."),
+			PartsFieldSpec,
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AWSComprehendConfig contains configuration fields for the AWSComprehend
+// processor.
+type AWSComprehendConfig struct {
+	session.Config `json:",inline" yaml:",inline"`
+	Parts          []int  `json:"parts" yaml:"parts"`
+	Operator       string `json:"operator" yaml:"operator"`
+	Text           string `json:"text" yaml:"text"`
+	LanguageCode   string `json:"language_code" yaml:"language_code"`
+	RateLimit      string `json:"rate_limit" yaml:"rate_limit"`
+	Timeout        string `json:"timeout" yaml:"timeout"`
+}
+
+// NewAWSComprehendConfig returns a AWSComprehendConfig with default values.
+func NewAWSComprehendConfig() AWSComprehendConfig {
+	return AWSComprehendConfig{
+		Config:       session.NewConfig(),
+		Parts:        []int{},
+		Operator:     "sentiment",
+		Text:         "${! content() }",
+		LanguageCode: "en",
+		RateLimit:    "",
+		Timeout:      "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// comprehendBatchSize is the maximum number of documents accepted by the
+// batch operations of Comprehend.
+const comprehendBatchSize = 25
+
+// comprehendBatchFn analyses a batch of texts, returning the results and
+// errors of individual texts keyed by their index within the batch.
+type comprehendBatchFn func(
+	ctx context.Context, texts []string,
+) (results map[int]interface{}, errs map[int]error, err error)
+
+// AWSComprehend is a processor that analyses the text of messages with AWS
+// Comprehend.
+type AWSComprehend struct {
+	parts    []int
+	text     *field.Expression
+	language string
+	timeout  time.Duration
+	batchFn  comprehendBatchFn
+	client   comprehendiface.ComprehendAPI
+	limiter  resourceRateLimiter
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrReq    metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewAWSComprehend returns an AWSComprehend processor.
+func NewAWSComprehend(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	sess, err := conf.AWSComprehend.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	return newAWSComprehend(conf, comprehend.New(sess), mgr, log, stats)
+}
+
+func newAWSComprehend(
+	conf Config, client comprehendiface.ComprehendAPI, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (*AWSComprehend, error) {
+	cConf := conf.AWSComprehend
+	p := &AWSComprehend{
+		parts:    cConf.Parts,
+		language: cConf.LanguageCode,
+		client:   client,
+		limiter:  newResourceRateLimiter(cConf.RateLimit, mgr, log, stats),
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrReq:    stats.GetCounter("error.request"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	if p.text, err = bloblang.NewField(cConf.Text); err != nil {
+		return nil, fmt.Errorf("failed to parse text expression: %v", err)
+	}
+	if cConf.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(cConf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	switch cConf.Operator {
+	case "sentiment":
+		p.batchFn = p.detectSentiment
+	case "entities":
+		p.batchFn = p.detectEntities
+	case "key_phrases":
+		p.batchFn = p.detectKeyPhrases
+	case "dominant_language":
+		p.batchFn = p.detectDominantLanguage
+	case "pii_entities":
+		p.batchFn = p.detectPIIEntities
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", cConf.Operator)
+	}
+	if cConf.Operator != "dominant_language" && p.language == "" {
+		return nil, errors.New("a language_code must be specified")
+	}
+
+	if err := p.limiter.probe(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func comprehendItemErrs(items []*comprehend.BatchItemError) map[int]error {
+	errs := make(map[int]error, len(items))
+	for _, item := range items {
+		errs[int(aws.Int64Value(item.Index))] = fmt.Errorf(
+			"%v: %v", aws.StringValue(item.ErrorCode), aws.StringValue(item.ErrorMessage),
+		)
+	}
+	return errs
+}
+
+func comprehendEntity(text string, typ *string, score *float64, begin, end *int64) map[string]interface{} {
+	return map[string]interface{}{
+		"text":         text,
+		"type":         aws.StringValue(typ),
+		"score":        aws.Float64Value(score),
+		"begin_offset": aws.Int64Value(begin),
+		"end_offset":   aws.Int64Value(end),
+	}
+}
+
+func (p *AWSComprehend) detectSentiment(ctx context.Context, texts []string) (map[int]interface{}, map[int]error, error) {
+	out, err := p.client.BatchDetectSentimentWithContext(ctx, &comprehend.BatchDetectSentimentInput{
+		LanguageCode: aws.String(p.language),
+		TextList:     aws.StringSlice(texts),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(map[int]interface{}, len(out.ResultList))
+	for _, r := range out.ResultList {
+		scores := map[string]interface{}{}
+		if s := r.SentimentScore; s != nil {
+			scores["positive"] = aws.Float64Value(s.Positive)
+			scores["negative"] = aws.Float64Value(s.Negative)
+			scores["neutral"] = aws.Float64Value(s.Neutral)
+			scores["mixed"] = aws.Float64Value(s.Mixed)
+		}
+		results[int(aws.Int64Value(r.Index))] = map[string]interface{}{
+			"sentiment": aws.StringValue(r.Sentiment),
+			"scores":    scores,
+		}
+	}
+	return results, comprehendItemErrs(out.ErrorList), nil
+}
+
+func (p *AWSComprehend) detectEntities(ctx context.Context, texts []string) (map[int]interface{}, map[int]error, error) {
+	out, err := p.client.BatchDetectEntitiesWithContext(ctx, &comprehend.BatchDetectEntitiesInput{
+		LanguageCode: aws.String(p.language),
+		TextList:     aws.StringSlice(texts),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(map[int]interface{}, len(out.ResultList))
+	for _, r := range out.ResultList {
+		entities := make([]interface{}, 0, len(r.Entities))
+		for _, e := range r.Entities {
+			entities = append(entities, comprehendEntity(aws.StringValue(e.Text), e.Type, e.Score, e.BeginOffset, e.EndOffset))
+		}
+		results[int(aws.Int64Value(r.Index))] = map[string]interface{}{
+			"entities": entities,
+		}
+	}
+	return results, comprehendItemErrs(out.ErrorList), nil
+}
+
+func (p *AWSComprehend) detectKeyPhrases(ctx context.Context, texts []string) (map[int]interface{}, map[int]error, error) {
+	out, err := p.client.BatchDetectKeyPhrasesWithContext(ctx, &comprehend.BatchDetectKeyPhrasesInput{
+		LanguageCode: aws.String(p.language),
+		TextList:     aws.StringSlice(texts),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(map[int]interface{}, len(out.ResultList))
+	for _, r := range out.ResultList {
+		phrases := make([]interface{}, 0, len(r.KeyPhrases))
+		for _, k := range r.KeyPhrases {
+			phrases = append(phrases, map[string]interface{}{
+				"text":         aws.StringValue(k.Text),
+				"score":        aws.Float64Value(k.Score),
+				"begin_offset": aws.Int64Value(k.BeginOffset),
+				"end_offset":   aws.Int64Value(k.EndOffset),
+			})
+		}
+		results[int(aws.Int64Value(r.Index))] = map[string]interface{}{
+			"key_phrases": phrases,
+		}
+	}
+	return results, comprehendItemErrs(out.ErrorList), nil
+}
+
+func (p *AWSComprehend) detectDominantLanguage(ctx context.Context, texts []string) (map[int]interface{}, map[int]error, error) {
+	out, err := p.client.BatchDetectDominantLanguageWithContext(ctx, &comprehend.BatchDetectDominantLanguageInput{
+		TextList: aws.StringSlice(texts),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(map[int]interface{}, len(out.ResultList))
+	for _, r := range out.ResultList {
+		languages := make([]interface{}, 0, len(r.Languages))
+		for _, l := range r.Languages {
+			languages = append(languages, map[string]interface{}{
+				"language_code": aws.StringValue(l.LanguageCode),
+				"score":         aws.Float64Value(l.Score),
+			})
+		}
+		results[int(aws.Int64Value(r.Index))] = map[string]interface{}{
+			"languages": languages,
+		}
+	}
+	return results, comprehendItemErrs(out.ErrorList), nil
+}
+
+// detectPIIEntities has no batch equivalent and therefore sends a request for
+// each text, where the failure of a request only fails its own text.
+func (p *AWSComprehend) detectPIIEntities(ctx context.Context, texts []string) (map[int]interface{}, map[int]error, error) {
+	results := make(map[int]interface{}, len(texts))
+	errs := map[int]error{}
+	for i, text := range texts {
+		if i > 0 {
+			p.limiter.wait()
+		}
+		out, err := p.client.DetectPiiEntitiesWithContext(ctx, &comprehend.DetectPiiEntitiesInput{
+			LanguageCode: aws.String(p.language),
+			Text:         aws.String(text),
+		})
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		runes := []rune(text)
+		entities := make([]interface{}, 0, len(out.Entities))
+		for _, e := range out.Entities {
+			var eText string
+			begin, end := aws.Int64Value(e.BeginOffset), aws.Int64Value(e.EndOffset)
+			if begin >= 0 && begin <= end && end <= int64(len(runes)) {
+				eText = string(runes[begin:end])
+			}
+			entities = append(entities, comprehendEntity(eText, e.Type, e.Score, e.BeginOffset, e.EndOffset))
+		}
+		results[i] = map[string]interface{}{
+			"entities": entities,
+		}
+	}
+	return results, errs, nil
+}
+
+// analyse sends the texts to Comprehend in batches, returning the result or
+// error of each text in the same order.
+func (p *AWSComprehend) analyse(texts []string) ([]interface{}, []error) {
+	results := make([]interface{}, len(texts))
+	errs := make([]error, len(texts))
+
+	for start := 0; start < len(texts); start += comprehendBatchSize {
+		end := start + comprehendBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		p.limiter.wait()
+		ctx, done := context.WithTimeout(context.Background(), p.timeout)
+		bResults, bErrs, err := p.batchFn(ctx, texts[start:end])
+		done()
+
+		if err != nil {
+			p.mErrReq.Incr(1)
+			p.log.Errorf("Comprehend request failed: %v\n", err)
+		}
+		for i := start; i < end; i++ {
+			if err != nil {
+				errs[i] = err
+			} else if bErr, exists := bErrs[i-start]; exists {
+				errs[i] = bErr
+			} else if res, exists := bResults[i-start]; exists {
+				results[i] = res
+			} else {
+				errs[i] = errors.New("no result was returned for the message")
+			}
+		}
+	}
+	return results, errs
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *AWSComprehend) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	indexes := p.parts
+	if len(indexes) == 0 {
+		indexes = make([]int, newMsg.Len())
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	var texts []string
+	var textIndexes []int
+	for _, index := range indexes {
+		if text := p.text.String(index, newMsg); text != "" {
+			texts = append(texts, text)
+			textIndexes = append(textIndexes, index)
+		}
+	}
+
+	results := map[int]interface{}{}
+	errs := map[int]error{}
+	if len(texts) > 0 {
+		tResults, tErrs := p.analyse(texts)
+		for i, index := range textIndexes {
+			if tErrs[i] != nil {
+				errs[index] = tErrs[i]
+			} else {
+				results[index] = tResults[i]
+			}
+		}
+	}
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		err := errs[index]
+		if err == nil {
+			if res, exists := results[index]; exists {
+				err = part.SetJSON(res)
+			} else {
+				err = errors.New("text is empty")
+			}
+		}
+		if err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to analyse message: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeAWSComprehend, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *AWSComprehend) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *AWSComprehend) WaitForClose(timeout time.Duration) error {
+	return nil
+}
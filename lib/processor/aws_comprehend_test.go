@@ -0,0 +1,153 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/comprehend"
+	"github.com/aws/aws-sdk-go/service/comprehend/comprehendiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockComprehend struct {
+	comprehendiface.ComprehendAPI
+
+	batches [][]string
+}
+
+func (m *mockComprehend) BatchDetectSentimentWithContext(
+	ctx aws.Context, in *comprehend.BatchDetectSentimentInput, opts ...request.Option,
+) (*comprehend.BatchDetectSentimentOutput, error) {
+	texts := aws.StringValueSlice(in.TextList)
+	m.batches = append(m.batches, texts)
+
+	out := &comprehend.BatchDetectSentimentOutput{}
+	for i, t := range texts {
+		if t == "fail" {
+			out.ErrorList = append(out.ErrorList, &comprehend.BatchItemError{
+				Index:        aws.Int64(int64(i)),
+				ErrorCode:    aws.String("TextSizeLimitExceededException"),
+				ErrorMessage: aws.String("too long"),
+			})
+			continue
+		}
+		out.ResultList = append(out.ResultList, &comprehend.BatchDetectSentimentItemResult{
+			Index:     aws.Int64(int64(i)),
+			Sentiment: aws.String("POSITIVE"),
+			SentimentScore: &comprehend.SentimentScore{
+				Positive: aws.Float64(0.5),
+				Negative: aws.Float64(0.25),
+				Neutral:  aws.Float64(0.25),
+				Mixed:    aws.Float64(0),
+			},
+		})
+	}
+	return out, nil
+}
+
+func (m *mockComprehend) BatchDetectEntitiesWithContext(
+	ctx aws.Context, in *comprehend.BatchDetectEntitiesInput, opts ...request.Option,
+) (*comprehend.BatchDetectEntitiesOutput, error) {
+	return nil, errors.New("service unavailable")
+}
+
+func (m *mockComprehend) DetectPiiEntitiesWithContext(
+	ctx aws.Context, in *comprehend.DetectPiiEntitiesInput, opts ...request.Option,
+) (*comprehend.DetectPiiEntitiesOutput, error) {
+	return &comprehend.DetectPiiEntitiesOutput{
+		Entities: []*comprehend.PiiEntity{
+			{
+				Type:        aws.String("NAME"),
+				Score:       aws.Float64(0.9),
+				BeginOffset: aws.Int64(6),
+				EndOffset:   aws.Int64(10),
+			},
+		},
+	}, nil
+}
+
+func TestAWSComprehendSentiment(t *testing.T) {
+	conf := NewConfig()
+	conf.AWSComprehend.Operator = "sentiment"
+	conf.AWSComprehend.Text = `${! json("text") }`
+
+	client := &mockComprehend{}
+	proc, err := newAWSComprehend(conf, client, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	var input [][]byte
+	for i := 0; i < 30; i++ {
+		input = append(input, []byte(fmt.Sprintf(`{"text":"message %v"}`, i)))
+	}
+	input[3] = []byte(`{"text":"fail"}`)
+	input[27] = []byte(`{"text":""}`)
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0], 25)
+	assert.Len(t, client.batches[1], 4)
+
+	msg := msgs[0]
+	require.Equal(t, 30, msg.Len())
+	assert.Equal(t, `{"scores":{"mixed":0,"negative":0.25,"neutral":0.25,"positive":0.5},"sentiment":"POSITIVE"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, "", GetFail(msg.Get(0)))
+	assert.Equal(t, `{"scores":{"mixed":0,"negative":0.25,"neutral":0.25,"positive":0.5},"sentiment":"POSITIVE"}`, string(msg.Get(29).Get()))
+
+	assert.Equal(t, `{"text":"fail"}`, string(msg.Get(3).Get()))
+	assert.Equal(t, "TextSizeLimitExceededException: too long", GetFail(msg.Get(3)))
+
+	assert.Equal(t, `{"text":""}`, string(msg.Get(27).Get()))
+	assert.Equal(t, "text is empty", GetFail(msg.Get(27)))
+}
+
+func TestAWSComprehendRequestError(t *testing.T) {
+	conf := NewConfig()
+	conf.AWSComprehend.Operator = "entities"
+
+	proc, err := newAWSComprehend(conf, &mockComprehend{}, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "service unavailable", GetFail(msgs[0].Get(i)))
+	}
+	assert.Equal(t, "foo", string(msgs[0].Get(0).Get()))
+}
+
+func TestAWSComprehendPII(t *testing.T) {
+	conf := NewConfig()
+	conf.AWSComprehend.Operator = "pii_entities"
+
+	proc, err := newAWSComprehend(conf, &mockComprehend{}, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("hello Jane, how are you?")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, `{"entities":[{"begin_offset":6,"end_offset":10,"score":0.9,"text":"Jane","type":"NAME"}]}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestAWSComprehendBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.AWSComprehend.Operator = "nope"
+	_, err := newAWSComprehend(conf, &mockComprehend{}, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "operator not recognised: nope")
+
+	conf = NewConfig()
+	conf.AWSComprehend.LanguageCode = ""
+	_, err = newAWSComprehend(conf, &mockComprehend{}, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a language_code must be specified")
+}
@@ -0,0 +1,257 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/translate"
+	"github.com/aws/aws-sdk-go/service/translate/translateiface"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeAWSTranslate] = TypeSpec{
+		constructor: NewAWSTranslate,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Translates the text of messages with [AWS Translate](https://aws.amazon.com/translate/),
+replacing the contents of each message with the translation.`,
+		Description: `
+The text translated for each message is resolved from the interpolated field ` + "`text`" + `, which is the entire contents of the message by default, and a request is sent for each message. The ` + "`rate_limit`" + ` field can be used to specify a rate limit [resource](/docs/components/rate_limits/about) to cap the rate of requests across parallel components service wide.
+
+Messages that fail translation continue through the pipeline with their contents unchanged but flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+In order to map the translation into the original message instead of replacing it entirely you can use the ` + "[`branch` processor](/docs/components/processors/branch)" + `.
+
+## Metadata
+
+This processor adds the following metadata fields to each translated message:
+
+` + "``` text" + `
+- translate_source_language
+- translate_target_language
+` + "```" + `
+
+When ` + "`source_language`" + ` is ` + "`auto`" + ` the field ` + "`translate_source_language`" + ` contains the language detected by AWS Translate.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS
+services. It's also possible to set them explicitly at the component level,
+allowing you to transfer data across accounts. You can find out more
+[in this document](/docs/guides/aws).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Translate Support Tickets",
+				Summary: "This example uses a [`branch` processor](/docs/components/processors/branch/) to translate the description of support tickets into English, keeping the original description along with its detected language.",
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - aws_translate:
+              text: ${! json("description") }
+              target_language: en
+              region: eu-west-1
+        result_map: |
+          root.description_en = content().string()
+          root.description_language = meta("translate_source_language")
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("text", "The text to translate for each message.").IsInterpolated(),
+			docs.FieldCommon("source_language", "The language code of the text, or `auto` in order for the language to be detected.", "auto", "de", "fr"),
+			docs.FieldCommon("target_language", "The language code to translate the text into.", "en", "es"),
+			docs.FieldAdvanced("terminologies", "An optional list of names of custom terminologies to apply to translations.").Array(),
+			docs.FieldAdvanced("rate_limit", "An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle requests by."),
+		}.Merge(session.FieldSpecs()).Add(
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait before abandoning a request."),
+			PartsFieldSpec,
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AWSTranslateConfig contains configuration fields for the AWSTranslate
+// processor.
+type AWSTranslateConfig struct {
+	session.Config `json:",inline" yaml:",inline"`
+	Parts          []int    `json:"parts" yaml:"parts"`
+	Text           string   `json:"text" yaml:"text"`
+	SourceLanguage string   `json:"source_language" yaml:"source_language"`
+	TargetLanguage string   `json:"target_language" yaml:"target_language"`
+	Terminologies  []string `json:"terminologies" yaml:"terminologies"`
+	RateLimit      string   `json:"rate_limit" yaml:"rate_limit"`
+	Timeout        string   `json:"timeout" yaml:"timeout"`
+}
+
+// NewAWSTranslateConfig returns a AWSTranslateConfig with default values.
+func NewAWSTranslateConfig() AWSTranslateConfig {
+	return AWSTranslateConfig{
+		Config:         session.NewConfig(),
+		Parts:          []int{},
+		Text:           "${! content() }",
+		SourceLanguage: "auto",
+		TargetLanguage: "",
+		Terminologies:  []string{},
+		RateLimit:      "",
+		Timeout:        "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// AWSTranslate is a processor that translates the text of messages with AWS
+// Translate.
+type AWSTranslate struct {
+	parts         []int
+	text          *field.Expression
+	source        string
+	target        string
+	terminologies []*string
+	timeout       time.Duration
+	client        translateiface.TranslateAPI
+	limiter       resourceRateLimiter
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewAWSTranslate returns an AWSTranslate processor.
+func NewAWSTranslate(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	sess, err := conf.AWSTranslate.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	return newAWSTranslate(conf, translate.New(sess), mgr, log, stats)
+}
+
+func newAWSTranslate(
+	conf Config, client translateiface.TranslateAPI, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (*AWSTranslate, error) {
+	tConf := conf.AWSTranslate
+	if tConf.TargetLanguage == "" {
+		return nil, errors.New("a target_language must be specified")
+	}
+	if tConf.SourceLanguage == "" {
+		return nil, errors.New("a source_language must be specified")
+	}
+
+	p := &AWSTranslate{
+		parts:   tConf.Parts,
+		source:  tConf.SourceLanguage,
+		target:  tConf.TargetLanguage,
+		client:  client,
+		limiter: newResourceRateLimiter(tConf.RateLimit, mgr, log, stats),
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if len(tConf.Terminologies) > 0 {
+		p.terminologies = aws.StringSlice(tConf.Terminologies)
+	}
+
+	var err error
+	if p.text, err = bloblang.NewField(tConf.Text); err != nil {
+		return nil, fmt.Errorf("failed to parse text expression: %v", err)
+	}
+	if tConf.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(tConf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if err := p.limiter.probe(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *AWSTranslate) translatePart(index int, msg types.Message) error {
+	text := p.text.String(index, msg)
+	if text == "" {
+		return errors.New("text is empty")
+	}
+
+	p.limiter.wait()
+	ctx, done := context.WithTimeout(context.Background(), p.timeout)
+	defer done()
+
+	out, err := p.client.TextWithContext(ctx, &translate.TextInput{
+		SourceLanguageCode: aws.String(p.source),
+		TargetLanguageCode: aws.String(p.target),
+		TerminologyNames:   p.terminologies,
+		Text:               aws.String(text),
+	})
+	if err != nil {
+		return err
+	}
+
+	part := msg.Get(index)
+	part.Set([]byte(aws.StringValue(out.TranslatedText)))
+	part.Metadata().Set("translate_source_language", aws.StringValue(out.SourceLanguageCode))
+	part.Metadata().Set("translate_target_language", aws.StringValue(out.TargetLanguageCode))
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *AWSTranslate) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.translatePart(index, newMsg); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to translate message: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeAWSTranslate, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *AWSTranslate) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *AWSTranslate) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/translate"
+	"github.com/aws/aws-sdk-go/service/translate/translateiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTranslate struct {
+	translateiface.TranslateAPI
+
+	inputs []*translate.TextInput
+}
+
+func (m *mockTranslate) TextWithContext(
+	ctx aws.Context, in *translate.TextInput, opts ...request.Option,
+) (*translate.TextOutput, error) {
+	m.inputs = append(m.inputs, in)
+	text := aws.StringValue(in.Text)
+	if text == "fail" {
+		return nil, errors.New("unsupported language pair")
+	}
+	return &translate.TextOutput{
+		SourceLanguageCode: aws.String("de"),
+		TargetLanguageCode: in.TargetLanguageCode,
+		TranslatedText:     aws.String(strings.ToUpper(text)),
+	}, nil
+}
+
+func TestAWSTranslate(t *testing.T) {
+	conf := NewConfig()
+	conf.AWSTranslate.Text = `${! json("text") }`
+	conf.AWSTranslate.TargetLanguage = "en"
+	conf.AWSTranslate.Terminologies = []string{"products"}
+
+	client := &mockTranslate{}
+	proc, err := newAWSTranslate(conf, client, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"text":"hallo welt"}`),
+		[]byte(`{"text":"fail"}`),
+		[]byte(`{"text":""}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	msg := msgs[0]
+	assert.Equal(t, "HALLO WELT", string(msg.Get(0).Get()))
+	assert.Equal(t, "", GetFail(msg.Get(0)))
+	assert.Equal(t, "de", msg.Get(0).Metadata().Get("translate_source_language"))
+	assert.Equal(t, "en", msg.Get(0).Metadata().Get("translate_target_language"))
+
+	assert.Equal(t, `{"text":"fail"}`, string(msg.Get(1).Get()))
+	assert.Equal(t, "unsupported language pair", GetFail(msg.Get(1)))
+
+	assert.Equal(t, "text is empty", GetFail(msg.Get(2)))
+
+	require.Len(t, client.inputs, 2)
+	assert.Equal(t, "auto", aws.StringValue(client.inputs[0].SourceLanguageCode))
+	assert.Equal(t, []string{"products"}, aws.StringValueSlice(client.inputs[0].TerminologyNames))
+}
+
+func TestAWSTranslateBadConfig(t *testing.T) {
+	conf := NewConfig()
+	_, err := newAWSTranslate(conf, &mockTranslate{}, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a target_language must be specified")
+}
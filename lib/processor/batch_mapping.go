@@ -0,0 +1,268 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeBatchMapping] = TypeSpec{
+		constructor: NewBatchMapping,
+		Categories: []Category{
+			CategoryMapping,
+			CategoryUtility,
+		},
+		config: docs.FieldComponent().Linter(docs.LintBloblangMapping),
+		Status: docs.StatusBeta,
+		Summary: `
+Executes a [Bloblang](/docs/guides/bloblang/about) mapping once on an entire batch, where the mapping receives all messages of the batch as an array and produces a new batch of any size.`,
+		Description: `
+Each message of the batch is represented within the array as an object with the fields ` + "`content`" + `, containing the message parsed as a JSON document or, when it isn't valid JSON, as a string, and ` + "`metadata`" + `, containing an object of the metadata key/value pairs of the message:
+
+` + "```json" + `
+[
+  {"content":{"id":"foo"},"metadata":{"kafka_key":"a"}},
+  {"content":"not json","metadata":{}}
+]
+` + "```" + `
+
+The mapping must result in an array of objects of the same form, each of which becomes a message of the new batch in the order of the array. The field ` + "`content`" + ` of each object is required, where strings are written as raw bytes and all other values are written as JSON, and the field ` + "`metadata`" + ` is optional. This allows batches to be reordered, filtered, and expanded with computed messages in a single step, which would otherwise require a combination of processors such as ` + "[`select_parts`](/docs/components/processors/select_parts)" + ` and ` + "[`insert_part`](/docs/components/processors/insert_part)" + `.
+
+If the mapping results in an empty array, or is deleted with ` + "`root = deleted()`" + `, the batch is dropped entirely.
+
+Functions that reference a message directly, such as ` + "`meta`" + `, reference the first message of the batch.`,
+		Footnotes: `
+## Error Handling
+
+When the mapping fails, or results in a value that isn't an array of objects with a ` + "`content`" + ` field, the batch remains unchanged, the error is logged, and all messages of the batch are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`,
+		UsesBatches: true,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Sort and Summarise",
+				Summary: `
+Given batches of JSON documents containing events with a timestamp and an amount, we can sort the events of each batch by timestamp and append a summary message containing the total amount:`,
+				Config: `
+pipeline:
+  processors:
+    - batch_mapping: |
+        root = this.sort_by(msg -> msg.content.ts).append({
+          "content": {
+            "count": this.length(),
+            "total": this.map_each(msg -> msg.content.amount).sum()
+          },
+          "metadata": {"summary": "true"}
+        })
+`,
+			},
+			{
+				Title: "Header Part",
+				Summary: `
+When the first message of each batch is a header containing a document type we can remove it and add its type to the remaining messages:`,
+				Config: `
+pipeline:
+  processors:
+    - batch_mapping: |
+        let header = this.index(0).content
+        root = this.slice(1).map_each(msg -> {
+          "content": msg.content.merge({"type": $header.type}),
+          "metadata": msg.metadata
+        })
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// BatchMappingConfig contains configuration fields for the BatchMapping
+// processor.
+type BatchMappingConfig string
+
+// NewBatchMappingConfig returns a BatchMappingConfig with default values.
+func NewBatchMappingConfig() BatchMappingConfig {
+	return ""
+}
+
+//------------------------------------------------------------------------------
+
+// BatchMapping is a processor that performs a Bloblang mapping on an entire
+// batch at once.
+type BatchMapping struct {
+	exec *mapping.Executor
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+	mDropped   metrics.StatCounter
+}
+
+// NewBatchMapping returns a BatchMapping processor.
+func NewBatchMapping(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	exec, err := bloblang.NewMapping("", string(conf.BatchMapping))
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			return nil, fmt.Errorf("%v", perr.ErrorAtPosition([]rune(conf.BatchMapping)))
+		}
+		return nil, err
+	}
+	return &BatchMapping{
+		exec: exec,
+
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+		mDropped:   stats.GetCounter("dropped"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func batchMappingInput(msg types.Message) []interface{} {
+	parts := make([]interface{}, 0, msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		var content interface{}
+		if jObj, err := p.JSON(); err == nil {
+			content = jObj
+		} else {
+			content = string(p.Get())
+		}
+		meta := map[string]interface{}{}
+		p.Metadata().Iter(func(k, v string) error {
+			meta[k] = v
+			return nil
+		})
+		parts = append(parts, map[string]interface{}{
+			"content":  content,
+			"metadata": meta,
+		})
+		return nil
+	})
+	return parts
+}
+
+func batchMappingOutput(v interface{}) ([]types.Part, error) {
+	switch v.(type) {
+	case query.Delete:
+		return nil, nil
+	case query.Nothing:
+		return nil, errors.New("mapping did not assign a root value")
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, query.NewTypeErrorFrom("mapping", v, query.ValueArray)
+	}
+
+	parts := make([]types.Part, 0, len(arr))
+	for i, ele := range arr {
+		obj, ok := ele.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("element %v: %w", i, query.NewTypeErrorFrom("mapping", ele, query.ValueObject))
+		}
+		content, exists := obj["content"]
+		if !exists {
+			return nil, fmt.Errorf("element %v: missing field content", i)
+		}
+
+		part := message.NewPart(nil)
+		switch t := content.(type) {
+		case string:
+			part.Set([]byte(t))
+		case []byte:
+			part.Set(t)
+		default:
+			if err := part.SetJSON(t); err != nil {
+				return nil, fmt.Errorf("element %v: failed to set content: %w", i, err)
+			}
+		}
+
+		if metaV, exists := obj["metadata"]; exists && metaV != nil {
+			metaObj, ok := metaV.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("element %v: metadata: %w", i, query.NewTypeErrorFrom("mapping", metaV, query.ValueObject))
+			}
+			for k, mv := range metaObj {
+				part.Metadata().Set(k, query.IToString(mv))
+			}
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (b *BatchMapping) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	b.mCount.Incr(1)
+
+	v, err := b.exec.Exec(query.FunctionContext{
+		Maps:     b.exec.Maps(),
+		Vars:     map[string]interface{}{},
+		Index:    0,
+		MsgBatch: msg,
+	}.WithValue(batchMappingInput(msg)))
+
+	var parts []types.Part
+	if err == nil {
+		parts, err = batchMappingOutput(v)
+	}
+	if err != nil {
+		b.mErr.Incr(1)
+		b.log.Errorf("%v\n", err)
+		resMsg := msg.Copy()
+		resMsg.Iter(func(i int, p types.Part) error {
+			FlagErr(p, err)
+			return nil
+		})
+		b.mBatchSent.Incr(1)
+		b.mSent.Incr(int64(resMsg.Len()))
+		return []types.Message{resMsg}, nil
+	}
+
+	if len(parts) == 0 {
+		b.mDropped.Incr(int64(msg.Len()))
+		return nil, response.NewAck()
+	}
+
+	newMsg := message.New(nil)
+	newMsg.SetAll(parts)
+
+	b.mBatchSent.Incr(1)
+	b.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (b *BatchMapping) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (b *BatchMapping) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
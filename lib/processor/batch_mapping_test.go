@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchMappingTestMsg() types.Message {
+	msg := message.New(nil)
+	for _, p := range []struct {
+		content, key string
+	}{
+		{`{"ts":3,"amount":5}`, "c"},
+		{`{"ts":1,"amount":2}`, "a"},
+		{`not json`, "b"},
+	} {
+		part := message.NewPart([]byte(p.content))
+		part.Metadata().Set("key", p.key)
+		msg.Append(part)
+	}
+	return msg
+}
+
+func TestBatchMappingReshape(t *testing.T) {
+	conf := NewConfig()
+	conf.BatchMapping = `
+root = this.filter(msg -> msg.content.type() == "object").
+  sort_by(msg -> msg.content.ts).
+  append({
+    "content": {
+      "count": this.length(),
+      "total": this.filter(msg -> msg.content.type() == "object").map_each(msg -> msg.content.amount).sum()
+    },
+    "metadata": {"summary": true, "first_key": meta("key")}
+  })
+`
+	proc, err := NewBatchMapping(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	outMsgs, res := proc.ProcessMessage(newBatchMappingTestMsg())
+	require.Nil(t, res)
+	require.Len(t, outMsgs, 1)
+	require.Equal(t, 3, outMsgs[0].Len())
+
+	assert.Equal(t, `{"amount":2,"ts":1}`, string(outMsgs[0].Get(0).Get()))
+	assert.Equal(t, "a", outMsgs[0].Get(0).Metadata().Get("key"))
+	assert.Equal(t, `{"amount":5,"ts":3}`, string(outMsgs[0].Get(1).Get()))
+	assert.Equal(t, "c", outMsgs[0].Get(1).Metadata().Get("key"))
+	assert.Equal(t, `{"count":3,"total":7}`, string(outMsgs[0].Get(2).Get()))
+	assert.Equal(t, "true", outMsgs[0].Get(2).Metadata().Get("summary"))
+	assert.Equal(t, "c", outMsgs[0].Get(2).Metadata().Get("first_key"))
+	assert.Equal(t, "", outMsgs[0].Get(2).Metadata().Get("key"))
+}
+
+func TestBatchMappingRawContent(t *testing.T) {
+	conf := NewConfig()
+	conf.BatchMapping = `root = this.map_each(msg -> {"content": msg.content.string().uppercase()})`
+	proc, err := NewBatchMapping(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	outMsgs, res := proc.ProcessMessage(newBatchMappingTestMsg())
+	require.Nil(t, res)
+	require.Len(t, outMsgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"AMOUNT":5,"TS":3}`),
+		[]byte(`{"AMOUNT":2,"TS":1}`),
+		[]byte(`NOT JSON`),
+	}, message.GetAllBytes(outMsgs[0]))
+}
+
+func TestBatchMappingDrop(t *testing.T) {
+	for _, mapping := range []string{
+		`root = deleted()`,
+		`root = []`,
+	} {
+		conf := NewConfig()
+		conf.BatchMapping = BatchMappingConfig(mapping)
+		proc, err := NewBatchMapping(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+
+		outMsgs, res := proc.ProcessMessage(newBatchMappingTestMsg())
+		assert.Empty(t, outMsgs, mapping)
+		require.NotNil(t, res, mapping)
+		assert.NoError(t, res.Error(), mapping)
+	}
+}
+
+func TestBatchMappingErrors(t *testing.T) {
+	tests := map[string]string{
+		`root = this.index(0).content`:                   `expected array value, got object`,
+		`root = ["foo"]`:                                 `element 0: expected object value, got string`,
+		`root = [{"metadata": {}}]`:                      `element 0: missing field content`,
+		`root = [{"content": "foo", "metadata": "bar"}]`: `element 0: metadata: expected object value, got string`,
+		`root = this.index(10)`:                          `index '10' was out of bounds`,
+	}
+
+	for mapping, errContains := range tests {
+		conf := NewConfig()
+		conf.BatchMapping = BatchMappingConfig(mapping)
+		proc, err := NewBatchMapping(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+
+		outMsgs, res := proc.ProcessMessage(newBatchMappingTestMsg())
+		require.Nil(t, res, mapping)
+		require.Len(t, outMsgs, 1, mapping)
+		require.Equal(t, 3, outMsgs[0].Len(), mapping)
+		assert.Equal(t, `not json`, string(outMsgs[0].Get(2).Get()), mapping)
+		outMsgs[0].Iter(func(i int, p types.Part) error {
+			assert.Contains(t, GetFail(p), errContains, mapping)
+			return nil
+		})
+	}
+}
+
+func TestBatchMappingBadMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.BatchMapping = `root = this.`
+	_, err := NewBatchMapping(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -0,0 +1,365 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+var errorKinds = []string{
+	ErrorKindTimeout,
+	ErrorKindHTTP4xx,
+	ErrorKindHTTP5xx,
+	ErrorKindValidation,
+	ErrorKindParse,
+}
+
+func init() {
+	Constructors[TypeCatchSwitch] = TypeSpec{
+		constructor: NewCatchSwitch,
+		Categories: []Category{
+			CategoryComposition,
+		},
+		Summary: `
+Applies a different list of child processors to messages that failed a previous
+processing step depending on the type of error.`,
+		Description: `
+Behaves similarly to the ` + "[`catch`](/docs/components/processors/catch)" + ` processor, where messages that failed a
+processing step prior to this one are processed individually. However, each
+failed message is tested against a list of cases and is only processed by the
+first case that it matches. A case can match messages by the kind of error, a
+regular expression pattern on the error message, a
+[Bloblang query](/docs/guides/bloblang/about/), or any combination of these, and
+a case without any of these fields matches all failed messages.
+
+When messages leave a case their fail flags are cleared. Failed messages that do
+not match any case are left unchanged, and therefore remain flagged as failed.
+
+### Error Kinds
+
+Processors that fail a message also record the kind of error where it's known,
+which is one of:
+
+- ` + "`timeout`" + `: A request or operation exceeded a time limit.
+- ` + "`http_4xx`" + `: An HTTP request returned a 4XX status code.
+- ` + "`http_5xx`" + `: An HTTP request returned a 5XX status code.
+- ` + "`validation`" + `: A message failed a validation step such as a JSON schema check.
+- ` + "`parse`" + `: A message could not be parsed, for example as JSON.
+
+More information about error handing can be found [here](/docs/configuration/error_handling).`,
+		config: docs.FieldComponent().Array().WithChildren(
+			docs.FieldCommon(
+				"kinds",
+				"An optional list of error kinds that a failed message must match one of.",
+				[]string{ErrorKindTimeout, ErrorKindHTTP5xx},
+			).HasDefault([]interface{}{}).Array().HasOptions(errorKinds...),
+			docs.FieldCommon(
+				"pattern",
+				"An optional regular expression that the error message of a failed message must match.",
+				`status code \(4\d\d\)`,
+			).HasDefault(""),
+			docs.FieldCommon(
+				"check",
+				"An optional [Bloblang query](/docs/guides/bloblang/about/) that should return a boolean value indicating whether a failed message matches this case. The [`error_info` function](/docs/guides/bloblang/functions#error_info) can be used to test the details of the error.",
+				`error_info().source == "enrich"`,
+			).HasDefault("").Linter(docs.LintBloblangMapping),
+			docs.FieldCommon(
+				"processors",
+				"A list of [processors](/docs/components/processors/about/) to execute on a failed message that matches this case.",
+			).HasDefault([]interface{}{}).Array().HasType(docs.FieldProcessor),
+		),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Recovery per Error Kind",
+				Summary: `
+Here we enrich documents with an HTTP request. When the request times out or the
+service is having problems we route the document to a retry queue, when the
+document is rejected we send it to a dead letter queue instead, and any other
+error is logged and the message is dropped.`,
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.id = this.id'
+        processors:
+          - http:
+              url: http://example.com/enrich
+              verb: POST
+        result_map: 'root.enrichment = this'
+    - catch_switch:
+        - kinds: [ timeout, http_5xx ]
+          processors:
+            - bloblang: 'meta route = "retry"'
+        - kinds: [ http_4xx, validation ]
+          processors:
+            - bloblang: 'meta route = "dead_letter"'
+        - processors:
+            - log:
+                message: 'Dropping message due to: ${! error() }'
+            - bloblang: root = deleted()
+`,
+			},
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CatchSwitchCaseConfig contains the matching criteria and processors of an
+// individual case in the CatchSwitch processor.
+type CatchSwitchCaseConfig struct {
+	Kinds      []string `json:"kinds" yaml:"kinds"`
+	Pattern    string   `json:"pattern" yaml:"pattern"`
+	Check      string   `json:"check" yaml:"check"`
+	Processors []Config `json:"processors" yaml:"processors"`
+}
+
+// NewCatchSwitchCaseConfig returns a new CatchSwitchCaseConfig with default
+// values.
+func NewCatchSwitchCaseConfig() CatchSwitchCaseConfig {
+	return CatchSwitchCaseConfig{
+		Kinds:      []string{},
+		Pattern:    "",
+		Check:      "",
+		Processors: []Config{},
+	}
+}
+
+// UnmarshalJSON ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (c *CatchSwitchCaseConfig) UnmarshalJSON(bytes []byte) error {
+	type confAlias CatchSwitchCaseConfig
+	aliased := confAlias(NewCatchSwitchCaseConfig())
+
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+
+	*c = CatchSwitchCaseConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a map or slice
+// the default values are still applied.
+func (c *CatchSwitchCaseConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias CatchSwitchCaseConfig
+	aliased := confAlias(NewCatchSwitchCaseConfig())
+
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+
+	*c = CatchSwitchCaseConfig(aliased)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// CatchSwitchConfig is a config struct containing fields for the CatchSwitch
+// processor.
+type CatchSwitchConfig []CatchSwitchCaseConfig
+
+// NewCatchSwitchConfig returns a default CatchSwitchConfig.
+func NewCatchSwitchConfig() CatchSwitchConfig {
+	return CatchSwitchConfig{}
+}
+
+//------------------------------------------------------------------------------
+
+type catchSwitchCase struct {
+	kinds      map[string]struct{}
+	pattern    *regexp.Regexp
+	check      *mapping.Executor
+	processors []types.Processor
+}
+
+func (c catchSwitchCase) matches(index int, msg types.Message) (bool, error) {
+	part := msg.Get(index)
+	if len(c.kinds) > 0 {
+		if _, exists := c.kinds[part.Metadata().Get(types.FailKindKey)]; !exists {
+			return false, nil
+		}
+	}
+	if c.pattern != nil && !c.pattern.MatchString(GetFail(part)) {
+		return false, nil
+	}
+	if c.check != nil {
+		return c.check.QueryPart(index, msg)
+	}
+	return true, nil
+}
+
+// CatchSwitch is a processor that applies the child processors of the first
+// matching case to each message of a batch that failed a previous processing
+// step.
+type CatchSwitch struct {
+	cases []catchSwitchCase
+	log   log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewCatchSwitch returns a CatchSwitch processor.
+func NewCatchSwitch(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var cases []catchSwitchCase
+	for i, caseConf := range conf.CatchSwitch {
+		prefix := strconv.Itoa(i)
+
+		c := catchSwitchCase{}
+		if len(caseConf.Kinds) > 0 {
+			c.kinds = map[string]struct{}{}
+			for _, k := range caseConf.Kinds {
+				known := false
+				for _, ek := range errorKinds {
+					if k == ek {
+						known = true
+						break
+					}
+				}
+				if !known {
+					return nil, fmt.Errorf("case [%v] error kind not recognised: %v", i, k)
+				}
+				c.kinds[k] = struct{}{}
+			}
+		}
+
+		var err error
+		if len(caseConf.Pattern) > 0 {
+			if c.pattern, err = regexp.Compile(caseConf.Pattern); err != nil {
+				return nil, fmt.Errorf("failed to parse case %v pattern: %w", i, err)
+			}
+		}
+		if len(caseConf.Check) > 0 {
+			if c.check, err = bloblang.NewMapping("", caseConf.Check); err != nil {
+				return nil, fmt.Errorf("failed to parse case %v check: %w", i, err)
+			}
+		}
+
+		if len(caseConf.Processors) == 0 {
+			return nil, fmt.Errorf("case [%v] has no processors, in order to have a no-op case use a `noop` processor", i)
+		}
+		for j, procConf := range caseConf.Processors {
+			pMgr, pLog, pStats := interop.LabelChild(prefix+"."+strconv.Itoa(j), mgr, log, stats)
+			proc, err := New(procConf, pMgr, pLog, pStats)
+			if err != nil {
+				return nil, fmt.Errorf("case [%v] processor [%v]: %w", i, j, err)
+			}
+			c.processors = append(c.processors, proc)
+		}
+		cases = append(cases, c)
+	}
+	return &CatchSwitch{
+		cases: cases,
+		log:   log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *CatchSwitch) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	resMsg := message.New(nil)
+	for i := 0; i < msg.Len(); i++ {
+		part := msg.Get(i)
+		if !HasFailed(part) {
+			resMsg.Append(part)
+			continue
+		}
+
+		matched := -1
+		for j, sCase := range c.cases {
+			match, err := sCase.matches(i, msg)
+			if err != nil {
+				c.mErr.Incr(1)
+				c.log.Errorf("Failed to test case %v: %v\n", j, err)
+				continue
+			}
+			if match {
+				matched = j
+				break
+			}
+		}
+		if matched < 0 {
+			resMsg.Append(part)
+			continue
+		}
+
+		tmpMsg := message.New(nil)
+		tmpMsg.SetAll([]types.Part{part})
+
+		resultMsgs, res := ExecuteCatchAll(c.cases[matched].processors, tmpMsg)
+		if res != nil && res.Error() != nil {
+			return nil, res
+		}
+		for _, m := range resultMsgs {
+			m.Iter(func(_ int, p types.Part) error {
+				ClearFail(p)
+				resMsg.Append(p)
+				return nil
+			})
+		}
+	}
+
+	if resMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	c.mBatchSent.Incr(1)
+	c.mSent.Incr(int64(resMsg.Len()))
+
+	resMsgs := [1]types.Message{resMsg}
+	return resMsgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (c *CatchSwitch) CloseAsync() {
+	for _, sCase := range c.cases {
+		for _, proc := range sCase.processors {
+			proc.CloseAsync()
+		}
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (c *CatchSwitch) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, sCase := range c.cases {
+		for _, proc := range sCase.processors {
+			if err := proc.WaitForClose(time.Until(stopBy)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
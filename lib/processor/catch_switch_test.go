@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKind(t *testing.T) {
+	_, jsonErr := message.NewPart([]byte("not json")).JSON()
+	require.Error(t, jsonErr)
+
+	tests := map[string]struct {
+		err  error
+		kind string
+	}{
+		"generic":     {err: errors.New("nope")},
+		"deadline":    {err: fmt.Errorf("request failed: %w", context.DeadlineExceeded), kind: ErrorKindTimeout},
+		"timeout":     {err: types.ErrTimeout, kind: ErrorKindTimeout},
+		"http 404":    {err: types.ErrUnexpectedHTTPRes{Code: 404}, kind: ErrorKindHTTP4xx},
+		"http 503":    {err: fmt.Errorf("wrapped: %w", types.ErrUnexpectedHTTPRes{Code: 503}), kind: ErrorKindHTTP5xx},
+		"http 302":    {err: types.ErrUnexpectedHTTPRes{Code: 302}},
+		"validation":  {err: types.ErrValidation{S: "nope"}, kind: ErrorKindValidation},
+		"json syntax": {err: jsonErr, kind: ErrorKindParse},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.kind, errorKind(test.err))
+
+			part := message.NewPart(nil)
+			FlagErr(part, test.err)
+			assert.Equal(t, test.kind, part.Metadata().Get(types.FailKindKey))
+
+			ClearFail(part)
+			assert.Equal(t, "", part.Metadata().Get(types.FailKindKey))
+		})
+	}
+}
+
+func TestCatchSwitch(t *testing.T) {
+	retryConf := NewConfig()
+	retryConf.Type = TypeBloblang
+	retryConf.Bloblang = `meta route = "retry"`
+
+	dlqConf := NewConfig()
+	dlqConf.Type = TypeBloblang
+	dlqConf.Bloblang = `meta route = "dead_letter"`
+
+	nopeConf := NewConfig()
+	nopeConf.Type = TypeBloblang
+	nopeConf.Bloblang = `meta route = "nope"`
+
+	conf := NewConfig()
+	conf.Type = TypeCatchSwitch
+
+	timeoutCase := NewCatchSwitchCaseConfig()
+	timeoutCase.Kinds = []string{ErrorKindTimeout, ErrorKindHTTP5xx}
+	timeoutCase.Processors = []Config{retryConf}
+
+	patternCase := NewCatchSwitchCaseConfig()
+	patternCase.Pattern = `^bad \w+$`
+	patternCase.Check = `error_source() == "foo"`
+	patternCase.Processors = []Config{dlqConf}
+
+	checkCase := NewCatchSwitchCaseConfig()
+	checkCase.Check = `content() == "nope"`
+	checkCase.Processors = []Config{nopeConf}
+
+	conf.CatchSwitch = append(conf.CatchSwitch, timeoutCase, patternCase, checkCase)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	parts := []types.Part{
+		message.NewPart([]byte("fine")),
+		message.NewPart([]byte("timeout")),
+		message.NewPart([]byte("http")),
+		message.NewPart([]byte("bad thing")),
+		message.NewPart([]byte("very bad thing")),
+		message.NewPart([]byte("nope")),
+	}
+	FlagErr(parts[1], types.ErrTimeout)
+	FlagErr(parts[2], types.ErrUnexpectedHTTPRes{Code: 502})
+	FlagErr(parts[3], errors.New("bad thing"))
+	FlagErr(parts[4], errors.New("very bad thing"))
+	FlagErr(parts[5], errors.New("nope"))
+	parts[3].Metadata().Set(types.FailSourceKey, "foo")
+	parts[4].Metadata().Set(types.FailSourceKey, "foo")
+
+	msg := message.New(nil)
+	msg.SetAll(parts)
+
+	msgs, res := proc.ProcessMessage(msg)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 6, msgs[0].Len())
+
+	exp := []struct {
+		route  string
+		failed bool
+	}{
+		{route: "", failed: false},
+		{route: "retry", failed: false},
+		{route: "retry", failed: false},
+		{route: "dead_letter", failed: false},
+		{route: "", failed: true},
+		{route: "nope", failed: false},
+	}
+	for i, e := range exp {
+		p := msgs[0].Get(i)
+		assert.Equal(t, e.route, p.Metadata().Get("route"), i)
+		assert.Equal(t, e.failed, HasFailed(p), i)
+	}
+}
+
+func TestCatchSwitchBadConfig(t *testing.T) {
+	noopConf := NewConfig()
+	noopConf.Type = TypeNoop
+
+	conf := NewConfig()
+	conf.Type = TypeCatchSwitch
+
+	badKind := NewCatchSwitchCaseConfig()
+	badKind.Kinds = []string{"nope"}
+	badKind.Processors = []Config{noopConf}
+	conf.CatchSwitch = CatchSwitchConfig{badKind}
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "case [0] error kind not recognised: nope")
+
+	conf.CatchSwitch = CatchSwitchConfig{NewCatchSwitchCaseConfig()}
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
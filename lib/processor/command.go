@@ -0,0 +1,293 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeCommand] = TypeSpec{
+		constructor: NewCommand,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Executes a command for each message, piping the contents of the message to the
+stdin stream of the command and replacing the contents with its stdout.`,
+		Description: `
+Unlike the ` + "[`subprocess` processor](/docs/components/processors/subprocess)" + `, which keeps a single process alive and streams messages through it, this processor starts a new process for each message and waits for it to exit. This makes it suitable for tools that operate on a single document and cannot be adapted to process a stream, at the cost of the overhead of starting a process per message.
+
+The arguments provided to the command support [interpolation functions](/docs/configuration/interpolation#bloblang-queries), which are resolved for each message. The arguments are passed to the command directly and are not interpreted by a shell.
+
+The messages of a batch are executed in parallel, and the number of commands running at any given time across all threads of the pipeline is capped by the field ` + "`max_in_flight`" + `. Commands that do not exit within the ` + "`timeout`" + ` are killed.
+
+The execution environment of the command is the same as the Benthos instance, including environment variables and the current working directory.
+
+## Error Handling
+
+When a command exits with a code that is not listed in ` + "`success_codes`" + `, or fails to execute at all, the contents of the message remain unchanged and the message is flagged as having failed with an error containing the exit code and the stderr output of the command, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+## Metadata
+
+This processor adds the following metadata fields to each message that executed a command:
+
+` + "``` text" + `
+- command_exit_code
+` + "```" + ``,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Render Documents",
+				Summary: "This example renders Markdown documents into HTML with `pandoc`, using a title taken from the metadata of each message.",
+				Config: `
+pipeline:
+  processors:
+    - command:
+        name: pandoc
+        args:
+          - --standalone
+          - --metadata=title:${! meta("title") }
+          - --to=html
+        max_in_flight: 8
+        timeout: 10s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("name", "The name or path of the command to execute.", "jq", "/usr/local/bin/render"),
+			docs.FieldCommon("args", "A list of arguments to provide the command.").IsInterpolated().Array(),
+			docs.FieldCommon("max_in_flight", "The maximum number of commands to execute in parallel."),
+			docs.FieldCommon("timeout", "The maximum period of time to wait for a command to exit before it is killed."),
+			docs.FieldAdvanced("success_codes", "A list of exit codes that indicate a command succeeded.").Array(),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CommandConfig contains configuration fields for the Command processor.
+type CommandConfig struct {
+	Parts        []int    `json:"parts" yaml:"parts"`
+	Name         string   `json:"name" yaml:"name"`
+	Args         []string `json:"args" yaml:"args"`
+	MaxInFlight  int      `json:"max_in_flight" yaml:"max_in_flight"`
+	Timeout      string   `json:"timeout" yaml:"timeout"`
+	SuccessCodes []int    `json:"success_codes" yaml:"success_codes"`
+}
+
+// NewCommandConfig returns a CommandConfig with default values.
+func NewCommandConfig() CommandConfig {
+	return CommandConfig{
+		Parts:        []int{},
+		Name:         "",
+		Args:         []string{},
+		MaxInFlight:  1,
+		Timeout:      "5s",
+		SuccessCodes: []int{0},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Command is a processor that executes a command for each message.
+type Command struct {
+	parts        []int
+	name         string
+	args         []*field.Expression
+	timeout      time.Duration
+	successCodes map[int]struct{}
+	pool         chan struct{}
+
+	ctx  context.Context
+	done func()
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewCommand returns a Command processor.
+func NewCommand(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	cConf := conf.Command
+	if cConf.Name == "" {
+		return nil, errors.New("a command name must be specified")
+	}
+	if cConf.MaxInFlight <= 0 {
+		return nil, errors.New("max_in_flight must be larger than zero")
+	}
+	if len(cConf.SuccessCodes) == 0 {
+		return nil, errors.New("at least one success code must be specified")
+	}
+
+	c := &Command{
+		parts:        cConf.Parts,
+		name:         cConf.Name,
+		successCodes: make(map[int]struct{}, len(cConf.SuccessCodes)),
+		pool:         make(chan struct{}, cConf.MaxInFlight),
+
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	for _, code := range cConf.SuccessCodes {
+		c.successCodes[code] = struct{}{}
+	}
+	for i, v := range cConf.Args {
+		expr, err := bloblang.NewField(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse arg %v expression: %v", i, err)
+		}
+		c.args = append(c.args, expr)
+	}
+
+	var err error
+	if c.timeout, err = time.ParseDuration(cConf.Timeout); err != nil {
+		return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+	}
+	if c.timeout <= 0 {
+		return nil, errors.New("timeout must be larger than zero")
+	}
+
+	c.ctx, c.done = context.WithCancel(context.Background())
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+type commandResult struct {
+	stdout   []byte
+	exitCode int
+	err      error
+}
+
+func (c *Command) execute(args []string, stdin []byte) commandResult {
+	select {
+	case c.pool <- struct{}{}:
+	case <-c.ctx.Done():
+		return commandResult{exitCode: -1, err: types.ErrTypeClosed}
+	}
+	defer func() {
+		<-c.pool
+	}()
+
+	ctx, done := context.WithTimeout(c.ctx, c.timeout)
+	defer done()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return commandResult{exitCode: -1, err: fmt.Errorf("command timed out after %v", c.timeout)}
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return commandResult{exitCode: -1, err: err}
+	}
+
+	exitCode := cmd.ProcessState.ExitCode()
+	if _, ok := c.successCodes[exitCode]; !ok {
+		errStr := fmt.Sprintf("command exited with code %v", exitCode)
+		if stderrStr := strings.TrimSpace(stderr.String()); stderrStr != "" {
+			errStr += ": " + stderrStr
+		}
+		return commandResult{exitCode: exitCode, err: errors.New(errStr)}
+	}
+	return commandResult{stdout: stdout.Bytes(), exitCode: exitCode}
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *Command) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	indexes := c.parts
+	if len(indexes) == 0 {
+		indexes = make([]int, newMsg.Len())
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	results := make(map[int]commandResult, len(indexes))
+	var resultsMut sync.Mutex
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(indexes))
+	for _, index := range indexes {
+		// Arguments are resolved before dispatching commands in parallel as
+		// interpolation functions may access any part of the batch.
+		args := make([]string, len(c.args))
+		for i, arg := range c.args {
+			args[i] = arg.String(index, newMsg)
+		}
+		go func(index int, args []string, stdin []byte) {
+			res := c.execute(args, stdin)
+			resultsMut.Lock()
+			results[index] = res
+			resultsMut.Unlock()
+			wg.Done()
+		}(index, args, newMsg.Get(index).Get())
+	}
+	wg.Wait()
+
+	IteratePartsWithSpan(TypeCommand, c.parts, newMsg, func(index int, span opentracing.Span, part types.Part) error {
+		res := results[index]
+		if res.exitCode >= 0 {
+			part.Metadata().Set("command_exit_code", strconv.Itoa(res.exitCode))
+		}
+		if res.err != nil {
+			c.mErr.Incr(1)
+			c.log.Debugf("Failed to execute command: %v\n", res.err)
+			return res.err
+		}
+		part.Set(res.stdout)
+		return nil
+	})
+
+	c.mBatchSent.Incr(1)
+	c.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (c *Command) CloseAsync() {
+	c.done()
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (c *Command) WaitForClose(timeout time.Duration) error {
+	return nil
+}
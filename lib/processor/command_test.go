@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandArgsInterpolation(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeCommand
+	conf.Command.Name = "sh"
+	conf.Command.Args = []string{"-c", `printf '%s:' "$0"; cat`, `${! meta("foo") }`}
+	conf.Command.MaxInFlight = 2
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgIn := message.New([][]byte{
+		[]byte(`first`),
+		[]byte(`second`),
+		[]byte(`third`),
+	})
+	msgIn.Get(0).Metadata().Set("foo", "a")
+	msgIn.Get(1).Metadata().Set("foo", "b")
+	msgIn.Get(2).Metadata().Set("foo", "c")
+
+	msgs, res := proc.ProcessMessage(msgIn)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`a:first`),
+		[]byte(`b:second`),
+		[]byte(`c:third`),
+	}, message.GetAllBytes(msgs[0]))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "", GetFail(msgs[0].Get(i)))
+		assert.Equal(t, "0", msgs[0].Get(i).Metadata().Get("command_exit_code"))
+	}
+
+	proc.CloseAsync()
+	require.NoError(t, proc.WaitForClose(time.Second))
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeCommand
+	conf.Command.Name = "sh"
+	conf.Command.Args = []string{"-c", `cat >/dev/null; echo "exiting with $0" >&2; exit $0`, `${! content() }`}
+	conf.Command.SuccessCodes = []int{0, 3}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`0`),
+		[]byte(`3`),
+		[]byte(`2`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "", string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+
+	assert.Equal(t, "", string(msgs[0].Get(1).Get()))
+	assert.Equal(t, "", GetFail(msgs[0].Get(1)))
+	assert.Equal(t, "3", msgs[0].Get(1).Metadata().Get("command_exit_code"))
+
+	assert.Equal(t, "2", string(msgs[0].Get(2).Get()))
+	assert.Equal(t, "command exited with code 2: exiting with 2", GetFail(msgs[0].Get(2)))
+	assert.Equal(t, "2", msgs[0].Get(2).Metadata().Get("command_exit_code"))
+}
+
+func TestCommandTimeout(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeCommand
+	conf.Command.Name = "sleep"
+	conf.Command.Args = []string{"10"}
+	conf.Command.Timeout = "10ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`hello`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "hello", string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "command timed out after 10ms", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get("command_exit_code"))
+}
+
+func TestCommandBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeCommand
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a command name must be specified")
+
+	conf.Command.Name = "cat"
+	conf.Command.MaxInFlight = 0
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "max_in_flight must be larger than zero")
+}
@@ -129,212 +129,274 @@ func Block(typeStr, reason string) {
 
 // String constants representing each processor type.
 const (
-	TypeArchive      = "archive"
-	TypeAvro         = "avro"
-	TypeAWK          = "awk"
-	TypeAWSLambda    = "aws_lambda"
-	TypeBatch        = "batch"
-	TypeBloblang     = "bloblang"
-	TypeBoundsCheck  = "bounds_check"
-	TypeBranch       = "branch"
-	TypeCache        = "cache"
-	TypeCatch        = "catch"
-	TypeCompress     = "compress"
-	TypeConditional  = "conditional"
-	TypeDecode       = "decode"
-	TypeDecompress   = "decompress"
-	TypeDedupe       = "dedupe"
-	TypeEncode       = "encode"
-	TypeFilter       = "filter"
-	TypeFilterParts  = "filter_parts"
-	TypeForEach      = "for_each"
-	TypeGrok         = "grok"
-	TypeGroupBy      = "group_by"
-	TypeGroupByValue = "group_by_value"
-	TypeHash         = "hash"
-	TypeHashSample   = "hash_sample"
-	TypeHTTP         = "http"
-	TypeInsertPart   = "insert_part"
-	TypeJMESPath     = "jmespath"
-	TypeJQ           = "jq"
-	TypeJSON         = "json"
-	TypeJSONSchema   = "json_schema"
-	TypeLambda       = "lambda"
-	TypeLog          = "log"
-	TypeMergeJSON    = "merge_json"
-	TypeMetadata     = "metadata"
-	TypeMetric       = "metric"
-	TypeMongoDB      = "mongodb"
-	TypeNoop         = "noop"
-	TypeNumber       = "number"
-	TypeParallel     = "parallel"
-	TypeParseLog     = "parse_log"
-	TypeProcessBatch = "process_batch"
-	TypeProcessDAG   = "process_dag"
-	TypeProcessField = "process_field"
-	TypeProcessMap   = "process_map"
-	TypeProtobuf     = "protobuf"
-	TypeRateLimit    = "rate_limit"
-	TypeRedis        = "redis"
-	TypeResource     = "resource"
-	TypeSample       = "sample"
-	TypeSelectParts  = "select_parts"
-	TypeSleep        = "sleep"
-	TypeSplit        = "split"
-	TypeSQL          = "sql"
-	TypeSubprocess   = "subprocess"
-	TypeSwitch       = "switch"
-	TypeSyncResponse = "sync_response"
-	TypeText         = "text"
-	TypeTry          = "try"
-	TypeThrottle     = "throttle"
-	TypeUnarchive    = "unarchive"
-	TypeWhile        = "while"
-	TypeWorkflow     = "workflow"
-	TypeXML          = "xml"
+	TypeAnomalyDetect  = "anomaly_detect"
+	TypeArchive        = "archive"
+	TypeArtificialLoad = "artificial_load"
+	TypeAvro           = "avro"
+	TypeAWK            = "awk"
+	TypeAWSComprehend  = "aws_comprehend"
+	TypeAWSLambda      = "aws_lambda"
+	TypeAWSTranslate   = "aws_translate"
+	TypeBatch          = "batch"
+	TypeBatchMapping   = "batch_mapping"
+	TypeBloblang       = "bloblang"
+	TypeBoundsCheck    = "bounds_check"
+	TypeBranch         = "branch"
+	TypeCache          = "cache"
+	TypeCatch          = "catch"
+	TypeCatchSwitch    = "catch_switch"
+	TypeCommand        = "command"
+	TypeCompress       = "compress"
+	TypeConditional    = "conditional"
+	TypeCouchbase      = "couchbase"
+	TypeDecode         = "decode"
+	TypeDecompress     = "decompress"
+	TypeDedupe         = "dedupe"
+	TypeEDI            = "edi"
+	TypeEncode         = "encode"
+	TypeFaultInjection = "fault_injection"
+	TypeFilter         = "filter"
+	TypeFilterParts    = "filter_parts"
+	TypeForEach        = "for_each"
+	TypeGeoIP          = "geoip"
+	TypeGrok           = "grok"
+	TypeGroupBy        = "group_by"
+	TypeGroupByValue   = "group_by_value"
+	TypeHash           = "hash"
+	TypeHashSample     = "hash_sample"
+	TypeHL7            = "hl7"
+	TypeHTTP           = "http"
+	TypeInsertPart     = "insert_part"
+	TypeJMESPath       = "jmespath"
+	TypeJQ             = "jq"
+	TypeJSON           = "json"
+	TypeJSONSchema     = "json_schema"
+	TypeLambda         = "lambda"
+	TypeLog            = "log"
+	TypeMergeJSON      = "merge_json"
+	TypeMetadata       = "metadata"
+	TypeMetric         = "metric"
+	TypeMongoDB        = "mongodb"
+	TypeMultiline      = "multiline"
+	TypeNoop           = "noop"
+	TypeOpenAI         = "openai"
+	TypeNumber         = "number"
+	TypeParallel       = "parallel"
+	TypeParquetDecode  = "parquet_decode"
+	TypeParquetEncode  = "parquet_encode"
+	TypeParseLog       = "parse_log"
+	TypeProcessBatch   = "process_batch"
+	TypeProcessDAG     = "process_dag"
+	TypeProcessField   = "process_field"
+	TypeProcessMap     = "process_map"
+	TypeProtobuf       = "protobuf"
+	TypeRateLimit      = "rate_limit"
+	TypeRecord         = "record"
+	TypeRedis          = "redis"
+	TypeResource       = "resource"
+	TypeSample         = "sample"
+	TypeSchemaDrift    = "schema_drift"
+	TypeSelectParts    = "select_parts"
+	TypeSleep          = "sleep"
+	TypeSplit          = "split"
+	TypeSQL            = "sql"
+	TypeSubprocess     = "subprocess"
+	TypeSwitch         = "switch"
+	TypeSyncResponse   = "sync_response"
+	TypeText           = "text"
+	TypeTry            = "try"
+	TypeThrottle       = "throttle"
+	TypeUnarchive      = "unarchive"
+	TypeUserAgent      = "user_agent"
+	TypeValidate       = "validate"
+	TypeWhile          = "while"
+	TypeWorkflow       = "workflow"
+	TypeXML            = "xml"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all processor types.
 type Config struct {
-	Label        string             `json:"label" yaml:"label"`
-	Type         string             `json:"type" yaml:"type"`
-	Archive      ArchiveConfig      `json:"archive" yaml:"archive"`
-	Avro         AvroConfig         `json:"avro" yaml:"avro"`
-	AWK          AWKConfig          `json:"awk" yaml:"awk"`
-	AWSLambda    LambdaConfig       `json:"aws_lambda" yaml:"aws_lambda"`
-	Batch        BatchConfig        `json:"batch" yaml:"batch"`
-	Bloblang     BloblangConfig     `json:"bloblang" yaml:"bloblang"`
-	BoundsCheck  BoundsCheckConfig  `json:"bounds_check" yaml:"bounds_check"`
-	Branch       BranchConfig       `json:"branch" yaml:"branch"`
-	Cache        CacheConfig        `json:"cache" yaml:"cache"`
-	Catch        CatchConfig        `json:"catch" yaml:"catch"`
-	Compress     CompressConfig     `json:"compress" yaml:"compress"`
-	Conditional  ConditionalConfig  `json:"conditional" yaml:"conditional"`
-	Decode       DecodeConfig       `json:"decode" yaml:"decode"`
-	Decompress   DecompressConfig   `json:"decompress" yaml:"decompress"`
-	Dedupe       DedupeConfig       `json:"dedupe" yaml:"dedupe"`
-	Encode       EncodeConfig       `json:"encode" yaml:"encode"`
-	Filter       FilterConfig       `json:"filter" yaml:"filter"`
-	FilterParts  FilterPartsConfig  `json:"filter_parts" yaml:"filter_parts"`
-	ForEach      ForEachConfig      `json:"for_each" yaml:"for_each"`
-	Grok         GrokConfig         `json:"grok" yaml:"grok"`
-	GroupBy      GroupByConfig      `json:"group_by" yaml:"group_by"`
-	GroupByValue GroupByValueConfig `json:"group_by_value" yaml:"group_by_value"`
-	Hash         HashConfig         `json:"hash" yaml:"hash"`
-	HashSample   HashSampleConfig   `json:"hash_sample" yaml:"hash_sample"`
-	HTTP         HTTPConfig         `json:"http" yaml:"http"`
-	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
-	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
-	JQ           JQConfig           `json:"jq" yaml:"jq"`
-	JSON         JSONConfig         `json:"json" yaml:"json"`
-	JSONSchema   JSONSchemaConfig   `json:"json_schema" yaml:"json_schema"`
-	Lambda       LambdaConfig       `json:"lambda" yaml:"lambda"`
-	Log          LogConfig          `json:"log" yaml:"log"`
-	MergeJSON    MergeJSONConfig    `json:"merge_json" yaml:"merge_json"`
-	Metadata     MetadataConfig     `json:"metadata" yaml:"metadata"`
-	Metric       MetricConfig       `json:"metric" yaml:"metric"`
-	MongoDB      MongoDBConfig      `json:"mongodb" yaml:"mongodb"`
-	Noop         NoopConfig         `json:"noop" yaml:"noop"`
-	Number       NumberConfig       `json:"number" yaml:"number"`
-	Plugin       interface{}        `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	Parallel     ParallelConfig     `json:"parallel" yaml:"parallel"`
-	ParseLog     ParseLogConfig     `json:"parse_log" yaml:"parse_log"`
-	ProcessBatch ForEachConfig      `json:"process_batch" yaml:"process_batch"`
-	ProcessDAG   ProcessDAGConfig   `json:"process_dag" yaml:"process_dag"`
-	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
-	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
-	Protobuf     ProtobufConfig     `json:"protobuf" yaml:"protobuf"`
-	RateLimit    RateLimitConfig    `json:"rate_limit" yaml:"rate_limit"`
-	Redis        RedisConfig        `json:"redis" yaml:"redis"`
-	Resource     string             `json:"resource" yaml:"resource"`
-	Sample       SampleConfig       `json:"sample" yaml:"sample"`
-	SelectParts  SelectPartsConfig  `json:"select_parts" yaml:"select_parts"`
-	Sleep        SleepConfig        `json:"sleep" yaml:"sleep"`
-	Split        SplitConfig        `json:"split" yaml:"split"`
-	SQL          SQLConfig          `json:"sql" yaml:"sql"`
-	Subprocess   SubprocessConfig   `json:"subprocess" yaml:"subprocess"`
-	Switch       SwitchConfig       `json:"switch" yaml:"switch"`
-	SyncResponse SyncResponseConfig `json:"sync_response" yaml:"sync_response"`
-	Text         TextConfig         `json:"text" yaml:"text"`
-	Try          TryConfig          `json:"try" yaml:"try"`
-	Throttle     ThrottleConfig     `json:"throttle" yaml:"throttle"`
-	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
-	While        WhileConfig        `json:"while" yaml:"while"`
-	Workflow     WorkflowConfig     `json:"workflow" yaml:"workflow"`
-	XML          XMLConfig          `json:"xml" yaml:"xml"`
+	Label          string               `json:"label" yaml:"label"`
+	Type           string               `json:"type" yaml:"type"`
+	AnomalyDetect  AnomalyDetectConfig  `json:"anomaly_detect" yaml:"anomaly_detect"`
+	Archive        ArchiveConfig        `json:"archive" yaml:"archive"`
+	ArtificialLoad ArtificialLoadConfig `json:"artificial_load" yaml:"artificial_load"`
+	Avro           AvroConfig           `json:"avro" yaml:"avro"`
+	AWK            AWKConfig            `json:"awk" yaml:"awk"`
+	AWSComprehend  AWSComprehendConfig  `json:"aws_comprehend" yaml:"aws_comprehend"`
+	AWSLambda      LambdaConfig         `json:"aws_lambda" yaml:"aws_lambda"`
+	AWSTranslate   AWSTranslateConfig   `json:"aws_translate" yaml:"aws_translate"`
+	Batch          BatchConfig          `json:"batch" yaml:"batch"`
+	BatchMapping   BatchMappingConfig   `json:"batch_mapping" yaml:"batch_mapping"`
+	Bloblang       BloblangConfig       `json:"bloblang" yaml:"bloblang"`
+	BoundsCheck    BoundsCheckConfig    `json:"bounds_check" yaml:"bounds_check"`
+	Branch         BranchConfig         `json:"branch" yaml:"branch"`
+	Cache          CacheConfig          `json:"cache" yaml:"cache"`
+	Catch          CatchConfig          `json:"catch" yaml:"catch"`
+	CatchSwitch    CatchSwitchConfig    `json:"catch_switch" yaml:"catch_switch"`
+	Command        CommandConfig        `json:"command" yaml:"command"`
+	Compress       CompressConfig       `json:"compress" yaml:"compress"`
+	Conditional    ConditionalConfig    `json:"conditional" yaml:"conditional"`
+	Couchbase      CouchbaseConfig      `json:"couchbase" yaml:"couchbase"`
+	Decode         DecodeConfig         `json:"decode" yaml:"decode"`
+	Decompress     DecompressConfig     `json:"decompress" yaml:"decompress"`
+	Dedupe         DedupeConfig         `json:"dedupe" yaml:"dedupe"`
+	EDI            EDIConfig            `json:"edi" yaml:"edi"`
+	Encode         EncodeConfig         `json:"encode" yaml:"encode"`
+	FaultInjection FaultInjectionConfig `json:"fault_injection" yaml:"fault_injection"`
+	Filter         FilterConfig         `json:"filter" yaml:"filter"`
+	FilterParts    FilterPartsConfig    `json:"filter_parts" yaml:"filter_parts"`
+	ForEach        ForEachConfig        `json:"for_each" yaml:"for_each"`
+	ForEachOptions ForEachOptionsConfig `json:"-" yaml:"-"`
+	GeoIP          GeoIPConfig          `json:"geoip" yaml:"geoip"`
+	Grok           GrokConfig           `json:"grok" yaml:"grok"`
+	GroupBy        GroupByConfig        `json:"group_by" yaml:"group_by"`
+	GroupByValue   GroupByValueConfig   `json:"group_by_value" yaml:"group_by_value"`
+	Hash           HashConfig           `json:"hash" yaml:"hash"`
+	HashSample     HashSampleConfig     `json:"hash_sample" yaml:"hash_sample"`
+	HL7            HL7Config            `json:"hl7" yaml:"hl7"`
+	HTTP           HTTPConfig           `json:"http" yaml:"http"`
+	InsertPart     InsertPartConfig     `json:"insert_part" yaml:"insert_part"`
+	JMESPath       JMESPathConfig       `json:"jmespath" yaml:"jmespath"`
+	JQ             JQConfig             `json:"jq" yaml:"jq"`
+	JSON           JSONConfig           `json:"json" yaml:"json"`
+	JSONSchema     JSONSchemaConfig     `json:"json_schema" yaml:"json_schema"`
+	Lambda         LambdaConfig         `json:"lambda" yaml:"lambda"`
+	Log            LogConfig            `json:"log" yaml:"log"`
+	MergeJSON      MergeJSONConfig      `json:"merge_json" yaml:"merge_json"`
+	Metadata       MetadataConfig       `json:"metadata" yaml:"metadata"`
+	Metric         MetricConfig         `json:"metric" yaml:"metric"`
+	MongoDB        MongoDBConfig        `json:"mongodb" yaml:"mongodb"`
+	Multiline      MultilineConfig      `json:"multiline" yaml:"multiline"`
+	Noop           NoopConfig           `json:"noop" yaml:"noop"`
+	OpenAI         OpenAIConfig         `json:"openai" yaml:"openai"`
+	Number         NumberConfig         `json:"number" yaml:"number"`
+	Plugin         interface{}          `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Parallel       ParallelConfig       `json:"parallel" yaml:"parallel"`
+	ParquetDecode  ParquetDecodeConfig  `json:"parquet_decode" yaml:"parquet_decode"`
+	ParquetEncode  ParquetEncodeConfig  `json:"parquet_encode" yaml:"parquet_encode"`
+	ParseLog       ParseLogConfig       `json:"parse_log" yaml:"parse_log"`
+	ProcessBatch   ForEachConfig        `json:"process_batch" yaml:"process_batch"`
+	ProcessDAG     ProcessDAGConfig     `json:"process_dag" yaml:"process_dag"`
+	ProcessField   ProcessFieldConfig   `json:"process_field" yaml:"process_field"`
+	ProcessMap     ProcessMapConfig     `json:"process_map" yaml:"process_map"`
+	Protobuf       ProtobufConfig       `json:"protobuf" yaml:"protobuf"`
+	RateLimit      RateLimitConfig      `json:"rate_limit" yaml:"rate_limit"`
+	Record         RecordConfig         `json:"record" yaml:"record"`
+	Redis          RedisConfig          `json:"redis" yaml:"redis"`
+	Resource       string               `json:"resource" yaml:"resource"`
+	Sample         SampleConfig         `json:"sample" yaml:"sample"`
+	SchemaDrift    SchemaDriftConfig    `json:"schema_drift" yaml:"schema_drift"`
+	SelectParts    SelectPartsConfig    `json:"select_parts" yaml:"select_parts"`
+	Sleep          SleepConfig          `json:"sleep" yaml:"sleep"`
+	Split          SplitConfig          `json:"split" yaml:"split"`
+	SQL            SQLConfig            `json:"sql" yaml:"sql"`
+	Subprocess     SubprocessConfig     `json:"subprocess" yaml:"subprocess"`
+	Switch         SwitchConfig         `json:"switch" yaml:"switch"`
+	SyncResponse   SyncResponseConfig   `json:"sync_response" yaml:"sync_response"`
+	Text           TextConfig           `json:"text" yaml:"text"`
+	Try            TryConfig            `json:"try" yaml:"try"`
+	Throttle       ThrottleConfig       `json:"throttle" yaml:"throttle"`
+	Unarchive      UnarchiveConfig      `json:"unarchive" yaml:"unarchive"`
+	UserAgent      UserAgentConfig      `json:"user_agent" yaml:"user_agent"`
+	Validate       ValidateConfig       `json:"validate" yaml:"validate"`
+	While          WhileConfig          `json:"while" yaml:"while"`
+	Workflow       WorkflowConfig       `json:"workflow" yaml:"workflow"`
+	XML            XMLConfig            `json:"xml" yaml:"xml"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Label:        "",
-		Type:         "bounds_check",
-		Archive:      NewArchiveConfig(),
-		Avro:         NewAvroConfig(),
-		AWK:          NewAWKConfig(),
-		AWSLambda:    NewLambdaConfig(),
-		Batch:        NewBatchConfig(),
-		Bloblang:     NewBloblangConfig(),
-		BoundsCheck:  NewBoundsCheckConfig(),
-		Branch:       NewBranchConfig(),
-		Cache:        NewCacheConfig(),
-		Catch:        NewCatchConfig(),
-		Compress:     NewCompressConfig(),
-		Conditional:  NewConditionalConfig(),
-		Decode:       NewDecodeConfig(),
-		Decompress:   NewDecompressConfig(),
-		Dedupe:       NewDedupeConfig(),
-		Encode:       NewEncodeConfig(),
-		Filter:       NewFilterConfig(),
-		FilterParts:  NewFilterPartsConfig(),
-		ForEach:      NewForEachConfig(),
-		Grok:         NewGrokConfig(),
-		GroupBy:      NewGroupByConfig(),
-		GroupByValue: NewGroupByValueConfig(),
-		Hash:         NewHashConfig(),
-		HashSample:   NewHashSampleConfig(),
-		HTTP:         NewHTTPConfig(),
-		InsertPart:   NewInsertPartConfig(),
-		JMESPath:     NewJMESPathConfig(),
-		JQ:           NewJQConfig(),
-		JSON:         NewJSONConfig(),
-		JSONSchema:   NewJSONSchemaConfig(),
-		Lambda:       NewLambdaConfig(),
-		Log:          NewLogConfig(),
-		MergeJSON:    NewMergeJSONConfig(),
-		Metadata:     NewMetadataConfig(),
-		Metric:       NewMetricConfig(),
-		MongoDB:      NewMongoDBConfig(),
-		Noop:         NewNoopConfig(),
-		Number:       NewNumberConfig(),
-		Plugin:       nil,
-		Parallel:     NewParallelConfig(),
-		ParseLog:     NewParseLogConfig(),
-		ProcessBatch: NewForEachConfig(),
-		ProcessDAG:   NewProcessDAGConfig(),
-		ProcessField: NewProcessFieldConfig(),
-		ProcessMap:   NewProcessMapConfig(),
-		Protobuf:     NewProtobufConfig(),
-		RateLimit:    NewRateLimitConfig(),
-		Redis:        NewRedisConfig(),
-		Resource:     "",
-		Sample:       NewSampleConfig(),
-		SelectParts:  NewSelectPartsConfig(),
-		Sleep:        NewSleepConfig(),
-		Split:        NewSplitConfig(),
-		SQL:          NewSQLConfig(),
-		Subprocess:   NewSubprocessConfig(),
-		Switch:       NewSwitchConfig(),
-		SyncResponse: NewSyncResponseConfig(),
-		Text:         NewTextConfig(),
-		Try:          NewTryConfig(),
-		Throttle:     NewThrottleConfig(),
-		Unarchive:    NewUnarchiveConfig(),
-		While:        NewWhileConfig(),
-		Workflow:     NewWorkflowConfig(),
-		XML:          NewXMLConfig(),
+		Label:          "",
+		Type:           "bounds_check",
+		AnomalyDetect:  NewAnomalyDetectConfig(),
+		Archive:        NewArchiveConfig(),
+		ArtificialLoad: NewArtificialLoadConfig(),
+		Avro:           NewAvroConfig(),
+		AWK:            NewAWKConfig(),
+		AWSComprehend:  NewAWSComprehendConfig(),
+		AWSLambda:      NewLambdaConfig(),
+		AWSTranslate:   NewAWSTranslateConfig(),
+		Batch:          NewBatchConfig(),
+		BatchMapping:   NewBatchMappingConfig(),
+		Bloblang:       NewBloblangConfig(),
+		BoundsCheck:    NewBoundsCheckConfig(),
+		Branch:         NewBranchConfig(),
+		Cache:          NewCacheConfig(),
+		Catch:          NewCatchConfig(),
+		CatchSwitch:    NewCatchSwitchConfig(),
+		Command:        NewCommandConfig(),
+		Compress:       NewCompressConfig(),
+		Conditional:    NewConditionalConfig(),
+		Couchbase:      NewCouchbaseConfig(),
+		Decode:         NewDecodeConfig(),
+		Decompress:     NewDecompressConfig(),
+		Dedupe:         NewDedupeConfig(),
+		EDI:            NewEDIConfig(),
+		Encode:         NewEncodeConfig(),
+		FaultInjection: NewFaultInjectionConfig(),
+		Filter:         NewFilterConfig(),
+		FilterParts:    NewFilterPartsConfig(),
+		ForEach:        NewForEachConfig(),
+		ForEachOptions: NewForEachOptionsConfig(),
+		GeoIP:          NewGeoIPConfig(),
+		Grok:           NewGrokConfig(),
+		GroupBy:        NewGroupByConfig(),
+		GroupByValue:   NewGroupByValueConfig(),
+		Hash:           NewHashConfig(),
+		HashSample:     NewHashSampleConfig(),
+		HL7:            NewHL7Config(),
+		HTTP:           NewHTTPConfig(),
+		InsertPart:     NewInsertPartConfig(),
+		JMESPath:       NewJMESPathConfig(),
+		JQ:             NewJQConfig(),
+		JSON:           NewJSONConfig(),
+		JSONSchema:     NewJSONSchemaConfig(),
+		Lambda:         NewLambdaConfig(),
+		Log:            NewLogConfig(),
+		MergeJSON:      NewMergeJSONConfig(),
+		Metadata:       NewMetadataConfig(),
+		Metric:         NewMetricConfig(),
+		MongoDB:        NewMongoDBConfig(),
+		Multiline:      NewMultilineConfig(),
+		Noop:           NewNoopConfig(),
+		OpenAI:         NewOpenAIConfig(),
+		Number:         NewNumberConfig(),
+		Plugin:         nil,
+		Parallel:       NewParallelConfig(),
+		ParquetDecode:  NewParquetDecodeConfig(),
+		ParquetEncode:  NewParquetEncodeConfig(),
+		ParseLog:       NewParseLogConfig(),
+		ProcessBatch:   NewForEachConfig(),
+		ProcessDAG:     NewProcessDAGConfig(),
+		ProcessField:   NewProcessFieldConfig(),
+		ProcessMap:     NewProcessMapConfig(),
+		Protobuf:       NewProtobufConfig(),
+		RateLimit:      NewRateLimitConfig(),
+		Record:         NewRecordConfig(),
+		Redis:          NewRedisConfig(),
+		Resource:       "",
+		Sample:         NewSampleConfig(),
+		SchemaDrift:    NewSchemaDriftConfig(),
+		SelectParts:    NewSelectPartsConfig(),
+		Sleep:          NewSleepConfig(),
+		Split:          NewSplitConfig(),
+		SQL:            NewSQLConfig(),
+		Subprocess:     NewSubprocessConfig(),
+		Switch:         NewSwitchConfig(),
+		SyncResponse:   NewSyncResponseConfig(),
+		Text:           NewTextConfig(),
+		Try:            NewTryConfig(),
+		Throttle:       NewThrottleConfig(),
+		Unarchive:      NewUnarchiveConfig(),
+		UserAgent:      NewUserAgentConfig(),
+		Validate:       NewValidateConfig(),
+		While:          NewWhileConfig(),
+		Workflow:       NewWorkflowConfig(),
+		XML:            NewXMLConfig(),
 	}
 }
 
@@ -369,13 +431,34 @@ func (conf Config) Sanitised(removeDeprecated bool) (interface{}, error) {
 
 //------------------------------------------------------------------------------
 
+// MarshalYAML encodes the config with the for_each field in its object form so
+// that the options of the processor are retained.
+func (conf Config) MarshalYAML() (interface{}, error) {
+	type confAlias Config
+
+	var node yaml.Node
+	if err := node.Encode(confAlias(conf)); err != nil {
+		return nil, err
+	}
+	if err := forEachListToNode(&node, conf.ForEach, conf.ForEachOptions); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
 // UnmarshalYAML ensures that when parsing configs that are in a slice the
 // default values are still applied.
 func (conf *Config) UnmarshalYAML(value *yaml.Node) error {
 	type confAlias Config
 	aliased := confAlias(NewConfig())
 
-	err := value.Decode(&aliased)
+	listValue, forEachOpts, err := forEachNodeToList(value)
+	if err != nil {
+		return err
+	}
+	aliased.ForEachOptions = forEachOpts
+
+	err = listValue.Decode(&aliased)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "line ") {
 			return err
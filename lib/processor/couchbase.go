@@ -0,0 +1,407 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/couchbase"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeCouchbase] = TypeSpec{
+		constructor: NewCouchbase,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Performs key value and sub-document operations on the documents of a
+[Couchbase](https://www.couchbase.com/) bucket for each message.`,
+		Description: `
+The document of each message is identified by the interpolated field ` + "`id`" + `, and the result of the operation is stored in the metadata field ` + "`couchbase_cas`" + ` as the CAS (compare and swap) value of the document.
+
+Messages that fail an operation, for example because the document does not exist, continue through the pipeline with their contents unchanged but flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling). In order to map the result of a ` + "`get`" + ` or ` + "`lookup_in`" + ` operation into the original message instead of replacing it entirely you can use the ` + "[`branch` processor](/docs/components/processors/branch)" + `.
+
+## Operations
+
+### ` + "`get`" + `
+
+Replaces the contents of the message with the document.
+
+### ` + "`upsert`, `insert` and `replace`" + `
+
+Writes the result of the interpolated field ` + "`content`" + ` as the document, leaving the message unchanged. An ` + "`insert`" + ` fails when the document already exists and a ` + "`replace`" + ` fails when it does not.
+
+### ` + "`remove`" + `
+
+Deletes the document, leaving the message unchanged.
+
+### ` + "`lookup_in`" + `
+
+Reads the paths of the ` + "`subdoc`" + ` specs from the document, replacing the contents of the message with an object of the results keyed by their paths. Paths that do not exist are omitted from the result, except for ` + "`exists`" + ` lookups, which result in a boolean.
+
+### ` + "`mutate_in`" + `
+
+Applies the ` + "`subdoc`" + ` specs to the document atomically, leaving the message unchanged. Intermediate paths are created as needed, and the document itself is created when ` + "`create_document`" + ` is ` + "`true`" + `.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Enrich From Profiles",
+				Summary: "This example uses a [`branch` processor](/docs/components/processors/branch/) to fetch the name and tier of the customer of each order from their profile document.",
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - couchbase:
+              url: couchbase://localhost
+              bucket: profiles
+              username: benthos
+              password: ${CB_PASSWORD}
+              operation: lookup_in
+              id: customer::${! json("customer_id") }
+              subdoc:
+                - op: get
+                  path: name
+                - op: get
+                  path: tier
+        result_map: |
+          root.customer = this
+`,
+			},
+			{
+				Title:   "Counting Visits",
+				Summary: "This example increments a visit counter and appends the page view object of each event to the session document, creating the document when it does not exist.",
+				Config: `
+pipeline:
+  processors:
+    - couchbase:
+        url: couchbase://localhost
+        bucket: sessions
+        username: benthos
+        password: ${CB_PASSWORD}
+        operation: mutate_in
+        id: ${! json("session_id") }
+        create_document: true
+        expiry: 30m
+        subdoc:
+          - op: counter
+            path: visits
+            value: "1"
+          - op: array_append
+            path: page_views
+            value: ${! json("page_view") }
+`,
+			},
+		},
+		FieldSpecs: couchbase.FieldSpecs().Add(
+			docs.FieldCommon("operation", "The [operation](#operations) to perform on the document of each message.").HasOptions(
+				"get", "upsert", "insert", "replace", "remove", "lookup_in", "mutate_in",
+			),
+			docs.FieldCommon("id", "The ID of the document of each message.", `${! json("id") }`, `${! meta("kafka_key") }`).IsInterpolated(),
+			docs.FieldCommon("content", "The document to write for the operations `upsert`, `insert` and `replace`.").IsInterpolated(),
+			docs.FieldCommon("subdoc", "A list of sub-document operations for the operations `lookup_in` and `mutate_in`.").Array().WithChildren(
+				docs.FieldCommon(
+					"op", "The sub-document operation, where `lookup_in` supports `get`, `exists` and `count`, and `mutate_in` supports `insert`, `upsert`, `replace`, `remove`, `array_append`, `array_prepend`, `array_add_unique` and `counter`.",
+				).HasType(docs.FieldString).HasDefault(""),
+				docs.FieldCommon("path", "The path within the document.", "name", "address.city", "tags[0]").HasType(docs.FieldString).HasDefault(""),
+				docs.FieldCommon("value", "A JSON value for mutations, where `counter` expects an integer delta.", `"foo"`, `${! json("page_view") }`).HasType(docs.FieldString).HasDefault("").IsInterpolated(),
+			),
+			docs.FieldAdvanced("create_document", "Whether `mutate_in` operations should create the document when it does not exist."),
+			docs.FieldAdvanced("expiry", "An optional period after which written documents expire.", "1h", "30m"),
+			PartsFieldSpec,
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CouchbaseSubdocConfig contains configuration fields of a sub-document
+// operation of the Couchbase processor.
+type CouchbaseSubdocConfig struct {
+	Op    string `json:"op" yaml:"op"`
+	Path  string `json:"path" yaml:"path"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// CouchbaseConfig contains configuration fields for the Couchbase processor.
+type CouchbaseConfig struct {
+	couchbase.Config `json:",inline" yaml:",inline"`
+	Parts            []int                   `json:"parts" yaml:"parts"`
+	Operation        string                  `json:"operation" yaml:"operation"`
+	ID               string                  `json:"id" yaml:"id"`
+	Content          string                  `json:"content" yaml:"content"`
+	Subdoc           []CouchbaseSubdocConfig `json:"subdoc" yaml:"subdoc"`
+	CreateDocument   bool                    `json:"create_document" yaml:"create_document"`
+	Expiry           string                  `json:"expiry" yaml:"expiry"`
+}
+
+// NewCouchbaseConfig returns a CouchbaseConfig with default values.
+func NewCouchbaseConfig() CouchbaseConfig {
+	return CouchbaseConfig{
+		Config:         couchbase.NewConfig(),
+		Parts:          []int{},
+		Operation:      "get",
+		ID:             "",
+		Content:        "${! content() }",
+		Subdoc:         []CouchbaseSubdocConfig{},
+		CreateDocument: false,
+		Expiry:         "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// couchbaseClient is the subset of the Couchbase client used by the processor.
+type couchbaseClient interface {
+	Get(ctx context.Context, key string) ([]byte, uint64, error)
+	Upsert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error)
+	Insert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error)
+	Replace(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error)
+	Remove(ctx context.Context, key string) (uint64, error)
+	LookupIn(ctx context.Context, key string, specs []couchbase.LookupSpec) ([]couchbase.LookupResult, uint64, error)
+	MutateIn(ctx context.Context, key string, specs []couchbase.MutateSpec, upsertDoc bool, expiry time.Duration) (uint64, error)
+	Close() error
+}
+
+type couchbaseSubdoc struct {
+	op    string
+	path  string
+	value *field.Expression
+}
+
+// Couchbase is a processor that performs operations on the documents of a
+// Couchbase bucket.
+type Couchbase struct {
+	parts     []int
+	operation string
+	id        *field.Expression
+	content   *field.Expression
+	subdoc    []couchbaseSubdoc
+	createDoc bool
+	expiry    time.Duration
+	client    couchbaseClient
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewCouchbase returns a Couchbase processor.
+func NewCouchbase(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	client, err := couchbase.NewClient(conf.Couchbase.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newCouchbase(conf, client, log, stats)
+}
+
+func newCouchbase(
+	conf Config, client couchbaseClient, log log.Modular, stats metrics.Type,
+) (*Couchbase, error) {
+	cConf := conf.Couchbase
+	p := &Couchbase{
+		parts:     cConf.Parts,
+		operation: cConf.Operation,
+		createDoc: cConf.CreateDocument,
+		client:    client,
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	if cConf.ID == "" {
+		return nil, errors.New("an id must be specified")
+	}
+	if p.id, err = bloblang.NewField(cConf.ID); err != nil {
+		return nil, fmt.Errorf("failed to parse id expression: %v", err)
+	}
+	if p.content, err = bloblang.NewField(cConf.Content); err != nil {
+		return nil, fmt.Errorf("failed to parse content expression: %v", err)
+	}
+	if cConf.Expiry != "" {
+		if p.expiry, err = time.ParseDuration(cConf.Expiry); err != nil {
+			return nil, fmt.Errorf("failed to parse expiry string: %v", err)
+		}
+	}
+
+	switch p.operation {
+	case "get", "upsert", "insert", "replace", "remove":
+	case "lookup_in", "mutate_in":
+		if len(cConf.Subdoc) == 0 {
+			return nil, fmt.Errorf("at least one subdoc spec must be specified for the operation %v", p.operation)
+		}
+	default:
+		return nil, fmt.Errorf("operation not recognised: %v", p.operation)
+	}
+
+	for i, s := range cConf.Subdoc {
+		if p.operation == "lookup_in" {
+			err = couchbase.ValidateLookupOp(s.Op)
+		} else {
+			err = couchbase.ValidateMutateOp(s.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("subdoc spec %v: %v", i, err)
+		}
+		spec := couchbaseSubdoc{op: s.Op, path: s.Path}
+		if spec.value, err = bloblang.NewField(s.Value); err != nil {
+			return nil, fmt.Errorf("subdoc spec %v: failed to parse value expression: %v", i, err)
+		}
+		p.subdoc = append(p.subdoc, spec)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *Couchbase) lookupIn(ctx context.Context, id string) ([]byte, uint64, error) {
+	specs := make([]couchbase.LookupSpec, len(p.subdoc))
+	for i, s := range p.subdoc {
+		specs[i] = couchbase.LookupSpec{Op: couchbase.LookupOp(s.op), Path: s.path}
+	}
+	results, cas, err := p.client.LookupIn(ctx, id, specs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	obj := make(map[string]interface{}, len(results))
+	for i, r := range results {
+		if i >= len(p.subdoc) {
+			break
+		}
+		s := p.subdoc[i]
+		if couchbase.LookupOp(s.op) == couchbase.LookupExists {
+			obj[s.path] = r.Err == nil
+			continue
+		}
+		if r.Err == couchbase.ErrPathNotFound {
+			continue
+		}
+		if r.Err != nil {
+			return nil, 0, fmt.Errorf("lookup of path '%v' failed: %w", s.path, r.Err)
+		}
+		obj[s.path] = json.RawMessage(r.Value)
+	}
+	content, err := json.Marshal(obj)
+	if err != nil {
+		return nil, 0, err
+	}
+	return content, cas, nil
+}
+
+func (p *Couchbase) mutateIn(ctx context.Context, id string, index int, msg types.Message) (uint64, error) {
+	specs := make([]couchbase.MutateSpec, len(p.subdoc))
+	for i, s := range p.subdoc {
+		specs[i] = couchbase.MutateSpec{Op: couchbase.MutateOp(s.op), Path: s.path}
+		if couchbase.MutateOp(s.op) == couchbase.MutateRemove {
+			continue
+		}
+		value := s.value.Bytes(index, msg)
+		if !json.Valid(value) {
+			return 0, fmt.Errorf("value of path '%v' is not valid JSON: %s", s.path, value)
+		}
+		if couchbase.MutateOp(s.op) == couchbase.MutateCounter {
+			if _, err := strconv.ParseInt(string(value), 10, 64); err != nil {
+				return 0, fmt.Errorf("value of counter path '%v' is not an integer: %s", s.path, value)
+			}
+		}
+		specs[i].Value = value
+	}
+	return p.client.MutateIn(ctx, id, specs, p.createDoc, p.expiry)
+}
+
+func (p *Couchbase) processPart(index int, msg types.Message) error {
+	id := p.id.String(index, msg)
+	if id == "" {
+		return errors.New("id is empty")
+	}
+
+	ctx := context.Background()
+	part := msg.Get(index)
+	var content []byte
+	var cas uint64
+	var err error
+	switch p.operation {
+	case "get":
+		content, cas, err = p.client.Get(ctx, id)
+	case "upsert":
+		cas, err = p.client.Upsert(ctx, id, p.content.Bytes(index, msg), p.expiry)
+	case "insert":
+		cas, err = p.client.Insert(ctx, id, p.content.Bytes(index, msg), p.expiry)
+	case "replace":
+		cas, err = p.client.Replace(ctx, id, p.content.Bytes(index, msg), p.expiry)
+	case "remove":
+		cas, err = p.client.Remove(ctx, id)
+	case "lookup_in":
+		content, cas, err = p.lookupIn(ctx, id)
+	case "mutate_in":
+		cas, err = p.mutateIn(ctx, id, index, msg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if content != nil {
+		part.Set(content)
+	}
+	part.Metadata().Set("couchbase_cas", strconv.FormatUint(cas, 10))
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Couchbase) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.processPart(index, newMsg); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Couchbase %v operation failed: %v\n", p.operation, err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeCouchbase, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Couchbase) CloseAsync() {
+	p.client.Close()
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Couchbase) WaitForClose(timeout time.Duration) error {
+	return nil
+}
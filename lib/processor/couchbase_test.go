@@ -0,0 +1,253 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/couchbase"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCouchbase struct {
+	docs    map[string][]byte
+	cas     uint64
+	expiry  time.Duration
+	mutates [][]couchbase.MutateSpec
+}
+
+func (m *mockCouchbase) store(key string, value []byte, expiry time.Duration) uint64 {
+	m.cas++
+	m.docs[key] = value
+	m.expiry = expiry
+	return m.cas
+}
+
+func (m *mockCouchbase) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	v, exists := m.docs[key]
+	if !exists {
+		return nil, 0, couchbase.ErrKeyNotFound
+	}
+	return v, m.cas, nil
+}
+
+func (m *mockCouchbase) Upsert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	return m.store(key, value, expiry), nil
+}
+
+func (m *mockCouchbase) Insert(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	if _, exists := m.docs[key]; exists {
+		return 0, couchbase.ErrKeyExists
+	}
+	return m.store(key, value, expiry), nil
+}
+
+func (m *mockCouchbase) Replace(ctx context.Context, key string, value []byte, expiry time.Duration) (uint64, error) {
+	if _, exists := m.docs[key]; !exists {
+		return 0, couchbase.ErrKeyNotFound
+	}
+	return m.store(key, value, expiry), nil
+}
+
+func (m *mockCouchbase) Remove(ctx context.Context, key string) (uint64, error) {
+	if _, exists := m.docs[key]; !exists {
+		return 0, couchbase.ErrKeyNotFound
+	}
+	delete(m.docs, key)
+	m.cas++
+	return m.cas, nil
+}
+
+func (m *mockCouchbase) LookupIn(ctx context.Context, key string, specs []couchbase.LookupSpec) ([]couchbase.LookupResult, uint64, error) {
+	v, exists := m.docs[key]
+	if !exists {
+		return nil, 0, couchbase.ErrKeyNotFound
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(v, &obj); err != nil {
+		return nil, 0, err
+	}
+	results := make([]couchbase.LookupResult, len(specs))
+	for i, s := range specs {
+		pv, exists := obj[s.Path]
+		if !exists {
+			results[i].Err = couchbase.ErrPathNotFound
+		} else if s.Op == couchbase.LookupGet {
+			results[i].Value = pv
+		}
+	}
+	return results, m.cas, nil
+}
+
+func (m *mockCouchbase) MutateIn(ctx context.Context, key string, specs []couchbase.MutateSpec, upsertDoc bool, expiry time.Duration) (uint64, error) {
+	if _, exists := m.docs[key]; !exists && !upsertDoc {
+		return 0, couchbase.ErrKeyNotFound
+	}
+	m.mutates = append(m.mutates, specs)
+	m.cas++
+	return m.cas, nil
+}
+
+func (m *mockCouchbase) Close() error {
+	return nil
+}
+
+func TestCouchbaseKV(t *testing.T) {
+	client := &mockCouchbase{docs: map[string][]byte{}}
+
+	newProc := func(operation string) *Couchbase {
+		t.Helper()
+		conf := NewConfig()
+		conf.Couchbase.Operation = operation
+		conf.Couchbase.ID = `${! json("id") }`
+		conf.Couchbase.Content = `${! json("doc") }`
+		conf.Couchbase.Expiry = "1h"
+		proc, err := newCouchbase(conf, client, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		return proc
+	}
+
+	msgs, res := newProc("insert").ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo","doc":{"a":1}}`),
+		[]byte(`{"id":"foo","doc":{"a":2}}`),
+		[]byte(`{"id":"","doc":{"a":3}}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"id":"foo","doc":{"a":1}}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, "1", msgs[0].Get(0).Metadata().Get("couchbase_cas"))
+	assert.Equal(t, couchbase.ErrKeyExists.Error(), GetFail(msgs[0].Get(1)))
+	assert.Equal(t, "id is empty", GetFail(msgs[0].Get(2)))
+	assert.Equal(t, `{"a":1}`, string(client.docs["foo"]))
+	assert.Equal(t, time.Hour, client.expiry)
+
+	msgs, _ = newProc("replace").ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo","doc":{"a":4}}`),
+		[]byte(`{"id":"bar","doc":{"a":5}}`),
+	}))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, couchbase.ErrKeyNotFound.Error(), GetFail(msgs[0].Get(1)))
+
+	msgs, _ = newProc("upsert").ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"bar","doc":{"a":6}}`),
+	}))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+
+	msgs, _ = newProc("get").ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"bar"}`),
+		[]byte(`{"id":"baz"}`),
+	}))
+	assert.Equal(t, `{"a":4}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "3", msgs[0].Get(0).Metadata().Get("couchbase_cas"))
+	assert.Equal(t, `{"a":6}`, string(msgs[0].Get(1).Get()))
+	assert.Equal(t, `{"id":"baz"}`, string(msgs[0].Get(2).Get()))
+	assert.Equal(t, couchbase.ErrKeyNotFound.Error(), GetFail(msgs[0].Get(2)))
+
+	msgs, _ = newProc("remove").ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"foo"}`),
+	}))
+	assert.Equal(t, `{"id":"foo"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, couchbase.ErrKeyNotFound.Error(), GetFail(msgs[0].Get(1)))
+	assert.NotContains(t, client.docs, "foo")
+}
+
+func TestCouchbaseSubdoc(t *testing.T) {
+	client := &mockCouchbase{docs: map[string][]byte{
+		"profile": []byte(`{"name":"foo","tier":"gold","tags":["a"]}`),
+	}}
+
+	conf := NewConfig()
+	conf.Couchbase.Operation = "lookup_in"
+	conf.Couchbase.ID = `${! content() }`
+	conf.Couchbase.Subdoc = []CouchbaseSubdocConfig{
+		{Op: "get", Path: "name"},
+		{Op: "get", Path: "missing"},
+		{Op: "exists", Path: "tier"},
+		{Op: "exists", Path: "nope"},
+	}
+	proc, err := newCouchbase(conf, client, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("profile")}))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, `{"name":"foo","nope":false,"tier":true}`, string(msgs[0].Get(0).Get()))
+
+	conf = NewConfig()
+	conf.Couchbase.Operation = "mutate_in"
+	conf.Couchbase.ID = `${! json("id") }`
+	conf.Couchbase.CreateDocument = true
+	conf.Couchbase.Subdoc = []CouchbaseSubdocConfig{
+		{Op: "counter", Path: "visits", Value: `${! json("n") }`},
+		{Op: "array_append", Path: "pages", Value: `${! json("page") }`},
+		{Op: "remove", Path: "old"},
+	}
+	proc, err = newCouchbase(conf, client, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"session","n":1,"page":{"path":"/home"}}`),
+		[]byte(`{"id":"session","n":1.5,"page":{"path":"/home"}}`),
+	}))
+	assert.Equal(t, "", GetFail(msgs[0].Get(0)))
+	assert.Contains(t, GetFail(msgs[0].Get(1)), "is not an integer")
+
+	require.Len(t, client.mutates, 1)
+	assert.Equal(t, []couchbase.MutateSpec{
+		{Op: couchbase.MutateCounter, Path: "visits", Value: []byte(`1`)},
+		{Op: couchbase.MutateArrayAppend, Path: "pages", Value: []byte(`{"path":"/home"}`)},
+		{Op: couchbase.MutateRemove, Path: "old"},
+	}, client.mutates[0])
+}
+
+func TestCouchbaseBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *CouchbaseConfig)
+		err  string
+	}{
+		"no id": {
+			conf: func(c *CouchbaseConfig) {},
+			err:  "an id must be specified",
+		},
+		"bad operation": {
+			conf: func(c *CouchbaseConfig) {
+				c.ID = "foo"
+				c.Operation = "nope"
+			},
+			err: "operation not recognised: nope",
+		},
+		"no specs": {
+			conf: func(c *CouchbaseConfig) {
+				c.ID = "foo"
+				c.Operation = "lookup_in"
+			},
+			err: "at least one subdoc spec must be specified for the operation lookup_in",
+		},
+		"bad spec op": {
+			conf: func(c *CouchbaseConfig) {
+				c.ID = "foo"
+				c.Operation = "lookup_in"
+				c.Subdoc = []CouchbaseSubdocConfig{{Op: "upsert", Path: "foo"}}
+			},
+			err: "subdoc spec 0: lookup operation not recognised: upsert",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			test.conf(&conf.Couchbase)
+			_, err := newCouchbase(conf, &mockCouchbase{}, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
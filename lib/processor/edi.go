@@ -0,0 +1,209 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/edi"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeEDI] = TypeSpec{
+		constructor: NewEDI,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryParsing,
+		},
+		Summary: `
+Parses messages as EDI documents of the X12 or EDIFACT standards and replaces
+them with a JSON structure of their envelopes and segments.`,
+		Description: `
+## Operators
+
+### ` + "`to_json`" + `
+
+Converts an EDI document into a JSON structure of its interchange envelope. The delimiters of X12 documents are detected from their ISA segment, and the delimiters of EDIFACT documents are detected from their UNA service string advice or the default delimiters are used when it is absent. The standard of a document is detected from its first segment when ` + "`standard` is set to `auto`" + `.
+
+Segments are converted into objects where elements are addressed by the segment ID followed by their two digit position, e.g. the third element of a ` + "`BEG`" + ` segment has the key ` + "`BEG03`" + `. Simple elements are strings, composite elements are arrays of strings, repeated elements are arrays of arrays of strings, and empty elements are omitted.
+
+For example, given the following X12 document:
+
+` + "```text" + `
+ISA*00*          *00*          *ZZ*SENDERID       *ZZ*RECEIVERID     *210101*1253*^*00501*000000001*0*P*:~
+GS*PO*SENDERID*RECEIVERID*20210101*1253*1*X*005010~
+ST*850*0001~
+BEG*00*SA*PO123**20210101~
+SV1*HC:99213*50~
+SE*4*0001~
+GE*1*1~
+IEA*1*000000001~
+` + "```" + `
+
+The resulting JSON structure would look like this, where the headers and trailers of envelopes have been shortened for brevity:
+
+` + "```json" + `
+{
+  "standard": "x12",
+  "delimiters": {"component":":","element":"*","repetition":"^","segment":"~"},
+  "interchange": {
+    "header": {"segment":"ISA","ISA01":"00","ISA06":"SENDERID","...":"..."},
+    "groups": [
+      {
+        "header": {"segment":"GS","GS01":"PO","...":"..."},
+        "transactions": [
+          {
+            "header": {"segment":"ST","ST01":"850","ST02":"0001"},
+            "segments": [
+              {"segment":"BEG","BEG01":"00","BEG02":"SA","BEG03":"PO123","BEG05":"20210101"},
+              {"segment":"SV1","SV101":["HC","99213"],"SV102":"50"}
+            ],
+            "trailer": {"segment":"SE","SE01":"4","SE02":"0001"}
+          }
+        ],
+        "trailer": {"segment":"GE","GE01":"1","GE02":"1"}
+      }
+    ],
+    "trailer": {"segment":"IEA","IEA01":"1","IEA02":"000000001"}
+  }
+}
+` + "```" + `
+
+EDIFACT documents result in the same structure, where messages are listed under the key ` + "`messages`" + ` of either their functional group or, when groups are not used, their interchange.
+
+When ` + "`validate_envelopes`" + ` is enabled the segment counts and control numbers within the trailers of envelopes are checked, and documents that fail the check are flagged with an error that can be handled using [error handling patterns](/docs/configuration/error_handling).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Purchase Order Lines",
+				Summary: "In this example we parse X12 850 purchase orders and emit a message for each line item of each transaction.",
+				Config: `
+pipeline:
+  processors:
+    - edi:
+        operator: to_json
+        standard: x12
+    - bloblang: |
+        root = this.interchange.groups.map_each(g -> g.transactions).flatten().map_each(tx -> tx.segments.filter(s -> s.segment == "PO1").map_each(line -> {
+          "po": tx.segments.filter(s -> s.segment == "BEG").index(0).BEG03,
+          "quantity": line.PO102.number(),
+          "price": line.PO104,
+        })).flatten()
+    - unarchive:
+        format: json_array
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("operator", "An EDI [operation](#operators) to apply to messages.").HasOptions("to_json"),
+			docs.FieldCommon("standard", "The standard of EDI documents.").HasOptions("auto", "x12", "edifact"),
+			docs.FieldAdvanced("validate_envelopes", "Whether to check the segment counts and control numbers within the trailers of envelopes."),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// EDIConfig contains configuration fields for the EDI processor.
+type EDIConfig struct {
+	Parts             []int  `json:"parts" yaml:"parts"`
+	Operator          string `json:"operator" yaml:"operator"`
+	Standard          string `json:"standard" yaml:"standard"`
+	ValidateEnvelopes bool   `json:"validate_envelopes" yaml:"validate_envelopes"`
+}
+
+// NewEDIConfig returns a EDIConfig with default values.
+func NewEDIConfig() EDIConfig {
+	return EDIConfig{
+		Parts:             []int{},
+		Operator:          "to_json",
+		Standard:          "auto",
+		ValidateEnvelopes: true,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// EDI is a processor that performs an operation on an EDI payload.
+type EDI struct {
+	parts []int
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewEDI returns an EDI processor.
+func NewEDI(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.EDI.Operator != "to_json" {
+		return nil, fmt.Errorf("operator not recognised: %v", conf.EDI.Operator)
+	}
+	switch conf.EDI.Standard {
+	case edi.StandardAuto, edi.StandardX12, edi.StandardEDIFACT:
+	default:
+		return nil, fmt.Errorf("standard not recognised: %v", conf.EDI.Standard)
+	}
+
+	return &EDI{
+		parts: conf.EDI.Parts,
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *EDI) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		root, err := edi.Parse(part.Get(), p.conf.EDI.Standard, p.conf.EDI.ValidateEnvelopes)
+		if err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to parse part as EDI: %v\n", err)
+			return err
+		}
+		if err = part.SetJSON(root); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to marshal EDI as JSON: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeEDI, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *EDI) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *EDI) WaitForClose(timeout time.Duration) error {
+	return nil
+}
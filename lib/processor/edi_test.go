@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEDIToJSON(t *testing.T) {
+	conf := NewConfig()
+	conf.EDI.Standard = "auto"
+
+	proc, err := NewEDI(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380+INV?'1'UNT+3+1'UNZ+1+9'`),
+		[]byte(`UNB+UNOA:1+A+B+210101:1253+9'UNH+1+INVOIC:D:96A:UN'BGM+380'UNT+5+1'UNZ+1+9'`),
+		[]byte(`not edi`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assert.Equal(t, `{"delimiters":{"component":":","element":"+","release":"?","repetition":"*","segment":"'"},"interchange":{"header":{"UNB01":["UNOA","1"],"UNB02":"A","UNB03":"B","UNB04":["210101","1253"],"UNB05":"9","segment":"UNB"},"messages":[{"header":{"UNH01":"1","UNH02":["INVOIC","D","96A","UN"],"segment":"UNH"},"segments":[{"BGM01":"380","BGM02":"INV'1","segment":"BGM"}],"trailer":{"UNT01":"3","UNT02":"1","segment":"UNT"}}],"trailer":{"UNZ01":"1","UNZ02":"9","segment":"UNZ"}},"standard":"edifact"}`, string(msgsOut[0].Get(0).Get()))
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(0)))
+
+	assert.Equal(t, "message UNT count 5 does not match the actual count 3", GetFail(msgsOut[0].Get(1)))
+	assert.Equal(t, "unable to detect the standard of the document", GetFail(msgsOut[0].Get(2)))
+	assert.Equal(t, "not edi", string(msgsOut[0].Get(2).Get()))
+}
+
+func TestEDIBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.EDI.Standard = "hl7"
+
+	_, err := NewEDI(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "standard not recognised: hl7")
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+// PayloadHash returns the hash of a message payload as recorded in the
+// structured metadata of processing errors.
+func PayloadHash(b []byte) string {
+	return fmt.Sprintf("%016x", xxhash.Checksum64(b))
+}
+
+// SetFailAttempts records the number of times a processor was attempted on a
+// failed message part.
+func SetFailAttempts(part types.Part, attempts int) {
+	part.Metadata().Set(types.FailAttemptsKey, strconv.Itoa(attempts))
+}
+
+//------------------------------------------------------------------------------
+
+// errorMetadata wraps a processor and annotates message parts that it fails
+// with structured metadata describing the failure.
+type errorMetadata struct {
+	source string
+	child  types.Processor
+}
+
+// WithErrorMetadata wraps a processor so that message parts that it fails are
+// annotated with structured metadata describing the failure, including the
+// source (the label or path of the processor), the class of error, the number
+// of attempts and a hash of the payload before the failure.
+//
+// Parts that were already annotated, usually by a nested child processor, are
+// left unchanged unless the error itself has changed.
+func WithErrorMetadata(source string, proc types.Processor) types.Processor {
+	return &errorMetadata{
+		source: source,
+		child:  proc,
+	}
+}
+
+// ProcessMessage executes the child processor and annotates failed parts.
+func (e *errorMetadata) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	beforeFails := make([]string, msg.Len())
+	beforeSources := make([]string, msg.Len())
+	beforeData := make([][]byte, msg.Len())
+	_ = msg.Iter(func(i int, p types.Part) error {
+		beforeFails[i] = GetFail(p)
+		beforeSources[i] = p.Metadata().Get(types.FailSourceKey)
+		beforeData[i] = p.Get()
+		return nil
+	})
+
+	msgs, res := e.child.ProcessMessage(msg)
+
+	for _, m := range msgs {
+		aligned := len(msgs) == 1 && m.Len() == len(beforeFails)
+		_ = m.Iter(func(i int, p types.Part) error {
+			fail := GetFail(p)
+			if fail == "" {
+				return nil
+			}
+			source := p.Metadata().Get(types.FailSourceKey)
+			if aligned {
+				// Ignore parts that had already failed before reaching this
+				// processor, or that were annotated by a child of it.
+				if fail == beforeFails[i] || source != beforeSources[i] {
+					return nil
+				}
+			} else if source != "" {
+				return nil
+			}
+			p.Metadata().Set(types.FailSourceKey, e.source)
+			SetFailAttempts(p, 1)
+			if aligned {
+				p.Metadata().Set(types.FailPayloadHashKey, PayloadHash(beforeData[i]))
+			} else {
+				p.Metadata().Set(types.FailPayloadHashKey, PayloadHash(p.Get()))
+			}
+			return nil
+		})
+	}
+	return msgs, res
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (e *errorMetadata) CloseAsync() {
+	e.child.CloseAsync()
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (e *errorMetadata) WaitForClose(timeout time.Duration) error {
+	return e.child.WaitForClose(timeout)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,186 @@
+package processor
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/faults"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFaultInjection] = TypeSpec{
+		constructor: NewFaultInjection,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Injects random delays, errors, duplicates and reordering into message batches in
+order to test the resilience of a pipeline.`,
+		Description: `
+This processor is intended for staging environments where you wish to verify
+that the error handling, retry and deduplication mechanisms of a pipeline behave
+as expected under failure. Each batch has a chance of being delayed and of
+having its messages shuffled, and each message of a batch has a chance of being
+flagged with an error and of being duplicated.
+
+Messages flagged with an error can be handled with
+[error handling patterns](/docs/configuration/error_handling). The sequence of
+injected faults can be reproduced by setting a non-zero ` + "`seed`" + ` and
+running the processor with a single pipeline thread.`,
+		FieldSpecs: faults.FieldSpecs(),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Testing Deduplication",
+				Summary: "In this example we duplicate a tenth of all messages before a deduplication step, which allows us to check that duplicates are removed.",
+				Config: `
+pipeline:
+  processors:
+    - fault_injection:
+        seed: 42
+        duplicate_probability: 0.1
+    - dedupe:
+        cache: keycache
+        key: ${! json("id") }
+
+cache_resources:
+  - label: keycache
+    memory:
+      ttl: 60
+`,
+			},
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FaultInjectionConfig contains configuration fields for the FaultInjection
+// processor.
+type FaultInjectionConfig struct {
+	faults.Config `json:",inline" yaml:",inline"`
+}
+
+// NewFaultInjectionConfig returns a FaultInjectionConfig with default values.
+func NewFaultInjectionConfig() FaultInjectionConfig {
+	return FaultInjectionConfig{
+		Config: faults.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FaultInjection is a processor that injects random faults into message
+// batches.
+type FaultInjection struct {
+	closed    int32
+	closeChan chan struct{}
+
+	faults *faults.Injector
+	log    log.Modular
+
+	mCount      metrics.StatCounter
+	mDelayed    metrics.StatCounter
+	mErrored    metrics.StatCounter
+	mDuplicated metrics.StatCounter
+	mReordered  metrics.StatCounter
+	mSent       metrics.StatCounter
+	mBatchSent  metrics.StatCounter
+}
+
+// NewFaultInjection returns a FaultInjection processor.
+func NewFaultInjection(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	injector, err := conf.FaultInjection.NewInjector()
+	if err != nil {
+		return nil, err
+	}
+	return &FaultInjection{
+		closeChan: make(chan struct{}),
+
+		faults: injector,
+		log:    log,
+
+		mCount:      stats.GetCounter("count"),
+		mDelayed:    stats.GetCounter("delayed"),
+		mErrored:    stats.GetCounter("errored"),
+		mDuplicated: stats.GetCounter("duplicated"),
+		mReordered:  stats.GetCounter("reordered"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (f *FaultInjection) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	f.mCount.Incr(1)
+
+	spans := tracing.CreateChildSpans(TypeFaultInjection, msg)
+	defer func() {
+		for _, span := range spans {
+			span.Finish()
+		}
+	}()
+
+	if delay := f.faults.Delay(); delay > 0 {
+		f.mDelayed.Incr(1)
+		select {
+		case <-time.After(delay):
+		case <-f.closeChan:
+		}
+	}
+
+	newMsg := msg.Copy()
+	parts := make([]types.Part, 0, newMsg.Len())
+	newMsg.Iter(func(i int, p types.Part) error {
+		if f.faults.Error() {
+			f.mErrored.Incr(1)
+			FlagErr(p, faults.ErrInjected)
+		}
+		parts = append(parts, p)
+		if f.faults.Duplicate() {
+			f.mDuplicated.Incr(1)
+			parts = append(parts, p.Copy())
+		}
+		return nil
+	})
+
+	if len(parts) > 1 && f.faults.Reorder() {
+		f.mReordered.Incr(1)
+		f.faults.Shuffle(len(parts), func(i, j int) {
+			parts[i], parts[j] = parts[j], parts[i]
+		})
+	}
+	newMsg.SetAll(parts)
+
+	f.mBatchSent.Incr(1)
+	f.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (f *FaultInjection) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&f.closed, 0, 1) {
+		close(f.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (f *FaultInjection) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
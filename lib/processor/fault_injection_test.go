@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjectionNoFaults(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeFaultInjection
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, message.GetAllBytes(input), message.GetAllBytes(msgs[0]))
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+}
+
+func TestFaultInjectionAllFaults(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeFaultInjection
+	conf.FaultInjection.Seed = 5
+	conf.FaultInjection.ErrorProbability = 1
+	conf.FaultInjection.DuplicateProbability = 1
+	conf.FaultInjection.ReorderProbability = 1
+	conf.FaultInjection.Delay.Probability = 1
+	conf.FaultInjection.Delay.Min = "1ms"
+	conf.FaultInjection.Delay.Max = "1ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	contents := []string{}
+	for _, b := range message.GetAllBytes(msgs[0]) {
+		contents = append(contents, string(b))
+	}
+	assert.ElementsMatch(t, []string{"foo", "foo", "bar", "bar"}, contents)
+	for i := 0; i < msgs[0].Len(); i++ {
+		assert.Equal(t, "injected fault", GetFail(msgs[0].Get(i)))
+	}
+
+	// The input batch is left unchanged.
+	assert.Equal(t, 2, input.Len())
+	assert.False(t, HasFailed(input.Get(0)))
+}
+
+func TestFaultInjectionBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeFaultInjection
+	conf.FaultInjection.DuplicateProbability = -1
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -84,3 +84,32 @@ func TestFilterPartsTextCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterPartsBloblangBatchAccess(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "filter_parts"
+	conf.FilterParts.Type = "bloblang"
+	conf.FilterParts.Bloblang = `batch_index() > 0 && this.type == json("keep").from(0)`
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, res := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"keep":"foo"}`),
+		[]byte(`{"type":"foo","id":1}`),
+		[]byte(`{"type":"bar","id":2}`),
+		[]byte(`{"type":"foo","id":3}`),
+	}))
+	if res != nil {
+		t.Fatalf("unexpected response: %v", res.Error())
+	}
+	exp := [][]byte{
+		[]byte(`{"type":"foo","id":1}`),
+		[]byte(`{"type":"foo","id":3}`),
+	}
+	if act := message.GetAllBytes(got[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
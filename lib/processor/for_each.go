@@ -2,8 +2,11 @@ package processor
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -11,6 +14,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -31,8 +35,54 @@ as the ` + "`value`" + ` field of the ` + "`metadata`" + ` processor to execute
 on individual message parts of a batch instead.
 
 Please note that most processors already process per message of a batch, and
-this processor is not needed in those cases.`,
-		config: docs.FieldComponent().Array().HasType(docs.FieldProcessor),
+this processor is not needed in those cases.
+
+The child processors can be configured either as a plain list, or as the field
+` + "`processors`" + ` of an object along with the fields below.
+
+### Parallelism
+
+By default messages are processed one at a time in order. The field
+` + "`parallelism`" + ` sets the maximum number of messages of a batch that are
+processed at the same time, the order of the resulting batch is preserved
+regardless.
+
+### Exiting Early
+
+The field ` + "`exit_check`" + ` is an optional
+[Bloblang query](/docs/guides/bloblang/about/) that is checked against the
+result of each message once processed. When it resolves to ` + "`true`" + ` the
+remaining messages of the batch are not processed and are instead added to the
+resulting batch unchanged. When processing in parallel any messages that are
+already being processed when the check passes are processed to completion.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Lookups Until Found",
+				Summary: "Here we attempt a lookup for each message of a batch in parallel, and once a lookup succeeds the remaining messages are left untouched.",
+				Config: `
+pipeline:
+  processors:
+    - for_each:
+        parallelism: 4
+        exit_check: '!errored()'
+        processors:
+          - cache:
+              resource: lookups
+              operator: get
+              key: ${! json("id") }
+`,
+			},
+		},
+		config: docs.FieldComponent().WithChildren(
+			docs.FieldCommon("processors", "A list of child processors to apply.").Array().HasType(docs.FieldProcessor),
+			docs.FieldAdvanced("parallelism", "The maximum number of messages of a batch to process at the same time. When set to `0` all messages of a batch are processed at the same time.").AtVersion("3.47.0"),
+			docs.FieldAdvanced(
+				"exit_check",
+				"An optional [Bloblang query](/docs/guides/bloblang/about/) checked against the result of each message, when it resolves to `true` the remaining messages of the batch are not processed.",
+				`errored()`,
+				`!errored()`,
+			).HasDefault("").Linter(docs.LintBloblangMapping).AtVersion("3.47.0"),
+		).ArrayShorthand("processors"),
 	}
 	Constructors[TypeProcessBatch] = TypeSpec{
 		constructor: NewProcessBatch,
@@ -55,12 +105,89 @@ func NewForEachConfig() ForEachConfig {
 	return []Config{}
 }
 
+// ForEachOptionsConfig contains the optional fields of the ForEach processor,
+// which are set when it is configured as an object rather than a plain list
+// of child processors.
+type ForEachOptionsConfig struct {
+	Parallelism int    `json:"parallelism" yaml:"parallelism"`
+	ExitCheck   string `json:"exit_check" yaml:"exit_check"`
+}
+
+// NewForEachOptionsConfig returns a default ForEachOptionsConfig.
+func NewForEachOptionsConfig() ForEachOptionsConfig {
+	return ForEachOptionsConfig{
+		Parallelism: 1,
+		ExitCheck:   "",
+	}
+}
+
+// forEachObjectConfig is the object form of a ForEach processor config.
+type forEachObjectConfig struct {
+	Processors           ForEachConfig `yaml:"processors"`
+	ForEachOptionsConfig `yaml:",inline"`
+}
+
+// forEachNodeToList replaces the object form of a for_each field within a
+// processor config node with a plain list of processors, and returns the
+// options parsed from the object.
+func forEachNodeToList(value *yaml.Node) (*yaml.Node, ForEachOptionsConfig, error) {
+	opts := NewForEachOptionsConfig()
+	if value.Kind != yaml.MappingNode {
+		return value, opts, nil
+	}
+	for i := 0; i < len(value.Content)-1; i += 2 {
+		if value.Content[i].Value != TypeForEach || value.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		objNode := value.Content[i+1]
+		obj := forEachObjectConfig{ForEachOptionsConfig: opts}
+		if err := objNode.Decode(&obj.ForEachOptionsConfig); err != nil {
+			return nil, opts, fmt.Errorf("line %v: %v", objNode.Line, err)
+		}
+
+		listNode := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: objNode.Line, Column: objNode.Column}
+		for j := 0; j < len(objNode.Content)-1; j += 2 {
+			if objNode.Content[j].Value == "processors" {
+				listNode = objNode.Content[j+1]
+			}
+		}
+
+		newValue := *value
+		newValue.Content = make([]*yaml.Node, len(value.Content))
+		copy(newValue.Content, value.Content)
+		newValue.Content[i+1] = listNode
+		return &newValue, obj.ForEachOptionsConfig, nil
+	}
+	return value, opts, nil
+}
+
+// forEachListToNode replaces the plain list form of a for_each field within an
+// encoded processor config node with the object form.
+func forEachListToNode(node *yaml.Node, list ForEachConfig, opts ForEachOptionsConfig) error {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value != TypeForEach {
+			continue
+		}
+		var objNode yaml.Node
+		if err := objNode.Encode(forEachObjectConfig{
+			Processors:           list,
+			ForEachOptionsConfig: opts,
+		}); err != nil {
+			return err
+		}
+		node.Content[i+1] = &objNode
+	}
+	return nil
+}
+
 //------------------------------------------------------------------------------
 
 // ForEach is a processor that applies a list of child processors to each
 // message of a batch individually.
 type ForEach struct {
-	children []types.Processor
+	children    []types.Processor
+	parallelism int
+	exitCheck   *mapping.Executor
 
 	log log.Modular
 
@@ -74,6 +201,18 @@ type ForEach struct {
 func NewForEach(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
+	if conf.ForEachOptions.Parallelism < 0 {
+		return nil, fmt.Errorf("parallelism must not be negative, got %v", conf.ForEachOptions.Parallelism)
+	}
+
+	var exitCheck *mapping.Executor
+	if len(conf.ForEachOptions.ExitCheck) > 0 {
+		var err error
+		if exitCheck, err = bloblang.NewMapping("", conf.ForEachOptions.ExitCheck); err != nil {
+			return nil, fmt.Errorf("failed to parse exit check query: %w", err)
+		}
+	}
+
 	var children []types.Processor
 	for i, pconf := range conf.ForEach {
 		pMgr, pLog, pStats := interop.LabelChild(fmt.Sprintf("%v", i), mgr, log, stats)
@@ -84,8 +223,10 @@ func NewForEach(
 		children = append(children, proc)
 	}
 	return &ForEach{
-		children: children,
-		log:      log,
+		children:    children,
+		parallelism: conf.ForEachOptions.Parallelism,
+		exitCheck:   exitCheck,
+		log:         log,
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
@@ -108,8 +249,9 @@ func NewProcessBatch(
 		children = append(children, proc)
 	}
 	return &ForEach{
-		children: children,
-		log:      log,
+		children:    children,
+		parallelism: 1,
+		log:         log,
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
@@ -120,6 +262,26 @@ func NewProcessBatch(
 
 //------------------------------------------------------------------------------
 
+func (p *ForEach) shouldExit(msgs []types.Message) bool {
+	if p.exitCheck == nil {
+		return false
+	}
+	for _, m := range msgs {
+		if m.Len() == 0 {
+			continue
+		}
+		exit, err := p.exitCheck.QueryPart(0, m)
+		if err != nil {
+			p.log.Errorf("Exit check query failed: %v\n", err)
+			continue
+		}
+		if exit {
+			return true
+		}
+	}
+	return false
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *ForEach) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -133,12 +295,69 @@ func (p *ForEach) ProcessMessage(msg types.Message) ([]types.Message, types.Resp
 		return nil
 	})
 
-	resMsg := message.New(nil)
-	for _, tmpMsg := range individualMsgs {
-		resultMsgs, res := ExecuteAll(p.children, tmpMsg)
-		if res != nil && res.Error() != nil {
-			return nil, res
+	max := p.parallelism
+	if max == 0 || len(individualMsgs) < max {
+		max = len(individualMsgs)
+	}
+
+	// Messages that are never processed due to an early exit retain their
+	// original contents.
+	results := make([][]types.Message, len(individualMsgs))
+	for i, m := range individualMsgs {
+		results[i] = []types.Message{m}
+	}
+
+	var resMut sync.Mutex
+	var exited bool
+	var errRes types.Response
+
+	// A slot is only released once the results of a message are recorded,
+	// which ensures that an early exit is observed before further messages
+	// are dispatched.
+	slots := make(chan struct{}, max)
+	wg := sync.WaitGroup{}
+	for i := range individualMsgs {
+		slots <- struct{}{}
+
+		resMut.Lock()
+		stop := exited || errRes != nil
+		resMut.Unlock()
+		if stop {
+			break
 		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			resultMsgs, res := ExecuteAll(p.children, individualMsgs[index])
+			exit := p.shouldExit(resultMsgs)
+
+			resMut.Lock()
+			if res != nil && res.Error() != nil {
+				if errRes == nil {
+					errRes = res
+				}
+			} else {
+				results[index] = resultMsgs
+			}
+			if exit {
+				exited = true
+			}
+			resMut.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if errRes != nil {
+		return nil, errRes
+	}
+
+	resMsg := message.New(nil)
+	for _, resultMsgs := range results {
 		for _, m := range resultMsgs {
 			m.Iter(func(i int, p types.Part) error {
 				resMsg.Append(p)
@@ -4,10 +4,15 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/condition"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/util/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -383,3 +388,125 @@ func TestProcessBatchFilterAll(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestForEachParallel(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEachOptions.Parallelism = 2
+
+	blobConf := NewConfig()
+	blobConf.Type = "bloblang"
+	blobConf.Bloblang = `root = content().uppercase()`
+	conf.ForEach = append(conf.ForEach, blobConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"), []byte("buz"), []byte("bev"),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("FOO"), []byte("BAR"), []byte("BAZ"), []byte("BUZ"), []byte("BEV"),
+	}, message.GetAllBytes(msgs[0]))
+}
+
+func TestForEachExitCheck(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEachOptions.ExitCheck = `content() == "BAR"`
+
+	blobConf := NewConfig()
+	blobConf.Type = "bloblang"
+	blobConf.Bloblang = `root = content().uppercase()`
+	conf.ForEach = append(conf.ForEach, blobConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"), []byte("buz"),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("FOO"), []byte("BAR"), []byte("baz"), []byte("buz"),
+	}, message.GetAllBytes(msgs[0]))
+}
+
+func TestForEachBadExitCheck(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "for_each"
+	conf.ForEachOptions.ExitCheck = `content() ==`
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestForEachConfigForms(t *testing.T) {
+	tests := map[string]string{
+		"array form": `
+for_each:
+  - bloblang: root = content().uppercase()
+`,
+		"object form": `
+for_each:
+  parallelism: 3
+  exit_check: errored()
+  processors:
+    - bloblang: root = content().uppercase()
+`,
+	}
+
+	for name, confStr := range tests {
+		confStr := confStr
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			require.NoError(t, yaml.Unmarshal([]byte(confStr), &conf))
+
+			assert.Equal(t, "for_each", conf.Type)
+			require.Len(t, conf.ForEach, 1)
+			assert.Equal(t, "bloblang", conf.ForEach[0].Type)
+			if name == "object form" {
+				assert.Equal(t, 3, conf.ForEachOptions.Parallelism)
+				assert.Equal(t, "errored()", conf.ForEachOptions.ExitCheck)
+			} else {
+				assert.Equal(t, 1, conf.ForEachOptions.Parallelism)
+				assert.Equal(t, "", conf.ForEachOptions.ExitCheck)
+			}
+
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(confStr), &node))
+			assert.Empty(t, docs.LintNode(docs.NewLintContext(), docs.TypeProcessor, node.Content[0]))
+		})
+	}
+}
+
+func TestForEachConfigRoundTrip(t *testing.T) {
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+for_each:
+  parallelism: 3
+  exit_check: errored()
+  processors:
+    - bloblang: root = content().uppercase()
+`), &conf))
+
+	confBytes, err := yaml.Marshal(conf)
+	require.NoError(t, err)
+
+	reparsed := NewConfig()
+	require.NoError(t, yaml.Unmarshal(confBytes, &reparsed))
+	assert.Equal(t, conf.ForEachOptions, reparsed.ForEachOptions)
+	require.Len(t, reparsed.ForEach, 1)
+	assert.Equal(t, "bloblang", reparsed.ForEach[0].Type)
+
+	sanitConf, err := conf.Sanitised(false)
+	require.NoError(t, err)
+	sanitForEach, ok := sanitConf.(config.Sanitised)["for_each"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, 3, sanitForEach["parallelism"])
+	assert.Equal(t, "errored()", sanitForEach["exit_check"])
+	assert.Len(t, sanitForEach["processors"], 1)
+}
@@ -0,0 +1,482 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/mmdb"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeGeoIP] = TypeSpec{
+		constructor: NewGeoIP,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Enriches JSON documents with the location and autonomous system of an IP
+address field, using [MaxMind DB](https://maxmind.github.io/MaxMind-DB/) files
+such as the GeoIP2 and GeoLite2 databases.`,
+		Description: `
+The IP address at the ` + "`field`" + ` path of each document is looked up in each of the listed ` + "`databases`" + `, and the results are merged into an object that is written to the ` + "`target_field`" + ` path. Any of the City, Country and ASN databases (or compatible databases) can be used, and combining a City database with an ASN database results in an object such as:
+
+` + "```json" + `
+{
+  "ip": "81.2.69.142",
+  "continent_code": "EU",
+  "continent_name": "Europe",
+  "country_iso_code": "GB",
+  "country_name": "United Kingdom",
+  "region_iso_code": "ENG",
+  "region_name": "England",
+  "city_name": "London",
+  "postal_code": "EC2V",
+  "timezone": "Europe/London",
+  "location": { "lat": 51.5142, "lon": -0.0931 },
+  "accuracy_radius": 10,
+  "asn": 20712,
+  "as_org": "Andrews & Arnold Ltd"
+}
+` + "```" + `
+
+Fields that are absent from the databases are omitted, and names are given in the configured ` + "`language`" + `. When an address is not found in any of the databases the document is left unchanged. Documents where the field is missing or is not a valid IP address are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+### Reloading
+
+The modification time and size of the database files are checked every ` + "`reload_interval`" + `, and databases that have changed are reloaded without interrupting processing, which allows databases to be kept up to date with tools such as ` + "[`geoipupdate`](https://github.com/maxmind/geoipupdate)" + `. When a changed database fails to load, for example because it's only partially written, the previous version continues to be used until the next check.
+
+### Caching
+
+The results of the most recently looked up addresses are kept in a least recently used cache of up to ` + "`cache_size`" + ` entries, which is cleared whenever a database is reloaded.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Enriching Access Logs",
+				Summary: "This example adds the location and network of the client of each access log to the log under the field `client.geo`, and routes logs where the lookup fails to a separate output.",
+				Config: `
+pipeline:
+  processors:
+    - geoip:
+        field: client.ip
+        target_field: client.geo
+        databases:
+          - /usr/share/GeoIP/GeoLite2-City.mmdb
+          - /usr/share/GeoIP/GeoLite2-ASN.mmdb
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          file:
+            path: ./failed_lookups.jsonl
+      - output:
+          stdout: {}
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("field", "A [dot path](/docs/configuration/field_paths) pointing to the IP address of each document."),
+			docs.FieldCommon("target_field", "A [dot path](/docs/configuration/field_paths) pointing to where the result of each lookup is written."),
+			docs.FieldCommon("databases", "A list of paths to MaxMind DB files, where results of the databases are merged in order, with earlier databases taking precedence.").Array(),
+			docs.FieldAdvanced("language", "The language of names within the result, which must be supported by the databases."),
+			docs.FieldAdvanced("reload_interval", "The period between checks of whether the databases have changed, or an empty string to disable reloading."),
+			docs.FieldAdvanced("cache_size", "The maximum number of lookup results to cache, or zero to disable caching."),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIPConfig contains configuration fields for the GeoIP processor.
+type GeoIPConfig struct {
+	Parts          []int    `json:"parts" yaml:"parts"`
+	Field          string   `json:"field" yaml:"field"`
+	TargetField    string   `json:"target_field" yaml:"target_field"`
+	Databases      []string `json:"databases" yaml:"databases"`
+	Language       string   `json:"language" yaml:"language"`
+	ReloadInterval string   `json:"reload_interval" yaml:"reload_interval"`
+	CacheSize      int      `json:"cache_size" yaml:"cache_size"`
+}
+
+// NewGeoIPConfig returns a GeoIPConfig with default values.
+func NewGeoIPConfig() GeoIPConfig {
+	return GeoIPConfig{
+		Parts:          []int{},
+		Field:          "",
+		TargetField:    "geoip",
+		Databases:      []string{},
+		Language:       "en",
+		ReloadInterval: "1m",
+		CacheSize:      1000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// geoipDatabase is a database along with the state of its file when it was
+// loaded.
+type geoipDatabase struct {
+	path    string
+	reader  *mmdb.Reader
+	modTime time.Time
+	size    int64
+}
+
+func openGeoIPDatabase(path string) (*geoipDatabase, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := mmdb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database %v: %w", path, err)
+	}
+	return &geoipDatabase{
+		path:    path,
+		reader:  reader,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}, nil
+}
+
+// GeoIP is a processor that enriches documents with the location of an IP
+// address.
+type GeoIP struct {
+	parts       []int
+	field       []string
+	targetField []string
+	language    string
+
+	dbMut sync.RWMutex
+	dbs   []*geoipDatabase
+	cache *lruCache
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mReload    metrics.StatCounter
+	mReloadErr metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewGeoIP returns a GeoIP processor.
+func NewGeoIP(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	gConf := conf.GeoIP
+	if gConf.Field == "" {
+		return nil, errors.New("a field must be specified")
+	}
+	if len(gConf.Databases) == 0 {
+		return nil, errors.New("at least one database must be specified")
+	}
+
+	var reloadInterval time.Duration
+	if gConf.ReloadInterval != "" {
+		var err error
+		if reloadInterval, err = time.ParseDuration(gConf.ReloadInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse reload_interval: %v", err)
+		}
+	}
+
+	g := &GeoIP{
+		parts:       gConf.Parts,
+		field:       gabs.DotPathToSlice(gConf.Field),
+		targetField: gabs.DotPathToSlice(gConf.TargetField),
+		language:    gConf.Language,
+		cache:       newLRUCache(gConf.CacheSize),
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mReload:    stats.GetCounter("reload.success"),
+		mReloadErr: stats.GetCounter("reload.error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	for _, path := range gConf.Databases {
+		db, err := openGeoIPDatabase(path)
+		if err != nil {
+			return nil, err
+		}
+		g.dbs = append(g.dbs, db)
+	}
+
+	if reloadInterval > 0 {
+		go g.reloadLoop(reloadInterval)
+	} else {
+		close(g.closedChan)
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GeoIP) reloadLoop(interval time.Duration) {
+	defer close(g.closedChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.reload()
+		case <-g.closeChan:
+			return
+		}
+	}
+}
+
+// reload replaces any databases that have changed since they were loaded.
+func (g *GeoIP) reload() {
+	g.dbMut.RLock()
+	dbs := append([]*geoipDatabase(nil), g.dbs...)
+	g.dbMut.RUnlock()
+
+	changed := false
+	for i, db := range dbs {
+		info, err := os.Stat(db.path)
+		if err != nil {
+			g.mReloadErr.Incr(1)
+			g.log.Errorf("Failed to check database %v for changes: %v\n", db.path, err)
+			continue
+		}
+		if info.ModTime().Equal(db.modTime) && info.Size() == db.size {
+			continue
+		}
+		newDB, err := openGeoIPDatabase(db.path)
+		if err != nil {
+			g.mReloadErr.Incr(1)
+			g.log.Errorf("Failed to reload database, continuing with the previous version: %v\n", err)
+			continue
+		}
+		g.mReload.Incr(1)
+		g.log.Infof("Reloaded database %v built at %v\n", db.path, newDB.reader.Metadata().BuildTime)
+		dbs[i] = newDB
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	// Results cached from the previous databases are purged while holding
+	// the lock so that lookups in progress can't add them back.
+	g.dbMut.Lock()
+	g.dbs = dbs
+	g.cache.purge()
+	g.dbMut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+func geoipString(v interface{}, path ...string) (string, bool) {
+	for _, k := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		v = m[k]
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+func geoipValue(v interface{}, path ...string) (interface{}, bool) {
+	for _, k := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// extract adds the fields of a database record to a result, where fields
+// already present are kept.
+func (g *GeoIP) extract(record interface{}, result map[string]interface{}) {
+	setString := func(key string, path ...string) {
+		if _, exists := result[key]; exists {
+			return
+		}
+		if s, ok := geoipString(record, path...); ok && s != "" {
+			result[key] = s
+		}
+	}
+
+	setString("continent_code", "continent", "code")
+	setString("continent_name", "continent", "names", g.language)
+	setString("country_iso_code", "country", "iso_code")
+	setString("country_name", "country", "names", g.language)
+	if subs, ok := geoipValue(record, "subdivisions"); ok {
+		if arr, ok := subs.([]interface{}); ok && len(arr) > 0 {
+			if s, ok := geoipString(arr[0], "iso_code"); ok && result["region_iso_code"] == nil {
+				result["region_iso_code"] = s
+			}
+			if s, ok := geoipString(arr[0], "names", g.language); ok && result["region_name"] == nil {
+				result["region_name"] = s
+			}
+		}
+	}
+	setString("city_name", "city", "names", g.language)
+	setString("postal_code", "postal", "code")
+	setString("timezone", "location", "time_zone")
+	setString("as_org", "autonomous_system_organization")
+
+	if _, exists := result["location"]; !exists {
+		lat, latOk := geoipValue(record, "location", "latitude")
+		lon, lonOk := geoipValue(record, "location", "longitude")
+		if latOk && lonOk {
+			result["location"] = map[string]interface{}{"lat": lat, "lon": lon}
+		}
+	}
+	if _, exists := result["accuracy_radius"]; !exists {
+		if v, ok := geoipValue(record, "location", "accuracy_radius"); ok {
+			result["accuracy_radius"] = v
+		}
+	}
+	if _, exists := result["asn"]; !exists {
+		if v, ok := geoipValue(record, "autonomous_system_number"); ok {
+			result["asn"] = v
+		}
+	}
+}
+
+// lookup returns the merged result of an IP address from all databases, or
+// nil if it isn't found in any of them.
+func (g *GeoIP) lookup(ipStr string) (map[string]interface{}, error) {
+	if result, exists := g.cache.get(ipStr); exists {
+		return result.(map[string]interface{}), nil
+	}
+
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil, fmt.Errorf("value is not a valid IP address: %v", ipStr)
+	}
+
+	g.dbMut.RLock()
+	defer g.dbMut.RUnlock()
+
+	var result map[string]interface{}
+	for _, db := range g.dbs {
+		record, _, err := db.reader.Lookup(ip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %v in %v: %w", ipStr, db.path, err)
+		}
+		if record == nil {
+			continue
+		}
+		if result == nil {
+			result = map[string]interface{}{"ip": ipStr}
+		}
+		g.extract(record, result)
+	}
+
+	g.cache.add(ipStr, result)
+	return result, nil
+}
+
+func (g *GeoIP) enrich(part types.Part) error {
+	jObj, err := part.JSON()
+	if err != nil {
+		return err
+	}
+
+	ipStr, ok := gabs.Wrap(jObj).S(g.field...).Data().(string)
+	if !ok {
+		return fmt.Errorf("field %v is missing or is not a string", g.conf.GeoIP.Field)
+	}
+	result, err := g.lookup(ipStr)
+	if err != nil || result == nil {
+		return err
+	}
+
+	// Results are shared through the cache and are therefore copied.
+	enrichment := make(map[string]interface{}, len(result))
+	for k, v := range result {
+		if loc, ok := v.(map[string]interface{}); ok {
+			v = map[string]interface{}{"lat": loc["lat"], "lon": loc["lon"]}
+		}
+		enrichment[k] = v
+	}
+
+	if jObj, err = message.CopyJSON(jObj); err != nil {
+		return err
+	}
+	gObj := gabs.Wrap(jObj)
+	if _, err := gObj.Set(enrichment, g.targetField...); err != nil {
+		return err
+	}
+	return part.SetJSON(gObj.Data())
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (g *GeoIP) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	g.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := g.enrich(part); err != nil {
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to enrich IP address: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeGeoIP, g.parts, newMsg, proc)
+
+	g.mBatchSent.Incr(1)
+	g.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (g *GeoIP) CloseAsync() {
+	select {
+	case <-g.closeChan:
+	default:
+		close(g.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (g *GeoIP) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
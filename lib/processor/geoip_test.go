@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/mmdb"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGeoIPTestDB(t *testing.T, path, dbType string, records map[string]interface{}) {
+	t.Helper()
+
+	w := mmdb.NewWriter(dbType, "en", "de")
+	for cidr, record := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		require.NoError(t, w.Insert(network, record))
+	}
+	b, err := w.Bytes()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, b, 0o644))
+}
+
+func geoipTestCity(city string) map[string]interface{} {
+	return map[string]interface{}{
+		"continent": map[string]interface{}{
+			"code":  "EU",
+			"names": map[string]interface{}{"en": "Europe", "de": "Europa"},
+		},
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+			"names":    map[string]interface{}{"en": "United Kingdom", "de": "Vereinigtes Königreich"},
+		},
+		"subdivisions": []interface{}{
+			map[string]interface{}{
+				"iso_code": "ENG",
+				"names":    map[string]interface{}{"en": "England"},
+			},
+		},
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": city},
+		},
+		"postal": map[string]interface{}{"code": "EC2V"},
+		"location": map[string]interface{}{
+			"latitude":        51.5142,
+			"longitude":       -0.0931,
+			"accuracy_radius": uint16(10),
+			"time_zone":       "Europe/London",
+		},
+	}
+}
+
+func TestGeoIP(t *testing.T) {
+	dir := t.TempDir()
+	cityPath, asnPath := filepath.Join(dir, "city.mmdb"), filepath.Join(dir, "asn.mmdb")
+	writeGeoIPTestDB(t, cityPath, "GeoIP2-City", map[string]interface{}{
+		"81.2.69.0/24": geoipTestCity("London"),
+	})
+	writeGeoIPTestDB(t, asnPath, "GeoLite2-ASN", map[string]interface{}{
+		"81.2.0.0/16": map[string]interface{}{
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		},
+		"2001:db8::/32": map[string]interface{}{
+			"autonomous_system_number":       uint32(64496),
+			"autonomous_system_organization": "Example",
+		},
+	})
+
+	conf := NewConfig()
+	conf.GeoIP.Field = "client.ip"
+	conf.GeoIP.TargetField = "client.geo"
+	conf.GeoIP.Databases = []string{cityPath, asnPath}
+	conf.GeoIP.ReloadInterval = ""
+
+	proc, err := NewGeoIP(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		require.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"client":{"ip":"81.2.69.142"}}`),
+		[]byte(`{"client":{"ip":"2001:db8::1"}}`),
+		[]byte(`{"client":{"ip":"10.0.0.1"}}`),
+		[]byte(`{"client":{"ip":"not an ip"}}`),
+		[]byte(`{"client":{}}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+	out := msgsOut[0]
+
+	assert.JSONEq(t, `{"client":{"ip":"81.2.69.142","geo":{
+		"ip": "81.2.69.142",
+		"continent_code": "EU",
+		"continent_name": "Europe",
+		"country_iso_code": "GB",
+		"country_name": "United Kingdom",
+		"region_iso_code": "ENG",
+		"region_name": "England",
+		"city_name": "London",
+		"postal_code": "EC2V",
+		"timezone": "Europe/London",
+		"location": {"lat": 51.5142, "lon": -0.0931},
+		"accuracy_radius": 10,
+		"asn": 20712,
+		"as_org": "Andrews & Arnold Ltd"
+	}}}`, string(out.Get(0).Get()))
+	assert.JSONEq(t, `{"client":{"ip":"2001:db8::1","geo":{
+		"ip": "2001:db8::1",
+		"asn": 64496,
+		"as_org": "Example"
+	}}}`, string(out.Get(1).Get()))
+	assert.JSONEq(t, `{"client":{"ip":"10.0.0.1"}}`, string(out.Get(2).Get()))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "", GetFail(out.Get(i)), i)
+	}
+	assert.Equal(t, "value is not a valid IP address: not an ip", GetFail(out.Get(3)))
+	assert.Equal(t, "field client.ip is missing or is not a string", GetFail(out.Get(4)))
+}
+
+func TestGeoIPLanguage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeGeoIPTestDB(t, path, "GeoIP2-City", map[string]interface{}{
+		"81.2.69.0/24": geoipTestCity("London"),
+	})
+
+	conf := NewConfig()
+	conf.GeoIP.Field = "ip"
+	conf.GeoIP.Databases = []string{path}
+	conf.GeoIP.Language = "de"
+	conf.GeoIP.ReloadInterval = ""
+
+	proc, err := NewGeoIP(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, _ := proc.ProcessMessage(message.New([][]byte{[]byte(`{"ip":"81.2.69.1"}`)}))
+	jObj, err := msgsOut[0].Get(0).JSON()
+	require.NoError(t, err)
+	geo := jObj.(map[string]interface{})["geoip"].(map[string]interface{})
+	assert.Equal(t, "Vereinigtes Königreich", geo["country_name"])
+	assert.Equal(t, "Europa", geo["continent_name"])
+	assert.NotContains(t, geo, "city_name")
+}
+
+func TestGeoIPReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeGeoIPTestDB(t, path, "GeoIP2-City", map[string]interface{}{
+		"81.2.69.0/24": geoipTestCity("London"),
+	})
+
+	conf := NewConfig()
+	conf.GeoIP.Field = "ip"
+	conf.GeoIP.Databases = []string{path}
+	conf.GeoIP.ReloadInterval = ""
+
+	proc, err := NewGeoIP(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	g := proc.(*GeoIP)
+
+	cityOf := func() interface{} {
+		t.Helper()
+		msgsOut, _ := g.ProcessMessage(message.New([][]byte{[]byte(`{"ip":"81.2.69.1"}`)}))
+		jObj, err := msgsOut[0].Get(0).JSON()
+		require.NoError(t, err)
+		return jObj.(map[string]interface{})["geoip"].(map[string]interface{})["city_name"]
+	}
+	assert.Equal(t, "London", cityOf())
+
+	// A partially written database is ignored.
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a database"), 0o644))
+	g.reload()
+	assert.Equal(t, "London", cityOf())
+
+	writeGeoIPTestDB(t, path, "GeoIP2-City", map[string]interface{}{
+		"81.2.69.0/24": geoipTestCity("Londinium"),
+	})
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	g.reload()
+	assert.Equal(t, "Londinium", cityOf())
+}
+
+func TestGeoIPBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.GeoIP.Databases = []string{"foo.mmdb"}
+	_, err := NewGeoIP(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a field must be specified")
+
+	conf.GeoIP.Field = "ip"
+	conf.GeoIP.Databases = nil
+	_, err = NewGeoIP(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "at least one database must be specified")
+
+	conf.GeoIP.Databases = []string{filepath.Join(t.TempDir(), "nope.mmdb")}
+	_, err = NewGeoIP(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
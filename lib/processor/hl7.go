@@ -0,0 +1,291 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/hl7"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeHL7] = TypeSpec{
+		constructor: NewHL7,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryParsing,
+		},
+		Summary: `
+Parses HL7 version 2 messages into JSON structures or FHIR resources, and
+generates acknowledgements for them.`,
+		Description: `
+## Operators
+
+### ` + "`to_json`" + `
+
+Converts a pipe delimited HL7 version 2 message into a JSON structure of its segments. Segments can be separated by carriage returns, line feeds or both, and the delimiters and escape character of the message are read from its MSH segment.
+
+Fields are addressed by the segment ID followed by their two digit position, e.g. the fifth field of a ` + "`PID`" + ` segment has the key ` + "`PID05`" + `. Simple fields are strings, fields with components are arrays where each component is either a string or, when it has subcomponents, an array of strings, repeated fields are arrays of arrays of components, and empty fields are omitted. Escape sequences within values are decoded.
+
+For example, given the following message:
+
+` + "```text" + `
+MSH|^~\&|LAB|HOSP|EHR|HOSP|20210101120000||ORU^R01|MSG001|P|2.5
+PID|1||12345^^^HOSP^MR||Doe^John^A||19800101|M
+OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-99|N|||F
+` + "```" + `
+
+The resulting JSON structure would be:
+
+` + "```json" + `
+{
+  "message_type": "ORU",
+  "trigger_event": "R01",
+  "control_id": "MSG001",
+  "version": "2.5",
+  "segments": [
+    {"segment":"MSH","MSH01":"|","MSH02":"^~\\&","MSH03":"LAB","MSH04":"HOSP","MSH05":"EHR","MSH06":"HOSP","MSH07":"20210101120000","MSH09":["ORU","R01"],"MSH10":"MSG001","MSH11":"P","MSH12":"2.5"},
+    {"segment":"PID","PID01":"1","PID03":["12345","","","HOSP","MR"],"PID05":["Doe","John","A"],"PID07":"19800101","PID08":"M"},
+    {"segment":"OBX","OBX01":"1","OBX02":"NM","OBX03":["2345-7","Glucose","LN"],"OBX05":"95","OBX06":"mg/dL","OBX07":"70-99","OBX08":"N","OBX11":"F"}
+  ]
+}
+` + "```" + `
+
+### ` + "`to_fhir`" + `
+
+Converts an HL7 version 2 message into a FHIR R4 ` + "`Bundle`" + ` of type ` + "`message`" + `. The MSH segment becomes a ` + "`MessageHeader`" + ` resource, and PID, PV1 and OBX segments become ` + "`Patient`, `Encounter` and `Observation`" + ` resources respectively, where observations reference the patient and encounter that precede them. Other segments are ignored, and so this mapping is a starting point that can be refined with a [` + "`bloblang`" + ` processor](/docs/components/processors/bloblang).
+
+### ` + "`ack`" + `
+
+Replaces a message with an HL7 acknowledgement (ACK) of it, where the sending and receiving applications are swapped and the control ID of the original message is acknowledged. If the message has failed a prior processing step the acknowledgement code is ` + "`AE`" + ` and the error is included in the MSA segment, otherwise the code is ` + "`AA`" + `.
+
+When the message is not HL7, for example because it has already been converted with ` + "`to_json`" + `, the header fields are read from the metadata added by the other operators instead.
+
+## Metadata
+
+The ` + "`to_json` and `to_fhir`" + ` operators add the following metadata fields to each message:
+
+` + "``` text" + `
+- hl7_message_type
+- hl7_trigger_event
+- hl7_control_id
+- hl7_version
+- hl7_sending_application
+- hl7_sending_facility
+- hl7_receiving_application
+- hl7_receiving_facility
+- hl7_processing_id
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Acknowledged Ingestion",
+				Summary: "In this example we receive HL7 messages over HTTP, store them as FHIR bundles and respond to each request with an acknowledgement of whether the message was processed successfully.",
+				Config: `
+input:
+  http_server:
+    path: /hl7
+pipeline:
+  processors:
+    - hl7:
+        operator: to_fhir
+    - branch:
+        processors:
+          - cache:
+              resource: bundles
+              operator: set
+              key: ${! meta("hl7_control_id") }
+              value: ${! content() }
+    - hl7:
+        operator: ack
+    - sync_response: {}
+output:
+  drop: {}
+resources:
+  caches:
+    bundles:
+      memory: {}
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("operator", "An HL7 [operation](#operators) to apply to messages.").HasOptions("to_json", "to_fhir", "ack"),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// HL7Config contains configuration fields for the HL7 processor.
+type HL7Config struct {
+	Parts    []int  `json:"parts" yaml:"parts"`
+	Operator string `json:"operator" yaml:"operator"`
+}
+
+// NewHL7Config returns a HL7Config with default values.
+func NewHL7Config() HL7Config {
+	return HL7Config{
+		Parts:    []int{},
+		Operator: "to_json",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type hl7Operator func(part types.Part) error
+
+// HL7 is a processor that performs an operation on an HL7 payload.
+type HL7 struct {
+	parts    []int
+	operator hl7Operator
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewHL7 returns an HL7 processor.
+func NewHL7(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	p := &HL7{
+		parts: conf.HL7.Parts,
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	switch conf.HL7.Operator {
+	case "to_json":
+		p.operator = p.toJSON
+	case "to_fhir":
+		p.operator = p.toFHIR
+	case "ack":
+		p.operator = p.ack
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.HL7.Operator)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func setHL7Metadata(part types.Part, h hl7.AckHeader, msgType string) {
+	meta := part.Metadata()
+	meta.Set("hl7_message_type", msgType)
+	meta.Set("hl7_trigger_event", h.TriggerEvent)
+	meta.Set("hl7_control_id", h.ControlID)
+	meta.Set("hl7_version", h.Version)
+	meta.Set("hl7_sending_application", h.SendingApplication)
+	meta.Set("hl7_sending_facility", h.SendingFacility)
+	meta.Set("hl7_receiving_application", h.ReceivingApplication)
+	meta.Set("hl7_receiving_facility", h.ReceivingFacility)
+	meta.Set("hl7_processing_id", h.ProcessingID)
+}
+
+func hl7HeaderFromMetadata(part types.Part) hl7.AckHeader {
+	meta := part.Metadata()
+	return hl7.AckHeader{
+		SendingApplication:   meta.Get("hl7_sending_application"),
+		SendingFacility:      meta.Get("hl7_sending_facility"),
+		ReceivingApplication: meta.Get("hl7_receiving_application"),
+		ReceivingFacility:    meta.Get("hl7_receiving_facility"),
+		TriggerEvent:         meta.Get("hl7_trigger_event"),
+		ControlID:            meta.Get("hl7_control_id"),
+		ProcessingID:         meta.Get("hl7_processing_id"),
+		Version:              meta.Get("hl7_version"),
+	}
+}
+
+func (p *HL7) parse(part types.Part) (*hl7.Message, error) {
+	msg, err := hl7.Parse(part.Get())
+	if err != nil {
+		return nil, err
+	}
+	setHL7Metadata(part, msg.AckHeader(), msg.Header().Get(9, 1, 1))
+	return msg, nil
+}
+
+func (p *HL7) toJSON(part types.Part) error {
+	msg, err := p.parse(part)
+	if err != nil {
+		return err
+	}
+	return part.SetJSON(msg.ToMap())
+}
+
+func (p *HL7) toFHIR(part types.Part) error {
+	msg, err := p.parse(part)
+	if err != nil {
+		return err
+	}
+	return part.SetJSON(msg.ToFHIR())
+}
+
+func (p *HL7) ack(part types.Part) error {
+	var header hl7.AckHeader
+	if msg, err := hl7.Parse(part.Get()); err == nil {
+		header = msg.AckHeader()
+	} else {
+		header = hl7HeaderFromMetadata(part)
+	}
+	if header.ControlID == "" {
+		return errors.New("unable to determine the control ID of the message")
+	}
+
+	code, text := hl7.AckAccept, ""
+	if text = GetFail(part); text != "" {
+		code = hl7.AckError
+	}
+	part.Set(header.Ack(code, text, time.Now()))
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *HL7) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.operator(part); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to apply HL7 operator: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeHL7, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *HL7) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *HL7) WaitForClose(timeout time.Duration) error {
+	return nil
+}
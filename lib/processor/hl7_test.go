@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHL7 = "MSH|^~\\&|LAB|HOSP|EHR|CLINIC|20210101120000||ORU^R01|MSG001|P|2.5\r" +
+	"PID|1||12345^^^HOSP^MR||Doe^John||19800101|F\r" +
+	"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|||||F\r"
+
+func TestHL7ToJSON(t *testing.T) {
+	conf := NewConfig()
+	conf.HL7.Operator = "to_json"
+
+	proc, err := NewHL7(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(testHL7),
+		[]byte(`not hl7`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	part := msgsOut[0].Get(0)
+	assert.Equal(t, "", GetFail(part))
+	jObj, err := part.JSON()
+	require.NoError(t, err)
+	obj := jObj.(map[string]interface{})
+	assert.Equal(t, "ORU", obj["message_type"])
+	assert.Len(t, obj["segments"], 3)
+
+	for k, v := range map[string]string{
+		"hl7_message_type":          "ORU",
+		"hl7_trigger_event":         "R01",
+		"hl7_control_id":            "MSG001",
+		"hl7_version":               "2.5",
+		"hl7_sending_application":   "LAB",
+		"hl7_sending_facility":      "HOSP",
+		"hl7_receiving_application": "EHR",
+		"hl7_receiving_facility":    "CLINIC",
+		"hl7_processing_id":         "P",
+	} {
+		assert.Equal(t, v, part.Metadata().Get(k), k)
+	}
+
+	assert.Equal(t, "not hl7", string(msgsOut[0].Get(1).Get()))
+	assert.Equal(t, "message does not begin with an MSH segment", GetFail(msgsOut[0].Get(1)))
+}
+
+func TestHL7ToFHIR(t *testing.T) {
+	conf := NewConfig()
+	conf.HL7.Operator = "to_fhir"
+
+	proc, err := NewHL7(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{[]byte(testHL7)}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	part := msgsOut[0].Get(0)
+	assert.Equal(t, "", GetFail(part))
+	assert.Equal(t, "MSG001", part.Metadata().Get("hl7_control_id"))
+
+	jObj, err := part.JSON()
+	require.NoError(t, err)
+	entries := jObj.(map[string]interface{})["entry"].([]interface{})
+	require.Len(t, entries, 3)
+	patient := entries[1].(map[string]interface{})["resource"].(map[string]interface{})
+	assert.Equal(t, "female", patient["gender"])
+	obs := entries[2].(map[string]interface{})["resource"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"reference": "Patient/12345"}, obs["subject"])
+}
+
+func TestHL7Ack(t *testing.T) {
+	conf := NewConfig()
+	conf.HL7.Operator = "to_json"
+	toJSON, err := NewHL7(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf.HL7.Operator = "ack"
+	ack, err := NewHL7(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(testHL7),
+		[]byte(testHL7),
+		[]byte(`{"foo":"bar"}`),
+	})
+	FlagErr(msg.Get(1), errors.New("database is down"))
+
+	msgsOut, res := ack.ProcessMessage(msg)
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assertAck := func(index int, exp string) {
+		t.Helper()
+		segments := strings.Split(string(msgsOut[0].Get(index).Get()), "\r")
+		require.Len(t, segments, 3)
+		assert.True(t, strings.HasPrefix(segments[0], "MSH|^~\\&|EHR|CLINIC|LAB|HOSP|"), segments[0])
+		assert.True(t, strings.HasSuffix(segments[0], "||ACK^R01^ACK|MSG001|P|2.5"), segments[0])
+		assert.Equal(t, exp, segments[1])
+	}
+
+	assertAck(0, "MSA|AA|MSG001")
+	assertAck(1, "MSA|AE|MSG001|database is down")
+	assert.Equal(t, "database is down", GetFail(msgsOut[0].Get(1)))
+	assert.Equal(t, `{"foo":"bar"}`, string(msgsOut[0].Get(2).Get()))
+	assert.Equal(t, "unable to determine the control ID of the message", GetFail(msgsOut[0].Get(2)))
+
+	// Acknowledgements can be generated from the metadata of converted
+	// messages.
+	msgsOut, res = toJSON.ProcessMessage(message.New([][]byte{[]byte(testHL7)}))
+	require.Nil(t, res)
+	msgsOut, res = ack.ProcessMessage(msgsOut[0])
+	require.Nil(t, res)
+	assertAck(0, "MSA|AA|MSG001")
+}
+
+func TestHL7BadOperator(t *testing.T) {
+	conf := NewConfig()
+	conf.HL7.Operator = "nope"
+	_, err := NewHL7(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "operator not recognised: nope")
+}
@@ -35,7 +35,11 @@ The new message will have metadata copied from the first pre-existing message of
 the batch.
 
 This processor will interpolate functions within the 'content' field, you can
-find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).`,
+find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).
+
+For reshaping batches in ways that depend on the contents of their messages use
+the ` + "[`batch_mapping`](/docs/components/processors/batch_mapping)" + `
+processor instead.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("index", "The index within the batch to insert the message at."),
 			docs.FieldCommon("content", "The content of the message being inserted.").IsInterpolated(),
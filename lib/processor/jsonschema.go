@@ -1,7 +1,6 @@
 package processor
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -202,7 +201,7 @@ func (s *JSONSchema) ProcessMessage(msg types.Message) ([]types.Message, types.R
 				}
 				errStr += desc.Field() + " " + description
 			}
-			return errors.New(errStr)
+			return types.ErrValidation{S: errStr}
 		}
 		s.log.Debugf("The document is valid\n")
 
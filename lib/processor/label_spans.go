@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// labelSpans wraps a processor so that its execution is traced within a span
+// named by the label of the processor.
+type labelSpans struct {
+	label string
+	child types.Processor
+}
+
+// WithLabelSpans wraps a processor so that each execution is traced with a span
+// named by its label, which is the parent of any spans created by the processor
+// itself.
+func WithLabelSpans(label string, proc types.Processor) types.Processor {
+	return &labelSpans{
+		label: label,
+		child: proc,
+	}
+}
+
+// ProcessMessage executes the child processor within labelled spans.
+func (l *labelSpans) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	spanMsg, spans := tracing.WithChildSpans(l.label, msg)
+	msgs, res := l.child.ProcessMessage(spanMsg)
+	for _, s := range spans {
+		s.Finish()
+	}
+
+	// Where possible restore the original spans of the message so that the
+	// spans of subsequent components are siblings rather than children.
+	if len(msgs) == 1 && msgs[0].Len() == msg.Len() {
+		parts := make([]types.Part, msg.Len())
+		_ = msgs[0].Iter(func(i int, p types.Part) error {
+			parts[i] = message.WithContext(message.GetContext(msg.Get(i)), p)
+			return nil
+		})
+		msgs[0].SetAll(parts)
+	}
+	return msgs, res
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (l *labelSpans) CloseAsync() {
+	l.child.CloseAsync()
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (l *labelSpans) WaitForClose(timeout time.Duration) error {
+	return l.child.WaitForClose(timeout)
+}
+
+//------------------------------------------------------------------------------
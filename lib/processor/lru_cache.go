@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a least recently used cache of values by key, which is safe for
+// concurrent use.
+type lruCache struct {
+	mut   sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List
+}
+
+type lruCacheEntry struct {
+	key   string
+	value interface{}
+}
+
+// newLRUCache creates a cache of up to size entries, where a size of zero or
+// less disables caching.
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		items: map[string]*list.Element{},
+		order: list.New(),
+	}
+}
+
+func (c *lruCache) get(key string) (interface{}, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruCacheEntry).value, true
+}
+
+func (c *lruCache) add(key string, value interface{}) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if e, exists := c.items[key]; exists {
+		e.Value.(*lruCacheEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruCacheEntry{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruCacheEntry).key)
+	}
+}
+
+func (c *lruCache) purge() {
+	c.mut.Lock()
+	c.items = map[string]*list.Element{}
+	c.order.Init()
+	c.mut.Unlock()
+}
@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2)
+	c.add("a", "a")
+	c.add("b", nil)
+	_, exists := c.get("a")
+	assert.True(t, exists)
+
+	c.add("c", "c")
+	_, exists = c.get("b")
+	assert.False(t, exists)
+	v, exists := c.get("a")
+	assert.True(t, exists)
+	assert.Equal(t, "a", v)
+
+	c.add("a", "A")
+	v, _ = c.get("a")
+	assert.Equal(t, "A", v)
+
+	c.purge()
+	_, exists = c.get("a")
+	assert.False(t, exists)
+
+	c = newLRUCache(0)
+	c.add("a", "a")
+	_, exists = c.get("a")
+	assert.False(t, exists)
+}
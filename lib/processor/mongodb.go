@@ -15,6 +15,8 @@ type MongoDBConfig struct {
 	FilterMap   string         `json:"filter_map" yaml:"filter_map"`
 	DocumentMap string         `json:"document_map" yaml:"document_map"`
 	HintMap     string         `json:"hint_map" yaml:"hint_map"`
+	PipelineMap string         `json:"pipeline_map" yaml:"pipeline_map"`
+	Upsert      bool           `json:"upsert" yaml:"upsert"`
 	RetryConfig retries.Config `json:",inline" yaml:",inline"`
 }
 
@@ -29,7 +31,7 @@ func NewMongoDBConfig() MongoDBConfig {
 	return MongoDBConfig{
 		MongoDB:      client.NewConfig(),
 		Parts:        []int{},
-		Operation:    "insert",
+		Operation:    "insert-one",
 		RetryConfig:  rConf,
 		WriteConcern: client.WriteConcern{},
 	}
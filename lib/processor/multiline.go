@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMultiline] = TypeSpec{
+		constructor: NewMultiline,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryParsing,
+			CategoryUtility,
+		},
+		Summary: `
+Joins the messages of a batch that are lines of a single log entry, such as stack traces, into single messages.`,
+		Description: `
+A log entry begins with each message that matches the ` + "`start_pattern`" + `, and all following messages of the batch that don't match it are joined to it with line breaks. The resulting messages keep the metadata of the first line of their entry. Messages at the start of a batch that precede any match are joined together as an entry of their own.
+
+Entries can't span batches, and therefore this processor is best suited to batches that contain whole files or documents. When consuming continuous streams of lines with the ` + "`file`, `socket`, `socket_server` or `stdin`" + ` inputs their ` + "`multiline`" + ` field joins lines as they are read instead, and is able to wait for the remaining lines of an entry.`,
+		UsesBatches: true,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Java Stack Traces",
+				Summary: "This example reads archived log files from S3 as batches of lines and joins the lines of each stack trace into the entry that precedes it.",
+				Config: `
+input:
+  aws_s3:
+    bucket: logs
+    codec: lines/multipart
+
+pipeline:
+  processors:
+    - multiline:
+        start_pattern: '^\d{4}-\d{2}-\d{2} '
+    - split:
+        size: 1
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("start_pattern", "A regular expression that matches the first line of each log entry.", `^\d{4}-\d{2}-\d{2}`, `^\S`),
+			docs.FieldAdvanced("max_lines", "The maximum number of lines to join into a single message, after which a new message is started."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MultilineConfig contains configuration fields for the Multiline processor.
+type MultilineConfig struct {
+	StartPattern string `json:"start_pattern" yaml:"start_pattern"`
+	MaxLines     int    `json:"max_lines" yaml:"max_lines"`
+}
+
+// NewMultilineConfig returns a MultilineConfig with default values.
+func NewMultilineConfig() MultilineConfig {
+	return MultilineConfig{
+		StartPattern: "",
+		MaxLines:     500,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Multiline is a processor that joins the lines of log entries within a batch.
+type Multiline struct {
+	log   log.Modular
+	stats metrics.Type
+
+	start    *regexp.Regexp
+	maxLines int
+
+	mCount     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewMultiline returns a Multiline processor.
+func NewMultiline(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.Multiline.StartPattern == "" {
+		return nil, errors.New("a start_pattern must be specified")
+	}
+	start, err := regexp.Compile(conf.Multiline.StartPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile start pattern: %v", err)
+	}
+	if conf.Multiline.MaxLines <= 0 {
+		return nil, fmt.Errorf("max lines must be greater than zero, got: %v", conf.Multiline.MaxLines)
+	}
+	return &Multiline{
+		log:   log,
+		stats: stats,
+
+		start:    start,
+		maxLines: conf.Multiline.MaxLines,
+
+		mCount:     stats.GetCounter("count"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (m *Multiline) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	m.mCount.Incr(1)
+
+	if msg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	newMsg := message.New(nil)
+
+	var entry types.Part
+	var buf bytes.Buffer
+	lines := 0
+	flush := func() {
+		if entry != nil {
+			entry.Set(append([]byte(nil), buf.Bytes()...))
+			newMsg.Append(entry)
+		}
+		entry, lines = nil, 0
+		buf.Reset()
+	}
+
+	msg.Iter(func(i int, p types.Part) error {
+		if entry != nil && (lines >= m.maxLines || m.start.Match(p.Get())) {
+			flush()
+		}
+		if entry == nil {
+			entry = p.Copy()
+		} else {
+			buf.WriteByte('\n')
+		}
+		buf.Write(p.Get())
+		lines++
+		return nil
+	})
+	flush()
+
+	m.mBatchSent.Incr(1)
+	m.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (m *Multiline) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (m *Multiline) WaitForClose(timeout time.Duration) error {
+	return nil
+}
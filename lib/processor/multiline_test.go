@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiline(t *testing.T) {
+	conf := NewConfig()
+	conf.Multiline.StartPattern = `^\d{4}-`
+	conf.Multiline.MaxLines = 3
+
+	proc, err := NewMultiline(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	inMsg := message.New([][]byte{
+		[]byte(`  orphaned`),
+		[]byte(`2021-01-01 INFO started`),
+		[]byte(`2021-01-01 ERROR failed`),
+		[]byte(`java.lang.NullPointerException`),
+		[]byte(`	at foo.Bar(Bar.java:12)`),
+		[]byte(`	at foo.Baz(Baz.java:34)`),
+		[]byte(`2021-01-02 INFO done`),
+	})
+	inMsg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set("line", string(rune('0'+i)))
+		return nil
+	})
+
+	msgsOut, res := proc.ProcessMessage(inMsg)
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	var contents, lines []string
+	msgsOut[0].Iter(func(i int, p types.Part) error {
+		contents = append(contents, string(p.Get()))
+		lines = append(lines, p.Metadata().Get("line"))
+		return nil
+	})
+	assert.Equal(t, []string{
+		"  orphaned",
+		"2021-01-01 INFO started",
+		"2021-01-01 ERROR failed\njava.lang.NullPointerException\n\tat foo.Bar(Bar.java:12)",
+		"\tat foo.Baz(Baz.java:34)",
+		"2021-01-02 INFO done",
+	}, contents)
+	assert.Equal(t, []string{"0", "1", "2", "5", "6"}, lines)
+
+	// The input batch must not be modified.
+	assert.Equal(t, "2021-01-01 ERROR failed", string(inMsg.Get(2).Get()))
+}
+
+func TestMultilineBadConfig(t *testing.T) {
+	conf := NewConfig()
+	_, err := NewMultiline(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a start_pattern must be specified")
+
+	conf.Multiline.StartPattern = `^\S`
+	conf.Multiline.MaxLines = 0
+	_, err = NewMultiline(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "max lines must be greater than zero, got: 0")
+}
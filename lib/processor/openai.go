@@ -0,0 +1,558 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeOpenAI] = TypeSpec{
+		constructor: NewOpenAI,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Generates embeddings or chat completions for the text of messages with an
+OpenAI compatible API.`,
+		Description: `
+The text sent for each message is resolved from the interpolated field ` + "`text`" + `, which is the entire contents of the message by default. By default the result replaces the contents of the message, and a ` + "`result_map`" + ` can instead be specified in order to map the result into the message, where the original message is the starting point of the mapping and the result is referenced with ` + "`this`" + `.
+
+Any API that is compatible with the OpenAI embeddings or chat completions endpoints can be used by setting the ` + "`url`" + ` field, such as Azure OpenAI, Ollama or vLLM.
+
+## Operators
+
+### ` + "`embeddings`" + `
+
+Generates an embedding vector for the text of each message, resulting in an array of numbers. The texts of a batch are sent in requests of up to ` + "`batch_size`" + ` inputs.
+
+### ` + "`chat_completion`" + `
+
+Sends the text of each message as a user message to a chat model, along with the optional ` + "`system_prompt`" + `, resulting in the content of the reply. A request is sent for each message, and the metadata field ` + "`openai_finish_reason`" + ` is set to the reason that the model stopped generating the reply.
+
+## Rate Limits and Retries
+
+The ` + "`rate_limit`" + ` field can be used to specify a rate limit [resource](/docs/components/rate_limits/about) to cap the rate of requests across parallel components service wide, where each request consumes an access of the rate limit.
+
+Requests that fail with a status of 429 or 5XX, or fail to connect, are retried according to ` + "`max_retries` and `backoff`" + `, and when a response contains a ` + "`Retry-After`" + ` header the retry waits for at least that period. Messages of requests that still fail continue through the pipeline with their contents unchanged but flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Document Embeddings",
+				Summary: "In this example an embedding of the body of each document is generated and added to the document, ready to be written to a vector store.",
+				Config: `
+pipeline:
+  processors:
+    - openai:
+        operator: embeddings
+        api_key: ${OPENAI_API_KEY}
+        model: text-embedding-3-small
+        text: ${! json("body") }
+        result_map: root.embedding = this
+`,
+			},
+			{
+				Title:   "Ticket Classification",
+				Summary: "In this example support tickets are classified into a category by a chat model, with a rate limit shared across the pipeline in order to remain within the limits of the API.",
+				Config: `
+pipeline:
+  processors:
+    - openai:
+        operator: chat_completion
+        api_key: ${OPENAI_API_KEY}
+        model: gpt-4o-mini
+        system_prompt: |
+          Classify the support ticket as one of: billing, technical, account, other.
+          Reply with the category only.
+        text: ${! json("subject") + "\n\n" + json("description") }
+        rate_limit: openai
+        result_map: root.category = content().string().trim().lowercase()
+
+resources:
+  rate_limits:
+    openai:
+      local:
+        count: 500
+        interval: 1m
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("operator", "The [operation](#operators) to perform.").HasOptions("embeddings", "chat_completion"),
+			docs.FieldAdvanced("url", "The base URL of the API.", "https://api.openai.com/v1", "http://localhost:11434/v1"),
+			docs.FieldCommon("api_key", "The API key to authenticate with, which is sent as a bearer token."),
+			docs.FieldCommon("model", "The model to use.", "text-embedding-3-small", "gpt-4o-mini"),
+			docs.FieldCommon("text", "The text to send for each message.").IsInterpolated(),
+			docs.FieldCommon("system_prompt", "An optional system prompt for the `chat_completion` operator."),
+			docs.FieldAdvanced("max_tokens", "The maximum number of tokens to generate for the `chat_completion` operator, where zero leaves the limit to the API."),
+			docs.FieldAdvanced("batch_size", "The maximum number of inputs of a request for the `embeddings` operator."),
+			docs.FieldCommon(
+				"result_map", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that maps the result into the message, where the result is referenced with `this`.",
+				`root.embedding = this`,
+				`root.summary = content().string()`,
+			).HasType(docs.FieldString).Linter(docs.LintBloblangMapping),
+			docs.FieldAdvanced("rate_limit", "An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle requests by."),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a request to complete."),
+		}.Merge(retries.FieldSpecs()).Add(PartsFieldSpec),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenAIConfig contains configuration fields for the OpenAI processor.
+type OpenAIConfig struct {
+	retries.Config `json:",inline" yaml:",inline"`
+	Parts          []int  `json:"parts" yaml:"parts"`
+	Operator       string `json:"operator" yaml:"operator"`
+	URL            string `json:"url" yaml:"url"`
+	APIKey         string `json:"api_key" yaml:"api_key"`
+	Model          string `json:"model" yaml:"model"`
+	Text           string `json:"text" yaml:"text"`
+	SystemPrompt   string `json:"system_prompt" yaml:"system_prompt"`
+	MaxTokens      int    `json:"max_tokens" yaml:"max_tokens"`
+	BatchSize      int    `json:"batch_size" yaml:"batch_size"`
+	ResultMap      string `json:"result_map" yaml:"result_map"`
+	RateLimit      string `json:"rate_limit" yaml:"rate_limit"`
+	Timeout        string `json:"timeout" yaml:"timeout"`
+}
+
+// NewOpenAIConfig returns a OpenAIConfig with default values.
+func NewOpenAIConfig() OpenAIConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "30s"
+	rConf.Backoff.MaxElapsedTime = "2m"
+	return OpenAIConfig{
+		Config:       rConf,
+		Parts:        []int{},
+		Operator:     "embeddings",
+		URL:          "https://api.openai.com/v1",
+		APIKey:       "",
+		Model:        "",
+		Text:         "${! content() }",
+		SystemPrompt: "",
+		MaxTokens:    0,
+		BatchSize:    100,
+		ResultMap:    "",
+		RateLimit:    "",
+		Timeout:      "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// openAIResult is the result of a request for a single message.
+type openAIResult struct {
+	content      []byte
+	finishReason string
+}
+
+// OpenAI is a processor that generates embeddings or chat completions with an
+// OpenAI compatible API.
+type OpenAI struct {
+	parts     []int
+	oConf     OpenAIConfig
+	baseURL   string
+	text      *field.Expression
+	resultMap *mapping.Executor
+	client    *http.Client
+	backoff   func() backoff.BackOff
+	limiter   resourceRateLimiter
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrReq    metrics.StatCounter
+	mRetry     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewOpenAI returns an OpenAI processor.
+func NewOpenAI(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	oConf := conf.OpenAI
+	if oConf.Operator != "embeddings" && oConf.Operator != "chat_completion" {
+		return nil, fmt.Errorf("operator not recognised: %v", oConf.Operator)
+	}
+	if oConf.Model == "" {
+		return nil, errors.New("a model must be specified")
+	}
+	if oConf.BatchSize <= 0 {
+		return nil, errors.New("batch_size must be greater than zero")
+	}
+
+	p := &OpenAI{
+		parts:   oConf.Parts,
+		oConf:   oConf,
+		baseURL: strings.TrimSuffix(oConf.URL, "/"),
+		client:  &http.Client{},
+		limiter: newResourceRateLimiter(oConf.RateLimit, mgr, log, stats),
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrReq:    stats.GetCounter("error.request"),
+		mRetry:     stats.GetCounter("retry"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	if p.text, err = bloblang.NewField(oConf.Text); err != nil {
+		return nil, fmt.Errorf("failed to parse text expression: %v", err)
+	}
+	if oConf.ResultMap != "" {
+		if p.resultMap, err = bloblang.NewMapping("", oConf.ResultMap); err != nil {
+			return nil, fmt.Errorf("failed to parse result_map: %w", err)
+		}
+	}
+	if oConf.Timeout != "" {
+		if p.client.Timeout, err = time.ParseDuration(oConf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if p.backoff, err = oConf.Config.GetCtor(); err != nil {
+		return nil, err
+	}
+	if err := p.limiter.probe(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// openAIStatusError is returned for requests that fail with a status code.
+type openAIStatusError struct {
+	status     int
+	retryAfter time.Duration
+	message    string
+}
+
+func (e *openAIStatusError) Error() string {
+	return fmt.Sprintf("request failed with status %v: %v", e.status, e.message)
+}
+
+func (e *openAIStatusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+func newOpenAIStatusError(res *http.Response) *openAIStatusError {
+	e := &openAIStatusError{status: res.StatusCode}
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.retryAfter = time.Duration(secs) * time.Second
+	}
+
+	body, _ := ioutil.ReadAll(res.Body)
+	var errBody struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errBody); err == nil && errBody.Error.Message != "" {
+		e.message = errBody.Error.Message
+	} else {
+		e.message = string(bytes.TrimSpace(body))
+	}
+	return e
+}
+
+func (p *OpenAI) doRequest(path string, reqBody []byte, resBody interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, p.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.oConf.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.oConf.APIKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newOpenAIStatusError(res)
+	}
+	return json.NewDecoder(res.Body).Decode(resBody)
+}
+
+// request sends a request to an endpoint of the API, retrying requests that
+// are rate limited or fail due to the server or connection.
+func (p *OpenAI) request(path string, body interface{}, resBody interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	boff := p.backoff()
+	for {
+		p.limiter.wait()
+		err := p.doRequest(path, reqBody, resBody)
+		if err == nil {
+			return nil
+		}
+
+		var retryAfter time.Duration
+		var sErr *openAIStatusError
+		if errors.As(err, &sErr) {
+			if !sErr.retryable() {
+				return err
+			}
+			retryAfter = sErr.retryAfter
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		p.mRetry.Incr(1)
+		p.log.Warnf("Request to %v failed, retrying in %v: %v\n", path, wait, err)
+		<-time.After(wait)
+	}
+}
+
+func (p *OpenAI) embeddings(texts []string) ([]openAIResult, error) {
+	var resBody struct {
+		Data []struct {
+			Index     int             `json:"index"`
+			Embedding json.RawMessage `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := p.request("/embeddings", map[string]interface{}{
+		"model": p.oConf.Model,
+		"input": texts,
+	}, &resBody); err != nil {
+		return nil, err
+	}
+
+	results := make([]openAIResult, len(texts))
+	for _, d := range resBody.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("response contained an embedding with an unexpected index: %v", d.Index)
+		}
+		results[d.Index].content = []byte(d.Embedding)
+	}
+	for i, r := range results {
+		if r.content == nil {
+			return nil, fmt.Errorf("response did not contain an embedding for input %v", i)
+		}
+	}
+	return results, nil
+}
+
+func (p *OpenAI) chatCompletion(text string) (openAIResult, error) {
+	var messages []interface{}
+	if p.oConf.SystemPrompt != "" {
+		messages = append(messages, map[string]interface{}{
+			"role":    "system",
+			"content": p.oConf.SystemPrompt,
+		})
+	}
+	messages = append(messages, map[string]interface{}{
+		"role":    "user",
+		"content": text,
+	})
+
+	reqBody := map[string]interface{}{
+		"model":    p.oConf.Model,
+		"messages": messages,
+	}
+	if p.oConf.MaxTokens > 0 {
+		reqBody["max_tokens"] = p.oConf.MaxTokens
+	}
+
+	var resBody struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := p.request("/chat/completions", reqBody, &resBody); err != nil {
+		return openAIResult{}, err
+	}
+	if len(resBody.Choices) == 0 {
+		return openAIResult{}, errors.New("response did not contain any choices")
+	}
+	return openAIResult{
+		content:      []byte(resBody.Choices[0].Message.Content),
+		finishReason: resBody.Choices[0].FinishReason,
+	}, nil
+}
+
+// generate sends the texts to the API, returning the result or error of each
+// text in the same order.
+func (p *OpenAI) generate(texts []string) ([]openAIResult, []error) {
+	results := make([]openAIResult, len(texts))
+	errs := make([]error, len(texts))
+
+	if p.oConf.Operator == "chat_completion" {
+		for i, text := range texts {
+			if results[i], errs[i] = p.chatCompletion(text); errs[i] != nil {
+				p.mErrReq.Incr(1)
+				p.log.Errorf("Chat completion request failed: %v\n", errs[i])
+			}
+		}
+		return results, errs
+	}
+
+	for start := 0; start < len(texts); start += p.oConf.BatchSize {
+		end := start + p.oConf.BatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		bResults, err := p.embeddings(texts[start:end])
+		if err != nil {
+			p.mErrReq.Incr(1)
+			p.log.Errorf("Embeddings request failed: %v\n", err)
+		}
+		for i := start; i < end; i++ {
+			if err != nil {
+				errs[i] = err
+			} else {
+				results[i] = bResults[i-start]
+			}
+		}
+	}
+	return results, errs
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *OpenAI) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	indexes := p.parts
+	if len(indexes) == 0 {
+		indexes = make([]int, newMsg.Len())
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	var texts []string
+	var textIndexes []int
+	for _, index := range indexes {
+		if text := p.text.String(index, newMsg); text != "" {
+			texts = append(texts, text)
+			textIndexes = append(textIndexes, index)
+		}
+	}
+
+	results := map[int]openAIResult{}
+	errs := map[int]error{}
+	if len(texts) > 0 {
+		tResults, tErrs := p.generate(texts)
+		for i, index := range textIndexes {
+			if tErrs[i] != nil {
+				errs[index] = tErrs[i]
+			} else {
+				results[index] = tResults[i]
+			}
+		}
+	}
+
+	// The result mapping references the results as a message with the same
+	// indexes as the original.
+	var resultMsg types.Message
+	if p.resultMap != nil {
+		resultMsg = newMsg.Copy()
+		for index, res := range results {
+			resultMsg.Get(index).Set(res.content)
+		}
+	}
+
+	mapped := map[int]types.Part{}
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		err := errs[index]
+		res, exists := results[index]
+		if err == nil && !exists {
+			err = errors.New("text is empty")
+		}
+		if err == nil && p.resultMap != nil {
+			var newPart types.Part
+			if newPart, err = p.resultMap.MapOnto(part, index, resultMsg); err != nil {
+				err = fmt.Errorf("result mapping failed: %w", err)
+			} else if newPart != nil {
+				mIndex := index
+				if mIndex < 0 {
+					mIndex += newMsg.Len()
+				}
+				mapped[mIndex] = newPart
+				part = newPart
+			}
+		} else if err == nil {
+			part.Set(res.content)
+		}
+		if err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to process message: %v\n", err)
+			return err
+		}
+		if res.finishReason != "" {
+			part.Metadata().Set("openai_finish_reason", res.finishReason)
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeOpenAI, p.parts, newMsg, proc)
+
+	if len(mapped) > 0 {
+		parts := make([]types.Part, newMsg.Len())
+		newMsg.Iter(func(i int, part types.Part) error {
+			if newPart, exists := mapped[i]; exists {
+				part = newPart
+			}
+			parts[i] = part
+			return nil
+		})
+		newMsg.SetAll(parts)
+	}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *OpenAI) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *OpenAI) WaitForClose(timeout time.Duration) error {
+	return nil
+}
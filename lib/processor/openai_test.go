@@ -0,0 +1,146 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIEmbeddings(t *testing.T) {
+	var reqMut sync.Mutex
+	var inputs [][]string
+	attempts := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer foosecret", r.Header.Get("Authorization"))
+
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		// Rate limit the first attempt in order to test retries.
+		if attempts++; attempts == 1 {
+			http.Error(w, `{"error":{"message":"Rate limit reached"}}`, http.StatusTooManyRequests)
+			return
+		}
+
+		var reqBody struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.Equal(t, "foomodel", reqBody.Model)
+		inputs = append(inputs, reqBody.Input)
+
+		var data []interface{}
+		for i := len(reqBody.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{
+				"index":     i,
+				"embedding": []float64{float64(len(reqBody.Input[i])), 0.5},
+			})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"data": data}))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.OpenAI.URL = ts.URL + "/v1/"
+	conf.OpenAI.APIKey = "foosecret"
+	conf.OpenAI.Model = "foomodel"
+	conf.OpenAI.Text = `${! json("text") }`
+	conf.OpenAI.BatchSize = 2
+	conf.OpenAI.ResultMap = `root.embedding = this`
+	conf.OpenAI.Backoff.InitialInterval = "1ms"
+	conf.OpenAI.Backoff.MaxInterval = "1ms"
+
+	proc, err := NewOpenAI(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"text":"a"}`),
+		[]byte(`{"text":"bb"}`),
+		[]byte(`{"text":""}`),
+		[]byte(`{"text":"ccc"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, [][]string{{"a", "bb"}, {"ccc"}}, inputs)
+
+	msg := msgs[0]
+	assert.Equal(t, `{"embedding":[1,0.5],"text":"a"}`, string(msg.Get(0).Get()))
+	assert.Equal(t, `{"embedding":[2,0.5],"text":"bb"}`, string(msg.Get(1).Get()))
+	assert.Equal(t, `{"text":""}`, string(msg.Get(2).Get()))
+	assert.Equal(t, "text is empty", GetFail(msg.Get(2)))
+	assert.Equal(t, `{"embedding":[3,0.5],"text":"ccc"}`, string(msg.Get(3).Get()))
+	assert.Equal(t, "", GetFail(msg.Get(3)))
+}
+
+func TestOpenAIChatCompletion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+
+		var reqBody struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			MaxTokens int `json:"max_tokens"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.Len(t, reqBody.Messages, 2)
+		assert.Equal(t, "system", reqBody.Messages[0].Role)
+		assert.Equal(t, "Shout", reqBody.Messages[0].Content)
+		assert.Equal(t, 10, reqBody.MaxTokens)
+
+		if reqBody.Messages[1].Content == "bad" {
+			http.Error(w, `{"error":{"message":"Invalid request"}}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"%v!"},"finish_reason":"stop"}]}`, reqBody.Messages[1].Content)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.OpenAI.Operator = "chat_completion"
+	conf.OpenAI.URL = ts.URL
+	conf.OpenAI.Model = "foomodel"
+	conf.OpenAI.SystemPrompt = "Shout"
+	conf.OpenAI.MaxTokens = 10
+
+	proc, err := NewOpenAI(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`hello`),
+		[]byte(`bad`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	msg := msgs[0]
+	assert.Equal(t, "hello!", string(msg.Get(0).Get()))
+	assert.Equal(t, "stop", msg.Get(0).Metadata().Get("openai_finish_reason"))
+	assert.Equal(t, "bad", string(msg.Get(1).Get()))
+	assert.Equal(t, "request failed with status 400: Invalid request", GetFail(msg.Get(1)))
+}
+
+func TestOpenAIBadConfig(t *testing.T) {
+	conf := NewConfig()
+	_, err := NewOpenAI(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a model must be specified")
+
+	conf.OpenAI.Model = "foo"
+	conf.OpenAI.Operator = "nope"
+	_, err = NewOpenAI(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "operator not recognised: nope")
+}
@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/parquet"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParquetDecode] = TypeSpec{
+		constructor: NewParquetDecode,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryParsing,
+		},
+		Summary: `
+Decodes Parquet files into a batch of JSON documents, with a document for each
+row.`,
+		Description: `
+The schema of each file is read from the file itself, and each row becomes a JSON object keyed by the names of its columns, where nested groups become nested objects and repeated columns become arrays. Values of ` + "`INT96`" + ` columns are converted into RFC 3339 timestamps, and other values are converted into their closest JSON type.
+
+Each decoded document keeps the metadata of the message it was decoded from. Messages that fail to decode remain unchanged and can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
+
+Parquet files can only be decoded once they are held in memory in full. In order to consume large files from inputs such as ` + "`file` and `aws_s3`" + ` the ` + "`parquet`" + ` codec can be used instead, which emits rows as individual messages.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Decoding Uploads",
+				Summary: "In this example Parquet files are uploaded over HTTP and each of their rows is sent to Kafka as a JSON document.",
+				Config: `
+input:
+  http_server:
+    path: /upload
+
+pipeline:
+  processors:
+    - parquet_decode: {}
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: uploaded_rows
+`,
+			},
+		},
+		config: docs.FieldComponent().Map(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetDecodeConfig contains configuration fields for the ParquetDecode
+// processor.
+type ParquetDecodeConfig struct{}
+
+// NewParquetDecodeConfig returns a ParquetDecodeConfig with default values.
+func NewParquetDecodeConfig() ParquetDecodeConfig {
+	return ParquetDecodeConfig{}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetDecode is a processor that decodes Parquet files into a JSON document
+// for each row.
+type ParquetDecode struct {
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewParquetDecode returns a ParquetDecode processor.
+func NewParquetDecode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	return &ParquetDecode{
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func decodeParquetPart(part types.Part) ([]types.Part, error) {
+	r, err := parquet.NewReader(part.Get())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	parts := make([]types.Part, 0, r.NumRows())
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		newPart := part.Copy()
+		if err := newPart.SetJSON(row); err != nil {
+			return nil, fmt.Errorf("failed to set JSON contents of message: %v", err)
+		}
+		parts = append(parts, newPart)
+	}
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *ParquetDecode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	msg.Iter(func(i int, part types.Part) error {
+		span := tracing.CreateChildSpan(TypeParquetDecode, part)
+		defer span.Finish()
+
+		newParts, err := decodeParquetPart(part)
+		if err == nil {
+			newMsg.Append(newParts...)
+		} else {
+			p.mErr.Incr(1)
+			p.log.Errorf("Failed to decode parquet file: %v\n", err)
+			newMsg.Append(part)
+			FlagErr(newMsg.Get(-1), err)
+			span.LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+		}
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *ParquetDecode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *ParquetDecode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/parquet"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParquetEncode] = TypeSpec{
+		constructor: NewParquetEncode,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryParsing,
+		},
+		Summary: `
+Encodes a batch of JSON documents into a single Parquet file, with a row for
+each document.`,
+		Description: `
+The columns of the file are described by ` + "`schema`" + `, and fields of documents that are not columns of the schema are ignored. Columns are required unless they are either ` + "`optional` or `repeated`" + `, and a document that is missing a required column, or that has a value which cannot be converted to the type of its column, causes the whole batch to fail. Failed batches remain unchanged and can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
+
+The number of rows within each file is therefore determined by the size of the batches that reach this processor, which is usually set with a [batching policy](/docs/configuration/batching). Within a file rows are grouped into row groups of approximately ` + "`row_group_size`" + ` bytes before compression, and the processor holds an entire file in memory while encoding it.
+
+Files can be decoded back into JSON documents with the ` + "[`parquet_decode` processor](/docs/components/processors/parquet_decode)" + `, or consumed by inputs such as ` + "`file` and `aws_s3`" + ` with the ` + "`parquet`" + ` codec.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Writing Parquet Files to S3",
+				Summary: "In this example we write batches of orders to S3 as Parquet files, with a file written for every 10000 orders or every five minutes, whichever comes first.",
+				Config: `
+output:
+  aws_s3:
+    bucket: company-lake
+    path: 'orders/${! timestamp_unix_nano() }.parquet'
+    batching:
+      count: 10000
+      period: 5m
+      processors:
+        - parquet_encode:
+            compression: zstd
+            schema:
+              - name: id
+                type: INT64
+              - name: customer
+                type: UTF8
+              - name: total
+                type: DOUBLE
+                optional: true
+              - name: items
+                repeated: true
+                fields:
+                  - name: sku
+                    type: UTF8
+                  - name: quantity
+                    type: INT32
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("schema", "The columns of the Parquet schema.").Array().WithChildren(parquet.ColumnFieldSpecs()...),
+			docs.FieldCommon("compression", "The compression codec of the file.").HasOptions("uncompressed", "snappy", "gzip", "zstd"),
+			docs.FieldAdvanced("row_group_size", "The approximate size in bytes of each row group before compression."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetEncodeConfig contains configuration fields for the ParquetEncode
+// processor.
+type ParquetEncodeConfig struct {
+	Schema       []parquet.Column `json:"schema" yaml:"schema"`
+	Compression  string           `json:"compression" yaml:"compression"`
+	RowGroupSize int64            `json:"row_group_size" yaml:"row_group_size"`
+}
+
+// NewParquetEncodeConfig returns a ParquetEncodeConfig with default values.
+func NewParquetEncodeConfig() ParquetEncodeConfig {
+	return ParquetEncodeConfig{
+		Schema:       []parquet.Column{},
+		Compression:  "snappy",
+		RowGroupSize: 128 * 1024 * 1024,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetEncode is a processor that encodes a batch of JSON documents into a
+// Parquet file.
+type ParquetEncode struct {
+	encoder *parquet.Encoder
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewParquetEncode returns a ParquetEncode processor.
+func NewParquetEncode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	encoder, err := parquet.NewEncoder(conf.ParquetEncode.Schema, conf.ParquetEncode.Compression, conf.ParquetEncode.RowGroupSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ParquetEncode{
+		encoder: encoder,
+		log:     log,
+		stats:   stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *ParquetEncode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	if msg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	newMsg := msg.Copy()
+	spans := tracing.CreateChildSpans(TypeParquetEncode, newMsg)
+
+	rows := make([][]byte, msg.Len())
+	msg.Iter(func(i int, part types.Part) error {
+		rows[i] = part.Get()
+		return nil
+	})
+
+	data, err := p.encoder.Encode(rows)
+	if err != nil {
+		newMsg.Iter(func(i int, part types.Part) error {
+			FlagErr(part, err)
+			spans[i].LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+			return nil
+		})
+		p.log.Errorf("Failed to encode parquet file: %v\n", err)
+		p.mErr.Incr(1)
+	} else {
+		newPart := newMsg.Get(0)
+		newPart.Set(data)
+		newMsg.SetAll([]types.Part{batch.WithCollapsedCount(newPart, msg.Len())})
+	}
+	for _, s := range spans {
+		s.Finish()
+	}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *ParquetEncode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *ParquetEncode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/parquet"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetEncodeDecode(t *testing.T) {
+	encConf := NewConfig()
+	encConf.Type = TypeParquetEncode
+	encConf.ParquetEncode.Schema = []parquet.Column{
+		{Name: "id", Type: "INT64"},
+		{Name: "name", Type: "UTF8", Optional: true},
+		{Name: "items", Repeated: true, Fields: []parquet.Column{
+			{Name: "sku", Type: "UTF8"},
+		}},
+	}
+
+	enc, err := New(encConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	decConf := NewConfig()
+	decConf.Type = TypeParquetDecode
+
+	dec, err := New(decConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo","items":[{"sku":"a"},{"sku":"b"}]}`),
+		[]byte(`{"id":2}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := enc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, []string{
+		`{"id":1,"items":[{"sku":"a"},{"sku":"b"}],"name":"foo"}`,
+		`{"id":2,"items":[],"name":null}`,
+	}, []string{string(msgs[0].Get(0).Get()), string(msgs[0].Get(1).Get())})
+	assert.Equal(t, "bar", msgs[0].Get(1).Metadata().Get("foo"))
+}
+
+func TestParquetEncodeErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeParquetEncode
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "schema must contain at least one column")
+
+	conf.ParquetEncode.Schema = []parquet.Column{{Name: "id", Type: "INT64"}}
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{[]byte(`{"id":1}`), []byte(`{"id":"nope"}`)})
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+	assert.Equal(t, `{"id":1}`, string(msgs[0].Get(0).Get()))
+	msgs[0].Iter(func(i int, part types.Part) error {
+		assert.True(t, HasFailed(part))
+		return nil
+	})
+}
@@ -3,10 +3,14 @@ package processor
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -14,6 +18,7 @@ import (
 
 	// nolint:staticcheck // Ignore SA1019 deprecation warning until we can switch to "google.golang.org/protobuf/types/dynamicpb"
 	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
@@ -54,11 +59,22 @@ to manipulate the contents of the document within Benthos.
 
 ### ` + "`from_json`" + `
 
-Attempts to create a target protobuf message from a generic JSON structure.`,
+Attempts to create a target protobuf message from a generic JSON structure.
+
+## Descriptors
+
+Message definitions are loaded when the processor is created, either by parsing the .proto files found within ` + "`import_paths`" + `, or from compiled descriptor sets listed in ` + "`descriptor_sets`" + `, or both. A descriptor set is a serialised ` + "`FileDescriptorSet`" + ` such as those produced by the ` + "`--descriptor_set_out`" + ` flag of ` + "`protoc`" + `, and must include all of the files imported by the target messages, which can be achieved with the ` + "`--include_imports`" + ` flag:
+
+` + "```sh" + `
+protoc --include_imports --descriptor_set_out=schemas.pb -I ./schema ./schema/*.proto
+` + "```" + `
+
+The ` + "`message`" + ` field supports [interpolation functions](/docs/configuration/interpolation#bloblang-queries), allowing messages of different types to be converted by a single processor, with the descriptor of each type resolved and cached the first time it is used. If the message type of a message cannot be found then the message fails to be converted and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
-			docs.FieldCommon("message", "The fully qualified name of the protobuf message to convert to/from."),
-			docs.FieldCommon("import_paths", "A list of directories containing .proto files, including all definitions required for parsing the target message. If left empty the current directory is used. Each directory listed will be walked with all found .proto files imported.").Array(),
+			docs.FieldCommon("message", "The fully qualified name of the protobuf message to convert to/from.", "testing.Person", `${! meta("message_type") }`).IsInterpolated(),
+			docs.FieldCommon("import_paths", "A list of directories containing .proto files, including all definitions required for parsing the target message. If both this field and `descriptor_sets` are left empty the current directory is used. Each directory listed will be walked with all found .proto files imported.").Array(),
+			docs.FieldCommon("descriptor_sets", "A list of paths to compiled descriptor set files containing the definitions of target messages, which must include all imported files.", []string{"./schemas.pb"}).Array().AtVersion("3.47.0"),
 			docs.FieldDeprecated("import_path"),
 			PartsFieldSpec,
 		},
@@ -147,6 +163,25 @@ pipeline:
         operator: to_json
         message: testing.Person
         import_paths: [ testing/schema ]
+`,
+			},
+			{
+				Title: "Mixed Message Types",
+				Summary: `
+If a Kafka topic contains protobuf messages of several types, where the type of each message is set in the header ` + "`message_type`" + `, we can convert them all into JSON documents using a compiled descriptor set containing the definitions of every type:`,
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: events_json
+
+pipeline:
+  processors:
+    - protobuf:
+        operator: to_json
+        message: ${! meta("message_type") }
+        descriptor_sets: [ ./schemas.pb ]
 `,
 			},
 		},
@@ -157,85 +192,71 @@ pipeline:
 
 // ProtobufConfig contains configuration fields for the Protobuf processor.
 type ProtobufConfig struct {
-	Parts       []int    `json:"parts" yaml:"parts"`
-	Operator    string   `json:"operator" yaml:"operator"`
-	Message     string   `json:"message" yaml:"message"`
-	ImportPaths []string `json:"import_paths" yaml:"import_paths"`
-	ImportPath  string   `json:"import_path" yaml:"import_path"`
+	Parts          []int    `json:"parts" yaml:"parts"`
+	Operator       string   `json:"operator" yaml:"operator"`
+	Message        string   `json:"message" yaml:"message"`
+	ImportPaths    []string `json:"import_paths" yaml:"import_paths"`
+	ImportPath     string   `json:"import_path" yaml:"import_path"`
+	DescriptorSets []string `json:"descriptor_sets" yaml:"descriptor_sets"`
 }
 
 // NewProtobufConfig returns a ProtobufConfig with default values.
 func NewProtobufConfig() ProtobufConfig {
 	return ProtobufConfig{
-		Parts:       []int{},
-		Operator:    "to_json",
-		Message:     "",
-		ImportPaths: []string{},
-		ImportPath:  "",
+		Parts:          []int{},
+		Operator:       "to_json",
+		Message:        "",
+		ImportPaths:    []string{},
+		ImportPath:     "",
+		DescriptorSets: []string{},
 	}
 }
 
 //------------------------------------------------------------------------------
 
-type protobufOperator func(part types.Part) error
+type protobufOperator func(m *desc.MessageDescriptor, part types.Part) error
 
-func newProtobufToJSONOperator(message string, importPaths []string) (protobufOperator, error) {
-	m, err := loadDescriptor(message, importPaths)
-	if err != nil {
-		return nil, err
+func protobufToJSONOperator(m *desc.MessageDescriptor, part types.Part) error {
+	msg := dynamic.NewMessage(m)
+	if err := proto.Unmarshal(part.Get(), msg); err != nil {
+		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
-	return func(part types.Part) error {
-		msg := dynamic.NewMessage(m)
-		if err := proto.Unmarshal(part.Get(), msg); err != nil {
-			return fmt.Errorf("failed to unmarshal message: %w", err)
-		}
 
-		data, err := msg.MarshalJSON()
-		if err != nil {
-			return fmt.Errorf("failed to marshal protobuf message: %w", err)
-		}
+	data, err := msg.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal protobuf message: %w", err)
+	}
 
-		part.Set(data)
-		return nil
-	}, nil
+	part.Set(data)
+	return nil
 }
 
-func newProtobufFromJSONOperator(message string, importPaths []string) (protobufOperator, error) {
-	m, err := loadDescriptor(message, importPaths)
-	if err != nil {
-		return nil, err
+func protobufFromJSONOperator(m *desc.MessageDescriptor, part types.Part) error {
+	msg := dynamic.NewMessage(m)
+	if err := msg.UnmarshalJSON(part.Get()); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON message: %w", err)
 	}
-	return func(part types.Part) error {
-		msg := dynamic.NewMessage(m)
-		if err := msg.UnmarshalJSON(part.Get()); err != nil {
-			return fmt.Errorf("failed to unmarshal JSON message: %w", err)
-		}
 
-		data, err := msg.Marshal()
-		if err != nil {
-			return fmt.Errorf("failed to marshal protobuf message: %v", err)
-		}
+	data, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal protobuf message: %v", err)
+	}
 
-		part.Set(data)
-		return nil
-	}, nil
+	part.Set(data)
+	return nil
 }
 
-func strToProtobufOperator(opStr, message string, importPaths []string) (protobufOperator, error) {
+func strToProtobufOperator(opStr string) (protobufOperator, error) {
 	switch opStr {
 	case "to_json":
-		return newProtobufToJSONOperator(message, importPaths)
+		return protobufToJSONOperator, nil
 	case "from_json":
-		return newProtobufFromJSONOperator(message, importPaths)
+		return protobufFromJSONOperator, nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
-func loadDescriptor(message string, importPaths []string) (*desc.MessageDescriptor, error) {
-	if message == "" {
-		return nil, errors.New("message field must not be empty")
-	}
-
+func loadProtoFiles(importPaths []string) ([]*desc.FileDescriptor, error) {
 	var parser protoparse.Parser
 	if len(importPaths) == 0 {
 		importPaths = []string{"."}
@@ -269,17 +290,45 @@ func loadDescriptor(message string, importPaths []string) (*desc.MessageDescript
 	if len(fds) == 0 {
 		return nil, fmt.Errorf("no .proto files were found in the paths '%v'", importPaths)
 	}
+	return fds, nil
+}
 
-	var msg *desc.MessageDescriptor
-	for _, d := range fds {
-		if msg = d.FindMessage(message); msg != nil {
-			break
+func loadDescriptorSet(path string) ([]*desc.FileDescriptor, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	var set dpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal descriptor set '%v': %w", path, err)
+	}
+	fdMap, err := desc.CreateFileDescriptorsFromSet(&set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load descriptor set '%v', which must include all imported files: %w", path, err)
+	}
+	fds := make([]*desc.FileDescriptor, 0, len(fdMap))
+	for _, fd := range fdMap {
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
+
+func loadFileDescriptors(importPaths, descriptorSets []string) ([]*desc.FileDescriptor, error) {
+	var fds []*desc.FileDescriptor
+	if len(importPaths) > 0 || len(descriptorSets) == 0 {
+		var err error
+		if fds, err = loadProtoFiles(importPaths); err != nil {
+			return nil, err
 		}
 	}
-	if msg == nil {
-		err = fmt.Errorf("unable to find message '%v' definition within '%v'", message, importPaths)
+	for _, path := range descriptorSets {
+		setFDs, err := loadDescriptorSet(path)
+		if err != nil {
+			return nil, err
+		}
+		fds = append(fds, setFDs...)
 	}
-	return msg, err
+	return fds, nil
 }
 
 //------------------------------------------------------------------------------
@@ -288,6 +337,11 @@ func loadDescriptor(message string, importPaths []string) (*desc.MessageDescript
 type Protobuf struct {
 	parts    []int
 	operator protobufOperator
+	message  *field.Expression
+	files    []*desc.FileDescriptor
+
+	descMut     sync.RWMutex
+	descriptors map[string]*desc.MessageDescriptor
 
 	conf  Config
 	log   log.Modular
@@ -304,10 +358,11 @@ func NewProtobuf(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
 	p := &Protobuf{
-		parts: conf.Protobuf.Parts,
-		conf:  conf,
-		log:   log,
-		stats: stats,
+		parts:       conf.Protobuf.Parts,
+		descriptors: map[string]*desc.MessageDescriptor{},
+		conf:        conf,
+		log:         log,
+		stats:       stats,
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
@@ -321,14 +376,53 @@ func NewProtobuf(
 	}
 
 	var err error
-	if p.operator, err = strToProtobufOperator(conf.Protobuf.Operator, conf.Protobuf.Message, importPaths); err != nil {
+	if p.operator, err = strToProtobufOperator(conf.Protobuf.Operator); err != nil {
 		return nil, err
 	}
+	if conf.Protobuf.Message == "" {
+		return nil, errors.New("message field must not be empty")
+	}
+	if p.message, err = bloblang.NewField(conf.Protobuf.Message); err != nil {
+		return nil, fmt.Errorf("failed to parse message expression: %v", err)
+	}
+	if p.files, err = loadFileDescriptors(importPaths, conf.Protobuf.DescriptorSets); err != nil {
+		return nil, err
+	}
+
+	// When the message type is static we can check that it exists up front.
+	if p.message.NumDynamicExpressions() == 0 {
+		if _, err = p.getDescriptor(conf.Protobuf.Message); err != nil {
+			return nil, err
+		}
+	}
 	return p, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (p *Protobuf) getDescriptor(message string) (*desc.MessageDescriptor, error) {
+	p.descMut.RLock()
+	m, exists := p.descriptors[message]
+	p.descMut.RUnlock()
+	if exists {
+		return m, nil
+	}
+
+	for _, fd := range p.files {
+		if m = fd.FindMessage(message); m != nil {
+			break
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("unable to find message '%v' definition", message)
+	}
+
+	p.descMut.Lock()
+	p.descriptors[message] = m
+	p.descMut.Unlock()
+	return m, nil
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *Protobuf) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -336,7 +430,11 @@ func (p *Protobuf) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 	newMsg := msg.Copy()
 
 	proc := func(index int, span opentracing.Span, part types.Part) error {
-		if err := p.operator(part); err != nil {
+		m, err := p.getDescriptor(p.message.String(index, msg))
+		if err == nil {
+			err = p.operator(m, part)
+		}
+		if err != nil {
 			p.mErr.Incr(1)
 			p.log.Debugf("Operator failed: %v\n", err)
 			return err
@@ -1,6 +1,9 @@
 package processor
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -9,6 +12,13 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	// nolint:staticcheck // Ignore SA1019 deprecation warning until we can switch to "google.golang.org/protobuf/types/dynamicpb"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
 )
 
 func TestProtobuf(t *testing.T) {
@@ -154,3 +164,109 @@ func TestProtobufErrors(t *testing.T) {
 		})
 	}
 }
+
+func writeProtobufDescriptorSet(t *testing.T, importPath string, files ...string) string {
+	t.Helper()
+
+	parser := protoparse.Parser{ImportPaths: []string{importPath}}
+	fds, err := parser.ParseFiles(files...)
+	require.NoError(t, err)
+
+	var set dpb.FileDescriptorSet
+	seen := map[string]struct{}{}
+	var add func(fd *desc.FileDescriptor)
+	add = func(fd *desc.FileDescriptor) {
+		if _, exists := seen[fd.GetName()]; exists {
+			return
+		}
+		seen[fd.GetName()] = struct{}{}
+		for _, dep := range fd.GetDependencies() {
+			add(dep)
+		}
+		set.File = append(set.File, fd.AsFileDescriptorProto())
+	}
+	for _, fd := range fds {
+		add(fd)
+	}
+
+	data, err := proto.Marshal(&set)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "benthos_protobuf_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "schemas.pb")
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestProtobufDescriptorSets(t *testing.T) {
+	setPath := writeProtobufDescriptorSet(t, "../../config/test/protobuf/schema", "house.proto")
+
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = `${! meta("message_type") }`
+	conf.Protobuf.DescriptorSets = []string{setPath}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"firstName":"john","lastName":"oates","age":10}`),
+		[]byte(`{"address":"foo","people":[{"firstName":"daryl"}]}`),
+		[]byte(`{"firstName":"caleb"}`),
+	})
+	input.Get(0).Metadata().Set("message_type", "testing.Person")
+	input.Get(1).Metadata().Set("message_type", "testing.House")
+	input.Get(2).Metadata().Set("message_type", "testing.Nope")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, []byte{0x0a, 0x04, 0x6a, 0x6f, 0x68, 0x6e, 0x12, 0x05, 0x6f, 0x61, 0x74, 0x65, 0x73, 0x20, 0x0a}, msgs[0].Get(0).Get())
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.Equal(t, "", msgs[0].Get(1).Metadata().Get(FailFlagKey))
+	assert.Equal(t, "unable to find message 'testing.Nope' definition", msgs[0].Get(2).Metadata().Get(FailFlagKey))
+
+	// Convert back using the proto files in order to check the nested message.
+	conf.Protobuf.Operator = "to_json"
+	conf.Protobuf.Message = "testing.House"
+	conf.Protobuf.DescriptorSets = nil
+	conf.Protobuf.ImportPaths = []string{"../../config/test/protobuf/schema"}
+
+	proc, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{msgs[0].Get(1).Get()}))
+	require.Nil(t, res)
+	assert.Equal(t, `{"people":[{"firstName":"daryl"}],"address":"foo"}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestProtobufConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Message = "testing.Nope"
+	conf.Protobuf.ImportPaths = []string{"../../config/test/protobuf/schema"}
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "unable to find message 'testing.Nope' definition")
+
+	dir, err := ioutil.TempDir("", "benthos_protobuf_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	badPath := filepath.Join(dir, "bad.pb")
+	require.NoError(t, ioutil.WriteFile(badPath, []byte("not a descriptor set"), 0644))
+
+	conf.Protobuf.ImportPaths = nil
+	conf.Protobuf.DescriptorSets = []string{badPath}
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to unmarshal descriptor set")
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"context"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// resourceRateLimiter blocks requests to external services until an optional
+// rate limit resource grants access.
+type resourceRateLimiter struct {
+	name string
+	mgr  types.Manager
+	log  log.Modular
+
+	mLimited  metrics.StatCounter
+	mLimitFor metrics.StatCounter
+	mLimitErr metrics.StatCounter
+}
+
+func newResourceRateLimiter(name string, mgr types.Manager, log log.Modular, stats metrics.Type) resourceRateLimiter {
+	return resourceRateLimiter{
+		name: name,
+		mgr:  mgr,
+		log:  log,
+
+		mLimited:  stats.GetCounter("rate_limit.count"),
+		mLimitFor: stats.GetCounter("rate_limit.total_ms"),
+		mLimitErr: stats.GetCounter("rate_limit.error"),
+	}
+}
+
+func (r resourceRateLimiter) probe() error {
+	if r.name == "" {
+		return nil
+	}
+	return interop.ProbeRateLimit(context.Background(), r.mgr, r.name)
+}
+
+func (r resourceRateLimiter) wait() {
+	if r.name == "" {
+		return
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := interop.AccessRateLimit(context.Background(), r.mgr, r.name, func(rl types.RateLimit) {
+			period, err = rl.Access()
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			r.log.Errorf("Rate limit error: %v\n", err)
+			r.mLimitErr.Incr(1)
+			period = time.Second
+		}
+		if period <= 0 {
+			return
+		}
+		if err == nil {
+			r.mLimited.Incr(1)
+			r.mLimitFor.Incr(period.Nanoseconds() / 1000000)
+		}
+		<-time.After(period)
+	}
+}
@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/recording"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRecord] = TypeSpec{
+		constructor: NewRecord,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Passes messages through unchanged while appending a sample of redacted copies,
+along with their metadata and the time at which they were seen, to a recording
+file that can be replayed with the ` + "[`replay_file`](/docs/components/inputs/replay_file)" + ` input.`,
+		Description: `
+This processor is useful for capturing real traffic at a particular step of a pipeline in order to reproduce issues or to test changes locally. Since recordings are written to a local file the processor should only be used with a sample of traffic from a single instance.
+
+A recording contains a line of JSON for each message, and each pipeline thread that runs the processor shares the same recording file, as does any other ` + "`record`" + ` processor or output with the same path. Messages that fail to be redacted are logged and not recorded, and failures to record messages never affect the messages that pass through the processor.`,
+		FieldSpecs: recording.FieldSpecs(),
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Capturing Traffic",
+				Summary: "In this example we record one in a hundred of the messages consumed from Kafka, with the email addresses of users removed, so that the traffic can later be replayed with the `replay_file` input.",
+				Config: `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ users ]
+    consumer_group: benthos
+  processors:
+    - record:
+        path: ./users_recording.jsonl.gz
+        sample_ratio: 0.01
+        redact: |
+          root = this
+          root.email = deleted()
+`,
+			},
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.47.0",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RecordConfig contains configuration fields for the Record processor.
+type RecordConfig struct {
+	recording.Config `json:",inline" yaml:",inline"`
+}
+
+// NewRecordConfig returns a RecordConfig with default values.
+func NewRecordConfig() RecordConfig {
+	return RecordConfig{
+		Config: recording.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Record is a processor that appends a sample of messages to a recording
+// without modifying them.
+type Record struct {
+	recorder *recording.Recorder
+	log      log.Modular
+
+	mCount     metrics.StatCounter
+	mRecorded  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewRecord returns a Record processor.
+func NewRecord(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	recorder, err := conf.Record.NewRecorder()
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		recorder: recorder,
+		log:      log,
+
+		mCount:     stats.GetCounter("count"),
+		mRecorded:  stats.GetCounter("recorded"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *Record) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+
+	recorded, err := r.recorder.Record(msg)
+	r.mRecorded.Incr(int64(recorded))
+	if err != nil {
+		r.mErr.Incr(1)
+		r.log.Errorf("Failed to record messages: %v\n", err)
+	}
+
+	r.mBatchSent.Incr(1)
+	r.mSent.Incr(int64(msg.Len()))
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (r *Record) CloseAsync() {
+	if err := r.recorder.Close(); err != nil {
+		r.log.Errorf("Failed to close recording: %v\n", err)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (r *Record) WaitForClose(timeout time.Duration) error {
+	return nil
+}
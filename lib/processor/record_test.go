@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+
+	conf := NewConfig()
+	conf.Record.Path = path
+	conf.Record.Redact = `root.id = this.id`
+
+	proc, err := NewRecord(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo","secret":"bar"}`),
+		[]byte(`not json`),
+	})
+	msgs, res := proc.ProcessMessage(msg)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"id":"foo","secret":"bar"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, `not json`, string(msgs[0].Get(1).Get()))
+	assert.Equal(t, "", GetFail(msgs[0].Get(1)))
+
+	proc.CloseAsync()
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"content":"eyJpZCI6ImZvbyJ9"`)
+	assert.NotContains(t, string(data), "secret")
+}
+
+func TestRecordConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	_, err := NewRecord(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a path must be specified")
+
+	conf.Record.Path = filepath.Join(t.TempDir(), "recording.jsonl")
+	conf.Record.Redact = `root = `
+	_, err = NewRecord(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
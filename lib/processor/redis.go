@@ -1,12 +1,15 @@
 package processor
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	bredis "github.com/Jeffail/benthos/v3/internal/service/redis"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -38,10 +41,30 @@ Returns the cardinality of a set, or ` + "`0`" + ` if the key does not exist.
 
 ### ` + "`sadd`" + `
 
-Adds a new member to a set. Returns ` + "`1`" + ` if the member was added.`,
+Adds a new member to a set. Returns ` + "`1`" + ` if the member was added.
+
+### ` + "`command`" + `
+
+Executes the command specified by the field ` + "`command`" + ` with the arguments produced by the mapping ` + "`args_mapping`" + `, which allows any Redis command to be executed, such as ` + "`hgetall`" + `, ` + "`zadd`" + ` or ` + "`lpush`" + `. The field ` + "`key`" + ` is ignored, and keys should instead be included within the arguments.
+
+The result of the command replaces the message as a JSON document, where strings, integers and arrays are converted to their JSON equivalents, including hashes which are returned as arrays of alternating fields and values, and results that don't exist, such as the value of a ` + "`get`" + ` command on a missing key, are written as ` + "`null`" + `. Errors returned by Redis are not retried, and cause the message to be flagged as having failed.
+
+## Batch Modes
+
+By default a command is executed for each message of a batch in turn, waiting for the result of each command before executing the next. When the ` + "`batch_mode`" + ` is ` + "`pipeline`" + ` the commands of all messages of a batch are instead sent to Redis at once within a [pipeline](https://redis.io/topics/pipelining), which greatly improves the throughput of large batches, especially against remote instances. When the ` + "`batch_mode`" + ` is ` + "`transaction`" + ` the pipeline is also wrapped in a [transaction](https://redis.io/topics/transactions) with ` + "`MULTI`" + ` and ` + "`EXEC`" + `, ensuring that no other clients execute commands in between them.
+
+In both modes, messages of commands that fail with an error returned by Redis are flagged as having failed individually, and when the pipeline fails as a whole, such as when the connection is lost, it is retried in its entirety.`,
 		FieldSpecs: bredis.ConfigDocs().Add(
-			docs.FieldCommon("operator", "The [operator](#operators) to apply.").HasOptions("scard", "sadd"),
+			docs.FieldCommon("operator", "The [operator](#operators) to apply.").HasOptions("scard", "sadd", "command"),
 			docs.FieldCommon("key", "A key to use for the target operator.").IsInterpolated(),
+			docs.FieldCommon("command", "The command to execute when the operator is `command`.", "hgetall", "zadd", "${! meta(\"command\") }").IsInterpolated().AtVersion("3.47.0"),
+			docs.FieldCommon(
+				"args_mapping",
+				"A [Bloblang mapping](/docs/guides/bloblang/about) that produces the arguments of the command when the operator is `command`. The mapping must return an array, where objects and arrays within it are converted to JSON strings.",
+				`root = [ meta("key") ]`,
+				`root = [ "scores", this.score, this.name ]`,
+			).HasType(docs.FieldString).Linter(docs.LintBloblangMapping).AtVersion("3.47.0"),
+			docs.FieldAdvanced("batch_mode", "Whether to execute the commands of all messages of a batch in a single round trip, and if so whether within a pipeline or a transaction. See [batch modes](#batch-modes).").HasOptions("none", "pipeline", "transaction").AtVersion("3.47.0"),
 			docs.FieldAdvanced("retries", "The maximum number of retries before abandoning a request."),
 			docs.FieldAdvanced("retry_period", "The time to wait before consecutive retry attempts."),
 			PartsFieldSpec,
@@ -64,6 +87,26 @@ pipeline:
               operator: scard
               key: ${! meta("set_key") }
         result_map: 'root.cardinality = this'
+`,
+			},
+			{
+				Title: "Hash Enrichment",
+				Summary: `
+The ` + "`command`" + ` operator can execute any command, here fields of a hash
+keyed by a user ID are added to each message:`,
+				Config: `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - redis:
+              url: TODO
+              operator: command
+              command: hmget
+              args_mapping: 'root = [ "user:" + this.user.id, "name", "email" ]'
+        result_map: |
+          root.user.name = this.index(0)
+          root.user.email = this.index(1)
 `,
 			},
 		},
@@ -78,6 +121,9 @@ type RedisConfig struct {
 	Parts         []int  `json:"parts" yaml:"parts"`
 	Operator      string `json:"operator" yaml:"operator"`
 	Key           string `json:"key" yaml:"key"`
+	Command       string `json:"command" yaml:"command"`
+	ArgsMapping   string `json:"args_mapping" yaml:"args_mapping"`
+	BatchMode     string `json:"batch_mode" yaml:"batch_mode"`
 	Retries       int    `json:"retries" yaml:"retries"`
 	RetryPeriod   string `json:"retry_period" yaml:"retry_period"`
 }
@@ -89,6 +135,9 @@ func NewRedisConfig() RedisConfig {
 		Parts:       []int{},
 		Operator:    "scard",
 		Key:         "",
+		Command:     "",
+		ArgsMapping: "",
+		BatchMode:   "none",
 		Retries:     3,
 		RetryPeriod: "500ms",
 	}
@@ -103,7 +152,9 @@ type Redis struct {
 	log   log.Modular
 	stats metrics.Type
 
-	key *field.Expression
+	key         *field.Expression
+	command     *field.Expression
+	argsMapping *mapping.Executor
 
 	operator    redisOperator
 	client      redis.UniversalClient
@@ -138,13 +189,31 @@ func NewRedis(
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
 
+	var command *field.Expression
+	var argsMapping *mapping.Executor
+	if conf.Redis.Operator == "command" {
+		if conf.Redis.Command == "" {
+			return nil, errors.New("a command must be specified when the operator is command")
+		}
+		if command, err = bloblang.NewField(conf.Redis.Command); err != nil {
+			return nil, fmt.Errorf("failed to parse command expression: %v", err)
+		}
+		if conf.Redis.ArgsMapping != "" {
+			if argsMapping, err = bloblang.NewMapping("", conf.Redis.ArgsMapping); err != nil {
+				return nil, fmt.Errorf("failed to parse `args_mapping`: %w", err)
+			}
+		}
+	}
+
 	r := &Redis{
 		parts: conf.Redis.Parts,
 		conf:  conf,
 		log:   log,
 		stats: stats,
 
-		key: key,
+		key:         key,
+		command:     command,
+		argsMapping: argsMapping,
 
 		retryPeriod: retryPeriod,
 		client:      client,
@@ -156,6 +225,11 @@ func NewRedis(
 		mRedisRetry: stats.GetCounter("redis.retry"),
 	}
 
+	switch conf.Redis.BatchMode {
+	case "none", "pipeline", "transaction":
+	default:
+		return nil, fmt.Errorf("batch mode not recognised: %v", conf.Redis.BatchMode)
+	}
 	if r.operator, err = getRedisOperator(conf.Redis.Operator); err != nil {
 		return nil, err
 	}
@@ -164,42 +238,167 @@ func NewRedis(
 
 //------------------------------------------------------------------------------
 
-type redisOperator func(r *Redis, key string, value []byte) ([]byte, error)
+// redisOperator describes how an operator forms the command to execute for a
+// message, and how the result of the command is written to the message.
+type redisOperator struct {
+	args   func(r *Redis, index int, msg types.Message) ([]interface{}, error)
+	result func(part types.Part, res interface{}) error
+}
+
+func redisIntResult(part types.Part, res interface{}) error {
+	i, ok := res.(int64)
+	if !ok {
+		return fmt.Errorf("expected integer result, got %T", res)
+	}
+	part.Set(strconv.AppendInt(nil, i, 10))
+	return nil
+}
 
 func newRedisSCardOperator() redisOperator {
-	return func(r *Redis, key string, value []byte) ([]byte, error) {
-		res, err := r.client.SCard(key).Result()
-
-		for i := 0; i <= r.conf.Redis.Retries && err != nil; i++ {
-			r.log.Errorf("SCard command failed: %v\n", err)
-			<-time.After(r.retryPeriod)
-			r.mRedisRetry.Incr(1)
-			res, err = r.client.SCard(key).Result()
-		}
+	return redisOperator{
+		args: func(r *Redis, index int, msg types.Message) ([]interface{}, error) {
+			return []interface{}{"scard", r.key.String(index, msg)}, nil
+		},
+		result: redisIntResult,
+	}
+}
 
-		if err != nil {
-			return nil, err
+func newRedisSAddOperator() redisOperator {
+	return redisOperator{
+		args: func(r *Redis, index int, msg types.Message) ([]interface{}, error) {
+			return []interface{}{"sadd", r.key.String(index, msg), msg.Get(index).Get()}, nil
+		},
+		result: redisIntResult,
+	}
+}
+
+func newRedisCommandOperator() redisOperator {
+	return redisOperator{
+		args: func(r *Redis, index int, msg types.Message) ([]interface{}, error) {
+			return r.redisCommandArgs(index, msg)
+		},
+		result: func(part types.Part, res interface{}) error {
+			return part.SetJSON(redisCommandResult(res))
+		},
+	}
+}
+
+// redisCommandArgs resolves the command and arguments of the command operator
+// for a message.
+func (r *Redis) redisCommandArgs(index int, msg types.Message) ([]interface{}, error) {
+	args := []interface{}{r.command.String(index, msg)}
+	if r.argsMapping == nil {
+		return args, nil
+	}
+
+	pargs, err := r.argsMapping.MapPart(index, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	iargs, err := pargs.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("mapping returned non-structured result: %w", err)
+	}
+
+	margs, ok := iargs.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mapping returned non-array result: %T", iargs)
+	}
+	for _, v := range margs {
+		switch t := v.(type) {
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, string(b))
+		case json.Number:
+			args = append(args, t.String())
+		case nil:
+			args = append(args, "")
+		default:
+			args = append(args, t)
 		}
-		return strconv.AppendInt(nil, res, 10), nil
 	}
+	return args, nil
 }
 
-func newRedisSAddOperator() redisOperator {
-	return func(r *Redis, key string, value []byte) ([]byte, error) {
-		res, err := r.client.SAdd(key, value).Result()
-
-		for i := 0; i <= r.conf.Redis.Retries && err != nil; i++ {
-			r.log.Errorf("SCard command failed: %v\n", err)
-			<-time.After(r.retryPeriod)
-			r.mRedisRetry.Incr(1)
-			res, err = r.client.SAdd(key, value).Result()
+// redisCommandResult converts the result of a command into a value that can be
+// serialised as JSON.
+func redisCommandResult(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		res := make([]interface{}, len(t))
+		for i, e := range t {
+			res[i] = redisCommandResult(e)
 		}
+		return res
+	case redis.Error:
+		return t.Error()
+	}
+	return v
+}
 
-		if err != nil {
-			return nil, err
+// redisRetryable returns whether a failed command should be retried, which
+// isn't the case for errors returned by Redis itself.
+func redisRetryable(err error) bool {
+	if err == nil || err == redis.Nil {
+		return false
+	}
+	_, isRedisErr := err.(redis.Error)
+	return !isRedisErr
+}
+
+// redisCmdResult returns the result of an executed command, where missing
+// results are nil.
+func redisCmdResult(cmd *redis.Cmd) (interface{}, error) {
+	res, err := cmd.Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return res, err
+}
+
+func (r *Redis) execCommand(args []interface{}) (interface{}, error) {
+	cmd := r.client.Do(args...)
+
+	for i := 0; i <= r.conf.Redis.Retries && redisRetryable(cmd.Err()); i++ {
+		r.log.Errorf("%v command failed: %v\n", args[0], cmd.Err())
+		<-time.After(r.retryPeriod)
+		r.mRedisRetry.Incr(1)
+		cmd = r.client.Do(args...)
+	}
+	return redisCmdResult(cmd)
+}
+
+// execBatch executes the commands of a batch within a single pipeline,
+// retrying the entire pipeline when it fails for reasons other than errors
+// returned by Redis for individual commands.
+func (r *Redis) execBatch(batchArgs [][]interface{}) []*redis.Cmd {
+	exec := func() ([]*redis.Cmd, error) {
+		var pipe redis.Pipeliner
+		if r.conf.Redis.BatchMode == "transaction" {
+			pipe = r.client.TxPipeline()
+		} else {
+			pipe = r.client.Pipeline()
 		}
-		return strconv.AppendInt(nil, res, 10), nil
+		cmds := make([]*redis.Cmd, len(batchArgs))
+		for i, args := range batchArgs {
+			cmds[i] = pipe.Do(args...)
+		}
+		_, err := pipe.Exec()
+		return cmds, err
+	}
+
+	cmds, err := exec()
+	for i := 0; i <= r.conf.Redis.Retries && redisRetryable(err); i++ {
+		r.log.Errorf("Pipeline of %v commands failed: %v\n", len(batchArgs), err)
+		<-time.After(r.retryPeriod)
+		r.mRedisRetry.Incr(1)
+		cmds, err = exec()
 	}
+	return cmds
 }
 
 func getRedisOperator(opStr string) (redisOperator, error) {
@@ -208,29 +407,74 @@ func getRedisOperator(opStr string) (redisOperator, error) {
 		return newRedisSAddOperator(), nil
 	case "scard":
 		return newRedisSCardOperator(), nil
+	case "command":
+		return newRedisCommandOperator(), nil
 	}
-	return nil, fmt.Errorf("operator not recognised: %v", opStr)
+	return redisOperator{}, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
-// ProcessMessage applies the processor to a message, either creating >0
-// resulting messages or a response to be sent back to the message source.
-func (r *Redis) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
-	r.mCount.Incr(1)
-	newMsg := msg.Copy()
+// processBatch queues the commands of all parts of a message and executes them
+// in a single round trip.
+func (r *Redis) processBatch(msg types.Message) {
+	var batchArgs [][]interface{}
+	cmdIndexes := map[int]int{}
 
-	proc := func(index int, span opentracing.Span, part types.Part) error {
-		key := r.key.String(index, newMsg)
-		res, err := r.operator(r, key, part.Get())
+	IteratePartsWithSpan(TypeRedis, r.parts, msg, func(index int, span opentracing.Span, part types.Part) error {
+		args, err := r.operator.args(r, index, msg)
 		if err != nil {
 			r.mErr.Incr(1)
-			r.log.Debugf("Operator failed for key '%s': %v\n", key, err)
+			r.log.Debugf("Failed to resolve command arguments: %v\n", err)
 			return err
 		}
-		part.Set(res)
+		cmdIndexes[index] = len(batchArgs)
+		batchArgs = append(batchArgs, args)
 		return nil
+	})
+	if len(batchArgs) == 0 {
+		return
 	}
 
-	IteratePartsWithSpan(TypeRedis, r.parts, newMsg, proc)
+	cmds := r.execBatch(batchArgs)
+	IteratePartsWithSpan(TypeRedis, r.parts, msg, func(index int, span opentracing.Span, part types.Part) error {
+		i, exists := cmdIndexes[index]
+		if !exists {
+			return nil
+		}
+		res, err := redisCmdResult(cmds[i])
+		if err != nil {
+			r.mErr.Incr(1)
+			r.log.Debugf("Command %v failed: %v\n", batchArgs[i][0], err)
+			return err
+		}
+		return r.operator.result(part, res)
+	})
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *Redis) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	if r.conf.Redis.BatchMode != "none" {
+		r.processBatch(newMsg)
+	} else {
+		IteratePartsWithSpan(TypeRedis, r.parts, newMsg, func(index int, span opentracing.Span, part types.Part) error {
+			args, err := r.operator.args(r, index, newMsg)
+			if err != nil {
+				r.mErr.Incr(1)
+				r.log.Debugf("Failed to resolve command arguments: %v\n", err)
+				return err
+			}
+			res, err := r.execCommand(args)
+			if err != nil {
+				r.mErr.Incr(1)
+				r.log.Debugf("Command %v failed: %v\n", args[0], err)
+				return err
+			}
+			return r.operator.result(part, res)
+		})
+	}
 
 	r.mBatchSent.Incr(1)
 	r.mSent.Incr(int64(newMsg.Len()))
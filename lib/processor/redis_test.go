@@ -68,6 +68,62 @@ func TestRedisIntegration(t *testing.T) {
 	t.Run("testRedisSCard", func(t *testing.T) {
 		testRedisSCard(t, client, urlStr)
 	})
+	t.Run("testRedisCommand", func(t *testing.T) {
+		testRedisCommand(t, client, urlStr, "none")
+	})
+	t.Run("testRedisCommandPipeline", func(t *testing.T) {
+		testRedisCommand(t, client, urlStr, "pipeline")
+	})
+	t.Run("testRedisCommandTransaction", func(t *testing.T) {
+		testRedisCommand(t, client, urlStr, "transaction")
+	})
+	t.Run("testRedisSAddPipeline", func(t *testing.T) {
+		testRedisSAddPipeline(t, client, urlStr)
+	})
+}
+
+func TestRedisCommandArgs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.Operator = "command"
+	conf.Redis.Command = `${! meta("command") }`
+	conf.Redis.ArgsMapping = `root = [ this.key, this.score, this.doc, null ]`
+
+	r, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`{"key":"foo","score":1.5,"doc":{"a":1}}`)})
+	msg.Get(0).Metadata().Set("command", "zadd")
+
+	args, err := r.(*Redis).redisCommandArgs(0, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []interface{}{"zadd", "foo", "1.5", `{"a":1}`, ""}
+	if !reflect.DeepEqual(exp, args) {
+		t.Errorf("Wrong args: %#v != %#v", args, exp)
+	}
+
+	conf.Redis.ArgsMapping = `root = this.key`
+	if r, err = NewRedis(conf, nil, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.(*Redis).redisCommandArgs(0, msg); err == nil {
+		t.Error("Expected error from non-array mapping")
+	}
+
+	conf.Redis.BatchMode = "nope"
+	if _, err = NewRedis(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unrecognised batch mode")
+	}
+
+	conf.Redis.BatchMode = "none"
+	conf.Redis.Command = ""
+	if _, err = NewRedis(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing command")
+	}
 }
 
 func testRedisSAdd(t *testing.T, client *redis.Client, url string) {
@@ -175,3 +231,99 @@ func testRedisSCard(t *testing.T, client *redis.Client, url string) {
 		t.Fatalf("Wrong result: %s != %s", act, exp)
 	}
 }
+
+func testRedisCommand(t *testing.T, client *redis.Client, url, batchMode string) {
+	hashKey := "hash_" + batchMode
+
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.URL = url
+	conf.Redis.BatchMode = batchMode
+	conf.Redis.Operator = "command"
+	conf.Redis.Command = `${! meta("command") }`
+	conf.Redis.ArgsMapping = `root = this`
+
+	r, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New(nil)
+	for _, c := range []struct {
+		command, args string
+	}{
+		{"hset", `["$KEY","foo","bar","baz","10"]`},
+		{"hgetall", `["$KEY"]`},
+		{"hincrby", `["$KEY","baz",5]`},
+		{"get", `["doesntexist"]`},
+		{"lpush", `["$KEY","nope"]`},
+	} {
+		part := message.NewPart([]byte(strings.ReplaceAll(c.args, "$KEY", hashKey)))
+		part.Metadata().Set("command", c.command)
+		msg.Append(part)
+	}
+
+	resMsgs, response := r.ProcessMessage(msg)
+	if response != nil {
+		t.Fatal("Expected nil response")
+	}
+	if len(resMsgs) != 1 {
+		t.Fatalf("Wrong resulting msgs: %v != %v", len(resMsgs), 1)
+	}
+
+	exp := []string{
+		`2`,
+		`["foo","bar","baz","10"]`,
+		`15`,
+		`null`,
+		`["hash1","nope"]`,
+	}
+	for i, e := range exp[:4] {
+		if act := string(resMsgs[0].Get(i).Get()); act != e {
+			t.Errorf("Wrong result %v: %s != %s", i, act, e)
+		}
+	}
+	if fail := GetFail(resMsgs[0].Get(4)); !strings.Contains(fail, "WRONGTYPE") {
+		t.Errorf("Expected WRONGTYPE error, got: %v", fail)
+	}
+}
+
+func testRedisSAddPipeline(t *testing.T, client *redis.Client, url string) {
+	conf := NewConfig()
+	conf.Type = TypeRedis
+	conf.Redis.URL = url
+	conf.Redis.BatchMode = "pipeline"
+	conf.Redis.Operator = "sadd"
+	conf.Redis.Key = "${! meta(\"key\") }"
+	conf.Redis.Parts = []int{0, 1, 2}
+
+	r, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`foo`),
+		[]byte(`untouched`),
+	})
+	msg.Get(0).Metadata().Set("key", "pipeset1")
+	msg.Get(1).Metadata().Set("key", "pipeset1")
+	msg.Get(2).Metadata().Set("key", "pipeset1")
+
+	resMsgs, response := r.ProcessMessage(msg)
+	if response != nil {
+		t.Fatal("Expected nil response")
+	}
+
+	exp := [][]byte{
+		[]byte(`1`),
+		[]byte(`1`),
+		[]byte(`0`),
+		[]byte(`untouched`),
+	}
+	if act := message.GetAllBytes(resMsgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Fatalf("Wrong result: %s != %s", act, exp)
+	}
+}
@@ -0,0 +1,349 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/schema"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeSchemaDrift] = TypeSpec{
+		constructor: NewSchemaDrift,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Infers a JSON schema from the structure of the first messages that it observes,
+and flags subsequent messages that drift from it.`,
+		Description: `
+While learning, messages pass through unchanged and the schema is widened in order to describe each of them. A field is required by the schema only when it is present in all of the observed objects, and a field that is observed with several types, such as a string and null, allows all of them. Learning ends once ` + "`learn_count`" + ` messages have been observed, or once ` + "`learn_period`" + ` has elapsed since the first message when it is set.
+
+Once the schema is learned each message is checked against it, and a message drifts when it contains a value of a type that was not observed, is missing a required field, or contains a field that was not observed. Drifting messages are flagged as having failed with a validation error, and the metadata field ` + "`schema_drift`" + ` is set to a description of the differences, which allows them to be routed or handled with [error handling patterns](/docs/configuration/error_handling).
+
+The learned schema is a [JSON Schema](https://json-schema.org/) document. When either ` + "`path` or `cache`" + ` is set the learned schema is stored there, and is loaded from there at startup when it exists, in which case learning is skipped. A stored schema can therefore be inspected, edited or deleted in order to learn a new one.
+
+Each instance of this processor learns a schema independently, and so when a pipeline has multiple threads a schema should be stored and loaded in order for them to share it.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Quarantine Drift",
+				Summary: "In this example we learn a schema from the first thousand events of a stream and store it in a file, and then route events that drift from it to a separate topic for inspection.",
+				Config: `
+pipeline:
+  processors:
+    - schema_drift:
+        learn_count: 1000
+        path: ./events_schema.json
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: events_drift
+          processors:
+            - bloblang: |
+                root.event = this
+                root.drift = meta("schema_drift")
+      - output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: events
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("learn_count", "The number of messages to observe before the schema is learned."),
+			docs.FieldCommon("learn_period", "An optional period of time after the first observed message at which the schema is learned, even if fewer than `learn_count` messages have been observed.", "1h", "24h"),
+			docs.FieldCommon("path", "An optional file path to store the learned schema in and load it from."),
+			docs.FieldAdvanced("cache", "An optional [`cache` resource](/docs/components/caches/about) to store the learned schema in and load it from."),
+			docs.FieldAdvanced("cache_key", "The key of the learned schema within the cache."),
+			docs.FieldAdvanced("allow_new_fields", "Whether fields that were not observed while learning are allowed."),
+			docs.FieldAdvanced("allow_missing_fields", "Whether required fields are allowed to be missing."),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaDriftConfig contains configuration fields for the SchemaDrift
+// processor.
+type SchemaDriftConfig struct {
+	Parts              []int  `json:"parts" yaml:"parts"`
+	LearnCount         int    `json:"learn_count" yaml:"learn_count"`
+	LearnPeriod        string `json:"learn_period" yaml:"learn_period"`
+	Path               string `json:"path" yaml:"path"`
+	Cache              string `json:"cache" yaml:"cache"`
+	CacheKey           string `json:"cache_key" yaml:"cache_key"`
+	AllowNewFields     bool   `json:"allow_new_fields" yaml:"allow_new_fields"`
+	AllowMissingFields bool   `json:"allow_missing_fields" yaml:"allow_missing_fields"`
+}
+
+// NewSchemaDriftConfig returns a SchemaDriftConfig with default values.
+func NewSchemaDriftConfig() SchemaDriftConfig {
+	return SchemaDriftConfig{
+		Parts:              []int{},
+		LearnCount:         100,
+		LearnPeriod:        "",
+		Path:               "",
+		Cache:              "",
+		CacheKey:           "schema",
+		AllowNewFields:     false,
+		AllowMissingFields: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaDrift is a processor that learns a JSON schema from messages and flags
+// messages that drift from it.
+type SchemaDrift struct {
+	parts       []int
+	learnCount  int
+	learnPeriod time.Duration
+	checkOpts   schema.CheckOptions
+
+	path      string
+	cacheName string
+	cacheKey  string
+
+	mut        sync.Mutex
+	loaded     bool
+	learned    bool
+	observed   int
+	learnStart time.Time
+	schema     *schema.Schema
+
+	conf  Config
+	mgr   types.Manager
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mLearned   metrics.StatCounter
+	mDrift     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaDrift returns a SchemaDrift processor.
+func NewSchemaDrift(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.SchemaDrift.LearnCount <= 0 {
+		return nil, errors.New("learn_count must be greater than zero")
+	}
+
+	p := &SchemaDrift{
+		parts:      conf.SchemaDrift.Parts,
+		learnCount: conf.SchemaDrift.LearnCount,
+		checkOpts: schema.CheckOptions{
+			AllowNewFields:     conf.SchemaDrift.AllowNewFields,
+			AllowMissingFields: conf.SchemaDrift.AllowMissingFields,
+		},
+
+		path:      conf.SchemaDrift.Path,
+		cacheName: conf.SchemaDrift.Cache,
+		cacheKey:  conf.SchemaDrift.CacheKey,
+
+		schema: schema.New(),
+
+		conf:  conf,
+		mgr:   mgr,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mLearned:   stats.GetCounter("learned"),
+		mDrift:     stats.GetCounter("drift"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	if conf.SchemaDrift.LearnPeriod != "" {
+		var err error
+		if p.learnPeriod, err = time.ParseDuration(conf.SchemaDrift.LearnPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse learn_period: %v", err)
+		}
+	}
+	if p.cacheName != "" {
+		if err := interop.ProbeCache(context.Background(), mgr, p.cacheName); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// load attempts to read a stored schema from the file path or cache, returning
+// nil if none is stored.
+func (p *SchemaDrift) load() (*schema.Schema, error) {
+	var schemaBytes []byte
+	if p.path != "" {
+		b, err := ioutil.ReadFile(p.path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		schemaBytes = b
+	}
+	if schemaBytes == nil && p.cacheName != "" {
+		var err error
+		if cerr := interop.AccessCache(context.Background(), p.mgr, p.cacheName, func(c types.Cache) {
+			schemaBytes, err = c.Get(p.cacheKey)
+		}); cerr != nil {
+			err = cerr
+		}
+		if err != nil && !errors.Is(err, types.ErrKeyNotFound) {
+			return nil, err
+		}
+	}
+	if schemaBytes == nil {
+		return nil, nil
+	}
+	return schema.Parse(schemaBytes)
+}
+
+// store writes the learned schema to the file path and cache.
+func (p *SchemaDrift) store() error {
+	schemaBytes, err := json.Marshal(p.schema)
+	if err != nil {
+		return err
+	}
+	if p.path != "" {
+		if err := ioutil.WriteFile(p.path, schemaBytes, 0644); err != nil {
+			return err
+		}
+	}
+	if p.cacheName != "" {
+		if cerr := interop.AccessCache(context.Background(), p.mgr, p.cacheName, func(c types.Cache) {
+			err = c.Set(p.cacheKey, schemaBytes)
+		}); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (p *SchemaDrift) loadOnce() {
+	if p.loaded {
+		return
+	}
+	p.loaded = true
+	if p.path == "" && p.cacheName == "" {
+		return
+	}
+	s, err := p.load()
+	if err != nil {
+		p.log.Errorf("Failed to load stored schema, a new schema will be learned: %v\n", err)
+		return
+	}
+	if s != nil {
+		p.schema = s
+		p.learned = true
+		p.log.Infof("Loaded stored schema, learning is skipped\n")
+	}
+}
+
+func (p *SchemaDrift) finishLearning() {
+	p.learned = true
+	p.mLearned.Incr(1)
+	p.log.Infof("Learned schema from %v messages\n", p.observed)
+	if err := p.store(); err != nil {
+		p.log.Errorf("Failed to store learned schema: %v\n", err)
+	}
+}
+
+func (p *SchemaDrift) process(part types.Part) error {
+	doc, err := part.JSON()
+	if err != nil {
+		return err
+	}
+
+	if !p.learned {
+		now := time.Now()
+		if p.observed == 0 {
+			p.learnStart = now
+		}
+		if p.learnPeriod > 0 && p.observed > 0 && now.Sub(p.learnStart) >= p.learnPeriod {
+			// The period has elapsed and so this message is checked instead.
+			p.finishLearning()
+		} else {
+			p.schema.Observe(doc)
+			if p.observed++; p.observed >= p.learnCount {
+				p.finishLearning()
+			}
+			return nil
+		}
+	}
+
+	drifts := p.schema.Check(doc, p.checkOpts)
+	if len(drifts) == 0 {
+		return nil
+	}
+	p.mDrift.Incr(1)
+
+	descriptions := make([]string, len(drifts))
+	for i, d := range drifts {
+		descriptions[i] = d.Description
+	}
+	desc := strings.Join(descriptions, "; ")
+	part.Metadata().Set("schema_drift", desc)
+	return types.ErrValidation{S: "message drifted from the learned schema: " + desc}
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *SchemaDrift) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.loadOnce()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.process(part); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Schema drift check failed: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeSchemaDrift, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *SchemaDrift) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *SchemaDrift) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDriftLearnAndCheck(t *testing.T) {
+	conf := NewConfig()
+	conf.SchemaDrift.LearnCount = 2
+
+	proc, err := NewSchemaDrift(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo"}`),
+		[]byte(`{"id":2,"name":"bar","opt":true}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "", GetFail(msgsOut[0].Get(i)))
+	}
+
+	msgsOut, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":3,"name":"baz"}`),
+		[]byte(`{"id":"4","extra":5}`),
+		[]byte(`not json`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(0)))
+	assert.Equal(t, "", msgsOut[0].Get(0).Metadata().Get("schema_drift"))
+
+	assert.Equal(t, `{"id":"4","extra":5}`, string(msgsOut[0].Get(1).Get()))
+	assert.Equal(t, "name is missing; extra is new; id has type string, expected integer", msgsOut[0].Get(1).Metadata().Get("schema_drift"))
+	assert.Equal(t, "message drifted from the learned schema: name is missing; extra is new; id has type string, expected integer", GetFail(msgsOut[0].Get(1)))
+	assert.Equal(t, ErrorKindValidation, msgsOut[0].Get(1).Metadata().Get(types.FailKindKey))
+
+	assert.NotEqual(t, "", GetFail(msgsOut[0].Get(2)))
+	assert.Equal(t, "", msgsOut[0].Get(2).Metadata().Get("schema_drift"))
+}
+
+func TestSchemaDriftAllowFields(t *testing.T) {
+	conf := NewConfig()
+	conf.SchemaDrift.LearnCount = 1
+	conf.SchemaDrift.AllowNewFields = true
+	conf.SchemaDrift.AllowMissingFields = true
+
+	proc, err := NewSchemaDrift(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo"}`),
+		[]byte(`{"id":2,"extra":true}`),
+		[]byte(`{"id":2.5}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(0)))
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(1)))
+	assert.Equal(t, "id has type number, expected integer", msgsOut[0].Get(2).Metadata().Get("schema_drift"))
+}
+
+func TestSchemaDriftStorePath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_schema_drift_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	conf := NewConfig()
+	conf.SchemaDrift.LearnCount = 1
+	conf.SchemaDrift.Path = filepath.Join(tmpDir, "schema.json")
+
+	proc, err := NewSchemaDrift(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"id":1}`)}))
+	require.Nil(t, res)
+
+	schemaBytes, err := ioutil.ReadFile(conf.SchemaDrift.Path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","required":["id"],"properties":{"id":{"type":"integer"}}}`, string(schemaBytes))
+
+	// A new processor loads the stored schema rather than learning.
+	conf.SchemaDrift.LearnCount = 10
+	proc, err = NewSchemaDrift(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"id":"nope"}`)}))
+	require.Nil(t, res)
+	assert.Equal(t, "id has type string, expected integer", msgsOut[0].Get(0).Metadata().Get("schema_drift"))
+}
+
+func TestSchemaDriftStoreCache(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	require.NoError(t, memCache.Set("events", []byte(`{"type":"object","properties":{"id":{"type":"string"}},"required":["id"]}`)))
+
+	conf := NewConfig()
+	conf.SchemaDrift.Cache = "foocache"
+	conf.SchemaDrift.CacheKey = "events"
+
+	proc, err := NewSchemaDrift(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{}`),
+	}))
+	require.Nil(t, res)
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(0)))
+	assert.Equal(t, "id is missing", msgsOut[0].Get(1).Metadata().Get("schema_drift"))
+
+	conf.SchemaDrift.Cache = "nope"
+	_, err = NewSchemaDrift(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -33,7 +33,11 @@ output message) the batch is dropped entirely.
 Message indexes can be negative, and if so the part will be selected from the
 end counting backwards starting from -1. E.g. if index = -1 then the selected
 part will be the last part of the message, if index = -2 then the part before
-the last element with be selected, and so on.`,
+the last element with be selected, and so on.
+
+For reshaping batches in ways that depend on the contents of their messages use
+the ` + "[`batch_mapping`](/docs/components/processors/batch_mapping)" + `
+processor instead.`,
 		UsesBatches: true,
 		FieldSpecs: docs.FieldSpecs{
 			PartsFieldSpec,
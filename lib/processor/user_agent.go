@@ -0,0 +1,344 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/useragent"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeUserAgent] = TypeSpec{
+		constructor: NewUserAgent,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryParsing,
+		},
+		Summary: `
+Parses the user agent field of JSON documents into the browser, operating system
+and device that sent it.`,
+		Description: `
+The user agent at the ` + "`field`" + ` path of each document is parsed and the result is written to the ` + "`target_field`" + ` path, resulting in an object such as:
+
+` + "```json" + `
+{
+  "browser": { "family": "Chrome Mobile", "version": "91.0.4472", "major": "91", "minor": "0", "patch": "4472" },
+  "os": { "family": "Android", "version": "11", "major": "11" },
+  "device": { "family": "Samsung SM-G991B", "brand": "Samsung", "model": "SM-G991B", "type": "mobile" }
+}
+` + "```" + `
+
+Unknown families are ` + "`Other`" + `, and absent versions, brands and models are omitted. The ` + "`type`" + ` of a device classifies it as one of ` + "`desktop`, `mobile`, `tablet`, `tv`, `console`, `bot` or `other`" + `. Documents where the field is missing or is not a string are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+The same parsing is available within [Bloblang](/docs/guides/bloblang/about) with the method ` + "[`parse_user_agent`](/docs/guides/bloblang/methods#parse_user_agent)" + `.
+
+### Regexes
+
+User agents are parsed with a database of patterns of common browsers, crawlers, operating systems and devices that is embedded within Benthos. In order to recognise newer or less common user agents a database in the format of [uap-core](https://github.com/ua-parser/uap-core) can be used instead by setting ` + "`regexes_file`" + ` to the path of a ` + "`regexes.yaml`" + ` file. Patterns of the database that aren't supported by the [regular expression syntax of Go](https://golang.org/s/re2syntax) are skipped with a warning.
+
+The modification time and size of the regexes file are checked every ` + "`reload_interval`" + `, and the database is reloaded when it has changed, allowing it to be updated without restarting Benthos. When a changed file fails to load the previous database continues to be used until the next check.
+
+### Caching
+
+The results of the most recently parsed user agents are kept in a least recently used cache of up to ` + "`cache_size`" + ` entries, which is cleared whenever the database is reloaded.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Clickstream Devices",
+				Summary: "This example parses the user agent of clickstream events, drops events of crawlers and adds the device type as a metadata field in order to write events to a topic per type of device.",
+				Config: `
+pipeline:
+  processors:
+    - user_agent:
+        field: request.headers.user_agent
+        target_field: agent
+    - bloblang: |
+        root = if this.agent.device.type == "bot" { deleted() }
+        meta device_type = this.agent.device.type
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: clicks_${! meta("device_type") }
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("field", "A [dot path](/docs/configuration/field_paths) pointing to the user agent of each document."),
+			docs.FieldCommon("target_field", "A [dot path](/docs/configuration/field_paths) pointing to where the result is written."),
+			docs.FieldAdvanced("regexes_file", "An optional path to a regexes database in the format of uap-core to parse user agents with instead of the embedded database."),
+			docs.FieldAdvanced("reload_interval", "The period between checks of whether the regexes file has changed, or an empty string to disable reloading."),
+			docs.FieldAdvanced("cache_size", "The maximum number of parsed user agents to cache, or zero to disable caching."),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// UserAgentConfig contains configuration fields for the UserAgent processor.
+type UserAgentConfig struct {
+	Parts          []int  `json:"parts" yaml:"parts"`
+	Field          string `json:"field" yaml:"field"`
+	TargetField    string `json:"target_field" yaml:"target_field"`
+	RegexesFile    string `json:"regexes_file" yaml:"regexes_file"`
+	ReloadInterval string `json:"reload_interval" yaml:"reload_interval"`
+	CacheSize      int    `json:"cache_size" yaml:"cache_size"`
+}
+
+// NewUserAgentConfig returns a UserAgentConfig with default values.
+func NewUserAgentConfig() UserAgentConfig {
+	return UserAgentConfig{
+		Parts:          []int{},
+		Field:          "",
+		TargetField:    "user_agent",
+		RegexesFile:    "",
+		ReloadInterval: "1m",
+		CacheSize:      1000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// UserAgent is a processor that parses user agents.
+type UserAgent struct {
+	parts       []int
+	field       []string
+	targetField []string
+
+	parserMut   sync.RWMutex
+	parser      *useragent.Parser
+	fileModTime time.Time
+	fileSize    int64
+	cache       *lruCache
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mReload    metrics.StatCounter
+	mReloadErr metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewUserAgent returns a UserAgent processor.
+func NewUserAgent(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	uConf := conf.UserAgent
+	if uConf.Field == "" {
+		return nil, errors.New("a field must be specified")
+	}
+
+	var reloadInterval time.Duration
+	if uConf.RegexesFile != "" && uConf.ReloadInterval != "" {
+		var err error
+		if reloadInterval, err = time.ParseDuration(uConf.ReloadInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse reload_interval: %v", err)
+		}
+	}
+
+	u := &UserAgent{
+		parts:       uConf.Parts,
+		field:       gabs.DotPathToSlice(uConf.Field),
+		targetField: gabs.DotPathToSlice(uConf.TargetField),
+		parser:      useragent.Default(),
+		cache:       newLRUCache(uConf.CacheSize),
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mReload:    stats.GetCounter("reload.success"),
+		mReloadErr: stats.GetCounter("reload.error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	if uConf.RegexesFile != "" {
+		info, err := os.Stat(uConf.RegexesFile)
+		if err != nil {
+			return nil, err
+		}
+		if u.parser, err = u.loadParser(); err != nil {
+			return nil, err
+		}
+		u.fileModTime, u.fileSize = info.ModTime(), info.Size()
+	}
+
+	if reloadInterval > 0 {
+		go u.reloadLoop(reloadInterval)
+	} else {
+		close(u.closedChan)
+	}
+	return u, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (u *UserAgent) loadParser() (*useragent.Parser, error) {
+	path := u.conf.UserAgent.RegexesFile
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := useragent.NewParser(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read regexes file %v: %w", path, err)
+	}
+	if len(parser.Skipped) > 0 {
+		u.log.Warnf("Skipping %v unsupported patterns of regexes file %v: %v\n", len(parser.Skipped), path, parser.Skipped)
+	}
+	return parser, nil
+}
+
+func (u *UserAgent) reloadLoop(interval time.Duration) {
+	defer close(u.closedChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			u.reload()
+		case <-u.closeChan:
+			return
+		}
+	}
+}
+
+// reload replaces the parser when the regexes file has changed since it was
+// loaded.
+func (u *UserAgent) reload() {
+	path := u.conf.UserAgent.RegexesFile
+	info, err := os.Stat(path)
+	if err != nil {
+		u.mReloadErr.Incr(1)
+		u.log.Errorf("Failed to check regexes file %v for changes: %v\n", path, err)
+		return
+	}
+
+	u.parserMut.RLock()
+	unchanged := info.ModTime().Equal(u.fileModTime) && info.Size() == u.fileSize
+	u.parserMut.RUnlock()
+	if unchanged {
+		return
+	}
+
+	parser, err := u.loadParser()
+	if err != nil {
+		u.mReloadErr.Incr(1)
+		u.log.Errorf("Failed to reload regexes, continuing with the previous version: %v\n", err)
+		return
+	}
+	u.mReload.Incr(1)
+	u.log.Infof("Reloaded regexes file %v\n", path)
+
+	// Results cached from the previous parser are purged while holding the
+	// lock so that parses in progress can't add them back.
+	u.parserMut.Lock()
+	u.parser = parser
+	u.fileModTime, u.fileSize = info.ModTime(), info.Size()
+	u.cache.purge()
+	u.parserMut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+func (u *UserAgent) parse(ua string) useragent.Result {
+	if res, exists := u.cache.get(ua); exists {
+		return res.(useragent.Result)
+	}
+
+	u.parserMut.RLock()
+	defer u.parserMut.RUnlock()
+
+	res := u.parser.Parse(ua)
+	u.cache.add(ua, res)
+	return res
+}
+
+func (u *UserAgent) enrich(part types.Part) error {
+	jObj, err := part.JSON()
+	if err != nil {
+		return err
+	}
+
+	ua, ok := gabs.Wrap(jObj).S(u.field...).Data().(string)
+	if !ok {
+		return fmt.Errorf("field %v is missing or is not a string", u.conf.UserAgent.Field)
+	}
+	res := u.parse(ua)
+
+	if jObj, err = message.CopyJSON(jObj); err != nil {
+		return err
+	}
+	gObj := gabs.Wrap(jObj)
+	if _, err := gObj.Set(res.ToMap(), u.targetField...); err != nil {
+		return err
+	}
+	return part.SetJSON(gObj.Data())
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (u *UserAgent) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	u.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := u.enrich(part); err != nil {
+			u.mErr.Incr(1)
+			u.log.Debugf("Failed to parse user agent: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeUserAgent, u.parts, newMsg, proc)
+
+	u.mBatchSent.Incr(1)
+	u.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (u *UserAgent) CloseAsync() {
+	select {
+	case <-u.closeChan:
+	default:
+		close(u.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (u *UserAgent) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-u.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
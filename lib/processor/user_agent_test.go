@@ -0,0 +1,111 @@
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAgent(t *testing.T) {
+	conf := NewConfig()
+	conf.UserAgent.Field = "headers.ua"
+	conf.UserAgent.TargetField = "agent"
+
+	proc, err := NewUserAgent(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		require.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"headers":{"ua":"Mozilla/5.0 (Linux; Android 11; SM-G991B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36"}}`),
+		[]byte(`{"headers":{"ua":"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}}`),
+		[]byte(`{"headers":{}}`),
+		[]byte(`not json`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+	out := msgsOut[0]
+
+	assert.JSONEq(t, `{
+		"headers": {"ua":"Mozilla/5.0 (Linux; Android 11; SM-G991B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36"},
+		"agent": {
+			"browser": {"family":"Chrome Mobile","version":"91.0.4472","major":"91","minor":"0","patch":"4472"},
+			"os": {"family":"Android","version":"11","major":"11"},
+			"device": {"family":"Samsung SM-G991B","brand":"Samsung","model":"SM-G991B","type":"mobile"}
+		}
+	}`, string(out.Get(0).Get()))
+	assert.Equal(t, "", GetFail(out.Get(0)))
+
+	jObj, err := out.Get(1).JSON()
+	require.NoError(t, err)
+	agent := jObj.(map[string]interface{})["agent"].(map[string]interface{})
+	assert.Equal(t, "bot", agent["device"].(map[string]interface{})["type"])
+
+	assert.Equal(t, "field headers.ua is missing or is not a string", GetFail(out.Get(2)))
+	assert.NotEqual(t, "", GetFail(out.Get(3)))
+}
+
+func TestUserAgentRegexesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regexes.yaml")
+	writeRegexes := func(family string, modTime time.Time) {
+		t.Helper()
+		require.NoError(t, ioutil.WriteFile(path, []byte(`
+user_agent_parsers:
+  - regex: '(acme)/(\d+)'
+    family_replacement: '`+family+`'
+`), 0o644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	writeRegexes("Acme", time.Now())
+
+	conf := NewConfig()
+	conf.UserAgent.Field = "ua"
+	conf.UserAgent.RegexesFile = path
+	conf.UserAgent.ReloadInterval = ""
+
+	proc, err := NewUserAgent(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	u := proc.(*UserAgent)
+
+	browserOf := func(ua string) interface{} {
+		t.Helper()
+		msgsOut, _ := u.ProcessMessage(message.New([][]byte{[]byte(`{"ua":"` + ua + `"}`)}))
+		jObj, err := msgsOut[0].Get(0).JSON()
+		require.NoError(t, err)
+		return jObj.(map[string]interface{})["user_agent"].(map[string]interface{})["browser"]
+	}
+	assert.Equal(t, map[string]interface{}{"family": "Acme", "version": "2", "major": "2"}, browserOf("acme/2"))
+
+	// The embedded database is replaced entirely.
+	assert.Equal(t, map[string]interface{}{"family": "Other"}, browserOf("curl/7.68.0"))
+
+	// Invalid regexes are ignored.
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{{{`), 0o644))
+	u.reload()
+	assert.Equal(t, map[string]interface{}{"family": "Acme", "version": "2", "major": "2"}, browserOf("acme/2"))
+
+	writeRegexes("Acme Agent", time.Now().Add(time.Minute))
+	u.reload()
+	assert.Equal(t, map[string]interface{}{"family": "Acme Agent", "version": "2", "major": "2"}, browserOf("acme/2"))
+}
+
+func TestUserAgentBadConfig(t *testing.T) {
+	conf := NewConfig()
+	_, err := NewUserAgent(conf, nil, log.Noop(), metrics.Noop())
+	assert.EqualError(t, err, "a field must be specified")
+
+	conf.UserAgent.Field = "ua"
+	conf.UserAgent.RegexesFile = filepath.Join(t.TempDir(), "nope.yaml")
+	_, err = NewUserAgent(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
@@ -1,6 +1,13 @@
 package processor
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -133,7 +140,71 @@ func FlagFail(part types.Part) {
 func FlagErr(part types.Part, err error) {
 	if err != nil {
 		part.Metadata().Set(FailFlagKey, err.Error())
+		if class := errorClass(err); class != "" {
+			part.Metadata().Set(types.FailClassKey, class)
+		}
+		if kind := errorKind(err); kind != "" {
+			part.Metadata().Set(types.FailKindKey, kind)
+		}
+	}
+}
+
+// errorClass returns the type name of the root cause of an error, or an empty
+// string if the error is of a generic type.
+func errorClass(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			break
+		}
+		err = next
+	}
+	class := strings.TrimPrefix(fmt.Sprintf("%T", err), "*")
+	switch class {
+	case "errors.errorString", "fmt.wrapError", "fmt.wrapErrors":
+		return ""
+	}
+	return class
+}
+
+// Error kinds that broadly describe the cause of a processing error.
+const (
+	ErrorKindTimeout    = "timeout"
+	ErrorKindHTTP4xx    = "http_4xx"
+	ErrorKindHTTP5xx    = "http_5xx"
+	ErrorKindValidation = "validation"
+	ErrorKindParse      = "parse"
+)
+
+// errorKind returns the kind of an error, or an empty string if the error
+// does not match a known kind.
+func errorKind(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, types.ErrTimeout) {
+		return ErrorKindTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorKindTimeout
+	}
+	var httpErr types.ErrUnexpectedHTTPRes
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.Code >= 400 && httpErr.Code < 500:
+			return ErrorKindHTTP4xx
+		case httpErr.Code >= 500 && httpErr.Code < 600:
+			return ErrorKindHTTP5xx
+		}
+	}
+	var validationErr types.ErrValidation
+	if errors.As(err, &validationErr) {
+		return ErrorKindValidation
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return ErrorKindParse
 	}
+	return ""
 }
 
 // GetFail returns an error string for a message part if it has failed, or an
@@ -147,9 +218,16 @@ func HasFailed(part types.Part) bool {
 	return len(part.Metadata().Get(FailFlagKey)) > 0
 }
 
-// ClearFail removes any existing failure flags from a message part.
+// ClearFail removes any existing failure flags from a message part, along with
+// the structured metadata of the failure.
 func ClearFail(part types.Part) {
-	part.Metadata().Delete(FailFlagKey)
+	part.Metadata().
+		Delete(FailFlagKey).
+		Delete(types.FailSourceKey).
+		Delete(types.FailClassKey).
+		Delete(types.FailKindKey).
+		Delete(types.FailAttemptsKey).
+		Delete(types.FailPayloadHashKey)
 }
 
 //------------------------------------------------------------------------------
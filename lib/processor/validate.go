@@ -0,0 +1,314 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+func init() {
+	Constructors[TypeValidate] = TypeSpec{
+		constructor: NewValidate,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Evaluates a set of named data quality rules against each message, attaching a
+report of the results as metadata and optionally quarantining messages that fail.`,
+		Description: `
+Each rule is a [Bloblang query](/docs/guides/bloblang/about/) that should return a boolean value indicating whether a message passes the rule. A rule that returns false or fails to execute, for example because a field does not exist, is failed by the message. All rules are evaluated for every message, and the following metadata fields are added:
+
+` + "``` text" + `
+- validation_status
+- validation_report
+` + "```" + `
+
+The field ` + "`validation_status`" + ` is ` + "`pass`" + ` when a message passes all rules and ` + "`fail`" + ` otherwise, and ` + "`validation_report`" + ` is a JSON object that maps the name of each rule to whether the message passed it, e.g. ` + "`{\"has_id\":true,\"positive_amount\":false}`" + `.
+
+Messages that fail any rule are flagged as having failed with a validation error, which can be handled with [error handling patterns](/docs/configuration/error_handling). When ` + "`quarantine`" + ` is set to the name of an [output resource](/docs/configuration/resources) failed messages are instead removed from the batch and written to that output. If the write fails the messages remain in the batch.
+
+## Metrics
+
+For each rule the counters ` + "`rule.<name>.passed` and `rule.<name>.failed`" + ` are incremented for each message that passes or fails it respectively, which allows the quality of a stream to be monitored for each rule.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Quarantine Invalid Orders",
+				Summary: "In this example orders are validated against a set of rules and those that fail are written to a separate topic along with a report of the rules that they failed, while valid orders continue through the pipeline.",
+				Config: `
+pipeline:
+  processors:
+    - validate:
+        rules:
+          - name: has_id
+            check: this.id.type() == "string" && this.id != ""
+          - name: positive_amount
+            check: this.amount > 0
+          - name: known_currency
+            check: '["EUR","GBP","USD"].contains(this.currency)'
+        quarantine: quarantined_orders
+
+resources:
+  outputs:
+    quarantined_orders:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: orders_quarantine
+      processors:
+        - bloblang: |
+            root.order = this
+            root.report = meta("validation_report").parse_json()
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("rules", "A list of named rules to evaluate against each message.").Array().WithChildren(
+				docs.FieldCommon("name", "A unique name of the rule, which is used within the report and metrics of the rule.").HasType(docs.FieldString).HasDefault(""),
+				docs.FieldCommon(
+					"check", "A [Bloblang query](/docs/guides/bloblang/about/) that should return a boolean value indicating whether a message passes the rule.",
+					`this.amount > 0`,
+					`this.email.re_match("^[^@]+@[^@]+$")`,
+				).HasType(docs.FieldString).HasDefault("").Linter(docs.LintBloblangMapping),
+			),
+			docs.FieldCommon("quarantine", "An optional [output resource](/docs/configuration/resources) to write messages that fail any rule to, rather than passing them on flagged as failed."),
+			PartsFieldSpec,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ValidateRuleConfig contains configuration fields for a rule of the Validate
+// processor.
+type ValidateRuleConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Check string `json:"check" yaml:"check"`
+}
+
+// ValidateConfig contains configuration fields for the Validate processor.
+type ValidateConfig struct {
+	Parts      []int                `json:"parts" yaml:"parts"`
+	Rules      []ValidateRuleConfig `json:"rules" yaml:"rules"`
+	Quarantine string               `json:"quarantine" yaml:"quarantine"`
+}
+
+// NewValidateConfig returns a ValidateConfig with default values.
+func NewValidateConfig() ValidateConfig {
+	return ValidateConfig{
+		Parts:      []int{},
+		Rules:      []ValidateRuleConfig{},
+		Quarantine: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type validateRule struct {
+	name  string
+	check *mapping.Executor
+
+	mPassed metrics.StatCounter
+	mFailed metrics.StatCounter
+}
+
+// Validate is a processor that evaluates data quality rules against messages.
+type Validate struct {
+	parts      []int
+	rules      []validateRule
+	quarantine string
+
+	conf  Config
+	mgr   types.Manager
+	log   log.Modular
+	stats metrics.Type
+
+	mCount         metrics.StatCounter
+	mPassed        metrics.StatCounter
+	mFailed        metrics.StatCounter
+	mQuarantined   metrics.StatCounter
+	mErrQuarantine metrics.StatCounter
+	mSent          metrics.StatCounter
+	mBatchSent     metrics.StatCounter
+}
+
+// NewValidate returns a Validate processor.
+func NewValidate(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.Validate.Rules) == 0 {
+		return nil, errors.New("at least one rule must be specified")
+	}
+
+	p := &Validate{
+		parts:      conf.Validate.Parts,
+		quarantine: conf.Validate.Quarantine,
+
+		conf:  conf,
+		mgr:   mgr,
+		log:   log,
+		stats: stats,
+
+		mCount:         stats.GetCounter("count"),
+		mPassed:        stats.GetCounter("passed"),
+		mFailed:        stats.GetCounter("failed"),
+		mQuarantined:   stats.GetCounter("quarantined"),
+		mErrQuarantine: stats.GetCounter("error.quarantine"),
+		mSent:          stats.GetCounter("sent"),
+		mBatchSent:     stats.GetCounter("batch.sent"),
+	}
+
+	names := map[string]struct{}{}
+	for i, ruleConf := range conf.Validate.Rules {
+		if ruleConf.Name == "" {
+			return nil, fmt.Errorf("rule %v: a name must be specified", i)
+		}
+		if _, exists := names[ruleConf.Name]; exists {
+			return nil, fmt.Errorf("rule %v: name '%v' is not unique", i, ruleConf.Name)
+		}
+		names[ruleConf.Name] = struct{}{}
+
+		check, err := bloblang.NewMapping("", ruleConf.Check)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rule '%v' check: %w", ruleConf.Name, err)
+		}
+		p.rules = append(p.rules, validateRule{
+			name:    ruleConf.Name,
+			check:   check,
+			mPassed: stats.GetCounter(fmt.Sprintf("rule.%v.passed", ruleConf.Name)),
+			mFailed: stats.GetCounter(fmt.Sprintf("rule.%v.failed", ruleConf.Name)),
+		})
+	}
+
+	if p.quarantine != "" {
+		if err := interop.ProbeOutput(context.Background(), mgr, p.quarantine); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// validatePart evaluates all rules against a message part, adds the report
+// metadata and returns an error when any rule fails.
+func (p *Validate) validatePart(index int, msg types.Message) error {
+	report := make(map[string]bool, len(p.rules))
+	var failed []string
+	for _, rule := range p.rules {
+		passed, err := rule.check.QueryPart(index, msg)
+		if err != nil {
+			p.log.Debugf("Rule '%v' failed to execute: %v\n", rule.name, err)
+		}
+		report[rule.name] = passed
+		if passed {
+			rule.mPassed.Incr(1)
+		} else {
+			rule.mFailed.Incr(1)
+			failed = append(failed, rule.name)
+		}
+	}
+
+	part := msg.Get(index)
+	reportBytes, _ := json.Marshal(report)
+	part.Metadata().Set("validation_report", string(reportBytes))
+	if len(failed) == 0 {
+		part.Metadata().Set("validation_status", "pass")
+		return nil
+	}
+	part.Metadata().Set("validation_status", "fail")
+	return types.ErrValidation{S: "failed validation rules: " + strings.Join(failed, ", ")}
+}
+
+// writeQuarantine writes failed message parts to the quarantine output and
+// blocks until the write is acknowledged.
+func (p *Validate) writeQuarantine(parts []types.Part) error {
+	qMsg := message.New(nil)
+	qMsg.Append(parts...)
+
+	resChan := make(chan types.Response)
+	var err error
+	if oerr := interop.AccessOutput(context.Background(), p.mgr, p.quarantine, func(o types.OutputWriter) {
+		err = o.WriteTransaction(context.Background(), types.NewTransaction(qMsg, resChan))
+	}); oerr != nil {
+		err = oerr
+	}
+	if err != nil {
+		return err
+	}
+	return (<-resChan).Error()
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Validate) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	failed := make([]bool, newMsg.Len())
+	anyFailed := false
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.validatePart(index, newMsg); err != nil {
+			p.mFailed.Incr(1)
+			p.log.Debugf("Message failed validation: %v\n", err)
+			failed[index] = true
+			anyFailed = true
+			return err
+		}
+		p.mPassed.Incr(1)
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeValidate, p.parts, newMsg, proc)
+
+	if anyFailed && p.quarantine != "" {
+		var passedParts, failedParts []types.Part
+		newMsg.Iter(func(i int, part types.Part) error {
+			if failed[i] {
+				failedParts = append(failedParts, part)
+			} else {
+				passedParts = append(passedParts, part)
+			}
+			return nil
+		})
+		if err := p.writeQuarantine(failedParts); err != nil {
+			p.mErrQuarantine.Incr(1)
+			p.log.Errorf("Failed to write messages to quarantine: %v\n", err)
+		} else {
+			p.mQuarantined.Incr(int64(len(failedParts)))
+			if len(passedParts) == 0 {
+				return nil, response.NewAck()
+			}
+			newMsg = message.New(nil)
+			newMsg.SetAll(passedParts)
+		}
+	}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Validate) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Validate) WaitForClose(timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutputWriter struct {
+	err      error
+	received []types.Message
+}
+
+func (f *fakeOutputWriter) WriteTransaction(ctx context.Context, t types.Transaction) error {
+	f.received = append(f.received, t.Payload)
+	go func() {
+		t.ResponseChan <- response.NewError(f.err)
+	}()
+	return nil
+}
+func (f *fakeOutputWriter) Connected() bool                          { return true }
+func (f *fakeOutputWriter) CloseAsync()                              {}
+func (f *fakeOutputWriter) WaitForClose(timeout time.Duration) error { return nil }
+
+type fakeOutputMgr struct {
+	fakeMgr
+	outputs map[string]types.OutputWriter
+}
+
+func (f *fakeOutputMgr) GetOutput(name string) (types.OutputWriter, error) {
+	if o, exists := f.outputs[name]; exists {
+		return o, nil
+	}
+	return nil, types.ErrOutputNotFound
+}
+
+func testValidateConfig() Config {
+	conf := NewConfig()
+	conf.Validate.Rules = []ValidateRuleConfig{
+		{Name: "has_id", Check: `this.id != null`},
+		{Name: "positive_amount", Check: `this.amount > 0`},
+	}
+	return conf
+}
+
+func TestValidateReport(t *testing.T) {
+	stats := metrics.NewLocal()
+	proc, err := NewValidate(testValidateConfig(), nil, log.Noop(), stats)
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","amount":5}`),
+		[]byte(`{"id":"b","amount":-1}`),
+		[]byte(`{"amount":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+	require.Equal(t, 3, msgsOut[0].Len())
+
+	part := msgsOut[0].Get(0)
+	assert.Equal(t, "pass", part.Metadata().Get("validation_status"))
+	assert.Equal(t, `{"has_id":true,"positive_amount":true}`, part.Metadata().Get("validation_report"))
+	assert.Equal(t, "", GetFail(part))
+
+	part = msgsOut[0].Get(1)
+	assert.Equal(t, "fail", part.Metadata().Get("validation_status"))
+	assert.Equal(t, `{"has_id":true,"positive_amount":false}`, part.Metadata().Get("validation_report"))
+	assert.Equal(t, "failed validation rules: positive_amount", GetFail(part))
+	assert.Equal(t, ErrorKindValidation, part.Metadata().Get(types.FailKindKey))
+
+	part = msgsOut[0].Get(2)
+	assert.Equal(t, `{"has_id":false,"positive_amount":false}`, part.Metadata().Get("validation_report"))
+	assert.Equal(t, "failed validation rules: has_id, positive_amount", GetFail(part))
+
+	counters := stats.GetCounters()
+	assert.Equal(t, int64(2), counters["rule.has_id.passed"])
+	assert.Equal(t, int64(1), counters["rule.has_id.failed"])
+	assert.Equal(t, int64(1), counters["rule.positive_amount.passed"])
+	assert.Equal(t, int64(2), counters["rule.positive_amount.failed"])
+}
+
+func TestValidateQuarantine(t *testing.T) {
+	out := &fakeOutputWriter{}
+	mgr := &fakeOutputMgr{outputs: map[string]types.OutputWriter{"foo": out}}
+
+	conf := testValidateConfig()
+	conf.Validate.Quarantine = "foo"
+
+	proc, err := NewValidate(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","amount":5}`),
+		[]byte(`{"id":"b","amount":-1}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+	require.Equal(t, 1, msgsOut[0].Len())
+	assert.Equal(t, `{"id":"a","amount":5}`, string(msgsOut[0].Get(0).Get()))
+
+	require.Len(t, out.received, 1)
+	require.Equal(t, 1, out.received[0].Len())
+	assert.Equal(t, `{"id":"b","amount":-1}`, string(out.received[0].Get(0).Get()))
+	assert.Equal(t, "fail", out.received[0].Get(0).Metadata().Get("validation_status"))
+
+	// A batch where all messages are quarantined is acknowledged.
+	msgsOut, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"c"}`),
+	}))
+	assert.Empty(t, msgsOut)
+	require.NotNil(t, res)
+	assert.NoError(t, res.Error())
+	assert.Len(t, out.received, 2)
+
+	// Messages remain in the batch when the quarantine write fails.
+	out.err = errors.New("nope")
+	msgsOut, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"d"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+	assert.Equal(t, "failed validation rules: positive_amount", GetFail(msgsOut[0].Get(0)))
+}
+
+func TestValidateBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		rules []ValidateRuleConfig
+		quar  string
+		err   string
+	}{
+		"no rules": {
+			err: "at least one rule must be specified",
+		},
+		"no name": {
+			rules: []ValidateRuleConfig{{Check: "true"}},
+			err:   "rule 0: a name must be specified",
+		},
+		"duplicate name": {
+			rules: []ValidateRuleConfig{{Name: "a", Check: "true"}, {Name: "a", Check: "false"}},
+			err:   "rule 1: name 'a' is not unique",
+		},
+		"missing quarantine": {
+			rules: []ValidateRuleConfig{{Name: "a", Check: "true"}},
+			quar:  "nope",
+			err:   "output resource 'nope' was not found",
+		},
+	}
+
+	mgr := &fakeOutputMgr{}
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Validate.Rules = test.rules
+		conf.Validate.Quarantine = test.quar
+		_, err := NewValidate(conf, mgr, log.Noop(), metrics.Noop())
+		require.EqualError(t, err, test.err, name)
+	}
+}
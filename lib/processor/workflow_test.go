@@ -389,6 +389,10 @@ func TestWorkflows(t *testing.T) {
 					`not even a json object`,
 					FailFlagKey,
 					"invalid character 'o' in literal null (expecting 'u')",
+					types.FailClassKey,
+					"json.SyntaxError",
+					types.FailKindKey,
+					"parse",
 				),
 			},
 		},
@@ -1,7 +1,9 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -66,9 +68,70 @@ The resulting JSON structure would look like this:
     ]
   }
 }
-` + "```" + ``,
+` + "```" + `
+
+The conversion can be adjusted with the fields ` + "`attribute_prefix`, `keep_namespaces` and `arrays`" + `. For example, setting ` + "`attribute_prefix` to `@`, `keep_namespaces` to `true` and `arrays` to `[ root.title ]`" + ` would result in ` + "`title`" + ` being an array with a single element, and attributes being prefixed with ` + "`@`" + ` instead.
+
+### ` + "`extract`" + `
+
+Extracts values from an XML document with [XPath](https://www.w3.org/TR/1999/REC-xpath-19991116/) expressions and replaces the message with a JSON object containing the values under the keys of the ` + "`xpaths`" + ` field. Expressions that select a single node result in a string, expressions that select multiple nodes result in an array of strings, and expressions that select nothing result in ` + "`null`" + `.
+
+Only a subset of XPath 1.0 is supported, consisting of absolute and relative location paths with the child (` + "`/`) and descendant (`//`) separators, the steps `.`, `..`, `*`, `text()`, `node()`" + `, element names and attributes (` + "`@name` or `@*`" + `), and predicates that are either a position (` + "`[1]`, `[last()]`" + `), an existence test of a relative path (` + "`[@id]`" + `) or a comparison of a relative path with a literal (` + "`[@id='foo']`, `[price!=0]`" + `). Names without a prefix match elements and attributes regardless of their namespace.
+
+### ` + "`validate`" + `
+
+Validates XML documents against the XSD set by ` + "`schema` or `schema_path`" + ` without changing them.
+
+## Schema Validation
+
+When a schema is set with either ` + "`schema` or `schema_path`" + ` documents are validated against it before any operator is applied, and documents that fail validation are flagged with an error that has the [error kind](/docs/configuration/error_handling#recover-by-error-kind) ` + "`validation`" + `. This applies to all operators.
+
+Only a subset of XSD 1.0 is supported: element declarations with occurrence constraints, named and anonymous complex and simple types, sequence, choice and all groups, named groups and attribute groups, wildcards, simple and complex content extensions, and simple type restrictions with the enumeration, pattern, length, range and digits facets, lists and unions. Namespaces are not validated and schemas that use ` + "`include`, `import` or `redefine`" + ` are rejected.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Extract Payment Fields",
+				Summary: "In this example we validate payment instructions against a schema and extract a few fields of interest, routing invalid documents to a dead letter queue.",
+				Config: `
+pipeline:
+  processors:
+    - xml:
+        operator: extract
+        schema_path: ./schemas/payment.xsd
+        xpaths:
+          id: /Document/PmtInf/PmtInfId
+          amounts: //CdtTrfTxInf/Amt/InstdAmt
+          currencies: //CdtTrfTxInf/Amt/InstdAmt/@Ccy
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          file:
+            path: ./invalid/${! uuid_v4() }.xml
+            codec: all-bytes
+      - output:
+          stdout: {}
+`,
+			},
+		},
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("operator", "An XML [operation](#operators) to apply to messages.").HasOptions("to_json"),
+			docs.FieldCommon("operator", "An XML [operation](#operators) to apply to messages.").HasOptions("to_json", "extract", "validate"),
+			docs.FieldCommon("schema", "An optional XSD document to [validate](#schema-validation) messages against. Use either this or the `schema_path` field.").AtVersion("3.47.0"),
+			docs.FieldCommon("schema_path", "The path of an optional XSD document to [validate](#schema-validation) messages against. Use either this or the `schema` field.").AtVersion("3.47.0"),
+			docs.FieldCommon(
+				"xpaths", "A map of keys to XPath expressions used by the `extract` operator.",
+				map[string]string{
+					"id":    "/order/@id",
+					"items": "//item/name",
+				},
+			).Map().AtVersion("3.47.0"),
+			docs.FieldAdvanced("attribute_prefix", "A prefix added to the keys of attributes by the `to_json` operator.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("keep_namespaces", "Whether the `to_json` operator should keep the namespace prefixes of elements and attributes in keys.").AtVersion("3.47.0"),
+			docs.FieldAdvanced(
+				"arrays", "A list of dot separated paths of elements that the `to_json` operator always converts into arrays, even when they only appear once.",
+				[]string{"root.items.item"},
+			).Array().AtVersion("3.47.0"),
 			PartsFieldSpec,
 		},
 	}
@@ -78,15 +141,27 @@ The resulting JSON structure would look like this:
 
 // XMLConfig contains configuration fields for the XML processor.
 type XMLConfig struct {
-	Parts    []int  `json:"parts" yaml:"parts"`
-	Operator string `json:"operator" yaml:"operator"`
+	Parts           []int             `json:"parts" yaml:"parts"`
+	Operator        string            `json:"operator" yaml:"operator"`
+	Schema          string            `json:"schema" yaml:"schema"`
+	SchemaPath      string            `json:"schema_path" yaml:"schema_path"`
+	XPaths          map[string]string `json:"xpaths" yaml:"xpaths"`
+	AttributePrefix string            `json:"attribute_prefix" yaml:"attribute_prefix"`
+	KeepNamespaces  bool              `json:"keep_namespaces" yaml:"keep_namespaces"`
+	Arrays          []string          `json:"arrays" yaml:"arrays"`
 }
 
 // NewXMLConfig returns a XMLConfig with default values.
 func NewXMLConfig() XMLConfig {
 	return XMLConfig{
-		Parts:    []int{},
-		Operator: "to_json",
+		Parts:           []int{},
+		Operator:        "to_json",
+		Schema:          "",
+		SchemaPath:      "",
+		XPaths:          map[string]string{},
+		AttributePrefix: "-",
+		KeepNamespaces:  false,
+		Arrays:          []string{},
 	}
 }
 
@@ -96,6 +171,11 @@ func NewXMLConfig() XMLConfig {
 type XML struct {
 	parts []int
 
+	schema     *xml.Schema
+	xpaths     map[string]*xml.XPath
+	opts       xml.ToMapOptions
+	customOpts bool
+
 	conf  Config
 	log   log.Modular
 	stats metrics.Type
@@ -110,26 +190,114 @@ type XML struct {
 func NewXML(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	if conf.XML.Operator != "to_json" {
+	switch conf.XML.Operator {
+	case "to_json", "extract", "validate":
+	default:
 		return nil, fmt.Errorf("operator not recognised: %v", conf.XML.Operator)
 	}
 
 	j := &XML{
-		parts: conf.XML.Parts,
-		conf:  conf,
-		log:   log,
-		stats: stats,
+		parts:  conf.XML.Parts,
+		xpaths: map[string]*xml.XPath{},
+		opts:   xml.NewToMapOptions(),
+		conf:   conf,
+		log:    log,
+		stats:  stats,
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
+
+	schemaBytes := []byte(conf.XML.Schema)
+	if conf.XML.SchemaPath != "" {
+		if conf.XML.Schema != "" {
+			return nil, errors.New("only one of schema or schema_path can be set")
+		}
+		var err error
+		if schemaBytes, err = ioutil.ReadFile(conf.XML.SchemaPath); err != nil {
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+	}
+	if len(schemaBytes) > 0 {
+		var err error
+		if j.schema, err = xml.CompileSchema(schemaBytes); err != nil {
+			return nil, err
+		}
+	} else if conf.XML.Operator == "validate" {
+		return nil, errors.New("a schema is required by the validate operator")
+	}
+
+	for k, v := range conf.XML.XPaths {
+		x, err := xml.CompileXPath(v)
+		if err != nil {
+			return nil, err
+		}
+		j.xpaths[k] = x
+	}
+	if conf.XML.Operator == "extract" && len(j.xpaths) == 0 {
+		return nil, errors.New("at least one xpath is required by the extract operator")
+	}
+
+	j.opts.AttrPrefix = conf.XML.AttributePrefix
+	j.opts.KeepNamespaces = conf.XML.KeepNamespaces
+	for _, path := range conf.XML.Arrays {
+		j.opts.Arrays[path] = struct{}{}
+	}
+	j.customOpts = j.opts.AttrPrefix != "-" || j.opts.KeepNamespaces || len(j.opts.Arrays) > 0
 	return j, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (p *XML) extract(doc *xml.Node) map[string]interface{} {
+	result := make(map[string]interface{}, len(p.xpaths))
+	for k, x := range p.xpaths {
+		var values []interface{}
+		for _, r := range x.Select(doc) {
+			values = append(values, r.Value)
+		}
+		switch len(values) {
+		case 0:
+			result[k] = nil
+		case 1:
+			result[k] = values[0]
+		default:
+			result[k] = values
+		}
+	}
+	return result
+}
+
+func (p *XML) process(b []byte) (interface{}, error) {
+	// The default conversion is kept on mxj in order to preserve the existing
+	// behaviour exactly when no other features are used.
+	if p.schema == nil && p.conf.XML.Operator == "to_json" && !p.customOpts {
+		return xml.ToMap(b)
+	}
+
+	doc, err := xml.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	if p.schema != nil {
+		if err := p.schema.Validate(doc); err != nil {
+			return nil, types.ErrValidation{S: err.Error()}
+		}
+	}
+
+	switch p.conf.XML.Operator {
+	case "extract":
+		return p.extract(doc), nil
+	case "validate":
+		return nil, nil
+	}
+	return doc.ToMapWithOptions(p.opts), nil
+}
+
+//------------------------------------------------------------------------------
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *XML) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -137,12 +305,15 @@ func (p *XML) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 	newMsg := msg.Copy()
 
 	proc := func(index int, span opentracing.Span, part types.Part) error {
-		root, err := xml.ToMap(part.Get())
+		root, err := p.process(part.Get())
 		if err != nil {
 			p.mErr.Incr(1)
-			p.log.Debugf("Failed to parse part as XML: %v\n", err)
+			p.log.Debugf("Failed to process part as XML: %v\n", err)
 			return err
 		}
+		if p.conf.XML.Operator == "validate" {
+			return nil
+		}
 		if err = part.SetJSON(root); err != nil {
 			p.mErr.Incr(1)
 			p.log.Debugf("Failed to marshal XML as JSON: %v\n", err)
@@ -1,11 +1,17 @@
 package processor
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestXMLCases(t *testing.T) {
@@ -107,3 +113,130 @@ func TestXMLCases(t *testing.T) {
 		})
 	}
 }
+
+const testXMLSchema = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="order">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="item" maxOccurs="unbounded">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="price" type="xs:decimal"/>
+            </xs:sequence>
+            <xs:attribute name="sku" type="xs:string" use="required"/>
+          </xs:complexType>
+        </xs:element>
+      </xs:sequence>
+      <xs:attribute name="id" type="xs:int" use="required"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func TestXMLExtract(t *testing.T) {
+	conf := NewConfig()
+	conf.XML.Operator = "extract"
+	conf.XML.Schema = testXMLSchema
+	conf.XML.XPaths = map[string]string{
+		"id":      "/order/@id",
+		"skus":    "//item/@sku",
+		"first":   "/order/item[1]/price",
+		"missing": "/order/nope",
+	}
+
+	proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`<order id="5"><item sku="foo"><price>1.50</price></item><item sku="bar"><price>2</price></item></order>`),
+		[]byte(`<order id="nope"><item><price>1.50</price></item></order>`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assert.Equal(t, `{"first":"1.50","id":"5","missing":null,"skus":["foo","bar"]}`, string(msgsOut[0].Get(0).Get()))
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(0)))
+
+	assert.Equal(t, `<order id="nope"><item><price>1.50</price></item></order>`, string(msgsOut[0].Get(1).Get()))
+	assert.Equal(t, "/order: attribute id: value 'nope' is not a valid int: not an integer; /order/item: missing required attribute sku", GetFail(msgsOut[0].Get(1)))
+	assert.Equal(t, ErrorKindValidation, msgsOut[0].Get(1).Metadata().Get(types.FailKindKey))
+}
+
+func TestXMLValidate(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_xml_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	schemaPath := filepath.Join(tmpDir, "schema.xsd")
+	require.NoError(t, ioutil.WriteFile(schemaPath, []byte(testXMLSchema), 0644))
+
+	conf := NewConfig()
+	conf.XML.Operator = "validate"
+	conf.XML.SchemaPath = schemaPath
+
+	proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := `<order id="5"><item sku="foo"><price>1.50</price></item></order>`
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(input),
+		[]byte(`<order id="5"></order>`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assert.Equal(t, input, string(msgsOut[0].Get(0).Get()))
+	assert.Equal(t, "", GetFail(msgsOut[0].Get(0)))
+	assert.Equal(t, "/order: element content is incomplete", GetFail(msgsOut[0].Get(1)))
+}
+
+func TestXMLToJSONOptions(t *testing.T) {
+	conf := NewConfig()
+	conf.XML.AttributePrefix = "@"
+	conf.XML.KeepNamespaces = true
+	conf.XML.Arrays = []string{"root.ns:item"}
+
+	proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgsOut, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`<root xmlns:ns="http://example.com"><ns:item ns:id="1">foo</ns:item></root>`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgsOut, 1)
+
+	assert.Equal(t, `{"root":{"@xmlns:ns":"http://example.com","ns:item":[{"#text":"foo","@ns:id":"1"}]}}`, string(msgsOut[0].Get(0).Get()))
+}
+
+func TestXMLBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf func(c *XMLConfig)
+		err  string
+	}{
+		"validate without schema": {
+			conf: func(c *XMLConfig) { c.Operator = "validate" },
+			err:  "a schema is required by the validate operator",
+		},
+		"extract without xpaths": {
+			conf: func(c *XMLConfig) { c.Operator = "extract" },
+			err:  "at least one xpath is required by the extract operator",
+		},
+		"bad xpath": {
+			conf: func(c *XMLConfig) { c.XPaths = map[string]string{"foo": "/a["} },
+			err:  "failed to parse xpath '/a[': expected a step at char 3",
+		},
+		"bad schema": {
+			conf: func(c *XMLConfig) { c.Schema = "<nope/>" },
+			err:  "expected root element schema, got nope",
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		test.conf(&conf.XML)
+		_, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+		require.Error(t, err, name)
+		assert.Equal(t, test.err, err.Error(), name)
+	}
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeConcurrency] = TypeSpec{
+		constructor: NewConcurrency,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+A rate limit that caps the number of requests in flight at any given time
+across all components that share it, which does not support distributed rate
+limits across multiple running instances of Benthos.`,
+		Description: `
+This rate limit is useful for protecting fragile services that struggle with
+many parallel requests regardless of their rate. Each granted request occupies
+a slot until the component that made it releases it, and therefore this rate
+limit is only supported by components that release their requests once they
+complete, which are currently the ` + "[`http` processor](/docs/components/processors/http), [`http_client` input](/docs/components/inputs/http_client) and [`http_client` output](/docs/components/outputs/http_client)" + `. Using it with any other component results in slots never being released.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("max_in_flight", "The maximum number of requests to allow in flight at any given time."),
+			docs.FieldAdvanced("check_interval", "The period of time to wait before checking again for an available slot once the limit is reached."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ConcurrencyConfig is a config struct containing fields for a concurrency
+// rate limit.
+type ConcurrencyConfig struct {
+	MaxInFlight   int    `json:"max_in_flight" yaml:"max_in_flight"`
+	CheckInterval string `json:"check_interval" yaml:"check_interval"`
+}
+
+// NewConcurrencyConfig returns a concurrency rate limit configuration struct
+// with default values.
+func NewConcurrencyConfig() ConcurrencyConfig {
+	return ConcurrencyConfig{
+		MaxInFlight:   1,
+		CheckInterval: "10ms",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Concurrency is a rate limit that acts as a semaphore, where each granted
+// access must be released before the maximum number of accesses is granted
+// again.
+type Concurrency struct {
+	mut      sync.Mutex
+	inFlight int
+
+	max      int
+	interval time.Duration
+
+	mChecked  metrics.StatCounter
+	mLimited  metrics.StatCounter
+	mInFlight metrics.StatGauge
+}
+
+// NewConcurrency creates a concurrency rate limit from a configuration struct.
+// This type is safe to share and call from parallel goroutines.
+func NewConcurrency(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (types.RateLimit, error) {
+	if conf.Concurrency.MaxInFlight <= 0 {
+		return nil, errors.New("max_in_flight must be larger than zero")
+	}
+	interval, err := time.ParseDuration(conf.Concurrency.CheckInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse check interval: %v", err)
+	}
+	if interval <= 0 {
+		return nil, errors.New("check interval must be larger than zero")
+	}
+	return &Concurrency{
+		max:      conf.Concurrency.MaxInFlight,
+		interval: interval,
+
+		mChecked:  stats.GetCounter("checked"),
+		mLimited:  stats.GetCounter("limited"),
+		mInFlight: stats.GetGauge("in_flight"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Access the rate limited resource. Returns a duration or an error if the rate
+// limit check fails. The returned duration is either zero (meaning the resource
+// can be accessed) or a reasonable length of time to wait before requesting
+// again.
+func (r *Concurrency) Access() (time.Duration, error) {
+	r.mChecked.Incr(1)
+	r.mut.Lock()
+	if r.inFlight >= r.max {
+		r.mut.Unlock()
+		r.mLimited.Incr(1)
+		return r.interval, nil
+	}
+	r.inFlight++
+	inFlight := r.inFlight
+	r.mut.Unlock()
+	r.mInFlight.Set(int64(inFlight))
+	return 0, nil
+}
+
+// Release an access previously granted by the rate limit.
+func (r *Concurrency) Release() {
+	r.mut.Lock()
+	if r.inFlight > 0 {
+		r.inFlight--
+	}
+	inFlight := r.inFlight
+	r.mut.Unlock()
+	r.mInFlight.Set(int64(inFlight))
+}
+
+// CloseAsync shuts down the rate limit.
+func (r *Concurrency) CloseAsync() {
+}
+
+// WaitForClose blocks until the rate limit has closed down.
+func (r *Concurrency) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyConfErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeConcurrency
+	conf.Concurrency.MaxInFlight = 0
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeConcurrency
+	conf.Concurrency.CheckInterval = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestConcurrencyBasic(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeConcurrency
+	conf.Concurrency.MaxInFlight = 2
+	conf.Concurrency.CheckInterval = "5ms"
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	releaser, ok := rl.(types.RateLimitReleaser)
+	require.True(t, ok)
+
+	for i := 0; i < 2; i++ {
+		period, err := rl.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	period, err := rl.Access()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Millisecond, period)
+
+	releaser.Release()
+
+	period, err = rl.Access()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, err = rl.Access()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Millisecond, period)
+}
@@ -67,26 +67,32 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each ratelimit type.
 const (
-	TypeLocal = "local"
+	TypeConcurrency   = "concurrency"
+	TypeLocal         = "local"
+	TypeSlidingWindow = "sliding_window"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all cache types.
 type Config struct {
-	Label  string      `json:"label" yaml:"label"`
-	Type   string      `json:"type" yaml:"type"`
-	Local  LocalConfig `json:"local" yaml:"local"`
-	Plugin interface{} `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Label         string              `json:"label" yaml:"label"`
+	Type          string              `json:"type" yaml:"type"`
+	Concurrency   ConcurrencyConfig   `json:"concurrency" yaml:"concurrency"`
+	Local         LocalConfig         `json:"local" yaml:"local"`
+	Plugin        interface{}         `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	SlidingWindow SlidingWindowConfig `json:"sliding_window" yaml:"sliding_window"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Label:  "",
-		Type:   "local",
-		Local:  NewLocalConfig(),
-		Plugin: nil,
+		Label:         "",
+		Type:          "local",
+		Concurrency:   NewConcurrencyConfig(),
+		Local:         NewLocalConfig(),
+		Plugin:        nil,
+		SlidingWindow: NewSlidingWindowConfig(),
 	}
 }
 
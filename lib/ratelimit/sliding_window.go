@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSlidingWindow] = TypeSpec{
+		constructor: NewSlidingWindow,
+		Status:      docs.StatusExperimental,
+		Version:     "3.47.0",
+		Summary: `
+A rate limit that allows at most X requests within any window of time of
+length Y, which does not support distributed rate limits across multiple
+running instances of Benthos.`,
+		Description: `
+Unlike the ` + "[`local`](/docs/components/rate_limits/local)" + ` rate limit,
+which resets its count at fixed intervals and can therefore allow up to twice
+the count within a short period that spans two intervals, this rate limit keeps
+a log of the times at which requests were granted and only grants a new request
+once the oldest within the window has expired. The log holds one timestamp per
+request of the count, and therefore memory usage grows with the count.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("count", "The maximum number of requests to allow within any window of time."),
+			docs.FieldCommon("interval", "The length of the sliding window to limit requests by."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SlidingWindowConfig is a config struct containing fields for a sliding
+// window rate limit.
+type SlidingWindowConfig struct {
+	Count    int    `json:"count" yaml:"count"`
+	Interval string `json:"interval" yaml:"interval"`
+}
+
+// NewSlidingWindowConfig returns a sliding window rate limit configuration
+// struct with default values.
+func NewSlidingWindowConfig() SlidingWindowConfig {
+	return SlidingWindowConfig{
+		Count:    1000,
+		Interval: "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SlidingWindow is a rate limit that tracks the times of granted requests in
+// order to allow a maximum number of requests within any window of time.
+type SlidingWindow struct {
+	mut    sync.Mutex
+	log    []time.Time
+	next   int
+	period time.Duration
+
+	mChecked metrics.StatCounter
+	mLimited metrics.StatCounter
+}
+
+// NewSlidingWindow creates a sliding window rate limit from a configuration
+// struct. This type is safe to share and call from parallel goroutines.
+func NewSlidingWindow(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (types.RateLimit, error) {
+	if conf.SlidingWindow.Count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	period, err := time.ParseDuration(conf.SlidingWindow.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	return &SlidingWindow{
+		log:    make([]time.Time, conf.SlidingWindow.Count),
+		period: period,
+
+		mChecked: stats.GetCounter("checked"),
+		mLimited: stats.GetCounter("limited"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Access the rate limited resource. Returns a duration or an error if the rate
+// limit check fails. The returned duration is either zero (meaning the resource
+// can be accessed) or a reasonable length of time to wait before requesting
+// again.
+func (r *SlidingWindow) Access() (time.Duration, error) {
+	r.mChecked.Incr(1)
+	now := time.Now()
+
+	r.mut.Lock()
+	// The log is a ring buffer where the next slot holds the oldest granted
+	// request, which must fall outside of the window before another is granted.
+	if remaining := r.period - now.Sub(r.log[r.next]); remaining > 0 {
+		r.mut.Unlock()
+		r.mLimited.Incr(1)
+		return remaining, nil
+	}
+	r.log[r.next] = now
+	r.next = (r.next + 1) % len(r.log)
+	r.mut.Unlock()
+	return 0, nil
+}
+
+// CloseAsync shuts down the rate limit.
+func (r *SlidingWindow) CloseAsync() {
+}
+
+// WaitForClose blocks until the rate limit has closed down.
+func (r *SlidingWindow) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindowConfErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSlidingWindow
+	conf.SlidingWindow.Count = 0
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Type = TypeSlidingWindow
+	conf.SlidingWindow.Interval = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestSlidingWindowBasic(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSlidingWindow
+	conf.SlidingWindow.Count = 10
+	conf.SlidingWindow.Interval = "1s"
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	for i := 0; i < conf.SlidingWindow.Count; i++ {
+		period, err := rl.Access()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), period)
+	}
+
+	period, err := rl.Access()
+	require.NoError(t, err)
+	assert.Greater(t, int64(period), int64(0))
+	assert.LessOrEqual(t, int64(period), int64(time.Second))
+}
+
+func TestSlidingWindowSlides(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSlidingWindow
+	conf.SlidingWindow.Count = 2
+	conf.SlidingWindow.Interval = "100ms"
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	period, _ := rl.Access()
+	require.Equal(t, time.Duration(0), period)
+
+	<-time.After(60 * time.Millisecond)
+
+	period, _ = rl.Access()
+	require.Equal(t, time.Duration(0), period)
+
+	// The window is full until the first access expires, which is sooner than
+	// a full interval.
+	period, _ = rl.Access()
+	assert.Greater(t, int64(period), int64(0))
+	assert.Less(t, int64(period), int64(60*time.Millisecond))
+
+	<-time.After(period)
+
+	period, _ = rl.Access()
+	assert.Equal(t, time.Duration(0), period)
+
+	period, _ = rl.Access()
+	assert.Greater(t, int64(period), int64(0))
+}
@@ -10,7 +10,17 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/internal/bundle"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/condition"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/tracer"
+	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
 	"github.com/urfave/cli/v2"
 )
 
@@ -28,7 +38,106 @@ func listableStatus(s docs.Status) bool {
 	return false
 }
 
+func componentSchemas(specs []docs.ComponentSpec, sanitise func(name string) (interface{}, error)) (map[string]interface{}, error) {
+	schemas := map[string]interface{}{}
+	for _, spec := range specs {
+		confSanit, err := sanitise(spec.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate schema for '%v': %w", spec.Name, err)
+		}
+		if s, ok := confSanit.(uconfig.Sanitised); ok {
+			confSanit = map[string]interface{}(s)
+		}
+		schemas[spec.Name] = spec.JSONSchema(confSanit)
+	}
+	return schemas, nil
+}
+
+func jsonSchema() ([]byte, error) {
+	components := map[docs.Type]map[string]interface{}{}
+
+	var err error
+	if components[docs.TypeBuffer], err = componentSchemas(bundle.AllBuffers.Docs(), func(name string) (interface{}, error) {
+		conf := buffer.NewConfig()
+		conf.Type = name
+		return buffer.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeCache], err = componentSchemas(bundle.AllCaches.Docs(), func(name string) (interface{}, error) {
+		conf := cache.NewConfig()
+		conf.Type = name
+		return cache.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeInput], err = componentSchemas(bundle.AllInputs.Docs(), func(name string) (interface{}, error) {
+		conf := input.NewConfig()
+		conf.Type = name
+		return input.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeMetrics], err = componentSchemas(bundle.AllMetrics.Docs(), func(name string) (interface{}, error) {
+		conf := metrics.NewConfig()
+		conf.Type = name
+		return metrics.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeOutput], err = componentSchemas(bundle.AllOutputs.Docs(), func(name string) (interface{}, error) {
+		conf := output.NewConfig()
+		conf.Type = name
+		return output.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeProcessor], err = componentSchemas(bundle.AllProcessors.Docs(), func(name string) (interface{}, error) {
+		conf := processor.NewConfig()
+		conf.Type = name
+		return processor.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeRateLimit], err = componentSchemas(bundle.AllRateLimits.Docs(), func(name string) (interface{}, error) {
+		conf := ratelimit.NewConfig()
+		conf.Type = name
+		return ratelimit.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+	if components[docs.TypeTracer], err = componentSchemas(bundle.AllTracers.Docs(), func(name string) (interface{}, error) {
+		conf := tracer.NewConfig()
+		conf.Type = name
+		return tracer.SanitiseConfig(conf)
+	}); err != nil {
+		return nil, err
+	}
+
+	rootNode, err := config.New().SanitisedV2(config.SanitisedV2Config{
+		RemoveTypeField: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rootExample interface{}
+	if err := rootNode.Decode(&rootExample); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(docs.JSONSchema(config.Spec(), rootExample, components))
+}
+
 func listComponents(c *cli.Context) {
+	if c.String("format") == "jsonschema" {
+		b, err := jsonSchema()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(b))
+		return
+	}
+
 	jsonFmt := c.String("format") == "json"
 
 	ofType := c.Args().Slice()
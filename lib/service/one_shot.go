@@ -0,0 +1,134 @@
+package service
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/urfave/cli/v2"
+)
+
+//------------------------------------------------------------------------------
+
+// oneShot describes a single execution of the pipeline of a config, where data
+// is read from stdin or a file and the results are written to stdout.
+type oneShot struct {
+	path   string
+	codec  string
+	failed int64
+}
+
+// apply replaces the input and output of a config with the data source and
+// stdout, and disables the HTTP server.
+func (o *oneShot) apply(conf *config.Type) {
+	inConf := input.NewConfig()
+	if o.path == "" {
+		inConf.Type = input.TypeSTDIN
+		inConf.STDIN.Codec = o.codec
+	} else {
+		inConf.Type = input.TypeFile
+		inConf.File.Paths = []string{o.path}
+		inConf.File.Codec = o.codec
+	}
+	conf.Input = inConf
+
+	outConf := output.NewConfig()
+	outConf.Type = output.TypeSTDOUT
+	conf.Output = outConf
+
+	conf.HTTP.Enabled = false
+}
+
+// processor creates a processor that counts the messages that failed the
+// pipeline without modifying them.
+func (o *oneShot) processor() (types.Processor, error) {
+	return &oneShotFailureCounter{failed: &o.failed}, nil
+}
+
+// exitCode returns the exit code of the execution, which is non-zero if any
+// message failed the pipeline.
+func (o *oneShot) exitCode() int {
+	if atomic.LoadInt64(&o.failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+type oneShotFailureCounter struct {
+	failed *int64
+}
+
+func (c *oneShotFailureCounter) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	var failed int64
+	_ = msg.Iter(func(i int, p types.Part) error {
+		if processor.HasFailed(p) {
+			failed++
+		}
+		return nil
+	})
+	if failed > 0 {
+		atomic.AddInt64(c.failed, failed)
+	}
+	return []types.Message{msg}, nil
+}
+
+func (c *oneShotFailureCounter) CloseAsync() {
+}
+
+func (c *oneShotFailureCounter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func oneShotCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "one-shot",
+		Usage: "Process data from stdin or a file through the pipeline of a config, then exit",
+		Description: `
+   Reads data from stdin, or a file when a path is provided, and processes each
+   message through the pipeline of a config before writing the results to
+   stdout. The input and output of the config are ignored. Once all data has
+   been processed Benthos exits, with a non-zero status if any message failed
+   the pipeline, which makes it possible to use Benthos configs within shell
+   scripts and CI jobs:
+
+   cat ./data.jsonl | benthos -c ./config.yaml one-shot > ./results.jsonl
+   benthos -c ./config.yaml one-shot --codec all-bytes ./document.json`[4:],
+		ArgsUsage: "[path]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "codec",
+				Value: "lines",
+				Usage: "The codec used to divide the data into messages, supports the same codecs as the stdin input.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() > 1 {
+				cli.ShowSubcommandHelp(c)
+				os.Exit(1)
+			}
+			os.Exit(cmdService(
+				c.String("config"),
+				c.StringSlice("resources"),
+				c.String("log.level"),
+				!c.Bool("chilled"),
+				c.Bool("quiet"),
+				false,
+				nil,
+				"",
+				nil,
+				&oneShot{
+					path:  c.Args().First(),
+					codec: c.String("codec"),
+				},
+			))
+			return nil
+		},
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/template"
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/service/blobl"
+	"github.com/Jeffail/benthos/v3/lib/service/studio"
 	"github.com/Jeffail/benthos/v3/lib/service/test"
 	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
 	"github.com/urfave/cli/v2"
@@ -127,6 +128,12 @@ func Run() {
 			Value: false,
 			Usage: "continue to execute a config containing linter errors",
 		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Aliases: []string{"q"},
+			Value:   false,
+			Usage:   "disable logging and write messages of a stdout output without formatting, which is useful when piping data into other tools",
+		},
 	}
 	if len(customFlags) > 0 {
 		flags = append(flags, customFlags...)
@@ -177,8 +184,12 @@ func Run() {
 				c.StringSlice("resources"),
 				c.String("log.level"),
 				!c.Bool("chilled"),
+				c.Bool("quiet"),
 				false,
 				nil,
+				"",
+				nil,
+				nil,
 			))
 			return nil
 		},
@@ -216,6 +227,7 @@ func Run() {
 				},
 			},
 			lintCliCommand(),
+			oneShotCliCommand(),
 			{
 				Name:  "streams",
 				Usage: "Run Benthos in streams mode",
@@ -232,16 +244,37 @@ func Run() {
    pipeline, output) will be ignored. Other fields will be shared across all
    loaded streams (resources, metrics, etc).
 
+   Defaults for all streams can be provided with the --defaults flag, and the
+   --webhook flag specifies URLs that receive an HTTP POST request whenever a
+   stream is created, updated, deleted or finishes, or when an operation fails:
+
+   benthos streams --defaults ./defaults.yaml --webhook http://localhost:8080/audit
+
    For more information check out the docs at:
    https://benthos.dev/docs/guides/streams_mode/about`[4:],
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "defaults",
+						Value: "",
+						Usage: "A path to a stream config providing default field values for all streams.",
+					},
+					&cli.StringSliceFlag{
+						Name:  "webhook",
+						Usage: "A URL to send stream lifecycle events to, can be specified multiple times.",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					os.Exit(cmdService(
 						c.String("config"),
 						c.StringSlice("resources"),
 						c.String("log.level"),
 						!c.Bool("chilled"),
+						c.Bool("quiet"),
 						true,
 						c.Args().Slice(),
+						c.String("defaults"),
+						c.StringSlice("webhook"),
+						nil,
 					))
 					return nil
 				},
@@ -255,12 +288,18 @@ func Run() {
 
    benthos list
    benthos list inputs output
-   benthos list rate-limits buffers`[4:],
+   benthos list rate-limits buffers
+
+   The jsonschema format prints a JSON Schema document describing the entire
+   config format, which can be used by editors and CI tools for validating and
+   autocompleting configs:
+
+   benthos list --format jsonschema > ./benthos_schema.json`[4:],
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:  "format",
 						Value: "text",
-						Usage: "Print the component list in a specific format. Options are text or json.",
+						Usage: "Print the component list in a specific format. Options are text, json or jsonschema.",
 					},
 				},
 				Action: func(c *cli.Context) error {
@@ -273,6 +312,7 @@ func Run() {
 			test.CliCommand(testSuffix),
 			clitemplate.CliCommand(),
 			blobl.CliCommand(),
+			studio.CliCommand(),
 		},
 	}
 
@@ -301,7 +341,7 @@ func Run() {
 		}
 
 		deprecatedExecute(*configPath, testSuffix)
-		os.Exit(cmdService(*configPath, nil, "", false, false, nil))
+		os.Exit(cmdService(*configPath, nil, "", false, false, false, nil, "", nil, nil))
 		return nil
 	}
 
@@ -166,8 +166,12 @@ func cmdService(
 	resourcesPaths []string,
 	overrideLogLevel string,
 	strict bool,
+	quiet bool,
 	streamsMode bool,
 	streamsConfigs []string,
+	streamsDefaults string,
+	streamsWebhooks []string,
+	oneShot *oneShot,
 ) int {
 	var err error
 	if resourcesPaths, err = filepath.Globs(resourcesPaths); err != nil {
@@ -186,6 +190,13 @@ func cmdService(
 	if len(overrideLogLevel) > 0 {
 		conf.Logger.LogLevel = strings.ToUpper(overrideLogLevel)
 	}
+	if oneShot != nil {
+		oneShot.apply(&conf)
+	}
+	if quiet {
+		conf.Logger.LogLevel = "OFF"
+		conf.Output.STDOUT.Format = "raw"
+	}
 
 	// Logging and stats aggregation.
 	var logger log.Modular
@@ -264,16 +275,34 @@ func cmdService(
 
 	// Create data streams.
 	if streamsMode {
+		var defaultsBytes []byte
+		if len(streamsDefaults) > 0 {
+			if defaultsBytes, err = config.ReadWithJSONPointers(streamsDefaults, true); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to load stream defaults: %v\n", err)
+				return 1
+			}
+		}
+		var hooks []strmmgr.LifecycleHookFunc
+		for _, url := range streamsWebhooks {
+			hooks = append(hooks, strmmgr.NewWebhook(url, time.Second*5, logger))
+		}
+
 		streamMgr := strmmgr.New(
 			strmmgr.OptSetAPITimeout(time.Second*5),
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
+			strmmgr.OptSetStreamDefaults(defaultsBytes),
+			strmmgr.OptAddLifecycleHooks(hooks...),
 		)
+		if _, err = streamMgr.NewStreamConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load stream defaults: %v\n", err)
+			return 1
+		}
 		streamConfs := map[string]stream.Config{}
 		var streamLints []string
 		for _, path := range streamsConfigs {
-			lints, err := strmmgr.LoadStreamConfigsFromPath(path, testSuffix, streamConfs)
+			lints, err := strmmgr.LoadStreamConfigsFromPathWithDefaults(path, testSuffix, defaultsBytes, streamConfs)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to load stream configs: %v\n", err)
 				return 1
@@ -303,15 +332,18 @@ func cmdService(
 		}
 		logger.Infoln("Launching benthos in streams mode, use CTRL+C to close.")
 	} else {
-		if dataStream, err = stream.New(
-			conf.Config,
+		streamOpts := []func(*stream.Type){
 			stream.OptSetLogger(logger),
 			stream.OptSetStats(stats),
 			stream.OptSetManager(manager),
 			stream.OptOnClose(func() {
 				close(dataStreamClosedChan)
 			}),
-		); err != nil {
+		}
+		if oneShot != nil {
+			streamOpts = append(streamOpts, stream.OptAddProcessors(oneShot.processor))
+		}
+		if dataStream, err = stream.New(conf.Config, streamOpts...); err != nil {
 			logger.Errorf("Service closing due to: %v\n", err)
 			return 1
 		}
@@ -386,6 +418,9 @@ func cmdService(
 		logger.Infoln("Received SIGTERM, the service is closing.")
 	case <-dataStreamClosedChan:
 		logger.Infoln("Pipeline has terminated. Shutting down the service.")
+		if oneShot != nil {
+			return oneShot.exitCode()
+		}
 	case <-httpServerClosedChan:
 		logger.Infoln("HTTP Server has terminated. Shutting down the service.")
 	}
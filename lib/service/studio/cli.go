@@ -0,0 +1,54 @@
+package studio
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+// CliCommand is a cli.Command definition for running a local visual editor of
+// Benthos configs.
+func CliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "studio",
+		Usage: "EXPERIMENTAL: Run a web server that hosts a visual config editor",
+		Description: `
+   Run a local web application that renders a config as a graph of inputs,
+   processors and outputs, allows editing component fields with forms generated
+   from the component documentation, and can run test messages through the
+   processors of the pipeline:
+
+   benthos -c ./config.yaml studio --write
+
+   Changes made within the editor are only written back to the config file when
+   the --write flag is set.
+
+   Requests to the app must include a session token that is generated at
+   startup and printed as part of the URL to open, and must be addressed to the
+   host and port that the server is bound to.`[4:],
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Value: "localhost",
+				Usage: "the host to bind to.",
+			},
+			&cli.StringFlag{
+				Name:    "port",
+				Value:   "4196",
+				Aliases: []string{"p"},
+				Usage:   "the port to bind to.",
+			},
+			&cli.BoolFlag{
+				Name:    "no-open",
+				Value:   false,
+				Aliases: []string{"n"},
+				Usage:   "do not open the app in the browser automatically.",
+			},
+			&cli.BoolFlag{
+				Name:    "write",
+				Value:   false,
+				Aliases: []string{"w"},
+				Usage:   "write changes made to the config back to the config file, if the file does not exist it will be created.",
+			},
+		},
+		Action: runServer,
+	}
+}
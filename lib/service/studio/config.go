@@ -0,0 +1,301 @@
+package studio
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bundle"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
+	"github.com/Jeffail/benthos/v3/lib/util/text"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+const defaultConfig = `input:
+  generate:
+    mapping: 'root.message = "hello world"'
+    interval: 1s
+
+pipeline:
+  processors: []
+
+output:
+  stdout: {}
+`
+
+// parseConfig parses a raw YAML config into a structured config, with
+// environment variables replaced, and returns any linting errors.
+func parseConfig(raw []byte) (config.Type, []string, error) {
+	conf := config.New()
+	replaced := text.ReplaceEnvVariables(raw)
+	if err := yaml.Unmarshal(replaced, &conf); err != nil {
+		return conf, nil, err
+	}
+	lints, err := config.Lint(replaced, conf)
+	if err != nil {
+		return conf, nil, err
+	}
+	return conf, lints, nil
+}
+
+// normaliseConfig returns a config as YAML with all default values populated.
+func normaliseConfig(conf config.Type) ([]byte, error) {
+	node, err := conf.SanitisedV2(config.SanitisedV2Config{
+		RemoveTypeField: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uconfig.MarshalYAML(node)
+}
+
+//------------------------------------------------------------------------------
+
+// graphNode is a component within the graph of a config.
+type graphNode struct {
+	ID     int         `json:"id"`
+	Kind   string      `json:"kind"`
+	Type   string      `json:"type"`
+	Label  string      `json:"label"`
+	Path   string      `json:"path"`
+	Config interface{} `json:"config"`
+}
+
+// graph describes the flow of data through the components of a config, where
+// each edge connects the ID of one node to another.
+type graph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges [][2]int    `json:"edges"`
+}
+
+func (g *graph) add(kind, cType, label, path string, root *yaml.Node) int {
+	n := graphNode{
+		ID:    len(g.Nodes),
+		Kind:  kind,
+		Type:  cType,
+		Label: label,
+		Path:  path,
+	}
+	if node, err := nodeAtPath(root, path); err == nil {
+		_ = node.Decode(&n.Config)
+	}
+	g.Nodes = append(g.Nodes, n)
+	return n.ID
+}
+
+func (g *graph) connect(from, to []int) {
+	for _, f := range from {
+		for _, t := range to {
+			g.Edges = append(g.Edges, [2]int{f, t})
+		}
+	}
+}
+
+func (g *graph) addProcs(from []int, procs []processor.Config, path string, root *yaml.Node) []int {
+	for i, p := range procs {
+		id := g.add("processor", p.Type, p.Label, fmt.Sprintf("%v.%v", path, i), root)
+		g.connect(from, []int{id})
+		from = []int{id}
+	}
+	return from
+}
+
+// configGraph creates a graph of the components of a config, where the inputs
+// and outputs of brokers are expanded.
+func configGraph(conf config.Type, raw []byte) graph {
+	var root yaml.Node
+	_ = yaml.Unmarshal(raw, &root)
+
+	g := graph{
+		Nodes: []graphNode{},
+		Edges: [][2]int{},
+	}
+
+	var inputs []int
+	if conf.Input.Type == input.TypeBroker {
+		for i, c := range conf.Input.Broker.Inputs {
+			path := fmt.Sprintf("input.broker.inputs.%v", i)
+			id := g.add("input", c.Type, c.Label, path, &root)
+			inputs = append(inputs, g.addProcs([]int{id}, c.Processors, path+".processors", &root)...)
+		}
+	} else {
+		inputs = []int{g.add("input", conf.Input.Type, conf.Input.Label, "input", &root)}
+	}
+	inputs = g.addProcs(inputs, conf.Input.Processors, "input.processors", &root)
+
+	tail := g.addProcs(inputs, conf.Pipeline.Processors, "pipeline.processors", &root)
+	tail = g.addProcs(tail, conf.Output.Processors, "output.processors", &root)
+
+	if conf.Output.Type == output.TypeBroker {
+		for i, c := range conf.Output.Broker.Outputs {
+			path := fmt.Sprintf("output.broker.outputs.%v", i)
+			procTail := g.addProcs(tail, c.Processors, path+".processors", &root)
+			id := g.add("output", c.Type, c.Label, path, &root)
+			g.connect(procTail, []int{id})
+		}
+	} else {
+		id := g.add("output", conf.Output.Type, conf.Output.Label, "output", &root)
+		g.connect(tail, []int{id})
+	}
+	return g
+}
+
+//------------------------------------------------------------------------------
+
+// nodeAtPath walks a YAML document by a dot separated path of map keys and
+// array indexes.
+func nodeAtPath(root *yaml.Node, path string) (*yaml.Node, error) {
+	node := root
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil, errors.New("empty document")
+		}
+		node = node.Content[0]
+	}
+	if path == "" {
+		return node, nil
+	}
+	for _, seg := range strings.Split(path, ".") {
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i < len(node.Content)-1; i += 2 {
+				if node.Content[i].Value == seg {
+					next = node.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return nil, fmt.Errorf("field '%v' not found", seg)
+			}
+			node = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(seg)
+			if err != nil {
+				return nil, fmt.Errorf("expected array index, got '%v'", seg)
+			}
+			if i < 0 || i >= len(node.Content) {
+				return nil, fmt.Errorf("array index '%v' out of bounds", i)
+			}
+			node = node.Content[i]
+		default:
+			return nil, fmt.Errorf("field '%v' not found", seg)
+		}
+	}
+	return node, nil
+}
+
+// setComponent replaces the component found at a path within a raw YAML
+// config and returns the resulting document.
+func setComponent(raw []byte, path string, value interface{}) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(raw, &root); err != nil {
+		return nil, err
+	}
+	target, err := nodeAtPath(&root, path)
+	if err != nil {
+		return nil, err
+	}
+	var replacement yaml.Node
+	if err := replacement.Encode(value); err != nil {
+		return nil, err
+	}
+	*target = replacement
+	return uconfig.MarshalYAML(&root)
+}
+
+//------------------------------------------------------------------------------
+
+// fieldSchema is a simplified representation of a docs.FieldSpec used for
+// generating forms.
+type fieldSchema struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Kind        string        `json:"kind"`
+	Description string        `json:"description"`
+	Advanced    bool          `json:"advanced"`
+	Default     interface{}   `json:"default,omitempty"`
+	Options     []string      `json:"options,omitempty"`
+	Children    []fieldSchema `json:"children,omitempty"`
+}
+
+type componentSchema struct {
+	Name    string        `json:"name"`
+	Summary string        `json:"summary"`
+	Status  string        `json:"status"`
+	Fields  []fieldSchema `json:"fields"`
+}
+
+func newFieldSchema(f docs.FieldSpec) fieldSchema {
+	s := fieldSchema{
+		Name:        f.Name,
+		Type:        string(f.Type),
+		Kind:        "scalar",
+		Description: f.Description,
+		Advanced:    f.Advanced,
+		Options:     f.Options,
+	}
+	if f.IsArray {
+		s.Kind = "array"
+	} else if f.IsMap {
+		s.Kind = "map"
+	}
+	if f.Default != nil {
+		s.Default = *f.Default
+	}
+	for _, o := range f.AnnotatedOptions {
+		s.Options = append(s.Options, o[0])
+	}
+	for _, c := range f.Children {
+		if c.Deprecated {
+			continue
+		}
+		s.Children = append(s.Children, newFieldSchema(c))
+	}
+	return s
+}
+
+func newComponentSchemas(specs []docs.ComponentSpec) []componentSchema {
+	schemas := []componentSchema{}
+	for _, spec := range specs {
+		if spec.Status == docs.StatusDeprecated {
+			continue
+		}
+		c := componentSchema{
+			Name:    spec.Name,
+			Summary: spec.Summary,
+			Status:  string(spec.Status),
+			Fields:  []fieldSchema{},
+		}
+		if c.Status == "" {
+			c.Status = string(docs.StatusStable)
+		}
+		for _, f := range spec.Config.Children {
+			if f.Deprecated {
+				continue
+			}
+			c.Fields = append(c.Fields, newFieldSchema(f))
+		}
+		schemas = append(schemas, c)
+	}
+	return schemas
+}
+
+// componentsSchema returns the schemas of all inputs, processors and outputs.
+func componentsSchema() map[string][]componentSchema {
+	return map[string][]componentSchema{
+		"input":     newComponentSchemas(bundle.AllInputs.Docs()),
+		"processor": newComponentSchemas(bundle.AllProcessors.Docs()),
+		"output":    newComponentSchemas(bundle.AllOutputs.Docs()),
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,251 @@
+package studio
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/Jeffail/benthos/v3/public/components/legacy"
+)
+
+const testConfig = `input:
+  broker:
+    inputs:
+      - label: foo
+        generate:
+          mapping: 'root = "foo"'
+      - kafka:
+          addresses: [ localhost:9092 ]
+          topics: [ bar ]
+pipeline:
+  processors:
+    - bloblang: 'root = content().uppercase()'
+    - label: meta
+      bloblang: 'meta foo = "bar"'
+output:
+  drop: {}
+`
+
+func TestConfigGraph(t *testing.T) {
+	conf, lints, err := parseConfig([]byte(testConfig))
+	require.NoError(t, err)
+	assert.Empty(t, lints)
+
+	g := configGraph(conf, []byte(testConfig))
+
+	var paths []string
+	for _, n := range g.Nodes {
+		paths = append(paths, n.Kind+":"+n.Type+":"+n.Path)
+	}
+	assert.Equal(t, []string{
+		"input:generate:input.broker.inputs.0",
+		"input:kafka:input.broker.inputs.1",
+		"processor:bloblang:pipeline.processors.0",
+		"processor:bloblang:pipeline.processors.1",
+		"output:drop:output",
+	}, paths)
+	assert.Equal(t, [][2]int{{0, 2}, {1, 2}, {2, 3}, {3, 4}}, g.Edges)
+	assert.Equal(t, "foo", g.Nodes[0].Label)
+	assert.Equal(t, map[string]interface{}{
+		"label":    "meta",
+		"bloblang": `meta foo = "bar"`,
+	}, g.Nodes[3].Config)
+}
+
+func TestSetComponent(t *testing.T) {
+	newRaw, err := setComponent([]byte(testConfig), "pipeline.processors.1", map[string]interface{}{
+		"label":    "meta",
+		"bloblang": `meta foo = "baz"`,
+	})
+	require.NoError(t, err)
+
+	conf, _, err := parseConfig(newRaw)
+	require.NoError(t, err)
+	require.Len(t, conf.Pipeline.Processors, 2)
+	assert.Equal(t, `meta foo = "baz"`, string(conf.Pipeline.Processors[1].Bloblang))
+	assert.Equal(t, "foo", conf.Input.Broker.Inputs[0].Label)
+
+	_, err = setComponent([]byte(testConfig), "pipeline.processors.5", map[string]interface{}{})
+	require.Error(t, err)
+}
+
+func TestExecuteProcessors(t *testing.T) {
+	conf, _, err := parseConfig([]byte(testConfig))
+	require.NoError(t, err)
+
+	res, err := executeProcessors(conf, nil, executeMessage{Content: "hello"}, log.Noop())
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "HELLO", res[0].Content)
+	assert.Equal(t, map[string]string{"foo": "bar"}, res[0].Metadata)
+
+	res, err = executeProcessors(conf, []int{1}, executeMessage{Content: "hello"}, log.Noop())
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "hello", res[0].Content)
+
+	_, err = executeProcessors(conf, []int{2}, executeMessage{Content: "hello"}, log.Noop())
+	require.Error(t, err)
+}
+
+func newTestServer(t *testing.T, state *configState) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config.Handler = newRequestGuard("footoken", ts.Listener.Addr().String()).wrap(newServeMux(state, log.Noop()))
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestServerConfigRoundTrip(t *testing.T) {
+	state, err := newConfigState("", false)
+	require.NoError(t, err)
+
+	ts := newTestServer(t, state)
+
+	body, err := json.Marshal(map[string]string{"yaml": testConfig})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", ts.URL+"/api/config", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(tokenHeader, "footoken")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var confRes configResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&confRes))
+	assert.Empty(t, confRes.Error)
+	require.NotNil(t, confRes.Graph)
+	assert.Len(t, confRes.Graph.Nodes, 5)
+	assert.Equal(t, testConfig, string(state.get()))
+
+	req, err = http.NewRequest("GET", ts.URL+"/api/schema", nil)
+	require.NoError(t, err)
+	req.Header.Set(tokenHeader, "footoken")
+
+	schemaRes, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer schemaRes.Body.Close()
+
+	var schema map[string][]componentSchema
+	require.NoError(t, json.NewDecoder(schemaRes.Body).Decode(&schema))
+	assert.NotEmpty(t, schema["input"])
+	assert.NotEmpty(t, schema["processor"])
+	assert.NotEmpty(t, schema["output"])
+}
+
+func TestServerRequestGuard(t *testing.T) {
+	state, err := newConfigState("", false)
+	require.NoError(t, err)
+
+	ts := newTestServer(t, state)
+
+	tests := []struct {
+		name   string
+		path   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{
+			name:   "valid request",
+			modify: func(r *http.Request) {},
+			status: http.StatusOK,
+		},
+		{
+			name:   "page without token",
+			path:   "/",
+			modify: func(r *http.Request) { r.Header.Del(tokenHeader) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "missing token",
+			modify: func(r *http.Request) { r.Header.Del(tokenHeader) },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			modify: func(r *http.Request) { r.Header.Set(tokenHeader, "bartoken") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "form content type",
+			modify: func(r *http.Request) { r.Header.Set("Content-Type", "application/x-www-form-urlencoded") },
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "foreign origin",
+			modify: func(r *http.Request) { r.Header.Set("Origin", "http://evil.example.com") },
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "foreign host",
+			modify: func(r *http.Request) { r.Host = "evil.example.com" },
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path := test.path
+			if path == "" {
+				path = "/api/config"
+			}
+			body, err := json.Marshal(map[string]string{"yaml": testConfig})
+			require.NoError(t, err)
+
+			method := "POST"
+			if path == "/" {
+				method = "GET"
+			}
+			req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Origin", ts.URL)
+			req.Header.Set(tokenHeader, "footoken")
+			test.modify(req)
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, test.status, res.StatusCode)
+		})
+	}
+}
+
+func TestRequestGuardHosts(t *testing.T) {
+	for bind, hosts := range map[string]map[string]bool{
+		"localhost:4196": {
+			"localhost:4196": true,
+			"127.0.0.1:4196": true,
+			"[::1]:4196":     true,
+			"localhost:4197": false,
+			"10.0.0.1:4196":  false,
+		},
+		"0.0.0.0:4196": {
+			"0.0.0.0:4196":   true,
+			"localhost:4196": true,
+			"10.0.0.1:4196":  false,
+		},
+		"10.0.0.1:4196": {
+			"10.0.0.1:4196":  true,
+			"localhost:4196": false,
+		},
+	} {
+		g := newRequestGuard("footoken", bind)
+		for host, allowed := range hosts {
+			_, exists := g.allowedHosts[host]
+			assert.Equal(t, allowed, exists, "%v: %v", bind, host)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package studio
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type executeMessage struct {
+	Content  string            `json:"content"`
+	Metadata map[string]string `json:"metadata"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// executeProcessors runs a test message through a selection of the pipeline
+// processors of a config, identified by their index. When no indexes are
+// provided all pipeline processors are executed.
+func executeProcessors(conf config.Type, indexes []int, in executeMessage, logger log.Modular) ([]executeMessage, error) {
+	procConfs := conf.Pipeline.Processors
+	if len(indexes) > 0 {
+		procConfs = make([]processor.Config, 0, len(indexes))
+		for _, i := range indexes {
+			if i < 0 || i >= len(conf.Pipeline.Processors) {
+				return nil, fmt.Errorf("processor index '%v' does not exist", i)
+			}
+			procConfs = append(procConfs, conf.Pipeline.Processors[i])
+		}
+	}
+
+	mgr, err := manager.NewV2(conf.ResourceConfig, types.NoopMgr(), logger, metrics.Noop())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+	}
+	defer func() {
+		mgr.CloseAsync()
+		_ = mgr.WaitForClose(time.Second * 5)
+	}()
+
+	procs := make([]types.Processor, len(procConfs))
+	for i, pConf := range procConfs {
+		if procs[i], err = processor.New(pConf, mgr, logger, metrics.Noop()); err != nil {
+			return nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
+		}
+	}
+	defer func() {
+		for _, p := range procs {
+			p.CloseAsync()
+		}
+		for _, p := range procs {
+			_ = p.WaitForClose(time.Second * 5)
+		}
+	}()
+
+	part := message.NewPart([]byte(in.Content))
+	for k, v := range in.Metadata {
+		part.Metadata().Set(k, v)
+	}
+	msg := message.New(nil)
+	msg.Append(part)
+
+	results := []executeMessage{}
+	outMsgs, res := processor.ExecuteAll(procs, msg)
+	if res != nil && res.Error() != nil {
+		return nil, res.Error()
+	}
+	for _, m := range outMsgs {
+		_ = m.Iter(func(_ int, p types.Part) error {
+			out := executeMessage{
+				Content:  string(p.Get()),
+				Metadata: map[string]string{},
+				Error:    processor.GetFail(p),
+			}
+			_ = p.Metadata().Iter(func(k, v string) error {
+				out.Metadata[k] = v
+				return nil
+			})
+			results = append(results, out)
+			return nil
+		})
+	}
+	return results, nil
+}
+
+//------------------------------------------------------------------------------
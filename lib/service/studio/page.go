@@ -0,0 +1,462 @@
+package studio
+
+// TODO: When we upgrade to Go 1.16 we can use the new embed stuff.
+const studioPage = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <title>Benthos Studio</title>
+    <style>
+      html, body {
+        background-color: #202020;
+        color: #fff;
+        font-family: monospace;
+        margin: 0;
+        padding: 0;
+        height: 100%;
+        width: 100%;
+      }
+      .panel {
+        position: absolute;
+        box-sizing: border-box;
+        padding: 5px;
+        overflow: auto;
+      }
+      .panel > h2 {
+        margin: 0 0 5px 0;
+        font-size: 11pt;
+        color: #a6e22e;
+      }
+      textarea, input, select {
+        background-color: #33352e;
+        color: #fff;
+        border: solid #33352e 2px;
+        font-family: monospace;
+        font-size: 11pt;
+        box-sizing: border-box;
+      }
+      textarea {
+        resize: none;
+        width: 100%;
+      }
+      #yaml {
+        height: calc(100% - 120px);
+      }
+      #lints {
+        height: 80px;
+        overflow: auto;
+        margin: 5px 0 0 0;
+        white-space: pre-wrap;
+      }
+      .error {
+        color: #f92672;
+      }
+      #graph {
+        display: flex;
+        flex-direction: row;
+        align-items: center;
+        overflow-x: auto;
+      }
+      .column {
+        display: flex;
+        flex-direction: column;
+        margin-right: 25px;
+        position: relative;
+      }
+      .column:not(:last-child)::after {
+        content: "\2192";
+        position: absolute;
+        right: -20px;
+        top: 50%;
+        color: #a6e22e;
+      }
+      .node {
+        background-color: #33352e;
+        border: solid #33352e 2px;
+        padding: 5px 10px;
+        margin: 5px 0;
+        cursor: pointer;
+        min-width: 120px;
+      }
+      .node.input { border-left-color: #66d9ef; }
+      .node.processor { border-left-color: #a6e22e; }
+      .node.output { border-left-color: #fd971f; }
+      .node.selected { border-color: #e6db74; }
+      .node .kind {
+        font-size: 8pt;
+        color: #75715e;
+      }
+      .field {
+        margin: 5px 0;
+      }
+      .field label {
+        display: block;
+        color: #e6db74;
+      }
+      .field .desc {
+        color: #75715e;
+        font-size: 9pt;
+      }
+      .field input[type=text], .field input[type=number], .field select {
+        width: 100%;
+      }
+      button {
+        background-color: #a6e22e;
+        color: #202020;
+        border: none;
+        padding: 5px 10px;
+        font-family: monospace;
+        cursor: pointer;
+      }
+    </style>
+  </head>
+  <body>
+    <div class="panel" style="top:0;bottom:0;left:0;width:40%">
+      <h2>Config</h2>
+      <textarea id="yaml" spellcheck="false"></textarea>
+      <div id="lints"></div>
+    </div>
+    <div class="panel" style="top:0;height:30%;left:40%;right:0">
+      <h2>Pipeline</h2>
+      <div id="graph"></div>
+    </div>
+    <div class="panel" style="top:30%;bottom:0;left:40%;width:30%">
+      <h2>Component</h2>
+      <div id="form">Select a component in order to edit it.</div>
+    </div>
+    <div class="panel" style="top:30%;bottom:0;left:70%;right:0">
+      <h2>Test</h2>
+      <div class="field">
+        <label>Content</label>
+        <textarea id="test-content" rows="6" spellcheck="false">{"message":"hello world"}</textarea>
+      </div>
+      <div class="field">
+        <label>Metadata (JSON)</label>
+        <textarea id="test-metadata" rows="3" spellcheck="false">{}</textarea>
+      </div>
+      <div class="field">
+        <span class="desc">Runs the selected pipeline processors, or all of them if none are selected.</span>
+      </div>
+      <button id="test-run">Run</button>
+      <pre id="test-results"></pre>
+    </div>
+  </body>
+  <script>
+    var schema = {};
+    var currentGraph = null;
+    var selectedNode = null;
+    var selectedProcs = {};
+
+    const yamlArea = document.getElementById("yaml");
+    const lintsArea = document.getElementById("lints");
+    const graphArea = document.getElementById("graph");
+    const formArea = document.getElementById("form");
+
+    const token = new URLSearchParams(window.location.search).get("token") || "";
+
+    function apiFetch(path, opts) {
+      opts = opts || {};
+      opts.headers = Object.assign({ "X-Studio-Token": token }, opts.headers);
+      return fetch(path, opts);
+    }
+
+    function postJSON(path, body) {
+      return apiFetch(path, {
+        method: 'POST',
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(body),
+      }).then(response => {
+        if (response.status !== 200) {
+          throw new Error('Request failed with status ' + response.status);
+        }
+        return response.json();
+      });
+    }
+
+    function text(tag, content, className) {
+      const el = document.createElement(tag);
+      el.appendChild(document.createTextNode(content));
+      if (className) {
+        el.className = className;
+      }
+      return el;
+    }
+
+    function applyConfigResponse(res, updateYAML) {
+      if (updateYAML) {
+        yamlArea.value = res.yaml;
+      }
+      lintsArea.innerHTML = "";
+      if (res.error) {
+        yamlArea.style.borderColor = "#f92672";
+        lintsArea.appendChild(text("div", res.error, "error"));
+        return;
+      }
+      yamlArea.style.borderColor = "#33352e";
+      res.lints.forEach(l => lintsArea.appendChild(text("div", l)));
+      if (res.graph) {
+        currentGraph = res.graph;
+        renderGraph();
+      }
+    }
+
+    function procIndex(node) {
+      const prefix = "pipeline.processors.";
+      if (node.kind !== "processor" || !node.path.startsWith(prefix)) {
+        return -1;
+      }
+      return parseInt(node.path.substring(prefix.length));
+    }
+
+    function renderGraph() {
+      graphArea.innerHTML = "";
+      const ranks = {};
+      currentGraph.nodes.forEach(n => ranks[n.id] = 0);
+      let changed = true;
+      while (changed) {
+        changed = false;
+        currentGraph.edges.forEach(e => {
+          if (ranks[e[1]] < ranks[e[0]] + 1) {
+            ranks[e[1]] = ranks[e[0]] + 1;
+            changed = true;
+          }
+        });
+      }
+      const columns = [];
+      currentGraph.nodes.forEach(n => {
+        const r = ranks[n.id];
+        while (columns.length <= r) {
+          const col = document.createElement("div");
+          col.className = "column";
+          columns.push(col);
+        }
+        const el = document.createElement("div");
+        el.className = "node " + n.kind;
+        if (selectedNode !== null && selectedNode.path === n.path) {
+          el.className += " selected";
+          selectedNode = n;
+        }
+        el.appendChild(text("div", n.kind, "kind"));
+        el.appendChild(text("div", n.type + (n.label ? " (" + n.label + ")" : "")));
+        const idx = procIndex(n);
+        if (idx >= 0) {
+          const check = document.createElement("input");
+          check.type = "checkbox";
+          check.title = "Include in test runs";
+          check.checked = !!selectedProcs[idx];
+          check.addEventListener("click", e => {
+            e.stopPropagation();
+            selectedProcs[idx] = check.checked;
+          });
+          el.appendChild(check);
+        }
+        el.addEventListener("click", () => {
+          selectedNode = n;
+          renderGraph();
+          renderForm(n, n.type);
+        });
+        columns[r].appendChild(el);
+      });
+      columns.forEach(c => graphArea.appendChild(c));
+    }
+
+    function findSpec(kind, cType) {
+      return (schema[kind] || []).find(s => s.name === cType);
+    }
+
+    function fieldInput(field, value) {
+      let input;
+      if (field.kind === "scalar" && field.type === "bool") {
+        input = document.createElement("input");
+        input.type = "checkbox";
+        input.checked = !!value;
+        input.getValue = () => input.checked;
+      } else if (field.kind === "scalar" && field.options && field.options.length > 0) {
+        input = document.createElement("select");
+        field.options.forEach(o => {
+          const opt = text("option", o);
+          opt.value = o;
+          input.appendChild(opt);
+        });
+        input.value = value === undefined ? "" : value;
+        input.getValue = () => input.value;
+      } else if (field.kind === "scalar" && (field.type === "int" || field.type === "float")) {
+        input = document.createElement("input");
+        input.type = "number";
+        input.value = value === undefined ? "" : value;
+        input.getValue = () => input.value === "" ? undefined : Number(input.value);
+      } else if (field.kind === "scalar" && (field.type === "string" || field.type === "")) {
+        input = document.createElement("input");
+        input.type = "text";
+        input.value = value === undefined ? "" : value;
+        input.getValue = () => input.value;
+      } else {
+        input = document.createElement("textarea");
+        input.rows = 3;
+        input.value = value === undefined ? "" : JSON.stringify(value, null, 2);
+        input.getValue = () => input.value.trim() === "" ? undefined : JSON.parse(input.value);
+      }
+      return input;
+    }
+
+    function renderForm(node, cType) {
+      formArea.innerHTML = "";
+      const original = node.config || {};
+      const spec = findSpec(node.kind, cType);
+
+      const typeField = document.createElement("div");
+      typeField.className = "field";
+      typeField.appendChild(text("label", "type"));
+      const typeSelect = document.createElement("select");
+      (schema[node.kind] || []).forEach(s => {
+        const opt = text("option", s.name);
+        opt.value = s.name;
+        typeSelect.appendChild(opt);
+      });
+      typeSelect.value = cType;
+      typeSelect.addEventListener("change", () => renderForm(node, typeSelect.value));
+      typeField.appendChild(typeSelect);
+      formArea.appendChild(typeField);
+
+      const labelField = document.createElement("div");
+      labelField.className = "field";
+      labelField.appendChild(text("label", "label"));
+      const labelInput = document.createElement("input");
+      labelInput.type = "text";
+      labelInput.value = original.label || "";
+      labelField.appendChild(labelInput);
+      formArea.appendChild(labelField);
+
+      const values = (cType === node.type && original[cType]) || {};
+      const inputs = {};
+      const advanced = document.createElement("details");
+      advanced.appendChild(text("summary", "Advanced"));
+
+      if (spec) {
+        formArea.appendChild(text("div", spec.summary, "desc"));
+        spec.fields.forEach(f => {
+          const el = document.createElement("div");
+          el.className = "field";
+          el.appendChild(text("label", f.name));
+          const input = fieldInput(f, f.name in values ? values[f.name] : f.default);
+          inputs[f.name] = input;
+          el.appendChild(input);
+          if (f.description) {
+            el.appendChild(text("div", f.description.split("\n")[0], "desc"));
+          }
+          (f.advanced ? advanced : formArea).appendChild(el);
+        });
+      }
+      formArea.appendChild(advanced);
+
+      const apply = text("button", "Apply");
+      apply.addEventListener("click", () => {
+        const newConf = {};
+        Object.keys(original).forEach(k => {
+          if (k !== node.type && k !== "label") {
+            newConf[k] = original[k];
+          }
+        });
+        if (labelInput.value !== "") {
+          newConf.label = labelInput.value;
+        }
+        const fields = {};
+        try {
+          if (spec) {
+            spec.fields.forEach(f => {
+              const v = inputs[f.name].getValue();
+              if (v === undefined) {
+                return;
+              }
+              if (f.name in values || JSON.stringify(v) !== JSON.stringify(f.default)) {
+                fields[f.name] = v;
+              }
+            });
+          }
+        } catch (err) {
+          lintsArea.innerHTML = "";
+          lintsArea.appendChild(text("div", "Invalid field value: " + err, "error"));
+          return;
+        }
+        newConf[typeSelect.value] = fields;
+        postJSON("/api/component", {
+          yaml: yamlArea.value,
+          path: node.path,
+          config: newConf,
+        }).then(res => applyConfigResponse(res, !res.error)).catch(console.error);
+      });
+      formArea.appendChild(apply);
+    }
+
+    var updateTimeout = null;
+    yamlArea.addEventListener("input", () => {
+      clearTimeout(updateTimeout);
+      updateTimeout = setTimeout(() => {
+        postJSON("/api/config", { yaml: yamlArea.value })
+          .then(res => applyConfigResponse(res, false))
+          .catch(console.error);
+      }, 300);
+    });
+    yamlArea.addEventListener("keydown", function(e) {
+      if (e.key == "Tab") {
+        e.preventDefault();
+        const start = this.selectionStart;
+        const end = this.selectionEnd;
+        this.value = this.value.substring(0, start) + "  " + this.value.substring(end);
+        this.selectionStart = start + 2;
+        this.selectionEnd = start + 2;
+      }
+    });
+
+    document.getElementById("test-run").addEventListener("click", () => {
+      const resultsArea = document.getElementById("test-results");
+      let metadata = {};
+      try {
+        metadata = JSON.parse(document.getElementById("test-metadata").value || "{}");
+      } catch (err) {
+        resultsArea.innerHTML = "";
+        resultsArea.appendChild(text("div", "Invalid metadata: " + err, "error"));
+        return;
+      }
+      const procs = Object.keys(selectedProcs)
+        .filter(k => selectedProcs[k])
+        .map(k => parseInt(k))
+        .sort((a, b) => a - b);
+      postJSON("/api/execute", {
+        yaml: yamlArea.value,
+        processors: procs,
+        input: {
+          content: document.getElementById("test-content").value,
+          metadata: metadata,
+        },
+      }).then(res => {
+        resultsArea.innerHTML = "";
+        if (res.error) {
+          resultsArea.appendChild(text("div", res.error, "error"));
+          return;
+        }
+        if (res.results.length === 0) {
+          resultsArea.appendChild(text("div", "No messages remaining"));
+        }
+        res.results.forEach((r, i) => {
+          resultsArea.appendChild(text("div", "Message " + i + ":"));
+          resultsArea.appendChild(text("div", r.content));
+          resultsArea.appendChild(text("div", "Metadata: " + JSON.stringify(r.metadata)));
+          if (r.error) {
+            resultsArea.appendChild(text("div", "Error: " + r.error, "error"));
+          }
+        });
+      }).catch(console.error);
+    });
+
+    apiFetch("/api/schema")
+      .then(response => response.json())
+      .then(res => {
+        schema = res;
+        return apiFetch("/api/config");
+      })
+      .then(response => response.json())
+      .then(res => applyConfigResponse(res, true))
+      .catch(console.error);
+  </script>
+</html>`
@@ -0,0 +1,361 @@
+package studio
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/urfave/cli/v2"
+)
+
+//------------------------------------------------------------------------------
+
+func openBrowserAt(url string) {
+	switch runtime.GOOS {
+	case "linux":
+		_ = exec.Command("xdg-open", url).Start()
+	case "windows":
+		_ = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	case "darwin":
+		_ = exec.Command("open", url).Start()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type configState struct {
+	mut sync.Mutex
+
+	raw       []byte
+	path      string
+	writeBack bool
+}
+
+func newConfigState(path string, writeBack bool) (*configState, error) {
+	s := &configState{
+		raw:       []byte(defaultConfig),
+		path:      path,
+		writeBack: writeBack,
+	}
+	if path != "" {
+		rawBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			if !writeBack || !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		} else {
+			s.raw = rawBytes
+		}
+	}
+	return s, nil
+}
+
+func (s *configState) get() []byte {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.raw
+}
+
+func (s *configState) set(raw []byte) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if string(raw) == string(s.raw) {
+		return nil
+	}
+	s.raw = raw
+	if !s.writeBack || s.path == "" {
+		return nil
+	}
+	return ioutil.WriteFile(s.path, raw, 0644)
+}
+
+//------------------------------------------------------------------------------
+
+type configResponse struct {
+	YAML       string   `json:"yaml"`
+	Normalised string   `json:"normalised"`
+	Graph      *graph   `json:"graph,omitempty"`
+	Lints      []string `json:"lints"`
+	Error      string   `json:"error,omitempty"`
+}
+
+func newConfigResponse(raw []byte) configResponse {
+	res := configResponse{
+		YAML:  string(raw),
+		Lints: []string{},
+	}
+	conf, lints, err := parseConfig(raw)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if lints != nil {
+		res.Lints = lints
+	}
+	if norm, err := normaliseConfig(conf); err == nil {
+		res.Normalised = string(norm)
+	}
+	g := configGraph(conf, raw)
+	res.Graph = &g
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	resBytes, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resBytes)
+}
+
+// tokenHeader is the header that API requests must provide the session token
+// within.
+const tokenHeader = "X-Studio-Token"
+
+func newSessionToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// requestGuard prevents the API of the server from being driven by other
+// origins, such as a malicious page open in the same browser or a DNS
+// rebinding attack, as the API is able to write files and execute processors.
+type requestGuard struct {
+	token        string
+	allowedHosts map[string]struct{}
+}
+
+func newRequestGuard(token, bindAddress string) *requestGuard {
+	g := &requestGuard{
+		token:        token,
+		allowedHosts: map[string]struct{}{bindAddress: {}},
+	}
+	host, port, err := net.SplitHostPort(bindAddress)
+	if err != nil {
+		return g
+	}
+	if ip := net.ParseIP(host); host == "" || host == "localhost" || (ip != nil && (ip.IsLoopback() || ip.IsUnspecified())) {
+		for _, h := range []string{"localhost", "127.0.0.1", "::1"} {
+			g.allowedHosts[net.JoinHostPort(h, port)] = struct{}{}
+		}
+	}
+	return g
+}
+
+func (g *requestGuard) check(r *http.Request) (int, error) {
+	if _, exists := g.allowedHosts[r.Host]; !exists {
+		return http.StatusForbidden, fmt.Errorf("host %v does not match the bind address", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return http.StatusForbidden, fmt.Errorf("failed to parse origin: %w", err)
+		}
+		if _, exists := g.allowedHosts[u.Host]; !exists {
+			return http.StatusForbidden, fmt.Errorf("origin %v does not match the bind address", origin)
+		}
+	}
+	if r.URL.Path == "/" {
+		return 0, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(tokenHeader)), []byte(g.token)) != 1 {
+		return http.StatusUnauthorized, errors.New("missing or invalid session token")
+	}
+	if r.Method == "POST" {
+		if mType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mType != "application/json" {
+			return http.StatusUnsupportedMediaType, errors.New("content type must be application/json")
+		}
+	}
+	return 0, nil
+}
+
+func (g *requestGuard) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, err := g.check(r); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func newServeMux(state *configState, logger log.Modular) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/config", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			writeJSON(w, newConfigResponse(state.get()))
+		case "POST":
+			req := struct {
+				YAML string `json:"yaml"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			res := newConfigResponse([]byte(req.YAML))
+			if res.Error == "" {
+				if err := state.set([]byte(req.YAML)); err != nil {
+					res.Error = fmt.Sprintf("failed to write config: %v", err)
+				}
+			}
+			writeJSON(w, res)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/schema", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, componentsSchema())
+	})
+
+	mux.HandleFunc("/api/component", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req := struct {
+			YAML   string      `json:"yaml"`
+			Path   string      `json:"path"`
+			Config interface{} `json:"config"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		newRaw, err := setComponent([]byte(req.YAML), req.Path, req.Config)
+		if err != nil {
+			writeJSON(w, configResponse{
+				YAML:  req.YAML,
+				Lints: []string{},
+				Error: err.Error(),
+			})
+			return
+		}
+		res := newConfigResponse(newRaw)
+		if res.Error == "" {
+			if err := state.set(newRaw); err != nil {
+				res.Error = fmt.Sprintf("failed to write config: %v", err)
+			}
+		}
+		writeJSON(w, res)
+	})
+
+	mux.HandleFunc("/api/execute", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req := struct {
+			YAML       string         `json:"yaml"`
+			Processors []int          `json:"processors"`
+			Input      executeMessage `json:"input"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res := struct {
+			Results []executeMessage `json:"results"`
+			Error   string           `json:"error,omitempty"`
+		}{
+			Results: []executeMessage{},
+		}
+		conf, _, err := parseConfig([]byte(req.YAML))
+		if err == nil {
+			var results []executeMessage
+			if results, err = executeProcessors(conf, req.Processors, req.Input, logger); err == nil {
+				res.Results = results
+			}
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		writeJSON(w, res)
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(studioPage))
+	})
+
+	return mux
+}
+
+func runServer(c *cli.Context) error {
+	state, err := newConfigState(c.String("config"), c.Bool("write"))
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	host, port := c.String("host"), c.String("port")
+	bindAddress := net.JoinHostPort(host, port)
+
+	logger := log.Noop()
+	mux := newServeMux(state, logger)
+
+	urlHost := bindAddress
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		urlHost = net.JoinHostPort("localhost", port)
+	}
+	appURL := fmt.Sprintf("http://%v/?token=%v", urlHost, token)
+
+	if !c.Bool("no-open") {
+		openBrowserAt(appURL)
+	}
+
+	fmt.Printf("Serving at: %v\n", appURL)
+
+	server := http.Server{
+		Addr:    bindAddress,
+		Handler: newRequestGuard(token, bindAddress).wrap(mux),
+	}
+
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+		// Wait for termination signal
+		<-sigChan
+		_ = server.Shutdown(context.Background())
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to listen and serve: %w", err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -20,6 +20,7 @@ func CliCommand(testSuffix string) *cli.Command {
    benthos test ./path/to/configs/...
    benthos test ./foo_configs ./bar_configs
    benthos test ./foo.yaml
+   benthos test --integration ./...
 
    For more information check out the docs at:
    https://benthos.dev/docs/configuration/unit_testing`[4:],
@@ -29,6 +30,11 @@ func CliCommand(testSuffix string) *cli.Command {
 				Value: false,
 				Usage: "instead of testing, detect untested Benthos configs and generate test definitions for them.",
 			},
+			&cli.BoolFlag{
+				Name:  "integration",
+				Value: false,
+				Usage: "run tests that declare services, starting each service within a docker container for the duration of the test.",
+			},
 			&cli.StringFlag{
 				Name:  "log",
 				Value: "",
@@ -49,10 +55,10 @@ func CliCommand(testSuffix string) *cli.Command {
 				logConf := log.NewConfig()
 				logConf.LogLevel = logLevel
 				logger := log.New(os.Stdout, logConf)
-				if runAll(c.Args().Slice(), testSuffix, true, logger, c.StringSlice("resources")) {
+				if runAll(c.Args().Slice(), testSuffix, true, c.Bool("integration"), logger, c.StringSlice("resources")) {
 					os.Exit(0)
 				}
-			} else if runAll(c.Args().Slice(), testSuffix, true, log.Noop(), c.StringSlice("resources")) {
+			} else if runAll(c.Args().Slice(), testSuffix, true, c.Bool("integration"), log.Noop(), c.StringSlice("resources")) {
 				os.Exit(0)
 			}
 			os.Exit(1)
@@ -158,17 +158,32 @@ func Run(path, testSuffix string, lint bool) bool {
 // a config file, a config files test definition file, a directory, or the
 // wildcard pattern './...'.
 func RunAll(paths []string, testSuffix string, lint bool) bool {
-	return runAll(paths, testSuffix, lint, log.Noop(), nil)
+	return runAll(paths, testSuffix, lint, false, log.Noop(), nil)
 }
 
 // RunAllWithLogger executes the test command for a slice of paths. The path can
 // either be a config file, a config files test definition file, a directory, or
 // the wildcard pattern './...'.
 func RunAllWithLogger(paths []string, testSuffix string, lint bool, logger log.Modular) bool {
-	return runAll(paths, testSuffix, lint, logger, nil)
+	return runAll(paths, testSuffix, lint, false, logger, nil)
 }
 
-func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string) bool {
+// executeWithServices runs a test definition after starting any services it
+// depends on, exposing their connection details as environment variables.
+func executeWithServices(def Definition, target string, resourcesPaths []string, logger log.Modular) ([]CaseFailure, error) {
+	envVars, cleanup, err := startServices(def.Services)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	cleanupEnv := setEnvironment(envVars)
+	defer cleanupEnv()
+
+	return def.execute(target, resourcesPaths, logger)
+}
+
+func runAll(paths []string, testSuffix string, lint, integration bool, logger log.Modular, resourcesPaths []string) bool {
 	targets := map[string]Definition{}
 
 	for _, path := range paths {
@@ -204,6 +219,10 @@ func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, re
 
 	var err error
 	for _, target := range targetPaths {
+		if len(targets[target].Services) > 0 && !integration {
+			fmt.Printf("Test '%v' %v\n", target, yellow("skipped (requires --integration)"))
+			continue
+		}
 		var lints []string
 		var failCases []CaseFailure
 		if lint {
@@ -212,7 +231,7 @@ func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, re
 				return false
 			}
 		}
-		if failCases, err = targets[target].execute(target, resourcesPaths, logger); err != nil {
+		if failCases, err = executeWithServices(targets[target], target, resourcesPaths, logger); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 			return false
 		}
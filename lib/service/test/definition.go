@@ -11,8 +11,9 @@ import (
 
 // Definition of a group of tests for a Benthos config file.
 type Definition struct {
-	Parallel bool   `yaml:"parallel"`
-	Cases    []Case `yaml:"tests"`
+	Parallel bool                         `yaml:"parallel"`
+	Services map[string]ServiceDefinition `yaml:"services,omitempty"`
+	Cases    []Case                       `yaml:"tests"`
 }
 
 // ExampleDefinition returns a Definition containing an example case.
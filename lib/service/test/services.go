@@ -0,0 +1,144 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// ServiceDefinition describes a dependency of a test definition that is run
+// within a docker container when tests are executed in integration mode.
+type ServiceDefinition struct {
+	Preset string   `yaml:"preset"`
+	Image  string   `yaml:"image"`
+	Tag    string   `yaml:"tag"`
+	Env    []string `yaml:"env"`
+	Cmd    []string `yaml:"cmd"`
+	Ports  []string `yaml:"ports"`
+}
+
+var servicePresets = map[string]ServiceDefinition{
+	"redis": {
+		Image: "redis",
+		Tag:   "6.2.6",
+		Ports: []string{"6379/tcp"},
+	},
+	"kafka": {
+		Image: "vectorized/redpanda",
+		Tag:   "v21.11.2",
+		Cmd: []string{
+			"redpanda", "start", "--smp", "1", "--overprovisioned",
+			"--kafka-addr", "0.0.0.0:9092",
+			"--advertise-kafka-addr", "localhost:${HOST_PORT_9092}",
+		},
+		Ports: []string{"9092/tcp"},
+	},
+	"localstack": {
+		Image: "localstack/localstack",
+		Tag:   "0.12.17",
+		Ports: []string{"4566/tcp"},
+	},
+}
+
+// resolve returns a copy of the service definition with any preset applied,
+// where the preset defaults to the name of the service when an image is not
+// specified.
+func (s ServiceDefinition) resolve(name string) (ServiceDefinition, error) {
+	preset := s.Preset
+	if preset == "" && s.Image == "" {
+		preset = name
+	}
+	if preset != "" {
+		p, exists := servicePresets[preset]
+		if !exists {
+			return s, fmt.Errorf("service '%v' preset '%v' not recognised", name, preset)
+		}
+		if s.Image == "" {
+			s.Image = p.Image
+			if s.Tag == "" {
+				s.Tag = p.Tag
+			}
+		}
+		if len(s.Cmd) == 0 {
+			s.Cmd = p.Cmd
+		}
+		if len(s.Ports) == 0 {
+			s.Ports = p.Ports
+		}
+		s.Env = append(append([]string{}, p.Env...), s.Env...)
+	}
+	if s.Tag == "" {
+		s.Tag = "latest"
+	}
+	if len(s.Ports) == 0 {
+		return s, fmt.Errorf("service '%v' must expose at least one port", name)
+	}
+	ports := make([]string, len(s.Ports))
+	for i, p := range s.Ports {
+		if !strings.Contains(p, "/") {
+			p += "/tcp"
+		}
+		ports[i] = p
+	}
+	s.Ports = ports
+	s.Preset = ""
+	return s, nil
+}
+
+// usesHostPorts returns whether the env or cmd of a service refers to the host
+// ports it is bound to, in which case they must be known before it starts.
+func usesHostPorts(svc ServiceDefinition) bool {
+	for _, strs := range [][]string{svc.Env, svc.Cmd} {
+		for _, str := range strs {
+			if strings.Contains(str, "HOST_PORT_") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func serviceEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func portNumber(port string) string {
+	return strings.SplitN(port, "/", 2)[0]
+}
+
+// serviceEnvVars returns the environment variables that expose the connection
+// details of a running service to the config being tested.
+func serviceEnvVars(name string, ports []string, hostPorts map[string]string) map[string]string {
+	prefix := serviceEnvName(name)
+	vars := map[string]string{
+		prefix + "_HOST": "localhost",
+	}
+	for i, p := range ports {
+		if i == 0 {
+			vars[prefix+"_PORT"] = hostPorts[p]
+		}
+		vars[prefix+"_PORT_"+portNumber(p)] = hostPorts[p]
+	}
+	return vars
+}
+
+func sortedServiceNames(services map[string]ServiceDefinition) []string {
+	names := make([]string, 0, len(services))
+	for k := range services {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//------------------------------------------------------------------------------
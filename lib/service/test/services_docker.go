@@ -0,0 +1,176 @@
+// +build !wasm
+
+package test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+//------------------------------------------------------------------------------
+
+// maxBindAttempts is the number of times a service that binds explicit host
+// ports is started before giving up, as a port found to be free may be taken
+// by another process before the container binds it.
+const maxBindAttempts = 5
+
+func getFreePort() (string, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+func isBindConflict(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "port is already allocated") ||
+		strings.Contains(msg, "address already in use")
+}
+
+// runService starts the container of a service and returns it along with the
+// host ports its container ports are bound to. Host ports are assigned by
+// docker unless the service refers to them, in which case free ports are
+// chosen up front and the container is restarted if any of them are taken by
+// the time it binds them.
+func runService(pool *dockertest.Pool, name string, svc ServiceDefinition) (*dockertest.Resource, map[string]string, error) {
+	hostPorts := map[string]string{}
+	expand := func(strs []string) []string {
+		expanded := make([]string, len(strs))
+		for i, str := range strs {
+			expanded[i] = os.Expand(str, func(k string) string {
+				if strings.HasPrefix(k, "HOST_PORT_") {
+					return hostPorts[strings.TrimPrefix(k, "HOST_PORT_")+"/tcp"]
+				}
+				return os.Getenv(k)
+			})
+		}
+		return expanded
+	}
+
+	if !usesHostPorts(svc) {
+		resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+			Repository:   svc.Image,
+			Tag:          svc.Tag,
+			Env:          expand(svc.Env),
+			Cmd:          expand(svc.Cmd),
+			ExposedPorts: svc.Ports,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range svc.Ports {
+			if hostPorts[p] = resource.GetPort(p); hostPorts[p] == "" {
+				_ = pool.Purge(resource)
+				return nil, nil, fmt.Errorf("no host port was bound to port %v", p)
+			}
+		}
+		return resource, hostPorts, nil
+	}
+
+	var err error
+	for attempt := 0; attempt < maxBindAttempts; attempt++ {
+		bindings := map[docker.Port][]docker.PortBinding{}
+		for _, p := range svc.Ports {
+			var hostPort string
+			if hostPort, err = getFreePort(); err != nil {
+				return nil, nil, fmt.Errorf("failed to allocate port: %w", err)
+			}
+			hostPorts[p] = hostPort
+			bindings[docker.Port(p)] = []docker.PortBinding{{HostPort: hostPort}}
+		}
+
+		// The container is named so that it can be removed when it was created
+		// but failed to start.
+		containerName := fmt.Sprintf("benthos-test-%v-%v", strings.ToLower(serviceEnvName(name)), time.Now().UnixNano())
+
+		var resource *dockertest.Resource
+		if resource, err = pool.RunWithOptions(&dockertest.RunOptions{
+			Name:         containerName,
+			Repository:   svc.Image,
+			Tag:          svc.Tag,
+			Env:          expand(svc.Env),
+			Cmd:          expand(svc.Cmd),
+			ExposedPorts: svc.Ports,
+			PortBindings: bindings,
+		}); err == nil {
+			return resource, hostPorts, nil
+		}
+		_ = pool.RemoveContainerByName(containerName)
+		if !isBindConflict(err) {
+			break
+		}
+	}
+	return nil, nil, err
+}
+
+// startServices runs each service of a test definition within a docker
+// container and waits for their ports to accept connections. Returns the
+// environment variables describing how to connect to the services and a
+// function that tears the containers down.
+func startServices(services map[string]ServiceDefinition) (map[string]string, func(), error) {
+	envVars := map[string]string{}
+	if len(services) == 0 {
+		return envVars, func() {}, nil
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to docker: %w", err)
+	}
+	pool.MaxWait = time.Minute
+
+	var resources []*dockertest.Resource
+	cleanup := func() {
+		for _, r := range resources {
+			if perr := pool.Purge(r); perr != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove service container '%v': %v\n", r.Container.Name, perr)
+			}
+		}
+	}
+
+	for _, name := range sortedServiceNames(services) {
+		svc, err := services[name].resolve(name)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+
+		resource, hostPorts, err := runService(pool, name, svc)
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to start service '%v': %w", name, err)
+		}
+		resources = append(resources, resource)
+		_ = resource.Expire(900)
+
+		for _, p := range svc.Ports {
+			addr := "localhost:" + hostPorts[p]
+			if err := pool.Retry(func() error {
+				conn, derr := net.Dial("tcp", addr)
+				if derr != nil {
+					return derr
+				}
+				return conn.Close()
+			}); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("service '%v' did not become ready: %w", name, err)
+			}
+		}
+
+		for k, v := range serviceEnvVars(name, svc.Ports, hostPorts) {
+			envVars[k] = v
+		}
+	}
+	return envVars, cleanup, nil
+}
+
+//------------------------------------------------------------------------------
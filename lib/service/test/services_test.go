@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceDefinitionResolve(t *testing.T) {
+	svc, err := ServiceDefinition{}.resolve("redis")
+	require.NoError(t, err)
+	assert.Equal(t, ServiceDefinition{
+		Image: "redis",
+		Tag:   "6.2.6",
+		Env:   []string{},
+		Ports: []string{"6379/tcp"},
+	}, svc)
+
+	svc, err = ServiceDefinition{
+		Preset: "kafka",
+		Tag:    "v21.4.1",
+	}.resolve("events")
+	require.NoError(t, err)
+	assert.Equal(t, "vectorized/redpanda", svc.Image)
+	assert.Equal(t, "v21.4.1", svc.Tag)
+	assert.Equal(t, []string{"9092/tcp"}, svc.Ports)
+	assert.Equal(t, []string{
+		"redpanda", "start", "--smp", "1", "--overprovisioned",
+		"--kafka-addr", "0.0.0.0:9092",
+		"--advertise-kafka-addr", "localhost:${HOST_PORT_9092}",
+	}, svc.Cmd)
+
+	svc, err = ServiceDefinition{
+		Image: "nats",
+		Ports: []string{"4222", "8222/tcp"},
+	}.resolve("nats")
+	require.NoError(t, err)
+	assert.Equal(t, "latest", svc.Tag)
+	assert.Equal(t, []string{"4222/tcp", "8222/tcp"}, svc.Ports)
+
+	_, err = ServiceDefinition{}.resolve("nope")
+	require.EqualError(t, err, "service 'nope' preset 'nope' not recognised")
+
+	_, err = ServiceDefinition{Image: "foo"}.resolve("foo")
+	require.EqualError(t, err, "service 'foo' must expose at least one port")
+}
+
+func TestServiceUsesHostPorts(t *testing.T) {
+	svc, err := ServiceDefinition{}.resolve("redis")
+	require.NoError(t, err)
+	assert.False(t, usesHostPorts(svc))
+
+	svc, err = ServiceDefinition{}.resolve("kafka")
+	require.NoError(t, err)
+	assert.True(t, usesHostPorts(svc))
+
+	assert.True(t, usesHostPorts(ServiceDefinition{
+		Env: []string{"ADVERTISED_PORT=${HOST_PORT_8080}"},
+	}))
+	assert.False(t, usesHostPorts(ServiceDefinition{
+		Env: []string{"FOO=${BAR}"},
+	}))
+}
+
+func TestServiceEnvVars(t *testing.T) {
+	vars := serviceEnvVars("my-cache", []string{"6379/tcp", "8001/tcp"}, map[string]string{
+		"6379/tcp": "1234",
+		"8001/tcp": "5678",
+	})
+	assert.Equal(t, map[string]string{
+		"MY_CACHE_HOST":      "localhost",
+		"MY_CACHE_PORT":      "1234",
+		"MY_CACHE_PORT_6379": "1234",
+		"MY_CACHE_PORT_8001": "5678",
+	}, vars)
+}
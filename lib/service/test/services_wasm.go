@@ -0,0 +1,16 @@
+// +build wasm
+
+package test
+
+import "errors"
+
+//------------------------------------------------------------------------------
+
+func startServices(services map[string]ServiceDefinition) (map[string]string, func(), error) {
+	if len(services) == 0 {
+		return map[string]string{}, func() {}, nil
+	}
+	return nil, nil, errors.New("test services are disabled in WASM builds")
+}
+
+//------------------------------------------------------------------------------
@@ -16,6 +16,9 @@ type Config struct {
 	Buffer   buffer.Config   `json:"buffer" yaml:"buffer"`
 	Pipeline pipeline.Config `json:"pipeline" yaml:"pipeline"`
 	Output   output.Config   `json:"output" yaml:"output"`
+
+	ErrorHandling ErrorHandlingConfig `json:"error_handling" yaml:"error_handling"`
+	Expiry        ExpiryConfig        `json:"expiry" yaml:"expiry"`
 }
 
 // NewConfig returns a new configuration with default values.
@@ -25,6 +28,9 @@ func NewConfig() Config {
 		Buffer:   buffer.NewConfig(),
 		Pipeline: pipeline.NewConfig(),
 		Output:   output.NewConfig(),
+
+		ErrorHandling: NewErrorHandlingConfig(),
+		Expiry:        NewExpiryConfig(),
 	}
 }
 
@@ -55,16 +61,41 @@ func (c Config) Sanitised() (interface{}, error) {
 		return nil, err
 	}
 
+	errConf := map[string]interface{}{
+		"strategy":    c.ErrorHandling.Strategy,
+		"max_retries": c.ErrorHandling.MaxRetries,
+		"processors":  c.ErrorHandling.Processors,
+	}
+	if c.ErrorHandling.Output != nil {
+		if errConf["output"], err = output.SanitiseConfig(*c.ErrorHandling.Output); err != nil {
+			return nil, err
+		}
+	}
+
+	expConf := map[string]interface{}{
+		"max_age":  c.Expiry.MaxAge,
+		"strategy": c.Expiry.Strategy,
+	}
+	if c.Expiry.Output != nil {
+		if expConf["output"], err = output.SanitiseConfig(*c.Expiry.Output); err != nil {
+			return nil, err
+		}
+	}
+
 	return struct {
-		Input    interface{} `json:"input" yaml:"input"`
-		Buffer   interface{} `json:"buffer" yaml:"buffer"`
-		Pipeline interface{} `json:"pipeline" yaml:"pipeline"`
-		Output   interface{} `json:"output" yaml:"output"`
+		Input         interface{} `json:"input" yaml:"input"`
+		Buffer        interface{} `json:"buffer" yaml:"buffer"`
+		Pipeline      interface{} `json:"pipeline" yaml:"pipeline"`
+		Output        interface{} `json:"output" yaml:"output"`
+		ErrorHandling interface{} `json:"error_handling" yaml:"error_handling"`
+		Expiry        interface{} `json:"expiry" yaml:"expiry"`
 	}{
-		Input:    inConf,
-		Buffer:   bufConf,
-		Pipeline: pipeConf,
-		Output:   outConf,
+		Input:         inConf,
+		Buffer:        bufConf,
+		Pipeline:      pipeConf,
+		Output:        outConf,
+		ErrorHandling: errConf,
+		Expiry:        expConf,
 	}, nil
 }
 
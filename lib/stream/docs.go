@@ -11,8 +11,38 @@ func Spec() docs.FieldSpecs {
 		docs.FieldCommon("buffer", "An optional buffer to store messages during transit.").HasType(docs.FieldBuffer),
 		docs.FieldCommon("pipeline", "Describes optional processing pipelines used for mutating messages.").WithChildren(
 			docs.FieldCommon("threads", "The number of threads to execute processing pipelines across."),
+			docs.FieldAdvanced(
+				"key_affinity", "An optional key used to dispatch messages to processing threads, where messages that resolve to the same key are always processed by the same thread in the order that they were consumed. This is useful for preserving per-key ordering from partitioned inputs such as `kafka` or `aws_kinesis` whilst still processing messages of different keys in parallel. When processing batches the key is resolved from the first message of the batch. Has no effect when `threads` is `1`, as messages are then always processed in the order that they were consumed.",
+				`${! meta("kafka_key") }`, `${! meta("kafka_partition") }`,
+			).IsInterpolated().AtVersion("3.47.0"),
+			docs.FieldAdvanced("profiling", "Profiles the time spent and allocations made within each processor of the pipeline, which are served from the endpoint `/debug/processors` and logged when the pipeline is closed. Time is measured for every call, whereas allocations are measured for a sample of calls and are approximate when multiple threads are processing in parallel.").WithChildren(
+				docs.FieldCommon("enabled", "Whether processors are profiled.").HasDefault(false),
+				docs.FieldCommon("sample_rate", "The fraction of calls to each processor for which allocations are measured, between 0 and 1.").HasDefault(0.01),
+			).AtVersion("3.47.0"),
 			docs.FieldCommon("processors", "A list of processors to apply to messages.").Array().HasType(docs.FieldProcessor),
 		),
 		docs.FieldCommon("output", "An output to sink messages to.").HasType(docs.FieldOutput),
+		docs.FieldAdvanced("error_handling", "A policy for handling messages that fail pipeline processors, which removes the need for catching errors throughout a pipeline.").WithChildren(
+			docs.FieldCommon("strategy", "The strategy applied to messages that fail a processor.").HasAnnotatedOptions(
+				"none", "Failed messages continue through the pipeline with their errors flagged.",
+				"drop", "Failed messages are removed from the pipeline and acknowledged.",
+				"reject", "The batch containing a failed message is rejected and the input is instructed to reattempt delivery (or nack it).",
+				"route", "Failed messages skip the remaining processors and are sent to the error handling `output`.",
+			).HasDefault("none"),
+			docs.FieldCommon("max_retries", "The maximum number of times to reattempt a processor for messages that fail it before the strategy is applied.").HasDefault(0),
+			docs.FieldCommon("output", "An optional output that messages reaching the end of the pipeline with errors flagged are sent to instead of the main output. Required by the `route` strategy.").HasType(docs.FieldOutput),
+			docs.FieldAdvanced("processors", "A map of processor labels to error policies that override the stream-wide `strategy` and `max_retries` for individual processors of the pipeline. Omitted fields use the stream-wide value.").Map().WithChildren(
+				docs.FieldCommon("strategy", "The strategy applied to messages that fail the processor.").HasOptions("none", "drop", "reject", "route"),
+				docs.FieldCommon("max_retries", "The maximum number of times to reattempt the processor."),
+			).HasDefault(map[string]interface{}{}),
+		).AtVersion("3.47.0"),
+		docs.FieldAdvanced("expiry", "A maximum age of messages, after which they are no longer delivered to the output. This prevents stale data from being delivered after long outages. Messages are given a deadline in the metadata field `"+ExpiryMetadataKey+"` when they are consumed by the input, which is checked as they leave the buffer and again before they reach the output. Messages that already have a deadline keep it, and the metadata field is removed before messages reach an output. The number of expired messages is tracked with the metrics `expiry.dropped` and `expiry.output.routed`.").WithChildren(
+			docs.FieldCommon("max_age", "The maximum period of time after being consumed that a message may be delivered, when empty messages never expire.", "30s", "1h").HasDefault(""),
+			docs.FieldCommon("strategy", "The strategy applied to expired messages.").HasAnnotatedOptions(
+				"drop", "Expired messages are removed from the stream and acknowledged.",
+				"route", "Expired messages are sent to the expiry `output` instead of the main output. These messages are still processed by the pipeline.",
+			).HasDefault("drop"),
+			docs.FieldCommon("output", "An output that expired messages are sent to. Required by the `route` strategy.").HasType(docs.FieldOutput),
+		).AtVersion("3.47.0"),
 	}
 }
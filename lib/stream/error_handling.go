@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"errors"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	ioutput "github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrorHandlingConfig describes a stream-wide policy for handling messages that
+// fail processors, and an optional output for failed messages to be routed to.
+type ErrorHandlingConfig struct {
+	pipeline.ErrorHandlingConfig `json:",inline" yaml:",inline"`
+	Output                       *ioutput.Config `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+// NewErrorHandlingConfig returns an ErrorHandlingConfig with default values.
+func NewErrorHandlingConfig() ErrorHandlingConfig {
+	return ErrorHandlingConfig{
+		ErrorHandlingConfig: pipeline.NewErrorHandlingConfig(),
+		Output:              nil,
+	}
+}
+
+// Validate returns an error if the error handling config is invalid.
+func (e ErrorHandlingConfig) Validate() error {
+	if err := e.ErrorHandlingConfig.Validate(); err != nil {
+		return err
+	}
+	if e.Routes() && e.Output == nil {
+		return errors.New("error handling strategy 'route' requires an output")
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+//------------------------------------------------------------------------------
+
+// newErrorRouter returns an output that dispatches message parts flagged as
+// having failed processing to a dedicated output, and all other message parts
+// to the main output of a stream.
+func newErrorRouter(main, errs ioutput.Type, log log.Modular, stats metrics.Type) (*partRouter, error) {
+	return newPartRouter(main, errs, processor.HasFailed, nil, stats.GetCounter("error_handling.output.routed"), log)
+}
@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestTypeErrorHandlingRoute(t *testing.T) {
+	dir := t.TempDir()
+	mainPath, errsPath := filepath.Join(dir, "main.txt"), filepath.Join(dir, "errs.txt")
+
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(fmt.Sprintf(`
+input:
+  generate:
+    count: 4
+    interval: ""
+    mapping: 'root = if count("error_handling_route") %% 2 == 0 { "bad" } else { {"doc":"good"} }'
+pipeline:
+  processors:
+    - bloblang: 'root = this.doc.uppercase()'
+output:
+  file:
+    path: %v
+    codec: lines
+error_handling:
+  strategy: route
+  output:
+    file:
+      path: %v
+      codec: lines
+`, mainPath, errsPath)), &conf))
+
+	closed := make(chan struct{})
+	strm, err := New(conf, OptOnClose(func() {
+		close(closed)
+	}))
+	require.NoError(t, err)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 10):
+		t.Fatal("timed out")
+	}
+	require.NoError(t, strm.Stop(time.Second*10))
+
+	mainBytes, err := ioutil.ReadFile(mainPath)
+	require.NoError(t, err)
+	assert.Equal(t, "GOOD\nGOOD\n", string(mainBytes))
+
+	errsBytes, err := ioutil.ReadFile(errsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "bad\nbad\n", string(errsBytes))
+}
+
+func TestTypeErrorHandlingRouteNoOutput(t *testing.T) {
+	conf := NewConfig()
+	conf.ErrorHandling.Strategy = "route"
+
+	_, err := New(conf)
+	require.EqualError(t, err, "error handling strategy 'route' requires an output")
+}
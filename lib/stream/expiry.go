@@ -0,0 +1,214 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	ioutput "github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// ExpiryMetadataKey is the metadata key that holds the deadline of a message,
+// after which it is considered expired, formatted as an RFC3339 timestamp. The
+// key is removed from messages before they reach an output.
+const ExpiryMetadataKey = "benthos_expires_at"
+
+//------------------------------------------------------------------------------
+
+// ExpiryConfig describes a stream-wide maximum age of messages, and how
+// messages that exceed it are handled.
+type ExpiryConfig struct {
+	MaxAge   string          `json:"max_age" yaml:"max_age"`
+	Strategy string          `json:"strategy" yaml:"strategy"`
+	Output   *ioutput.Config `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+// NewExpiryConfig returns an ExpiryConfig with default values.
+func NewExpiryConfig() ExpiryConfig {
+	return ExpiryConfig{
+		MaxAge:   "",
+		Strategy: "drop",
+		Output:   nil,
+	}
+}
+
+// Enabled returns true if messages of the stream are given a deadline.
+func (e ExpiryConfig) Enabled() bool {
+	return e.MaxAge != ""
+}
+
+// Routes returns true if expired messages are routed to a dedicated output.
+func (e ExpiryConfig) Routes() bool {
+	return e.Enabled() && e.Strategy == "route"
+}
+
+// Validate returns an error if the expiry config is invalid.
+func (e ExpiryConfig) Validate() error {
+	if !e.Enabled() {
+		return nil
+	}
+	if d, err := time.ParseDuration(e.MaxAge); err != nil {
+		return fmt.Errorf("failed to parse expiry max_age: %v", err)
+	} else if d <= 0 {
+		return fmt.Errorf("expiry max_age must be greater than zero, got: %v", e.MaxAge)
+	}
+	switch e.Strategy {
+	case "drop":
+	case "route":
+		if e.Output == nil {
+			return errors.New("expiry strategy 'route' requires an output")
+		}
+	default:
+		return fmt.Errorf("expiry strategy not recognised: %v", e.Strategy)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// expired returns true if a message part carries a deadline that has passed.
+// Parts with a missing or malformed deadline never expire.
+func expired(p types.Part) bool {
+	v := p.Metadata().Get(ExpiryMetadataKey)
+	if v == "" {
+		return false
+	}
+	deadline, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return false
+	}
+	return time.Now().After(deadline)
+}
+
+// expiryStamp is a processor that gives each message part without a deadline
+// one that is a fixed duration from now.
+type expiryStamp struct {
+	maxAge time.Duration
+}
+
+func (e *expiryStamp) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	deadline := time.Now().Add(e.maxAge).UTC().Format(time.RFC3339Nano)
+	newMsg := msg.Copy()
+	_ = newMsg.Iter(func(i int, p types.Part) error {
+		if p.Metadata().Get(ExpiryMetadataKey) == "" {
+			p.Metadata().Set(ExpiryMetadataKey, deadline)
+		}
+		return nil
+	})
+	return []types.Message{newMsg}, nil
+}
+
+func (e *expiryStamp) CloseAsync() {}
+
+func (e *expiryStamp) WaitForClose(time.Duration) error {
+	return nil
+}
+
+// expiryFilter is a processor that drops expired message parts.
+type expiryFilter struct {
+	mDropped metrics.StatCounter
+}
+
+func (e *expiryFilter) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	var parts []types.Part
+	_ = msg.Iter(func(i int, p types.Part) error {
+		if !expired(p) {
+			parts = append(parts, p)
+		}
+		return nil
+	})
+	if dropped := msg.Len() - len(parts); dropped > 0 {
+		e.mDropped.Incr(int64(dropped))
+		if len(parts) == 0 {
+			return nil, response.NewAck()
+		}
+		newMsg := msg.Copy()
+		newMsg.SetAll(parts)
+		return []types.Message{newMsg}, nil
+	}
+	return []types.Message{msg}, nil
+}
+
+func (e *expiryFilter) CloseAsync() {}
+
+func (e *expiryFilter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// newExpiryStamp returns a pipeline constructor for giving the messages of an
+// input a deadline.
+func newExpiryStamp(conf ExpiryConfig, log log.Modular, stats metrics.Type) (types.PipelineConstructorFunc, error) {
+	maxAge, err := time.ParseDuration(conf.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	return func(*int) (types.Pipeline, error) {
+		return pipeline.NewProcessor(log, stats, &expiryStamp{maxAge: maxAge}), nil
+	}, nil
+}
+
+// expiryPipeline drops expired message parts before they reach an optional
+// processing pipeline, in order to avoid processing messages that expired
+// within a buffer.
+type expiryPipeline struct {
+	filter *pipeline.Processor
+	next   pipeline.Type
+}
+
+func newExpiryPipeline(next pipeline.Type, log log.Modular, stats metrics.Type) *expiryPipeline {
+	return &expiryPipeline{
+		filter: pipeline.NewProcessor(log, stats, &expiryFilter{
+			mDropped: stats.GetCounter("expiry.dropped"),
+		}),
+		next: next,
+	}
+}
+
+// Consume assigns a messages channel for the pipeline to read.
+func (e *expiryPipeline) Consume(msgs <-chan types.Transaction) error {
+	if err := e.filter.Consume(msgs); err != nil {
+		return err
+	}
+	if e.next != nil {
+		return e.next.Consume(e.filter.TransactionChan())
+	}
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming messages from this
+// pipeline.
+func (e *expiryPipeline) TransactionChan() <-chan types.Transaction {
+	if e.next != nil {
+		return e.next.TransactionChan()
+	}
+	return e.filter.TransactionChan()
+}
+
+// CloseAsync shuts down the pipeline and stops processing messages.
+func (e *expiryPipeline) CloseAsync() {
+	e.filter.CloseAsync()
+	if e.next != nil {
+		e.next.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the pipeline has closed down.
+func (e *expiryPipeline) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	if err := e.filter.WaitForClose(timeout); err != nil {
+		return err
+	}
+	if e.next != nil {
+		return e.next.WaitForClose(time.Until(stopBy))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,143 @@
+package stream
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func runExpiryStream(t *testing.T, confStr string) {
+	t.Helper()
+
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(confStr), &conf))
+
+	closed := make(chan struct{})
+	strm, err := New(conf, OptOnClose(func() {
+		close(closed)
+	}))
+	require.NoError(t, err)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 10):
+		t.Fatal("timed out")
+	}
+	require.NoError(t, strm.Stop(time.Second*10))
+}
+
+func TestTypeExpiryDrop(t *testing.T) {
+	mainPath := filepath.Join(t.TempDir(), "main.txt")
+
+	runExpiryStream(t, fmt.Sprintf(`
+input:
+  generate:
+    count: 4
+    interval: ""
+    mapping: |
+      root = "msg" + count("expiry_drop").string()
+      meta benthos_expires_at = if count("expiry_drop_meta") == 2 { "2000-01-01T00:00:00Z" }
+pipeline:
+  processors:
+    - bloblang: 'root = content().uppercase() + " " + (meta("benthos_expires_at") != "").string()'
+output:
+  file:
+    path: %v
+    codec: lines
+expiry:
+  max_age: 1h
+`, mainPath))
+
+	mainBytes, err := ioutil.ReadFile(mainPath)
+	require.NoError(t, err)
+	assert.Equal(t, "MSG1 true\nMSG3 true\nMSG4 true\n", string(mainBytes))
+}
+
+func TestTypeExpiryRoute(t *testing.T) {
+	dir := t.TempDir()
+	mainPath, expPath := filepath.Join(dir, "main.txt"), filepath.Join(dir, "expired.txt")
+
+	runExpiryStream(t, fmt.Sprintf(`
+input:
+  generate:
+    count: 4
+    interval: ""
+    mapping: |
+      root = "msg" + count("expiry_route").string()
+      meta benthos_expires_at = if count("expiry_route_meta") %% 2 == 0 { "2000-01-01T00:00:00Z" }
+pipeline:
+  processors:
+    - bloblang: 'root = content().uppercase()'
+output:
+  file:
+    path: %v
+    codec: lines
+expiry:
+  max_age: 1h
+  strategy: route
+  output:
+    file:
+      path: %v
+      codec: lines
+`, mainPath, expPath))
+
+	mainBytes, err := ioutil.ReadFile(mainPath)
+	require.NoError(t, err)
+	assert.Equal(t, "MSG1\nMSG3\n", string(mainBytes))
+
+	expBytes, err := ioutil.ReadFile(expPath)
+	require.NoError(t, err)
+	assert.Equal(t, "MSG2\nMSG4\n", string(expBytes))
+}
+
+func TestTypeExpiryMetadataStripped(t *testing.T) {
+	dir := t.TempDir()
+
+	runExpiryStream(t, fmt.Sprintf(`
+input:
+  generate:
+    count: 2
+    interval: ""
+    mapping: |
+      root = "msg" + count("expiry_stripped").string()
+      meta benthos_expires_at = if count("expiry_stripped_meta") == 2 { "2000-01-01T00:00:00Z" }
+output:
+  file:
+    path: '%v/main_${! meta("benthos_expires_at").or("stripped") }.txt'
+    codec: lines
+expiry:
+  max_age: 1h
+  strategy: route
+  output:
+    file:
+      path: '%v/expired_${! meta("benthos_expires_at").or("stripped") }.txt'
+      codec: lines
+`, dir, dir))
+
+	mainBytes, err := ioutil.ReadFile(filepath.Join(dir, "main_stripped.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "msg1\n", string(mainBytes))
+
+	expBytes, err := ioutil.ReadFile(filepath.Join(dir, "expired_stripped.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "msg2\n", string(expBytes))
+}
+
+func TestTypeExpiryBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Expiry.MaxAge = "1h"
+	conf.Expiry.Strategy = "route"
+
+	_, err := New(conf)
+	require.EqualError(t, err, "expiry strategy 'route' requires an output")
+
+	conf.Expiry.MaxAge = "nope"
+	_, err = New(conf)
+	require.EqualError(t, err, `failed to parse expiry max_age: time: invalid duration "nope"`)
+}
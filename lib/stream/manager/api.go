@@ -101,16 +101,29 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newSet := ConfigSet{}
-
 	var setBytes []byte
 	if setBytes, requestErr = ioutil.ReadAll(r.Body); requestErr != nil {
 		return
 	}
-	if requestErr = yaml.Unmarshal(setBytes, &newSet); requestErr != nil {
+
+	newNodes := map[string]yaml.Node{}
+	if requestErr = yaml.Unmarshal(setBytes, &newNodes); requestErr != nil {
 		return
 	}
 
+	newSet := ConfigSet{}
+	for id, node := range newNodes {
+		conf, err := m.NewStreamConfig()
+		if err != nil {
+			serverErr = err
+			return
+		}
+		if requestErr = node.Decode(&conf); requestErr != nil {
+			return
+		}
+		newSet[id] = conf
+	}
+
 	toDelete := []string{}
 	toUpdate := map[string]stream.Config{}
 	toCreate := map[string]stream.Config{}
@@ -221,7 +234,9 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		confOut = stream.NewConfig()
+		if confOut, err = m.NewStreamConfig(); err != nil {
+			return
+		}
 		err = yaml.Unmarshal(text.ReplaceEnvVariables(confBytes), &confOut)
 		if err == nil {
 			lConfig := config.New()
@@ -251,11 +266,15 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			Buffer   aliasedBuf  `json:"buffer"`
 			Pipeline aliasedPipe `json:"pipeline"`
 			Output   aliasedOut  `json:"output"`
+
+			ErrorHandling stream.ErrorHandlingConfig `json:"error_handling"`
 		}{
 			Input:    aliasedIn(confIn.Input),
 			Buffer:   aliasedBuf(confIn.Buffer),
 			Pipeline: aliasedPipe(confIn.Pipeline),
 			Output:   aliasedOut(confIn.Output),
+
+			ErrorHandling: confIn.ErrorHandling,
 		}
 		if err = yaml.Unmarshal(patchBytes, &aliasedConf); err != nil {
 			return
@@ -265,6 +284,8 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			Buffer:   buffer.Config(aliasedConf.Buffer),
 			Pipeline: pipeline.Config(aliasedConf.Pipeline),
 			Output:   output.Config(aliasedConf.Output),
+
+			ErrorHandling: aliasedConf.ErrorHandling,
 		}
 		return
 	}
@@ -8,11 +8,12 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/stream"
+	yaml "gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
 
-func loadFile(dir, path, testSuffix string, confs map[string]stream.Config) ([]string, error) {
+func loadFile(dir, path, testSuffix string, defaults []byte, confs map[string]stream.Config) ([]string, error) {
 	var id string
 	if len(dir) > 0 {
 		var err error
@@ -38,6 +39,11 @@ func loadFile(dir, path, testSuffix string, confs map[string]stream.Config) ([]s
 	}
 
 	conf := config.New()
+	if len(defaults) > 0 {
+		if err := yaml.Unmarshal(defaults, &conf.Config); err != nil {
+			return nil, fmt.Errorf("failed to parse stream defaults: %w", err)
+		}
+	}
 	lints, err := config.Read(path, true, &conf)
 	if err != nil {
 		return nil, err
@@ -54,13 +60,20 @@ func loadFile(dir, path, testSuffix string, confs map[string]stream.Config) ([]s
 // by either walking a directory of .json and .yaml files or by reading a file
 // directly. Returns linting errors prefixed with their path.
 func LoadStreamConfigsFromPath(target, testSuffix string, streamMap map[string]stream.Config) ([]string, error) {
+	return LoadStreamConfigsFromPathWithDefaults(target, testSuffix, nil, streamMap)
+}
+
+// LoadStreamConfigsFromPathWithDefaults reads a map of stream ids to
+// configurations in the same way as LoadStreamConfigsFromPath, where each
+// stream config is parsed on top of a YAML document of default values.
+func LoadStreamConfigsFromPathWithDefaults(target, testSuffix string, defaults []byte, streamMap map[string]stream.Config) ([]string, error) {
 	pathLints := []string{}
 	target = filepath.Clean(target)
 
 	if info, err := os.Stat(target); err != nil {
 		return nil, err
 	} else if !info.IsDir() {
-		if pathLints, err = loadFile("", target, "", streamMap); err != nil {
+		if pathLints, err = loadFile("", target, "", defaults, streamMap); err != nil {
 			return nil, fmt.Errorf("failed to load config '%v': %v", target, err)
 		}
 		return pathLints, nil
@@ -77,7 +90,7 @@ func LoadStreamConfigsFromPath(target, testSuffix string, streamMap map[string]s
 		}
 
 		var lints []string
-		if lints, werr = loadFile(target, path, testSuffix, streamMap); werr != nil {
+		if lints, werr = loadFile(target, path, testSuffix, defaults, streamMap); werr != nil {
 			return fmt.Errorf("failed to load config '%v': %v", path, werr)
 		}
 
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// LifecycleEventType describes the kind of change that occurred to a stream.
+type LifecycleEventType string
+
+// Lifecycle event types emitted by a stream manager.
+const (
+	LifecycleEventCreate   LifecycleEventType = "create"
+	LifecycleEventUpdate   LifecycleEventType = "update"
+	LifecycleEventDelete   LifecycleEventType = "delete"
+	LifecycleEventFinished LifecycleEventType = "finished"
+	LifecycleEventFailure  LifecycleEventType = "failure"
+)
+
+// LifecycleEvent describes a change to a stream managed by a stream manager.
+type LifecycleEvent struct {
+	Type      LifecycleEventType `json:"type"`
+	StreamID  string             `json:"stream_id"`
+	Timestamp time.Time          `json:"timestamp"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// LifecycleHookFunc is a closure type that is called for each lifecycle event
+// of the streams of a manager. Hooks are called synchronously and therefore
+// should not block.
+type LifecycleHookFunc func(event LifecycleEvent)
+
+// OptAddLifecycleHooks adds hooks that are called whenever a stream is
+// created, updated or deleted, when a stream finishes, or when an operation on
+// a stream fails.
+func OptAddLifecycleHooks(hooks ...LifecycleHookFunc) func(*Type) {
+	return func(t *Type) {
+		t.hooks = append(t.hooks, hooks...)
+	}
+}
+
+func (m *Type) emit(eType LifecycleEventType, id string, err error) {
+	if len(m.hooks) == 0 {
+		return
+	}
+	event := LifecycleEvent{
+		Type:      eType,
+		StreamID:  id,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	for _, h := range m.hooks {
+		h(event)
+	}
+}
+
+// emitResult emits an event of a given type if the operation was successful,
+// or a failure event if the operation failed for reasons other than a bad
+// request.
+func (m *Type) emitResult(eType LifecycleEventType, id string, err error) {
+	switch err {
+	case nil:
+		m.emit(eType, id, nil)
+	case ErrStreamExists, ErrStreamDoesNotExist, types.ErrTypeClosed:
+	default:
+		m.emit(LifecycleEventFailure, id, err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewWebhook returns a LifecycleHookFunc that sends each event as a JSON
+// document in the body of a POST request to a URL. Requests are made
+// asynchronously and failures are logged rather than retried.
+func NewWebhook(url string, timeout time.Duration, logger log.Modular) LifecycleHookFunc {
+	client := &http.Client{Timeout: timeout}
+	return func(event LifecycleEvent) {
+		go func() {
+			if err := sendWebhook(client, url, event); err != nil {
+				logger.Errorf("Failed to send stream %v event to webhook '%v': %v\n", event.Type, url, err)
+			}
+		}()
+	}
+}
+
+func sendWebhook(client *http.Client, url string, event LifecycleEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %v", res.StatusCode)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeLifecycleHooks(t *testing.T) {
+	var eventsMut sync.Mutex
+	var events []LifecycleEvent
+
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.DudMgr{}),
+		OptAddLifecycleHooks(func(e LifecycleEvent) {
+			eventsMut.Lock()
+			events = append(events, e)
+			eventsMut.Unlock()
+		}),
+	)
+
+	eventTypes := func() []LifecycleEventType {
+		eventsMut.Lock()
+		defer eventsMut.Unlock()
+		var eTypes []LifecycleEventType
+		for _, e := range events {
+			assert.Equal(t, "foo", e.StreamID)
+			eTypes = append(eTypes, e.Type)
+		}
+		return eTypes
+	}
+
+	require.NoError(t, mgr.Create("foo", harmlessConf()))
+	require.Error(t, mgr.Create("foo", harmlessConf()))
+
+	// Updating with an identical config is a no-op.
+	require.NoError(t, mgr.Update("foo", harmlessConf(), time.Second))
+
+	newConf := harmlessConf()
+	newConf.Buffer.Type = "memory"
+	require.NoError(t, mgr.Update("foo", newConf, time.Second))
+
+	badConf := harmlessConf()
+	badConf.Input.Type = "notexist"
+	require.Error(t, mgr.Update("foo", badConf, time.Second))
+
+	require.NoError(t, mgr.Create("foo", harmlessConf()))
+	require.NoError(t, mgr.Delete("foo", time.Second))
+	require.NoError(t, mgr.Stop(time.Second))
+
+	assert.Equal(t, []LifecycleEventType{
+		LifecycleEventCreate,
+		LifecycleEventUpdate,
+		LifecycleEventFailure,
+		LifecycleEventCreate,
+		LifecycleEventDelete,
+	}, eventTypes())
+}
+
+func TestTypeLifecycleFinished(t *testing.T) {
+	events := make(chan LifecycleEvent, 10)
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.DudMgr{}),
+		OptAddLifecycleHooks(func(e LifecycleEvent) {
+			events <- e
+		}),
+	)
+
+	conf := stream.NewConfig()
+	conf.Input.Type = "generate"
+	conf.Input.Generate.Mapping = `root = "hello world"`
+	conf.Input.Generate.Count = 1
+	conf.Input.Generate.Interval = ""
+	conf.Output.Type = "drop"
+
+	require.NoError(t, mgr.Create("foo", conf))
+	for _, eType := range []LifecycleEventType{LifecycleEventCreate, LifecycleEventFinished} {
+		select {
+		case e := <-events:
+			assert.Equal(t, eType, e.Type)
+			assert.Equal(t, "foo", e.StreamID)
+			assert.Empty(t, e.Error)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timed out waiting for %v event", eType)
+		}
+	}
+	require.NoError(t, mgr.Stop(time.Second))
+}
+
+func TestTypeStreamDefaults(t *testing.T) {
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.DudMgr{}),
+		OptSetStreamDefaults([]byte(`
+buffer:
+  memory:
+    limit: 1000
+pipeline:
+  threads: 4
+`)),
+	)
+
+	conf, err := mgr.NewStreamConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "memory", conf.Buffer.Type)
+	assert.Equal(t, 1000, conf.Buffer.Memory.Limit)
+	assert.Equal(t, 4, conf.Pipeline.Threads)
+
+	mgr = New(OptSetStreamDefaults([]byte(`pipeline: [ nope ]`)))
+	_, err = mgr.NewStreamConfig()
+	require.Error(t, err)
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan LifecycleEvent, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var e LifecycleEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- e
+	}))
+	defer ts.Close()
+
+	hook := NewWebhook(ts.URL, time.Second, log.Noop())
+	hook(LifecycleEvent{
+		Type:      LifecycleEventFailure,
+		StreamID:  "foo",
+		Timestamp: time.Now(),
+		Error:     "oh no",
+	})
+
+	select {
+	case e := <-received:
+		assert.Equal(t, LifecycleEventFailure, e.Type)
+		assert.Equal(t, "foo", e.StreamID)
+		assert.Equal(t, "oh no", e.Error)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for webhook")
+	}
+}
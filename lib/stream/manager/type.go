@@ -13,6 +13,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/stream"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -20,6 +21,7 @@ import (
 // StreamStatus tracks a stream along with information regarding its internals.
 type StreamStatus struct {
 	stoppedAfter int64
+	stopping     int32
 	config       stream.Config
 	strm         *stream.Type
 	logger       log.Modular
@@ -83,6 +85,18 @@ func (s *StreamStatus) setClosed() {
 	atomic.SwapInt64(&s.stoppedAfter, int64(time.Since(s.createdAt)))
 }
 
+// setStopping sets the flag indicating that the stream is being deliberately
+// stopped.
+func (s *StreamStatus) setStopping() {
+	atomic.StoreInt32(&s.stopping, 1)
+}
+
+// isStopping returns a boolean indicating whether the stream is being
+// deliberately stopped.
+func (s *StreamStatus) isStopping() bool {
+	return atomic.LoadInt32(&s.stopping) == 1
+}
+
 //------------------------------------------------------------------------------
 
 // StreamProcConstructorFunc is a closure type that constructs a processor type
@@ -104,6 +118,9 @@ type Type struct {
 
 	pipelineProcCtors []StreamProcConstructorFunc
 
+	defaults []byte
+	hooks    []LifecycleHookFunc
+
 	lock sync.Mutex
 }
 
@@ -165,6 +182,15 @@ func OptAddProcessors(procs ...StreamProcConstructorFunc) func(*Type) {
 	}
 }
 
+// OptSetStreamDefaults sets a stream config, in YAML form, that provides the
+// default values of stream configs parsed by the manager. Fields that are set
+// within a stream config override the defaults.
+func OptSetStreamDefaults(confBytes []byte) func(*Type) {
+	return func(t *Type) {
+		t.defaults = confBytes
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Errors specifically returned by a stream manager.
@@ -175,9 +201,27 @@ var (
 
 //------------------------------------------------------------------------------
 
+// NewStreamConfig returns a stream config populated with the default values of
+// the manager, which should be used as the basis for parsing new streams.
+func (m *Type) NewStreamConfig() (stream.Config, error) {
+	conf := stream.NewConfig()
+	if len(m.defaults) > 0 {
+		if err := yaml.Unmarshal(m.defaults, &conf); err != nil {
+			return conf, fmt.Errorf("failed to parse stream defaults: %w", err)
+		}
+	}
+	return conf, nil
+}
+
 // Create attempts to construct and run a new stream under a unique ID. If the
 // ID already exists an error is returned.
 func (m *Type) Create(id string, conf stream.Config) error {
+	err := m.create(id, conf)
+	m.emitResult(LifecycleEventCreate, id, err)
+	return err
+}
+
+func (m *Type) create(id string, conf stream.Config) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -211,6 +255,11 @@ func (m *Type) Create(id string, conf stream.Config) error {
 		stream.OptSetManager(sMgr),
 		stream.OptOnClose(func() {
 			wrapper.setClosed()
+			// Components retry errors indefinitely, and therefore a stream
+			// that closes without being stopped has consumed all of its input.
+			if !wrapper.isStopping() {
+				m.emit(LifecycleEventFinished, id, nil)
+			}
 		}),
 	)
 	if err != nil {
@@ -243,32 +292,46 @@ func (m *Type) Read(id string) (*StreamStatus, error) {
 // Update attempts to stop an existing stream and replace it with a new version
 // of the same stream.
 func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) error {
+	changed, err := m.update(id, conf, timeout)
+	if changed || err != nil {
+		m.emitResult(LifecycleEventUpdate, id, err)
+	}
+	return err
+}
+
+func (m *Type) update(id string, conf stream.Config, timeout time.Duration) (bool, error) {
 	m.lock.Lock()
 	wrapper, exists := m.streams[id]
 	closed := m.closed
 	m.lock.Unlock()
 
 	if closed {
-		return types.ErrTypeClosed
+		return false, types.ErrTypeClosed
 	}
 	if !exists {
-		return ErrStreamDoesNotExist
+		return false, ErrStreamDoesNotExist
 	}
 
 	if reflect.DeepEqual(wrapper.config, conf) {
-		return nil
+		return false, nil
 	}
 
-	if err := m.Delete(id, timeout); err != nil {
-		return err
+	if err := m.delete(id, timeout); err != nil {
+		return false, err
 	}
-	return m.Create(id, conf)
+	return true, m.create(id, conf)
 }
 
 // Delete attempts to stop and remove a stream by its ID. Returns an error if
 // the stream was not found, or if clean shutdown fails in the specified period
 // of time.
 func (m *Type) Delete(id string, timeout time.Duration) error {
+	err := m.delete(id, timeout)
+	m.emitResult(LifecycleEventDelete, id, err)
+	return err
+}
+
+func (m *Type) delete(id string, timeout time.Duration) error {
 	m.lock.Lock()
 	if m.closed {
 		m.lock.Unlock()
@@ -281,6 +344,7 @@ func (m *Type) Delete(id string, timeout time.Duration) error {
 		return ErrStreamDoesNotExist
 	}
 
+	wrapper.setStopping()
 	if err := wrapper.strm.Stop(timeout); err != nil {
 		return err
 	}
@@ -303,6 +367,7 @@ func (m *Type) Stop(timeout time.Duration) error {
 	resultChan := make(chan string)
 
 	for k, v := range m.streams {
+		v.setStopping()
 		go func(id string, strm *StreamStatus) {
 			if err := strm.strm.Stop(timeout); err != nil {
 				resultChan <- id
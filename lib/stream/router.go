@@ -0,0 +1,237 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	ioutput "github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"golang.org/x/sync/errgroup"
+)
+
+//------------------------------------------------------------------------------
+
+// partRouter is an output that dispatches message parts that pass a test to an
+// alternative output, and all other message parts to the main output of a
+// stream. When the alternative output is nil the parts that pass the test are
+// acknowledged and dropped instead. Metadata keys listed in stripMeta are
+// removed from all parts before they are dispatched to either output.
+type partRouter struct {
+	log log.Modular
+
+	maxInFlight  int
+	transactions <-chan types.Transaction
+
+	main     ioutput.Type
+	mainChan chan types.Transaction
+	alt      ioutput.Type
+	altChan  chan types.Transaction
+	test     func(p types.Part) bool
+
+	stripMeta []string
+
+	mRouted metrics.StatCounter
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+func newPartRouter(
+	main, alt ioutput.Type,
+	test func(p types.Part) bool,
+	stripMeta []string,
+	mRouted metrics.StatCounter,
+	log log.Modular,
+) (*partRouter, error) {
+	ctx, done := context.WithCancel(context.Background())
+	r := &partRouter{
+		log:         log,
+		maxInFlight: 1,
+		main:        main,
+		mainChan:    make(chan types.Transaction),
+		alt:         alt,
+		altChan:     make(chan types.Transaction),
+		test:        test,
+		stripMeta:   stripMeta,
+		mRouted:     mRouted,
+		ctx:         ctx,
+		close:       done,
+		closedChan:  make(chan struct{}),
+	}
+	for _, o := range r.outputs() {
+		if mif, ok := output.GetMaxInFlight(o); ok && mif > r.maxInFlight {
+			r.maxInFlight = mif
+		}
+	}
+	if err := main.Consume(r.mainChan); err != nil {
+		return nil, err
+	}
+	if alt != nil {
+		if err := alt.Consume(r.altChan); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *partRouter) outputs() []ioutput.Type {
+	if r.alt == nil {
+		return []ioutput.Type{r.main}
+	}
+	return []ioutput.Type{r.main, r.alt}
+}
+
+//------------------------------------------------------------------------------
+
+func (r *partRouter) dispatch(target chan types.Transaction, msg types.Message) error {
+	resChan := make(chan types.Response)
+	select {
+	case target <- types.NewTransaction(msg, resChan):
+	case <-r.ctx.Done():
+		return types.ErrTypeClosed
+	}
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-r.ctx.Done():
+		return types.ErrTypeClosed
+	}
+}
+
+func (r *partRouter) loop() {
+	wg := sync.WaitGroup{}
+	defer func() {
+		wg.Wait()
+		for _, o := range r.outputs() {
+			o.CloseAsync()
+		}
+		close(r.mainChan)
+		close(r.altChan)
+		for _, o := range r.outputs() {
+			for o.WaitForClose(time.Second) != nil {
+			}
+		}
+		close(r.closedChan)
+	}()
+
+	sendLoop := func() {
+		defer wg.Done()
+		for {
+			var ts types.Transaction
+			var open bool
+			select {
+			case ts, open = <-r.transactions:
+				if !open {
+					return
+				}
+			case <-r.ctx.Done():
+				return
+			}
+
+			var mainParts, altParts []types.Part
+			_ = ts.Payload.Iter(func(i int, p types.Part) error {
+				isAlt := r.test(p)
+				if len(r.stripMeta) > 0 {
+					p = p.Copy()
+					for _, k := range r.stripMeta {
+						p.Metadata().Delete(k)
+					}
+				}
+				if isAlt {
+					altParts = append(altParts, p)
+				} else {
+					mainParts = append(mainParts, p)
+				}
+				return nil
+			})
+
+			var owg errgroup.Group
+			if len(mainParts) > 0 {
+				msg := message.New(nil)
+				msg.SetAll(mainParts)
+				owg.Go(func() error {
+					return r.dispatch(r.mainChan, msg)
+				})
+			}
+			if len(altParts) > 0 {
+				r.mRouted.Incr(int64(len(altParts)))
+				if r.alt != nil {
+					msg := message.New(nil)
+					msg.SetAll(altParts)
+					owg.Go(func() error {
+						return r.dispatch(r.altChan, msg)
+					})
+				}
+			}
+
+			var res types.Response = response.NewAck()
+			if err := owg.Wait(); err != nil {
+				if err == types.ErrTypeClosed {
+					return
+				}
+				res = response.NewError(err)
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}
+
+	for i := 0; i < r.maxInFlight; i++ {
+		wg.Add(1)
+		go sendLoop()
+	}
+}
+
+// Consume assigns a new transactions channel for the router to read.
+func (r *partRouter) Consume(transactions <-chan types.Transaction) error {
+	if r.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	r.transactions = transactions
+	go r.loop()
+	return nil
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// router.
+func (r *partRouter) MaxInFlight() (int, bool) {
+	return r.maxInFlight, true
+}
+
+// Connected returns a boolean indicating whether all outputs are currently
+// connected to their targets.
+func (r *partRouter) Connected() bool {
+	for _, o := range r.outputs() {
+		if !o.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+// CloseAsync shuts down the router and stops processing requests.
+func (r *partRouter) CloseAsync() {
+	r.close()
+}
+
+// WaitForClose blocks until the router has closed down.
+func (r *partRouter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
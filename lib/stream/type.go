@@ -139,27 +139,73 @@ func (t *Type) IsReady() bool {
 }
 
 func (t *Type) start() (err error) {
+	if err = t.conf.ErrorHandling.Validate(); err != nil {
+		return
+	}
+	if err = t.conf.Expiry.Validate(); err != nil {
+		return
+	}
+
 	// Constructors
 	iMgr, iLog, iStats := interop.LabelChild("input", t.manager, t.logger, t.stats)
 	if t.inputLayer, err = input.New(t.conf.Input, iMgr, iLog, iStats); err != nil {
 		return
 	}
+	if t.conf.Expiry.Enabled() {
+		var stampCtor types.PipelineConstructorFunc
+		if stampCtor, err = newExpiryStamp(t.conf.Expiry, t.logger, t.stats); err != nil {
+			return
+		}
+		if t.inputLayer, err = input.WrapWithPipelines(t.inputLayer, stampCtor); err != nil {
+			return
+		}
+	}
 	if t.conf.Buffer.Type != buffer.TypeNone {
 		bMgr, bLog, bStats := interop.LabelChild("buffer", t.manager, t.logger, t.stats)
 		if t.bufferLayer, err = buffer.New(t.conf.Buffer, bMgr, bLog, bStats); err != nil {
 			return
 		}
 	}
-	if tLen := len(t.complementaryProcs) + len(t.conf.Pipeline.Processors); tLen > 0 {
+	if tLen := len(t.complementaryProcs) + len(t.conf.Pipeline.Processors); tLen > 0 || t.conf.ErrorHandling.Enabled() {
 		pMgr, pLog, pStats := interop.LabelChild("pipeline", t.manager, t.logger, t.stats)
-		if t.pipelineLayer, err = pipeline.New(t.conf.Pipeline, pMgr, pLog, pStats, t.complementaryProcs...); err != nil {
+		if t.pipelineLayer, err = pipeline.NewWithErrorHandling(
+			t.conf.Pipeline, t.conf.ErrorHandling.ErrorHandlingConfig,
+			pMgr, pLog, pStats, t.complementaryProcs...,
+		); err != nil {
 			return
 		}
 	}
+	if t.conf.Expiry.Enabled() && !t.conf.Expiry.Routes() {
+		t.pipelineLayer = newExpiryPipeline(t.pipelineLayer, t.logger, t.stats)
+	}
 	oMgr, oLog, oStats := interop.LabelChild("output", t.manager, t.logger, t.stats)
 	if t.outputLayer, err = output.New(t.conf.Output, oMgr, oLog, oStats); err != nil {
 		return
 	}
+	if t.conf.ErrorHandling.Output != nil {
+		eMgr, eLog, eStats := interop.LabelChild("error_handling.output", t.manager, t.logger, t.stats)
+		var errOutput output.Type
+		if errOutput, err = output.New(*t.conf.ErrorHandling.Output, eMgr, eLog, eStats); err != nil {
+			return
+		}
+		if t.outputLayer, err = newErrorRouter(t.outputLayer, errOutput, t.logger, t.stats); err != nil {
+			return
+		}
+	}
+	if t.conf.Expiry.Enabled() {
+		var expOutput output.Type
+		mExpired := t.stats.GetCounter("expiry.dropped")
+		if t.conf.Expiry.Routes() {
+			xMgr, xLog, xStats := interop.LabelChild("expiry.output", t.manager, t.logger, t.stats)
+			if expOutput, err = output.New(*t.conf.Expiry.Output, xMgr, xLog, xStats); err != nil {
+				return
+			}
+			mExpired = t.stats.GetCounter("expiry.output.routed")
+		}
+		if t.outputLayer, err = newPartRouter(t.outputLayer, expOutput, expired, []string{ExpiryMetadataKey}, mExpired, t.logger); err != nil {
+			return
+		}
+	}
 
 	// Start chaining components
 	var nextTranChan <-chan types.Transaction
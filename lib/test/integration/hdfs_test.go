@@ -4,7 +4,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/colinmarc/hdfs"
+	"github.com/colinmarc/hdfs/v2"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
@@ -71,6 +71,7 @@ output:
     user: root
     directory: /$ID
     path: ${!count("$ID")}-${!timestamp_unix_nano()}.txt
+    atomic_rename: $VAR1
     max_in_flight: $MAX_IN_FLIGHT
     batching:
       count: $OUTPUT_BATCH_COUNT
@@ -85,5 +86,17 @@ input:
 		integrationTestOpenCloseIsolated(),
 		integrationTestStreamIsolated(10),
 		integrationTestSendBatchCountIsolated(10),
-	).Run(t, template)
+	).Run(
+		t, template,
+		testOptVarOne("false"),
+	)
+	t.Run("with atomic rename", func(t *testing.T) {
+		integrationTests(
+			integrationTestOpenCloseIsolated(),
+			integrationTestStreamIsolated(10),
+		).Run(
+			t, template,
+			testOptVarOne("true"),
+		)
+	})
 })
@@ -49,6 +49,22 @@ input:
 			testOptMaxInFlight(10),
 		)
 	})
+	t.Run("with req rep", func(t *testing.T) {
+		t.Parallel()
+		// Requests are only acknowledged once they are replied to, which
+		// happens after they are consumed, and therefore only tests that read
+		// whilst writing are applicable.
+		integrationTests(
+			integrationTestStreamParallel(100),
+		).Run(
+			t, template,
+			testOptSleepAfterInput(500*time.Millisecond),
+			testOptSleepAfterOutput(500*time.Millisecond),
+			testOptVarOne("REQ"),
+			testOptVarTwo("REP"),
+			testOptMaxInFlight(10),
+		)
+	})
 	t.Run("with pub sub", func(t *testing.T) {
 		t.Parallel()
 		suite.Run(
@@ -53,6 +53,7 @@ output:
   pulsar:
     url: pulsar://localhost:$PORT/
     topic: "topic-$ID"
+    key: "$VAR1"
     max_in_flight: $MAX_IN_FLIGHT
 
 input:
@@ -60,6 +61,7 @@ input:
     url: pulsar://localhost:$PORT/
     topics: [ "topic-$ID" ]
     subscription_name: "sub-$ID"
+    subscription_type: $VAR2
 `
 	suite := integrationTests(
 		integrationTestOpenClose(),
@@ -75,6 +77,7 @@ input:
 		testOptSleepAfterInput(500*time.Millisecond),
 		testOptSleepAfterOutput(500*time.Millisecond),
 		testOptPort(resource.GetPort("6650/tcp")),
+		testOptVarTwo("shared"),
 	)
 	t.Run("with max in flight", func(t *testing.T) {
 		t.Parallel()
@@ -84,6 +87,18 @@ input:
 			testOptSleepAfterOutput(500*time.Millisecond),
 			testOptPort(resource.GetPort("6650/tcp")),
 			testOptMaxInFlight(10),
+			testOptVarTwo("shared"),
+		)
+	})
+	t.Run("with key shared", func(t *testing.T) {
+		t.Parallel()
+		suite.Run(
+			t, template,
+			testOptSleepAfterInput(500*time.Millisecond),
+			testOptSleepAfterOutput(500*time.Millisecond),
+			testOptPort(resource.GetPort("6650/tcp")),
+			testOptVarOne(`${! content() }`),
+			testOptVarTwo("key_shared"),
 		)
 	})
 })
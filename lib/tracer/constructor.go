@@ -152,6 +152,10 @@ ingestion that represents their journey through a Benthos pipeline. Many Benthos
 processors create spans, and so opentracing is a great way to analyse the
 pathways of individual messages as they progress through a Benthos instance.
 
+Inputs, processors and outputs that are configured with a ` + "`label`" + ` create spans
+named by that label, which wrap any spans created by the component itself. The
+root span of a message is also named by the label of the input that consumed it.
+
 Some inputs, such as ` + "`http_server` and `http_client`" + `, are capable of
 extracting a root span from the source of the message (HTTP headers). This is
 a work in progress and should eventually expand so that all inputs have a way of
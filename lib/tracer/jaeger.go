@@ -34,6 +34,8 @@ Send spans to a [Jaeger](https://www.jaegertracing.io/) agent.`,
 			docs.FieldAdvanced("sampler_param", "A parameter to use for sampling. This field is unused for some sampling types."),
 			docs.FieldAdvanced("tags", "A map of tags to add to tracing spans.").Map(),
 			docs.FieldCommon("flush_interval", "The period of time between each flush of tracing spans."),
+			tailSamplingFieldSpec(),
+			docs.FieldAdvanced("disabled_spans", "A list of operation names of spans that should not be created, which can be used to reduce the overhead of tracing components that are trivial and process large volumes of messages. The spans of a component are named after its type, and the spans of labelled components are also named after their label. Spans that would have been children of a disabled span are instead children of its parent.", []string{"bloblang", "log"}).Array().AtVersion("3.47.0"),
 		},
 	}
 }
@@ -42,14 +44,16 @@ Send spans to a [Jaeger](https://www.jaegertracing.io/) agent.`,
 
 // JaegerConfig is config for the Jaeger metrics type.
 type JaegerConfig struct {
-	AgentAddress          string            `json:"agent_address" yaml:"agent_address"`
-	CollectorURL          string            `json:"collector_url" yaml:"collector_url"`
-	ServiceName           string            `json:"service_name" yaml:"service_name"`
-	SamplerType           string            `json:"sampler_type" yaml:"sampler_type"`
-	SamplerManagerAddress string            `json:"sampler_manager_address" yaml:"sampler_manager_address"`
-	SamplerParam          float64           `json:"sampler_param" yaml:"sampler_param"`
-	Tags                  map[string]string `json:"tags" yaml:"tags"`
-	FlushInterval         string            `json:"flush_interval" yaml:"flush_interval"`
+	AgentAddress          string             `json:"agent_address" yaml:"agent_address"`
+	CollectorURL          string             `json:"collector_url" yaml:"collector_url"`
+	ServiceName           string             `json:"service_name" yaml:"service_name"`
+	SamplerType           string             `json:"sampler_type" yaml:"sampler_type"`
+	SamplerManagerAddress string             `json:"sampler_manager_address" yaml:"sampler_manager_address"`
+	SamplerParam          float64            `json:"sampler_param" yaml:"sampler_param"`
+	Tags                  map[string]string  `json:"tags" yaml:"tags"`
+	FlushInterval         string             `json:"flush_interval" yaml:"flush_interval"`
+	TailSampling          TailSamplingConfig `json:"tail_sampling" yaml:"tail_sampling"`
+	DisabledSpans         []string           `json:"disabled_spans" yaml:"disabled_spans"`
 }
 
 // NewJaegerConfig creates an JaegerConfig struct with default values.
@@ -62,6 +66,8 @@ func NewJaegerConfig() JaegerConfig {
 		SamplerParam:          1.0,
 		Tags:                  map[string]string{},
 		FlushInterval:         "",
+		TailSampling:          NewTailSamplingConfig(),
+		DisabledSpans:         []string{},
 	}
 }
 
@@ -136,10 +142,31 @@ func NewJaeger(config Config, opts ...func(Type)) (Type, error) {
 		reporterConf.CollectorEndpoint = i
 	}
 
-	tracer, closer, err := cfg.NewTracer()
+	var tailReporter *tailSamplingReporter
+	var tracerOpts []jaegercfg.Option
+	if config.Jaeger.TailSampling.Enabled {
+		next, err := reporterConf.NewReporter(cfg.ServiceName, jaeger.NewNullMetrics(), jaeger.NullLogger)
+		if err != nil {
+			return nil, err
+		}
+		if tailReporter, err = newTailSamplingReporter(config.Jaeger.TailSampling, next); err != nil {
+			next.Close()
+			return nil, err
+		}
+		tracerOpts = append(tracerOpts, jaegercfg.Reporter(tailReporter))
+	}
+
+	tracer, closer, err := cfg.NewTracer(tracerOpts...)
 	if err != nil {
 		return nil, err
 	}
+	if len(config.Jaeger.DisabledSpans) > 0 {
+		var onError func(opentracing.SpanContext)
+		if tailReporter != nil {
+			onError = tailReporter.markErrored
+		}
+		tracer = newSpanFilterTracer(tracer, config.Jaeger.DisabledSpans, onError)
+	}
 	opentracing.SetGlobalTracer(tracer)
 	j.closer = closer
 
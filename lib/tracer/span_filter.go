@@ -0,0 +1,76 @@
+package tracer
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+// spanFilterTracer wraps a tracer and skips the creation of spans with
+// disabled operation names, in which case spans that would have been children
+// of the skipped span become children of its parent.
+type spanFilterTracer struct {
+	opentracing.Tracer
+	disabled map[string]struct{}
+	onError  func(opentracing.SpanContext)
+}
+
+func newSpanFilterTracer(t opentracing.Tracer, disabled []string, onError func(opentracing.SpanContext)) *spanFilterTracer {
+	f := &spanFilterTracer{
+		Tracer:   t,
+		disabled: make(map[string]struct{}, len(disabled)),
+		onError:  onError,
+	}
+	for _, name := range disabled {
+		f.disabled[name] = struct{}{}
+	}
+	return f
+}
+
+// StartSpan creates a span unless the operation name is disabled.
+func (f *spanFilterTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	if _, disabled := f.disabled[operationName]; !disabled {
+		return f.Tracer.StartSpan(operationName, opts...)
+	}
+
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	for _, ref := range sso.References {
+		if ref.ReferencedContext != nil {
+			return &skippedSpan{tracer: f, parent: ref.ReferencedContext}
+		}
+	}
+	return opentracing.NoopTracer{}.StartSpan(operationName)
+}
+
+//------------------------------------------------------------------------------
+
+// skippedSpan stands in for a span of a disabled operation, tags and logs are
+// discarded except for errors, which are passed to the tracer.
+type skippedSpan struct {
+	tracer *spanFilterTracer
+	parent opentracing.SpanContext
+}
+
+func (s *skippedSpan) Finish()                                          {}
+func (s *skippedSpan) FinishWithOptions(opts opentracing.FinishOptions) {}
+func (s *skippedSpan) Context() opentracing.SpanContext                 { return s.parent }
+func (s *skippedSpan) SetOperationName(string) opentracing.Span         { return s }
+func (s *skippedSpan) LogFields(...log.Field)                           {}
+func (s *skippedSpan) LogKV(...interface{})                             {}
+func (s *skippedSpan) SetBaggageItem(string, string) opentracing.Span   { return s }
+func (s *skippedSpan) BaggageItem(string) string                        { return "" }
+func (s *skippedSpan) Tracer() opentracing.Tracer                       { return s.tracer }
+func (s *skippedSpan) LogEvent(string)                                  {}
+func (s *skippedSpan) LogEventWithPayload(string, interface{})          {}
+func (s *skippedSpan) Log(opentracing.LogData)                          {}
+
+func (s *skippedSpan) SetTag(key string, value interface{}) opentracing.Span {
+	if key == "error" && isErrorTag(value) && s.tracer.onError != nil {
+		s.tracer.onError(s.parent)
+	}
+	return s
+}
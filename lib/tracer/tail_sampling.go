@@ -0,0 +1,179 @@
+package tracer
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+//------------------------------------------------------------------------------
+
+// TailSamplingConfig contains configuration fields for sampling traces once
+// they have completed.
+type TailSamplingConfig struct {
+	Enabled          bool   `json:"enabled" yaml:"enabled"`
+	Errored          bool   `json:"errored" yaml:"errored"`
+	LatencyThreshold string `json:"latency_threshold" yaml:"latency_threshold"`
+	MaxTraces        int    `json:"max_traces" yaml:"max_traces"`
+}
+
+// NewTailSamplingConfig creates a TailSamplingConfig struct with default
+// values.
+func NewTailSamplingConfig() TailSamplingConfig {
+	return TailSamplingConfig{
+		Enabled:          false,
+		Errored:          true,
+		LatencyThreshold: "",
+		MaxTraces:        10000,
+	}
+}
+
+func tailSamplingFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"tail_sampling",
+		"Buffer the spans of traces in memory until the root span of the trace finishes, and only send traces that meet the conditions below. Traces are only considered when they are sampled by the `sampler_type`, and therefore the `const` sampler type with a `sampler_param` of `1` should be used in order to consider all traces.",
+	).WithChildren(
+		docs.FieldCommon("enabled", "Whether tail sampling is enabled."),
+		docs.FieldCommon("errored", "Whether to send traces where any span has an error."),
+		docs.FieldCommon("latency_threshold", "An optional duration where traces with a root span that exceeds it are sent.", "500ms"),
+		docs.FieldAdvanced("max_traces", "The maximum number of incomplete traces to buffer, once reached the oldest incomplete trace is dropped."),
+	).AtVersion("3.47.0")
+}
+
+//------------------------------------------------------------------------------
+
+type pendingTrace struct {
+	spans   []*jaeger.Span
+	errored bool
+	elem    *list.Element
+}
+
+// tailSamplingReporter buffers the spans of each trace until its root span is
+// finished, and then forwards the spans of traces that meet the configured
+// conditions to a child reporter.
+type tailSamplingReporter struct {
+	next      jaeger.Reporter
+	errored   bool
+	latency   time.Duration
+	maxTraces int
+
+	mut    sync.Mutex
+	traces map[jaeger.TraceID]*pendingTrace
+	order  *list.List
+}
+
+func newTailSamplingReporter(conf TailSamplingConfig, next jaeger.Reporter) (*tailSamplingReporter, error) {
+	r := &tailSamplingReporter{
+		next:      next,
+		errored:   conf.Errored,
+		maxTraces: conf.MaxTraces,
+		traces:    map[jaeger.TraceID]*pendingTrace{},
+		order:     list.New(),
+	}
+	if r.maxTraces <= 0 {
+		return nil, fmt.Errorf("max_traces must be greater than zero, got %v", conf.MaxTraces)
+	}
+	if conf.LatencyThreshold != "" {
+		var err error
+		if r.latency, err = time.ParseDuration(conf.LatencyThreshold); err != nil {
+			return nil, fmt.Errorf("failed to parse latency threshold '%s': %v", conf.LatencyThreshold, err)
+		}
+	}
+	return r, nil
+}
+
+func isErrorTag(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// pending returns the buffered trace of an ID, creating it if it does not
+// exist. The mutex must be held.
+func (r *tailSamplingReporter) pending(id jaeger.TraceID) *pendingTrace {
+	t, exists := r.traces[id]
+	if exists {
+		return t
+	}
+	for r.order.Len() >= r.maxTraces {
+		oldest := r.order.Front()
+		r.drop(oldest.Value.(jaeger.TraceID))
+	}
+	t = &pendingTrace{}
+	t.elem = r.order.PushBack(id)
+	r.traces[id] = t
+	return t
+}
+
+// drop discards a buffered trace. The mutex must be held.
+func (r *tailSamplingReporter) drop(id jaeger.TraceID) {
+	t, exists := r.traces[id]
+	if !exists {
+		return
+	}
+	delete(r.traces, id)
+	r.order.Remove(t.elem)
+	for _, s := range t.spans {
+		s.Release()
+	}
+}
+
+// markErrored flags the trace of a span context as having an error, which is
+// used for spans that are not reported.
+func (r *tailSamplingReporter) markErrored(ctx opentracing.SpanContext) {
+	jCtx, ok := ctx.(jaeger.SpanContext)
+	if !ok || !jCtx.IsValid() {
+		return
+	}
+	r.mut.Lock()
+	r.pending(jCtx.TraceID()).errored = true
+	r.mut.Unlock()
+}
+
+// Report buffers a span until the root span of its trace is reported.
+func (r *tailSamplingReporter) Report(span *jaeger.Span) {
+	ctx := span.SpanContext()
+	errored := isErrorTag(span.Tags()["error"])
+
+	r.mut.Lock()
+	if ctx.ParentID() != 0 {
+		t := r.pending(ctx.TraceID())
+		t.spans = append(t.spans, span.Retain())
+		t.errored = t.errored || errored
+		r.mut.Unlock()
+		return
+	}
+
+	var spans []*jaeger.Span
+	if t, exists := r.traces[ctx.TraceID()]; exists {
+		spans, errored = t.spans, t.errored || errored
+		delete(r.traces, ctx.TraceID())
+		r.order.Remove(t.elem)
+	}
+	r.mut.Unlock()
+
+	keep := (r.errored && errored) || (r.latency > 0 && span.Duration() >= r.latency)
+	for _, s := range spans {
+		if keep {
+			r.next.Report(s)
+		}
+		s.Release()
+	}
+	if keep {
+		r.next.Report(span)
+	}
+}
+
+// Close drops all buffered traces and closes the child reporter.
+func (r *tailSamplingReporter) Close() {
+	r.mut.Lock()
+	for id := range r.traces {
+		r.drop(id)
+	}
+	r.mut.Unlock()
+	r.next.Close()
+}
@@ -0,0 +1,121 @@
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-client-go"
+)
+
+func newTestTailTracer(t *testing.T, conf TailSamplingConfig, disabled ...string) (opentracing.Tracer, *jaeger.InMemoryReporter) {
+	t.Helper()
+
+	mem := jaeger.NewInMemoryReporter()
+	reporter, err := newTailSamplingReporter(conf, mem)
+	require.NoError(t, err)
+
+	jTracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), reporter)
+	t.Cleanup(func() { closer.Close() })
+
+	var tracer opentracing.Tracer = jTracer
+	if len(disabled) > 0 {
+		tracer = newSpanFilterTracer(tracer, disabled, reporter.markErrored)
+	}
+	return tracer, mem
+}
+
+func spanNames(mem *jaeger.InMemoryReporter) []string {
+	var names []string
+	for _, s := range mem.GetSpans() {
+		names = append(names, s.(*jaeger.Span).OperationName())
+	}
+	return names
+}
+
+func TestTailSamplingErrored(t *testing.T) {
+	tracer, mem := newTestTailTracer(t, NewTailSamplingConfig())
+
+	root := tracer.StartSpan("input")
+	tracer.StartSpan("foo", opentracing.ChildOf(root.Context())).Finish()
+	root.Finish()
+	assert.Empty(t, mem.GetSpans())
+
+	root = tracer.StartSpan("input")
+	child := tracer.StartSpan("bar", opentracing.ChildOf(root.Context()))
+	child.SetTag("error", true)
+	child.Finish()
+	assert.Empty(t, mem.GetSpans())
+
+	root.Finish()
+	assert.Equal(t, []string{"bar", "input"}, spanNames(mem))
+}
+
+func TestTailSamplingLatency(t *testing.T) {
+	conf := NewTailSamplingConfig()
+	conf.Errored = false
+	conf.LatencyThreshold = "50ms"
+	tracer, mem := newTestTailTracer(t, conf)
+
+	root := tracer.StartSpan("input")
+	child := tracer.StartSpan("foo", opentracing.ChildOf(root.Context()))
+	child.SetTag("error", true)
+	child.Finish()
+	root.Finish()
+	assert.Empty(t, mem.GetSpans())
+
+	start := time.Now()
+	root = tracer.StartSpan("input", opentracing.StartTime(start))
+	tracer.StartSpan("bar", opentracing.ChildOf(root.Context())).Finish()
+	root.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(time.Millisecond * 100)})
+	assert.Equal(t, []string{"bar", "input"}, spanNames(mem))
+}
+
+func TestTailSamplingMaxTraces(t *testing.T) {
+	conf := NewTailSamplingConfig()
+	conf.MaxTraces = 1
+	tracer, mem := newTestTailTracer(t, conf)
+
+	rootA := tracer.StartSpan("a")
+	childA := tracer.StartSpan("a_child", opentracing.ChildOf(rootA.Context()))
+	childA.SetTag("error", true)
+	childA.Finish()
+
+	rootB := tracer.StartSpan("b")
+	childB := tracer.StartSpan("b_child", opentracing.ChildOf(rootB.Context()))
+	childB.SetTag("error", true)
+	childB.Finish()
+
+	// The incomplete trace a was dropped when trace b was buffered.
+	rootA.Finish()
+	rootB.Finish()
+	assert.Equal(t, []string{"b_child", "b"}, spanNames(mem))
+}
+
+func TestDisabledSpans(t *testing.T) {
+	tracer, mem := newTestTailTracer(t, NewTailSamplingConfig(), "noisy")
+
+	root := tracer.StartSpan("input")
+	skipped := tracer.StartSpan("noisy", opentracing.ChildOf(root.Context()))
+	assert.Equal(t, root.Context(), skipped.Context())
+
+	child := tracer.StartSpan("foo", opentracing.ChildOf(skipped.Context()))
+	child.Finish()
+
+	// Errors of skipped spans still mark the trace as errored.
+	skipped.SetTag("error", true)
+	skipped.Finish()
+	root.Finish()
+
+	require.Equal(t, []string{"foo", "input"}, spanNames(mem))
+	rootCtx := root.Context().(jaeger.SpanContext)
+	childCtx := mem.GetSpans()[0].Context().(jaeger.SpanContext)
+	assert.Equal(t, rootCtx.SpanID(), childCtx.ParentID())
+
+	// Disabled spans without a parent are not traced.
+	orphan := tracer.StartSpan("noisy")
+	orphan.Finish()
+	assert.Len(t, mem.GetSpans(), 2)
+}
@@ -105,6 +105,19 @@ var (
 
 //------------------------------------------------------------------------------
 
+// ErrValidation is an error returned when the contents of a message fail a
+// validation step, such as a schema check.
+type ErrValidation struct {
+	S string
+}
+
+// Error returns the Error string.
+func (e ErrValidation) Error() string {
+	return e.S
+}
+
+//------------------------------------------------------------------------------
+
 // ErrUnexpectedHTTPRes is an error returned when an HTTP request returned an
 // unexpected response.
 type ErrUnexpectedHTTPRes struct {
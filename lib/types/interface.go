@@ -73,6 +73,14 @@ type RateLimit interface {
 	Closable
 }
 
+// RateLimitReleaser is implemented by rate limits that restrict the number of
+// concurrent accesses to a resource, where each granted access is released
+// once the resource is no longer in use.
+type RateLimitReleaser interface {
+	// Release an access previously granted by the rate limit.
+	Release()
+}
+
 //------------------------------------------------------------------------------
 
 // Condition reads a message, calculates a condition and returns a boolean.
@@ -11,6 +11,26 @@ import (
 // be interpretted as having failed a processor step somewhere in the pipeline.
 var FailFlagKey = "benthos_processing_failed"
 
+// FailSourceKey is a metadata key used for recording the label (or path) of the
+// component that flagged a processor error.
+var FailSourceKey = "benthos_processing_failed_source"
+
+// FailClassKey is a metadata key used for recording the type of a processor
+// error.
+var FailClassKey = "benthos_processing_failed_class"
+
+// FailKindKey is a metadata key used for recording the kind of a processor
+// error, such as a timeout or a validation failure.
+var FailKindKey = "benthos_processing_failed_kind"
+
+// FailAttemptsKey is a metadata key used for recording the number of times a
+// processor was attempted before an error was flagged.
+var FailAttemptsKey = "benthos_processing_failed_attempts"
+
+// FailPayloadHashKey is a metadata key used for recording a hash of the payload
+// of a message part as it was before a processor flagged an error.
+var FailPayloadHashKey = "benthos_processing_failed_payload_hash"
+
 //------------------------------------------------------------------------------
 
 // Metadata is an interface representing the metadata of a message part within
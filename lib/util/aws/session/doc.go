@@ -14,6 +14,13 @@ func FieldSpecs() docs.FieldSpecs {
 			docs.FieldAdvanced("token", "The token for the credentials being used, required when using short term credentials."),
 			docs.FieldAdvanced("role", "A role ARN to assume."),
 			docs.FieldAdvanced("role_external_id", "An external ID to provide when assuming a role."),
+			docs.FieldAdvanced("web_identity_token_file", "A path to a file containing an OIDC token, such as the service account token provided by [IAM roles for service accounts](https://docs.aws.amazon.com/eks/latest/userguide/iam-roles-for-service-accounts.html), which is exchanged for the credentials of `role`.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("role_chain", "A list of roles to assume in order after any `role`, where each role is assumed with the credentials of the role before it.").Array().WithChildren(
+				docs.FieldAdvanced("role", "A role ARN to assume.").HasType(docs.FieldString).HasDefault(""),
+				docs.FieldAdvanced("role_external_id", "An external ID to provide when assuming the role.").HasType(docs.FieldString).HasDefault(""),
+			).AtVersion("3.47.0"),
+			docs.FieldAdvanced("sts_regional_endpoint", "Whether to obtain credentials from the STS endpoint of the configured region rather than the global endpoint.").AtVersion("3.47.0"),
+			docs.FieldAdvanced("sts_endpoint", "Allows you to specify a custom endpoint for obtaining credentials from STS, which otherwise defaults to `endpoint` when it is set.").AtVersion("3.47.0"),
 		),
 	}
 }
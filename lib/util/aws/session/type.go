@@ -1,10 +1,14 @@
 package session
 
 import (
+	"errors"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 //------------------------------------------------------------------------------
@@ -17,6 +21,18 @@ type CredentialsConfig struct {
 	Token      string `json:"token" yaml:"token"`
 	Role       string `json:"role" yaml:"role"`
 	ExternalID string `json:"role_external_id" yaml:"role_external_id"`
+
+	WebIdentityTokenFile string       `json:"web_identity_token_file" yaml:"web_identity_token_file"`
+	RoleChain            []RoleConfig `json:"role_chain" yaml:"role_chain"`
+	STSRegionalEndpoint  bool         `json:"sts_regional_endpoint" yaml:"sts_regional_endpoint"`
+	STSEndpoint          string       `json:"sts_endpoint" yaml:"sts_endpoint"`
+}
+
+// RoleConfig contains configuration params for a role assumed as part of a
+// chain.
+type RoleConfig struct {
+	Role       string `json:"role" yaml:"role"`
+	ExternalID string `json:"role_external_id" yaml:"role_external_id"`
 }
 
 // Config contains configuration fields for an AWS session. This config is
@@ -37,6 +53,11 @@ func NewConfig() Config {
 			Token:      "",
 			Role:       "",
 			ExternalID: "",
+
+			WebIdentityTokenFile: "",
+			RoleChain:            []RoleConfig{},
+			STSRegionalEndpoint:  false,
+			STSEndpoint:          "",
 		},
 		Endpoint: "",
 		Region:   "eu-west-1",
@@ -68,6 +89,10 @@ func (c Config) GetSession(opts ...func(*aws.Config)) (*session.Session, error)
 		))
 	}
 
+	if c.Credentials.STSRegionalEndpoint {
+		awsConf = awsConf.WithSTSRegionalEndpoint(endpoints.RegionalSTSEndpoint)
+	}
+
 	for _, opt := range opts {
 		opt(awsConf)
 	}
@@ -77,21 +102,68 @@ func (c Config) GetSession(opts ...func(*aws.Config)) (*session.Session, error)
 		return nil, err
 	}
 
-	if len(c.Credentials.Role) > 0 {
-		var opts []func(*stscreds.AssumeRoleProvider)
-		if len(c.Credentials.ExternalID) > 0 {
-			opts = []func(*stscreds.AssumeRoleProvider){
-				func(p *stscreds.AssumeRoleProvider) {
-					p.ExternalID = &c.Credentials.ExternalID
-				},
-			}
+	creds, err := c.Credentials.assumeRoles(sess)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		sess.Config = sess.Config.WithCredentials(creds)
+	}
+	return sess, nil
+}
+
+// stsClient returns an STS client that obtains credentials from creds, or from
+// the session when creds is nil.
+func (c CredentialsConfig) stsClient(sess *session.Session, creds *credentials.Credentials) *sts.STS {
+	stsConf := aws.NewConfig()
+	if creds != nil {
+		stsConf = stsConf.WithCredentials(creds)
+	}
+	if len(c.STSEndpoint) > 0 {
+		stsConf = stsConf.WithEndpoint(c.STSEndpoint)
+	}
+	return sts.New(sess, stsConf)
+}
+
+func assumeRoleOpts(externalID string) []func(*stscreds.AssumeRoleProvider) {
+	if len(externalID) == 0 {
+		return nil
+	}
+	return []func(*stscreds.AssumeRoleProvider){
+		func(p *stscreds.AssumeRoleProvider) {
+			p.ExternalID = &externalID
+		},
+	}
+}
+
+// assumeRoles returns credentials obtained by assuming the configured role,
+// either with a web identity token or the credentials of the session, followed
+// by each role of the chain in turn using the credentials of the role before
+// it. Returns nil when no roles are configured.
+func (c CredentialsConfig) assumeRoles(sess *session.Session) (*credentials.Credentials, error) {
+	var creds *credentials.Credentials
+	if len(c.WebIdentityTokenFile) > 0 {
+		if len(c.Role) == 0 {
+			return nil, errors.New("a role must be specified in order to use a web_identity_token_file")
 		}
-		sess.Config = sess.Config.WithCredentials(
-			stscreds.NewCredentials(sess, c.Credentials.Role, opts...),
+		creds = credentials.NewCredentials(stscreds.NewWebIdentityRoleProvider(
+			c.stsClient(sess, nil), c.Role, "", c.WebIdentityTokenFile,
+		))
+	} else if len(c.Role) > 0 {
+		creds = stscreds.NewCredentialsWithClient(
+			c.stsClient(sess, nil), c.Role, assumeRoleOpts(c.ExternalID)...,
 		)
 	}
 
-	return sess, nil
+	for _, link := range c.RoleChain {
+		if len(link.Role) == 0 {
+			return nil, errors.New("each role of a role_chain must specify a role")
+		}
+		creds = stscreds.NewCredentialsWithClient(
+			c.stsClient(sess, creds), link.Role, assumeRoleOpts(link.ExternalID)...,
+		)
+	}
+	return creds, nil
 }
 
 //------------------------------------------------------------------------------
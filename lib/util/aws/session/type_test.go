@@ -0,0 +1,137 @@
+package session
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stsRequest struct {
+	action     string
+	role       string
+	keyID      string
+	externalID string
+	token      string
+}
+
+func roleARN(name string) string {
+	return "arn:aws:iam::123456789012:role/" + name
+}
+
+var credentialKeyIDRegexp = regexp.MustCompile(`Credential=([^/]+)/`)
+
+func newTestSTSServer(t *testing.T) (*httptest.Server, func() []stsRequest) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var reqs []stsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		req := stsRequest{
+			action:     r.PostForm.Get("Action"),
+			role:       r.PostForm.Get("RoleArn"),
+			externalID: r.PostForm.Get("ExternalId"),
+			token:      r.PostForm.Get("WebIdentityToken"),
+		}
+		if m := credentialKeyIDRegexp.FindStringSubmatch(r.Header.Get("Authorization")); len(m) > 1 {
+			req.keyID = m[1]
+		}
+
+		mut.Lock()
+		reqs = append(reqs, req)
+		mut.Unlock()
+
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<%[1]vResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <%[1]vResult>
+    <Credentials>
+      <AccessKeyId>key-%[2]v</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </%[1]vResult>
+</%[1]vResponse>`, req.action, path.Base(req.role))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []stsRequest {
+		mut.Lock()
+		defer mut.Unlock()
+		return append([]stsRequest(nil), reqs...)
+	}
+}
+
+func TestSessionRoleChain(t *testing.T) {
+	server, reqs := newTestSTSServer(t)
+
+	conf := NewConfig()
+	conf.Credentials.ID = "root"
+	conf.Credentials.Secret = "secret"
+	conf.Credentials.Role = roleARN("first")
+	conf.Credentials.RoleChain = []RoleConfig{
+		{Role: roleARN("second"), ExternalID: "foo"},
+		{Role: roleARN("third")},
+	}
+	conf.Credentials.STSEndpoint = server.URL
+
+	sess, err := conf.GetSession()
+	require.NoError(t, err)
+
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "key-third", creds.AccessKeyID)
+
+	assert.Equal(t, []stsRequest{
+		{action: "AssumeRole", role: roleARN("first"), keyID: "root"},
+		{action: "AssumeRole", role: roleARN("second"), keyID: "key-first", externalID: "foo"},
+		{action: "AssumeRole", role: roleARN("third"), keyID: "key-second"},
+	}, reqs())
+}
+
+func TestSessionWebIdentity(t *testing.T) {
+	server, reqs := newTestSTSServer(t)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("oidc-token"), 0600))
+
+	conf := NewConfig()
+	conf.Credentials.WebIdentityTokenFile = tokenFile
+	conf.Credentials.Role = roleARN("first")
+	conf.Credentials.RoleChain = []RoleConfig{{Role: roleARN("second")}}
+	conf.Credentials.STSEndpoint = server.URL
+
+	sess, err := conf.GetSession()
+	require.NoError(t, err)
+
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "key-second", creds.AccessKeyID)
+
+	assert.Equal(t, []stsRequest{
+		{action: "AssumeRoleWithWebIdentity", role: roleARN("first"), token: "oidc-token"},
+		{action: "AssumeRole", role: roleARN("second"), keyID: "key-first"},
+	}, reqs())
+}
+
+func TestSessionRoleErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Credentials.WebIdentityTokenFile = "/var/run/token"
+	_, err := conf.GetSession()
+	require.EqualError(t, err, "a role must be specified in order to use a web_identity_token_file")
+
+	conf = NewConfig()
+	conf.Credentials.RoleChain = []RoleConfig{{ExternalID: "foo"}}
+	_, err = conf.GetSession()
+	require.EqualError(t, err, "each role of a role_chain must specify a role")
+}
@@ -0,0 +1,18 @@
+package faults
+
+import "github.com/Jeffail/benthos/v3/internal/docs"
+
+// FieldSpecs returns documentation specs for fault injection fields.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("seed", "A seed for the random number generator that decides which faults are injected, allowing a sequence of faults to be reproduced. If set to zero a seed is derived from the current time."),
+		docs.FieldCommon("delay", "Inject a random delay.").WithChildren(
+			docs.FieldCommon("probability", "The probability, between 0 and 1, of a delay being injected."),
+			docs.FieldCommon("min", "The minimum duration of an injected delay."),
+			docs.FieldCommon("max", "The maximum duration of an injected delay."),
+		),
+		docs.FieldCommon("error_probability", "The probability, between 0 and 1, of an error being injected."),
+		docs.FieldCommon("duplicate_probability", "The probability, between 0 and 1, of a message being duplicated."),
+		docs.FieldCommon("reorder_probability", "The probability, between 0 and 1, of messages being reordered."),
+	}
+}
@@ -0,0 +1,4 @@
+// Package faults implements a seeded mechanism for injecting delays, errors,
+// duplicates and reordering into a stream around a standard configuration
+// scheme.
+package faults
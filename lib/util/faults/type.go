@@ -0,0 +1,148 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrInjected is returned or flagged wherever an error has been injected.
+var ErrInjected = errors.New("injected fault")
+
+//------------------------------------------------------------------------------
+
+// Delay contains configuration params for injected delays.
+type Delay struct {
+	Probability float64 `json:"probability" yaml:"probability"`
+	Min         string  `json:"min" yaml:"min"`
+	Max         string  `json:"max" yaml:"max"`
+}
+
+// Config contains configuration params for a fault injection mechanism.
+type Config struct {
+	Seed                 int64   `json:"seed" yaml:"seed"`
+	Delay                Delay   `json:"delay" yaml:"delay"`
+	ErrorProbability     float64 `json:"error_probability" yaml:"error_probability"`
+	DuplicateProbability float64 `json:"duplicate_probability" yaml:"duplicate_probability"`
+	ReorderProbability   float64 `json:"reorder_probability" yaml:"reorder_probability"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Seed: 0,
+		Delay: Delay{
+			Probability: 0,
+			Min:         "10ms",
+			Max:         "1s",
+		},
+		ErrorProbability:     0,
+		DuplicateProbability: 0,
+		ReorderProbability:   0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Injector decides which faults should be injected based on the probabilities
+// of a Config. It is safe to use from multiple goroutines, although the
+// sequence of faults is only reproducible when used from one.
+type Injector struct {
+	conf     Config
+	delayMin time.Duration
+	delayMax time.Duration
+
+	mut sync.Mutex
+	rng *rand.Rand
+}
+
+// NewInjector returns an Injector based on the configuration values of Config.
+func (c Config) NewInjector() (*Injector, error) {
+	for k, v := range map[string]float64{
+		"delay probability":     c.Delay.Probability,
+		"error probability":     c.ErrorProbability,
+		"duplicate probability": c.DuplicateProbability,
+		"reorder probability":   c.ReorderProbability,
+	} {
+		if v < 0 || v > 1 {
+			return nil, fmt.Errorf("%v must be between 0 and 1, got %v", k, v)
+		}
+	}
+
+	i := &Injector{conf: c}
+
+	var err error
+	if c.Delay.Min != "" {
+		if i.delayMin, err = time.ParseDuration(c.Delay.Min); err != nil {
+			return nil, fmt.Errorf("invalid delay min: %v", err)
+		}
+	}
+	if c.Delay.Max != "" {
+		if i.delayMax, err = time.ParseDuration(c.Delay.Max); err != nil {
+			return nil, fmt.Errorf("invalid delay max: %v", err)
+		}
+	}
+	if i.delayMax < i.delayMin {
+		return nil, fmt.Errorf("delay max (%v) must not be less than delay min (%v)", i.delayMax, i.delayMin)
+	}
+
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	i.rng = rand.New(rand.NewSource(seed))
+	return i, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (i *Injector) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	return i.rng.Float64() < probability
+}
+
+// Delay returns a duration to delay for, which is zero when a delay should not
+// be injected.
+func (i *Injector) Delay() time.Duration {
+	if !i.roll(i.conf.Delay.Probability) {
+		return 0
+	}
+	if i.delayMax == i.delayMin {
+		return i.delayMin
+	}
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	return i.delayMin + time.Duration(i.rng.Int63n(int64(i.delayMax-i.delayMin)))
+}
+
+// Error returns true when an error should be injected.
+func (i *Injector) Error() bool {
+	return i.roll(i.conf.ErrorProbability)
+}
+
+// Duplicate returns true when a message should be duplicated.
+func (i *Injector) Duplicate() bool {
+	return i.roll(i.conf.DuplicateProbability)
+}
+
+// Reorder returns true when messages should be reordered.
+func (i *Injector) Reorder() bool {
+	return i.roll(i.conf.ReorderProbability)
+}
+
+// Shuffle pseudo-randomises the order of n elements using a swap function.
+func (i *Injector) Shuffle(n int, swap func(i, j int)) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	i.rng.Shuffle(n, swap)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,57 @@
+package faults
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectorSeeded(t *testing.T) {
+	conf := NewConfig()
+	conf.Seed = 10
+	conf.Delay.Probability = 0.5
+	conf.Delay.Min = "1ms"
+	conf.Delay.Max = "5ms"
+	conf.ErrorProbability = 0.5
+
+	rolls := func() (res []interface{}) {
+		i, err := conf.NewInjector()
+		require.NoError(t, err)
+		for j := 0; j < 20; j++ {
+			res = append(res, i.Delay(), i.Error(), i.Duplicate())
+		}
+		return
+	}
+
+	first := rolls()
+	assert.Equal(t, first, rolls())
+
+	var delays, errs int
+	for j := 0; j < len(first); j += 3 {
+		if d := first[j].(time.Duration); d > 0 {
+			delays++
+			assert.GreaterOrEqual(t, int64(d), int64(time.Millisecond))
+			assert.Less(t, int64(d), int64(time.Millisecond*5))
+		}
+		if first[j+1].(bool) {
+			errs++
+		}
+		assert.False(t, first[j+2].(bool))
+	}
+	assert.Greater(t, delays, 0)
+	assert.Greater(t, errs, 0)
+}
+
+func TestInjectorBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.ErrorProbability = 1.5
+	_, err := conf.NewInjector()
+	require.EqualError(t, err, "error probability must be between 0 and 1, got 1.5")
+
+	conf = NewConfig()
+	conf.Delay.Min = "2s"
+	_, err = conf.NewInjector()
+	require.EqualError(t, err, "delay max (1s) must not be less than delay min (2s)")
+}
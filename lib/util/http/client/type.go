@@ -351,6 +351,29 @@ func (h *Type) waitForAccess() bool {
 	}
 }
 
+// releaseAccess releases an access granted by the rate limit, which is only
+// necessary for rate limits that restrict concurrent requests.
+func (h *Type) releaseAccess() {
+	if h.conf.RateLimit == "" {
+		return
+	}
+	if err := interop.AccessRateLimit(context.Background(), h.mgr, h.conf.RateLimit, func(rl types.RateLimit) {
+		if r, ok := rl.(types.RateLimitReleaser); ok {
+			r.Release()
+		}
+	}); err != nil {
+		h.log.Errorf("Rate limit error: %v\n", err)
+		h.mLimitErr.Incr(1)
+	}
+}
+
+// doRequest performs an HTTP request and releases the access granted by the
+// rate limit once a response is received.
+func (h *Type) doRequest(req *http.Request) (*http.Response, error) {
+	defer h.releaseAccess()
+	return h.client.Do(req)
+}
+
 // CreateRequest creates an HTTP request out of a single message.
 func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 	url := h.url.String(0, msg)
@@ -578,7 +601,7 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 
 	rateLimited := false
 	numRetries := h.conf.NumRetries
-	if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+	if res, err = h.doRequest(req.WithContext(ctx)); err == nil {
 		h.incrCode(res.StatusCode)
 		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
 			rateLimited = retryStrat == retryBackoff
@@ -620,7 +643,7 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 			return nil, types.ErrTypeClosed
 		}
 		rateLimited = false
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+		if res, err = h.doRequest(req.WithContext(ctx)); err == nil {
 			h.incrCode(res.StatusCode)
 			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
 				rateLimited = retryStrat == retryBackoff
@@ -575,3 +575,65 @@ func TestHTTPClientReceiveMultipartWithHeaders(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+type fakeRateLimit struct {
+	accessed int32
+	released int32
+}
+
+func (f *fakeRateLimit) Access() (time.Duration, error) {
+	atomic.AddInt32(&f.accessed, 1)
+	return 0, nil
+}
+
+func (f *fakeRateLimit) Release() {
+	atomic.AddInt32(&f.released, 1)
+}
+
+func (f *fakeRateLimit) CloseAsync() {}
+
+func (f *fakeRateLimit) WaitForClose(time.Duration) error {
+	return nil
+}
+
+type fakeRateLimitMgr struct {
+	types.Manager
+	rl *fakeRateLimit
+}
+
+func (f fakeRateLimitMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	if name != "foo" {
+		return nil, types.ErrRateLimitNotFound
+	}
+	return f.rl, nil
+}
+
+func TestHTTPClientRateLimitRelease(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "test error", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.NumRetries = 2
+	conf.RateLimit = "foo"
+
+	rl := &fakeRateLimit{}
+	h, err := New(conf, OptSetManager(fakeRateLimitMgr{rl: rl}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Send(message.New([][]byte{[]byte("test")})); err == nil {
+		t.Error("Expected error from end of retries")
+	}
+
+	if exp, act := int32(3), atomic.LoadInt32(&rl.accessed); exp != act {
+		t.Errorf("Wrong count of rate limit accesses: %v != %v", exp, act)
+	}
+	if exp, act := int32(3), atomic.LoadInt32(&rl.released); exp != act {
+		t.Errorf("Wrong count of rate limit releases: %v != %v", exp, act)
+	}
+}
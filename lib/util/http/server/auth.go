@@ -0,0 +1,350 @@
+package server
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/dgrijalva/jwt-go"
+)
+
+//------------------------------------------------------------------------------
+
+// BasicAuthConfig contains configuration fields for basic authentication of
+// requests.
+type BasicAuthConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	Realm    string `json:"realm" yaml:"realm"`
+}
+
+// NewBasicAuthConfig returns a BasicAuthConfig with default values.
+func NewBasicAuthConfig() BasicAuthConfig {
+	return BasicAuthConfig{
+		Enabled:  false,
+		Username: "",
+		Password: "",
+		Realm:    "restricted",
+	}
+}
+
+// APIKeysConfig contains configuration fields for authenticating requests with
+// a static list of API keys.
+type APIKeysConfig struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Header  string   `json:"header" yaml:"header"`
+	Keys    []string `json:"keys" yaml:"keys"`
+}
+
+// NewAPIKeysConfig returns an APIKeysConfig with default values.
+func NewAPIKeysConfig() APIKeysConfig {
+	return APIKeysConfig{
+		Enabled: false,
+		Header:  "X-API-Key",
+		Keys:    []string{},
+	}
+}
+
+// JWTConfig contains configuration fields for authenticating requests with a
+// bearer JWT validated against a JSON Web Key Set.
+type JWTConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	JWKSURL  string `json:"jwks_url" yaml:"jwks_url"`
+	Issuer   string `json:"issuer" yaml:"issuer"`
+	Audience string `json:"audience" yaml:"audience"`
+}
+
+// NewJWTConfig returns a JWTConfig with default values.
+func NewJWTConfig() JWTConfig {
+	return JWTConfig{
+		Enabled:  false,
+		JWKSURL:  "",
+		Issuer:   "",
+		Audience: "",
+	}
+}
+
+// MTLSConfig contains configuration fields for authenticating requests with a
+// TLS client certificate.
+type MTLSConfig struct {
+	Enabled            bool     `json:"enabled" yaml:"enabled"`
+	ClientCAFile       string   `json:"client_ca_file" yaml:"client_ca_file"`
+	AllowedCommonNames []string `json:"allowed_common_names" yaml:"allowed_common_names"`
+}
+
+// NewMTLSConfig returns an MTLSConfig with default values.
+func NewMTLSConfig() MTLSConfig {
+	return MTLSConfig{
+		Enabled:            false,
+		ClientCAFile:       "",
+		AllowedCommonNames: []string{},
+	}
+}
+
+// AuthConfig contains configuration fields for authenticating and
+// authorising requests made to an HTTP server.
+type AuthConfig struct {
+	BasicAuth    BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	APIKeys      APIKeysConfig   `json:"api_keys" yaml:"api_keys"`
+	JWT          JWTConfig       `json:"jwt" yaml:"jwt"`
+	MTLS         MTLSConfig      `json:"mtls" yaml:"mtls"`
+	AllowedCIDRs []string        `json:"allowed_cidrs" yaml:"allowed_cidrs"`
+	ExemptPaths  []string        `json:"exempt_paths" yaml:"exempt_paths"`
+}
+
+// NewAuthConfig returns an AuthConfig with default values.
+func NewAuthConfig() AuthConfig {
+	return AuthConfig{
+		BasicAuth:    NewBasicAuthConfig(),
+		APIKeys:      NewAPIKeysConfig(),
+		JWT:          NewJWTConfig(),
+		MTLS:         NewMTLSConfig(),
+		AllowedCIDRs: []string{},
+		ExemptPaths:  []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Authenticator wraps HTTP handlers with authentication and authorisation of
+// requests. When a CIDR allowlist is configured requests must originate from
+// an allowed network. When one or more authentication methods are enabled
+// requests must satisfy at least one of them.
+type Authenticator struct {
+	conf AuthConfig
+
+	allowedNets []*net.IPNet
+	exemptPaths map[string]struct{}
+	jwtKeys     *jwksCache
+	clientCAs   *x509.CertPool
+	commonNames map[string]struct{}
+
+	log log.Modular
+
+	mForbidden    metrics.StatCounter
+	mUnauthorized metrics.StatCounter
+}
+
+// NewAuthenticator creates a new authenticator from a config.
+func NewAuthenticator(conf AuthConfig, log log.Modular, stats metrics.Type) (*Authenticator, error) {
+	a := &Authenticator{
+		conf:          conf,
+		exemptPaths:   map[string]struct{}{},
+		log:           log,
+		mForbidden:    stats.GetCounter("auth.forbidden"),
+		mUnauthorized: stats.GetCounter("auth.unauthorized"),
+	}
+	for _, c := range conf.AllowedCIDRs {
+		_, ipNet, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse allowed cidr '%v': %w", c, err)
+		}
+		a.allowedNets = append(a.allowedNets, ipNet)
+	}
+	for _, p := range conf.ExemptPaths {
+		a.exemptPaths[p] = struct{}{}
+	}
+	if conf.BasicAuth.Enabled && conf.BasicAuth.Username == "" {
+		return nil, errors.New("a username must be specified for basic_auth")
+	}
+	if conf.APIKeys.Enabled {
+		if len(conf.APIKeys.Keys) == 0 {
+			return nil, errors.New("at least one key must be specified for api_keys")
+		}
+		if conf.APIKeys.Header == "" {
+			return nil, errors.New("a header must be specified for api_keys")
+		}
+	}
+	if conf.JWT.Enabled {
+		if conf.JWT.JWKSURL == "" {
+			return nil, errors.New("a jwks_url must be specified for jwt")
+		}
+		a.jwtKeys = newJWKSCache(conf.JWT.JWKSURL, nil)
+	}
+	if conf.MTLS.Enabled {
+		if conf.MTLS.ClientCAFile == "" {
+			return nil, errors.New("a client_ca_file must be specified for mtls")
+		}
+		caPEM, err := ioutil.ReadFile(conf.MTLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_ca_file: %w", err)
+		}
+		a.clientCAs = x509.NewCertPool()
+		if !a.clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("failed to parse any certificates from client_ca_file")
+		}
+		if len(conf.MTLS.AllowedCommonNames) > 0 {
+			a.commonNames = map[string]struct{}{}
+			for _, cn := range conf.MTLS.AllowedCommonNames {
+				a.commonNames[cn] = struct{}{}
+			}
+		}
+	}
+	return a, nil
+}
+
+// MTLSEnabled returns true if requests may be authenticated with a TLS client
+// certificate, in which case the server must be configured with the result of
+// TLSConfig.
+func (a *Authenticator) MTLSEnabled() bool {
+	return a.conf.MTLS.Enabled
+}
+
+// TLSConfig returns a TLS config that requests client certificates and
+// verifies them against the configured client CAs, or nil if mTLS is not
+// enabled. Client certificates are optional at the TLS layer so that requests
+// may still satisfy other authentication methods.
+func (a *Authenticator) TLSConfig() *tls.Config {
+	if !a.conf.MTLS.Enabled {
+		return nil
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  a.clientCAs,
+	}
+}
+
+// Enabled returns true if any form of authentication or authorisation is
+// enabled.
+func (a *Authenticator) Enabled() bool {
+	return a.authEnabled() || len(a.allowedNets) > 0
+}
+
+func (a *Authenticator) authEnabled() bool {
+	return a.conf.BasicAuth.Enabled || a.conf.APIKeys.Enabled || a.conf.JWT.Enabled || a.conf.MTLS.Enabled
+}
+
+// Middleware wraps an http.Handler with authentication.
+func (a *Authenticator) Middleware(h http.Handler) http.Handler {
+	if !a.Enabled() {
+		return h
+	}
+	return a.WrapHandler(h.ServeHTTP)
+}
+
+// WrapHandler wraps an http.HandlerFunc with authentication.
+func (a *Authenticator) WrapHandler(fn http.HandlerFunc) http.HandlerFunc {
+	if !a.Enabled() {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, exempt := a.exemptPaths[r.URL.Path]; exempt {
+			fn(w, r)
+			return
+		}
+		if !a.allowedAddr(r.RemoteAddr) {
+			a.log.Debugf("Rejecting request from disallowed address: %v\n", r.RemoteAddr)
+			a.mForbidden.Incr(1)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if err := a.authenticate(r); err != nil {
+			a.log.Debugf("Rejecting unauthenticated request: %v\n", err)
+			a.mUnauthorized.Incr(1)
+			if a.conf.BasicAuth.Enabled {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", a.conf.BasicAuth.Realm))
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+func (a *Authenticator) allowedAddr(remoteAddr string) bool {
+	if len(a.allowedNets) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.allowedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func secureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (a *Authenticator) authenticate(r *http.Request) error {
+	if !a.authEnabled() {
+		return nil
+	}
+	if a.conf.BasicAuth.Enabled {
+		if user, pass, ok := r.BasicAuth(); ok {
+			userMatch := secureCompare(user, a.conf.BasicAuth.Username)
+			passMatch := secureCompare(pass, a.conf.BasicAuth.Password)
+			if userMatch && passMatch {
+				return nil
+			}
+		}
+	}
+	if a.conf.APIKeys.Enabled {
+		if key := r.Header.Get(a.conf.APIKeys.Header); key != "" {
+			for _, k := range a.conf.APIKeys.Keys {
+				if secureCompare(key, k) {
+					return nil
+				}
+			}
+		}
+	}
+	if a.conf.MTLS.Enabled && a.verifiedClientCert(r) {
+		return nil
+	}
+	if a.conf.JWT.Enabled {
+		authHeader := r.Header.Get("Authorization")
+		if strings.HasPrefix(authHeader, "Bearer ") {
+			return a.verifyJWT(r, strings.TrimPrefix(authHeader, "Bearer "))
+		}
+	}
+	return errors.New("no valid credentials provided")
+}
+
+func (a *Authenticator) verifiedClientCert(r *http.Request) bool {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	if a.commonNames == nil {
+		return true
+	}
+	_, allowed := a.commonNames[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+	return allowed
+}
+
+func (a *Authenticator) verifyJWT(r *http.Request, token string) error {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return a.jwtKeys.Get(r.Context(), kid)
+	}); err != nil {
+		return err
+	}
+	if iss := a.conf.JWT.Issuer; iss != "" && !claims.VerifyIssuer(iss, true) {
+		return fmt.Errorf("unexpected token issuer: %v", claims["iss"])
+	}
+	if aud := a.conf.JWT.Audience; aud != "" && !claims.VerifyAudience(aud, true) {
+		return fmt.Errorf("unexpected token audience: %v", claims["aud"])
+	}
+	return nil
+}
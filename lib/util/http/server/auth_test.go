@@ -0,0 +1,258 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthDisabled(t *testing.T) {
+	a, err := NewAuthenticator(NewAuthConfig(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.False(t, a.Enabled())
+
+	rec := httptest.NewRecorder()
+	a.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})(rec, httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthMethods(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	conf := NewAuthConfig()
+	conf.BasicAuth.Enabled = true
+	conf.BasicAuth.Username = "foo"
+	conf.BasicAuth.Password = "bar"
+	conf.APIKeys.Enabled = true
+	conf.APIKeys.Keys = []string{"keyone", "keytwo"}
+	conf.JWT.Enabled = true
+	conf.JWT.JWKSURL = jwksServer(t, "jwtkey", &key.PublicKey)
+	conf.JWT.Issuer = "https://issuer.example.com"
+	conf.ExemptPaths = []string{"/ping"}
+
+	a, err := NewAuthenticator(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	hdlr := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	sign := func(iss string) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": iss,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		tok.Header["kid"] = "jwtkey"
+		str, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return str
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{
+			name:   "no credentials",
+			modify: func(r *http.Request) {},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "exempt path",
+			path:   "/ping",
+			modify: func(r *http.Request) {},
+			status: http.StatusOK,
+		},
+		{
+			name:   "basic auth",
+			modify: func(r *http.Request) { r.SetBasicAuth("foo", "bar") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "bad basic auth",
+			modify: func(r *http.Request) { r.SetBasicAuth("foo", "baz") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "api key",
+			modify: func(r *http.Request) { r.Header.Set("X-API-Key", "keytwo") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "bad api key",
+			modify: func(r *http.Request) { r.Header.Set("X-API-Key", "keythree") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "jwt",
+			modify: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+sign("https://issuer.example.com"))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "jwt bad issuer",
+			modify: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+sign("https://other.example.com"))
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path := test.path
+			if path == "" {
+				path = "/foo"
+			}
+			req := httptest.NewRequest("GET", path, nil)
+			test.modify(req)
+			rec := httptest.NewRecorder()
+			hdlr.ServeHTTP(rec, req)
+			assert.Equal(t, test.status, rec.Code)
+		})
+	}
+}
+
+func TestAuthAllowedCIDRs(t *testing.T) {
+	conf := NewAuthConfig()
+	conf.AllowedCIDRs = []string{"10.0.0.0/8", "192.168.1.5/32"}
+
+	a, err := NewAuthenticator(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	hdlr := a.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for addr, status := range map[string]int{
+		"10.1.2.3:1234":    http.StatusOK,
+		"192.168.1.5:80":   http.StatusOK,
+		"192.168.1.6:80":   http.StatusForbidden,
+		"172.16.0.1:43000": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/foo", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		hdlr(rec, req)
+		assert.Equal(t, status, rec.Code, addr)
+	}
+
+	conf.AllowedCIDRs = []string{"nope"}
+	_, err = NewAuthenticator(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func testClientCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestAuthMTLS(t *testing.T) {
+	caCert, caKey := testClientCert(t, "ca", nil, nil)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: caCert.Raw,
+	}), 0600))
+
+	conf := NewAuthConfig()
+	conf.APIKeys.Enabled = true
+	conf.APIKeys.Keys = []string{"keyone"}
+	conf.MTLS.Enabled = true
+	conf.MTLS.ClientCAFile = caFile
+	conf.MTLS.AllowedCommonNames = []string{"allowed"}
+
+	a, err := NewAuthenticator(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.True(t, a.MTLSEnabled())
+
+	server := httptest.NewUnstartedServer(a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})))
+	server.TLS = a.TLSConfig()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	get := func(cert *x509.Certificate, key *rsa.PrivateKey, apiKey string) int {
+		client := server.Client()
+		transport := client.Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  key,
+			}}
+		}
+		client.Transport = transport
+
+		req, err := http.NewRequest("GET", server.URL+"/foo", nil)
+		require.NoError(t, err)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	allowedCert, allowedKey := testClientCert(t, "allowed", caCert, caKey)
+	otherCert, otherKey := testClientCert(t, "other", caCert, caKey)
+	untrustedCA, untrustedCAKey := testClientCert(t, "untrusted", nil, nil)
+	untrustedCert, untrustedKey := testClientCert(t, "allowed", untrustedCA, untrustedCAKey)
+
+	assert.Equal(t, http.StatusUnauthorized, get(nil, nil, ""))
+	assert.Equal(t, http.StatusOK, get(nil, nil, "keyone"))
+	assert.Equal(t, http.StatusOK, get(allowedCert, allowedKey, ""))
+	assert.Equal(t, http.StatusUnauthorized, get(otherCert, otherKey, ""))
+	// Clients only present certificates issued by an acceptable authority.
+	assert.Equal(t, http.StatusUnauthorized, get(untrustedCert, untrustedKey, ""))
+
+	conf.MTLS.ClientCAFile = ""
+	_, err = NewAuthenticator(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "a client_ca_file must be specified for mtls")
+}
@@ -0,0 +1,134 @@
+package server
+
+import "github.com/Jeffail/benthos/v3/internal/docs"
+
+// PushVerifyFieldSpec returns a field spec for push request verification.
+func PushVerifyFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("push_verification",
+		"Allows you to verify requests pushed to the server by cloud services, rejecting requests that fail verification with a 401 status code.",
+	).WithChildren(
+		docs.FieldCommon("google_oidc",
+			"Verify the OIDC tokens attached to push requests by Google Cloud services such as Cloud Tasks, Cloud Scheduler and Pub/Sub push subscriptions.",
+		).WithChildren(
+			docs.FieldCommon(
+				"enabled", "Whether to verify Google OIDC tokens.",
+			).HasType(docs.FieldBool).HasDefault(false),
+			docs.FieldCommon(
+				"audience", "The expected audience of tokens, which is usually the URL of this endpoint. This is required when verification is enabled, as otherwise tokens issued by Google for any other audience would be accepted.",
+			).HasType(docs.FieldString).HasDefault(""),
+			docs.FieldCommon(
+				"service_account_emails", "An optional list of service account emails that tokens must have been issued to.",
+			).Array().HasType(docs.FieldString).HasDefault([]string{}),
+			docs.FieldAdvanced(
+				"certs_url", "The URL of the JSON Web Key Set used to verify token signatures.",
+			).HasType(docs.FieldString).HasDefault("https://www.googleapis.com/oauth2/v3/certs"),
+		),
+		docs.FieldCommon("aws_sns",
+			"Verify the signatures of AWS SNS HTTP(S) deliveries, and optionally confirm subscriptions automatically.",
+		).WithChildren(
+			docs.FieldCommon(
+				"enabled", "Whether to verify AWS SNS message signatures.",
+			).HasType(docs.FieldBool).HasDefault(false),
+			docs.FieldCommon(
+				"topic_arns", "An optional list of topic ARNs that messages must originate from.",
+			).Array().HasType(docs.FieldString).HasDefault([]string{}),
+			docs.FieldCommon(
+				"confirm_subscriptions", "Whether to automatically confirm subscription requests. Subscription and unsubscribe confirmations are consumed by the server and are not passed downstream when enabled.",
+			).HasType(docs.FieldBool).HasDefault(true),
+		),
+	)
+}
+
+// AuthFieldSpec returns a field spec for server side authentication.
+func AuthFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("auth",
+		"Allows you to restrict access to the server. When `allowed_cidrs` is set requests must originate from an allowed network, and when one or more authentication methods are enabled requests must satisfy at least one of them.",
+	).WithChildren(
+		docs.FieldCommon("basic_auth", "Authenticate requests with basic authentication.").WithChildren(
+			docs.FieldCommon(
+				"enabled", "Whether to accept basic authentication.",
+			).HasType(docs.FieldBool).HasDefault(false),
+			docs.FieldCommon(
+				"username", "The username that requests must provide.",
+			).HasType(docs.FieldString).HasDefault(""),
+			docs.FieldCommon(
+				"password", "The password that requests must provide.",
+			).HasType(docs.FieldString).HasDefault(""),
+			docs.FieldAdvanced(
+				"realm", "The realm returned to clients that fail authentication.",
+			).HasType(docs.FieldString).HasDefault("restricted"),
+		),
+		docs.FieldCommon("api_keys", "Authenticate requests with a static list of API keys.").WithChildren(
+			docs.FieldCommon(
+				"enabled", "Whether to accept API keys.",
+			).HasType(docs.FieldBool).HasDefault(false),
+			docs.FieldCommon(
+				"header", "The header that API keys are read from.",
+			).HasType(docs.FieldString).HasDefault("X-API-Key"),
+			docs.FieldCommon(
+				"keys", "A list of accepted API keys.",
+			).Array().HasType(docs.FieldString).HasDefault([]string{}),
+		),
+		docs.FieldCommon("jwt", "Authenticate requests with a bearer JWT signed by a key from a JSON Web Key Set.").WithChildren(
+			docs.FieldCommon(
+				"enabled", "Whether to accept JWTs.",
+			).HasType(docs.FieldBool).HasDefault(false),
+			docs.FieldCommon(
+				"jwks_url", "The URL of a JSON Web Key Set used to verify token signatures.",
+				"https://example.auth0.com/.well-known/jwks.json",
+			).HasType(docs.FieldString).HasDefault(""),
+			docs.FieldCommon(
+				"issuer", "The expected issuer of tokens. When empty the issuer is not checked.",
+			).HasType(docs.FieldString).HasDefault(""),
+			docs.FieldCommon(
+				"audience", "The expected audience of tokens. When empty the audience is not checked.",
+			).HasType(docs.FieldString).HasDefault(""),
+		),
+		docs.FieldCommon("mtls", "Authenticate requests with a TLS client certificate signed by a trusted certificate authority. Requires the server to be serving HTTPS with a `cert_file` and `key_file`.").WithChildren(
+			docs.FieldCommon(
+				"enabled", "Whether to accept client certificates.",
+			).HasType(docs.FieldBool).HasDefault(false),
+			docs.FieldCommon(
+				"client_ca_file", "The path of a PEM file containing the certificate authorities used to verify client certificates.",
+				"./certs/client_ca.pem",
+			).HasType(docs.FieldString).HasDefault(""),
+			docs.FieldCommon(
+				"allowed_common_names", "An optional list of subject common names that client certificates must match. When empty any certificate signed by a trusted authority is accepted.",
+				[]string{"benthos-client"},
+			).Array().HasType(docs.FieldString).HasDefault([]string{}),
+		),
+		docs.FieldCommon(
+			"allowed_cidrs", "An optional list of networks that requests must originate from.",
+			[]string{"10.0.0.0/8", "127.0.0.1/32"},
+		).Array().HasType(docs.FieldString).HasDefault([]string{}),
+		docs.FieldAdvanced(
+			"exempt_paths", "A list of request paths that are exempt from authentication, which is useful for liveness and readiness probes.",
+			[]string{"/ping", "/ready"},
+		).Array().HasType(docs.FieldString).HasDefault([]string{}),
+	)
+}
+
+// CORSFieldSpec returns a field spec for cross-origin resource sharing.
+func CORSFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("cors",
+		"Adds cross-origin resource sharing (CORS) headers to responses for requests from allowed origins, and responds to preflight requests.",
+	).WithChildren(
+		docs.FieldCommon(
+			"enabled", "Whether to handle CORS requests.",
+		).HasType(docs.FieldBool).HasDefault(false),
+		docs.FieldCommon(
+			"allowed_origins", "A list of origins that are allowed to make requests, where `*` allows all origins.",
+			[]string{"https://example.com"}, []string{"*"},
+		).Array().HasType(docs.FieldString).HasDefault([]string{}),
+		docs.FieldCommon(
+			"allowed_headers", "A list of request headers that are allowed in preflight requests.",
+			[]string{"Content-Type", "Authorization"},
+		).Array().HasType(docs.FieldString).HasDefault([]string{}),
+		docs.FieldAdvanced(
+			"allowed_methods", "A list of methods that are allowed in preflight requests.",
+		).Array().HasType(docs.FieldString).HasDefault([]string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}),
+		docs.FieldAdvanced(
+			"max_age", "The number of seconds that preflight responses can be cached by clients, zero omits the header.",
+		).HasType(docs.FieldInt).HasDefault(0),
+	)
+}
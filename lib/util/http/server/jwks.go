@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// jwksCache fetches RSA public keys from a JSON Web Key Set URL and caches
+// them by key ID. The key set is refreshed periodically, and also whenever a
+// key ID is requested that isn't currently cached (at most once per
+// jwksMinRefresh).
+type jwksCache struct {
+	url    string
+	client *http.Client
+
+	keys        map[string]*rsa.PublicKey
+	lastFetched time.Time
+	mut         sync.Mutex
+}
+
+const (
+	jwksMaxAge     = time.Hour
+	jwksMinRefresh = time.Second * 30
+)
+
+func newJWKSCache(url string, client *http.Client) *jwksCache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &jwksCache{
+		url:    url,
+		client: client,
+		keys:   map[string]*rsa.PublicKey{},
+	}
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (j *jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	if j.Kty != "RSA" {
+		return nil, fmt.Errorf("key type %v not supported", j.Kty)
+	}
+	nBytes, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+func (c *jwksCache) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch key set: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch key set: status code %v", res.StatusCode)
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&keySet); err != nil {
+		return fmt.Errorf("failed to parse key set: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(keySet.Keys))
+	for _, k := range keySet.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pubKey, err := k.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to parse key '%v': %w", k.Kid, err)
+		}
+		keys[k.Kid] = pubKey
+	}
+	c.keys = keys
+	return nil
+}
+
+// Get returns the public key identified by a key ID.
+func (c *jwksCache) Get(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	since := time.Since(c.lastFetched)
+	if key, exists := c.keys[kid]; exists && since < jwksMaxAge {
+		return key, nil
+	}
+	if since >= jwksMinRefresh {
+		c.lastFetched = time.Now()
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if key, exists := c.keys[kid]; exists {
+		return key, nil
+	}
+	return nil, errors.New("signing key not recognised")
+}
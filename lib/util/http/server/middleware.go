@@ -0,0 +1,287 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// CORSConfig contains configuration fields for handling cross-origin resource
+// sharing requests.
+type CORSConfig struct {
+	Enabled        bool     `json:"enabled" yaml:"enabled"`
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+	AllowedHeaders []string `json:"allowed_headers" yaml:"allowed_headers"`
+	AllowedMethods []string `json:"allowed_methods" yaml:"allowed_methods"`
+	MaxAge         int      `json:"max_age" yaml:"max_age"`
+}
+
+// NewCORSConfig returns a CORSConfig with default values.
+func NewCORSConfig() CORSConfig {
+	return CORSConfig{
+		Enabled:        false,
+		AllowedOrigins: []string{},
+		AllowedHeaders: []string{},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		MaxAge:         0,
+	}
+}
+
+// CORSHandler wraps an http.HandlerFunc with cross-origin resource sharing
+// support. Preflight requests from allowed origins are responded to directly
+// and never reach the wrapped handler.
+func CORSHandler(conf CORSConfig, fn http.HandlerFunc) http.HandlerFunc {
+	if !conf.Enabled {
+		return fn
+	}
+
+	allowAll := false
+	origins := map[string]struct{}{}
+	for _, o := range conf.AllowedOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+		origins[o] = struct{}{}
+	}
+	methods := strings.Join(conf.AllowedMethods, ", ")
+	headers := strings.Join(conf.AllowedHeaders, ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			fn(w, r)
+			return
+		}
+
+		_, allowed := origins[origin]
+		if !allowed && !allowAll {
+			fn(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if allowAll {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if methods != "" {
+				h.Set("Access-Control-Allow-Methods", methods)
+			}
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			} else if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" && allowAll {
+				h.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			if conf.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(conf.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// IsBodyTooLarge returns true if an error was returned from reading a request
+// body beyond the limit of a MaxBodySizeHandler.
+func IsBodyTooLarge(err error) bool {
+	// The error returned by http.MaxBytesReader isn't exported.
+	return err != nil && strings.Contains(err.Error(), "request body too large")
+}
+
+// MaxBodySizeHandler wraps an http.HandlerFunc with a limit on the size of
+// request bodies. Requests that declare a content length beyond the limit are
+// rejected with a 413 status code, and reading beyond the limit of other
+// requests results in an error that satisfies IsBodyTooLarge.
+func MaxBodySizeHandler(size int64, fn http.HandlerFunc) http.HandlerFunc {
+	if size <= 0 {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > size {
+			http.Error(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, size)
+		}
+		fn(w, r)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TimeoutHandler wraps an http.HandlerFunc with a timeout, where requests that
+// take longer than the timeout to begin a response are responded to with a 503
+// status code and have their context cancelled. Once a handler has begun a
+// response, by writing, flushing or hijacking the connection, the timeout no
+// longer applies, which allows streamed responses to remain open. Websocket
+// upgrade requests are exempt from the timeout as their connections are
+// expected to be long lived.
+func TimeoutHandler(timeout time.Duration, fn http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			fn(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		tw := &timeoutWriter{w: w, h: make(http.Header)}
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+					return
+				}
+				close(done)
+			}()
+			fn(tw, r.WithContext(ctx))
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			tw.finish()
+			return
+		case <-timer.C:
+		}
+
+		if tw.timeout() {
+			cancel()
+			return
+		}
+
+		// The response has already begun and therefore we wait for the handler
+		// to finish it.
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+		}
+	}
+}
+
+// timeoutWriter is an http.ResponseWriter that passes writes directly to an
+// underlying writer, unless a timeout occurs before a response has begun, in
+// which case all subsequent writes are rejected.
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mut      sync.Mutex
+	started  bool
+	timedOut bool
+}
+
+// start begins the response by copying headers to the underlying writer,
+// returns false if the response has already timed out. Must be called with
+// the lock held.
+func (t *timeoutWriter) start() bool {
+	if t.timedOut {
+		return false
+	}
+	if !t.started {
+		t.started = true
+		dst := t.w.Header()
+		for k, v := range t.h {
+			dst[k] = v
+		}
+	}
+	return true
+}
+
+// timeout attempts to respond with a timeout error, returns false if the
+// response has already begun.
+func (t *timeoutWriter) timeout() bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.started {
+		return false
+	}
+	t.timedOut = true
+	http.Error(t.w, "Request timed out", http.StatusServiceUnavailable)
+	return true
+}
+
+// finish ensures that headers set by a handler that never began a response
+// are still sent.
+func (t *timeoutWriter) finish() {
+	t.mut.Lock()
+	t.start()
+	t.mut.Unlock()
+}
+
+func (t *timeoutWriter) Header() http.Header {
+	return t.h
+}
+
+func (t *timeoutWriter) Write(b []byte) (int, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if !t.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return t.w.Write(b)
+}
+
+func (t *timeoutWriter) WriteHeader(code int) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.start() {
+		t.w.WriteHeader(code)
+	}
+}
+
+// Flush sends any buffered data to the client, which begins the response.
+func (t *timeoutWriter) Flush() {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if !t.start() {
+		return
+	}
+	if f, ok := t.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, which begins the response.
+func (t *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	h, ok := t.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying response writer does not support hijacking")
+	}
+	t.started = true
+	return h.Hijack()
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSHandler(t *testing.T) {
+	conf := NewCORSConfig()
+	conf.Enabled = true
+	conf.AllowedOrigins = []string{"https://foo.example.com"}
+	conf.AllowedHeaders = []string{"Content-Type"}
+	conf.MaxAge = 60
+
+	reached := 0
+	hdlr := CORSHandler(conf, func(w http.ResponseWriter, r *http.Request) {
+		reached++
+		w.Write([]byte("ok"))
+	})
+
+	req := httptest.NewRequest("POST", "/post", nil)
+	req.Header.Set("Origin", "https://foo.example.com")
+	rec := httptest.NewRecorder()
+	hdlr(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://foo.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, 1, reached)
+
+	req = httptest.NewRequest("POST", "/post", nil)
+	req.Header.Set("Origin", "https://bar.example.com")
+	rec = httptest.NewRecorder()
+	hdlr(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, 2, reached)
+
+	req = httptest.NewRequest("OPTIONS", "/post", nil)
+	req.Header.Set("Origin", "https://foo.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	hdlr(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://foo.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, POST, PUT, PATCH, DELETE", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "60", rec.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, 2, reached)
+}
+
+func TestCORSHandlerWildcard(t *testing.T) {
+	conf := NewCORSConfig()
+	conf.Enabled = true
+	conf.AllowedOrigins = []string{"*"}
+
+	hdlr := CORSHandler(conf, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	req := httptest.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Origin", "https://anything.example.com")
+	rec := httptest.NewRecorder()
+	hdlr(rec, req)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestMaxBodySizeHandler(t *testing.T) {
+	var readErr error
+	hdlr := MaxBodySizeHandler(5, func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = ioutil.ReadAll(r.Body)
+		if readErr != nil {
+			http.Error(w, readErr.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	hdlr(rec, httptest.NewRequest("POST", "/post", strings.NewReader("hello")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, readErr)
+
+	rec = httptest.NewRecorder()
+	hdlr(rec, httptest.NewRequest("POST", "/post", strings.NewReader("hello world")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	req := httptest.NewRequest("POST", "/post", ioutil.NopCloser(strings.NewReader("hello world")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	hdlr(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.True(t, IsBodyTooLarge(readErr))
+}
+
+func TestTimeoutHandler(t *testing.T) {
+	hdlr := TimeoutHandler(time.Millisecond*10, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	hdlr(rec, httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestTimeoutHandlerHeaders(t *testing.T) {
+	hdlr := TimeoutHandler(time.Second, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Foo", "bar")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	hdlr(rec, httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "bar", rec.Header().Get("X-Foo"))
+	assert.Equal(t, "ok", rec.Body.String())
+}
+
+func TestTimeoutHandlerFlushed(t *testing.T) {
+	server := httptest.NewServer(TimeoutHandler(time.Millisecond*50, func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("response writer is not a flusher")
+			return
+		}
+
+		w.Write([]byte("foo\n"))
+		flusher.Flush()
+
+		select {
+		case <-time.After(time.Millisecond * 200):
+		case <-r.Context().Done():
+			t.Error("context cancelled after response began")
+		}
+		w.Write([]byte("bar\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	res, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "foo\nbar\n", string(body))
+}
+
+func TestTimeoutHandlerLateWrite(t *testing.T) {
+	writeErrChan := make(chan error, 1)
+	hdlr := TimeoutHandler(time.Millisecond*10, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		_, err := w.Write([]byte("ok"))
+		writeErrChan <- err
+	})
+
+	rec := httptest.NewRecorder()
+	hdlr(rec, httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, http.ErrHandlerTimeout, <-writeErrChan)
+	assert.Equal(t, "Request timed out\n", rec.Body.String())
+}
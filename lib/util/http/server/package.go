@@ -0,0 +1,3 @@
+// Package server provides configuration fields and implementations of HTTP
+// server middleware shared by components that host HTTP endpoints.
+package server
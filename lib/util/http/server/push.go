@@ -0,0 +1,412 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/dgrijalva/jwt-go"
+)
+
+//------------------------------------------------------------------------------
+
+// GoogleOIDCConfig contains configuration fields for verifying OIDC tokens
+// attached to push requests by Google Cloud services such as Cloud Tasks,
+// Cloud Scheduler and Pub/Sub push subscriptions.
+type GoogleOIDCConfig struct {
+	Enabled              bool     `json:"enabled" yaml:"enabled"`
+	Audience             string   `json:"audience" yaml:"audience"`
+	ServiceAccountEmails []string `json:"service_account_emails" yaml:"service_account_emails"`
+	CertsURL             string   `json:"certs_url" yaml:"certs_url"`
+}
+
+// NewGoogleOIDCConfig returns a GoogleOIDCConfig with default values.
+func NewGoogleOIDCConfig() GoogleOIDCConfig {
+	return GoogleOIDCConfig{
+		Enabled:              false,
+		Audience:             "",
+		ServiceAccountEmails: []string{},
+		CertsURL:             "https://www.googleapis.com/oauth2/v3/certs",
+	}
+}
+
+// AWSSNSConfig contains configuration fields for verifying the signatures of
+// AWS SNS HTTP(S) deliveries.
+type AWSSNSConfig struct {
+	Enabled              bool     `json:"enabled" yaml:"enabled"`
+	TopicARNs            []string `json:"topic_arns" yaml:"topic_arns"`
+	ConfirmSubscriptions bool     `json:"confirm_subscriptions" yaml:"confirm_subscriptions"`
+}
+
+// NewAWSSNSConfig returns an AWSSNSConfig with default values.
+func NewAWSSNSConfig() AWSSNSConfig {
+	return AWSSNSConfig{
+		Enabled:              false,
+		TopicARNs:            []string{},
+		ConfirmSubscriptions: true,
+	}
+}
+
+// PushVerifyConfig contains configuration fields for verifying requests pushed
+// to an HTTP server by cloud services.
+type PushVerifyConfig struct {
+	GoogleOIDC GoogleOIDCConfig `json:"google_oidc" yaml:"google_oidc"`
+	AWSSNS     AWSSNSConfig     `json:"aws_sns" yaml:"aws_sns"`
+}
+
+// NewPushVerifyConfig returns a PushVerifyConfig with default values.
+func NewPushVerifyConfig() PushVerifyConfig {
+	return PushVerifyConfig{
+		GoogleOIDC: NewGoogleOIDCConfig(),
+		AWSSNS:     NewAWSSNSConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	googleIssuers   = []string{"https://accounts.google.com", "accounts.google.com"}
+	snsCertHostExpr = regexp.MustCompile(`^sns\.[a-z0-9\-]+\.amazonaws\.com(\.cn)?$`)
+)
+
+// PushVerifier wraps HTTP handlers with verification of the requests pushed to
+// them by cloud services.
+type PushVerifier struct {
+	conf PushVerifyConfig
+
+	googleKeys   *jwksCache
+	googleEmails map[string]struct{}
+
+	snsTopics   map[string]struct{}
+	snsHostExpr *regexp.Regexp
+	snsCerts    map[string]*x509.Certificate
+	snsCertsMut sync.Mutex
+
+	client *http.Client
+	log    log.Modular
+
+	mRejected  metrics.StatCounter
+	mConfirmed metrics.StatCounter
+}
+
+// NewPushVerifier creates a new push verifier from a config.
+func NewPushVerifier(conf PushVerifyConfig, log log.Modular, stats metrics.Type) (*PushVerifier, error) {
+	p := &PushVerifier{
+		conf:         conf,
+		googleEmails: map[string]struct{}{},
+		snsTopics:    map[string]struct{}{},
+		snsHostExpr:  snsCertHostExpr,
+		snsCerts:     map[string]*x509.Certificate{},
+		client:       &http.Client{Timeout: time.Second * 10},
+		log:          log,
+		mRejected:    stats.GetCounter("push_verification.rejected"),
+		mConfirmed:   stats.GetCounter("push_verification.confirmed"),
+	}
+	if conf.GoogleOIDC.Enabled {
+		if conf.GoogleOIDC.CertsURL == "" {
+			return nil, errors.New("a certs_url must be specified for google_oidc verification")
+		}
+		if conf.GoogleOIDC.Audience == "" {
+			return nil, errors.New("an audience must be specified for google_oidc verification")
+		}
+		p.googleKeys = newJWKSCache(conf.GoogleOIDC.CertsURL, p.client)
+		for _, e := range conf.GoogleOIDC.ServiceAccountEmails {
+			p.googleEmails[e] = struct{}{}
+		}
+	}
+	for _, t := range conf.AWSSNS.TopicARNs {
+		p.snsTopics[t] = struct{}{}
+	}
+	return p, nil
+}
+
+// Enabled returns true if any form of verification is enabled.
+func (p *PushVerifier) Enabled() bool {
+	return p.conf.GoogleOIDC.Enabled || p.conf.AWSSNS.Enabled
+}
+
+// WrapHandler returns a handler that verifies requests before passing them on
+// to the wrapped handler. Requests that fail verification are rejected with a
+// 401 status code. AWS SNS subscription confirmation requests are handled by
+// the verifier and never reach the wrapped handler.
+func (p *PushVerifier) WrapHandler(fn http.HandlerFunc) http.HandlerFunc {
+	if !p.Enabled() {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.conf.GoogleOIDC.Enabled {
+			if err := p.verifyGoogleOIDC(r); err != nil {
+				p.log.Debugf("Rejecting request due to failed OIDC token verification: %v\n", err)
+				p.mRejected.Incr(1)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		if p.conf.AWSSNS.Enabled {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+			r.Body.Close()
+
+			handled, err := p.verifySNS(r.Context(), body)
+			if err != nil {
+				p.log.Debugf("Rejecting request due to failed SNS signature verification: %v\n", err)
+				p.mRejected.Incr(1)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if handled {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		fn(w, r)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (p *PushVerifier) verifyGoogleOIDC(r *http.Request) error {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return errors.New("missing bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(strings.TrimPrefix(authHeader, "Bearer "), claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.googleKeys.Get(r.Context(), kid)
+	}); err != nil {
+		return err
+	}
+
+	validIssuer := false
+	for _, iss := range googleIssuers {
+		if claims.VerifyIssuer(iss, true) {
+			validIssuer = true
+			break
+		}
+	}
+	if !validIssuer {
+		return fmt.Errorf("unexpected token issuer: %v", claims["iss"])
+	}
+	if !claims.VerifyAudience(p.conf.GoogleOIDC.Audience, true) {
+		return fmt.Errorf("unexpected token audience: %v", claims["aud"])
+	}
+	if len(p.googleEmails) > 0 {
+		email, _ := claims["email"].(string)
+		if _, exists := p.googleEmails[email]; !exists {
+			return fmt.Errorf("unexpected token email: %v", email)
+		}
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return errors.New("token email is not verified")
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+func (m *snsMessage) stringToSign() string {
+	var b strings.Builder
+	add := func(k, v string) {
+		b.WriteString(k)
+		b.WriteByte('\n')
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+	add("Message", m.Message)
+	add("MessageId", m.MessageID)
+	if m.Type == "Notification" {
+		if m.Subject != "" {
+			add("Subject", m.Subject)
+		}
+		add("Timestamp", m.Timestamp)
+		add("TopicArn", m.TopicArn)
+		add("Type", m.Type)
+	} else {
+		add("SubscribeURL", m.SubscribeURL)
+		add("Timestamp", m.Timestamp)
+		add("Token", m.Token)
+		add("TopicArn", m.TopicArn)
+		add("Type", m.Type)
+	}
+	return b.String()
+}
+
+func (p *PushVerifier) checkSNSURL(urlStr string) error {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("url scheme %v is not https", u.Scheme)
+	}
+	if !p.snsHostExpr.MatchString(u.Hostname()) {
+		return fmt.Errorf("url host %v is not an SNS endpoint", u.Hostname())
+	}
+	return nil
+}
+
+func (p *PushVerifier) getSNSCert(ctx context.Context, urlStr string) (*x509.Certificate, error) {
+	p.snsCertsMut.Lock()
+	defer p.snsCertsMut.Unlock()
+
+	if cert, exists := p.snsCerts[urlStr]; exists {
+		return cert, nil
+	}
+	if err := p.checkSNSURL(urlStr); err != nil {
+		return nil, fmt.Errorf("invalid signing cert url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing cert: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch signing cert: status code %v", res.StatusCode)
+	}
+	certBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing cert: %w", err)
+	}
+
+	block, _ := pem.Decode(certBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode signing cert")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing cert: %w", err)
+	}
+	p.snsCerts[urlStr] = cert
+	return cert, nil
+}
+
+// verifySNS checks the signature of an SNS message and returns true if the
+// message was a subscription control message that has been fully handled.
+func (p *PushVerifier) verifySNS(ctx context.Context, body []byte) (bool, error) {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return false, fmt.Errorf("failed to parse message: %w", err)
+	}
+	if len(p.snsTopics) > 0 {
+		if _, exists := p.snsTopics[msg.TopicArn]; !exists {
+			return false, fmt.Errorf("unexpected topic arn: %v", msg.TopicArn)
+		}
+	}
+
+	var hash crypto.Hash
+	switch msg.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return false, fmt.Errorf("signature version %v not supported", msg.SignatureVersion)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	cert, err := p.getSNSCert(ctx, msg.SigningCertURL)
+	if err != nil {
+		return false, err
+	}
+	pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return false, errors.New("signing cert does not contain an RSA public key")
+	}
+
+	var digest []byte
+	if hash == crypto.SHA1 {
+		h := sha1.Sum([]byte(msg.stringToSign()))
+		digest = h[:]
+	} else {
+		h := sha256.Sum256([]byte(msg.stringToSign()))
+		digest = h[:]
+	}
+	if err := rsa.VerifyPKCS1v15(pubKey, hash, digest, sig); err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		if !p.conf.AWSSNS.ConfirmSubscriptions {
+			return false, nil
+		}
+		if err := p.confirmSNSSubscription(ctx, msg.SubscribeURL); err != nil {
+			return false, err
+		}
+		p.mConfirmed.Incr(1)
+		p.log.Infof("Confirmed SNS subscription to topic: %v\n", msg.TopicArn)
+		return true, nil
+	case "UnsubscribeConfirmation":
+		return p.conf.AWSSNS.ConfirmSubscriptions, nil
+	}
+	return false, nil
+}
+
+func (p *PushVerifier) confirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	if err := p.checkSNSURL(subscribeURL); err != nil {
+		return fmt.Errorf("invalid subscribe url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", subscribeURL, nil)
+	if err != nil {
+		return err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to confirm subscription: status code %v", res.StatusCode)
+	}
+	return nil
+}
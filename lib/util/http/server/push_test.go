@@ -0,0 +1,261 @@
+package server
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jwksServer(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []interface{}{
+				map[string]interface{}{
+					"kid": kid,
+					"kty": "RSA",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestPushVerifyGoogleOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	conf := NewPushVerifyConfig()
+	conf.GoogleOIDC.Enabled = true
+	conf.GoogleOIDC.Audience = "https://example.com/post"
+	conf.GoogleOIDC.ServiceAccountEmails = []string{"foo@example.iam.gserviceaccount.com"}
+	conf.GoogleOIDC.CertsURL = jwksServer(t, "keyone", &key.PublicKey)
+
+	v, err := NewPushVerifier(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	hdlr := v.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	sign := func(kid string, claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = kid
+		str, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return str
+	}
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            "https://accounts.google.com",
+			"aud":            "https://example.com/post",
+			"email":          "foo@example.iam.gserviceaccount.com",
+			"email_verified": true,
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		token  func() string
+		status int
+	}{
+		{
+			name:   "valid token",
+			token:  func() string { return sign("keyone", validClaims()) },
+			status: http.StatusOK,
+		},
+		{
+			name:   "missing token",
+			token:  func() string { return "" },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown key",
+			token:  func() string { return sign("keytwo", validClaims()) },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := validClaims()
+				c["aud"] = "https://example.com/other"
+				return sign("keyone", c)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				c := validClaims()
+				c["iss"] = "https://example.com"
+				return sign("keyone", c)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong email",
+			token: func() string {
+				c := validClaims()
+				c["email"] = "bar@example.iam.gserviceaccount.com"
+				return sign("keyone", c)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				c := validClaims()
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+				return sign("keyone", c)
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/post", strings.NewReader("hello"))
+			if tok := test.token(); tok != "" {
+				req.Header.Set("Authorization", "Bearer "+tok)
+			}
+			rec := httptest.NewRecorder()
+			hdlr(rec, req)
+			assert.Equal(t, test.status, rec.Code)
+		})
+	}
+}
+
+func TestPushVerifyAWSSNS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	certTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &key.PublicKey, key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	confirmed := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cert.pem":
+			w.Write(certPEM)
+		case "/confirm":
+			confirmed++
+			w.Write([]byte("<ConfirmSubscriptionResponse/>"))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	conf := NewPushVerifyConfig()
+	conf.AWSSNS.Enabled = true
+	conf.AWSSNS.TopicARNs = []string{"arn:aws:sns:us-east-1:123456789012:foo"}
+
+	v, err := NewPushVerifier(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	v.client = ts.Client()
+	v.snsHostExpr = regexp.MustCompile(`.*`)
+
+	var received []string
+	hdlr := v.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(b))
+	})
+
+	signed := func(m snsMessage) []byte {
+		m.SignatureVersion = "2"
+		m.SigningCertURL = ts.URL + "/cert.pem"
+		digest := sha256.Sum256([]byte(m.stringToSign()))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		m.Signature = base64.StdEncoding.EncodeToString(sig)
+		b, err := json.Marshal(m)
+		require.NoError(t, err)
+		return b
+	}
+
+	send := func(body []byte) int {
+		rec := httptest.NewRecorder()
+		hdlr(rec, httptest.NewRequest("POST", "/post", strings.NewReader(string(body))))
+		return rec.Code
+	}
+
+	confirmMsg := signed(snsMessage{
+		Type:         "SubscriptionConfirmation",
+		MessageID:    "1",
+		Token:        "footoken",
+		TopicArn:     "arn:aws:sns:us-east-1:123456789012:foo",
+		Message:      "You have chosen to subscribe",
+		SubscribeURL: ts.URL + "/confirm",
+		Timestamp:    "2021-05-20T10:00:00.000Z",
+	})
+	assert.Equal(t, http.StatusOK, send(confirmMsg))
+	assert.Equal(t, 1, confirmed)
+	assert.Empty(t, received)
+
+	notifMsg := signed(snsMessage{
+		Type:      "Notification",
+		MessageID: "2",
+		TopicArn:  "arn:aws:sns:us-east-1:123456789012:foo",
+		Subject:   "hello",
+		Message:   `{"foo":"bar"}`,
+		Timestamp: "2021-05-20T10:00:01.000Z",
+	})
+	assert.Equal(t, http.StatusOK, send(notifMsg))
+	assert.Equal(t, []string{string(notifMsg)}, received)
+
+	tampered := strings.Replace(string(notifMsg), `bar`, `baz`, 1)
+	assert.Equal(t, http.StatusUnauthorized, send([]byte(tampered)))
+
+	wrongTopic := signed(snsMessage{
+		Type:      "Notification",
+		MessageID: "3",
+		TopicArn:  "arn:aws:sns:us-east-1:123456789012:bar",
+		Message:   `{"foo":"bar"}`,
+		Timestamp: "2021-05-20T10:00:02.000Z",
+	})
+	assert.Equal(t, http.StatusUnauthorized, send(wrongTopic))
+	assert.Len(t, received, 1)
+}
+
+func TestPushVerifyGoogleOIDCNoAudience(t *testing.T) {
+	conf := NewPushVerifyConfig()
+	conf.GoogleOIDC.Enabled = true
+
+	_, err := NewPushVerifier(conf, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "an audience must be specified for google_oidc verification")
+}
@@ -0,0 +1,164 @@
+package sasl
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Shopify/sarama"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// AWSMSKIAMConfig contains configuration for obtaining SASL OAUTHBEARER tokens
+// for Amazon MSK clusters with IAM access control.
+type AWSMSKIAMConfig struct {
+	Enabled     bool `json:"enabled" yaml:"enabled"`
+	sess.Config `json:",inline" yaml:",inline"`
+}
+
+// NewAWSMSKIAMConfig returns a new AWSMSKIAMConfig with default values.
+func NewAWSMSKIAMConfig() AWSMSKIAMConfig {
+	return AWSMSKIAMConfig{
+		Enabled: false,
+		Config:  sess.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// fetchTokenFn obtains a new access token along with the time at which it
+// expires, which is zero for tokens that don't expire.
+type fetchTokenFn func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// fetchTokenTimeout is the maximum period to wait for a new access token.
+const fetchTokenTimeout = time.Second * 30
+
+// maxRefreshMargin is the maximum period before a token expires at which it is
+// refreshed.
+const maxRefreshMargin = time.Minute
+
+// refreshingAccessTokenProvider caches SASL OAUTHBEARER access tokens obtained
+// from a token provider, and obtains new tokens as they approach expiry. Since
+// sarama obtains a token each time a broker connection is authenticated this
+// ensures that reconnections are authenticated with valid tokens.
+type refreshingAccessTokenProvider struct {
+	fetch      fetchTokenFn
+	extensions map[string]string
+	now        func() time.Time
+
+	mut       sync.Mutex
+	token     string
+	expiry    time.Time
+	refreshAt time.Time
+}
+
+func newRefreshingAccessTokenProvider(fetch fetchTokenFn, extensions map[string]string) *refreshingAccessTokenProvider {
+	return &refreshingAccessTokenProvider{
+		fetch:      fetch,
+		extensions: extensions,
+		now:        time.Now,
+	}
+}
+
+func (r *refreshingAccessTokenProvider) Token() (*sarama.AccessToken, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := r.now()
+	if r.token == "" || (!r.refreshAt.IsZero() && !now.Before(r.refreshAt)) {
+		ctx, done := context.WithTimeout(context.Background(), fetchTokenTimeout)
+		token, expiry, err := r.fetch(ctx)
+		done()
+		if err != nil {
+			// The current token can still be used until it actually expires.
+			if r.token == "" || !now.Before(r.expiry) {
+				return nil, fmt.Errorf("failed to obtain access token: %w", err)
+			}
+		} else {
+			r.token, r.expiry, r.refreshAt = token, expiry, time.Time{}
+			if !expiry.IsZero() {
+				margin := expiry.Sub(now) / 5
+				if margin > maxRefreshMargin {
+					margin = maxRefreshMargin
+				}
+				r.refreshAt = expiry.Add(-margin)
+			}
+		}
+	}
+	return &sarama.AccessToken{Token: r.token, Extensions: r.extensions}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func oauth2FetchToken(conf auth.OAuth2Config) (fetchTokenFn, error) {
+	if conf.TokenURL == "" {
+		return nil, errors.New("an oauth2 token_url must be specified")
+	}
+	ccConf := &clientcredentials.Config{
+		ClientID:     conf.ClientKey,
+		ClientSecret: conf.ClientSecret,
+		TokenURL:     conf.TokenURL,
+		Scopes:       conf.Scopes,
+	}
+	return func(ctx context.Context) (string, time.Time, error) {
+		tok, err := ccConf.Token(ctx)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return tok.AccessToken, tok.Expiry, nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	mskIAMService     = "kafka-cluster"
+	mskIAMAction      = "kafka-cluster:Connect"
+	mskIAMTokenExpiry = time.Minute * 15
+	mskIAMUserAgent   = "benthos"
+)
+
+func awsMSKIAMFetchToken(conf AWSMSKIAMConfig) (fetchTokenFn, error) {
+	if conf.Region == "" {
+		return nil, errors.New("an aws_msk_iam region must be specified")
+	}
+	awsSess, err := conf.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	creds := awsSess.Config.Credentials
+	return func(ctx context.Context) (string, time.Time, error) {
+		return mskIAMToken(creds, conf.Region, time.Now())
+	}, nil
+}
+
+// mskIAMToken creates an MSK IAM access token, which is the base64 encoding of
+// a URL presigned for the kafka-cluster:Connect action.
+func mskIAMToken(creds *credentials.Credentials, region string, now time.Time) (string, time.Time, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://kafka.%v.amazonaws.com/", region), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	query := req.URL.Query()
+	query.Set("Action", mskIAMAction)
+	req.URL.RawQuery = query.Encode()
+
+	if _, err = v4.NewSigner(creds).Presign(req, nil, mskIAMService, region, mskIAMTokenExpiry, now); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	query = req.URL.Query()
+	query.Set("User-Agent", mskIAMUserAgent)
+	req.URL.RawQuery = query.Encode()
+
+	token := base64.RawURLEncoding.EncodeToString([]byte(req.URL.String()))
+	return token, now.Add(mskIAMTokenExpiry), nil
+}
@@ -0,0 +1,150 @@
+package sasl
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshingAccessTokenProvider(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var fetches int
+	var fetchErr error
+	tp := newRefreshingAccessTokenProvider(func(ctx context.Context) (string, time.Time, error) {
+		if fetchErr != nil {
+			return "", time.Time{}, fetchErr
+		}
+		fetches++
+		return fmt.Sprintf("token%v", fetches), now.Add(time.Minute * 10), nil
+	}, map[string]string{"foo": "bar"})
+	tp.now = func() time.Time { return now }
+
+	tok, err := tp.Token()
+	require.NoError(t, err)
+	assert.Equal(t, &sarama.AccessToken{Token: "token1", Extensions: map[string]string{"foo": "bar"}}, tok)
+
+	// Refreshed a minute before expiring.
+	now = now.Add(time.Minute * 8)
+	tok, err = tp.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token1", tok.Token)
+
+	now = now.Add(time.Minute)
+	tok, err = tp.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token2", tok.Token)
+
+	// The current token is used while it's valid when refreshing fails.
+	fetchErr = errors.New("nope")
+	now = now.Add(time.Minute * 9)
+	tok, err = tp.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token2", tok.Token)
+
+	now = now.Add(time.Minute)
+	_, err = tp.Token()
+	require.EqualError(t, err, "failed to obtain access token: nope")
+
+	fetchErr = nil
+	tok, err = tp.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token3", tok.Token)
+}
+
+func TestApplyOAuth2(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "kafka", r.Form.Get("scope"))
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token%v","token_type":"bearer","expires_in":3600}`, n)
+	}))
+	defer server.Close()
+
+	conf := &sarama.Config{}
+
+	saslConf := NewConfig()
+	saslConf.Mechanism = sarama.SASLTypeOAuth
+	saslConf.OAuth2.Enabled = true
+	saslConf.OAuth2.ClientKey = "foo"
+	saslConf.OAuth2.ClientSecret = "bar"
+	saslConf.OAuth2.TokenURL = server.URL
+	saslConf.OAuth2.Scopes = []string{"kafka"}
+	saslConf.Extensions = map[string]string{"logicalCluster": "baz"}
+
+	require.NoError(t, saslConf.Apply(types.NoopMgr(), conf))
+	assert.True(t, conf.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), conf.Net.SASL.Mechanism)
+
+	for i := 0; i < 2; i++ {
+		tok, err := conf.Net.SASL.TokenProvider.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "token1", tok.Token)
+		assert.Equal(t, map[string]string{"logicalCluster": "baz"}, tok.Extensions)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	saslConf.AWSMSKIAM.Enabled = true
+	require.EqualError(t, saslConf.Apply(types.NoopMgr(), conf), "cannot enable both oauth2 and aws_msk_iam token providers")
+}
+
+func TestMSKIAMToken(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	creds := credentials.NewStaticCredentials("foo", "bar", "")
+
+	token, expiry, err := mskIAMToken(creds, "us-east-1", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute*15), expiry)
+
+	rawURL, err := base64.RawURLEncoding.DecodeString(token)
+	require.NoError(t, err)
+
+	u, err := url.Parse(string(rawURL))
+	require.NoError(t, err)
+	assert.Equal(t, "kafka.us-east-1.amazonaws.com", u.Host)
+
+	query := u.Query()
+	assert.Equal(t, "kafka-cluster:Connect", query.Get("Action"))
+	assert.Equal(t, "AWS4-HMAC-SHA256", query.Get("X-Amz-Algorithm"))
+	assert.Equal(t, "foo/20210601/us-east-1/kafka-cluster/aws4_request", query.Get("X-Amz-Credential"))
+	assert.Equal(t, "20210601T120000Z", query.Get("X-Amz-Date"))
+	assert.Equal(t, "900", query.Get("X-Amz-Expires"))
+	assert.NotEmpty(t, query.Get("X-Amz-Signature"))
+	assert.Equal(t, "benthos", query.Get("User-Agent"))
+}
+
+func TestApplyAWSMSKIAM(t *testing.T) {
+	conf := &sarama.Config{}
+
+	saslConf := NewConfig()
+	saslConf.Mechanism = sarama.SASLTypeOAuth
+	saslConf.AWSMSKIAM.Enabled = true
+	saslConf.AWSMSKIAM.Region = "eu-west-2"
+	saslConf.AWSMSKIAM.Credentials.ID = "foo"
+	saslConf.AWSMSKIAM.Credentials.Secret = "bar"
+
+	require.NoError(t, saslConf.Apply(types.NoopMgr(), conf))
+
+	tok, err := conf.Net.SASL.TokenProvider.Token()
+	require.NoError(t, err)
+
+	rawURL, err := base64.RawURLEncoding.DecodeString(tok.Token)
+	require.NoError(t, err)
+	assert.Contains(t, string(rawURL), "https://kafka.eu-west-2.amazonaws.com/?")
+}
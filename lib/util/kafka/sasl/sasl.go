@@ -8,6 +8,8 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
 	"github.com/Shopify/sarama"
 )
 
@@ -26,11 +28,19 @@ type Config struct {
 	AccessToken string `json:"access_token" yaml:"access_token"`
 	TokenCache  string `json:"token_cache" yaml:"token_cache"`
 	TokenKey    string `json:"token_key" yaml:"token_key"`
+
+	OAuth2     auth.OAuth2Config `json:"oauth2" yaml:"oauth2"`
+	AWSMSKIAM  AWSMSKIAMConfig   `json:"aws_msk_iam" yaml:"aws_msk_iam"`
+	Extensions map[string]string `json:"extensions" yaml:"extensions"`
 }
 
 // NewConfig returns a new SASL config for Kafka with default values.
 func NewConfig() Config {
-	return Config{} // Well, that seemed pointless.
+	return Config{
+		OAuth2:     auth.NewOAuth2Config(),
+		AWSMSKIAM:  NewAWSMSKIAMConfig(),
+		Extensions: map[string]string{},
+	}
 }
 
 // FieldSpec returns specs for SASL fields.
@@ -39,7 +49,7 @@ func FieldSpec() docs.FieldSpec {
 		docs.FieldDeprecated("enabled"),
 		docs.FieldCommon("mechanism", "The SASL authentication mechanism, if left empty SASL authentication is not used. Warning: SCRAM based methods within Benthos have not received a security audit.").HasAnnotatedOptions(
 			sarama.SASLTypePlaintext, "Plain text authentication.",
-			sarama.SASLTypeOAuth, "OAuth Bearer based authentication.",
+			sarama.SASLTypeOAuth, "OAuth Bearer based authentication, where tokens are either static, fetched from a cache, or obtained from an `oauth2` or `aws_msk_iam` token provider.",
 			sarama.SASLTypeSCRAMSHA256, "Authentication using the SCRAM-SHA-256 mechanism.",
 			sarama.SASLTypeSCRAMSHA512, "Authentication using the SCRAM-SHA-512 mechanism.",
 		),
@@ -48,6 +58,22 @@ func FieldSpec() docs.FieldSpec {
 		docs.FieldAdvanced("access_token", "A static `"+sarama.SASLTypeOAuth+"` access token"),
 		docs.FieldAdvanced("token_cache", "Instead of using a static `access_token` allows you to query a [`cache`](/docs/components/caches/about) resource to fetch `"+sarama.SASLTypeOAuth+"` tokens from"),
 		docs.FieldAdvanced("token_key", "Required when using a `token_cache`, the key to query the cache with for tokens."),
+		docs.FieldAdvanced("oauth2", "Obtain `"+sarama.SASLTypeOAuth+"` tokens from an OAuth2 or OpenID Connect provider using the client credentials flow. Tokens are cached and obtained again shortly before they expire.").WithChildren(
+			docs.FieldCommon("enabled", "Whether to obtain tokens from an OAuth2 provider."),
+			docs.FieldCommon("client_key", "A value used to identify the client to the token provider."),
+			docs.FieldCommon("client_secret", "A secret used to establish ownership of the client key."),
+			docs.FieldCommon("token_url", "The URL of the token provider.", "https://auth.example.com/realms/kafka/protocol/openid-connect/token"),
+			docs.FieldAdvanced("scopes", "A list of optional requested permissions.").Array(),
+		).AtVersion("3.47.0"),
+		docs.FieldAdvanced("aws_msk_iam", "Obtain `"+sarama.SASLTypeOAuth+"` tokens for Amazon MSK clusters with IAM access control, which are signed with AWS credentials. Tokens are valid for fifteen minutes and are signed again shortly before they expire.").WithChildren(
+			append(docs.FieldSpecs{
+				docs.FieldCommon("enabled", "Whether to obtain tokens for Amazon MSK IAM access control."),
+			}, sess.FieldSpecs()...)...,
+		).AtVersion("3.47.0"),
+		docs.FieldAdvanced("extensions", "A map of optional extensions to send with `"+sarama.SASLTypeOAuth+"` tokens, which some providers require in order to identify a cluster or identity pool.", map[string]string{
+			"logicalCluster": "lkc-abc123",
+			"identityPoolId": "pool-abc",
+		}).Map().AtVersion("3.47.0"),
 	)
 }
 
@@ -61,13 +87,30 @@ func (s Config) Apply(mgr types.Manager, conf *sarama.Config) error {
 		var tp sarama.AccessTokenProvider
 		var err error
 
-		if s.TokenCache != "" {
-			tp, err = newCacheAccessTokenProvider(mgr, s.TokenCache, s.TokenKey)
+		if s.OAuth2.Enabled && s.AWSMSKIAM.Enabled {
+			return errors.New("cannot enable both oauth2 and aws_msk_iam token providers")
+		}
+
+		switch {
+		case s.OAuth2.Enabled:
+			fetch, err := oauth2FetchToken(s.OAuth2)
+			if err != nil {
+				return err
+			}
+			tp = newRefreshingAccessTokenProvider(fetch, s.Extensions)
+		case s.AWSMSKIAM.Enabled:
+			fetch, err := awsMSKIAMFetchToken(s.AWSMSKIAM)
+			if err != nil {
+				return err
+			}
+			tp = newRefreshingAccessTokenProvider(fetch, s.Extensions)
+		case s.TokenCache != "":
+			tp, err = newCacheAccessTokenProvider(mgr, s.TokenCache, s.TokenKey, s.Extensions)
 			if err != nil {
 				return err
 			}
-		} else {
-			tp, err = newStaticAccessTokenProvider(s.AccessToken)
+		default:
+			tp, err = newStaticAccessTokenProvider(s.AccessToken, s.Extensions)
 			if err != nil {
 				return err
 			}
@@ -104,19 +147,21 @@ func (s Config) Apply(mgr types.Manager, conf *sarama.Config) error {
 
 // cacheAccessTokenProvider fetches SASL OAUTHBEARER access tokens from a cache.
 type cacheAccessTokenProvider struct {
-	mgr       types.Manager
-	cacheName string
-	key       string
+	mgr        types.Manager
+	cacheName  string
+	key        string
+	extensions map[string]string
 }
 
-func newCacheAccessTokenProvider(mgr types.Manager, cache, key string) (*cacheAccessTokenProvider, error) {
+func newCacheAccessTokenProvider(mgr types.Manager, cache, key string, extensions map[string]string) (*cacheAccessTokenProvider, error) {
 	if err := interop.ProbeCache(context.Background(), mgr, cache); err != nil {
 		return nil, err
 	}
 	return &cacheAccessTokenProvider{
-		mgr:       mgr,
-		cacheName: cache,
-		key:       key,
+		mgr:        mgr,
+		cacheName:  cache,
+		key:        key,
+		extensions: extensions,
 	}, nil
 }
 
@@ -131,22 +176,23 @@ func (c *cacheAccessTokenProvider) Token() (*sarama.AccessToken, error) {
 	if terr != nil {
 		return nil, terr
 	}
-	return &sarama.AccessToken{Token: string(tok)}, nil
+	return &sarama.AccessToken{Token: string(tok), Extensions: c.extensions}, nil
 }
 
 //------------------------------------------------------------------------------
 
 // staticAccessTokenProvider provides a static SASL OAUTHBEARER access token.
 type staticAccessTokenProvider struct {
-	token string
+	token      string
+	extensions map[string]string
 }
 
-func newStaticAccessTokenProvider(token string) (*staticAccessTokenProvider, error) {
-	return &staticAccessTokenProvider{token}, nil
+func newStaticAccessTokenProvider(token string, extensions map[string]string) (*staticAccessTokenProvider, error) {
+	return &staticAccessTokenProvider{token, extensions}, nil
 }
 
 func (s *staticAccessTokenProvider) Token() (*sarama.AccessToken, error) {
-	return &sarama.AccessToken{Token: s.token}, nil
+	return &sarama.AccessToken{Token: s.token, Extensions: s.extensions}, nil
 }
 
 //------------------------------------------------------------------------------
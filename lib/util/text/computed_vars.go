@@ -0,0 +1,134 @@
+package text
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/message"
+)
+
+//------------------------------------------------------------------------------
+
+var (
+	computedStart        = []byte("${=")
+	escapedComputedStart = []byte("${{=")
+)
+
+// ContainsComputedValues returns true if inBytes contains computed value
+// patterns.
+func ContainsComputedValues(inBytes []byte) bool {
+	return bytes.Contains(inBytes, computedStart) || bytes.Contains(inBytes, escapedComputedStart)
+}
+
+// ReplaceComputedValues will search a blob of data for the pattern
+// `${= <query> }`, where `<query>` is a Bloblang query that is executed once
+// and replaces the pattern with the result. Queries do not have access to a
+// message and can therefore only use functions that access the environment,
+// such as `env`, `file` and `hostname`, or generate values.
+//
+// The pattern can be escaped with `${{= <query> }}`, which is replaced with
+// `${= <query> }`. An error is returned when a query fails to parse or execute.
+func ReplaceComputedValues(inBytes []byte) ([]byte, error) {
+	if !ContainsComputedValues(inBytes) {
+		return inBytes, nil
+	}
+
+	var out bytes.Buffer
+	remaining := inBytes
+	for {
+		i := bytes.Index(remaining, []byte("${"))
+		if i == -1 {
+			out.Write(remaining)
+			break
+		}
+		out.Write(remaining[:i])
+		remaining = remaining[i:]
+
+		if bytes.HasPrefix(remaining, escapedComputedStart) {
+			if end := bytes.Index(remaining, []byte("}}")); end != -1 {
+				out.WriteString("${=")
+				out.Write(remaining[len(escapedComputedStart):end])
+				out.WriteString("}")
+				remaining = remaining[end+2:]
+				continue
+			}
+		}
+
+		if !bytes.HasPrefix(remaining, computedStart) {
+			out.WriteString("${")
+			remaining = remaining[2:]
+			continue
+		}
+
+		value, n, err := computeValue(remaining[len(computedStart):])
+		if err != nil {
+			line := bytes.Count(inBytes[:len(inBytes)-len(remaining)], []byte("\n")) + 1
+			return nil, fmt.Errorf("line %v: failed to compute value: %w", line, err)
+		}
+		out.WriteString(value)
+		remaining = remaining[len(computedStart)+n:]
+	}
+	return out.Bytes(), nil
+}
+
+// ExecEnvironmentQuery parses and executes a Bloblang query without a
+// message, and therefore only functions that access the environment or
+// generate values can be used. If the query does not assign a value the result
+// is query.Nothing.
+func ExecEnvironmentQuery(expr string) (interface{}, error) {
+	exec, err := bloblang.NewEnvironmentMapping(expr)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			return nil, fmt.Errorf("%v", perr.ErrorAtPosition([]rune(expr)))
+		}
+		return nil, err
+	}
+	return exec.Exec(query.FunctionContext{
+		Maps:     exec.Maps(),
+		Vars:     map[string]interface{}{},
+		MsgBatch: message.New(nil),
+	})
+}
+
+// computeValue parses the query of a computed value up to the earliest closing
+// brace that results in a valid query, allowing queries to contain braces, and
+// returns the result along with the number of bytes consumed.
+func computeValue(input []byte) (string, int, error) {
+	var firstErr error
+	for end := 0; end < len(input); end++ {
+		if input[end] != '}' {
+			continue
+		}
+		expr := string(input[:end])
+		exec, err := bloblang.NewEnvironmentMapping(expr)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+				if perr, ok := err.(*parser.Error); ok {
+					firstErr = fmt.Errorf("%v", perr.ErrorAtPosition([]rune(expr)))
+				}
+			}
+			continue
+		}
+		res, err := exec.Exec(query.FunctionContext{
+			Maps:     exec.Maps(),
+			Vars:     map[string]interface{}{},
+			MsgBatch: message.New(nil),
+		})
+		if err != nil {
+			return "", 0, err
+		}
+		if _, isNothing := res.(query.Nothing); isNothing {
+			return "", end + 1, nil
+		}
+		return query.IToString(res), end + 1, nil
+	}
+	if firstErr == nil {
+		firstErr = errors.New("expected closing brace")
+	}
+	return "", 0, firstErr
+}
@@ -0,0 +1,84 @@
+package text
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputedValues(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_COMPUTED", "prod")
+	defer os.Unsetenv("BENTHOS_TEST_COMPUTED")
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	tmpDir := t.TempDir()
+	secretPath := filepath.Join(tmpDir, "secret.txt")
+	require.NoError(t, ioutil.WriteFile(secretPath, []byte("hunter2\n"), 0644))
+
+	tests := map[string]struct {
+		input  string
+		output string
+		err    string
+	}{
+		"no computed values": {
+			input:  `foo: ${BENTHOS_TEST_COMPUTED} ${!hostname()}`,
+			output: `foo: ${BENTHOS_TEST_COMPUTED} ${!hostname()}`,
+		},
+		"hostname and env": {
+			input:  `client_id: ${= hostname() + "-" + env("BENTHOS_TEST_COMPUTED") }`,
+			output: `client_id: ` + hostname + `-prod`,
+		},
+		"multiple values": {
+			input:  `foo: ${= "a" }/${="b".uppercase()}`,
+			output: `foo: a/B`,
+		},
+		"file contents": {
+			input:  `password: ${= file("` + secretPath + `").string().trim() }`,
+			output: `password: hunter2`,
+		},
+		"braces within query": {
+			input:  `foo: ${= {"a":"b"}.a } bar: ${= if env("BENTHOS_TEST_COMPUTED") == "prod" { 10 } else { 1 } }`,
+			output: `foo: b bar: 10`,
+		},
+		"structured result": {
+			input:  `foo: ${= ["a","b"] }`,
+			output: `foo: ["a","b"]`,
+		},
+		"escaped": {
+			input:  `foo: ${{= hostname() }}`,
+			output: `foo: ${= hostname() }`,
+		},
+		"bad query": {
+			input: "foo: bar\nbaz: ${= nope( }",
+			err:   "line 2: failed to compute value: ",
+		},
+		"message functions": {
+			input: `foo: ${= content() }`,
+			err:   "line 1: failed to compute value: ",
+		},
+		"unterminated": {
+			input: `foo: ${= hostname()`,
+			err:   "line 1: failed to compute value: expected closing brace",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			res, err := ReplaceComputedValues([]byte(test.input))
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(res))
+		})
+	}
+}
@@ -13,4 +13,5 @@ import (
 	_ "github.com/Jeffail/benthos/v3/internal/service/mongodb"
 	_ "github.com/Jeffail/benthos/v3/internal/service/nats"
 	_ "github.com/Jeffail/benthos/v3/internal/service/pulsar"
+	_ "github.com/Jeffail/benthos/v3/internal/service/snowflake"
 )
@@ -29,15 +29,16 @@ import (
 //
 // Benthos streams register HTTP methods
 type StreamBuilder struct {
-	http       api.Config
-	threads    int
-	inputs     []input.Config
-	buffer     buffer.Config
-	processors []processor.Config
-	outputs    []output.Config
-	resources  manager.ResourceConfig
-	metrics    metrics.Config
-	logger     log.Config
+	http        api.Config
+	threads     int
+	keyAffinity string
+	inputs      []input.Config
+	buffer      buffer.Config
+	processors  []processor.Config
+	outputs     []output.Config
+	resources   manager.ResourceConfig
+	metrics     metrics.Config
+	logger      log.Config
 
 	apiMut       manager.APIReg
 	customLogger log.Modular
@@ -250,6 +251,7 @@ func (s *StreamBuilder) SetYAML(conf string) error {
 	s.buffer = sconf.Buffer
 	s.processors = sconf.Pipeline.Processors
 	s.threads = sconf.Pipeline.Threads
+	s.keyAffinity = sconf.Pipeline.KeyAffinity
 	s.outputs = []output.Config{sconf.Output}
 	s.resources = sconf.ResourceConfig
 	s.logger = sconf.Logger
@@ -403,6 +405,7 @@ func (s *StreamBuilder) buildConfig() builderConfig {
 	conf.Buffer = s.buffer
 
 	conf.Pipeline.Threads = s.threads
+	conf.Pipeline.KeyAffinity = s.keyAffinity
 	conf.Pipeline.Processors = s.processors
 
 	if len(s.outputs) == 1 {
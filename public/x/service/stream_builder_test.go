@@ -19,9 +19,14 @@ func TestStreamBuilderDefault(t *testing.T) {
     label: ""
     stdin:`,
 		`buffer:
+    label: ""
     none: {}`,
 		`pipeline:
     threads: 0
+    key_affinity: ""
+    profiling:
+        enabled: false
+        sample_rate: 0.01
     processors: []`,
 		`output:
     label: ""
@@ -72,9 +77,14 @@ type: local`))
     label: ""
     kafka:`,
 		`buffer:
+    label: ""
     none: {}`,
 		`pipeline:
     threads: 10
+    key_affinity: ""
+    profiling:
+        enabled: false
+        sample_rate: 0.01
     processors:`,
 		`
         - label: ""
@@ -260,9 +270,14 @@ output:
     label: ""
     kafka:`,
 		`buffer:
+    label: ""
     none: {}`,
 		`pipeline:
     threads: 5
+    key_affinity: ""
+    profiling:
+        enabled: false
+        sample_rate: 0.01
     processors:`,
 		`
         - label: ""